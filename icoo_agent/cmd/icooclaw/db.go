@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/config"
+	"icooclaw/pkg/storage"
+)
+
+var dbBatchSize int
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "数据库维护",
+}
+
+var dbKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "生成数据库加密密钥",
+	Long: `生成随机的 32 字节密钥并以 base64 输出，可保存到 database.encryption.key_env 指定的环境变量，
+或存入系统钥匙串后通过 key_command 读取。`,
+	Args: cobra.NoArgs,
+	RunE: runDBKeygen,
+}
+
+var dbEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "加密已有的对话内容",
+	Long: `用当前密钥加密启用加密前写入的明文消息、记忆、会话摘要和元数据、对话轨迹、离线消息、实体事实和键值，并重新加密使用旧密钥的记录。
+轮换密钥时将新密钥设为当前密钥、旧密钥加入 previous_key_envs 或 previous_key_commands，
+执行本命令后即可移除旧密钥。已处理的记录不会重复写入，中断后可以重新执行。`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error { return runReencrypt(cmd, false) },
+}
+
+var dbDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "解密全部对话内容",
+	Long:  `将加密的对话内容写回明文，之后可以关闭 database.encryption。执行时加密配置需要保持启用。`,
+	Args:  cobra.NoArgs,
+	RunE:  func(cmd *cobra.Command, args []string) error { return runReencrypt(cmd, true) },
+}
+
+var dbMoveHistoryCmd = &cobra.Command{
+	Use:   "move-history",
+	Short: "将消息和记忆迁移到会话历史数据库",
+	Long: `配置 database.history_path 后，将仍保存在主数据库中的消息和记忆分批迁移到会话历史数据库。
+记录按原样复制，已加密的内容保持加密。已迁移的记录不会重复写入，中断后可以重新执行。`,
+	Args: cobra.NoArgs,
+	RunE: runDBMoveHistory,
+}
+
+func init() {
+	dbEncryptCmd.Flags().IntVar(&dbBatchSize, "batch", 500, "每批处理的记录数")
+	dbDecryptCmd.Flags().IntVar(&dbBatchSize, "batch", 500, "每批处理的记录数")
+	dbMoveHistoryCmd.Flags().IntVar(&dbBatchSize, "batch", 500, "每批处理的记录数")
+
+	dbCmd.AddCommand(dbKeygenCmd)
+	dbCmd.AddCommand(dbEncryptCmd)
+	dbCmd.AddCommand(dbDecryptCmd)
+	dbCmd.AddCommand(dbMoveHistoryCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+func runDBKeygen(cmd *cobra.Command, args []string) error {
+	key := make([]byte, storage.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("生成密钥失败: %w", err)
+	}
+	fmt.Println(base64.StdEncoding.EncodeToString(key))
+	return nil
+}
+
+// runReencrypt 加密或解密已有的记录
+func runReencrypt(cmd *cobra.Command, decrypt bool) error {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if !cfg.Database.Encryption.Enabled {
+		return fmt.Errorf("未启用 database.encryption")
+	}
+	store, err := openConfigStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	results, err := store.Reencrypt(ctx, decrypt, dbBatchSize, func(r storage.ReencryptResult) {
+		fmt.Printf("\r%s: 已检查 %d 条，已写入 %d 条", r.Table, r.Rows, r.Rewritten)
+	})
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+
+	action := "加密"
+	if decrypt {
+		action = "解密"
+	}
+	for _, r := range results {
+		fmt.Printf("%s: 共 %d 条，%s %d 条\n", r.Table, r.Rows, action, r.Rewritten)
+	}
+	return nil
+}
+
+// runDBMoveHistory 将主数据库中的消息和记忆迁移到会话历史数据库
+func runDBMoveHistory(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if cfg.Database.HistoryPath == "" {
+		return fmt.Errorf("未配置 database.history_path")
+	}
+	if err := cfg.EnsureDatabasePath(); err != nil {
+		return err
+	}
+	store, err := openConfigStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	results, err := store.MoveHistory(ctx, dbBatchSize, func(r storage.HistoryMoveResult) {
+		fmt.Printf("\r%s: 已迁移 %d 条", r.Table, r.Rows)
+	})
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		fmt.Printf("%s: 共迁移 %d 条\n", r.Table, r.Rows)
+	}
+	return nil
+}
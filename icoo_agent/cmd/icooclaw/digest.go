@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/config"
+	"icooclaw/pkg/digest"
+)
+
+var (
+	digestSend   bool
+	digestFormat string
+)
+
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "预览或立即发送活动摘要邮件",
+	Long: `按 [digest] 配置生成最近一个已结束周期的活动摘要，默认输出到标准输出，
+--send 时通过 [smtp] 发送给 digest.recipients。等待确认的请求只保存在运行中的网关进程内，
+命令行生成的摘要不包含这部分内容。`,
+	Args: cobra.NoArgs,
+	RunE: runDigest,
+}
+
+func init() {
+	digestCmd.Flags().BoolVar(&digestSend, "send", false, "立即发送邮件")
+	digestCmd.Flags().StringVarP(&digestFormat, "format", "f", "text", "预览格式: text 或 html")
+
+	rootCmd.AddCommand(digestCmd)
+}
+
+func runDigest(cmd *cobra.Command, args []string) error {
+	if digestFormat != "text" && digestFormat != "html" {
+		return fmt.Errorf("未知的格式: %s", digestFormat)
+	}
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	schedule, err := cfg.Digest.Schedule()
+	if err != nil {
+		return fmt.Errorf("digest 配置错误: %w", err)
+	}
+
+	store, err := openConfigStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	runner := digest.NewRunner(store, schedule, cfg.Digest.Options(), cfg.SMTP.Mail(), cfg.Digest.Recipients, false, nil)
+	from, to := schedule.Window(time.Now())
+
+	if digestSend {
+		if len(cfg.Digest.Recipients) == 0 {
+			return fmt.Errorf("digest.recipients 不能为空")
+		}
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		report, err := runner.Send(ctx, from, to)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已发送 %s 的活动摘要给 %d 个收件人\n", report.Period(), len(cfg.Digest.Recipients))
+		return nil
+	}
+
+	report, err := runner.Build(from, to)
+	if err != nil {
+		return err
+	}
+	if digestFormat == "html" {
+		html, err := report.HTML()
+		if err != nil {
+			return err
+		}
+		fmt.Print(html)
+		return nil
+	}
+	fmt.Print(report.Text())
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/history"
+	"icooclaw/pkg/transcript"
+)
+
+var (
+	historyChannel    string
+	historyChats      []string
+	historyAssistants []string
+	historyUser       string
+	historyMemory     bool
+	historyDryRun     bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "历史消息导入",
+}
+
+var historyImportCmd = &cobra.Command{
+	Use:   "import <telegram|slack|openai|anthropic|langchain|sharegpt> <file>",
+	Short: "导入部署前的聊天记录",
+	Long: `将 Telegram Desktop 导出的 result.json 或 Slack 工作区导出的 zip 导入为会话和消息。
+每个聊天对应一个会话，会话 ID 为来源中的会话 ID（Telegram chat id、Slack 频道 ID）。
+openai、anthropic、langchain、sharegpt 格式的对话文件导入为一个会话，会话 ID 为文件名（不含扩展名），
+只导入用户和助手的文本消息。
+消息 ID 由来源生成，重复导入同一份文件不会产生重复记录。
+--memory 同时写入记忆，供记忆检索和合并使用。`,
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: append([]string{history.SourceTelegram, history.SourceSlack}, transcript.Formats()...),
+	RunE:      runHistoryImport,
+}
+
+func init() {
+	historyImportCmd.Flags().StringVar(&historyChannel, "channel", "", "导入到的渠道，默认与来源同名")
+	historyImportCmd.Flags().StringSliceVar(&historyChats, "chat", nil, "只导入指定 ID 或名称的会话，可重复指定")
+	historyImportCmd.Flags().StringSliceVar(&historyAssistants, "assistant", nil, "作为助手消息导入的发送者 ID 或名称，可重复指定")
+	historyImportCmd.Flags().StringVar(&historyUser, "user", "", "会话所属用户 ID，默认为第一个非助手发送者")
+	historyImportCmd.Flags().BoolVar(&historyMemory, "memory", false, "同时写入记忆")
+	historyImportCmd.Flags().BoolVar(&historyDryRun, "dry-run", false, "只列出将导入的会话，不写入存储")
+
+	historyCmd.AddCommand(historyImportCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistoryImport(cmd *cobra.Command, args []string) error {
+	source, path := args[0], args[1]
+	isTranscript := false
+	if format, err := transcript.ParseFormat(source); err == nil {
+		source, isTranscript = format, true
+	}
+
+	conversations, err := readHistory(source, path)
+	if err != nil {
+		return err
+	}
+	if len(historyChats) > 0 {
+		conversations = slices.DeleteFunc(conversations, func(c *history.Conversation) bool {
+			return !slices.Contains(historyChats, c.ID) && !slices.Contains(historyChats, c.Title)
+		})
+		if len(conversations) == 0 {
+			return fmt.Errorf("没有匹配 --chat 的会话")
+		}
+	}
+
+	opts := history.Options{
+		Source:     source,
+		Channel:    historyChannel,
+		Assistants: historyAssistants,
+		UserID:     historyUser,
+		Memory:     historyMemory,
+	}
+	if isTranscript {
+		opts.Assistants = append(opts.Assistants, history.TranscriptAssistant)
+	}
+
+	if historyDryRun {
+		for _, conv := range conversations {
+			sess, messages, _ := history.Records(conv, opts)
+			fmt.Printf("%s:%s\t%s\t消息 %d 条\n", sess.Channel, sess.ID, sess.Title, len(messages))
+		}
+		fmt.Printf("共 %d 个会话（未写入）\n", len(conversations))
+		return nil
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var sessions int
+	var messages, memories int64
+	for _, conv := range conversations {
+		sess, msgs, mems := history.Records(conv, opts)
+		if len(msgs) == 0 {
+			continue
+		}
+		res, err := store.ImportHistory(sess, msgs, mems)
+		if err != nil {
+			return fmt.Errorf("导入会话 %s 失败: %w", conv.ID, err)
+		}
+		fmt.Printf("%s:%s\t%s\t新增消息 %d 条\n", sess.Channel, sess.ID, sess.Title, res.Messages)
+		sessions++
+		messages += res.Messages
+		memories += res.Memories
+	}
+
+	fmt.Printf("导入 %d 个会话，新增消息 %d 条，记忆 %d 条\n", sessions, messages, memories)
+	return nil
+}
+
+// readHistory 按来源解析导出文件
+func readHistory(source, path string) ([]*history.Conversation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer f.Close()
+
+	switch source {
+	case history.SourceTelegram:
+		return history.ParseTelegram(f)
+	case history.SourceSlack:
+		info, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("读取文件失败: %w", err)
+		}
+		return history.ParseSlack(f, info.Size())
+	default:
+		if _, err := transcript.ParseFormat(source); err != nil {
+			return nil, fmt.Errorf("不支持的来源: %s（可选 telegram、slack、%s）", source, strings.Join(transcript.Formats(), "、"))
+		}
+		info, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("读取文件失败: %w", err)
+		}
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		return history.ParseTranscript(source, id, f, info.ModTime())
+	}
+}
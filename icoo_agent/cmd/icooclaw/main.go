@@ -14,6 +14,8 @@ import (
 	// Import channel implementations to register their factories
 	_ "icooclaw/pkg/channels/dingtalk"
 	_ "icooclaw/pkg/channels/feishu"
+	_ "icooclaw/pkg/channels/qq"
+	_ "icooclaw/pkg/channels/wecom"
 )
 
 var (
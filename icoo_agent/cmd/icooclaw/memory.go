@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/storage"
+)
+
+// memoryFilterFlags 批量操作共用的筛选参数
+type memoryFilterFlags struct {
+	sessionID string
+	role      string
+	memType   string
+	tag       string
+	query     string
+	before    string
+	after     string
+	pinned    string
+}
+
+var (
+	memFilter       memoryFilterFlags
+	memOutput       string
+	memSkipExisting bool
+	memAddTags      []string
+	memRemoveTags   []string
+	memYes          bool
+	memThreshold    float64
+	memDryRun       bool
+)
+
+var memoryCmd = &cobra.Command{
+	Use:   "memory",
+	Short: "记忆管理",
+	Long: `批量导入导出和整理智能体记忆。
+筛选参数（--session、--role、--type、--tag、--query、--before、--after、--pinned）可用于
+export、retag、set-type、delete 和 dedupe 子命令。`,
+}
+
+var memoryExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "导出记忆为 JSONL",
+	Args:  cobra.NoArgs,
+	RunE:  runMemoryExport,
+}
+
+var memoryImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "从 JSONL 导入记忆",
+	Long:  "从 JSONL 文件导入记忆，每行一条记录，保留原有 ID 和创建时间。文件为 - 时从标准输入读取。",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMemoryImport,
+}
+
+var memoryRetagCmd = &cobra.Command{
+	Use:   "retag",
+	Short: "批量添加或移除标签",
+	Args:  cobra.NoArgs,
+	RunE:  runMemoryRetag,
+}
+
+var memorySetTypeCmd = &cobra.Command{
+	Use:   "set-type <type>",
+	Short: "批量修改记忆类型",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMemorySetType,
+}
+
+var memoryDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "按条件批量删除记忆",
+	Args:  cobra.NoArgs,
+	RunE:  runMemoryDelete,
+}
+
+var memoryDedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "检测并合并相似的重复记忆",
+	Long: `在同一会话内按内容相似度检测重复记忆，保留最早的一条，
+合并标签和置顶状态后删除其余记录。`,
+	Args: cobra.NoArgs,
+	RunE: runMemoryDedupe,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{memoryExportCmd, memoryRetagCmd, memorySetTypeCmd, memoryDeleteCmd, memoryDedupeCmd} {
+		addMemoryFilterFlags(cmd)
+	}
+
+	memoryExportCmd.Flags().StringVarP(&memOutput, "output", "o", "-", "输出文件，- 表示标准输出")
+	memoryImportCmd.Flags().BoolVar(&memSkipExisting, "skip-existing", false, "跳过 ID 已存在的记录，默认覆盖")
+	memoryRetagCmd.Flags().StringSliceVar(&memAddTags, "add", nil, "添加的标签，逗号分隔")
+	memoryRetagCmd.Flags().StringSliceVar(&memRemoveTags, "remove", nil, "移除的标签，逗号分隔")
+	memoryDeleteCmd.Flags().BoolVarP(&memYes, "yes", "y", false, "跳过确认")
+	memoryDedupeCmd.Flags().Float64Var(&memThreshold, "threshold", 0.85, "相似度阈值 (0-1)")
+	memoryDedupeCmd.Flags().BoolVar(&memDryRun, "dry-run", false, "只列出重复记忆，不做修改")
+	memoryDedupeCmd.Flags().BoolVarP(&memYes, "yes", "y", false, "跳过确认")
+
+	memoryCmd.AddCommand(memoryExportCmd, memoryImportCmd, memoryRetagCmd, memorySetTypeCmd, memoryDeleteCmd, memoryDedupeCmd)
+	rootCmd.AddCommand(memoryCmd)
+}
+
+// addMemoryFilterFlags 注册筛选参数
+func addMemoryFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&memFilter.sessionID, "session", "", "会话键，如 websocket:<session_id>")
+	cmd.Flags().StringVar(&memFilter.role, "role", "", "角色")
+	cmd.Flags().StringVar(&memFilter.memType, "type", "", "记忆类型")
+	cmd.Flags().StringVar(&memFilter.tag, "tag", "", "包含的标签")
+	cmd.Flags().StringVar(&memFilter.query, "query", "", "内容包含的文本")
+	cmd.Flags().StringVar(&memFilter.before, "before", "", "创建时间早于 (2006-01-02 或 RFC3339)")
+	cmd.Flags().StringVar(&memFilter.after, "after", "", "创建时间晚于 (2006-01-02 或 RFC3339)")
+	cmd.Flags().StringVar(&memFilter.pinned, "pinned", "", "是否置顶 (true/false)")
+}
+
+// build 将命令行参数转换为存储层筛选条件
+func (f memoryFilterFlags) build() (storage.MemoryFilter, error) {
+	filter := storage.MemoryFilter{
+		SessionID: f.sessionID,
+		Role:      f.role,
+		Type:      f.memType,
+		Tag:       f.tag,
+		Query:     f.query,
+	}
+
+	var err error
+	if filter.Before, err = parseTimeFlag(f.before); err != nil {
+		return filter, fmt.Errorf("--before 格式错误: %w", err)
+	}
+	if filter.After, err = parseTimeFlag(f.after); err != nil {
+		return filter, fmt.Errorf("--after 格式错误: %w", err)
+	}
+
+	switch f.pinned {
+	case "":
+	case "true", "false":
+		pinned := f.pinned == "true"
+		filter.Pinned = &pinned
+	default:
+		return filter, fmt.Errorf("--pinned 只能为 true 或 false")
+	}
+	return filter, nil
+}
+
+// parseTimeFlag 解析日期或 RFC3339 时间
+func parseTimeFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func runMemoryExport(cmd *cobra.Command, args []string) error {
+	filter, err := memFilter.build()
+	if err != nil {
+		return err
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	memories, err := store.Memory().List(filter)
+	if err != nil {
+		return fmt.Errorf("查询记忆失败: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if memOutput != "-" {
+		f, err := os.Create(memOutput)
+		if err != nil {
+			return fmt.Errorf("创建输出文件失败: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for _, m := range memories {
+		if err := enc.Encode(m); err != nil {
+			return fmt.Errorf("写入记忆失败: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("写入记忆失败: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "已导出 %d 条记忆\n", len(memories))
+	return nil
+}
+
+func runMemoryImport(cmd *cobra.Command, args []string) error {
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("打开文件失败: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	memories, err := readMemoriesJSONL(r)
+	if err != nil {
+		return err
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	imported, err := store.Memory().Import(memories, memSkipExisting)
+	if err != nil {
+		return fmt.Errorf("导入记忆失败: %w", err)
+	}
+
+	fmt.Printf("读取 %d 条，导入 %d 条\n", len(memories), imported)
+	return nil
+}
+
+// readMemoriesJSONL 逐行解析 JSONL，空行忽略，出错时报告行号
+func readMemoriesJSONL(r io.Reader) ([]*storage.Memory, error) {
+	var memories []*storage.Memory
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+
+		var m storage.Memory
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("第 %d 行解析失败: %w", line, err)
+		}
+		if m.Content == "" || m.SessionID == "" {
+			return nil, fmt.Errorf("第 %d 行缺少 session_id 或 content", line)
+		}
+		if m.Role == "" {
+			m.Role = "user"
+		}
+		memories = append(memories, &m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	return memories, nil
+}
+
+func runMemoryRetag(cmd *cobra.Command, args []string) error {
+	if len(memAddTags) == 0 && len(memRemoveTags) == 0 {
+		return fmt.Errorf("需要指定 --add 或 --remove")
+	}
+
+	filter, err := memFilter.build()
+	if err != nil {
+		return err
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	changed, err := store.Memory().Retag(filter, memAddTags, memRemoveTags)
+	if err != nil {
+		return fmt.Errorf("修改标签失败: %w", err)
+	}
+
+	fmt.Printf("已更新 %d 条记忆的标签\n", changed)
+	return nil
+}
+
+func runMemorySetType(cmd *cobra.Command, args []string) error {
+	filter, err := memFilter.build()
+	if err != nil {
+		return err
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	changed, err := store.Memory().SetType(filter, args[0])
+	if err != nil {
+		return fmt.Errorf("修改记忆类型失败: %w", err)
+	}
+
+	fmt.Printf("已将 %d 条记忆的类型修改为 %s\n", changed, args[0])
+	return nil
+}
+
+func runMemoryDelete(cmd *cobra.Command, args []string) error {
+	filter, err := memFilter.build()
+	if err != nil {
+		return err
+	}
+	if filter.IsEmpty() {
+		return fmt.Errorf("批量删除至少需要一个筛选条件")
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	memories, err := store.Memory().List(filter)
+	if err != nil {
+		return fmt.Errorf("查询记忆失败: %w", err)
+	}
+	if len(memories) == 0 {
+		fmt.Println("没有匹配的记忆")
+		return nil
+	}
+
+	if !memYes && !confirm(bufio.NewReader(os.Stdin), fmt.Sprintf("将删除 %d 条记忆，确认? [y/N]: ", len(memories))) {
+		fmt.Println("已取消")
+		return nil
+	}
+
+	deleted, err := store.Memory().DeleteByFilter(filter)
+	if err != nil {
+		return fmt.Errorf("删除记忆失败: %w", err)
+	}
+
+	fmt.Printf("已删除 %d 条记忆\n", deleted)
+	return nil
+}
+
+func runMemoryDedupe(cmd *cobra.Command, args []string) error {
+	if memThreshold <= 0 || memThreshold > 1 {
+		return fmt.Errorf("--threshold 必须在 (0, 1] 之间")
+	}
+
+	filter, err := memFilter.build()
+	if err != nil {
+		return err
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	groups, err := store.Memory().FindDuplicates(filter, memThreshold)
+	if err != nil {
+		return fmt.Errorf("检测重复记忆失败: %w", err)
+	}
+	if len(groups) == 0 {
+		fmt.Println("未发现重复记忆")
+		return nil
+	}
+
+	total := 0
+	for _, g := range groups {
+		fmt.Printf("保留 %s: %s\n", g.Keep.ID, preview(g.Keep.Content))
+		for _, d := range g.Duplicates {
+			fmt.Printf("  重复 %s: %s\n", d.ID, preview(d.Content))
+		}
+		total += len(g.Duplicates)
+	}
+	fmt.Printf("共 %d 组，%d 条重复记忆\n", len(groups), total)
+
+	if memDryRun {
+		return nil
+	}
+	if !memYes && !confirm(bufio.NewReader(os.Stdin), "合并并删除重复记忆? [y/N]: ") {
+		fmt.Println("已取消")
+		return nil
+	}
+
+	removed, err := store.Memory().MergeDuplicates(groups)
+	if err != nil {
+		return fmt.Errorf("合并重复记忆失败: %w", err)
+	}
+
+	fmt.Printf("已合并，删除 %d 条重复记忆\n", removed)
+	return nil
+}
+
+// preview 截取记忆内容用于展示
+func preview(content string) string {
+	runes := []rune(content)
+	if len(runes) > 60 {
+		return string(runes[:60]) + "…"
+	}
+	return string(runes)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/config"
+	"icooclaw/pkg/routing"
+)
+
+var (
+	routeChannel  string
+	routeUser     string
+	routeUserName string
+	routeSession  string
+	routeTime     string
+	routeTimezone string
+)
+
+var routeCmd = &cobra.Command{
+	Use:   "route",
+	Short: "消息路由规则",
+}
+
+var routeTestCmd = &cobra.Command{
+	Use:   "test <消息>",
+	Short: "测试路由规则，不发送消息",
+	Long: `用配置文件中的 routing.rules 评估一条模拟消息，按顺序输出每条规则是否匹配及原因，
+以及最终的路由结果。不连接渠道和模型，不执行命令。`,
+	Example: `  icooclaw route test "我要退款"
+  icooclaw route test --channel feishu --user ou_123 --time 22:30 "在吗"
+  icooclaw route test --time "2026-10-18 10:00" --tz Asia/Shanghai "周末有人吗"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRouteTest,
+}
+
+func init() {
+	routeTestCmd.Flags().StringVar(&routeChannel, "channel", consts.WEBSOCKET, "消息渠道")
+	routeTestCmd.Flags().StringVar(&routeUser, "user", "", "发送者 ID")
+	routeTestCmd.Flags().StringVar(&routeUserName, "name", "", "发送者名称")
+	routeTestCmd.Flags().StringVar(&routeSession, "session", "", "会话 ID")
+	routeTestCmd.Flags().StringVar(&routeTime, "time", "", "消息时间，HH:MM（今天）或 YYYY-MM-DD HH:MM，默认当前时间")
+	routeTestCmd.Flags().StringVar(&routeTimezone, "tz", "", "用户时区，如 Asia/Shanghai，默认本地时区")
+
+	routeCmd.AddCommand(routeTestCmd)
+	rootCmd.AddCommand(routeCmd)
+}
+
+func runRouteTest(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	router, err := routing.New(cfg.Routing.Rules)
+	if err != nil {
+		return fmt.Errorf("routing.rules 配置错误: %w", err)
+	}
+
+	loc := time.Local
+	if routeTimezone != "" {
+		if loc, err = time.LoadLocation(routeTimezone); err != nil {
+			return fmt.Errorf("时区无效: %w", err)
+		}
+	}
+	now, err := parseRouteTime(routeTime, loc)
+	if err != nil {
+		return err
+	}
+
+	steps, d, err := router.Explain(routing.Message{
+		Channel:   routeChannel,
+		SessionID: routeSession,
+		UserID:    routeUser,
+		UserName:  routeUserName,
+		Text:      args[0],
+		Time:      now,
+	})
+
+	fmt.Printf("消息时间 %s (%s)\n", now.Format("2006-01-02 15:04 Mon"), loc)
+	if router.Len() == 0 {
+		fmt.Println("未配置路由规则，消息交给默认智能体")
+		return nil
+	}
+	for _, s := range steps {
+		if s.Matched {
+			fmt.Printf("  ✓ %s\n", s.Rule)
+		} else {
+			fmt.Printf("  ✗ %s: %s\n", s.Rule, s.Reason)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case d == nil:
+		fmt.Println("没有匹配的规则，消息交给默认智能体")
+	case d.Action == routing.ActionPersona:
+		fmt.Printf("规则 %s: 交给人设 %s 处理\n", d.Rule, d.Persona)
+	case d.Action == routing.ActionCommand:
+		fmt.Printf("规则 %s: 执行命令 %s\n", d.Rule, d.Text)
+	default:
+		fmt.Printf("规则 %s: 自动回复\n%s\n", d.Rule, d.Text)
+	}
+	return nil
+}
+
+// parseRouteTime 解析 --time，为空时返回当前时间。
+func parseRouteTime(s string, loc *time.Location) (time.Time, error) {
+	now := time.Now().In(loc)
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return now, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, loc); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("15:04", s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("时间格式应为 HH:MM 或 YYYY-MM-DD HH:MM: %s", s)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, loc), nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/config"
+	"icooclaw/pkg/storage"
+)
+
+var (
+	resetChannel     string
+	resetKeep        bool
+	resetClean       bool
+	resetKeepPinned  bool
+	resetKeepSummary bool
+
+	mergeChannel string
+	mergeInto    string
+	mergeDryRun  bool
+)
+
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "会话管理",
+}
+
+var sessionResetCmd = &cobra.Command{
+	Use:   "reset <session_id>",
+	Short: "归档会话并重新开始",
+	Long: `归档指定会话的消息历史，并以全新的上下文继续该会话。
+未指定 --keep/--clean 等参数时会交互式询问是否保留置顶记忆和会话摘要。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionReset,
+}
+
+var sessionMergeCmd = &cobra.Command{
+	Use:   "merge <session_id>...",
+	Short: "合并重复会话",
+	Long: `将同一逻辑对话产生的多个会话（如 Telegram 重连、REST 客户端重复创建）合并到 --into 指定的会话。
+消息按原始时间合并，记忆、制品和对话轨迹归属到目标会话；被合并的会话归档并保留重定向，
+之后发往这些会话的消息在目标会话中继续处理。--dry-run 只预览合并后的时间线。`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSessionMerge,
+}
+
+func init() {
+	sessionMergeCmd.Flags().StringVar(&mergeChannel, "channel", consts.WEBSOCKET, "会话所属渠道")
+	sessionMergeCmd.Flags().StringVar(&mergeInto, "into", "", "保留的目标会话ID")
+	sessionMergeCmd.Flags().BoolVar(&mergeDryRun, "dry-run", false, "只预览合并后的时间线，不修改数据")
+	sessionMergeCmd.MarkFlagRequired("into")
+	sessionCmd.AddCommand(sessionMergeCmd)
+
+	sessionResetCmd.Flags().StringVar(&resetChannel, "channel", consts.WEBSOCKET, "会话所属渠道")
+	sessionResetCmd.Flags().BoolVar(&resetKeep, "keep", false, "保留置顶记忆和会话摘要")
+	sessionResetCmd.Flags().BoolVar(&resetClean, "clean", false, "完全清空，不保留任何上下文")
+	sessionResetCmd.Flags().BoolVar(&resetKeepPinned, "keep-pinned", false, "保留置顶记忆")
+	sessionResetCmd.Flags().BoolVar(&resetKeepSummary, "keep-summary", false, "保留会话摘要")
+
+	sessionCmd.AddCommand(sessionResetCmd)
+	rootCmd.AddCommand(sessionCmd)
+}
+
+func runSessionReset(cmd *cobra.Command, args []string) error {
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	opts := storage.ResetOptions{
+		KeepPinned:  resetKeep || resetKeepPinned,
+		KeepSummary: resetKeep || resetKeepSummary,
+	}
+
+	// 未指定任何选项时交互式询问
+	flags := cmd.Flags()
+	if !flags.Changed("keep") && !flags.Changed("clean") &&
+		!flags.Changed("keep-pinned") && !flags.Changed("keep-summary") {
+		reader := bufio.NewReader(os.Stdin)
+		opts.KeepPinned = confirm(reader, "保留置顶记忆? [y/N]: ")
+		opts.KeepSummary = confirm(reader, "保留会话摘要? [y/N]: ")
+	}
+
+	result, err := store.ResetSession(resetChannel, args[0], opts)
+	if err != nil {
+		return fmt.Errorf("重置会话失败: %w", err)
+	}
+
+	fmt.Printf("会话已重置: %s\n", result.SessionID)
+	fmt.Printf("归档会话: %s (消息 %d 条, 记忆 %d 条)\n", result.ArchiveID, result.Messages, result.Memories)
+	fmt.Printf("保留置顶记忆: %d 条, 保留摘要: %v\n", result.KeptMemories, result.SummaryCarried)
+	return nil
+}
+
+func runSessionMerge(cmd *cobra.Command, args []string) error {
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	result, err := store.MergeSessions(storage.MergeOptions{
+		Channel: mergeChannel,
+		Target:  mergeInto,
+		Sources: args,
+		DryRun:  mergeDryRun,
+	})
+	if err != nil {
+		return fmt.Errorf("合并会话失败: %w", err)
+	}
+
+	if result.DryRun {
+		for _, e := range result.Timeline {
+			fmt.Printf("%s\t%s\t%s\t%s\n", e.CreatedAt.Local().Format("2006-01-02 15:04"), e.SessionID, e.Role,
+				strings.ReplaceAll(e.Content, "\n", " "))
+		}
+		fmt.Printf("将合并 %s 到 %s：消息 %d 条，记忆 %d 条，制品 %d 个，对话轨迹 %d 条（未修改）\n",
+			strings.Join(result.Sources, ", "), result.Target, result.Messages, result.Memories, result.Artifacts, result.Traces)
+		return nil
+	}
+
+	fmt.Printf("已合并 %s 到 %s：消息 %d 条，记忆 %d 条，制品 %d 个，对话轨迹 %d 条\n",
+		strings.Join(result.Sources, ", "), result.Target, result.Messages, result.Memories, result.Artifacts, result.Traces)
+	return nil
+}
+
+// openStorage 按配置打开存储
+func openStorage() (*storage.Storage, error) {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	return openConfigStorage(cfg)
+}
+
+// openConfigStorage 按已加载的配置打开存储，启用加密时读取密钥
+func openConfigStorage(cfg *config.Config) (*storage.Storage, error) {
+	dbPath, err := cfg.GetDatabasePath()
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库路径失败: %w", err)
+	}
+	opts, err := cfg.StorageOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := storage.New(cfg.Agent.Workspace, cfg.Mode, dbPath, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("初始化存储失败: %w", err)
+	}
+	return store, nil
+}
+
+// confirm 读取 y/N 确认
+func confirm(reader *bufio.Reader, prompt string) bool {
+	fmt.Print(prompt)
+	line, _ := reader.ReadString('\n')
+	line = strings.ToLower(strings.TrimSpace(line))
+	return line == "y" || line == "yes"
+}
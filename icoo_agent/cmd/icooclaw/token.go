@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/config"
+	"icooclaw/pkg/gateway/auth"
+)
+
+var (
+	tokenSubject string
+	tokenScopes  []string
+	tokenTTL     time.Duration
+	tokenKeyName string
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "网关认证凭证",
+}
+
+var tokenIssueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "签发 JWT 访问令牌",
+	Long: `用 gateway.auth.jwt_secret_env 指向的签名密钥签发 JWT，客户端以 Authorization: Bearer <令牌> 访问网关。
+令牌在过期前一直有效，需要提前作废时轮换签名密钥。`,
+	Example: `  icooclaw token issue --subject web --scope chat
+  icooclaw token issue --subject ops --scope admin --ttl 720h`,
+	Args: cobra.NoArgs,
+	RunE: runTokenIssue,
+}
+
+var tokenKeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "生成 API 密钥",
+	Long: `生成随机 API 密钥并输出 gateway.auth.api_keys 配置片段。配置中只保存密钥的 SHA-256 摘要，
+明文密钥仅输出一次。轮换时先添加新密钥，客户端切换后再删除旧密钥。`,
+	Example: `  icooclaw token key --name web --scope chat`,
+	Args:    cobra.NoArgs,
+	RunE:    runTokenKey,
+}
+
+var tokenSecretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "生成 JWT 签名密钥",
+	Long: `生成随机 JWT 签名密钥，设置到 gateway.auth.jwt_secret_env 指向的环境变量。
+轮换时把当前密钥移到 previous_jwt_secret_envs 中的环境变量，旧令牌过期后再移除。`,
+	Args: cobra.NoArgs,
+	RunE: runTokenSecret,
+}
+
+func init() {
+	tokenIssueCmd.Flags().StringVar(&tokenSubject, "subject", "", "令牌主体，作为请求的用户 ID")
+	tokenIssueCmd.Flags().StringSliceVar(&tokenScopes, "scope", []string{auth.ScopeChat}, "权限范围：chat、admin")
+	tokenIssueCmd.Flags().DurationVar(&tokenTTL, "ttl", 0, "有效期，默认使用 gateway.auth.token_ttl")
+	_ = tokenIssueCmd.MarkFlagRequired("subject")
+
+	tokenKeyCmd.Flags().StringVar(&tokenKeyName, "name", "", "密钥名称，作为请求的用户 ID")
+	tokenKeyCmd.Flags().StringSliceVar(&tokenScopes, "scope", []string{auth.ScopeChat}, "权限范围：chat、admin")
+	_ = tokenKeyCmd.MarkFlagRequired("name")
+
+	tokenCmd.AddCommand(tokenIssueCmd, tokenKeyCmd, tokenSecretCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
+
+func runTokenIssue(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	opts, err := cfg.Gateway.Auth.Options()
+	if err != nil {
+		return err
+	}
+	if len(opts.Secrets) == 0 {
+		return fmt.Errorf("环境变量 %s 未设置签名密钥，可用 icooclaw token secret 生成", cfg.Gateway.Auth.JWTSecretEnv)
+	}
+	a, err := auth.New(auth.Options{Secrets: opts.Secrets, Issuer: opts.Issuer, TTL: opts.TTL})
+	if err != nil {
+		return err
+	}
+	token, exp, err := a.Issue(tokenSubject, tokenScopes, tokenTTL)
+	if err != nil {
+		return err
+	}
+
+	if !cfg.Gateway.Auth.Enabled {
+		fmt.Fprintln(os.Stderr, "提示：gateway.auth.enabled 未开启，网关暂不校验令牌")
+	}
+	fmt.Fprintf(os.Stderr, "主体 %s，权限 %s，有效期至 %s\n", tokenSubject, strings.Join(tokenScopes, ","), exp.Format(time.RFC3339))
+	fmt.Println(token)
+	return nil
+}
+
+func runTokenKey(cmd *cobra.Command, args []string) error {
+	key, err := auth.GenerateKey()
+	if err != nil {
+		return err
+	}
+	// 校验名称和权限范围
+	if _, err := auth.New(auth.Options{Keys: []auth.APIKey{{Name: tokenKeyName, Key: key, Scopes: tokenScopes}}}); err != nil {
+		return err
+	}
+
+	fmt.Printf("API 密钥（仅显示一次）: %s\n\n", key)
+	fmt.Println("添加到配置文件：")
+	fmt.Println("[[gateway.auth.api_keys]]")
+	fmt.Printf("name = %q\n", tokenKeyName)
+	fmt.Printf("key_sha256 = %q\n", auth.HashKey(key))
+	fmt.Printf("scopes = [%s]\n", quoteList(tokenScopes))
+	return nil
+}
+
+func runTokenSecret(cmd *cobra.Command, args []string) error {
+	secret, err := auth.GenerateSecret()
+	if err != nil {
+		return err
+	}
+	fmt.Println(secret)
+	return nil
+}
+
+// quoteList 输出 TOML 字符串数组的元素。
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/app"
+)
+
+var toolserverStdio bool
+
+var toolserverCmd = &cobra.Command{
+	Use:   "toolserver",
+	Short: "启动独立工具服务",
+	Long: `只运行工具注册表（文件、网络、命令、JavaScript 和插件工具），不启动智能体、提供商和渠道，
+供其他智能体框架通过 MCP 或 REST 调用 icooclaw 的沙箱工具。
+
+默认在 toolserver.addr 上提供 REST 接口（/api/v1/tools）和 MCP streamable HTTP 接口（/mcp），
+启用 gateway.auth 时需要 admin 权限的凭证；--stdio 通过标准输入输出提供 MCP 服务，日志输出到标准错误。`,
+	Example: `  icooclaw toolserver
+  icooclaw toolserver --stdio -c ./config.toml`,
+	Args: cobra.NoArgs,
+	RunE: runToolServer,
+}
+
+func init() {
+	toolserverCmd.Flags().BoolVar(&toolserverStdio, "stdio", false, "通过标准输入输出提供 MCP 服务")
+	rootCmd.AddCommand(toolserverCmd)
+}
+
+func runToolServer(cmd *cobra.Command, args []string) error {
+	a := app.NewApp()
+	defer a.Close()
+	if err := a.InitToolServer(cfgFile, version); err != nil {
+		return err
+	}
+	return a.RunToolServer(toolserverStdio)
+}
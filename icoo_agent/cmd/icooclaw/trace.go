@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/config"
+	icooclawConsts "icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/trace"
+)
+
+var (
+	traceChannel string
+	traceLimit   int
+	traceSession string
+	traceFormat  string
+	traceOutput  string
+)
+
+var traceCmd = &cobra.Command{
+	Use:   "trace",
+	Short: "对话轨迹",
+}
+
+var traceListCmd = &cobra.Command{
+	Use:   "list <session_id>",
+	Short: "列出会话最近的对话轨迹",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTraceList,
+}
+
+var traceExportCmd = &cobra.Command{
+	Use:   "export [trace_id]",
+	Short: "导出单轮对话轨迹报告",
+	Long: `将一轮对话的提示词、推理过程、工具调用（参数和截断后的结果）、耗时和 Token 用量
+导出为独立的 Markdown 或 HTML 报告。未指定 trace_id 时通过 --session 导出该会话最近一轮。
+--format 为 openai、anthropic、langchain 或 sharegpt 时导出该轮的消息序列（JSON），
+可作为评测用例或在其他框架中回放。`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTraceExport,
+}
+
+var (
+	traceFile string
+	traceJSON bool
+)
+
+var traceReplayCmd = &cobra.Command{
+	Use:   "replay [trace_id]",
+	Short: "用当前代码重放一轮对话轨迹并对比结果",
+	Long: `从录制的提示词开始重新运行 ReAct 循环：模型回复按录制顺序返回，工具按名称和参数返回录制的结果，
+不请求提供商也不执行真实工具，之后对比工具调用序列、模型调用次数和最终回复。
+重构 ReAct 循环或提示词构建前后运行，确认行为没有变化；有差异时以非零状态退出。
+未指定 trace_id 时重放 --session 最近一轮，--file 读取 trace export -f turn 保存的用例。`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTraceReplay,
+}
+
+func init() {
+	traceListCmd.Flags().StringVar(&traceChannel, "channel", consts.WEBSOCKET, "会话所属渠道")
+	traceListCmd.Flags().IntVar(&traceLimit, "limit", 20, "最多列出的条数")
+
+	traceExportCmd.Flags().StringVar(&traceChannel, "channel", consts.WEBSOCKET, "会话所属渠道")
+	traceExportCmd.Flags().StringVar(&traceSession, "session", "", "导出该会话最近一轮")
+	traceExportCmd.Flags().StringVarP(&traceFormat, "format", "f", trace.FormatMarkdown, "导出格式: markdown、html、turn、openai、anthropic、langchain 或 sharegpt")
+	traceExportCmd.Flags().StringVarP(&traceOutput, "output", "o", "", "输出文件，默认输出到标准输出")
+
+	traceReplayCmd.Flags().StringVar(&traceChannel, "channel", consts.WEBSOCKET, "会话所属渠道")
+	traceReplayCmd.Flags().StringVar(&traceSession, "session", "", "重放该会话最近一轮")
+	traceReplayCmd.Flags().StringVar(&traceFile, "file", "", "重放 turn 格式导出的轨迹文件")
+	traceReplayCmd.Flags().BoolVar(&traceJSON, "json", false, "以 JSON 输出重放结果")
+
+	traceCmd.AddCommand(traceListCmd)
+	traceCmd.AddCommand(traceExportCmd)
+	traceCmd.AddCommand(traceReplayCmd)
+	rootCmd.AddCommand(traceCmd)
+}
+
+func runTraceList(cmd *cobra.Command, args []string) error {
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	traces, err := store.Trace().ListBySession(traceChannel, args[0], traceLimit)
+	if err != nil {
+		return fmt.Errorf("获取轨迹列表失败: %w", err)
+	}
+	if len(traces) == 0 {
+		fmt.Println("没有轨迹记录")
+		return nil
+	}
+
+	for _, t := range traces {
+		status := ""
+		if t.Error != "" {
+			status = " [失败]"
+		}
+		input, _ := trace.Truncate(t.Input, 40)
+		fmt.Printf("%s  %s  迭代 %d  工具 %d  耗时 %s%s  %s\n",
+			t.ID, t.CreatedAt.Format(time.DateTime), t.Iterations, t.ToolCalls,
+			time.Duration(t.DurationMs)*time.Millisecond, status, input)
+	}
+	return nil
+}
+
+func runTraceExport(cmd *cobra.Command, args []string) error {
+	turn, err := loadTrace(args)
+	if err != nil {
+		return err
+	}
+
+	content, _, _, err := trace.Render(turn, traceFormat)
+	if err != nil {
+		return err
+	}
+
+	if traceOutput == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	if err := os.WriteFile(traceOutput, content, 0o644); err != nil {
+		return fmt.Errorf("写入报告失败: %w", err)
+	}
+	fmt.Printf("轨迹报告已导出: %s\n", traceOutput)
+	return nil
+}
+
+// loadTrace 按 trace_id 或 --session 从存储读取一轮轨迹。
+func loadTrace(args []string) (*trace.Turn, error) {
+	if len(args) == 0 && traceSession == "" {
+		return nil, fmt.Errorf("需要指定 trace_id 或 --session")
+	}
+
+	store, err := openStorage()
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	var record *storage.Trace
+	if len(args) > 0 {
+		record, err = store.Trace().Get(args[0])
+	} else {
+		record, err = store.Trace().Latest(traceChannel, traceSession)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取轨迹失败: %w", err)
+	}
+
+	turn, err := trace.Decode(record.Data)
+	if err != nil {
+		return nil, err
+	}
+	turn.ID = record.ID
+	return turn, nil
+}
+
+func runTraceReplay(cmd *cobra.Command, args []string) error {
+	var turn *trace.Turn
+	if traceFile != "" {
+		data, err := os.ReadFile(traceFile)
+		if err != nil {
+			return fmt.Errorf("读取轨迹文件失败: %w", err)
+		}
+		if turn, err = trace.Decode(string(data)); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if turn, err = loadTrace(args); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	// 只设置影响 ReAct 循环的配置，重放不访问存储、提供商和真实工具
+	agent, err := react.NewReActAgent(cmd.Context(), nil,
+		react.WithMaxToolIterations(icooclawConsts.DEFAULT_TOOL_ITERATIONS),
+		react.WithToolRepeatLimit(cfg.Agent.ToolRepeatLimit),
+		react.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		return err
+	}
+	result := agent.Replay(cmd.Context(), turn)
+
+	if traceJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		printReplay(turn, result)
+	}
+	if !result.Equal() {
+		return fmt.Errorf("重放结果与录制不一致（%d 处差异）", len(result.Diff))
+	}
+	return nil
+}
+
+// printReplay 输出重放结果和差异。
+func printReplay(turn *trace.Turn, result *react.ReplayResult) {
+	fmt.Printf("轨迹 %s  模型调用 %d  工具调用 %d\n", turn.ID, result.Iterations, len(result.ToolCalls))
+	if result.Truncated {
+		fmt.Println("注意: 录制的部分工具结果已截断，重放时使用截断后的内容")
+	}
+	if result.Equal() {
+		fmt.Println("重放结果与录制一致")
+		return
+	}
+	fmt.Println("差异:")
+	for _, d := range result.Diff {
+		fmt.Println("  - " + d)
+	}
+}
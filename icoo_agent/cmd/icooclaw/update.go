@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/config"
+	"icooclaw/pkg/update"
+)
+
+var (
+	updateCheck     bool
+	updateChannel   string
+	updateForce     bool
+	updateNoRestart bool
+	updateRollback  bool
+	updateYes       bool
+)
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "检查并安装新版本",
+	Long: `从 update.endpoint 获取发布渠道（stable/beta）的版本清单，下载当前平台的可执行文件，
+校验 sha256 和 ed25519 签名后原子替换当前可执行文件，旧版本保留为 <可执行文件>.old，
+然后执行 update.restart_command 重启服务。会话、记忆等数据保存在数据库和工作目录中，重启后保留。
+--rollback 恢复上一个版本。`,
+	Args: cobra.NoArgs,
+	RunE: runSelfUpdate,
+}
+
+func init() {
+	selfUpdateCmd.Flags().BoolVar(&updateCheck, "check", false, "只检查是否有新版本")
+	selfUpdateCmd.Flags().StringVar(&updateChannel, "channel", "", "发布渠道 stable 或 beta，默认使用 update.channel")
+	selfUpdateCmd.Flags().BoolVar(&updateForce, "force", false, "版本不比当前新时也安装（可用于切换渠道后降级）")
+	selfUpdateCmd.Flags().BoolVar(&updateNoRestart, "no-restart", false, "替换后不重启服务")
+	selfUpdateCmd.Flags().BoolVar(&updateRollback, "rollback", false, "恢复上一个版本")
+	selfUpdateCmd.Flags().BoolVarP(&updateYes, "yes", "y", false, "跳过确认")
+
+	rootCmd.AddCommand(selfUpdateCmd)
+}
+
+func runSelfUpdate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+
+	if updateRollback {
+		if !updateYes && !confirm(bufio.NewReader(os.Stdin), fmt.Sprintf("用 %s 恢复上一个版本? [y/N]: ", update.BackupPath(exe))) {
+			return nil
+		}
+		if err := update.Rollback(exe); err != nil {
+			return err
+		}
+		fmt.Println("已恢复上一个版本")
+		return restartService(cfg.Update.RestartCommand)
+	}
+
+	channel := updateChannel
+	if channel == "" {
+		channel = cfg.Update.Channel
+	}
+	updater, err := update.New(cfg.Update.Endpoint, channel, cfg.Update.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	release, newer, err := updater.Check(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("当前版本: %s\n%s 渠道最新版本: %s", version, release.Channel, release.Version)
+	if !release.PublishedAt.IsZero() {
+		fmt.Printf("（发布于 %s）", release.PublishedAt.Local().Format(time.DateTime))
+	}
+	fmt.Println()
+	if release.Notes != "" {
+		fmt.Println(release.Notes)
+	}
+	if !newer && !updateForce {
+		fmt.Println("已是最新版本")
+		return nil
+	}
+	if updateCheck {
+		return nil
+	}
+	if !updateYes && !confirm(bufio.NewReader(os.Stdin), fmt.Sprintf("安装 %s 到 %s? [y/N]: ", release.Version, exe)) {
+		return nil
+	}
+
+	path, err := updater.Download(ctx, release, filepath.Dir(exe))
+	if err != nil {
+		return err
+	}
+	if err := smokeTest(ctx, path, release.Version); err != nil {
+		os.Remove(path)
+		return err
+	}
+	if err := update.Install(path, exe); err != nil {
+		os.Remove(path)
+		return err
+	}
+	fmt.Printf("已安装 %s，旧版本保留在 %s\n", release.Version, update.BackupPath(exe))
+	return restartService(cfg.Update.RestartCommand)
+}
+
+// executablePath 当前可执行文件的真实路径
+func executablePath() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("获取可执行文件路径失败: %w", err)
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return "", fmt.Errorf("获取可执行文件路径失败: %w", err)
+	}
+	return exe, nil
+}
+
+// smokeTest 替换前运行新版本的 version 命令，确认可以在当前平台启动且版本与清单一致
+func smokeTest(ctx context.Context, path, want string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("新版本无法运行: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	if !strings.Contains(string(out), strings.TrimPrefix(want, "v")) {
+		return fmt.Errorf("新版本报告的版本与发布清单不一致: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// restartService 执行重启命令，服务收到停止信号后正常关闭，状态保存在数据库中
+func restartService(command string) error {
+	if updateNoRestart {
+		return nil
+	}
+	if command == "" {
+		fmt.Println("未配置 update.restart_command，请手动重启服务以使用新版本")
+		return nil
+	}
+
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command("sh", "-c", command)
+	}
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("重启服务失败: %w", err)
+	}
+	fmt.Println("服务已重启")
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/config"
+	"icooclaw/pkg/userdata"
+)
+
+var (
+	userOutput string
+	userDryRun bool
+	userYes    bool
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "用户数据管理",
+}
+
+var userExportCmd = &cobra.Command{
+	Use:   "export <user_id>",
+	Short: "导出用户的全部数据",
+	Long:  "导出用户的会话（含归档）、消息、记忆、轨迹、离线消息、绑定、键值、实体事实以及工作目录 users/<user_id> 下的文件。",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUserExport,
+}
+
+var userPurgeCmd = &cobra.Command{
+	Use:   "purge <user_id>",
+	Short: "导出并删除用户的全部数据",
+	Long: `先将用户的全部数据导出到 --output 指定的文件，再从存储和各工作目录中删除。
+--dry-run 只列出将被删除的内容，不做任何修改。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runUserPurge,
+}
+
+func init() {
+	userExportCmd.Flags().StringVarP(&userOutput, "output", "o", "-", "输出文件，- 表示标准输出")
+	userPurgeCmd.Flags().StringVarP(&userOutput, "output", "o", "", "删除前导出数据的文件，默认为 user-<user_id>-export.json")
+	userPurgeCmd.Flags().BoolVar(&userDryRun, "dry-run", false, "只列出将被删除的内容，不做修改")
+	userPurgeCmd.Flags().BoolVarP(&userYes, "yes", "y", false, "跳过确认")
+
+	userCmd.AddCommand(userExportCmd, userPurgeCmd)
+	rootCmd.AddCommand(userCmd)
+}
+
+// openUserData 按配置创建用户数据服务，返回的 close 用于关闭存储
+func openUserData() (*userdata.Service, func(), error) {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	store, err := openStorage()
+	if err != nil {
+		return nil, nil, err
+	}
+	return userdata.New(store, cfg.Agent.WorkspaceList(), nil), func() { store.Close() }, nil
+}
+
+func runUserExport(cmd *cobra.Command, args []string) error {
+	svc, closeFn, err := openUserData()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	export, err := svc.Export(args[0])
+	if err != nil {
+		return fmt.Errorf("导出用户数据失败: %w", err)
+	}
+	if err := writeUserExport(userOutput, export); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "已导出用户 %s 的数据: %d 个会话, %d 条消息, %d 条记忆, %d 个文件\n",
+		args[0], len(export.Sessions), len(export.Messages), len(export.Memories), len(export.Files))
+	return nil
+}
+
+func runUserPurge(cmd *cobra.Command, args []string) error {
+	userID := args[0]
+	svc, closeFn, err := openUserData()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	plan, err := svc.Plan(userID)
+	if err != nil {
+		return fmt.Errorf("统计用户数据失败: %w", err)
+	}
+	printUserReport(plan)
+	if userDryRun {
+		return nil
+	}
+
+	output := userOutput
+	if output == "" {
+		output = fmt.Sprintf("user-%s-export.json", userID)
+	}
+	if !userYes && !confirm(bufio.NewReader(os.Stdin), fmt.Sprintf("将导出到 %s 后删除以上数据，确认? [y/N]: ", output)) {
+		fmt.Println("已取消")
+		return nil
+	}
+
+	// 先写出导出文件再删除，导出失败时不删除任何数据
+	export, err := svc.Export(userID)
+	if err != nil {
+		return fmt.Errorf("导出用户数据失败: %w", err)
+	}
+	if err := writeUserExport(output, export); err != nil {
+		return err
+	}
+	_, report, err := svc.Purge(userID)
+	if err != nil {
+		return fmt.Errorf("删除用户数据失败: %w", err)
+	}
+
+	fmt.Printf("已导出到 %s 并删除用户 %s 的数据\n", output, userID)
+	for _, e := range report.FileErrors {
+		fmt.Printf("  删除文件失败: %s\n", e)
+	}
+	return nil
+}
+
+// printUserReport 打印将被删除的内容
+func printUserReport(r *userdata.Report) {
+	fmt.Printf("用户 %s:\n", r.UserID)
+	fmt.Printf("  会话 %d, 消息 %d, 记忆 %d, 轨迹 %d\n", r.Sessions, r.Messages, r.Memories, r.Traces)
+	fmt.Printf("  离线消息 %d, 绑定 %d, 键值 %d, 实体事实 %d, 实体关系 %d\n",
+		r.OfflineMessages, r.Bindings, r.KV, r.EntityFacts, r.EntityRelations)
+	fmt.Printf("  文件 %d 个, 共 %d 字节\n", len(r.Files), r.FileBytes)
+	for _, f := range r.Files {
+		fmt.Printf("    %s\n", f)
+	}
+}
+
+// writeUserExport 以 JSON 写出用户数据，path 为 - 时写到标准输出
+func writeUserExport(path string, export *userdata.Export) error {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("创建输出文件失败: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return fmt.Errorf("写入导出文件失败: %w", err)
+	}
+	return nil
+}
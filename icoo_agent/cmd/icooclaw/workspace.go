@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"icooclaw/pkg/config"
+	"icooclaw/pkg/workspace"
+)
+
+var (
+	workspaceProfile string
+	workspaceForce   bool
+)
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "工作目录管理",
+}
+
+var workspaceInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "用档案模板初始化工作目录",
+	Long: `用档案的模板套装初始化工作目录。以 .tmpl 结尾的模板按 Go 模板渲染，可引用
+{{.AgentName}}、{{.UserName}}、{{.Date}}、{{.Profile}} 和 {{.Vars.key}}，其他文件原样复制。
+默认跳过已存在的文件，--force 时覆盖。`,
+	Args: cobra.NoArgs,
+	RunE: runWorkspaceInit,
+}
+
+func init() {
+	workspaceInitCmd.Flags().StringVarP(&workspaceProfile, "profile", "p", "", "模板档案，默认使用 agent.templates.profile")
+	workspaceInitCmd.Flags().BoolVar(&workspaceForce, "force", false, "覆盖已存在的文件")
+
+	workspaceCmd.AddCommand(workspaceInitCmd)
+	rootCmd.AddCommand(workspaceCmd)
+}
+
+func runWorkspaceInit(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	opts := cfg.Agent.Templates.InitOptions(workspaceProfile)
+	opts.Force = workspaceForce
+	result, err := workspace.Init(cfg.Agent.Workspace, opts)
+	if err != nil {
+		return fmt.Errorf("初始化工作目录失败: %w", err)
+	}
+
+	fmt.Printf("档案 %s，模板来源: %s\n", opts.Profile, result.Source)
+	for _, name := range result.Created {
+		fmt.Printf("  写入 %s\n", name)
+	}
+	for _, name := range result.Skipped {
+		fmt.Printf("  跳过 %s（已存在）\n", name)
+	}
+	fmt.Printf("工作目录 %s 初始化完成，写入 %d 个文件，跳过 %d 个\n",
+		cfg.Agent.Workspace, len(result.Created), len(result.Skipped))
+	return nil
+}
@@ -0,0 +1,24 @@
+package agent
+
+import (
+	"slices"
+
+	"icooclaw/pkg/bus"
+)
+
+// adminOnly 非管理员使用管理命令时的回复
+const adminOnly = "只有管理员可以修改渠道默认设置"
+
+// WithAdmins 设置管理员发送者 ID，写作“渠道:ID”时只匹配该渠道的发送者。
+func (m *AgentManager) WithAdmins(ids []string) *AgentManager {
+	m.admins = ids
+	return m
+}
+
+// isAdmin 判断消息发送者是否为管理员。
+func (m *AgentManager) isAdmin(msg bus.InboundMessage) bool {
+	if msg.Sender.ID == "" {
+		return false
+	}
+	return slices.Contains(m.admins, msg.Sender.ID) || slices.Contains(m.admins, msg.Channel+":"+msg.Sender.ID)
+}
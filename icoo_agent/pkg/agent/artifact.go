@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
+	"icooclaw/pkg/tools/builtin/artifact"
+)
+
+// resolveArtifacts 将回复中的制品引用替换为文件名，返回需要作为附件发送的文件。
+// 会话历史中保存的仍是带引用的原始回复。
+func (m *AgentManager) resolveArtifacts(msg bus.InboundMessage, text string) (string, []channels.File) {
+	if m.storage == nil {
+		return text, nil
+	}
+
+	text, artifacts := artifact.Resolve(text, msg.Channel, msg.SessionID, m.storage.Artifact())
+	files := make([]channels.File, 0, len(artifacts))
+	for _, a := range artifacts {
+		files = append(files, channels.File{
+			ID:       a.ID,
+			Name:     a.Name,
+			Path:     a.Path,
+			MIMEType: a.MIMEType,
+			Size:     a.Size,
+		})
+	}
+	return text, files
+}
+
+// resolveArtifactsStream 包装流式回调，完成时下发的完整回复中的制品引用替换为文件名。
+func (m *AgentManager) resolveArtifactsStream(msg bus.InboundMessage, callback react.StreamCallback) react.StreamCallback {
+	if callback == nil || m.storage == nil {
+		return callback
+	}
+
+	return func(chunk react.StreamChunk) error {
+		if chunk.Done {
+			chunk.Content, _ = m.resolveArtifacts(msg, chunk.Content)
+		}
+		return callback(chunk)
+	}
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"errors"
+	"maps"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/tools/builtin/ask"
+)
+
+// WithAskUser 启用对话中提问，智能体调用 ask_user 后本轮暂停，会话的下一条消息作为回答，
+// 在斜杠命令之后、表单之前处理；超过 timeout 未回答时放弃本轮。
+func (m *AgentManager) WithAskUser(store *ask.Store, timeout time.Duration) *AgentManager {
+	m.askStore = store
+	m.askTimeout = timeout
+	return m
+}
+
+// isAnswer 判断消息是否为对智能体提问的回答，回答从暂停处继续，不再经过路由规则和常见问题。
+func isAnswer(msg bus.InboundMessage) bool {
+	_, ok := msg.Metadata[consts.META_ASK_ANSWER].(*ask.Answer)
+	return ok
+}
+
+// holdForQuestion 智能体向用户提问时保存本轮进度，返回发送给用户的问题。
+func (m *AgentManager) holdForQuestion(msg bus.InboundMessage, err error) (*ask.Pending, bool) {
+	var asked *react.AskUserError
+	if !errors.As(err, &asked) {
+		return nil, false
+	}
+
+	p := asked.Pending
+	timeout := m.askTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+	p.ExpiresAt = p.AskedAt.Add(timeout)
+	if m.askStore != nil {
+		if err := m.askStore.Save(msg.Channel, msg.SessionID, p); err != nil {
+			m.logger.With("name", "【提问】").Warn("保存提问进度失败", "error", err, "session_id", msg.SessionID)
+		}
+	}
+	return p, true
+}
+
+// publishQuestion 发送问题，候选答案作为快捷操作，点击后以答案文本回传。
+func (m *AgentManager) publishQuestion(msg bus.InboundMessage, p *ask.Pending) {
+	out := bus.OutboundMessage{
+		Channel:   msg.Channel,
+		SessionID: msg.SessionID,
+		Text:      p.Prompt(),
+	}
+	if len(p.Options) > 0 {
+		actions := make([]quickAction, 0, len(p.Options))
+		for _, o := range p.Options {
+			actions = append(actions, quickAction{Label: o, Command: o})
+		}
+		out.Metadata = map[string]any{consts.META_ACTIONS: actions}
+	}
+	m.bus.PublishOutbound(m.ctx, out)
+}
+
+// answerQuestion 会话有等待回答的问题时，将消息作为回答，返回恢复本轮的消息：
+// 内容为暂停时的用户消息，回答和进度记录在元数据中。问题已过期时放弃本轮，消息按新消息处理。
+func (m *AgentManager) answerQuestion(msg bus.InboundMessage) bus.InboundMessage {
+	if m.askStore == nil || isHeartbeat(msg) || strings.TrimSpace(msg.Text) == "" {
+		return msg
+	}
+
+	logger := m.logger.With("name", "【提问】", "channel", msg.Channel, "session_id", msg.SessionID)
+	p, err := m.askStore.Load(msg.Channel, msg.SessionID)
+	if err != nil {
+		logger.Warn("加载提问进度失败", "error", err)
+		return msg
+	}
+	if p == nil {
+		return msg
+	}
+	if err := m.askStore.Clear(msg.Channel, msg.SessionID); err != nil {
+		logger.Warn("清除提问进度失败", "error", err)
+	}
+	if p.Expired(time.Now()) {
+		logger.Info("问题超时未回答，已放弃本轮", "question", p.Question, "asked_at", p.AskedAt)
+		return msg
+	}
+
+	logger.Info("收到回答，继续本轮", "question", p.Question)
+	resumed := msg
+	resumed.Text = p.Text
+	resumed.Metadata = maps.Clone(msg.Metadata)
+	if resumed.Metadata == nil {
+		resumed.Metadata = make(map[string]any)
+	}
+	resumed.Metadata[consts.META_ASK_ANSWER] = &ask.Answer{Pending: p, Text: p.Resolve(msg.Text)}
+	resumed.Metadata[consts.META_HISTORY_SAVED] = true
+	resumed.Metadata[consts.META_COST_APPROVED] = true
+	if p.Persona != "" {
+		resumed.Metadata[consts.META_PERSONA] = p.Persona
+	}
+	return resumed
+}
@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"errors"
+
+	"icooclaw/pkg/authz"
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/notice"
+	"icooclaw/pkg/tools"
+)
+
+// WithAuthorizer 设置授权器，处理每条消息（包括斜杠命令）前先经过授权。
+// 工具执行前的授权由工具注册表的 SetAuthorizer 设置。
+func (m *AgentManager) WithAuthorizer(a *authz.Authorizer) *AgentManager {
+	m.authorizer = a
+	return m
+}
+
+// authorizeMessage 处理消息前授权，被拒绝时返回回复给用户的说明。
+func (m *AgentManager) authorizeMessage(msg bus.InboundMessage) (string, bool) {
+	if m.authorizer == nil {
+		return "", true
+	}
+
+	ctx := m.ctx
+	if m.timezones != nil {
+		ctx = tools.WithLocation(ctx, m.timezones.Current(msg.Channel, msg.SessionID))
+	}
+	err := m.authorizer.AuthorizeMessage(ctx, msg)
+	if err == nil {
+		return "", true
+	}
+
+	var (
+		denied *authz.DeniedError
+		reason string
+	)
+	if errors.As(err, &denied) {
+		reason = denied.Reason
+	}
+	return m.notice(msg, notice.Denied, map[string]any{"Reason": reason}), false
+}
@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"context"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/tools"
+)
+
+// trackChanges 记录本轮工具对工作目录的修改。
+func trackChanges(ctx context.Context) (context.Context, *tools.Changes) {
+	changes := tools.NewChanges()
+	return tools.WithChanges(ctx, changes), changes
+}
+
+// reportChanges 汇总本轮对工作目录的修改并发布 workspace.changed 事件，没有修改时返回 nil。
+// 本轮失败或暂停时文件也可能已被修改，因此无论结果如何都会汇总。
+func (m *AgentManager) reportChanges(msg bus.InboundMessage, changes *tools.Changes) *tools.ChangeSummary {
+	summary := changes.Summary()
+	if summary == nil {
+		return nil
+	}
+	m.logger.With("name", "【智能体】").Info("本轮修改了工作目录",
+		"channel", msg.Channel, "session_id", msg.SessionID,
+		"files", len(summary.Files), "added", summary.Added, "removed", summary.Removed)
+	m.publishEvent(bus.Event{
+		Type:      bus.EventWorkspaceChanged,
+		Channel:   msg.Channel,
+		SessionID: msg.SessionID,
+		Data: map[string]any{
+			"changes": summary,
+			"summary": summary.Text(),
+		},
+	})
+	return summary
+}
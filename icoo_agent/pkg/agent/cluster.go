@@ -0,0 +1,15 @@
+package agent
+
+import "icooclaw/pkg/cluster"
+
+// WithCluster 设置集群实例。多个实例共享数据库时，离线队列重放、记忆合并和记忆回顾
+// 这些全局后台任务只在主实例上运行；会话空闲检查等只涉及本实例内存状态的任务不受影响。
+func (m *AgentManager) WithCluster(n *cluster.Node) *AgentManager {
+	m.cluster = n
+	return m
+}
+
+// isLeader 本实例是否负责运行全局后台任务，未启用集群时总是返回 true。
+func (m *AgentManager) isLeader() bool {
+	return m.cluster.IsLeader()
+}
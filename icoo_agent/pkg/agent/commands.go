@@ -0,0 +1,352 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/command"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/utils"
+)
+
+// Commands 返回命令注册表，渠道可通过它注册自定义命令。
+func (m *AgentManager) Commands() *command.Registry {
+	return m.commands
+}
+
+// registerBuiltinCommands 注册内置斜杠命令。
+func (m *AgentManager) registerBuiltinCommands() {
+	builtins := []*command.Command{
+		{
+			Name:        "help",
+			Description: "查看可用命令",
+			Handler:     m.cmdHelp,
+		},
+		{
+			Name:        "reset",
+			Description: "归档当前会话并重新开始",
+			Usage:       "[keep|clean]",
+			Handler:     m.cmdReset,
+		},
+		{
+			Name:        "memory",
+			Description: "查看当前会话的记忆，或置顶、删除、修正某条记忆",
+			Usage:       "[pin|unpin|delete|correct <id> [新内容]]",
+			Handler:     m.cmdMemory,
+		},
+		{
+			Name:        "usage",
+			Description: "查看当前会话的用量统计",
+			Handler:     m.cmdUsage,
+		},
+		{
+			Name:        "tools",
+			Description: "查看可用工具",
+			Handler:     m.cmdTools,
+		},
+		{
+			Name:        "model",
+			Description: "查看或切换默认模型",
+			Usage:       "[provider/model]",
+			Handler:     m.cmdModel,
+		},
+		{
+			Name:        "persona",
+			Description: "查看或切换人设",
+			Usage:       "[name|off] [channel]",
+			Handler:     m.cmdPersona,
+		},
+		{
+			Name:        "workspace",
+			Description: "查看或切换工作目录",
+			Usage:       "[name|off] [channel]",
+			Handler:     m.cmdWorkspace,
+		},
+		{
+			Name:        "timezone",
+			Description: "查看或设置时区",
+			Usage:       "[zone|off] [channel]",
+			Handler:     m.cmdTimezone,
+		},
+		{
+			Name:        "language",
+			Description: "查看或设置回复语言",
+			Usage:       "[lang|auto] [channel]",
+			Handler:     m.cmdLanguage,
+		},
+		{
+			Name:        "incognito",
+			Description: "开启或关闭无痕模式，无痕对话不保存任何记录",
+			Usage:       "[on|off]",
+			Handler:     m.cmdIncognito,
+		},
+		{
+			Name:        "set",
+			Description: "设置会话变量，提示词中以 {{key}} 引用",
+			Usage:       "key=value",
+			Handler:     m.cmdSet,
+		},
+		{
+			Name:        "unset",
+			Description: "删除会话变量",
+			Usage:       "key",
+			Handler:     m.cmdUnset,
+		},
+		{
+			Name:        "vars",
+			Description: "查看当前会话的变量",
+			Handler:     m.cmdVars,
+		},
+		{
+			Name:        "faq",
+			Description: "查看常见问题，或将上一条回复提升为常见问题",
+			Usage:       "[promote [问题]]",
+			Handler:     m.cmdFAQ,
+		},
+		{
+			Name:        "form",
+			Description: "查看或开始填写表单，或退出正在填写的表单",
+			Usage:       "[name|cancel]",
+			Handler:     m.cmdForm,
+		},
+		{
+			Name:        "cost",
+			Description: "确认或取消预计用量较大的请求，或设置确认阈值",
+			Usage:       "[yes|no|off|default|<tokens> [美元]]",
+			Handler:     m.cmdCost,
+		},
+		{
+			Name:        "stop",
+			Description: "停止当前正在进行的回复",
+			Handler:     m.cmdStop,
+		},
+	}
+
+	for _, cmd := range builtins {
+		if err := m.commands.Register(cmd); err != nil {
+			m.logger.With("name", "【智能体】").Error("注册内置命令失败", "command", cmd.Name, "error", err)
+		}
+	}
+}
+
+// cmdHelp 列出当前渠道可用的命令。
+func (m *AgentManager) cmdHelp(ctx context.Context, c *command.Context) (string, error) {
+	return command.RenderText(c.Registry.List(c.Msg.Channel)), nil
+}
+
+// cmdReset 归档当前会话并开始新的上下文。
+//
+//	/reset                               提示选择重置方式
+//	/reset keep                          保留置顶记忆和会话摘要
+//	/reset clean                         完全清空
+//	/reset --keep-pinned --keep-summary  按需保留
+func (m *AgentManager) cmdReset(ctx context.Context, c *command.Context) (string, error) {
+	if m.storage == nil {
+		return "", fmt.Errorf("未配置存储")
+	}
+
+	if len(c.Args) == 0 {
+		return "请选择重置方式:\n" +
+			"- /reset keep: 归档当前会话，保留置顶记忆和会话摘要\n" +
+			"- /reset clean: 归档当前会话，完全重新开始", nil
+	}
+
+	var opts storage.ResetOptions
+	for _, arg := range c.Args {
+		switch arg {
+		case "keep":
+			opts.KeepPinned, opts.KeepSummary = true, true
+		case "clean":
+			opts.KeepPinned, opts.KeepSummary = false, false
+		case "--keep-pinned":
+			opts.KeepPinned = true
+		case "--keep-summary":
+			opts.KeepSummary = true
+		default:
+			return "", fmt.Errorf("未知参数: %s", arg)
+		}
+	}
+
+	result, err := m.storage.ResetSession(c.Msg.Channel, c.Msg.SessionID, opts)
+	if err != nil {
+		return "", err
+	}
+	m.releaseAgent(c.Msg.SessionID)
+
+	reply := fmt.Sprintf("会话已重置，归档了 %d 条消息", result.Messages)
+	if result.KeptMemories > 0 {
+		reply += fmt.Sprintf("，保留 %d 条置顶记忆", result.KeptMemories)
+	}
+	if result.SummaryCarried {
+		reply += "，保留会话摘要"
+	}
+	return reply, nil
+}
+
+// cmdMemory 查看会话记忆，或按 ID（前缀）编辑单条记忆，供记忆回顾摘要中的操作使用。
+//
+//	/memory                       查看当前会话记忆
+//	/memory pin <id>              置顶
+//	/memory unpin <id>            取消置顶
+//	/memory delete <id>           删除
+//	/memory correct <id> <内容>   修正内容
+func (m *AgentManager) cmdMemory(ctx context.Context, c *command.Context) (string, error) {
+	if action := c.Arg(0); action != "" {
+		return m.editMemory(action, c.Args[1:])
+	}
+	if m.memory == nil {
+		return "", fmt.Errorf("未配置记忆加载器")
+	}
+
+	sessionKey := consts.GetSessionKey(c.Msg.Channel, c.Msg.SessionID)
+	history, err := m.memory.Load(ctx, sessionKey)
+	if err != nil {
+		return "", err
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("当前会话记忆: %d 条消息", len(history)))
+	if m.storage != nil {
+		if sess, err := m.storage.Session().Get(c.Msg.SessionID); err == nil && sess.Summary != "" {
+			sb.WriteString("\n会话摘要: ")
+			sb.WriteString(sess.Summary)
+		}
+	}
+	return sb.String(), nil
+}
+
+// editMemory 按 ID 前缀编辑单条记忆。
+func (m *AgentManager) editMemory(action string, args []string) (string, error) {
+	if m.storage == nil {
+		return "", fmt.Errorf("未配置存储")
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("需要提供记忆 ID")
+	}
+
+	mem, err := m.storage.Memory().FindByPrefix(args[0])
+	if err != nil {
+		return "", fmt.Errorf("查找记忆失败: %w", err)
+	}
+
+	switch action {
+	case "pin", "unpin":
+		if err := m.storage.Memory().SetPinned(mem.ID, action == "pin"); err != nil {
+			return "", err
+		}
+		if action == "pin" {
+			return fmt.Sprintf("已置顶记忆 %s", args[0]), nil
+		}
+		return fmt.Sprintf("已取消置顶记忆 %s", args[0]), nil
+	case "delete":
+		if err := m.storage.Memory().DeleteByID(mem.ID); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("已删除记忆 %s", args[0]), nil
+	case "correct":
+		content := strings.TrimSpace(strings.Join(args[1:], " "))
+		if content == "" {
+			return "", fmt.Errorf("需要提供修正后的内容")
+		}
+		if err := m.storage.Memory().UpdateContent(mem.ID, content); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("已修正记忆 %s", args[0]), nil
+	default:
+		return "", fmt.Errorf("未知操作: %s", action)
+	}
+}
+
+// cmdUsage 查看会话用量。
+func (m *AgentManager) cmdUsage(ctx context.Context, c *command.Context) (string, error) {
+	if m.storage == nil {
+		return "", fmt.Errorf("未配置存储")
+	}
+
+	sessionKey := consts.GetSessionKey(c.Msg.Channel, c.Msg.SessionID)
+	counts, err := m.storage.Message().CountByRole(sessionKey)
+	if err != nil {
+		return "", err
+	}
+
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+
+	return fmt.Sprintf("当前会话消息: %d 条\n- 用户: %d\n- 助手: %d",
+		total,
+		counts[consts.RoleUser],
+		counts[consts.RoleAssistant],
+	), nil
+}
+
+// cmdTools 列出当前会话可用的工具。
+func (m *AgentManager) cmdTools(ctx context.Context, c *command.Context) (string, error) {
+	if m.tools == nil {
+		return "暂无可用工具", nil
+	}
+
+	var policy tools.Policy
+	if m.storage != nil {
+		if _, err := m.storage.Session().GetMetadata(c.Msg.Channel, c.Msg.SessionID, tools.PolicyMetadataKey, &policy); err != nil {
+			return "", err
+		}
+	}
+	available := m.tools.ListFor(policy)
+	if len(available) == 0 {
+		return "暂无可用工具", nil
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("可用工具:\n")
+	for _, t := range available {
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", t.Name(), firstLine(t.Description())))
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// cmdModel 查看或切换默认模型。
+func (m *AgentManager) cmdModel(ctx context.Context, c *command.Context) (string, error) {
+	if m.storage == nil {
+		return "", fmt.Errorf("未配置存储")
+	}
+
+	model := c.Arg(0)
+	if model == "" {
+		current, err := m.storage.Param().Get(consts.DEFAULT_MODEL_KEY)
+		if err != nil {
+			return "", err
+		}
+		if current == nil || current.Value == "" {
+			return "未设置默认模型", nil
+		}
+		return fmt.Sprintf("当前模型: %s", current.Value), nil
+	}
+
+	parts := utils.SplitProviderModel(model)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("模型格式错误，应为 provider/model: %s", model)
+	}
+	if m.providerFactory != nil {
+		if _, err := m.providerFactory.Get(parts[0]); err != nil {
+			return "", fmt.Errorf("提供商不可用: %s", parts[0])
+		}
+	}
+
+	if err := m.storage.Param().Set(consts.DEFAULT_MODEL_KEY, model, "AI Agent 默认使用的模型", "agent"); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("已切换模型: %s", model), nil
+}
+
+// firstLine 返回文本的第一行。
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
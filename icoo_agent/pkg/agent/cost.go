@@ -0,0 +1,301 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/command"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/digest"
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/notice"
+)
+
+// costThresholdKey 用户自定义的用量确认阈值在键值存储中的键
+const costThresholdKey = "cost_preview"
+
+// CostThreshold 需要用户确认的预计用量阈值，任一项达到即确认，为 0 的项不检查。
+type CostThreshold struct {
+	MinTokens int     `json:"min_tokens"`    // 预计 token 数
+	MinCost   float64 `json:"min_cost"`      // 预计费用（美元）
+	Off       bool    `json:"off,omitempty"` // 不再确认
+}
+
+// Exceeded 预计用量是否达到阈值。
+func (t CostThreshold) Exceeded(est react.CostEstimate) bool {
+	if t.Off {
+		return false
+	}
+	return (t.MinTokens > 0 && est.Tokens() >= t.MinTokens) || (t.MinCost > 0 && est.Cost >= t.MinCost)
+}
+
+// String 返回阈值的说明。
+func (t CostThreshold) String() string {
+	if t.Off || (t.MinTokens <= 0 && t.MinCost <= 0) {
+		return "不确认"
+	}
+	var parts []string
+	if t.MinTokens > 0 {
+		parts = append(parts, react.FormatTokens(t.MinTokens)+" tokens")
+	}
+	if t.MinCost > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f", t.MinCost))
+	}
+	return "预计达到 " + strings.Join(parts, " 或 ") + " 时确认"
+}
+
+// heldTurn 等待用户确认的消息。
+type heldTurn struct {
+	msg      bus.InboundMessage
+	estimate react.CostEstimate
+	expires  time.Time
+}
+
+// quickAction 提示附带的快捷操作，支持按钮的渠道渲染为按钮
+type quickAction struct {
+	Label   string `json:"label"`
+	Command string `json:"command"`
+}
+
+// WithCostPreview 调用模型前预估本轮用量，达到阈值时先发送预估并等待用户用 /cost yes 确认。
+// threshold 为默认阈值，用户可用 /cost 设置自己的阈值；等待确认的消息超过 expire 后丢弃。
+func (m *AgentManager) WithCostPreview(threshold CostThreshold, toolRounds int, expire time.Duration) *AgentManager {
+	m.costPreview = true
+	m.costThreshold = threshold
+	m.costToolRounds = toolRounds
+	m.costExpire = expire
+	return m
+}
+
+// costGate 返回智能体判断本轮是否需要确认的函数，未启用时返回 nil。
+// 已确认的消息和离线重放的消息不再确认。
+func (m *AgentManager) costGate() react.CostGate {
+	if !m.costPreview {
+		return nil
+	}
+	return func(msg bus.InboundMessage, est react.CostEstimate) bool {
+		if approved, _ := msg.Metadata[consts.META_COST_APPROVED].(bool); approved || isOfflineReplay(msg) {
+			return false
+		}
+		return m.userCostThreshold(msg).Exceeded(est)
+	}
+}
+
+// holdForCost 智能体因预计用量等待确认时暂存消息，返回确认提示。
+// 用户消息此时已写入历史，确认后处理时不再重复保存。
+func (m *AgentManager) holdForCost(msg bus.InboundMessage, err error) (string, bool) {
+	var hold *react.CostHoldError
+	if !errors.As(err, &hold) {
+		return "", false
+	}
+
+	held := msg
+	held.Metadata = maps.Clone(msg.Metadata)
+	if held.Metadata == nil {
+		held.Metadata = make(map[string]any)
+	}
+	held.Metadata[consts.META_HISTORY_SAVED] = true
+	held.Metadata[consts.META_COST_APPROVED] = true
+
+	expire := m.costExpire
+	if expire <= 0 {
+		expire = 10 * time.Minute
+	}
+	m.costMu.Lock()
+	if m.costHeld == nil {
+		m.costHeld = make(map[string]heldTurn)
+	}
+	m.costHeld[consts.GetSessionKey(msg.Channel, msg.SessionID)] = heldTurn{
+		msg:      held,
+		estimate: hold.Estimate,
+		expires:  time.Now().Add(expire),
+	}
+	m.costMu.Unlock()
+
+	est := hold.Estimate
+	return m.notice(msg, notice.CostConfirm, map[string]any{
+		"Estimate": est.Text(),
+		"Model":    est.Model,
+		"Tools":    est.Tools,
+	}), true
+}
+
+// publishCostNotice 发送确认提示，附带继续和取消两个快捷操作。
+func (m *AgentManager) publishCostNotice(msg bus.InboundMessage, text string) {
+	m.bus.PublishOutbound(m.ctx, bus.OutboundMessage{
+		Channel:   msg.Channel,
+		SessionID: msg.SessionID,
+		Text:      text,
+		Metadata: map[string]any{
+			consts.META_ACTIONS: []quickAction{
+				{Label: "继续", Command: "/cost yes"},
+				{Label: "取消", Command: "/cost no"},
+			},
+		},
+	})
+}
+
+// takeHeld 取出会话等待确认的消息，已过期时丢弃。
+func (m *AgentManager) takeHeld(channel, sessionID string) (heldTurn, bool) {
+	m.costMu.Lock()
+	defer m.costMu.Unlock()
+
+	key := consts.GetSessionKey(channel, sessionID)
+	turn, ok := m.costHeld[key]
+	delete(m.costHeld, key)
+	if !ok || time.Now().After(turn.expires) {
+		return heldTurn{}, false
+	}
+	return turn, true
+}
+
+// PendingApprovals 返回所有会话中未过期的等待确认的消息，用于活动摘要。
+func (m *AgentManager) PendingApprovals() []digest.Pending {
+	m.costMu.Lock()
+	defer m.costMu.Unlock()
+
+	now := time.Now()
+	var pending []digest.Pending
+	for _, turn := range m.costHeld {
+		if now.After(turn.expires) {
+			continue
+		}
+		pending = append(pending, digest.Pending{
+			Channel:   turn.msg.Channel,
+			SessionID: turn.msg.SessionID,
+			Model:     turn.estimate.Model,
+			Tokens:    turn.estimate.Tokens(),
+			Cost:      turn.estimate.Cost,
+			Expires:   turn.expires,
+		})
+	}
+	return pending
+}
+
+// costScope 用户阈值的存储作用域，按渠道和用户隔离，无法确定用户时按会话隔离。
+func costScope(msg bus.InboundMessage) string {
+	if msg.Sender.ID != "" {
+		return fmt.Sprintf("user:%s:%s", msg.Channel, msg.Sender.ID)
+	}
+	return fmt.Sprintf("session:%s:%s", msg.Channel, msg.SessionID)
+}
+
+// userCostThreshold 返回用户设置的阈值，未设置时返回默认阈值。
+func (m *AgentManager) userCostThreshold(msg bus.InboundMessage) CostThreshold {
+	if m.storage == nil {
+		return m.costThreshold
+	}
+	item, err := m.storage.KV().Get(costScope(msg), costThresholdKey)
+	if err != nil {
+		if !errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+			m.logger.With("name", "【智能体】").Warn("读取用量确认阈值失败", "error", err)
+		}
+		return m.costThreshold
+	}
+
+	var t CostThreshold
+	if err := json.Unmarshal([]byte(item.Value), &t); err != nil {
+		m.logger.With("name", "【智能体】").Warn("解析用量确认阈值失败", "error", err)
+		return m.costThreshold
+	}
+	return t
+}
+
+// cmdCost 处理 /cost 命令。
+//
+//	/cost                  查看确认阈值和等待确认的消息
+//	/cost yes|no           继续或取消等待确认的消息
+//	/cost <tokens> [美元]  设置本人的确认阈值，例如 /cost 50k 0.5
+//	/cost off              不再确认
+//	/cost default          恢复默认阈值
+func (m *AgentManager) cmdCost(ctx context.Context, c *command.Context) (string, error) {
+	if !m.costPreview {
+		return "用量预估未启用", nil
+	}
+
+	msg := c.Msg
+	switch c.Arg(0) {
+	case "":
+		text := "用量确认: " + m.userCostThreshold(msg).String()
+		m.costMu.Lock()
+		turn, ok := m.costHeld[consts.GetSessionKey(msg.Channel, msg.SessionID)]
+		m.costMu.Unlock()
+		if ok && time.Now().Before(turn.expires) {
+			text += fmt.Sprintf("\n等待确认: %s（%s）", turn.msg.Text, turn.estimate.Text())
+		}
+		return text, nil
+	case "yes":
+		turn, ok := m.takeHeld(msg.Channel, msg.SessionID)
+		if !ok {
+			return "没有等待确认的消息", nil
+		}
+		// 在新的协程中投递，避免阻塞正在处理本命令的消息循环
+		go func() {
+			if err := m.bus.PublishInbound(m.ctx, turn.msg); err != nil {
+				m.logger.With("name", "【智能体】").Warn("投递已确认的消息失败", "error", err, "session_id", msg.SessionID)
+			}
+		}()
+		return "已确认，开始处理", nil
+	case "no":
+		if _, ok := m.takeHeld(msg.Channel, msg.SessionID); !ok {
+			return "没有等待确认的消息", nil
+		}
+		return "已取消", nil
+	case "default":
+		if err := m.storage.KV().Delete(costScope(msg), costThresholdKey); err != nil {
+			return "", err
+		}
+		return "已恢复默认: " + m.costThreshold.String(), nil
+	case "off":
+		return m.saveCostThreshold(msg, CostThreshold{Off: true})
+	}
+
+	tokens, err := parseTokens(c.Arg(0))
+	if err != nil {
+		return "", err
+	}
+	t := CostThreshold{MinTokens: tokens}
+	if arg := strings.TrimPrefix(c.Arg(1), "$"); arg != "" {
+		if t.MinCost, err = strconv.ParseFloat(arg, 64); err != nil || t.MinCost < 0 {
+			return "", fmt.Errorf("无效的费用阈值: %s", c.Arg(1))
+		}
+	}
+	return m.saveCostThreshold(msg, t)
+}
+
+// saveCostThreshold 保存用户的确认阈值。
+func (m *AgentManager) saveCostThreshold(msg bus.InboundMessage, t CostThreshold) (string, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	if err := m.storage.KV().Set(costScope(msg), costThresholdKey, string(data)); err != nil {
+		return "", err
+	}
+	return "已设置用量确认: " + t.String(), nil
+}
+
+// parseTokens 解析 30000、30k、1.5m 形式的 token 数。
+func parseTokens(s string) (int, error) {
+	lower := strings.ToLower(s)
+	unit := 1.0
+	switch {
+	case strings.HasSuffix(lower, "k"):
+		unit, lower = 1000, strings.TrimSuffix(lower, "k")
+	case strings.HasSuffix(lower, "m"):
+		unit, lower = 1_000_000, strings.TrimSuffix(lower, "m")
+	}
+	n, err := strconv.ParseFloat(lower, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的 token 阈值: %s，可用 yes、no、off、default 或数字（如 50k）", s)
+	}
+	return int(n * unit), nil
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/memory"
+)
+
+// entityExtractTimeout 单次实体抽取的超时时间
+const entityExtractTimeout = time.Minute
+
+// WithEntityGraph 配置实体图：extract 为 true 时每轮回复后异步抽取对话中的实体，
+// recallLimit 为用户消息提到实体时注入提示词的最多实体个数，0 表示不注入。
+func (m *AgentManager) WithEntityGraph(extract bool, recallLimit int) *AgentManager {
+	m.entityExtract = extract
+	m.entityRecallLimit = recallLimit
+	return m
+}
+
+// extractEntities 异步从本轮对话中抽取实体写入实体图，失败只记录日志。无痕会话不抽取。
+func (m *AgentManager) extractEntities(msg bus.InboundMessage, reply string) {
+	if !m.entityExtract || m.storage == nil || msg.Text == "" || reply == "" || m.isEphemeral(msg) {
+		return
+	}
+
+	go func() {
+		provider, modelName, err := m.defaultProvider()
+		if err != nil {
+			m.logger.With("name", "【智能体】").Warn("获取提供商失败，跳过实体抽取", "error", err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(m.ctx, entityExtractTimeout)
+		defer cancel()
+
+		extractor := memory.NewEntityExtractor(provider, modelName, m.storage, m.logger)
+		saved, err := extractor.Process(ctx, msg.SessionID, msg.Text, reply)
+		if err != nil {
+			m.logger.With("name", "【智能体】").Warn("实体抽取失败", "error", err, "session_id", msg.SessionID)
+			return
+		}
+		if saved > 0 {
+			m.logger.With("name", "【智能体】").Debug("实体已写入实体图", "count", saved, "session_id", msg.SessionID)
+		}
+	}()
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/command"
+	"icooclaw/pkg/ephemeral"
+)
+
+// WithEphemeral 启用无痕会话，idle 为无痕会话空闲多久后自动清除，deny 为无痕会话中禁用的工具。
+// 记忆加载器需要同时使用 ephemeral.NewLoader 包装，无痕会话的消息才只保存在内存中。
+func (m *AgentManager) WithEphemeral(r *ephemeral.Registry, idle time.Duration, deny []string) *AgentManager {
+	m.ephemeral = r
+	m.ephemeralIdle = idle
+	m.ephemeralDeny = deny
+	return m
+}
+
+// isEphemeral 消息是否属于无痕会话。
+func (m *AgentManager) isEphemeral(msg bus.InboundMessage) bool {
+	return m.ephemeral.Get(msg.Channel, msg.SessionID) != nil
+}
+
+// RunEphemeralSweeper 定期清除空闲的无痕会话。
+func (m *AgentManager) RunEphemeralSweeper(ctx context.Context) {
+	if m.ephemeral == nil || m.ephemeralIdle <= 0 {
+		return
+	}
+
+	interval := m.ephemeralIdle / 4
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := m.ephemeral.CloseIdle(m.ephemeralIdle); n > 0 {
+				m.logger.With("name", "【智能体】").Info("已清除空闲的无痕会话", "count", n)
+			}
+		}
+	}
+}
+
+// cmdIncognito 处理 /incognito 命令。
+//
+//	/incognito       查看当前会话是否为无痕会话
+//	/incognito on    开启无痕模式，之后的消息、工具结果和文件只保存在内存和临时目录中
+//	/incognito off   关闭无痕模式并清除本次无痕对话的全部内容
+func (m *AgentManager) cmdIncognito(ctx context.Context, c *command.Context) (string, error) {
+	if m.ephemeral == nil {
+		return "无痕会话未启用", nil
+	}
+
+	switch c.Arg(0) {
+	case "":
+		if m.isEphemeral(c.Msg) {
+			return "当前会话为无痕会话，关闭后对话内容和临时文件会全部清除", nil
+		}
+		return "当前会话为普通会话", nil
+	case "on":
+		if _, err := m.ephemeral.Open(c.Msg.Channel, c.Msg.SessionID); err != nil {
+			return "", err
+		}
+		m.releaseAgent(c.Msg.SessionID)
+		return "已开启无痕模式：之后的消息、工具结果和文件不会保存，也不会带入此前的对话历史。发送 /incognito off 结束并清除", nil
+	case "off":
+		if !m.ephemeral.Close(c.Msg.Channel, c.Msg.SessionID) {
+			return "当前会话不是无痕会话", nil
+		}
+		m.releaseAgent(c.Msg.SessionID)
+		return "已关闭无痕模式，本次无痕对话的内容和临时文件已清除", nil
+	default:
+		return "", fmt.Errorf("未知参数: %s，可用 on 或 off", c.Arg(0))
+	}
+}
@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/command"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/faq"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+// faqListLimit /faq 列出的常见问题条数
+const faqListLimit = 10
+
+// WithFAQ 启用常见问题快速回复，消息在路由规则之后、调用模型之前匹配常见问题。
+func (m *AgentManager) WithFAQ(matcher *faq.Matcher) *AgentManager {
+	m.faq = matcher
+	return m
+}
+
+// answerFAQ 用常见问题回复消息，命中时问答写入会话历史，返回 true。
+func (m *AgentManager) answerFAQ(msg bus.InboundMessage) (string, bool) {
+	if m.faq == nil || len(msg.Media) > 0 || isHeartbeat(msg) || isFormSubmission(msg) || isAnswer(msg) {
+		return "", false
+	}
+
+	match, err := m.faq.Match(msg.Text, msg.Channel)
+	if err != nil {
+		m.logger.With("name", "【常见问题】").Warn("匹配常见问题失败", "error", err)
+		return "", false
+	}
+	if match == nil {
+		return "", false
+	}
+
+	reply := m.faqVars(msg).Interpolate(match.FAQ.Answer)
+	m.logger.With("name", "【常见问题】").Info("消息已由常见问题回复",
+		"faq_id", match.FAQ.ID, "question", match.Question, "score", match.Score,
+		"channel", msg.Channel, "session_id", msg.SessionID)
+
+	if err := m.storage.FAQ().Hit(match.FAQ.ID); err != nil {
+		m.logger.With("name", "【常见问题】").Warn("更新命中次数失败", "error", err)
+	}
+	if m.memory != nil {
+		sessionKey := consts.GetSessionKey(msg.Channel, msg.SessionID)
+		for _, turn := range [][2]string{
+			{consts.RoleUser.ToString(), msg.Text},
+			{consts.RoleAssistant.ToString(), reply},
+		} {
+			if err := m.memory.Save(m.ctx, sessionKey, turn[0], turn[1]); err != nil {
+				m.logger.With("name", "【常见问题】").Warn("保存会话历史失败", "error", err)
+			}
+		}
+	}
+	return reply, true
+}
+
+// faqVars 返回常见问题回复可引用的变量，会话变量覆盖同名的内置变量。
+func (m *AgentManager) faqVars(msg bus.InboundMessage) tools.Vars {
+	now := time.Now()
+	if m.timezones != nil {
+		now = now.In(m.timezones.Current(msg.Channel, msg.SessionID))
+	}
+	v := tools.Vars{
+		"user_id":   msg.Sender.ID,
+		"user_name": msg.Sender.Name,
+		"channel":   msg.Channel,
+		"date":      now.Format("2006-01-02"),
+		"time":      now.Format("15:04"),
+	}
+
+	if store, err := m.varStore(); err == nil {
+		session, err := store.Load(msg.Channel, msg.SessionID)
+		if err != nil {
+			m.logger.With("name", "【常见问题】").Warn("加载会话变量失败", "error", err)
+		}
+		for k, value := range session {
+			v[k] = value
+		}
+	}
+	return v
+}
+
+// cmdFAQ 处理 /faq 命令。
+//
+//	/faq                 查看命中最多的常见问题
+//	/faq promote [问题]  将本会话智能体的上一条回复提升为常见问题，问题默认为对应的用户消息
+func (m *AgentManager) cmdFAQ(ctx context.Context, c *command.Context) (string, error) {
+	if m.faq == nil {
+		return "常见问题未启用", nil
+	}
+
+	switch c.Arg(0) {
+	case "", "list":
+		return m.renderFAQList()
+	case "promote":
+		sessionKey := consts.GetSessionKey(c.Msg.Channel, c.Msg.SessionID)
+		messages, err := m.storage.Message().Get(sessionKey, 20)
+		if err != nil {
+			return "", err
+		}
+		for _, msg := range messages {
+			if msg.Role != consts.RoleAssistant {
+				continue
+			}
+			f, err := faq.Promote(m.storage, msg.ID, strings.Join(c.Args[1:], " "))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("已添加常见问题: %s", f.Question), nil
+		}
+		return "当前会话没有可提升的回复", nil
+	default:
+		return "", fmt.Errorf("未知参数: %s，可用 list 或 promote", c.Arg(0))
+	}
+}
+
+// renderFAQList 渲染命中最多的常见问题。
+func (m *AgentManager) renderFAQList() (string, error) {
+	entries, err := m.storage.FAQ().ListEnabled()
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "暂无常见问题，可用 /faq promote 将上一条回复添加为常见问题", nil
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("常见问题 %d 条:\n", len(entries)))
+	slices.SortStableFunc(entries, func(a, b *storage.FAQ) int { return cmp.Compare(b.Hits, a.Hits) })
+	for i, f := range entries {
+		if i == faqListLimit {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("- %s（命中 %d 次）\n", f.Question, f.Hits))
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/command"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/form"
+)
+
+// WithForms 启用表单，正在填写表单的会话中消息在斜杠命令之后、路由规则之前作为当前字段的回答。
+func (m *AgentManager) WithForms(forms *form.Registry, store *form.Store) *AgentManager {
+	m.forms = forms
+	m.formStore = store
+	return m
+}
+
+// isFormSubmission 判断消息是否为填写完成的表单，表单内容直接交给智能体，不再经过路由规则和常见问题。
+func isFormSubmission(msg bus.InboundMessage) bool {
+	_, ok := msg.Metadata[consts.META_FORM].(string)
+	return ok
+}
+
+// fillForm 将消息作为正在填写的表单的回答。handled 为 true 时 reply 为回复用户的内容；
+// 表单填写完成时返回的消息内容替换为表单内容，交给智能体处理。
+func (m *AgentManager) fillForm(msg bus.InboundMessage) (filled bus.InboundMessage, reply string, handled bool) {
+	if m.forms.Len() == 0 || m.formStore == nil || isHeartbeat(msg) {
+		return msg, "", false
+	}
+
+	logger := m.logger.With("name", "【表单】", "channel", msg.Channel, "session_id", msg.SessionID)
+	st, err := m.formStore.Load(msg.Channel, msg.SessionID)
+	if err != nil {
+		logger.Warn("加载表单进度失败", "error", err)
+		return msg, "", false
+	}
+	if st == nil {
+		return msg, "", false
+	}
+
+	// 表单已删除或进度过期时退出表单，本条消息按普通消息处理
+	now := time.Now()
+	if _, ok := m.forms.Get(st.Form); !ok || m.forms.Expired(st, now) {
+		logger.Info("表单已失效，退出表单", "form", st.Form, "updated_at", st.UpdatedAt)
+		m.clearForm(msg)
+		return msg, "", false
+	}
+	if len(msg.Media) > 0 && strings.TrimSpace(msg.Text) == "" {
+		return msg, "表单只接受文字回答\n" + m.forms.Question(st), true
+	}
+
+	step, err := m.forms.Answer(st, msg.Text, now)
+	if err != nil {
+		logger.Warn("处理表单回答失败", "form", st.Form, "error", err)
+		m.clearForm(msg)
+		return msg, "", false
+	}
+
+	switch step.Outcome {
+	case form.Asking:
+		if err := m.formStore.Save(msg.Channel, msg.SessionID, st); err != nil {
+			logger.Warn("保存表单进度失败", "form", st.Form, "error", err)
+		}
+		return msg, step.Reply, true
+	case form.Cancelled:
+		logger.Info("用户取消填写表单", "form", st.Form)
+		m.clearForm(msg)
+		return msg, step.Reply, true
+	case form.Abandoned:
+		logger.Info("多次回答无效，退出表单", "form", st.Form)
+		m.clearForm(msg)
+		return msg, step.Reply, true
+	}
+
+	logger.Info("表单填写完成，交给智能体处理", "form", st.Form)
+	m.clearForm(msg)
+	m.publishEvent(bus.Event{
+		Type:      bus.EventFormSubmitted,
+		Channel:   msg.Channel,
+		SessionID: msg.SessionID,
+		Data:      map[string]any{"form": st.Form, "values": step.Values},
+	})
+	msg.Text = step.Submit
+	msg.Metadata = maps.Clone(msg.Metadata)
+	if msg.Metadata == nil {
+		msg.Metadata = make(map[string]any)
+	}
+	msg.Metadata[consts.META_FORM] = st.Form
+	return msg, "", false
+}
+
+// clearForm 清除会话的表单进度。
+func (m *AgentManager) clearForm(msg bus.InboundMessage) {
+	if err := m.formStore.Clear(msg.Channel, msg.SessionID); err != nil {
+		m.logger.With("name", "【表单】").Warn("清除表单进度失败", "error", err)
+	}
+}
+
+// cmdForm 处理 /form 命令。
+//
+//	/form            查看可用表单和当前进度
+//	/form ticket     开始填写表单
+//	/form cancel     退出正在填写的表单
+func (m *AgentManager) cmdForm(ctx context.Context, c *command.Context) (string, error) {
+	if m.forms.Len() == 0 || m.formStore == nil {
+		return "未配置表单", nil
+	}
+
+	switch name := c.Arg(0); name {
+	case "", "list", "status":
+		return m.renderForms(c.Msg)
+	case "cancel":
+		st, err := m.formStore.Load(c.Msg.Channel, c.Msg.SessionID)
+		if err != nil {
+			return "", err
+		}
+		if st == nil {
+			return "当前没有正在填写的表单", nil
+		}
+		if err := m.formStore.Clear(c.Msg.Channel, c.Msg.SessionID); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("已退出表单 %s", st.Form), nil
+	default:
+		st, prompt, _, err := m.forms.Start(name, nil, time.Now())
+		if err != nil {
+			return "", err
+		}
+		if err := m.formStore.Save(c.Msg.Channel, c.Msg.SessionID, st); err != nil {
+			return "", err
+		}
+		return prompt, nil
+	}
+}
+
+// renderForms 渲染可用表单，正在填写时附上进度。
+func (m *AgentManager) renderForms(msg bus.InboundMessage) (string, error) {
+	sb := strings.Builder{}
+	st, err := m.formStore.Load(msg.Channel, msg.SessionID)
+	if err != nil {
+		return "", err
+	}
+	if st != nil {
+		if progress := m.forms.Progress(st); progress != "" {
+			sb.WriteString(progress + "\n\n")
+		}
+	}
+
+	sb.WriteString("可用表单:\n")
+	for _, f := range m.forms.List() {
+		sb.WriteString(fmt.Sprintf("- %s（%s）", f.Name, f.Title))
+		if f.Description != "" {
+			sb.WriteString(": " + f.Description)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("使用 /form <名称> 开始填写")
+	return sb.String(), nil
+}
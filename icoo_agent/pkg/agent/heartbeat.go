@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"strings"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+)
+
+// isHeartbeat 判断消息是否为调度器发起的心跳检查。
+func isHeartbeat(msg bus.InboundMessage) bool {
+	heartbeat, _ := msg.Metadata[consts.META_HEARTBEAT].(bool)
+	return heartbeat
+}
+
+// heartbeatDone 心跳处理完成后发布事件，调度器据此释放并发名额。
+func (m *AgentManager) heartbeatDone(msg bus.InboundMessage) {
+	id, _ := msg.Metadata[consts.META_HEARTBEAT_ID].(string)
+	if id == "" {
+		return
+	}
+	m.bus.PublishEvent(bus.Event{
+		Type:      bus.EventHeartbeatDone,
+		Channel:   msg.Channel,
+		SessionID: msg.SessionID,
+		Data:      map[string]any{"id": id},
+	})
+}
+
+// heartbeatReply 去掉心跳回复中的 HEARTBEAT_OK 标记，只剩标记时 ok 为 false，表示没有需要告知用户的事项。
+func heartbeatReply(content string) (reply string, ok bool) {
+	reply = strings.TrimSpace(strings.ReplaceAll(content, consts.HEARTBEAT_OK, ""))
+	if strings.Trim(reply, " \t\r\n*`_.。!！") == "" {
+		return "", false
+	}
+	return reply, true
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/jobs"
+	"icooclaw/pkg/memory"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+)
+
+// JobResummarizeSessions 重新生成全部会话摘要的作业类型
+const JobResummarizeSessions = "resummarize_sessions"
+
+// resummarizeMaxMessages 重新摘要时每个会话最多使用的历史消息数
+const resummarizeMaxMessages = 200
+
+// ResummarizeJob 返回重新摘要作业：修改摘要提示词或更换模型后，
+// 用会话全部归档历史重新生成每个未归档会话的摘要，没有归档历史的会话保持不变。
+func (m *AgentManager) ResummarizeJob() jobs.Handler {
+	return &resummarizeJob{manager: m}
+}
+
+type resummarizeJob struct {
+	manager *AgentManager
+}
+
+// Count 返回未归档的会话数。
+func (j *resummarizeJob) Count(ctx context.Context, job *storage.Job) (int64, error) {
+	return j.manager.storage.Session().CountActive()
+}
+
+// Batch 重新摘要一批会话，单个会话失败不影响其他会话。
+func (j *resummarizeJob) Batch(ctx context.Context, job *storage.Job, size int) (jobs.BatchResult, error) {
+	var res jobs.BatchResult
+	sessions, err := j.manager.storage.Session().ListActiveAfter(job.Cursor, size)
+	if err != nil {
+		return res, err
+	}
+	if len(sessions) == 0 {
+		res.Done = true
+		return res, nil
+	}
+
+	summarizer, err := j.manager.newSummarizer()
+	if err != nil {
+		return res, err
+	}
+
+	for _, sess := range sessions {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		if err := j.manager.resummarize(ctx, summarizer, sess); err != nil {
+			res.Failed++
+			res.Error = fmt.Sprintf("%s: %v", sess.ID, err)
+		} else {
+			res.Processed++
+		}
+		res.Cursor = sess.ID
+	}
+	res.Done = len(sessions) < size
+	return res, nil
+}
+
+// resummarize 用会话的归档历史重新生成摘要。
+func (m *AgentManager) resummarize(ctx context.Context, summarizer memory.Summarizer, sess *storage.Session) error {
+	archives, err := m.storage.Session().ListArchives(sess.Channel, sess.ID)
+	if err != nil {
+		return err
+	}
+	if len(archives) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(archives))
+	for _, a := range archives {
+		keys = append(keys, consts.GetSessionKey(a.Channel, a.ID))
+	}
+	messages, err := m.storage.Message().Recent(keys, resummarizeMaxMessages)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	history := make([]providers.ChatMessage, 0, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		history = append(history, providers.ChatMessage{
+			Role:    messages[i].Role.ToString(),
+			Content: messages[i].Content,
+		})
+	}
+
+	summary, err := summarizer.Summarize(ctx, history)
+	if err != nil {
+		return fmt.Errorf("生成会话摘要失败: %w", err)
+	}
+	return m.storage.Session().SetSummary(sess.Channel, sess.ID, summary)
+}
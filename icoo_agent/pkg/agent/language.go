@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"icooclaw/pkg/command"
+	"icooclaw/pkg/language"
+)
+
+// cmdLanguage 处理 /language 命令。
+//
+//	/language                  查看当前回复语言
+//	/language <lang>           为当前会话设置回复语言，例如 zh、en、日语
+//	/language <lang> channel   为当前渠道设置回复语言
+//	/language auto [channel]   清除设置，按用户消息自动识别
+func (m *AgentManager) cmdLanguage(ctx context.Context, c *command.Context) (string, error) {
+	if m.languages == nil {
+		return "回复语言设置未启用", nil
+	}
+
+	input := c.Arg(0)
+	if input == "" {
+		code, source := m.languages.Current(c.Msg.Channel, c.Msg.SessionID)
+		if code == "" {
+			return "当前回复语言: 未识别，跟随用户消息", nil
+		}
+		return fmt.Sprintf("当前回复语言: %s (%s)，来源: %s", language.Name(code), code, sourceLabel(source)), nil
+	}
+
+	sessionID := c.Msg.SessionID
+	if c.Arg(1) == "channel" {
+		sessionID = ""
+	}
+	if input == "auto" || input == "off" {
+		input = ""
+	}
+
+	code, err := m.languages.Select(c.Msg.Channel, sessionID, input)
+	if err != nil {
+		return "", err
+	}
+	if code == "" {
+		return "已清除回复语言设置，将按用户消息自动识别", nil
+	}
+	return fmt.Sprintf("已设置回复语言: %s (%s)", language.Name(code), code), nil
+}
+
+// sourceLabel 回复语言来源的说明。
+func sourceLabel(s language.Source) string {
+	switch s {
+	case language.SourceSession:
+		return "会话设置"
+	case language.SourceChannel:
+		return "渠道设置"
+	case language.SourceDetected:
+		return "自动识别"
+	default:
+		return "默认配置"
+	}
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/memory"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/utils"
+)
+
+// WithSessionIdle 设置会话空闲超时与检查间隔，timeout 为 0 时不启用空闲归档。
+func (m *AgentManager) WithSessionIdle(timeout, interval time.Duration) *AgentManager {
+	m.sessionIdleTimeout = timeout
+	m.sessionSweepInterval = interval
+	return m
+}
+
+// touchSession 记录会话活跃时间，无痕会话只记录在内存中。
+func (m *AgentManager) touchSession(msg bus.InboundMessage) {
+	if m.isEphemeral(msg) {
+		m.ephemeral.Touch(msg.Channel, msg.SessionID)
+		return
+	}
+	// 心跳不是用户发来的消息，不计入活跃时间，否则会压制后续心跳
+	if m.storage == nil || msg.SessionID == "" || isHeartbeat(msg) {
+		return
+	}
+	if err := m.storage.Session().Touch(msg.Channel, msg.SessionID, msg.Sender.ID); err != nil {
+		m.logger.With("name", "【智能体】").Warn("更新会话活跃时间失败", "error", err, "session_id", msg.SessionID)
+	}
+}
+
+// redirectMerged 发往已合并会话的消息转到合并后的会话继续处理。
+func (m *AgentManager) redirectMerged(msg bus.InboundMessage) bus.InboundMessage {
+	if m.storage == nil || msg.SessionID == "" || m.isEphemeral(msg) {
+		return msg
+	}
+	target, err := m.storage.Session().ResolveMerged(msg.Channel, msg.SessionID)
+	if err != nil {
+		m.logger.With("name", "【智能体】").Warn("解析会话合并记录失败", "error", err, "session_id", msg.SessionID)
+		return msg
+	}
+	if target != msg.SessionID {
+		m.logger.With("name", "【智能体】").Debug("会话已合并，转到目标会话", "session_id", msg.SessionID, "target", target)
+		msg.SessionID = target
+	}
+	return msg
+}
+
+// RunSessionSweeper 定期检查空闲会话，摘要后归档。
+func (m *AgentManager) RunSessionSweeper(ctx context.Context) {
+	if m.sessionIdleTimeout <= 0 || m.storage == nil || m.memory == nil {
+		return
+	}
+
+	interval := m.sessionSweepInterval
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	m.logger.With("name", "【智能体】").Info("空闲会话检查已启动",
+		"idle_timeout", m.sessionIdleTimeout,
+		"interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sweepIdleSessions(ctx)
+		}
+	}
+}
+
+// sweepIdleSessions 摘要并归档所有空闲会话。
+func (m *AgentManager) sweepIdleSessions(ctx context.Context) {
+	sessions, err := m.storage.Session().ListIdle(time.Now().Add(-m.sessionIdleTimeout))
+	if err != nil {
+		m.logger.With("name", "【智能体】").Warn("获取空闲会话失败", "error", err)
+		return
+	}
+
+	for _, sess := range sessions {
+		if err := m.closeSession(ctx, sess); err != nil {
+			m.logger.With("name", "【智能体】").Warn("关闭空闲会话失败", "error", err, "session_id", sess.ID)
+		}
+	}
+}
+
+// closeSession 将会话历史摘要写入会话摘要，并归档历史消息。
+// 下次该会话收到消息时，只会带上摘要和置顶记忆，而不是重放全部历史。
+func (m *AgentManager) closeSession(ctx context.Context, sess *storage.Session) error {
+	sessionKey := consts.GetSessionKey(sess.Channel, sess.ID)
+
+	history, err := m.memory.Load(ctx, sessionKey)
+	if err != nil {
+		return err
+	}
+
+	// 没有新的对话，只需释放缓存；更新最后活跃时间，避免之后每次检查都再取到该会话
+	if len(history) == 0 {
+		m.releaseAgent(sess.ID)
+		return m.storage.Session().Save(sess)
+	}
+
+	// 合并此前的摘要，避免多次归档后丢失早期上下文
+	if sess.Summary != "" {
+		history = append([]providers.ChatMessage{{
+			Role:    consts.RoleSystem.ToString(),
+			Content: "此前的会话摘要: " + sess.Summary,
+		}}, history...)
+	}
+
+	summarizer, err := m.newSummarizer()
+	if err != nil {
+		return err
+	}
+
+	summary, err := summarizer.Summarize(ctx, history)
+	if err != nil {
+		return fmt.Errorf("生成会话摘要失败: %w", err)
+	}
+
+	sess.Summary = summary
+	if err := m.storage.Session().Save(sess); err != nil {
+		return err
+	}
+
+	result, err := m.storage.ResetSession(sess.Channel, sess.ID, storage.ResetOptions{
+		KeepPinned:  true,
+		KeepSummary: true,
+	})
+	if err != nil {
+		return err
+	}
+	m.releaseAgent(sess.ID)
+
+	m.logger.With("name", "【智能体】").Info("空闲会话已摘要归档",
+		"session_id", sess.ID,
+		"archive_id", result.ArchiveID,
+		"messages", result.Messages)
+	m.publishEvent(bus.Event{
+		Type:      bus.EventSessionClosed,
+		Channel:   sess.Channel,
+		SessionID: sess.ID,
+		Data:      map[string]any{"archive_id": result.ArchiveID, "messages": result.Messages, "summary": summary},
+	})
+	return nil
+}
+
+// newSummarizer 使用默认模型创建摘要器。
+func (m *AgentManager) newSummarizer() (memory.Summarizer, error) {
+	provider, modelName, err := m.defaultProvider()
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewSummarizer(provider, modelName, m.logger), nil
+}
+
+// defaultProvider 获取默认模型对应的提供商。
+func (m *AgentManager) defaultProvider() (providers.Provider, string, error) {
+	if m.providerFactory == nil || m.storage == nil {
+		return nil, "", fmt.Errorf("未配置提供商工厂或存储")
+	}
+
+	defaultModel, err := m.storage.Param().Get(consts.DEFAULT_MODEL_KEY)
+	if err != nil || defaultModel == nil || defaultModel.Value == "" {
+		return nil, "", fmt.Errorf("默认模型未配置")
+	}
+
+	parts := utils.SplitProviderModel(defaultModel.Value)
+	if len(parts) != 2 {
+		return nil, "", fmt.Errorf("默认模型格式错误: %s", defaultModel.Value)
+	}
+
+	provider, err := m.providerFactory.Get(parts[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("获取Provider失败: %w", err)
+	}
+	return provider, parts[1], nil
+}
@@ -3,16 +3,35 @@ package agent
 import (
 	"context"
 	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/authz"
 	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
 	channelschannels "icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/clock"
+	"icooclaw/pkg/cluster"
+	"icooclaw/pkg/command"
 	"icooclaw/pkg/consts"
+	"icooclaw/pkg/dispatch"
+	"icooclaw/pkg/ephemeral"
+	"icooclaw/pkg/faq"
+	"icooclaw/pkg/form"
+	"icooclaw/pkg/language"
 	"icooclaw/pkg/memory"
+	"icooclaw/pkg/notice"
+	"icooclaw/pkg/persona"
+	"icooclaw/pkg/postprocess"
 	"icooclaw/pkg/providers"
+	"icooclaw/pkg/routing"
 	"icooclaw/pkg/skill"
+	"icooclaw/pkg/skill/trigger"
 	"icooclaw/pkg/storage"
 	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/ask"
+	"icooclaw/pkg/workspace"
 	"log/slog"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Manager interface {
@@ -44,8 +63,113 @@ type AgentManager struct {
 	providerFactory *providers.Factory
 	// 存储加载器
 	storage *storage.Storage
+	// 人设管理器
+	personas *persona.Manager
+	// 工作目录管理器
+	workspaces *workspace.Manager
+	// 用户时区管理器
+	timezones *clock.Manager
+	// 回复语言管理器
+	languages *language.Manager
+	// 系统提示模板目录
+	notices *notice.Catalog
+	// 无痕会话注册表
+	ephemeral *ephemeral.Registry
+	// 无痕会话空闲清除时间
+	ephemeralIdle time.Duration
+	// 无痕会话中禁用的工具
+	ephemeralDeny []string
+	// 授权器，处理消息前检查
+	authorizer *authz.Authorizer
+	// 管理员发送者 ID，可使用管理命令
+	admins []string
+	// 集群实例，全局后台任务只在主实例上运行
+	cluster *cluster.Node
+	// 斜杠命令注册表
+	commands *command.Registry
+	// 入站消息去重器
+	dedup *channels.Deduper
 	// 智能体示例map
 	agentsMap map[string]*react.ReActAgent
+	agentsMu  sync.Mutex
+	// 正在进行的对话，按会话键索引，停止请求据此中断
+	turns   map[string]*turn
+	turnsMu sync.Mutex
+	// 会话空闲超时
+	sessionIdleTimeout time.Duration
+	// 空闲会话检查间隔
+	sessionSweepInterval time.Duration
+	// 是否启用离线队列
+	offlineEnabled bool
+	// 提供商是否不可用
+	offline atomic.Bool
+	// 离线恢复检查间隔
+	offlineRetry time.Duration
+	// 恢复后处理积压消息的间隔
+	offlineReplay time.Duration
+	// 回复后处理管道
+	postprocess *postprocess.Pipeline
+	// 入站消息路由规则
+	router *routing.Router
+	// 常见问题匹配器
+	faq *faq.Matcher
+	// 表单定义及会话的填写进度
+	forms     *form.Registry
+	formStore *form.Store
+	// 是否在调用模型前预估用量，超过阈值时请用户确认
+	costPreview bool
+	// 默认的用量确认阈值
+	costThreshold CostThreshold
+	// 提供工具时额外估计的模型调用次数
+	costToolRounds int
+	// 等待确认的消息保留时间
+	costExpire time.Duration
+	// 等待确认的消息，按会话键索引
+	costHeld map[string]heldTurn
+	costMu   sync.Mutex
+	// 等待用户回答的问题及本轮进度
+	askStore *ask.Store
+	// 等待回答的时间
+	askTimeout time.Duration
+	// 并发处理入站消息的工作池，nil 时逐条处理
+	pool *dispatch.Pool
+	// 按触发规则自动激活技能
+	skillTriggers *trigger.Engine
+	// 工具执行进度心跳间隔
+	statusInterval time.Duration
+	// 是否注入工具使用提示
+	toolNotes bool
+	// 单轮对话时间预算
+	turnBudget time.Duration
+	// 相同工具调用重复多少次后注入纠正提示
+	toolRepeatLimit int
+	// 冗长工具结果的压缩规则
+	toolCondense *react.ToolCondense
+	// 系统提示词自动生成的片段
+	promptSections react.PromptSections
+	// 每个会话保留的对话轨迹条数及工具结果截断长度
+	traceKeep        int
+	traceResultChars int
+	// 记忆评分与合并参数
+	memoryDecay memory.ConsolidateConfig
+	// 注入提示词的相关记忆条数
+	memoryRecallLimit int
+	// 语义记忆检索参数，Model 为空表示只按关键词检索
+	semanticRecall     memory.Semantic
+	semanticRecallTopK int
+	// 低分记忆合并间隔
+	memoryConsolidateInterval time.Duration
+	// 是否从对话中抽取实体
+	entityExtract bool
+	// 注入提示词的相关实体个数
+	entityRecallLimit int
+	// 记忆回顾参数
+	memoryDigest memory.DigestConfig
+	// 记忆回顾间隔
+	memoryDigestInterval time.Duration
+	// 记忆回顾发送的渠道和会话
+	memoryDigestChannel string
+	memoryDigestSession string
 }
 
 // NewAgentManager 创建智能体管理器
@@ -54,12 +178,17 @@ func NewAgentManager(
 	logger *slog.Logger,
 ) *AgentManager {
 	manager := AgentManager{
-		ctx:     ctx,
-		running: atomic.Bool{},
-		logger:  logger,
+		ctx:            ctx,
+		running:        atomic.Bool{},
+		logger:         logger,
+		promptSections: react.DefaultPromptSections(),
 	}
 
 	manager.agentsMap = make(map[string]*react.ReActAgent)
+	// 内置模板不会出错
+	manager.notices, _ = notice.New("", nil, logger)
+	manager.commands = command.NewRegistry(logger)
+	manager.registerBuiltinCommands()
 	return &manager
 }
 
@@ -73,8 +202,11 @@ func (m *AgentManager) WithHooks(hooks react.ReactHooks) *AgentManager {
 	return m
 }
 
+// WithBus 设置消息总线，停止请求和斜杠命令在总线上优先处理，停止请求立即中断当前回复。
 func (m *AgentManager) WithBus(b *bus.MessageBus) *AgentManager {
 	m.bus = b
+	b.SetPrioritizer(m.prioritize)
+	b.SetInterrupter(m.interrupt)
 	return m
 }
 
@@ -93,6 +225,72 @@ func (m *AgentManager) WithSkills(skills skill.Loader) *AgentManager {
 	return m
 }
 
+func (m *AgentManager) WithPersonas(p *persona.Manager) *AgentManager {
+	m.personas = p
+	return m
+}
+
+// WithWorkspaces 设置工作目录管理器，会话可通过 /workspace 切换工作目录。
+func (m *AgentManager) WithWorkspaces(w *workspace.Manager) *AgentManager {
+	m.workspaces = w
+	return m
+}
+
+// WithTimezones 设置用户时区管理器，会话可通过 /timezone 设置时区。
+func (m *AgentManager) WithTimezones(t *clock.Manager) *AgentManager {
+	m.timezones = t
+	return m
+}
+
+// WithLanguages 设置回复语言管理器，会话可通过 /language 设置回复语言。
+func (m *AgentManager) WithLanguages(l *language.Manager) *AgentManager {
+	m.languages = l
+	return m
+}
+
+// WithToolNotes 启用工具使用提示，根据工具近期失败情况自动生成并注入系统提示词。
+func (m *AgentManager) WithToolNotes(enabled bool) *AgentManager {
+	m.toolNotes = enabled
+	return m
+}
+
+// WithTurnBudget 设置单轮对话的时间预算，覆盖所有迭代和工具调用，0 表示不限制。
+func (m *AgentManager) WithTurnBudget(d time.Duration) *AgentManager {
+	m.turnBudget = d
+	return m
+}
+
+// WithToolRepeatLimit 启用本轮工具调用去重，相同调用重复 limit 次后提醒模型，0 表示不去重。
+func (m *AgentManager) WithToolRepeatLimit(limit int) *AgentManager {
+	m.toolRepeatLimit = limit
+	return m
+}
+
+// WithToolCondense 设置冗长工具结果的压缩规则，nil 表示不压缩。
+func (m *AgentManager) WithToolCondense(c *react.ToolCondense) *AgentManager {
+	m.toolCondense = c
+	return m
+}
+
+// WithPromptSections 设置系统提示词中自动生成的片段。
+func (m *AgentManager) WithPromptSections(s react.PromptSections) *AgentManager {
+	m.promptSections = s
+	return m
+}
+
+// WithTrace 记录每轮对话的轨迹，每个会话保留最近 keep 条，0 表示不记录。
+func (m *AgentManager) WithTrace(keep, maxResultChars int) *AgentManager {
+	m.traceKeep = keep
+	m.traceResultChars = maxResultChars
+	return m
+}
+
+// WithDeduper 设置入站消息去重器，丢弃平台重复投递的消息。
+func (m *AgentManager) WithDeduper(d *channels.Deduper) *AgentManager {
+	m.dedup = d
+	return m
+}
+
 func (m *AgentManager) WithStorage(s *storage.Storage) *AgentManager {
 	m.storage = s
 	return m
@@ -116,45 +314,59 @@ func (m *AgentManager) IsRunning() bool {
 
 // start 启动智能体循环
 func (m *AgentManager) start() error {
-	// 监听消息总线
+	if m.pool != nil {
+		go m.pool.Run(m.ctx)
+	}
+
+	// 监听消息总线，紧急消息优先于已排队的普通消息和定时消息
 	for m.running.Load() {
-		select {
-		case <-m.ctx.Done():
+		msg, ok := m.bus.ConsumeInbound(m.ctx)
+		if !ok {
 			m.logger.With("name", "【智能体】").Info("代理循环已停止", "reason", m.ctx.Err())
 			return m.ctx.Err()
-		case msg := <-m.bus.Inbound():
-			switch msg.Channel {
-			case channelschannels.WEBSOCKET:
-				// 处理消息
-				err := m.RunAgentStream(msg, m.callback(msg))
-				if err != nil {
-					m.logger.With("name", "【智能体】").Error("处理消息失败", "reason", err)
-					continue
-				}
-			case channelschannels.FEISHU:
-				// 处理消息
-				finallyContent, err := m.RunAgent(msg)
-				if err != nil {
-					m.logger.With("name", "【智能体】").Error("处理消息失败", "reason", err)
-					continue
-				}
-
-				// 发送消息到bus
-				out := bus.OutboundMessage{
-					Channel:   msg.Channel,
-					SessionID: msg.SessionID,
-					Text:      finallyContent,
-				}
-				m.bus.PublishOutbound(m.ctx, out)
-			}
 		}
+		// 丢弃网络重试导致的重复投递
+		if m.dedup != nil && m.dedup.Duplicate(msg) {
+			continue
+		}
+
+		// 启用工作池时交给工作池并发处理，否则逐条处理
+		if m.pool != nil {
+			m.submit(msg)
+			continue
+		}
+		m.process(msg)
 	}
 
 	return nil
 }
 
+// process 处理一条入站消息，WebSocket 消息流式回复。
+func (m *AgentManager) process(msg bus.InboundMessage) {
+	if isHeartbeat(msg) {
+		defer m.heartbeatDone(msg)
+	}
+	switch {
+	case msg.Channel == channelschannels.WEBSOCKET && !isHeartbeat(msg):
+		// 处理消息
+		if err := m.RunAgentStream(msg, m.callback(msg)); err != nil {
+			m.logger.With("name", "【智能体】").Error("处理消息失败", "reason", err)
+			m.publishError(msg)
+		}
+	default:
+		// 处理消息，回复由 RunAgent 发送到消息总线；心跳需要先检查回复再决定是否发送，不走流式
+		if _, err := m.RunAgent(msg); err != nil {
+			m.logger.With("name", "【智能体】").Error("处理消息失败", "reason", err)
+			m.publishError(msg)
+		}
+	}
+}
+
 func (m *AgentManager) callback(inbound bus.InboundMessage) react.StreamCallback {
 	return func(chunk react.StreamChunk) error {
+		if chunk.Start {
+			return nil
+		}
 		// 发送消息到bus
 		out := bus.OutboundMessage{
 			Channel:   inbound.Channel,
@@ -175,33 +387,166 @@ func (m *AgentManager) Stop() error {
 	return nil
 }
 
+// getAgent 获取会话对应的智能体实例，不存在时创建。
+func (m *AgentManager) getAgent(sessionID string) (*react.ReActAgent, error) {
+	m.agentsMu.Lock()
+	defer m.agentsMu.Unlock()
+
+	if agent, ok := m.agentsMap[sessionID]; ok {
+		return agent, nil
+	}
+
+	agent, err := react.NewReActAgent(
+		m.ctx,
+		m.hooks,
+		react.WithBus(m.bus),
+		react.WithLogger(m.logger),
+		react.WithMaxToolIterations(consts.DEFAULT_TOOL_ITERATIONS),
+		react.WithMemory(m.memory),
+		react.WithSkills(m.skills),
+		react.WithTools(m.tools),
+		react.WithProviderFactory(m.providerFactory),
+		react.WithStorage(m.storage),
+		react.WithPersonas(m.personas),
+		react.WithWorkspaces(m.workspaces),
+		react.WithTimezones(m.timezones),
+		react.WithLanguages(m.languages),
+		react.WithEphemeral(m.ephemeral, m.ephemeralDeny),
+		react.WithStatus(m.publishStatus, m.statusInterval),
+		react.WithToolNotes(m.toolNotes),
+		react.WithTurnBudget(m.turnBudget),
+		react.WithToolRepeatLimit(m.toolRepeatLimit),
+		react.WithToolCondense(m.toolCondense),
+		react.WithTrace(m.traceKeep, m.traceResultChars),
+		react.WithPromptSections(m.promptSections),
+		react.WithMemoryRecall(m.memoryDecay.Score, m.memoryRecallLimit),
+		react.WithSemanticRecall(m.semanticRecall, m.semanticRecallTopK),
+		react.WithEntityRecall(m.entityRecallLimit),
+		react.WithCostPreview(m.costGate(), m.costToolRounds),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.agentsMap[sessionID] = agent
+	return agent, nil
+}
+
+// releaseAgent 释放会话对应的智能体实例。
+func (m *AgentManager) releaseAgent(sessionID string) {
+	m.agentsMu.Lock()
+	defer m.agentsMu.Unlock()
+	delete(m.agentsMap, sessionID)
+}
+
 func (m *AgentManager) RunAgent(msg bus.InboundMessage) (string, error) {
+	reply, _, err := m.RunAgentStats(msg)
+	return reply, err
+}
+
+// RunAgentStats 与 RunAgent 相同，同时返回本轮的统计。命令、提示等没有调用模型的回复统计为 nil。
+func (m *AgentManager) RunAgentStats(msg bus.InboundMessage) (string, *react.TurnStats, error) {
+	// 已合并的会话转到目标会话，并记录会话活跃时间
+	msg = m.redirectMerged(msg)
+	m.touchSession(msg)
+
+	// 授权检查，被拒绝时不处理消息
+	if notice, ok := m.authorizeMessage(msg); !ok {
+		m.publishNotice(msg, notice)
+		return notice, nil, nil
+	}
+
+	// 处理斜杠命令
+	if reply, ok := m.commands.Execute(m.ctx, msg); ok {
+		m.bus.PublishOutbound(m.ctx, bus.OutboundMessage{
+			Channel:   msg.Channel,
+			SessionID: msg.SessionID,
+			Text:      reply,
+		})
+		return reply, nil, nil
+	}
+
+	// 智能体等待回答时作为回答，从暂停处继续
+	msg = m.answerQuestion(msg)
+
+	// 正在填写表单时作为当前字段的回答
+	msg, reply, handled := m.fillForm(msg)
+	if handled {
+		m.publishNotice(msg, reply)
+		return reply, nil, nil
+	}
+
+	// 按人设前缀和路由规则分流
+	msg, reply, handled = m.route(msg)
+	if handled {
+		m.publishNotice(msg, reply)
+		return reply, nil, nil
+	}
+
+	// 常见问题直接回复
+	if reply, ok := m.answerFAQ(msg); ok {
+		m.publishNotice(msg, reply)
+		return reply, nil, nil
+	}
+
+	// 按触发规则激活技能
+	msg = m.activateSkills(msg)
+
+	// 提供商离线时排队
+	if notice, ok := m.queueIfOffline(msg); ok {
+		m.publishNotice(msg, notice)
+		return notice, nil, nil
+	}
+
 	// 生成智能体实例
-	var (
-		agent *react.ReActAgent
-		err   error
-	)
-	agent, ok := m.agentsMap[msg.SessionID]
-	if !ok {
-		agent, err = react.NewReActAgent(
-			m.ctx,
-			m.hooks,
-			react.WithBus(m.bus),
-			react.WithMaxToolIterations(consts.DEFAULT_TOOL_ITERATIONS),
-			react.WithMemory(m.memory),
-			react.WithSkills(m.skills),
-			react.WithTools(m.tools),
-			react.WithProviderFactory(m.providerFactory),
-			react.WithStorage(m.storage),
-		)
-	}
-
-	m.agentsMap[msg.SessionID] = agent
-
-	finallyContent, finallyIteration, err := agent.Chat(m.ctx, msg)
+	agent, err := m.getAgent(msg.SessionID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ctx, done := m.beginTurn(msg)
+	defer done()
+	ctx, changes := trackChanges(ctx)
+	stats := react.NewTurnStats()
+	ctx = react.WithStats(ctx, stats)
+	finallyContent, finallyIteration, err := agent.Chat(ctx, msg)
+	summary := m.reportChanges(msg, changes)
+	if notice, ok := m.holdForCost(msg, err); ok {
+		m.publishCostNotice(msg, notice)
+		return notice, nil, nil
+	}
+	if p, ok := m.holdForQuestion(msg, err); ok {
+		m.publishQuestion(msg, p)
+		return p.Prompt(), nil, nil
+	}
+	if interrupted(ctx, err) {
+		m.logger.With("name", "【智能体】").Info("回复已被用户停止", "channel", msg.Channel, "session_id", msg.SessionID)
+		return "", nil, nil
+	}
 	if err != nil {
 		m.logger.With("name", "【智能体】").Error("处理消息失败", "reason", err)
-		return "", err
+		if notice, ok := m.queueOnError(msg, err); ok {
+			m.publishNotice(msg, notice)
+			return notice, nil, nil
+		}
+		if notice, ok := m.blockedNotice(msg, err); ok {
+			m.publishNotice(msg, notice)
+			return notice, nil, nil
+		}
+		return "", nil, err
+	}
+	finallyContent = m.postProcess(msg, finallyContent)
+	m.extractEntities(msg, finallyContent)
+	finallyContent, files := m.resolveArtifacts(msg, finallyContent)
+
+	// 心跳检查没有需要告知的事项时不打扰用户
+	if isHeartbeat(msg) {
+		reply, ok := heartbeatReply(finallyContent)
+		if !ok && len(files) == 0 {
+			m.logger.With("name", "【心跳】").Debug("心跳无事可报", "channel", msg.Channel, "session_id", msg.SessionID)
+			return finallyContent, stats.Snapshot(), nil
+		}
+		finallyContent = reply
 	}
 
 	// 将消息发送到消息总线
@@ -213,40 +558,125 @@ func (m *AgentManager) RunAgent(msg bus.InboundMessage) (string, error) {
 			"iteration": finallyIteration, // 迭代次数
 		},
 	}
+	if len(files) > 0 {
+		out.Metadata[consts.META_ATTACHMENTS] = files
+	}
+	if summary != nil {
+		out.Metadata[consts.META_CHANGES] = summary
+	}
 	m.bus.PublishOutbound(m.ctx, out)
 
 	// 调用 agent
-	return finallyContent, nil
+	return finallyContent, stats.Snapshot(), nil
 }
 
 func (m *AgentManager) RunAgentStream(msg bus.InboundMessage, callback react.StreamCallback) error {
+	// 已合并的会话转到目标会话，并记录会话活跃时间
+	msg = m.redirectMerged(msg)
+	m.touchSession(msg)
+
+	// 授权检查，被拒绝时不处理消息
+	if notice, ok := m.authorizeMessage(msg); !ok {
+		if callback != nil {
+			callback(react.StreamChunk{Content: notice, Done: true})
+		}
+		return nil
+	}
+
+	// 处理斜杠命令
+	if reply, ok := m.commands.Execute(m.ctx, msg); ok {
+		if callback != nil {
+			callback(react.StreamChunk{Content: reply, Done: true})
+		}
+		return nil
+	}
+
+	// 智能体等待回答时作为回答，从暂停处继续
+	msg = m.answerQuestion(msg)
+
+	// 正在填写表单时作为当前字段的回答
+	msg, reply, handled := m.fillForm(msg)
+	if handled {
+		if callback != nil {
+			callback(react.StreamChunk{Content: reply, Done: true})
+		}
+		return nil
+	}
+
+	// 按人设前缀和路由规则分流
+	msg, reply, handled = m.route(msg)
+	if handled {
+		if callback != nil {
+			callback(react.StreamChunk{Content: reply, Done: true})
+		}
+		return nil
+	}
+
+	// 常见问题直接回复
+	if reply, ok := m.answerFAQ(msg); ok {
+		if callback != nil {
+			callback(react.StreamChunk{Content: reply, Done: true})
+		}
+		return nil
+	}
+
+	// 按触发规则激活技能
+	msg = m.activateSkills(msg)
+
+	// 提供商离线时排队
+	if notice, ok := m.queueIfOffline(msg); ok {
+		if callback != nil {
+			callback(react.StreamChunk{Content: notice, Done: true})
+		}
+		return nil
+	}
+
 	// 生成智能体实例
-	var (
-		agent *react.ReActAgent
-		err   error
-	)
-	agent, ok := m.agentsMap[msg.SessionID]
-	if !ok {
-		agent, err = react.NewReActAgent(
-			m.ctx,
-			m.hooks,
-			react.WithBus(m.bus),
-			react.WithMaxToolIterations(consts.DEFAULT_TOOL_ITERATIONS),
-			react.WithMemory(m.memory),
-			react.WithSkills(m.skills),
-			react.WithTools(m.tools),
-			react.WithProviderFactory(m.providerFactory),
-			react.WithStorage(m.storage),
-		)
-	}
-
-	m.agentsMap[msg.SessionID] = agent
-
-	finallyContent, finallyIteration, err := agent.ChatStream(m.ctx, msg, callback)
+	agent, err := m.getAgent(msg.SessionID)
+	if err != nil {
+		return err
+	}
+
+	ctx, done := m.beginTurn(msg)
+	defer done()
+	ctx, changes := trackChanges(ctx)
+	finallyContent, finallyIteration, err := agent.ChatStream(ctx, msg, m.postProcessStream(msg, m.resolveArtifactsStream(msg, callback)))
+	summary := m.reportChanges(msg, changes)
+	if notice, ok := m.holdForCost(msg, err); ok {
+		if callback != nil {
+			callback(react.StreamChunk{Content: notice, Done: true})
+		}
+		return nil
+	}
+	if p, ok := m.holdForQuestion(msg, err); ok {
+		if callback != nil {
+			callback(react.StreamChunk{Content: p.Prompt(), Done: true})
+		}
+		return nil
+	}
+	if interrupted(ctx, err) {
+		m.logger.With("name", "【智能体】").Info("回复已被用户停止", "channel", msg.Channel, "session_id", msg.SessionID)
+		return nil
+	}
 	if err != nil {
 		m.logger.With("name", "【智能体】").Error("处理消息失败", "reason", err)
+		if notice, ok := m.queueOnError(msg, err); ok {
+			if callback != nil {
+				callback(react.StreamChunk{Content: notice, Done: true})
+			}
+			return nil
+		}
+		if notice, ok := m.blockedNotice(msg, err); ok {
+			if callback != nil {
+				callback(react.StreamChunk{Content: notice, Done: true})
+			}
+			return nil
+		}
 		return err
 	}
+	finallyContent = m.postProcess(msg, finallyContent)
+	m.extractEntities(msg, finallyContent)
+	finallyContent, files := m.resolveArtifacts(msg, finallyContent)
 
 	// 将消息发送到消息总线
 	out := bus.OutboundMessage{
@@ -257,8 +687,30 @@ func (m *AgentManager) RunAgentStream(msg bus.InboundMessage, callback react.Str
 			"iteration": finallyIteration, // 迭代次数
 		},
 	}
+	if len(files) > 0 {
+		out.Metadata[consts.META_ATTACHMENTS] = files
+	}
+	if summary != nil {
+		out.Metadata[consts.META_CHANGES] = summary
+	}
 	m.bus.PublishOutbound(m.ctx, out)
 
 	// 调用 agent
 	return nil
 }
+
+// publishEvent 在消息总线上发布事件，未设置消息总线时忽略。
+func (m *AgentManager) publishEvent(ev bus.Event) {
+	if m.bus != nil {
+		m.bus.PublishEvent(ev)
+	}
+}
+
+// publishNotice 将提示消息发送到消息总线。
+func (m *AgentManager) publishNotice(msg bus.InboundMessage, text string) {
+	m.bus.PublishOutbound(m.ctx, bus.OutboundMessage{
+		Channel:   msg.Channel,
+		SessionID: msg.SessionID,
+		Text:      text,
+	})
+}
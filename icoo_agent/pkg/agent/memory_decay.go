@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"icooclaw/pkg/memory"
+)
+
+// WithMemoryDecay 启用记忆评分：检索时按 评分×相关度 向提示词注入 recallLimit 条相关记忆，
+// 并每隔 interval 将评分衰减到阈值以下的记忆合并为摘要。interval 为 0 时不合并。
+func (m *AgentManager) WithMemoryDecay(cfg memory.ConsolidateConfig, recallLimit int, interval time.Duration) *AgentManager {
+	m.memoryDecay = cfg
+	m.memoryRecallLimit = recallLimit
+	m.memoryConsolidateInterval = interval
+	return m
+}
+
+// WithSemanticRecall 启用语义记忆检索：按用户消息与记忆向量的余弦相似度注入 topK 条相关记忆。
+// model 为 提供商/模型，嵌入接口不可用时退回关键词检索。
+func (m *AgentManager) WithSemanticRecall(model string, topK int, minSimilarity float64, embedMissing int) *AgentManager {
+	m.semanticRecall = memory.Semantic{Model: model, MinSimilarity: minSimilarity, EmbedMissing: embedMissing}
+	m.semanticRecallTopK = topK
+	return m
+}
+
+// RunMemoryConsolidator 定期合并低分记忆。
+func (m *AgentManager) RunMemoryConsolidator(ctx context.Context) {
+	if m.memoryConsolidateInterval <= 0 || m.storage == nil {
+		return
+	}
+
+	m.logger.With("name", "【智能体】").Info("记忆合并已启动",
+		"interval", m.memoryConsolidateInterval,
+		"threshold", m.memoryDecay.Threshold)
+
+	ticker := time.NewTicker(m.memoryConsolidateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if m.isLeader() {
+				m.consolidateMemories(ctx)
+			}
+		}
+	}
+}
+
+// consolidateMemories 将低分记忆合并为摘要，提供商不可用时跳过本轮。
+func (m *AgentManager) consolidateMemories(ctx context.Context) {
+	summarizer, err := m.newSummarizer()
+	if err != nil {
+		m.logger.With("name", "【智能体】").Warn("创建摘要器失败，跳过记忆合并", "error", err)
+		return
+	}
+
+	result, err := memory.Consolidate(ctx, m.storage, summarizer, m.memoryDecay, time.Now())
+	if err != nil {
+		m.logger.With("name", "【智能体】").Warn("合并低分记忆失败", "error", err)
+	}
+	if result != nil && result.Memories > 0 {
+		m.logger.With("name", "【智能体】").Info("低分记忆已合并为摘要",
+			"sessions", result.Sessions,
+			"memories", result.Memories)
+	}
+}
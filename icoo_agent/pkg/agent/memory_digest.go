@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/memory"
+	"icooclaw/pkg/storage"
+)
+
+const (
+	// digestCheckInterval 检查是否到达回顾时间的间隔，重启后按上次回顾时间继续计时
+	digestCheckInterval = time.Hour
+	// digestKVScope 记录上次回顾时间的键值作用域
+	digestKVScope = "system"
+	// digestKVKey 记录上次回顾时间的键
+	digestKVKey = "memory.digest.last_at"
+)
+
+// WithMemoryDigest 启用记忆回顾：每隔 interval 汇总新增、即将合并和可能矛盾的记忆，
+// 发送到 channel 的 sessionID 会话，供人工置顶、删除或修正。
+func (m *AgentManager) WithMemoryDigest(cfg memory.DigestConfig, interval time.Duration, channel, sessionID string) *AgentManager {
+	m.memoryDigest = cfg
+	m.memoryDigestInterval = interval
+	m.memoryDigestChannel = channel
+	m.memoryDigestSession = sessionID
+	return m
+}
+
+// RunMemoryDigest 定期发送记忆回顾摘要。
+func (m *AgentManager) RunMemoryDigest(ctx context.Context) {
+	if m.memoryDigestInterval <= 0 || m.memoryDigestChannel == "" || m.storage == nil {
+		return
+	}
+
+	m.logger.With("name", "【智能体】").Info("记忆回顾已启动",
+		"interval", m.memoryDigestInterval,
+		"channel", m.memoryDigestChannel)
+
+	ticker := time.NewTicker(min(digestCheckInterval, m.memoryDigestInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if m.isLeader() {
+				m.sendMemoryDigest(ctx, now)
+			}
+		}
+	}
+}
+
+// sendMemoryDigest 到达回顾时间时生成并发送摘要，首次运行只记录起始时间。
+func (m *AgentManager) sendMemoryDigest(ctx context.Context, now time.Time) {
+	last, ok := m.lastDigestAt()
+	if !ok {
+		m.saveDigestAt(now)
+		return
+	}
+	if now.Sub(last) < m.memoryDigestInterval {
+		return
+	}
+
+	memories, err := m.storage.Memory().List(storage.MemoryFilter{})
+	if err != nil {
+		m.logger.With("name", "【智能体】").Warn("加载记忆失败，跳过记忆回顾", "error", err)
+		return
+	}
+
+	digest := memory.BuildDigest(memories, last, now, m.memoryDigest)
+	if !digest.IsEmpty() {
+		m.bus.PublishOutbound(ctx, bus.OutboundMessage{
+			Channel:   m.memoryDigestChannel,
+			SessionID: m.memoryDigestSession,
+			Text:      digest.Text(),
+			Metadata: map[string]any{
+				consts.META_ACTIONS: digest.Actions(),
+			},
+		})
+		m.logger.With("name", "【智能体】").Info("记忆回顾已发送",
+			"new", len(digest.New),
+			"expiring", len(digest.Expiring),
+			"conflicts", len(digest.Conflicts))
+	}
+	m.saveDigestAt(now)
+}
+
+// lastDigestAt 读取上次回顾时间。
+func (m *AgentManager) lastDigestAt() (time.Time, bool) {
+	kv, err := m.storage.KV().Get(digestKVScope, digestKVKey)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var t time.Time
+	if err := json.Unmarshal([]byte(kv.Value), &t); err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// saveDigestAt 记录回顾时间。
+func (m *AgentManager) saveDigestAt(t time.Time) {
+	value, _ := json.Marshal(t)
+	if err := m.storage.KV().Set(digestKVScope, digestKVKey, string(value)); err != nil {
+		m.logger.With("name", "【智能体】").Warn("记录记忆回顾时间失败", "error", err)
+	}
+}
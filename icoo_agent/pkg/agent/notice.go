@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/notice"
+	"icooclaw/pkg/providers"
+)
+
+// WithNotices 设置系统提示模板目录，离线、排队、确认、错误等不经过模型的提示按用户语言和渠道渲染。
+func (m *AgentManager) WithNotices(c *notice.Catalog) *AgentManager {
+	m.notices = c
+	return m
+}
+
+// notice 按会话的回复语言和消息渠道渲染系统提示。
+func (m *AgentManager) notice(msg bus.InboundMessage, key string, vars map[string]any) string {
+	var lang string
+	if m.languages != nil {
+		lang, _ = m.languages.Current(msg.Channel, msg.SessionID)
+	}
+	return m.notices.Render(key, lang, notice.Data{
+		Channel:  msg.Channel,
+		UserID:   msg.Sender.ID,
+		UserName: msg.Sender.Name,
+		Vars:     vars,
+	})
+}
+
+// publishError 处理消息失败时告知用户，心跳失败不打扰用户。
+func (m *AgentManager) publishError(msg bus.InboundMessage) {
+	if isHeartbeat(msg) {
+		return
+	}
+	m.publishNotice(msg, m.notice(msg, notice.Error, nil))
+}
+
+// blockedNotice 回复被提供商内容安全策略拦截（智能体已重试过一次）时返回给用户的提示，
+// 代替空消息或笼统的错误提示。心跳被拦截时不打扰用户，按失败处理。
+func (m *AgentManager) blockedNotice(msg bus.InboundMessage, err error) (string, bool) {
+	if !providers.IsContentFiltered(err) || isHeartbeat(msg) {
+		return "", false
+	}
+	return m.notice(msg, notice.Blocked, nil), true
+}
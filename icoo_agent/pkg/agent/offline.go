@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/notice"
+	"icooclaw/pkg/storage"
+)
+
+// offlineMaxAttempts 非网络错误的最大重试次数，超过后放弃该消息
+const offlineMaxAttempts = 3
+
+// WithOfflineQueue 启用离线队列。
+// retry 为提供商不可用时的恢复检查间隔，replay 为恢复后逐条处理积压消息的间隔。
+func (m *AgentManager) WithOfflineQueue(retry, replay time.Duration) *AgentManager {
+	m.offlineEnabled = true
+	m.offlineRetry = retry
+	m.offlineReplay = replay
+	return m
+}
+
+// IsOffline 提供商是否处于不可用状态。
+func (m *AgentManager) IsOffline() bool {
+	return m.offline.Load()
+}
+
+// queueIfOffline 提供商离线时直接将消息排队，返回给用户的提示。
+func (m *AgentManager) queueIfOffline(msg bus.InboundMessage) (string, bool) {
+	if !m.offline.Load() || isOfflineReplay(msg) {
+		return "", false
+	}
+	return m.enqueueOffline(msg, false)
+}
+
+// queueOnError 提供商不可达导致处理失败时将消息排队。
+// 此时用户消息已经写入历史，重放时不再重复保存。
+func (m *AgentManager) queueOnError(msg bus.InboundMessage, err error) (string, bool) {
+	if !icooclawErrors.IsProviderUnavailable(err) || isOfflineReplay(msg) {
+		return "", false
+	}
+	return m.enqueueOffline(msg, true)
+}
+
+// enqueueOffline 持久化入站消息并标记提供商离线，无痕会话的消息和心跳不排队。
+func (m *AgentManager) enqueueOffline(msg bus.InboundMessage, saved bool) (string, bool) {
+	if !m.offlineEnabled || m.storage == nil || m.isEphemeral(msg) || isHeartbeat(msg) {
+		return "", false
+	}
+
+	metadata, _ := json.Marshal(msg.Metadata)
+	item := &storage.OfflineMessage{
+		Channel:    msg.Channel,
+		SessionID:  msg.SessionID,
+		SenderID:   msg.Sender.ID,
+		SenderName: msg.Sender.Name,
+		Text:       msg.Text,
+		Metadata:   string(metadata),
+		Saved:      saved,
+		ReceivedAt: msg.Timestamp,
+	}
+	if item.ReceivedAt.IsZero() {
+		item.ReceivedAt = time.Now()
+	}
+
+	if err := m.storage.Offline().Enqueue(item); err != nil {
+		m.logger.With("name", "【智能体】").Error("离线消息排队失败", "error", err, "session_id", msg.SessionID)
+		return "", false
+	}
+
+	if !m.offline.Swap(true) {
+		m.logger.With("name", "【智能体】").Warn("提供商不可用，进入离线模式，新消息将排队处理")
+		m.publishEvent(bus.Event{Type: bus.EventProviderOffline, Channel: msg.Channel, SessionID: msg.SessionID})
+	}
+	m.logger.With("name", "【智能体】").Info("消息已加入离线队列", "session_id", msg.SessionID, "channel", msg.Channel)
+	return m.notice(msg, notice.Offline, nil), true
+}
+
+// RunOfflineQueue 定期检查离线队列，提供商恢复后按接收顺序逐条处理积压消息。
+// 重放最早的一条消息本身就是恢复检查：成功则继续处理，仍不可达则等待下一轮。
+func (m *AgentManager) RunOfflineQueue(ctx context.Context) {
+	if !m.offlineEnabled || m.storage == nil {
+		return
+	}
+
+	interval := m.offlineRetry
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	// 启动时恢复上次未处理完的积压
+	if count, err := m.storage.Offline().CountPending(); err == nil && count > 0 {
+		m.offline.Store(true)
+		m.logger.With("name", "【智能体】").Info("发现未处理的离线消息", "count", count)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !m.isLeader() {
+				m.followOfflineQueue()
+				continue
+			}
+			// 其他实例排队的消息也由主实例处理
+			if m.offline.Load() || m.hasPendingOffline() {
+				m.drainOfflineQueue(ctx)
+			}
+		}
+	}
+}
+
+// hasPendingOffline 离线队列中是否有待处理的消息。
+func (m *AgentManager) hasPendingOffline() bool {
+	count, err := m.storage.Offline().CountPending()
+	return err == nil && count > 0
+}
+
+// followOfflineQueue 非主实例不处理积压，主实例处理完后恢复在线模式，
+// 在此之前新消息继续排队，保证同一会话的消息按接收顺序处理。
+func (m *AgentManager) followOfflineQueue() {
+	if !m.offline.Load() {
+		return
+	}
+	if count, err := m.storage.Offline().CountPending(); err == nil && count == 0 {
+		m.offline.Store(false)
+		m.logger.With("name", "【智能体】").Info("离线队列已由主实例处理完毕，恢复在线模式")
+	}
+}
+
+// drainOfflineQueue 处理积压消息，直到队列为空或提供商再次不可达。
+func (m *AgentManager) drainOfflineQueue(ctx context.Context) {
+	for {
+		items, err := m.storage.Offline().ListPending(1)
+		if err != nil {
+			m.logger.With("name", "【智能体】").Warn("获取离线消息失败", "error", err)
+			return
+		}
+		if len(items) == 0 {
+			m.offline.Store(false)
+			m.logger.With("name", "【智能体】").Info("离线队列已处理完毕，恢复在线模式")
+			m.publishEvent(bus.Event{Type: bus.EventProviderOnline})
+			return
+		}
+
+		item := items[0]
+		_, err = m.RunAgent(offlineInbound(item))
+		switch {
+		case err == nil:
+			if err := m.storage.Offline().MarkDone(item.ID); err != nil {
+				m.logger.With("name", "【智能体】").Warn("更新离线消息状态失败", "error", err, "id", item.ID)
+			}
+		case icooclawErrors.IsProviderUnavailable(err):
+			m.logger.With("name", "【智能体】").Info("提供商仍不可用，稍后重试", "error", err, "pending_id", item.ID)
+			return
+		default:
+			m.logger.With("name", "【智能体】").Warn("处理离线消息失败", "error", err, "id", item.ID)
+			if err := m.storage.Offline().MarkFailed(item.ID, err, offlineMaxAttempts); err != nil {
+				m.logger.With("name", "【智能体】").Warn("更新离线消息状态失败", "error", err, "id", item.ID)
+			}
+		}
+
+		// 限速，避免恢复后瞬间压垮提供商
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.offlineReplay):
+		}
+	}
+}
+
+// offlineInbound 将排队的消息还原为入站消息。
+func offlineInbound(item *storage.OfflineMessage) bus.InboundMessage {
+	metadata := map[string]any{}
+	if item.Metadata != "" {
+		_ = json.Unmarshal([]byte(item.Metadata), &metadata)
+	}
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	metadata[consts.META_OFFLINE_REPLAY] = true
+	metadata[consts.META_HISTORY_SAVED] = item.Saved
+
+	return bus.InboundMessage{
+		Channel:   item.Channel,
+		SessionID: item.SessionID,
+		Sender:    bus.SenderInfo{ID: item.SenderID, Name: item.SenderName},
+		Text:      item.Text,
+		Timestamp: item.ReceivedAt,
+		Metadata:  metadata,
+	}
+}
+
+// isOfflineReplay 是否为离线队列重放的消息。
+func isOfflineReplay(msg bus.InboundMessage) bool {
+	replay, _ := msg.Metadata[consts.META_OFFLINE_REPLAY].(bool)
+	return replay
+}
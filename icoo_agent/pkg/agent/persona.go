@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/command"
+	"strings"
+)
+
+// cmdPersona 处理 /persona 命令。
+//
+//	/persona                 查看当前人设与可用人设
+//	/persona <name>          为当前会话切换人设
+//	/persona <name> channel  为当前渠道设置默认人设，仅限管理员
+//	/persona off [channel]   清除人设选择，清除渠道默认人设仅限管理员
+func (m *AgentManager) cmdPersona(ctx context.Context, c *command.Context) (string, error) {
+	if m.personas == nil {
+		return "人设功能未启用", nil
+	}
+
+	name := c.Arg(0)
+	if name == "" || name == "list" {
+		return m.renderPersonaList(c.Msg), nil
+	}
+
+	sessionID := c.Msg.SessionID
+	if c.Arg(1) == "channel" {
+		// 渠道默认人设影响渠道内的所有会话
+		if !m.isAdmin(c.Msg) {
+			return adminOnly, nil
+		}
+		sessionID = ""
+	}
+	if name == "off" {
+		name = ""
+	}
+
+	p, err := m.personas.Select(c.Msg.Channel, sessionID, name)
+	if err != nil {
+		return "", err
+	}
+
+	if p == nil {
+		return "已清除人设选择", nil
+	}
+	if p.Greeting != "" {
+		return p.Greeting, nil
+	}
+	return fmt.Sprintf("已切换到人设: %s", p.Name), nil
+}
+
+// renderPersonaList 渲染人设列表。
+func (m *AgentManager) renderPersonaList(msg bus.InboundMessage) string {
+	sb := strings.Builder{}
+
+	if current := m.personas.Current(msg.Channel, msg.SessionID); current != nil {
+		sb.WriteString(fmt.Sprintf("当前人设: %s\n", current.Name))
+	} else {
+		sb.WriteString("当前人设: 默认\n")
+	}
+
+	list := m.personas.List()
+	if len(list) == 0 {
+		sb.WriteString(fmt.Sprintf("暂无可用人设，请在 %s 目录下添加人设文件", m.personas.Dir()))
+		return sb.String()
+	}
+
+	sb.WriteString("可用人设:\n")
+	for _, p := range list {
+		if p.Description != "" {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", p.Name, p.Description))
+		} else {
+			sb.WriteString(fmt.Sprintf("- %s\n", p.Name))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/command"
+	"icooclaw/pkg/persona"
+	"icooclaw/pkg/storage"
+)
+
+func TestCmdPersona_ChannelRequiresAdmin(t *testing.T) {
+	workspace := t.TempDir()
+	dir := filepath.Join(workspace, persona.PERSONA_DIR)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ops.md"), []byte("---\nname: ops\n---\nops"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.New(workspace, "", filepath.Join(t.TempDir(), "persona.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	personas := persona.NewManager(workspace, store, nil)
+	if err := personas.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	m := NewAgentManager(context.Background(), slog.Default()).
+		WithPersonas(personas).
+		WithAdmins([]string{"feishu:root", "alice"})
+	run := func(sender, session string, args ...string) string {
+		msg := bus.InboundMessage{Channel: "feishu", SessionID: session, Sender: bus.SenderInfo{ID: sender}}
+		reply, err := m.cmdPersona(context.Background(), &command.Context{Msg: msg, Name: "persona", Args: args})
+		if err != nil {
+			t.Fatalf("cmdPersona(%v) error = %v", args, err)
+		}
+		return reply
+	}
+	channelDefault := func() string {
+		if p := personas.Current("feishu", "other"); p != nil {
+			return p.Name
+		}
+		return ""
+	}
+
+	// 普通用户只能为自己的会话选择人设
+	if reply := run("bob", "s1", "ops", "channel"); reply != adminOnly || channelDefault() != "" {
+		t.Errorf("non-admin set channel = %q, default %q", reply, channelDefault())
+	}
+	if run("bob", "s1", "ops"); personas.Current("feishu", "s1") == nil {
+		t.Error("non-admin should select a session persona")
+	}
+
+	// 管理员可以设置和清除渠道默认人设，“渠道:ID”只匹配该渠道
+	run("root", "s2", "ops", "channel")
+	if channelDefault() != "ops" {
+		t.Fatalf("admin set channel default = %q", channelDefault())
+	}
+	if reply := run("bob", "s1", "off", "channel"); reply != adminOnly || channelDefault() != "ops" {
+		t.Errorf("non-admin clear channel = %q, default %q", reply, channelDefault())
+	}
+	run("alice", "s3", "off", "channel")
+	if channelDefault() != "" {
+		t.Errorf("admin clear channel default = %q", channelDefault())
+	}
+}
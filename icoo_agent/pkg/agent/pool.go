@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"errors"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/dispatch"
+	"icooclaw/pkg/notice"
+)
+
+// WithWorkerPool 启用工作池，不同会话的消息最多由 cfg.Workers 个协程并发处理，用户之间轮转取消息。
+// notify 为 true 时因并发已满而排队的消息会通知用户排队位置。
+func (m *AgentManager) WithWorkerPool(cfg dispatch.Config, notify bool) *AgentManager {
+	m.pool = dispatch.New(cfg, m.process)
+	if notify {
+		m.pool.WithNotifier(m.notifyPosition)
+	}
+	return m
+}
+
+// WorkerPoolStats 返回工作池的运行状态，未启用时返回 false。
+func (m *AgentManager) WorkerPoolStats() (dispatch.Stats, bool) {
+	if m.pool == nil {
+		return dispatch.Stats{}, false
+	}
+	return m.pool.Stats(), true
+}
+
+// submit 将消息交给工作池，排队已满时告知用户稍后再试。
+func (m *AgentManager) submit(msg bus.InboundMessage) {
+	err := m.pool.Submit(msg)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, dispatch.ErrQueueFull) {
+		m.logger.With("name", "【工作池】").Warn("排队的消息已达上限，拒绝消息",
+			"channel", msg.Channel, "session_id", msg.SessionID)
+		if !isHeartbeat(msg) {
+			m.publishNotice(msg, m.notice(msg, notice.QueueFull, nil))
+		}
+		return
+	}
+	m.logger.With("name", "【工作池】").Warn("提交消息失败", "error", err, "session_id", msg.SessionID)
+}
+
+// notifyPosition 通知用户消息的排队位置。
+func (m *AgentManager) notifyPosition(msg bus.InboundMessage, ahead int, first bool) {
+	key := notice.QueueMoved
+	if first {
+		key = notice.Queued
+	}
+	m.publishNotice(msg, m.notice(msg, key, map[string]any{"Ahead": ahead}))
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/postprocess"
+)
+
+// WithPostProcess 设置回复后处理管道，回复投递前按规则处理。
+func (m *AgentManager) WithPostProcess(p *postprocess.Pipeline) *AgentManager {
+	m.postprocess = p
+	return m
+}
+
+// postProcess 对智能体回复执行后处理规则，规则按渠道和当前人设匹配。
+// 会话历史中保存的仍是模型原始输出。
+func (m *AgentManager) postProcess(msg bus.InboundMessage, text string) string {
+	if m.postprocess.Len() == 0 {
+		return text
+	}
+
+	scope := postprocess.Scope{Channel: msg.Channel, SessionID: msg.SessionID}
+	if m.personas != nil {
+		routed, _ := msg.Metadata[consts.META_PERSONA].(string)
+		if p := m.personas.Resolve(msg.Channel, msg.SessionID, routed); p != nil {
+			scope.Persona = p.Name
+		}
+	}
+	return m.postprocess.Apply(text, scope)
+}
+
+// postProcessStream 包装流式回调，对完成时下发的完整回复执行后处理。
+// 流式增量无法可靠匹配规则，按原样下发。
+func (m *AgentManager) postProcessStream(msg bus.InboundMessage, callback react.StreamCallback) react.StreamCallback {
+	if callback == nil || m.postprocess.Len() == 0 {
+		return callback
+	}
+
+	return func(chunk react.StreamChunk) error {
+		if chunk.Done {
+			chunk.Content = m.postProcess(msg, chunk.Content)
+		}
+		return callback(chunk)
+	}
+}
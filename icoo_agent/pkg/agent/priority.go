@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/command"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/notice"
+)
+
+// stopWords 不带斜杠也视为停止当前回复的消息
+var stopWords = []string{"stop", "停止", "停", "别说了"}
+
+// turn 正在进行的一轮对话
+type turn struct {
+	cancel context.CancelFunc
+}
+
+// prioritize 为总线上未指定优先级的入站消息分配优先级：停止请求和斜杠命令（包括用量确认、管理命令）
+// 优先于普通消息处理，不必排在长时间运行的工具调用之后。
+func (m *AgentManager) prioritize(msg bus.InboundMessage) bus.Priority {
+	if isStopRequest(msg) {
+		return bus.PriorityUrgent
+	}
+	if name, _, ok := command.Parse(msg.Text); ok {
+		if _, ok := m.commands.Get(name); ok {
+			return bus.PriorityUrgent
+		}
+	}
+	return bus.PriorityNormal
+}
+
+// interrupt 在紧急消息入队前调用：停止请求立即中断该会话正在进行的一轮对话，不再排队。
+// 其他紧急消息只是优先处理，不中断当前对话。
+func (m *AgentManager) interrupt(msg bus.InboundMessage) bool {
+	if !isStopRequest(msg) {
+		return false
+	}
+	if !m.stopTurn(msg.Channel, m.redirectMerged(msg).SessionID) {
+		return false
+	}
+	m.logger.With("name", "【智能体】").Info("已中断当前回复", "channel", msg.Channel, "session_id", msg.SessionID)
+	m.publishNotice(msg, m.notice(msg, notice.Stopped, nil))
+	return true
+}
+
+// beginTurn 登记一轮对话，返回可被 /stop 取消的上下文和结束时调用的函数。
+func (m *AgentManager) beginTurn(msg bus.InboundMessage) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	key := consts.GetSessionKey(msg.Channel, msg.SessionID)
+	t := &turn{cancel: cancel}
+
+	m.turnsMu.Lock()
+	if m.turns == nil {
+		m.turns = make(map[string]*turn)
+	}
+	m.turns[key] = t
+	m.turnsMu.Unlock()
+
+	return ctx, func() {
+		m.turnsMu.Lock()
+		if m.turns[key] == t {
+			delete(m.turns, key)
+		}
+		m.turnsMu.Unlock()
+		cancel()
+	}
+}
+
+// stopTurn 取消会话正在进行的一轮对话，没有进行中的对话时返回 false。
+func (m *AgentManager) stopTurn(channel, sessionID string) bool {
+	key := consts.GetSessionKey(channel, sessionID)
+	m.turnsMu.Lock()
+	t, ok := m.turns[key]
+	delete(m.turns, key)
+	m.turnsMu.Unlock()
+	if ok {
+		t.cancel()
+	}
+	return ok
+}
+
+// interrupted 判断本轮对话是否因停止请求而结束。
+func interrupted(ctx context.Context, err error) bool {
+	return err != nil && errors.Is(ctx.Err(), context.Canceled)
+}
+
+// isStopRequest 判断消息是否为停止当前回复的请求。
+func isStopRequest(msg bus.InboundMessage) bool {
+	if name, _, ok := command.Parse(msg.Text); ok {
+		return name == "stop"
+	}
+	return slices.Contains(stopWords, strings.ToLower(strings.TrimSpace(msg.Text)))
+}
+
+// cmdStop 停止当前会话正在进行的回复。
+func (m *AgentManager) cmdStop(ctx context.Context, c *command.Context) (string, error) {
+	if !m.stopTurn(c.Msg.Channel, c.Msg.SessionID) {
+		return "当前没有正在进行的回复", nil
+	}
+	return m.notice(c.Msg, notice.Stopped, nil), nil
+}
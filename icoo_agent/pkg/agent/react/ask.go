@@ -0,0 +1,103 @@
+package react
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/ask"
+)
+
+// AskUserError 模型调用 ask_user 向用户提问，本轮暂停，用户回答后继续。
+type AskUserError struct {
+	Pending *ask.Pending
+}
+
+func (e *AskUserError) Error() string {
+	return "等待用户回答: " + e.Pending.Question
+}
+
+// askToolCall 判断工具调用是否为 ask_user。是提问时返回问题；参数无效时 result 为返回给模型的错误。
+// 未注册或会话策略不允许 ask_user 时按普通工具调用处理。
+func (a *ReActAgent) askToolCall(ctx context.Context, tc providers.ToolCall) (pending *ask.Pending, result string, ok bool) {
+	if tc.Function.Name != ask.ToolName || a.tools == nil || !a.tools.HasTool(ask.ToolName) ||
+		!a.tools.Permitted(ask.ToolName, tools.GetPolicy(ctx)) {
+		return nil, "", false
+	}
+
+	var args map[string]any
+	if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+		return nil, "错误: 参数解析失败: " + err.Error(), true
+	}
+	question, options, err := ask.ParseArgs(args)
+	if err != nil {
+		return nil, "错误: " + err.Error(), true
+	}
+	return &ask.Pending{Question: question, Options: options, ToolCallID: tc.ID}, "", true
+}
+
+// suspendTurn 暂停本轮，返回 *AskUserError。保存用户消息之后本轮已产生的消息，回答后从此处继续。
+func (a *ReActAgent) suspendTurn(msg bus.InboundMessage, pending *ask.Pending, messages []providers.ChatMessage) error {
+	start := len(messages)
+	for start > 0 && messages[start-1].Role != consts.RoleUser.ToString() {
+		start--
+	}
+	pending.Text = msg.Text
+	pending.Persona, _ = msg.Metadata[consts.META_PERSONA].(string)
+	pending.Messages = append([]providers.ChatMessage(nil), messages[start:]...)
+	pending.AskedAt = time.Now()
+
+	a.logger.With("name", "【智能体】").Info("向用户提问，本轮暂停",
+		"session_id", msg.SessionID,
+		"question", pending.Question)
+	return &AskUserError{Pending: pending}
+}
+
+// resumeTurn 消息是对 ask_user 的回答时，在消息列表后接上暂停时的进度和回答，并将回答保存到记忆。
+func (a *ReActAgent) resumeTurn(ctx context.Context, sessionKey string, msg bus.InboundMessage, messages []providers.ChatMessage) []providers.ChatMessage {
+	answer, ok := msg.Metadata[consts.META_ASK_ANSWER].(*ask.Answer)
+	if !ok || answer.Pending == nil {
+		return messages
+	}
+
+	messages = append(messages, answer.Pending.Messages...)
+	messages = append(messages, providers.ChatMessage{
+		Role:       consts.RoleTool.ToString(),
+		Content:    "用户回答: " + answer.Text,
+		ToolCallID: answer.Pending.ToolCallID,
+	})
+
+	if a.memory != nil {
+		if err := a.memory.Save(ctx, sessionKey, consts.RoleUser.ToString(), answer.Text); err != nil {
+			a.logger.With("name", "【智能体】").Warn("保存用户回答失败", "error", err)
+		}
+	}
+	return messages
+}
+
+// saveQuestion 将向用户提出的问题作为助手消息保存到记忆。
+func (a *ReActAgent) saveQuestion(ctx context.Context, sessionKey string, err error) {
+	var asked *AskUserError
+	if a.memory == nil || !errors.As(err, &asked) {
+		return
+	}
+	if err := a.memory.Save(ctx, sessionKey, consts.RoleAssistant.ToString(), asked.Pending.Prompt()); err != nil {
+		a.logger.With("name", "【智能体】").Warn("保存提问失败", "error", err)
+	}
+}
+
+// trimSaved 去掉历史中已保存的本轮用户消息及其后的消息，避免与随后添加的用户消息重复。
+// 回答问题恢复时，提问时保存的问题也一并去掉，由暂停时的进度代替。
+func trimSaved(history []providers.ChatMessage, text string) []providers.ChatMessage {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == consts.RoleUser.ToString() && history[i].Content == text {
+			return history[:i]
+		}
+	}
+	return history
+}
@@ -0,0 +1,85 @@
+package react
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/ask"
+)
+
+func TestRunLLM_AskUser(t *testing.T) {
+	tool := &countTool{}
+	registry := tools.NewRegistry()
+	registry.Register(tool)
+	registry.Register(ask.NewTool())
+
+	provider := &scriptedProvider{responses: []*providers.ChatResponse{
+		{ToolCalls: []providers.ToolCall{
+			toolCall("c1", ask.ToolName, `{"question":"去哪个城市？","options":["北京","上海"]}`),
+			toolCall("c2", "search", `{"q":"天气"}`),
+		}},
+		{Content: "上海明天晴"},
+	}}
+	agent := &ReActAgent{
+		tools:             registry,
+		logger:            slog.Default(),
+		maxToolIterations: 10,
+	}
+
+	msg := bus.InboundMessage{Channel: "websocket", SessionID: "s1", Text: "明天天气怎么样"}
+	history := []providers.ChatMessage{
+		{Role: consts.RoleSystem.ToString(), Content: "system"},
+		{Role: consts.RoleUser.ToString(), Content: msg.Text},
+	}
+	_, _, err := agent.RunLLM(context.Background(), "m", provider, history, msg)
+	var asked *AskUserError
+	if !errors.As(err, &asked) {
+		t.Fatalf("RunLLM error = %v, want *AskUserError", err)
+	}
+	p := asked.Pending
+	if p.Question != "去哪个城市？" || len(p.Options) != 2 || p.ToolCallID != "c1" || p.Text != msg.Text {
+		t.Errorf("pending = %+v", p)
+	}
+	// 同一批的其他工具先执行，进度只保留用户消息之后的部分
+	if tool.calls != 1 || len(p.Messages) != 2 || p.Messages[0].Role != consts.RoleAssistant.ToString() ||
+		p.Messages[1].ToolCallID != "c2" {
+		t.Errorf("search calls = %d, saved messages = %+v", tool.calls, p.Messages)
+	}
+
+	// 回答后从暂停处继续，回答作为 ask_user 的结果
+	msg.Metadata = map[string]any{consts.META_ASK_ANSWER: &ask.Answer{Pending: p, Text: p.Resolve("2")}}
+	messages := agent.resumeTurn(context.Background(), "websocket:s1", msg, history)
+	content, _, err := agent.RunLLM(context.Background(), "m", provider, messages, msg)
+	if err != nil || content != "上海明天晴" {
+		t.Fatalf("resumed RunLLM = %q, %v", content, err)
+	}
+	final := provider.requests[1].Messages
+	if last := final[len(final)-1]; last.ToolCallID != "c1" || last.Content != "用户回答: 上海" {
+		t.Errorf("answer message = %+v", last)
+	}
+	if tool.calls != 1 {
+		t.Errorf("恢复后不应重新执行已完成的工具, calls = %d", tool.calls)
+	}
+}
+
+func TestTrimSaved(t *testing.T) {
+	history := []providers.ChatMessage{
+		{Role: consts.RoleUser.ToString(), Content: "你好"},
+		{Role: consts.RoleAssistant.ToString(), Content: "你好！"},
+		{Role: consts.RoleUser.ToString(), Content: "订机票"},
+		{Role: consts.RoleAssistant.ToString(), Content: "去哪里？"},
+		{Role: consts.RoleUser.ToString(), Content: "上海"},
+	}
+	if got := trimSaved(history, "订机票"); len(got) != 2 {
+		t.Errorf("trimSaved() = %+v", got)
+	}
+	if got := trimSaved(history, "没有这条"); len(got) != len(history) {
+		t.Errorf("trimSaved() without match = %+v", got)
+	}
+}
@@ -0,0 +1,59 @@
+package react
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+)
+
+// toolSkippedByBudget 时间预算用尽后未执行的工具调用结果
+const toolSkippedByBudget = "已跳过: 本轮时间预算已用尽，工具未执行"
+
+// WithTurnBudget 设置单轮对话的时间预算，覆盖所有迭代和工具调用，0 表示不限制。
+// 预算用尽后不再执行工具，模型基于已有结果直接给出答复。
+func WithTurnBudget(d time.Duration) Option {
+	return func(a *ReActAgent) {
+		a.turnBudget = d
+	}
+}
+
+// turnBudget 一轮对话的时间预算，零值表示不限制。
+type turnBudget struct {
+	budget   time.Duration
+	deadline time.Time
+}
+
+// newTurnBudget 从当前时间开始计算本轮预算。
+func (a *ReActAgent) newTurnBudget() turnBudget {
+	if a.turnBudget <= 0 {
+		return turnBudget{}
+	}
+	return turnBudget{budget: a.turnBudget, deadline: time.Now().Add(a.turnBudget)}
+}
+
+// exceeded 预算是否已用尽。
+func (b turnBudget) exceeded() bool {
+	return !b.deadline.IsZero() && !time.Now().Before(b.deadline)
+}
+
+// toolContext 返回在预算截止时取消的工具执行上下文，慢工具不会拖过预算。
+// 截止时间同时记录在上下文中，工具据此把预算用尽和自身超时区分开。
+func (b turnBudget) toolContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return tools.WithBudgetDeadline(ctx, b.deadline)
+}
+
+// wrapUpMessage 预算用尽后追加的系统消息，要求模型停止调用工具并给出阶段性答复。
+func (b turnBudget) wrapUpMessage() providers.ChatMessage {
+	return providers.ChatMessage{
+		Role: consts.RoleSystem.ToString(),
+		Content: fmt.Sprintf("本轮对话已超过 %s 的时间预算，不能再调用工具。"+
+			"请根据已有的工具结果直接答复用户，说明哪些部分尚未完成，以及用户可以如何继续。", b.budget),
+	}
+}
@@ -0,0 +1,99 @@
+package react
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+)
+
+// scriptedProvider 按顺序返回预设回复，并记录每次请求。
+type scriptedProvider struct {
+	mockProvider
+	mu        sync.Mutex
+	responses []*providers.ChatResponse
+	requests  []providers.ChatRequest
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	if len(p.responses) == 0 {
+		return &providers.ChatResponse{Content: "done"}, nil
+	}
+	resp := p.responses[0]
+	p.responses = p.responses[1:]
+	return resp, nil
+}
+
+// slowTool 阻塞到上下文取消。
+type slowTool struct{ calls int }
+
+func (t *slowTool) Name() string               { return "slow" }
+func (t *slowTool) Description() string        { return "slow tool" }
+func (t *slowTool) Parameters() map[string]any { return map[string]any{} }
+func (t *slowTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	t.calls++
+	<-ctx.Done()
+	return tools.ErrorResult("canceled")
+}
+
+func toolCall(id, name, args string) providers.ToolCall {
+	tc := providers.ToolCall{ID: id, Type: "function"}
+	tc.Function.Name = name
+	tc.Function.Arguments = args
+	return tc
+}
+
+func TestRunLLM_TurnBudget(t *testing.T) {
+	tool := &slowTool{}
+	registry := tools.NewRegistry()
+	registry.Register(tool)
+
+	provider := &scriptedProvider{responses: []*providers.ChatResponse{
+		{ToolCalls: []providers.ToolCall{toolCall("c1", "slow", "{}"), toolCall("c2", "slow", "{}")}},
+		{Content: "partial answer"},
+	}}
+	agent := &ReActAgent{
+		tools:             registry,
+		logger:            slog.Default(),
+		maxToolIterations: 10,
+		turnBudget:        50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	content, iteration, err := agent.RunLLM(context.Background(), "m", provider,
+		[]providers.ChatMessage{{Role: consts.RoleUser.ToString(), Content: "hi"}}, bus.InboundMessage{})
+	if err != nil {
+		t.Fatalf("RunLLM error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("预算用尽后应及时收尾, 耗时 %s", elapsed)
+	}
+	if content != "partial answer" || iteration != 2 {
+		t.Errorf("content = %q, iteration = %d", content, iteration)
+	}
+	if tool.calls != 1 {
+		t.Errorf("预算用尽后不应继续执行工具, calls = %d", tool.calls)
+	}
+
+	final := provider.requests[1]
+	if len(final.Tools) != 0 {
+		t.Error("收尾请求不应再提供工具")
+	}
+	msgs := final.Messages
+	if last := msgs[len(msgs)-1]; last.Role != consts.RoleSystem.ToString() || !strings.Contains(last.Content, "时间预算") {
+		t.Errorf("收尾请求应追加预算提示, got %+v", last)
+	}
+	if skipped := msgs[len(msgs)-2]; skipped.ToolCallID != "c2" || skipped.Content != toolSkippedByBudget {
+		t.Errorf("未执行的工具调用应有跳过结果, got %+v", skipped)
+	}
+}
@@ -6,15 +6,18 @@ import (
 	"icooclaw/pkg/bus"
 	"icooclaw/pkg/consts"
 	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/ask"
+	"time"
 )
 
 // Chat 发送消息（非流式）
 func (a *ReActAgent) Chat(ctx context.Context, msg bus.InboundMessage) (string, int, error) {
 	// 会话键
-	sessionKey := consts.GetSessionKey(msg.Channel, msg.SessionID)
+	sessionKey := a.memoryKey(msg)
 
 	// 1. 获取供应商实例
-	provider, modelName, err := a.GetDynamicProvider(ctx)
+	provider, modelName, err := a.providerFor(ctx, msg)
 	if err != nil {
 		return "", 0, err
 	}
@@ -25,9 +28,18 @@ func (a *ReActAgent) Chat(ctx context.Context, msg bus.InboundMessage) (string,
 		return "", 0, err
 	}
 
+	// 回答智能体的提问时从暂停处继续
+	messages = a.resumeTurn(ctx, sessionKey, msg, messages)
+
+	// 预计用量超过阈值时先请用户确认，不调用模型
+	if err := a.previewCost(msg, modelName, messages); err != nil {
+		return "", 0, err
+	}
+
 	// 3. 运行LLM模型
 	content, iteration, err := a.RunLLM(ctx, modelName, provider, messages, msg)
 	if err != nil {
+		a.saveQuestion(ctx, sessionKey, err)
 		return "", 0, err
 	}
 
@@ -48,10 +60,26 @@ func (a *ReActAgent) RunLLM(
 	provider providers.Provider,
 	messages []providers.ChatMessage,
 	msg bus.InboundMessage,
-) (string, int, error) {
-	iteration := 0
+) (content string, iteration int, err error) {
 	currentMessages := messages
-	var err error
+	status := a.newStatusTracker(msg)
+	defer status.end()
+	budget := a.newTurnBudget()
+	calls := a.newToolCallCache()
+	filterRetried := false
+	stats := turnStats(ctx, modelName)
+
+	// 会话级工具策略，同时约束提供给模型的工具定义和工具执行
+	policy := a.toolPolicy(msg)
+	ctx = tools.WithPolicy(ctx, policy)
+
+	// 会话变量，工具执行时可读取
+	ctx = tools.WithVars(ctx, a.sessionVars(msg))
+
+	// 会话选择的工作目录，文件和命令工具据此解析路径
+	ctx = a.withSessionWorkspace(ctx, msg)
+	ctx = a.withSessionLocation(ctx, msg)
+	ctx = tools.WithSender(ctx, msg.Sender.ID, msg.Sender.Name)
 
 	// 调用钩子运行LLM模型前
 	if a.hooks != nil {
@@ -61,30 +89,60 @@ func (a *ReActAgent) RunLLM(
 		}
 	}
 
+	// 记录本轮轨迹，结束时写入存储
+	recorder := a.newTurnRecorder(msg, modelName, currentMessages)
+	defer func() { a.saveTrace(ctx, recorder, content, err) }()
+
 	// 迭代调用LLM
 	for iteration < a.maxToolIterations {
 		iteration++
 
+		// 时间预算用尽后不再提供工具，要求模型基于已有结果收尾
+		wrapUp := budget.exceeded()
+		if wrapUp {
+			a.logger.With("name", "【智能体】").Info("本轮时间预算已用尽，开始收尾",
+				"session_id", msg.SessionID,
+				"iteration", iteration)
+			currentMessages = append(currentMessages, budget.wrapUpMessage())
+		}
+		recorder.request(iteration, wrapUp)
+
 		// 1. 构建请求消息
 		req := providers.ChatRequest{
-			Model:    modelName,
-			Messages: currentMessages,
+			Model: modelName,
 		}
 
-		// 2. 处理工具调用
-		toolDefs := a.tools.ToProviderDefs()
-		if len(toolDefs) > 0 {
+		// 2. 处理工具调用，探测到模型不支持工具时不提供
+		toolDefs := a.tools.ToProviderDefsFor(policy)
+		if len(toolDefs) > 0 && !wrapUp && supportsTools(modelName) {
 			req.Tools = a.convertToolDefinitions(toolDefs)
 		}
 
+		// 按模型上下文长度省略较早的历史消息
+		currentMessages = a.fitContext(msg, modelName, currentMessages, req.Tools)
+		req.Messages = currentMessages
+		recorder.toolDefs(req.Tools)
+
 		// 3. 发送请求到提供商
 		resp, err := provider.Chat(ctx, req)
 		if err != nil {
+			// 被内容安全策略拦截时追加安全提示重试一次
+			if next, ok := a.retryFiltered(msg, err, &filterRetried, currentMessages); ok {
+				currentMessages = next
+				continue
+			}
 			return "", iteration, fmt.Errorf("LLM请求失败: %w", err)
 		}
+		recorder.response(resp.Content, resp.Reasoning, &resp.Usage)
+		stats.response(req, resp)
+
+		// 调用钩子处理模型响应
+		if err := a.onLLMResponse(ctx, msg, resp); err != nil {
+			return "", iteration, err
+		}
 
 		// 4. 处理工具调用响应
-		if len(resp.ToolCalls) > 0 {
+		if len(resp.ToolCalls) > 0 && !wrapUp {
 			// 添加助手消息
 			assistantMsg := providers.ChatMessage{
 				Role:      consts.RoleAssistant.ToString(),
@@ -94,13 +152,34 @@ func (a *ReActAgent) RunLLM(
 			currentMessages = append(currentMessages, assistantMsg)
 
 			// 5. 执行每个工具调用
-			for _, tc := range resp.ToolCalls {
-				// 执行工具调用
-				toolResult, err := a.executeToolCall(ctx, tc, msg)
-				if err != nil {
-					toolResult = fmt.Sprintf("错误: %v", err)
+			status.begin(iteration, len(resp.ToolCalls))
+			var asked *ask.Pending
+			for i, tc := range resp.ToolCalls {
+				// ask_user 在其他工具执行完后暂停本轮，参数无效时作为错误结果返回给模型
+				if pending, result, ok := a.askToolCall(ctx, tc); ok {
+					if pending != nil && asked == nil {
+						asked = pending
+						continue
+					}
+					if pending != nil {
+						result = "错误: 每次只能提出一个问题，请等待用户回答上一个问题"
+					}
+					currentMessages = append(currentMessages, providers.ChatMessage{
+						Role:       consts.RoleTool.ToString(),
+						Content:    result,
+						ToolCallID: tc.ID,
+					})
+					continue
 				}
 
+				status.tool(tc.Function.Name, i)
+
+				// 执行工具调用，实时输出附加到进度心跳
+				started := time.Now()
+				toolResult := a.runToolCall(ctx, tc, msg, iteration, status, nil, budget, calls)
+				recorder.tool(tc, toolResult, time.Since(started))
+				stats.tool(tc, iteration, time.Since(started))
+
 				// 添加工具调用结果消息
 				currentMessages = append(currentMessages, providers.ChatMessage{
 					Role:       consts.RoleTool.ToString(),
@@ -108,12 +187,22 @@ func (a *ReActAgent) RunLLM(
 					ToolCallID: tc.ID,
 				})
 			}
+			status.end()
+			if asked != nil {
+				return "", iteration, a.suspendTurn(msg, asked, currentMessages)
+			}
+
+			// 反复发起相同调用时提醒模型换个方向
+			if note, ok := calls.correction(); ok {
+				currentMessages = append(currentMessages, note)
+			}
 
 			continue
 		}
 
 		// 6. 返回响应内容
-		return resp.Content, iteration, nil
+		content, err = a.complete(ctx, msg, currentMessages, resp.Content)
+		return content, iteration, err
 	}
 
 	// 到达最大迭代次数
@@ -6,15 +6,18 @@ import (
 	"icooclaw/pkg/bus"
 	"icooclaw/pkg/consts"
 	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/ask"
+	"time"
 )
 
 // ChatStream 发送消息（流式）
 func (a *ReActAgent) ChatStream(ctx context.Context, msg bus.InboundMessage, callback StreamCallback) (string, int, error) {
 	// 会话键
-	sessionKey := consts.GetSessionKey(msg.Channel, msg.SessionID)
+	sessionKey := a.memoryKey(msg)
 
 	// 1. 获取供应商实例
-	provider, modelName, err := a.GetDynamicProvider(ctx)
+	provider, modelName, err := a.providerFor(ctx, msg)
 	if err != nil {
 		if callback != nil {
 			callback(StreamChunk{Error: err})
@@ -31,9 +34,18 @@ func (a *ReActAgent) ChatStream(ctx context.Context, msg bus.InboundMessage, cal
 		return "", 0, err
 	}
 
+	// 回答智能体的提问时从暂停处继续
+	messages = a.resumeTurn(ctx, sessionKey, msg, messages)
+
+	// 预计用量超过阈值时先请用户确认，不调用模型
+	if err := a.previewCost(msg, modelName, messages); err != nil {
+		return "", 0, err
+	}
+
 	// 3. 运行LLM模型（流式）
 	content, iteration, err := a.RunLLMStream(ctx, modelName, provider, messages, msg, callback)
 	if err != nil {
+		a.saveQuestion(ctx, sessionKey, err)
 		return "", iteration, err
 	}
 
@@ -55,10 +67,26 @@ func (a *ReActAgent) RunLLMStream(
 	messages []providers.ChatMessage,
 	msg bus.InboundMessage,
 	callback StreamCallback,
-) (string, int, error) {
-	iteration := 0
+) (content string, iteration int, err error) {
 	currentMessages := messages
-	var err error
+	status := a.newStatusTracker(msg)
+	defer status.end()
+	budget := a.newTurnBudget()
+	calls := a.newToolCallCache()
+	filterRetried := false
+	stats := turnStats(ctx, modelName)
+
+	// 会话级工具策略，同时约束提供给模型的工具定义和工具执行
+	policy := a.toolPolicy(msg)
+	ctx = tools.WithPolicy(ctx, policy)
+
+	// 会话变量，工具执行时可读取
+	ctx = tools.WithVars(ctx, a.sessionVars(msg))
+
+	// 会话选择的工作目录，文件和命令工具据此解析路径
+	ctx = a.withSessionWorkspace(ctx, msg)
+	ctx = a.withSessionLocation(ctx, msg)
+	ctx = tools.WithSender(ctx, msg.Sender.ID, msg.Sender.Name)
 
 	// 调用钩子运行LLM模型前
 	if a.hooks != nil {
@@ -68,22 +96,47 @@ func (a *ReActAgent) RunLLMStream(
 		}
 	}
 
+	// 记录本轮轨迹，结束时写入存储
+	recorder := a.newTurnRecorder(msg, modelName, currentMessages)
+	defer func() { a.saveTrace(ctx, recorder, content, err) }()
+
 	// 迭代调用LLM
 	for iteration < a.maxToolIterations {
 		iteration++
 
+		// 时间预算用尽后不再提供工具，要求模型基于已有结果收尾
+		wrapUp := budget.exceeded()
+		if wrapUp {
+			a.logger.With("name", "【智能体】").Info("本轮时间预算已用尽，开始收尾",
+				"session_id", msg.SessionID,
+				"iteration", iteration)
+			currentMessages = append(currentMessages, budget.wrapUpMessage())
+		}
+		recorder.request(iteration, wrapUp)
+
+		// 通知新一轮迭代开始，便于客户端按迭代展示推理轨迹
+		if callback != nil {
+			if err = callback(StreamChunk{Start: true, WrapUp: wrapUp, Iteration: iteration}); err != nil {
+				return "", iteration, err
+			}
+		}
+
 		// 1. 构建请求消息
 		req := providers.ChatRequest{
-			Model:    modelName,
-			Messages: currentMessages,
+			Model: modelName,
 		}
 
-		// 2. 处理工具调用
-		toolDefs := a.tools.ToProviderDefs()
-		if len(toolDefs) > 0 {
+		// 2. 处理工具调用，探测到模型不支持工具时不提供
+		toolDefs := a.tools.ToProviderDefsFor(policy)
+		if len(toolDefs) > 0 && !wrapUp && supportsTools(modelName) {
 			req.Tools = a.convertToolDefinitions(toolDefs)
 		}
 
+		// 按模型上下文长度省略较早的历史消息
+		currentMessages = a.fitContext(msg, modelName, currentMessages, req.Tools)
+		req.Messages = currentMessages
+		recorder.toolDefs(req.Tools)
+
 		// 3. 发送流式请求到提供商
 		var collectedContent string
 		var collectedReasoning string
@@ -124,24 +177,38 @@ func (a *ReActAgent) RunLLMStream(
 		})
 
 		if err != nil {
-			if callback != nil {
+			// 被内容安全策略拦截时追加安全提示重试一次，已推送的部分内容不撤回
+			if next, ok := a.retryFiltered(msg, err, &filterRetried, currentMessages); ok {
+				currentMessages = next
+				continue
+			}
+			// 仍被拦截时由调用方发送拦截提示，不推送错误块
+			if callback != nil && !providers.IsContentFiltered(err) {
 				callback(StreamChunk{Error: err, Iteration: iteration})
 			}
 			return "", iteration, fmt.Errorf("LLM请求失败: %w", err)
 		}
+		recorder.response(collectedContent, collectedReasoning, nil)
+		stats.response(req, &providers.ChatResponse{Content: collectedContent, Reasoning: collectedReasoning, ToolCalls: collectedToolCalls})
 
-		// 4. 处理工具调用响应
-		if len(collectedToolCalls) > 0 {
-			// 合并工具调用
-			mergedToolCalls := a.mergeToolCalls(collectedToolCalls)
-
-			// 验证工具调用
-			validToolCalls := a.validateToolCalls(mergedToolCalls)
-			if len(validToolCalls) == 0 {
-				// 没有有效工具调用，作为普通响应处理
-				return collectedContent, iteration, nil
+		// 合并并验证工具调用，没有有效工具调用时作为普通响应处理
+		var validToolCalls []providers.ToolCall
+		if len(collectedToolCalls) > 0 && !wrapUp {
+			validToolCalls = a.validateToolCalls(a.mergeToolCalls(collectedToolCalls))
+		}
+
+		// 调用钩子处理模型响应，已推送的内容块不受改写影响
+		resp := &providers.ChatResponse{Content: collectedContent, Reasoning: collectedReasoning, ToolCalls: validToolCalls}
+		if err = a.onLLMResponse(ctx, msg, resp); err != nil {
+			if callback != nil {
+				callback(StreamChunk{Error: err, Iteration: iteration})
 			}
+			return "", iteration, err
+		}
+		collectedContent, validToolCalls = resp.Content, resp.ToolCalls
 
+		// 4. 处理工具调用响应
+		if len(validToolCalls) > 0 {
 			// 添加助手消息
 			assistantMsg := providers.ChatMessage{
 				Role:      consts.RoleAssistant.ToString(),
@@ -151,26 +218,51 @@ func (a *ReActAgent) RunLLMStream(
 			currentMessages = append(currentMessages, assistantMsg)
 
 			// 5. 执行每个工具调用
-			for _, tc := range validToolCalls {
+			status.begin(iteration, len(validToolCalls))
+			var asked *ask.Pending
+			for i, tc := range validToolCalls {
+				// ask_user 在其他工具执行完后暂停本轮，参数无效时作为错误结果返回给模型
+				if pending, result, ok := a.askToolCall(ctx, tc); ok {
+					if pending != nil && asked == nil {
+						asked = pending
+						continue
+					}
+					if pending != nil {
+						result = "错误: 每次只能提出一个问题，请等待用户回答上一个问题"
+					}
+					currentMessages = append(currentMessages, providers.ChatMessage{
+						Role:       consts.RoleTool.ToString(),
+						Content:    result,
+						ToolCallID: tc.ID,
+					})
+					continue
+				}
+
+				status.tool(tc.Function.Name, i)
+
 				// 发送工具调用通知
 				if callback != nil {
 					if err := callback(StreamChunk{
-						ToolName:  tc.Function.Name,
-						Iteration: iteration,
+						ToolCallID: tc.ID,
+						ToolName:   tc.Function.Name,
+						ToolArgs:   tc.Function.Arguments,
+						Iteration:  iteration,
 					}); err != nil {
 						return "", iteration, err
 					}
 				}
 
-				// 执行工具调用
-				toolResult, execErr := a.executeToolCall(ctx, tc, msg)
-				if execErr != nil {
-					toolResult = fmt.Sprintf("错误: %v", execErr)
-				}
+				// 执行工具调用，实时输出通过回调下发
+				started := time.Now()
+				toolResult := a.runToolCall(ctx, tc, msg, iteration, status, callback, budget, calls)
+				recorder.tool(tc, toolResult, time.Since(started))
+				stats.tool(tc, iteration, time.Since(started))
 
 				// 发送工具结果通知
 				if callback != nil {
 					if err := callback(StreamChunk{
+						ToolCallID: tc.ID,
+						ToolName:   tc.Function.Name,
 						ToolResult: toolResult,
 						Iteration:  iteration,
 					}); err != nil {
@@ -185,18 +277,36 @@ func (a *ReActAgent) RunLLMStream(
 					ToolCallID: tc.ID,
 				})
 			}
+			status.end()
+			if asked != nil {
+				return "", iteration, a.suspendTurn(msg, asked, currentMessages)
+			}
+
+			// 反复发起相同调用时提醒模型换个方向
+			if note, ok := calls.correction(); ok {
+				currentMessages = append(currentMessages, note)
+			}
 
 			// 继续下一个迭代
 			continue
 		}
 
 		// 6. 没有工具调用，返回响应内容
+		collectedContent, err = a.complete(ctx, msg, currentMessages, collectedContent)
+		if err != nil {
+			if callback != nil {
+				callback(StreamChunk{Error: err, Iteration: iteration})
+			}
+			return "", iteration, err
+		}
+
 		// 发送完成信号
 		if callback != nil {
 			if err := callback(StreamChunk{
 				Content:   collectedContent,
 				Done:      true,
 				Iteration: iteration,
+				Stats:     stats.Snapshot(),
 			}); err != nil {
 				return "", iteration, err
 			}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"icooclaw/pkg/bus"
 	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
 )
 
 func TestStreamChunk_Types(t *testing.T) {
@@ -272,3 +275,74 @@ func (m *mockProvider) ChatStream(ctx context.Context, req providers.ChatRequest
 	callback("", "", nil, true)
 	return nil
 }
+
+func TestStatusTracker(t *testing.T) {
+	var got []Status
+	done := make(chan struct{}, 1)
+	agent := &ReActAgent{
+		statusFn: func(msg bus.InboundMessage, s Status) {
+			got = append(got, s)
+			select {
+			case done <- struct{}{}:
+			default:
+			}
+		},
+		statusInterval: 10 * time.Millisecond,
+	}
+
+	tracker := agent.newStatusTracker(bus.InboundMessage{SessionID: "s1"})
+	tracker.begin(1, 5)
+	tracker.tool("grep", 3)
+	<-done
+	tracker.end()
+
+	if got[0].Tool != "grep" || got[0].Completed != 3 || got[0].Total != 5 || got[0].Elapsed <= 0 {
+		t.Errorf("unexpected status: %+v", got[0])
+	}
+	if want := "正在运行 grep… 3/5 个工具已完成"; !strings.HasPrefix(got[0].String(), want) {
+		t.Errorf("String() = %q, want prefix %q", got[0].String(), want)
+	}
+
+	// 工具在一个间隔内完成时不上报
+	n := len(got)
+	tracker.begin(2, 1)
+	tracker.end()
+	if len(got) != n {
+		t.Error("fast tools should not emit status")
+	}
+}
+
+func TestStatusTracker_Disabled(t *testing.T) {
+	agent := &ReActAgent{}
+	tracker := agent.newStatusTracker(bus.InboundMessage{})
+	tracker.begin(1, 1)
+	tracker.tool("grep", 0)
+	tracker.end()
+}
+
+func TestRunLLMStream_IterationAndStats(t *testing.T) {
+	agent := &ReActAgent{tools: tools.NewRegistry(), logger: slog.Default(), maxToolIterations: 10}
+	messages := []providers.ChatMessage{{Role: "user", Content: "hi"}}
+	provider := &mockProvider{streamContent: []string{"你好", "，世界"}}
+
+	var chunks []StreamChunk
+	content, _, err := agent.RunLLMStream(context.Background(), "m", provider, messages, bus.InboundMessage{}, func(chunk StreamChunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil || content != "你好，世界" {
+		t.Fatalf("RunLLMStream() = %q, %v", content, err)
+	}
+
+	if first := chunks[0]; !first.Start || first.Iteration != 1 {
+		t.Errorf("第一个数据块应为迭代开始, got %+v", first)
+	}
+	last := chunks[len(chunks)-1]
+	if !last.Done || last.Stats == nil {
+		t.Fatalf("完成块应携带统计, got %+v", last)
+	}
+	if st := last.Stats; st.Iterations != 1 || !st.Estimated || st.PromptTokens == 0 || st.CompletionTokens == 0 ||
+		st.TotalTokens != st.PromptTokens+st.CompletionTokens {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
@@ -0,0 +1,171 @@
+package react
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools/builtin/output"
+	"icooclaw/pkg/utils"
+)
+
+// 工具结果的压缩方式
+const (
+	CondenseLLM  = "llm"  // 调用模型提取与用户问题相关的内容
+	CondenseRule = "rule" // 保留开头和结尾
+	CondenseOff  = "off"  // 不压缩
+)
+
+// condenseInputTokens 交给模型压缩的工具输出的最大长度，超出部分先按规则截取
+const condenseInputTokens = 24000
+
+// CondensePolicy 工具结果的压缩规则。
+type CondensePolicy struct {
+	Mode      string // 压缩方式：llm、rule 或 off
+	Threshold int    // 估计 token 数超过该值的结果才压缩
+}
+
+// ToolCondense 冗长工具结果的压缩配置。
+type ToolCondense struct {
+	Default   CondensePolicy            // 默认规则
+	Tools     map[string]CondensePolicy // 按工具名覆盖默认规则
+	Model     string                    // llm 压缩使用的模型，格式为 提供商/模型，为空时使用默认模型
+	MaxTokens int                       // 压缩结果的目标长度
+	Cache     *output.Cache             // 完整输出缓存，nil 时不提供完整输出
+}
+
+// policy 返回工具的压缩规则。
+func (c *ToolCondense) policy(tool string) CondensePolicy {
+	if p, ok := c.Tools[tool]; ok {
+		return p
+	}
+	return c.Default
+}
+
+// WithToolCondense 工具结果超过阈值时先压缩再写入对话，完整输出缓存后可通过 tool_output 工具读取，
+// 避免网页、日志等冗长结果挤出上下文中的对话历史。c 为 nil 时不压缩。
+func WithToolCondense(c *ToolCondense) Option {
+	return func(a *ReActAgent) {
+		a.condense = c
+	}
+}
+
+// condenseToolResult 按工具的压缩规则压缩过长的结果，附带完整输出的 ID。
+// 模型压缩失败时退回按规则截取。
+func (a *ReActAgent) condenseToolResult(ctx context.Context, tool string, msg bus.InboundMessage, result string) string {
+	if a.condense == nil || tool == output.ToolName {
+		return result
+	}
+	p := a.condense.policy(tool)
+	if p.Mode == CondenseOff || p.Threshold <= 0 {
+		return result
+	}
+	tokens := channels.EstimateTokens(result)
+	if tokens <= p.Threshold {
+		return result
+	}
+
+	var condensed string
+	if p.Mode == CondenseLLM {
+		var err error
+		condensed, err = a.condenseWithLLM(ctx, tool, msg.Text, result)
+		if err != nil {
+			a.logger.With("name", "【智能体】").Warn("模型压缩工具结果失败，改为截取",
+				"tool", tool,
+				"session_id", msg.SessionID,
+				"error", err)
+		}
+	}
+	if condensed == "" {
+		condensed = excerpt(result, a.condense.MaxTokens)
+	}
+
+	a.logger.With("name", "【智能体】").Info("工具结果过长，已压缩",
+		"tool", tool,
+		"session_id", msg.SessionID,
+		"tokens", tokens,
+		"condensed_tokens", channels.EstimateTokens(condensed))
+
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "[%s 的结果过长（约 %d tokens），以下为压缩后的内容]\n", tool, tokens)
+	sb.WriteString(condensed)
+	if a.condense.Cache != nil {
+		id := a.condense.Cache.Put(msg.Channel, msg.SessionID, tool, result)
+		fmt.Fprintf(&sb, "\n\n[完整输出共 %d 字符，已缓存为 %s，需要更多细节时可调用 %s 工具分段读取或搜索]",
+			utf8.RuneCountInString(result), id, output.ToolName)
+	}
+	return sb.String()
+}
+
+// condenseWithLLM 调用模型从工具输出中提取与用户问题相关的内容。
+func (a *ReActAgent) condenseWithLLM(ctx context.Context, tool, question, result string) (string, error) {
+	provider, modelName, err := a.condenseProvider(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	maxChars := a.condense.MaxTokens * 2
+	resp, err := provider.Chat(ctx, providers.ChatRequest{
+		Model: modelName,
+		Messages: []providers.ChatMessage{
+			{
+				Role: consts.RoleSystem.ToString(),
+				Content: fmt.Sprintf("你负责压缩工具输出。根据用户的问题，从工具输出中提取相关的事实、数据和结论，"+
+					"保留关键的数字、名称、链接、路径和错误信息，省略导航、广告、样板和重复内容。"+
+					"只输出提取的内容，不要回答用户的问题，不超过 %d 字。", maxChars),
+			},
+			{
+				Role: consts.RoleUser.ToString(),
+				Content: fmt.Sprintf("用户的问题：%s\n\n工具 %s 的输出：\n%s",
+					question, tool, excerpt(result, condenseInputTokens)),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	content := strings.TrimSpace(resp.Content)
+	if content == "" {
+		return "", fmt.Errorf("模型返回了空内容")
+	}
+	return content, nil
+}
+
+// condenseProvider 返回压缩使用的提供商和模型，未配置时使用默认模型。
+func (a *ReActAgent) condenseProvider(ctx context.Context) (providers.Provider, string, error) {
+	if a.condense.Model == "" {
+		return a.GetDynamicProvider(ctx)
+	}
+	if a.providerFactory == nil {
+		return nil, "", fmt.Errorf("未配置提供商工厂")
+	}
+	parts := utils.SplitProviderModel(a.condense.Model)
+	if len(parts) != 2 {
+		return nil, "", fmt.Errorf("压缩模型格式错误: %s", a.condense.Model)
+	}
+	provider, err := a.providerFactory.Get(parts[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("获取Provider失败: %w", err)
+	}
+	return provider, parts[1], nil
+}
+
+// excerpt 按规则截取文本：超过 maxTokens 时保留开头约三分之二和结尾约三分之一，中间注明省略的字符数。
+func excerpt(text string, maxTokens int) string {
+	tokens := channels.EstimateTokens(text)
+	if maxTokens <= 0 || tokens <= maxTokens {
+		return text
+	}
+
+	runes := []rune(text)
+	keep := len(runes) * maxTokens / tokens
+	head := keep * 2 / 3
+	tail := keep - head
+	return fmt.Sprintf("%s\n…[省略 %d 字符]…\n%s",
+		string(runes[:head]), len(runes)-keep, string(runes[len(runes)-tail:]))
+}
@@ -0,0 +1,67 @@
+package react
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/output"
+)
+
+func TestExcerpt(t *testing.T) {
+	text := strings.Repeat("a", 4000) + strings.Repeat("z", 4000)
+	got := excerpt(text, 200)
+	if !strings.HasPrefix(got, "aaa") || !strings.HasSuffix(got, "zzz") || !strings.Contains(got, "省略") {
+		t.Errorf("excerpt() = %q", got)
+	}
+	if tokens := channels.EstimateTokens(got); tokens > 220 {
+		t.Errorf("excerpt() tokens = %d", tokens)
+	}
+	if got := excerpt("short", 200); got != "short" {
+		t.Errorf("excerpt(short) = %q", got)
+	}
+}
+
+func TestCondenseToolResult(t *testing.T) {
+	cache := output.NewCache(1<<20, 0)
+	agent := &ReActAgent{
+		logger: slog.Default(),
+		condense: &ToolCondense{
+			Default:   CondensePolicy{Mode: CondenseRule, Threshold: 100},
+			Tools:     map[string]CondensePolicy{"shell_command": {Mode: CondenseOff}, "http_request": {Mode: CondenseLLM, Threshold: 100}},
+			MaxTokens: 50,
+			Cache:     cache,
+		},
+	}
+	msg := bus.InboundMessage{Channel: "websocket", SessionID: "s1", Text: "页面标题是什么"}
+	long := strings.Repeat("navigation ", 200)
+
+	if got := agent.condenseToolResult(context.Background(), "search", msg, "short"); got != "short" {
+		t.Errorf("short result = %q", got)
+	}
+	if got := agent.condenseToolResult(context.Background(), "shell_command", msg, long); got != long {
+		t.Error("tools with mode off should not be condensed")
+	}
+
+	// 没有可用的模型时退回按规则截取
+	got := agent.condenseToolResult(context.Background(), "http_request", msg, long)
+	id := regexp.MustCompile(`out-[0-9a-f]+`).FindString(got)
+	if id == "" || !strings.Contains(got, "省略") || len(got) >= len(long) {
+		t.Fatalf("condensed = %q", got)
+	}
+
+	// 完整输出可以通过 tool_output 读回
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+	r := output.NewTool(cache).Execute(ctx, map[string]any{"id": id, "limit": float64(len(long))})
+	if !r.Success || !strings.HasSuffix(r.Content, long) {
+		t.Errorf("tool_output = %q", r.Content)
+	}
+	if got := agent.condenseToolResult(context.Background(), output.ToolName, msg, long); got != long {
+		t.Error("tool_output results should not be condensed again")
+	}
+}
@@ -0,0 +1,118 @@
+package react
+
+import (
+	"fmt"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/providers"
+)
+
+// costReplyTokens 每次模型回复的估计输出长度
+const costReplyTokens = 1000
+
+// CostEstimate 一轮对话的预计用量。
+type CostEstimate struct {
+	Model        string  `json:"model"`         // 模型
+	InputTokens  int     `json:"input_tokens"`  // 预计输入 token 数，包含工具调用轮次重复发送的上下文
+	OutputTokens int     `json:"output_tokens"` // 预计输出 token 数
+	Cost         float64 `json:"cost"`          // 预计费用（美元），模型价格未知时为 0
+	Tools        int     `json:"tools"`         // 提供给模型的工具数
+}
+
+// Tokens 预计的总 token 数。
+func (e CostEstimate) Tokens() int {
+	return e.InputTokens + e.OutputTokens
+}
+
+// Text 返回 "约 30k tokens ≈ $0.45" 形式的说明，价格未知时不显示费用。
+func (e CostEstimate) Text() string {
+	text := "约 " + FormatTokens(e.Tokens()) + " tokens"
+	if e.Cost > 0 {
+		text += fmt.Sprintf(" ≈ $%.2f", e.Cost)
+	}
+	return text
+}
+
+// FormatTokens 将 token 数格式化为 950、12k、1.5M 这样的简写。
+func FormatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1000:
+		return fmt.Sprintf("%dk", (n+500)/1000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// CostGate 根据预计用量判断本轮是否需要用户确认，返回 true 时不调用模型。
+type CostGate func(msg bus.InboundMessage, est CostEstimate) bool
+
+// CostHoldError 本轮预计用量超过阈值，等待用户确认后再处理。
+type CostHoldError struct {
+	Estimate CostEstimate
+}
+
+func (e *CostHoldError) Error() string {
+	return "本轮预计用量超过阈值，等待用户确认: " + e.Estimate.Text()
+}
+
+// WithCostPreview 调用模型前预估本轮用量，gate 判断需要确认时返回 *CostHoldError。
+// toolRounds 为提供工具时额外估计的模型调用次数，每次调用都会重复发送上下文。
+func WithCostPreview(gate CostGate, toolRounds int) Option {
+	return func(a *ReActAgent) {
+		a.costGate = gate
+		a.costToolRounds = toolRounds
+	}
+}
+
+// previewCost 预估本轮用量，需要用户确认时返回 *CostHoldError。
+func (a *ReActAgent) previewCost(msg bus.InboundMessage, modelName string, messages []providers.ChatMessage) error {
+	if a.costGate == nil {
+		return nil
+	}
+	est := a.estimateCost(msg, modelName, messages)
+	if !a.costGate(msg, est) {
+		return nil
+	}
+	a.logger.With("name", "【智能体】").Info("本轮预计用量超过阈值，等待用户确认",
+		"session_id", msg.SessionID,
+		"model", modelName,
+		"estimated_tokens", est.Tokens(),
+		"estimated_cost", est.Cost)
+	return &CostHoldError{Estimate: est}
+}
+
+// estimateCost 估计本轮的输入输出 token 数和费用。
+// 上下文按模型长度封顶；提供工具时按 costToolRounds 估计额外的调用次数，每次都重新发送上下文。
+func (a *ReActAgent) estimateCost(msg bus.InboundMessage, modelName string, messages []providers.ChatMessage) CostEstimate {
+	est := CostEstimate{Model: modelName}
+
+	prompt := 0
+	for _, m := range messages {
+		prompt += estimateMessage(m)
+	}
+	if a.tools != nil && supportsTools(modelName) {
+		defs := a.tools.ToProviderDefsFor(a.toolPolicy(msg))
+		est.Tools = len(defs)
+		prompt += estimateToolDefs(a.convertToolDefinitions(defs))
+	}
+
+	info := providers.GetModelInfo(modelName)
+	reply := costReplyTokens
+	if info != nil && info.ContextWindow > 0 {
+		prompt = min(prompt, info.ContextWindow-outputReserve(info))
+		reply = min(reply, outputReserve(info))
+	}
+
+	rounds := 1
+	if est.Tools > 0 {
+		rounds += min(max(a.costToolRounds, 0), max(a.maxToolIterations-1, 0))
+	}
+	est.InputTokens = prompt * rounds
+	est.OutputTokens = reply * rounds
+	if info != nil {
+		est.Cost = (float64(est.InputTokens)*info.InputPrice + float64(est.OutputTokens)*info.OutputPrice) / 1_000_000
+	}
+	return est
+}
@@ -0,0 +1,86 @@
+package react
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+)
+
+func TestEstimateCost(t *testing.T) {
+	messages := []providers.ChatMessage{
+		{Role: consts.RoleSystem.ToString(), Content: strings.Repeat("system prompt ", 200)},
+		{Role: consts.RoleUser.ToString(), Content: "hi"},
+	}
+	prompt := 0
+	for _, m := range messages {
+		prompt += estimateMessage(m)
+	}
+
+	// 不提供工具时只估计一次调用
+	plain := &ReActAgent{logger: slog.Default(), maxToolIterations: 10, costToolRounds: 2}
+	est := plain.estimateCost(bus.InboundMessage{}, "gpt-4o", messages)
+	if est.InputTokens != prompt || est.OutputTokens != costReplyTokens || est.Tools != 0 {
+		t.Errorf("estimateCost() = %+v, want input %d, output %d", est, prompt, costReplyTokens)
+	}
+	wantCost := (float64(prompt)*2.5 + float64(costReplyTokens)*10) / 1_000_000
+	if math.Abs(est.Cost-wantCost) > 1e-9 {
+		t.Errorf("Cost = %v, want %v", est.Cost, wantCost)
+	}
+
+	// 提供工具时按额外的调用次数重复计算上下文
+	registry := tools.NewRegistry()
+	registry.Register(&slowTool{})
+	withTools := &ReActAgent{tools: registry, logger: slog.Default(), maxToolIterations: 10, costToolRounds: 2}
+	est = withTools.estimateCost(bus.InboundMessage{}, "gpt-4o", messages)
+	if est.Tools != 1 || est.InputTokens <= 3*prompt || est.OutputTokens != 3*costReplyTokens {
+		t.Errorf("estimateCost(tools) = %+v, want 3 rounds over %d tokens", est, prompt)
+	}
+
+	// 价格未知的模型不估计费用
+	if est := plain.estimateCost(bus.InboundMessage{}, "unknown-model", messages); est.Cost != 0 || est.Tokens() == 0 {
+		t.Errorf("estimateCost(unknown) = %+v", est)
+	}
+}
+
+func TestPreviewCost(t *testing.T) {
+	messages := []providers.ChatMessage{{Role: consts.RoleUser.ToString(), Content: "hi"}}
+
+	agent := &ReActAgent{logger: slog.Default(), maxToolIterations: 10}
+	if err := agent.previewCost(bus.InboundMessage{}, "gpt-4o", messages); err != nil {
+		t.Fatalf("未启用时不应预估, got %v", err)
+	}
+
+	var seen CostEstimate
+	WithCostPreview(func(msg bus.InboundMessage, est CostEstimate) bool {
+		seen = est
+		return est.Tokens() >= 1000
+	}, 2)(agent)
+
+	err := agent.previewCost(bus.InboundMessage{}, "gpt-4o", messages)
+	var hold *CostHoldError
+	if !errors.As(err, &hold) {
+		t.Fatalf("previewCost() error = %v, want *CostHoldError", err)
+	}
+	if hold.Estimate != seen || hold.Estimate.Model != "gpt-4o" {
+		t.Errorf("Estimate = %+v, want %+v", hold.Estimate, seen)
+	}
+	if !strings.Contains(hold.Estimate.Text(), "tokens ≈ $") {
+		t.Errorf("Text() = %q", hold.Estimate.Text())
+	}
+}
+
+func TestFormatTokens(t *testing.T) {
+	tests := map[int]string{950: "950", 12400: "12k", 30000: "30k", 1_500_000: "1.5M"}
+	for n, want := range tests {
+		if got := FormatTokens(n); got != want {
+			t.Errorf("FormatTokens(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package react
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+)
+
+// WithToolRepeatLimit 启用本轮工具调用去重：相同工具和参数的只读调用直接返回缓存结果，
+// 同一调用被重复 limit 次后向模型注入纠正提示，0 表示不去重。失败的调用不缓存，
+// 修改状态的调用执行后清空缓存。
+func WithToolRepeatLimit(limit int) Option {
+	return func(a *ReActAgent) {
+		a.toolRepeatLimit = limit
+	}
+}
+
+// cachedToolCall 本轮已执行的一次工具调用。
+type cachedToolCall struct {
+	name      string
+	result    string
+	repeats   int  // 被重复调用的次数
+	corrected bool // 是否已注入纠正提示
+}
+
+// toolCallCache 一轮对话内的工具调用结果缓存，nil 表示不去重。
+type toolCallCache struct {
+	limit int
+	calls map[string]*cachedToolCall
+	order []string // 按首次执行顺序记录，纠正提示顺序稳定
+}
+
+// newToolCallCache 创建本轮的工具调用缓存。
+func (a *ReActAgent) newToolCallCache() *toolCallCache {
+	if a.toolRepeatLimit <= 0 {
+		return nil
+	}
+	return &toolCallCache{limit: a.toolRepeatLimit, calls: make(map[string]*cachedToolCall)}
+}
+
+// toolCallKey 工具名称加规范化后的参数，参数顺序和空白不同视为同一调用。
+func toolCallKey(tc providers.ToolCall) string {
+	args := strings.TrimSpace(tc.Function.Arguments)
+	var v any
+	if args != "" && json.Unmarshal([]byte(args), &v) == nil {
+		// encoding/json 序列化 map 时按键排序
+		if data, err := json.Marshal(v); err == nil {
+			args = string(data)
+		}
+	}
+	return tc.Function.Name + "\x00" + args
+}
+
+// lookup 查找相同的已执行调用，命中时累计重复次数并返回附带说明的缓存结果。
+func (c *toolCallCache) lookup(tc providers.ToolCall) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	call, ok := c.calls[toolCallKey(tc)]
+	if !ok {
+		return "", false
+	}
+	call.repeats++
+	return fmt.Sprintf("[重复调用] 本轮已使用相同参数调用过 %s，以下是之前的结果，无需再次调用：\n%s",
+		call.name, call.result), true
+}
+
+// stateChangingTools 没有实现 tools.Mutator 但可能修改状态的内置工具，执行后同样清空缓存。
+var stateChangingTools = map[string]bool{
+	"kv_set":        true,
+	"kv_delete":     true,
+	"session_vars":  true,
+	"scheduler":     true,
+	"skill_install": true,
+	"user_timezone": true,
+	"start_form":    true,
+}
+
+// mutates 判断调用是否可能修改工作目录或其他状态。这类调用不使用也不写入缓存，
+// 执行后清空缓存，之后读取文件、运行测试等调用会重新执行而不是回放过期的结果。
+func (a *ReActAgent) mutates(ctx context.Context, tc providers.ToolCall) bool {
+	if stateChangingTools[tc.Function.Name] {
+		return true
+	}
+	if a.tools == nil {
+		return false
+	}
+	tool, ok := a.tools.GetOK(tc.Function.Name)
+	if !ok {
+		return false
+	}
+	m, ok := tool.(tools.Mutator)
+	if !ok {
+		return false
+	}
+	var args map[string]any
+	if s := strings.TrimSpace(tc.Function.Arguments); s != "" && json.Unmarshal([]byte(s), &args) != nil {
+		// 无法判断时按修改处理
+		return true
+	}
+	_, mutates := m.DescribeChange(ctx, args)
+	return mutates
+}
+
+// invalidate 清空已缓存的结果和重复计数。
+func (c *toolCallCache) invalidate() {
+	if c == nil {
+		return
+	}
+	clear(c.calls)
+	c.order = c.order[:0]
+}
+
+// store 记录已执行调用的结果。
+func (c *toolCallCache) store(tc providers.ToolCall, result string) {
+	if c == nil {
+		return
+	}
+	key := toolCallKey(tc)
+	if _, ok := c.calls[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.calls[key] = &cachedToolCall{name: tc.Function.Name, result: result}
+}
+
+// correction 有调用重复次数达到上限时返回纠正提示，每个调用只提示一次。
+func (c *toolCallCache) correction() (providers.ChatMessage, bool) {
+	if c == nil {
+		return providers.ChatMessage{}, false
+	}
+
+	var names []string
+	for _, key := range c.order {
+		call := c.calls[key]
+		if call.repeats >= c.limit && !call.corrected {
+			call.corrected = true
+			names = append(names, call.name)
+		}
+	}
+	if len(names) == 0 {
+		return providers.ChatMessage{}, false
+	}
+	return providers.ChatMessage{
+		Role: consts.RoleSystem.ToString(),
+		Content: fmt.Sprintf("你已多次使用完全相同的参数调用 %s，结果不会改变。"+
+			"请不要再重复这些调用：换用不同的参数或工具，或者根据已有结果直接答复用户。", strings.Join(names, "、")),
+	}, true
+}
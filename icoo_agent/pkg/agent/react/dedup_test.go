@@ -0,0 +1,147 @@
+package react
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/file"
+)
+
+// countTool 记录执行次数。
+type countTool struct{ calls int }
+
+func (t *countTool) Name() string               { return "search" }
+func (t *countTool) Description() string        { return "search tool" }
+func (t *countTool) Parameters() map[string]any { return map[string]any{} }
+func (t *countTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	t.calls++
+	return tools.SuccessResult("3 results")
+}
+
+// flakyTool 第一次执行失败，之后成功。
+type flakyTool struct{ calls int }
+
+func (t *flakyTool) Name() string               { return "flaky" }
+func (t *flakyTool) Description() string        { return "flaky tool" }
+func (t *flakyTool) Parameters() map[string]any { return map[string]any{} }
+func (t *flakyTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	t.calls++
+	if t.calls == 1 {
+		return tools.ErrorResult("temporary failure")
+	}
+	return tools.SuccessResult("ok")
+}
+
+func TestToolCallKey(t *testing.T) {
+	a := toolCall("1", "search", `{"q": "go", "limit": 5}`)
+	b := toolCall("2", "search", `{"limit":5,"q":"go"}`)
+	c := toolCall("3", "search", `{"q":"rust","limit":5}`)
+	if toolCallKey(a) != toolCallKey(b) {
+		t.Error("参数顺序和空白不同应视为相同调用")
+	}
+	if toolCallKey(a) == toolCallKey(c) {
+		t.Error("参数不同应视为不同调用")
+	}
+}
+
+func TestRunLLM_ToolCallDedup(t *testing.T) {
+	tool := &countTool{}
+	registry := tools.NewRegistry()
+	registry.Register(tool)
+
+	repeat := func(id string) *providers.ChatResponse {
+		return &providers.ChatResponse{ToolCalls: []providers.ToolCall{toolCall(id, "search", `{"q":"go"}`)}}
+	}
+	provider := &scriptedProvider{responses: []*providers.ChatResponse{
+		repeat("c1"), repeat("c2"), repeat("c3"), {Content: "answer"},
+	}}
+	agent := &ReActAgent{
+		tools:             registry,
+		logger:            slog.Default(),
+		maxToolIterations: 10,
+		toolRepeatLimit:   2,
+	}
+
+	content, _, err := agent.RunLLM(context.Background(), "m", provider,
+		[]providers.ChatMessage{{Role: consts.RoleUser.ToString(), Content: "hi"}}, bus.InboundMessage{})
+	if err != nil || content != "answer" {
+		t.Fatalf("RunLLM = %q, %v", content, err)
+	}
+	if tool.calls != 1 {
+		t.Errorf("相同调用只应执行一次, calls = %d", tool.calls)
+	}
+
+	// 第二次请求中 c2 的结果是缓存回放
+	second := provider.requests[2].Messages
+	if replay := second[len(second)-1]; replay.ToolCallID != "c2" || !strings.HasPrefix(replay.Content, "[重复调用]") ||
+		!strings.Contains(replay.Content, "3 results") {
+		t.Errorf("重复调用应回放缓存结果, got %+v", replay)
+	}
+
+	// 重复两次后追加纠正提示，且只追加一次
+	final := provider.requests[3].Messages
+	var notes int
+	for _, m := range final {
+		if m.Role == consts.RoleSystem.ToString() && strings.Contains(m.Content, "完全相同的参数调用 search") {
+			notes++
+		}
+	}
+	if notes != 1 {
+		t.Errorf("纠正提示应出现一次, got %d", notes)
+	}
+	if last := final[len(final)-1]; last.Role != consts.RoleSystem.ToString() {
+		t.Errorf("纠正提示应紧跟在工具结果之后, got %+v", last)
+	}
+}
+
+func TestRunLLM_ToolCallDedupInvalidation(t *testing.T) {
+	workDir := t.TempDir()
+	flaky := &flakyTool{}
+	registry := tools.NewRegistry()
+	registry.Register(file.NewReadFileTool(workDir))
+	registry.Register(file.NewWriteFileTool(workDir))
+	registry.Register(flaky)
+
+	call := func(id, name, args string) *providers.ChatResponse {
+		return &providers.ChatResponse{ToolCalls: []providers.ToolCall{toolCall(id, name, args)}}
+	}
+	provider := &scriptedProvider{responses: []*providers.ChatResponse{
+		call("w1", "write_file", `{"path":"a.txt","content":"old"}`),
+		call("r1", "read_file", `{"path":"a.txt"}`),
+		call("w2", "write_file", `{"path":"a.txt","content":"new"}`),
+		call("r2", "read_file", `{"path":"a.txt"}`),
+		call("f1", "flaky", `{}`),
+		call("f2", "flaky", `{}`),
+		{Content: "answer"},
+	}}
+	agent := &ReActAgent{
+		tools:             registry,
+		logger:            slog.Default(),
+		maxToolIterations: 10,
+		toolRepeatLimit:   2,
+	}
+
+	if _, _, err := agent.RunLLM(context.Background(), "m", provider,
+		[]providers.ChatMessage{{Role: consts.RoleUser.ToString(), Content: "hi"}}, bus.InboundMessage{}); err != nil {
+		t.Fatalf("RunLLM error = %v", err)
+	}
+	result := func(req int) string {
+		msgs := provider.requests[req].Messages
+		return msgs[len(msgs)-1].Content
+	}
+
+	// 写入后再次读取同一文件应重新执行，而不是回放写入前的结果
+	if got := result(4); strings.HasPrefix(got, "[重复调用]") || !strings.Contains(got, "new") {
+		t.Errorf("写入后的 read_file = %q, want fresh content", got)
+	}
+	// 失败的调用不缓存，可以重试
+	if got := result(6); flaky.calls != 2 || got != "ok" {
+		t.Errorf("重试失败的调用 = %q, calls = %d", got, flaky.calls)
+	}
+}
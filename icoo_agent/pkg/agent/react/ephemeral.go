@@ -0,0 +1,42 @@
+package react
+
+import (
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/ephemeral"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/workspace"
+)
+
+// ephemeralWorkspaceName 无痕会话临时工作目录在提示词中的名称
+const ephemeralWorkspaceName = "incognito"
+
+// WithEphemeral 设置无痕会话注册表，deny 为无痕会话中禁用的工具。
+// 无痕会话的工具在临时工作目录中执行，不记录对话轨迹，也不持久化识别出的语言。
+func WithEphemeral(r *ephemeral.Registry, deny []string) Option {
+	return func(a *ReActAgent) {
+		a.ephemeral = r
+		a.ephemeralDeny = deny
+	}
+}
+
+// ephemeralSession 返回消息所属的无痕会话，普通会话返回 nil。
+func (a *ReActAgent) ephemeralSession(msg bus.InboundMessage) *ephemeral.Session {
+	return a.ephemeral.Get(msg.Channel, msg.SessionID)
+}
+
+// ephemeralWorkspace 无痕会话使用的临时工作目录。
+func ephemeralWorkspace(s *ephemeral.Session) workspace.Entry {
+	return workspace.Entry{Name: ephemeralWorkspaceName, Dir: s.Dir}
+}
+
+// ephemeralPolicy 在会话工具策略上追加无痕会话禁用的工具。
+func (a *ReActAgent) ephemeralPolicy(policy tools.Policy) tools.Policy {
+	policy.Deny = append(append([]string(nil), policy.Deny...), a.ephemeralDeny...)
+	return policy
+}
+
+// buildEphemeral 说明当前为无痕会话，模型不应承诺记住本次对话的内容。
+func buildEphemeral() string {
+	return "\n\n## 无痕模式\n当前会话为无痕会话：对话、工具结果和文件只保存在内存和临时工作目录中，会话关闭后全部清除，" +
+		"不会写入记忆。需要保存的内容请提醒用户自行保存，不要承诺以后还能记得。\n"
+}
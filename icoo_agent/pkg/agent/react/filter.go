@@ -0,0 +1,36 @@
+package react
+
+import (
+	"errors"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+)
+
+// safetyRetryPrompt 回复被提供商内容安全策略拦截后追加的系统消息
+const safetyRetryPrompt = "上一次回复被模型提供商的内容安全策略拦截，没有送达用户。" +
+	"请在遵守安全策略的前提下重新组织回答：只提供安全、合规的信息，避免描述有害细节；" +
+	"如果请求本身无法安全地回答，请简要、礼貌地说明无法协助的原因，并在可能时给出替代建议。"
+
+// retryFiltered 判断被内容安全策略拦截的请求是否重试：每轮只重试一次，
+// 返回追加了安全提示的消息列表。已重试过或不是拦截错误时返回 false，由调用方按失败处理。
+func (a *ReActAgent) retryFiltered(msg bus.InboundMessage, err error, retried *bool, messages []providers.ChatMessage) ([]providers.ChatMessage, bool) {
+	var filtered *providers.ContentFilterError
+	if !errors.As(err, &filtered) {
+		return messages, false
+	}
+	if *retried {
+		a.logger.With("name", "【智能体】").Warn("重试后回复仍被内容安全策略拦截",
+			"session_id", msg.SessionID, "provider", filtered.Provider, "reason", filtered.Reason)
+		return messages, false
+	}
+	*retried = true
+
+	a.logger.With("name", "【智能体】").Warn("回复被内容安全策略拦截，调整提示后重试",
+		"session_id", msg.SessionID, "provider", filtered.Provider, "reason", filtered.Reason)
+	return append(messages, providers.ChatMessage{
+		Role:    consts.RoleSystem.ToString(),
+		Content: safetyRetryPrompt,
+	}), true
+}
@@ -0,0 +1,52 @@
+package react
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+)
+
+// filteringProvider 前 blocked 次请求被内容安全策略拦截，之后正常回复。
+type filteringProvider struct {
+	mockProvider
+	blocked  int
+	requests []providers.ChatRequest
+}
+
+func (p *filteringProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.requests = append(p.requests, req)
+	if len(p.requests) <= p.blocked {
+		return nil, &providers.ContentFilterError{Provider: "test", Reason: "content_filter"}
+	}
+	return &providers.ChatResponse{Content: "safe answer"}, nil
+}
+
+func TestRunLLM_ContentFilterRetry(t *testing.T) {
+	agent := &ReActAgent{tools: tools.NewRegistry(), logger: slog.Default(), maxToolIterations: 10}
+	messages := []providers.ChatMessage{{Role: consts.RoleUser.ToString(), Content: "hi"}}
+
+	provider := &filteringProvider{blocked: 1}
+	content, _, err := agent.RunLLM(context.Background(), "m", provider, messages, bus.InboundMessage{})
+	if err != nil || content != "safe answer" {
+		t.Fatalf("RunLLM() = %q, %v", content, err)
+	}
+	retry := provider.requests[1].Messages
+	if last := retry[len(retry)-1]; last.Role != consts.RoleSystem.ToString() || last.Content != safetyRetryPrompt {
+		t.Errorf("重试请求应追加安全提示, got %+v", last)
+	}
+
+	// 只重试一次，仍被拦截时返回可识别的错误
+	provider = &filteringProvider{blocked: 2}
+	_, _, err = agent.RunLLM(context.Background(), "m", provider, messages, bus.InboundMessage{})
+	if !providers.IsContentFiltered(err) {
+		t.Errorf("err = %v, want content filter error", err)
+	}
+	if len(provider.requests) != 2 {
+		t.Errorf("requests = %d, want 2", len(provider.requests))
+	}
+}
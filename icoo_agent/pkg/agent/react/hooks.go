@@ -2,7 +2,10 @@ package react
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
 	"icooclaw/pkg/providers"
 	"icooclaw/pkg/storage"
 	"icooclaw/pkg/tools"
@@ -32,8 +35,10 @@ type MessageHooks interface {
 type LLMHooks interface {
 	// RunLLMBefore 运行LLM模型前钩子
 	OnRunLLMBefore(ctx context.Context, msg bus.InboundMessage, history []providers.ChatMessage) ([]providers.ChatMessage, error)
-	// RunLLMAfter 运行LLM模型后钩子
+	// RunLLMAfter 运行LLM模型后钩子，history 的最后一条为最终回复，钩子可以改写其内容
 	OnRunLLMAfter(ctx context.Context, msg bus.InboundMessage, history []providers.ChatMessage) ([]providers.ChatMessage, error)
+	// LLMResponse 每次收到模型响应后钩子，可以改写内容和工具调用，返回错误时结束本轮
+	OnLLMResponse(ctx context.Context, msg bus.InboundMessage, resp *providers.ChatResponse) error
 }
 
 // ToolHooks 工具钩子
@@ -45,3 +50,87 @@ type ToolHooks interface {
 	// ToolParseArguments 工具参数解析钩子
 	OnToolParseArguments(ctx context.Context, toolName string, tc providers.ToolCall, msg bus.InboundMessage) (map[string]any, error)
 }
+
+// BaseHooks 保持默认行为的钩子实现，嵌入后只需实现关心的钩子。
+type BaseHooks struct{}
+
+// OnGetProvider 不指定提供商，使用默认提供商。
+func (BaseHooks) OnGetProvider(ctx context.Context, defaultModel string, storage *storage.ProviderStorage) (providers.Provider, string, error) {
+	return nil, "", nil
+}
+
+// OnCreateAgent 原样返回智能体。
+func (BaseHooks) OnCreateAgent(ctx context.Context, a *ReActAgent) (*ReActAgent, error) {
+	return a, nil
+}
+
+// OnBuildMessagesBefore 原样返回消息列表。
+func (BaseHooks) OnBuildMessagesBefore(ctx context.Context, sessionKey string, msg bus.InboundMessage, history []providers.ChatMessage) ([]providers.ChatMessage, error) {
+	return history, nil
+}
+
+// OnBuildMessagesAfter 原样返回消息列表。
+func (BaseHooks) OnBuildMessagesAfter(ctx context.Context, sessionKey string, msg bus.InboundMessage, history []providers.ChatMessage) ([]providers.ChatMessage, error) {
+	return history, nil
+}
+
+// OnRunLLMBefore 原样返回消息列表。
+func (BaseHooks) OnRunLLMBefore(ctx context.Context, msg bus.InboundMessage, history []providers.ChatMessage) ([]providers.ChatMessage, error) {
+	return history, nil
+}
+
+// OnRunLLMAfter 原样返回消息列表。
+func (BaseHooks) OnRunLLMAfter(ctx context.Context, msg bus.InboundMessage, history []providers.ChatMessage) ([]providers.ChatMessage, error) {
+	return history, nil
+}
+
+// OnLLMResponse 不修改响应。
+func (BaseHooks) OnLLMResponse(ctx context.Context, msg bus.InboundMessage, resp *providers.ChatResponse) error {
+	return nil
+}
+
+// OnToolCallBefore 原样返回工具调用。
+func (BaseHooks) OnToolCallBefore(ctx context.Context, toolName string, tc providers.ToolCall, msg bus.InboundMessage) (providers.ToolCall, error) {
+	return tc, nil
+}
+
+// OnToolCallAfter 不修改工具结果。
+func (BaseHooks) OnToolCallAfter(ctx context.Context, toolName string, msg bus.InboundMessage, result *tools.Result) error {
+	return nil
+}
+
+// OnToolParseArguments 按 JSON 解析工具参数。
+func (BaseHooks) OnToolParseArguments(ctx context.Context, toolName string, tc providers.ToolCall, msg bus.InboundMessage) (map[string]any, error) {
+	var args map[string]any
+	if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+		return nil, fmt.Errorf("解析参数失败: %w", err)
+	}
+	return args, nil
+}
+
+// onLLMResponse 调用模型响应钩子。
+func (a *ReActAgent) onLLMResponse(ctx context.Context, msg bus.InboundMessage, resp *providers.ChatResponse) error {
+	if a.hooks == nil {
+		return nil
+	}
+	return a.hooks.OnLLMResponse(ctx, msg, resp)
+}
+
+// complete 调用运行LLM模型后钩子，返回钩子改写后的最终回复。
+func (a *ReActAgent) complete(ctx context.Context, msg bus.InboundMessage, messages []providers.ChatMessage, content string) (string, error) {
+	if a.hooks == nil {
+		return content, nil
+	}
+	history := append(messages[:len(messages):len(messages)], providers.ChatMessage{
+		Role:    consts.RoleAssistant.ToString(),
+		Content: content,
+	})
+	history, err := a.hooks.OnRunLLMAfter(ctx, msg, history)
+	if err != nil {
+		return "", err
+	}
+	if n := len(history); n > 0 && history[n-1].Role == consts.RoleAssistant.ToString() {
+		return history[n-1].Content, nil
+	}
+	return content, nil
+}
@@ -5,26 +5,41 @@ import (
 	"encoding/json"
 	"fmt"
 	"icooclaw/pkg/bus"
+	"icooclaw/pkg/clock"
 	"icooclaw/pkg/consts"
+	"icooclaw/pkg/ephemeral"
+	"icooclaw/pkg/language"
 	"icooclaw/pkg/memory"
+	"icooclaw/pkg/persona"
 	"icooclaw/pkg/providers"
 	"icooclaw/pkg/skill"
 	"icooclaw/pkg/storage"
 	"icooclaw/pkg/tools"
 	"icooclaw/pkg/utils"
+	"icooclaw/pkg/workspace"
 	"log/slog"
 	"strings"
+	"time"
 )
 
 // StreamChunk 表示流式响应的一个数据块。
 type StreamChunk struct {
-	Content    string `json:"content,omitempty"`     // 内容
-	Reasoning  string `json:"reasoning,omitempty"`   // 推理过程
-	ToolName   string `json:"tool_name,omitempty"`   // 工具名称
-	ToolResult string `json:"tool_result,omitempty"` // 工具结果
-	Iteration  int    `json:"iteration,omitempty"`   // 迭代次数
-	Done       bool   `json:"done,omitempty"`        // 是否完成
-	Error      error  `json:"error,omitempty"`       // 错误信息
+	Content    string `json:"content,omitempty"`      // 内容
+	Reasoning  string `json:"reasoning,omitempty"`    // 推理过程
+	ToolCallID string `json:"tool_call_id,omitempty"` // 工具调用ID
+	ToolName   string `json:"tool_name,omitempty"`    // 工具名称
+	ToolArgs   string `json:"tool_args,omitempty"`    // 工具参数（JSON）
+	ToolResult string `json:"tool_result,omitempty"`  // 工具结果
+	ToolOutput string `json:"tool_output,omitempty"`  // 工具执行中的实时输出片段
+	ToolStream string `json:"tool_stream,omitempty"`  // 实时输出所属的流，stdout 或 stderr
+	Iteration  int    `json:"iteration,omitempty"`    // 迭代次数
+	Start      bool   `json:"start,omitempty"`        // 新一轮迭代开始，请求模型之前下发
+	WrapUp     bool   `json:"wrap_up,omitempty"`      // 本轮迭代因时间预算用尽而收尾，不再提供工具
+	Done       bool   `json:"done,omitempty"`         // 是否完成
+	Error      error  `json:"error,omitempty"`        // 错误信息
+
+	// Stats 本轮对话的统计，随完成块下发
+	Stats *TurnStats `json:"stats,omitempty"`
 }
 
 // StreamCallback 流式响应的回调函数。
@@ -39,9 +54,37 @@ type ReActAgent struct {
 	providerFactory *providers.Factory // 提供商工厂
 	logger          *slog.Logger       // 日志记录器
 	hooks           ReactHooks         // React钩子接口
+	personas        *persona.Manager   // 人设管理器
+	workspaces      *workspace.Manager // 工作目录管理器
+	timezones       *clock.Manager     // 用户时区管理器
+	languages       *language.Manager  // 回复语言管理器
 
 	// Configuration 配置项
-	maxToolIterations int // 最大工具迭代次数
+	maxToolIterations int           // 最大工具迭代次数
+	turnBudget        time.Duration // 单轮对话时间预算
+	toolRepeatLimit   int           // 相同工具调用重复多少次后注入纠正提示，0 表示不去重
+	traceKeep         int           // 每个会话保留的对话轨迹条数，0 表示不记录
+	traceResultChars  int           // 轨迹中工具结果的截断长度
+
+	statusFn       StatusFunc      // 工具执行状态回调
+	statusInterval time.Duration   // 状态上报间隔
+	toolNotes      bool            // 是否在提示词中注入工具使用提示
+	prompt         *PromptSections // 系统提示词自动生成的片段，nil 表示使用默认设置
+
+	memoryScore memory.ScoreConfig // 记忆评分参数
+	recallLimit int                // 注入提示词的相关记忆条数，0 表示不注入
+	semantic    memory.Semantic    // 语义检索参数，Model 为空表示只按关键词检索
+	semanticTop int                // 语义检索注入提示词的相关记忆条数
+
+	entityLimit int // 注入提示词的相关实体个数，0 表示不注入
+
+	condense *ToolCondense // 冗长工具结果的压缩配置，nil 表示不压缩
+
+	ephemeral     *ephemeral.Registry // 无痕会话注册表
+	ephemeralDeny []string            // 无痕会话中禁用的工具
+
+	costGate       CostGate // 预计用量确认，nil 表示不预估
+	costToolRounds int      // 提供工具时额外估计的模型调用次数
 }
 
 type Option func(*ReActAgent)
@@ -82,12 +125,56 @@ func WithStorage(s *storage.Storage) Option {
 	}
 }
 
+func WithPersonas(p *persona.Manager) Option {
+	return func(a *ReActAgent) {
+		a.personas = p
+	}
+}
+
+// WithLogger 设置日志记录器。
+func WithLogger(l *slog.Logger) Option {
+	return func(a *ReActAgent) {
+		a.logger = l
+	}
+}
+
 func WithMaxToolIterations(max int) Option {
 	return func(a *ReActAgent) {
 		a.maxToolIterations = max
 	}
 }
 
+// WithToolNotes 根据工具近期失败情况在系统提示词中注入工具使用提示。
+func WithToolNotes(enabled bool) Option {
+	return func(a *ReActAgent) {
+		a.toolNotes = enabled
+	}
+}
+
+// WithMemoryRecall 按 评分×相关度 检索与用户消息相关的记忆并注入系统提示词。
+func WithMemoryRecall(score memory.ScoreConfig, limit int) Option {
+	return func(a *ReActAgent) {
+		a.memoryScore = score
+		a.recallLimit = limit
+	}
+}
+
+// WithSemanticRecall 按用户消息与记忆向量的余弦相似度检索相关记忆，sem.Model 为空时不启用。
+// 嵌入接口在每次检索时由提供商工厂创建，不可用时退回关键词检索。
+func WithSemanticRecall(sem memory.Semantic, topK int) Option {
+	return func(a *ReActAgent) {
+		a.semantic = sem
+		a.semanticTop = topK
+	}
+}
+
+// WithEntityRecall 用户消息提到已知实体时，将实体的事实和关系注入系统提示词。
+func WithEntityRecall(limit int) Option {
+	return func(a *ReActAgent) {
+		a.entityLimit = limit
+	}
+}
+
 func NewReActAgent(ctx context.Context, hooks ReactHooks, opts ...Option) (*ReActAgent, error) {
 	a := &ReActAgent{hooks: hooks}
 	for _, opt := range opts {
@@ -119,10 +206,26 @@ func (a *ReActAgent) GetDynamicProvider(ctx context.Context) (providers.Provider
 		return nil, "", fmt.Errorf("默认模型未配置")
 	}
 
+	return a.resolveProvider(ctx, defaultModel.Value)
+}
+
+// providerFor 返回处理消息使用的提供商：人设指定了模型时使用人设的模型，否则使用默认模型。
+func (a *ReActAgent) providerFor(ctx context.Context, msg bus.InboundMessage) (providers.Provider, string, error) {
+	if p := a.persona(msg); p != nil && p.Model != "" {
+		if a.providerFactory == nil {
+			return nil, "", fmt.Errorf("未配置提供商工厂")
+		}
+		return a.resolveProvider(ctx, p.Model)
+	}
+	return a.GetDynamicProvider(ctx)
+}
+
+// resolveProvider 按“提供商/模型”获取提供商实例，钩子可替换提供商。
+func (a *ReActAgent) resolveProvider(ctx context.Context, model string) (providers.Provider, string, error) {
 	// 分割模型字符串
-	parts := utils.SplitProviderModel(defaultModel.Value)
+	parts := utils.SplitProviderModel(model)
 	if len(parts) != 2 {
-		return nil, "", fmt.Errorf("默认模型格式错误: %s", defaultModel.Value)
+		return nil, "", fmt.Errorf("模型格式错误: %s", model)
 	}
 
 	providerName, modelName := parts[0], parts[1]
@@ -133,12 +236,15 @@ func (a *ReActAgent) GetDynamicProvider(ctx context.Context) (providers.Provider
 		return nil, "", fmt.Errorf("获取Provider失败: %w", err)
 	}
 
-	// 调用钩子获取提供商实例
-	if a.hooks != nil {
-		provider, modelName, err = a.hooks.OnGetProvider(ctx, providerName, a.storage.Provider())
+	// 调用钩子获取提供商实例，钩子未指定提供商时使用默认提供商
+	if a.hooks != nil && a.storage != nil {
+		hooked, hookedModel, err := a.hooks.OnGetProvider(ctx, providerName, a.storage.Provider())
 		if err != nil {
 			return nil, "", err
 		}
+		if hooked != nil {
+			provider, modelName = hooked, hookedModel
+		}
 	}
 
 	// 返回提供商实例
@@ -167,6 +273,10 @@ func (a *ReActAgent) buildMessages(ctx context.Context, sessionKey string, msg b
 		return nil, err
 	}
 
+	// 提示词中的 {{key}} 替换为会话变量
+	vars := a.sessionVars(msg)
+	systemPrompt = vars.Interpolate(systemPrompt)
+
 	// 加载 SKILL 工具
 	skills, err := a.skills.List(ctx)
 	if err != nil {
@@ -182,6 +292,45 @@ func (a *ReActAgent) buildMessages(ctx context.Context, sessionKey string, msg b
 
 	systemPrompt += sb.String()
 
+	// 加载触发规则激活的技能
+	systemPrompt += a.buildActiveSkills(ctx, msg)
+
+	// 自动生成的运行时信息：时间、工作目录和可用工具
+	sections := a.promptSections()
+	if sections.DateTime {
+		systemPrompt += buildDateTime(time.Now(), a.sessionLocation(msg))
+	}
+	ws := a.sessionWorkspace(msg)
+	if a.ephemeralSession(msg) != nil {
+		systemPrompt += buildEphemeral()
+	}
+	systemPrompt += buildWorkspaceName(ws)
+	systemPrompt += buildWorkspaceLayout(ws.Dir, sections.WorkspaceEntries)
+	if sections.Tools {
+		systemPrompt += a.buildToolCategories(a.toolPolicy(msg))
+	}
+
+	// 加载工具使用提示
+	systemPrompt += a.buildToolNotes()
+
+	// 加载当前会话的人设，路由规则指定的人设优先
+	if sections.Persona {
+		if p := a.persona(msg); p != nil {
+			systemPrompt += vars.Interpolate(p.Prompt())
+		}
+	}
+
+	// 加载回复语言
+	if sections.Language {
+		systemPrompt += buildReplyLanguage(a.replyLanguage(msg))
+	}
+
+	// 加载会话变量
+	systemPrompt += buildSessionVars(vars)
+
+	// 加载会话摘要与记忆
+	systemPrompt += a.buildSessionContext(ctx, sessionKey, msg, sections.Memory)
+
 	messages = append(messages, providers.ChatMessage{
 		Role:    consts.RoleSystem.ToString(),
 		Content: systemPrompt,
@@ -197,6 +346,11 @@ func (a *ReActAgent) buildMessages(ctx context.Context, sessionKey string, msg b
 			history = mem
 		}
 	}
+	// 用户消息已写入历史时去掉历史中的这一条，避免重复
+	saved, _ := msg.Metadata[consts.META_HISTORY_SAVED].(bool)
+	if saved {
+		history = trimSaved(history, msg.Text)
+	}
 	messages = append(messages, history...)
 
 	// 4. Add user message 添加用户消息。
@@ -213,8 +367,8 @@ func (a *ReActAgent) buildMessages(ctx context.Context, sessionKey string, msg b
 		}
 	}
 
-	// 6. 保存用户消息到记忆历史记录。
-	if a.memory != nil {
+	// 6. 保存用户消息到记忆历史记录，离线重放的消息可能已经保存过。
+	if a.memory != nil && !saved {
 		err = a.memory.Save(ctx, sessionKey, consts.RoleUser.ToString(), msg.Text)
 		if err != nil {
 			return nil, err
@@ -224,6 +378,244 @@ func (a *ReActAgent) buildMessages(ctx context.Context, sessionKey string, msg b
 	return messages, nil
 }
 
+// buildActiveSkills 注入触发规则为本条消息激活的技能内容。
+func (a *ReActAgent) buildActiveSkills(ctx context.Context, msg bus.InboundMessage) string {
+	names := activeSkills(msg)
+	if len(names) == 0 {
+		return ""
+	}
+
+	sb := strings.Builder{}
+	for _, name := range names {
+		info, err := a.skills.LoadInfo(ctx, name)
+		if err != nil {
+			a.logger.With("name", "【智能体】").Warn("加载激活的技能失败", "error", err, "skill", name)
+			continue
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("\n\n## 已激活技能\n本条消息触发了以下技能，请按技能说明处理。\n")
+		}
+		sb.WriteString(fmt.Sprintf("\n### %s\n%s\n", name, info.Content))
+	}
+	return sb.String()
+}
+
+// activeSkills 返回消息激活的技能名称，离线重放的消息经过 JSON 编码后为 []any。
+func activeSkills(msg bus.InboundMessage) []string {
+	switch v := msg.Metadata[consts.META_SKILL].(type) {
+	case []string:
+		return v
+	case []any:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// buildSessionContext 构建会话摘要与置顶记忆，会话重置后依然保留的上下文。
+// withMemory 为 false 时只注入会话摘要，不注入记忆和实体。
+func (a *ReActAgent) buildSessionContext(ctx context.Context, sessionKey string, msg bus.InboundMessage, withMemory bool) string {
+	sb := strings.Builder{}
+
+	sess, err := a.storage.Session().GetBySessionID(msg.Channel, msg.SessionID)
+	if err == nil && sess.Summary != "" {
+		sb.WriteString("\n\n## 会话摘要\n")
+		sb.WriteString(sess.Summary)
+		sb.WriteString("\n")
+	}
+	if !withMemory {
+		return sb.String()
+	}
+
+	pinned, err := a.storage.Memory().ListPinned(sessionKey)
+	if err == nil && len(pinned) > 0 {
+		sb.WriteString("\n\n## 置顶记忆\n")
+		for _, m := range pinned {
+			sb.WriteString(fmt.Sprintf("- %s\n", m.Content))
+		}
+	}
+
+	if recalled := a.recallMemories(ctx, sessionKey, msg.Text); len(recalled) > 0 {
+		sb.WriteString("\n\n## 相关记忆\n")
+		for _, m := range recalled {
+			sb.WriteString(fmt.Sprintf("- %s\n", m.Content))
+		}
+	}
+
+	if graphs := a.recallEntities(msg.Text); len(graphs) > 0 {
+		sb.WriteString("\n\n## 相关实体\n")
+		for _, g := range graphs {
+			sb.WriteString(g.String())
+		}
+	}
+
+	return sb.String()
+}
+
+// entityFactLimit 每个实体注入提示词的最多事实条数
+const entityFactLimit = 10
+
+// recallEntities 查找用户消息提到的实体及其事实和关系。
+func (a *ReActAgent) recallEntities(text string) []*storage.EntityGraph {
+	if a.entityLimit <= 0 || text == "" {
+		return nil
+	}
+
+	entities, err := a.storage.Entity().Mentioned(text, a.entityLimit)
+	if err != nil {
+		a.logger.With("name", "【智能体】").Warn("检索相关实体失败", "error", err)
+		return nil
+	}
+
+	graphs := make([]*storage.EntityGraph, 0, len(entities))
+	for _, e := range entities {
+		g, err := a.storage.Entity().Graph(e, entityFactLimit)
+		if err != nil {
+			a.logger.With("name", "【智能体】").Warn("加载实体关系失败", "error", err, "entity", e.Name)
+			continue
+		}
+		graphs = append(graphs, g)
+	}
+	return graphs
+}
+
+// recallMinRelevance 注入提示词的记忆与用户消息的最低相关度
+const recallMinRelevance = 0.3
+
+// recallMemories 检索与用户消息相关的非置顶记忆，置顶记忆已全部注入。
+// 启用语义检索时优先按向量相似度检索，失败时退回关键词检索。
+func (a *ReActAgent) recallMemories(ctx context.Context, sessionKey, query string) []memory.Ranked {
+	if query == "" {
+		return nil
+	}
+
+	pinned := false
+	filter := storage.MemoryFilter{SessionID: sessionKey, Pinned: &pinned}
+	if a.semantic.Model != "" && a.semanticTop > 0 {
+		ranked, err := a.recallSemantic(ctx, filter, query)
+		if err == nil {
+			return ranked
+		}
+		a.logger.With("name", "【智能体】").Warn("语义检索记忆失败，退回关键词检索", "error", err, "session_key", sessionKey)
+	}
+	if a.recallLimit <= 0 {
+		return nil
+	}
+
+	ranked, err := a.memoryScore.Recall(a.storage, filter, query, recallMinRelevance, a.recallLimit)
+	if err != nil {
+		a.logger.With("name", "【智能体】").Warn("检索相关记忆失败", "error", err, "session_key", sessionKey)
+		return nil
+	}
+	return ranked
+}
+
+// recallSemantic 按向量相似度检索相关记忆。
+func (a *ReActAgent) recallSemantic(ctx context.Context, filter storage.MemoryFilter, query string) ([]memory.Ranked, error) {
+	if a.providerFactory == nil {
+		return nil, fmt.Errorf("未配置提供商工厂")
+	}
+	parts := utils.SplitProviderModel(a.semantic.Model)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("嵌入模型格式错误: %s", a.semantic.Model)
+	}
+	embedder, err := a.providerFactory.Embedder(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sem := a.semantic
+	sem.Embedder = embedder
+	return a.memoryScore.RecallSemantic(ctx, a.storage, sem, filter, query, recallMinRelevance, a.semanticTop)
+}
+
+// buildToolNotes 根据工具执行统计生成工具使用提示，避免模型反复调用已知故障的工具。
+func (a *ReActAgent) buildToolNotes() string {
+	if !a.toolNotes || a.tools == nil {
+		return ""
+	}
+
+	notes := a.tools.Stats().Notes()
+	if len(notes) == 0 {
+		return ""
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("\n\n## 工具使用提示\n")
+	for _, note := range notes {
+		sb.WriteString(note)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// toolPolicy 读取会话元数据中的工具策略，未设置或读取失败时不做限制，无痕会话追加禁用的工具。
+func (a *ReActAgent) toolPolicy(msg bus.InboundMessage) tools.Policy {
+	var policy tools.Policy
+	if a.storage != nil {
+		if _, err := a.storage.Session().GetMetadata(msg.Channel, msg.SessionID, tools.PolicyMetadataKey, &policy); err != nil {
+			a.logger.With("name", "【智能体】").Warn("读取会话工具策略失败", "error", err, "session_id", msg.SessionID)
+		}
+	}
+	if a.ephemeralSession(msg) != nil {
+		policy = a.ephemeralPolicy(policy)
+	}
+	// 人设限定的工具集，会话策略不能越过
+	if p := a.persona(msg); p != nil {
+		policy.Scope = p.AllowTools
+		policy.Deny = append(policy.Deny, p.DenyTools...)
+	}
+	return policy
+}
+
+// persona 返回处理消息的人设，路由规则或消息前缀指定的人设优先，未设置时返回 nil。
+func (a *ReActAgent) persona(msg bus.InboundMessage) *persona.Persona {
+	if a.personas == nil {
+		return nil
+	}
+	routed, _ := msg.Metadata[consts.META_PERSONA].(string)
+	return a.personas.Resolve(msg.Channel, msg.SessionID, routed)
+}
+
+// memoryKey 返回读写对话历史和记忆的键，独立记忆的人设与会话中的其他人设分开保存。
+func (a *ReActAgent) memoryKey(msg bus.InboundMessage) string {
+	sessionKey := consts.GetSessionKey(msg.Channel, msg.SessionID)
+	if p := a.persona(msg); p != nil {
+		return p.MemoryKey(sessionKey)
+	}
+	return sessionKey
+}
+
+// sessionVars 读取会话变量，未设置或读取失败时返回 nil。
+func (a *ReActAgent) sessionVars(msg bus.InboundMessage) tools.Vars {
+	if a.storage == nil {
+		return nil
+	}
+	var vars tools.Vars
+	if _, err := a.storage.Session().GetMetadata(msg.Channel, msg.SessionID, tools.VarsMetadataKey, &vars); err != nil {
+		a.logger.With("name", "【智能体】").Warn("读取会话变量失败", "error", err, "session_id", msg.SessionID)
+	}
+	return vars
+}
+
+// buildSessionVars 列出会话变量，模型可直接使用其中的默认值。
+func buildSessionVars(vars tools.Vars) string {
+	if len(vars) == 0 {
+		return ""
+	}
+	sb := strings.Builder{}
+	sb.WriteString("\n\n## 会话变量\n用户为当前会话设置的变量，未特别说明时作为默认值使用:\n")
+	for _, key := range vars.Keys() {
+		sb.WriteString(fmt.Sprintf("- %s = %s\n", key, vars[key]))
+	}
+	return sb.String()
+}
+
 // convertToolDefinitions 转换工具定义为提供商工具
 func (a *ReActAgent) convertToolDefinitions(defs []tools.ToolDefinition) []providers.Tool {
 	tools := make([]providers.Tool, 0, len(defs))
@@ -240,6 +632,49 @@ func (a *ReActAgent) convertToolDefinitions(defs []tools.ToolDefinition) []provi
 	return tools
 }
 
+// runToolCall 在本轮时间预算内执行一次工具调用，返回写入对话的工具结果。
+// 预算已用尽时不再执行，返回跳过说明，保证每个工具调用都有对应的结果消息；
+// 本轮已执行过的相同只读调用直接返回缓存结果，修改状态的调用执行后清空缓存，失败的调用不缓存；
+// 过长的结果按压缩配置压缩。
+func (a *ReActAgent) runToolCall(
+	ctx context.Context,
+	tc providers.ToolCall,
+	msg bus.InboundMessage,
+	iteration int,
+	status *statusTracker,
+	callback StreamCallback,
+	budget turnBudget,
+	calls *toolCallCache,
+) string {
+	mutates := calls != nil && a.mutates(ctx, tc)
+	if cached, ok := calls.lookup(tc); ok && !mutates {
+		a.logger.With("name", "【智能体】").Info("重复的工具调用，返回缓存结果",
+			"tool", tc.Function.Name,
+			"session_id", msg.SessionID)
+		return cached
+	}
+	if budget.exceeded() {
+		return toolSkippedByBudget
+	}
+
+	toolCtx, cancel := budget.toolContext(a.withToolOutput(ctx, tc, iteration, status, callback))
+	defer cancel()
+
+	result, err := a.executeToolCall(toolCtx, tc, msg)
+	if mutates {
+		// 失败的调用也可能已修改了部分状态
+		calls.invalidate()
+	}
+	if err != nil {
+		return fmt.Sprintf("错误: %v", err)
+	}
+	result = a.condenseToolResult(ctx, tc.Function.Name, msg, result)
+	if !mutates {
+		calls.store(tc, result)
+	}
+	return result
+}
+
 // executeToolCall 执行工具调用
 func (a *ReActAgent) executeToolCall(ctx context.Context, tc providers.ToolCall, msg bus.InboundMessage) (string, error) {
 	toolName := tc.Function.Name
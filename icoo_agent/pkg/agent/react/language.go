@@ -0,0 +1,49 @@
+package react
+
+import (
+	"fmt"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/language"
+)
+
+// WithLanguages 设置回复语言管理器，识别用户消息的语言并在提示词中约束回复语言。
+func WithLanguages(m *language.Manager) Option {
+	return func(a *ReActAgent) {
+		a.languages = m
+	}
+}
+
+// replyLanguage 记录本条消息的语言并返回会话的回复语言，未配置管理器时不约束。
+// 无痕会话只按本条消息识别，不写入会话元数据。
+func (a *ReActAgent) replyLanguage(msg bus.InboundMessage) (string, language.Source) {
+	if a.languages == nil {
+		return "", language.SourceDefault
+	}
+	if a.ephemeralSession(msg) == nil {
+		a.languages.Observe(msg.Channel, msg.SessionID, msg.Text)
+		return a.languages.Current(msg.Channel, msg.SessionID)
+	}
+	code, source := a.languages.Current(msg.Channel, msg.SessionID)
+	if source == language.SourceSession || source == language.SourceChannel {
+		return code, source
+	}
+	if detected := language.Detect(msg.Text); detected != "" {
+		return detected, language.SourceDetected
+	}
+	return code, source
+}
+
+// buildReplyLanguage 生成回复语言片段，放在人设之后，避免提示词本身的语言决定回复语言。
+func buildReplyLanguage(code string, source language.Source) string {
+	if code == "" {
+		return ""
+	}
+	name := language.Name(code)
+	if source == language.SourceSession || source == language.SourceChannel {
+		return fmt.Sprintf("\n\n## 回复语言\n用户设定的回复语言为 %s (%s)，无论用户用什么语言提问，都使用 %s 回复。代码、命令和专有名词保持原样。\n",
+			name, code, name)
+	}
+	return fmt.Sprintf("\n\n## 回复语言\n用户使用 %s (%s)，请使用 %s 回复，不要因为提示词或资料的语言而改变。代码、命令和专有名词保持原样；用户明确要求其他语言时以用户要求为准。\n",
+		name, code, name)
+}
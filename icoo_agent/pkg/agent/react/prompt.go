@@ -0,0 +1,161 @@
+package react
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/clock"
+	"icooclaw/pkg/tools"
+)
+
+// PromptSections 系统提示词中自动生成的片段，每项可单独开关。
+// 这些事实由运行时生成，避免在静态提示词里手工维护而过时或互相矛盾。
+type PromptSections struct {
+	DateTime         bool           // 当前日期、时间和时区
+	Location         *time.Location // 默认时区，nil 表示本地时区；配置了时区管理器时使用会话的用户时区
+	WorkspaceEntries int            // 工作目录顶层条目的最多列出数量，0 表示不注入
+	Persona          bool           // 当前人设
+	Language         bool           // 回复语言
+	Tools            bool           // 按类别列出当前会话可用的工具
+	Memory           bool           // 置顶记忆、相关记忆和相关实体
+}
+
+// DefaultPromptSections 默认启用全部片段。
+func DefaultPromptSections() PromptSections {
+	return PromptSections{
+		DateTime:         true,
+		WorkspaceEntries: 30,
+		Persona:          true,
+		Language:         true,
+		Tools:            true,
+		Memory:           true,
+	}
+}
+
+// WithPromptSections 设置系统提示词中自动生成的片段。
+func WithPromptSections(s PromptSections) Option {
+	return func(a *ReActAgent) {
+		a.prompt = &s
+	}
+}
+
+// promptSections 未设置时使用默认片段。
+func (a *ReActAgent) promptSections() PromptSections {
+	if a.prompt == nil {
+		return DefaultPromptSections()
+	}
+	return *a.prompt
+}
+
+// buildDateTime 生成当前日期时间片段，时间按用户时区展示。
+func buildDateTime(now time.Time, loc *time.Location) string {
+	return fmt.Sprintf("\n\n## 当前时间\n%s\n用户所说的时间都按该时区理解，不确定的相对时间（如\"下周二下午3点\"）先用 datetime 工具解析。\n",
+		clock.Format(now, loc))
+}
+
+// buildWorkspaceLayout 列出工作目录顶层的目录和文件，目录在前，隐藏条目不列出。
+func buildWorkspaceLayout(dir string, limit int) string {
+	if limit <= 0 || dir == "" {
+		return ""
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	var dirs, files []string
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if e.IsDir() {
+			dirs = append(dirs, name+"/")
+		} else {
+			files = append(files, name)
+		}
+	}
+	if len(dirs)+len(files) == 0 {
+		return ""
+	}
+	sort.Strings(dirs)
+	sort.Strings(files)
+	names := append(dirs, files...)
+
+	sb := strings.Builder{}
+	sb.WriteString("\n\n## 工作目录\n文件工具和命令默认在工作目录中执行，路径相对于工作目录。顶层内容:\n")
+	for i, name := range names {
+		if i == limit {
+			sb.WriteString(fmt.Sprintf("- ... 另有 %d 项\n", len(names)-limit))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("- %s\n", name))
+	}
+	return sb.String()
+}
+
+// toolCategories 内置工具的类别，按名称匹配（支持通配符）
+var toolCategories = []struct {
+	name     string
+	patterns []string
+}{
+	{"文件", []string{"read_file", "write_file", "copy_file", "apply_patch", "list_directory", "grep", "filesystem"}},
+	{"代码", []string{"code_outline", "symbol_search"}},
+	{"版本控制", []string{"git_*"}},
+	{"命令执行", []string{"shell_command"}},
+	{"网络", []string{"web_search", "http_request"}},
+	{"脚本", []string{"script", "script_file"}},
+	{"记忆与存储", []string{"kv_*", "recall_entity", "session_vars"}},
+	{"定时任务", []string{"scheduler"}},
+	{"技能", []string{"skill_install"}},
+	{"时间", []string{"datetime", "user_timezone"}},
+}
+
+// buildToolCategories 按类别列出当前会话可用的工具，不在内置类别中的归为扩展工具（MCP、插件等）。
+func (a *ReActAgent) buildToolCategories(policy tools.Policy) string {
+	if a.tools == nil {
+		return ""
+	}
+	available := a.tools.ListFor(policy)
+	if len(available) == 0 {
+		return ""
+	}
+
+	grouped := make(map[string][]string)
+	for _, t := range available {
+		category := "扩展工具"
+		for _, c := range toolCategories {
+			if matchToolName(c.patterns, t.Name()) {
+				category = c.name
+				break
+			}
+		}
+		grouped[category] = append(grouped[category], t.Name())
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("\n\n## 可用工具\n")
+	for _, c := range toolCategories {
+		if names := grouped[c.name]; len(names) > 0 {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", c.name, strings.Join(names, ", ")))
+		}
+	}
+	if names := grouped["扩展工具"]; len(names) > 0 {
+		sb.WriteString(fmt.Sprintf("- 扩展工具: %s\n", strings.Join(names, ", ")))
+	}
+	return sb.String()
+}
+
+// matchToolName 工具名称是否匹配任一模式。
+func matchToolName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package react
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/language"
+	"icooclaw/pkg/tools"
+)
+
+// namedTool 只有名称的工具。
+type namedTool string
+
+func (t namedTool) Name() string               { return string(t) }
+func (t namedTool) Description() string        { return string(t) }
+func (t namedTool) Parameters() map[string]any { return map[string]any{} }
+func (t namedTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	return tools.SuccessResult("")
+}
+
+func TestBuildDateTime(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	got := buildDateTime(time.Date(2024, 5, 1, 2, 30, 0, 0, time.UTC), loc)
+	if want := "2024-05-01 10:30 星期三 (CST, UTC+08:00)"; !strings.Contains(got, want) {
+		t.Errorf("buildDateTime() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildWorkspaceLayout(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"src", "docs", ".git"} {
+		os.Mkdir(filepath.Join(dir, d), 0o755)
+	}
+	for _, f := range []string{"README.md", "go.mod"} {
+		os.WriteFile(filepath.Join(dir, f), nil, 0o644)
+	}
+
+	got := buildWorkspaceLayout(dir, 3)
+	if !strings.Contains(got, "- docs/\n- src/\n- README.md\n- ... 另有 1 项\n") {
+		t.Errorf("目录应在前且按数量截断, got:\n%s", got)
+	}
+	if strings.Contains(got, ".git") {
+		t.Error("隐藏条目不应列出")
+	}
+	if buildWorkspaceLayout(dir, 0) != "" || buildWorkspaceLayout(filepath.Join(dir, "none"), 3) != "" {
+		t.Error("关闭或目录不存在时不应注入")
+	}
+}
+
+func TestBuildToolCategories(t *testing.T) {
+	registry := tools.NewRegistry()
+	for _, name := range []string{"read_file", "write_file", "shell_command", "kv_get", "jira_search"} {
+		registry.Register(namedTool(name))
+	}
+	agent := &ReActAgent{tools: registry}
+
+	got := agent.buildToolCategories(tools.Policy{Deny: []string{"shell_command"}})
+	for _, want := range []string{"- 文件: read_file, write_file\n", "- 记忆与存储: kv_get\n", "- 扩展工具: jira_search\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("缺少 %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "shell_command") {
+		t.Error("会话策略禁用的工具不应列出")
+	}
+}
+
+func TestBuildReplyLanguage(t *testing.T) {
+	if got := buildReplyLanguage("", language.SourceDefault); got != "" {
+		t.Errorf("buildReplyLanguage(\"\") = %q, want empty", got)
+	}
+	if got := buildReplyLanguage("en", language.SourceDetected); !strings.Contains(got, "用户使用 English (en)") {
+		t.Errorf("detected = %q", got)
+	}
+	if got := buildReplyLanguage("ja", language.SourceSession); !strings.Contains(got, "无论用户用什么语言提问，都使用 日本語 回复") {
+		t.Errorf("session = %q", got)
+	}
+}
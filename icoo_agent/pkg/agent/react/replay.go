@@ -0,0 +1,250 @@
+package react
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/trace"
+)
+
+// ReplayResult 用当前代码重放一轮录制轨迹的结果。
+type ReplayResult struct {
+	Content    string           `json:"content"`             // 重放得到的最终回复
+	Error      string           `json:"error,omitempty"`     // 重放失败原因
+	Iterations int              `json:"iterations"`          // 重放中的模型调用次数
+	ToolCalls  []trace.ToolCall `json:"tool_calls"`          // 重放中执行的工具调用，按执行顺序
+	Diff       []string         `json:"diff,omitempty"`      // 与录制不一致之处，为空表示行为一致
+	Truncated  bool             `json:"truncated,omitempty"` // 录制的工具结果有截断，重放时使用截断后的内容
+}
+
+// Equal 重放结果与录制是否一致。
+func (r *ReplayResult) Equal() bool {
+	return len(r.Diff) == 0
+}
+
+// Replay 用当前代码重放录制的一轮对话，重构 ReAct 循环前后可据此确认行为没有变化。
+//
+// 从录制的提示词开始运行 RunLLM：模型调用按顺序返回录制的回复，工具按名称和参数返回录制的结果，
+// 不请求提供商也不执行真实工具；之后对比工具调用序列、模型调用次数和最终回复。
+// 重放不使用钩子、时间预算和轨迹记录，结果只取决于录制内容和 ReAct 循环本身。
+func (a *ReActAgent) Replay(ctx context.Context, turn *trace.Turn) *ReplayResult {
+	recorded := newReplayTools(turn)
+
+	clone := *a
+	clone.tools = recorded.registry
+	clone.hooks = nil
+	clone.storage = nil
+	clone.providerFactory = nil
+	clone.statusFn = nil
+	clone.turnBudget = 0
+	clone.traceKeep = 0
+	clone.costGate = nil
+	if a.condense != nil {
+		// 不写入完整输出缓存，压缩结果中不出现每次不同的缓存 ID
+		condense := *a.condense
+		condense.Cache = nil
+		clone.condense = &condense
+	}
+
+	provider := &replayProvider{iterations: turn.Iterations}
+	msg := bus.InboundMessage{Channel: turn.Channel, SessionID: turn.SessionID, Text: turn.Input}
+	content, _, err := clone.RunLLM(ctx, turn.Model, provider, turn.Prompt, msg)
+
+	result := &ReplayResult{
+		Content:    content,
+		Iterations: provider.calls,
+		ToolCalls:  recorded.executed,
+		Truncated:  recorded.truncated,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Diff = replayDiff(turn, result)
+	return result
+}
+
+// replayDiff 对比录制和重放的工具调用序列、模型调用次数、最终回复和错误。
+func replayDiff(turn *trace.Turn, result *ReplayResult) []string {
+	var diff []string
+	var calls []trace.ToolCall
+	for _, it := range turn.Iterations {
+		calls = append(calls, it.ToolCalls...)
+	}
+	diff = append(diff, diffCalls(calls, result.ToolCalls)...)
+	if len(turn.Iterations) != result.Iterations {
+		diff = append(diff, fmt.Sprintf("模型调用次数: 录制 %d，重放 %d", len(turn.Iterations), result.Iterations))
+	}
+	if turn.Content != result.Content {
+		diff = append(diff, fmt.Sprintf("最终回复: 录制 %q，重放 %q", turn.Content, result.Content))
+	}
+	if turn.Error != result.Error {
+		diff = append(diff, fmt.Sprintf("错误: 录制 %q，重放 %q", turn.Error, result.Error))
+	}
+	return diff
+}
+
+// diffCalls 按最长公共子序列对齐录制和重放的工具调用，列出只在一方出现的调用。
+func diffCalls(recorded, replayed []trace.ToolCall) []string {
+	same := func(i, j int) bool {
+		return recorded[i].Name == replayed[j].Name && canonicalArgs(recorded[i].Arguments) == replayed[j].Arguments
+	}
+	// lcs[i][j] 为 recorded[i:] 和 replayed[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(recorded)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(replayed)+1)
+	}
+	for i := len(recorded) - 1; i >= 0; i-- {
+		for j := len(replayed) - 1; j >= 0; j-- {
+			if same(i, j) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(recorded) || j < len(replayed) {
+		switch {
+		case i < len(recorded) && j < len(replayed) && same(i, j):
+			i++
+			j++
+		case j == len(replayed) || (i < len(recorded) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, fmt.Sprintf("工具调用: 录制第 %d 次 %s，重放中没有", i+1, callString(recorded[i])))
+			i++
+		default:
+			diff = append(diff, fmt.Sprintf("工具调用: 重放第 %d 次 %s，录制中没有", j+1, callString(replayed[j])))
+			j++
+		}
+	}
+	return diff
+}
+
+// callString 返回工具调用的简要描述。
+func callString(tc trace.ToolCall) string {
+	return tc.Name + " " + tc.Arguments
+}
+
+// replayProvider 按顺序返回录制的模型回复。
+type replayProvider struct {
+	iterations []trace.Iteration
+	calls      int
+}
+
+func (p *replayProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	if p.calls >= len(p.iterations) {
+		p.calls++
+		return nil, fmt.Errorf("录制的模型回复已用完")
+	}
+	it := p.iterations[p.calls]
+	p.calls++
+
+	resp := &providers.ChatResponse{Model: req.Model, Content: it.Content, Reasoning: it.Reasoning}
+	if it.Usage != nil {
+		resp.Usage = *it.Usage
+	}
+	for _, tc := range it.ToolCalls {
+		call := providers.ToolCall{ID: tc.ID, Type: "function"}
+		call.Function.Name = tc.Name
+		call.Function.Arguments = tc.Arguments
+		resp.ToolCalls = append(resp.ToolCalls, call)
+	}
+	return resp, nil
+}
+
+func (p *replayProvider) ChatStream(ctx context.Context, req providers.ChatRequest, callback providers.StreamCallback) error {
+	resp, err := p.Chat(ctx, req)
+	if err != nil {
+		return err
+	}
+	return callback(resp.Content, resp.Reasoning, resp.ToolCalls, true)
+}
+
+func (p *replayProvider) GetName() string  { return "replay" }
+func (p *replayProvider) GetModel() string { return "" }
+func (p *replayProvider) SetModel(string)  {}
+
+// replayTools 由录制的工具定义和调用结果构建的工具注册表。
+type replayTools struct {
+	registry  *tools.Registry
+	results   map[string][]string // 名称和参数 -> 按录制顺序排列的结果
+	executed  []trace.ToolCall
+	truncated bool
+}
+
+// newReplayTools 注册录制中出现的所有工具，没有工具定义快照的旧轨迹按调用过的工具名注册。
+func newReplayTools(turn *trace.Turn) *replayTools {
+	r := &replayTools{registry: tools.NewRegistry(), results: make(map[string][]string)}
+	for _, def := range turn.Tools {
+		properties, _ := def.Function.Parameters["properties"].(map[string]any)
+		r.registry.Register(&replayTool{replay: r, name: def.Function.Name, description: def.Function.Description, parameters: properties})
+	}
+	for _, it := range turn.Iterations {
+		for _, tc := range it.ToolCalls {
+			key := replayKey(tc.Name, canonicalArgs(tc.Arguments))
+			r.results[key] = append(r.results[key], tc.Result)
+			r.truncated = r.truncated || tc.Truncated
+			if _, ok := r.registry.GetOK(tc.Name); !ok {
+				r.registry.Register(&replayTool{replay: r, name: tc.Name})
+			}
+		}
+	}
+	return r
+}
+
+// replayKey 录制结果的查找键。
+func replayKey(name, arguments string) string {
+	return name + "\x00" + arguments
+}
+
+// replayTool 返回录制结果的工具。
+type replayTool struct {
+	replay      *replayTools
+	name        string
+	description string
+	parameters  map[string]any
+}
+
+func (t *replayTool) Name() string               { return t.name }
+func (t *replayTool) Description() string        { return t.description }
+func (t *replayTool) Parameters() map[string]any { return t.parameters }
+
+// Execute 返回录制中相同名称和参数的下一个结果，录制的错误结果仍作为错误返回。
+func (t *replayTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	arguments := "{}"
+	if len(args) > 0 {
+		data, _ := json.Marshal(args)
+		arguments = string(data)
+	}
+	t.replay.executed = append(t.replay.executed, trace.ToolCall{Name: t.name, Arguments: arguments})
+
+	key := replayKey(t.name, arguments)
+	results := t.replay.results[key]
+	if len(results) == 0 {
+		return tools.ErrorResult("录制中没有该调用的结果")
+	}
+	t.replay.results[key] = results[1:]
+	if msg, ok := strings.CutPrefix(results[0], "错误: "); ok {
+		return tools.ErrorResult(msg)
+	}
+	return tools.SuccessResult(results[0])
+}
+
+// canonicalArgs 将参数 JSON 转为与重放执行时相同的形式（键排序、无空白），便于比较。
+func canonicalArgs(arguments string) string {
+	var args map[string]any
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return arguments
+	}
+	if len(args) == 0 {
+		return "{}"
+	}
+	data, _ := json.Marshal(args)
+	return string(data)
+}
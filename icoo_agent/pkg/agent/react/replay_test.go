@@ -0,0 +1,73 @@
+package react
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/trace"
+)
+
+// replayTurn 两次工具调用后回复的录制轨迹。
+func replayTurn() *trace.Turn {
+	return &trace.Turn{
+		Channel:   "websocket",
+		SessionID: "s1",
+		Model:     "m",
+		Input:     "查一下",
+		Prompt:    []providers.ChatMessage{{Role: consts.RoleUser.ToString(), Content: "查一下"}},
+		Tools: []providers.Tool{{Type: "function", Function: providers.Function{
+			Name: "search", Description: "搜索", Parameters: map[string]any{"type": "object", "properties": map[string]any{}},
+		}}},
+		Iterations: []trace.Iteration{
+			{Index: 1, ToolCalls: []trace.ToolCall{
+				{ID: "c1", Name: "search", Arguments: `{"q": "go", "limit": 2}`, Result: "r1"},
+				{ID: "c2", Name: "fetch", Arguments: `{}`, Result: "错误: 404"},
+			}},
+			{Index: 2, Content: "done"},
+		},
+		Content: "done",
+	}
+}
+
+func TestReplay(t *testing.T) {
+	agent := &ReActAgent{tools: tools.NewRegistry(), logger: slog.Default(), maxToolIterations: 10}
+
+	result := agent.Replay(context.Background(), replayTurn())
+	if !result.Equal() {
+		t.Fatalf("Diff = %v", result.Diff)
+	}
+	if result.Iterations != 2 || len(result.ToolCalls) != 2 || result.ToolCalls[0].Arguments != `{"limit":2,"q":"go"}` {
+		t.Errorf("result = %+v", result)
+	}
+
+	// 循环行为变化时报告差异：迭代上限改变后录制的回复用不完
+	agent.maxToolIterations = 1
+	result = agent.Replay(context.Background(), replayTurn())
+	if result.Equal() {
+		t.Fatal("应报告差异")
+	}
+	diff := strings.Join(result.Diff, "\n")
+	for _, want := range []string{"模型调用次数: 录制 2，重放 1", "最终回复"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff 缺少 %q:\n%s", want, diff)
+		}
+	}
+
+	// 录制时未去重的重复调用，开启去重后不再执行
+	agent.maxToolIterations = 10
+	agent.toolRepeatLimit = 2
+	turn := replayTurn()
+	turn.Iterations = append([]trace.Iteration{{Index: 1, ToolCalls: []trace.ToolCall{
+		{ID: "c0", Name: "search", Arguments: `{"q":"go","limit":2}`, Result: "r1"},
+	}}}, turn.Iterations...)
+	turn.Content = "done"
+	result = agent.Replay(context.Background(), turn)
+	if len(result.Diff) != 1 || !strings.Contains(result.Diff[0], "重放中没有") {
+		t.Errorf("Diff = %v", result.Diff)
+	}
+}
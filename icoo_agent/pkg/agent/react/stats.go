@@ -0,0 +1,107 @@
+package react
+
+import (
+	"context"
+	"time"
+
+	"icooclaw/pkg/channels"
+	"icooclaw/pkg/providers"
+)
+
+// TurnStats 一轮对话的统计：迭代次数、工具耗时、Token 用量和费用，随回复下发给客户端展示详情。
+type TurnStats struct {
+	Model            string     `json:"model,omitempty"`
+	Iterations       int        `json:"iterations"`        // 模型调用次数
+	Tools            []ToolStat `json:"tools,omitempty"`   // 按调用顺序排列的工具
+	PromptTokens     int        `json:"prompt_tokens"`     // 各次模型调用的累计输入
+	CompletionTokens int        `json:"completion_tokens"` // 各次模型调用的累计输出
+	TotalTokens      int        `json:"total_tokens"`
+	Estimated        bool       `json:"estimated,omitempty"` // 部分用量按字数估算，提供商未返回实际用量
+	Cost             float64    `json:"cost,omitempty"`      // 估计费用（美元），模型价格未知时为 0
+	DurationMs       int64      `json:"duration_ms"`         // 总耗时
+	startedAt        time.Time
+}
+
+// ToolStat 一次工具调用的统计。
+type ToolStat struct {
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name"`
+	Iteration  int    `json:"iteration"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// statsKey 对话统计的上下文键
+type statsKey struct{}
+
+// WithStats 将本轮的统计注入上下文，智能体运行时据此记录。
+func WithStats(ctx context.Context, s *TurnStats) context.Context {
+	return context.WithValue(ctx, statsKey{}, s)
+}
+
+// NewTurnStats 开始统计一轮对话。
+func NewTurnStats() *TurnStats {
+	return &TurnStats{startedAt: time.Now()}
+}
+
+// turnStats 返回上下文中的统计，未设置时新建，流式完成块总是携带统计。
+func turnStats(ctx context.Context, modelName string) *TurnStats {
+	s, _ := ctx.Value(statsKey{}).(*TurnStats)
+	if s == nil {
+		s = NewTurnStats()
+	}
+	s.Model = modelName
+	return s
+}
+
+// response 累计一次模型调用的用量，提供商未返回用量时按请求和回复估算。
+func (s *TurnStats) response(req providers.ChatRequest, resp *providers.ChatResponse) {
+	s.Iterations++
+	usage := resp.Usage
+	if usage.TotalTokens == 0 {
+		usage = estimateUsage(req, resp)
+		s.Estimated = true
+	}
+	s.PromptTokens += usage.PromptTokens
+	s.CompletionTokens += usage.CompletionTokens
+	s.TotalTokens += usage.TotalTokens
+}
+
+// tool 记录一次工具调用。
+func (s *TurnStats) tool(tc providers.ToolCall, iteration int, elapsed time.Duration) {
+	s.Tools = append(s.Tools, ToolStat{
+		ID:         tc.ID,
+		Name:       tc.Function.Name,
+		Iteration:  iteration,
+		DurationMs: elapsed.Milliseconds(),
+	})
+}
+
+// Snapshot 计算耗时和费用，返回当前统计的副本。
+func (s *TurnStats) Snapshot() *TurnStats {
+	out := *s
+	out.Tools = append([]ToolStat(nil), s.Tools...)
+	if !s.startedAt.IsZero() {
+		out.DurationMs = time.Since(s.startedAt).Milliseconds()
+	}
+	if info := providers.GetModelInfo(s.Model); info != nil {
+		out.Cost = (float64(s.PromptTokens)*info.InputPrice + float64(s.CompletionTokens)*info.OutputPrice) / 1_000_000
+	}
+	return &out
+}
+
+// estimateUsage 按字数估算一次模型调用的用量。
+func estimateUsage(req providers.ChatRequest, resp *providers.ChatResponse) providers.Usage {
+	prompt := estimateToolDefs(req.Tools)
+	for _, m := range req.Messages {
+		prompt += estimateMessage(m)
+	}
+	completion := channels.EstimateTokens(resp.Reasoning) + estimateMessage(providers.ChatMessage{
+		Content:   resp.Content,
+		ToolCalls: resp.ToolCalls,
+	})
+	return providers.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		TotalTokens:      prompt + completion,
+	}
+}
@@ -0,0 +1,46 @@
+package react
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+)
+
+func TestRunLLM_Stats(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(&countTool{})
+	provider := &scriptedProvider{responses: []*providers.ChatResponse{
+		{ToolCalls: []providers.ToolCall{toolCall("c1", "search", `{"q":"go"}`)},
+			Usage: providers.Usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110}},
+		{Content: "answer"},
+	}}
+	agent := &ReActAgent{tools: registry, logger: slog.Default(), maxToolIterations: 10}
+
+	stats := NewTurnStats()
+	ctx := WithStats(context.Background(), stats)
+	_, _, err := agent.RunLLM(ctx, "gpt-4o", provider,
+		[]providers.ChatMessage{{Role: consts.RoleUser.ToString(), Content: "hi"}}, bus.InboundMessage{})
+	if err != nil {
+		t.Fatalf("RunLLM() error = %v", err)
+	}
+
+	got := stats.Snapshot()
+	if got.Model != "gpt-4o" || got.Iterations != 2 {
+		t.Errorf("model = %q, iterations = %d", got.Model, got.Iterations)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Name != "search" || got.Tools[0].Iteration != 1 {
+		t.Errorf("tools = %+v", got.Tools)
+	}
+	// 第二次调用没有返回用量，按字数估算
+	if !got.Estimated || got.PromptTokens <= 100 || got.TotalTokens != got.PromptTokens+got.CompletionTokens {
+		t.Errorf("usage = %+v", got)
+	}
+	if got.Cost <= 0 {
+		t.Errorf("已知价格的模型应估计费用, cost = %v", got.Cost)
+	}
+}
@@ -0,0 +1,154 @@
+package react
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/bus"
+)
+
+// Status 长时间工具执行期间的进度状态。
+type Status struct {
+	Tool      string        `json:"tool"`             // 正在执行的工具
+	Completed int           `json:"completed"`        // 本轮已完成的工具数
+	Total     int           `json:"total"`            // 本轮工具总数
+	Iteration int           `json:"iteration"`        // 迭代次数
+	Elapsed   time.Duration `json:"elapsed"`          // 本次对话已耗时
+	Output    string        `json:"output,omitempty"` // 正在执行的工具最近输出的一行
+}
+
+// String 返回面向用户的状态描述，如 "正在运行 grep… 3/5 个工具已完成，已用时 42s"，
+// 工具有实时输出时在下一行附上最近一行输出。
+func (s Status) String() string {
+	text := fmt.Sprintf("正在运行 %s… %d/%d 个工具已完成，已用时 %s",
+		s.Tool, s.Completed, s.Total, s.Elapsed.Round(time.Second))
+	if s.Output != "" {
+		text += "\n> " + s.Output
+	}
+	return text
+}
+
+// StatusFunc 状态回调，由调用方决定如何投递。
+type StatusFunc func(msg bus.InboundMessage, status Status)
+
+// WithStatus 设置状态回调。工具执行超过 interval 后开始按 interval 周期上报，
+// interval 同时是两次上报的最小间隔，避免刷屏。
+func WithStatus(fn StatusFunc, interval time.Duration) Option {
+	return func(a *ReActAgent) {
+		a.statusFn = fn
+		a.statusInterval = interval
+	}
+}
+
+// statusTracker 跟踪一次对话的工具执行进度，并按间隔上报心跳。
+type statusTracker struct {
+	fn       StatusFunc
+	msg      bus.InboundMessage
+	interval time.Duration
+	start    time.Time
+
+	mu      sync.Mutex
+	status  Status
+	running bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// newStatusTracker 创建状态跟踪器，未配置回调时返回 nil，nil 跟踪器的方法均为空操作。
+func (a *ReActAgent) newStatusTracker(msg bus.InboundMessage) *statusTracker {
+	if a.statusFn == nil || a.statusInterval <= 0 {
+		return nil
+	}
+	return &statusTracker{
+		fn:       a.statusFn,
+		msg:      msg,
+		interval: a.statusInterval,
+		start:    time.Now(),
+	}
+}
+
+// begin 开始一轮工具执行。
+func (t *statusTracker) begin(iteration, total int) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.status = Status{Iteration: iteration, Total: total}
+	t.running = true
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+	t.mu.Unlock()
+
+	go t.run(t.stop, t.done)
+}
+
+// tool 记录正在执行的工具及已完成数量。
+func (t *statusTracker) tool(name string, completed int) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.status.Tool = name
+	t.status.Completed = completed
+	t.status.Output = ""
+	t.mu.Unlock()
+}
+
+// output 记录工具实时输出的最后一个非空行。
+func (t *statusTracker) output(chunk string) {
+	if t == nil {
+		return
+	}
+
+	line := lastLine(chunk)
+	if line == "" {
+		return
+	}
+	t.mu.Lock()
+	t.status.Output = line
+	t.mu.Unlock()
+}
+
+// end 结束一轮工具执行，等待心跳协程退出，确保最终回复之后不再有状态消息。
+func (t *statusTracker) end() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	if !t.running {
+		t.mu.Unlock()
+		return
+	}
+	t.running = false
+	stop, done := t.stop, t.done
+	t.mu.Unlock()
+
+	close(stop)
+	<-done
+}
+
+// run 按间隔上报状态，工具在一个间隔内完成时不会产生任何消息。
+func (t *statusTracker) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			t.mu.Lock()
+			status := t.status
+			t.mu.Unlock()
+
+			status.Elapsed = now.Sub(t.start)
+			t.fn(t.msg, status)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package react
+
+import (
+	"context"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/clock"
+	"icooclaw/pkg/tools"
+)
+
+// WithTimezones 设置用户时区管理器，提示词中的当前时间和时间相关工具按会话的用户时区处理。
+func WithTimezones(m *clock.Manager) Option {
+	return func(a *ReActAgent) {
+		a.timezones = m
+	}
+}
+
+// sessionLocation 获取会话的用户时区，未配置管理器时使用提示词配置的时区。
+func (a *ReActAgent) sessionLocation(msg bus.InboundMessage) *time.Location {
+	if a.timezones != nil {
+		return a.timezones.Current(msg.Channel, msg.SessionID)
+	}
+	if loc := a.promptSections().Location; loc != nil {
+		return loc
+	}
+	return time.Local
+}
+
+// withSessionLocation 将会话的用户时区注入上下文。
+func (a *ReActAgent) withSessionLocation(ctx context.Context, msg bus.InboundMessage) context.Context {
+	return tools.WithLocation(ctx, a.sessionLocation(msg))
+}
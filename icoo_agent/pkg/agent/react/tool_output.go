@@ -0,0 +1,53 @@
+package react
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+)
+
+// statusOutputLen 进度心跳中附带的输出行最大字符数
+const statusOutputLen = 200
+
+// withToolOutput 为一次工具调用注入实时输出回调：流式对话通过 callback 下发 ToolOutput 片段，
+// 同时记录最后一行输出供进度心跳展示。两者都未启用时原样返回 ctx。
+func (a *ReActAgent) withToolOutput(ctx context.Context, tc providers.ToolCall, iteration int, status *statusTracker, callback StreamCallback) context.Context {
+	if status == nil && callback == nil {
+		return ctx
+	}
+
+	return tools.WithOutput(ctx, func(stream, chunk string) {
+		status.output(chunk)
+		if callback == nil {
+			return
+		}
+		// 实时输出只用于展示，下发失败不影响工具执行
+		if err := callback(StreamChunk{
+			ToolCallID: tc.ID,
+			ToolName:   tc.Function.Name,
+			ToolOutput: chunk,
+			ToolStream: stream,
+			Iteration:  iteration,
+		}); err != nil {
+			a.logger.With("name", "【智能体】").Debug("下发工具实时输出失败", "tool", tc.Function.Name, "error", err)
+		}
+	})
+}
+
+// lastLine 返回文本中最后一个非空行，超长时截断。
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\r\n\t "), "\n")
+	line := lines[len(lines)-1]
+	// 进度条等输出用 \r 覆盖同一行，只保留最后一段
+	if i := strings.LastIndex(line, "\r"); i >= 0 {
+		line = line[i+1:]
+	}
+	line = strings.TrimSpace(line)
+	if utf8.RuneCountInString(line) > statusOutputLen {
+		line = string([]rune(line)[:statusOutputLen]) + "…"
+	}
+	return line
+}
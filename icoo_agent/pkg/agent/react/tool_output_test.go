@@ -0,0 +1,52 @@
+package react
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+)
+
+func TestLastLine(t *testing.T) {
+	tests := map[string]string{
+		"a\nb\n":             "b",
+		"ok  \n\n":           "ok",
+		"10%\r50%\r100%\r\n": "100%",
+		"":                   "",
+	}
+	for in, want := range tests {
+		if got := lastLine(in); got != want {
+			t.Errorf("lastLine(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWithToolOutput(t *testing.T) {
+	a := &ReActAgent{logger: slog.Default()}
+	tc := providers.ToolCall{ID: "call_1"}
+	tc.Function.Name = "shell_command"
+
+	if ctx := context.Background(); a.withToolOutput(ctx, tc, 1, nil, nil) != ctx {
+		t.Error("未启用回调和心跳时应返回原上下文")
+	}
+
+	var got []StreamChunk
+	status := &statusTracker{fn: func(bus.InboundMessage, Status) {}, interval: time.Hour}
+	ctx := a.withToolOutput(context.Background(), tc, 2, status, func(chunk StreamChunk) error {
+		got = append(got, chunk)
+		return nil
+	})
+
+	tools.GetOutput(ctx)(tools.StreamStdout, "building...\ndone\n")
+	if len(got) != 1 || got[0].ToolOutput != "building...\ndone\n" || got[0].ToolCallID != "call_1" ||
+		got[0].ToolStream != tools.StreamStdout || got[0].Iteration != 2 {
+		t.Errorf("chunks = %+v", got)
+	}
+	if status.status.Output != "done" {
+		t.Errorf("status output = %q, want done", status.status.Output)
+	}
+}
@@ -0,0 +1,156 @@
+package react
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/trace"
+	"icooclaw/pkg/utils"
+)
+
+// traceInputChars 轨迹列表中用户消息摘要的最大字符数
+const traceInputChars = 200
+
+// WithTrace 记录每轮对话的完整轨迹，每个会话保留最近 keep 条，0 表示不记录。
+// 工具结果超过 maxResultChars 个字符时截断，0 表示不截断。
+func WithTrace(keep, maxResultChars int) Option {
+	return func(a *ReActAgent) {
+		a.traceKeep = keep
+		a.traceResultChars = maxResultChars
+	}
+}
+
+// turnRecorder 记录一轮对话的轨迹，nil 表示不记录。内容写入前脱敏，报告可以直接分享。
+type turnRecorder struct {
+	turn     trace.Turn
+	redactor *utils.Redactor
+	current  *trace.Iteration
+	callAt   time.Time // 当前模型调用开始时间
+}
+
+// newTurnRecorder 开始记录本轮轨迹，prompt 为首次请求模型的消息，无痕会话不记录。
+func (a *ReActAgent) newTurnRecorder(msg bus.InboundMessage, modelName string, prompt []providers.ChatMessage) *turnRecorder {
+	if a.traceKeep <= 0 || a.storage == nil || a.ephemeralSession(msg) != nil {
+		return nil
+	}
+	redactor, _ := utils.NewRedactor(nil)
+	r := &turnRecorder{
+		redactor: redactor,
+		turn: trace.Turn{
+			Channel:     msg.Channel,
+			SessionID:   msg.SessionID,
+			Model:       modelName,
+			Input:       redactor.Redact(msg.Text),
+			StartedAt:   time.Now(),
+			ResultLimit: a.traceResultChars,
+		},
+	}
+	r.turn.Prompt = make([]providers.ChatMessage, 0, len(prompt))
+	for _, m := range prompt {
+		m.Content = redactor.Redact(m.Content)
+		if len(m.ToolCalls) > 0 {
+			m.ToolCalls = slices.Clone(m.ToolCalls)
+			for i := range m.ToolCalls {
+				m.ToolCalls[i].Function.Arguments = redactor.Redact(m.ToolCalls[i].Function.Arguments)
+			}
+		}
+		r.turn.Prompt = append(r.turn.Prompt, m)
+	}
+	return r
+}
+
+// request 开始一次模型调用。
+func (r *turnRecorder) request(iteration int, wrapUp bool) {
+	if r == nil {
+		return
+	}
+	r.turn.Iterations = append(r.turn.Iterations, trace.Iteration{Index: iteration, WrapUp: wrapUp})
+	r.current = &r.turn.Iterations[len(r.turn.Iterations)-1]
+	r.callAt = time.Now()
+}
+
+// toolDefs 记录首次提供给模型的工具定义，重放时据此重建工具注册表。
+func (r *turnRecorder) toolDefs(defs []providers.Tool) {
+	if r == nil || r.turn.Tools != nil || len(defs) == 0 {
+		return
+	}
+	r.turn.Tools = slices.Clone(defs)
+}
+
+// response 记录模型回复，usage 为 nil 或为零表示提供商未返回用量。
+func (r *turnRecorder) response(content, reasoning string, usage *providers.Usage) {
+	if r == nil || r.current == nil {
+		return
+	}
+	r.current.DurationMs = time.Since(r.callAt).Milliseconds()
+	r.current.Content = r.redactor.Redact(content)
+	r.current.Reasoning = r.redactor.Redact(reasoning)
+	if usage != nil && usage.TotalTokens > 0 {
+		r.current.Usage = usage
+		r.turn.Usage.PromptTokens += usage.PromptTokens
+		r.turn.Usage.CompletionTokens += usage.CompletionTokens
+		r.turn.Usage.TotalTokens += usage.TotalTokens
+	}
+}
+
+// tool 记录一次工具调用及其结果。
+func (r *turnRecorder) tool(tc providers.ToolCall, result string, elapsed time.Duration) {
+	if r == nil || r.current == nil {
+		return
+	}
+	result, truncated := trace.Truncate(r.redactor.Redact(result), r.turn.ResultLimit)
+	r.current.ToolCalls = append(r.current.ToolCalls, trace.ToolCall{
+		ID:         tc.ID,
+		Name:       tc.Function.Name,
+		Arguments:  r.redactor.Redact(tc.Function.Arguments),
+		Result:     result,
+		Truncated:  truncated,
+		DurationMs: elapsed.Milliseconds(),
+	})
+}
+
+// saveTrace 结束记录并写入存储，失败只记录日志，不影响对话。
+func (a *ReActAgent) saveTrace(ctx context.Context, r *turnRecorder, content string, err error) {
+	if r == nil {
+		return
+	}
+	t := &r.turn
+	t.DurationMs = time.Since(t.StartedAt).Milliseconds()
+	t.Content = r.redactor.Redact(content)
+	t.Changes = tools.GetChanges(ctx).Summary()
+	if err != nil {
+		t.Error = r.redactor.Redact(err.Error())
+	}
+
+	data, jerr := json.Marshal(t)
+	if jerr != nil {
+		a.logger.With("name", "【智能体】").Warn("序列化对话轨迹失败", "error", jerr)
+		return
+	}
+	input, _ := trace.Truncate(t.Input, traceInputChars)
+	record := &storage.Trace{
+		Channel:     t.Channel,
+		SessionID:   t.SessionID,
+		ModelName:   t.Model,
+		Input:       input,
+		Iterations:  len(t.Iterations),
+		ToolCalls:   t.ToolCallCount(),
+		TotalTokens: t.Usage.TotalTokens,
+		DurationMs:  t.DurationMs,
+		Error:       t.Error,
+		Data:        string(data),
+	}
+	if err := a.storage.Trace().Save(record); err != nil {
+		a.logger.With("name", "【智能体】").Warn("保存对话轨迹失败", "error", err)
+		return
+	}
+	if err := a.storage.Trace().Prune(t.Channel, t.SessionID, a.traceKeep); err != nil {
+		a.logger.With("name", "【智能体】").Warn("清理对话轨迹失败", "error", err)
+	}
+}
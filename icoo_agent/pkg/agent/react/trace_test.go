@@ -0,0 +1,85 @@
+package react
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/trace"
+)
+
+func TestRunLLM_Trace(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "trace.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	registry := tools.NewRegistry()
+	registry.Register(&countTool{})
+
+	agent := &ReActAgent{
+		tools:             registry,
+		storage:           store,
+		logger:            slog.Default(),
+		maxToolIterations: 10,
+		traceKeep:         2,
+		traceResultChars:  4,
+	}
+	msg := bus.InboundMessage{Channel: "websocket", SessionID: "s1", Text: "token=abc123 查一下"}
+
+	for i := 0; i < 3; i++ {
+		provider := &scriptedProvider{responses: []*providers.ChatResponse{
+			{
+				Reasoning: "需要搜索",
+				ToolCalls: []providers.ToolCall{toolCall("c1", "search", `{"q":"go"}`)},
+				Usage:     providers.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+			},
+			{Content: "answer", Usage: providers.Usage{PromptTokens: 20, CompletionTokens: 3, TotalTokens: 23}},
+		}}
+		if _, _, err := agent.RunLLM(context.Background(), "m", provider,
+			[]providers.ChatMessage{{Role: consts.RoleUser.ToString(), Content: msg.Text}}, msg); err != nil {
+			t.Fatalf("RunLLM error: %v", err)
+		}
+	}
+
+	records, err := store.Trace().ListBySession("websocket", "s1", 10)
+	if err != nil {
+		t.Fatalf("ListBySession() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("每个会话只应保留 2 条轨迹, got %d", len(records))
+	}
+	if r := records[0]; r.Iterations != 2 || r.ToolCalls != 1 || r.TotalTokens != 35 || r.Data != "" {
+		t.Errorf("轨迹摘要不正确: %+v", r)
+	}
+
+	record, err := store.Trace().Latest("websocket", "s1")
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	turn, err := trace.Decode(record.Data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if strings.Contains(turn.Input, "abc123") || strings.Contains(turn.Prompt[0].Content, "abc123") {
+		t.Error("轨迹内容应脱敏")
+	}
+	first := turn.Iterations[0]
+	if first.Reasoning != "需要搜索" || first.Usage == nil || first.Usage.TotalTokens != 12 {
+		t.Errorf("第一次迭代记录不正确: %+v", first)
+	}
+	if tc := first.ToolCalls[0]; tc.Name != "search" || tc.Result != "3 re" || !tc.Truncated {
+		t.Errorf("工具调用记录不正确: %+v", tc)
+	}
+	if turn.Content != "answer" {
+		t.Errorf("Content = %q", turn.Content)
+	}
+}
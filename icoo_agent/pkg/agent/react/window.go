@@ -0,0 +1,108 @@
+package react
+
+import (
+	"encoding/json"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+)
+
+// messageOverhead 每条消息在角色、分隔符等格式上的估计开销
+const messageOverhead = 4
+
+// fitContext 按模型的上下文长度裁剪消息，超出时从最早的历史消息开始省略。
+// 开头的系统提示词和本轮消息（最后一条用户消息及其后的工具调用）总是保留；
+// 模型信息未知时不裁剪，由服务端处理。
+func (a *ReActAgent) fitContext(msg bus.InboundMessage, modelName string, messages []providers.ChatMessage, toolDefs []providers.Tool) []providers.ChatMessage {
+	info := providers.GetModelInfo(modelName)
+	if info == nil || info.ContextWindow <= 0 {
+		return messages
+	}
+
+	limit := info.ContextWindow - outputReserve(info)
+	used := estimateToolDefs(toolDefs)
+	for _, m := range messages {
+		used += estimateMessage(m)
+	}
+	if used <= limit {
+		return messages
+	}
+
+	// 可省略的范围：开头的系统消息之后、最后一条用户消息之前
+	start := 0
+	for start < len(messages) && messages[start].Role == consts.RoleSystem.ToString() {
+		start++
+	}
+	last := len(messages) - 1
+	for last >= start && messages[last].Role != consts.RoleUser.ToString() {
+		last--
+	}
+
+	drop := start
+	for drop < last && used > limit {
+		used -= estimateMessage(messages[drop])
+		drop++
+	}
+	// 不以助手回复或工具结果开头
+	for drop < last && messages[drop].Role != consts.RoleUser.ToString() {
+		used -= estimateMessage(messages[drop])
+		drop++
+	}
+
+	if dropped := drop - start; dropped > 0 {
+		a.logger.With("name", "【智能体】").Info("超出模型上下文长度，已省略较早的历史消息",
+			"session_id", msg.SessionID,
+			"model", modelName,
+			"context_window", info.ContextWindow,
+			"dropped", dropped)
+		fitted := make([]providers.ChatMessage, 0, len(messages)-dropped)
+		fitted = append(fitted, messages[:start]...)
+		messages = append(fitted, messages[drop:]...)
+	}
+	if used > limit {
+		a.logger.With("name", "【智能体】").Warn("系统提示词和本轮消息已超出模型上下文长度",
+			"session_id", msg.SessionID,
+			"model", modelName,
+			"context_window", info.ContextWindow,
+			"estimated_tokens", used)
+	}
+	return messages
+}
+
+// supportsTools 模型是否支持工具调用。只有从服务端探测到不支持时才返回 false。
+func supportsTools(modelName string) bool {
+	info := providers.GetModelInfo(modelName)
+	return info == nil || !info.Discovered || info.SupportsTools
+}
+
+// outputReserve 为模型回复预留的长度，不超过上下文长度的四分之一。
+func outputReserve(info *providers.ModelInfo) int {
+	reserve := info.ContextWindow / 4
+	if info.MaxOutputTokens > 0 && info.MaxOutputTokens < reserve {
+		reserve = info.MaxOutputTokens
+	}
+	return reserve
+}
+
+// estimateMessage 估计单条消息的 token 数。
+func estimateMessage(m providers.ChatMessage) int {
+	n := messageOverhead + channels.EstimateTokens(m.Content)
+	for _, tc := range m.ToolCalls {
+		n += channels.EstimateTokens(tc.Function.Name) + channels.EstimateTokens(tc.Function.Arguments)
+	}
+	return n
+}
+
+// estimateToolDefs 估计工具定义的 token 数。
+func estimateToolDefs(toolDefs []providers.Tool) int {
+	if len(toolDefs) == 0 {
+		return 0
+	}
+	data, err := json.Marshal(toolDefs)
+	if err != nil {
+		return 0
+	}
+	return channels.EstimateTokens(string(data))
+}
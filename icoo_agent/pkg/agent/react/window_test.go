@@ -0,0 +1,63 @@
+package react
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+)
+
+func TestRunLLM_FitContext(t *testing.T) {
+	// 探测到的 4K 本地模型，不支持工具
+	providers.RegisterModel(&providers.ModelInfo{ID: "window-test:4k", ContextWindow: 4096})
+
+	registry := tools.NewRegistry()
+	registry.Register(&slowTool{})
+	provider := &scriptedProvider{}
+	agent := &ReActAgent{tools: registry, logger: slog.Default(), maxToolIterations: 3}
+
+	user, assistant := consts.RoleUser.ToString(), consts.RoleAssistant.ToString()
+	long := strings.Repeat("word ", 1200) // 约 1500 token
+	messages := []providers.ChatMessage{
+		{Role: consts.RoleSystem.ToString(), Content: "system"},
+		{Role: user, Content: "old question 1 " + long},
+		{Role: assistant, Content: "old answer 1 " + long},
+		{Role: user, Content: "old question 2"},
+		{Role: assistant, Content: "old answer 2 " + long},
+		{Role: user, Content: "current question"},
+	}
+
+	if _, _, err := agent.RunLLM(context.Background(), "window-test:4k", provider, messages, bus.InboundMessage{}); err != nil {
+		t.Fatalf("RunLLM error: %v", err)
+	}
+	req := provider.requests[0]
+	if len(req.Tools) != 0 {
+		t.Error("不支持工具的模型不应提供工具")
+	}
+	var got []string
+	for _, m := range req.Messages {
+		got = append(got, strings.Fields(m.Content)[0]+" "+m.Role)
+	}
+	// 省略最早的一问一答，保留系统提示词和本轮问题，且不以助手回复开头
+	want := "system system;old user;old assistant;current user"
+	if strings.Join(got, ";") != want {
+		t.Errorf("messages = %v, want %s", got, want)
+	}
+	if req.Messages[1].Content != "old question 2" {
+		t.Errorf("first history message = %q", req.Messages[1].Content)
+	}
+
+	// 未知模型不裁剪，并提供工具
+	provider = &scriptedProvider{}
+	if _, _, err := agent.RunLLM(context.Background(), "unknown-model", provider, messages, bus.InboundMessage{}); err != nil {
+		t.Fatalf("RunLLM error: %v", err)
+	}
+	if req := provider.requests[0]; len(req.Messages) != len(messages) || len(req.Tools) != 1 {
+		t.Errorf("unknown model: %d messages, %d tools", len(req.Messages), len(req.Tools))
+	}
+}
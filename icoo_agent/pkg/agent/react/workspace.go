@@ -0,0 +1,67 @@
+package react
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/workspace"
+)
+
+// WithWorkspaces 设置工作目录管理器，会话选择命名工作目录后文件、命令和插件工具在该目录中执行。
+func WithWorkspaces(m *workspace.Manager) Option {
+	return func(a *ReActAgent) {
+		a.workspaces = m
+	}
+}
+
+// sessionWorkspace 获取会话当前的工作目录，未配置管理器时为存储的默认工作目录，无痕会话为临时工作目录。
+func (a *ReActAgent) sessionWorkspace(msg bus.InboundMessage) workspace.Entry {
+	if s := a.ephemeralSession(msg); s != nil {
+		return ephemeralWorkspace(s)
+	}
+	if a.workspaces != nil {
+		return a.workspaces.Current(msg.Channel, msg.SessionID)
+	}
+	entry := workspace.Entry{Name: workspace.DefaultName}
+	if a.storage != nil {
+		entry.Dir = a.storage.Workspace().GetWorkspace()
+	}
+	return entry
+}
+
+// withSessionWorkspace 将会话选择的命名工作目录及其只读标记注入上下文。
+// 使用 default 时不注入目录，工具保持各自配置的工作目录；无痕会话使用临时工作目录，并跳过提示词日志。
+func (a *ReActAgent) withSessionWorkspace(ctx context.Context, msg bus.InboundMessage) context.Context {
+	if s := a.ephemeralSession(msg); s != nil {
+		ctx = providers.WithoutPromptLog(ctx)
+		return tools.WithEphemeral(tools.WithWorkspace(ctx, s.Dir))
+	}
+	ws := a.sessionWorkspace(msg)
+	if ws.Name != workspace.DefaultName {
+		ctx = tools.WithWorkspace(ctx, ws.Dir)
+	}
+	if ws.ReadOnly {
+		ctx = tools.WithReadOnly(ctx)
+	}
+	return ctx
+}
+
+// buildWorkspaceName 说明会话选择的命名工作目录和只读限制，可写的 default 工作目录不生成。
+func buildWorkspaceName(ws workspace.Entry) string {
+	if ws.Name == workspace.DefaultName && !ws.ReadOnly {
+		return ""
+	}
+	sb := strings.Builder{}
+	sb.WriteString("\n\n## 当前工作目录\n")
+	sb.WriteString(fmt.Sprintf("当前会话使用工作目录 %s (%s)，文件工具和命令的相对路径基于该目录，不能访问其他工作目录中的文件。\n",
+		ws.Name, ws.Dir))
+	if ws.ReadOnly {
+		sb.WriteString("该工作目录为只读：写入、复制、删除文件和执行命令都不会真正执行，只能读取和查看。" +
+			"需要修改时，说明具体要做的修改，由用户决定是否在其他地方执行。\n")
+	}
+	return sb.String()
+}
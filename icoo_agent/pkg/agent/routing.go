@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/routing"
+	"maps"
+	"time"
+)
+
+// WithRouter 设置路由规则，消息在斜杠命令之后、到达默认智能体之前按规则分流。
+func (m *AgentManager) WithRouter(r *routing.Router) *AgentManager {
+	m.router = r
+	return m
+}
+
+// route 按消息前缀和路由规则分流消息。handled 为 true 时消息已由自动回复或命令处理，reply 为回复内容；
+// 路由到人设时在返回消息的元数据中记录人设，本条消息仍交给智能体处理。
+func (m *AgentManager) route(msg bus.InboundMessage) (routed bus.InboundMessage, reply string, handled bool) {
+	if isHeartbeat(msg) || isFormSubmission(msg) || isAnswer(msg) {
+		return msg, "", false
+	}
+
+	// 以人设前缀开头的消息直接交给该人设，去掉前缀后处理
+	if m.personas != nil {
+		if p, rest := m.personas.MatchPrefix(msg.Text); p != nil {
+			m.logger.With("name", "【路由】").Info("消息已按前缀路由到人设",
+				"persona", p.Name, "channel", msg.Channel, "session_id", msg.SessionID)
+			msg.Text = rest
+			return withPersona(msg, p.Name), "", false
+		}
+	}
+
+	if m.router.Len() == 0 {
+		return msg, "", false
+	}
+
+	d, err := m.router.Route(m.routingMessage(msg))
+	if err != nil {
+		m.logger.With("name", "【路由】").Warn("路由规则执行失败，交给默认智能体", "error", err)
+		return msg, "", false
+	}
+	if d == nil {
+		return msg, "", false
+	}
+
+	logger := m.logger.With("name", "【路由】", "rule", d.Rule, "action", d.Action,
+		"channel", msg.Channel, "session_id", msg.SessionID)
+	switch d.Action {
+	case routing.ActionReply:
+		logger.Info("消息已自动回复")
+		return msg, d.Text, true
+	case routing.ActionCommand:
+		cmd := msg
+		cmd.Text = d.Text
+		if reply, ok := m.commands.Execute(m.ctx, cmd); ok {
+			logger.Info("消息已路由到命令", "command", d.Text)
+			return msg, reply, true
+		}
+		logger.Warn("路由命令无效，交给默认智能体", "command", d.Text)
+	case routing.ActionPersona:
+		if m.personas == nil {
+			logger.Warn("人设功能未启用，交给默认智能体")
+			break
+		}
+		if _, ok := m.personas.Get(d.Persona); !ok {
+			logger.Warn("路由的人设不存在，交给默认智能体", "persona", d.Persona)
+			break
+		}
+		logger.Info("消息已路由到人设", "persona", d.Persona)
+		return withPersona(msg, d.Persona), "", false
+	}
+	return msg, "", false
+}
+
+// withPersona 在消息元数据中记录处理本条消息的人设。
+func withPersona(msg bus.InboundMessage, name string) bus.InboundMessage {
+	msg.Metadata = maps.Clone(msg.Metadata)
+	if msg.Metadata == nil {
+		msg.Metadata = make(map[string]any)
+	}
+	msg.Metadata[consts.META_PERSONA] = name
+	return msg
+}
+
+// routingMessage 构造路由规则匹配的消息，时间按用户时区。
+func (m *AgentManager) routingMessage(msg bus.InboundMessage) routing.Message {
+	now := time.Now()
+	if m.timezones != nil {
+		now = now.In(m.timezones.Current(msg.Channel, msg.SessionID))
+	}
+	return routing.Message{
+		Channel:   msg.Channel,
+		SessionID: msg.SessionID,
+		UserID:    msg.Sender.ID,
+		UserName:  msg.Sender.Name,
+		Text:      msg.Text,
+		Time:      now,
+	}
+}
@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"maps"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/skill/trigger"
+)
+
+// WithSkillTriggers 启用技能触发规则，消息在常见问题之后、调用模型之前匹配正则触发规则。
+func (m *AgentManager) WithSkillTriggers(engine *trigger.Engine) *AgentManager {
+	m.skillTriggers = engine
+	return m
+}
+
+// activateSkills 为命中正则触发规则的消息设置激活的技能。
+// 定时和事件触发的消息已带有激活的技能，心跳和对提问的回答不匹配。
+func (m *AgentManager) activateSkills(msg bus.InboundMessage) bus.InboundMessage {
+	if m.skillTriggers == nil || isHeartbeat(msg) || isAnswer(msg) {
+		return msg
+	}
+	if _, ok := msg.Metadata[consts.META_SKILL]; ok {
+		return msg
+	}
+	skills := m.skillTriggers.Match(msg)
+	if len(skills) == 0 {
+		return msg
+	}
+	m.logger.With("name", "【技能触发】").Info("消息激活技能",
+		"skills", skills, "channel", msg.Channel, "session_id", msg.SessionID)
+
+	metadata := maps.Clone(msg.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	metadata[consts.META_SKILL] = skills
+	msg.Metadata = metadata
+	return msg
+}
@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"time"
+
+	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+)
+
+// WithStatusUpdates 启用工具执行进度心跳。
+// 工具执行超过 interval 后，每隔 interval 向消息总线发送一次状态消息，0 表示不启用。
+func (m *AgentManager) WithStatusUpdates(interval time.Duration) *AgentManager {
+	m.statusInterval = interval
+	return m
+}
+
+// publishStatus 将工具执行进度发送到消息总线。
+func (m *AgentManager) publishStatus(msg bus.InboundMessage, status react.Status) {
+	m.bus.PublishOutbound(m.ctx, bus.OutboundMessage{
+		Channel:   msg.Channel,
+		SessionID: msg.SessionID,
+		Text:      status.String(),
+		Metadata: map[string]any{
+			consts.META_STATUS: status,
+		},
+	})
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"icooclaw/pkg/clock"
+	"icooclaw/pkg/command"
+)
+
+// cmdTimezone 处理 /timezone 命令。
+//
+//	/timezone                  查看当前时区
+//	/timezone <zone>           为当前会话设置时区，例如 Asia/Shanghai、UTC+8
+//	/timezone <zone> channel   为当前渠道设置默认时区
+//	/timezone off [channel]    清除时区设置
+func (m *AgentManager) cmdTimezone(ctx context.Context, c *command.Context) (string, error) {
+	if m.timezones == nil {
+		return "时区设置未启用", nil
+	}
+
+	name := c.Arg(0)
+	if name == "" {
+		loc := m.timezones.Current(c.Msg.Channel, c.Msg.SessionID)
+		return fmt.Sprintf("当前时区: %s\n当前时间: %s", loc, clock.Format(time.Now(), loc)), nil
+	}
+
+	sessionID := c.Msg.SessionID
+	if c.Arg(1) == "channel" {
+		sessionID = ""
+	}
+	if name == "off" {
+		name = ""
+	}
+
+	loc, err := m.timezones.Select(c.Msg.Channel, sessionID, name)
+	if err != nil {
+		return "", err
+	}
+	if loc == nil {
+		loc = m.timezones.Current(c.Msg.Channel, c.Msg.SessionID)
+		return fmt.Sprintf("已清除时区设置，当前使用: %s", loc), nil
+	}
+	return fmt.Sprintf("已设置时区: %s，当前时间 %s", loc, clock.Format(time.Now(), loc)), nil
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/command"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/vars"
+)
+
+// varStore 返回会话变量仓库。
+func (m *AgentManager) varStore() (*vars.Store, error) {
+	if m.storage == nil {
+		return nil, fmt.Errorf("未配置存储")
+	}
+	return vars.NewStore(m.storage.Session()), nil
+}
+
+// cmdSet 设置会话变量。
+//
+//	/set project_path=/srv/app   设置变量
+//	/set ticket = ABC-123        等号两侧可以有空格
+func (m *AgentManager) cmdSet(ctx context.Context, c *command.Context) (string, error) {
+	store, err := m.varStore()
+	if err != nil {
+		return "", err
+	}
+	if len(c.Args) == 0 {
+		return "用法: /set key=value", nil
+	}
+
+	key, value, err := tools.ParseAssignment(strings.Join(c.Args, " "))
+	if err != nil {
+		return "", err
+	}
+	if err := store.Set(c.Msg.Channel, c.Msg.SessionID, key, value); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("已设置会话变量: %s = %s", key, value), nil
+}
+
+// cmdUnset 删除会话变量。
+func (m *AgentManager) cmdUnset(ctx context.Context, c *command.Context) (string, error) {
+	store, err := m.varStore()
+	if err != nil {
+		return "", err
+	}
+	key := c.Arg(0)
+	if key == "" {
+		return "用法: /unset key", nil
+	}
+
+	deleted, err := store.Delete(c.Msg.Channel, c.Msg.SessionID, key)
+	if err != nil {
+		return "", err
+	}
+	if !deleted {
+		return fmt.Sprintf("会话变量 %s 未设置", key), nil
+	}
+	return fmt.Sprintf("已删除会话变量: %s", key), nil
+}
+
+// cmdVars 列出当前会话的变量。
+func (m *AgentManager) cmdVars(ctx context.Context, c *command.Context) (string, error) {
+	store, err := m.varStore()
+	if err != nil {
+		return "", err
+	}
+	v, err := store.Load(c.Msg.Channel, c.Msg.SessionID)
+	if err != nil {
+		return "", err
+	}
+	if len(v) == 0 {
+		return "暂无会话变量，可用 /set key=value 设置", nil
+	}
+	return "会话变量:\n" + vars.Render(v), nil
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/command"
+	"icooclaw/pkg/workspace"
+)
+
+// cmdWorkspace 处理 /workspace 命令。
+//
+//	/workspace                 查看当前工作目录与可用工作目录
+//	/workspace <name>          为当前会话切换工作目录
+//	/workspace <name> channel  为当前渠道设置默认工作目录
+//	/workspace off [channel]   清除工作目录选择
+func (m *AgentManager) cmdWorkspace(ctx context.Context, c *command.Context) (string, error) {
+	if m.workspaces == nil {
+		return "工作目录切换未启用", nil
+	}
+
+	name := c.Arg(0)
+	if name == "" || name == "list" {
+		return m.renderWorkspaceList(c.Msg), nil
+	}
+
+	sessionID := c.Msg.SessionID
+	if c.Arg(1) == "channel" {
+		sessionID = ""
+	}
+	if name == "off" {
+		name = ""
+	}
+
+	entry, err := m.workspaces.Select(c.Msg.Channel, sessionID, name)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		current := m.workspaces.Current(c.Msg.Channel, c.Msg.SessionID)
+		return fmt.Sprintf("已清除工作目录选择，当前使用: %s", current.Name), nil
+	}
+	return fmt.Sprintf("已切换到工作目录: %s (%s)%s", entry.Name, entry.Dir, readOnlyMark(*entry)), nil
+}
+
+// readOnlyMark 只读工作目录的标记。
+func readOnlyMark(e workspace.Entry) string {
+	if e.ReadOnly {
+		return " [只读]"
+	}
+	return ""
+}
+
+// renderWorkspaceList 渲染工作目录列表。
+func (m *AgentManager) renderWorkspaceList(msg bus.InboundMessage) string {
+	sb := strings.Builder{}
+	current := m.workspaces.Current(msg.Channel, msg.SessionID)
+	sb.WriteString(fmt.Sprintf("当前工作目录: %s%s\n", current.Name, readOnlyMark(current)))
+
+	list := m.workspaces.List()
+	sb.WriteString("可用工作目录:\n")
+	for _, e := range list {
+		sb.WriteString(fmt.Sprintf("- %s: %s%s\n", e.Name, e.Dir, readOnlyMark(e)))
+	}
+	if len(list) == 1 {
+		sb.WriteString("可在配置的 agent.workspaces 中添加其他工作目录\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
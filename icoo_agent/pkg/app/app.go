@@ -4,25 +4,65 @@ import (
 	"context"
 	"fmt"
 	"icooclaw/pkg/agent"
+	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/audit"
+	"icooclaw/pkg/authz"
 	"icooclaw/pkg/bus"
 	"icooclaw/pkg/channels"
+	"icooclaw/pkg/clock"
+	"icooclaw/pkg/cluster"
 	"icooclaw/pkg/config"
 	"icooclaw/pkg/consts"
+	"icooclaw/pkg/digest"
+	"icooclaw/pkg/dispatch"
+	documentTool "icooclaw/pkg/document/tool"
+	"icooclaw/pkg/ephemeral"
+	"icooclaw/pkg/faq"
+	"icooclaw/pkg/form"
 	"icooclaw/pkg/gateway"
+	"icooclaw/pkg/gateway/auth"
 	"icooclaw/pkg/gateway/websocket"
+	"icooclaw/pkg/grpcapi"
+	"icooclaw/pkg/hooks"
+	"icooclaw/pkg/jobs"
+	"icooclaw/pkg/language"
 	"icooclaw/pkg/memory"
+	"icooclaw/pkg/notice"
+	"icooclaw/pkg/persona"
+	"icooclaw/pkg/postprocess"
 	"icooclaw/pkg/providers"
+	"icooclaw/pkg/routing"
 	"icooclaw/pkg/scheduler"
 	schedulerTool "icooclaw/pkg/scheduler/tool"
 	"icooclaw/pkg/skill"
 	skillTool "icooclaw/pkg/skill/tool"
+	"icooclaw/pkg/skill/trigger"
 	"icooclaw/pkg/storage"
 	"icooclaw/pkg/tools"
 	"icooclaw/pkg/tools/builtin"
+	artifactTool "icooclaw/pkg/tools/builtin/artifact"
+	askTool "icooclaw/pkg/tools/builtin/ask"
+	diagramTool "icooclaw/pkg/tools/builtin/diagram"
+	entityTool "icooclaw/pkg/tools/builtin/entity"
+	formTool "icooclaw/pkg/tools/builtin/form"
+	gitTool "icooclaw/pkg/tools/builtin/git"
+	kvTool "icooclaw/pkg/tools/builtin/kv"
+	outputTool "icooclaw/pkg/tools/builtin/output"
+	"icooclaw/pkg/tools/builtin/shell"
+	spreadsheetTool "icooclaw/pkg/tools/builtin/spreadsheet"
+	timezoneTool "icooclaw/pkg/tools/builtin/timezone"
+	varsTool "icooclaw/pkg/tools/builtin/vars"
+	"icooclaw/pkg/tools/plugin"
+	"icooclaw/pkg/toolserver"
+	"icooclaw/pkg/workspace"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -40,11 +80,31 @@ type App struct {
 	ToolRegistry    *tools.Registry      // 工具注册表
 	MemoryLoader    memory.Loader        // 记忆加载器
 	SkillLoader     skill.Loader         // skill 加载加载器
+	PersonaManager  *persona.Manager     // 人设管理器
+	Workspaces      *workspace.Manager   // 工作目录管理器
+	Timezones       *clock.Manager       // 用户时区管理器
+	Languages       *language.Manager    // 回复语言管理器
+	Ephemeral       *ephemeral.Registry  // 无痕会话注册表
 	AgentManager    *agent.AgentManager  // 代理管理器
 	AgentRegistry   *agent.AgentRegistry // 代理注册表
 	ChannelManager  *channels.Manager    // 渠道管理器
+	Deduper         *channels.Deduper    // 入站消息去重器，未启用时为 nil
 	Gw              *gateway.Server      // 网关服务器
+	Grpc            *grpcapi.Server      // gRPC 服务
 	Scheduler       *scheduler.Scheduler // 任务调度器
+	Cluster         *cluster.Node        // 集群实例，未启用时为 nil
+	Jobs            *jobs.Runner         // 后台作业执行器
+	FAQ             *faq.Matcher         // 常见问题匹配器，未启用时为 nil
+	Forms           *form.Registry       // 表单定义，未配置时为 nil
+	Digest          *digest.Runner       // 活动摘要邮件，未启用时为 nil
+	Permissions     *authz.Permissions   // 工具权限引擎，未启用时为 nil
+	ToolOutputs     *outputTool.Cache    // 被压缩的工具结果的完整输出，未启用压缩时为 nil
+	SkillTriggers   *trigger.Engine      // 技能触发引擎，未启用时为 nil
+	PluginMonitor   *plugin.Monitor      // 插件健康检查，未启用或没有插件时为 nil
+	PromptLogFile   *os.File             // 提示词日志文件
+	Audit           *audit.Recorder      // 工具调用审计，未启用时为 nil
+	AuditFile       *os.File             // 审计日志文件
+	ToolServer      *toolserver.Server   // 独立工具服务，只在 icooclaw toolserver 中创建
 }
 
 func NewApp() *App {
@@ -62,7 +122,15 @@ func (a *App) InitTool() {
 	a.ToolRegistry = tools.NewRegistry()
 
 	// 注册内置工具
-	builtin.RegisterBuiltinTools(a.ToolRegistry)
+	// 配置已在加载时校验，这里不会出错
+	execPolicy, _ := a.Cfg.Agent.Exec.Policy()
+	mounts, _ := a.Cfg.Agent.BuildMounts()
+	builtin.RegisterBuiltinTools(a.ToolRegistry, mounts, a.Cfg.Agent.Ignore.Options(),
+		shell.WithShell(a.Cfg.Agent.Exec.Shell),
+		shell.WithEnv(a.Cfg.Agent.Exec.EnvConfig()),
+		shell.WithPolicy(execPolicy),
+		shell.WithTailSize(a.Cfg.Agent.Exec.OutputTailKB*1024),
+	)
 
 	// 注册定时任务
 	schedulerTl := schedulerTool.NewTool(a.Storage.Task(), a.Scheduler, a.MessageBus, a.Logger)
@@ -71,11 +139,204 @@ func (a *App) InitTool() {
 	// 注册技能工具
 	skilltl := skillTool.NewInstallTool(a.Cfg.Agent.Workspace, a.Storage.Skill())
 	a.ToolRegistry.Register(skilltl)
+
+	// 注册键值存储工具
+	kvTool.RegisterTools(a.ToolRegistry, kvTool.NewStore(a.Storage.KV(), a.Storage.Session()))
+
+	// 注册实体图工具
+	a.ToolRegistry.Register(entityTool.NewRecallTool(a.Storage.Entity()))
+
+	// 注册会话变量工具
+	a.ToolRegistry.Register(varsTool.NewTool(varsTool.NewStore(a.Storage.Session())))
+
+	// 注册表单工具，配置了表单时智能体可为会话开始填写表单
+	if c := a.Cfg.Forms; len(c.Flows) > 0 {
+		forms, err := form.New(c.Flows, c.Options())
+		if err != nil {
+			slog.Error("表单配置无效，已忽略", "error", err)
+		} else {
+			a.Forms = forms
+			a.ToolRegistry.Register(formTool.NewTool(a.Forms, form.NewStore(a.Storage.Session())))
+		}
+	}
+
+	// 注册用户时区工具
+	a.ToolRegistry.Register(timezoneTool.NewTool(a.Timezones))
+
+	// 注册制品工具，制品保存到会话的工作目录
+	a.ToolRegistry.Register(artifactTool.NewTool(a.Storage.Artifact(), a.Cfg.Agent.Workspace))
+
+	// 注册表格工具，生成的 xlsx 保存为制品
+	a.ToolRegistry.Register(spreadsheetTool.NewTool(a.Storage.Artifact(), a.Cfg.Agent.Workspace))
+
+	// 注册文档生成工具，生成的文档保存为制品
+	a.ToolRegistry.Register(documentTool.NewTool(a.Storage.Artifact(), a.Cfg.Agent.Workspace,
+		a.Cfg.Agent.Documents.ChromePath, a.Cfg.Agent.Documents.PDFTimeout))
+
+	// 注册图表渲染工具，渲染结果保存为制品
+	if d := a.Cfg.Agent.Diagram; d.Enabled {
+		a.ToolRegistry.Register(diagramTool.NewTool(a.Storage.Artifact(), a.Cfg.Agent.Workspace, d.KrokiURL, d.Timeout))
+	}
+
+	// 注册 git 工具，不开放命令执行也能查看和提交修改
+	if g := a.Cfg.Agent.Git; g.Enabled && gitTool.Available() {
+		gitTool.RegisterTools(a.ToolRegistry, a.Cfg.Agent.Workspace, gitTool.Options{AuthorName: g.AuthorName, AuthorEmail: g.AuthorEmail})
+	}
+
+	// 注册完整输出读取工具，过长的工具结果压缩后可按 ID 读回
+	if c := a.Cfg.Agent.ToolCondense; c.Enabled {
+		a.ToolOutputs = outputTool.NewCache(c.CacheMB<<20, c.CacheTTL)
+		a.ToolRegistry.Register(outputTool.NewTool(a.ToolOutputs))
+	}
+
+	// 注册提问工具，智能体可在对话中向用户提出澄清问题
+	if a.Cfg.Agent.AskUser.Enabled {
+		a.ToolRegistry.Register(askTool.NewTool())
+	}
+
+	// 注册插件工具，放在最后以免覆盖内置工具
+	if p := a.Cfg.Agent.Plugins; p.Enabled {
+		names := plugin.Register(a.ToolRegistry, p.Dir, a.Cfg.Agent.Workspace, a.Logger)
+		if p.Health.Enabled && len(names) > 0 {
+			a.PluginMonitor = plugin.NewMonitor(a.ToolRegistry, names, p.Health.HealthConfig(), a.Logger).
+				OnDisable(a.publishToolDisabled)
+		}
+	}
+
+	// 可选工具只提供给通过会话工具策略启用它们的会话
+	a.ToolRegistry.SetOptional(a.Cfg.Agent.OptionalTools...)
+
+	// 记录每次工具调用的审计日志
+	a.initAudit()
+
+	// 工具执行超时，超时后立即返回超时结果，不等待忽略上下文的工具
+	a.ToolRegistry.SetTimeout(a.Cfg.Agent.ToolTimeout)
+	for name, timeout := range a.Cfg.Agent.ToolTimeouts {
+		a.ToolRegistry.SetToolTimeout(name, timeout)
+	}
+}
+
+// initAudit 按配置为工具注册表接入审计记录器
+func (a *App) initAudit() {
+	cfg := a.Cfg.Audit
+	if !cfg.Enabled {
+		return
+	}
+
+	var file *os.File
+	if cfg.File != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+			slog.Error("创建审计日志目录失败", "error", err)
+			return
+		}
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			slog.Error("打开审计日志失败", "error", err)
+			return
+		}
+		file = f
+	}
+
+	var w io.Writer
+	if file != nil {
+		w = file
+	}
+	recorder, err := audit.New(a.Storage.Audit(), w, audit.Config{Keep: cfg.Keep, Redact: cfg.Redact}, a.Logger)
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		slog.Error("创建工具审计失败", "error", err)
+		return
+	}
+	a.Audit = recorder
+	a.AuditFile = file
+	a.ToolRegistry.SetAuditor(recorder)
+	slog.Info("工具调用审计已启用", "file", cfg.File)
+}
+
+// publishToolDisabled 发布插件停用事件，可由事件触发器通知管理员
+func (a *App) publishToolDisabled(d plugin.Disabled) {
+	a.MessageBus.PublishEvent(bus.Event{
+		Type: bus.EventToolDisabled,
+		Data: map[string]any{
+			"tool":      d.Name,
+			"reason":    d.Reason,
+			"self_test": d.SelfTest,
+		},
+	})
+}
+
+// toolCondense 按配置生成工具结果压缩规则，未启用时返回 nil
+func (a *App) toolCondense() *react.ToolCondense {
+	c := a.Cfg.Agent.ToolCondense
+	if !c.Enabled {
+		return nil
+	}
+
+	cond := &react.ToolCondense{
+		Default:   react.CondensePolicy{Mode: c.Mode, Threshold: c.Threshold},
+		Tools:     make(map[string]react.CondensePolicy, len(c.Tools)),
+		Model:     c.Model,
+		MaxTokens: c.MaxTokens,
+		Cache:     a.ToolOutputs,
+	}
+	// 工具规则中未设置的字段沿用默认值
+	for name, r := range c.Tools {
+		p := cond.Default
+		if r.Mode != "" {
+			p.Mode = r.Mode
+		}
+		if r.Threshold > 0 {
+			p.Threshold = r.Threshold
+		}
+		cond.Tools[name] = p
+	}
+	return cond
+}
+
+// initPromptLog 按配置为提供商工厂接入提示词日志
+func (a *App) initPromptLog(factory *providers.Factory) {
+	cfg := a.Cfg.Logging.Prompt
+	mode, err := providers.ParsePromptLogMode(cfg.Mode)
+	if err != nil || mode == providers.PromptLogOff {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		slog.Error("创建提示词日志目录失败", "error", err)
+		return
+	}
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		slog.Error("打开提示词日志失败", "error", err)
+		return
+	}
+
+	promptLog, err := providers.NewPromptLog(mode, file, cfg.Redact)
+	if err != nil {
+		file.Close()
+		slog.Error("创建提示词日志失败", "error", err)
+		return
+	}
+	a.PromptLogFile = file
+	factory.WithPromptLog(promptLog)
+	slog.Info("提示词日志已启用", "mode", mode, "path", cfg.Path)
 }
 
 // InitProvider 初始化提供商工厂
 func (a *App) InitProvider() {
-	factory := providers.NewFactory(a.Storage)
+	factory := providers.NewFactory(a.Storage).WithRetry(a.Cfg.Agent.ProviderRetry.RetryConfig())
+	if h := a.Cfg.Agent.ProviderHealth; h.Enabled {
+		factory.WithHealth(providers.HealthConfig{
+			Interval:         h.Interval,
+			Timeout:          h.Timeout,
+			FailureThreshold: h.FailureThreshold,
+			Cooldown:         h.Cooldown,
+			Fallbacks:        h.Fallbacks,
+		})
+	}
+	a.initPromptLog(factory)
 
 	// 获取默认提供商
 	var defaultProvider providers.Provider
@@ -101,9 +362,33 @@ func (a *App) InitProvider() {
 	a.ProviderFactory = factory
 }
 
-// InitMemory 初始化记忆加载器
+// InitAuthz 加载工具权限规则和授权策略，处理消息和执行工具前都经过授权
+func (a *App) InitAuthz() error {
+	authorizer := authz.New(a.Logger)
+	if p := a.Cfg.Agent.ToolPermissions; p.Enabled {
+		permissions, err := authz.NewPermissions(p.Rules, a.Cfg.Agent.Workspace, a.Storage.Param(), a.Logger)
+		if err != nil {
+			return fmt.Errorf("加载工具权限规则失败: %w", err)
+		}
+		authorizer.Use(permissions)
+		a.Permissions = permissions
+	}
+	if a.Cfg.Agent.Authz.Enabled {
+		engine, err := authz.NewEngine(a.Cfg.Agent.PolicyDir(), a.Logger)
+		if err != nil {
+			return fmt.Errorf("加载授权策略失败: %w", err)
+		}
+		authorizer.Use(engine)
+	}
+	a.ToolRegistry.SetAuthorizer(authorizer)
+	a.AgentManager.WithAuthorizer(authorizer)
+	return nil
+}
+
+// InitMemory 初始化记忆加载器，无痕会话的消息只保存在内存中
 func (a *App) InitMemory() {
-	a.MemoryLoader = memory.NewLoader(a.Storage, 100, slog.Default())
+	a.Ephemeral = ephemeral.NewRegistry(a.Cfg.Agent.Ephemeral.Dir, a.Logger)
+	a.MemoryLoader = ephemeral.NewLoader(memory.NewLoader(a.Storage, 100, slog.Default()), a.Ephemeral)
 }
 
 // InitSkill 初始化 skill 加载器
@@ -111,10 +396,37 @@ func (a *App) InitSkill() {
 	a.SkillLoader = skill.NewLoader(a.Cfg.Agent.Workspace, a.Storage, slog.Default())
 }
 
+// InitSkillTriggers 初始化技能触发引擎，定时触发只在主实例上执行
+func (a *App) InitSkillTriggers() {
+	c := a.Cfg.Agent.SkillTriggers
+	a.SkillTriggers = trigger.New(a.Storage, a.MessageBus, trigger.Config{MaxActive: c.MaxActive, AuditKeep: c.AuditKeep}, a.Logger)
+	if err := a.SkillTriggers.Reload(); err != nil {
+		slog.Warn("加载技能触发规则失败", "error", err)
+	}
+	if a.Cluster != nil {
+		a.SkillTriggers.SetLeader(a.Cluster.IsLeader)
+	}
+	a.AgentManager.WithSkillTriggers(a.SkillTriggers)
+}
+
+// InitPersona 初始化人设管理器，并监听人设文件变更
+func (a *App) InitPersona() {
+	a.PersonaManager = persona.NewManager(a.Cfg.Agent.Workspace, a.Storage, slog.Default())
+	if err := a.PersonaManager.Load(); err != nil {
+		slog.Warn("加载人设失败", "error", err)
+	}
+	go a.PersonaManager.Watch(a.Ctx, 5*time.Second)
+}
+
 // InitStorage 初始化存储
 func (a *App) InitStorage() {
 	dbPath, _ := a.Cfg.GetDatabasePath()
-	store, err := storage.New(a.Cfg.Agent.Workspace, a.Cfg.Mode, dbPath)
+	opts, err := a.Cfg.StorageOptions()
+	if err != nil {
+		slog.Error("初始化存储失败", "error", err)
+		os.Exit(1)
+	}
+	store, err := storage.New(a.Cfg.Agent.Workspace, a.Cfg.Mode, dbPath, opts...)
 	if err != nil {
 		slog.Error("初始化存储失败", "error", err)
 		os.Exit(1)
@@ -148,17 +460,35 @@ func (a *App) InitConfig(cfgFile string) error {
 	return nil
 }
 
+// InitWorkspace 用配置的档案模板补全工作目录中缺失的文件，已存在的文件不会被覆盖。
+func (a *App) InitWorkspace() {
+	opts := a.Cfg.Agent.Templates.InitOptions("")
+	result, err := workspace.Init(a.Cfg.Agent.Workspace, opts)
+	if err != nil {
+		a.Logger.Warn("初始化工作目录失败", "profile", opts.Profile, "error", err)
+		return
+	}
+	if len(result.Created) > 0 {
+		a.Logger.Info("已根据模板补全工作目录", "profile", opts.Profile, "source", result.Source, "files", result.Created)
+	}
+}
+
 // InitLog 初始化日志记录器
 func (a *App) InitLog() *slog.Logger {
+	return a.initLogTo(os.Stdout)
+}
+
+// initLogTo 初始化输出到 w 的日志记录器并设为默认
+func (a *App) initLogTo(w io.Writer) *slog.Logger {
 	opts := &slog.HandlerOptions{
 		Level: parseLogLevel(a.Cfg.Logging.Level),
 	}
 
 	var handler slog.Handler
 	if a.Cfg.Logging.Format == "json" {
-		handler = slog.NewJSONHandler(os.Stdout, opts)
+		handler = slog.NewJSONHandler(w, opts)
 	} else {
-		handler = slog.NewTextHandler(os.Stdout, opts)
+		handler = slog.NewTextHandler(w, opts)
 	}
 
 	logger := slog.New(handler)
@@ -174,22 +504,107 @@ func (a *App) InitChannel() {
 	if err := channelManager.InitChannels(a.Ctx); err != nil {
 		slog.Warn("初始化渠道失败", "error", err)
 	}
+	if f := a.Cfg.Channels.Feishu; f.Enabled {
+		err := channelManager.AddChannel("feishu", "feishu", map[string]any{
+			"app_id":             f.AppID,
+			"app_secret":         f.AppSecret,
+			"encrypt_key":        f.EncryptKey,
+			"verification_token": f.VerificationToken,
+			"allow_from":         f.AllowFrom,
+			"reasoning_chat_id":  f.ReasoningChatID,
+			"mode":               f.Mode,
+			"webhook_path":       f.WebhookPath,
+			"group_reply_all":    f.GroupReplyAll,
+		})
+		if err != nil {
+			slog.Warn("初始化飞书渠道失败", "error", err)
+		}
+	}
+	if d := a.Cfg.Channels.DingTalk; d.Enabled {
+		err := channelManager.AddChannel("dingtalk", "dingtalk", map[string]any{
+			"client_id":         d.ClientID,
+			"client_secret":     d.ClientSecret,
+			"agent_id":          d.AgentID,
+			"allow_from":        d.AllowFrom,
+			"reasoning_chat_id": d.ReasoningChatID,
+		})
+		if err != nil {
+			slog.Warn("初始化钉钉渠道失败", "error", err)
+		}
+	}
+	if w := a.Cfg.Channels.WeCom; w.Enabled {
+		err := channelManager.AddChannel("wecom", "wecom", map[string]any{
+			"corp_id":           w.CorpID,
+			"agent_id":          w.AgentID,
+			"secret":            w.Secret,
+			"token":             w.Token,
+			"encoding_aes_key":  w.EncodingAESKey,
+			"webhook_path":      w.WebhookPath,
+			"message_type":      w.MessageType,
+			"external_users":    w.ExternalUsers,
+			"allow_from":        w.AllowFrom,
+			"reasoning_chat_id": w.ReasoningChatID,
+		})
+		if err != nil {
+			slog.Warn("初始化企业微信渠道失败", "error", err)
+		}
+	}
+	if q := a.Cfg.Channels.QQ; q.Enabled {
+		err := channelManager.AddChannel("qq", "qq", map[string]any{
+			"app_id":            q.AppID,
+			"app_secret":        q.AppSecret,
+			"sandbox":           q.Sandbox,
+			"allow_from":        q.AllowFrom,
+			"reasoning_chat_id": q.ReasoningChatID,
+		})
+		if err != nil {
+			slog.Warn("初始化 QQ 渠道失败", "error", err)
+		}
+	}
 
 	// 设置渠道管理器
 	a.ChannelManager = channelManager
+
+	// 入站消息去重，丢弃平台重复投递的消息
+	if ttl := a.Cfg.Channels.DedupTTL; ttl > 0 {
+		a.Deduper = channels.NewDeduper(a.Storage.Dedup(), ttl, a.Logger)
+	}
 }
 
 // InitGateway 初始化网关服务器
 func (a *App) InitGateway() {
 	// 创建网关服务器配置
+	gwCfg := a.Cfg.Gateway
 	serverCfg := gateway.DefaultServerConfig()
-	if a.Cfg.Gateway.Port > 0 {
-		serverCfg.Addr = fmt.Sprintf(":%d", a.Cfg.Gateway.Port)
+	if gwCfg.Port > 0 {
+		serverCfg.Addr = net.JoinHostPort(gwCfg.Host, strconv.Itoa(gwCfg.Port))
 	}
+	serverCfg.UnixSocket = gwCfg.UnixSocket
+	serverCfg.TrustedProxies = gwCfg.TrustedProxies
+	serverCfg.TLSCertFile = gwCfg.TLS.CertFile
+	serverCfg.TLSKeyFile = gwCfg.TLS.KeyFile
+	serverCfg.AutocertDomains = gwCfg.TLS.AutocertDomains
+	serverCfg.AutocertCacheDir = gwCfg.TLS.AutocertCacheDir
+	serverCfg.AutocertEmail = gwCfg.TLS.AutocertEmail
+
+	// 接口认证，配置已在加载时校验，这里不会出错
+	authenticator, _ := gwCfg.Auth.Authenticator()
 
 	// 创建 WebSocket 管理器
+	wsCfg := websocket.DefaultManagerConfig()
+	if authenticator != nil {
+		// 认证中间件已校验凭证，这里只取出用户 ID
+		wsCfg.Authenticate = func(r *http.Request) (string, bool) {
+			id := auth.FromContext(r.Context())
+			if id == nil {
+				return "", false
+			}
+			return id.Subject, true
+		}
+	}
+	serverCfg.AuthEnabled = wsCfg.Authenticate != nil
 	wsManager := websocket.NewManager(
-		websocket.DefaultManagerConfig(),
+		wsCfg,
 		a.Logger,
 	)
 	wsManager.WithAgentManager(a.AgentManager)
@@ -203,7 +618,89 @@ func (a *App) InitGateway() {
 		a.MessageBus,
 		wsManager,
 		a.AgentManager,
-	).WithSSE().Setup()
+	).WithSSE().WithProviderFactory(a.ProviderFactory).WithToolRegistry(a.ToolRegistry).
+		WithMemoryScore(a.Cfg.Agent.MemoryDecay.ScoreConfig()).WithDeduper(a.Deduper).
+		WithWorkspaces(a.Workspaces).WithEphemeral(a.Ephemeral).WithJobs(a.Jobs).WithFAQ(a.FAQ).
+		WithPermissions(a.Permissions).WithPluginMonitor(a.PluginMonitor).WithSkillTriggers(a.SkillTriggers).
+		WithChannels(a.ChannelManager).WithAuth(authenticator).Setup()
+
+	a.InitGRPC()
+}
+
+// InitJobs 初始化后台作业执行器并注册作业类型
+func (a *App) InitJobs() {
+	owner := cluster.DefaultID()
+	if a.Cluster != nil {
+		owner = a.Cluster.ID()
+	}
+	cfg := a.Cfg.Agent.Jobs
+	a.Jobs = jobs.NewRunner(a.Storage.Job(), jobs.Config{BatchSize: cfg.BatchSize, Interval: cfg.Interval}, owner, a.Logger)
+	a.Jobs.Register(jobs.TypeEmbedMemories, jobs.NewEmbedMemories(a.Storage, a.ProviderFactory, cfg.EmbeddingModel))
+	a.Jobs.Register(agent.JobResummarizeSessions, a.AgentManager.ResummarizeJob())
+	if a.Cluster != nil {
+		a.Jobs.SetLeader(a.Cluster.IsLeader)
+	}
+}
+
+// InitDigest 创建活动摘要邮件发送器，只由主实例发送
+func (a *App) InitDigest() {
+	d := a.Cfg.Digest
+	// 配置已在加载时校验，这里不会出错
+	schedule, _ := d.Schedule()
+	a.Digest = digest.NewRunner(a.Storage, schedule, d.Options(), a.Cfg.SMTP.Mail(), d.Recipients, d.SkipEmpty, a.Logger)
+	a.Digest.SetPending(a.AgentManager.PendingApprovals)
+	if a.Cluster != nil {
+		a.Digest.SetLeader(a.Cluster.IsLeader)
+	}
+}
+
+// InitHeartbeat 注册心跳，免打扰时段和运行时段按用户时区判断，用户近期活跃或并发已满时跳过
+func (a *App) InitHeartbeat() {
+	// 配置已在加载时校验，这里不会出错
+	h := a.Cfg.Agent.Heartbeat
+	list, _ := h.Heartbeats(a.Cfg.Agent.Workspace)
+	a.Scheduler.SetSessions(a.Storage.Session())
+	a.Scheduler.SetLocation(a.Timezones.Current)
+	a.Scheduler.SetHeartbeatLimit(h.MaxConcurrent, h.RunTimeout)
+	go a.Scheduler.WatchHeartbeats(a.Ctx)
+	for _, hb := range list {
+		if hb.Persona != "" {
+			if _, ok := a.PersonaManager.Get(hb.Persona); !ok {
+				slog.Warn("心跳的人设不存在，将由默认智能体处理", "persona", hb.Persona)
+			}
+		}
+		if err := a.Scheduler.AddHeartbeat(hb); err != nil {
+			slog.Error("添加心跳失败", "error", err)
+		}
+	}
+}
+
+// InitGRPC 初始化 gRPC 服务
+func (a *App) InitGRPC() {
+	grpcCfg := a.Cfg.Gateway.GRPC
+	if !grpcCfg.Enabled {
+		return
+	}
+
+	srv, err := grpcapi.NewServer(
+		&grpcapi.Config{
+			Addr:        net.JoinHostPort(a.Cfg.Gateway.Host, strconv.Itoa(grpcCfg.Port)),
+			TLSCertFile: a.Cfg.Gateway.TLS.CertFile,
+			TLSKeyFile:  a.Cfg.Gateway.TLS.KeyFile,
+			Reflection:  grpcCfg.Reflection,
+		},
+		a.Logger,
+		a.Storage,
+		a.AgentManager,
+		a.ToolRegistry,
+	)
+	if err != nil {
+		a.Logger.With("name", "【gRPC服务】").Error("创建失败", "error", err)
+		return
+	}
+	// 与 HTTP 网关使用相同的认证，配置已在加载时校验，这里不会出错
+	authenticator, _ := a.Cfg.Gateway.Auth.Authenticator()
+	a.Grpc = srv.WithAuth(authenticator)
 }
 
 func (a *App) Init(path string) error {
@@ -215,6 +712,8 @@ func (a *App) Init(path string) error {
 	}
 	// 初始化日志
 	a.Logger = a.InitLog()
+	// 补全工作目录缺失的文件
+	a.InitWorkspace()
 	// 初始化存储
 	a.InitStorage()
 	// 初始化消息总线
@@ -225,12 +724,23 @@ func (a *App) Init(path string) error {
 		a.MessageBus,
 		a.Logger,
 	)
+	// 初始化时区管理器，配置已在加载时校验，这里不会出错
+	location, _ := a.Cfg.Agent.Prompt.Location()
+	channelLocations, _ := a.Cfg.Agent.ChannelLocations()
+	a.Timezones = clock.NewManager(location, channelLocations, a.Storage, a.Logger)
+	// 初始化回复语言管理器，未配置默认语言时 Normalize 返回空串
+	replyLanguage, _ := language.Normalize(a.Cfg.Agent.ReplyLanguage)
+	a.Languages = language.NewManager(replyLanguage, a.Storage, a.Logger)
 	// 初始化工具
 	a.InitTool()
 	// 初始化记忆加载器
 	a.InitMemory()
 	// 初始化 skill 加载器
 	a.InitSkill()
+	// 初始化人设管理器
+	a.InitPersona()
+	// 初始化工作目录管理器
+	a.Workspaces = workspace.NewManager(a.Cfg.Agent.WorkspaceList(), a.Storage, a.Logger)
 	// 初始化提供商工厂
 	a.InitProvider()
 	// 初始化渠道
@@ -242,7 +752,122 @@ func (a *App) Init(path string) error {
 		WithMemory(a.MemoryLoader).
 		WithTools(a.ToolRegistry).
 		WithSkills(a.SkillLoader).
-		WithStorage(a.Storage)
+		WithPersonas(a.PersonaManager).
+		WithWorkspaces(a.Workspaces).
+		WithTimezones(a.Timezones).
+		WithLanguages(a.Languages).
+		WithEphemeral(a.Ephemeral, a.Cfg.Agent.Ephemeral.IdleTimeout, a.Cfg.Agent.Ephemeral.DenyTools).
+		WithStorage(a.Storage).
+		WithAdmins(a.Cfg.Agent.Admins).
+		WithSessionIdle(a.Cfg.Agent.SessionIdleTimeout, a.Cfg.Agent.SessionSweepInterval).
+		WithStatusUpdates(a.Cfg.Agent.StatusInterval).
+		WithToolNotes(a.Cfg.Agent.ToolNotes).
+		WithTurnBudget(a.Cfg.Agent.TurnBudget).
+		WithToolRepeatLimit(a.Cfg.Agent.ToolRepeatLimit).
+		WithToolCondense(a.toolCondense()).
+		WithMemoryDecay(a.Cfg.Agent.MemoryDecay.ConsolidateConfig(),
+			a.Cfg.Agent.MemoryDecay.RecallLimit,
+			a.Cfg.Agent.MemoryDecay.ConsolidateInterval).
+		WithSemanticRecall(a.Cfg.Agent.SemanticRecallModel(),
+			a.Cfg.Agent.SemanticRecall.TopK,
+			a.Cfg.Agent.SemanticRecall.MinSimilarity,
+			a.Cfg.Agent.SemanticRecall.EmbedMissing).
+		WithEntityGraph(a.Cfg.Agent.EntityGraph.Extract, a.Cfg.Agent.EntityGraph.RecallLimit)
+	a.AgentManager.WithPromptSections(react.PromptSections{
+		DateTime:         a.Cfg.Agent.Prompt.DateTime,
+		Location:         location,
+		WorkspaceEntries: a.Cfg.Agent.Prompt.WorkspaceEntries,
+		Persona:          a.Cfg.Agent.Prompt.Persona,
+		Language:         a.Cfg.Agent.Prompt.Language,
+		Tools:            a.Cfg.Agent.Prompt.Tools,
+		Memory:           a.Cfg.Agent.Prompt.Memory,
+	})
+	if t := a.Cfg.Agent.Trace; t.Enabled {
+		a.AgentManager.WithTrace(t.Keep, t.MaxResultChars)
+	}
+	if d := a.Cfg.Agent.MemoryDigest; d.Enabled {
+		a.AgentManager.WithMemoryDigest(a.Cfg.Agent.MemoryDigestConfig(), d.Interval, d.Channel, d.SessionID)
+	}
+	if a.Cfg.Agent.Authz.Enabled || a.Cfg.Agent.ToolPermissions.Enabled {
+		if err := a.InitAuthz(); err != nil {
+			return err
+		}
+	}
+	if h := a.Cfg.Agent.Hooks; h.Enabled {
+		kv := kvTool.NewStore(a.Storage.KV(), a.Storage.Session())
+		scriptHooks, err := hooks.NewScriptHooks(a.Cfg.Agent.HooksDir(), h.ScriptConfig(a.Cfg.Agent.Workspace, kv), h.Timeout, a.Logger)
+		if err != nil {
+			return fmt.Errorf("加载钩子脚本失败: %w", err)
+		}
+		a.AgentManager.WithHooks(scriptHooks)
+	}
+	if h := a.Cfg.Agent.Heartbeat; h.Enabled {
+		a.InitHeartbeat()
+	}
+	if c := a.Cfg.Cluster; c.Enabled {
+		a.Cluster = cluster.NewNode(c.InstanceID, a.Storage.Lock(), c.LeaseTTL, a.Logger)
+		a.Scheduler.SetLeader(a.Cluster.IsLeader)
+		a.AgentManager.WithCluster(a.Cluster)
+	}
+	a.InitJobs()
+	if a.Deduper != nil {
+		a.AgentManager.WithDeduper(a.Deduper)
+	}
+	if a.Cfg.Agent.OfflineQueue {
+		a.AgentManager.WithOfflineQueue(a.Cfg.Agent.OfflineRetryInterval, a.Cfg.Agent.OfflineReplayInterval)
+	}
+	if rules := a.Cfg.PostProcess.Rules; len(rules) > 0 {
+		pipeline, err := postprocess.New(rules)
+		if err != nil {
+			slog.Error("回复后处理规则无效，已忽略", "error", err)
+		} else {
+			a.AgentManager.WithPostProcess(pipeline)
+		}
+	}
+	if rules := a.Cfg.Routing.Rules; len(rules) > 0 {
+		router, err := routing.New(rules)
+		if err != nil {
+			slog.Error("路由规则无效，已忽略", "error", err)
+		} else {
+			a.AgentManager.WithRouter(router)
+		}
+	}
+	// 系统提示默认使用回复语言
+	noticeLanguage := a.Cfg.Notices.Language
+	if noticeLanguage == "" {
+		noticeLanguage = a.Cfg.Agent.ReplyLanguage
+	}
+	if notices, err := notice.New(noticeLanguage, a.Cfg.Notices.Templates, a.Logger); err != nil {
+		slog.Error("系统提示模板无效，使用内置文本", "error", err)
+	} else {
+		a.AgentManager.WithNotices(notices)
+	}
+	if a.Forms != nil {
+		a.AgentManager.WithForms(a.Forms, form.NewStore(a.Storage.Session()))
+	}
+	if c := a.Cfg.Agent.CostPreview; c.Enabled {
+		a.AgentManager.WithCostPreview(agent.CostThreshold{MinTokens: c.MinTokens, MinCost: c.MinCost}, c.ToolRounds, c.Expire)
+	}
+	if c := a.Cfg.Agent.WorkerPool; c.Enabled {
+		a.AgentManager.WithWorkerPool(dispatch.Config{
+			Workers:        c.Workers,
+			MaxQueued:      c.MaxQueued,
+			NotifyInterval: c.NotifyInterval,
+		}, c.NotifyPosition)
+	}
+	if c := a.Cfg.Agent.AskUser; c.Enabled {
+		a.AgentManager.WithAskUser(askTool.NewStore(a.Storage.Session()), c.Timeout)
+	}
+	if c := a.Cfg.Agent.SkillTriggers; c.Enabled {
+		a.InitSkillTriggers()
+	}
+	if c := a.Cfg.Agent.FAQ; c.Enabled {
+		a.FAQ = faq.NewMatcher(a.Storage.FAQ(), faq.Config{Mode: c.Mode, Threshold: c.Threshold})
+		a.AgentManager.WithFAQ(a.FAQ)
+	}
+	if d := a.Cfg.Digest; d.Enabled {
+		a.InitDigest()
+	}
 
 	// 初始化网关服务器
 	a.InitGateway()
@@ -259,9 +884,67 @@ func (a *App) RunGateway() {
 		}
 	}()
 
+	// 参与选主，定时任务和全局后台任务只在主实例上运行
+	if a.Cluster != nil {
+		go a.Cluster.Run(a.Ctx)
+	}
+
 	// 启动任务调度器
 	a.Scheduler.Start()
 
+	// 启动后台作业执行
+	go a.Jobs.Run(a.Ctx)
+
+	// 启动空闲会话检查
+	go a.AgentManager.RunSessionSweeper(a.Ctx)
+
+	// 启动空闲无痕会话清除
+	go a.AgentManager.RunEphemeralSweeper(a.Ctx)
+
+	// 启动离线队列处理
+	go a.AgentManager.RunOfflineQueue(a.Ctx)
+
+	// 启动低分记忆合并
+	go a.AgentManager.RunMemoryConsolidator(a.Ctx)
+
+	// 启动记忆回顾
+	go a.AgentManager.RunMemoryDigest(a.Ctx)
+
+	// 启动活动摘要邮件
+	if a.Digest != nil {
+		go a.Digest.Run(a.Ctx)
+	}
+
+	// 启动技能定时触发和事件触发
+	if a.SkillTriggers != nil {
+		go a.SkillTriggers.Run(a.Ctx)
+	}
+
+	// 定期清理过期的审计记录
+	if a.Audit != nil {
+		go a.Audit.Run(a.Ctx)
+	}
+
+	// 启动插件自检和运行失败检查
+	if a.PluginMonitor != nil {
+		go a.PluginMonitor.Run(a.Ctx)
+	}
+
+	// 启动提供商健康检查，并探测本地模型的上下文长度
+	if a.ProviderFactory != nil {
+		go a.ProviderFactory.RunHealthChecks(a.Ctx)
+		go a.ProviderFactory.DiscoverModels(a.Ctx)
+	}
+
+	// 启动 gRPC 服务
+	if a.Grpc != nil {
+		go func() {
+			if err := a.Grpc.Start(); err != nil {
+				slog.Error("gRPC 服务错误", "error", err)
+			}
+		}()
+	}
+
 	// 启动网关服务器
 	err := a.Gw.Start()
 	if err != nil && err != http.ErrServerClosed {
@@ -298,6 +981,13 @@ func (a *App) RunGateway() {
 			slog.Error("网关服务关闭失败", "error", err)
 		}
 
+		// 关闭 gRPC 服务
+		if a.Grpc != nil {
+			if err := a.Grpc.Shutdown(shutdownCtx); err != nil {
+				slog.Error("gRPC 服务关闭失败", "error", err)
+			}
+		}
+
 		// 取消上下文
 		a.Cancel()
 	}()
@@ -309,6 +999,16 @@ func (a *App) Close() {
 		a.Cancel()
 	}
 
+	// 关闭提示词日志
+	if a.PromptLogFile != nil {
+		a.PromptLogFile.Close()
+	}
+
+	// 关闭审计日志
+	if a.AuditFile != nil {
+		a.AuditFile.Close()
+	}
+
 	// 关闭存储
 	if a.Storage != nil {
 		a.Storage.Close()
@@ -319,6 +1019,9 @@ func (a *App) Close() {
 		a.AgentManager.Stop()
 	}
 	a.AgentManager = nil
+
+	// 清除全部无痕会话及其临时目录
+	a.Ephemeral.CloseAll()
 }
 
 func parseLogLevel(level string) slog.Level {
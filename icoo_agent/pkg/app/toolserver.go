@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"icooclaw/pkg/authz"
+	"icooclaw/pkg/config"
+	"icooclaw/pkg/script"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin"
+	gitTool "icooclaw/pkg/tools/builtin/git"
+	kvTool "icooclaw/pkg/tools/builtin/kv"
+	"icooclaw/pkg/tools/builtin/shell"
+	"icooclaw/pkg/tools/plugin"
+	"icooclaw/pkg/toolserver"
+)
+
+// InitToolServer 初始化独立工具服务，只加载文件、网络、命令、git、JavaScript 和插件工具，
+// 不创建智能体、提供商和渠道，只在启用 JavaScript 工具时连接数据库，为脚本的 kv 对象提供存储。
+// 日志输出到标准错误，以免干扰 MCP stdio 协议。
+func (a *App) InitToolServer(path, version string) error {
+	a.Ctx, a.Cancel = context.WithCancel(context.Background())
+	cfg, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if err := cfg.EnsureWorkspace(); err != nil {
+		return fmt.Errorf("创建工作目录失败: %w", err)
+	}
+	a.Cfg = cfg
+	a.Logger = a.initLogTo(os.Stderr)
+
+	// 注册工具，配置已在加载时校验，这里不会出错
+	a.ToolRegistry = tools.NewRegistryWithLogger(a.Logger)
+	execPolicy, _ := cfg.Agent.Exec.Policy()
+	mounts, _ := cfg.Agent.BuildMounts()
+	builtin.RegisterBuiltinTools(a.ToolRegistry, mounts, cfg.Agent.Ignore.Options(),
+		shell.WithShell(cfg.Agent.Exec.Shell),
+		shell.WithEnv(cfg.Agent.Exec.EnvConfig()),
+		shell.WithPolicy(execPolicy),
+		shell.WithTailSize(cfg.Agent.Exec.OutputTailKB*1024),
+	)
+	if g := cfg.Agent.Git; g.Enabled && gitTool.Available() {
+		gitTool.RegisterTools(a.ToolRegistry, cfg.Agent.Workspace, gitTool.Options{AuthorName: g.AuthorName, AuthorEmail: g.AuthorEmail})
+	}
+	if s := cfg.ToolServer.Script; s.Enabled {
+		a.InitStorage()
+		kv := kvTool.NewStore(a.Storage.KV(), a.Storage.Session())
+		script.RegisterScriptTools(a.ToolRegistry, s.ScriptConfig(cfg.Agent.Workspace, kv), a.Logger)
+	}
+	if p := cfg.Agent.Plugins; p.Enabled {
+		plugin.Register(a.ToolRegistry, p.Dir, cfg.Agent.Workspace, a.Logger)
+	}
+
+	// 授权策略同样约束工具服务的调用；工具权限规则依赖数据库，这里不加载
+	if cfg.Agent.Authz.Enabled {
+		engine, err := authz.NewEngine(cfg.Agent.PolicyDir(), a.Logger)
+		if err != nil {
+			return fmt.Errorf("加载授权策略失败: %w", err)
+		}
+		authorizer := authz.New(a.Logger)
+		authorizer.Use(engine)
+		a.ToolRegistry.SetAuthorizer(authorizer)
+	}
+
+	srv, err := toolserver.New(&toolserver.Config{
+		Addr:      cfg.ToolServer.Addr,
+		Workspace: cfg.Agent.Workspace,
+		Tools:     cfg.ToolServer.Tools,
+		ReadOnly:  cfg.ToolServer.ReadOnly || cfg.Agent.WorkspaceReadOnly,
+		Version:   version,
+	}, a.ToolRegistry, a.Logger)
+	if err != nil {
+		return fmt.Errorf("toolserver.tools 配置错误: %w", err)
+	}
+	authenticator, _ := cfg.Gateway.Auth.Authenticator()
+	a.ToolServer = srv.WithAuth(authenticator)
+	return nil
+}
+
+// RunToolServer 运行工具服务直到收到退出信号。stdio 为 true 时通过标准输入输出提供 MCP 服务，
+// 否则在 toolserver.addr 上提供 REST 和 MCP 接口。
+func (a *App) RunToolServer(stdio bool) error {
+	ctx, stop := signal.NotifyContext(a.Ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if stdio {
+		return a.ToolServer.ServeStdio(ctx, os.Stdin, os.Stdout)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- a.ToolServer.Start()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := a.ToolServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
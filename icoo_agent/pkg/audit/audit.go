@@ -0,0 +1,172 @@
+// Package audit 记录工具调用审计日志：每次调用写入数据库中的哈希链，并可同时追加到 JSON Lines 文件。
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/utils"
+)
+
+// pruneInterval 清理过期审计记录的间隔
+const pruneInterval = time.Hour
+
+// Config 审计配置。
+type Config struct {
+	// Keep 审计记录保留时长，0 表示永久保留
+	Keep time.Duration
+	// Redact 额外的脱敏正则，参数和错误信息中匹配的内容替换为 [REDACTED]
+	Redact []string
+}
+
+// Recorder 实现 tools.Auditor，记录每次工具调用的名称、脱敏后的参数、结果哈希、耗时、会话和用户。
+type Recorder struct {
+	store    *storage.AuditStorage
+	cfg      Config
+	redactor *utils.Redactor
+	logger   *slog.Logger
+
+	mu   sync.Mutex
+	file io.Writer // JSON Lines 文件，nil 表示只写入数据库
+}
+
+// New 创建审计记录器，file 不为 nil 时每条记录同时追加到该文件。
+func New(store *storage.AuditStorage, file io.Writer, cfg Config, logger *slog.Logger) (*Recorder, error) {
+	redactor, err := utils.NewRedactor(cfg.Redact)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{
+		store:    store,
+		cfg:      cfg,
+		redactor: redactor,
+		logger:   logger.With("name", "【审计】"),
+		file:     file,
+	}, nil
+}
+
+// AuditTool 实现 tools.Auditor。写入失败只记录日志，不影响工具调用。
+func (r *Recorder) AuditTool(ctx context.Context, inv tools.Invocation) {
+	record := r.record(inv)
+	if err := r.store.Append(record); err != nil {
+		r.logger.Error("写入工具审计记录失败", "tool", inv.Name, "session_id", inv.SessionID, "error", err)
+		return
+	}
+	r.writeFile(record)
+}
+
+// record 将调用转换为审计记录。
+func (r *Recorder) record(inv tools.Invocation) *storage.ToolAudit {
+	record := &storage.ToolAudit{
+		Tool:       inv.Name,
+		Params:     r.params(inv.Args),
+		Status:     status(inv),
+		DurationMs: inv.Duration.Milliseconds(),
+		Channel:    inv.Channel,
+		SessionID:  inv.SessionID,
+		UserID:     inv.UserID,
+	}
+	if inv.Result != nil {
+		record.ResultHash = ResultHash(inv.Result)
+		if inv.Result.Error != nil {
+			record.Error = r.redactor.Redact(inv.Result.Error.Error())
+		}
+	}
+	return record
+}
+
+// params 返回脱敏后的参数 JSON。
+func (r *Recorder) params(args map[string]any) string {
+	if len(args) == 0 {
+		return "{}"
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "{}"
+	}
+	return r.redactor.Redact(string(data))
+}
+
+// status 返回调用的结果状态。
+func status(inv tools.Invocation) string {
+	switch {
+	case inv.Denied:
+		return storage.AuditStatusDenied
+	case inv.Skipped:
+		return storage.AuditStatusSkipped
+	case inv.Result == nil || inv.Result.Error != nil || !inv.Result.Success:
+		return storage.AuditStatusError
+	default:
+		return storage.AuditStatusSuccess
+	}
+}
+
+// ResultHash 返回工具结果的 SHA-256，覆盖结果内容和错误信息，可与对话记录中的工具结果比对。
+func ResultHash(result *tools.Result) string {
+	h := sha256.New()
+	h.Write([]byte(result.Content))
+	h.Write([]byte{0})
+	if result.Error != nil {
+		h.Write([]byte(result.Error.Error()))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeFile 将记录追加到 JSON Lines 文件。
+func (r *Recorder) writeFile(record *storage.ToolAudit) {
+	if r.file == nil {
+		return
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		r.logger.Error("写入审计日志文件失败", "error", err)
+	}
+}
+
+// Run 定期清理超过保留时长的记录，直到 ctx 取消。
+func (r *Recorder) Run(ctx context.Context) {
+	if r.cfg.Keep <= 0 {
+		return
+	}
+	r.Prune()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Prune()
+		}
+	}
+}
+
+// Prune 清理超过保留时长的记录，JSON Lines 文件不清理，由运维按需归档。
+func (r *Recorder) Prune() {
+	if r.cfg.Keep <= 0 {
+		return
+	}
+	n, err := r.store.Prune(time.Now().Add(-r.cfg.Keep))
+	if err != nil {
+		r.logger.Warn("清理审计记录失败", "error", err)
+		return
+	}
+	if n > 0 {
+		r.logger.Info("已清理过期审计记录", "count", n)
+	}
+}
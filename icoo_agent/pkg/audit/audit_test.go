@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+type echoTool struct{}
+
+func (echoTool) Name() string               { return "echo" }
+func (echoTool) Description() string        { return "echo" }
+func (echoTool) Parameters() map[string]any { return map[string]any{} }
+func (echoTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	return tools.SuccessResult("ok")
+}
+
+func TestRecorder(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	var file bytes.Buffer
+	recorder, err := New(store.Audit(), &file, Config{Redact: []string{`\d{4}-\d{4}`}}, slog.Default())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	registry := tools.NewRegistry()
+	registry.Register(echoTool{})
+	registry.SetAuditor(recorder)
+
+	ctx := tools.WithSender(context.Background(), "u1", "Alice")
+	registry.ExecuteWithContext(ctx, "echo", map[string]any{"password": "hunter2", "card": "1234-5678"}, "web", "s1", nil)
+	registry.ExecuteWithContext(tools.WithPolicy(ctx, tools.Policy{Deny: []string{"echo"}}), "echo", nil, "web", "s1", nil)
+
+	records, total, err := store.Audit().List(storage.AuditFilter{SessionID: "s1"})
+	if err != nil || total != 2 {
+		t.Fatalf("List() = %d, %v", total, err)
+	}
+	denied, executed := records[0], records[1]
+	if executed.Status != storage.AuditStatusSuccess || executed.UserID != "u1" || executed.Channel != "web" {
+		t.Errorf("executed = %+v", executed)
+	}
+	if strings.Contains(executed.Params, "hunter2") || strings.Contains(executed.Params, "1234-5678") {
+		t.Errorf("Params 未脱敏: %s", executed.Params)
+	}
+	if executed.ResultHash != ResultHash(tools.SuccessResult("ok")) {
+		t.Errorf("ResultHash = %s", executed.ResultHash)
+	}
+	if denied.Status != storage.AuditStatusDenied || denied.Error == "" {
+		t.Errorf("denied = %+v", denied)
+	}
+
+	// 文件中的记录与数据库一致，包括哈希链
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("file lines = %d, want 2", len(lines))
+	}
+	var line storage.ToolAudit
+	if err := json.Unmarshal([]byte(lines[1]), &line); err != nil || line.Hash != denied.Hash || line.PrevHash != executed.Hash {
+		t.Errorf("file record = %+v, %v", line, err)
+	}
+}
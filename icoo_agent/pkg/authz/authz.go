@@ -0,0 +1,172 @@
+// Package authz 提供可插拔的授权钩子：在处理消息和执行工具之前询问已注册的钩子，
+// 任一钩子拒绝即拒绝。内置的策略引擎从工作目录加载规则，
+// 组织可以用规则表达"只有某个用户能在工作日执行命令"这类约束，而无需修改工具代码。
+package authz
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/tools"
+)
+
+// Action 授权的操作类型。
+type Action string
+
+const (
+	ActionMessage Action = "message" // 处理用户消息
+	ActionTool    Action = "tool"    // 执行工具
+)
+
+// Request 授权请求。
+type Request struct {
+	Action    Action
+	Channel   string
+	SessionID string
+	UserID    string
+	UserName  string
+	Text      string         // 消息文本，仅 ActionMessage
+	Tool      string         // 工具名称，仅 ActionTool
+	Args      map[string]any // 工具参数，仅 ActionTool
+	Time      time.Time      // 请求时间，使用会话的用户时区
+}
+
+// Decision 授权结果。
+type Decision struct {
+	Allow  bool   `json:"allow"`
+	Policy string `json:"policy,omitempty"` // 作出决定的规则
+	Reason string `json:"reason,omitempty"` // 拒绝原因
+}
+
+// Allow 允许的授权结果。
+var Allow = Decision{Allow: true}
+
+// Hook 授权钩子。返回错误时按拒绝处理。
+type Hook interface {
+	Authorize(ctx context.Context, req *Request) (Decision, error)
+}
+
+// HookFunc 函数形式的授权钩子。
+type HookFunc func(ctx context.Context, req *Request) (Decision, error)
+
+// Authorize 实现 Hook。
+func (f HookFunc) Authorize(ctx context.Context, req *Request) (Decision, error) {
+	return f(ctx, req)
+}
+
+// DeniedError 请求被拒绝。
+type DeniedError struct {
+	Action Action
+	Target string
+	Decision
+}
+
+func (e *DeniedError) Error() string {
+	msg := fmt.Sprintf("%s %q 未通过授权", e.Action, e.Target)
+	if e.Policy != "" {
+		msg += fmt.Sprintf("（规则 %s）", e.Policy)
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Authorizer 依次询问已注册的钩子，实现 tools.Authorizer。
+type Authorizer struct {
+	mu     sync.RWMutex
+	hooks  []Hook
+	logger *slog.Logger
+}
+
+// New 创建授权器。
+func New(logger *slog.Logger, hooks ...Hook) *Authorizer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Authorizer{hooks: hooks, logger: logger}
+}
+
+// Use 注册授权钩子。
+func (a *Authorizer) Use(h Hook) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.hooks = append(a.hooks, h)
+}
+
+// Authorize 依次询问钩子，第一个拒绝的结果即为最终结果；没有钩子时允许。
+func (a *Authorizer) Authorize(ctx context.Context, req *Request) Decision {
+	if a == nil {
+		return Allow
+	}
+	if req.Time.IsZero() {
+		req.Time = time.Now()
+	}
+	req.Time = req.Time.In(tools.GetLocation(ctx))
+
+	a.mu.RLock()
+	hooks := a.hooks
+	a.mu.RUnlock()
+
+	for _, h := range hooks {
+		d, err := h.Authorize(ctx, req)
+		if err != nil {
+			a.logger.With("name", "【授权】").Error("授权钩子执行失败，按拒绝处理",
+				"action", req.Action,
+				"tool", req.Tool,
+				"error", err)
+			return Decision{Reason: "授权检查失败"}
+		}
+		if !d.Allow {
+			a.logger.With("name", "【授权】").Info("请求被拒绝",
+				"action", req.Action,
+				"channel", req.Channel,
+				"session_id", req.SessionID,
+				"user_id", req.UserID,
+				"tool", req.Tool,
+				"policy", d.Policy,
+				"reason", d.Reason)
+			return d
+		}
+	}
+	return Allow
+}
+
+// AuthorizeMessage 处理消息前授权，拒绝时返回 *DeniedError。
+func (a *Authorizer) AuthorizeMessage(ctx context.Context, msg bus.InboundMessage) error {
+	req := &Request{
+		Action:    ActionMessage,
+		Channel:   msg.Channel,
+		SessionID: msg.SessionID,
+		UserID:    msg.Sender.ID,
+		UserName:  msg.Sender.Name,
+		Text:      msg.Text,
+		Time:      msg.Timestamp,
+	}
+	if d := a.Authorize(ctx, req); !d.Allow {
+		return &DeniedError{Action: ActionMessage, Target: msg.SessionID, Decision: d}
+	}
+	return nil
+}
+
+// AuthorizeTool 执行工具前授权，实现 tools.Authorizer。
+func (a *Authorizer) AuthorizeTool(ctx context.Context, name string, args map[string]any) error {
+	sender := tools.GetSender(ctx)
+	req := &Request{
+		Action:    ActionTool,
+		Channel:   tools.GetChannel(ctx),
+		SessionID: tools.GetSessionID(ctx),
+		UserID:    sender.ID,
+		UserName:  sender.Name,
+		Tool:      name,
+		Args:      args,
+	}
+	if d := a.Authorize(ctx, req); !d.Allow {
+		return &DeniedError{Action: ActionTool, Target: name, Decision: d}
+	}
+	return nil
+}
@@ -0,0 +1,412 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/file"
+	"icooclaw/pkg/tools/builtin/shell"
+)
+
+// PermissionsParamKey 运行时添加的工具权限规则在参数配置中的键
+const PermissionsParamKey = "agent.tool_permissions"
+
+// 规则来源
+const (
+	SourceConfig  = "config"  // 配置文件
+	SourceRuntime = "runtime" // 通过接口添加，保存在参数配置中
+)
+
+// pathArgs 工具参数中表示文件或目录的键
+var pathArgs = []string{"path", "file_path", "source", "destination", "work_dir", "dir", "template", "data_path", "csv_path", "repo"}
+
+// PermissionRule 工具权限规则。所有模式均为通配符：工具和域名使用 path.Match，
+// 路径额外支持 ** 匹配任意层级，相对路径相对会话的工作目录。
+//
+// deny 规则：工具匹配且调用涉及的任一资源匹配时拒绝；只设置 tools 时拒绝整个工具。
+// allow 规则：工具匹配的调用必须满足至少一条 allow 规则，其涉及的全部资源都要匹配。
+// 规则只约束调用中实际出现的资源类型，例如 domains 规则不影响没有 url 参数的调用。
+type PermissionRule struct {
+	Name     string   `mapstructure:"name" json:"name"`
+	Effect   string   `mapstructure:"effect" json:"effect"`
+	Tools    []string `mapstructure:"tools" json:"tools,omitempty"`       // 为空匹配所有工具
+	Paths    []string `mapstructure:"paths" json:"paths,omitempty"`       // 文件参数，如 /workspace/output/**
+	Domains  []string `mapstructure:"domains" json:"domains,omitempty"`   // url 参数的主机名，如 *.example.com
+	Commands []string `mapstructure:"commands" json:"commands,omitempty"` // 复合命令中每一段的程序名，如 rm、dd
+	Reason   string   `mapstructure:"reason" json:"reason,omitempty"`
+	Source   string   `mapstructure:"-" json:"source,omitempty"`
+}
+
+// Validate 校验规则。
+func (r *PermissionRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("规则缺少 name")
+	}
+	switch r.Effect {
+	case EffectAllow, EffectDeny:
+	default:
+		return fmt.Errorf("规则 %s 的 effect 必须是 %s 或 %s", r.Name, EffectAllow, EffectDeny)
+	}
+	if len(r.Tools) == 0 && len(r.Paths) == 0 && len(r.Domains) == 0 && len(r.Commands) == 0 {
+		return fmt.Errorf("规则 %s 至少需要设置 tools、paths、domains 或 commands 之一", r.Name)
+	}
+	for _, patterns := range [][]string{r.Tools, r.Paths, r.Domains, r.Commands} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("规则 %s 的通配符 %q 无效: %w", r.Name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// resources 一次工具调用涉及的资源。
+type resources struct {
+	paths    []string
+	domains  []string
+	commands []string
+}
+
+// constrains 规则是否约束本次调用：工具匹配，且规则的资源类型中至少有一种出现在调用中
+// （规则只设置 tools 时总是约束）。
+func (r *PermissionRule) constrains(tool string, res resources) bool {
+	if len(r.Tools) > 0 && !matchAny(r.Tools, tool, path.Match) {
+		return false
+	}
+	if len(r.Paths) == 0 && len(r.Domains) == 0 && len(r.Commands) == 0 {
+		return true
+	}
+	return len(r.Paths) > 0 && len(res.paths) > 0 ||
+		len(r.Domains) > 0 && len(res.domains) > 0 ||
+		len(r.Commands) > 0 && len(res.commands) > 0
+}
+
+// denies 任一资源匹配时拒绝。
+func (r *PermissionRule) denies(res resources, workDir string) bool {
+	if len(r.Paths) == 0 && len(r.Domains) == 0 && len(r.Commands) == 0 {
+		return true
+	}
+	paths := r.resolvedPaths(workDir)
+	return slices.ContainsFunc(res.paths, func(p string) bool { return matchAny(paths, p, matchPath) }) ||
+		slices.ContainsFunc(res.domains, func(d string) bool { return matchAny(r.Domains, d, path.Match) }) ||
+		slices.ContainsFunc(res.commands, func(c string) bool { return matchAny(r.Commands, c, path.Match) })
+}
+
+// allows 调用中规则约束的资源全部匹配时允许。
+func (r *PermissionRule) allows(res resources, workDir string) bool {
+	if len(r.Paths) > 0 {
+		paths := r.resolvedPaths(workDir)
+		for _, p := range res.paths {
+			if !matchAny(paths, p, matchPath) {
+				return false
+			}
+		}
+	}
+	if len(r.Domains) > 0 {
+		for _, d := range res.domains {
+			if !matchAny(r.Domains, d, path.Match) {
+				return false
+			}
+		}
+	}
+	if len(r.Commands) > 0 {
+		for _, c := range res.commands {
+			if !matchAny(r.Commands, c, path.Match) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// resolvedPaths 将相对路径模式解析到工作目录下。
+func (r *PermissionRule) resolvedPaths(workDir string) []string {
+	paths := make([]string, len(r.Paths))
+	for i, p := range r.Paths {
+		paths[i] = resolvePath(workDir, p)
+	}
+	return paths
+}
+
+// Permissions 工具权限引擎，按配置和运行时添加的规则检查工具调用涉及的
+// 工具名称、文件路径、域名和命令，实现 Hook。
+type Permissions struct {
+	workDir string
+	params  *storage.ParamStorage
+	logger  *slog.Logger
+
+	mu      sync.RWMutex
+	config  []PermissionRule
+	runtime []PermissionRule
+}
+
+// NewPermissions creates the permission engine with the rules from the
+// configuration and loads the rules added at runtime from params. workDir is
+// the default workspace that relative paths resolve against.
+func NewPermissions(rules []PermissionRule, workDir string, params *storage.ParamStorage, logger *slog.Logger) (*Permissions, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	p := &Permissions{workDir: workDir, params: params, logger: logger}
+	for _, r := range rules {
+		r.Source = SourceConfig
+		if err := p.validateConfig(r); err != nil {
+			return nil, err
+		}
+		p.config = append(p.config, r)
+	}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	p.logger.With("name", "【授权】").Info("已加载工具权限规则", "config", len(p.config), "runtime", len(p.runtime))
+	return p, nil
+}
+
+// Rules 返回全部规则，配置中的规则在前。
+func (p *Permissions) Rules() []PermissionRule {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Concat(p.config, p.runtime)
+}
+
+// Add 添加或替换运行时规则并保存，不能覆盖配置中的规则。
+func (p *Permissions) Add(rule PermissionRule) error {
+	rule.Source = SourceRuntime
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if slices.ContainsFunc(p.config, func(r PermissionRule) bool { return r.Name == rule.Name }) {
+		return fmt.Errorf("规则 %s 定义在配置文件中，不能通过接口修改", rule.Name)
+	}
+
+	runtime := slices.Clone(p.runtime)
+	if i := slices.IndexFunc(runtime, func(r PermissionRule) bool { return r.Name == rule.Name }); i >= 0 {
+		runtime[i] = rule
+	} else {
+		runtime = append(runtime, rule)
+	}
+	if err := p.save(runtime); err != nil {
+		return err
+	}
+	p.runtime = runtime
+	return nil
+}
+
+// Remove 删除运行时规则，规则不存在时返回错误。
+func (p *Permissions) Remove(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := slices.IndexFunc(p.runtime, func(r PermissionRule) bool { return r.Name == name })
+	if i < 0 {
+		if slices.ContainsFunc(p.config, func(r PermissionRule) bool { return r.Name == name }) {
+			return fmt.Errorf("规则 %s 定义在配置文件中，不能通过接口删除", name)
+		}
+		return fmt.Errorf("规则 %s 不存在", name)
+	}
+	runtime := slices.Delete(slices.Clone(p.runtime), i, i+1)
+	if err := p.save(runtime); err != nil {
+		return err
+	}
+	p.runtime = runtime
+	return nil
+}
+
+// Check 检查一次工具调用：先检查 deny 规则，再要求约束本次调用的 allow 规则中至少一条允许；
+// 没有 allow 规则约束时允许。
+func (p *Permissions) Check(ctx context.Context, tool string, args map[string]any) Decision {
+	workDir := tools.GetWorkspace(ctx, p.workDir)
+	res := extractResources(args, workDir)
+
+	rules := p.Rules()
+	for _, r := range rules {
+		if r.Effect == EffectDeny && r.constrains(tool, res) && r.denies(res, workDir) {
+			return Decision{Policy: r.Name, Reason: r.reason(tool)}
+		}
+	}
+
+	var candidates []string
+	for _, r := range rules {
+		if r.Effect != EffectAllow || !r.constrains(tool, res) {
+			continue
+		}
+		if r.allows(res, workDir) {
+			return Allow
+		}
+		candidates = append(candidates, r.Name)
+	}
+	if len(candidates) > 0 {
+		return Decision{
+			Policy: strings.Join(candidates, ","),
+			Reason: fmt.Sprintf("%s 的调用不在允许范围内", tool),
+		}
+	}
+	return Allow
+}
+
+// Authorize 实现 Hook，只检查工具调用。
+func (p *Permissions) Authorize(ctx context.Context, req *Request) (Decision, error) {
+	if req.Action != ActionTool {
+		return Allow, nil
+	}
+	return p.Check(ctx, req.Tool, req.Args), nil
+}
+
+// reason 拒绝原因，未配置时生成。
+func (r *PermissionRule) reason(tool string) string {
+	if r.Reason != "" {
+		return r.Reason
+	}
+	return fmt.Sprintf("%s 的调用命中了禁止规则", tool)
+}
+
+// validateConfig 校验配置规则并检查名称是否重复。
+func (p *Permissions) validateConfig(rule PermissionRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if slices.ContainsFunc(p.config, func(r PermissionRule) bool { return r.Name == rule.Name }) {
+		return fmt.Errorf("工具权限规则 %s 重复", rule.Name)
+	}
+	return nil
+}
+
+// load 从参数配置加载运行时规则，与配置规则同名的被忽略。
+func (p *Permissions) load() error {
+	if p.params == nil {
+		return nil
+	}
+	param, err := p.params.Get(PermissionsParamKey)
+	if err != nil {
+		return fmt.Errorf("读取工具权限规则失败: %w", err)
+	}
+	if param == nil || param.Value == "" {
+		return nil
+	}
+	var rules []PermissionRule
+	if err := json.Unmarshal([]byte(param.Value), &rules); err != nil {
+		return fmt.Errorf("解析工具权限规则失败: %w", err)
+	}
+	for _, r := range rules {
+		r.Source = SourceRuntime
+		if err := r.Validate(); err != nil {
+			p.logger.With("name", "【授权】").Warn("忽略无效的工具权限规则", "error", err)
+			continue
+		}
+		if slices.ContainsFunc(p.config, func(c PermissionRule) bool { return c.Name == r.Name }) {
+			p.logger.With("name", "【授权】").Warn("忽略与配置同名的工具权限规则", "rule", r.Name)
+			continue
+		}
+		p.runtime = append(p.runtime, r)
+	}
+	return nil
+}
+
+// save 保存运行时规则。
+func (p *Permissions) save(rules []PermissionRule) error {
+	if p.params == nil {
+		return nil
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	if err := p.params.Set(PermissionsParamKey, string(data), "运行时添加的工具权限规则", "agent"); err != nil {
+		return fmt.Errorf("保存工具权限规则失败: %w", err)
+	}
+	return nil
+}
+
+// extractResources 从工具参数中提取路径、域名和命令。
+func extractResources(args map[string]any, workDir string) resources {
+	var res resources
+	for _, key := range pathArgs {
+		if s, _ := args[key].(string); strings.TrimSpace(s) != "" {
+			res.paths = append(res.paths, resolvePath(workDir, s))
+		}
+	}
+	// git_commit 一次暂存多个路径
+	if list, _ := args["paths"].([]any); len(list) > 0 {
+		for _, v := range list {
+			if s, _ := v.(string); strings.TrimSpace(s) != "" {
+				res.paths = append(res.paths, resolvePath(workDir, s))
+			}
+		}
+	}
+	// apply_patch 的路径在补丁的文件头中
+	if s, _ := args["patch"].(string); s != "" {
+		for _, p := range file.PatchPaths(s) {
+			res.paths = append(res.paths, resolvePath(workDir, p))
+		}
+	}
+	if s, _ := args["url"].(string); s != "" {
+		host := s
+		if u, err := url.Parse(s); err == nil && u.Host != "" {
+			host = u.Hostname()
+		}
+		res.domains = append(res.domains, strings.ToLower(host))
+	}
+	if s, _ := args["command"].(string); s != "" {
+		res.commands = shell.Programs(s)
+	}
+	return res
+}
+
+// resolvePath 将路径解析为以 / 分隔的绝对路径，相对路径相对 workDir。
+func resolvePath(workDir, p string) string {
+	p = filepath.FromSlash(p)
+	if !filepath.IsAbs(p) && filepath.VolumeName(p) == "" {
+		p = filepath.Join(workDir, p)
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	return filepath.ToSlash(p)
+}
+
+// matchAny 名称是否匹配任一模式。
+func matchAny(patterns []string, name string, match func(pattern, name string) (bool, error)) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, err := match(pattern, name)
+		return ok && err == nil
+	})
+}
+
+// matchPath 按 / 分段匹配路径，** 匹配任意层级（包括零层）。
+func matchPath(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if ok, err := matchSegments(pattern[1:], name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		if ok, err := path.Match(pattern[0], name[0]); !ok || err != nil {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
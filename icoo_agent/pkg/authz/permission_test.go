@@ -0,0 +1,98 @@
+package authz
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+func TestPermissions(t *testing.T) {
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "authz.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	workDir := t.TempDir()
+	rules := []PermissionRule{
+		{Name: "write-output", Effect: EffectAllow, Tools: []string{"write_file"}, Paths: []string{"output/**"}},
+		{Name: "no-rm-dd", Effect: EffectDeny, Tools: []string{"shell_command"}, Commands: []string{"rm", "dd"}, Reason: "禁止删除和写设备"},
+		{Name: "docs-only", Effect: EffectAllow, Domains: []string{"*.example.com", "example.com"}},
+		{Name: "no-secrets", Effect: EffectDeny, Paths: []string{"**/.env"}},
+	}
+	p, err := NewPermissions(rules, workDir, s.Param(), nil)
+	if err != nil {
+		t.Fatalf("NewPermissions() error = %v", err)
+	}
+
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		tool   string
+		args   map[string]any
+		allow  bool
+		policy string
+	}{
+		{"write under output", "write_file", map[string]any{"path": "output/report.md"}, true, ""},
+		{"write nested output", "write_file", map[string]any{"path": filepath.Join(workDir, "output/a/b.md")}, true, ""},
+		{"write elsewhere", "write_file", map[string]any{"path": "notes.md"}, false, "write-output"},
+		{"write escaping output", "write_file", map[string]any{"path": "output/../notes.md"}, false, "write-output"},
+		{"write env in output", "write_file", map[string]any{"path": "output/.env"}, false, "no-secrets"},
+		{"patch touching env", "apply_patch", map[string]any{"patch": "--- a/app.go\n+++ b/app.go\n@@ -1 +1 @@\n-a\n+b\n--- a/.env\n+++ b/.env\n@@ -1 +1 @@\n-x\n+y\n"}, false, "no-secrets"},
+		{"commit staging env", "git_commit", map[string]any{"message": "m", "paths": []any{"app.go", "config/.env"}}, false, "no-secrets"},
+		{"read anywhere", "read_file", map[string]any{"path": "notes.md"}, true, ""},
+		{"rm in pipeline", "shell_command", map[string]any{"command": "ls && sudo /bin/rm -rf x"}, false, "no-rm-dd"},
+		{"safe command", "shell_command", map[string]any{"command": "ls | grep go"}, true, ""},
+		{"allowed domain", "http_request", map[string]any{"url": "https://docs.example.com/a"}, true, ""},
+		{"other domain", "download_file", map[string]any{"url": "https://evil.test/x", "path": "x"}, false, "docs-only"},
+		{"no url", "web_search", map[string]any{"query": "go"}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := p.Check(ctx, tt.tool, tt.args)
+			if d.Allow != tt.allow || d.Policy != tt.policy {
+				t.Errorf("Check() = %+v, want allow=%v policy=%q", d, tt.allow, tt.policy)
+			}
+		})
+	}
+
+	// 相对路径按会话的工作目录解析
+	other := t.TempDir()
+	if d := p.Check(tools.WithWorkspace(ctx, other), "write_file", map[string]any{"path": "output/a.md"}); !d.Allow {
+		t.Errorf("Check() in other workspace = %+v", d)
+	}
+
+	// 运行时规则保存后重新加载仍然有效，配置规则不能通过接口修改
+	if err := p.Add(PermissionRule{Name: "no-curl", Effect: EffectDeny, Commands: []string{"curl"}}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := p.Add(PermissionRule{Name: "no-rm-dd", Effect: EffectDeny, Tools: []string{"*"}}); err == nil {
+		t.Error("Add() should not replace a config rule")
+	}
+	if err := p.Remove("no-rm-dd"); err == nil {
+		t.Error("Remove() should not delete a config rule")
+	}
+	reloaded, err := NewPermissions(rules, workDir, s.Param(), nil)
+	if err != nil {
+		t.Fatalf("NewPermissions() error = %v", err)
+	}
+	if d := reloaded.Check(ctx, "shell_command", map[string]any{"command": "curl https://example.com"}); d.Allow || d.Policy != "no-curl" {
+		t.Errorf("Check() with runtime rule = %+v", d)
+	}
+	if got := reloaded.Rules(); len(got) != 5 || got[4].Source != SourceRuntime {
+		t.Errorf("Rules() = %+v", got)
+	}
+	if err := reloaded.Remove("no-curl"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if d := reloaded.Check(ctx, "shell_command", map[string]any{"command": "curl https://example.com"}); !d.Allow {
+		t.Errorf("Check() after Remove() = %+v", d)
+	}
+
+	if _, err := NewPermissions([]PermissionRule{{Name: "bad", Effect: "maybe", Tools: []string{"x"}}}, workDir, nil, nil); err == nil {
+		t.Error("NewPermissions() should reject an invalid effect")
+	}
+}
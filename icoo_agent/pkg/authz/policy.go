@@ -0,0 +1,319 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// DefaultDir 工作目录中存放策略文件的默认子目录
+const DefaultDir = "policies"
+
+// evalTimeout 单条规则条件的最长执行时间
+const evalTimeout = 100 * time.Millisecond
+
+// 规则效果
+const (
+	EffectAllow = "allow" // 命中范围的请求必须满足条件，否则拒绝
+	EffectDeny  = "deny"  // 命中范围且满足条件的请求被拒绝
+)
+
+// Rule 策略规则。范围字段为空表示不限；条件为空表示总是成立。
+//
+// 条件是一个 JavaScript 表达式，可使用以下变量：
+//
+//	user.id, user.name       发送者
+//	channel, session         渠道和会话 ID
+//	action                   "message" 或 "tool"
+//	text                     消息文本
+//	tool.name, tool.args     工具名称和参数
+//	now.weekday              星期，0 为周日
+//	now.hour, now.minute     时、分
+//	now.date, now.time       日期 2006-01-02 和时间 15:04
+type Rule struct {
+	Name     string   `toml:"name" json:"name"`
+	Effect   string   `toml:"effect" json:"effect"`
+	Actions  []Action `toml:"actions" json:"actions,omitempty"`
+	Channels []string `toml:"channels" json:"channels,omitempty"`
+	Tools    []string `toml:"tools" json:"tools,omitempty"` // 支持 path.Match 通配符，设置后只匹配工具调用
+	When     string   `toml:"when" json:"when,omitempty"`
+	Reason   string   `toml:"reason" json:"reason,omitempty"`
+
+	program *goja.Program
+}
+
+// policyFile 策略文件结构。
+type policyFile struct {
+	Rules []*Rule `toml:"rules"`
+}
+
+// Engine 内置策略引擎，从目录加载 *.toml 策略文件，文件变化后自动重新加载。
+//
+// 规则按文件名和文件内顺序依次检查：deny 规则条件成立即拒绝，
+// allow 规则条件不成立即拒绝；全部通过时允许。
+type Engine struct {
+	dir    string
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	rules []*Rule
+	stamp string // 已加载文件的名称和修改时间
+}
+
+// NewEngine 创建策略引擎并加载 dir 中的策略，目录不存在时没有任何规则。
+func NewEngine(dir string, logger *slog.Logger) (*Engine, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	e := &Engine{dir: dir, logger: logger}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Rules 返回当前加载的规则。
+func (e *Engine) Rules() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rules := make([]Rule, 0, len(e.rules))
+	for _, r := range e.rules {
+		rules = append(rules, *r)
+	}
+	return rules
+}
+
+// Reload 重新加载策略文件，任一文件有误时保留原有规则并返回错误。
+func (e *Engine) Reload() error {
+	files, stamp, err := e.scan()
+	if err != nil {
+		return err
+	}
+
+	var rules []*Rule
+	for _, file := range files {
+		loaded, err := loadFile(file)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, loaded...)
+	}
+
+	e.mu.Lock()
+	e.rules = rules
+	e.stamp = stamp
+	e.mu.Unlock()
+
+	e.logger.With("name", "【授权】").Info("已加载授权策略", "dir", e.dir, "rules", len(rules))
+	return nil
+}
+
+// Authorize 实现 Hook。
+func (e *Engine) Authorize(ctx context.Context, req *Request) (Decision, error) {
+	e.reloadIfChanged()
+
+	e.mu.Lock()
+	rules := e.rules
+	e.mu.Unlock()
+
+	var env map[string]any
+	for _, r := range rules {
+		if !r.matches(req) {
+			continue
+		}
+		ok := true
+		if r.program != nil {
+			if env == nil {
+				env = requestEnv(req)
+			}
+			var err error
+			if ok, err = r.eval(env); err != nil {
+				return Decision{}, err
+			}
+		}
+		if (r.Effect == EffectDeny) == ok {
+			return Decision{Policy: r.Name, Reason: r.Reason}, nil
+		}
+	}
+	return Allow, nil
+}
+
+// reloadIfChanged 策略文件有增删改时重新加载。
+func (e *Engine) reloadIfChanged() {
+	_, stamp, err := e.scan()
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	changed := stamp != e.stamp
+	e.mu.Unlock()
+	if !changed {
+		return
+	}
+	if err := e.Reload(); err != nil {
+		e.logger.With("name", "【授权】").Error("重新加载授权策略失败，继续使用原有规则", "error", err)
+		// 记录本次文件状态，避免每次请求都重复加载同一个有误的文件
+		e.mu.Lock()
+		e.stamp = stamp
+		e.mu.Unlock()
+	}
+}
+
+// scan 列出策略文件，返回按名称排序的路径和文件状态摘要。
+func (e *Engine) scan() ([]string, string, error) {
+	entries, err := os.ReadDir(e.dir)
+	if os.IsNotExist(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("读取策略目录失败: %w", err)
+	}
+
+	var files []string
+	var stamp strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".toml" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(e.dir, entry.Name()))
+		fmt.Fprintf(&stamp, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	sort.Strings(files)
+	return files, stamp.String(), nil
+}
+
+// loadFile 解析并校验一个策略文件。
+func loadFile(file string) ([]*Rule, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取策略文件失败: %w", err)
+	}
+	var pf policyFile
+	if err := toml.Unmarshal(data, &pf); err != nil {
+		return nil, fmt.Errorf("解析策略文件 %s 失败: %w", filepath.Base(file), err)
+	}
+
+	for i, r := range pf.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("%s#%d", strings.TrimSuffix(filepath.Base(file), ".toml"), i+1)
+		}
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("策略文件 %s 中的规则 %s 无效: %w", filepath.Base(file), r.Name, err)
+		}
+	}
+	return pf.Rules, nil
+}
+
+// compile 校验规则并编译条件。
+func (r *Rule) compile() error {
+	switch r.Effect {
+	case EffectAllow, EffectDeny:
+	default:
+		return fmt.Errorf("effect 必须是 %s 或 %s", EffectAllow, EffectDeny)
+	}
+	for _, action := range r.Actions {
+		if action != ActionMessage && action != ActionTool {
+			return fmt.Errorf("未知的 action: %s", action)
+		}
+	}
+	for _, pattern := range r.Tools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("无效的工具通配符 %q: %w", pattern, err)
+		}
+	}
+	if strings.TrimSpace(r.When) == "" {
+		return nil
+	}
+	program, err := goja.Compile(r.Name, "("+r.When+"\n)", true)
+	if err != nil {
+		return fmt.Errorf("条件编译失败: %w", err)
+	}
+	r.program = program
+	return nil
+}
+
+// matches 判断请求是否在规则范围内。
+func (r *Rule) matches(req *Request) bool {
+	if len(r.Actions) > 0 && !slices.Contains(r.Actions, req.Action) {
+		return false
+	}
+	if len(r.Channels) > 0 && !slices.Contains(r.Channels, req.Channel) {
+		return false
+	}
+	if len(r.Tools) > 0 {
+		if req.Action != ActionTool {
+			return false
+		}
+		matched := false
+		for _, pattern := range r.Tools {
+			if ok, _ := path.Match(pattern, req.Tool); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// eval 在独立的运行时中计算条件，超时或出错时返回错误。
+func (r *Rule) eval(env map[string]any) (bool, error) {
+	vm := goja.New()
+	for name, value := range env {
+		if err := vm.Set(name, value); err != nil {
+			return false, err
+		}
+	}
+
+	timer := time.AfterFunc(evalTimeout, func() {
+		vm.Interrupt("timeout")
+	})
+	defer timer.Stop()
+
+	value, err := vm.RunProgram(r.program)
+	if err != nil {
+		return false, fmt.Errorf("规则 %s 条件执行失败: %w", r.Name, err)
+	}
+	return value.ToBoolean(), nil
+}
+
+// requestEnv 构造条件表达式可用的变量。
+func requestEnv(req *Request) map[string]any {
+	args := req.Args
+	if args == nil {
+		args = map[string]any{}
+	}
+	return map[string]any{
+		"user":    map[string]any{"id": req.UserID, "name": req.UserName},
+		"channel": req.Channel,
+		"session": req.SessionID,
+		"action":  string(req.Action),
+		"text":    req.Text,
+		"tool":    map[string]any{"name": req.Tool, "args": args},
+		"now": map[string]any{
+			"weekday": int(req.Time.Weekday()),
+			"hour":    req.Time.Hour(),
+			"minute":  req.Time.Minute(),
+			"date":    req.Time.Format("2006-01-02"),
+			"time":    req.Time.Format("15:04"),
+		},
+	}
+}
@@ -0,0 +1,143 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/tools"
+)
+
+const testPolicy = `
+[[rules]]
+name = "exec-weekdays-alice"
+effect = "allow"
+tools = ["exec", "shell_*"]
+when = 'user.id == "alice" && now.weekday >= 1 && now.weekday <= 5'
+reason = "只有 alice 可以在工作日执行命令"
+
+[[rules]]
+name = "no-secrets"
+effect = "deny"
+actions = ["message"]
+channels = ["feishu"]
+when = 'text.includes("password")'
+reason = "不要在群聊中发送密码"
+
+[[rules]]
+name = "read-outside-tmp"
+effect = "deny"
+tools = ["read_file"]
+when = '!String(tool.args.path || "").startsWith("/tmp/")'
+`
+
+func TestEngine(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "org.toml"), []byte(testPolicy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewEngine(dir, nil)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	if got := len(engine.Rules()); got != 3 {
+		t.Fatalf("Rules() = %d, want 3", got)
+	}
+
+	// 2026-03-11 是周三，2026-03-14 是周六
+	wednesday := time.Date(2026, 3, 11, 10, 0, 0, 0, time.UTC)
+	saturday := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		req    Request
+		allow  bool
+		policy string
+	}{
+		{"alice exec weekday", Request{Action: ActionTool, Tool: "exec", UserID: "alice", Time: wednesday}, true, ""},
+		{"alice exec weekend", Request{Action: ActionTool, Tool: "exec", UserID: "alice", Time: saturday}, false, "exec-weekdays-alice"},
+		{"bob exec weekday", Request{Action: ActionTool, Tool: "shell_run", UserID: "bob", Time: wednesday}, false, "exec-weekdays-alice"},
+		{"bob other tool", Request{Action: ActionTool, Tool: "web_search", UserID: "bob", Time: saturday}, true, ""},
+		{"secret on feishu", Request{Action: ActionMessage, Channel: "feishu", Text: "my password is 123", Time: wednesday}, false, "no-secrets"},
+		{"secret elsewhere", Request{Action: ActionMessage, Channel: "websocket", Text: "my password is 123", Time: wednesday}, true, ""},
+		{"read in tmp", Request{Action: ActionTool, Tool: "read_file", Args: map[string]any{"path": "/tmp/a.txt"}, Time: wednesday}, true, ""},
+		{"read elsewhere", Request{Action: ActionTool, Tool: "read_file", Args: map[string]any{"path": "/etc/passwd"}, Time: wednesday}, false, "read-outside-tmp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := engine.Authorize(context.Background(), &tt.req)
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+			if d.Allow != tt.allow || d.Policy != tt.policy {
+				t.Errorf("Authorize() = %+v, want allow=%v policy=%q", d, tt.allow, tt.policy)
+			}
+		})
+	}
+
+	// 有误的策略文件不影响已加载的规则
+	bad := filepath.Join(dir, "zz-bad.toml")
+	if err := os.WriteFile(bad, []byte("[[rules]]\neffect = \"maybe\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, _ := engine.Authorize(context.Background(), &Request{Action: ActionTool, Tool: "exec", UserID: "bob", Time: wednesday})
+	if d.Allow || len(engine.Rules()) != 3 {
+		t.Errorf("rules after invalid file = %d, decision %+v", len(engine.Rules()), d)
+	}
+
+	// 文件变化后自动重新加载
+	if err := os.Remove(bad); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "org.toml")); err != nil {
+		t.Fatal(err)
+	}
+	d, _ = engine.Authorize(context.Background(), &Request{Action: ActionTool, Tool: "exec", UserID: "bob", Time: wednesday})
+	if !d.Allow || len(engine.Rules()) != 0 {
+		t.Errorf("rules after removal = %d, decision %+v", len(engine.Rules()), d)
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	var seen *Request
+	a := New(nil, HookFunc(func(ctx context.Context, req *Request) (Decision, error) {
+		seen = req
+		if req.Tool == "exec" {
+			return Decision{Policy: "custom", Reason: "disabled"}, nil
+		}
+		if req.Tool == "broken" {
+			return Decision{}, errors.New("boom")
+		}
+		return Allow, nil
+	}))
+
+	ctx := tools.WithToolContext(context.Background(), "feishu", "s1")
+	ctx = tools.WithSender(ctx, "alice", "Alice")
+
+	err := a.AuthorizeTool(ctx, "exec", nil)
+	var denied *DeniedError
+	if !errors.As(err, &denied) || denied.Policy != "custom" {
+		t.Fatalf("AuthorizeTool(exec) error = %v", err)
+	}
+	if seen.UserID != "alice" || seen.Channel != "feishu" || seen.SessionID != "s1" {
+		t.Errorf("request = %+v", seen)
+	}
+	if err := a.AuthorizeTool(ctx, "broken", nil); err == nil {
+		t.Error("hook errors should deny")
+	}
+	if err := a.AuthorizeTool(ctx, "web_search", nil); err != nil {
+		t.Errorf("AuthorizeTool(web_search) error = %v", err)
+	}
+
+	msg := bus.InboundMessage{Channel: "feishu", SessionID: "s1", Sender: bus.SenderInfo{ID: "bob"}, Text: "hi"}
+	if err := a.AuthorizeMessage(context.Background(), msg); err != nil {
+		t.Errorf("AuthorizeMessage() error = %v", err)
+	}
+	if seen.Action != ActionMessage || seen.UserID != "bob" || seen.Time.IsZero() {
+		t.Errorf("message request = %+v", seen)
+	}
+}
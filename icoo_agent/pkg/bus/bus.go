@@ -18,6 +18,30 @@ type SenderInfo struct {
 	IsBot    bool
 }
 
+// Priority is the processing priority of an inbound message. Higher priority
+// messages are consumed before lower priority ones that are already queued.
+type Priority int
+
+const (
+	// PriorityLow is used for bulk and scheduled traffic such as scheduled tasks and heartbeats.
+	PriorityLow Priority = -1
+	// PriorityNormal is the default priority of user messages.
+	PriorityNormal Priority = 0
+	// PriorityUrgent is used for control messages such as stop requests, slash commands and approval replies.
+	PriorityUrgent Priority = 1
+)
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	switch {
+	case p > PriorityNormal:
+		return "urgent"
+	case p < PriorityNormal:
+		return "low"
+	}
+	return "normal"
+}
+
 // InboundMessage represents a message received from a channel.
 type InboundMessage struct {
 	Channel   string
@@ -28,6 +52,8 @@ type InboundMessage struct {
 	ReplyTo   string
 	Timestamp time.Time
 	Metadata  map[string]any
+	// Priority is assigned by the publisher or, when left normal, by the bus prioritizer.
+	Priority Priority
 }
 
 // OutboundMessage represents a message to be sent to a channel.
@@ -55,6 +81,8 @@ const defaultBusBufferSize = 64
 // MessageBus provides pub/sub messaging between components.
 type MessageBus struct {
 	inbound       chan InboundMessage
+	urgent        chan InboundMessage // 紧急消息，优先于 inbound 消费
+	low           chan InboundMessage // 批量和定时消息，inbound 为空时才消费
 	outbound      chan OutboundMessage
 	outboundMedia chan OutboundMediaMessage
 	done          chan struct{}
@@ -68,7 +96,13 @@ type MessageBus struct {
 	// Subscribers
 	inboundSubs  map[string]chan InboundMessage
 	outboundSubs map[string]chan OutboundMessage
+	eventSubs    map[string]chan Event
+	eventDrops   atomic.Int64
 	mu           sync.RWMutex
+
+	// Priority handling
+	prioritizer func(InboundMessage) Priority
+	interrupter func(InboundMessage) bool
 }
 
 // Config contains configuration for MessageBus.
@@ -96,6 +130,8 @@ func NewMessageBus(cfg Config) *MessageBus {
 
 	return &MessageBus{
 		inbound:          make(chan InboundMessage, cfg.InboundCapacity),
+		urgent:           make(chan InboundMessage, cfg.InboundCapacity),
+		low:              make(chan InboundMessage, cfg.InboundCapacity),
 		outbound:         make(chan OutboundMessage, cfg.OutboundCapacity),
 		outboundMedia:    make(chan OutboundMediaMessage, cfg.InboundCapacity),
 		done:             make(chan struct{}),
@@ -103,9 +139,29 @@ func NewMessageBus(cfg Config) *MessageBus {
 		outboundCapacity: cfg.OutboundCapacity,
 		inboundSubs:      make(map[string]chan InboundMessage),
 		outboundSubs:     make(map[string]chan OutboundMessage),
+		eventSubs:        make(map[string]chan Event),
 	}
 }
 
+// SetPrioritizer sets the function that assigns a priority to inbound messages
+// published with PriorityNormal. Messages published with an explicit priority
+// keep it.
+func (mb *MessageBus) SetPrioritizer(fn func(InboundMessage) Priority) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.prioritizer = fn
+}
+
+// SetInterrupter sets the function called with each urgent message before it
+// is queued, so that the consumer can interrupt work in progress without
+// waiting for the message to reach the front of the queue. When it returns
+// true the message has been handled and is not queued.
+func (mb *MessageBus) SetInterrupter(fn func(InboundMessage) bool) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.interrupter = fn
+}
+
 // PublishInbound publishes an inbound message with context support.
 // Returns ErrBusClosed if the bus is closed, or ctx.Err() if context is canceled.
 func (mb *MessageBus) PublishInbound(ctx context.Context, msg InboundMessage) error {
@@ -118,8 +174,26 @@ func (mb *MessageBus) PublishInbound(ctx context.Context, msg InboundMessage) er
 		return err
 	}
 
+	mb.mu.RLock()
+	prioritizer, interrupter := mb.prioritizer, mb.interrupter
+	mb.mu.RUnlock()
+	if msg.Priority == PriorityNormal && prioritizer != nil {
+		msg.Priority = prioritizer(msg)
+	}
+
+	lane := mb.inbound
+	switch {
+	case msg.Priority > PriorityNormal:
+		if interrupter != nil && interrupter(msg) {
+			return nil
+		}
+		lane = mb.urgent
+	case msg.Priority < PriorityNormal:
+		lane = mb.low
+	}
+
 	select {
-	case mb.inbound <- msg:
+	case lane <- msg:
 		// Also forward to subscribers
 		mb.mu.RLock()
 		if sub, ok := mb.inboundSubs["all"]; ok {
@@ -146,12 +220,25 @@ func (mb *MessageBus) PublishInboundNoCtx(msg InboundMessage) error {
 	return mb.PublishInbound(ctx, msg)
 }
 
-// ConsumeInbound consumes an inbound message from the bus.
+// ConsumeInbound consumes an inbound message from the bus. Queued urgent
+// messages are returned first, then normal messages, then low priority ones.
 // Returns the message and true if successful, or empty message and false if the bus is closed or context is canceled.
 func (mb *MessageBus) ConsumeInbound(ctx context.Context) (InboundMessage, bool) {
+	for _, lane := range []chan InboundMessage{mb.urgent, mb.inbound} {
+		select {
+		case msg, ok := <-lane:
+			return msg, ok
+		default:
+		}
+	}
+
 	select {
+	case msg, ok := <-mb.urgent:
+		return msg, ok
 	case msg, ok := <-mb.inbound:
 		return msg, ok
+	case msg, ok := <-mb.low:
+		return msg, ok
 	case <-mb.done:
 		return InboundMessage{}, false
 	case <-ctx.Done():
@@ -244,7 +331,8 @@ func (mb *MessageBus) ConsumeOutboundMedia(ctx context.Context) (OutboundMediaMe
 	}
 }
 
-// Inbound returns the inbound message channel.
+// Inbound returns the normal priority inbound message channel; urgent and low
+// priority messages are only delivered by ConsumeInbound.
 // Deprecated: Use ConsumeInbound for safer consumption with context.
 func (mb *MessageBus) Inbound() <-chan InboundMessage {
 	return mb.inbound
@@ -326,6 +414,10 @@ func (mb *MessageBus) Close() {
 			select {
 			case <-mb.inbound:
 				drained++
+			case <-mb.urgent:
+				drained++
+			case <-mb.low:
+				drained++
 			default:
 				goto doneInbound
 			}
@@ -359,8 +451,12 @@ func (mb *MessageBus) Close() {
 		for _, ch := range mb.outboundSubs {
 			close(ch)
 		}
+		for _, ch := range mb.eventSubs {
+			close(ch)
+		}
 		mb.inboundSubs = make(map[string]chan InboundMessage)
 		mb.outboundSubs = make(map[string]chan OutboundMessage)
+		mb.eventSubs = make(map[string]chan Event)
 		mb.mu.Unlock()
 	}
 }
@@ -375,6 +471,37 @@ func (mb *MessageBus) IsClosed() bool {
 	return mb.closed.Load()
 }
 
+// QueueStats 入站队列积压情况
+type QueueStats struct {
+	Urgent   int `json:"urgent"`
+	Normal   int `json:"normal"`
+	Low      int `json:"low"`
+	Outbound int `json:"outbound"`
+
+	InboundCapacity  int `json:"inbound_capacity"`
+	OutboundCapacity int `json:"outbound_capacity"`
+}
+
+// Full reports whether any inbound lane or the outbound queue is full, in
+// which case publishers block or drop messages.
+func (q QueueStats) Full() bool {
+	return q.Urgent >= q.InboundCapacity || q.Normal >= q.InboundCapacity || q.Low >= q.InboundCapacity ||
+		q.Outbound >= q.OutboundCapacity
+}
+
+// Stats returns the number of messages waiting in each queue.
+func (mb *MessageBus) Stats() QueueStats {
+	return QueueStats{
+		Urgent:   len(mb.urgent),
+		Normal:   len(mb.inbound),
+		Low:      len(mb.low),
+		Outbound: len(mb.outbound),
+
+		InboundCapacity:  mb.inboundCapacity,
+		OutboundCapacity: mb.outboundCapacity,
+	}
+}
+
 // DropCount returns the number of dropped messages.
 func (mb *MessageBus) DropCount() int64 {
 	return mb.dropCount.Load()
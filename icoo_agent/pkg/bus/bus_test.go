@@ -0,0 +1,89 @@
+package bus
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMessageBus_Priority(t *testing.T) {
+	mb := NewMessageBus(DefaultConfig())
+	t.Cleanup(mb.Close)
+
+	var interrupted []string
+	mb.SetPrioritizer(func(msg InboundMessage) Priority {
+		if msg.Text == "/stop" || msg.Text == "/cost yes" {
+			return PriorityUrgent
+		}
+		return PriorityNormal
+	})
+	mb.SetInterrupter(func(msg InboundMessage) bool {
+		interrupted = append(interrupted, msg.Text)
+		return msg.Text == "/stop"
+	})
+
+	ctx := context.Background()
+	mb.PublishInbound(ctx, InboundMessage{Text: "task", Priority: PriorityLow})
+	mb.PublishInbound(ctx, InboundMessage{Text: "hello"})
+	mb.PublishInbound(ctx, InboundMessage{Text: "/stop"})
+	mb.PublishInbound(ctx, InboundMessage{Text: "/cost yes"})
+	mb.PublishInbound(ctx, InboundMessage{Text: "world"})
+
+	var got []string
+	for range 4 {
+		msg, ok := mb.ConsumeInbound(ctx)
+		if !ok {
+			t.Fatal("ConsumeInbound() = false")
+		}
+		got = append(got, msg.Text)
+	}
+	want := []string{"/cost yes", "hello", "world", "task"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("consumed %v, want %v", got, want)
+		}
+	}
+	if len(interrupted) != 2 {
+		t.Errorf("interrupter called with %v, want the two urgent messages", interrupted)
+	}
+}
+
+func TestMessageBus_Stats(t *testing.T) {
+	mb := NewMessageBus(Config{InboundCapacity: 2, OutboundCapacity: 4})
+	ctx := context.Background()
+	mb.PublishInbound(ctx, InboundMessage{Text: "a"})
+	mb.PublishInbound(ctx, InboundMessage{Text: "b", Priority: PriorityLow})
+
+	stats := mb.Stats()
+	if stats.Normal != 1 || stats.Low != 1 || stats.InboundCapacity != 2 || stats.OutboundCapacity != 4 {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+	if stats.Full() {
+		t.Error("Full() = true, want false")
+	}
+
+	mb.PublishInbound(ctx, InboundMessage{Text: "c"})
+	if !mb.Stats().Full() {
+		t.Error("Full() = false after filling the inbound queue")
+	}
+}
+
+func TestMessageBus_Events(t *testing.T) {
+	mb := NewMessageBus(DefaultConfig())
+	events := mb.SubscribeEvents("test", 1)
+
+	mb.PublishEvent(Event{Type: EventSessionClosed, Channel: "telegram", SessionID: "s1"})
+	mb.PublishEvent(Event{Type: EventFormSubmitted})
+	ev := <-events
+	if ev.Type != EventSessionClosed || ev.SessionID != "s1" || ev.Time.IsZero() {
+		t.Errorf("event = %+v", ev)
+	}
+	if mb.EventDropCount() != 1 {
+		t.Errorf("EventDropCount() = %d", mb.EventDropCount())
+	}
+
+	mb.Close()
+	mb.PublishEvent(Event{Type: EventSessionClosed})
+	if _, ok := <-events; ok {
+		t.Error("subscription should be closed with the bus")
+	}
+}
@@ -0,0 +1,80 @@
+package bus
+
+import "time"
+
+// Event types published by icooclaw components.
+const (
+	EventSessionClosed    = "session.closed"    // 空闲会话已关闭
+	EventFormSubmitted    = "form.submitted"    // 表单填写完成
+	EventProviderOffline  = "provider.offline"  // 提供商不可达，开始离线排队
+	EventProviderOnline   = "provider.online"   // 提供商恢复
+	EventWorkspaceChanged = "workspace.changed" // 一轮对话修改了工作目录中的文件
+	EventToolDisabled     = "tool.disabled"     // 插件工具自检失败或反复失败，已停用
+	EventHeartbeatDone    = "heartbeat.done"    // 智能体处理完一次心跳检查
+)
+
+// Event is a notification about something that happened, as opposed to a
+// message to process. Components subscribe to react to activity such as a
+// session closing; external systems can publish their own event types.
+type Event struct {
+	Type      string         `json:"type"`
+	Channel   string         `json:"channel,omitempty"`
+	SessionID string         `json:"session_id,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+	Time      time.Time      `json:"time"`
+}
+
+// PublishEvent delivers an event to all event subscribers without blocking.
+// Subscribers whose buffer is full miss the event.
+func (mb *MessageBus) PublishEvent(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	// 持有读锁时检查，Close 关闭订阅通道前需要写锁
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+	if mb.closed.Load() {
+		return
+	}
+	for _, ch := range mb.eventSubs {
+		select {
+		case ch <- ev:
+		default:
+			mb.eventDrops.Add(1)
+		}
+	}
+}
+
+// SubscribeEvents subscribes to events under the given name, replacing an
+// earlier subscription with the same name.
+func (mb *MessageBus) SubscribeEvents(name string, buffer int) <-chan Event {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if buffer <= 0 {
+		buffer = 100
+	}
+	if ch, ok := mb.eventSubs[name]; ok {
+		close(ch)
+	}
+	ch := make(chan Event, buffer)
+	mb.eventSubs[name] = ch
+	return ch
+}
+
+// UnsubscribeEvents unsubscribes from events.
+func (mb *MessageBus) UnsubscribeEvents(name string) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if ch, ok := mb.eventSubs[name]; ok {
+		close(ch)
+		delete(mb.eventSubs, name)
+	}
+}
+
+// EventDropCount returns the number of events dropped for slow subscribers.
+func (mb *MessageBus) EventDropCount() int64 {
+	return mb.eventDrops.Load()
+}
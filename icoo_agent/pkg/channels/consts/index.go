@@ -5,6 +5,8 @@ import "time"
 const (
 	DINGTALK  = "dingtalk"
 	FEISHU    = "feishu"
+	WECOM     = "wecom"
+	QQ        = "qq"
 	TELEGRAM  = "telegram"
 	DISCORD   = "discord"
 	SLACK     = "slack"
@@ -16,6 +18,8 @@ const (
 var ChannelRateConfig = map[string]float64{
 	DINGTALK: 10,
 	FEISHU:   10,
+	WECOM:    5,
+	QQ:       5,
 	TELEGRAM: 20,
 	DISCORD:  1,
 	SLACK:    100,
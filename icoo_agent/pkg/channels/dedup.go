@@ -0,0 +1,101 @@
+package channels
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/storage"
+)
+
+// MessageIDKey 入站消息元数据中平台消息 ID 的键，渠道设置后才能去重
+const MessageIDKey = "message_id"
+
+// dedupPruneInterval 清理过期去重记录的最小间隔
+const dedupPruneInterval = time.Hour
+
+// Deduper 丢弃重复投递的入站消息，并为带幂等键的请求保存处理结果。
+//
+// 平台在网络重试后可能重复投递同一条消息，已见的消息 ID 在 TTL 内保存在存储中，
+// 重启后仍然有效，避免重复运行智能体和重复消耗 Token。
+type Deduper struct {
+	store     *storage.DedupStorage
+	ttl       time.Duration
+	logger    *slog.Logger
+	lastPrune atomic.Int64
+}
+
+// NewDeduper 创建去重器，ttl 为记录的保留时间。
+func NewDeduper(store *storage.DedupStorage, ttl time.Duration, logger *slog.Logger) *Deduper {
+	return &Deduper{store: store, ttl: ttl, logger: logger}
+}
+
+// MessageKey 入站消息的去重键，消息没有平台消息 ID 时返回空字符串。
+func MessageKey(msg bus.InboundMessage) string {
+	id, _ := msg.Metadata[MessageIDKey].(string)
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf("msg:%s:%s", msg.Channel, id)
+}
+
+// IdempotencyKey 请求幂等键的去重键。
+func IdempotencyKey(channel, sessionID, key string) string {
+	return fmt.Sprintf("idem:%s:%s:%s", channel, sessionID, key)
+}
+
+// Duplicate 消息是否已在 TTL 内收到过。没有消息 ID 或存储出错时按新消息处理，宁可重复也不丢消息。
+func (d *Deduper) Duplicate(msg bus.InboundMessage) bool {
+	key := MessageKey(msg)
+	if key == "" {
+		return false
+	}
+	_, claimed, err := d.Claim(key, msg.Channel)
+	if err != nil {
+		d.logger.With("name", "【渠道】").Warn("消息去重失败", "error", err, "channel", msg.Channel)
+		return false
+	}
+	if !claimed {
+		d.logger.With("name", "【渠道】").Info("丢弃重复投递的消息",
+			"channel", msg.Channel,
+			"session_id", msg.SessionID,
+			"message_id", msg.Metadata[MessageIDKey])
+	}
+	return !claimed
+}
+
+// Claim 记录去重键，首次出现时返回 true，否则返回已有记录。
+func (d *Deduper) Claim(key, channel string) (*storage.Dedup, bool, error) {
+	d.prune()
+	return d.store.Claim(key, channel, d.ttl)
+}
+
+// Complete 标记处理完成并保存结果，重试的幂等请求直接返回该结果。
+func (d *Deduper) Complete(key, response string) {
+	if err := d.store.Complete(key, response); err != nil {
+		d.logger.With("name", "【渠道】").Warn("保存幂等请求结果失败", "error", err)
+	}
+}
+
+// Release 处理失败时删除去重键，允许重试再次处理。
+func (d *Deduper) Release(key string) {
+	if err := d.store.Release(key); err != nil {
+		d.logger.With("name", "【渠道】").Warn("释放去重键失败", "error", err)
+	}
+}
+
+// prune 定期清理过期记录。
+func (d *Deduper) prune() {
+	now := time.Now()
+	last := d.lastPrune.Load()
+	if now.Sub(time.Unix(0, last)) < dedupPruneInterval || !d.lastPrune.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	if n, err := d.store.Prune(now); err != nil {
+		d.logger.With("name", "【渠道】").Warn("清理过期去重记录失败", "error", err)
+	} else if n > 0 {
+		d.logger.With("name", "【渠道】").Debug("已清理过期去重记录", "count", n)
+	}
+}
@@ -0,0 +1,87 @@
+package channels
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/storage"
+)
+
+func newTestDeduper(t *testing.T, ttl time.Duration) *Deduper {
+	t.Helper()
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "dedup.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return NewDeduper(store.Dedup(), ttl, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestDeduper_Duplicate(t *testing.T) {
+	d := newTestDeduper(t, time.Hour)
+
+	msg := bus.InboundMessage{Channel: "feishu", SessionID: "c1", Metadata: map[string]any{MessageIDKey: "om_1"}}
+	if d.Duplicate(msg) {
+		t.Fatal("first delivery should not be a duplicate")
+	}
+	if !d.Duplicate(msg) {
+		t.Error("redelivery should be a duplicate")
+	}
+
+	// 不同渠道的相同消息 ID 互不影响
+	other := msg
+	other.Channel = "dingtalk"
+	if d.Duplicate(other) {
+		t.Error("same ID on another channel should not be a duplicate")
+	}
+
+	// 没有消息 ID 的消息不去重
+	plain := bus.InboundMessage{Channel: "feishu", SessionID: "c1"}
+	if d.Duplicate(plain) || d.Duplicate(plain) {
+		t.Error("messages without ID should never be duplicates")
+	}
+}
+
+func TestDeduper_Expiry(t *testing.T) {
+	d := newTestDeduper(t, 50*time.Millisecond)
+
+	msg := bus.InboundMessage{Channel: "feishu", Metadata: map[string]any{MessageIDKey: "om_1"}}
+	if d.Duplicate(msg) {
+		t.Fatal("first delivery should not be a duplicate")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if d.Duplicate(msg) {
+		t.Error("expired ID should be accepted again")
+	}
+}
+
+func TestDeduper_Idempotency(t *testing.T) {
+	d := newTestDeduper(t, time.Hour)
+	key := IdempotencyKey("websocket", "s1", "req-1")
+
+	if _, claimed, err := d.Claim(key, "websocket"); err != nil || !claimed {
+		t.Fatalf("Claim() = %v, %v", claimed, err)
+	}
+	record, claimed, err := d.Claim(key, "websocket")
+	if err != nil || claimed || record.Done {
+		t.Fatalf("in-flight Claim() = %+v, %v, %v", record, claimed, err)
+	}
+
+	d.Complete(key, "答复")
+	record, claimed, _ = d.Claim(key, "websocket")
+	if claimed || !record.Done || record.Response != "答复" {
+		t.Errorf("completed Claim() = %+v, %v", record, claimed)
+	}
+
+	// 失败后释放，重试重新处理
+	failed := IdempotencyKey("websocket", "s1", "req-2")
+	d.Claim(failed, "websocket")
+	d.Release(failed)
+	if _, claimed, _ := d.Claim(failed, "websocket"); !claimed {
+		t.Error("released key should be claimable again")
+	}
+}
@@ -16,7 +16,8 @@ import (
 )
 
 const (
-	dingtalkAPIBase = "https://oapi.dingtalk.com"
+	dingtalkAPIBase   = "https://oapi.dingtalk.com"
+	dingtalkAPIBaseV1 = "https://api.dingtalk.com/v1.0"
 )
 
 // APIClient provides DingTalk API access.
@@ -155,6 +156,68 @@ func (c *APIClient) SendMessage(ctx context.Context, agentID int64, userID, msgT
 	return nil
 }
 
+// SendRobotMessage sends a message as the chatbot without a session webhook. Group chats
+// are addressed by openConversationId, direct chats by the user's staff ID.
+func (c *APIClient) SendRobotMessage(ctx context.Context, target RobotTarget, msgKey string, msgParam any) error {
+	token, err := c.GetAccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("get access token: %w", err)
+	}
+
+	param, err := json.Marshal(msgParam)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	body := map[string]any{
+		"robotCode": c.clientID,
+		"msgKey":    msgKey,
+		"msgParam":  string(param),
+	}
+	apiURL := dingtalkAPIBaseV1 + "/robot/oToMessages/batchSend"
+	if target.ConversationID != "" {
+		apiURL = dingtalkAPIBaseV1 + "/robot/groupMessages/send"
+		body["openConversationId"] = target.ConversationID
+	} else {
+		body["userIds"] = []string{target.UserID}
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-acs-dingtalk-access-token", token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return fmt.Errorf("API error (status=%d code=%s msg=%s)", resp.StatusCode, result.Code, result.Message)
+	}
+
+	return nil
+}
+
+// RobotTarget identifies the chat a robot message is sent to.
+type RobotTarget struct {
+	ConversationID string // 群聊的 openConversationId
+	UserID         string // 单聊用户的 staffId
+}
+
 // UploadMedia uploads a media file to DingTalk.
 func (c *APIClient) UploadMedia(ctx context.Context, filePath, mediaType string) (string, error) {
 	token, err := c.GetAccessToken(ctx)
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,6 +28,13 @@ type Config struct {
 	ReasoningChatID string   `json:"reasoning_chat_id" mapstructure:"reasoning_chat_id"`
 }
 
+// conversation 会话的回复方式。Session Webhook 过期或不存在时通过机器人接口主动发送。
+type conversation struct {
+	webhook   string
+	expiresAt time.Time
+	target    RobotTarget
+}
+
 // Channel implements the channels.Channel interface for DingTalk.
 type Channel struct {
 	config       Config
@@ -35,11 +43,12 @@ type Channel struct {
 	clientID     string
 	clientSecret string
 	streamClient *client.StreamClient
+	api          *APIClient
 	ctx          context.Context
 	cancel       context.CancelFunc
 
-	// Map to store session webhooks for each chat
-	sessionWebhooks sync.Map // chatID -> sessionWebhook
+	// Map to store how to reply to each chat
+	conversations sync.Map // chatID -> conversation
 
 	running atomic.Bool
 }
@@ -56,6 +65,7 @@ func New(cfg Config, b *bus.MessageBus, logger *slog.Logger) (*Channel, error) {
 		logger:       logger,
 		clientID:     cfg.ClientID,
 		clientSecret: cfg.ClientSecret,
+		api:          NewAPIClient(cfg.ClientID, cfg.ClientSecret, logger),
 	}, nil
 }
 
@@ -145,23 +155,54 @@ func (c *Channel) Send(ctx context.Context, msg channels.OutboundMessage) error
 		return errs.ErrNotRunning
 	}
 
-	// Get session webhook from storage
-	sessionWebhookRaw, ok := c.sessionWebhooks.Load(msg.SessionID)
-	if !ok {
-		c.logger.With("name", "【钉钉】").Error("未找到会话webhook", "session_id", msg.SessionID)
-		return fmt.Errorf("未找到会话webhook，无法发送消息：%s", msg.SessionID)
+	// Long replies are split into chunks, action buttons go on the last one
+	var actions []cardAction
+	index, _ := msg.Metadata["chunk_index"].(int)
+	total, _ := msg.Metadata["chunk_total"].(int)
+	if index == total {
+		actions = parseActions(msg.Metadata)
+	}
+
+	c.logger.With("name", "【钉钉】").Debug("发送消息", "session_id", msg.SessionID, "preview", truncate(msg.Text, 100))
+
+	conv, ok := c.lookupConversation(msg.SessionID)
+	if ok && conv.webhook != "" && time.Now().Before(conv.expiresAt) {
+		// Use the session webhook to send the reply
+		return c.sendReply(ctx, conv.webhook, buildReplyBody(msg.Text, actions))
 	}
 
-	sessionWebhook, ok := sessionWebhookRaw.(string)
+	// Session webhook expired or unknown (e.g. replayed or scheduled messages), send as the robot
 	if !ok {
-		c.logger.With("name", "【钉钉】").Error("无效的会话webhook类型", "session_id", msg.SessionID)
-		return fmt.Errorf("invalid session_webhook type for session %s", msg.SessionID)
+		conv.target = c.guessTarget(msg.SessionID)
 	}
+	text := FormatMarkdownForDingTalk(msg.Text)
+	err := c.api.SendRobotMessage(ctx, conv.target, "sampleMarkdown", map[string]string{
+		"title": markdownTitle(text),
+		"text":  text,
+	})
+	if err != nil {
+		c.logger.With("name", "【钉钉】").Error("发送失败", "session_id", msg.SessionID, "error", err)
+		return fmt.Errorf("dingtalk send: %w", errs.ErrTemporary)
+	}
+	return nil
+}
 
-	c.logger.With("name", "【钉钉】").Debug("发送消息", "session_id", msg.SessionID, "preview", truncate(msg.Text, 100))
+// lookupConversation returns the reply route recorded for a chat.
+func (c *Channel) lookupConversation(chatID string) (conversation, bool) {
+	v, ok := c.conversations.Load(chatID)
+	if !ok {
+		return conversation{}, false
+	}
+	return v.(conversation), true
+}
 
-	// Use the session webhook to send the reply
-	return c.SendDirectReply(ctx, sessionWebhook, msg.Text)
+// guessTarget derives the robot target from a session ID seen before a restart:
+// group sessions are conversation IDs (prefixed "cid"), direct sessions are staff IDs.
+func (c *Channel) guessTarget(chatID string) RobotTarget {
+	if strings.HasPrefix(chatID, "cid") {
+		return RobotTarget{ConversationID: chatID}
+	}
+	return RobotTarget{UserID: chatID}
 }
 
 // onChatBotMessageReceived implements the IChatBotMessageHandler function signature.
@@ -177,7 +218,7 @@ func (c *Channel) onChatBotMessageReceived(
 // processDingTalkMessage processes an incoming message asynchronously.
 func (c *Channel) processDingTalkMessage(ctx context.Context, data *chatbot.BotCallbackDataModel) {
 	// Extract message content from Text field
-	content := data.Text.Content
+	content := strings.TrimSpace(data.Text.Content)
 	if content == "" {
 		// Try to extract from Content interface{} if Text is empty
 		if contentMap, ok := data.Content.(map[string]any); ok {
@@ -191,16 +232,26 @@ func (c *Channel) processDingTalkMessage(ctx context.Context, data *chatbot.BotC
 		return // Ignore empty messages
 	}
 
+	// Direct chats map to the sender, group chats to the conversation
 	senderID := data.SenderStaffId
+	if senderID == "" {
+		senderID = data.SenderId
+	}
 	senderNick := data.SenderNick
 	chatID := senderID
+	target := RobotTarget{UserID: data.SenderStaffId}
 	if data.ConversationType != "1" {
 		// For group chats
 		chatID = data.ConversationId
+		target = RobotTarget{ConversationID: data.ConversationId}
 	}
 
 	// Store the session webhook for this chat so we can reply later
-	c.sessionWebhooks.Store(chatID, data.SessionWebhook)
+	c.conversations.Store(chatID, conversation{
+		webhook:   data.SessionWebhook,
+		expiresAt: time.UnixMilli(data.SessionWebhookExpiredTime),
+		target:    target,
+	})
 
 	// Check allowlist
 	if !c.IsAllowed(senderID) {
@@ -214,6 +265,16 @@ func (c *Channel) processDingTalkMessage(ctx context.Context, data *chatbot.BotC
 		"platform":          "dingtalk",
 		"session_webhook":   data.SessionWebhook,
 	}
+	if data.ConversationType != "1" {
+		metadata["chat_type"] = "group"
+		metadata["conversation_title"] = data.ConversationTitle
+		metadata["mentioned"] = data.IsInAtList
+	} else {
+		metadata["chat_type"] = "p2p"
+	}
+	if data.MsgId != "" {
+		metadata[channels.MessageIDKey] = data.MsgId
+	}
 
 	c.logger.With("name", "【钉钉】").Debug("收到消息",
 		"sender_nick", senderNick,
@@ -239,22 +300,15 @@ func (c *Channel) processDingTalkMessage(ctx context.Context, data *chatbot.BotC
 	}
 }
 
-// SendDirectReply sends a direct reply using the session webhook.
+// SendDirectReply sends a markdown reply using the session webhook.
 func (c *Channel) SendDirectReply(ctx context.Context, sessionWebhook, content string) error {
-	replier := chatbot.NewChatbotReplier()
-
-	// Convert string content to []byte for the API
-	contentBytes := []byte(content)
-	titleBytes := []byte("AI Assistant")
+	return c.sendReply(ctx, sessionWebhook, buildReplyBody(content, nil))
+}
 
-	// Send markdown formatted reply
-	err := replier.SimpleReplyMarkdown(
-		ctx,
-		sessionWebhook,
-		titleBytes,
-		contentBytes,
-	)
-	if err != nil {
+// sendReply posts a message body to the session webhook.
+func (c *Channel) sendReply(ctx context.Context, sessionWebhook string, body map[string]any) error {
+	replier := chatbot.NewChatbotReplier()
+	if err := replier.ReplyMessage(ctx, sessionWebhook, body); err != nil {
 		c.logger.With("name", "【钉钉】").Error("发送失败", "error", err)
 		return fmt.Errorf("dingtalk send: %w", errs.ErrTemporary)
 	}
@@ -3,8 +3,12 @@ package dingtalk
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
+	"unicode/utf8"
+
+	"icooclaw/pkg/consts"
 )
 
 // MessageType represents the type of DingTalk message.
@@ -51,6 +55,102 @@ type LinkMessage struct {
 	PicURL     string `json:"picUrl,omitempty"`
 }
 
+// ActionCardMessage represents an action card message with independent buttons.
+type ActionCardMessage struct {
+	Title          string             `json:"title"`
+	Text           string             `json:"text"`
+	BtnOrientation string             `json:"btnOrientation"`
+	Btns           []ActionCardButton `json:"btns"`
+}
+
+// ActionCardButton represents a button of an action card.
+type ActionCardButton struct {
+	Title     string `json:"title"`
+	ActionURL string `json:"actionURL"`
+}
+
+// cardAction 快捷操作，点击按钮后把 Command 作为用户消息发回。
+type cardAction struct {
+	Label   string `json:"label"`
+	Command string `json:"command"`
+}
+
+// parseActions 从出站消息元数据中读取快捷操作列表。
+// 元数据中的操作可能是任意带 label、command 字段的结构体切片，统一经 JSON 转换。
+func parseActions(metadata map[string]any) []cardAction {
+	raw, ok := metadata[consts.META_ACTIONS]
+	if !ok || raw == nil {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var actions []cardAction
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil
+	}
+
+	valid := actions[:0]
+	for _, a := range actions {
+		if a.Label != "" && a.Command != "" {
+			valid = append(valid, a)
+		}
+	}
+	return valid
+}
+
+// sendMessageURL 返回点击后以用户身份在当前会话发送 text 的钉钉客户端链接。
+func sendMessageURL(text string) string {
+	return "dtmd://dingtalkclient/sendMessage?content=" + url.QueryEscape(text)
+}
+
+// buildReplyBody builds the session webhook request body: a markdown message, or an
+// action card when the reply carries actions.
+func buildReplyBody(content string, actions []cardAction) map[string]any {
+	text := FormatMarkdownForDingTalk(content)
+	title := markdownTitle(text)
+
+	if len(actions) == 0 {
+		return map[string]any{
+			"msgtype":  string(MessageTypeMarkdown),
+			"markdown": map[string]any{"title": title, "text": text},
+		}
+	}
+
+	card := ActionCardMessage{Title: title, Text: text, BtnOrientation: "1"}
+	if len(actions) > 2 {
+		card.BtnOrientation = "0" // 按钮较多时纵向排列
+	}
+	for _, a := range actions {
+		card.Btns = append(card.Btns, ActionCardButton{Title: a.Label, ActionURL: sendMessageURL(a.Command)})
+	}
+	return map[string]any{
+		"msgtype":    string(MessageTypeAction),
+		"actionCard": card,
+	}
+}
+
+// markdownTitle 取正文第一行的纯文本作为消息标题，显示在会话列表和通知中。
+func markdownTitle(content string) string {
+	const maxTitle = 30
+
+	title := ""
+	for _, line := range strings.Split(ExtractTextFromMarkdown(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" && line != "[code]" {
+			title = line
+			break
+		}
+	}
+	if title == "" {
+		return "AI Assistant"
+	}
+	if utf8.RuneCountInString(title) > maxTitle {
+		title = string([]rune(title)[:maxTitle]) + "…"
+	}
+	return title
+}
+
 // BuildTextContent builds a text message content JSON.
 func BuildTextContent(text string) string {
 	msg := TextMessage{Content: text}
@@ -0,0 +1,70 @@
+package dingtalk
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/consts"
+)
+
+func TestBuildReplyBody(t *testing.T) {
+	body := buildReplyBody("## 结果\n一切正常", nil)
+	if body["msgtype"] != "markdown" {
+		t.Fatalf("msgtype = %v, want markdown", body["msgtype"])
+	}
+	md := body["markdown"].(map[string]any)
+	if md["title"] != "结果" || md["text"] != "**结果**\n一切正常" {
+		t.Errorf("markdown = %+v", md)
+	}
+
+	body = buildReplyBody("预计消耗较多 tokens", []cardAction{
+		{Label: "继续", Command: "/cost yes"},
+		{Label: "取消", Command: "/cost no"},
+	})
+	if body["msgtype"] != "actionCard" {
+		t.Fatalf("msgtype = %v, want actionCard", body["msgtype"])
+	}
+	card := body["actionCard"].(ActionCardMessage)
+	if len(card.Btns) != 2 || card.BtnOrientation != "1" || card.Btns[0].Title != "继续" {
+		t.Fatalf("card = %+v", card)
+	}
+
+	u, err := url.Parse(card.Btns[1].ActionURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "dtmd" || u.Query().Get("content") != "/cost no" {
+		t.Errorf("actionURL = %q", card.Btns[1].ActionURL)
+	}
+}
+
+func TestParseActions(t *testing.T) {
+	type action struct {
+		Label   string `json:"label"`
+		Command string `json:"command"`
+	}
+	got := parseActions(map[string]any{
+		consts.META_ACTIONS: []action{{Label: "置顶", Command: "/memory pin 1"}, {Command: "/x"}},
+	})
+	if len(got) != 1 || got[0].Command != "/memory pin 1" {
+		t.Errorf("parseActions() = %+v", got)
+	}
+}
+
+func TestMarkdownTitle(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"**摘要**\n内容", "摘要"},
+		{"```\ncode\n```\n之后", "之后"},
+		{"", "AI Assistant"},
+		{strings.Repeat("长", 40), strings.Repeat("长", 30) + "…"},
+	}
+	for _, tt := range tests {
+		if got := markdownTitle(tt.in); got != tt.want {
+			t.Errorf("markdownTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -54,12 +54,15 @@ func parseConfig(config map[string]any) (Config, error) {
 	}
 
 	if v, ok := config["allow_from"]; ok {
-		if arr, ok := v.([]any); ok {
+		switch arr := v.(type) {
+		case []any:
 			for _, item := range arr {
 				if s, ok := item.(string); ok {
 					cfg.AllowFrom = append(cfg.AllowFrom, s)
 				}
 			}
+		case []string:
+			cfg.AllowFrom = append(cfg.AllowFrom, arr...)
 		}
 	}
 
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 
+	"icooclaw/pkg/channels"
 	"icooclaw/pkg/channels/errs"
 )
 
@@ -97,44 +100,34 @@ func (c *Channel) SendImage(ctx context.Context, chatID string, imagePath string
 	return nil
 }
 
-// SendFile sends a file message to a chat.
-func (c *Channel) SendFile(ctx context.Context, chatID string, filePath string, fileType string) error {
+// SendFile implements channels.FileSender: images are sent as image messages so
+// they display inline, other files are uploaded and sent as file messages.
+func (c *Channel) SendFile(ctx context.Context, chatID string, f channels.File) error {
 	if !c.IsRunning() {
 		return errs.ErrNotRunning
 	}
+	if f.IsImage() {
+		return c.SendImage(ctx, chatID, f.Path)
+	}
 
-	file, err := os.Open(filePath)
+	file, err := os.Open(f.Path)
 	if err != nil {
 		return fmt.Errorf("open file: %w", err)
 	}
 	defer file.Close()
 
-	// Map file type to Feishu file type
-	feishuFileType := "stream"
-	switch fileType {
-	case "audio":
-		feishuFileType = "opus"
-	case "video":
-		feishuFileType = "mp4"
-	}
-
-	filename := filePath
-	if len(filename) > 100 {
-		filename = filename[len(filename)-100:]
-	}
-
 	// Upload file to get file_key
 	uploadReq := larkim.NewCreateFileReqBuilder().
 		Body(larkim.NewCreateFileReqBodyBuilder().
-			FileType(feishuFileType).
-			FileName(filename).
+			FileType(uploadFileType(f.Name)).
+			FileName(f.Name).
 			File(file).
 			Build()).
 		Build()
 
 	uploadResp, err := c.client.Im.V1.File.Create(ctx, uploadReq)
 	if err != nil {
-		return fmt.Errorf("feishu file upload: %w", err)
+		return fmt.Errorf("feishu file upload: %w", errs.ErrTemporary)
 	}
 	if !uploadResp.Success() {
 		return fmt.Errorf("feishu file upload api error (code=%d msg=%s)", uploadResp.Code, uploadResp.Msg)
@@ -158,7 +151,7 @@ func (c *Channel) SendFile(ctx context.Context, chatID string, filePath string,
 
 	resp, err := c.client.Im.V1.Message.Create(ctx, req)
 	if err != nil {
-		return fmt.Errorf("feishu file send: %w", err)
+		return fmt.Errorf("feishu file send: %w", errs.ErrTemporary)
 	}
 	if !resp.Success() {
 		return fmt.Errorf("feishu file send api error (code=%d msg=%s)", resp.Code, resp.Msg)
@@ -166,6 +159,26 @@ func (c *Channel) SendFile(ctx context.Context, chatID string, filePath string,
 	return nil
 }
 
+// uploadFileType maps a file name to the Feishu upload file type, "stream" for anything else.
+func uploadFileType(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".pdf":
+		return larkim.FileTypePdf
+	case ".doc", ".docx":
+		return larkim.FileTypeDoc
+	case ".xls", ".xlsx":
+		return larkim.FileTypeXls
+	case ".ppt", ".pptx":
+		return larkim.FileTypePpt
+	case ".mp4":
+		return larkim.FileTypeMp4
+	case ".opus":
+		return larkim.FileTypeOpus
+	default:
+		return larkim.FileTypeStream
+	}
+}
+
 // UserInfo contains user information.
 type UserInfo struct {
 	Name    string
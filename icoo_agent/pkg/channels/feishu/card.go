@@ -0,0 +1,110 @@
+package feishu
+
+import (
+	"encoding/json"
+
+	"icooclaw/pkg/consts"
+)
+
+// cardAction 卡片按钮，点击后把 Command 作为用户消息发回。
+type cardAction struct {
+	Label   string `json:"label"`
+	Command string `json:"command"`
+}
+
+// parseActions 从出站消息元数据中读取快捷操作列表。
+// 元数据中的操作可能是任意带 label、command 字段的结构体切片，统一经 JSON 转换。
+func parseActions(metadata map[string]any) []cardAction {
+	raw, ok := metadata[consts.META_ACTIONS]
+	if !ok || raw == nil {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var actions []cardAction
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil
+	}
+
+	valid := actions[:0]
+	for _, a := range actions {
+		if a.Label != "" && a.Command != "" {
+			valid = append(valid, a)
+		}
+	}
+	return valid
+}
+
+// buildCard builds a Feishu Interactive Card JSON 2.0 string with markdown content
+// followed by one callback button per action.
+func buildCard(content string, actions []cardAction) (string, error) {
+	elements := []map[string]any{
+		{
+			"tag":     "markdown",
+			"content": content,
+		},
+	}
+
+	if len(actions) > 0 {
+		columns := make([]map[string]any, 0, len(actions))
+		for i, a := range actions {
+			buttonType := "default"
+			if i == 0 {
+				buttonType = "primary"
+			}
+			columns = append(columns, map[string]any{
+				"tag":   "column",
+				"width": "auto",
+				"elements": []map[string]any{
+					{
+						"tag":  "button",
+						"text": map[string]any{"tag": "plain_text", "content": a.Label},
+						"type": buttonType,
+						"behaviors": []map[string]any{
+							{"type": "callback", "value": map[string]any{"command": a.Command}},
+						},
+					},
+				},
+			})
+		}
+		elements = append(elements, map[string]any{
+			"tag":       "column_set",
+			"flex_mode": "flow",
+			"columns":   columns,
+		})
+	}
+
+	return marshalCard(map[string]any{
+		"schema": "2.0",
+		"body":   map[string]any{"elements": elements},
+	})
+}
+
+// buildStatusCard builds the progress card shown while tools are running.
+func buildStatusCard(text string) (string, error) {
+	return marshalCard(map[string]any{
+		"schema": "2.0",
+		"header": map[string]any{
+			"title":    map[string]any{"tag": "plain_text", "content": "处理中"},
+			"template": "blue",
+		},
+		"body": map[string]any{
+			"elements": []map[string]any{
+				{
+					"tag":     "markdown",
+					"content": text,
+				},
+			},
+		},
+	})
+}
+
+func marshalCard(card map[string]any) (string, error) {
+	data, err := json.Marshal(card)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
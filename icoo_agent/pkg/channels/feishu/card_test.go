@@ -0,0 +1,120 @@
+package feishu
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	larkevent "github.com/larksuite/oapi-sdk-go/v3/event"
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+
+	"icooclaw/pkg/consts"
+)
+
+func TestParseActions(t *testing.T) {
+	type action struct {
+		Label   string `json:"label"`
+		Command string `json:"command"`
+	}
+	metadata := map[string]any{
+		consts.META_ACTIONS: []action{
+			{Label: "继续", Command: "/cost yes"},
+			{Label: "", Command: "/skip"},
+			{Label: "取消", Command: "/cost no"},
+		},
+	}
+
+	got := parseActions(metadata)
+	if len(got) != 2 || got[0].Command != "/cost yes" || got[1].Label != "取消" {
+		t.Fatalf("parseActions() = %+v", got)
+	}
+	if parseActions(map[string]any{}) != nil {
+		t.Error("parseActions() without actions should return nil")
+	}
+}
+
+func TestBuildCard(t *testing.T) {
+	content, err := buildCard("**hi**", []cardAction{{Label: "继续", Command: "/cost yes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var card struct {
+		Schema string `json:"schema"`
+		Body   struct {
+			Elements []struct {
+				Tag     string `json:"tag"`
+				Content string `json:"content"`
+				Columns []struct {
+					Elements []struct {
+						Type      string `json:"type"`
+						Behaviors []struct {
+							Type  string            `json:"type"`
+							Value map[string]string `json:"value"`
+						} `json:"behaviors"`
+					} `json:"elements"`
+				} `json:"columns"`
+			} `json:"elements"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(content), &card); err != nil {
+		t.Fatal(err)
+	}
+	if card.Schema != "2.0" || len(card.Body.Elements) != 2 || card.Body.Elements[0].Content != "**hi**" {
+		t.Fatalf("unexpected card: %s", content)
+	}
+	button := card.Body.Elements[1].Columns[0].Elements[0]
+	if button.Type != "primary" || button.Behaviors[0].Type != "callback" || button.Behaviors[0].Value["command"] != "/cost yes" {
+		t.Errorf("unexpected button: %+v", button)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	str := func(s string) *string { return &s }
+	mentions := []*larkim.MentionEvent{
+		{Key: str("@_user_1"), Name: str("bot"), Id: &larkim.UserId{OpenId: str("ou_bot")}},
+		{Key: str("@_user_2"), Name: str("张三"), Id: &larkim.UserId{OpenId: str("ou_zs")}},
+	}
+
+	if got := stripMentionPlaceholders("@_user_1 问一下 @_user_2 的进度", mentions, "ou_bot"); got != "问一下 @张三 的进度" {
+		t.Errorf("stripMentionPlaceholders() = %q", got)
+	}
+	if !mentionsBot(mentions, "ou_bot") {
+		t.Error("mentionsBot() should find the bot")
+	}
+	if mentionsBot(mentions[1:], "ou_bot") {
+		t.Error("mentionsBot() should ignore other users")
+	}
+	if !mentionsBot(mentions[1:], "") {
+		t.Error("mentionsBot() should accept any mention when the bot ID is unknown")
+	}
+}
+
+func TestIsChallenge(t *testing.T) {
+	const key = "encrypt-key"
+	// 与飞书相同的加密方式：AES-256-CBC，密钥为 SHA256(key)，IV 置于密文前
+	encrypt := func(plain string) []byte {
+		sum := sha256.Sum256([]byte(key))
+		block, _ := aes.NewCipher(sum[:])
+		pad := aes.BlockSize - len(plain)%aes.BlockSize
+		buf := append([]byte(plain), bytes.Repeat([]byte{byte(pad)}, pad)...)
+		out := make([]byte, aes.BlockSize+len(buf))
+		cipher.NewCBCEncrypter(block, out[:aes.BlockSize]).CryptBlocks(out[aes.BlockSize:], buf)
+		body, _ := json.Marshal(larkevent.EventEncryptMsg{Encrypt: base64.StdEncoding.EncodeToString(out)})
+		return body
+	}
+
+	if !isChallenge(encrypt(`{"type":"url_verification","challenge":"c"}`), key) {
+		t.Error("url_verification should be a challenge")
+	}
+	if isChallenge(encrypt(`{"schema":"2.0","header":{}}`), key) {
+		t.Error("events should not be challenges")
+	}
+	if isChallenge([]byte(`{"type":"url_verification"}`), key) {
+		t.Error("unencrypted bodies should not be challenges")
+	}
+}
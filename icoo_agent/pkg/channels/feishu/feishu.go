@@ -16,7 +16,9 @@ import (
 
 	lark "github.com/larksuite/oapi-sdk-go/v3"
 	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+	"github.com/larksuite/oapi-sdk-go/v3/core/httpserverext"
 	larkdispatcher "github.com/larksuite/oapi-sdk-go/v3/event/dispatcher"
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 	larkws "github.com/larksuite/oapi-sdk-go/v3/ws"
 
@@ -34,6 +36,18 @@ type Config struct {
 	VerificationToken string   `json:"verification_token" mapstructure:"verification_token"`
 	AllowFrom         []string `json:"allow_from" mapstructure:"allow_from"`
 	ReasoningChatID   string   `json:"reasoning_chat_id" mapstructure:"reasoning_chat_id"`
+	Mode              string   `json:"mode" mapstructure:"mode"`                       // 接收事件的方式：websocket（默认）或 webhook
+	WebhookPath       string   `json:"webhook_path" mapstructure:"webhook_path"`       // webhook 模式的回调路径，默认 /webhook/feishu
+	GroupReplyAll     bool     `json:"group_reply_all" mapstructure:"group_reply_all"` // 群聊中没有 @ 机器人的消息也回复
+}
+
+// statusCardTTL 进度卡片的有效期，超过后回复不再替换它
+const statusCardTTL = 10 * time.Minute
+
+// statusCard 本轮对话的进度卡片
+type statusCard struct {
+	messageID string
+	createdAt time.Time
 }
 
 // Channel implements the channels.Channel interface for Feishu/Lark.
@@ -44,6 +58,10 @@ type Channel struct {
 	wsClient *larkws.Client
 	logger   *slog.Logger
 
+	dispatcher   *larkdispatcher.EventDispatcher
+	eventHandler http.HandlerFunc // webhook 模式的事件回调处理
+	statusCards  sync.Map         // chatID -> statusCard
+
 	botOpenID atomic.Value // stores string; populated lazily for @mention detection
 
 	running atomic.Bool
@@ -57,12 +75,25 @@ func New(cfg Config, b *bus.MessageBus, logger *slog.Logger) (*Channel, error) {
 		return nil, fmt.Errorf("feishu app_id and app_secret are required")
 	}
 
-	return &Channel{
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = ModeWebSocket
+	case ModeWebSocket, ModeWebhook:
+	default:
+		return nil, fmt.Errorf("feishu mode must be %s or %s", ModeWebSocket, ModeWebhook)
+	}
+
+	c := &Channel{
 		config: cfg,
 		bus:    b,
 		client: lark.NewClient(cfg.AppID, cfg.AppSecret),
 		logger: logger,
-	}, nil
+	}
+	c.dispatcher = larkdispatcher.NewEventDispatcher(cfg.VerificationToken, cfg.EncryptKey).
+		OnP2MessageReceiveV1(c.handleMessageReceive).
+		OnP2CardActionTrigger(c.handleCardAction)
+	c.eventHandler = httpserverext.NewEventHandlerFunc(c.dispatcher)
+	return c, nil
 }
 
 // Name returns the channel name.
@@ -82,8 +113,12 @@ func (c *Channel) Start(ctx context.Context) error {
 		return fmt.Errorf("获取机器人open_id失败：%w", err)
 	}
 
-	dispatcher := larkdispatcher.NewEventDispatcher(c.config.VerificationToken, c.config.EncryptKey).
-		OnP2MessageReceiveV1(c.handleMessageReceive)
+	// webhook 模式由事件订阅的请求地址接收事件，不建立长连接
+	if c.config.Mode == ModeWebhook {
+		c.running.Store(true)
+		c.logger.With("name", "【飞书】").Info("启动通道...（webhook 模式）", "path", c.WebhookPath())
+		return nil
+	}
 
 	runCtx, cancel := context.WithCancel(ctx)
 
@@ -92,7 +127,7 @@ func (c *Channel) Start(ctx context.Context) error {
 	c.wsClient = larkws.NewClient(
 		c.config.AppID,
 		c.config.AppSecret,
-		larkws.WithEventHandler(dispatcher),
+		larkws.WithEventHandler(c.dispatcher),
 	)
 	wsClient := c.wsClient
 	c.mu.Unlock()
@@ -164,13 +199,68 @@ func (c *Channel) Send(ctx context.Context, msg channels.OutboundMessage) error
 		return fmt.Errorf("session ID is empty: %w", errs.ErrSendFailed)
 	}
 
+	// Long replies are split into chunks: the first one replaces the progress card,
+	// action buttons go on the last one.
+	index, _ := msg.Metadata["chunk_index"].(int)
+	total, _ := msg.Metadata["chunk_total"].(int)
+
+	var actions []cardAction
+	if index == total {
+		actions = parseActions(msg.Metadata)
+	}
+
 	// Build interactive card with markdown content
-	cardContent, err := buildMarkdownCard(msg.Text)
+	cardContent, err := buildCard(msg.Text, actions)
 	if err != nil {
 		c.logger.With("name", "【飞书】").Error("发送消息失败：卡片构建失败", "error", err)
 		return fmt.Errorf("feishu send: card build failed: %w", err)
 	}
-	return c.sendCard(ctx, msg.SessionID, cardContent)
+
+	if index <= 1 {
+		if card, ok := c.takeStatusCard(msg.SessionID); ok {
+			if err := c.patchCard(ctx, card.messageID, cardContent); err == nil {
+				return nil
+			}
+			c.logger.With("name", "【飞书】").Warn("替换进度卡片失败，作为新消息发送", "chat_id", msg.SessionID)
+		}
+	}
+
+	_, err = c.sendCard(ctx, msg.SessionID, cardContent)
+	return err
+}
+
+// ReportStatus implements channels.StatusReporter. The first heartbeat of a turn sends a
+// progress card, later ones update it in place and the reply replaces it.
+func (c *Channel) ReportStatus(ctx context.Context, chatID, text string) error {
+	cardContent, err := buildStatusCard(text)
+	if err != nil {
+		return fmt.Errorf("feishu status: card build failed: %w", err)
+	}
+
+	if v, ok := c.statusCards.Load(chatID); ok {
+		if card := v.(statusCard); time.Since(card.createdAt) < statusCardTTL {
+			return c.patchCard(ctx, card.messageID, cardContent)
+		}
+	}
+
+	messageID, err := c.sendCard(ctx, chatID, cardContent)
+	if err != nil {
+		return err
+	}
+	if messageID != "" {
+		c.statusCards.Store(chatID, statusCard{messageID: messageID, createdAt: time.Now()})
+	}
+	return nil
+}
+
+// takeStatusCard removes and returns the chat's progress card if it is still fresh.
+func (c *Channel) takeStatusCard(chatID string) (statusCard, bool) {
+	v, ok := c.statusCards.LoadAndDelete(chatID)
+	if !ok {
+		return statusCard{}, false
+	}
+	card := v.(statusCard)
+	return card, time.Since(card.createdAt) < statusCardTTL
 }
 
 // EditMessage implements channels.MessageEditor.
@@ -179,7 +269,11 @@ func (c *Channel) EditMessage(ctx context.Context, chatID, messageID, content st
 	if err != nil {
 		return fmt.Errorf("feishu edit: card build failed: %w", err)
 	}
+	return c.patchCard(ctx, messageID, cardContent)
+}
 
+// patchCard replaces the content of a card message.
+func (c *Channel) patchCard(ctx context.Context, messageID, cardContent string) error {
 	req := larkim.NewPatchMessageReqBuilder().
 		MessageId(messageID).
 		Body(larkim.NewPatchMessageReqBodyBuilder().Content(cardContent).Build()).
@@ -304,8 +398,19 @@ func (c *Channel) processMessageAsync(ctx context.Context, event *larkim.P2Messa
 		return
 	}
 
+	// 群聊中只处理 @ 机器人的消息
+	chatType := stringValue(message.ChatType)
+	botOpenID, _ := c.botOpenID.Load().(string)
+	mentioned := mentionsBot(message.Mentions, botOpenID)
+	if chatType == "group" && !mentioned && !c.config.GroupReplyAll {
+		return
+	}
+
 	// Extract content based on message type
 	content := extractContent(messageType, rawContent)
+	if messageType == larkim.MsgTypeText {
+		content = stripMentionPlaceholders(content, message.Mentions, botOpenID)
+	}
 
 	// Handle media messages
 	var mediaRefs []string
@@ -322,15 +427,17 @@ func (c *Channel) processMessageAsync(ctx context.Context, event *larkim.P2Messa
 
 	metadata := map[string]any{}
 	if messageID != "" {
-		metadata["message_id"] = messageID
+		metadata[channels.MessageIDKey] = messageID
 	}
 	if messageType != "" {
 		metadata["message_type"] = messageType
 	}
-	chatType := stringValue(message.ChatType)
 	if chatType != "" {
 		metadata["chat_type"] = chatType
 	}
+	if mentioned {
+		metadata["mentioned"] = true
+	}
 	if sender != nil && sender.TenantKey != nil {
 		metadata["tenant_key"] = *sender.TenantKey
 	}
@@ -361,6 +468,44 @@ func (c *Channel) processMessageAsync(ctx context.Context, event *larkim.P2Messa
 	}
 }
 
+// handleCardAction turns a card button click into a user message carrying the button's command.
+func (c *Channel) handleCardAction(ctx context.Context, event *callback.CardActionTriggerEvent) (*callback.CardActionTriggerResponse, error) {
+	if event == nil || event.Event == nil || event.Event.Action == nil || event.Event.Context == nil {
+		return nil, nil
+	}
+	command, _ := event.Event.Action.Value["command"].(string)
+	chatID := event.Event.Context.OpenChatID
+	if command == "" || chatID == "" {
+		return nil, nil
+	}
+
+	senderID := extractOperatorID(event.Event.Operator)
+	if !c.IsAllowed(senderID) {
+		return &callback.CardActionTriggerResponse{Toast: &callback.Toast{Type: "error", Content: "无权操作"}}, nil
+	}
+
+	inboundMsg := bus.InboundMessage{
+		Channel:   c.Name(),
+		SessionID: chatID,
+		Sender:    bus.SenderInfo{ID: senderID},
+		Text:      command,
+		Metadata: map[string]any{
+			"card_action":     true,
+			"card_message_id": event.Event.Context.OpenMessageID,
+		},
+	}
+
+	c.logger.With("name", "【飞书】").Info("收到卡片操作", "sender_id", senderID, "chat_id", chatID, "command", command)
+
+	pubCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.bus.PublishInbound(pubCtx, inboundMsg); err != nil {
+		c.logger.With("name", "【飞书】").Error("发布消息失败", "error", err)
+		return &callback.CardActionTriggerResponse{Toast: &callback.Toast{Type: "error", Content: "操作失败，请稍后重试"}}, nil
+	}
+	return &callback.CardActionTriggerResponse{Toast: &callback.Toast{Type: "info", Content: "已发送 " + command}}, nil
+}
+
 // --- Internal helpers ---
 
 // fetchBotOpenID calls the Feishu bot info API to retrieve and store the bot's open_id.
@@ -399,8 +544,8 @@ func (c *Channel) fetchBotOpenID(ctx context.Context) error {
 	return nil
 }
 
-// sendCard sends an interactive card message to a chat.
-func (c *Channel) sendCard(ctx context.Context, chatID, cardContent string) error {
+// sendCard sends an interactive card message to a chat and returns its message ID.
+func (c *Channel) sendCard(ctx context.Context, chatID, cardContent string) (string, error) {
 	req := larkim.NewCreateMessageReqBuilder().
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
@@ -413,16 +558,19 @@ func (c *Channel) sendCard(ctx context.Context, chatID, cardContent string) erro
 	resp, err := c.client.Im.V1.Message.Create(ctx, req)
 	if err != nil {
 		c.logger.With("name", "【飞书】").Error("发送消息失败：发送卡片失败", "error", err)
-		return fmt.Errorf("发送卡片失败 %w", err)
+		return "", fmt.Errorf("发送卡片失败 %w", err)
 	}
 
 	if !resp.Success() {
 		c.logger.With("name", "【飞书】").Error("发送消息失败：发送卡片失败", slog.Any("code", resp.Code), slog.Any("msg", resp.Msg))
-		return fmt.Errorf("发送卡片失败 %w", err)
+		return "", fmt.Errorf("发送卡片失败 (code=%d msg=%s): %w", resp.Code, resp.Msg, errs.ErrSendFailed)
 	}
 
 	c.logger.With("name", "【飞书】").Info("发送卡片成功", slog.String("chat_id", chatID))
-	return nil
+	if resp.Data != nil && resp.Data.MessageId != nil {
+		return *resp.Data.MessageId, nil
+	}
+	return "", nil
 }
 
 // downloadInboundMedia downloads media from inbound messages.
@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 )
 
@@ -21,22 +22,7 @@ func stringValue(v *string) string {
 
 // buildMarkdownCard builds a Feishu Interactive Card JSON 2.0 string with markdown content.
 func buildMarkdownCard(content string) (string, error) {
-	card := map[string]any{
-		"schema": "2.0",
-		"body": map[string]any{
-			"elements": []map[string]any{
-				{
-					"tag":     "markdown",
-					"content": content,
-				},
-			},
-		},
-	}
-	data, err := json.Marshal(card)
-	if err != nil {
-		return "", err
-	}
-	return string(data), nil
+	return buildCard(content, nil)
 }
 
 // extractJSONStringField unmarshals content as JSON and returns the value of the given string field.
@@ -65,21 +51,43 @@ func extractFileKey(content string) string { return extractJSONStringField(conte
 // extractFileName extracts the file_name from a Feishu file message content JSON.
 func extractFileName(content string) string { return extractJSONStringField(content, "file_name") }
 
-// stripMentionPlaceholders removes @_user_N placeholders from the text content.
-func stripMentionPlaceholders(content string, mentions []*larkim.MentionEvent) string {
+// stripMentionPlaceholders replaces @_user_N placeholders in the text content: the bot's own
+// mention is removed, other users become @name.
+func stripMentionPlaceholders(content string, mentions []*larkim.MentionEvent, botOpenID string) string {
 	if len(mentions) == 0 {
 		return content
 	}
 	for _, m := range mentions {
-		if m.Key != nil && *m.Key != "" {
-			content = strings.ReplaceAll(content, *m.Key, "")
+		if m.Key == nil || *m.Key == "" {
+			continue
 		}
+		replacement := ""
+		if !isBotMention(m, botOpenID) && stringValue(m.Name) != "" {
+			replacement = "@" + stringValue(m.Name)
+		}
+		content = strings.ReplaceAll(content, *m.Key, replacement)
 	}
 	// Also clean up any remaining @_user_N patterns
 	content = mentionPlaceholderRegex.ReplaceAllString(content, "")
 	return strings.TrimSpace(content)
 }
 
+// mentionsBot reports whether the bot is among the mentions. When the bot's open_id is
+// unknown any mention is treated as addressing the bot.
+func mentionsBot(mentions []*larkim.MentionEvent, botOpenID string) bool {
+	for _, m := range mentions {
+		if botOpenID == "" || isBotMention(m, botOpenID) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBotMention reports whether a mention refers to the bot.
+func isBotMention(m *larkim.MentionEvent, botOpenID string) bool {
+	return botOpenID != "" && m.Id != nil && stringValue(m.Id.OpenId) == botOpenID
+}
+
 // extractContent extracts text content from different message types.
 func extractContent(messageType, rawContent string) string {
 	if rawContent == "" {
@@ -160,6 +168,21 @@ func extractSenderID(sender *larkim.EventSender) string {
 	return ""
 }
 
+// extractOperatorID extracts the ID of the user who clicked a card button,
+// preferring the same ID type as extractSenderID.
+func extractOperatorID(op *callback.Operator) string {
+	if op == nil {
+		return "unknown"
+	}
+	if op.UserID != nil && *op.UserID != "" {
+		return *op.UserID
+	}
+	if op.OpenID != "" {
+		return op.OpenID
+	}
+	return "unknown"
+}
+
 // truncate truncates a string to maxLen characters.
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -55,12 +55,15 @@ func parseConfig(config map[string]any) (Config, error) {
 	}
 
 	if v, ok := config["allow_from"]; ok {
-		if arr, ok := v.([]any); ok {
+		switch arr := v.(type) {
+		case []any:
 			for _, item := range arr {
 				if s, ok := item.(string); ok {
 					cfg.AllowFrom = append(cfg.AllowFrom, s)
 				}
 			}
+		case []string:
+			cfg.AllowFrom = append(cfg.AllowFrom, arr...)
 		}
 	}
 
@@ -70,6 +73,24 @@ func parseConfig(config map[string]any) (Config, error) {
 		}
 	}
 
+	if v, ok := config["mode"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.Mode = s
+		}
+	}
+
+	if v, ok := config["webhook_path"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.WebhookPath = s
+		}
+	}
+
+	if v, ok := config["group_reply_all"]; ok {
+		if b, ok := v.(bool); ok {
+			cfg.GroupReplyAll = b
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -0,0 +1,82 @@
+package feishu
+
+import (
+	"encoding/json"
+	"net/http"
+
+	larkevent "github.com/larksuite/oapi-sdk-go/v3/event"
+
+	"icooclaw/pkg/channels/webhook"
+)
+
+// 接收事件的方式
+const (
+	ModeWebSocket = "websocket" // 长连接，不需要公网地址
+	ModeWebhook   = "webhook"   // 事件订阅的请求地址
+)
+
+// defaultWebhookPath webhook 模式默认的回调路径
+const defaultWebhookPath = "/webhook/feishu"
+
+// WebhookPath implements channels.WebhookHandler.
+func (c *Channel) WebhookPath() string {
+	if c.config.WebhookPath != "" {
+		return c.config.WebhookPath
+	}
+	return defaultWebhookPath
+}
+
+// ServeHTTP handles event subscription callbacks in webhook mode: URL verification,
+// decrypting encrypted events, message events and card actions.
+func (c *Channel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.config.Mode != ModeWebhook {
+		http.NotFound(w, r)
+		return
+	}
+	if !c.IsRunning() {
+		http.Error(w, "feishu channel is not running", http.StatusServiceUnavailable)
+		return
+	}
+	c.eventHandler(w, r)
+}
+
+// WebhookVerifier implements channels.WebhookVerifier. Signatures are only present when an
+// encrypt key is configured; without one the SDK checks the verification token instead.
+func (c *Channel) WebhookVerifier() webhook.Verifier {
+	if c.config.Mode != ModeWebhook || c.config.EncryptKey == "" {
+		return nil
+	}
+	return eventVerifier{key: c.config.EncryptKey, signed: webhook.Feishu(c.config.EncryptKey)}
+}
+
+// eventVerifier 飞书事件回调验签。配置请求地址时的 URL 验证请求不带签名，
+// 能用 Encrypt Key 解密出 url_verification 时放行，其余请求按签名校验并防重放。
+type eventVerifier struct {
+	key    string
+	signed webhook.Verifier
+}
+
+func (v eventVerifier) Name() string { return v.signed.Name() }
+
+func (v eventVerifier) Verify(r webhook.Request) (webhook.Result, error) {
+	if r.Header.Get("X-Lark-Signature") == "" && isChallenge(r.Body, v.key) {
+		return webhook.Result{}, nil
+	}
+	return v.signed.Verify(r)
+}
+
+// isChallenge reports whether an encrypted body is a URL verification request.
+func isChallenge(body []byte, key string) bool {
+	var msg larkevent.EventEncryptMsg
+	if err := json.Unmarshal(body, &msg); err != nil || msg.Encrypt == "" {
+		return false
+	}
+	plain, err := larkevent.EventDecrypt(msg.Encrypt, key)
+	if err != nil {
+		return false
+	}
+	var req struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(plain, &req) == nil && req.Type == "url_verification"
+}
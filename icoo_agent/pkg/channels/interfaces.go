@@ -4,6 +4,8 @@ package channels
 import (
 	"context"
 	"net/http"
+
+	"icooclaw/pkg/channels/webhook"
 )
 
 // TypingCapable is an optional interface for channels that support typing indicators.
@@ -26,17 +28,35 @@ type PlaceholderCapable interface {
 	SendPlaceholder(ctx context.Context, sessionID string) (messageID string, err error)
 }
 
+// StatusReporter is an optional interface for channels that render progress heartbeats themselves,
+// e.g. as a card that is updated in place and replaced by the reply.
+type StatusReporter interface {
+	ReportStatus(ctx context.Context, sessionID, text string) error
+}
+
 // MediaSender is an optional interface for channels that support media sending.
 type MediaSender interface {
 	SendMedia(ctx context.Context, msg OutboundMediaMessage) error
 }
 
+// FileSender is an optional interface for channels that can deliver files as attachments.
+// Files listed in the outbound metadata under consts.META_ATTACHMENTS are sent after the reply text.
+type FileSender interface {
+	SendFile(ctx context.Context, sessionID string, file File) error
+}
+
 // WebhookHandler is an optional interface for channels that handle webhooks.
 type WebhookHandler interface {
 	WebhookPath() string
 	http.Handler
 }
 
+// WebhookVerifier is an optional interface for webhook channels that verify inbound payload signatures.
+// The manager wraps the webhook handler with the returned verifier; nil disables verification.
+type WebhookVerifier interface {
+	WebhookVerifier() webhook.Verifier
+}
+
 // HealthChecker is an optional interface for channels with health endpoints.
 type HealthChecker interface {
 	HealthPath() string
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"math"
 	"net/http"
@@ -17,6 +18,8 @@ import (
 	"icooclaw/pkg/bus"
 	"icooclaw/pkg/channels/consts"
 	"icooclaw/pkg/channels/errs"
+	"icooclaw/pkg/channels/webhook"
+	icooclawConsts "icooclaw/pkg/consts"
 	"icooclaw/pkg/storage"
 )
 
@@ -90,7 +93,7 @@ func (m *Manager) StartAll(ctx context.Context) error {
 
 	for name, channel := range m.channels {
 		if err := channel.Start(ctx); err != nil {
-			m.logger.With("name", "【通道管理器】").Error("启动通道失败", err)
+			m.logger.With("name", "【通道管理器】").Error("启动通道失败", "error", err)
 			continue
 		}
 
@@ -143,7 +146,7 @@ func (m *Manager) StopAll(ctx context.Context) error {
 	m.mu.RLock()
 	for name, channel := range m.channels {
 		if err := channel.Stop(ctx); err != nil {
-			m.logger.With("name", "【通道管理器】", slog.Any("channel", name)).Error("关闭通道失败", err)
+			m.logger.With("name", "【通道管理器】", slog.Any("channel", name)).Error("关闭通道失败", "error", err)
 		}
 	}
 	m.mu.RUnlock()
@@ -219,6 +222,12 @@ func (m *Manager) runMediaWorker(ctx context.Context, name string, w *channelWor
 
 // processOutbound processes an outbound message.
 func (m *Manager) processOutbound(ctx context.Context, name string, w *channelWorker, msg bus.OutboundMessage) {
+	// Status heartbeats refresh the typing indicator instead of being sent as replies
+	if _, ok := msg.Metadata[icooclawConsts.META_STATUS]; ok {
+		m.refreshStatus(ctx, name, msg)
+		return
+	}
+
 	// Rate limiting
 	if err := w.limiter.Wait(ctx); err != nil {
 		return
@@ -229,19 +238,73 @@ func (m *Manager) processOutbound(ctx context.Context, name string, w *channelWo
 
 	// Split message if needed
 	maxLen := GetMaxMessageLength(name)
-	chunks := SplitMessage(msg.Text, maxLen)
+	chunks := ChunkMessage(msg.Text, ChunkOptions{MaxLen: maxLen, Number: true})
 
 	for i, chunk := range chunks {
 		msgCopy := msg
-		msgCopy.Text = chunk
+		msgCopy.Text = chunk.Text
 
 		// Only edit the first chunk if we have an edit ID
 		if i > 0 {
 			msgCopy.EditID = ""
 		}
 
+		// Attach chunk position so channels can group the parts
+		if chunk.Total > 1 {
+			msgCopy.Metadata = make(map[string]any, len(msg.Metadata)+2)
+			for k, v := range msg.Metadata {
+				msgCopy.Metadata[k] = v
+			}
+			msgCopy.Metadata["chunk_index"] = chunk.Index
+			msgCopy.Metadata["chunk_total"] = chunk.Total
+		}
+
 		m.sendWithRetry(ctx, name, w, msgCopy)
 	}
+
+	m.sendFiles(ctx, name, w, msg)
+}
+
+// sendFiles delivers the attachments listed in the message metadata to channels that support files.
+// Other channels only get the reply text, which already names the files.
+func (m *Manager) sendFiles(ctx context.Context, name string, w *channelWorker, msg bus.OutboundMessage) {
+	fs, ok := w.channel.(FileSender)
+	if !ok {
+		return
+	}
+
+	for _, file := range FilesFromMetadata(msg.Metadata) {
+		if err := w.limiter.Wait(ctx); err != nil {
+			return
+		}
+		if err := fs.SendFile(ctx, msg.SessionID, file); err != nil {
+			m.logger.With("name", "【通道管理器】").Error("发送文件失败", "channel", name, "file", file.Name, "error", err)
+		}
+	}
+}
+
+// FilesFromMetadata reads the attachments listed under consts.META_ATTACHMENTS.
+func FilesFromMetadata(metadata map[string]any) []File {
+	raw, ok := metadata[icooclawConsts.META_ATTACHMENTS]
+	if !ok || raw == nil {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var files []File
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil
+	}
+
+	valid := files[:0]
+	for _, f := range files {
+		if f.Name != "" && f.Path != "" {
+			valid = append(valid, f)
+		}
+	}
+	return valid
 }
 
 // processOutboundMedia processes an outbound media message.
@@ -261,7 +324,7 @@ func (m *Manager) processOutboundMedia(ctx context.Context, name string, w *chan
 			Metadata:  msg.Metadata,
 		}
 		if err := ms.SendMedia(ctx, mediaMsg); err != nil {
-			m.logger.With("name", "【通道管理器】").Error("发送媒体失败", err)
+			m.logger.With("name", "【通道管理器】").Error("发送媒体失败", "error", err)
 		}
 	}
 }
@@ -293,6 +356,48 @@ func (m *Manager) preSend(ctx context.Context, name, sessionID string) {
 	}
 }
 
+// refreshStatus shows a progress heartbeat: channels with their own status rendering get the
+// text directly, otherwise the placeholder message is edited to the status text when available,
+// otherwise the typing indicator is reissued.
+func (m *Manager) refreshStatus(ctx context.Context, name string, msg bus.OutboundMessage) {
+	key := name + ":" + msg.SessionID
+	channel := m.channels[name]
+
+	if reporter, ok := channel.(StatusReporter); ok {
+		if err := reporter.ReportStatus(ctx, msg.SessionID, msg.Text); err != nil {
+			m.logger.With("name", "【通道管理器】").Warn("更新状态消息失败", "error", err)
+		}
+		return
+	}
+
+	if placeholderID, ok := m.placeholders.Load(key); ok {
+		if editor, ok := channel.(MessageEditor); ok {
+			if err := editor.EditMessage(ctx, msg.SessionID, placeholderID.(string), msg.Text); err != nil {
+				m.logger.With("name", "【通道管理器】").Warn("更新状态消息失败", "error", err)
+			}
+			return
+		}
+	}
+
+	typing, ok := channel.(TypingCapable)
+	if !ok {
+		return
+	}
+
+	// Typing indicators usually expire after a few seconds, restart it
+	if entry, ok := m.typingStops.LoadAndDelete(key); ok {
+		if te, ok := entry.(typingEntry); ok {
+			te.stop()
+		}
+	}
+	stop, err := typing.StartTyping(ctx, msg.SessionID)
+	if err != nil {
+		m.logger.With("name", "【通道管理器】").Warn("刷新输入状态失败", "error", err)
+		return
+	}
+	m.typingStops.Store(key, typingEntry{stop: stop, createdAt: time.Now()})
+}
+
 // sendWithRetry sends a message with retry logic.
 func (m *Manager) sendWithRetry(ctx context.Context, name string, w *channelWorker, msg bus.OutboundMessage) {
 	var lastErr error
@@ -315,7 +420,7 @@ func (m *Manager) sendWithRetry(ctx context.Context, name string, w *channelWork
 
 		// Permanent failure - don't retry
 		if errs.IsPermanent(lastErr) {
-			m.logger.With("name", "【通道管理器】").Error("永久发送失败", lastErr)
+			m.logger.With("name", "【通道管理器】").Error("永久发送失败", "error", lastErr)
 			return
 		}
 
@@ -333,7 +438,7 @@ func (m *Manager) sendWithRetry(ctx context.Context, name string, w *channelWork
 		time.Sleep(backoff)
 	}
 
-	m.logger.With("name", "【通道管理器】").Error("发送消息失败", lastErr)
+	m.logger.With("name", "【通道管理器】").Error("发送消息失败", "error", lastErr)
 }
 
 // runTTLJanitor cleans up expired state entries.
@@ -381,6 +486,52 @@ func (m *Manager) DeletePlaceholder(channel, sessionID string) {
 	m.placeholders.Delete(key)
 }
 
+// AddChannel creates a channel from static configuration. Channels already loaded from the
+// database under the same name take precedence and are left untouched.
+func (m *Manager) AddChannel(name, channelType string, config map[string]any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.channels[name]; exists {
+		m.logger.With("name", "【通道管理器】").Info("通道已存在，忽略配置文件中的同名通道", "name", name)
+		return nil
+	}
+
+	factory, ok := GetFactory(channelType)
+	if !ok {
+		return fmt.Errorf("未找到通道工厂: %s", channelType)
+	}
+	channel, err := factory(config, m.bus, m.logger.With("channel", name))
+	if err != nil {
+		return fmt.Errorf("创建通道 %s 失败: %w", name, err)
+	}
+
+	m.channels[name] = channel
+	m.logger.With("name", "【通道管理器】").Info("通道创建成功", "type", channelType, "name", name)
+	return nil
+}
+
+// WebhookHandlers returns the webhook handlers of all channels keyed by path,
+// wrapped with signature verification when the channel provides a verifier.
+func (m *Manager) WebhookHandlers() map[string]http.Handler {
+	handlers := make(map[string]http.Handler)
+	for name, ch := range m.channels {
+		wh, ok := ch.(WebhookHandler)
+		if !ok {
+			continue
+		}
+		var handler http.Handler = wh
+		if wv, ok := ch.(WebhookVerifier); ok {
+			if v := wv.WebhookVerifier(); v != nil {
+				handler = webhook.Middleware(v, webhook.Options{Logger: m.logger})(handler)
+			}
+		}
+		handlers[wh.WebhookPath()] = handler
+		m.logger.With("name", "【通道管理器】").Info("注册 webhook 成功", "channel", name, "path", wh.WebhookPath())
+	}
+	return handlers
+}
+
 // SetupHTTPServer sets up the shared HTTP server.
 func (m *Manager) SetupHTTPServer(addr string) {
 	m.mux = http.NewServeMux()
@@ -390,11 +541,8 @@ func (m *Manager) SetupHTTPServer(addr string) {
 	}
 
 	// Register webhook handlers
-	for name, ch := range m.channels {
-		if wh, ok := ch.(WebhookHandler); ok {
-			m.mux.Handle(wh.WebhookPath(), wh)
-			m.logger.With("name", "【通道管理器】").Info("注册 webhook 成功", "channel", name, "path", wh.WebhookPath())
-		}
+	for path, handler := range m.WebhookHandlers() {
+		m.mux.Handle(path, handler)
 	}
 
 	// Health endpoint
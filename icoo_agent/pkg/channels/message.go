@@ -25,6 +25,25 @@ type OutboundMediaMessage struct {
 	Metadata  map[string]any `json:"metadata,omitempty"`
 }
 
+// File is a file attachment delivered after a reply, e.g. an artifact saved by the agent.
+type File struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name"`
+	Path     string `json:"path"` // local path readable by the channel
+	MIMEType string `json:"mime_type,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+}
+
+// IsImage reports whether the file is a raster image that chat apps display
+// inline. SVG is not, most platforms only accept it as a plain file.
+func (f File) IsImage() bool {
+	switch f.MIMEType {
+	case "image/png", "image/jpeg", "image/gif":
+		return true
+	}
+	return false
+}
+
 // InboundMessage represents a message received from a channel.
 type InboundMessage struct {
 	Channel   string         `json:"channel"`
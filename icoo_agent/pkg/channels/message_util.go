@@ -2,7 +2,9 @@
 package channels
 
 import (
+	"fmt"
 	"math"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 )
@@ -11,6 +13,8 @@ import (
 var channelMaxLen = map[string]int{
 	"dingtalk": 4096,
 	"feishu":   4096,
+	"wecom":    680, // 消息内容上限 2048 字节，按中文 3 字节估算
+	"qq":       2000,
 	"telegram": 4096,
 	"discord":  2000,
 	"slack":    40000,
@@ -25,100 +29,215 @@ func GetMaxMessageLength(channel string) int {
 	return 4096 // default
 }
 
+// MessageChunk is one part of a long message split for delivery.
+type MessageChunk struct {
+	Index int    `json:"index"` // 序号，从 1 开始
+	Total int    `json:"total"` // 总段数
+	Text  string `json:"text"`  // 分段内容（含编号）
+}
+
+// ChunkOptions controls how a long message is split.
+type ChunkOptions struct {
+	MaxLen int  // 每段最大字符数，<=0 时使用 4096
+	Number bool // 多段时在每段末尾追加 (i/n) 编号
+}
+
 // SplitMessage splits a message into chunks that fit within maxLen.
-// It tries to preserve code block integrity.
+// It prefers paragraph boundaries and keeps code blocks intact.
 func SplitMessage(content string, maxLen int) []string {
+	chunks := ChunkMessage(content, ChunkOptions{MaxLen: maxLen})
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
+	return texts
+}
+
+// ChunkMessage splits a message into numbered chunks that fit within opts.MaxLen.
+//
+// 切分优先级：段落边界（代码块外的空行）> 行尾 > 句末标点/空格 > 强制截断。
+// 代码块被切开时，在当前段末尾补上 ``` 闭合，并在下一段开头按原语言重新打开，
+// 保证每段都是完整的 markdown。每段长度（含编号）都不超过 MaxLen。
+func ChunkMessage(content string, opts ChunkOptions) []MessageChunk {
+	maxLen := opts.MaxLen
 	if maxLen <= 0 {
 		maxLen = 4096
 	}
 
 	if utf8.RuneCountInString(content) <= maxLen {
-		return []string{content}
+		return []MessageChunk{{Index: 1, Total: 1, Text: content}}
 	}
 
-	runes := []rune(content)
-	var chunks []string
-
-	for len(runes) > 0 {
-		chunkEnd := min(maxLen, len(runes))
-		chunk := runes[:chunkEnd]
-
-		// Check for unclosed code blocks
-		if hasUnclosedCodeBlock(string(chunk)) {
-			// Try to extend to include closing ```
-			closingIdx := findClosingCodeBlock(runes, chunkEnd)
-			if closingIdx > 0 && closingIdx <= maxLen*3/2 {
-				chunkEnd = closingIdx
-				chunk = runes[:chunkEnd]
-			} else {
-				// Inject closing and reopening code blocks
-				chunkStr := strings.TrimRight(string(chunk), " \t\n\r") + "\n```\n"
-				chunks = append(chunks, chunkStr)
-				runes = append([]rune("```\n"), runes[chunkEnd:]...)
-				continue
+	var parts []string
+	if opts.Number {
+		// 编号长度取决于总段数，位数不够时放宽预留后重新切分
+		for digits := 1; ; digits++ {
+			parts = splitMarkdown(content, maxLen-len("\n\n(/)")-2*digits)
+			if len(strconv.Itoa(len(parts))) <= digits {
+				break
 			}
 		}
+	} else {
+		parts = splitMarkdown(content, maxLen)
+	}
 
-		// Try to break at a newline
-		if breakIdx := findBreakPoint(chunk); breakIdx > 0 {
-			chunkEnd = breakIdx
-			chunk = runes[:chunkEnd]
+	chunks := make([]MessageChunk, len(parts))
+	for i, part := range parts {
+		if opts.Number && len(parts) > 1 {
+			part += fmt.Sprintf("\n\n(%d/%d)", i+1, len(parts))
 		}
-
-		chunks = append(chunks, string(chunk))
-		runes = runes[chunkEnd:]
+		chunks[i] = MessageChunk{Index: i + 1, Total: len(parts), Text: part}
 	}
+	return chunks
+}
 
+// ClientChunks returns numbered chunks for API clients that declared a max message length.
+// It returns nil when maxLen is not set or the content fits in a single message.
+func ClientChunks(content string, maxLen int) []MessageChunk {
+	if maxLen <= 0 {
+		return nil
+	}
+	chunks := ChunkMessage(content, ChunkOptions{MaxLen: maxLen, Number: true})
+	if len(chunks) < 2 {
+		return nil
+	}
 	return chunks
 }
 
-// hasUnclosedCodeBlock checks if there's an unclosed code block.
-func hasUnclosedCodeBlock(s string) bool {
-	count := strings.Count(s, "```")
-	return count%2 != 0
+// mdLine is a source line annotated with markdown block state.
+type mdLine struct {
+	text  string // 行内容，含换行符
+	n     int    // 字符数
+	fence string // 该行之后仍未闭合的代码块开头行，如 ```go
+	blank bool   // 代码块外的空行，即段落边界
 }
 
-// findClosingCodeBlock finds the closing ``` after start.
-func findClosingCodeBlock(runes []rune, start int) int {
-	for i := start; i < len(runes)-2; i++ {
-		if runes[i] == '`' && runes[i+1] == '`' && runes[i+2] == '`' {
-			return i + 3
-		}
+// fenceOverhead returns the extra characters needed to reopen and close a code block.
+func fenceOverhead(fence string) int {
+	if fence == "" {
+		return 0
 	}
-	return -1
+	return utf8.RuneCountInString(fence) + len("\n\n```")
 }
 
-// findBreakPoint finds a good break point in the chunk.
-func findBreakPoint(chunk []rune) int {
-	// Prefer breaking at double newline
-	for i := len(chunk) - 1; i >= 0; i-- {
-		if i > 0 && chunk[i] == '\n' && chunk[i-1] == '\n' {
-			return i + 1
+// parseLines splits content into lines and tracks code fence state.
+// Lines longer than limit are split further so every line fits in a chunk.
+func parseLines(content string, limit int) []mdLine {
+	var (
+		lines []mdLine
+		open  string
+	)
+
+	for _, text := range strings.SplitAfter(content, "\n") {
+		if text == "" {
+			continue
 		}
-	}
 
-	// Then single newline
-	for i := len(chunk) - 1; i >= 0; i-- {
-		if chunk[i] == '\n' {
-			return i + 1
+		before := open
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if open == "" {
+				open = trimmed
+			} else if trimmed == open[:3] {
+				open = ""
+			}
 		}
-	}
 
-	// Then space
-	for i := len(chunk) - 1; i >= 0; i-- {
-		if chunk[i] == ' ' || chunk[i] == '\t' {
-			return i + 1
+		size := limit - max(fenceOverhead(before), fenceOverhead(open))
+		for _, piece := range splitLongLine(text, size) {
+			lines = append(lines, mdLine{
+				text:  piece,
+				n:     utf8.RuneCountInString(piece),
+				fence: open,
+				blank: before == "" && open == "" && trimmed == "",
+			})
 		}
 	}
+	return lines
+}
+
+// splitLongLine splits a single line into pieces of at most size characters,
+// breaking after sentence punctuation or whitespace when possible.
+func splitLongLine(text string, size int) []string {
+	size = max(size, 1)
+	runes := []rune(text)
+	if len(runes) <= size {
+		return []string{text}
+	}
 
-	return 0
+	var pieces []string
+	for len(runes) > size {
+		cut := size
+		for i := size - 1; i >= size/2; i-- {
+			if strings.ContainsRune(" \t。！？；，.!?;,", runes[i]) {
+				cut = i + 1
+				break
+			}
+		}
+		pieces = append(pieces, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		pieces = append(pieces, string(runes))
+	}
+	return pieces
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// splitMarkdown splits content into parts of at most limit characters.
+func splitMarkdown(content string, limit int) []string {
+	limit = max(limit, 16)
+	lines := parseLines(content, limit)
+
+	var parts []string
+	for start := 0; start < len(lines); {
+		// 上一段停在代码块中间时，本段先重新打开代码块
+		reopen := ""
+		if start > 0 {
+			reopen = lines[start-1].fence
+		}
+		used := 0
+		if reopen != "" {
+			used = utf8.RuneCountInString(reopen) + 1
+		}
+
+		end, paragraphEnd, paragraphUsed := start, -1, 0
+		for end < len(lines) {
+			need := used + lines[end].n
+			if lines[end].fence != "" {
+				need += len("\n```")
+			}
+			if need > limit && end > start {
+				break
+			}
+			used += lines[end].n
+			end++
+			if lines[end-1].blank {
+				paragraphEnd, paragraphUsed = end, used
+			}
+		}
+
+		// 在段落边界切分，除非这样会让本段过短
+		if end < len(lines) && paragraphEnd > start && paragraphUsed >= limit/2 {
+			end = paragraphEnd
+		}
+
+		var sb strings.Builder
+		if reopen != "" {
+			sb.WriteString(reopen + "\n")
+		}
+		for _, l := range lines[start:end] {
+			sb.WriteString(l.text)
+		}
+		part := strings.TrimRight(sb.String(), " \t\r\n")
+		if end < len(lines) && lines[end-1].fence != "" {
+			part += "\n```"
+		}
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, strings.TrimLeft(part, "\r\n"))
+		}
+		start = end
 	}
-	return b
+	return parts
 }
 
 // TruncateMessage truncates a message to maxLen with ellipsis.
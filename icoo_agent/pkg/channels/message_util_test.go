@@ -0,0 +1,79 @@
+package channels
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkMessage_Short(t *testing.T) {
+	chunks := ChunkMessage("你好", ChunkOptions{MaxLen: 10, Number: true})
+	if len(chunks) != 1 || chunks[0].Text != "你好" || chunks[0].Total != 1 {
+		t.Errorf("short message should be a single unnumbered chunk, got %+v", chunks)
+	}
+}
+
+func TestChunkMessage_Paragraphs(t *testing.T) {
+	para := strings.Repeat("段落内容。", 10)
+	content := strings.Join([]string{para, para, para, para}, "\n\n")
+
+	chunks := ChunkMessage(content, ChunkOptions{MaxLen: 120, Number: true})
+	if len(chunks) < 2 {
+		t.Fatalf("expected multiple chunks, got %d", len(chunks))
+	}
+
+	for i, c := range chunks {
+		if n := utf8.RuneCountInString(c.Text); n > 120 {
+			t.Errorf("chunk %d has %d runes, exceeds limit", i+1, n)
+		}
+		if c.Index != i+1 || c.Total != len(chunks) {
+			t.Errorf("chunk %d has index %d/%d", i+1, c.Index, c.Total)
+		}
+		if !strings.HasSuffix(c.Text, fmt.Sprintf("\n\n(%d/%d)", i+1, len(chunks))) {
+			t.Errorf("chunk %d should be numbered: %q", i+1, c.Text)
+		}
+		body := c.Text[:strings.LastIndex(c.Text, "\n\n(")]
+		if !strings.HasPrefix(body, "段落") || !strings.HasSuffix(body, "。") {
+			t.Errorf("chunk %d should break on a paragraph boundary: %q", i+1, c.Text)
+		}
+	}
+}
+
+func TestChunkMessage_CodeFence(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("示例代码：\n\n```go\n")
+	for range 30 {
+		sb.WriteString("fmt.Println(\"hello\")\n")
+	}
+	sb.WriteString("```\n\n结束")
+
+	chunks := ChunkMessage(sb.String(), ChunkOptions{MaxLen: 200})
+	if len(chunks) < 3 {
+		t.Fatalf("expected code block to span chunks, got %d", len(chunks))
+	}
+
+	for i, c := range chunks {
+		if n := utf8.RuneCountInString(c.Text); n > 200 {
+			t.Errorf("chunk %d has %d runes, exceeds limit", i+1, n)
+		}
+		if strings.Count(c.Text, "```")%2 != 0 {
+			t.Errorf("chunk %d has unbalanced code fences: %q", i+1, c.Text)
+		}
+		if i > 0 && i < len(chunks)-1 && !strings.HasPrefix(c.Text, "```go\n") {
+			t.Errorf("chunk %d should reopen the go code block: %q", i+1, c.Text)
+		}
+	}
+}
+
+func TestChunkMessage_LongLine(t *testing.T) {
+	content := strings.Repeat("word ", 100)
+	for i, c := range SplitMessage(content, 64) {
+		if n := utf8.RuneCountInString(c); n > 64 {
+			t.Errorf("chunk %d has %d runes, exceeds limit", i+1, n)
+		}
+		if strings.HasPrefix(c, "ord") {
+			t.Errorf("chunk %d splits inside a word: %q", i+1, c)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package qq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	tokenURL       = "https://bots.qq.com/app/getAppAccessToken"
+	apiBase        = "https://api.sgroup.qq.com"
+	sandboxAPIBase = "https://sandbox.api.sgroup.qq.com"
+)
+
+// APIClient provides QQ bot OpenAPI access.
+type APIClient struct {
+	appID     string
+	appSecret string
+	baseURL   string
+	tokenURL  string
+	logger    *slog.Logger
+
+	accessToken     string
+	tokenExpireTime time.Time
+	tokenMu         sync.RWMutex
+	httpClient      *http.Client
+}
+
+// NewAPIClient creates a new QQ bot API client.
+func NewAPIClient(appID, appSecret string, sandbox bool, logger *slog.Logger) *APIClient {
+	base := apiBase
+	if sandbox {
+		base = sandboxAPIBase
+	}
+	return &APIClient{
+		appID:      appID,
+		appSecret:  appSecret,
+		baseURL:    base,
+		tokenURL:   tokenURL,
+		logger:     logger,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetAccessToken gets the access token for API calls.
+func (c *APIClient) GetAccessToken(ctx context.Context) (string, error) {
+	c.tokenMu.RLock()
+	if c.accessToken != "" && time.Now().Before(c.tokenExpireTime) {
+		token := c.accessToken
+		c.tokenMu.RUnlock()
+		return token, nil
+	}
+	c.tokenMu.RUnlock()
+
+	return c.refreshAccessToken(ctx)
+}
+
+// refreshAccessToken refreshes the access token.
+func (c *APIClient) refreshAccessToken(ctx context.Context) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	// Double check after acquiring write lock
+	if c.accessToken != "" && time.Now().Before(c.tokenExpireTime) {
+		return c.accessToken, nil
+	}
+
+	body, _ := json.Marshal(map[string]string{"appId": c.appID, "clientSecret": c.appSecret})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code        int         `json:"code"`
+		Message     string      `json:"message"`
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"` // 接口返回字符串
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("API error (code=%d msg=%s)", result.Code, result.Message)
+	}
+
+	expiresIn, _ := result.ExpiresIn.Int64()
+	if expiresIn <= 0 {
+		expiresIn = 7200
+	}
+	c.accessToken = result.AccessToken
+	// Refresh one minute early, the old token stays valid for 60s after a refresh
+	c.tokenExpireTime = time.Now().Add(time.Duration(expiresIn-60) * time.Second)
+
+	return c.accessToken, nil
+}
+
+// GatewayURL returns the WebSocket gateway address.
+func (c *APIClient) GatewayURL(ctx context.Context) (string, error) {
+	var result struct {
+		URL string `json:"url"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/gateway", nil, &result); err != nil {
+		return "", err
+	}
+	if result.URL == "" {
+		return "", fmt.Errorf("gateway url is empty")
+	}
+	return result.URL, nil
+}
+
+// PostMessage sends a message to the target's message endpoint.
+func (c *APIClient) PostMessage(ctx context.Context, t target, body map[string]any) error {
+	return c.do(ctx, http.MethodPost, t.messagePath(), body, nil)
+}
+
+// UploadImage uploads an image by URL for group and direct (C2C) chats and returns the
+// file_info used in a media message.
+func (c *APIClient) UploadImage(ctx context.Context, t target, imageURL string) (string, error) {
+	var result struct {
+		FileInfo string `json:"file_info"`
+	}
+	body := map[string]any{"file_type": 1, "url": imageURL, "srv_send_msg": false}
+	if err := c.do(ctx, http.MethodPost, t.filePath(), body, &result); err != nil {
+		return "", err
+	}
+	return result.FileInfo, nil
+}
+
+// do sends an authorized OpenAPI request and decodes the JSON response into out.
+func (c *APIClient) do(ctx context.Context, method, path string, body any, out any) error {
+	token, err := c.GetAccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("get access token: %w", err)
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "QQBot "+token)
+	req.Header.Set("X-Union-Appid", c.appID)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("API error (status=%d code=%d msg=%s)", resp.StatusCode, apiErr.Code, apiErr.Message)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
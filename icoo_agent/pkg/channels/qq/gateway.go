@@ -0,0 +1,222 @@
+package qq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 网关操作码
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opResume         = 6
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatAck   = 11
+)
+
+// 订阅的事件
+const (
+	intentPublicGuildMessages = 1 << 30 // AT_MESSAGE_CREATE
+	intentDirectMessage       = 1 << 12 // DIRECT_MESSAGE_CREATE
+	intentGroupAndC2C         = 1 << 25 // GROUP_AT_MESSAGE_CREATE, C2C_MESSAGE_CREATE
+
+	defaultIntents = intentPublicGuildMessages | intentDirectMessage | intentGroupAndC2C
+)
+
+// 重连间隔
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// payload 网关消息。
+type payload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d,omitempty"`
+	S  int64           `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// readyEvent READY 事件。
+type readyEvent struct {
+	SessionID string `json:"session_id"`
+	User      struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+}
+
+// runGateway keeps the gateway connection alive until ctx is cancelled, resuming the
+// session after disconnects so events in between are replayed.
+func (c *Channel) runGateway(ctx context.Context) {
+	delay := minReconnectDelay
+	for {
+		started := time.Now()
+		err := c.connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// 连接维持了一段时间后断开，重置退避
+		if time.Since(started) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		c.logger.With("name", "【QQ】").Warn("网关连接断开，准备重连", "error", err, "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// connect runs one gateway connection: hello, identify or resume, then heartbeats and events.
+func (c *Channel) connect(ctx context.Context) error {
+	url, err := c.api.GatewayURL(ctx)
+	if err != nil {
+		return fmt.Errorf("获取网关地址失败: %w", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return fmt.Errorf("连接网关失败: %w", err)
+	}
+	defer conn.Close()
+
+	// ctx 取消时关闭连接，结束阻塞的读取
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-connCtx.Done()
+		conn.Close()
+	}()
+
+	var hello payload
+	if err := conn.ReadJSON(&hello); err != nil {
+		return fmt.Errorf("读取 Hello 失败: %w", err)
+	}
+	if hello.Op != opHello {
+		return fmt.Errorf("预期 Hello，收到 op=%d", hello.Op)
+	}
+	var helloData struct {
+		HeartbeatInterval int64 `json:"heartbeat_interval"`
+	}
+	if err := json.Unmarshal(hello.D, &helloData); err != nil || helloData.HeartbeatInterval <= 0 {
+		return fmt.Errorf("解析 Hello 失败: %v", err)
+	}
+
+	if err := c.authenticate(ctx, conn); err != nil {
+		return err
+	}
+
+	// websocket 连接不支持并发写，心跳等写操作统一由一个协程发送
+	writes := make(chan payload, 8)
+	go c.heartbeat(connCtx, writes, time.Duration(helloData.HeartbeatInterval)*time.Millisecond)
+	go func() {
+		for {
+			select {
+			case <-connCtx.Done():
+				return
+			case p := <-writes:
+				if err := conn.WriteJSON(p); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var p payload
+		if err := conn.ReadJSON(&p); err != nil {
+			return fmt.Errorf("读取网关消息失败: %w", err)
+		}
+		if p.S > 0 {
+			c.seq.Store(p.S)
+		}
+
+		switch p.Op {
+		case opDispatch:
+			c.handleDispatch(p.T, p.D)
+		case opReconnect:
+			return fmt.Errorf("网关要求重连")
+		case opInvalidSession:
+			// 会话无法恢复，下次连接重新鉴权
+			c.resetSession()
+			return fmt.Errorf("会话已失效")
+		case opHeartbeatAck:
+		}
+	}
+}
+
+// authenticate resumes the previous session when possible, otherwise identifies.
+func (c *Channel) authenticate(ctx context.Context, conn *websocket.Conn) error {
+	token, err := c.api.GetAccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("获取 access_token 失败: %w", err)
+	}
+
+	c.mu.Lock()
+	sessionID := c.gatewaySession
+	c.mu.Unlock()
+
+	var p payload
+	if sessionID != "" {
+		d, _ := json.Marshal(map[string]any{
+			"token":      "QQBot " + token,
+			"session_id": sessionID,
+			"seq":        c.seq.Load(),
+		})
+		p = payload{Op: opResume, D: d}
+	} else {
+		d, _ := json.Marshal(map[string]any{
+			"token":   "QQBot " + token,
+			"intents": c.intents,
+			"shard":   []int{0, 1},
+		})
+		p = payload{Op: opIdentify, D: d}
+	}
+	if err := conn.WriteJSON(p); err != nil {
+		return fmt.Errorf("发送鉴权失败: %w", err)
+	}
+	return nil
+}
+
+// heartbeat sends the last sequence number at the interval given by Hello.
+func (c *Channel) heartbeat(ctx context.Context, writes chan<- payload, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d := json.RawMessage("null")
+			if seq := c.seq.Load(); seq > 0 {
+				d, _ = json.Marshal(seq)
+			}
+			select {
+			case writes <- payload{Op: opHeartbeat, D: d}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// resetSession forgets the gateway session so the next connection identifies again.
+func (c *Channel) resetSession() {
+	c.mu.Lock()
+	c.gatewaySession = ""
+	c.mu.Unlock()
+	c.seq.Store(0)
+}
@@ -0,0 +1,97 @@
+package qq
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 消息场景
+const (
+	sceneGuild  = "guild" // 频道子频道，@ 机器人的消息
+	sceneDirect = "dm"    // 频道私信
+	sceneGroup  = "group" // QQ 群，@ 机器人的消息
+	sceneC2C    = "c2c"   // QQ 单聊
+)
+
+// target 回复的目标：场景和场景内的 ID（子频道 ID、私信的 guild_id、群 openid、用户 openid）。
+type target struct {
+	scene string
+	id    string
+}
+
+func (t target) messagePath() string {
+	switch t.scene {
+	case sceneGuild:
+		return "/channels/" + t.id + "/messages"
+	case sceneDirect:
+		return "/dms/" + t.id + "/messages"
+	case sceneGroup:
+		return "/v2/groups/" + t.id + "/messages"
+	default:
+		return "/v2/users/" + t.id + "/messages"
+	}
+}
+
+func (t target) filePath() string {
+	if t.scene == sceneGroup {
+		return "/v2/groups/" + t.id + "/files"
+	}
+	return "/v2/users/" + t.id + "/files"
+}
+
+// v2 reports whether the target uses the QQ group / C2C message API.
+func (t target) v2() bool {
+	return t.scene == sceneGroup || t.scene == sceneC2C
+}
+
+// sessionID 生成会话 ID。群聊和子频道按"群 + 用户"区分会话，
+// 同一个群里的每个成员各自拥有独立的上下文。
+func sessionID(t target, userID string) string {
+	if t.scene == sceneC2C {
+		return sceneC2C + ":" + t.id
+	}
+	return t.scene + ":" + t.id + ":" + userID
+}
+
+// parseSessionID 从会话 ID 解析回复目标。
+func parseSessionID(sessionID string) (target, error) {
+	parts := strings.SplitN(sessionID, ":", 3)
+	if len(parts) < 2 || parts[1] == "" {
+		return target{}, fmt.Errorf("无效的 QQ 会话 ID：%s", sessionID)
+	}
+	switch parts[0] {
+	case sceneGuild, sceneDirect, sceneGroup, sceneC2C:
+		return target{scene: parts[0], id: parts[1]}, nil
+	}
+	return target{}, fmt.Errorf("无效的 QQ 会话 ID：%s", sessionID)
+}
+
+// mentionRegex 频道消息中的 @ 标记，如 <@!1234567>
+var mentionRegex = regexp.MustCompile(`<@!?(\d+)>`)
+
+// stripMention 去掉对机器人的 @，其他人的 @ 保留。
+func stripMention(content, botID string) string {
+	content = mentionRegex.ReplaceAllStringFunc(content, func(m string) string {
+		if id := mentionRegex.FindStringSubmatch(m)[1]; botID == "" || id == botID {
+			return ""
+		}
+		return m
+	})
+	return strings.TrimSpace(content)
+}
+
+// imageRegex 回复中的 Markdown 图片，QQ 不渲染 Markdown，图片单独发送
+var imageRegex = regexp.MustCompile(`!\[[^\]]*\]\((https?://[^)\s]+)\)`)
+
+// extractImages 取出回复中的图片链接，返回去掉图片后的正文。
+func extractImages(text string) (string, []string) {
+	var urls []string
+	for _, m := range imageRegex.FindAllStringSubmatch(text, -1) {
+		urls = append(urls, m[1])
+	}
+	if len(urls) == 0 {
+		return text, nil
+	}
+	return strings.TrimSpace(imageRegex.ReplaceAllString(text, "")), urls
+}
@@ -0,0 +1,378 @@
+// Package qq provides the QQ official bot channel implementation for icooclaw.
+package qq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
+	"icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/channels/errs"
+)
+
+// replyWindow 被动回复的有效期，超过后改为主动消息（主动消息有每月额度限制）
+const replyWindow = 5 * time.Minute
+
+// Config contains QQ bot channel configuration.
+type Config struct {
+	Enabled         bool     `json:"enabled" mapstructure:"enabled"`
+	AppID           string   `json:"app_id" mapstructure:"app_id"`
+	AppSecret       string   `json:"app_secret" mapstructure:"app_secret"`
+	Sandbox         bool     `json:"sandbox" mapstructure:"sandbox"` // 使用沙箱环境
+	AllowFrom       []string `json:"allow_from" mapstructure:"allow_from"`
+	ReasoningChatID string   `json:"reasoning_chat_id" mapstructure:"reasoning_chat_id"`
+}
+
+// lastMessage 会话最近一条用户消息，用于被动回复。
+type lastMessage struct {
+	id         string
+	receivedAt time.Time
+	seq        *atomic.Int64 // 同一条消息的多次回复需要递增的 msg_seq
+}
+
+// Channel implements the channels.Channel interface for QQ bots.
+type Channel struct {
+	config  Config
+	bus     *bus.MessageBus
+	api     *APIClient
+	logger  *slog.Logger
+	intents int
+	cancel  context.CancelFunc
+
+	mu             sync.Mutex
+	gatewaySession string // 网关会话 ID，断线后用于 Resume
+	botID          string
+	seq            atomic.Int64 // 最近收到的事件序号
+
+	lastMessages sync.Map // sessionID -> lastMessage
+
+	running atomic.Bool
+}
+
+// New creates a new QQ bot channel instance.
+func New(cfg Config, b *bus.MessageBus, logger *slog.Logger) (*Channel, error) {
+	if cfg.AppID == "" || cfg.AppSecret == "" {
+		return nil, fmt.Errorf("QQ 机器人 app_id 和 app_secret 不能为空")
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Channel{
+		config:  cfg,
+		bus:     b,
+		api:     NewAPIClient(cfg.AppID, cfg.AppSecret, cfg.Sandbox, logger),
+		logger:  logger,
+		intents: defaultIntents,
+	}, nil
+}
+
+// Name returns the channel name.
+func (c *Channel) Name() string {
+	return consts.QQ
+}
+
+// Start connects to the QQ bot WebSocket gateway.
+func (c *Channel) Start(ctx context.Context) error {
+	c.logger.With("name", "【QQ】").Info("启动通道...")
+
+	// 先获取 access_token，app_id 或 app_secret 错误时直接报错
+	if _, err := c.api.GetAccessToken(ctx); err != nil {
+		c.logger.With("name", "【QQ】").Error("启动通道失败", "error", err)
+		return fmt.Errorf("启动通道失败：%w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.runGateway(runCtx)
+
+	c.running.Store(true)
+	c.logger.With("name", "【QQ】").Info("通道已启动（WebSocket 网关）", "sandbox", c.config.Sandbox)
+	return nil
+}
+
+// Stop disconnects from the gateway.
+func (c *Channel) Stop(ctx context.Context) error {
+	c.logger.With("name", "【QQ】").Info("关闭通道...")
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	c.running.Store(false)
+	c.logger.With("name", "【QQ】").Info("通道已停止")
+	return nil
+}
+
+// IsRunning returns true if the channel is running.
+func (c *Channel) IsRunning() bool {
+	return c.running.Load()
+}
+
+// IsAllowed checks if a sender is allowed.
+func (c *Channel) IsAllowed(senderID string) bool {
+	if len(c.config.AllowFrom) == 0 {
+		return true
+	}
+
+	for _, allowed := range c.config.AllowFrom {
+		if senderID == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAllowedSender checks if a sender is allowed (with full info).
+func (c *Channel) IsAllowedSender(sender channels.SenderInfo) bool {
+	return c.IsAllowed(sender.ID)
+}
+
+// ReasoningChannelID returns the channel ID for reasoning messages.
+func (c *Channel) ReasoningChannelID() string {
+	return c.config.ReasoningChatID
+}
+
+// Send sends a text reply. Markdown images in the text are sent as separate image messages.
+func (c *Channel) Send(ctx context.Context, msg channels.OutboundMessage) error {
+	if !c.IsRunning() {
+		return errs.ErrNotRunning
+	}
+
+	t, err := parseSessionID(msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("qq send: %w", errs.ErrSendFailed)
+	}
+
+	text, images := extractImages(msg.Text)
+	images = append(images, msg.Media...)
+
+	if text != "" {
+		body := map[string]any{"content": text}
+		if t.v2() {
+			body["msg_type"] = 0
+		}
+		if err := c.post(ctx, msg.SessionID, t, body); err != nil {
+			return err
+		}
+	}
+	for _, url := range images {
+		if err := c.sendImage(ctx, msg.SessionID, t, url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendMedia implements channels.MediaSender. Media must be image URLs reachable by QQ.
+func (c *Channel) SendMedia(ctx context.Context, msg channels.OutboundMediaMessage) error {
+	if !c.IsRunning() {
+		return errs.ErrNotRunning
+	}
+
+	t, err := parseSessionID(msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("qq send: %w", errs.ErrSendFailed)
+	}
+
+	for i, url := range msg.Media {
+		if err := c.sendImage(ctx, msg.SessionID, t, url); err != nil {
+			return err
+		}
+		// 说明文字跟在第一张图片后
+		if i == 0 && msg.Caption != "" {
+			body := map[string]any{"content": msg.Caption}
+			if t.v2() {
+				body["msg_type"] = 0
+			}
+			if err := c.post(ctx, msg.SessionID, t, body); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sendImage sends one image. Group and C2C chats upload the URL first and send a media
+// message, guild channels take the URL directly.
+func (c *Channel) sendImage(ctx context.Context, sessionID string, t target, url string) error {
+	if !t.v2() {
+		return c.post(ctx, sessionID, t, map[string]any{"image": url})
+	}
+
+	fileInfo, err := c.api.UploadImage(ctx, t, url)
+	if err != nil {
+		c.logger.With("name", "【QQ】").Error("上传图片失败", "session_id", sessionID, "error", err)
+		return fmt.Errorf("qq upload image: %w", errs.ErrTemporary)
+	}
+	return c.post(ctx, sessionID, t, map[string]any{
+		"msg_type": 7,
+		"content":  " ",
+		"media":    map[string]string{"file_info": fileInfo},
+	})
+}
+
+// post sends a message, as a passive reply to the session's last message when it is recent.
+func (c *Channel) post(ctx context.Context, sessionID string, t target, body map[string]any) error {
+	if v, ok := c.lastMessages.Load(sessionID); ok {
+		last := v.(lastMessage)
+		if time.Since(last.receivedAt) < replyWindow {
+			body["msg_id"] = last.id
+			if t.v2() {
+				body["msg_seq"] = last.seq.Add(1)
+			}
+		}
+	}
+
+	if err := c.api.PostMessage(ctx, t, body); err != nil {
+		c.logger.With("name", "【QQ】").Error("发送失败", "session_id", sessionID, "error", err)
+		return fmt.Errorf("qq send: %w", errs.ErrTemporary)
+	}
+	return nil
+}
+
+// messageEvent covers the fields used from guild, direct, group and C2C message events.
+type messageEvent struct {
+	ID          string `json:"id"`
+	ChannelID   string `json:"channel_id"`
+	GuildID     string `json:"guild_id"`
+	GroupOpenID string `json:"group_openid"`
+	Content     string `json:"content"`
+	Author      struct {
+		ID           string `json:"id"`
+		Username     string `json:"username"`
+		MemberOpenID string `json:"member_openid"`
+		UserOpenID   string `json:"user_openid"`
+	} `json:"author"`
+	Attachments []struct {
+		ContentType string `json:"content_type"`
+		URL         string `json:"url"`
+	} `json:"attachments"`
+}
+
+// handleDispatch handles a dispatched gateway event.
+func (c *Channel) handleDispatch(eventType string, data json.RawMessage) {
+	switch eventType {
+	case "READY":
+		var ready readyEvent
+		if err := json.Unmarshal(data, &ready); err != nil {
+			c.logger.With("name", "【QQ】").Error("解析 READY 失败", "error", err)
+			return
+		}
+		c.mu.Lock()
+		c.gatewaySession = ready.SessionID
+		c.botID = ready.User.ID
+		c.mu.Unlock()
+		c.logger.With("name", "【QQ】").Info("网关已就绪", "bot", ready.User.Username)
+		return
+	case "RESUMED":
+		c.logger.With("name", "【QQ】").Info("网关会话已恢复")
+		return
+	}
+
+	var scene string
+	switch eventType {
+	case "AT_MESSAGE_CREATE":
+		scene = sceneGuild
+	case "DIRECT_MESSAGE_CREATE":
+		scene = sceneDirect
+	case "GROUP_AT_MESSAGE_CREATE":
+		scene = sceneGroup
+	case "C2C_MESSAGE_CREATE":
+		scene = sceneC2C
+	default:
+		return
+	}
+
+	var event messageEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		c.logger.With("name", "【QQ】").Error("解析消息失败", "event", eventType, "error", err)
+		return
+	}
+	if err := c.processMessage(scene, &event); err != nil {
+		c.logger.With("name", "【QQ】").Error("发布消息失败", "error", err)
+	}
+}
+
+// processMessage maps a message event to a session and publishes it to the bus.
+func (c *Channel) processMessage(scene string, event *messageEvent) error {
+	var t target
+	var senderID string
+	switch scene {
+	case sceneGuild:
+		t, senderID = target{scene: scene, id: event.ChannelID}, event.Author.ID
+	case sceneDirect:
+		t, senderID = target{scene: scene, id: event.GuildID}, event.Author.ID
+	case sceneGroup:
+		t, senderID = target{scene: scene, id: event.GroupOpenID}, event.Author.MemberOpenID
+	case sceneC2C:
+		t, senderID = target{scene: scene, id: event.Author.UserOpenID}, event.Author.UserOpenID
+	}
+	if t.id == "" || senderID == "" {
+		return errors.New("消息缺少会话或发送者")
+	}
+	if !c.IsAllowed(senderID) {
+		return nil
+	}
+
+	c.mu.Lock()
+	botID := c.botID
+	c.mu.Unlock()
+
+	content := stripMention(event.Content, botID)
+	var media []string
+	for _, a := range event.Attachments {
+		if a.URL != "" {
+			media = append(media, a.URL)
+		}
+	}
+	if content == "" && len(media) == 0 {
+		return nil
+	}
+
+	sid := sessionID(t, senderID)
+	c.lastMessages.Store(sid, lastMessage{id: event.ID, receivedAt: time.Now(), seq: new(atomic.Int64)})
+
+	metadata := map[string]any{
+		"platform":            "qq",
+		"chat_type":           scene,
+		channels.MessageIDKey: event.ID,
+	}
+	if scene == sceneGuild || scene == sceneGroup {
+		metadata["mentioned"] = true // 频道和群里只会收到 @ 机器人的消息
+	}
+	if event.GuildID != "" {
+		metadata["guild_id"] = event.GuildID
+	}
+
+	c.logger.With("name", "【QQ】").Debug("收到消息", "scene", scene, "sender_id", senderID, "preview", truncate(content, 50))
+
+	inboundMsg := bus.InboundMessage{
+		Channel:   c.Name(),
+		SessionID: sid,
+		Sender:    bus.SenderInfo{ID: senderID, Name: event.Author.Username},
+		Text:      content,
+		Media:     media,
+		Metadata:  metadata,
+	}
+
+	pubCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return c.bus.PublishInbound(pubCtx, inboundMsg)
+}
+
+// truncate truncates a string to maxLen runes.
+func truncate(s string, maxLen int) string {
+	r := []rune(s)
+	if len(r) <= maxLen {
+		return s
+	}
+	return string(r[:maxLen]) + "..."
+}
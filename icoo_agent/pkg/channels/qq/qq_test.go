@@ -0,0 +1,169 @@
+package qq
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
+)
+
+func TestSessionID(t *testing.T) {
+	tests := []struct {
+		target target
+		user   string
+		want   string
+	}{
+		{target{sceneGroup, "G1"}, "U1", "group:G1:U1"},
+		{target{sceneGuild, "123"}, "456", "guild:123:456"},
+		{target{sceneDirect, "789"}, "456", "dm:789:456"},
+		{target{sceneC2C, "U2"}, "U2", "c2c:U2"},
+	}
+	for _, tt := range tests {
+		sid := sessionID(tt.target, tt.user)
+		if sid != tt.want {
+			t.Errorf("sessionID() = %q, want %q", sid, tt.want)
+		}
+		got, err := parseSessionID(sid)
+		if err != nil || got != tt.target {
+			t.Errorf("parseSessionID(%q) = %+v, %v", sid, got, err)
+		}
+	}
+
+	if _, err := parseSessionID("telegram:1"); err == nil {
+		t.Error("unknown scene should be rejected")
+	}
+}
+
+func TestStripMention(t *testing.T) {
+	if got := stripMention("<@!42> 今天天气 <@!7>", "42"); got != "今天天气 <@!7>" {
+		t.Errorf("stripMention() = %q", got)
+	}
+	if got := stripMention("  你好 ", "42"); got != "你好" {
+		t.Errorf("stripMention() = %q", got)
+	}
+}
+
+func TestExtractImages(t *testing.T) {
+	text, urls := extractImages("看这张图：\n![猫](https://example.com/cat.png)\n很可爱")
+	if text != "看这张图：\n\n很可爱" || len(urls) != 1 || urls[0] != "https://example.com/cat.png" {
+		t.Errorf("extractImages() = %q, %v", text, urls)
+	}
+	if text, urls := extractImages("没有图片"); text != "没有图片" || urls != nil {
+		t.Errorf("extractImages() = %q, %v", text, urls)
+	}
+}
+
+// recordedRequest 测试服务器收到的请求。
+type recordedRequest struct {
+	path string
+	body map[string]any
+}
+
+func newTestChannel(t *testing.T) (*Channel, *bus.MessageBus, func() []recordedRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []recordedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Write([]byte(`{"access_token":"tok","expires_in":"7200"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "QQBot tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests = append(requests, recordedRequest{path: r.URL.Path, body: body})
+		mu.Unlock()
+		w.Write([]byte(`{"id":"m1","file_info":"FILE"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	b := bus.NewMessageBus(bus.DefaultConfig())
+	c, err := New(Config{AppID: "1", AppSecret: "s"}, b, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.api.baseURL = srv.URL
+	c.api.tokenURL = srv.URL + "/token"
+	c.running.Store(true)
+
+	return c, b, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest(nil), requests...)
+	}
+}
+
+func TestGroupMessageAndReply(t *testing.T) {
+	c, b, requests := newTestChannel(t)
+
+	c.handleDispatch("GROUP_AT_MESSAGE_CREATE", json.RawMessage(
+		`{"id":"msg-1","group_openid":"G1","content":" 画一只猫","author":{"member_openid":"U1"}}`))
+
+	var in bus.InboundMessage
+	select {
+	case in = <-b.Inbound():
+	case <-time.After(time.Second):
+		t.Fatal("no inbound message published")
+	}
+	if in.SessionID != "group:G1:U1" || in.Sender.ID != "U1" || in.Text != "画一只猫" {
+		t.Fatalf("inbound = %+v", in)
+	}
+	if in.Metadata[channels.MessageIDKey] != "msg-1" {
+		t.Errorf("message id = %v", in.Metadata[channels.MessageIDKey])
+	}
+
+	err := c.Send(context.Background(), channels.OutboundMessage{
+		SessionID: in.SessionID,
+		Text:      "好的 ![猫](https://example.com/cat.png)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := requests()
+	if len(got) != 3 {
+		t.Fatalf("requests = %+v", got)
+	}
+	if got[0].path != "/v2/groups/G1/messages" || got[0].body["content"] != "好的" || got[0].body["msg_id"] != "msg-1" || got[0].body["msg_seq"] != float64(1) {
+		t.Errorf("text message = %+v", got[0])
+	}
+	if got[1].path != "/v2/groups/G1/files" || got[1].body["url"] != "https://example.com/cat.png" {
+		t.Errorf("upload = %+v", got[1])
+	}
+	if got[2].body["msg_type"] != float64(7) || got[2].body["msg_seq"] != float64(2) {
+		t.Errorf("media message = %+v", got[2])
+	}
+}
+
+func TestGuildMessageStripsBotMention(t *testing.T) {
+	c, b, requests := newTestChannel(t)
+	c.handleDispatch("READY", json.RawMessage(`{"session_id":"s1","user":{"id":"42","username":"bot"}}`))
+	c.handleDispatch("AT_MESSAGE_CREATE", json.RawMessage(
+		`{"id":"msg-2","channel_id":"C1","guild_id":"GU","content":"<@!42> 你好","author":{"id":"7","username":"alice"}}`))
+
+	in := <-b.Inbound()
+	if in.SessionID != "guild:C1:7" || in.Text != "你好" || in.Sender.Name != "alice" {
+		t.Fatalf("inbound = %+v", in)
+	}
+
+	if err := c.Send(context.Background(), channels.OutboundMessage{SessionID: in.SessionID, Text: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	got := requests()
+	if len(got) != 1 || got[0].path != "/channels/C1/messages" || got[0].body["msg_seq"] != nil {
+		t.Errorf("requests = %+v", got)
+	}
+}
@@ -0,0 +1,78 @@
+package qq
+
+import (
+	"log/slog"
+	"strconv"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
+	"icooclaw/pkg/channels/consts"
+)
+
+func init() {
+	channels.RegisterFactory(consts.QQ, func(config map[string]any, bus *bus.MessageBus, logger *slog.Logger) (channels.Channel, error) {
+		cfg, err := parseConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return New(cfg, bus, logger)
+	})
+}
+
+// parseConfig parses the configuration map into Config struct.
+func parseConfig(config map[string]any) (Config, error) {
+	cfg := Config{}
+
+	if v, ok := config["enabled"]; ok {
+		if b, ok := v.(bool); ok {
+			cfg.Enabled = b
+		}
+	}
+
+	if v, ok := config["app_id"]; ok {
+		switch val := v.(type) {
+		case string:
+			cfg.AppID = val
+		case float64:
+			cfg.AppID = strconv.FormatInt(int64(val), 10)
+		case int64:
+			cfg.AppID = strconv.FormatInt(val, 10)
+		case int:
+			cfg.AppID = strconv.Itoa(val)
+		}
+	}
+
+	if v, ok := config["app_secret"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.AppSecret = s
+		}
+	}
+
+	if v, ok := config["sandbox"]; ok {
+		if b, ok := v.(bool); ok {
+			cfg.Sandbox = b
+		}
+	}
+
+	if v, ok := config["allow_from"]; ok {
+		switch arr := v.(type) {
+		case []any:
+			for _, item := range arr {
+				if s, ok := item.(string); ok {
+					cfg.AllowFrom = append(cfg.AllowFrom, s)
+				}
+			}
+		case []string:
+			cfg.AllowFrom = append(cfg.AllowFrom, arr...)
+		}
+	}
+
+	if v, ok := config["reasoning_chat_id"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.ReasoningChatID = s
+		}
+	}
+
+	return cfg, nil
+}
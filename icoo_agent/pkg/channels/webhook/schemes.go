@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Telegram 校验 setWebhook 时设置的 secret_token，请求头 X-Telegram-Bot-Api-Secret-Token。
+// Telegram 不带时间戳，按 update_id 防重放。
+func Telegram(secretToken string) Verifier {
+	return telegram{secret: secretToken}
+}
+
+type telegram struct{ secret string }
+
+func (telegram) Name() string { return "telegram" }
+
+func (t telegram) Verify(r Request) (Result, error) {
+	token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if token == "" {
+		return Result{}, ErrMissingSignature
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(t.secret)) != 1 {
+		return Result{}, ErrInvalidSignature
+	}
+
+	var update struct {
+		UpdateID int64 `json:"update_id"`
+	}
+	if err := json.Unmarshal(r.Body, &update); err == nil && update.UpdateID != 0 {
+		return Result{ID: strconv.FormatInt(update.UpdateID, 10)}, nil
+	}
+	return Result{}, nil
+}
+
+// Slack 校验 Slack 应用的 Signing Secret：
+// X-Slack-Signature = "v0=" + hex(HMAC-SHA256(secret, "v0:" + X-Slack-Request-Timestamp + ":" + body))。
+func Slack(signingSecret string) Verifier {
+	return slack{secret: signingSecret}
+}
+
+type slack struct{ secret string }
+
+func (slack) Name() string { return "slack" }
+
+func (s slack) Verify(r Request) (Result, error) {
+	sig := r.Header.Get("X-Slack-Signature")
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	if sig == "" || ts == "" {
+		return Result{}, ErrMissingSignature
+	}
+	timestamp, err := parseUnix(ts, time.Second)
+	if err != nil {
+		return Result{}, err
+	}
+
+	want := "v0=" + hex.EncodeToString(hmacSHA256(s.secret, "v0:"+ts+":"+string(r.Body)))
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return Result{}, ErrInvalidSignature
+	}
+	return Result{Timestamp: timestamp, ID: sig}, nil
+}
+
+// GitHub 校验仓库 Webhook 的 Secret：X-Hub-Signature-256 = "sha256=" + hex(HMAC-SHA256(secret, body))。
+// GitHub 不带时间戳，按 X-GitHub-Delivery 防重放。
+func GitHub(secret string) Verifier {
+	return github{secret: secret}
+}
+
+type github struct{ secret string }
+
+func (github) Name() string { return "github" }
+
+func (g github) Verify(r Request) (Result, error) {
+	sig := r.Header.Get("X-Hub-Signature-256")
+	if sig == "" {
+		return Result{}, ErrMissingSignature
+	}
+
+	want := "sha256=" + hex.EncodeToString(hmacSHA256(g.secret, string(r.Body)))
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return Result{}, ErrInvalidSignature
+	}
+	return Result{ID: r.Header.Get("X-GitHub-Delivery")}, nil
+}
+
+// Feishu 校验飞书事件订阅的 Encrypt Key：
+// X-Lark-Signature = hex(SHA256(X-Lark-Request-Timestamp + X-Lark-Request-Nonce + encryptKey + body))。
+func Feishu(encryptKey string) Verifier {
+	return feishu{key: encryptKey}
+}
+
+type feishu struct{ key string }
+
+func (feishu) Name() string { return "feishu" }
+
+func (f feishu) Verify(r Request) (Result, error) {
+	sig := r.Header.Get("X-Lark-Signature")
+	ts := r.Header.Get("X-Lark-Request-Timestamp")
+	nonce := r.Header.Get("X-Lark-Request-Nonce")
+	if sig == "" || ts == "" {
+		return Result{}, ErrMissingSignature
+	}
+	timestamp, err := parseUnix(ts, time.Second)
+	if err != nil {
+		return Result{}, err
+	}
+
+	sum := sha256.Sum256([]byte(ts + nonce + f.key + string(r.Body)))
+	if !hmac.Equal([]byte(strings.ToLower(sig)), []byte(hex.EncodeToString(sum[:]))) {
+		return Result{}, ErrInvalidSignature
+	}
+	id := nonce
+	if id == "" {
+		id = sig
+	}
+	return Result{Timestamp: timestamp, ID: id}, nil
+}
+
+// DingTalk 校验钉钉机器人回调的 AppSecret：请求头 timestamp 为毫秒时间戳，
+// sign = base64(HMAC-SHA256(appSecret, timestamp + "\n" + appSecret))。
+// 签名不覆盖请求体，同一时间戳和签名只接受一次。
+func DingTalk(appSecret string) Verifier {
+	return dingtalk{secret: appSecret}
+}
+
+type dingtalk struct{ secret string }
+
+func (dingtalk) Name() string { return "dingtalk" }
+
+func (d dingtalk) Verify(r Request) (Result, error) {
+	sig := r.Header.Get("sign")
+	ts := r.Header.Get("timestamp")
+	if sig == "" || ts == "" {
+		return Result{}, ErrMissingSignature
+	}
+	timestamp, err := parseUnix(ts, time.Millisecond)
+	if err != nil {
+		return Result{}, err
+	}
+
+	want := base64.StdEncoding.EncodeToString(hmacSHA256(d.secret, ts+"\n"+d.secret))
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return Result{}, ErrInvalidSignature
+	}
+	return Result{Timestamp: timestamp, ID: ts + ":" + sig}, nil
+}
+
+// hmacSHA256 计算 HMAC-SHA256。
+func hmacSHA256(key, data string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// parseUnix 解析以 unit 为单位的 Unix 时间戳。
+func parseUnix(s string, unit time.Duration) (time.Time, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: 时间戳格式错误 %q", ErrInvalidSignature, s)
+	}
+	return time.Unix(0, n*int64(unit)), nil
+}
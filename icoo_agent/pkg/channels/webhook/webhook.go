@@ -0,0 +1,171 @@
+// Package webhook verifies the signatures of inbound webhook payloads.
+//
+// 各平台的回调签名方案（Telegram、Slack、GitHub、飞书、钉钉）统一实现为 Verifier，
+// 渠道通过 Middleware 启用验签：签名不符、时间戳超出允许偏差或在防重放窗口内重复出现的请求
+// 直接返回 401，不再交给渠道处理。
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 验签失败的原因
+var (
+	ErrMissingSignature = errors.New("缺少签名")
+	ErrInvalidSignature = errors.New("签名无效")
+	ErrExpired          = errors.New("时间戳超出允许偏差")
+	ErrReplayed         = errors.New("重复的请求")
+)
+
+// 默认参数
+const (
+	DefaultTolerance = 5 * time.Minute
+	DefaultMaxBody   = 1 << 20
+)
+
+// Request 验签所需的请求内容。
+type Request struct {
+	Header http.Header
+	Body   []byte
+}
+
+// Result 验签通过的请求信息，用于检查时间戳和防重放。
+type Result struct {
+	Timestamp time.Time // 签名时间，零值表示方案不带时间戳
+	ID        string    // 请求唯一标识（nonce、投递 ID 等），为空时不做防重放检查
+}
+
+// Verifier 一种平台签名方案。
+type Verifier interface {
+	// Name 方案名称，同时用于区分防重放缓存的键
+	Name() string
+	// Verify 校验签名，通过时返回请求信息
+	Verify(r Request) (Result, error)
+}
+
+// ReplayCache 防重放缓存，记录窗口内见过的请求标识。
+type ReplayCache interface {
+	// Claim 记录 key，ttl 内首次出现时返回 true
+	Claim(key string, ttl time.Duration) bool
+}
+
+// Options 验签参数。
+type Options struct {
+	Tolerance time.Duration    // 时间戳允许的偏差，默认 5m
+	MaxBody   int64            // 请求体大小上限，默认 1MB
+	Cache     ReplayCache      // 防重放缓存，默认使用内存缓存
+	Logger    *slog.Logger     // 日志记录器，默认 slog.Default()
+	Now       func() time.Time // 当前时间，测试时替换
+}
+
+func (o Options) withDefaults() Options {
+	if o.Tolerance <= 0 {
+		o.Tolerance = DefaultTolerance
+	}
+	if o.MaxBody <= 0 {
+		o.MaxBody = DefaultMaxBody
+	}
+	if o.Cache == nil {
+		o.Cache = NewMemoryCache()
+	}
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+// Middleware 返回验签中间件。验签失败时返回 401，通过后请求体可再次读取。
+func Middleware(v Verifier, opts Options) func(http.Handler) http.Handler {
+	opts = opts.withDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := verify(r, v, opts); err != nil {
+				opts.Logger.With("name", "【Webhook】").Warn("请求验签失败",
+					"scheme", v.Name(), "path", r.URL.Path, "remote", r.RemoteAddr, "error", err)
+				http.Error(w, "webhook 验签失败", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Verify 校验请求签名、时间戳和是否重放，读取后请求体可再次读取。
+func Verify(r *http.Request, v Verifier, opts Options) error {
+	return verify(r, v, opts.withDefaults())
+}
+
+func verify(r *http.Request, v Verifier, opts Options) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, opts.MaxBody+1))
+	r.Body.Close()
+	if err != nil {
+		return fmt.Errorf("读取请求体失败: %w", err)
+	}
+	if int64(len(body)) > opts.MaxBody {
+		return fmt.Errorf("请求体超过 %d 字节", opts.MaxBody)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	res, err := v.Verify(Request{Header: r.Header, Body: body})
+	if err != nil {
+		return err
+	}
+
+	if !res.Timestamp.IsZero() {
+		skew := opts.Now().Sub(res.Timestamp)
+		if skew > opts.Tolerance || skew < -opts.Tolerance {
+			return ErrExpired
+		}
+	}
+
+	// 带时间戳的请求在偏差范围外已被拒绝，窗口为两倍偏差即可覆盖
+	if res.ID != "" && !opts.Cache.Claim(v.Name()+":"+res.ID, 2*opts.Tolerance) {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// MemoryCache 进程内的防重放缓存。
+type MemoryCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time // key -> 过期时间
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemoryCache 创建内存防重放缓存。
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{seen: make(map[string]time.Time), now: time.Now}
+}
+
+// Claim 记录 key，ttl 内首次出现时返回 true。
+func (c *MemoryCache) Claim(key string, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if now.Sub(c.lastPrune) > ttl {
+		for k, expires := range c.seen {
+			if now.After(expires) {
+				delete(c.seen, k)
+			}
+		}
+		c.lastPrune = now
+	}
+
+	if expires, ok := c.seen[key]; ok && now.Before(expires) {
+		return false
+	}
+	c.seen[key] = now.Add(ttl)
+	return true
+}
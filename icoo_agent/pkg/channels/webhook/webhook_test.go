@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRequest(body string, header map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	return r
+}
+
+func TestVerifiers(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	tsMilli := strconv.FormatInt(now.UnixMilli(), 10)
+	body := `{"update_id":42,"text":"hi"}`
+
+	slackSig := "v0=" + hex.EncodeToString(hmacSHA256("slack-secret", "v0:"+ts+":"+body))
+	githubSig := "sha256=" + hex.EncodeToString(hmacSHA256("gh-secret", body))
+	feishuSum := sha256.Sum256([]byte(ts + "n1" + "encrypt-key" + body))
+	feishuSig := hex.EncodeToString(feishuSum[:])
+	dingSig := base64.StdEncoding.EncodeToString(hmacSHA256("ding-secret", tsMilli+"\n"+"ding-secret"))
+
+	tests := []struct {
+		name     string
+		verifier Verifier
+		header   map[string]string
+		body     string
+		wantErr  error
+		wantID   string
+	}{
+		{"telegram ok", Telegram("tg"), map[string]string{"X-Telegram-Bot-Api-Secret-Token": "tg"}, body, nil, "42"},
+		{"telegram wrong token", Telegram("tg"), map[string]string{"X-Telegram-Bot-Api-Secret-Token": "x"}, body, ErrInvalidSignature, ""},
+		{"telegram missing", Telegram("tg"), nil, body, ErrMissingSignature, ""},
+		{"slack ok", Slack("slack-secret"), map[string]string{"X-Slack-Signature": slackSig, "X-Slack-Request-Timestamp": ts}, body, nil, slackSig},
+		{"slack tampered body", Slack("slack-secret"), map[string]string{"X-Slack-Signature": slackSig, "X-Slack-Request-Timestamp": ts}, body + " ", ErrInvalidSignature, ""},
+		{"github ok", GitHub("gh-secret"), map[string]string{"X-Hub-Signature-256": githubSig, "X-GitHub-Delivery": "d1"}, body, nil, "d1"},
+		{"github wrong secret", GitHub("other"), map[string]string{"X-Hub-Signature-256": githubSig}, body, ErrInvalidSignature, ""},
+		{"feishu ok", Feishu("encrypt-key"), map[string]string{"X-Lark-Signature": feishuSig, "X-Lark-Request-Timestamp": ts, "X-Lark-Request-Nonce": "n1"}, body, nil, "n1"},
+		{"feishu wrong nonce", Feishu("encrypt-key"), map[string]string{"X-Lark-Signature": feishuSig, "X-Lark-Request-Timestamp": ts, "X-Lark-Request-Nonce": "n2"}, body, ErrInvalidSignature, ""},
+		{"dingtalk ok", DingTalk("ding-secret"), map[string]string{"sign": dingSig, "timestamp": tsMilli}, body, nil, tsMilli + ":" + dingSig},
+		{"dingtalk bad timestamp", DingTalk("ding-secret"), map[string]string{"sign": dingSig, "timestamp": "abc"}, body, ErrInvalidSignature, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRequest(tt.body, tt.header)
+			res, err := tt.verifier.Verify(Request{Header: r.Header, Body: []byte(tt.body)})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && res.ID != tt.wantID {
+				t.Errorf("Verify() ID = %q, want %q", res.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	sign := func(ts time.Time, body string) map[string]string {
+		s := strconv.FormatInt(ts.Unix(), 10)
+		return map[string]string{
+			"X-Slack-Request-Timestamp": s,
+			"X-Slack-Signature":         "v0=" + hex.EncodeToString(hmacSHA256("secret", "v0:"+s+":"+body)),
+		}
+	}
+
+	var got string
+	handler := Middleware(Slack("secret"), Options{Now: func() time.Time { return now }})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			got = string(data)
+		}))
+
+	serve := func(body string, header map[string]string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest(body, header))
+		return rec.Code
+	}
+
+	if code := serve("hello", sign(now, "hello")); code != http.StatusOK || got != "hello" {
+		t.Fatalf("valid request: code = %d, body = %q", code, got)
+	}
+	if code := serve("hello", sign(now, "hello")); code != http.StatusUnauthorized {
+		t.Errorf("replayed request: code = %d, want 401", code)
+	}
+	if code := serve("late", sign(now.Add(-10*time.Minute), "late")); code != http.StatusUnauthorized {
+		t.Errorf("expired request: code = %d, want 401", code)
+	}
+	if code := serve("forged", sign(now, "other")); code != http.StatusUnauthorized {
+		t.Errorf("forged request: code = %d, want 401", code)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := NewMemoryCache()
+	c.now = func() time.Time { return now }
+
+	if !c.Claim("a", time.Minute) || c.Claim("a", time.Minute) {
+		t.Fatal("first claim should succeed and the second fail")
+	}
+	now = now.Add(2 * time.Minute)
+	if !c.Claim("a", time.Minute) {
+		t.Error("claim after the window should succeed")
+	}
+}
@@ -0,0 +1,292 @@
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	wecomAPIBase = "https://qyapi.weixin.qq.com/cgi-bin"
+)
+
+// APIClient provides WeCom API access.
+type APIClient struct {
+	corpID  string
+	secret  string
+	agentID int64
+	logger  *slog.Logger
+
+	accessToken     string
+	tokenExpireTime time.Time
+	tokenMu         sync.RWMutex
+	httpClient      *http.Client
+	baseURL         string
+}
+
+// NewAPIClient creates a new WeCom API client.
+func NewAPIClient(corpID, secret string, agentID int64, logger *slog.Logger) *APIClient {
+	return &APIClient{
+		corpID:     corpID,
+		secret:     secret,
+		agentID:    agentID,
+		logger:     logger,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    wecomAPIBase,
+	}
+}
+
+// apiResult is the common error envelope of WeCom API responses.
+type apiResult struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (r apiResult) err() error {
+	if r.ErrCode != 0 {
+		return fmt.Errorf("API error (code=%d msg=%s)", r.ErrCode, r.ErrMsg)
+	}
+	return nil
+}
+
+// GetAccessToken gets the access token for API calls.
+func (c *APIClient) GetAccessToken(ctx context.Context) (string, error) {
+	c.tokenMu.RLock()
+	if c.accessToken != "" && time.Now().Before(c.tokenExpireTime) {
+		token := c.accessToken
+		c.tokenMu.RUnlock()
+		return token, nil
+	}
+	c.tokenMu.RUnlock()
+
+	return c.refreshAccessToken(ctx)
+}
+
+// refreshAccessToken refreshes the access token.
+func (c *APIClient) refreshAccessToken(ctx context.Context) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	// Double check after acquiring write lock
+	if c.accessToken != "" && time.Now().Before(c.tokenExpireTime) {
+		return c.accessToken, nil
+	}
+
+	apiURL := fmt.Sprintf("%s/gettoken?corpid=%s&corpsecret=%s",
+		c.baseURL, url.QueryEscape(c.corpID), url.QueryEscape(c.secret))
+
+	var result struct {
+		apiResult
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := c.do(ctx, http.MethodGet, apiURL, nil, &result); err != nil {
+		return "", err
+	}
+	if err := result.err(); err != nil {
+		return "", err
+	}
+
+	c.accessToken = result.AccessToken
+	// Set expire time with 5 minute buffer
+	c.tokenExpireTime = time.Now().Add(time.Duration(result.ExpiresIn-300) * time.Second)
+
+	return c.accessToken, nil
+}
+
+// Recipients is the target of an application message.
+type Recipients struct {
+	Users   []string // 成员 userid，"@all" 表示全部成员
+	Parties []string // 部门 ID
+	Tags    []string // 标签 ID
+}
+
+// SendMessage sends an application message. msgType is "text" or "markdown".
+func (c *APIClient) SendMessage(ctx context.Context, to Recipients, msgType, content string) error {
+	return c.send(ctx, to, msgType, map[string]string{"content": content})
+}
+
+// SendMediaMessage sends media uploaded with UploadMedia as an application
+// message. msgType is the media type, "image" or "file".
+func (c *APIClient) SendMediaMessage(ctx context.Context, to Recipients, msgType, mediaID string) error {
+	return c.send(ctx, to, msgType, map[string]string{"media_id": mediaID})
+}
+
+// send sends an application message of msgType with the given payload.
+func (c *APIClient) send(ctx context.Context, to Recipients, msgType string, payload any) error {
+	token, err := c.GetAccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("get access token: %w", err)
+	}
+
+	body := map[string]any{
+		"agentid": c.agentID,
+		"msgtype": msgType,
+		msgType:   payload,
+	}
+	if len(to.Users) > 0 {
+		body["touser"] = joinIDs(to.Users)
+	}
+	if len(to.Parties) > 0 {
+		body["toparty"] = joinIDs(to.Parties)
+	}
+	if len(to.Tags) > 0 {
+		body["totag"] = joinIDs(to.Tags)
+	}
+
+	var result struct {
+		apiResult
+		InvalidUser string `json:"invaliduser"`
+	}
+	apiURL := fmt.Sprintf("%s/message/send?access_token=%s", c.baseURL, url.QueryEscape(token))
+	if err := c.do(ctx, http.MethodPost, apiURL, body, &result); err != nil {
+		return err
+	}
+	if err := result.err(); err != nil {
+		return err
+	}
+	if result.InvalidUser != "" {
+		c.logger.With("name", "【企业微信】").Warn("部分接收人无效", "invaliduser", result.InvalidUser)
+	}
+	return nil
+}
+
+// UploadMedia uploads a file as temporary media (valid for 3 days) of the given
+// type ("image" or "file") and returns its media_id.
+func (c *APIClient) UploadMedia(ctx context.Context, mediaType, path, name string) (string, error) {
+	token, err := c.GetAccessToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get access token: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("media", name)
+	if err != nil {
+		return "", fmt.Errorf("create form file: %w", err)
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return "", fmt.Errorf("close form: %w", err)
+	}
+
+	apiURL := fmt.Sprintf("%s/media/upload?access_token=%s&type=%s", c.baseURL, url.QueryEscape(token), mediaType)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, &buf)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		apiResult
+		MediaID string `json:"media_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	if err := result.err(); err != nil {
+		return "", err
+	}
+	return result.MediaID, nil
+}
+
+// ExternalContact contains the fields of an external contact used by the channel.
+type ExternalContact struct {
+	ExternalUserID string
+	Name           string
+	UnionID        string
+	CorpName       string
+}
+
+// GetExternalContact gets an external contact (WeChat user) by external_userid.
+func (c *APIClient) GetExternalContact(ctx context.Context, externalUserID string) (*ExternalContact, error) {
+	token, err := c.GetAccessToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get access token: %w", err)
+	}
+
+	var result struct {
+		apiResult
+		ExternalContact struct {
+			ExternalUserID string `json:"external_userid"`
+			Name           string `json:"name"`
+			UnionID        string `json:"unionid"`
+			CorpName       string `json:"corp_name"`
+		} `json:"external_contact"`
+	}
+	apiURL := fmt.Sprintf("%s/externalcontact/get?access_token=%s&external_userid=%s",
+		c.baseURL, url.QueryEscape(token), url.QueryEscape(externalUserID))
+	if err := c.do(ctx, http.MethodGet, apiURL, nil, &result); err != nil {
+		return nil, err
+	}
+	if err := result.err(); err != nil {
+		return nil, err
+	}
+
+	return &ExternalContact{
+		ExternalUserID: result.ExternalContact.ExternalUserID,
+		Name:           result.ExternalContact.Name,
+		UnionID:        result.ExternalContact.UnionID,
+		CorpName:       result.ExternalContact.CorpName,
+	}, nil
+}
+
+// do sends a request and decodes the JSON response into out.
+func (c *APIClient) do(ctx context.Context, method, apiURL string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, reader)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// joinIDs joins IDs with "|" as WeCom expects.
+func joinIDs(ids []string) string {
+	return strings.Join(ids, "|")
+}
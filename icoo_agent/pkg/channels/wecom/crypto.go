@@ -0,0 +1,95 @@
+package wecom
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 回调消息解密失败的原因
+var (
+	ErrInvalidSignature = errors.New("企业微信回调签名无效")
+	ErrInvalidReceiver  = errors.New("企业微信回调的 CorpID 不匹配")
+)
+
+// msgCrypt 企业微信回调消息加解密（与官方 WXBizMsgCrypt 一致）：
+// 签名为 SHA1(sort(token, timestamp, nonce, encrypt))，消息体为 AES-256-CBC，
+// 密钥为 Base64(EncodingAESKey + "=")，IV 取密钥前 16 字节，
+// 明文为 16 字节随机串 + 4 字节网络序长度 + 消息 + CorpID。
+type msgCrypt struct {
+	token  string
+	key    []byte
+	corpID string
+}
+
+// newMsgCrypt 创建加解密器，EncodingAESKey 为 43 位。
+func newMsgCrypt(token, encodingAESKey, corpID string) (*msgCrypt, error) {
+	key, err := base64.StdEncoding.DecodeString(encodingAESKey + "=")
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("企业微信 encoding_aes_key 格式错误，应为 43 位")
+	}
+	return &msgCrypt{token: token, key: key, corpID: corpID}, nil
+}
+
+// signature 计算回调签名。
+func (c *msgCrypt) signature(timestamp, nonce, encrypt string) string {
+	parts := []string{c.token, timestamp, nonce, encrypt}
+	sort.Strings(parts)
+	sum := sha1.Sum([]byte(strings.Join(parts, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// verify 校验签名并解密消息。
+func (c *msgCrypt) verify(msgSignature, timestamp, nonce, encrypt string) ([]byte, error) {
+	want := c.signature(timestamp, nonce, encrypt)
+	if subtle.ConstantTimeCompare([]byte(msgSignature), []byte(want)) != 1 {
+		return nil, ErrInvalidSignature
+	}
+	return c.decrypt(encrypt)
+}
+
+// decrypt 解密消息体并校验 CorpID。
+func (c *msgCrypt) decrypt(encrypt string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypt)
+	if err != nil {
+		return nil, fmt.Errorf("解码密文失败: %w", err)
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("密文长度错误")
+	}
+
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, c.key[:aes.BlockSize]).CryptBlocks(plain, data)
+
+	// PKCS#7，块大小为 32
+	pad := int(plain[len(plain)-1])
+	if pad < 1 || pad > 32 || pad > len(plain) {
+		return nil, fmt.Errorf("填充错误")
+	}
+	plain = plain[:len(plain)-pad]
+
+	if len(plain) < 20 {
+		return nil, fmt.Errorf("明文长度错误")
+	}
+	msgLen := int(binary.BigEndian.Uint32(plain[16:20]))
+	if 20+msgLen > len(plain) {
+		return nil, fmt.Errorf("消息长度错误")
+	}
+	msg, receiver := plain[20:20+msgLen], plain[20+msgLen:]
+	if c.corpID != "" && string(receiver) != c.corpID {
+		return nil, ErrInvalidReceiver
+	}
+	return msg, nil
+}
@@ -0,0 +1,113 @@
+package wecom
+
+import (
+	"log/slog"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
+	"icooclaw/pkg/channels/consts"
+)
+
+func init() {
+	channels.RegisterFactory(consts.WECOM, func(config map[string]any, bus *bus.MessageBus, logger *slog.Logger) (channels.Channel, error) {
+		cfg, err := parseConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return New(cfg, bus, logger)
+	})
+}
+
+// parseConfig parses the configuration map into Config struct.
+func parseConfig(config map[string]any) (Config, error) {
+	cfg := Config{}
+
+	if v, ok := config["enabled"]; ok {
+		if b, ok := v.(bool); ok {
+			cfg.Enabled = b
+		}
+	}
+
+	if v, ok := config["corp_id"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.CorpID = s
+		}
+	}
+
+	if v, ok := config["secret"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.Secret = s
+		}
+	}
+
+	if v, ok := config["token"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.Token = s
+		}
+	}
+
+	if v, ok := config["encoding_aes_key"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.EncodingAESKey = s
+		}
+	}
+
+	if v, ok := config["webhook_path"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.WebhookPath = s
+		}
+	}
+
+	if v, ok := config["message_type"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.MessageType = s
+		}
+	}
+
+	if v, ok := config["reasoning_chat_id"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.ReasoningChatID = s
+		}
+	}
+
+	if v, ok := config["agent_id"]; ok {
+		switch val := v.(type) {
+		case int64:
+			cfg.AgentID = val
+		case int:
+			cfg.AgentID = int64(val)
+		case float64:
+			cfg.AgentID = int64(val)
+		}
+	}
+
+	if v, ok := config["allow_from"]; ok {
+		switch arr := v.(type) {
+		case []any:
+			for _, item := range arr {
+				if s, ok := item.(string); ok {
+					cfg.AllowFrom = append(cfg.AllowFrom, s)
+				}
+			}
+		case []string:
+			cfg.AllowFrom = append(cfg.AllowFrom, arr...)
+		}
+	}
+
+	if v, ok := config["external_users"]; ok {
+		switch m := v.(type) {
+		case map[string]any:
+			cfg.ExternalUsers = make(map[string]string, len(m))
+			for k, item := range m {
+				if s, ok := item.(string); ok {
+					cfg.ExternalUsers[k] = s
+				}
+			}
+		case map[string]string:
+			cfg.ExternalUsers = m
+		}
+	}
+
+	return cfg, nil
+}
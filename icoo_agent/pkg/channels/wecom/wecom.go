@@ -0,0 +1,402 @@
+// Package wecom provides WeCom (企业微信) channel implementation for icooclaw.
+package wecom
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
+	"icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/channels/errs"
+	"icooclaw/pkg/channels/webhook"
+)
+
+// defaultWebhookPath 默认的回调路径
+const defaultWebhookPath = "/webhook/wecom"
+
+// Config contains WeCom channel configuration.
+type Config struct {
+	Enabled         bool              `json:"enabled" mapstructure:"enabled"`
+	CorpID          string            `json:"corp_id" mapstructure:"corp_id"`
+	AgentID         int64             `json:"agent_id" mapstructure:"agent_id"`
+	Secret          string            `json:"secret" mapstructure:"secret"`
+	Token           string            `json:"token" mapstructure:"token"`
+	EncodingAESKey  string            `json:"encoding_aes_key" mapstructure:"encoding_aes_key"`
+	WebhookPath     string            `json:"webhook_path" mapstructure:"webhook_path"`     // 回调路径，默认 /webhook/wecom
+	MessageType     string            `json:"message_type" mapstructure:"message_type"`     // 回复的消息类型：markdown（默认）或 text
+	ExternalUsers   map[string]string `json:"external_users" mapstructure:"external_users"` // 外部联系人 external_userid -> 用户 ID
+	AllowFrom       []string          `json:"allow_from" mapstructure:"allow_from"`
+	ReasoningChatID string            `json:"reasoning_chat_id" mapstructure:"reasoning_chat_id"`
+}
+
+// Channel implements the channels.Channel interface for WeCom.
+type Channel struct {
+	config Config
+	bus    *bus.MessageBus
+	crypt  *msgCrypt
+	api    *APIClient
+	logger *slog.Logger
+	now    func() time.Time
+
+	// 外部联系人 external_userid -> 解析后的用户
+	externalUsers sync.Map
+
+	running atomic.Bool
+}
+
+// New creates a new WeCom channel instance.
+func New(cfg Config, b *bus.MessageBus, logger *slog.Logger) (*Channel, error) {
+	if cfg.CorpID == "" || cfg.Secret == "" || cfg.AgentID == 0 {
+		return nil, fmt.Errorf("企业微信 corp_id、secret 和 agent_id 不能为空")
+	}
+	if cfg.Token == "" || cfg.EncodingAESKey == "" {
+		return nil, fmt.Errorf("企业微信 token 和 encoding_aes_key 不能为空")
+	}
+	switch cfg.MessageType {
+	case "":
+		cfg.MessageType = "markdown"
+	case "markdown", "text":
+	default:
+		return nil, fmt.Errorf("企业微信 message_type 只能是 markdown 或 text")
+	}
+
+	// 配置文件中的键会被转成小写，映射统一按小写查找
+	externalMap := make(map[string]string, len(cfg.ExternalUsers))
+	for k, v := range cfg.ExternalUsers {
+		externalMap[strings.ToLower(k)] = v
+	}
+	cfg.ExternalUsers = externalMap
+
+	crypt, err := newMsgCrypt(cfg.Token, cfg.EncodingAESKey, cfg.CorpID)
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Channel{
+		config: cfg,
+		bus:    b,
+		crypt:  crypt,
+		api:    NewAPIClient(cfg.CorpID, cfg.Secret, cfg.AgentID, logger),
+		logger: logger,
+		now:    time.Now,
+	}, nil
+}
+
+// Name returns the channel name.
+func (c *Channel) Name() string {
+	return consts.WECOM
+}
+
+// Start starts the WeCom channel. Messages arrive on the callback URL mounted on the gateway.
+func (c *Channel) Start(ctx context.Context) error {
+	c.logger.With("name", "【企业微信】").Info("启动通道...")
+
+	// 提前获取 access_token，尽早发现 corp_id 或 secret 配置错误
+	if _, err := c.api.GetAccessToken(ctx); err != nil {
+		c.logger.With("name", "【企业微信】").Warn("获取 access_token 失败", "error", err)
+	}
+
+	c.running.Store(true)
+	c.logger.With("name", "【企业微信】").Info("通道已启动", "path", c.WebhookPath())
+	return nil
+}
+
+// Stop stops the WeCom channel.
+func (c *Channel) Stop(ctx context.Context) error {
+	c.logger.With("name", "【企业微信】").Info("关闭通道...")
+	c.running.Store(false)
+	c.logger.With("name", "【企业微信】").Info("通道已停止")
+	return nil
+}
+
+// IsRunning returns true if the channel is running.
+func (c *Channel) IsRunning() bool {
+	return c.running.Load()
+}
+
+// IsAllowed checks if a sender is allowed.
+func (c *Channel) IsAllowed(senderID string) bool {
+	if len(c.config.AllowFrom) == 0 {
+		return true
+	}
+
+	for _, allowed := range c.config.AllowFrom {
+		if senderID == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAllowedSender checks if a sender is allowed (with full info).
+func (c *Channel) IsAllowedSender(sender channels.SenderInfo) bool {
+	return c.IsAllowed(sender.ID)
+}
+
+// ReasoningChannelID returns the channel ID for reasoning messages.
+func (c *Channel) ReasoningChannelID() string {
+	return c.config.ReasoningChatID
+}
+
+// Send sends an application message. The session ID is the recipient: member user IDs
+// separated by "|" ("@all" for everyone), "party:<id>" for a department or "tag:<id>" for a tag.
+func (c *Channel) Send(ctx context.Context, msg channels.OutboundMessage) error {
+	if !c.IsRunning() {
+		return errs.ErrNotRunning
+	}
+
+	c.logger.With("name", "【企业微信】").Debug("发送消息", "session_id", msg.SessionID, "preview", truncate(msg.Text, 100))
+
+	if err := c.api.SendMessage(ctx, parseRecipients(msg.SessionID), c.config.MessageType, msg.Text); err != nil {
+		c.logger.With("name", "【企业微信】").Error("发送失败", "session_id", msg.SessionID, "error", err)
+		return fmt.Errorf("wecom send: %w", errs.ErrTemporary)
+	}
+	return nil
+}
+
+// SendFile implements channels.FileSender: the file is uploaded as temporary
+// media and sent as an image message for images, a file message otherwise.
+func (c *Channel) SendFile(ctx context.Context, sessionID string, file channels.File) error {
+	if !c.IsRunning() {
+		return errs.ErrNotRunning
+	}
+
+	mediaType := "file"
+	if file.IsImage() {
+		mediaType = "image"
+	}
+	mediaID, err := c.api.UploadMedia(ctx, mediaType, file.Path, file.Name)
+	if err != nil {
+		c.logger.With("name", "【企业微信】").Error("上传文件失败", "file", file.Name, "error", err)
+		return fmt.Errorf("wecom upload file: %w", errs.ErrTemporary)
+	}
+	if err := c.api.SendMediaMessage(ctx, parseRecipients(sessionID), mediaType, mediaID); err != nil {
+		c.logger.With("name", "【企业微信】").Error("发送文件失败", "session_id", sessionID, "file", file.Name, "error", err)
+		return fmt.Errorf("wecom send file: %w", errs.ErrTemporary)
+	}
+	return nil
+}
+
+// parseRecipients parses a session ID into application message recipients.
+func parseRecipients(sessionID string) Recipients {
+	switch {
+	case strings.HasPrefix(sessionID, "party:"):
+		return Recipients{Parties: strings.Split(strings.TrimPrefix(sessionID, "party:"), "|")}
+	case strings.HasPrefix(sessionID, "tag:"):
+		return Recipients{Tags: strings.Split(strings.TrimPrefix(sessionID, "tag:"), "|")}
+	default:
+		return Recipients{Users: strings.Split(sessionID, "|")}
+	}
+}
+
+// WebhookPath implements channels.WebhookHandler.
+func (c *Channel) WebhookPath() string {
+	if c.config.WebhookPath != "" {
+		return c.config.WebhookPath
+	}
+	return defaultWebhookPath
+}
+
+// ServeHTTP handles the callback API: GET verifies the callback URL, POST delivers
+// encrypted messages. Signatures cover the query string, so they are checked here
+// rather than by the shared webhook middleware.
+func (c *Channel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	signature, timestamp, nonce := query.Get("msg_signature"), query.Get("timestamp"), query.Get("nonce")
+
+	switch r.Method {
+	case http.MethodGet:
+		// 配置回调 URL 时的验证请求，原样返回解密后的 echostr
+		echo, err := c.crypt.verify(signature, timestamp, nonce, query.Get("echostr"))
+		if err != nil {
+			c.logger.With("name", "【企业微信】").Warn("回调 URL 验证失败", "error", err)
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		w.Write(echo)
+
+	case http.MethodPost:
+		if !c.IsRunning() {
+			http.Error(w, "wecom channel is not running", http.StatusServiceUnavailable)
+			return
+		}
+		msg, err := c.decodeMessage(r, signature, timestamp, nonce)
+		if err != nil {
+			c.logger.With("name", "【企业微信】").Warn("回调消息验签失败", "remote", r.RemoteAddr, "error", err)
+			http.Error(w, "invalid message", http.StatusUnauthorized)
+			return
+		}
+		// 企业微信 5 秒内收不到响应会重试，先应答再异步处理
+		w.Write([]byte("success"))
+		go c.processMessage(msg)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// callbackEnvelope is the encrypted POST body.
+type callbackEnvelope struct {
+	ToUserName string `xml:"ToUserName"`
+	AgentID    string `xml:"AgentID"`
+	Encrypt    string `xml:"Encrypt"`
+}
+
+// callbackMessage is a decrypted callback message.
+type callbackMessage struct {
+	ToUserName   string `xml:"ToUserName"`
+	FromUserName string `xml:"FromUserName"`
+	CreateTime   int64  `xml:"CreateTime"`
+	MsgType      string `xml:"MsgType"`
+	Content      string `xml:"Content"`
+	MsgID        string `xml:"MsgId"`
+	AgentID      int64  `xml:"AgentID"`
+	Recognition  string `xml:"Recognition"` // 开启语音识别时的识别结果
+	Event        string `xml:"Event"`
+}
+
+// decodeMessage verifies the timestamp and signature of a POST callback and decrypts it.
+func (c *Channel) decodeMessage(r *http.Request, signature, timestamp, nonce string) (*callbackMessage, error) {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 时间戳格式错误", webhook.ErrInvalidSignature)
+	}
+	if skew := c.now().Sub(time.Unix(ts, 0)); skew > webhook.DefaultTolerance || skew < -webhook.DefaultTolerance {
+		return nil, webhook.ErrExpired
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhook.DefaultMaxBody))
+	if err != nil {
+		return nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	var envelope callbackEnvelope
+	if err := xml.Unmarshal(body, &envelope); err != nil || envelope.Encrypt == "" {
+		return nil, fmt.Errorf("解析请求体失败: %v", err)
+	}
+
+	plain, err := c.crypt.verify(signature, timestamp, nonce, envelope.Encrypt)
+	if err != nil {
+		return nil, err
+	}
+	var msg callbackMessage
+	if err := xml.Unmarshal(plain, &msg); err != nil {
+		return nil, fmt.Errorf("解析消息失败: %w", err)
+	}
+	return &msg, nil
+}
+
+// processMessage publishes a decrypted message to the bus.
+func (c *Channel) processMessage(msg *callbackMessage) {
+	content := ""
+	switch msg.MsgType {
+	case "text":
+		content = strings.TrimSpace(msg.Content)
+	case "voice":
+		content = strings.TrimSpace(msg.Recognition)
+	case "event":
+		c.logger.With("name", "【企业微信】").Debug("忽略事件", "event", msg.Event, "from", msg.FromUserName)
+		return
+	default:
+		c.logger.With("name", "【企业微信】").Debug("暂不支持的消息类型", "msg_type", msg.MsgType)
+	}
+	if content == "" || msg.FromUserName == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sender := c.resolveSender(ctx, msg.FromUserName)
+	if !c.IsAllowed(sender.ID) && !c.IsAllowed(msg.FromUserName) {
+		return
+	}
+
+	metadata := map[string]any{
+		"platform":  "wecom",
+		"chat_type": "p2p",
+		"agent_id":  msg.AgentID,
+	}
+	if msg.MsgID != "" {
+		metadata[channels.MessageIDKey] = msg.MsgID
+	}
+	if sender.ID != msg.FromUserName {
+		metadata["external_userid"] = msg.FromUserName
+	}
+
+	c.logger.With("name", "【企业微信】").Debug("收到消息",
+		"sender_id", sender.ID,
+		"from", msg.FromUserName,
+		"preview", truncate(content, 50),
+	)
+
+	// 会话按发送者的企业微信账号区分，回复发回同一账号
+	inboundMsg := bus.InboundMessage{
+		Channel:   c.Name(),
+		SessionID: msg.FromUserName,
+		Sender:    sender,
+		Text:      content,
+		Metadata:  metadata,
+	}
+	if err := c.bus.PublishInbound(ctx, inboundMsg); err != nil {
+		c.logger.With("name", "【企业微信】").Error("发布消息失败", "error", err)
+	}
+}
+
+// resolveSender maps the sender to the user ID that scopes memory and sessions. Members keep
+// their userid. External contacts (WeChat users) use the configured mapping, otherwise their
+// WeChat unionid, so the same person keeps one identity across the corp's apps.
+func (c *Channel) resolveSender(ctx context.Context, fromUser string) bus.SenderInfo {
+	mapped := c.config.ExternalUsers[strings.ToLower(fromUser)]
+	if mapped == "" && !isExternalUserID(fromUser) {
+		return bus.SenderInfo{ID: fromUser}
+	}
+	if v, ok := c.externalUsers.Load(fromUser); ok {
+		return v.(bus.SenderInfo)
+	}
+
+	sender := bus.SenderInfo{ID: fromUser}
+	if mapped != "" {
+		sender.ID = mapped
+	}
+	contact, err := c.api.GetExternalContact(ctx, fromUser)
+	if err != nil {
+		// 查询失败时不缓存，下次消息再试
+		c.logger.With("name", "【企业微信】").Warn("查询外部联系人失败", "external_userid", fromUser, "error", err)
+		return sender
+	}
+	sender.Name = contact.Name
+	if sender.ID == fromUser && contact.UnionID != "" {
+		sender.ID = "wx:" + contact.UnionID
+	}
+	c.externalUsers.Store(fromUser, sender)
+	return sender
+}
+
+// isExternalUserID reports whether an ID is an external contact's external_userid:
+// 32 characters starting with "wm" or "wo". Member userids are chosen by the corp and
+// may share the prefix, so the length is checked too.
+func isExternalUserID(id string) bool {
+	return len(id) == 32 && (strings.HasPrefix(id, "wm") || strings.HasPrefix(id, "wo"))
+}
+
+// truncate truncates a string to maxLen runes.
+func truncate(s string, maxLen int) string {
+	r := []rune(s)
+	if len(r) <= maxLen {
+		return s
+	}
+	return string(r[:maxLen]) + "..."
+}
@@ -0,0 +1,173 @@
+package wecom
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/bus"
+)
+
+const (
+	testToken  = "token"
+	testAESKey = "jWmYm7qr5nMoAUwZRjGtBxmz3KA1tkAj3ykkR6q2B2C"
+	testCorpID = "wx5823bf96d3bd56c7"
+)
+
+// encrypt 按企业微信的方式加密消息，模拟回调请求。
+func encrypt(t *testing.T, c *msgCrypt, msg string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("0123456789abcdef")
+	binary.Write(&buf, binary.BigEndian, uint32(len(msg)))
+	buf.WriteString(msg)
+	buf.WriteString(c.corpID)
+	pad := 32 - buf.Len()%32
+	buf.Write(bytes.Repeat([]byte{byte(pad)}, pad))
+
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, buf.Len())
+	cipher.NewCBCEncrypter(block, c.key[:aes.BlockSize]).CryptBlocks(out, buf.Bytes())
+	return base64.StdEncoding.EncodeToString(out)
+}
+
+func newTestChannel(t *testing.T) (*Channel, *bus.MessageBus) {
+	t.Helper()
+	b := bus.NewMessageBus(bus.DefaultConfig())
+	c, err := New(Config{
+		CorpID:         testCorpID,
+		AgentID:        1000002,
+		Secret:         "secret",
+		Token:          testToken,
+		EncodingAESKey: testAESKey,
+	}, b, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, b
+}
+
+func TestMsgCrypt(t *testing.T) {
+	c, err := newMsgCrypt(testToken, testAESKey, testCorpID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := encrypt(t, c, "hello")
+	sig := c.signature("1409659813", "263014780", enc)
+	msg, err := c.verify(sig, "1409659813", "263014780", enc)
+	if err != nil || string(msg) != "hello" {
+		t.Fatalf("verify() = %q, %v", msg, err)
+	}
+
+	if _, err := c.verify(sig, "1409659814", "263014780", enc); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered timestamp: err = %v, want ErrInvalidSignature", err)
+	}
+
+	other, _ := newMsgCrypt(testToken, testAESKey, "another-corp")
+	if _, err := other.decrypt(enc); !errors.Is(err, ErrInvalidReceiver) {
+		t.Errorf("wrong corp: err = %v, want ErrInvalidReceiver", err)
+	}
+
+	if _, err := newMsgCrypt(testToken, "short", testCorpID); err == nil {
+		t.Error("invalid EncodingAESKey should be rejected")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	c, b := newTestChannel(t)
+	c.running.Store(true)
+	now := time.Unix(1_700_000_000, 0)
+	c.now = func() time.Time { return now }
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	query := func(encrypted string) string {
+		return url.Values{
+			"msg_signature": {c.crypt.signature(ts, "nonce", encrypted)},
+			"timestamp":     {ts},
+			"nonce":         {"nonce"},
+		}.Encode()
+	}
+
+	// URL verification
+	echo := encrypt(t, c.crypt, "echo-123")
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhook/wecom?"+query(echo)+"&echostr="+url.QueryEscape(echo), nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "echo-123" {
+		t.Fatalf("GET: code = %d, body = %q", rec.Code, rec.Body.String())
+	}
+
+	// Text message
+	plain := fmt.Sprintf(`<xml><ToUserName><![CDATA[%s]]></ToUserName><FromUserName><![CDATA[zhangsan]]></FromUserName>`+
+		`<CreateTime>%s</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[ 你好 ]]></Content>`+
+		`<MsgId>1234567890</MsgId><AgentID>1000002</AgentID></xml>`, testCorpID, ts)
+	enc := encrypt(t, c.crypt, plain)
+	body := fmt.Sprintf(`<xml><ToUserName><![CDATA[%s]]></ToUserName><AgentID>1000002</AgentID><Encrypt><![CDATA[%s]]></Encrypt></xml>`, testCorpID, enc)
+
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook/wecom?"+query(enc), strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST: code = %d", rec.Code)
+	}
+
+	select {
+	case msg := <-b.Inbound():
+		if msg.Channel != "wecom" || msg.SessionID != "zhangsan" || msg.Sender.ID != "zhangsan" || msg.Text != "你好" {
+			t.Errorf("inbound = %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no inbound message published")
+	}
+
+	// Forged signature
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook/wecom?msg_signature=bad&timestamp="+ts+"&nonce=nonce", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("forged POST: code = %d, want 401", rec.Code)
+	}
+
+	// Stale timestamp
+	now = now.Add(time.Hour)
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook/wecom?"+query(enc), strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("stale POST: code = %d, want 401", rec.Code)
+	}
+}
+
+func TestParseRecipients(t *testing.T) {
+	if r := parseRecipients("zhangsan|lisi"); len(r.Users) != 2 || r.Users[1] != "lisi" {
+		t.Errorf("users = %+v", r)
+	}
+	if r := parseRecipients("party:2|3"); len(r.Parties) != 2 || len(r.Users) != 0 {
+		t.Errorf("parties = %+v", r)
+	}
+	if r := parseRecipients("tag:7"); len(r.Tags) != 1 || r.Tags[0] != "7" {
+		t.Errorf("tags = %+v", r)
+	}
+}
+
+func TestIsExternalUserID(t *testing.T) {
+	if !isExternalUserID("woAJ2GCAAAXtWyujaWJHDDGi0mACHAAA") {
+		t.Error("external_userid not recognized")
+	}
+	if isExternalUserID("wolfgang") {
+		t.Error("member userid with the same prefix treated as external")
+	}
+}
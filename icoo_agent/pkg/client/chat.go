@@ -0,0 +1,85 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Chat 发送一条消息并等待智能体完整回复。
+// 聊天会触发智能体执行，失败时不会自动重试。
+func (c *Client) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	return call[*ChatResponse](ctx, c, http.MethodPost, "/chat/", req, false)
+}
+
+// StreamHandler 流式回复回调，返回错误时中止读取。
+type StreamHandler func(content string) error
+
+// sseData SSE 事件数据。
+type sseData struct {
+	SessionID string `json:"session_id"`
+	Content   string `json:"content"`
+	Type      string `json:"type"`
+	Error     string `json:"error"`
+}
+
+// ChatStream 通过 SSE 发送消息，每收到一段回复调用一次 fn，返回完整回复。
+func (c *Client) ChatStream(ctx context.Context, req *ChatRequest, fn StreamHandler) (string, error) {
+	body := struct {
+		*ChatRequest
+		Stream bool `json:"stream"`
+	}{req, true}
+
+	resp, err := c.do(ctx, http.MethodPost, "/chat/stream", body, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var (
+		full  strings.Builder
+		event string
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			continue
+		case !strings.HasPrefix(line, "data:"):
+			continue
+		}
+
+		var data sseData
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &data); err != nil {
+			return full.String(), fmt.Errorf("icooclaw: decode stream event: %w", err)
+		}
+
+		switch event {
+		case "content":
+			if data.Type == "end" || data.Content == "" {
+				continue
+			}
+			full.WriteString(data.Content)
+			if fn != nil {
+				if err := fn(data.Content); err != nil {
+					return full.String(), err
+				}
+			}
+		case "error":
+			return full.String(), &APIError{StatusCode: http.StatusInternalServerError, Message: data.Error}
+		case "end":
+			return full.String(), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("icooclaw: read stream: %w", err)
+	}
+	return full.String(), errors.New("icooclaw: stream closed before end event")
+}
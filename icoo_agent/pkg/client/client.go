@@ -0,0 +1,197 @@
+// Package client provides a Go SDK for the icooclaw REST and WebSocket APIs.
+//
+// 内部服务通过该包调用 icooclaw，无需手写 HTTP 请求：
+//
+//	c := client.New("http://localhost:8080", client.WithAPIKey("..."))
+//	resp, err := c.Chat(ctx, &client.ChatRequest{SessionID: "s1", Content: "你好"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v1"
+
+// Client icooclaw API 客户端。
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	retryWait  time.Duration
+	userAgent  string
+}
+
+// Option 客户端配置项。
+type Option func(*Client)
+
+// WithHTTPClient 设置自定义 HTTP 客户端。
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithAPIKey 设置 API Key，以 Bearer Token 发送。
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithRetry 设置失败重试次数和初始等待时间，等待时间按指数增长。
+func WithRetry(maxRetries int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryWait = wait
+	}
+}
+
+// WithUserAgent 设置 User-Agent。
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New 创建客户端，baseURL 形如 http://localhost:8080。
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+		maxRetries: 3,
+		retryWait:  500 * time.Millisecond,
+		userAgent:  "icooclaw-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError 服务端返回的错误。
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("icooclaw: %d %s", e.StatusCode, e.Message)
+}
+
+// Temporary 是否为可重试的临时错误。
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// response 服务端统一响应结构。
+type response[T any] struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    T      `json:"data"`
+}
+
+// call 发送请求并解析统一响应中的 data。
+func call[T any](ctx context.Context, c *Client, method, path string, body any, retry bool) (T, error) {
+	var zero T
+
+	resp, err := c.do(ctx, method, path, body, retry)
+	if err != nil {
+		return zero, err
+	}
+	defer resp.Body.Close()
+
+	var out response[T]
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return zero, fmt.Errorf("icooclaw: decode response: %w", err)
+	}
+	return out.Data, nil
+}
+
+// do 发送请求，对网络错误、429 与 5xx 按指数退避重试。
+// 非幂等请求（如聊天）设置 retry=false，避免重复触发智能体。
+func (c *Client) do(ctx context.Context, method, path string, body any, retry bool) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("icooclaw: encode request: %w", err)
+		}
+	}
+
+	attempts := 1
+	if retry {
+		attempts += max(c.maxRetries, 0)
+	}
+
+	var lastErr error
+	for i := range attempts {
+		if i > 0 {
+			if err := sleep(ctx, c.retryWait<<(i-1)); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.send(ctx, method, path, payload)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && !apiErr.Temporary() {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, lastErr
+}
+
+// send 发送一次请求，非 2xx 响应转换为 APIError。
+func (c *Client) send(ctx context.Context, method, path string, payload []byte) (*http.Response, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiPrefix+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("icooclaw: create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("icooclaw: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	return resp, nil
+}
+
+// sleep 等待 d，ctx 取消时提前返回。
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
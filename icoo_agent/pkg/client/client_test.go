@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClient_RetryTemporaryErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("missing api key header")
+		}
+		fmt.Fprint(w, `{"code":200,"message":"ok","data":{"id":"s1","channel":"websocket"}}`)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithAPIKey("key"), WithRetry(3, time.Millisecond))
+	sess, err := c.GetSession(context.Background(), "s1")
+	if err != nil {
+		t.Fatalf("GetSession() error = %v", err)
+	}
+	if sess.ID != "s1" || sess.Channel != "websocket" {
+		t.Errorf("GetSession() = %+v", sess)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+}
+
+func TestClient_NoRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		call   func(c *Client) error
+	}{
+		{"client error", http.StatusBadRequest, func(c *Client) error {
+			_, err := c.GetSkill(context.Background(), "x")
+			return err
+		}},
+		{"chat is not retried", http.StatusBadGateway, func(c *Client) error {
+			_, err := c.Chat(context.Background(), &ChatRequest{SessionID: "s", Content: "hi"})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				http.Error(w, "失败", tt.status)
+			}))
+			defer srv.Close()
+
+			err := tt.call(New(srv.URL, WithRetry(3, time.Millisecond)))
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || apiErr.Message != "失败" {
+				t.Fatalf("error = %v, want APIError %d", err, tt.status)
+			}
+			if calls.Load() != 1 {
+				t.Errorf("calls = %d, want 1", calls.Load())
+			}
+		})
+	}
+}
+
+func TestClient_ChatStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/chat/stream" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["stream"] != true {
+			t.Errorf("stream flag not set: %v", req)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: start\ndata: {\"session_id\":\"s\"}\n\n")
+		fmt.Fprint(w, "event: content\ndata: {\"session_id\":\"s\",\"content\":\"你\"}\n\n")
+		fmt.Fprint(w, "event: content\ndata: {\"session_id\":\"s\",\"content\":\"好\"}\n\n")
+		fmt.Fprint(w, "event: content\ndata: {\"session_id\":\"s\",\"type\":\"end\"}\n\n")
+		fmt.Fprint(w, "event: end\ndata: {\"session_id\":\"s\"}\n\n")
+	}))
+	defer srv.Close()
+
+	var chunks []string
+	full, err := New(srv.URL).ChatStream(context.Background(), &ChatRequest{SessionID: "s", Content: "hi"}, func(content string) error {
+		chunks = append(chunks, content)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if full != "你好" || strings.Join(chunks, "|") != "你|好" {
+		t.Errorf("ChatStream() = %q, chunks %v", full, chunks)
+	}
+}
+
+func TestClient_Dial(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws/s1" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteJSON(map[string]any{"type": "connected"})
+
+		var hello map[string]any
+		ws.ReadJSON(&hello)
+		if hello["type"] != EventHello || hello["version"] != float64(ProtocolVersion) {
+			t.Errorf("unexpected hello: %v", hello)
+		}
+		ws.WriteJSON(map[string]any{"type": EventHello, "version": 1, "seq": 1,
+			"data": map[string]any{"version": 1, "capabilities": []string{CapTools}, "session_id": "s1"}})
+
+		var msg map[string]any
+		ws.ReadJSON(&msg)
+		ws.WriteJSON(map[string]any{"type": EventMessage, "version": 1, "seq": 2,
+			"data": map[string]any{"role": "assistant", "content": "echo: " + msg["content"].(string)}})
+	}))
+	defer srv.Close()
+
+	conn, err := New(srv.URL).Dial(context.Background(), "s1", CapTools)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	if h := conn.Hello(); h.Version != 1 || h.SessionID != "s1" {
+		t.Errorf("Hello() = %+v", h)
+	}
+
+	if err := conn.Send("", "hi", true); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	ev, err := conn.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	var msg MessagePayload
+	if err := ev.Decode(&msg); err != nil || ev.Type != EventMessage || msg.Content != "echo: hi" {
+		t.Errorf("Next() = %+v, %+v, %v", ev, msg, err)
+	}
+}
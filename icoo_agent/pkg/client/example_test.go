@@ -0,0 +1,85 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"icooclaw/pkg/client"
+)
+
+func Example() {
+	c := client.New("http://localhost:8080",
+		client.WithAPIKey("your-api-key"),
+		client.WithRetry(3, time.Second))
+
+	ctx := context.Background()
+	resp, err := c.Chat(ctx, &client.ChatRequest{SessionID: "demo", Content: "你好"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(resp.Content)
+}
+
+func ExampleClient_ChatStream() {
+	c := client.New("http://localhost:8080")
+
+	_, err := c.ChatStream(context.Background(), &client.ChatRequest{SessionID: "demo", Content: "写一首诗"},
+		func(content string) error {
+			fmt.Print(content)
+			return nil
+		})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleClient_Dial() {
+	c := client.New("http://localhost:8080")
+
+	conn, err := c.Dial(context.Background(), "demo", client.CapTools, client.CapUsage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.Send("", "列出当前目录的文件", true); err != nil {
+		log.Fatal(err)
+	}
+
+	for {
+		ev, err := conn.Next()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		switch ev.Type {
+		case client.EventDelta:
+			var d client.DeltaPayload
+			ev.Decode(&d)
+			fmt.Print(d.Content)
+		case client.EventToolCall:
+			var tc client.ToolCallPayload
+			ev.Decode(&tc)
+			fmt.Printf("\n[调用工具 %s]\n", tc.Name)
+		case client.EventUsage:
+			return
+		}
+	}
+}
+
+func ExampleClient_PageSessions() {
+	c := client.New("http://localhost:8080")
+
+	res, err := c.PageSessions(context.Background(), &client.SessionQuery{
+		Page:    client.Page{Page: 1, Size: 20},
+		Channel: "websocket",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, s := range res.Records {
+		fmt.Println(s.ID, s.Title)
+	}
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// PageMemories 分页查询记忆。
+func (c *Client) PageMemories(ctx context.Context, q *MemoryQuery) (*PageResult[*Memory], error) {
+	return call[*PageResult[*Memory]](ctx, c, http.MethodPost, "/memories/page", q, true)
+}
+
+// SessionMemories 获取会话最近的记忆，最多 100 条。
+func (c *Client) SessionMemories(ctx context.Context, sessionID string) ([]*Memory, error) {
+	return call[[]*Memory](ctx, c, http.MethodPost, "/memories/get", idRequest{ID: sessionID}, true)
+}
+
+// SearchMemories 按关键字搜索记忆。
+func (c *Client) SearchMemories(ctx context.Context, query string) (*PageResult[*Memory], error) {
+	req := struct {
+		Query string `json:"query"`
+	}{query}
+	return call[*PageResult[*Memory]](ctx, c, http.MethodPost, "/memories/search", req, true)
+}
+
+// CreateMemory 创建记忆。
+func (c *Client) CreateMemory(ctx context.Context, m *Memory) (*Memory, error) {
+	return call[*Memory](ctx, c, http.MethodPost, "/memories/create", m, false)
+}
+
+// UpdateMemory 更新记忆。
+func (c *Client) UpdateMemory(ctx context.Context, m *Memory) (*Memory, error) {
+	return call[*Memory](ctx, c, http.MethodPost, "/memories/update", m, true)
+}
+
+// DeleteMemory 删除记忆。
+func (c *Client) DeleteMemory(ctx context.Context, id string) error {
+	_, err := call[any](ctx, c, http.MethodPost, "/memories/delete", idRequest{ID: id}, true)
+	return err
+}
@@ -0,0 +1,150 @@
+package client
+
+import "time"
+
+// Page 分页参数，Page 或 Size 为 0 时返回全部记录。
+type Page struct {
+	Size  int   `json:"size"`
+	Page  int   `json:"page"`
+	Total int64 `json:"total"`
+}
+
+// PageResult 分页结果。
+type PageResult[T any] struct {
+	Page    Page `json:"page"`
+	Records []T  `json:"records"`
+}
+
+// Model 通用字段。
+type Model struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChatRequest 聊天请求。
+type ChatRequest struct {
+	SessionID string `json:"session_id"`
+	Content   string `json:"content"`
+	AgentName string `json:"agent_name,omitempty"`
+	// MaxChunkLength 单条消息最大字符数，设置后超长回复附带分段信息
+	MaxChunkLength int `json:"max_chunk_length,omitempty"`
+}
+
+// ChatResponse 聊天响应。
+type ChatResponse struct {
+	SessionID string         `json:"session_id"`
+	Content   string         `json:"content"`
+	AgentName string         `json:"agent_name,omitempty"`
+	Timestamp int64          `json:"timestamp"`
+	Chunks    []MessageChunk `json:"chunks,omitempty"`
+}
+
+// MessageChunk 超长回复的分段。
+type MessageChunk struct {
+	Index int    `json:"index"`
+	Total int    `json:"total"`
+	Text  string `json:"text"`
+}
+
+// Session 会话。
+type Session struct {
+	Model
+	Channel    string    `json:"channel"`
+	UserID     string    `json:"user_id"`
+	Summary    string    `json:"summary"`
+	Title      string    `json:"title"`
+	LastActive time.Time `json:"last_active"`
+	Archived   bool      `json:"archived"`
+	ArchivedAt time.Time `json:"archived_at"`
+	ParentID   string    `json:"parent_id"`
+}
+
+// SessionQuery 会话查询条件。
+type SessionQuery struct {
+	Page     Page   `json:"page"`
+	KeyWord  string `json:"key_word,omitempty"`
+	Channel  string `json:"channel,omitempty"`
+	UserID   string `json:"user_id,omitempty"`
+	Archived *bool  `json:"archived,omitempty"`
+}
+
+// CreateSessionRequest 创建会话请求。
+type CreateSessionRequest struct {
+	Channel   string            `json:"channel,omitempty"`
+	UserID    string            `json:"user_id,omitempty"`
+	SessionID string            `json:"session_id,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// CreateSessionResponse 创建会话响应。
+type CreateSessionResponse struct {
+	SessionID string `json:"session_id"`
+	Channel   string `json:"channel"`
+	UserID    string `json:"user_id"`
+}
+
+// ResetSessionRequest 重置会话请求。
+type ResetSessionRequest struct {
+	Channel     string `json:"channel,omitempty"`
+	SessionID   string `json:"session_id"`
+	KeepPinned  bool   `json:"keep_pinned"`
+	KeepSummary bool   `json:"keep_summary"`
+}
+
+// ResetSessionResult 重置会话结果。
+type ResetSessionResult struct {
+	SessionID      string `json:"session_id"`
+	ArchiveID      string `json:"archive_id"`
+	Messages       int64  `json:"messages"`
+	Memories       int64  `json:"memories"`
+	KeptMemories   int64  `json:"kept_memories"`
+	SummaryCarried bool   `json:"summary_carried"`
+}
+
+// Message 会话消息。
+type Message struct {
+	Model
+	SessionID  string `json:"session_id"`
+	Role       string `json:"role"`
+	Content    string `json:"content"`
+	ToolName   string `json:"tool_name"`
+	ToolArgs   string `json:"tool_args"`
+	ToolResult string `json:"tool_result"`
+	Metadata   string `json:"metadata"`
+}
+
+// Memory 记忆。
+type Memory struct {
+	Model
+	SessionID string `json:"session_id"`
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	Metadata  string `json:"metadata"`
+	Pinned    bool   `json:"pinned"`
+}
+
+// MemoryQuery 记忆查询条件。
+type MemoryQuery struct {
+	Page      Page   `json:"page"`
+	SessionID string `json:"session_id,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Query     string `json:"query,omitempty"`
+}
+
+// Skill 技能。
+type Skill struct {
+	Model
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Version     string `json:"version"`
+	Path        string `json:"path"`
+}
+
+// SkillQuery 技能查询条件。
+type SkillQuery struct {
+	Page    Page   `json:"page"`
+	KeyWord string `json:"key_word,omitempty"`
+	Enabled *bool  `json:"enabled,omitempty"`
+}
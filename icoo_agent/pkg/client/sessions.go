@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// idRequest 按 ID 操作的请求体。
+type idRequest struct {
+	ID string `json:"id"`
+}
+
+// PageSessions 分页查询会话。
+func (c *Client) PageSessions(ctx context.Context, q *SessionQuery) (*PageResult[*Session], error) {
+	return call[*PageResult[*Session]](ctx, c, http.MethodPost, "/sessions/page", q, true)
+}
+
+// GetSession 获取会话。
+func (c *Client) GetSession(ctx context.Context, id string) (*Session, error) {
+	return call[*Session](ctx, c, http.MethodPost, "/sessions/get", idRequest{ID: id}, true)
+}
+
+// CreateSession 创建会话，SessionID 为空时由服务端生成。
+func (c *Client) CreateSession(ctx context.Context, req *CreateSessionRequest) (*CreateSessionResponse, error) {
+	return call[*CreateSessionResponse](ctx, c, http.MethodPost, "/sessions/create", req, true)
+}
+
+// SaveSession 保存会话。
+func (c *Client) SaveSession(ctx context.Context, sess *Session) error {
+	_, err := call[any](ctx, c, http.MethodPost, "/sessions/save", sess, true)
+	return err
+}
+
+// DeleteSession 删除会话。
+func (c *Client) DeleteSession(ctx context.Context, id string) error {
+	_, err := call[any](ctx, c, http.MethodPost, "/sessions/delete", idRequest{ID: id}, true)
+	return err
+}
+
+// ResetSession 归档会话当前上下文并重新开始。
+// 重置不是幂等操作，失败时不会自动重试。
+func (c *Client) ResetSession(ctx context.Context, req *ResetSessionRequest) (*ResetSessionResult, error) {
+	return call[*ResetSessionResult](ctx, c, http.MethodPost, "/sessions/reset", req, false)
+}
+
+// SessionMessages 获取会话的历史消息，channel 为空时使用 websocket 渠道。
+func (c *Client) SessionMessages(ctx context.Context, channel, sessionID string) ([]*Message, error) {
+	req := struct {
+		Channel   string `json:"channel,omitempty"`
+		SessionID string `json:"session_id"`
+	}{channel, sessionID}
+	return call[[]*Message](ctx, c, http.MethodPost, "/messages/by-session", req, true)
+}
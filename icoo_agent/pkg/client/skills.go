@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// PageSkills 分页查询技能。
+func (c *Client) PageSkills(ctx context.Context, q *SkillQuery) (*PageResult[*Skill], error) {
+	return call[*PageResult[*Skill]](ctx, c, http.MethodPost, "/skills/page", q, true)
+}
+
+// ListSkills 获取全部技能。
+func (c *Client) ListSkills(ctx context.Context) ([]*Skill, error) {
+	return call[[]*Skill](ctx, c, http.MethodGet, "/skills/all", nil, true)
+}
+
+// EnabledSkills 获取已启用的技能。
+func (c *Client) EnabledSkills(ctx context.Context) ([]*Skill, error) {
+	return call[[]*Skill](ctx, c, http.MethodGet, "/skills/enabled", nil, true)
+}
+
+// GetSkill 按 ID 获取技能。
+func (c *Client) GetSkill(ctx context.Context, id string) (*Skill, error) {
+	return call[*Skill](ctx, c, http.MethodPost, "/skills/get", idRequest{ID: id}, true)
+}
+
+// GetSkillByName 按名称获取技能。
+func (c *Client) GetSkillByName(ctx context.Context, name string) (*Skill, error) {
+	req := struct {
+		Name string `json:"name"`
+	}{name}
+	return call[*Skill](ctx, c, http.MethodPost, "/skills/get-by-name", req, true)
+}
+
+// UpsertSkill 按名称创建或更新技能。
+func (c *Client) UpsertSkill(ctx context.Context, s *Skill) (*Skill, error) {
+	return call[*Skill](ctx, c, http.MethodPost, "/skills/upsert", s, true)
+}
+
+// DeleteSkill 删除技能。
+func (c *Client) DeleteSkill(ctx context.Context, id string) error {
+	_, err := call[any](ctx, c, http.MethodPost, "/skills/delete", idRequest{ID: id}, true)
+	return err
+}
@@ -0,0 +1,254 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ProtocolVersion SDK 支持的 WebSocket 协议版本。
+const ProtocolVersion = 1
+
+// 事件类型，与服务端协议保持一致。
+const (
+	EventHello      = "hello"
+	EventMessage    = "message"
+	EventDelta      = "delta"
+	EventToolCall   = "tool_call"
+	EventToolResult = "tool_result"
+	EventError      = "error"
+	EventUsage      = "usage"
+	EventSystem     = "system"
+)
+
+// 能力，Dial 时未指定则请求全部能力。
+const (
+	CapReasoning = "reasoning"
+	CapTools     = "tools"
+	CapUsage     = "usage"
+	CapSystem    = "system"
+)
+
+// Event WebSocket 事件帧，Data 按 Type 使用对应的 Payload 解码。
+type Event struct {
+	Type      string          `json:"type"`
+	Version   int             `json:"version"`
+	Seq       uint64          `json:"seq"`
+	SessionID string          `json:"session_id,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// Decode 将事件数据解码到 v。
+func (e *Event) Decode(v any) error {
+	if len(e.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(e.Data, v)
+}
+
+// HelloPayload 握手响应。
+type HelloPayload struct {
+	Version      int      `json:"version"`
+	MinVersion   int      `json:"min_version"`
+	MaxVersion   int      `json:"max_version"`
+	Capabilities []string `json:"capabilities"`
+	ClientID     string   `json:"client_id"`
+	SessionID    string   `json:"session_id"`
+}
+
+// MessagePayload 完整消息。
+type MessagePayload struct {
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	Iteration int            `json:"iteration,omitempty"`
+	Chunks    []MessageChunk `json:"chunks,omitempty"`
+}
+
+// DeltaPayload 流式增量。
+type DeltaPayload struct {
+	Content   string `json:"content,omitempty"`
+	Reasoning string `json:"reasoning,omitempty"`
+	Iteration int    `json:"iteration,omitempty"`
+}
+
+// ToolCallPayload 工具调用。
+type ToolCallPayload struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+	Iteration int    `json:"iteration,omitempty"`
+}
+
+// ToolResultPayload 工具结果。
+type ToolResultPayload struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Content   string `json:"content"`
+	Iteration int    `json:"iteration,omitempty"`
+}
+
+// ErrorPayload 错误。
+type ErrorPayload struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// UsagePayload 用量统计。
+type UsagePayload struct {
+	PromptTokens     int `json:"prompt_tokens,omitempty"`
+	CompletionTokens int `json:"completion_tokens,omitempty"`
+	TotalTokens      int `json:"total_tokens,omitempty"`
+	Iterations       int `json:"iterations"`
+}
+
+// SystemPayload 系统通知。
+type SystemPayload struct {
+	Message string         `json:"message"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// Conn WebSocket 连接，已完成协议握手。
+// Send 可并发调用，Next 只能在单个 goroutine 中调用。
+type Conn struct {
+	ws    *websocket.Conn
+	hello HelloPayload
+	mu    sync.Mutex
+}
+
+// Dial 建立 WebSocket 连接并完成 hello 握手。
+// sessionID 为空时由服务端分配，caps 为空时请求全部能力。
+func (c *Client) Dial(ctx context.Context, sessionID string, caps ...string) (*Conn, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("icooclaw: invalid base url: %w", err)
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/ws"
+	if sessionID != "" {
+		u.Path += "/" + url.PathEscape(sessionID)
+	}
+
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	header.Set("User-Agent", c.userAgent)
+
+	ws, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, &APIError{StatusCode: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, fmt.Errorf("icooclaw: dial websocket: %w", err)
+	}
+
+	conn := &Conn{ws: ws}
+	if err := conn.handshake(ctx, caps); err != nil {
+		ws.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// handshake 发送 hello 并等待服务端握手响应，握手前的其他帧（如连接通知）会被跳过。
+func (c *Conn) handshake(ctx context.Context, caps []string) error {
+	if err := c.write(map[string]any{
+		"type":         EventHello,
+		"version":      ProtocolVersion,
+		"capabilities": caps,
+		"client":       "icooclaw-go-client",
+	}); err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		c.ws.SetReadDeadline(deadline)
+		defer c.ws.SetReadDeadline(time.Time{})
+	}
+
+	for {
+		ev, err := c.Next()
+		if err != nil {
+			return err
+		}
+		switch ev.Type {
+		case EventHello:
+			return ev.Decode(&c.hello)
+		case EventError:
+			p := ErrorPayload{Message: "握手失败"}
+			ev.Decode(&p)
+			return &APIError{StatusCode: http.StatusBadRequest, Message: p.Message}
+		}
+	}
+}
+
+// Hello 返回握手协商结果。
+func (c *Conn) Hello() HelloPayload {
+	return c.hello
+}
+
+// Send 发送聊天消息，sessionID 为空时使用连接的默认会话。
+func (c *Conn) Send(sessionID, content string, stream bool) error {
+	return c.write(map[string]any{
+		"type":       "chat",
+		"session_id": sessionID,
+		"content":    content,
+		"stream":     stream,
+	})
+}
+
+// SendChunked 发送聊天消息，并要求服务端按 maxChunkLength 在完整消息中附带分段信息。
+func (c *Conn) SendChunked(sessionID, content string, stream bool, maxChunkLength int) error {
+	return c.write(map[string]any{
+		"type":             "chat",
+		"session_id":       sessionID,
+		"content":          content,
+		"stream":           stream,
+		"max_chunk_length": maxChunkLength,
+	})
+}
+
+// Next 读取下一个事件，连接关闭时返回错误。
+func (c *Conn) Next() (*Event, error) {
+	_, data, err := c.ws.ReadMessage()
+	if err != nil {
+		return nil, fmt.Errorf("icooclaw: read websocket: %w", err)
+	}
+
+	var ev Event
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return nil, fmt.Errorf("icooclaw: decode event: %w", err)
+	}
+	return &ev, nil
+}
+
+// Close 关闭连接。
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.mu.Unlock()
+	return c.ws.Close()
+}
+
+func (c *Conn) write(v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.ws.WriteJSON(v); err != nil {
+		return fmt.Errorf("icooclaw: write websocket: %w", err)
+	}
+	return nil
+}
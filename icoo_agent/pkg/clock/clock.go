@@ -0,0 +1,100 @@
+// Package clock 提供时区相关的时间处理：按会话和渠道跟踪用户时区、统一格式化面向模型的时间，
+// 以及解析"明天下午3点"、"next Tuesday 3pm"这类自然语言时间。
+package clock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// weekdays 中文星期名称，按 time.Weekday 排列
+var weekdays = [...]string{"日", "一", "二", "三", "四", "五", "六"}
+
+// LoadLocation 加载时区，支持 IANA 名称（Asia/Shanghai）、UTC、Local 以及 UTC+8、+08:00 这类固定偏移。
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	switch strings.ToLower(name) {
+	case "", "local":
+		return time.Local, nil
+	case "utc", "gmt", "z":
+		return time.UTC, nil
+	}
+	if loc, ok := fixedZone(name); ok {
+		return loc, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区 %q，请使用 IANA 名称（如 Asia/Shanghai）或 UTC+8 这样的偏移", name)
+	}
+	return loc, nil
+}
+
+// fixedZone 解析 UTC+8、GMT-5、+08:00、+0530 形式的固定偏移。
+func fixedZone(name string) (*time.Location, bool) {
+	s := strings.ToUpper(name)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "UTC"), "GMT")
+	if s == "" || (s[0] != '+' && s[0] != '-') {
+		return nil, false
+	}
+	sign := 1
+	if s[0] == '-' {
+		sign = -1
+	}
+	s = strings.ReplaceAll(s[1:], ":", "")
+
+	var hours, minutes int
+	switch len(s) {
+	case 1, 2:
+		if _, err := fmt.Sscanf(s, "%d", &hours); err != nil {
+			return nil, false
+		}
+	case 3, 4:
+		if _, err := fmt.Sscanf(s[:len(s)-2], "%d", &hours); err != nil {
+			return nil, false
+		}
+		if _, err := fmt.Sscanf(s[len(s)-2:], "%d", &minutes); err != nil {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	if hours > 14 || minutes > 59 {
+		return nil, false
+	}
+	offset := sign * (hours*3600 + minutes*60)
+	return time.FixedZone(offsetName(offset), offset), true
+}
+
+// offsetName 返回 UTC+08:00 形式的偏移名称。
+func offsetName(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("UTC%c%02d:%02d", sign, offset/3600, offset%3600/60)
+}
+
+// Format 按统一格式输出面向模型的时间："2026-03-10 15:04 星期二 (Asia/Shanghai, UTC+08:00)"。
+// loc 为 nil 时使用 t 自身的时区。
+func Format(t time.Time, loc *time.Location) string {
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return fmt.Sprintf("%s 星期%s (%s)", t.Format("2006-01-02 15:04"), weekdays[t.Weekday()], Zone(t))
+}
+
+// Zone 返回时间所在时区的名称和偏移，例如 "Asia/Shanghai, UTC+08:00"，固定偏移时区只返回偏移。
+func Zone(t time.Time) string {
+	_, offset := t.Zone()
+	name := t.Location().String()
+	if strings.HasPrefix(name, "UTC+") || strings.HasPrefix(name, "UTC-") {
+		return name
+	}
+	return fmt.Sprintf("%s, %s", name, offsetName(offset))
+}
+
+// Weekday 返回中文星期名称，例如 "星期二"。
+func Weekday(t time.Time) string {
+	return "星期" + weekdays[t.Weekday()]
+}
@@ -0,0 +1,156 @@
+package clock
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/storage"
+)
+
+func TestParse(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("时区数据不可用")
+	}
+	// 2026-03-11 是周三
+	now := time.Date(2026, 3, 11, 10, 30, 0, 0, shanghai)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, shanghai)
+	}
+
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		{"now", now},
+		{"next Tuesday 3pm", at(3, 17, 15, 0)},
+		{"tuesday", at(3, 17, 0, 0)},
+		{"Wednesday 9am", at(3, 11, 9, 0)},
+		{"next wednesday", at(3, 18, 0, 0)},
+		{"last friday", at(3, 6, 0, 0)},
+		{"tomorrow at 9:15", at(3, 12, 9, 15)},
+		{"day after tomorrow noon", at(3, 13, 12, 0)},
+		{"tonight 8:00", at(3, 11, 20, 0)},
+		{"in 2 hours", at(3, 11, 12, 30)},
+		{"in half an hour", at(3, 11, 11, 0)},
+		{"30 minutes later", at(3, 11, 11, 0)},
+		{"2 days ago", at(3, 9, 10, 30)},
+		{"+1h30m", at(3, 11, 12, 0)},
+		{"明天下午3点", at(3, 12, 15, 0)},
+		{"下周二 15:00", at(3, 17, 15, 0)},
+		{"周一", at(3, 16, 0, 0)},
+		{"本周一", at(3, 9, 0, 0)},
+		{"晚上8点半", at(3, 11, 20, 30)},
+		{"中午12点", at(3, 11, 12, 0)},
+		{"3小时后", at(3, 11, 13, 30)},
+		{"半小时后", at(3, 11, 11, 0)},
+		{"3月20日 上午十点", at(3, 20, 10, 0)},
+		{"2026-04-01 09:00", at(4, 1, 9, 0)},
+		{"2026-04-01", at(4, 1, 0, 0)},
+		{"2026-03-11T08:00:00Z", time.Date(2026, 3, 11, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.text, now)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.text, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	for _, text := range []string{"", "sometime", "25:00", "2026-02-30", "tomorrow banana"} {
+		if got, err := Parse(text, now); err == nil {
+			t.Errorf("Parse(%q) = %v, want error", text, got)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	loc, err := LoadLocation("UTC+8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Format(time.Date(2026, 3, 11, 2, 30, 0, 0, time.UTC), loc)
+	if want := "2026-03-11 10:30 星期三 (UTC+08:00)"; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+	if _, err := LoadLocation("Mars/Olympus"); err == nil {
+		t.Error("expected error for an unknown timezone")
+	}
+}
+
+func TestManager(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "clock.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	tokyo := time.FixedZone("UTC+09:00", 9*3600)
+	m := NewManager(time.UTC, map[string]*time.Location{"line": tokyo}, store, nil)
+
+	if got := m.Current("feishu", "c1"); got != time.UTC {
+		t.Errorf("Current() = %v, want the global default", got)
+	}
+	if got := m.Current("line", "c1"); got != tokyo {
+		t.Errorf("Current() = %v, want the channel default", got)
+	}
+
+	if _, err := m.Select("feishu", "", "UTC+8"); err != nil {
+		t.Fatalf("Select(channel) error = %v", err)
+	}
+	if _, err := m.Select("feishu", "c1", "-05:00"); err != nil {
+		t.Fatalf("Select(session) error = %v", err)
+	}
+	if got := m.Current("feishu", "c1").String(); got != "UTC-05:00" {
+		t.Errorf("Current() = %v, want the session timezone", got)
+	}
+	if got := m.Current("feishu", "c2").String(); got != "UTC+08:00" {
+		t.Errorf("other session Current() = %v, want the channel timezone", got)
+	}
+
+	if _, err := m.Select("feishu", "c1", ""); err != nil {
+		t.Fatalf("Select(clear) error = %v", err)
+	}
+	if got := m.Current("feishu", "c1").String(); got != "UTC+08:00" {
+		t.Errorf("Current() after clear = %v, want the channel timezone", got)
+	}
+	if _, err := m.Select("feishu", "c1", "Nowhere/City"); err == nil {
+		t.Error("expected error for an unknown timezone")
+	}
+}
+
+func TestHours(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2026, 1, 1, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		hours string
+		t     time.Time
+		want  bool
+	}{
+		{"09:00-18:00", at(9, 0), true},
+		{"09:00-18:00", at(18, 0), false},
+		{"09:00-18:00", at(8, 59), false},
+		{"22:00-06:00", at(23, 0), true},
+		{"22:00-06:00", at(5, 59), true},
+		{"22:00-06:00", at(12, 0), false},
+		{"00:00-24:00", at(23, 59), true},
+	}
+	for _, tt := range tests {
+		h, err := ParseHours(tt.hours)
+		if err != nil {
+			t.Fatalf("ParseHours(%q) error = %v", tt.hours, err)
+		}
+		if got := h.Contains(tt.t); got != tt.want {
+			t.Errorf("Contains(%s, %s) = %v, want %v", tt.hours, tt.t.Format("15:04"), got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"09:00", "9-18", "08:00-08:00", "25:00-06:00"} {
+		if _, err := ParseHours(s); err == nil {
+			t.Errorf("ParseHours(%q) should fail", s)
+		}
+	}
+}
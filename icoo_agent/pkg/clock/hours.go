@@ -0,0 +1,54 @@
+package clock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Hours 一天中的时段，如 09:00-18:00；结束早于开始时跨午夜，如 22:00-08:00。
+type Hours struct {
+	from, to int // 距零点的分钟数
+}
+
+// ParseHours 解析 HH:MM-HH:MM 时段，24:00 表示当天结束。
+func ParseHours(s string) (Hours, error) {
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return Hours{}, fmt.Errorf("格式应为 HH:MM-HH:MM: %s", s)
+	}
+	from, err := minuteOfDay(start)
+	if err != nil {
+		return Hours{}, err
+	}
+	to, err := minuteOfDay(end)
+	if err != nil {
+		return Hours{}, err
+	}
+	if from == to {
+		return Hours{}, fmt.Errorf("开始和结束时间相同: %s", s)
+	}
+	return Hours{from: from, to: to}, nil
+}
+
+// Contains 判断时间是否在 [开始, 结束) 时段内，按 t 自身的时区取钟点。
+func (h Hours) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if h.from < h.to {
+		return m >= h.from && m < h.to
+	}
+	return m >= h.from || m < h.to
+}
+
+// minuteOfDay 解析 HH:MM，返回距零点的分钟数，24:00 表示当天结束。
+func minuteOfDay(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式应为 HH:MM: %s", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,85 @@
+package clock
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/storage"
+)
+
+// Manager 跟踪用户时区。
+//
+// 时区保存在会话绑定中：会话级设置优先，其次渠道级设置，再次配置中的渠道默认时区，最后为全局默认时区。
+type Manager struct {
+	def      *time.Location
+	channels map[string]*time.Location
+	storage  *storage.Storage
+	logger   *slog.Logger
+}
+
+// NewManager 创建时区管理器，def 为全局默认时区，channels 为各渠道的默认时区。
+func NewManager(def *time.Location, channels map[string]*time.Location, s *storage.Storage, logger *slog.Logger) *Manager {
+	if def == nil {
+		def = time.Local
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Manager{def: def, channels: channels, storage: s, logger: logger}
+}
+
+// Default 返回渠道的默认时区，未配置渠道时区时为全局默认时区。
+func (m *Manager) Default(channel string) *time.Location {
+	if loc, ok := m.channels[channel]; ok {
+		return loc
+	}
+	return m.def
+}
+
+// Select 设置会话的时区，sessionID 为空时设置整个渠道的时区。name 为空表示清除设置。
+func (m *Manager) Select(channel, sessionID, name string) (*time.Location, error) {
+	if m.storage == nil {
+		return nil, errors.New("未配置存储")
+	}
+
+	var loc *time.Location
+	if name != "" {
+		var err error
+		if loc, err = LoadLocation(name); err != nil {
+			return nil, err
+		}
+		name = loc.String()
+	}
+
+	if err := m.storage.Binding().SetTimezone(channel, sessionID, name); err != nil {
+		return nil, err
+	}
+	return loc, nil
+}
+
+// Current 获取会话当前生效的时区。
+// 绑定的时区无法加载时（例如系统时区数据库变化）忽略该设置。
+func (m *Manager) Current(channel, sessionID string) *time.Location {
+	if m.storage != nil {
+		for _, sid := range []string{sessionID, ""} {
+			b, err := m.storage.Binding().GetBinding(channel, sid)
+			if err != nil {
+				if !errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+					m.logger.With("name", "【时区】").Warn("获取时区绑定失败", "channel", channel, "session_id", sid, "error", err)
+				}
+				continue
+			}
+			if b.Timezone == "" {
+				continue
+			}
+			loc, err := LoadLocation(b.Timezone)
+			if err == nil {
+				return loc
+			}
+			m.logger.With("name", "【时区】").Warn("绑定的时区无效，已忽略", "timezone", b.Timezone, "session_id", sid)
+		}
+	}
+	return m.Default(channel)
+}
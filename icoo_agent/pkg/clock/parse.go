@@ -0,0 +1,408 @@
+package clock
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// absoluteLayouts 带完整日期时间的格式，按 now 所在时区解析
+var absoluteLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+}
+
+var (
+	// 相对时间："in 2 hours"、"30 minutes later"、"2 days ago"
+	reInUnit    = regexp.MustCompile(`^in\s+(\d+|an?|half an?)\s*([a-z]+)$`)
+	reUnitLater = regexp.MustCompile(`^(\d+|an?)\s*([a-z]+)\s+(later|from now|ago)$`)
+	// 相对时间："3小时后"、"半小时后"、"2天前"
+	reCNRelative = regexp.MustCompile(`^([\d零一二两三四五六七八九十]+|半)\s*个?\s*(秒钟?|分钟|小时|钟头|天|周|星期|礼拜)\s*(后|之后|以后|前|之前|以前)$`)
+
+	// 日期
+	reISODate   = regexp.MustCompile(`(\d{4})[-/](\d{1,2})[-/](\d{1,2})`)
+	reCNDate    = regexp.MustCompile(`(?:(\d{4})年)?(\d{1,2})月(\d{1,2})[日号]`)
+	reDayAfter  = regexp.MustCompile(`\bday after tomorrow\b`)
+	reDayWord   = regexp.MustCompile(`\b(today|tonight|tomorrow|tmr|yesterday)\b`)
+	reCNDayWord = regexp.MustCompile(`大后天|后天|明天|明日|今天|今日|昨天|昨日|前天`)
+	reWeekday   = regexp.MustCompile(`\b(?:(next|this|last)\s+)?(monday|mon|tuesday|tues|tue|wednesday|wed|thursday|thurs|thu|friday|fri|saturday|sat|sunday|sun)\b`)
+	reCNWeekday = regexp.MustCompile(`(下下|下|这|本|上)?个?(?:周|星期|礼拜)([一二三四五六日天1-7])`)
+
+	// 时刻
+	reClock   = regexp.MustCompile(`(凌晨|早上|早晨|上午|中午|下午|傍晚|晚上|今晚)?\s*\b(\d{1,2}):(\d{2})(?::(\d{2}))?\s*(am|pm|a\.m\.|p\.m\.)?`)
+	reAMPM    = regexp.MustCompile(`\b(\d{1,2})\s*(am|pm|a\.m\.|p\.m\.)`)
+	reNoon    = regexp.MustCompile(`\b(noon|midnight)\b`)
+	reCNClock = regexp.MustCompile(`(凌晨|早上|早晨|上午|中午|下午|傍晚|晚上|今晚)?\s*([\d零一二两三四五六七八九十]+)\s*[点时](?:\s*(半|一刻|三刻|[\d零一二三四五六七八九十]+)\s*分?)?`)
+
+	// reFiller 日期和时刻之间允许出现的连接词
+	reFiller = regexp.MustCompile(`\b(at|on|the)\b|[,，的在]`)
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tues": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thurs": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var cnWeekdays = map[string]time.Weekday{
+	"一": time.Monday, "二": time.Tuesday, "三": time.Wednesday, "四": time.Thursday,
+	"五": time.Friday, "六": time.Saturday, "日": time.Sunday, "天": time.Sunday,
+	"1": time.Monday, "2": time.Tuesday, "3": time.Wednesday, "4": time.Thursday,
+	"5": time.Friday, "6": time.Saturday, "7": time.Sunday,
+}
+
+var cnDayOffsets = map[string]int{
+	"前天": -2, "昨天": -1, "昨日": -1, "今天": 0, "今日": 0,
+	"明天": 1, "明日": 1, "后天": 2, "大后天": 3,
+}
+
+// Parse 解析时间文本，相对时间以 now 为基准，没有显式时区的时间按 now 所在时区理解。
+//
+// 支持的写法：
+//   - 绝对时间：RFC3339、2006-01-02 15:04、2006/01/02、3月10日
+//   - 相对时间：now、in 2 hours、30 minutes later、3小时后、半小时后、+1h30m
+//   - 日期词：today、tomorrow、day after tomorrow、next tuesday、明天、后天、下周二
+//   - 时刻：15:00、3pm、3:30 pm、noon、下午3点、晚上8点半、15点20分
+//
+// 日期和时刻可以组合，例如 "next Tuesday 3pm"、"明天下午3点"。只有日期时为当天 00:00，只有时刻时为今天。
+// "Tuesday"、"周二" 指今天起最近的一个周二，"next Tuesday" 指今天之后的下一个周二，
+// "下周二" 指下一个自然周（周一开始）的周二。
+func Parse(text string, now time.Time) (time.Time, error) {
+	s := strings.ToLower(strings.TrimSpace(text))
+	if s == "" {
+		return time.Time{}, fmt.Errorf("时间不能为空")
+	}
+	loc := now.Location()
+
+	switch s {
+	case "now", "现在", "此刻", "立即", "马上":
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(s)); err == nil {
+		return t, nil
+	}
+	for _, layout := range absoluteLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		if d, err := time.ParseDuration(s); err == nil {
+			return now.Add(d), nil
+		}
+	}
+	if t, ok := parseRelative(s, now); ok {
+		return t, nil
+	}
+
+	rest := s
+	date, hasDate, err := parseDate(&rest, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	hour, minute, second, hasClock, err := parseClock(&rest)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if strings.TrimSpace(reFiller.ReplaceAllString(rest, "")) != "" || (!hasDate && !hasClock) {
+		return time.Time{}, fmt.Errorf("无法解析时间 %q", text)
+	}
+	// "tonight 8" 这类写法默认晚上
+	if hasClock && hour < 12 && (strings.Contains(s, "tonight") || strings.Contains(s, "今晚")) {
+		hour += 12
+	}
+	if !hasDate {
+		date = now
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, second, 0, loc), nil
+}
+
+// parseRelative 解析相对当前时间的写法。
+func parseRelative(s string, now time.Time) (time.Time, bool) {
+	if m := reInUnit.FindStringSubmatch(s); m != nil {
+		if d, ok := unitDuration(m[1], m[2]); ok {
+			return now.Add(d), true
+		}
+	}
+	if m := reUnitLater.FindStringSubmatch(s); m != nil {
+		if d, ok := unitDuration(m[1], m[2]); ok {
+			if m[3] == "ago" {
+				d = -d
+			}
+			return now.Add(d), true
+		}
+	}
+	if m := reCNRelative.FindStringSubmatch(s); m != nil {
+		var d time.Duration
+		if m[1] == "半" {
+			d = cnUnit(m[2]) / 2
+		} else if n, ok := cnNumber(m[1]); ok {
+			d = time.Duration(n) * cnUnit(m[2])
+		} else {
+			return time.Time{}, false
+		}
+		if strings.HasSuffix(m[3], "前") {
+			d = -d
+		}
+		return now.Add(d), true
+	}
+	return time.Time{}, false
+}
+
+// unitDuration 将英文数量和单位转换为时长，"a"、"an" 表示 1，"half a" 表示一半。
+func unitDuration(count, unit string) (time.Duration, bool) {
+	var base time.Duration
+	switch strings.TrimSuffix(unit, "s") {
+	case "second", "sec":
+		base = time.Second
+	case "minute", "min":
+		base = time.Minute
+	case "hour", "hr", "h":
+		base = time.Hour
+	case "day", "d":
+		base = 24 * time.Hour
+	case "week", "wk", "w":
+		base = 7 * 24 * time.Hour
+	default:
+		return 0, false
+	}
+	switch count {
+	case "a", "an":
+		return base, true
+	case "half a", "half an":
+		return base / 2, true
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(n) * base, true
+}
+
+// cnUnit 返回中文时间单位对应的时长。
+func cnUnit(unit string) time.Duration {
+	switch unit {
+	case "秒", "秒钟":
+		return time.Second
+	case "分钟":
+		return time.Minute
+	case "小时", "钟头":
+		return time.Hour
+	case "天":
+		return 24 * time.Hour
+	default: // 周、星期、礼拜
+		return 7 * 24 * time.Hour
+	}
+}
+
+// parseDate 从 rest 中找出日期部分并移除，返回当天零点所在的日期。
+func parseDate(rest *string, now time.Time) (time.Time, bool, error) {
+	take := func(re *regexp.Regexp) []string {
+		loc := re.FindStringSubmatchIndex(*rest)
+		if loc == nil {
+			return nil
+		}
+		m := submatches(*rest, loc)
+		*rest = (*rest)[:loc[0]] + " " + (*rest)[loc[1]:]
+		return m
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	ymd := func(y, mo, d string) (time.Time, bool, error) {
+		year := now.Year()
+		if y != "" {
+			year, _ = strconv.Atoi(y)
+		}
+		month, _ := strconv.Atoi(mo)
+		day, _ := strconv.Atoi(d)
+		t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, now.Location())
+		if month < 1 || month > 12 || t.Day() != day {
+			return time.Time{}, false, fmt.Errorf("无效的日期 %d-%d-%d", year, month, day)
+		}
+		return t, true, nil
+	}
+
+	if m := take(reISODate); m != nil {
+		return ymd(m[1], m[2], m[3])
+	}
+	if m := take(reCNDate); m != nil {
+		return ymd(m[1], m[2], m[3])
+	}
+	if take(reDayAfter) != nil {
+		return today.AddDate(0, 0, 2), true, nil
+	}
+	if m := take(reDayWord); m != nil {
+		switch m[1] {
+		case "tomorrow", "tmr":
+			return today.AddDate(0, 0, 1), true, nil
+		case "yesterday":
+			return today.AddDate(0, 0, -1), true, nil
+		}
+		return today, true, nil
+	}
+	if m := take(reCNDayWord); m != nil {
+		return today.AddDate(0, 0, cnDayOffsets[m[0]]), true, nil
+	}
+	if m := take(reWeekday); m != nil {
+		wd := weekdayNames[m[2]]
+		ahead := (int(wd) - int(now.Weekday()) + 7) % 7
+		switch m[1] {
+		case "next":
+			ahead = (int(wd)-int(now.Weekday())+6)%7 + 1
+		case "last":
+			ahead = -((int(now.Weekday())-int(wd)+6)%7 + 1)
+		}
+		return today.AddDate(0, 0, ahead), true, nil
+	}
+	if m := take(reCNWeekday); m != nil {
+		wd := cnWeekdays[m[2]]
+		// 中文的"下周"按自然周计算，周一为一周的第一天
+		idx := func(d time.Weekday) int { return (int(d) + 6) % 7 }
+		diff := idx(wd) - idx(now.Weekday())
+		switch m[1] {
+		case "":
+			diff = (int(wd) - int(now.Weekday()) + 7) % 7
+		case "下":
+			diff += 7
+		case "下下":
+			diff += 14
+		case "上":
+			diff -= 7
+		}
+		return today.AddDate(0, 0, diff), true, nil
+	}
+	return time.Time{}, false, nil
+}
+
+// parseClock 从 rest 中找出时刻部分并移除。
+func parseClock(rest *string) (hour, minute, second int, ok bool, err error) {
+	var period, meridiem string
+	if loc := reClock.FindStringSubmatchIndex(*rest); loc != nil {
+		m := submatches(*rest, loc)
+		period, meridiem = m[1], m[5]
+		hour, _ = strconv.Atoi(m[2])
+		minute, _ = strconv.Atoi(m[3])
+		if m[4] != "" {
+			second, _ = strconv.Atoi(m[4])
+		}
+		*rest = (*rest)[:loc[0]] + " " + (*rest)[loc[1]:]
+	} else if loc := reAMPM.FindStringSubmatchIndex(*rest); loc != nil {
+		m := submatches(*rest, loc)
+		hour, _ = strconv.Atoi(m[1])
+		meridiem = m[2]
+		*rest = (*rest)[:loc[0]] + " " + (*rest)[loc[1]:]
+	} else if loc := reNoon.FindStringSubmatchIndex(*rest); loc != nil {
+		if submatches(*rest, loc)[1] == "noon" {
+			hour = 12
+		}
+		*rest = (*rest)[:loc[0]] + " " + (*rest)[loc[1]:]
+	} else if loc := reCNClock.FindStringSubmatchIndex(*rest); loc != nil {
+		m := submatches(*rest, loc)
+		period = m[1]
+		var valid bool
+		if hour, valid = cnNumber(m[2]); !valid {
+			return 0, 0, 0, false, fmt.Errorf("无效的时刻 %q", m[0])
+		}
+		switch m[3] {
+		case "":
+		case "半":
+			minute = 30
+		case "一刻":
+			minute = 15
+		case "三刻":
+			minute = 45
+		default:
+			if minute, valid = cnNumber(m[3]); !valid {
+				return 0, 0, 0, false, fmt.Errorf("无效的时刻 %q", m[0])
+			}
+		}
+		*rest = (*rest)[:loc[0]] + " " + (*rest)[loc[1]:]
+	} else {
+		return 0, 0, 0, false, nil
+	}
+
+	switch strings.ReplaceAll(meridiem, ".", "") {
+	case "am":
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 12 {
+			hour += 12
+		}
+	}
+	switch period {
+	case "下午", "傍晚", "晚上", "今晚":
+		if hour < 12 {
+			hour += 12
+		}
+	case "中午":
+		if hour < 11 {
+			hour += 12
+		}
+	case "凌晨", "早上", "早晨", "上午":
+		if hour == 12 {
+			hour = 0
+		}
+	}
+	if hour > 23 || minute > 59 || second > 59 {
+		return 0, 0, 0, false, fmt.Errorf("无效的时刻 %02d:%02d", hour, minute)
+	}
+	return hour, minute, second, true, nil
+}
+
+// submatches 按 FindStringSubmatchIndex 的结果取出子匹配，未匹配的分组为空串。
+func submatches(s string, loc []int) []string {
+	m := make([]string, len(loc)/2)
+	for i := range m {
+		if loc[2*i] >= 0 {
+			m[i] = s[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return m
+}
+
+// cnNumber 解析阿拉伯数字或 0-99 的中文数字。
+func cnNumber(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	digits := map[rune]int{'零': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+	runes := []rune(s)
+	switch {
+	case len(runes) == 1 && runes[0] == '十':
+		return 10, true
+	case len(runes) == 1:
+		n, ok := digits[runes[0]]
+		return n, ok
+	}
+	tens, ones, ok := strings.Cut(s, "十")
+	if !ok {
+		return 0, false
+	}
+	n := 10
+	if tens != "" {
+		t, ok := digits[[]rune(tens)[0]]
+		if !ok || len([]rune(tens)) != 1 {
+			return 0, false
+		}
+		n = t * 10
+	}
+	if ones != "" {
+		o, ok := digits[[]rune(ones)[0]]
+		if !ok || len([]rune(ones)) != 1 {
+			return 0, false
+		}
+		n += o
+	}
+	return n, true
+}
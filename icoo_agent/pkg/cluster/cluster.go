@@ -0,0 +1,146 @@
+// Package cluster 协调共享同一数据库的多个实例：通过存储中的租约锁选出主实例，
+// 定时任务和后台任务只在主实例上运行。主实例宕机后租约过期，其他实例自动接管。
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"icooclaw/pkg/storage"
+)
+
+// LeaderLock 选主使用的锁名称
+const LeaderLock = "leader"
+
+// DefaultLeaseTTL 默认租约时长
+const DefaultLeaseTTL = 30 * time.Second
+
+// Node 集群中的一个实例。
+//
+// 主实例每隔租约时长的三分之一续约一次；续约失败（如数据库暂时不可用）时，
+// 在本地记录的租约到期前仍视为主实例，到期后即放弃，保证其他实例接管前本实例已停止执行任务。
+type Node struct {
+	id     string
+	locks  *storage.LockStorage
+	ttl    time.Duration
+	logger *slog.Logger
+
+	leader     atomic.Bool
+	leaseUntil atomic.Int64 // 本地记录的租约到期时间（UnixNano）
+
+	mu       sync.Mutex
+	onChange []func(leader bool)
+}
+
+// NewNode 创建集群实例，id 为空时使用 主机名-进程号-随机串。
+func NewNode(id string, locks *storage.LockStorage, ttl time.Duration, logger *slog.Logger) *Node {
+	if id == "" {
+		id = DefaultID()
+	}
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Node{id: id, locks: locks, ttl: ttl, logger: logger}
+}
+
+// DefaultID 生成默认的实例 ID。
+func DefaultID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "icooclaw"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+// ID 返回实例 ID。
+func (n *Node) ID() string {
+	return n.id
+}
+
+// IsLeader 判断本实例当前是否为主实例。未启用集群（n 为 nil）时总是返回 true。
+func (n *Node) IsLeader() bool {
+	if n == nil {
+		return true
+	}
+	return n.leader.Load() && time.Now().UnixNano() < n.leaseUntil.Load()
+}
+
+// OnChange 注册主实例身份变化的回调。
+func (n *Node) OnChange(fn func(leader bool)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.onChange = append(n.onChange, fn)
+}
+
+// Run 参与选主并定期续约，直到 ctx 取消；退出时释放持有的租约，其他实例可立即接管。
+func (n *Node) Run(ctx context.Context) {
+	n.logger.With("name", "【集群】").Info("已加入集群", "instance_id", n.id, "lease_ttl", n.ttl)
+
+	n.renew()
+
+	ticker := time.NewTicker(n.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if n.leader.Load() {
+				if err := n.locks.Release(LeaderLock, n.id); err != nil {
+					n.logger.With("name", "【集群】").Warn("释放主实例租约失败", "error", err)
+				}
+			}
+			n.setLeader(false)
+			return
+		case <-ticker.C:
+			n.renew()
+		}
+	}
+}
+
+// renew 获取或续约主实例租约。
+func (n *Node) renew() {
+	start := time.Now()
+	ok, err := n.locks.Acquire(LeaderLock, n.id, n.ttl)
+	if err != nil {
+		// 无法确认租约状态，本地租约到期前保持现状
+		n.logger.With("name", "【集群】").Warn("续约主实例租约失败", "error", err)
+		if n.leader.Load() && time.Now().UnixNano() >= n.leaseUntil.Load() {
+			n.setLeader(false)
+		}
+		return
+	}
+	if ok {
+		n.leaseUntil.Store(start.Add(n.ttl).UnixNano())
+	}
+	n.setLeader(ok)
+}
+
+// setLeader 更新主实例身份，变化时记录日志并通知回调。
+func (n *Node) setLeader(leader bool) {
+	if n.leader.Swap(leader) == leader {
+		return
+	}
+	if leader {
+		n.logger.With("name", "【集群】").Info("本实例成为主实例", "instance_id", n.id)
+	} else {
+		n.leaseUntil.Store(0)
+		n.logger.With("name", "【集群】").Info("本实例不再是主实例", "instance_id", n.id)
+	}
+
+	n.mu.Lock()
+	callbacks := n.onChange
+	n.mu.Unlock()
+	for _, fn := range callbacks {
+		fn(leader)
+	}
+}
@@ -0,0 +1,77 @@
+package cluster
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/storage"
+)
+
+func TestLeaderElection(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "cluster.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	ttl := 300 * time.Millisecond
+	a := NewNode("a", store.Lock(), ttl, nil)
+	b := NewNode("b", store.Lock(), ttl, nil)
+
+	// a 先取得租约，b 只能等待
+	a.renew()
+	b.renew()
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("leader a=%v b=%v, want only a", a.IsLeader(), b.IsLeader())
+	}
+	a.renew()
+	if !a.IsLeader() {
+		t.Fatal("renewing should keep a as leader")
+	}
+
+	// a 停止续约（模拟宕机），租约过期后 b 接管，a 在本地租约到期后也不再认为自己是主实例
+	time.Sleep(ttl + 50*time.Millisecond)
+	if a.IsLeader() {
+		t.Error("a should step down once its lease expires")
+	}
+	b.renew()
+	if !b.IsLeader() {
+		t.Fatal("b should take over the expired lease")
+	}
+	if lock, err := store.Lock().Get(LeaderLock); err != nil || lock.Owner != "b" {
+		t.Errorf("lock = %+v, %v", lock, err)
+	}
+	a.renew()
+	if a.IsLeader() {
+		t.Error("a must not reclaim a lease held by b")
+	}
+
+	// b 正常退出时释放租约，a 立即接管
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	changes := make(chan bool, 4)
+	b.OnChange(func(leader bool) { changes <- leader })
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+	if b.IsLeader() {
+		t.Error("b should not be leader after Run returns")
+	}
+	if got := <-changes; got {
+		t.Errorf("OnChange(%v), want false", got)
+	}
+	a.renew()
+	if !a.IsLeader() {
+		t.Error("a should acquire the released lease")
+	}
+
+	var nilNode *Node
+	if !nilNode.IsLeader() {
+		t.Error("a nil node runs everything")
+	}
+}
@@ -0,0 +1,168 @@
+// Package command provides a shared slash-command framework for channels.
+//
+// 以 "/" 开头的消息在进入智能体之前先由命令注册表解析，
+// 命中的命令直接返回结果，不再调用 LLM。
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"icooclaw/pkg/bus"
+)
+
+// namePattern 校验命令名称：小写字母开头，字母数字与下划线
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// Context 命令执行上下文。
+type Context struct {
+	Msg      bus.InboundMessage // 原始消息
+	Name     string             // 命令名称
+	Args     []string           // 命令参数
+	Registry *Registry          // 命令注册表
+}
+
+// Arg 获取第 i 个参数，不存在时返回空字符串。
+func (c *Context) Arg(i int) string {
+	if i < 0 || i >= len(c.Args) {
+		return ""
+	}
+	return c.Args[i]
+}
+
+// Handler 命令处理函数，返回回复内容。
+type Handler func(ctx context.Context, c *Context) (string, error)
+
+// Command 斜杠命令定义。
+type Command struct {
+	Name        string   `json:"name"`        // 命令名称（不含 /）
+	Description string   `json:"description"` // 命令描述
+	Usage       string   `json:"usage"`       // 参数说明
+	Channels    []string `json:"channels"`    // 可用渠道，为空表示全部渠道
+	Handler     Handler  `json:"-"`           // 处理函数
+}
+
+// AvailableIn 判断命令在指定渠道是否可用。
+func (c *Command) AvailableIn(channel string) bool {
+	return len(c.Channels) == 0 || slices.Contains(c.Channels, channel)
+}
+
+// Registry 命令注册表。
+type Registry struct {
+	commands map[string]*Command
+	logger   *slog.Logger
+	mu       sync.RWMutex
+}
+
+// NewRegistry 创建命令注册表。
+func NewRegistry(logger *slog.Logger) *Registry {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Registry{
+		commands: make(map[string]*Command),
+		logger:   logger,
+	}
+}
+
+// Register 注册命令，同名命令会被覆盖。
+func (r *Registry) Register(cmd *Command) error {
+	if cmd == nil || cmd.Handler == nil {
+		return fmt.Errorf("command handler is required")
+	}
+	if !namePattern.MatchString(cmd.Name) {
+		return fmt.Errorf("invalid command name: %s", cmd.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.commands[cmd.Name]; ok {
+		r.logger.With("name", "【命令】").Warn("命令已存在，将被覆盖", "command", cmd.Name)
+	}
+	r.commands[cmd.Name] = cmd
+	return nil
+}
+
+// Unregister 注销命令。
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.commands, name)
+}
+
+// Get 获取命令。
+func (r *Registry) Get(name string) (*Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.commands[name]
+	return cmd, ok
+}
+
+// List 列出指定渠道可用的命令，channel 为空时列出全部命令。
+func (r *Registry) List(channel string) []*Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		if channel == "" || cmd.AvailableIn(channel) {
+			list = append(list, cmd)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Parse 解析斜杠命令，支持 /name@bot 形式。
+// 非命令消息返回 ok=false。
+func Parse(text string) (name string, args []string, ok bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil, false
+	}
+
+	name = strings.TrimPrefix(fields[0], "/")
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.ToLower(name)
+	if !namePattern.MatchString(name) {
+		return "", nil, false
+	}
+
+	return name, fields[1:], true
+}
+
+// Execute 解析并执行消息中的命令。
+// 返回回复内容和消息是否被命令处理。
+func (r *Registry) Execute(ctx context.Context, msg bus.InboundMessage) (string, bool) {
+	name, args, ok := Parse(msg.Text)
+	if !ok {
+		return "", false
+	}
+
+	cmd, ok := r.Get(name)
+	if !ok || !cmd.AvailableIn(msg.Channel) {
+		return fmt.Sprintf("未知命令: /%s，输入 /help 查看可用命令", name), true
+	}
+
+	reply, err := cmd.Handler(ctx, &Context{
+		Msg:      msg,
+		Name:     name,
+		Args:     args,
+		Registry: r,
+	})
+	if err != nil {
+		r.logger.With("name", "【命令】").Warn("执行命令失败", "command", name, "error", err)
+		return fmt.Sprintf("执行命令 /%s 失败: %v", name, err), true
+	}
+
+	return reply, true
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels/consts"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text     string
+		wantName string
+		wantArgs int
+		wantOK   bool
+	}{
+		{"/help", "help", 0, true},
+		{"  /model openai/gpt-4o ", "model", 1, true},
+		{"/Reset@icoo_bot keep", "reset", 1, true},
+		{"hello /help", "", 0, false},
+		{"/etc/hosts 是什么", "", 0, false},
+		{"/", "", 0, false},
+		{"", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		name, args, ok := Parse(tt.text)
+		if ok != tt.wantOK || name != tt.wantName || len(args) != tt.wantArgs {
+			t.Errorf("Parse(%q) = %q, %v, %v; want %q, %d args, %v",
+				tt.text, name, args, ok, tt.wantName, tt.wantArgs, tt.wantOK)
+		}
+	}
+}
+
+func TestRegistry_Execute(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Register(&Command{
+		Name: "echo",
+		Handler: func(ctx context.Context, c *Context) (string, error) {
+			return strings.Join(c.Args, " "), nil
+		},
+	})
+	r.Register(&Command{
+		Name:     "tg_only",
+		Channels: []string{consts.TELEGRAM},
+		Handler: func(ctx context.Context, c *Context) (string, error) {
+			return "ok", nil
+		},
+	})
+
+	reply, ok := r.Execute(context.Background(), bus.InboundMessage{Channel: consts.WEBSOCKET, Text: "/echo a b"})
+	if !ok || reply != "a b" {
+		t.Errorf("Execute(/echo) = %q, %v", reply, ok)
+	}
+
+	if _, ok := r.Execute(context.Background(), bus.InboundMessage{Text: "plain text"}); ok {
+		t.Error("Execute(plain text) should not be handled")
+	}
+
+	reply, ok = r.Execute(context.Background(), bus.InboundMessage{Channel: consts.WEBSOCKET, Text: "/tg_only"})
+	if !ok || !strings.Contains(reply, "未知命令") {
+		t.Errorf("Execute(/tg_only) on websocket = %q, %v", reply, ok)
+	}
+
+	if got := len(r.List(consts.WEBSOCKET)); got != 1 {
+		t.Errorf("List(websocket) = %d commands, want 1", got)
+	}
+}
+
+func TestRegistry_RegisterInvalid(t *testing.T) {
+	r := NewRegistry(nil)
+	if err := r.Register(&Command{Name: "Bad Name", Handler: func(context.Context, *Context) (string, error) { return "", nil }}); err == nil {
+		t.Error("Register() with invalid name should fail")
+	}
+	if err := r.Register(&Command{Name: "nohandler"}); err == nil {
+		t.Error("Register() without handler should fail")
+	}
+}
+
+func TestRender(t *testing.T) {
+	cmds := []*Command{
+		{Name: "model", Description: "切换模型", Usage: "[provider/model]"},
+		{Name: "help", Description: strings.Repeat("长", 300)},
+	}
+
+	tg, ok := Render(consts.TELEGRAM, cmds).([]TelegramCommand)
+	if !ok || len(tg) != 2 || len([]rune(tg[1].Description)) != telegramMaxDesc {
+		t.Errorf("Render(telegram) = %#v", tg)
+	}
+
+	dc, ok := Render(consts.DISCORD, cmds).([]DiscordCommand)
+	if !ok || len(dc[0].Options) != 1 || len(dc[1].Options) != 0 {
+		t.Errorf("Render(discord) = %#v", dc)
+	}
+
+	palette, ok := Render(consts.WEBSOCKET, cmds).([]PaletteItem)
+	if !ok || palette[0].Command != "/model" {
+		t.Errorf("Render(websocket) = %#v", palette)
+	}
+
+	text, ok := Render(consts.FEISHU, cmds).(string)
+	if !ok || !strings.Contains(text, "/model [provider/model]") {
+		t.Errorf("Render(feishu) = %q", text)
+	}
+}
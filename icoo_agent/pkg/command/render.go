@@ -0,0 +1,140 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/channels/consts"
+)
+
+// TelegramCommand Telegram setMyCommands 命令格式。
+type TelegramCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// DiscordOption Discord 应用命令参数。
+type DiscordOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// DiscordCommand Discord 应用命令格式。
+type DiscordCommand struct {
+	Type        int             `json:"type"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []DiscordOption `json:"options,omitempty"`
+}
+
+// PaletteItem WebSocket 命令面板条目。
+type PaletteItem struct {
+	Name        string `json:"name"`
+	Command     string `json:"command"`
+	Description string `json:"description"`
+	Usage       string `json:"usage,omitempty"`
+}
+
+const (
+	discordChatInput    = 1   // CHAT_INPUT 命令类型
+	discordStringOption = 3   // STRING 参数类型
+	discordMaxDesc      = 100 // Discord 描述最大长度
+	telegramMaxDesc     = 256 // Telegram 描述最大长度
+)
+
+// Render 按渠道渲染命令列表。
+//   - telegram: []TelegramCommand
+//   - discord: []DiscordCommand
+//   - websocket/web: []PaletteItem
+//   - 其他渠道: 文本列表
+func Render(channel string, cmds []*Command) any {
+	switch channel {
+	case consts.TELEGRAM:
+		return RenderTelegram(cmds)
+	case consts.DISCORD:
+		return RenderDiscord(cmds)
+	case consts.WEBSOCKET, consts.WEB:
+		return RenderPalette(cmds)
+	default:
+		return RenderText(cmds)
+	}
+}
+
+// RenderText 渲染为文本列表，用于 /help 及不支持命令菜单的渠道。
+func RenderText(cmds []*Command) string {
+	if len(cmds) == 0 {
+		return "暂无可用命令"
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("可用命令:\n")
+	for _, cmd := range cmds {
+		sb.WriteString(fmt.Sprintf("- /%s", cmd.Name))
+		if cmd.Usage != "" {
+			sb.WriteString(" " + cmd.Usage)
+		}
+		if cmd.Description != "" {
+			sb.WriteString(fmt.Sprintf(": %s", cmd.Description))
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// RenderTelegram 渲染为 Telegram setMyCommands 参数。
+func RenderTelegram(cmds []*Command) []TelegramCommand {
+	list := make([]TelegramCommand, 0, len(cmds))
+	for _, cmd := range cmds {
+		list = append(list, TelegramCommand{
+			Command:     cmd.Name,
+			Description: truncate(cmd.Description, telegramMaxDesc),
+		})
+	}
+	return list
+}
+
+// RenderDiscord 渲染为 Discord 应用命令定义。
+func RenderDiscord(cmds []*Command) []DiscordCommand {
+	list := make([]DiscordCommand, 0, len(cmds))
+	for _, cmd := range cmds {
+		dc := DiscordCommand{
+			Type:        discordChatInput,
+			Name:        cmd.Name,
+			Description: truncate(cmd.Description, discordMaxDesc),
+		}
+		if cmd.Usage != "" {
+			dc.Options = []DiscordOption{{
+				Type:        discordStringOption,
+				Name:        "args",
+				Description: truncate(cmd.Usage, discordMaxDesc),
+			}}
+		}
+		list = append(list, dc)
+	}
+	return list
+}
+
+// RenderPalette 渲染为 WebSocket 命令面板数据。
+func RenderPalette(cmds []*Command) []PaletteItem {
+	list := make([]PaletteItem, 0, len(cmds))
+	for _, cmd := range cmds {
+		list = append(list, PaletteItem{
+			Name:        cmd.Name,
+			Command:     "/" + cmd.Name,
+			Description: cmd.Description,
+			Usage:       cmd.Usage,
+		})
+	}
+	return list
+}
+
+// truncate 按字符截断描述。
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
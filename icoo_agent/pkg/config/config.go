@@ -2,12 +2,46 @@
 package config
 
 import (
+	"cmp"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"icooclaw/pkg/authz"
+	"icooclaw/pkg/clock"
 	"icooclaw/pkg/consts"
+	"icooclaw/pkg/digest"
+	"icooclaw/pkg/faq"
+	"icooclaw/pkg/form"
+	"icooclaw/pkg/gateway/auth"
+	"icooclaw/pkg/hooks"
+	"icooclaw/pkg/language"
+	"icooclaw/pkg/mail"
+	"icooclaw/pkg/memory"
+	"icooclaw/pkg/notice"
+	"icooclaw/pkg/postprocess"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/routing"
+	"icooclaw/pkg/scheduler"
+	"icooclaw/pkg/script"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools/builtin/file"
+	"icooclaw/pkg/tools/builtin/shell"
+	"icooclaw/pkg/tools/plugin"
+	"icooclaw/pkg/update"
+	"icooclaw/pkg/utils"
+	"icooclaw/pkg/vfs"
+	"icooclaw/pkg/workspace"
+	"net"
+	netmail "net/mail"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
+	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
 )
 
@@ -21,6 +55,211 @@ type Config struct {
 	Gateway  GatewayConfig  `mapstructure:"gateway"`  // 网关配置
 	Logging  LoggingConfig  `mapstructure:"logging"`  // 日志配置
 	Channels ChannelsConfig `mapstructure:"channels"` // 渠道配置
+	// PostProcess 回复后处理配置
+	PostProcess PostProcessConfig `mapstructure:"postprocess"`
+	// Routing 入站消息路由配置
+	Routing RoutingConfig `mapstructure:"routing"`
+	// Forms 表单配置
+	Forms FormsConfig `mapstructure:"forms"`
+	// Notices 系统提示模板配置
+	Notices NoticesConfig `mapstructure:"notices"`
+	// Cluster 多实例部署配置
+	Cluster ClusterConfig `mapstructure:"cluster"`
+	// Update 自更新配置
+	Update UpdateConfig `mapstructure:"update"`
+	// SMTP 发送邮件的 SMTP 配置
+	SMTP SMTPConfig `mapstructure:"smtp"`
+	// Digest 活动摘要邮件配置
+	Digest DigestConfig `mapstructure:"digest"`
+	// ToolServer 独立工具服务（icooclaw toolserver）配置
+	ToolServer ToolServerConfig `mapstructure:"toolserver"`
+	// Audit 工具调用审计配置
+	Audit AuditConfig `mapstructure:"audit"`
+}
+
+// AuditConfig contains the tool invocation audit trail configuration.
+type AuditConfig struct {
+	// Enabled 是否记录每次工具调用的审计日志
+	Enabled bool `mapstructure:"enabled"`
+	// File 同时追加写入的 JSON Lines 文件，为空时只写入数据库
+	File string `mapstructure:"file"`
+	// Keep 数据库中审计记录的保留时长，0 表示永久保留
+	Keep time.Duration `mapstructure:"keep"`
+	// Redact 额外的脱敏正则，参数和错误信息中匹配的内容替换为 [REDACTED]
+	Redact []string `mapstructure:"redact"`
+}
+
+// SMTPConfig contains the outgoing mail server configuration.
+type SMTPConfig struct {
+	// Host SMTP 服务器地址
+	Host string `mapstructure:"host"`
+	// Port SMTP 端口，STARTTLS 通常为 587，隐式 TLS 通常为 465
+	Port int `mapstructure:"port"`
+	// Username 认证用户名，为空时不认证
+	Username string `mapstructure:"username"`
+	// Password 认证密码
+	Password string `mapstructure:"password"`
+	// From 发件人，可以是 "名称 <地址>" 格式
+	From string `mapstructure:"from"`
+	// Security 连接加密方式 starttls、tls 或 none
+	Security string `mapstructure:"security"`
+	// Timeout 连接和发送的超时时间
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// Mail returns the mail client configuration.
+func (c SMTPConfig) Mail() mail.Config {
+	return mail.Config{
+		Host:     c.Host,
+		Port:     c.Port,
+		Username: c.Username,
+		Password: c.Password,
+		From:     c.From,
+		Security: c.Security,
+		Timeout:  c.Timeout,
+	}
+}
+
+// DigestConfig contains the scheduled activity digest configuration.
+type DigestConfig struct {
+	// Enabled 是否定期通过 SMTP 发送活动摘要邮件，需要同时启用 agent.trace
+	Enabled bool `mapstructure:"enabled"`
+	// Frequency 发送频率 daily 或 weekly
+	Frequency string `mapstructure:"frequency"`
+	// At 发送时间 HH:MM，摘要覆盖到该时间为止的一天或一周
+	At string `mapstructure:"at"`
+	// Weekday 每周发送时的星期，0 为周日
+	Weekday int `mapstructure:"weekday"`
+	// Timezone 发送时间的时区，为空使用本地时区
+	Timezone string `mapstructure:"timezone"`
+	// Recipients 收件人
+	Recipients []string `mapstructure:"recipients"`
+	// SkipEmpty 周期内没有对话也没有等待确认的请求时不发送
+	SkipEmpty bool `mapstructure:"skip_empty"`
+	// WebURL 网关的外部访问地址，如 https://claw.example.com，配置后摘要附带轨迹报告和管理界面链接
+	WebURL string `mapstructure:"web_url"`
+	// SessionLink 会话链接模板，{{channel}} 和 {{session_id}} 替换为会话的渠道和 ID
+	SessionLink string `mapstructure:"session_link"`
+	// MaxItems 每个列表最多列出的条数
+	MaxItems int `mapstructure:"max_items"`
+	// NotableTools 值得关注的工具，为空时使用默认列表（执行命令、写文件、网络请求等）
+	NotableTools []string `mapstructure:"notable_tools"`
+}
+
+// Schedule returns the parsed send schedule.
+func (c DigestConfig) Schedule() (digest.Schedule, error) {
+	loc, err := clock.LoadLocation(c.Timezone)
+	if err != nil {
+		return digest.Schedule{}, err
+	}
+	return digest.ParseSchedule(c.Frequency, c.At, c.Weekday, loc)
+}
+
+// Options returns the digest content options.
+func (c DigestConfig) Options() digest.Options {
+	return digest.Options{
+		MaxItems:     c.MaxItems,
+		NotableTools: c.NotableTools,
+		WebURL:       c.WebURL,
+		SessionLink:  c.SessionLink,
+	}
+}
+
+// UpdateConfig contains the self-update configuration.
+type UpdateConfig struct {
+	// Endpoint 发布端点，渠道清单位于 <endpoint>/<channel>.json
+	Endpoint string `mapstructure:"endpoint"`
+	// Channel 发布渠道 stable 或 beta
+	Channel string `mapstructure:"channel"`
+	// PublicKey base64 编码的 ed25519 公钥，用于校验发布文件的签名
+	PublicKey string `mapstructure:"public_key"`
+	// RestartCommand 替换可执行文件后重启服务的命令，如 systemctl restart icooclaw，为空时只提示手动重启
+	RestartCommand string `mapstructure:"restart_command"`
+}
+
+// ClusterConfig contains the multi-instance coordination configuration.
+type ClusterConfig struct {
+	// Enabled 多个实例共享同一数据库时启用，定时任务和全局后台任务只在选出的主实例上运行
+	Enabled bool `mapstructure:"enabled"`
+	// InstanceID 实例 ID，为空时使用 主机名-进程号-随机串
+	InstanceID string `mapstructure:"instance_id"`
+	// LeaseTTL 主实例租约时长，主实例宕机后最多经过该时长由其他实例接管
+	LeaseTTL time.Duration `mapstructure:"lease_ttl"`
+}
+
+// PostProcessConfig contains assistant output post-processing rules.
+type PostProcessConfig struct {
+	// Rules 按顺序执行的后处理规则
+	Rules []postprocess.Rule `mapstructure:"rules"`
+}
+
+// RoutingConfig contains inbound message routing rules.
+type RoutingConfig struct {
+	// Rules 按顺序匹配的路由规则，第一条匹配的规则生效
+	Rules []routing.Rule `mapstructure:"rules"`
+}
+
+// FormsConfig contains the slot filling form definitions.
+type FormsConfig struct {
+	// Expire 表单进度有效期，超过后自动退出表单，0 表示不过期
+	Expire time.Duration `mapstructure:"expire"`
+	// MaxAttempts 同一字段连续无效回答的次数上限，达到后退出表单，0 表示不限
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Flows 表单定义
+	Flows []form.Form `mapstructure:"flows"`
+}
+
+// Options returns the form runtime options.
+func (c FormsConfig) Options() form.Options {
+	return form.Options{Expire: c.Expire, MaxAttempts: c.MaxAttempts}
+}
+
+// NoticesConfig contains the templates of system messages sent without calling the model.
+type NoticesConfig struct {
+	// Language 用户语言没有对应模板时使用的语言，为空时使用 agent.reply_language，仍为空时为 zh
+	Language string `mapstructure:"language"`
+	// Templates 按提示类型、语言和渠道覆盖内置文本的模板
+	Templates []notice.Template `mapstructure:"templates"`
+}
+
+// ToolServerConfig contains the standalone tool server (icooclaw toolserver) configuration.
+// 工具服务只加载工具注册表，不启动智能体、提供商和渠道，工作目录、挂载和命令策略沿用 agent 配置。
+type ToolServerConfig struct {
+	// Addr REST 和 MCP（streamable HTTP）接口的监听地址
+	Addr string `mapstructure:"addr"`
+	// Tools 对外提供的工具，为空时提供全部工具
+	Tools []string `mapstructure:"tools"`
+	// ReadOnly 只读模式，修改文件或执行命令的调用只返回将要做出的修改
+	ReadOnly bool `mapstructure:"read_only"`
+	// Script JavaScript 工具（script、script_file）
+	Script ToolServerScriptConfig `mapstructure:"script"`
+}
+
+// ToolServerScriptConfig contains the JavaScript tools of the tool server.
+type ToolServerScriptConfig struct {
+	// Enabled 是否提供 JavaScript 工具
+	Enabled bool `mapstructure:"enabled"`
+	// AllowFileWrite 脚本是否可以写入工作目录中的文件
+	AllowFileWrite bool `mapstructure:"allow_file_write"`
+	// AllowNetwork 脚本是否可以发起 HTTP 请求
+	AllowNetwork bool `mapstructure:"allow_network"`
+	// AllowedDomains 允许请求的域名，为空时不限制
+	AllowedDomains []string `mapstructure:"allowed_domains"`
+	// AllowExec 脚本是否可以执行命令
+	AllowExec bool `mapstructure:"allow_exec"`
+}
+
+// ScriptConfig 返回 JavaScript 工具沙箱的权限配置，脚本始终可以读取工作目录中的文件，
+// kv 为脚本 kv 对象使用的存储，nil 时不提供 kv 对象。
+func (c ToolServerScriptConfig) ScriptConfig(workspace string, kv script.KVStore) *script.Config {
+	cfg := script.DefaultConfig()
+	cfg.Workspace = workspace
+	cfg.KV = kv
+	cfg.AllowFileWrite = c.AllowFileWrite
+	cfg.AllowNetwork = c.AllowNetwork
+	cfg.AllowedDomains = c.AllowedDomains
+	cfg.AllowExec = c.AllowExec
+	return cfg
 }
 
 // AgentConfig contains basic agent configuration.
@@ -28,29 +267,1104 @@ type AgentConfig struct {
 	Workspace       string              `mapstructure:"workspace"`
 	DefaultModel    string              `mapstructure:"default_model"`
 	DefaultProvider consts.ProviderType `mapstructure:"default_provider"`
+	// WorkspaceReadOnly default 工作目录是否只读
+	WorkspaceReadOnly bool `mapstructure:"workspace_read_only"`
+	// Workspaces 其他命名工作目录，会话可通过 /workspace 或接口切换，
+	// agent.workspace 为名为 default 的工作目录
+	Workspaces map[string]WorkspaceConfig `mapstructure:"workspaces"`
+	// Mounts 挂载到工作目录子路径的远程存储（S3、WebDAV），文件工具通过挂载路径读写
+	Mounts []MountConfig `mapstructure:"mounts"`
+	// SessionIdleTimeout 会话空闲超时，超时后自动摘要并归档，0 表示不启用
+	SessionIdleTimeout time.Duration `mapstructure:"session_idle_timeout"`
+	// SessionSweepInterval 空闲会话检查间隔
+	SessionSweepInterval time.Duration `mapstructure:"session_sweep_interval"`
+	// OfflineQueue 提供商不可达时将入站消息排队，恢复后自动处理
+	OfflineQueue bool `mapstructure:"offline_queue"`
+	// OfflineRetryInterval 离线期间检查提供商是否恢复的间隔
+	OfflineRetryInterval time.Duration `mapstructure:"offline_retry_interval"`
+	// OfflineReplayInterval 恢复后逐条处理积压消息的间隔
+	OfflineReplayInterval time.Duration `mapstructure:"offline_replay_interval"`
+	// WorkerPool 并发处理入站消息的工作池配置
+	WorkerPool WorkerPoolConfig `mapstructure:"worker_pool"`
+	// StatusInterval 工具执行超过该时长后周期发送进度心跳，0 表示不启用
+	StatusInterval time.Duration `mapstructure:"status_interval"`
+	// ToolNotes 根据工具近期失败情况在系统提示词中注入工具使用提示
+	ToolNotes bool `mapstructure:"tool_notes"`
+	// TurnBudget 单轮对话的时间预算，覆盖所有迭代和工具调用，超出后基于已有结果收尾，0 表示不限制
+	TurnBudget time.Duration `mapstructure:"turn_budget"`
+	// ToolRepeatLimit 本轮重复的相同只读工具调用直接返回缓存结果，重复该次数后提醒模型，0 表示不去重。
+	// 失败的调用不缓存，修改文件或状态的调用执行后清空缓存
+	ToolRepeatLimit int `mapstructure:"tool_repeat_limit"`
+	// ToolTimeout 单次工具执行的超时，超时后中止并提示模型，0 表示只受本轮时间预算限制
+	ToolTimeout time.Duration `mapstructure:"tool_timeout"`
+	// ToolTimeouts 按工具名称设置的超时，覆盖 ToolTimeout，0 表示该工具不限制
+	ToolTimeouts map[string]time.Duration `mapstructure:"tool_timeouts"`
+	// ToolCondense 冗长工具结果的压缩配置
+	ToolCondense ToolCondenseConfig `mapstructure:"tool_condense"`
+	// OptionalTools 可选工具，默认不提供给模型，只有会话工具策略 enable 中列出时才可用
+	OptionalTools []string `mapstructure:"optional_tools"`
+	// Admins 管理员发送者 ID，可使用修改渠道默认设置的斜杠命令，写作“渠道:ID”时只匹配该渠道
+	Admins []string `mapstructure:"admins"`
+	// Exec 命令执行工具的 shell 与环境变量配置
+	Exec ExecConfig `mapstructure:"exec"`
+	// Ignore 递归列出目录和 grep 搜索时跳过的文件
+	Ignore IgnoreConfig `mapstructure:"ignore"`
+	// Plugins 编译型插件工具配置
+	Plugins PluginsConfig `mapstructure:"plugins"`
+	// ProviderHealth 提供商健康检查与熔断配置
+	ProviderHealth ProviderHealthConfig `mapstructure:"provider_health"`
+	// ProviderRetry 提供商请求重试配置
+	ProviderRetry ProviderRetryConfig `mapstructure:"provider_retry"`
+	// MemoryDecay 记忆重要度评分与衰减配置
+	MemoryDecay MemoryDecayConfig `mapstructure:"memory_decay"`
+	// SemanticRecall 基于向量嵌入的记忆检索配置
+	SemanticRecall SemanticRecallConfig `mapstructure:"semantic_recall"`
+	// EntityGraph 实体图配置
+	EntityGraph EntityGraphConfig `mapstructure:"entity_graph"`
+	// MemoryDigest 记忆回顾摘要配置
+	MemoryDigest MemoryDigestConfig `mapstructure:"memory_digest"`
+	// Heartbeat 主动心跳配置
+	Heartbeat HeartbeatConfig `mapstructure:"heartbeat"`
+	// Trace 对话轨迹记录配置
+	Trace TraceConfig `mapstructure:"trace"`
+	// Ephemeral 无痕会话配置
+	Ephemeral EphemeralConfig `mapstructure:"ephemeral"`
+	// Authz 授权策略配置
+	Authz AuthzConfig `mapstructure:"authz"`
+	// ToolPermissions 工具权限规则配置
+	ToolPermissions ToolPermissionsConfig `mapstructure:"tool_permissions"`
+	// Hooks JavaScript 钩子脚本配置
+	Hooks HooksConfig `mapstructure:"hooks"`
+	// Jobs 批量后台作业配置
+	Jobs JobsConfig `mapstructure:"jobs"`
+	// FAQ 常见问题快速回复配置
+	FAQ FAQConfig `mapstructure:"faq"`
+	// CostPreview 调用模型前的用量预估配置
+	CostPreview CostPreviewConfig `mapstructure:"cost_preview"`
+	// AskUser 对话中向用户提出澄清问题的配置
+	AskUser AskUserConfig `mapstructure:"ask_user"`
+	// SkillTriggers 按触发规则自动激活技能的配置
+	SkillTriggers SkillTriggersConfig `mapstructure:"skill_triggers"`
+	// Prompt 系统提示词自动生成的片段
+	Prompt PromptConfig `mapstructure:"prompt"`
+	// ReplyLanguage 默认回复语言（如 zh、en），未识别出用户语言时使用，为空不约束
+	ReplyLanguage string `mapstructure:"reply_language"`
+	// ChannelTimezones 各渠道用户的默认时区，未配置的渠道使用 agent.prompt.timezone
+	ChannelTimezones map[string]string `mapstructure:"channel_timezones"`
+	// Templates 工作目录模板配置
+	Templates TemplatesConfig `mapstructure:"templates"`
+	// Diagram 图表渲染工具配置
+	Diagram DiagramConfig `mapstructure:"diagram"`
+	// Git git 工具配置
+	Git GitConfig `mapstructure:"git"`
+	// Documents 模板文档生成工具配置
+	Documents DocumentsConfig `mapstructure:"documents"`
+}
+
+// WorkspaceConfig contains a named workspace.
+type WorkspaceConfig struct {
+	// Path 工作目录路径
+	Path string `mapstructure:"path"`
+	// ReadOnly 只读挂载：写入、删除和执行命令的工具不执行，只向模型说明将做出的修改
+	ReadOnly bool `mapstructure:"read_only"`
+}
+
+// WorkspaceList returns the default workspace followed by the named workspaces.
+func (c AgentConfig) WorkspaceList() []workspace.Entry {
+	list := []workspace.Entry{{Name: workspace.DefaultName, Dir: c.Workspace, ReadOnly: c.WorkspaceReadOnly}}
+	for name, w := range c.Workspaces {
+		list = append(list, workspace.Entry{Name: name, Dir: w.Path, ReadOnly: w.ReadOnly})
+	}
+	return list
+}
+
+// ChannelLocations returns the configured default time zone of each channel.
+func (c AgentConfig) ChannelLocations() (map[string]*time.Location, error) {
+	locations := make(map[string]*time.Location, len(c.ChannelTimezones))
+	for channel, name := range c.ChannelTimezones {
+		loc, err := clock.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("渠道 %s: %w", channel, err)
+		}
+		locations[channel] = loc
+	}
+	return locations, nil
+}
+
+// MountConfig contains a remote storage mounted as a sub-path of every workspace.
+type MountConfig struct {
+	// Path 挂载到工作目录中的相对路径，例如 drive/reports
+	Path string `mapstructure:"path"`
+	// Type 存储类型：s3 或 webdav
+	Type string `mapstructure:"type"`
+	// URL WebDAV 共享地址，或 S3 服务地址（默认 AWS）
+	URL string `mapstructure:"url"`
+	// Bucket S3 存储桶
+	Bucket string `mapstructure:"bucket"`
+	// Prefix 挂载的 S3 对象前缀，为空时挂载整个存储桶
+	Prefix string `mapstructure:"prefix"`
+	// Region S3 区域，默认 us-east-1
+	Region string `mapstructure:"region"`
+	// PathStyle S3 使用路径形式的地址，MinIO 等自建服务通常需要开启
+	PathStyle bool `mapstructure:"path_style"`
+	// AccessKey、SecretKey S3 访问凭证
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	// Username、Password WebDAV 基本认证凭证
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// MaxFileKB 单个文件读写的大小上限（KB），默认 10240
+	MaxFileKB int `mapstructure:"max_file_kb"`
+	// CacheTTL 文件内容和目录列表的缓存时间，默认 1m，负数表示不缓存
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	// CacheMB 缓存的文件内容总大小上限（MB），默认 32
+	CacheMB int `mapstructure:"cache_mb"`
+}
+
+// FS builds the remote file system with caching and size limits.
+func (m MountConfig) FS() (vfs.FS, error) {
+	var (
+		fsys vfs.FS
+		err  error
+	)
+	switch m.Type {
+	case "s3":
+		fsys, err = vfs.NewS3(vfs.S3Options{
+			Endpoint:  m.URL,
+			Region:    m.Region,
+			Bucket:    m.Bucket,
+			Prefix:    m.Prefix,
+			AccessKey: m.AccessKey,
+			SecretKey: m.SecretKey,
+			PathStyle: m.PathStyle,
+		})
+	case "webdav":
+		fsys, err = vfs.NewWebDAV(vfs.WebDAVOptions{URL: m.URL, Username: m.Username, Password: m.Password})
+	default:
+		return nil, fmt.Errorf("不支持的挂载类型: %q，可选 s3、webdav", m.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	opts := vfs.CacheOptions{
+		TTL:         cmp.Or(m.CacheTTL, time.Minute),
+		MaxSize:     int64(cmp.Or(m.CacheMB, 32)) << 20,
+		MaxFileSize: int64(cmp.Or(m.MaxFileKB, 10240)) << 10,
+	}
+	return vfs.NewCached(fsys, opts), nil
+}
+
+// BuildMounts builds the remote storage mounts used by the file tools.
+func (c AgentConfig) BuildMounts() (*vfs.Mounts, error) {
+	mounts := vfs.NewMounts()
+	for i, m := range c.Mounts {
+		p := filepath.ToSlash(m.Path)
+		if vfs.Clean(p) == "" || strings.HasPrefix(p, "/") || filepath.VolumeName(m.Path) != "" ||
+			strings.Contains("/"+p+"/", "/../") {
+			return nil, fmt.Errorf("agent.mounts[%d].path 必须是工作目录内的相对路径: %q", i, m.Path)
+		}
+		fsys, err := m.FS()
+		if err != nil {
+			return nil, fmt.Errorf("agent.mounts[%d] 配置错误: %w", i, err)
+		}
+		mounts.Add(p, fsys)
+	}
+	return mounts, nil
+}
+
+// ExecConfig contains the shell and environment used by the command execution tool.
+type ExecConfig struct {
+	// Shell 执行命令的 shell：sh、bash、zsh、cmd、powershell、pwsh，为空按操作系统选择
+	Shell string `mapstructure:"shell"`
+	// EnvAllow 命令继承的守护进程环境变量白名单，支持通配符，为空表示全部继承
+	EnvAllow []string `mapstructure:"env_allow"`
+	// EnvDeny 不继承的环境变量，优先于白名单
+	EnvDeny []string `mapstructure:"env_deny"`
+	// Env 工作目录下命令额外注入的环境变量
+	Env map[string]string `mapstructure:"env"`
+	// Path 非空时替换命令的 PATH，只能执行这些目录中的程序
+	Path []string `mapstructure:"path"`
+	// Allow 命令允许列表，前缀或 re: 开头的正则，为空表示不限制
+	Allow []string `mapstructure:"allow"`
+	// Deny 命令禁止列表，在内置禁止规则之外追加
+	Deny []string `mapstructure:"deny"`
+	// AllowPackageManagers 是否允许 apt、npm install、pip install 等包管理命令
+	AllowPackageManagers bool `mapstructure:"allow_package_managers"`
+	// OutputTailKB 工具结果中保留的命令输出尾部大小（KB），完整输出实时下发给流式客户端
+	OutputTailKB int `mapstructure:"output_tail_kb"`
+}
+
+// IgnoreConfig controls which files recursive directory listings and grep skip.
+type IgnoreConfig struct {
+	// Enabled 递归列出目录和搜索时跳过忽略的文件，工具调用可以用 all 参数包含全部文件
+	Enabled bool `mapstructure:"enabled"`
+	// GitIgnore 读取各级目录中的 .gitignore，.icooclawignore 总是读取
+	GitIgnore bool `mapstructure:"gitignore"`
+	// Patterns gitignore 格式的全局忽略模式，默认为依赖、缓存和构建产物目录
+	Patterns []string `mapstructure:"patterns"`
+}
+
+// Options returns the ignore options of the file tools, nil when disabled.
+func (c IgnoreConfig) Options() *file.IgnoreOptions {
+	if !c.Enabled {
+		return nil
+	}
+	return &file.IgnoreOptions{Patterns: c.Patterns, GitIgnore: c.GitIgnore}
+}
+
+// PluginsConfig contains compiled plugin tool configuration.
+type PluginsConfig struct {
+	// Enabled 是否加载插件工具
+	Enabled bool `mapstructure:"enabled"`
+	// Dir 插件目录，每个子目录包含一个 manifest.json
+	Dir string `mapstructure:"dir"`
+	// Health 插件健康检查，停用自检失败或反复失败的插件
+	Health PluginHealthConfig `mapstructure:"health"`
+}
+
+// PluginHealthConfig contains the plugin tool health check configuration.
+type PluginHealthConfig struct {
+	// Enabled 是否启用插件健康检查
+	Enabled bool `mapstructure:"enabled"`
+	// Interval 检查对话中运行失败的间隔
+	Interval time.Duration `mapstructure:"interval"`
+	// SelfTestInterval 执行清单中 self_test 的间隔，0 表示只在启动时自检
+	SelfTestInterval time.Duration `mapstructure:"self_test_interval"`
+	// FailureThreshold 对话中连续失败多少次后停用，0 表示不按运行失败停用
+	FailureThreshold int `mapstructure:"failure_threshold"`
+}
+
+// HealthConfig converts the configuration to the plugin monitor settings.
+func (c PluginHealthConfig) HealthConfig() plugin.HealthConfig {
+	return plugin.HealthConfig{
+		Interval:         c.Interval,
+		SelfTestInterval: c.SelfTestInterval,
+		FailureThreshold: c.FailureThreshold,
+	}
+}
+
+// EnvConfig converts the configuration to the shell tool environment settings.
+func (c ExecConfig) EnvConfig() shell.EnvConfig {
+	return shell.EnvConfig{
+		Allow: c.EnvAllow,
+		Deny:  c.EnvDeny,
+		Extra: c.Env,
+		Path:  c.Path,
+	}
+}
+
+// Policy builds the command execution policy.
+func (c ExecConfig) Policy() (*shell.Policy, error) {
+	return shell.NewPolicy(c.Allow, c.Deny, c.AllowPackageManagers)
+}
+
+// PromptConfig contains toggles for the generated system prompt sections.
+type PromptConfig struct {
+	// DateTime 注入当前日期、时间和时区
+	DateTime bool `mapstructure:"datetime"`
+	// Timezone 用户的默认时区，如 Asia/Shanghai、UTC+8，为空使用本地时区；会话可通过 /timezone 覆盖
+	Timezone string `mapstructure:"timezone"`
+	// WorkspaceEntries 注入工作目录顶层条目的最多数量，0 表示不注入
+	WorkspaceEntries int `mapstructure:"workspace_entries"`
+	// Persona 注入当前人设
+	Persona bool `mapstructure:"persona"`
+	// Language 注入回复语言：识别用户消息的语言，或使用 /language 设置的语言
+	Language bool `mapstructure:"language"`
+	// Tools 按类别列出当前会话可用的工具
+	Tools bool `mapstructure:"tools"`
+	// Memory 注入置顶记忆、相关记忆和相关实体
+	Memory bool `mapstructure:"memory"`
+}
+
+// Location returns the configured time zone, or the local zone when empty.
+func (c PromptConfig) Location() (*time.Location, error) {
+	return clock.LoadLocation(c.Timezone)
+}
+
+// TraceConfig contains per-turn trace recording configuration.
+type TraceConfig struct {
+	// Enabled 是否记录每轮对话的轨迹（提示词、推理过程、工具调用、耗时和用量）
+	Enabled bool `mapstructure:"enabled"`
+	// Keep 每个会话保留最近的轨迹条数
+	Keep int `mapstructure:"keep"`
+	// MaxResultChars 轨迹中工具结果的截断长度（字符），0 表示不截断
+	MaxResultChars int `mapstructure:"max_result_chars"`
+}
+
+// EphemeralConfig contains the ephemeral (incognito) session configuration.
+type EphemeralConfig struct {
+	// Dir 无痕会话临时工作目录的父目录，为空时使用系统临时目录
+	Dir string `mapstructure:"dir"`
+	// IdleTimeout 无痕会话空闲多久后自动关闭并清除，0 表示只在显式关闭或进程退出时清除
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+	// DenyTools 无痕会话中禁用的工具，支持通配符，这些工具会在临时工作目录之外留下记录
+	DenyTools []string `mapstructure:"deny_tools"`
+}
+
+// AuthzConfig contains the authorization policy configuration.
+type AuthzConfig struct {
+	// Enabled 是否在处理消息和执行工具前按策略授权
+	Enabled bool `mapstructure:"enabled"`
+	// Dir 策略文件目录，为空时使用默认工作目录下的 policies
+	Dir string `mapstructure:"dir"`
+}
+
+// ToolPermissionsConfig contains the tool permission rules.
+type ToolPermissionsConfig struct {
+	// Enabled 是否在执行工具前按规则检查工具名称、路径、域名和命令
+	Enabled bool `mapstructure:"enabled"`
+	// Rules 配置的规则，运行时还可通过接口添加规则
+	Rules []authz.PermissionRule `mapstructure:"rules"`
+}
+
+// JobsConfig contains the batch background job configuration.
+type JobsConfig struct {
+	// BatchSize 每批处理的记录数
+	BatchSize int `mapstructure:"batch_size"`
+	// Interval 批次之间的间隔，用于限制调用模型的速率
+	Interval time.Duration `mapstructure:"interval"`
+	// EmbeddingModel 嵌入作业默认使用的模型，格式为 提供商/模型
+	EmbeddingModel string `mapstructure:"embedding_model"`
+}
+
+// FAQConfig contains the canned response configuration.
+type FAQConfig struct {
+	// Enabled 是否用常见问题直接回复命中的消息，不调用模型
+	Enabled bool `mapstructure:"enabled"`
+	// Mode 匹配模式：exact 忽略大小写、空白和标点后完全相同，fuzzy 相似度不低于阈值
+	Mode string `mapstructure:"mode"`
+	// Threshold fuzzy 模式的最低相似度，0-1
+	Threshold float64 `mapstructure:"threshold"`
+}
+
+// CostPreviewConfig contains the turn cost preview configuration.
+type CostPreviewConfig struct {
+	// Enabled 是否在调用模型前预估本轮用量，达到阈值时先请用户确认
+	Enabled bool `mapstructure:"enabled"`
+	// MinTokens 预计 token 数达到该值时确认，0 表示不按 token 数确认
+	MinTokens int `mapstructure:"min_tokens"`
+	// MinCost 预计费用（美元）达到该值时确认，0 表示不按费用确认
+	MinCost float64 `mapstructure:"min_cost"`
+	// ToolRounds 提供工具时额外估计的模型调用次数
+	ToolRounds int `mapstructure:"tool_rounds"`
+	// Expire 等待确认的消息保留时间
+	Expire time.Duration `mapstructure:"expire"`
+}
+
+// WorkerPoolConfig contains the inbound message worker pool configuration.
+type WorkerPoolConfig struct {
+	// Enabled 是否并发处理不同会话的消息，未启用时逐条处理
+	Enabled bool `mapstructure:"enabled"`
+	// Workers 并发处理的消息数
+	Workers int `mapstructure:"workers"`
+	// MaxQueued 排队的消息上限，超过后新消息被拒绝并提示稍后再试，0 表示不限制
+	MaxQueued int `mapstructure:"max_queued"`
+	// NotifyPosition 并发已满而排队时是否通知用户排队位置
+	NotifyPosition bool `mapstructure:"notify_position"`
+	// NotifyInterval 同一条消息两次排队位置更新的最小间隔
+	NotifyInterval time.Duration `mapstructure:"notify_interval"`
+}
+
+// AskUserConfig contains the mid-turn clarification question configuration.
+type AskUserConfig struct {
+	// Enabled 是否提供 ask_user 工具，模型提问后本轮暂停，用户回答后继续
+	Enabled bool `mapstructure:"enabled"`
+	// Timeout 等待回答的时间，超过后放弃本轮，之后的消息按新消息处理
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// SkillTriggersConfig contains the skill trigger engine configuration.
+type SkillTriggersConfig struct {
+	// Enabled 是否按技能的触发规则（正则、定时、事件）自动激活技能
+	Enabled bool `mapstructure:"enabled"`
+	// MaxActive 同一条消息或事件命中多个技能时最多激活的技能数，按优先级选择
+	MaxActive int `mapstructure:"max_active"`
+	// AuditKeep 触发记录保留时长，0 表示永久保留
+	AuditKeep time.Duration `mapstructure:"audit_keep"`
+}
+
+// HooksConfig contains the JavaScript hook scripts configuration.
+type HooksConfig struct {
+	// Enabled 是否加载钩子脚本
+	Enabled bool `mapstructure:"enabled"`
+	// Dir 钩子脚本目录，为空时使用默认工作目录下的 hooks
+	Dir string `mapstructure:"dir"`
+	// Timeout 单个处理函数的最长执行时间
+	Timeout time.Duration `mapstructure:"timeout"`
+	// AllowFileRead 脚本是否可以读取工作目录中的文件
+	AllowFileRead bool `mapstructure:"allow_file_read"`
+	// AllowFileWrite 脚本是否可以写入工作目录中的文件
+	AllowFileWrite bool `mapstructure:"allow_file_write"`
+	// AllowNetwork 脚本是否可以发起 HTTP 请求
+	AllowNetwork bool `mapstructure:"allow_network"`
+	// AllowedDomains 允许请求的域名，为空时不限制
+	AllowedDomains []string `mapstructure:"allowed_domains"`
+	// AllowExec 脚本是否可以执行命令
+	AllowExec bool `mapstructure:"allow_exec"`
+}
+
+// ScriptConfig 返回钩子脚本沙箱的权限配置，kv 为脚本 kv 对象使用的存储，nil 时不提供 kv 对象。
+func (c HooksConfig) ScriptConfig(workspace string, kv script.KVStore) *script.Config {
+	cfg := script.DefaultConfig()
+	cfg.Workspace = workspace
+	cfg.KV = kv
+	cfg.AllowFileRead = c.AllowFileRead
+	cfg.AllowFileWrite = c.AllowFileWrite
+	cfg.AllowNetwork = c.AllowNetwork
+	cfg.AllowedDomains = c.AllowedDomains
+	cfg.AllowExec = c.AllowExec
+	return cfg
+}
+
+// HooksDir 返回钩子脚本目录。
+func (c AgentConfig) HooksDir() string {
+	if c.Hooks.Dir != "" {
+		return c.Hooks.Dir
+	}
+	return filepath.Join(c.Workspace, hooks.DefaultScriptDir)
+}
+
+// PolicyDir 返回策略文件目录。
+func (c AgentConfig) PolicyDir() string {
+	if c.Authz.Dir != "" {
+		return c.Authz.Dir
+	}
+	return filepath.Join(c.Workspace, authz.DefaultDir)
+}
+
+// TemplatesConfig contains the workspace template sets.
+type TemplatesConfig struct {
+	// Dir 模板根目录，每个子目录是一个档案的模板套装
+	Dir string `mapstructure:"dir"`
+	// Profile 启动时补全工作目录缺失文件使用的档案
+	Profile string `mapstructure:"profile"`
+	// Profiles 档案声明的模板目录和变量，未声明的档案使用根目录下的同名子目录
+	Profiles map[string]TemplateProfile `mapstructure:"profiles"`
+}
+
+// TemplateProfile contains the template set and variables of one profile.
+type TemplateProfile struct {
+	// Dir 模板套装目录，为空时为 templates.dir 下的同名子目录
+	Dir string `mapstructure:"dir"`
+	// AgentName 智能体名称，模板中以 {{.AgentName}} 引用
+	AgentName string `mapstructure:"agent_name"`
+	// UserName 用户名称，模板中以 {{.UserName}} 引用
+	UserName string `mapstructure:"user_name"`
+	// Vars 其他模板变量，模板中以 {{.Vars.key}} 引用
+	Vars map[string]string `mapstructure:"vars"`
+}
+
+// InitOptions builds the workspace initialization options of a profile,
+// an empty name selects the configured profile.
+func (c TemplatesConfig) InitOptions(name string) workspace.Options {
+	if name == "" {
+		name = c.Profile
+	}
+	p := c.Profiles[name]
+
+	dir := p.Dir
+	if dir == "" && c.Dir != "" {
+		dir = filepath.Join(c.Dir, name)
+	}
+	agentName := p.AgentName
+	if agentName == "" {
+		agentName = "icooclaw"
+	}
+	userName := p.UserName
+	if userName == "" {
+		userName = cmp.Or(os.Getenv("USER"), os.Getenv("USERNAME"))
+	}
+
+	return workspace.Options{
+		Profile:     name,
+		TemplateDir: dir,
+		Data: workspace.Data{
+			AgentName: agentName,
+			UserName:  userName,
+			Vars:      p.Vars,
+		},
+	}
+}
+
+// DiagramConfig contains the diagram rendering tool configuration.
+type DiagramConfig struct {
+	// Enabled 是否注册 render_diagram 工具
+	Enabled bool `mapstructure:"enabled"`
+	// KrokiURL Kroki 服务地址，图表源码会发送到该服务，敏感内容应自建服务
+	KrokiURL string `mapstructure:"kroki_url"`
+	// Timeout 单次渲染请求超时，与本轮剩余预算取较小者
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// GitConfig contains the git tool suite configuration.
+type GitConfig struct {
+	// Enabled 是否注册 git_status、git_diff、git_log、git_commit 和 git_branch 工具，PATH 中没有 git 时不注册
+	Enabled bool `mapstructure:"enabled"`
+	// AuthorName 版本库未配置 user.name 时提交使用的作者名
+	AuthorName string `mapstructure:"author_name"`
+	// AuthorEmail 版本库未配置 user.email 时提交使用的邮箱
+	AuthorEmail string `mapstructure:"author_email"`
+}
+
+// DocumentsConfig contains the template document generation tool configuration.
+type DocumentsConfig struct {
+	// ChromePath 生成 PDF 使用的 Chrome/Chromium 路径，为空时在 PATH 和默认安装位置中查找
+	ChromePath string `mapstructure:"chrome_path"`
+	// PDFTimeout 生成 PDF 的超时，与本轮剩余预算取较小者
+	PDFTimeout time.Duration `mapstructure:"pdf_timeout"`
+}
+
+// MemoryDigestConfig contains the scheduled memory review digest configuration.
+type MemoryDigestConfig struct {
+	// Enabled 是否定期发送记忆回顾
+	Enabled bool `mapstructure:"enabled"`
+	// Interval 回顾间隔
+	Interval time.Duration `mapstructure:"interval"`
+	// Channel 回顾发送的渠道
+	Channel string `mapstructure:"channel"`
+	// SessionID 回顾发送的会话ID
+	SessionID string `mapstructure:"session_id"`
+	// MaxItems 每类最多列出的记忆条数
+	MaxItems int `mapstructure:"max_items"`
+}
+
+// HeartbeatConfig contains the proactive heartbeat configuration.
+type HeartbeatConfig struct {
+	// Enabled 是否启用心跳
+	Enabled bool `mapstructure:"enabled"`
+	// Interval 心跳间隔
+	Interval time.Duration `mapstructure:"interval"`
+	// Cron cron 表达式（分 时 日 月 周，服务器时区，可加 CRON_TZ= 前缀），设置后代替 interval
+	Cron string `mapstructure:"cron"`
+	// Channel 心跳发送的渠道
+	Channel string `mapstructure:"channel"`
+	// SessionID 心跳发送的会话ID
+	SessionID string `mapstructure:"session_id"`
+	// Prompt 心跳内容，设置后不再读取 file
+	Prompt string `mapstructure:"prompt"`
+	// File 心跳清单文件（相对默认工作目录），每次心跳时读取，不存在或为空时跳过
+	File string `mapstructure:"file"`
+	// QuietHours 免打扰时段（HH:MM-HH:MM，可跨午夜），按会话用户的时区判断
+	QuietHours string `mapstructure:"quiet_hours"`
+	// Window 允许运行的时段（HH:MM-HH:MM），如工作时间，按会话用户的时区判断，为空时不限制
+	Window string `mapstructure:"window"`
+	// ActiveWindow 用户在此时间内发过消息则跳过本次心跳，0 表示不限制
+	ActiveWindow time.Duration `mapstructure:"active_window"`
+	// Jitter 每次触发前的最大随机延迟，错开同时触发的心跳
+	Jitter time.Duration `mapstructure:"jitter"`
+	// MaxConcurrent 同时运行的心跳上限，超出时跳过本次触发，0 表示不限制
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	// RunTimeout 心跳发出后超过该时长仍未处理完成时视为结束，释放并发名额
+	RunTimeout time.Duration `mapstructure:"run_timeout"`
+	// Schedules 子智能体（人设）各自的心跳，未设置的字段沿用上面的全局配置
+	Schedules []HeartbeatSchedule `mapstructure:"schedules"`
+}
+
+// HeartbeatSchedule contains a per-persona heartbeat that overrides the global heartbeat settings.
+type HeartbeatSchedule struct {
+	// Persona 处理心跳的人设
+	Persona string `mapstructure:"persona"`
+	// Interval 心跳间隔
+	Interval time.Duration `mapstructure:"interval"`
+	// Cron cron 表达式，设置 interval 或 cron 之一即可覆盖全局的触发计划
+	Cron string `mapstructure:"cron"`
+	// Channel 心跳发送的渠道
+	Channel string `mapstructure:"channel"`
+	// SessionID 心跳发送的会话ID
+	SessionID string `mapstructure:"session_id"`
+	// Prompt 心跳内容，设置后不再读取 file
+	Prompt string `mapstructure:"prompt"`
+	// File 心跳清单文件（相对默认工作目录）
+	File string `mapstructure:"file"`
+	// QuietHours 免打扰时段
+	QuietHours string `mapstructure:"quiet_hours"`
+	// Window 允许运行的时段
+	Window string `mapstructure:"window"`
+	// ActiveWindow 用户近期活跃时跳过心跳的时间窗口
+	ActiveWindow time.Duration `mapstructure:"active_window"`
+	// Jitter 每次触发前的最大随机延迟
+	Jitter time.Duration `mapstructure:"jitter"`
+}
+
+// Heartbeats returns the configured heartbeats: the global heartbeat handled by
+// the default agent when channel and session_id are set, followed by one
+// heartbeat per persona schedule with unset fields taken from the global settings.
+// Relative checklist files are resolved against workspace.
+func (c HeartbeatConfig) Heartbeats(workspace string) ([]*scheduler.Heartbeat, error) {
+	var list []*scheduler.Heartbeat
+	if c.Channel != "" && c.SessionID != "" {
+		hb, err := c.heartbeat("agent.heartbeat", HeartbeatSchedule{}, workspace)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, hb)
+	}
+	for i, s := range c.Schedules {
+		name := fmt.Sprintf("agent.heartbeat.schedules[%d]", i)
+		if s.Persona == "" {
+			return nil, fmt.Errorf("%s 需要配置 persona", name)
+		}
+		hb, err := c.heartbeat(name, s, workspace)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, hb)
+	}
+	return list, nil
+}
+
+// heartbeat 合并单个心跳与全局配置并校验。
+func (c HeartbeatConfig) heartbeat(name string, s HeartbeatSchedule, workspace string) (*scheduler.Heartbeat, error) {
+	hb := &scheduler.Heartbeat{
+		Persona:      s.Persona,
+		Channel:      cmp.Or(s.Channel, c.Channel),
+		SessionID:    cmp.Or(s.SessionID, c.SessionID),
+		Prompt:       cmp.Or(s.Prompt, c.Prompt),
+		ActiveWindow: cmp.Or(s.ActiveWindow, c.ActiveWindow),
+		Jitter:       cmp.Or(s.Jitter, c.Jitter),
+	}
+	// 单个心跳设置了 interval 或 cron 时不再沿用全局的触发计划
+	if s.Interval > 0 || s.Cron != "" {
+		hb.Interval, hb.Cron = s.Interval, s.Cron
+	} else {
+		hb.Interval, hb.Cron = c.Interval, c.Cron
+	}
+	if hb.Cron != "" {
+		if _, err := cron.ParseStandard(hb.Cron); err != nil {
+			return nil, fmt.Errorf("%s.cron 配置错误: %w", name, err)
+		}
+	} else if hb.Interval < time.Minute {
+		return nil, fmt.Errorf("%s.interval 不能小于 1m", name)
+	}
+	if hb.Channel == "" || hb.SessionID == "" {
+		return nil, fmt.Errorf("%s 需要配置 channel 和 session_id", name)
+	}
+	if hb.ActiveWindow < 0 {
+		return nil, fmt.Errorf("%s.active_window 不能为负数", name)
+	}
+	if hb.Jitter < 0 {
+		return nil, fmt.Errorf("%s.jitter 不能为负数", name)
+	}
+	if file := cmp.Or(s.File, c.File); file != "" && hb.Prompt == "" {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(workspace, file)
+		}
+		hb.File = file
+	}
+	if quiet := cmp.Or(s.QuietHours, c.QuietHours); quiet != "" {
+		hours, err := clock.ParseHours(quiet)
+		if err != nil {
+			return nil, fmt.Errorf("%s.quiet_hours 配置错误: %w", name, err)
+		}
+		hb.QuietHours = &hours
+	}
+	if window := cmp.Or(s.Window, c.Window); window != "" {
+		hours, err := clock.ParseHours(window)
+		if err != nil {
+			return nil, fmt.Errorf("%s.window 配置错误: %w", name, err)
+		}
+		hb.Window = &hours
+	}
+	return hb, nil
+}
+
+// EntityGraphConfig contains entity extraction and graph-aware retrieval configuration.
+type EntityGraphConfig struct {
+	// Extract 每轮回复后调用默认模型抽取对话中的实体、事实和关系
+	Extract bool `mapstructure:"extract"`
+	// RecallLimit 用户消息提到已知实体时注入提示词的最多实体个数，0 表示不注入
+	RecallLimit int `mapstructure:"recall_limit"`
+}
+
+// MemoryDecayConfig contains memory importance scoring and decay configuration.
+type MemoryDecayConfig struct {
+	// HalfLife 记忆评分衰减半衰期，从最近一次被检索开始计算
+	HalfLife time.Duration `mapstructure:"half_life"`
+	// AccessWeight 检索次数对评分的权重
+	AccessWeight float64 `mapstructure:"access_weight"`
+	// PinBonus 置顶记忆的加分，置顶记忆不衰减
+	PinBonus float64 `mapstructure:"pin_bonus"`
+	// RecallLimit 按 评分×相关度 注入提示词的相关记忆条数，0 表示不注入
+	RecallLimit int `mapstructure:"recall_limit"`
+	// ConsolidateInterval 合并低分记忆的间隔，0 表示不合并
+	ConsolidateInterval time.Duration `mapstructure:"consolidate_interval"`
+	// ConsolidateBelow 评分低于该值的记忆会被合并为摘要
+	ConsolidateBelow float64 `mapstructure:"consolidate_below"`
+	// ConsolidateMinAge 创建时间不足该时长的记忆不参与合并
+	ConsolidateMinAge time.Duration `mapstructure:"consolidate_min_age"`
+}
+
+// ToolCondenseConfig contains the configuration for condensing verbose tool results before they enter the context.
+type ToolCondenseConfig struct {
+	// Enabled 工具结果超过阈值时先压缩再写入对话，完整输出缓存后可通过 tool_output 工具读取
+	Enabled bool `mapstructure:"enabled"`
+	// Mode 压缩方式：llm 调用模型提取与用户问题相关的内容，rule 保留开头和结尾，off 不压缩
+	Mode string `mapstructure:"mode"`
+	// Threshold 估计 token 数超过该值的结果才压缩
+	Threshold int `mapstructure:"threshold"`
+	// MaxTokens 压缩结果的目标长度
+	MaxTokens int `mapstructure:"max_tokens"`
+	// Model llm 压缩使用的模型，格式为 提供商/模型，为空时使用默认模型
+	Model string `mapstructure:"model"`
+	// CacheMB 完整输出缓存的总大小，超出后淘汰最早的输出
+	CacheMB int `mapstructure:"cache_mb"`
+	// CacheTTL 完整输出的保留时长，0 表示只按大小淘汰
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	// Tools 按工具名覆盖 mode 和 threshold，未设置的字段沿用上面的默认值
+	Tools map[string]ToolCondenseRule `mapstructure:"tools"`
+}
+
+// ToolCondenseRule contains the condensing rule of a single tool.
+type ToolCondenseRule struct {
+	// Mode 压缩方式：llm、rule 或 off
+	Mode string `mapstructure:"mode"`
+	// Threshold 估计 token 数超过该值的结果才压缩
+	Threshold int `mapstructure:"threshold"`
+}
+
+// validMode reports whether mode is a known condensing mode; empty inherits the default.
+func (r ToolCondenseRule) validMode() bool {
+	switch r.Mode {
+	case "", "llm", "rule", "off":
+		return true
+	}
+	return false
+}
+
+// SemanticRecallConfig contains embedding-based memory retrieval configuration.
+type SemanticRecallConfig struct {
+	// Enabled 按查询与记忆向量的余弦相似度检索相关记忆，嵌入接口不可用时退回关键词检索
+	Enabled bool `mapstructure:"enabled"`
+	// Model 嵌入模型，格式为 提供商/模型，为空时使用 agent.jobs.embedding_model
+	Model string `mapstructure:"model"`
+	// TopK 注入提示词的相关记忆条数，代替 memory_decay.recall_limit
+	TopK int `mapstructure:"top_k"`
+	// MinSimilarity 最低余弦相似度，不同嵌入模型的相似度分布不同，需要按模型调整
+	MinSimilarity float64 `mapstructure:"min_similarity"`
+	// EmbedMissing 每次检索顺带为缺少向量的记忆生成向量的最多条数，0 表示只使用嵌入作业生成的向量
+	EmbedMissing int `mapstructure:"embed_missing"`
+}
+
+// ProviderHealthConfig contains provider health check and circuit breaker configuration.
+type ProviderHealthConfig struct {
+	// Enabled 是否启用熔断与健康检查
+	Enabled bool `mapstructure:"enabled"`
+	// Interval 健康探测间隔，0 表示只熔断不主动探测
+	Interval time.Duration `mapstructure:"interval"`
+	// Timeout 单次探测超时
+	Timeout time.Duration `mapstructure:"timeout"`
+	// FailureThreshold 连续失败多少次后熔断
+	FailureThreshold int `mapstructure:"failure_threshold"`
+	// Cooldown 熔断后多久放行试探请求
+	Cooldown time.Duration `mapstructure:"cooldown"`
+	// Fallbacks 熔断时依次尝试的备用提供商名称
+	Fallbacks []string `mapstructure:"fallbacks"`
+}
+
+// ProviderRetryConfig contains the provider request retry configuration.
+type ProviderRetryConfig struct {
+	// MaxRetries 遇到 429、5xx 或网络错误时最多重试次数，0 表示不重试
+	MaxRetries int `mapstructure:"max_retries"`
+	// InitialBackoff 第一次重试前的等待时间，之后每次翻倍
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	// MaxBackoff 指数退避的最长等待时间
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+	// MaxRetryAfter Retry-After 要求的等待超过该值时不再重试
+	MaxRetryAfter time.Duration `mapstructure:"max_retry_after"`
+}
+
+// RetryConfig converts the configuration to the provider retry settings.
+func (c ProviderRetryConfig) RetryConfig() providers.RetryConfig {
+	return providers.RetryConfig{
+		MaxRetries:     c.MaxRetries,
+		InitialBackoff: c.InitialBackoff,
+		MaxBackoff:     c.MaxBackoff,
+		MaxRetryAfter:  c.MaxRetryAfter,
+	}
+}
+
+// ScoreConfig converts the configuration to the memory scoring model.
+func (c MemoryDecayConfig) ScoreConfig() memory.ScoreConfig {
+	return memory.ScoreConfig{
+		HalfLife:     c.HalfLife,
+		AccessWeight: c.AccessWeight,
+		PinBonus:     c.PinBonus,
+	}
+}
+
+// ConsolidateConfig converts the configuration to memory consolidation parameters.
+func (c MemoryDecayConfig) ConsolidateConfig() memory.ConsolidateConfig {
+	return memory.ConsolidateConfig{
+		Score:     c.ScoreConfig(),
+		Threshold: c.ConsolidateBelow,
+		MinAge:    c.ConsolidateMinAge,
+	}
+}
+
+// SemanticRecallModel returns the embedding model used for semantic memory recall,
+// or an empty string when semantic recall is disabled.
+func (c AgentConfig) SemanticRecallModel() string {
+	if !c.SemanticRecall.Enabled {
+		return ""
+	}
+	if c.SemanticRecall.Model != "" {
+		return c.SemanticRecall.Model
+	}
+	return c.Jobs.EmbeddingModel
+}
+
+// MemoryDigestConfig converts the configuration to memory review digest parameters.
+// Memories predicted to drop below the consolidation threshold before the next digest are listed as expiring.
+func (c AgentConfig) MemoryDigestConfig() memory.DigestConfig {
+	return memory.DigestConfig{
+		Score:     c.MemoryDecay.ScoreConfig(),
+		Threshold: c.MemoryDecay.ConsolidateBelow,
+		Horizon:   c.MemoryDigest.Interval,
+		MaxItems:  c.MemoryDigest.MaxItems,
+	}
 }
 
 // DatabaseConfig contains database configuration.
 type DatabaseConfig struct {
 	Path string `mapstructure:"path"`
+	// HistoryPath 会话历史（消息和记忆）单独存放的数据库路径，为空时与 Path 共用一个数据库
+	HistoryPath string `mapstructure:"history_path"`
+	// Encryption 对话内容的加密配置
+	Encryption EncryptionConfig `mapstructure:"encryption"`
+}
+
+// EncryptionConfig contains the at-rest encryption configuration for conversation content.
+type EncryptionConfig struct {
+	// Enabled 是否以 AES-256-GCM 加密消息、记忆、会话摘要和元数据、对话轨迹、离线消息、实体事实和键值，启用前写入的明文记录可通过 icooclaw db encrypt 迁移
+	Enabled bool `mapstructure:"enabled"`
+	// KeyEnv 保存当前密钥的环境变量，密钥为 32 字节，base64 或 hex 编码
+	KeyEnv string `mapstructure:"key_env"`
+	// KeyCommand 输出当前密钥的命令，用于从系统钥匙串读取，如 security find-generic-password -s icooclaw -w，设置后优先于 KeyEnv
+	KeyCommand string `mapstructure:"key_command"`
+	// PreviousKeyEnvs 轮换前的旧密钥所在的环境变量，用于读取尚未重新加密的记录
+	PreviousKeyEnvs []string `mapstructure:"previous_key_envs"`
+	// PreviousKeyCommands 输出旧密钥的命令
+	PreviousKeyCommands []string `mapstructure:"previous_key_commands"`
+}
+
+// Keys loads the current key followed by the previous keys.
+func (c EncryptionConfig) Keys() ([][]byte, error) {
+	var current []byte
+	var err error
+	if c.KeyCommand != "" {
+		current, err = keyFromCommand(c.KeyCommand)
+	} else {
+		current, err = keyFromEnv(c.KeyEnv)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := [][]byte{current}
+	for _, name := range c.PreviousKeyEnvs {
+		key, err := keyFromEnv(name)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	for _, command := range c.PreviousKeyCommands {
+		key, err := keyFromCommand(command)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Cipher returns the storage cipher, or nil when encryption is disabled.
+func (c EncryptionConfig) Cipher() (*storage.Cipher, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	keys, err := c.Keys()
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewCipher(keys[0], keys[1:]...)
+}
+
+// keyFromEnv 从环境变量读取密钥。
+func keyFromEnv(name string) ([]byte, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, fmt.Errorf("环境变量 %s 未设置加密密钥", name)
+	}
+	key, err := decodeKey(value)
+	if err != nil {
+		return nil, fmt.Errorf("环境变量 %s 中的密钥无效: %w", name, err)
+	}
+	return key, nil
+}
+
+// keyFromCommand 运行命令读取密钥，命令的标准输出为密钥。
+func keyFromCommand(command string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("读取加密密钥失败 (%s): %w", command, err)
+	}
+	key, err := decodeKey(string(out))
+	if err != nil {
+		return nil, fmt.Errorf("命令 %s 输出的密钥无效: %w", command, err)
+	}
+	return key, nil
+}
+
+// decodeKey 解码 base64 或 hex 编码的 32 字节密钥。
+func decodeKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if key, err := hex.DecodeString(value); err == nil && len(key) == storage.KeySize {
+		return key, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(value); err == nil {
+			if len(key) != storage.KeySize {
+				return nil, fmt.Errorf("密钥长度为 %d 字节，需要 %d 字节", len(key), storage.KeySize)
+			}
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("密钥需要 base64 或 hex 编码")
 }
 
 // GatewayConfig contains HTTP gateway configuration.
 type GatewayConfig struct {
 	Enabled bool `mapstructure:"enabled"`
 	Port    int  `mapstructure:"port"`
+	// Host 监听地址，为空时监听所有网卡
+	Host string `mapstructure:"host"`
+	// UnixSocket unix socket 路径，设置后不再监听 TCP 端口
+	UnixSocket string `mapstructure:"unix_socket"`
+	// TrustedProxies 受信任的反向代理 IP 或 CIDR，仅这些来源的 X-Forwarded-For/X-Real-IP 会被采信
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// TLS HTTPS 配置
+	TLS TLSConfig `mapstructure:"tls"`
+	// GRPC gRPC 服务配置
+	GRPC GRPCConfig `mapstructure:"grpc"`
+	// Auth HTTP/WebSocket 和 gRPC 接口认证
+	Auth GatewayAuthConfig `mapstructure:"auth"`
+}
+
+// GatewayAuthConfig contains API key and JWT authentication for the gateway.
+type GatewayAuthConfig struct {
+	// Enabled 是否启用认证，启用后除健康检查和渠道回调外的接口都需要凭证
+	Enabled bool `mapstructure:"enabled"`
+	// APIKeys API 密钥，可用 icooclaw token key 生成
+	APIKeys []auth.APIKey `mapstructure:"api_keys"`
+	// JWTSecretEnv 保存 JWT 签名密钥的环境变量，可用 icooclaw token secret 生成，未设置时不接受 JWT
+	JWTSecretEnv string `mapstructure:"jwt_secret_env"`
+	// PreviousJWTSecretEnvs 轮换前的旧签名密钥所在的环境变量，旧密钥签发的令牌在过期前仍然有效
+	PreviousJWTSecretEnvs []string `mapstructure:"previous_jwt_secret_envs"`
+	// Issuer JWT 签发者
+	Issuer string `mapstructure:"issuer"`
+	// TokenTTL icooclaw token issue 签发令牌的默认有效期
+	TokenTTL time.Duration `mapstructure:"token_ttl"`
+}
+
+// Options returns the authenticator options, reading the JWT secrets from the environment.
+// The current secret is optional so API-key-only setups need no environment variable.
+func (c GatewayAuthConfig) Options() (auth.Options, error) {
+	opts := auth.Options{Keys: c.APIKeys, Issuer: c.Issuer, TTL: c.TokenTTL}
+	var current string
+	if c.JWTSecretEnv != "" {
+		current = os.Getenv(c.JWTSecretEnv)
+	}
+	if current != "" {
+		opts.Secrets = append(opts.Secrets, []byte(current))
+	} else if len(c.PreviousJWTSecretEnvs) > 0 {
+		return auth.Options{}, fmt.Errorf("配置了 previous_jwt_secret_envs 时必须在 jwt_secret_env 中设置当前签名密钥")
+	}
+	for _, name := range c.PreviousJWTSecretEnvs {
+		secret := os.Getenv(name)
+		if secret == "" {
+			return auth.Options{}, fmt.Errorf("环境变量 %s 未设置旧签名密钥", name)
+		}
+		opts.Secrets = append(opts.Secrets, []byte(secret))
+	}
+	return opts, nil
+}
+
+// Authenticator returns the gateway authenticator, or nil when authentication is disabled.
+func (c GatewayAuthConfig) Authenticator() (*auth.Authenticator, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	opts, err := c.Options()
+	if err != nil {
+		return nil, err
+	}
+	return auth.New(opts)
+}
+
+// GRPCConfig contains gRPC server configuration.
+type GRPCConfig struct {
+	// Enabled 是否启用 gRPC 服务
+	Enabled bool `mapstructure:"enabled"`
+	// Port gRPC 监听端口，监听地址与 gateway.host 相同
+	Port int `mapstructure:"port"`
+	// Reflection 是否开启服务反射，供 grpcurl 等工具列出服务和方法
+	Reflection bool `mapstructure:"reflection"`
+}
+
+// TLSConfig contains HTTPS configuration for the gateway.
+type TLSConfig struct {
+	// CertFile 证书文件
+	CertFile string `mapstructure:"cert_file"`
+	// KeyFile 私钥文件
+	KeyFile string `mapstructure:"key_file"`
+	// AutocertDomains 使用 Let's Encrypt 自动签发证书的域名
+	AutocertDomains []string `mapstructure:"autocert_domains"`
+	// AutocertCacheDir 自动证书缓存目录
+	AutocertCacheDir string `mapstructure:"autocert_cache_dir"`
+	// AutocertEmail ACME 账户邮箱
+	AutocertEmail string `mapstructure:"autocert_email"`
+}
+
+// Enabled reports whether HTTPS is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || len(c.AutocertDomains) > 0
 }
 
 // LoggingConfig contains logging configuration.
 type LoggingConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
+	// Prompt 模型提示词日志配置
+	Prompt PromptLogConfig `mapstructure:"prompt"`
+}
+
+// PromptLogConfig contains LLM prompt logging configuration.
+type PromptLogConfig struct {
+	// Mode 记录模式: off 不记录，metadata 只记录数量、哈希和用量，full 记录脱敏后的完整内容
+	Mode string `mapstructure:"mode"`
+	// Path 提示词日志文件路径（JSON Lines）
+	Path string `mapstructure:"path"`
+	// Redact 额外的脱敏正则，匹配内容替换为 [REDACTED]
+	Redact []string `mapstructure:"redact"`
 }
 
 // ChannelsConfig contains channel-specific configurations.
 type ChannelsConfig struct {
+	// DedupTTL 入站消息 ID 和 REST 幂等键的保留时间，期间重复投递的消息被丢弃，0 表示不去重
+	DedupTTL time.Duration  `mapstructure:"dedup_ttl"`
 	Feishu   FeishuConfig   `mapstructure:"feishu"`
 	DingTalk DingTalkConfig `mapstructure:"dingtalk"`
+	WeCom    WeComConfig    `mapstructure:"wecom"`
+	QQ       QQConfig       `mapstructure:"qq"`
 }
 
 // FeishuConfig contains Feishu/Lark channel configuration.
@@ -62,6 +1376,12 @@ type FeishuConfig struct {
 	VerificationToken string   `mapstructure:"verification_token"`
 	AllowFrom         []string `mapstructure:"allow_from"`
 	ReasoningChatID   string   `mapstructure:"reasoning_chat_id"`
+	// Mode 接收事件的方式：websocket（长连接，默认）或 webhook（事件订阅请求地址，挂载在网关上）
+	Mode string `mapstructure:"mode"`
+	// WebhookPath webhook 模式的回调路径，默认 /webhook/feishu
+	WebhookPath string `mapstructure:"webhook_path"`
+	// GroupReplyAll 群聊中没有 @ 机器人的消息也回复
+	GroupReplyAll bool `mapstructure:"group_reply_all"`
 }
 
 // DingTalkConfig contains DingTalk channel configuration.
@@ -74,6 +1394,35 @@ type DingTalkConfig struct {
 	ReasoningChatID string   `mapstructure:"reasoning_chat_id"`
 }
 
+// WeComConfig contains WeCom (企业微信) channel configuration.
+type WeComConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	CorpID         string `mapstructure:"corp_id"`
+	AgentID        int64  `mapstructure:"agent_id"`
+	Secret         string `mapstructure:"secret"`
+	Token          string `mapstructure:"token"`
+	EncodingAESKey string `mapstructure:"encoding_aes_key"`
+	// WebhookPath 接收消息的回调路径，默认 /webhook/wecom
+	WebhookPath string `mapstructure:"webhook_path"`
+	// MessageType 回复的消息类型：markdown（默认）或 text（微信插件中只显示 text）
+	MessageType string `mapstructure:"message_type"`
+	// ExternalUsers 外部联系人 external_userid 到用户 ID 的映射，映射后共享记忆和会话归属
+	ExternalUsers   map[string]string `mapstructure:"external_users"`
+	AllowFrom       []string          `mapstructure:"allow_from"`
+	ReasoningChatID string            `mapstructure:"reasoning_chat_id"`
+}
+
+// QQConfig contains QQ official bot channel configuration.
+type QQConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	AppID     string `mapstructure:"app_id"`
+	AppSecret string `mapstructure:"app_secret"`
+	// Sandbox 使用沙箱环境，机器人上线前只能在沙箱频道和群中调试
+	Sandbox         bool     `mapstructure:"sandbox"`
+	AllowFrom       []string `mapstructure:"allow_from"`
+	ReasoningChatID string   `mapstructure:"reasoning_chat_id"`
+}
+
 // DefaultConfig returns the default configuration.
 func DefaultConfig() *Config {
 	return &Config{
@@ -81,17 +1430,216 @@ func DefaultConfig() *Config {
 			Workspace:       "./workspace",
 			DefaultModel:    "gpt-4",
 			DefaultProvider: consts.ProviderQwen,
+
+			SessionIdleTimeout:   0,
+			SessionSweepInterval: 10 * time.Minute,
+
+			OfflineQueue:          true,
+			OfflineRetryInterval:  30 * time.Second,
+			OfflineReplayInterval: 2 * time.Second,
+			WorkerPool: WorkerPoolConfig{
+				Workers:        4,
+				MaxQueued:      200,
+				NotifyPosition: true,
+				NotifyInterval: 30 * time.Second,
+			},
+
+			StatusInterval:  15 * time.Second,
+			ToolNotes:       true,
+			TurnBudget:      2 * time.Minute,
+			ToolRepeatLimit: 2,
+			ToolCondense: ToolCondenseConfig{
+				Mode:      "rule",
+				Threshold: 4000,
+				MaxTokens: 800,
+				CacheMB:   64,
+				CacheTTL:  24 * time.Hour,
+			},
+
+			Exec: ExecConfig{
+				// 默认不向命令暴露提供商密钥等敏感变量
+				EnvDeny:      []string{"ICOOCLAW_*", "*_API_KEY", "*_SECRET*", "*_TOKEN", "*PASSWORD*"},
+				OutputTailKB: 10,
+			},
+
+			Ignore: IgnoreConfig{
+				Enabled:   true,
+				GitIgnore: true,
+				Patterns:  file.DefaultIgnorePatterns,
+			},
+
+			Plugins: PluginsConfig{
+				Dir: "./plugins",
+				Health: PluginHealthConfig{
+					Enabled:          true,
+					Interval:         time.Minute,
+					SelfTestInterval: time.Hour,
+					FailureThreshold: 5,
+				},
+			},
+
+			ProviderHealth: ProviderHealthConfig{
+				Enabled:          true,
+				Interval:         time.Minute,
+				Timeout:          10 * time.Second,
+				FailureThreshold: 3,
+				Cooldown:         30 * time.Second,
+			},
+			ProviderRetry: ProviderRetryConfig{
+				MaxRetries:     3,
+				InitialBackoff: time.Second,
+				MaxBackoff:     30 * time.Second,
+				MaxRetryAfter:  time.Minute,
+			},
+
+			MemoryDecay: MemoryDecayConfig{
+				HalfLife:            30 * 24 * time.Hour,
+				AccessWeight:        0.5,
+				PinBonus:            1,
+				RecallLimit:         5,
+				ConsolidateInterval: 24 * time.Hour,
+				ConsolidateBelow:    0.2,
+				ConsolidateMinAge:   7 * 24 * time.Hour,
+			},
+
+			SemanticRecall: SemanticRecallConfig{
+				TopK:          5,
+				MinSimilarity: 0.35,
+				EmbedMissing:  20,
+			},
+
+			EntityGraph: EntityGraphConfig{
+				RecallLimit: 3,
+			},
+
+			MemoryDigest: MemoryDigestConfig{
+				Interval: 7 * 24 * time.Hour,
+				MaxItems: 10,
+			},
+			Heartbeat: HeartbeatConfig{
+				Interval:     30 * time.Minute,
+				File:         "HEARTBEAT.md",
+				QuietHours:   "22:00-08:00",
+				ActiveWindow: 15 * time.Minute,
+				RunTimeout:   10 * time.Minute,
+			},
+
+			Prompt: PromptConfig{
+				DateTime:         true,
+				WorkspaceEntries: 30,
+				Persona:          true,
+				Language:         true,
+				Tools:            true,
+				Memory:           true,
+			},
+
+			Trace: TraceConfig{
+				Enabled:        true,
+				Keep:           20,
+				MaxResultChars: 2000,
+			},
+			Ephemeral: EphemeralConfig{
+				IdleTimeout: time.Hour,
+				DenyTools:   []string{"kv_*", "session_vars", "user_timezone", "scheduler", "skill_install", "save_artifact", "render_diagram", "write_spreadsheet", "generate_document"},
+			},
+			Jobs: JobsConfig{
+				BatchSize: 50,
+				Interval:  time.Second,
+			},
+			FAQ: FAQConfig{
+				Mode:      faq.ModeFuzzy,
+				Threshold: 0.85,
+			},
+			Hooks: HooksConfig{
+				Timeout:       hooks.DefaultScriptTimeout,
+				AllowFileRead: true,
+			},
+			CostPreview: CostPreviewConfig{
+				MinTokens:  30000,
+				ToolRounds: 2,
+				Expire:     10 * time.Minute,
+			},
+			AskUser: AskUserConfig{
+				Timeout: 30 * time.Minute,
+			},
+			SkillTriggers: SkillTriggersConfig{
+				MaxActive: 1,
+				AuditKeep: 30 * 24 * time.Hour,
+			},
+			Templates: TemplatesConfig{
+				Dir:     "./templates",
+				Profile: workspace.DefaultProfile,
+			},
+			Diagram: DiagramConfig{
+				Enabled:  true,
+				KrokiURL: "https://kroki.io",
+				Timeout:  30 * time.Second,
+			},
+			Git: GitConfig{
+				Enabled: true,
+			},
+			Documents: DocumentsConfig{
+				PDFTimeout: time.Minute,
+			},
 		},
 		Database: DatabaseConfig{
 			Path: "./data/icooclaw.db",
+			Encryption: EncryptionConfig{
+				KeyEnv: "ICOOCLAW_DB_KEY",
+			},
+		},
+		Cluster: ClusterConfig{
+			LeaseTTL: 30 * time.Second,
+		},
+		Update: UpdateConfig{
+			Channel: update.ChannelStable,
+		},
+		Forms: FormsConfig{
+			Expire:      30 * time.Minute,
+			MaxAttempts: 3,
+		},
+		SMTP: SMTPConfig{
+			Port:     587,
+			Security: mail.SecurityStartTLS,
+			Timeout:  30 * time.Second,
+		},
+		Digest: DigestConfig{
+			Frequency: digest.FrequencyDaily,
+			At:        "08:00",
+			Weekday:   1,
+			MaxItems:  10,
+		},
+		Channels: ChannelsConfig{
+			DedupTTL: 24 * time.Hour,
+		},
+		ToolServer: ToolServerConfig{
+			Addr: "127.0.0.1:8090",
+		},
+		Audit: AuditConfig{
+			Keep: 180 * 24 * time.Hour,
 		},
 		Gateway: GatewayConfig{
 			Enabled: true,
 			Port:    8080,
+			TLS: TLSConfig{
+				AutocertCacheDir: "./data/autocert",
+			},
+			GRPC: GRPCConfig{
+				Port: 9090,
+			},
+			Auth: GatewayAuthConfig{
+				JWTSecretEnv: "ICOOCLAW_JWT_SECRET",
+				Issuer:       auth.DefaultIssuer,
+				TokenTTL:     auth.DefaultTTL,
+			},
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
 			Format: "json",
+			Prompt: PromptLogConfig{
+				Mode: string(providers.PromptLogOff),
+				Path: "./data/logs/prompts.jsonl",
+			},
 		},
 	}
 }
@@ -146,11 +1694,162 @@ func setDefaults(v *viper.Viper, cfg *Config) {
 	v.SetDefault("agent.workspace", cfg.Agent.Workspace)
 	v.SetDefault("agent.default_model", cfg.Agent.DefaultModel)
 	v.SetDefault("agent.default_provider", cfg.Agent.DefaultProvider)
+	v.SetDefault("agent.session_idle_timeout", cfg.Agent.SessionIdleTimeout)
+	v.SetDefault("agent.session_sweep_interval", cfg.Agent.SessionSweepInterval)
+	v.SetDefault("agent.offline_queue", cfg.Agent.OfflineQueue)
+	v.SetDefault("agent.worker_pool.enabled", cfg.Agent.WorkerPool.Enabled)
+	v.SetDefault("agent.worker_pool.workers", cfg.Agent.WorkerPool.Workers)
+	v.SetDefault("agent.worker_pool.max_queued", cfg.Agent.WorkerPool.MaxQueued)
+	v.SetDefault("agent.worker_pool.notify_position", cfg.Agent.WorkerPool.NotifyPosition)
+	v.SetDefault("agent.worker_pool.notify_interval", cfg.Agent.WorkerPool.NotifyInterval)
+	v.SetDefault("agent.offline_retry_interval", cfg.Agent.OfflineRetryInterval)
+	v.SetDefault("agent.offline_replay_interval", cfg.Agent.OfflineReplayInterval)
+	v.SetDefault("agent.status_interval", cfg.Agent.StatusInterval)
+	v.SetDefault("agent.tool_notes", cfg.Agent.ToolNotes)
+	v.SetDefault("agent.turn_budget", cfg.Agent.TurnBudget)
+	v.SetDefault("agent.tool_repeat_limit", cfg.Agent.ToolRepeatLimit)
+	v.SetDefault("agent.tool_timeout", cfg.Agent.ToolTimeout)
+	v.SetDefault("agent.tool_condense.enabled", cfg.Agent.ToolCondense.Enabled)
+	v.SetDefault("agent.tool_condense.mode", cfg.Agent.ToolCondense.Mode)
+	v.SetDefault("agent.tool_condense.threshold", cfg.Agent.ToolCondense.Threshold)
+	v.SetDefault("agent.tool_condense.max_tokens", cfg.Agent.ToolCondense.MaxTokens)
+	v.SetDefault("agent.tool_condense.model", cfg.Agent.ToolCondense.Model)
+	v.SetDefault("agent.tool_condense.cache_mb", cfg.Agent.ToolCondense.CacheMB)
+	v.SetDefault("agent.tool_condense.cache_ttl", cfg.Agent.ToolCondense.CacheTTL)
+	v.SetDefault("agent.exec.env_deny", cfg.Agent.Exec.EnvDeny)
+	v.SetDefault("agent.exec.allow_package_managers", cfg.Agent.Exec.AllowPackageManagers)
+	v.SetDefault("agent.exec.output_tail_kb", cfg.Agent.Exec.OutputTailKB)
+	v.SetDefault("agent.ignore.enabled", cfg.Agent.Ignore.Enabled)
+	v.SetDefault("agent.ignore.gitignore", cfg.Agent.Ignore.GitIgnore)
+	v.SetDefault("agent.ignore.patterns", cfg.Agent.Ignore.Patterns)
+	v.SetDefault("agent.plugins.enabled", cfg.Agent.Plugins.Enabled)
+	v.SetDefault("agent.plugins.dir", cfg.Agent.Plugins.Dir)
+	v.SetDefault("agent.plugins.health.enabled", cfg.Agent.Plugins.Health.Enabled)
+	v.SetDefault("agent.plugins.health.interval", cfg.Agent.Plugins.Health.Interval)
+	v.SetDefault("agent.plugins.health.self_test_interval", cfg.Agent.Plugins.Health.SelfTestInterval)
+	v.SetDefault("agent.plugins.health.failure_threshold", cfg.Agent.Plugins.Health.FailureThreshold)
+	v.SetDefault("agent.provider_health.enabled", cfg.Agent.ProviderHealth.Enabled)
+	v.SetDefault("agent.provider_health.interval", cfg.Agent.ProviderHealth.Interval)
+	v.SetDefault("agent.provider_health.timeout", cfg.Agent.ProviderHealth.Timeout)
+	v.SetDefault("agent.provider_health.failure_threshold", cfg.Agent.ProviderHealth.FailureThreshold)
+	v.SetDefault("agent.provider_health.cooldown", cfg.Agent.ProviderHealth.Cooldown)
+	v.SetDefault("agent.provider_retry.max_retries", cfg.Agent.ProviderRetry.MaxRetries)
+	v.SetDefault("agent.provider_retry.initial_backoff", cfg.Agent.ProviderRetry.InitialBackoff)
+	v.SetDefault("agent.provider_retry.max_backoff", cfg.Agent.ProviderRetry.MaxBackoff)
+	v.SetDefault("agent.provider_retry.max_retry_after", cfg.Agent.ProviderRetry.MaxRetryAfter)
+	v.SetDefault("agent.memory_decay.half_life", cfg.Agent.MemoryDecay.HalfLife)
+	v.SetDefault("agent.memory_decay.access_weight", cfg.Agent.MemoryDecay.AccessWeight)
+	v.SetDefault("agent.memory_decay.pin_bonus", cfg.Agent.MemoryDecay.PinBonus)
+	v.SetDefault("agent.memory_decay.recall_limit", cfg.Agent.MemoryDecay.RecallLimit)
+	v.SetDefault("agent.memory_decay.consolidate_interval", cfg.Agent.MemoryDecay.ConsolidateInterval)
+	v.SetDefault("agent.memory_decay.consolidate_below", cfg.Agent.MemoryDecay.ConsolidateBelow)
+	v.SetDefault("agent.memory_decay.consolidate_min_age", cfg.Agent.MemoryDecay.ConsolidateMinAge)
+	v.SetDefault("agent.semantic_recall.enabled", cfg.Agent.SemanticRecall.Enabled)
+	v.SetDefault("agent.semantic_recall.model", cfg.Agent.SemanticRecall.Model)
+	v.SetDefault("agent.semantic_recall.top_k", cfg.Agent.SemanticRecall.TopK)
+	v.SetDefault("agent.semantic_recall.min_similarity", cfg.Agent.SemanticRecall.MinSimilarity)
+	v.SetDefault("agent.semantic_recall.embed_missing", cfg.Agent.SemanticRecall.EmbedMissing)
+	v.SetDefault("agent.entity_graph.extract", cfg.Agent.EntityGraph.Extract)
+	v.SetDefault("agent.entity_graph.recall_limit", cfg.Agent.EntityGraph.RecallLimit)
+	v.SetDefault("agent.memory_digest.enabled", cfg.Agent.MemoryDigest.Enabled)
+	v.SetDefault("agent.memory_digest.interval", cfg.Agent.MemoryDigest.Interval)
+	v.SetDefault("agent.memory_digest.max_items", cfg.Agent.MemoryDigest.MaxItems)
+	v.SetDefault("agent.heartbeat.enabled", cfg.Agent.Heartbeat.Enabled)
+	v.SetDefault("agent.heartbeat.interval", cfg.Agent.Heartbeat.Interval)
+	v.SetDefault("agent.heartbeat.file", cfg.Agent.Heartbeat.File)
+	v.SetDefault("agent.heartbeat.quiet_hours", cfg.Agent.Heartbeat.QuietHours)
+	v.SetDefault("agent.heartbeat.active_window", cfg.Agent.Heartbeat.ActiveWindow)
+	v.SetDefault("agent.heartbeat.run_timeout", cfg.Agent.Heartbeat.RunTimeout)
+	v.SetDefault("agent.prompt.datetime", cfg.Agent.Prompt.DateTime)
+	v.SetDefault("agent.prompt.workspace_entries", cfg.Agent.Prompt.WorkspaceEntries)
+	v.SetDefault("agent.prompt.persona", cfg.Agent.Prompt.Persona)
+	v.SetDefault("agent.prompt.language", cfg.Agent.Prompt.Language)
+	v.SetDefault("agent.prompt.tools", cfg.Agent.Prompt.Tools)
+	v.SetDefault("agent.prompt.memory", cfg.Agent.Prompt.Memory)
+	v.SetDefault("agent.trace.enabled", cfg.Agent.Trace.Enabled)
+	v.SetDefault("agent.trace.keep", cfg.Agent.Trace.Keep)
+	v.SetDefault("agent.trace.max_result_chars", cfg.Agent.Trace.MaxResultChars)
+	v.SetDefault("agent.ephemeral.dir", cfg.Agent.Ephemeral.Dir)
+	v.SetDefault("agent.ephemeral.idle_timeout", cfg.Agent.Ephemeral.IdleTimeout)
+	v.SetDefault("agent.ephemeral.deny_tools", cfg.Agent.Ephemeral.DenyTools)
+	v.SetDefault("agent.authz.enabled", cfg.Agent.Authz.Enabled)
+	v.SetDefault("agent.authz.dir", cfg.Agent.Authz.Dir)
+	v.SetDefault("agent.tool_permissions.enabled", cfg.Agent.ToolPermissions.Enabled)
+	v.SetDefault("agent.hooks.enabled", cfg.Agent.Hooks.Enabled)
+	v.SetDefault("agent.hooks.dir", cfg.Agent.Hooks.Dir)
+	v.SetDefault("agent.hooks.timeout", cfg.Agent.Hooks.Timeout)
+	v.SetDefault("agent.hooks.allow_file_read", cfg.Agent.Hooks.AllowFileRead)
+	v.SetDefault("agent.hooks.allow_file_write", cfg.Agent.Hooks.AllowFileWrite)
+	v.SetDefault("agent.hooks.allow_network", cfg.Agent.Hooks.AllowNetwork)
+	v.SetDefault("agent.hooks.allow_exec", cfg.Agent.Hooks.AllowExec)
+	v.SetDefault("agent.jobs.batch_size", cfg.Agent.Jobs.BatchSize)
+	v.SetDefault("agent.jobs.interval", cfg.Agent.Jobs.Interval)
+	v.SetDefault("agent.jobs.embedding_model", cfg.Agent.Jobs.EmbeddingModel)
+	v.SetDefault("agent.faq.enabled", cfg.Agent.FAQ.Enabled)
+	v.SetDefault("agent.faq.mode", cfg.Agent.FAQ.Mode)
+	v.SetDefault("agent.faq.threshold", cfg.Agent.FAQ.Threshold)
+	v.SetDefault("agent.cost_preview.enabled", cfg.Agent.CostPreview.Enabled)
+	v.SetDefault("agent.cost_preview.min_tokens", cfg.Agent.CostPreview.MinTokens)
+	v.SetDefault("agent.cost_preview.min_cost", cfg.Agent.CostPreview.MinCost)
+	v.SetDefault("agent.cost_preview.tool_rounds", cfg.Agent.CostPreview.ToolRounds)
+	v.SetDefault("agent.cost_preview.expire", cfg.Agent.CostPreview.Expire)
+	v.SetDefault("agent.ask_user.enabled", cfg.Agent.AskUser.Enabled)
+	v.SetDefault("agent.ask_user.timeout", cfg.Agent.AskUser.Timeout)
+	v.SetDefault("agent.skill_triggers.enabled", cfg.Agent.SkillTriggers.Enabled)
+	v.SetDefault("agent.skill_triggers.max_active", cfg.Agent.SkillTriggers.MaxActive)
+	v.SetDefault("agent.skill_triggers.audit_keep", cfg.Agent.SkillTriggers.AuditKeep)
+	v.SetDefault("agent.templates.dir", cfg.Agent.Templates.Dir)
+	v.SetDefault("agent.templates.profile", cfg.Agent.Templates.Profile)
+	v.SetDefault("agent.diagram.enabled", cfg.Agent.Diagram.Enabled)
+	v.SetDefault("agent.diagram.kroki_url", cfg.Agent.Diagram.KrokiURL)
+	v.SetDefault("agent.diagram.timeout", cfg.Agent.Diagram.Timeout)
+	v.SetDefault("agent.git.enabled", cfg.Agent.Git.Enabled)
+	v.SetDefault("agent.git.author_name", cfg.Agent.Git.AuthorName)
+	v.SetDefault("agent.git.author_email", cfg.Agent.Git.AuthorEmail)
+	v.SetDefault("agent.documents.chrome_path", cfg.Agent.Documents.ChromePath)
+	v.SetDefault("agent.documents.pdf_timeout", cfg.Agent.Documents.PDFTimeout)
 	v.SetDefault("database.path", cfg.Database.Path)
+	v.SetDefault("database.history_path", cfg.Database.HistoryPath)
+	v.SetDefault("database.encryption.enabled", cfg.Database.Encryption.Enabled)
+	v.SetDefault("database.encryption.key_env", cfg.Database.Encryption.KeyEnv)
+	v.SetDefault("cluster.enabled", cfg.Cluster.Enabled)
+	v.SetDefault("cluster.instance_id", cfg.Cluster.InstanceID)
+	v.SetDefault("cluster.lease_ttl", cfg.Cluster.LeaseTTL)
+	v.SetDefault("update.endpoint", cfg.Update.Endpoint)
+	v.SetDefault("update.channel", cfg.Update.Channel)
+	v.SetDefault("update.public_key", cfg.Update.PublicKey)
+	v.SetDefault("update.restart_command", cfg.Update.RestartCommand)
+	v.SetDefault("forms.expire", cfg.Forms.Expire)
+	v.SetDefault("forms.max_attempts", cfg.Forms.MaxAttempts)
+	v.SetDefault("notices.language", cfg.Notices.Language)
+	v.SetDefault("toolserver.addr", cfg.ToolServer.Addr)
+	v.SetDefault("audit.enabled", cfg.Audit.Enabled)
+	v.SetDefault("audit.keep", cfg.Audit.Keep)
+	v.SetDefault("smtp.port", cfg.SMTP.Port)
+	v.SetDefault("smtp.security", cfg.SMTP.Security)
+	v.SetDefault("smtp.timeout", cfg.SMTP.Timeout)
+	v.SetDefault("digest.enabled", cfg.Digest.Enabled)
+	v.SetDefault("digest.frequency", cfg.Digest.Frequency)
+	v.SetDefault("digest.at", cfg.Digest.At)
+	v.SetDefault("digest.weekday", cfg.Digest.Weekday)
+	v.SetDefault("digest.skip_empty", cfg.Digest.SkipEmpty)
+	v.SetDefault("digest.max_items", cfg.Digest.MaxItems)
 	v.SetDefault("gateway.enabled", cfg.Gateway.Enabled)
 	v.SetDefault("gateway.port", cfg.Gateway.Port)
+	v.SetDefault("gateway.host", cfg.Gateway.Host)
+	v.SetDefault("gateway.tls.autocert_cache_dir", cfg.Gateway.TLS.AutocertCacheDir)
+	v.SetDefault("gateway.grpc.enabled", cfg.Gateway.GRPC.Enabled)
+	v.SetDefault("gateway.grpc.port", cfg.Gateway.GRPC.Port)
+	v.SetDefault("gateway.grpc.reflection", cfg.Gateway.GRPC.Reflection)
+	v.SetDefault("gateway.auth.enabled", cfg.Gateway.Auth.Enabled)
+	v.SetDefault("gateway.auth.jwt_secret_env", cfg.Gateway.Auth.JWTSecretEnv)
+	v.SetDefault("gateway.auth.issuer", cfg.Gateway.Auth.Issuer)
+	v.SetDefault("gateway.auth.token_ttl", cfg.Gateway.Auth.TokenTTL)
 	v.SetDefault("logging.level", cfg.Logging.Level)
 	v.SetDefault("logging.format", cfg.Logging.Format)
+	v.SetDefault("logging.prompt.mode", cfg.Logging.Prompt.Mode)
+	v.SetDefault("logging.prompt.path", cfg.Logging.Prompt.Path)
+	v.SetDefault("channels.dedup_ttl", cfg.Channels.DedupTTL)
 }
 
 // Validate validates the configuration.
@@ -158,28 +1857,416 @@ func (c *Config) Validate() error {
 	if c.Agent.Workspace == "" {
 		return fmt.Errorf("agent.workspace 是必需的")
 	}
+	for name, w := range c.Agent.Workspaces {
+		if name == workspace.DefaultName {
+			return fmt.Errorf("agent.workspaces 不能定义 %s，默认工作目录由 agent.workspace 配置", name)
+		}
+		if w.Path == "" {
+			return fmt.Errorf("agent.workspaces.%s.path 不能为空", name)
+		}
+	}
+	if _, err := c.Agent.BuildMounts(); err != nil {
+		return err
+	}
 	if c.Database.Path == "" {
 		return fmt.Errorf("database.path 是必需的")
 	}
+	if e := c.Database.Encryption; e.Enabled && e.KeyEnv == "" && e.KeyCommand == "" {
+		return fmt.Errorf("database.encryption 需要设置 key_env 或 key_command")
+	}
+	if c.Agent.OfflineQueue && c.Agent.OfflineRetryInterval < time.Second {
+		return fmt.Errorf("agent.offline_retry_interval 不能小于 1s")
+	}
+	if c.Agent.OfflineReplayInterval < 0 {
+		return fmt.Errorf("agent.offline_replay_interval 不能为负数")
+	}
+	if c.Agent.StatusInterval != 0 && c.Agent.StatusInterval < time.Second {
+		return fmt.Errorf("agent.status_interval 不能小于 1s")
+	}
+	if c.Agent.TurnBudget != 0 && c.Agent.TurnBudget < time.Second {
+		return fmt.Errorf("agent.turn_budget 不能小于 1s")
+	}
+	if c.Channels.DedupTTL < 0 {
+		return fmt.Errorf("channels.dedup_ttl 不能为负数")
+	}
+	if f := c.Channels.Feishu; f.Enabled {
+		if f.AppID == "" || f.AppSecret == "" {
+			return fmt.Errorf("channels.feishu 需要配置 app_id 和 app_secret")
+		}
+		if f.Mode != "" && f.Mode != "websocket" && f.Mode != "webhook" {
+			return fmt.Errorf("channels.feishu.mode 只能是 websocket 或 webhook")
+		}
+	}
+	if d := c.Channels.DingTalk; d.Enabled && (d.ClientID == "" || d.ClientSecret == "") {
+		return fmt.Errorf("channels.dingtalk 需要配置 client_id 和 client_secret")
+	}
+	if w := c.Channels.WeCom; w.Enabled {
+		if w.CorpID == "" || w.Secret == "" || w.AgentID == 0 {
+			return fmt.Errorf("channels.wecom 需要配置 corp_id、secret 和 agent_id")
+		}
+		if w.Token == "" || len(w.EncodingAESKey) != 43 {
+			return fmt.Errorf("channels.wecom 需要配置 token 和 43 位的 encoding_aes_key")
+		}
+		if w.MessageType != "" && w.MessageType != "markdown" && w.MessageType != "text" {
+			return fmt.Errorf("channels.wecom.message_type 只能是 markdown 或 text")
+		}
+	}
+	if q := c.Channels.QQ; q.Enabled && (q.AppID == "" || q.AppSecret == "") {
+		return fmt.Errorf("channels.qq 需要配置 app_id 和 app_secret")
+	}
+	if c.Agent.ToolRepeatLimit < 0 {
+		return fmt.Errorf("agent.tool_repeat_limit 不能为负数")
+	}
+	if c.Agent.ToolTimeout < 0 {
+		return fmt.Errorf("agent.tool_timeout 不能为负数")
+	}
+	for name, timeout := range c.Agent.ToolTimeouts {
+		if timeout < 0 {
+			return fmt.Errorf("agent.tool_timeouts.%s 不能为负数", name)
+		}
+	}
+	if tc := c.Agent.ToolCondense; tc.Enabled {
+		if tc.Mode == "" || !(ToolCondenseRule{Mode: tc.Mode}).validMode() {
+			return fmt.Errorf("agent.tool_condense.mode 只能是 llm、rule 或 off")
+		}
+		if tc.Threshold <= 0 || tc.MaxTokens <= 0 || tc.MaxTokens >= tc.Threshold {
+			return fmt.Errorf("agent.tool_condense.threshold 和 max_tokens 必须大于 0，且 max_tokens 小于 threshold")
+		}
+		if tc.CacheMB < 0 || tc.CacheTTL < 0 {
+			return fmt.Errorf("agent.tool_condense 的缓存配置不能为负数")
+		}
+		if tc.Model != "" && !strings.Contains(tc.Model, "/") {
+			return fmt.Errorf("agent.tool_condense.model 格式应为 提供商/模型")
+		}
+		for name, r := range tc.Tools {
+			if !r.validMode() {
+				return fmt.Errorf("agent.tool_condense.tools.%s.mode 只能是 llm、rule 或 off", name)
+			}
+			if r.Threshold < 0 {
+				return fmt.Errorf("agent.tool_condense.tools.%s.threshold 不能为负数", name)
+			}
+		}
+	}
+	if err := shell.ValidateShell(c.Agent.Exec.Shell); err != nil {
+		return fmt.Errorf("agent.exec.shell 配置错误: %w", err)
+	}
+	if err := c.Agent.Exec.EnvConfig().Validate(); err != nil {
+		return fmt.Errorf("agent.exec 配置错误: %w", err)
+	}
+	if _, err := c.Agent.Exec.Policy(); err != nil {
+		return fmt.Errorf("agent.exec 配置错误: %w", err)
+	}
+	if c.Agent.Exec.OutputTailKB < 1 {
+		return fmt.Errorf("agent.exec.output_tail_kb 必须大于 0")
+	}
+	if err := file.ValidateIgnorePatterns(c.Agent.Ignore.Patterns); err != nil {
+		return fmt.Errorf("agent.ignore.patterns 配置错误: %w", err)
+	}
+	if p := c.Agent.ToolPermissions; p.Enabled {
+		names := make(map[string]bool, len(p.Rules))
+		for _, r := range p.Rules {
+			if err := r.Validate(); err != nil {
+				return fmt.Errorf("agent.tool_permissions 配置错误: %w", err)
+			}
+			if names[r.Name] {
+				return fmt.Errorf("agent.tool_permissions 中的规则 %s 重复", r.Name)
+			}
+			names[r.Name] = true
+		}
+	}
+	if h := c.Agent.Hooks; h.Enabled && h.Timeout < 10*time.Millisecond {
+		return fmt.Errorf("agent.hooks.timeout 不能小于 10ms")
+	}
+	if p := c.Agent.Plugins; p.Enabled && p.Dir == "" {
+		return fmt.Errorf("agent.plugins.dir 是必需的")
+	}
+	if h := c.Agent.Plugins.Health; h.Interval < 0 || h.SelfTestInterval < 0 || h.FailureThreshold < 0 {
+		return fmt.Errorf("agent.plugins.health 的间隔和失败次数不能为负数")
+	}
+	if r := c.Agent.ProviderRetry; r.MaxRetries < 0 || r.InitialBackoff < 0 || r.MaxBackoff < r.InitialBackoff || r.MaxRetryAfter < 0 {
+		return fmt.Errorf("agent.provider_retry 配置错误: 次数和时间不能为负数，max_backoff 不能小于 initial_backoff")
+	}
+	if h := c.Agent.ProviderHealth; h.Enabled {
+		if h.FailureThreshold < 1 {
+			return fmt.Errorf("agent.provider_health.failure_threshold 必须大于 0")
+		}
+		if h.Interval < 0 || h.Cooldown < 0 || h.Timeout < 0 {
+			return fmt.Errorf("agent.provider_health 的时间配置不能为负数")
+		}
+	}
+	if d := c.Agent.MemoryDecay; d.HalfLife < 0 || d.ConsolidateInterval < 0 || d.ConsolidateMinAge < 0 {
+		return fmt.Errorf("agent.memory_decay 的时间配置不能为负数")
+	}
+	if d := c.Agent.MemoryDecay; d.AccessWeight < 0 || d.PinBonus < 0 || d.RecallLimit < 0 || d.ConsolidateBelow < 0 {
+		return fmt.Errorf("agent.memory_decay 的数值配置不能为负数")
+	}
+	if r := c.Agent.SemanticRecall; r.Enabled {
+		model := c.Agent.SemanticRecallModel()
+		if model == "" {
+			return fmt.Errorf("启用 agent.semantic_recall 时需要设置 model 或 agent.jobs.embedding_model")
+		}
+		if !strings.Contains(model, "/") {
+			return fmt.Errorf("agent.semantic_recall.model 格式应为 提供商/模型")
+		}
+		if r.TopK <= 0 {
+			return fmt.Errorf("agent.semantic_recall.top_k 必须大于 0")
+		}
+		if r.MinSimilarity < 0 || r.MinSimilarity > 1 {
+			return fmt.Errorf("agent.semantic_recall.min_similarity 应在 0 到 1 之间")
+		}
+		if r.EmbedMissing < 0 {
+			return fmt.Errorf("agent.semantic_recall.embed_missing 不能为负数")
+		}
+	}
+	if c.Agent.EntityGraph.RecallLimit < 0 {
+		return fmt.Errorf("agent.entity_graph.recall_limit 不能为负数")
+	}
+	if d := c.Agent.MemoryDigest; d.Enabled {
+		if d.Interval < time.Hour {
+			return fmt.Errorf("agent.memory_digest.interval 不能小于 1h")
+		}
+		if d.Channel == "" || d.SessionID == "" {
+			return fmt.Errorf("agent.memory_digest 需要配置 channel 和 session_id")
+		}
+	}
+	if h := c.Agent.Heartbeat; h.Enabled {
+		list, err := h.Heartbeats(c.Agent.Workspace)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return fmt.Errorf("agent.heartbeat 需要配置 channel 和 session_id，或至少一个 schedules")
+		}
+		if h.MaxConcurrent < 0 || h.RunTimeout < 0 {
+			return fmt.Errorf("agent.heartbeat.max_concurrent 和 run_timeout 不能为负数")
+		}
+	}
+	if _, err := c.Agent.Prompt.Location(); err != nil {
+		return fmt.Errorf("agent.prompt.timezone 配置错误: %w", err)
+	}
+	if c.Agent.ReplyLanguage != "" {
+		if _, err := language.Normalize(c.Agent.ReplyLanguage); err != nil {
+			return fmt.Errorf("agent.reply_language 配置错误: %w", err)
+		}
+	}
+	if _, err := c.Agent.ChannelLocations(); err != nil {
+		return fmt.Errorf("agent.channel_timezones 配置错误: %w", err)
+	}
+	if c.Agent.Prompt.WorkspaceEntries < 0 {
+		return fmt.Errorf("agent.prompt.workspace_entries 不能为负数")
+	}
+	if t := c.Agent.Trace; t.Enabled && t.Keep < 1 {
+		return fmt.Errorf("agent.trace.keep 必须大于 0")
+	}
+	if c.Agent.Trace.MaxResultChars < 0 {
+		return fmt.Errorf("agent.trace.max_result_chars 不能为负数")
+	}
+	if c.Agent.Ephemeral.IdleTimeout < 0 {
+		return fmt.Errorf("agent.ephemeral.idle_timeout 不能为负数")
+	}
+	if c.Agent.Jobs.BatchSize < 1 {
+		return fmt.Errorf("agent.jobs.batch_size 必须大于 0")
+	}
+	if c.Agent.Jobs.Interval < 0 {
+		return fmt.Errorf("agent.jobs.interval 不能为负数")
+	}
+	if m := c.Agent.Jobs.EmbeddingModel; m != "" && !strings.Contains(m, "/") {
+		return fmt.Errorf("agent.jobs.embedding_model 格式应为 提供商/模型")
+	}
+	if m := c.Agent.FAQ.Mode; m != faq.ModeExact && m != faq.ModeFuzzy {
+		return fmt.Errorf("agent.faq.mode 必须是 exact 或 fuzzy")
+	}
+	if t := c.Agent.FAQ.Threshold; t <= 0 || t > 1 {
+		return fmt.Errorf("agent.faq.threshold 必须在 0-1 之间")
+	}
+	if c.Agent.CostPreview.MinTokens < 0 || c.Agent.CostPreview.MinCost < 0 {
+		return fmt.Errorf("agent.cost_preview 的阈值不能为负数")
+	}
+	if c.Agent.CostPreview.ToolRounds < 0 {
+		return fmt.Errorf("agent.cost_preview.tool_rounds 不能为负数")
+	}
+	if c.Agent.CostPreview.Expire < time.Second {
+		return fmt.Errorf("agent.cost_preview.expire 不能小于 1s")
+	}
+	if wp := c.Agent.WorkerPool; wp.Enabled && (wp.Workers < 1 || wp.MaxQueued < 0 || wp.NotifyInterval < 0) {
+		return fmt.Errorf("agent.worker_pool.workers 必须大于 0，max_queued 和 notify_interval 不能为负数")
+	}
+	if c.Agent.AskUser.Enabled && c.Agent.AskUser.Timeout < time.Second {
+		return fmt.Errorf("agent.ask_user.timeout 不能小于 1s")
+	}
+	if st := c.Agent.SkillTriggers; st.Enabled && (st.MaxActive < 1 || st.AuditKeep < 0) {
+		return fmt.Errorf("agent.skill_triggers.max_active 必须大于 0，audit_keep 不能为负数")
+	}
+	if c.Cluster.Enabled && c.Cluster.LeaseTTL < 3*time.Second {
+		return fmt.Errorf("cluster.lease_ttl 不能小于 3s")
+	}
+	if !update.ValidChannel(c.Update.Channel) {
+		return fmt.Errorf("update.channel 只能是 %s 或 %s", update.ChannelStable, update.ChannelBeta)
+	}
+	if e := c.Update.Endpoint; e != "" {
+		if u, err := url.Parse(e); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("update.endpoint 必须是 http(s) 地址")
+		}
+	}
+	if c.Agent.Templates.Profile == "" {
+		return fmt.Errorf("agent.templates.profile 不能为空")
+	}
+	if d := c.Agent.Diagram; d.Enabled {
+		if u, err := url.Parse(d.KrokiURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("agent.diagram.kroki_url 必须是 http(s) 地址")
+		}
+	}
 	if c.Gateway.Enabled && (c.Gateway.Port <= 0 || c.Gateway.Port > 65535) {
 		return fmt.Errorf("gateway.port 必须在 1 到 65535 之间")
 	}
+	if err := c.Gateway.validate(); err != nil {
+		return err
+	}
+	if _, err := postprocess.New(c.PostProcess.Rules); err != nil {
+		return fmt.Errorf("postprocess.rules 配置错误: %w", err)
+	}
+	if _, err := routing.New(c.Routing.Rules); err != nil {
+		return fmt.Errorf("routing.rules 配置错误: %w", err)
+	}
+	if c.Forms.Expire < 0 || c.Forms.MaxAttempts < 0 {
+		return fmt.Errorf("forms.expire 和 forms.max_attempts 不能为负数")
+	}
+	if _, err := form.New(c.Forms.Flows, c.Forms.Options()); err != nil {
+		return fmt.Errorf("forms.flows 配置错误: %w", err)
+	}
+	if c.Notices.Language != "" {
+		if _, err := language.Normalize(c.Notices.Language); err != nil {
+			return fmt.Errorf("notices.language 配置错误: %w", err)
+		}
+	}
+	if _, err := notice.New(c.Notices.Language, c.Notices.Templates, nil); err != nil {
+		return fmt.Errorf("notices.templates 配置错误: %w", err)
+	}
+	host, _, err := net.SplitHostPort(c.ToolServer.Addr)
+	if err != nil {
+		return fmt.Errorf("toolserver.addr 格式错误: %w", err)
+	}
+	// 工具服务可以执行命令和脚本，未启用认证时只允许本机访问
+	if !c.Gateway.Auth.Enabled && !isLoopbackHost(host) {
+		return fmt.Errorf("toolserver.addr 监听非本机地址 %q 时必须启用 gateway.auth，或将主机设为 127.0.0.1", c.ToolServer.Addr)
+	}
+	if d := c.Digest; d.Enabled {
+		if _, err := d.Schedule(); err != nil {
+			return fmt.Errorf("digest 配置错误: %w", err)
+		}
+		if len(d.Recipients) == 0 {
+			return fmt.Errorf("digest.recipients 不能为空")
+		}
+		for _, addr := range d.Recipients {
+			if _, err := netmail.ParseAddress(addr); err != nil {
+				return fmt.Errorf("digest.recipients 地址无效 %q: %w", addr, err)
+			}
+		}
+		if err := c.SMTP.Mail().Validate(); err != nil {
+			return fmt.Errorf("smtp 配置错误: %w", err)
+		}
+		if u := d.WebURL; u != "" {
+			if pu, err := url.Parse(u); err != nil || (pu.Scheme != "http" && pu.Scheme != "https") || pu.Host == "" {
+				return fmt.Errorf("digest.web_url 必须是 http(s) 地址")
+			}
+		}
+		if !c.Agent.Trace.Enabled {
+			return fmt.Errorf("digest 需要启用 agent.trace")
+		}
+	}
+	if a := c.Audit; a.Enabled {
+		if a.Keep < 0 {
+			return fmt.Errorf("audit.keep 不能为负数")
+		}
+		if _, err := utils.NewRedactor(a.Redact); err != nil {
+			return fmt.Errorf("audit.redact 配置错误: %w", err)
+		}
+	}
+	mode, err := providers.ParsePromptLogMode(c.Logging.Prompt.Mode)
+	if err != nil {
+		return fmt.Errorf("logging.prompt.mode 配置错误: %w", err)
+	}
+	if mode != providers.PromptLogOff && c.Logging.Prompt.Path == "" {
+		return fmt.Errorf("logging.prompt.path 是必需的")
+	}
+	if _, err := utils.NewRedactor(c.Logging.Prompt.Redact); err != nil {
+		return fmt.Errorf("logging.prompt.redact 配置错误: %w", err)
+	}
 	return nil
 }
 
-// EnsureWorkspace ensures the workspace directory exists.
+// isLoopbackHost 判断监听主机是否只对本机开放，空主机监听所有地址。
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// validate validates the gateway listen and TLS configuration.
+func (g *GatewayConfig) validate() error {
+	if g.Host != "" && net.ParseIP(strings.Trim(g.Host, "[]")) == nil && g.Host != "localhost" {
+		return fmt.Errorf("gateway.host 必须是 IP 地址或 localhost: %s", g.Host)
+	}
+	for _, proxy := range g.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("gateway.trusted_proxies 格式错误: %s", proxy)
+		}
+	}
+
+	tls := g.TLS
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		return fmt.Errorf("gateway.tls.cert_file 和 gateway.tls.key_file 必须同时配置")
+	}
+	if tls.CertFile != "" && len(tls.AutocertDomains) > 0 {
+		return fmt.Errorf("gateway.tls 证书文件与 autocert 不能同时配置")
+	}
+	if len(tls.AutocertDomains) > 0 && g.UnixSocket != "" {
+		return fmt.Errorf("gateway.tls.autocert_domains 不支持 unix socket 监听")
+	}
+
+	if g.GRPC.Enabled {
+		if g.GRPC.Port <= 0 || g.GRPC.Port > 65535 {
+			return fmt.Errorf("gateway.grpc.port 必须在 1 到 65535 之间")
+		}
+		if g.GRPC.Port == g.Port && g.UnixSocket == "" {
+			return fmt.Errorf("gateway.grpc.port 不能与 gateway.port 相同")
+		}
+		// gRPC 可以远程执行工具，未启用认证时只允许本机访问
+		if !g.Auth.Enabled && !isLoopbackHost(g.Host) {
+			return fmt.Errorf("gateway.grpc 监听非本机地址 %q 时必须启用 gateway.auth，或将 gateway.host 设为 127.0.0.1", g.Host)
+		}
+	}
+
+	if _, err := g.Auth.Authenticator(); err != nil {
+		return fmt.Errorf("gateway.auth 配置错误: %w", err)
+	}
+	return nil
+}
+
+// EnsureWorkspace ensures the default and named workspace directories exist.
 func (c *Config) EnsureWorkspace() error {
 	if err := os.MkdirAll(c.Agent.Workspace, 0755); err != nil {
 		return fmt.Errorf("创建工作目录失败: %w", err)
 	}
+	for name, w := range c.Agent.Workspaces {
+		if err := os.MkdirAll(w.Path, 0755); err != nil {
+			return fmt.Errorf("创建工作目录 %s 失败: %w", name, err)
+		}
+	}
 	return nil
 }
 
-// EnsureDatabasePath ensures the database directory exists.
+// EnsureDatabasePath ensures the database directories exist.
 func (c *Config) EnsureDatabasePath() error {
-	dir := filepath.Dir(c.Database.Path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("创建数据库目录失败:%w", err)
+	for _, path := range []string{c.Database.Path, c.Database.HistoryPath} {
+		if path == "" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("创建数据库目录失败:%w", err)
+		}
 	}
 	return nil
 }
@@ -193,3 +2280,25 @@ func (c *Config) GetWorkspacePath() (string, error) {
 func (c *Config) GetDatabasePath() (string, error) {
 	return filepath.Abs(c.Database.Path)
 }
+
+// GetHistoryDatabasePath returns the absolute path to the history database,
+// or an empty string when history shares the main database.
+func (c *Config) GetHistoryDatabasePath() (string, error) {
+	if c.Database.HistoryPath == "" {
+		return "", nil
+	}
+	return filepath.Abs(c.Database.HistoryPath)
+}
+
+// StorageOptions returns the storage options for the database configuration, loading the encryption keys.
+func (c *Config) StorageOptions() ([]storage.Option, error) {
+	cipher, err := c.Database.Encryption.Cipher()
+	if err != nil {
+		return nil, fmt.Errorf("加载数据库加密密钥失败: %w", err)
+	}
+	historyPath, err := c.GetHistoryDatabasePath()
+	if err != nil {
+		return nil, fmt.Errorf("解析会话历史数据库路径失败: %w", err)
+	}
+	return []storage.Option{storage.WithCipher(cipher), storage.WithHistoryPath(historyPath)}, nil
+}
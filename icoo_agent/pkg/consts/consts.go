@@ -54,6 +54,7 @@ const (
 	ProviderQwenCodingPlan ProviderType = "qwen_coding_plan"
 	ProviderSiliconFlow    ProviderType = "siliconflow"
 	ProviderGrok           ProviderType = "grok"
+	ProviderBaichuan       ProviderType = "baichuan"
 )
 
 func (p ProviderType) ToString() string {
@@ -70,6 +71,43 @@ func (p ProviderType) String() string {
 
 const DEFAULT_AGENT_NAME = "default"
 
+// 入站消息元数据键
+const (
+	// META_OFFLINE_REPLAY 离线队列重放的消息
+	META_OFFLINE_REPLAY = "offline_replay"
+	// META_HISTORY_SAVED 用户消息已写入历史，构建消息时不再重复保存
+	META_HISTORY_SAVED = "history_saved"
+	// META_PERSONA 路由规则指定处理本条消息的人设
+	META_PERSONA = "persona"
+	// META_COST_APPROVED 用户已确认本条消息的预计用量，不再预估
+	META_COST_APPROVED = "cost_approved"
+	// META_FORM 填写完成的表单名称，消息内容为表单内容，不再经过路由规则和常见问题
+	META_FORM = "form"
+	// META_ASK_ANSWER 对智能体提问的回答（*ask.Answer），消息内容为暂停时的用户消息，从暂停处继续本轮
+	META_ASK_ANSWER = "ask_answer"
+	// META_SKILL 触发规则为本条消息激活的技能名称（[]string），技能内容注入系统提示词
+	META_SKILL = "skill"
+	// META_HEARTBEAT 调度器发起的心跳检查，不计入用户活跃，无事可报时不发送回复
+	META_HEARTBEAT = "heartbeat"
+	// META_HEARTBEAT_ID 心跳标识，处理完成后随 heartbeat.done 事件返回，调度器据此释放并发名额
+	META_HEARTBEAT_ID = "heartbeat_id"
+)
+
+// HEARTBEAT_OK 心跳检查没有需要告知用户的事项时模型的回复，不会发送给用户
+const HEARTBEAT_OK = "HEARTBEAT_OK"
+
+// 出站消息元数据键
+const (
+	// META_STATUS 工具执行进度心跳，渠道可据此刷新输入状态而非当作回复发送
+	META_STATUS = "status"
+	// META_ACTIONS 快捷操作列表（[]{label, command}），支持按钮的渠道可渲染为按钮，点击后以命令文本回传
+	META_ACTIONS = "actions"
+	// META_ATTACHMENTS 随回复发送的文件（[]{id, name, path, mime_type, size}），支持文件的渠道作为附件发送
+	META_ATTACHMENTS = "attachments"
+	// META_CHANGES 本轮工具对工作目录的修改汇总（*tools.ChangeSummary），类似 git diff --stat
+	META_CHANGES = "changes"
+)
+
 // GetSessionKey 生成会话键，格式: channel:sessionID
 func GetSessionKey(channel, sessionID string) string {
 	return fmt.Sprintf("%s:%s", channel, sessionID)
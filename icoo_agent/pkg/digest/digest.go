@@ -0,0 +1,258 @@
+// Package digest builds and emails periodic summaries of agent activity.
+//
+// 摘要按对话轨迹统计周期内处理的会话、值得关注的工具调用（执行命令、写文件、网络请求等）、
+// 按模型价格估算的费用和失败的对话，并附上等待用户确认的请求。配置了网关地址时每条记录附带
+// 轨迹报告链接，配置了会话链接模板时附带会话链接，供不关注聊天渠道的管理员了解智能体的运行情况。
+package digest
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/trace"
+)
+
+// DefaultNotableTools 默认值得关注的工具：会修改文件、执行命令或访问网络的工具。
+var DefaultNotableTools = []string{"shell_command", "write_file", "copy_file", "apply_patch", "filesystem", "git_commit", "http_request", "download_file"}
+
+// 摘要中文本的截断长度
+const (
+	argumentsLimit = 120
+	inputLimit     = 60
+	errorLimit     = 200
+)
+
+// Options 摘要内容选项。
+type Options struct {
+	MaxItems     int      // 每个列表最多列出的条数，默认 10
+	NotableTools []string // 值得关注的工具，为空时使用 DefaultNotableTools
+	WebURL       string   // 网关地址，用于生成轨迹报告链接
+	SessionLink  string   // 会话链接模板，{{channel}} 和 {{session_id}} 替换为会话的渠道和 ID
+}
+
+// Session 周期内的一个会话。
+type Session struct {
+	Channel   string    `json:"channel"`
+	SessionID string    `json:"session_id"`
+	Turns     int       `json:"turns"`      // 对话轮数
+	ToolCalls int       `json:"tool_calls"` // 工具调用次数
+	Tokens    int       `json:"tokens"`     // Token 用量
+	Errors    int       `json:"errors"`     // 失败的对话轮数
+	LastAt    time.Time `json:"last_at"`    // 最后一轮对话的时间
+	Link      string    `json:"link,omitempty"`
+}
+
+// ModelSpend 单个模型的用量和费用。
+type ModelSpend struct {
+	Model            string  `json:"model"`
+	Turns            int     `json:"turns"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`   // 估算费用（美元）
+	Priced           bool    `json:"priced"` // 模型价格是否已知
+}
+
+// ToolCount 工具调用次数。
+type ToolCount struct {
+	Tool  string `json:"tool"`
+	Count int    `json:"count"`
+}
+
+// Action 一次值得关注的工具调用。
+type Action struct {
+	Time      time.Time `json:"time"`
+	Channel   string    `json:"channel"`
+	SessionID string    `json:"session_id"`
+	Tool      string    `json:"tool"`
+	Arguments string    `json:"arguments"` // 截断后的参数
+	Link      string    `json:"link,omitempty"`
+}
+
+// Failure 一轮失败的对话。
+type Failure struct {
+	Time      time.Time `json:"time"`
+	Channel   string    `json:"channel"`
+	SessionID string    `json:"session_id"`
+	Input     string    `json:"input"` // 截断后的用户消息
+	Error     string    `json:"error"` // 截断后的错误信息
+	Link      string    `json:"link,omitempty"`
+}
+
+// Pending 等待用户确认的请求。
+type Pending struct {
+	Channel   string    `json:"channel"`
+	SessionID string    `json:"session_id"`
+	Model     string    `json:"model"`
+	Tokens    int       `json:"tokens"`  // 预计 token 数
+	Cost      float64   `json:"cost"`    // 预计费用（美元），模型价格未知时为 0
+	Expires   time.Time `json:"expires"` // 确认的截止时间
+	Link      string    `json:"link,omitempty"`
+}
+
+// Report 一个周期的活动摘要。
+type Report struct {
+	From         time.Time    `json:"from"`
+	To           time.Time    `json:"to"`
+	Turns        int          `json:"turns"`         // 对话轮数
+	ToolCalls    int          `json:"tool_calls"`    // 工具调用次数
+	Tokens       int          `json:"tokens"`        // Token 用量
+	Cost         float64      `json:"cost"`          // 价格已知模型的估算费用（美元）
+	SessionCount int          `json:"session_count"` // 会话数
+	Sessions     []Session    `json:"sessions"`      // 对话轮数最多的会话
+	Models       []ModelSpend `json:"models"`        // 按费用排序的模型用量
+	Tools        []ToolCount  `json:"tools"`         // 值得关注的工具的调用次数
+	ActionCount  int          `json:"action_count"`  // 值得关注的工具调用次数
+	Actions      []Action     `json:"actions"`       // 最近的值得关注的工具调用
+	ErrorCount   int          `json:"error_count"`   // 失败的对话轮数
+	Failures     []Failure    `json:"failures"`      // 最近失败的对话
+	Pending      []Pending    `json:"pending"`       // 等待确认的请求
+	WebURL       string       `json:"web_url,omitempty"`
+}
+
+// Build 汇总 [from, to) 内的对话轨迹，traces 按时间升序。
+func Build(traces []*storage.Trace, pending []Pending, from, to time.Time, opts Options) *Report {
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = 10
+	}
+	notable := opts.NotableTools
+	if len(notable) == 0 {
+		notable = DefaultNotableTools
+	}
+
+	r := &Report{From: from, To: to, WebURL: strings.TrimRight(opts.WebURL, "/")}
+	sessions := make(map[string]*Session)
+	models := make(map[string]*ModelSpend)
+	toolCounts := make(map[string]int)
+
+	for _, t := range traces {
+		r.Turns++
+		r.ToolCalls += t.ToolCalls
+		r.Tokens += t.TotalTokens
+
+		key := t.Channel + "\x00" + t.SessionID
+		s, ok := sessions[key]
+		if !ok {
+			s = &Session{Channel: t.Channel, SessionID: t.SessionID, Link: opts.sessionLink(t.Channel, t.SessionID)}
+			sessions[key] = s
+		}
+		s.Turns++
+		s.ToolCalls += t.ToolCalls
+		s.Tokens += t.TotalTokens
+		s.LastAt = t.CreatedAt
+
+		link := r.traceLink(t.ID)
+		if t.Error != "" {
+			r.ErrorCount++
+			s.Errors++
+			input, _ := trace.Truncate(oneLine(t.Input), inputLimit)
+			msg, _ := trace.Truncate(oneLine(t.Error), errorLimit)
+			r.Failures = append(r.Failures, Failure{Time: t.CreatedAt, Channel: t.Channel, SessionID: t.SessionID,
+				Input: input, Error: msg, Link: link})
+		}
+
+		// 轨迹详情中有分别的输入输出用量和工具调用，解析失败时只统计列表字段
+		turn, err := trace.Decode(t.Data)
+		if err != nil {
+			continue
+		}
+		m, ok := models[t.ModelName]
+		if !ok {
+			m = &ModelSpend{Model: t.ModelName}
+			models[t.ModelName] = m
+		}
+		m.Turns++
+		m.PromptTokens += turn.Usage.PromptTokens
+		m.CompletionTokens += turn.Usage.CompletionTokens
+
+		for _, it := range turn.Iterations {
+			for _, tc := range it.ToolCalls {
+				if !slices.Contains(notable, tc.Name) {
+					continue
+				}
+				toolCounts[tc.Name]++
+				r.ActionCount++
+				args, _ := trace.Truncate(oneLine(tc.Arguments), argumentsLimit)
+				r.Actions = append(r.Actions, Action{Time: t.CreatedAt, Channel: t.Channel, SessionID: t.SessionID,
+					Tool: tc.Name, Arguments: args, Link: link})
+			}
+		}
+	}
+
+	for _, m := range models {
+		if cost, err := providers.CalculateCost(m.Model, m.PromptTokens, m.CompletionTokens); err == nil {
+			m.Cost, m.Priced = cost, true
+			r.Cost += cost
+		}
+		r.Models = append(r.Models, *m)
+	}
+	slices.SortFunc(r.Models, func(a, b ModelSpend) int {
+		return cmp.Or(cmp.Compare(b.Cost, a.Cost), cmp.Compare(b.PromptTokens+b.CompletionTokens, a.PromptTokens+a.CompletionTokens), cmp.Compare(a.Model, b.Model))
+	})
+
+	r.SessionCount = len(sessions)
+	for _, s := range sessions {
+		r.Sessions = append(r.Sessions, *s)
+	}
+	slices.SortFunc(r.Sessions, func(a, b Session) int {
+		return cmp.Or(cmp.Compare(b.Turns, a.Turns), b.LastAt.Compare(a.LastAt))
+	})
+	r.Sessions = r.Sessions[:min(len(r.Sessions), opts.MaxItems)]
+
+	for tool, n := range toolCounts {
+		r.Tools = append(r.Tools, ToolCount{Tool: tool, Count: n})
+	}
+	slices.SortFunc(r.Tools, func(a, b ToolCount) int { return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Tool, b.Tool)) })
+
+	// 只保留最近的记录，最新的在前
+	r.Actions = latest(r.Actions, opts.MaxItems)
+	r.Failures = latest(r.Failures, opts.MaxItems)
+
+	for _, p := range pending {
+		p.Link = opts.sessionLink(p.Channel, p.SessionID)
+		r.Pending = append(r.Pending, p)
+	}
+	slices.SortFunc(r.Pending, func(a, b Pending) int { return a.Expires.Compare(b.Expires) })
+	return r
+}
+
+// IsEmpty 周期内没有对话也没有等待确认的请求。
+func (r *Report) IsEmpty() bool {
+	return r.Turns == 0 && len(r.Pending) == 0
+}
+
+// traceLink 返回轨迹报告链接，未配置网关地址时为空。
+func (r *Report) traceLink(id string) string {
+	if r.WebURL == "" || id == "" {
+		return ""
+	}
+	return r.WebURL + "/api/v1/traces/export?" + url.Values{"id": {id}, "format": {trace.FormatHTML}}.Encode()
+}
+
+// sessionLink 按模板生成会话链接，未配置模板时为空。
+func (o Options) sessionLink(channel, sessionID string) string {
+	if o.SessionLink == "" {
+		return ""
+	}
+	return tools.Vars{
+		"channel":    url.PathEscape(channel),
+		"session_id": url.PathEscape(sessionID),
+	}.Interpolate(o.SessionLink)
+}
+
+// latest 返回最后 n 条记录，最新的在前。
+func latest[T any](items []T, n int) []T {
+	items = items[max(len(items)-n, 0):]
+	slices.Reverse(items)
+	return items
+}
+
+// oneLine 将换行替换为空格。
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
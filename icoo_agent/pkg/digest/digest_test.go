@@ -0,0 +1,158 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/mail"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/trace"
+)
+
+func newTrace(t *testing.T, id, session, model string, at time.Time, usage providers.Usage, errMsg string, calls ...trace.ToolCall) *storage.Trace {
+	t.Helper()
+	data, err := json.Marshal(trace.Turn{Model: model, Usage: usage, Error: errMsg, Iterations: []trace.Iteration{{ToolCalls: calls}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &storage.Trace{
+		Model:       storage.Model{ID: id, CreatedAt: at},
+		Channel:     "websocket",
+		SessionID:   session,
+		ModelName:   model,
+		Input:       "帮我\n整理日志",
+		ToolCalls:   len(calls),
+		TotalTokens: usage.PromptTokens + usage.CompletionTokens,
+		Error:       errMsg,
+		Data:        string(data),
+	}
+}
+
+func TestBuild(t *testing.T) {
+	from := time.Date(2026, 3, 5, 8, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+	traces := []*storage.Trace{
+		newTrace(t, "t1", "s1", "gpt-4o", from.Add(time.Hour), providers.Usage{PromptTokens: 1_000_000, CompletionTokens: 100_000}, "",
+			trace.ToolCall{Name: "shell_command", Arguments: `{"command":"ls"}`},
+			trace.ToolCall{Name: "read_file", Arguments: `{"path":"a"}`}),
+		newTrace(t, "t2", "s1", "gpt-4o", from.Add(2*time.Hour), providers.Usage{}, "provider timeout"),
+		newTrace(t, "t3", "s2", "unknown-model", from.Add(3*time.Hour), providers.Usage{PromptTokens: 10}, "",
+			trace.ToolCall{Name: "write_file", Arguments: `{"path":"b"}`}),
+	}
+	pending := []Pending{{Channel: "feishu", SessionID: "s3", Model: "gpt-4o", Tokens: 50_000, Expires: to.Add(time.Minute)}}
+
+	r := Build(traces, pending, from, to, Options{
+		WebURL:      "https://claw.example.com/",
+		SessionLink: "https://claw.example.com/#/chat/{{channel}}/{{session_id}}",
+	})
+
+	if r.Turns != 3 || r.ToolCalls != 3 || r.SessionCount != 2 || r.ErrorCount != 1 || r.ActionCount != 2 {
+		t.Errorf("totals = %+v", r)
+	}
+	if r.Cost != 3.5 || len(r.Models) != 2 || r.Models[0].Model != "gpt-4o" || r.Models[1].Priced {
+		t.Errorf("models = %+v, cost = %v", r.Models, r.Cost)
+	}
+	if r.Sessions[0].SessionID != "s1" || r.Sessions[0].Turns != 2 || r.Sessions[0].Link != "https://claw.example.com/#/chat/websocket/s1" {
+		t.Errorf("sessions = %+v", r.Sessions)
+	}
+	if len(r.Actions) != 2 || r.Actions[0].Tool != "write_file" || r.Actions[1].Link != "https://claw.example.com/api/v1/traces/export?format=html&id=t1" {
+		t.Errorf("actions = %+v", r.Actions)
+	}
+	if len(r.Failures) != 1 || r.Failures[0].Input != "帮我 整理日志" || r.Failures[0].Error != "provider timeout" {
+		t.Errorf("failures = %+v", r.Failures)
+	}
+	if len(r.Pending) != 1 || r.Pending[0].Link != "https://claw.example.com/#/chat/feishu/s3" {
+		t.Errorf("pending = %+v", r.Pending)
+	}
+
+	if got := r.Subject(); got != "icooclaw 活动摘要 2026-03-05 ~ 2026-03-06（失败 1，待确认 1）" {
+		t.Errorf("Subject() = %q", got)
+	}
+	text := r.Text()
+	for _, want := range []string{"会话 2 个，对话 3 轮", "费用 $3.50（部分模型价格未知，未计入）", "- shell_command: 1 次", "feishu/s3 模型 gpt-4o 约 50000 tokens"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+	html, err := r.HTML()
+	if err != nil || !strings.Contains(html, `<a href="https://claw.example.com/api/v1/traces/export?format=html&amp;id=t1">`) {
+		t.Errorf("HTML() = %s, %v", html, err)
+	}
+}
+
+func TestBuild_Empty(t *testing.T) {
+	now := time.Now()
+	r := Build(nil, nil, now.Add(-time.Hour), now, Options{})
+	if !r.IsEmpty() || !strings.Contains(r.Text(), "本期没有对话") {
+		t.Errorf("empty report = %+v", r)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	if _, err := ParseSchedule("hourly", "08:00", 0, loc); err == nil {
+		t.Error("ParseSchedule() should reject unknown frequency")
+	}
+	if _, err := ParseSchedule("daily", "8点", 0, loc); err == nil {
+		t.Error("ParseSchedule() should reject bad time")
+	}
+
+	// 2026-03-05 为周四
+	now := time.Date(2026, 3, 5, 9, 30, 0, 0, loc)
+	daily, _ := ParseSchedule("daily", "08:00", 0, loc)
+	if got := daily.Next(now); !got.Equal(time.Date(2026, 3, 6, 8, 0, 0, 0, loc)) {
+		t.Errorf("daily Next() = %v", got)
+	}
+	from, to := daily.Window(now)
+	if !from.Equal(time.Date(2026, 3, 4, 8, 0, 0, 0, loc)) || !to.Equal(time.Date(2026, 3, 5, 8, 0, 0, 0, loc)) {
+		t.Errorf("daily Window() = %v, %v", from, to)
+	}
+
+	weekly, _ := ParseSchedule("weekly", "08:00", int(time.Monday), loc)
+	if got := weekly.Next(now); !got.Equal(time.Date(2026, 3, 9, 8, 0, 0, 0, loc)) {
+		t.Errorf("weekly Next() = %v", got)
+	}
+	from, to = weekly.Window(time.Date(2026, 3, 9, 8, 0, 0, 0, loc))
+	if !from.Equal(time.Date(2026, 3, 2, 8, 0, 0, 0, loc)) || !to.Equal(time.Date(2026, 3, 9, 8, 0, 0, 0, loc)) {
+		t.Errorf("weekly Window() at send time = %v, %v", from, to)
+	}
+}
+
+func TestRunner_Build(t *testing.T) {
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "digest.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	now := time.Now()
+	for _, tr := range []*storage.Trace{
+		newTrace(t, "", "s1", "gpt-4o", now.Add(-2*time.Hour), providers.Usage{}, ""),
+		newTrace(t, "", "s1", "gpt-4o", now.Add(-48*time.Hour), providers.Usage{}, ""),
+	} {
+		if err := s.Trace().Save(tr); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	schedule, _ := ParseSchedule("daily", "00:00", 0, time.Local)
+	r := NewRunner(s, schedule, Options{}, mail.Config{}, []string{"admin@example.com"}, true, nil)
+	r.SetPending(func() []Pending { return []Pending{{Channel: "websocket", SessionID: "s9"}} })
+	report, err := r.Build(now.Add(-24*time.Hour), now)
+	if err != nil || report.Turns != 1 || len(report.Pending) != 1 {
+		t.Errorf("Build() = %+v, %v", report, err)
+	}
+
+	if _, ok := r.lastSent(); ok {
+		t.Error("lastSent() before any send should be unset")
+	}
+	r.check(context.Background(), now)
+	if last, ok := r.lastSent(); !ok || !last.Equal(schedule.Next(now).AddDate(0, 0, -1)) {
+		t.Errorf("first check should record the current period, got %v, %v", last, ok)
+	}
+}
@@ -0,0 +1,168 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// 摘要中的时间格式
+const (
+	dateLayout = "2006-01-02"
+	timeLayout = "01-02 15:04"
+)
+
+// Subject 邮件标题。
+func (r *Report) Subject() string {
+	subject := fmt.Sprintf("icooclaw 活动摘要 %s", r.Period())
+	if r.ErrorCount > 0 || len(r.Pending) > 0 {
+		subject += fmt.Sprintf("（失败 %d，待确认 %d）", r.ErrorCount, len(r.Pending))
+	}
+	return subject
+}
+
+// Period 格式化摘要周期，to 为开区间，显示为前一天。
+func (r *Report) Period() string {
+	from, to := r.From.Format(dateLayout), r.To.Add(-time.Second).Format(dateLayout)
+	if from == to {
+		return from
+	}
+	return from + " ~ " + to
+}
+
+// CostText 格式化费用，有价格未知的模型时注明。
+func (r *Report) CostText() string {
+	text := fmt.Sprintf("$%.2f", r.Cost)
+	for _, m := range r.Models {
+		if !m.Priced {
+			return text + "（部分模型价格未知，未计入）"
+		}
+	}
+	return text
+}
+
+// Text 渲染纯文本摘要。
+func (r *Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "icooclaw 活动摘要 %s\n\n", r.Period())
+	fmt.Fprintf(&b, "会话 %d 个，对话 %d 轮，工具调用 %d 次，Token %d，费用 %s\n",
+		r.SessionCount, r.Turns, r.ToolCalls, r.Tokens, r.CostText())
+	fmt.Fprintf(&b, "失败 %d 轮，值得关注的操作 %d 次，等待确认 %d 个\n", r.ErrorCount, r.ActionCount, len(r.Pending))
+	if r.WebURL != "" {
+		fmt.Fprintf(&b, "管理界面: %s\n", r.WebURL)
+	}
+
+	if len(r.Pending) > 0 {
+		b.WriteString("\n## 等待确认\n")
+		for _, p := range r.Pending {
+			fmt.Fprintf(&b, "- %s/%s 模型 %s 约 %d tokens", p.Channel, p.SessionID, p.Model, p.Tokens)
+			if p.Cost > 0 {
+				fmt.Fprintf(&b, " ≈ $%.2f", p.Cost)
+			}
+			fmt.Fprintf(&b, "，%s 前确认%s\n", p.Expires.Format(timeLayout), linkSuffix(p.Link))
+		}
+	}
+
+	if len(r.Failures) > 0 {
+		fmt.Fprintf(&b, "\n## 失败的对话（最近 %d 条）\n", len(r.Failures))
+		for _, f := range r.Failures {
+			fmt.Fprintf(&b, "- %s %s/%s「%s」: %s%s\n", f.Time.Format(timeLayout), f.Channel, f.SessionID, f.Input, f.Error, linkSuffix(f.Link))
+		}
+	}
+
+	if len(r.Tools) > 0 {
+		b.WriteString("\n## 值得关注的操作\n")
+		for _, t := range r.Tools {
+			fmt.Fprintf(&b, "- %s: %d 次\n", t.Tool, t.Count)
+		}
+		fmt.Fprintf(&b, "最近 %d 次:\n", len(r.Actions))
+		for _, a := range r.Actions {
+			fmt.Fprintf(&b, "- %s %s/%s %s %s%s\n", a.Time.Format(timeLayout), a.Channel, a.SessionID, a.Tool, a.Arguments, linkSuffix(a.Link))
+		}
+	}
+
+	if len(r.Models) > 0 {
+		b.WriteString("\n## 模型用量\n")
+		for _, m := range r.Models {
+			fmt.Fprintf(&b, "- %s: %d 轮，输入 %d / 输出 %d tokens，%s\n", m.Model, m.Turns, m.PromptTokens, m.CompletionTokens, modelCost(m))
+		}
+	}
+
+	if len(r.Sessions) > 0 {
+		fmt.Fprintf(&b, "\n## 最活跃的会话（共 %d 个）\n", r.SessionCount)
+		for _, s := range r.Sessions {
+			fmt.Fprintf(&b, "- %s/%s: %d 轮，工具 %d 次，Token %d", s.Channel, s.SessionID, s.Turns, s.ToolCalls, s.Tokens)
+			if s.Errors > 0 {
+				fmt.Fprintf(&b, "，失败 %d 轮", s.Errors)
+			}
+			fmt.Fprintf(&b, "，最后 %s%s\n", s.LastAt.Format(timeLayout), linkSuffix(s.Link))
+		}
+	}
+
+	if r.Turns == 0 {
+		b.WriteString("\n本期没有对话。\n")
+	}
+	return b.String()
+}
+
+// HTML 渲染 HTML 摘要。
+func (r *Report) HTML() (string, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, r); err != nil {
+		return "", fmt.Errorf("渲染摘要失败: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// linkSuffix 在纯文本中附加链接。
+func linkSuffix(link string) string {
+	if link == "" {
+		return ""
+	}
+	return " " + link
+}
+
+// modelCost 格式化模型费用。
+func modelCost(m ModelSpend) string {
+	if !m.Priced {
+		return "价格未知"
+	}
+	return fmt.Sprintf("$%.2f", m.Cost)
+}
+
+var htmlTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"time":      func(t time.Time) string { return t.Format(timeLayout) },
+	"modelCost": modelCost,
+	"cost":      func(c float64) string { return fmt.Sprintf("$%.2f", c) },
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head><meta charset="utf-8"><title>icooclaw 活动摘要 {{.Period}}</title></head>
+<body style="font-family:-apple-system,'Segoe UI','PingFang SC','Microsoft YaHei',sans-serif;color:#222;line-height:1.5;max-width:760px">
+<h2>icooclaw 活动摘要 {{.Period}}</h2>
+<p>会话 <b>{{.SessionCount}}</b> 个，对话 <b>{{.Turns}}</b> 轮，工具调用 <b>{{.ToolCalls}}</b> 次，Token <b>{{.Tokens}}</b>，费用 <b>{{.CostText}}</b><br>
+失败 <b{{if .ErrorCount}} style="color:#cf222e"{{end}}>{{.ErrorCount}}</b> 轮，值得关注的操作 <b>{{.ActionCount}}</b> 次，等待确认 <b>{{len .Pending}}</b> 个</p>
+{{if .WebURL}}<p><a href="{{.WebURL}}">打开管理界面</a></p>{{end}}
+{{define "link"}}{{if .}} <a href="{{.}}">查看</a>{{end}}{{end}}
+{{if .Pending}}<h3>等待确认</h3><ul>
+{{range .Pending}}<li>{{.Channel}}/{{.SessionID}} 模型 {{.Model}} 约 {{.Tokens}} tokens{{if .Cost}} ≈ {{cost .Cost}}{{end}}，{{time .Expires}} 前确认{{template "link" .Link}}</li>
+{{end}}</ul>{{end}}
+{{if .Failures}}<h3 style="color:#cf222e">失败的对话（最近 {{len .Failures}} 条）</h3><ul>
+{{range .Failures}}<li>{{time .Time}} {{.Channel}}/{{.SessionID}}「{{.Input}}」: <code>{{.Error}}</code>{{template "link" .Link}}</li>
+{{end}}</ul>{{end}}
+{{if .Tools}}<h3>值得关注的操作</h3>
+<p>{{range $i, $t := .Tools}}{{if $i}}，{{end}}{{$t.Tool}} {{$t.Count}} 次{{end}}</p><ul>
+{{range .Actions}}<li>{{time .Time}} {{.Channel}}/{{.SessionID}} <b>{{.Tool}}</b> <code>{{.Arguments}}</code>{{template "link" .Link}}</li>
+{{end}}</ul>{{end}}
+{{if .Models}}<h3>模型用量</h3>
+<table style="border-collapse:collapse" cellpadding="4" border="1"><tr><th>模型</th><th>轮数</th><th>输入</th><th>输出</th><th>费用</th></tr>
+{{range .Models}}<tr><td>{{.Model}}</td><td>{{.Turns}}</td><td>{{.PromptTokens}}</td><td>{{.CompletionTokens}}</td><td>{{modelCost .}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Sessions}}<h3>最活跃的会话（共 {{.SessionCount}} 个）</h3><ul>
+{{range .Sessions}}<li>{{.Channel}}/{{.SessionID}}: {{.Turns}} 轮，工具 {{.ToolCalls}} 次，Token {{.Tokens}}{{if .Errors}}，失败 {{.Errors}} 轮{{end}}，最后 {{time .LastAt}}{{template "link" .Link}}</li>
+{{end}}</ul>{{end}}
+{{if not .Turns}}<p>本期没有对话。</p>{{end}}
+</body>
+</html>
+`))
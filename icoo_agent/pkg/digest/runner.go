@@ -0,0 +1,191 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/mail"
+	"icooclaw/pkg/storage"
+)
+
+const (
+	// checkInterval 检查是否到达发送时间的间隔
+	checkInterval = time.Minute
+	// kvScope 记录上次发送的周期的键值作用域
+	kvScope = "system"
+	// kvKey 记录上次发送的周期终点的键
+	kvKey = "digest.last_sent"
+)
+
+// Runner 按计划生成并发送活动摘要邮件。
+type Runner struct {
+	store      *storage.Storage
+	schedule   Schedule
+	opts       Options
+	mail       mail.Config
+	recipients []string
+	skipEmpty  bool
+	logger     *slog.Logger
+
+	mu      sync.RWMutex
+	pending func() []Pending
+	leader  func() bool
+}
+
+// NewRunner 创建摘要发送器，skipEmpty 为 true 时周期内没有活动不发送。
+func NewRunner(store *storage.Storage, schedule Schedule, opts Options, mailCfg mail.Config, recipients []string, skipEmpty bool, logger *slog.Logger) *Runner {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Runner{
+		store:      store,
+		schedule:   schedule,
+		opts:       opts,
+		mail:       mailCfg,
+		recipients: recipients,
+		skipEmpty:  skipEmpty,
+		logger:     logger,
+	}
+}
+
+// SetPending 设置等待确认的请求的来源。
+func (r *Runner) SetPending(fn func() []Pending) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = fn
+}
+
+// SetLeader 设置主实例判断，多个实例共享数据库时只由主实例发送。
+func (r *Runner) SetLeader(fn func() bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leader = fn
+}
+
+// Schedule 返回发送时间。
+func (r *Runner) Schedule() Schedule {
+	return r.schedule
+}
+
+// Run 定期检查并发送摘要，直到 ctx 取消。
+// 首次运行只记录当前周期，之后每个周期结束后发送一次，停机错过的周期在启动后补发最近的一次。
+func (r *Runner) Run(ctx context.Context) {
+	r.logger.With("name", "【摘要】").Info("活动摘要已启动",
+		"next", r.schedule.Next(time.Now()).Format(time.DateTime),
+		"recipients", len(r.recipients))
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	r.check(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.check(ctx, now)
+		}
+	}
+}
+
+// check 最近一个已结束的周期尚未发送时发送摘要。
+func (r *Runner) check(ctx context.Context, now time.Time) {
+	if !r.isLeader() {
+		return
+	}
+	from, to := r.schedule.Window(now)
+	last, ok := r.lastSent()
+	if !ok {
+		r.saveSent(to)
+		return
+	}
+	if !last.Before(to) {
+		return
+	}
+
+	report, err := r.Send(ctx, from, to)
+	if err != nil {
+		r.logger.With("name", "【摘要】").Warn("发送活动摘要失败", "error", err, "period", fmt.Sprintf("%s ~ %s", from, to))
+		return
+	}
+	if report != nil {
+		r.logger.With("name", "【摘要】").Info("活动摘要已发送",
+			"turns", report.Turns,
+			"errors", report.ErrorCount,
+			"pending", len(report.Pending))
+	}
+	r.saveSent(to)
+}
+
+// Build 生成 [from, to) 的摘要。
+func (r *Runner) Build(from, to time.Time) (*Report, error) {
+	traces, err := r.store.Trace().ListBetween(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("加载对话轨迹失败: %w", err)
+	}
+	r.mu.RLock()
+	pendingFn := r.pending
+	r.mu.RUnlock()
+	var pending []Pending
+	if pendingFn != nil {
+		pending = pendingFn()
+	}
+	return Build(traces, pending, from.In(r.schedule.Location), to.In(r.schedule.Location), r.opts), nil
+}
+
+// Send 生成并发送 [from, to) 的摘要，设置了跳过空摘要且周期内没有活动时不发送并返回 nil。
+func (r *Runner) Send(ctx context.Context, from, to time.Time) (*Report, error) {
+	report, err := r.Build(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if r.skipEmpty && report.IsEmpty() {
+		return nil, nil
+	}
+	html, err := report.HTML()
+	if err != nil {
+		return nil, err
+	}
+	err = mail.Send(ctx, r.mail, mail.Message{
+		To:      r.recipients,
+		Subject: report.Subject(),
+		Text:    report.Text(),
+		HTML:    html,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// isLeader 未设置主实例判断时总是发送。
+func (r *Runner) isLeader() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.leader == nil || r.leader()
+}
+
+// lastSent 读取上次发送的周期终点。
+func (r *Runner) lastSent() (time.Time, bool) {
+	kv, err := r.store.KV().Get(kvScope, kvKey)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var t time.Time
+	if err := json.Unmarshal([]byte(kv.Value), &t); err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// saveSent 记录发送的周期终点。
+func (r *Runner) saveSent(t time.Time) {
+	value, _ := json.Marshal(t)
+	if err := r.store.KV().Set(kvScope, kvKey, string(value)); err != nil {
+		r.logger.With("name", "【摘要】").Warn("记录活动摘要发送时间失败", "error", err)
+	}
+}
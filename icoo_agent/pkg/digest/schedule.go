@@ -0,0 +1,73 @@
+package digest
+
+import (
+	"fmt"
+	"time"
+)
+
+// 发送频率
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+)
+
+// Schedule 摘要的发送时间，每天或每周的固定时刻。
+type Schedule struct {
+	Weekly   bool
+	Weekday  time.Weekday // 每周发送时的星期
+	Hour     int
+	Minute   int
+	Location *time.Location
+}
+
+// ParseSchedule 解析发送时间，at 为 "HH:MM"，weekday 为 0（周日）到 6，loc 为空时使用本地时区。
+func ParseSchedule(frequency, at string, weekday int, loc *time.Location) (Schedule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	s := Schedule{Location: loc}
+	switch frequency {
+	case "", FrequencyDaily:
+	case FrequencyWeekly:
+		if weekday < 0 || weekday > 6 {
+			return Schedule{}, fmt.Errorf("星期必须在 0（周日）到 6 之间")
+		}
+		s.Weekly, s.Weekday = true, time.Weekday(weekday)
+	default:
+		return Schedule{}, fmt.Errorf("未知的发送频率: %s，可用 daily 或 weekly", frequency)
+	}
+
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("发送时间格式应为 HH:MM: %s", at)
+	}
+	s.Hour, s.Minute = t.Hour(), t.Minute()
+	return s, nil
+}
+
+// days 两次发送间隔的天数。
+func (s Schedule) days() int {
+	if s.Weekly {
+		return 7
+	}
+	return 1
+}
+
+// Next 返回 after 之后的下一个发送时间。
+func (s Schedule) Next(after time.Time) time.Time {
+	after = after.In(s.Location)
+	next := time.Date(after.Year(), after.Month(), after.Day(), s.Hour, s.Minute, 0, 0, s.Location)
+	if s.Weekly {
+		next = next.AddDate(0, 0, (int(s.Weekday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(after) {
+		next = next.AddDate(0, 0, s.days())
+	}
+	return next
+}
+
+// Window 返回 at 时最近一个已结束的周期 [from, to)，to 为不晚于 at 的上一个发送时间。
+func (s Schedule) Window(at time.Time) (from, to time.Time) {
+	to = s.Next(at).AddDate(0, 0, -s.days())
+	return to.AddDate(0, 0, -s.days()), to
+}
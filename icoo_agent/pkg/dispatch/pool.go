@@ -0,0 +1,318 @@
+// Package dispatch provides a worker pool that processes inbound messages
+// concurrently while keeping users from starving each other.
+//
+// 同一会话的消息按到达顺序逐条处理，不同会话的消息最多由 Workers 个协程并发处理。
+// 普通消息按用户分队列，空闲协程在有消息的用户之间轮转取消息，一个用户连发多条消息不会挤占其他用户；
+// 紧急消息（停止请求、斜杠命令）优先于所有排队消息，低优先级消息（定时任务、心跳）只在没有其他消息时处理。
+// 排队的消息超过 MaxQueued 时拒绝新消息；因并发已满而排队的消息可收到排队位置的更新。
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	icooclawErrors "icooclaw/pkg/errors"
+)
+
+// ErrQueueFull 排队的消息已达上限。
+var ErrQueueFull = errors.New("dispatch: queue is full")
+
+// Config 工作池配置。
+type Config struct {
+	Workers        int           // 并发处理的消息数
+	MaxQueued      int           // 排队的普通和低优先级消息上限，0 表示不限制
+	NotifyInterval time.Duration // 同一条消息两次排队位置更新的最小间隔，0 表示位置变化即更新
+}
+
+// Handler 处理一条消息，返回后同一会话的下一条消息才会开始处理。
+type Handler func(msg bus.InboundMessage)
+
+// Notifier 通知用户消息的排队位置，ahead 为预计在它之前处理的消息数，first 表示首次通知。
+type Notifier func(msg bus.InboundMessage, ahead int, first bool)
+
+// Stats 工作池的运行状态。
+type Stats struct {
+	Workers int `json:"workers"` // 并发上限
+	Running int `json:"running"` // 正在处理的消息数
+	Queued  int `json:"queued"`  // 排队的消息数
+	Users   int `json:"users"`   // 有普通消息排队的用户数
+}
+
+// job 排队的消息。
+type job struct {
+	msg        bus.InboundMessage
+	session    string    // 会话键，同一会话的消息不并发处理
+	notify     bool      // 是否通知排队位置
+	ahead      int       // 最近一次通知的位置
+	notifiedAt time.Time // 最近一次通知的时间
+}
+
+// Pool 公平调度的工作池。
+type Pool struct {
+	cfg      Config
+	handle   Handler
+	notifier Notifier
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	urgent  []*job
+	low     []*job
+	users   map[string][]*job // 按用户排队的普通消息
+	ring    []string          // 有普通消息排队的用户，按轮转顺序
+	busy    map[string]bool   // 正在处理的会话
+	running int
+	closed  bool
+}
+
+// New 创建工作池，Workers 小于 1 时按 1 处理。
+func New(cfg Config, handle Handler) *Pool {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	p := &Pool{
+		cfg:    cfg,
+		handle: handle,
+		users:  make(map[string][]*job),
+		busy:   make(map[string]bool),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// WithNotifier 设置排队位置通知，未设置时不通知。
+func (p *Pool) WithNotifier(fn Notifier) *Pool {
+	p.notifier = fn
+	return p
+}
+
+// userKey 公平调度的用户键，按渠道和发送者区分，无法确定发送者时按会话区分。
+func userKey(msg bus.InboundMessage) string {
+	if msg.Sender.ID != "" {
+		return "user:" + msg.Channel + ":" + msg.Sender.ID
+	}
+	return "session:" + consts.GetSessionKey(msg.Channel, msg.SessionID)
+}
+
+// Submit 提交消息。排队的消息已达上限时返回 ErrQueueFull，紧急消息不受上限限制；工作池已停止时返回 ErrNotRunning。
+func (p *Pool) Submit(msg bus.InboundMessage) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return icooclawErrors.ErrNotRunning
+	}
+
+	j := &job{msg: msg, session: consts.GetSessionKey(msg.Channel, msg.SessionID)}
+	switch {
+	case msg.Priority > bus.PriorityNormal:
+		p.urgent = append(p.urgent, j)
+	case p.cfg.MaxQueued > 0 && p.queued() >= p.cfg.MaxQueued:
+		p.mu.Unlock()
+		return ErrQueueFull
+	case msg.Priority < bus.PriorityNormal:
+		p.low = append(p.low, j)
+	default:
+		// 并发已满时排队，通知用户排队位置；只是等待同一会话的上一条消息时不通知
+		j.notify = p.notifier != nil && p.running >= p.cfg.Workers
+		key := userKey(msg)
+		if len(p.users[key]) == 0 {
+			p.ring = append(p.ring, key)
+		}
+		p.users[key] = append(p.users[key], j)
+	}
+	notices := p.positions(time.Now())
+	p.mu.Unlock()
+
+	p.cond.Signal()
+	p.notify(notices)
+	return nil
+}
+
+// Run 启动工作协程，阻塞到 ctx 取消且正在处理的消息全部完成。取消后未处理的消息被丢弃。
+func (p *Pool) Run(ctx context.Context) {
+	stop := context.AfterFunc(ctx, func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		p.cond.Broadcast()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for range p.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work()
+		}()
+	}
+	wg.Wait()
+}
+
+// work 循环取出消息处理，工作池关闭后返回。
+func (p *Pool) work() {
+	for {
+		j, ok := p.take()
+		if !ok {
+			return
+		}
+		p.handle(j.msg)
+
+		p.mu.Lock()
+		delete(p.busy, j.session)
+		p.running--
+		p.mu.Unlock()
+		// 会话空闲后它排队的消息可能可以处理了
+		p.cond.Broadcast()
+	}
+}
+
+// take 取出下一条可以处理的消息，没有时等待。
+func (p *Pool) take() (*job, bool) {
+	p.mu.Lock()
+	var j *job
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, false
+		}
+		if j = p.next(); j != nil {
+			break
+		}
+		p.cond.Wait()
+	}
+	p.busy[j.session] = true
+	p.running++
+	notices := p.positions(time.Now())
+	p.mu.Unlock()
+
+	p.notify(notices)
+	return j, true
+}
+
+// next 按紧急、普通（用户间轮转）、低优先级的顺序取出第一条会话空闲的消息，调用方持有锁。
+func (p *Pool) next() *job {
+	if j := p.takeFIFO(&p.urgent); j != nil {
+		return j
+	}
+	for i, key := range p.ring {
+		jobs := p.users[key]
+		idx := p.firstIdle(jobs)
+		if idx < 0 {
+			continue
+		}
+		j := jobs[idx]
+		jobs = append(jobs[:idx:idx], jobs[idx+1:]...)
+		// 取过消息的用户移到队尾，队列为空时移出轮转
+		p.ring = append(p.ring[:i:i], p.ring[i+1:]...)
+		if len(jobs) == 0 {
+			delete(p.users, key)
+		} else {
+			p.users[key] = jobs
+			p.ring = append(p.ring, key)
+		}
+		return j
+	}
+	return p.takeFIFO(&p.low)
+}
+
+// takeFIFO 取出队列中第一条会话空闲的消息。
+func (p *Pool) takeFIFO(queue *[]*job) *job {
+	idx := p.firstIdle(*queue)
+	if idx < 0 {
+		return nil
+	}
+	j := (*queue)[idx]
+	*queue = append((*queue)[:idx:idx], (*queue)[idx+1:]...)
+	return j
+}
+
+// firstIdle 返回第一条会话空闲、且之前没有同一会话消息排队的消息下标，保证同一会话按顺序处理。
+func (p *Pool) firstIdle(jobs []*job) int {
+	var blocked map[string]bool
+	for i, j := range jobs {
+		if !p.busy[j.session] && !blocked[j.session] {
+			return i
+		}
+		if blocked == nil {
+			blocked = make(map[string]bool)
+		}
+		blocked[j.session] = true
+	}
+	return -1
+}
+
+// queued 排队的普通和低优先级消息数，调用方持有锁。
+func (p *Pool) queued() int {
+	n := len(p.low)
+	for _, jobs := range p.users {
+		n += len(jobs)
+	}
+	return n
+}
+
+// order 按轮转顺序排列排队的普通消息，即预计的处理顺序，调用方持有锁。
+func (p *Pool) order() []*job {
+	var out []*job
+	for round := 0; ; round++ {
+		added := false
+		for _, key := range p.ring {
+			if jobs := p.users[key]; round < len(jobs) {
+				out = append(out, jobs[round])
+				added = true
+			}
+		}
+		if !added {
+			return out
+		}
+	}
+}
+
+// notice 一条排队位置通知。
+type notice struct {
+	msg   bus.InboundMessage
+	ahead int
+	first bool
+}
+
+// positions 计算需要通知的排队位置：首次排队的消息，以及位置前移且距上次通知超过间隔的消息。调用方持有锁。
+func (p *Pool) positions(now time.Time) []notice {
+	if p.notifier == nil {
+		return nil
+	}
+	var notices []notice
+	ahead := len(p.urgent)
+	for _, j := range p.order() {
+		if j.notify {
+			first := j.notifiedAt.IsZero()
+			if first || (ahead < j.ahead && now.Sub(j.notifiedAt) >= p.cfg.NotifyInterval) {
+				notices = append(notices, notice{msg: j.msg, ahead: ahead, first: first})
+				j.ahead, j.notifiedAt = ahead, now
+			}
+		}
+		ahead++
+	}
+	return notices
+}
+
+// notify 在锁外发送排队位置通知。
+func (p *Pool) notify(notices []notice) {
+	for _, n := range notices {
+		p.notifier(n.msg, n.ahead, n.first)
+	}
+}
+
+// Stats 返回工作池的运行状态。
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Stats{
+		Workers: p.cfg.Workers,
+		Running: p.running,
+		Queued:  p.queued() + len(p.urgent),
+		Users:   len(p.ring),
+	}
+}
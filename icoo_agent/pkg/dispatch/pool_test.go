@@ -0,0 +1,158 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/bus"
+)
+
+// recorder 记录处理顺序，gate 未关闭前阻塞第一条消息。
+type recorder struct {
+	mu      sync.Mutex
+	order   []string
+	gate    chan struct{}
+	started chan struct{}
+	done    chan struct{}
+	want    int
+}
+
+func newRecorder(want int) *recorder {
+	return &recorder{gate: make(chan struct{}), started: make(chan struct{}, want), done: make(chan struct{}), want: want}
+}
+
+func (r *recorder) handle(msg bus.InboundMessage) {
+	r.started <- struct{}{}
+	<-r.gate
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.order = append(r.order, msg.Text)
+	if len(r.order) == r.want {
+		close(r.done)
+	}
+}
+
+func message(user, session, text string) bus.InboundMessage {
+	return bus.InboundMessage{Channel: "telegram", SessionID: session, Sender: bus.SenderInfo{ID: user}, Text: text}
+}
+
+func wait(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestPool_RoundRobin(t *testing.T) {
+	r := newRecorder(5)
+	type position struct {
+		text  string
+		ahead int
+	}
+	var notices []position
+	var mu sync.Mutex
+	p := New(Config{Workers: 1}, r.handle).WithNotifier(func(msg bus.InboundMessage, ahead int, first bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if first {
+			notices = append(notices, position{msg.Text, ahead})
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	p.Submit(message("a", "a1", "a1"))
+	wait(t, r.started)
+
+	// a 连发三条，b 只发一条，b 不必等 a 全部处理完
+	p.Submit(message("a", "a2", "a2"))
+	p.Submit(message("a", "a3", "a3"))
+	p.Submit(message("a", "a4", "a4"))
+	p.Submit(message("b", "b1", "b1"))
+	if s := p.Stats(); s.Running != 1 || s.Queued != 4 || s.Users != 2 {
+		t.Errorf("Stats() = %+v", s)
+	}
+	close(r.gate)
+	wait(t, r.done)
+
+	want := []string{"a1", "a2", "b1", "a3", "a4"}
+	for i := range want {
+		if r.order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", r.order, want)
+		}
+	}
+	// b1 加入后排在 a2 之后、a3 之前
+	mu.Lock()
+	defer mu.Unlock()
+	if len(notices) != 4 || notices[3] != (position{"b1", 1}) {
+		t.Errorf("notices = %+v", notices)
+	}
+}
+
+func TestPool_SessionOrder(t *testing.T) {
+	var mu sync.Mutex
+	active := map[string]int{}
+	overlap := false
+	var wg sync.WaitGroup
+	wg.Add(6)
+	p := New(Config{Workers: 3}, func(msg bus.InboundMessage) {
+		defer wg.Done()
+		mu.Lock()
+		active[msg.SessionID]++
+		if active[msg.SessionID] > 1 {
+			overlap = true
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		active[msg.SessionID]--
+		mu.Unlock()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	for i := 0; i < 3; i++ {
+		p.Submit(message("a", "s1", "x"))
+		p.Submit(message("b", "s2", "y"))
+	}
+	wg.Wait()
+	if overlap {
+		t.Error("messages of the same session should not run concurrently")
+	}
+}
+
+func TestPool_QueueFull(t *testing.T) {
+	r := newRecorder(3)
+	p := New(Config{Workers: 1, MaxQueued: 1}, r.handle)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	p.Submit(message("a", "s1", "first"))
+	wait(t, r.started)
+	if err := p.Submit(message("b", "s2", "queued")); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if err := p.Submit(message("c", "s3", "rejected")); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Submit() over the limit error = %v", err)
+	}
+
+	// 紧急消息不受上限限制，并且先于排队的普通消息处理
+	urgent := message("c", "s3", "/stop")
+	urgent.Priority = bus.PriorityUrgent
+	if err := p.Submit(urgent); err != nil {
+		t.Fatalf("Submit() urgent error = %v", err)
+	}
+	close(r.gate)
+	wait(t, r.done)
+	if r.order[1] != "/stop" || r.order[2] != "queued" {
+		t.Errorf("order = %v", r.order)
+	}
+}
@@ -0,0 +1,126 @@
+// Package document 将结构化数据合并到 Markdown/HTML 模板生成文档（周报、事故总结等），
+// 并可通过无头 Chrome 打印为 PDF。
+package document
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 输出格式
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+)
+
+// IsHTML 判断模板是否为 HTML 模板（.html、.htm，可带 .tmpl 后缀），其余按 Markdown 处理。
+func IsHTML(name string) bool {
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(name, ".tmpl"))) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
+
+// funcs 模板可用的函数
+var funcs = map[string]any{
+	"now": time.Now,
+	// date 按 Go 时间格式格式化时间，也接受 RFC3339 或 2006-01-02 形式的文本
+	"date": func(layout string, v any) (string, error) {
+		switch t := v.(type) {
+		case time.Time:
+			return t.Format(layout), nil
+		case string:
+			for _, l := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+				if parsed, err := time.Parse(l, t); err == nil {
+					return parsed.Format(layout), nil
+				}
+			}
+			return "", fmt.Errorf("无法解析时间: %s", t)
+		}
+		return "", fmt.Errorf("无法格式化 %T", v)
+	},
+	"join": func(sep string, items []any) string {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, sep)
+	},
+	"default": func(fallback, v any) any {
+		if v == nil || v == "" {
+			return fallback
+		}
+		return v
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Render merges data into the template. HTML templates (.html, .htm) escape
+// values for HTML, other templates are rendered as Markdown text. Missing keys
+// are an error so that an incomplete report is not produced silently; optional
+// fields use index, e.g. {{default "无" (index . "owner")}}.
+func Render(name string, content []byte, data any) ([]byte, error) {
+	var buf bytes.Buffer
+	if IsHTML(name) {
+		tmpl, err := htmltemplate.New(name).Funcs(funcs).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("解析模板 %s 失败: %w", name, err)
+		}
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("渲染模板 %s 失败: %w", name, err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("解析模板 %s 失败: %w", name, err)
+	}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("渲染模板 %s 失败: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// page 包装 Markdown 转换出的 HTML 正文，样式适合屏幕阅读和打印。
+var page = htmltemplate.Must(htmltemplate.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; font-size: 14px; line-height: 1.6; color: #24292f; max-width: 860px; margin: 0 auto; padding: 24px; }
+h1, h2, h3 { border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+table { border-collapse: collapse; margin: 12px 0; }
+th, td { border: 1px solid #d0d7de; padding: 6px 12px; }
+th { background: #f6f8fa; }
+code { background: #f6f8fa; padding: 2px 4px; border-radius: 4px; font-size: 90%; }
+pre { background: #f6f8fa; padding: 12px; border-radius: 6px; overflow: auto; }
+pre code { background: none; padding: 0; }
+blockquote { color: #57606a; border-left: 4px solid #d0d7de; margin: 0; padding: 0 1em; }
+@media print { body { max-width: none; padding: 0; } pre, table, blockquote { page-break-inside: avoid; } }
+</style>
+</head>
+<body>
+{{.Body}}
+</body>
+</html>
+`))
+
+// HTMLPage 将 Markdown 转换为完整的 HTML 页面。
+func HTMLPage(title, markdown string) []byte {
+	var buf bytes.Buffer
+	page.Execute(&buf, struct {
+		Title string
+		Body  htmltemplate.HTML
+	}{title, htmltemplate.HTML(MarkdownToHTML(markdown))})
+	return buf.Bytes()
+}
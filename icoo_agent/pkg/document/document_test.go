@@ -0,0 +1,60 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := map[string]any{
+		"title": "周报 <draft>",
+		"week":  "2024-03-04",
+		"items": []any{"发布 1.2", "修复登录"},
+	}
+
+	md, err := Render("weekly.md", []byte(`# {{.title}}
+{{date "01/02" .week}}: {{join ", " .items}}
+{{default "无" (index . "owner")}}`), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(md); got != "# 周报 <draft>\n03/04: 发布 1.2, 修复登录\n无" {
+		t.Errorf("markdown = %q", got)
+	}
+
+	html, err := Render("weekly.html.tmpl", []byte(`<h1>{{.title}}</h1>`), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(html); got != "<h1>周报 &lt;draft&gt;</h1>" {
+		t.Errorf("html = %q", got)
+	}
+
+	if _, err := Render("weekly.md", []byte(`{{.missing.key}}`), data); err == nil {
+		t.Error("missing key should fail")
+	}
+}
+
+func TestMarkdownToHTML(t *testing.T) {
+	src := "# Status `v1`\n\nDone **well** and *fast*, see [doc](https://example.com).\n\n" +
+		"| Service | Uptime |\n| --- | ---: |\n| api \\| web | 99.9% |\n\n" +
+		"- one\n- two\n\n1. first\n\n> note\n\n```go\nx := 1 < 2\n```\n\n---\n<script>"
+
+	got := MarkdownToHTML(src)
+	for _, want := range []string{
+		"<h1>Status <code>v1</code></h1>",
+		`<p>Done <strong>well</strong> and <em>fast</em>, see <a href="https://example.com">doc</a>.</p>`,
+		"<thead><tr><th>Service</th><th>Uptime</th></tr></thead>",
+		"<td>api | web</td><td>99.9%</td>",
+		"<ul>\n<li>one</li>\n<li>two</li>\n</ul>",
+		"<ol>\n<li>first</li>\n</ol>",
+		"<blockquote>\n<p>note</p>\n</blockquote>",
+		`<pre><code class="language-go">x := 1 &lt; 2</code></pre>`,
+		"<hr>",
+		"<p>&lt;script&gt;</p>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+}
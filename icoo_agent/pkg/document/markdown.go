@@ -0,0 +1,150 @@
+package document
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headingRegex   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	orderedRegex   = regexp.MustCompile(`^\d+[.)]\s+`)
+	unorderedRegex = regexp.MustCompile(`^[-*+]\s+`)
+	ruleRegex      = regexp.MustCompile(`^(?:-{3,}|\*{3,}|_{3,})$`)
+	tableSepRegex  = regexp.MustCompile(`^\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?$`)
+
+	codeSpanRegex = regexp.MustCompile("`([^`]+)`")
+	imageRegex    = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	linkRegex     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldRegex     = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	italicRegex   = regexp.MustCompile(`\*([^*\s][^*]*?)\*|\b_([^_\s][^_]*?)_\b`)
+)
+
+// MarkdownToHTML converts the Markdown subset used by report templates to HTML:
+// ATX headings, paragraphs, fenced code, block quotes, flat lists, GFM tables,
+// horizontal rules, and inline code, bold, italic, links and images. Raw HTML
+// in the source is escaped.
+func MarkdownToHTML(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	var para []string
+
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + inline(strings.Join(para, "\n")) + "</p>\n")
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			lang := strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			if lang != "" {
+				b.WriteString(`<pre><code class="language-` + html.EscapeString(lang) + `">`)
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case headingRegex.MatchString(trimmed):
+			flush()
+			m := headingRegex.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">" + inline(m[2]) + "</h" + level + ">\n")
+
+		case ruleRegex.MatchString(strings.ReplaceAll(trimmed, " ", "")):
+			flush()
+			b.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			i--
+			b.WriteString("<blockquote>\n" + MarkdownToHTML(strings.Join(quote, "\n")) + "</blockquote>\n")
+
+		case unorderedRegex.MatchString(trimmed) || orderedRegex.MatchString(trimmed):
+			flush()
+			marker, tag := unorderedRegex, "ul"
+			if orderedRegex.MatchString(trimmed) {
+				marker, tag = orderedRegex, "ol"
+			}
+			b.WriteString("<" + tag + ">\n")
+			for ; i < len(lines) && marker.MatchString(strings.TrimSpace(lines[i])); i++ {
+				item := marker.ReplaceAllString(strings.TrimSpace(lines[i]), "")
+				b.WriteString("<li>" + inline(item) + "</li>\n")
+			}
+			i--
+			b.WriteString("</" + tag + ">\n")
+
+		case strings.Contains(trimmed, "|") && i+1 < len(lines) && tableSepRegex.MatchString(strings.TrimSpace(lines[i+1])):
+			flush()
+			b.WriteString("<table>\n<thead><tr>")
+			for _, cell := range tableCells(trimmed) {
+				b.WriteString("<th>" + inline(cell) + "</th>")
+			}
+			b.WriteString("</tr></thead>\n<tbody>\n")
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+				b.WriteString("<tr>")
+				for _, cell := range tableCells(strings.TrimSpace(lines[i])) {
+					b.WriteString("<td>" + inline(cell) + "</td>")
+				}
+				b.WriteString("</tr>\n")
+			}
+			i--
+			b.WriteString("</tbody>\n</table>\n")
+
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// tableCells 拆分表格行，支持 \| 转义。
+func tableCells(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	row = strings.ReplaceAll(row, `\|`, "\x00")
+	cells := strings.Split(row, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(strings.ReplaceAll(cell, "\x00", "|"))
+	}
+	return cells
+}
+
+// inline 转换行内标记。代码片段先替换为占位符，避免其中的 * _ 被当作强调。
+func inline(text string) string {
+	text = html.EscapeString(text)
+
+	var spans []string
+	text = codeSpanRegex.ReplaceAllStringFunc(text, func(m string) string {
+		spans = append(spans, "<code>"+codeSpanRegex.FindStringSubmatch(m)[1]+"</code>")
+		return "\x00" + strconv.Itoa(len(spans)-1) + "\x00"
+	})
+
+	text = imageRegex.ReplaceAllString(text, `<img src="$2" alt="$1">`)
+	text = linkRegex.ReplaceAllString(text, `<a href="$2">$1</a>`)
+	text = boldRegex.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = italicRegex.ReplaceAllString(text, "<em>$1$2</em>")
+
+	for i, span := range spans {
+		text = strings.Replace(text, "\x00"+strconv.Itoa(i)+"\x00", span, 1)
+	}
+	return text
+}
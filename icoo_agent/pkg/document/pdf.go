@@ -0,0 +1,106 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// ErrNoChrome 未找到 Chrome/Chromium，无法生成 PDF
+var ErrNoChrome = errors.New("未找到 Chrome 或 Chromium，无法生成 PDF（可配置 agent.documents.chrome_path）")
+
+// chromeNames 在 PATH 中查找的浏览器程序
+var chromeNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome", "msedge"}
+
+// FindChrome returns the configured browser path, or the first Chrome or
+// Chromium found in PATH (and the default install locations on macOS and
+// Windows). It returns ErrNoChrome when none is available.
+func FindChrome(configured string) (string, error) {
+	if configured != "" {
+		if _, err := exec.LookPath(configured); err != nil {
+			return "", fmt.Errorf("chrome_path 不可用: %w", err)
+		}
+		return configured, nil
+	}
+	for _, name := range chromeNames {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+
+	var candidates []string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = []string{"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome", "/Applications/Chromium.app/Contents/MacOS/Chromium"}
+	case "windows":
+		for _, env := range []string{"ProgramFiles", "ProgramFiles(x86)", "LocalAppData"} {
+			if dir := os.Getenv(env); dir != "" {
+				candidates = append(candidates, filepath.Join(dir, `Google\Chrome\Application\chrome.exe`))
+			}
+		}
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrNoChrome
+}
+
+// PrintPDF prints an HTML page to PDF with headless Chrome. The page is written
+// to a temporary directory so that relative resources cannot reach the
+// workspace; ctx bounds the browser run.
+func PrintPDF(ctx context.Context, chrome string, page []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "icooclaw-pdf-")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "index.html")
+	output := filepath.Join(dir, "output.pdf")
+	if err := os.WriteFile(input, page, 0600); err != nil {
+		return nil, fmt.Errorf("写入临时文件失败: %w", err)
+	}
+
+	args := []string{
+		"--headless",
+		"--disable-gpu",
+		"--no-first-run",
+		"--no-pdf-header-footer",
+		"--user-data-dir=" + filepath.Join(dir, "profile"),
+		"--print-to-pdf=" + output,
+	}
+	// 以 root 运行（如容器中）时 Chrome 要求关闭沙箱
+	if runtime.GOOS == "linux" && os.Geteuid() == 0 {
+		args = append(args, "--no-sandbox")
+	}
+	args = append(args, "file://"+filepath.ToSlash(input))
+
+	cmd := exec.CommandContext(ctx, chrome, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("生成 PDF 超时: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("生成 PDF 失败: %w: %s", err, lastLine(stderr.String()))
+	}
+
+	pdf, err := os.ReadFile(output)
+	if err != nil {
+		return nil, fmt.Errorf("Chrome 未生成 PDF: %s", lastLine(stderr.String()))
+	}
+	return pdf, nil
+}
+
+// lastLine 返回输出中最后一个非空行，Chrome 的错误通常在最后。
+func lastLine(s string) string {
+	lines := bytes.Split(bytes.TrimSpace([]byte(s)), []byte("\n"))
+	return string(lines[len(lines)-1])
+}
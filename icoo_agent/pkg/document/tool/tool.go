@@ -0,0 +1,226 @@
+// Package tool provides the generate_document tool.
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/document"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/artifact"
+	"icooclaw/pkg/tools/builtin/file"
+)
+
+// Tool 根据工作目录中的模板生成文档。
+type Tool struct {
+	store   *storage.ArtifactStorage
+	workDir string
+	chrome  string        // 配置的浏览器路径，为空时自动查找
+	timeout time.Duration // 生成 PDF 的超时，与本轮剩余预算取较小者
+}
+
+// NewTool 创建 generate_document 工具。
+func NewTool(store *storage.ArtifactStorage, workDir, chrome string, timeout time.Duration) *Tool {
+	if workDir == "" {
+		workDir = "./workspace"
+	}
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	return &Tool{store: store, workDir: workDir, chrome: chrome, timeout: timeout}
+}
+
+// Name 工具名称.
+func (t *Tool) Name() string {
+	return "generate_document"
+}
+
+// Description 工具描述.
+func (t *Tool) Description() string {
+	return "将数据合并到工作目录中的 Markdown 或 HTML 模板（Go text/template 语法，如 {{.title}}、{{range .items}}）生成文档，" +
+		"可输出 Markdown、HTML 或 PDF，用于周报、事故总结等定期报告。结果保存为制品，生成后在回复中写 [artifact:<id>] 引用。"
+}
+
+// Parameters 工具参数.
+func (t *Tool) Parameters() map[string]any {
+	return map[string]any{
+		"template": map[string]any{
+			"type":        "string",
+			"description": "模板文件路径（相对工作目录），.html/.htm 为 HTML 模板，其余为 Markdown 模板",
+			"required":    true,
+		},
+		"data": map[string]any{
+			"type":        "object",
+			"description": "合并到模板的数据，模板中以 {{.键名}} 引用",
+		},
+		"data_path": map[string]any{
+			"type":        "string",
+			"description": "工作目录中的 JSON 数据文件，与 data 同时提供时 data 中的键优先",
+		},
+		"format": map[string]any{
+			"type":        "string",
+			"description": "输出格式，默认与模板相同；Markdown 模板可输出 html 或 pdf",
+			"enum":        []string{document.FormatMarkdown, document.FormatHTML, document.FormatPDF},
+		},
+		"name": map[string]any{
+			"type":        "string",
+			"description": "输出文件名（不含扩展名），默认为模板文件名",
+		},
+		"description": map[string]any{
+			"type":        "string",
+			"description": "一句话说明文档的内容",
+		},
+	}
+}
+
+// DescribeChange 实现 tools.Mutator，生成的文档会写入工作目录。
+func (t *Tool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	tmpl, _ := args["template"].(string)
+	return "根据模板 " + tmpl + " 生成文档", true
+}
+
+// Execute 执行 generate_document.
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	if tools.GetChannel(ctx) == "" || tools.GetSessionID(ctx) == "" {
+		return tools.ErrorResult("缺少会话上下文，无法保存文档")
+	}
+	if tools.IsEphemeral(ctx) {
+		return tools.ErrorResult("无痕会话不保存文档")
+	}
+
+	workDir := tools.GetWorkspace(ctx, t.workDir)
+	tmplPath := argString(args, "template")
+	if tmplPath == "" {
+		return tools.ErrorResult("需要提供 template 参数")
+	}
+	resolved, err := file.ResolvePath(workDir, tmplPath)
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+	content, err := os.ReadFile(resolved)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("读取模板失败: %s", err))
+	}
+
+	data, err := t.data(workDir, args)
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+
+	isHTML := document.IsHTML(resolved)
+	format := strings.ToLower(argString(args, "format"))
+	switch format {
+	case "":
+		format = document.FormatMarkdown
+		if isHTML {
+			format = document.FormatHTML
+		}
+	case document.FormatMarkdown:
+		if isHTML {
+			return tools.ErrorResult("HTML 模板不能输出 Markdown")
+		}
+	case document.FormatHTML, document.FormatPDF:
+	default:
+		return tools.ErrorResult(fmt.Sprintf("不支持的输出格式: %s", format))
+	}
+
+	out, err := document.Render(filepath.Base(resolved), content, data)
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+
+	base := outputName(argString(args, "name"), resolved)
+
+	var ext string
+	switch format {
+	case document.FormatMarkdown:
+		ext = ".md"
+	case document.FormatHTML:
+		ext = ".html"
+		if !isHTML {
+			out = document.HTMLPage(base, string(out))
+		}
+	case document.FormatPDF:
+		ext = ".pdf"
+		if !isHTML {
+			out = document.HTMLPage(base, string(out))
+		}
+		if out, err = t.printPDF(ctx, out); err != nil {
+			return tools.ErrorResult(err.Error())
+		}
+	}
+
+	name := base + ext
+	a, err := artifact.Save(ctx, t.store, t.workDir, name, "report", argString(args, "description"), out)
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+	return tools.SuccessResult(fmt.Sprintf("已生成文档 %s（%d 字节）。在回复中写 %s 引用它。",
+		name, a.Size, artifact.Reference(a.ID)))
+}
+
+// data 读取 data_path 中的 JSON 数据，再合并 data 参数。
+func (t *Tool) data(workDir string, args map[string]any) (map[string]any, error) {
+	data := make(map[string]any)
+	if path := argString(args, "data_path"); path != "" {
+		resolved, err := file.ResolvePath(workDir, path)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := os.ReadFile(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("读取数据文件失败: %w", err)
+		}
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, fmt.Errorf("数据文件必须是 JSON 对象: %w", err)
+		}
+	}
+	if extra, ok := args["data"].(map[string]any); ok {
+		for k, v := range extra {
+			data[k] = v
+		}
+	}
+	return data, nil
+}
+
+// printPDF 使用无头 Chrome 将 HTML 打印为 PDF。
+func (t *Tool) printPDF(ctx context.Context, page []byte) ([]byte, error) {
+	chrome, err := document.FindChrome(t.chrome)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := tools.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	pdf, err := document.PrintPDF(ctx, chrome, page)
+	if err != nil {
+		return nil, tools.DeadlineError(ctx, t.timeout, err)
+	}
+	return pdf, nil
+}
+
+// outputName 返回不含扩展名的输出文件名，默认为模板文件名。
+func outputName(name, template string) string {
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(template), ".tmpl")
+	}
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown", ".html", ".htm", ".pdf":
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "document"
+	}
+	return name
+}
+
+func argString(args map[string]any, key string) string {
+	s, _ := args[key].(string)
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,76 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+func TestTool_Execute(t *testing.T) {
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "document.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, "reports"), 0755)
+	os.WriteFile(filepath.Join(workspace, "reports", "incident.md"), []byte("# {{.title}}\n\n影响：{{.impact}}\n"), 0644)
+	os.WriteFile(filepath.Join(workspace, "reports", "incident.json"), []byte(`{"title": "API 故障", "impact": "未知"}`), 0644)
+
+	tool := NewTool(s.Artifact(), workspace, "", 0)
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+
+	tests := []struct {
+		name     string
+		args     map[string]any
+		wantFile string
+		want     string
+	}{
+		{
+			name:     "markdown",
+			args:     map[string]any{"template": "reports/incident.md", "data_path": "reports/incident.json", "data": map[string]any{"impact": "30 分钟"}},
+			wantFile: "incident.md",
+			want:     "# API 故障\n\n影响：30 分钟\n",
+		},
+		{
+			name:     "html",
+			args:     map[string]any{"template": "reports/incident.md", "data_path": "reports/incident.json", "format": "html", "name": "2024-03-04.md"},
+			wantFile: "2024-03-04.html",
+			want:     "<h1>API 故障</h1>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tool.Execute(ctx, tt.args)
+			if result.Error != nil {
+				t.Fatalf("Execute() error = %v", result.Error)
+			}
+			artifacts, _ := s.Artifact().ListBySession("websocket", "s1")
+			a := artifacts[len(artifacts)-1]
+			if a.Name != tt.wantFile || a.Kind != "report" || !strings.Contains(result.Content, a.ID) {
+				t.Errorf("artifact = %+v, result = %s", a, result.Content)
+			}
+			data, _ := os.ReadFile(a.Path)
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("content = %s", data)
+			}
+		})
+	}
+
+	for name, args := range map[string]map[string]any{
+		"missing key":      {"template": "reports/incident.md"},
+		"outside":          {"template": "../incident.md"},
+		"unknown format":   {"template": "reports/incident.md", "data_path": "reports/incident.json", "format": "docx"},
+		"missing template": {"template": "reports/none.md"},
+	} {
+		if result := tool.Execute(ctx, args); result.Error == nil {
+			t.Errorf("%s: Execute() = %+v, want error", name, result)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+// Package download 提供大文件下载：断点续传、进度回调、限速、SHA256 校验，以及超大响应的隔离。
+//
+// 下载过程中数据写入 <目标>.part，续传所需的 ETag / Last-Modified 记录在 <目标>.part.meta；
+// 中断后再次下载同一地址时用 Range 请求从已写入的位置继续，服务端不支持或文件已变化时从头开始。
+package download
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	// ErrTooLarge 响应超过大小上限，已下载的部分被移入隔离目录。
+	ErrTooLarge = errors.New("下载内容超过大小上限")
+	// ErrChecksum 下载完成的文件与期望的 SHA256 不一致，文件被移入隔离目录。
+	ErrChecksum = errors.New("SHA256 校验失败")
+)
+
+// Progress 下载进度。
+type Progress struct {
+	Downloaded int64 `json:"downloaded"`
+	Total      int64 `json:"total"` // 未知时为 -1
+	Resumed    bool  `json:"resumed"`
+}
+
+// String 返回可读的进度描述。
+func (p Progress) String() string {
+	if p.Total <= 0 {
+		return fmt.Sprintf("已下载 %s", FormatSize(p.Downloaded))
+	}
+	return fmt.Sprintf("已下载 %s / %s (%d%%)", FormatSize(p.Downloaded), FormatSize(p.Total), p.Downloaded*100/p.Total)
+}
+
+// Options 下载选项。
+type Options struct {
+	// Client HTTP 客户端，默认 http.DefaultClient；超时由 ctx 控制
+	Client *http.Client
+	// Headers 附加的请求头
+	Headers map[string]string
+	// SHA256 期望的十六进制摘要，为空时不校验
+	SHA256 string
+	// MaxBytes 文件大小上限，0 表示不限制。声明的长度超限时直接拒绝，实际内容超限时隔离已下载部分
+	MaxBytes int64
+	// RateLimit 限速（字节/秒），0 表示不限速
+	RateLimit int64
+	// Retries 中断后续传的最大次数，默认 3，负数表示不重试
+	Retries int
+	// RetryDelay 首次重试前的等待时间，之后逐次翻倍，默认 1s
+	RetryDelay time.Duration
+	// QuarantineDir 隔离目录，默认为目标文件所在目录下的 .quarantine
+	QuarantineDir string
+	// OnProgress 进度回调，最多每 ProgressInterval 调用一次，完成时总会调用
+	OnProgress func(Progress)
+	// ProgressInterval 进度回调间隔，默认 1s
+	ProgressInterval time.Duration
+}
+
+// Result 下载结果。
+type Result struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	Resumed  bool   `json:"resumed"`  // 是否从之前中断的位置续传
+	Attempts int    `json:"attempts"` // 发起的请求次数
+}
+
+// meta 续传元数据，用于判断服务端文件是否变化。
+type meta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// retryableError 可以通过续传恢复的错误：连接中断、5xx、429。
+type retryableError struct{ err error }
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// Download 将 url 下载到 dest，目标已存在时被覆盖。
+func Download(ctx context.Context, url, dest string, opts Options) (*Result, error) {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Retries == 0 {
+		opts.Retries = 3
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = time.Second
+	}
+	if opts.QuarantineDir == "" {
+		opts.QuarantineDir = filepath.Join(filepath.Dir(dest), ".quarantine")
+	}
+	opts.SHA256 = strings.ToLower(strings.TrimSpace(opts.SHA256))
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	d := &downloader{url: url, dest: dest, part: dest + ".part", opts: opts}
+	if opts.RateLimit > 0 {
+		burst := int(min(opts.RateLimit, 32*1024))
+		d.limiter = rate.NewLimiter(rate.Limit(opts.RateLimit), burst)
+	}
+
+	result := &Result{Path: dest}
+	for attempt := 0; ; attempt++ {
+		result.Attempts++
+		resumed, err := d.fetch(ctx)
+		result.Resumed = result.Resumed || resumed
+		if err == nil {
+			break
+		}
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= opts.Retries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		// 保留已下载部分，等待后续传
+		delay := opts.RetryDelay << attempt
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	sum, size, err := hashFile(d.part)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SHA256 != "" && sum != opts.SHA256 {
+		target, qerr := d.quarantine()
+		if qerr != nil {
+			return nil, fmt.Errorf("%w: 期望 %s，实际 %s；隔离失败: %v", ErrChecksum, opts.SHA256, sum, qerr)
+		}
+		return nil, fmt.Errorf("%w: 期望 %s，实际 %s，文件已隔离到 %s", ErrChecksum, opts.SHA256, sum, target)
+	}
+
+	if err := os.Rename(d.part, dest); err != nil {
+		return nil, fmt.Errorf("保存文件失败: %w", err)
+	}
+	os.Remove(d.metaPath())
+
+	result.Size = size
+	result.SHA256 = sum
+	return result, nil
+}
+
+// downloader 一次下载的状态。
+type downloader struct {
+	url     string
+	dest    string
+	part    string
+	opts    Options
+	limiter *rate.Limiter
+}
+
+func (d *downloader) metaPath() string {
+	return d.part + ".meta"
+}
+
+// resumeOffset 返回可以续传的位置和续传校验头，地址不同或没有部分文件时从头开始。
+func (d *downloader) resumeOffset() (int64, meta) {
+	var m meta
+	data, err := os.ReadFile(d.metaPath())
+	if err != nil || json.Unmarshal(data, &m) != nil || m.URL != d.url {
+		return 0, meta{}
+	}
+	info, err := os.Stat(d.part)
+	if err != nil {
+		return 0, meta{}
+	}
+	return info.Size(), m
+}
+
+// fetch 发起一次请求，把内容写入部分文件，返回本次是否为续传。
+func (d *downloader) fetch(ctx context.Context) (bool, error) {
+	offset, m := d.resumeOffset()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	if err != nil {
+		return false, err
+	}
+	for k, v := range d.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		// 文件已变化时服务端返回完整内容而不是 206
+		if validator := cmp.Or(m.ETag, m.LastModified); validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		return false, &retryableError{fmt.Errorf("请求失败: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			// 无法确认续传位置，丢弃部分文件从头开始
+			os.Remove(d.metaPath())
+			return false, &retryableError{fmt.Errorf("续传响应的 Content-Range 无效: %q", resp.Header.Get("Content-Range"))}
+		}
+		total = size
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// 部分文件已是完整内容
+		if _, size, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && size == offset {
+			d.progress(Progress{Downloaded: offset, Total: size, Resumed: true})
+			return true, nil
+		}
+		os.Remove(d.metaPath())
+		return false, &retryableError{fmt.Errorf("续传位置无效: %s", resp.Status)}
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		offset = 0
+		total = resp.ContentLength
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return false, &retryableError{fmt.Errorf("服务端返回 %s", resp.Status)}
+	default:
+		return false, fmt.Errorf("下载失败: %s", resp.Status)
+	}
+	resumed := offset > 0
+
+	if d.opts.MaxBytes > 0 && total > d.opts.MaxBytes {
+		return false, fmt.Errorf("%w: 文件大小 %s，上限 %s", ErrTooLarge, FormatSize(total), FormatSize(d.opts.MaxBytes))
+	}
+
+	m = meta{URL: d.url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if data, err := json.Marshal(m); err == nil {
+		os.WriteFile(d.metaPath(), data, 0o644)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !resumed {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(d.part, flags, 0o644)
+	if err != nil {
+		return false, fmt.Errorf("创建文件失败: %w", err)
+	}
+	defer f.Close()
+
+	downloaded, err := d.copy(ctx, f, resp.Body, offset, total, resumed)
+	if err != nil {
+		return resumed, err
+	}
+	if total >= 0 && downloaded != total {
+		return resumed, &retryableError{fmt.Errorf("连接提前结束: 已下载 %d / %d 字节", downloaded, total)}
+	}
+	return resumed, nil
+}
+
+// copy 把响应体写入文件，限速并上报进度，返回写入后的文件总大小。
+func (d *downloader) copy(ctx context.Context, w io.Writer, r io.Reader, offset, total int64, resumed bool) (int64, error) {
+	bufSize := 32 * 1024
+	if d.limiter != nil {
+		bufSize = d.limiter.Burst()
+	}
+	buf := make([]byte, bufSize)
+	downloaded := offset
+	last := time.Now()
+
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			if d.limiter != nil {
+				if err := d.limiter.WaitN(ctx, n); err != nil {
+					return downloaded, err
+				}
+			}
+			if d.opts.MaxBytes > 0 && downloaded+int64(n) > d.opts.MaxBytes {
+				// 超出声明的长度或未声明长度时才会走到这里，保留已下载部分供排查
+				target, qerr := d.quarantine()
+				if qerr != nil {
+					return downloaded, fmt.Errorf("%w (%s)，隔离失败: %v", ErrTooLarge, FormatSize(d.opts.MaxBytes), qerr)
+				}
+				return downloaded, fmt.Errorf("%w (%s)，已下载部分已隔离到 %s", ErrTooLarge, FormatSize(d.opts.MaxBytes), target)
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return downloaded, fmt.Errorf("写入文件失败: %w", err)
+			}
+			downloaded += int64(n)
+			if time.Since(last) >= d.opts.ProgressInterval {
+				last = time.Now()
+				d.progress(Progress{Downloaded: downloaded, Total: total, Resumed: resumed})
+			}
+		}
+		if rerr == io.EOF {
+			d.progress(Progress{Downloaded: downloaded, Total: total, Resumed: resumed})
+			return downloaded, nil
+		}
+		if rerr != nil {
+			if ctx.Err() != nil {
+				return downloaded, ctx.Err()
+			}
+			return downloaded, &retryableError{fmt.Errorf("读取响应失败: %w", rerr)}
+		}
+	}
+}
+
+func (d *downloader) progress(p Progress) {
+	if d.opts.OnProgress != nil {
+		d.opts.OnProgress(p)
+	}
+}
+
+// quarantine 将部分文件移入隔离目录，返回隔离后的路径。
+func (d *downloader) quarantine() (string, error) {
+	os.Remove(d.metaPath())
+	if err := os.MkdirAll(d.opts.QuarantineDir, 0o755); err != nil {
+		return "", err
+	}
+	target := filepath.Join(d.opts.QuarantineDir,
+		fmt.Sprintf("%s.%s", filepath.Base(d.dest), time.Now().Format("20060102-150405")))
+	if err := os.Rename(d.part, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// parseContentRange 解析 "bytes start-end/size" 或 "bytes */size"，size 未知时为 -1。
+func parseContentRange(v string) (start, size int64, ok bool) {
+	v, found := strings.CutPrefix(v, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, sizeStr, found := strings.Cut(v, "/")
+	if !found {
+		return 0, 0, false
+	}
+	size = -1
+	if sizeStr != "*" {
+		var err error
+		if size, err = strconv.ParseInt(sizeStr, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if rng == "*" {
+		return 0, size, true
+	}
+	startStr, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// hashFile 计算文件的 SHA256 和大小。
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("打开下载文件失败: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("计算 SHA256 失败: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// FormatSize 返回可读的文件大小。
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,150 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testContent() []byte {
+	return bytes.Repeat([]byte("0123456789abcdef"), 4096) // 64 KB
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// flakyServer 第一次请求只发送一半内容后断开连接，之后按 Range 正常响应。
+func flakyServer(t *testing.T, content []byte) (*httptest.Server, *atomic.Int32, *atomic.Value) {
+	var requests atomic.Int32
+	var lastRange atomic.Value
+	lastRange.Store("")
+	modTime := time.Now()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRange.Store(r.Header.Get("Range"))
+		if requests.Add(1) == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Header().Set("ETag", `"v1"`)
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "file.bin", modTime, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests, &lastRange
+}
+
+func TestDownload_Resume(t *testing.T) {
+	content := testContent()
+	srv, requests, lastRange := flakyServer(t, content)
+	dest := filepath.Join(t.TempDir(), "file.bin")
+
+	var progress []Progress
+	res, err := Download(context.Background(), srv.URL, dest, Options{
+		SHA256:     digest(content),
+		RetryDelay: time.Millisecond,
+		OnProgress: func(p Progress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if requests.Load() != 2 || !res.Resumed || res.Attempts != 2 {
+		t.Errorf("requests = %d, result = %+v, want one resumed retry", requests.Load(), res)
+	}
+	if got := lastRange.Load(); got != "bytes="+strconv.Itoa(len(content)/2)+"-" {
+		t.Errorf("Range = %q, want resume from the middle", got)
+	}
+	if data, _ := os.ReadFile(dest); !bytes.Equal(data, content) {
+		t.Error("downloaded content does not match")
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("partial file was left behind: %v", err)
+	}
+	if len(progress) == 0 || progress[len(progress)-1].Downloaded != int64(len(content)) {
+		t.Errorf("progress = %v, want final event with the full size", progress)
+	}
+}
+
+func TestDownload_ChecksumMismatch(t *testing.T) {
+	content := testContent()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "file.bin")
+
+	_, err := Download(context.Background(), srv.URL, dest, Options{SHA256: digest([]byte("other"))})
+	if !errors.Is(err, ErrChecksum) {
+		t.Fatalf("Download() error = %v, want ErrChecksum", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("file with a wrong checksum was saved: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, ".quarantine")); len(entries) != 1 {
+		t.Errorf("quarantine entries = %d, want 1", len(entries))
+	}
+}
+
+func TestDownload_TooLarge(t *testing.T) {
+	content := testContent()
+	// 不声明长度的响应只能在下载过程中发现超限
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/declared" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		} else {
+			w.Header().Set("Transfer-Encoding", "chunked")
+		}
+		w.Write(content)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	_, err := Download(context.Background(), srv.URL+"/declared", filepath.Join(dir, "a.bin"), Options{MaxBytes: 1024})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("declared Download() error = %v, want ErrTooLarge", err)
+	}
+
+	_, err = Download(context.Background(), srv.URL+"/chunked", filepath.Join(dir, "b.bin"), Options{MaxBytes: 1024})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("chunked Download() error = %v, want ErrTooLarge", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, ".quarantine"))
+	if len(entries) != 1 {
+		t.Fatalf("quarantine entries = %d, want 1", len(entries))
+	}
+	if info, _ := entries[0].Info(); info.Size() > 1024 {
+		t.Errorf("quarantined %d bytes, want at most the limit", info.Size())
+	}
+}
+
+func TestDownload_RateLimit(t *testing.T) {
+	content := testContent()[:48*1024]
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	_, err := Download(context.Background(), srv.URL, filepath.Join(t.TempDir(), "file.bin"), Options{RateLimit: 64 * 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 突发 32 KB 之后剩余 16 KB 按 64 KB/s 至少需要 250ms
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("elapsed = %v, want the rate limit to slow the download", elapsed)
+	}
+}
@@ -0,0 +1,188 @@
+// Package ephemeral 实现无痕会话：消息、工具结果和记忆只保存在内存中，
+// 工具在临时工作目录中执行，会话关闭时全部清除。
+package ephemeral
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+)
+
+// DefaultMaxMessages 单个无痕会话在内存中保留的最多消息数
+const DefaultMaxMessages = 200
+
+// Session 无痕会话在内存中的状态。
+type Session struct {
+	Channel   string
+	SessionID string
+	// Dir 临时工作目录，文件、命令和插件工具在其中执行，会话关闭时删除
+	Dir string
+
+	mu         sync.Mutex
+	messages   []providers.ChatMessage
+	lastActive time.Time
+}
+
+// History 返回会话的消息副本，最早的在前。
+func (s *Session) History() []providers.ChatMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]providers.ChatMessage(nil), s.messages...)
+}
+
+// Registry 管理当前打开的无痕会话，只存在于进程内存中，重启后全部消失。
+// nil Registry 表示未启用无痕会话，各方法均可安全调用。
+type Registry struct {
+	mu          sync.Mutex
+	sessions    map[string]*Session
+	baseDir     string
+	maxMessages int
+	logger      *slog.Logger
+}
+
+// NewRegistry 创建无痕会话注册表，baseDir 为临时工作目录的父目录，为空时使用系统临时目录。
+func NewRegistry(baseDir string, logger *slog.Logger) *Registry {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Registry{
+		sessions:    make(map[string]*Session),
+		baseDir:     baseDir,
+		maxMessages: DefaultMaxMessages,
+		logger:      logger,
+	}
+}
+
+// Open 将会话标记为无痕并创建临时工作目录，会话已是无痕时直接返回。
+func (r *Registry) Open(channel, sessionID string) (*Session, error) {
+	if r == nil {
+		return nil, fmt.Errorf("无痕会话未启用")
+	}
+	key := consts.GetSessionKey(channel, sessionID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[key]; ok {
+		return s, nil
+	}
+
+	if r.baseDir != "" {
+		if err := os.MkdirAll(r.baseDir, 0o700); err != nil {
+			return nil, fmt.Errorf("创建临时目录失败: %w", err)
+		}
+	}
+	dir, err := os.MkdirTemp(r.baseDir, "incognito-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时工作目录失败: %w", err)
+	}
+	s := &Session{Channel: channel, SessionID: sessionID, Dir: dir, lastActive: time.Now()}
+	r.sessions[key] = s
+	r.logger.With("name", "【无痕会话】").Info("已开启无痕会话", "channel", channel, "session_id", sessionID)
+	return s, nil
+}
+
+// Get 返回无痕会话，普通会话返回 nil。
+func (r *Registry) Get(channel, sessionID string) *Session {
+	return r.get(consts.GetSessionKey(channel, sessionID))
+}
+
+// get 按会话键查找无痕会话。
+func (r *Registry) get(key string) *Session {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sessions[key]
+}
+
+// Touch 记录无痕会话的活跃时间，普通会话忽略。
+func (r *Registry) Touch(channel, sessionID string) {
+	if s := r.Get(channel, sessionID); s != nil {
+		s.mu.Lock()
+		s.lastActive = time.Now()
+		s.mu.Unlock()
+	}
+}
+
+// Close 关闭无痕会话，清除内存中的消息并删除临时工作目录，返回会话是否存在。
+func (r *Registry) Close(channel, sessionID string) bool {
+	if r == nil {
+		return false
+	}
+	key := consts.GetSessionKey(channel, sessionID)
+	r.mu.Lock()
+	s, ok := r.sessions[key]
+	delete(r.sessions, key)
+	r.mu.Unlock()
+	if ok {
+		r.wipe(s)
+	}
+	return ok
+}
+
+// CloseIdle 关闭空闲超过 idle 的无痕会话，返回关闭的数量。
+func (r *Registry) CloseIdle(idle time.Duration) int {
+	if r == nil || idle <= 0 {
+		return 0
+	}
+	cutoff := time.Now().Add(-idle)
+	var idleSessions []*Session
+	r.mu.Lock()
+	for key, s := range r.sessions {
+		s.mu.Lock()
+		expired := s.lastActive.Before(cutoff)
+		s.mu.Unlock()
+		if expired {
+			idleSessions = append(idleSessions, s)
+			delete(r.sessions, key)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, s := range idleSessions {
+		r.wipe(s)
+	}
+	return len(idleSessions)
+}
+
+// CloseAll 关闭全部无痕会话，进程退出前调用以删除临时工作目录。
+func (r *Registry) CloseAll() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	sessions := r.sessions
+	r.sessions = make(map[string]*Session)
+	r.mu.Unlock()
+	for _, s := range sessions {
+		r.wipe(s)
+	}
+}
+
+// wipe 清除会话的消息并删除临时工作目录。
+func (r *Registry) wipe(s *Session) {
+	s.mu.Lock()
+	s.messages = nil
+	s.mu.Unlock()
+	if err := os.RemoveAll(s.Dir); err != nil {
+		r.logger.With("name", "【无痕会话】").Warn("删除临时工作目录失败", "dir", s.Dir, "error", err)
+	}
+	r.logger.With("name", "【无痕会话】").Info("已关闭无痕会话", "channel", s.Channel, "session_id", s.SessionID)
+}
+
+// append 追加消息，超过上限时丢弃最早的消息。
+func (r *Registry) append(s *Session, msg providers.ChatMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	if over := len(s.messages) - r.maxMessages; over > 0 {
+		s.messages = append([]providers.ChatMessage(nil), s.messages[over:]...)
+	}
+	s.lastActive = time.Now()
+}
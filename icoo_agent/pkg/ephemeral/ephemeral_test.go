@@ -0,0 +1,108 @@
+package ephemeral
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/memory"
+	"icooclaw/pkg/storage"
+)
+
+func TestRegistry(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "incognito")
+	r := NewRegistry(base, nil)
+
+	s, err := r.Open("feishu", "c1")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if again, _ := r.Open("feishu", "c1"); again != s {
+		t.Error("Open() should return the existing session")
+	}
+	if filepath.Dir(s.Dir) != base {
+		t.Errorf("Dir = %q, want under %q", s.Dir, base)
+	}
+	if r.Get("feishu", "c2") != nil {
+		t.Error("Get() returned a session that was never opened")
+	}
+
+	if err := os.WriteFile(filepath.Join(s.Dir, "notes.md"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Close("feishu", "c1") {
+		t.Fatal("Close() = false, want true")
+	}
+	if _, err := os.Stat(s.Dir); !os.IsNotExist(err) {
+		t.Errorf("temp dir still exists after Close(), err = %v", err)
+	}
+	if r.Close("feishu", "c1") {
+		t.Error("Close() of a closed session = true")
+	}
+
+	// 空闲会话被清除，活跃会话保留
+	idle, _ := r.Open("feishu", "idle")
+	idle.lastActive = time.Now().Add(-2 * time.Hour)
+	active, _ := r.Open("feishu", "active")
+	if n := r.CloseIdle(time.Hour); n != 1 {
+		t.Errorf("CloseIdle() = %d, want 1", n)
+	}
+	if r.Get("feishu", "idle") != nil || r.Get("feishu", "active") == nil {
+		t.Error("CloseIdle() closed the wrong sessions")
+	}
+
+	r.CloseAll()
+	if _, err := os.Stat(active.Dir); !os.IsNotExist(err) {
+		t.Errorf("temp dir still exists after CloseAll(), err = %v", err)
+	}
+
+	// nil 注册表表示未启用
+	var disabled *Registry
+	if disabled.Get("feishu", "c1") != nil || disabled.Close("feishu", "c1") {
+		t.Error("nil registry should have no sessions")
+	}
+	disabled.CloseAll()
+}
+
+func TestLoader(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "ephemeral.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	r := NewRegistry(t.TempDir(), nil)
+	l := NewLoader(memory.NewLoader(store, 100, nil), r)
+	ctx := context.Background()
+
+	if _, err := r.Open("feishu", "secret"); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	secretKey := consts.GetSessionKey("feishu", "secret")
+	normalKey := consts.GetSessionKey("feishu", "normal")
+	for _, key := range []string{secretKey, normalKey} {
+		if err := l.Save(ctx, key, consts.RoleUser.ToString(), "hello"); err != nil {
+			t.Fatalf("Save(%s) error = %v", key, err)
+		}
+	}
+
+	// 无痕会话的消息只在内存中
+	if history, _ := l.Load(ctx, secretKey); len(history) != 1 || history[0].Content != "hello" {
+		t.Errorf("Load(secret) = %+v", history)
+	}
+	if stored, err := store.Message().Get(secretKey, 10); err != nil || len(stored) != 0 {
+		t.Errorf("stored messages for the ephemeral session = %d, %v, want 0", len(stored), err)
+	}
+	if history, _ := l.Load(ctx, normalKey); len(history) != 1 {
+		t.Errorf("Load(normal) = %+v, want the stored message", history)
+	}
+
+	// 关闭后消息清除，之后按普通会话处理
+	r.Close("feishu", "secret")
+	if history, _ := l.Load(ctx, secretKey); len(history) != 0 {
+		t.Errorf("Load(secret) after Close() = %+v, want empty", history)
+	}
+}
@@ -0,0 +1,47 @@
+package ephemeral
+
+import (
+	"context"
+
+	"icooclaw/pkg/memory"
+	"icooclaw/pkg/providers"
+)
+
+// Loader 包装记忆加载器：无痕会话的消息保存在内存中，其他会话交给原加载器。
+type Loader struct {
+	next     memory.Loader
+	registry *Registry
+}
+
+// NewLoader 创建区分无痕会话的记忆加载器。
+func NewLoader(next memory.Loader, registry *Registry) *Loader {
+	return &Loader{next: next, registry: registry}
+}
+
+// Load 实现 memory.Loader。
+func (l *Loader) Load(ctx context.Context, sessionKey string) ([]providers.ChatMessage, error) {
+	if s := l.registry.get(sessionKey); s != nil {
+		return s.History(), nil
+	}
+	return l.next.Load(ctx, sessionKey)
+}
+
+// Save 实现 memory.Loader。
+func (l *Loader) Save(ctx context.Context, sessionKey, role, content string) error {
+	if s := l.registry.get(sessionKey); s != nil {
+		l.registry.append(s, providers.ChatMessage{Role: role, Content: content})
+		return nil
+	}
+	return l.next.Save(ctx, sessionKey, role, content)
+}
+
+// Clear 实现 memory.Loader。
+func (l *Loader) Clear(ctx context.Context, sessionKey string) error {
+	if s := l.registry.get(sessionKey); s != nil {
+		s.mu.Lock()
+		s.messages = nil
+		s.mu.Unlock()
+		return nil
+	}
+	return l.next.Clear(ctx, sessionKey)
+}
@@ -2,8 +2,10 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 )
 
 // Sentinel errors
@@ -99,6 +101,25 @@ func NewFailoverError(reason FailoverReason, provider, model string, status int,
 	}
 }
 
+// IsProviderUnavailable 判断错误是否表示提供商暂时不可达（网络错误、超时或 5xx），
+// 此类错误可以排队稍后重试；认证失败、限流和请求格式错误不属于此类。
+func IsProviderUnavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrProviderUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var failover *FailoverError
+	if errors.As(err, &failover) {
+		return failover.Reason == FailoverTimeout
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
 // ClassifiedError represents a classified error with retry information.
 type ClassifiedError struct {
 	Code      string
@@ -0,0 +1,37 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestIsProviderUnavailable(t *testing.T) {
+	connRefused := &url.Error{Op: "Post", URL: "http://127.0.0.1:1", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"connection refused", fmt.Errorf("LLM请求失败: request failed: %w", connRefused), true},
+		{"server error", NewFailoverError(FailoverTimeout, "openai", "", 503, errors.New("busy")), true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"canceled", fmt.Errorf("wrap: %w", context.Canceled), false},
+		{"auth", NewFailoverError(FailoverAuth, "openai", "", 401, errors.New("bad key")), false},
+		{"rate limit", NewFailoverError(FailoverRateLimit, "openai", "", 429, errors.New("slow down")), false},
+		{"other", errors.New("已达到最大工具迭代次数"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsProviderUnavailable(tt.err); got != tt.want {
+				t.Errorf("IsProviderUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
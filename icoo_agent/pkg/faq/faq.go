@@ -0,0 +1,181 @@
+// Package faq answers common questions from stored responses without calling the model.
+//
+// 常见问题保存在数据库中，入站消息与问题及其他问法比较：exact 模式在忽略大小写、空白和标点后完全相同才命中，
+// fuzzy 模式按字符二元组的相似度命中，超过阈值的最相似问题生效。回复中的 {{key}} 替换为会话变量
+// 和内置变量（user_id、user_name、channel、date、time）。智能体的好回答可以提升为常见问题。
+package faq
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"icooclaw/pkg/consts"
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/storage"
+)
+
+// 匹配模式
+const (
+	ModeExact = "exact" // 规范化后完全相同
+	ModeFuzzy = "fuzzy" // 相似度不低于阈值
+)
+
+// Config 匹配配置。
+type Config struct {
+	Mode      string  // 匹配模式
+	Threshold float64 // fuzzy 模式的最低相似度，0-1
+}
+
+// Match 命中的常见问题。
+type Match struct {
+	FAQ      *storage.FAQ `json:"faq"`
+	Question string       `json:"question"` // 命中的问法
+	Score    float64      `json:"score"`    // 相似度，完全相同为 1
+}
+
+// Matcher 从存储中匹配常见问题。
+type Matcher struct {
+	store *storage.FAQStorage
+	cfg   Config
+}
+
+// NewMatcher 创建匹配器。
+func NewMatcher(store *storage.FAQStorage, cfg Config) *Matcher {
+	if cfg.Mode == "" {
+		cfg.Mode = ModeFuzzy
+	}
+	return &Matcher{store: store, cfg: cfg}
+}
+
+// Match 返回与消息最匹配的已启用常见问题，没有命中时返回 nil。
+func (m *Matcher) Match(text, channel string) (*Match, error) {
+	if Normalize(text) == "" {
+		return nil, nil
+	}
+	entries, err := m.store.ListEnabled()
+	if err != nil {
+		return nil, err
+	}
+	return Best(entries, text, channel, m.cfg), nil
+}
+
+// Best 在 entries 中查找与 text 最匹配的常见问题，没有命中时返回 nil。
+func Best(entries []*storage.FAQ, text, channel string, cfg Config) *Match {
+	target := Normalize(text)
+	if target == "" {
+		return nil
+	}
+
+	var best *Match
+	for _, f := range entries {
+		if len(f.Channels) > 0 && !slices.Contains(f.Channels, channel) {
+			continue
+		}
+		for _, q := range append([]string{f.Question}, f.Aliases...) {
+			score := score(target, Normalize(q), cfg.Mode)
+			if score == 0 || (score < 1 && score < cfg.Threshold) {
+				continue
+			}
+			if best == nil || score > best.Score {
+				best = &Match{FAQ: f, Question: q, Score: score}
+			}
+		}
+	}
+	return best
+}
+
+// score 计算规范化后两个问题的相似度。
+func score(a, b, mode string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	if mode == ModeExact {
+		return 0
+	}
+	return Similarity(a, b)
+}
+
+// Normalize 转为小写并去除空白、标点和符号。
+func Normalize(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Similarity 按字符二元组计算 Dice 系数，适用于中文等不以空格分词的文本。
+func Similarity(a, b string) float64 {
+	ga, gb := bigrams(a), bigrams(b)
+	if len(ga) == 0 || len(gb) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(ga))
+	for _, g := range ga {
+		counts[g]++
+	}
+	common := 0
+	for _, g := range gb {
+		if counts[g] > 0 {
+			counts[g]--
+			common++
+		}
+	}
+	return float64(2*common) / float64(len(ga)+len(gb))
+}
+
+// bigrams 返回字符二元组，单个字符时返回该字符。
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) == 1 {
+		return []string{s}
+	}
+	grams := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+// Promote 将智能体的回复提升为常见问题。question 为空时使用该回复之前的用户消息。
+func Promote(s *storage.Storage, messageID, question string) (*storage.FAQ, error) {
+	msg, err := s.Message().GetByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Role != consts.RoleAssistant || strings.TrimSpace(msg.Content) == "" {
+		return nil, fmt.Errorf("只能提升智能体的回复")
+	}
+
+	if question == "" {
+		prev, err := s.Message().Previous(msg, consts.RoleUser)
+		if errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+			return nil, fmt.Errorf("未找到该回复对应的问题，请指定 question")
+		}
+		if err != nil {
+			return nil, err
+		}
+		question = prev.Content
+	}
+
+	f := &storage.FAQ{
+		Question: strings.TrimSpace(question),
+		Answer:   msg.Content,
+		Enabled:  true,
+		Source:   msg.ID,
+	}
+	if err := s.FAQ().Create(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
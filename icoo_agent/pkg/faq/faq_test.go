@@ -0,0 +1,103 @@
+package faq
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+)
+
+func TestBest(t *testing.T) {
+	entries := []*storage.FAQ{
+		{Model: storage.Model{ID: "hours"}, Question: "你们几点上班？", Aliases: []string{"营业时间是什么时候"}, Answer: "工作日 9:00-18:00"},
+		{Model: storage.Model{ID: "refund"}, Question: "如何申请退款", Answer: "在订单页点击退款"},
+		{Model: storage.Model{ID: "feishu"}, Question: "How do I reset my password?", Answer: "reset", Channels: []string{"feishu"}},
+	}
+	fuzzy := Config{Mode: ModeFuzzy, Threshold: 0.6}
+	exact := Config{Mode: ModeExact}
+
+	tests := []struct {
+		name    string
+		text    string
+		channel string
+		cfg     Config
+		want    string
+	}{
+		{"exact ignores punctuation and spaces", "你们几点 上班", "websocket", exact, "hours"},
+		{"exact alias", "营业时间是什么时候?", "websocket", exact, "hours"},
+		{"exact rejects variants", "请问如何申请退款", "websocket", exact, ""},
+		{"fuzzy variant", "请问如何申请退款呢", "websocket", fuzzy, "refund"},
+		{"fuzzy below threshold", "今天天气怎么样", "websocket", fuzzy, ""},
+		{"case insensitive", "how do i RESET my password", "feishu", exact, "feishu"},
+		{"channel restricted", "how do i reset my password", "websocket", exact, ""},
+		{"empty text", "？？", "websocket", fuzzy, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Best(entries, tt.text, tt.channel, tt.cfg)
+			var id string
+			if got != nil {
+				id = got.FAQ.ID
+			}
+			if id != tt.want {
+				t.Errorf("Best(%q) = %q, want %q", tt.text, id, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if s := Similarity("abc", "abc"); s != 1 {
+		t.Errorf("Similarity(same) = %v, want 1", s)
+	}
+	if s := Similarity("abc", "xyz"); s != 0 {
+		t.Errorf("Similarity(disjoint) = %v, want 0", s)
+	}
+	if s := Similarity("如何申请退款", "请问如何申请退款呢"); s < 0.7 || s >= 1 {
+		t.Errorf("Similarity(variant) = %v, want in [0.7, 1)", s)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "faq.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer store.Close()
+
+	sessionKey := consts.GetSessionKey("websocket", "s1")
+	now := time.Now()
+	question := &storage.Message{SessionID: sessionKey, Role: consts.RoleUser, Content: "怎么开发票"}
+	question.CreatedAt = now.Add(-time.Second)
+	answer := &storage.Message{SessionID: sessionKey, Role: consts.RoleAssistant, Content: "在订单详情页申请发票"}
+	answer.CreatedAt = now
+	for _, m := range []*storage.Message{question, answer} {
+		if err := store.Message().Save(m); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	if _, err := Promote(store, question.ID, ""); err == nil {
+		t.Error("Promote(user message) error = nil, want error")
+	}
+
+	f, err := Promote(store, answer.ID, "")
+	if err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	if f.Question != "怎么开发票" || f.Answer != answer.Content || f.Source != answer.ID || !f.Enabled {
+		t.Errorf("Promote() = %+v", f)
+	}
+
+	m := NewMatcher(store.FAQ(), Config{Mode: ModeExact})
+	match, err := m.Match("怎么开发票？", "websocket")
+	if err != nil {
+		t.Fatalf("Match() error = %v", err)
+	}
+	if match == nil || match.FAQ.ID != f.ID || match.Score != 1 {
+		t.Errorf("Match() = %+v, want promoted entry", match)
+	}
+}
@@ -0,0 +1,327 @@
+// Package form provides step-by-step slot filling flows for icooclaw.
+//
+// 表单定义需要收集的字段，进入表单后用户的每条消息作为当前字段的回答，按类型校验通过后进入下一个字段，
+// 全部填写完成后按 submit 模板生成一条消息交给智能体处理，而不依赖模型记住还缺哪些字段：
+//
+//	[[forms.flows]]
+//	name = "ticket"
+//	title = "提交工单"
+//	description = "用户要报告故障或提交工单时使用"
+//	confirm = true
+//	submit = "请按以下信息创建工单：标题 {{title}}，优先级 {{priority}}，描述 {{detail}}"
+//
+//	[[forms.flows.fields]]
+//	name = "priority"
+//	label = "优先级"
+//	type = "choice"
+//	options = ["低", "中", "高"]
+//
+// 字段类型：text（默认，可设 pattern 和 min/max 字符数）、number、integer（可设 min/max）、
+// boolean、date（规范为 YYYY-MM-DD）、email、choice（options 之一，也可回复序号）。
+// 填写过程中回复"取消"退出表单，"跳过"跳过可选字段，"上一步"重新填写上一个字段。
+// 进度保存在会话元数据中，重启后继续；超过有效期或连续多次回答无效时自动退出。
+package form
+
+import (
+	"fmt"
+	"maps"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"icooclaw/pkg/tools"
+)
+
+// 字段类型
+const (
+	TypeText    = "text"    // 文本
+	TypeNumber  = "number"  // 数字
+	TypeInteger = "integer" // 整数
+	TypeBoolean = "boolean" // 是或否
+	TypeDate    = "date"    // 日期
+	TypeEmail   = "email"   // 邮箱地址
+	TypeChoice  = "choice"  // 选项之一
+)
+
+// Field 表单字段。
+type Field struct {
+	Name     string   `mapstructure:"name" json:"name"`         // 字段名，在 submit 模板中以 {{name}} 引用
+	Label    string   `mapstructure:"label" json:"label"`       // 显示名称，默认为字段名
+	Type     string   `mapstructure:"type" json:"type"`         // 字段类型，默认 text
+	Prompt   string   `mapstructure:"prompt" json:"prompt"`     // 提问内容，默认为"请输入<显示名称>"
+	Optional bool     `mapstructure:"optional" json:"optional"` // 是否可以跳过
+	Default  string   `mapstructure:"default" json:"default"`   // 跳过时使用的值
+	Options  []string `mapstructure:"options" json:"options"`   // choice 类型的选项
+	Pattern  string   `mapstructure:"pattern" json:"pattern"`   // text 类型需要匹配的正则表达式
+	Min      *float64 `mapstructure:"min" json:"min"`           // 数字的最小值，text 类型的最少字符数
+	Max      *float64 `mapstructure:"max" json:"max"`           // 数字的最大值，text 类型的最多字符数
+}
+
+// Form 表单定义。
+type Form struct {
+	Name        string  `mapstructure:"name" json:"name"`               // 表单名称，/form <name> 进入
+	Title       string  `mapstructure:"title" json:"title"`             // 显示标题，默认为表单名称
+	Description string  `mapstructure:"description" json:"description"` // 用途说明，提供给模型判断何时进入表单
+	Fields      []Field `mapstructure:"fields" json:"fields"`           // 按顺序收集的字段
+	Confirm     bool    `mapstructure:"confirm" json:"confirm"`         // 填写完成后是否请用户确认
+	Submit      string  `mapstructure:"submit" json:"submit"`           // 完成后交给智能体的消息模板，为空时列出全部字段
+}
+
+// Options 表单运行参数。
+type Options struct {
+	Expire      time.Duration // 进度有效期，超过后自动退出，0 表示不过期
+	MaxAttempts int           // 同一字段连续无效回答的次数上限，达到后退出表单，0 表示不限
+}
+
+// compiledForm 校验后的表单。
+type compiledForm struct {
+	Form
+	patterns map[string]*regexp.Regexp
+}
+
+// Registry 已配置的表单集合。
+type Registry struct {
+	forms map[string]*compiledForm
+	names []string
+	opts  Options
+}
+
+// New 校验表单定义并创建表单集合，定义无效时返回错误。
+func New(forms []Form, opts Options) (*Registry, error) {
+	r := &Registry{forms: make(map[string]*compiledForm, len(forms)), opts: opts}
+
+	for i, f := range forms {
+		if f.Name == "" {
+			return nil, fmt.Errorf("第 %d 个表单缺少 name", i+1)
+		}
+		if _, exists := r.forms[f.Name]; exists {
+			return nil, fmt.Errorf("表单 %s 重复定义", f.Name)
+		}
+		if len(f.Fields) == 0 {
+			return nil, fmt.Errorf("表单 %s 没有字段", f.Name)
+		}
+		if f.Title == "" {
+			f.Title = f.Name
+		}
+
+		cf := &compiledForm{patterns: make(map[string]*regexp.Regexp)}
+		f.Fields = slices.Clone(f.Fields)
+		seen := make(map[string]bool, len(f.Fields))
+		for j := range f.Fields {
+			field := &f.Fields[j]
+			if err := tools.ValidVarName(field.Name); err != nil {
+				return nil, fmt.Errorf("表单 %s 的第 %d 个字段名无效: %w", f.Name, j+1, err)
+			}
+			if seen[field.Name] {
+				return nil, fmt.Errorf("表单 %s 的字段 %s 重复定义", f.Name, field.Name)
+			}
+			seen[field.Name] = true
+			if err := compileField(cf, field); err != nil {
+				return nil, fmt.Errorf("表单 %s 的字段 %s %w", f.Name, field.Name, err)
+			}
+		}
+		cf.Form = f
+		r.forms[f.Name] = cf
+		r.names = append(r.names, f.Name)
+	}
+	return r, nil
+}
+
+// compileField 补全字段的默认值并检查配置。
+func compileField(cf *compiledForm, field *Field) error {
+	if field.Label == "" {
+		field.Label = field.Name
+	}
+	if field.Type == "" {
+		field.Type = TypeText
+	}
+	if field.Prompt == "" {
+		field.Prompt = "请输入" + field.Label
+		if field.Type == TypeChoice {
+			field.Prompt = "请选择" + field.Label
+		}
+	}
+
+	switch field.Type {
+	case TypeText:
+		if field.Pattern != "" {
+			re, err := regexp.Compile(field.Pattern)
+			if err != nil {
+				return fmt.Errorf("的正则表达式无效: %w", err)
+			}
+			cf.patterns[field.Name] = re
+		}
+	case TypeChoice:
+		if len(field.Options) == 0 {
+			return fmt.Errorf("为 choice 类型，必须设置 options")
+		}
+	case TypeNumber, TypeInteger, TypeBoolean, TypeDate, TypeEmail:
+	default:
+		return fmt.Errorf("的类型 %s 无效，可用 text、number、integer、boolean、date、email、choice", field.Type)
+	}
+	if field.Min != nil && field.Max != nil && *field.Min > *field.Max {
+		return fmt.Errorf("的 min 大于 max")
+	}
+	if field.Default != "" {
+		value, err := cf.normalize(*field, field.Default)
+		if err != nil {
+			return fmt.Errorf("的默认值无效: %w", err)
+		}
+		field.Default = value
+	}
+	return nil
+}
+
+// Len 返回表单数量，r 为 nil 时返回 0。
+func (r *Registry) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.names)
+}
+
+// Get 按名称返回表单定义。
+func (r *Registry) Get(name string) (Form, bool) {
+	if r == nil {
+		return Form{}, false
+	}
+	f, ok := r.forms[name]
+	if !ok {
+		return Form{}, false
+	}
+	return f.Form, true
+}
+
+// List 按配置顺序返回全部表单定义。
+func (r *Registry) List() []Form {
+	if r == nil {
+		return nil
+	}
+	forms := make([]Form, 0, len(r.names))
+	for _, name := range r.names {
+		forms = append(forms, r.forms[name].Form)
+	}
+	return forms
+}
+
+// normalize 按字段类型校验回答并转为规范形式。
+func (cf *compiledForm) normalize(field Field, text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("回答不能为空")
+	}
+
+	switch field.Type {
+	case TypeNumber, TypeInteger:
+		s := strings.ReplaceAll(text, ",", "")
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return "", fmt.Errorf("请输入数字")
+		}
+		if field.Type == TypeInteger {
+			i, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("请输入整数")
+			}
+			n, s = float64(i), strconv.FormatInt(i, 10)
+		} else {
+			s = strconv.FormatFloat(n, 'f', -1, 64)
+		}
+		if field.Min != nil && n < *field.Min {
+			return "", fmt.Errorf("不能小于 %s", formatNumber(*field.Min))
+		}
+		if field.Max != nil && n > *field.Max {
+			return "", fmt.Errorf("不能大于 %s", formatNumber(*field.Max))
+		}
+		return s, nil
+	case TypeBoolean:
+		if yes, ok := parseYesNo(text); ok {
+			return strconv.FormatBool(yes), nil
+		}
+		return "", fmt.Errorf("请回复 是 或 否")
+	case TypeDate:
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t.Format(time.DateOnly), nil
+			}
+		}
+		return "", fmt.Errorf("请按 YYYY-MM-DD 格式输入日期")
+	case TypeEmail:
+		addr, err := mail.ParseAddress(text)
+		if err != nil || !strings.Contains(addr.Address, ".") {
+			return "", fmt.Errorf("请输入有效的邮箱地址")
+		}
+		return addr.Address, nil
+	case TypeChoice:
+		if i, err := strconv.Atoi(text); err == nil && i >= 1 && i <= len(field.Options) {
+			return field.Options[i-1], nil
+		}
+		for _, opt := range field.Options {
+			if strings.EqualFold(opt, text) {
+				return opt, nil
+			}
+		}
+		return "", fmt.Errorf("请从以下选项中选择: %s", strings.Join(field.Options, "、"))
+	default:
+		n := float64(utf8.RuneCountInString(text))
+		if field.Min != nil && n < *field.Min {
+			return "", fmt.Errorf("至少需要 %s 个字符", formatNumber(*field.Min))
+		}
+		if field.Max != nil && n > *field.Max {
+			return "", fmt.Errorf("最多 %s 个字符", formatNumber(*field.Max))
+		}
+		if re := cf.patterns[field.Name]; re != nil && !re.MatchString(text) {
+			return "", fmt.Errorf("格式不正确")
+		}
+		return text, nil
+	}
+}
+
+// dateLayouts 可识别的日期格式。
+var dateLayouts = []string{time.DateOnly, "2006/1/2", "2006.1.2", "2006-1-2", "2006年1月2日"}
+
+// formatNumber 格式化数字，整数不带小数点。
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// parseYesNo 识别是或否的回答。
+func parseYesNo(text string) (yes, ok bool) {
+	switch strings.ToLower(strings.TrimRight(strings.TrimSpace(text), "。.!！")) {
+	case "是", "是的", "对", "好", "确认", "提交", "y", "yes", "true", "1", "ok":
+		return true, true
+	case "否", "不", "不是", "没有", "n", "no", "false", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// render 按字段顺序渲染已填写的值。
+func (cf *compiledForm) render(values map[string]string) string {
+	sb := strings.Builder{}
+	for _, field := range cf.Fields {
+		value, ok := values[field.Name]
+		if !ok {
+			continue
+		}
+		if value == "" {
+			value = "（未填写）"
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", field.Label, value))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// submission 生成交给智能体的消息。
+func (cf *compiledForm) submission(values map[string]string) string {
+	if cf.Submit == "" {
+		return fmt.Sprintf("用户已填写「%s」:\n%s", cf.Title, cf.render(values))
+	}
+	v := make(tools.Vars, len(values))
+	maps.Copy(v, values)
+	return v.Interpolate(cf.Submit)
+}
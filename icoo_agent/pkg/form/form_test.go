@@ -0,0 +1,167 @@
+package form
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/storage"
+)
+
+func ptr(f float64) *float64 { return &f }
+
+func ticketForm() Form {
+	return Form{
+		Name:    "ticket",
+		Title:   "提交工单",
+		Confirm: true,
+		Submit:  "创建工单: {{title}} / {{priority}} / {{count}} / {{due}} / {{email}} / {{note}}",
+		Fields: []Field{
+			{Name: "title", Label: "标题", Min: ptr(2), Max: ptr(20)},
+			{Name: "priority", Label: "优先级", Type: TypeChoice, Options: []string{"低", "中", "高"}},
+			{Name: "count", Label: "影响人数", Type: TypeInteger, Min: ptr(1)},
+			{Name: "due", Label: "期望日期", Type: TypeDate},
+			{Name: "email", Label: "邮箱", Type: TypeEmail},
+			{Name: "note", Label: "备注", Optional: true, Default: "无"},
+		},
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		form Form
+		want string
+	}{
+		{"missing name", Form{Fields: []Field{{Name: "a"}}}, "缺少 name"},
+		{"no fields", Form{Name: "f"}, "没有字段"},
+		{"bad field name", Form{Name: "f", Fields: []Field{{Name: "a-b"}}}, "字段名无效"},
+		{"duplicate field", Form{Name: "f", Fields: []Field{{Name: "a"}, {Name: "a"}}}, "重复定义"},
+		{"unknown type", Form{Name: "f", Fields: []Field{{Name: "a", Type: "color"}}}, "类型 color 无效"},
+		{"choice without options", Form{Name: "f", Fields: []Field{{Name: "a", Type: TypeChoice}}}, "必须设置 options"},
+		{"bad pattern", Form{Name: "f", Fields: []Field{{Name: "a", Pattern: "("}}}, "正则表达式无效"},
+		{"bad default", Form{Name: "f", Fields: []Field{{Name: "a", Type: TypeInteger, Default: "x"}}}, "默认值无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]Form{tt.form}, Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := New([]Form{ticketForm(), ticketForm()}, Options{}); err == nil {
+		t.Error("New() should reject duplicate form names")
+	}
+}
+
+func TestRegistry_Answer(t *testing.T) {
+	r, err := New([]Form{ticketForm()}, Options{MaxAttempts: 3})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := time.Now()
+
+	// 预填的有效值不再提问，无效值被忽略
+	st, prompt, ignored, err := r.Start("ticket", map[string]string{"title": "打印机坏了", "count": "零"}, now)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if !strings.Contains(prompt, "请选择优先级\n1. 低\n2. 中\n3. 高") || ignored["count"] == "" {
+		t.Errorf("Start() prompt = %q, ignored = %v", prompt, ignored)
+	}
+
+	steps := []struct {
+		answer string
+		want   string
+	}{
+		{"紧急", "请从以下选项中选择"},
+		{"3", "请输入影响人数"},
+		{"0", "不能小于 1"},
+		{"1,200", "请输入期望日期（YYYY-MM-DD）"},
+		{"上一步", "请输入影响人数"},
+		{"12", "请输入期望日期"},
+		{"2026年3月5日", "请输入邮箱"},
+		{"Alice <alice@example.com>", "可回复\"跳过\"，默认为 无"},
+		{"跳过", "请确认以下信息:\n- 标题: 打印机坏了\n- 优先级: 高\n- 影响人数: 12"},
+		{"修改 优先级", "请选择优先级"},
+		{"中", "- 优先级: 中"},
+	}
+	for _, s := range steps {
+		step, err := r.Answer(st, s.answer, now)
+		if err != nil || step.Outcome != Asking || !strings.Contains(step.Reply, s.want) {
+			t.Fatalf("Answer(%q) = %+v, %v, want reply containing %q", s.answer, step, err, s.want)
+		}
+	}
+
+	step, err := r.Answer(st, "是", now)
+	if err != nil || step.Outcome != Done {
+		t.Fatalf("Answer(是) = %+v, %v", step, err)
+	}
+	if want := "创建工单: 打印机坏了 / 中 / 12 / 2026-03-05 / alice@example.com / 无"; step.Submit != want {
+		t.Errorf("Submit = %q, want %q", step.Submit, want)
+	}
+}
+
+func TestRegistry_EarlyExit(t *testing.T) {
+	r, err := New([]Form{{
+		Name:   "survey",
+		Fields: []Field{{Name: "age", Type: TypeInteger}, {Name: "ok", Type: TypeBoolean}},
+	}}, Options{Expire: time.Minute, MaxAttempts: 2})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := time.Now()
+
+	st, _, _, _ := r.Start("survey", nil, now)
+	if step, _ := r.Answer(st, "取消", now); step.Outcome != Cancelled {
+		t.Errorf("Answer(取消) outcome = %v", step.Outcome)
+	}
+
+	st, _, _, _ = r.Start("survey", nil, now)
+	if step, _ := r.Answer(st, "跳过", now); step.Outcome != Asking || !strings.Contains(step.Reply, "必填项") {
+		t.Errorf("skipping a required field = %+v", step)
+	}
+	if step, _ := r.Answer(st, "很多", now); step.Outcome != Abandoned || !strings.Contains(step.Reply, "/form survey") {
+		t.Errorf("second invalid answer = %+v", step)
+	}
+
+	// 未设置 confirm 时最后一个字段填写后直接完成，默认列出全部字段
+	st, _, _, _ = r.Start("survey", map[string]string{"age": "30"}, now)
+	step, _ := r.Answer(st, "yes", now)
+	if step.Outcome != Done || step.Submit != "用户已填写「survey」:\n- age: 30\n- ok: true" {
+		t.Errorf("Answer(yes) = %+v", step)
+	}
+
+	if r.Expired(st, now.Add(30*time.Second)) || !r.Expired(st, now.Add(2*time.Minute)) {
+		t.Error("Expired() should honour Options.Expire")
+	}
+}
+
+func TestStore(t *testing.T) {
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "form.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	store := NewStore(s.Session())
+
+	if st, err := store.Load("websocket", "s1"); st != nil || err != nil {
+		t.Fatalf("Load() before save = %+v, %v", st, err)
+	}
+	want := &State{Form: "ticket", Values: map[string]string{"title": "x"}, Field: 1}
+	if err := store.Save("websocket", "s1", want); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	got, err := store.Load("websocket", "s1")
+	if err != nil || got.Form != "ticket" || got.Field != 1 || got.Values["title"] != "x" {
+		t.Errorf("Load() = %+v, %v", got, err)
+	}
+	if err := store.Clear("websocket", "s1"); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if st, _ := store.Load("websocket", "s1"); st != nil {
+		t.Errorf("Load() after clear = %+v", st)
+	}
+}
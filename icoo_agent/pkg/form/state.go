@@ -0,0 +1,289 @@
+package form
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/storage"
+)
+
+// MetadataKey 表单进度在会话元数据中的键
+const MetadataKey = "form"
+
+// State 会话的表单填写进度。
+type State struct {
+	Form      string            `json:"form"`       // 表单名称
+	Values    map[string]string `json:"values"`     // 已填写的字段值
+	Field     int               `json:"field"`      // 当前字段下标，等于字段数时等待确认
+	Attempts  int               `json:"attempts"`   // 当前字段连续无效回答次数
+	StartedAt time.Time         `json:"started_at"` // 开始时间
+	UpdatedAt time.Time         `json:"updated_at"` // 最后一次回答的时间
+}
+
+// Outcome 处理一条回答后的结果。
+type Outcome int
+
+const (
+	// Asking 继续提问
+	Asking Outcome = iota
+	// Done 填写完成，Submit 交给智能体
+	Done
+	// Cancelled 用户取消
+	Cancelled
+	// Abandoned 多次回答无效，自动退出
+	Abandoned
+)
+
+// Step 处理一条回答后的结果。
+type Step struct {
+	Outcome Outcome
+	Reply   string            // 回复用户的内容，Done 时为空
+	Submit  string            // Done 时交给智能体的消息
+	Values  map[string]string // Done 时填写的全部字段值
+}
+
+// 填写过程中可用的指令
+var (
+	cancelWords = []string{"取消", "退出", "cancel", "quit", "exit"}
+	skipWords   = []string{"跳过", "skip"}
+	backWords   = []string{"上一步", "返回", "back"}
+	editPrefix  = []string{"修改", "edit"}
+)
+
+// Start 开始填写表单。prefill 为已知的字段值，无效的值被忽略，已填写的字段不再提问。
+// 返回的 ignored 为被忽略的字段及原因。
+func (r *Registry) Start(name string, prefill map[string]string, now time.Time) (st *State, prompt string, ignored map[string]string, err error) {
+	cf, ok := r.lookup(name)
+	if !ok {
+		return nil, "", nil, fmt.Errorf("表单 %s 不存在", name)
+	}
+
+	st = &State{Form: name, Values: make(map[string]string), StartedAt: now, UpdatedAt: now}
+	for _, field := range cf.Fields {
+		text, ok := prefill[field.Name]
+		if !ok || strings.TrimSpace(text) == "" {
+			continue
+		}
+		value, err := cf.normalize(field, text)
+		if err != nil {
+			if ignored == nil {
+				ignored = make(map[string]string)
+			}
+			ignored[field.Name] = err.Error()
+			continue
+		}
+		st.Values[field.Name] = value
+	}
+	cf.advance(st)
+	return st, fmt.Sprintf("开始填写「%s」，回复\"取消\"可随时退出。\n%s", cf.Title, cf.question(st)), ignored, nil
+}
+
+// Expired 进度是否已超过有效期。
+func (r *Registry) Expired(st *State, now time.Time) bool {
+	return r.opts.Expire > 0 && now.Sub(st.UpdatedAt) > r.opts.Expire
+}
+
+// Question 返回当前的提问，用于查看进度。
+func (r *Registry) Question(st *State) string {
+	cf, ok := r.lookup(st.Form)
+	if !ok {
+		return ""
+	}
+	return cf.question(st)
+}
+
+// Progress 渲染已填写的字段和当前提问。
+func (r *Registry) Progress(st *State) string {
+	cf, ok := r.lookup(st.Form)
+	if !ok {
+		return ""
+	}
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("正在填写「%s」（%d/%d）", cf.Title, min(st.Field, len(cf.Fields)), len(cf.Fields)))
+	if filled := cf.render(st.Values); filled != "" {
+		sb.WriteString("\n" + filled)
+	}
+	sb.WriteString("\n" + cf.question(st))
+	return sb.String()
+}
+
+// Answer 处理用户对当前提问的回答，st 原地更新。
+func (r *Registry) Answer(st *State, text string, now time.Time) (Step, error) {
+	cf, ok := r.lookup(st.Form)
+	if !ok {
+		return Step{}, fmt.Errorf("表单 %s 不存在", st.Form)
+	}
+	st.UpdatedAt = now
+	text = strings.TrimSpace(text)
+	word := strings.ToLower(strings.TrimRight(text, "。.!！"))
+
+	if slices.Contains(cancelWords, word) {
+		return Step{Outcome: Cancelled, Reply: fmt.Sprintf("已取消填写「%s」", cf.Title)}, nil
+	}
+	if slices.Contains(backWords, word) {
+		cf.back(st)
+		return Step{Outcome: Asking, Reply: cf.question(st)}, nil
+	}
+
+	// 全部字段已填写，等待确认或修改
+	if st.Field >= len(cf.Fields) {
+		if yes, ok := parseYesNo(word); ok && yes {
+			return cf.done(st), nil
+		}
+		if field, ok := cf.editTarget(text); ok {
+			delete(st.Values, cf.Fields[field].Name)
+			st.Field, st.Attempts = field, 0
+			return Step{Outcome: Asking, Reply: cf.question(st)}, nil
+		}
+		return r.invalid(cf, st, "请回复\"是\"提交，回复\"修改 字段名\"修改，或回复\"取消\"放弃")
+	}
+
+	field := cf.Fields[st.Field]
+	var value string
+	if slices.Contains(skipWords, word) {
+		if !field.Optional {
+			return r.invalid(cf, st, fmt.Sprintf("%s 为必填项，不能跳过", field.Label))
+		}
+		value = field.Default
+	} else {
+		var err error
+		if value, err = cf.normalize(field, text); err != nil {
+			return r.invalid(cf, st, err.Error())
+		}
+	}
+
+	st.Values[field.Name] = value
+	st.Attempts = 0
+	cf.advance(st)
+	if st.Field >= len(cf.Fields) && !cf.Confirm {
+		return cf.done(st), nil
+	}
+	return Step{Outcome: Asking, Reply: cf.question(st)}, nil
+}
+
+// invalid 记录一次无效回答，达到上限时退出表单。
+func (r *Registry) invalid(cf *compiledForm, st *State, reason string) (Step, error) {
+	st.Attempts++
+	if r.opts.MaxAttempts > 0 && st.Attempts >= r.opts.MaxAttempts {
+		return Step{Outcome: Abandoned, Reply: fmt.Sprintf("%s。多次回答无效，已退出「%s」，可用 /form %s 重新开始",
+			reason, cf.Title, cf.Name)}, nil
+	}
+	return Step{Outcome: Asking, Reply: reason + "\n" + cf.question(st)}, nil
+}
+
+// lookup 按名称查找表单。
+func (r *Registry) lookup(name string) (*compiledForm, bool) {
+	if r == nil {
+		return nil, false
+	}
+	cf, ok := r.forms[name]
+	return cf, ok
+}
+
+// advance 跳到第一个未填写的字段，全部填写时停在字段数处。
+func (cf *compiledForm) advance(st *State) {
+	st.Field = 0
+	for st.Field < len(cf.Fields) {
+		if _, ok := st.Values[cf.Fields[st.Field].Name]; !ok {
+			return
+		}
+		st.Field++
+	}
+}
+
+// back 清除上一个已填写的字段并回到该字段。
+func (cf *compiledForm) back(st *State) {
+	for i := min(st.Field, len(cf.Fields)) - 1; i >= 0; i-- {
+		if _, ok := st.Values[cf.Fields[i].Name]; ok {
+			delete(st.Values, cf.Fields[i].Name)
+			st.Field, st.Attempts = i, 0
+			return
+		}
+	}
+}
+
+// editTarget 解析"修改 字段名"，返回字段下标。
+func (cf *compiledForm) editTarget(text string) (int, bool) {
+	lower := strings.ToLower(text)
+	for _, prefix := range editPrefix {
+		if !strings.HasPrefix(lower, prefix) {
+			continue
+		}
+		target := strings.TrimSpace(text[len(prefix):])
+		for i, field := range cf.Fields {
+			if strings.EqualFold(target, field.Label) || strings.EqualFold(target, field.Name) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// question 当前字段的提问，全部填写时为确认提示。
+func (cf *compiledForm) question(st *State) string {
+	if st.Field >= len(cf.Fields) {
+		return fmt.Sprintf("请确认以下信息:\n%s\n回复\"是\"提交，回复\"修改 字段名\"修改", cf.render(st.Values))
+	}
+
+	field := cf.Fields[st.Field]
+	sb := strings.Builder{}
+	sb.WriteString(field.Prompt)
+	switch field.Type {
+	case TypeChoice:
+		for i, opt := range field.Options {
+			sb.WriteString(fmt.Sprintf("\n%d. %s", i+1, opt))
+		}
+	case TypeBoolean:
+		sb.WriteString("（是/否）")
+	case TypeDate:
+		sb.WriteString("（YYYY-MM-DD）")
+	}
+	if field.Optional {
+		if field.Default != "" {
+			sb.WriteString(fmt.Sprintf("\n可回复\"跳过\"，默认为 %s", field.Default))
+		} else {
+			sb.WriteString("\n可回复\"跳过\"")
+		}
+	}
+	return sb.String()
+}
+
+// done 生成填写完成的结果。
+func (cf *compiledForm) done(st *State) Step {
+	return Step{Outcome: Done, Submit: cf.submission(st.Values), Values: st.Values}
+}
+
+// Store 表单进度仓库，进度保存在会话元数据中，随会话持久化。
+type Store struct {
+	sessions *storage.SessionStorage
+}
+
+// NewStore 创建表单进度仓库。
+func NewStore(sessions *storage.SessionStorage) *Store {
+	return &Store{sessions: sessions}
+}
+
+// Load 读取会话的表单进度，没有正在填写的表单时返回 nil。
+func (s *Store) Load(channel, sessionID string) (*State, error) {
+	var st State
+	ok, err := s.sessions.GetMetadata(channel, sessionID, MetadataKey, &st)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if st.Values == nil {
+		st.Values = make(map[string]string)
+	}
+	return &st, nil
+}
+
+// Save 保存会话的表单进度。
+func (s *Store) Save(channel, sessionID string, st *State) error {
+	return s.sessions.SetMetadata(channel, sessionID, MetadataKey, st)
+}
+
+// Clear 清除会话的表单进度。
+func (s *Store) Clear(channel, sessionID string) error {
+	return s.sessions.SetMetadata(channel, sessionID, MetadataKey, nil)
+}
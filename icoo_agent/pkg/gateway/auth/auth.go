@@ -0,0 +1,349 @@
+// Package auth authenticates gateway requests with API keys or HS256 JWT bearer tokens
+// and checks the scope each route requires.
+//
+// 凭证按以下顺序读取：Authorization: Bearer 头、X-API-Key 头，以及浏览器建立 WebSocket 和 SSE
+// 连接时无法设置请求头所用的 access_token、api_key 查询参数。Bearer 值为 JWT 时校验签名，否则按 API 密钥匹配。
+package auth
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	gwMiddleware "icooclaw/pkg/gateway/middleware"
+)
+
+// 权限范围
+const (
+	ScopePublic = ""      // 无需认证，如健康检查和渠道回调
+	ScopeChat   = "chat"  // 聊天接口，如 /api/v1/chat、/ws、/events
+	ScopeAdmin  = "admin" // 管理接口，包含 chat 权限
+)
+
+// 认证方式
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+const (
+	// DefaultIssuer 默认的 JWT 签发者
+	DefaultIssuer = "icooclaw"
+	// DefaultTTL 签发令牌的默认有效期
+	DefaultTTL = 24 * time.Hour
+	// MinSecretSize JWT 签名密钥的最小字节数
+	MinSecretSize = 32
+	// keyPrefix 生成的 API 密钥前缀，便于在日志和代码中识别
+	keyPrefix = "icc_"
+)
+
+var (
+	// ErrMissing 请求未携带凭证
+	ErrMissing = errors.New("missing credentials")
+	// ErrInvalid 凭证无效或已过期
+	ErrInvalid = errors.New("invalid credentials")
+)
+
+// Scopes 返回所有权限范围。
+func Scopes() []string {
+	return []string{ScopeChat, ScopeAdmin}
+}
+
+// APIKey 配置的 API 密钥。
+type APIKey struct {
+	Name      string   `mapstructure:"name" json:"name"`     // 密钥名称，作为请求的用户 ID
+	Key       string   `mapstructure:"key" json:"-"`         // 明文密钥
+	KeySHA256 string   `mapstructure:"key_sha256" json:"-"`  // 密钥的 SHA-256 十六进制摘要，与 key 二选一，配置文件中不保存明文
+	Scopes    []string `mapstructure:"scopes" json:"scopes"` // 权限范围，默认 chat
+}
+
+// Options 认证器选项。
+type Options struct {
+	Keys    []APIKey
+	Secrets [][]byte      // JWT 签名密钥，第一个用于签发，全部用于校验
+	Issuer  string        // JWT 签发者，为空时使用 DefaultIssuer
+	TTL     time.Duration // 签发令牌的默认有效期，为 0 时使用 DefaultTTL
+}
+
+// Identity 通过认证的调用方。
+type Identity struct {
+	Subject string   // API 密钥名称或 JWT 的 sub
+	Scopes  []string // 权限范围
+	Method  string   // 认证方式
+}
+
+// Allows 判断调用方是否拥有权限，admin 包含全部权限。
+func (i *Identity) Allows(scope string) bool {
+	return scope == ScopePublic || slices.Contains(i.Scopes, scope) || slices.Contains(i.Scopes, ScopeAdmin)
+}
+
+// apiKey 解析后的 API 密钥。
+type apiKey struct {
+	name   string
+	hash   []byte
+	scopes []string
+}
+
+// Authenticator 校验 API 密钥和 JWT。
+type Authenticator struct {
+	keys    []apiKey
+	secrets [][]byte
+	issuer  string
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// New 校验选项并创建认证器，至少需要一个 API 密钥或 JWT 签名密钥。
+func New(opts Options) (*Authenticator, error) {
+	a := &Authenticator{
+		issuer: cmp.Or(opts.Issuer, DefaultIssuer),
+		ttl:    opts.TTL,
+		now:    time.Now,
+	}
+	if a.ttl < 0 {
+		return nil, fmt.Errorf("令牌有效期不能为负数")
+	}
+	if a.ttl == 0 {
+		a.ttl = DefaultTTL
+	}
+
+	for i, k := range opts.Keys {
+		if k.Name == "" {
+			return nil, fmt.Errorf("第 %d 个 API 密钥缺少 name", i+1)
+		}
+		if slices.ContainsFunc(a.keys, func(e apiKey) bool { return e.name == k.Name }) {
+			return nil, fmt.Errorf("API 密钥 %s 重复", k.Name)
+		}
+		var hash []byte
+		switch {
+		case k.Key != "" && k.KeySHA256 != "":
+			return nil, fmt.Errorf("API 密钥 %s 的 key 和 key_sha256 只能配置一个", k.Name)
+		case k.Key != "":
+			sum := sha256.Sum256([]byte(k.Key))
+			hash = sum[:]
+		case k.KeySHA256 != "":
+			var err error
+			if hash, err = hex.DecodeString(k.KeySHA256); err != nil || len(hash) != sha256.Size {
+				return nil, fmt.Errorf("API 密钥 %s 的 key_sha256 需要 64 位十六进制", k.Name)
+			}
+		default:
+			return nil, fmt.Errorf("API 密钥 %s 缺少 key 或 key_sha256", k.Name)
+		}
+		scopes, err := normalizeScopes(k.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("API 密钥 %s: %w", k.Name, err)
+		}
+		a.keys = append(a.keys, apiKey{name: k.Name, hash: hash, scopes: scopes})
+	}
+
+	for i, secret := range opts.Secrets {
+		if len(secret) < MinSecretSize {
+			return nil, fmt.Errorf("第 %d 个 JWT 签名密钥长度为 %d 字节，至少需要 %d 字节", i+1, len(secret), MinSecretSize)
+		}
+		a.secrets = append(a.secrets, secret)
+	}
+
+	if len(a.keys) == 0 && len(a.secrets) == 0 {
+		return nil, fmt.Errorf("至少需要配置一个 API 密钥或 JWT 签名密钥")
+	}
+	return a, nil
+}
+
+// normalizeScopes 校验权限范围并去重，为空时默认 chat。
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return []string{ScopeChat}, nil
+	}
+	var out []string
+	for _, s := range scopes {
+		if !slices.Contains(Scopes(), s) {
+			return nil, fmt.Errorf("权限范围 %q 无效，可选 %s", s, strings.Join(Scopes(), "、"))
+		}
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// Authenticate 校验请求携带的凭证。
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	return a.AuthenticateCredential(credential(r))
+}
+
+// AuthenticateCredential 校验 API 密钥或 JWT，用于 gRPC 等不经过 HTTP 中间件的请求。
+func (a *Authenticator) AuthenticateCredential(cred string) (*Identity, error) {
+	if cred == "" {
+		return nil, ErrMissing
+	}
+	if strings.Count(cred, ".") == 2 {
+		return a.verify(cred)
+	}
+	return a.matchKey(cred)
+}
+
+// credential 从请求头或查询参数中读取凭证。
+func credential(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	q := r.URL.Query()
+	return cmp.Or(q.Get("access_token"), q.Get("api_key"))
+}
+
+// matchKey 按 SHA-256 摘要以常量时间比较 API 密钥，逐个比较全部密钥以免泄露匹配位置。
+func (a *Authenticator) matchKey(key string) (*Identity, error) {
+	sum := sha256.Sum256([]byte(key))
+	var found *apiKey
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(sum[:], a.keys[i].hash) == 1 {
+			found = &a.keys[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrInvalid
+	}
+	return &Identity{Subject: found.name, Scopes: found.scopes, Method: MethodAPIKey}, nil
+}
+
+// Issue 用当前签名密钥签发令牌，ttl 为 0 时使用默认有效期。
+func (a *Authenticator) Issue(subject string, scopes []string, ttl time.Duration) (string, time.Time, error) {
+	if len(a.secrets) == 0 {
+		return "", time.Time{}, fmt.Errorf("未配置 JWT 签名密钥")
+	}
+	if subject == "" {
+		return "", time.Time{}, fmt.Errorf("缺少令牌主体")
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if ttl <= 0 {
+		ttl = a.ttl
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", time.Time{}, fmt.Errorf("生成令牌 ID 失败: %w", err)
+	}
+
+	now := a.now()
+	exp := now.Add(ttl)
+	token, err := sign(Claims{
+		Subject:   subject,
+		Scope:     strings.Join(scopes, " "),
+		Issuer:    a.issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: exp.Unix(),
+		ID:        hex.EncodeToString(id),
+	}, a.secrets[0])
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, exp, nil
+}
+
+// Route 路由前缀所需的权限范围。
+type Route struct {
+	Prefix string
+	Scope  string
+}
+
+// Middleware 按路由检查凭证和权限，使用第一个匹配的前缀，未匹配的路由需要 fallback 权限。
+// 通过认证的请求在上下文中携带 Identity，并以主体作为用户 ID。
+func (a *Authenticator) Middleware(routes []Route, fallback string, logger *slog.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With("name", "【网关认证】")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := scopeFor(routes, fallback, r.URL.Path)
+			if scope == ScopePublic {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			id, err := a.Authenticate(r)
+			if err != nil {
+				logger.Warn("认证失败", "path", r.URL.Path, "remote_addr", r.RemoteAddr, "error", err)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="icooclaw"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if !id.Allows(scope) {
+				logger.Warn("权限不足", "path", r.URL.Path, "subject", id.Subject, "scope", scope)
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="icooclaw", error="insufficient_scope", scope=%q`, scope))
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+
+			ctx := NewContext(r.Context(), id)
+			ctx = context.WithValue(ctx, gwMiddleware.UserIDKey, id.Subject)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// scopeFor 返回路径所需的权限范围。
+func scopeFor(routes []Route, fallback, path string) string {
+	for _, route := range routes {
+		prefix := strings.TrimSuffix(route.Prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return route.Scope
+		}
+	}
+	return fallback
+}
+
+// identityKey 调用方的上下文键
+type identityKey struct{}
+
+// NewContext 返回携带通过认证的调用方的上下文。
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext 从上下文提取通过认证的调用方，未启用认证或公开路由时返回 nil。
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// HashKey 返回 API 密钥的 SHA-256 十六进制摘要，用于配置 key_sha256。
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateKey 生成随机 API 密钥。
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成 API 密钥失败: %w", err)
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateSecret 生成随机 JWT 签名密钥。
+func GenerateSecret() (string, error) {
+	b := make([]byte, MinSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成签名密钥失败: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
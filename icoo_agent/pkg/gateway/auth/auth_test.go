@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gwMiddleware "icooclaw/pkg/gateway/middleware"
+)
+
+var (
+	current  = []byte(strings.Repeat("c", MinSecretSize))
+	previous = []byte(strings.Repeat("p", MinSecretSize))
+)
+
+func newTestAuth(t *testing.T, secrets ...[]byte) *Authenticator {
+	t.Helper()
+	a, err := New(Options{
+		Keys: []APIKey{
+			{Name: "web", Key: "web-key"},
+			{Name: "ops", KeySHA256: HashKey("ops-key"), Scopes: []string{ScopeAdmin}},
+		},
+		Secrets: secrets,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return a
+}
+
+func TestAuthenticate_APIKey(t *testing.T) {
+	a := newTestAuth(t)
+	tests := []struct {
+		name    string
+		setup   func(r *http.Request)
+		subject string
+		wantErr error
+	}{
+		{"header", func(r *http.Request) { r.Header.Set("X-API-Key", "web-key") }, "web", nil},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer ops-key") }, "ops", nil},
+		{"query", func(r *http.Request) { r.URL.RawQuery = "api_key=web-key" }, "web", nil},
+		{"wrong", func(r *http.Request) { r.Header.Set("X-API-Key", "nope") }, "", ErrInvalid},
+		{"missing", func(r *http.Request) {}, "", ErrMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/chat/status", nil)
+			tt.setup(r)
+			id, err := a.Authenticate(r)
+			if err != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && id.Subject != tt.subject {
+				t.Errorf("Subject = %q, want %q", id.Subject, tt.subject)
+			}
+		})
+	}
+}
+
+func TestIssue_Rotation(t *testing.T) {
+	old := newTestAuth(t, previous)
+	token, exp, err := old.Issue("alice", []string{ScopeChat}, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if time.Until(exp) > time.Hour || time.Until(exp) < 59*time.Minute {
+		t.Errorf("exp = %v", exp)
+	}
+
+	// 轮换后新密钥签发，旧令牌在移除旧密钥前仍然有效
+	rotated := newTestAuth(t, current, previous)
+	r := httptest.NewRequest(http.MethodGet, "/ws?access_token="+token, nil)
+	id, err := rotated.Authenticate(r)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if id.Subject != "alice" || id.Method != MethodJWT || !id.Allows(ScopeChat) || id.Allows(ScopeAdmin) {
+		t.Errorf("identity = %+v", id)
+	}
+
+	// 移除旧密钥后旧令牌失效
+	if _, err := newTestAuth(t, current).Authenticate(r); err != ErrInvalid {
+		t.Errorf("Authenticate() error = %v, want %v", err, ErrInvalid)
+	}
+}
+
+func TestVerify_Rejects(t *testing.T) {
+	a := newTestAuth(t, current)
+	token, _, err := a.Issue("alice", nil, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	parts := strings.Split(token, ".")
+
+	expired := newTestAuth(t, current)
+	expired.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	other, _ := New(Options{Secrets: [][]byte{current}, Issuer: "other"})
+
+	tests := []struct {
+		name  string
+		a     *Authenticator
+		token string
+	}{
+		{"expired", expired, token},
+		{"issuer", other, token},
+		{"tampered", a, parts[0] + "." + parts[1] + "x." + parts[2]},
+		{"none alg", a, "eyJhbGciOiJub25lIn0." + parts[1] + "."},
+		{"no secrets", newTestAuth(t), token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.a.verify(tt.token); err != ErrInvalid {
+				t.Errorf("verify() error = %v, want %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Scopes(t *testing.T) {
+	a := newTestAuth(t)
+	routes := []Route{
+		{Prefix: "/api/v1/health", Scope: ScopePublic},
+		{Prefix: "/api/v1/chat", Scope: ScopeChat},
+	}
+	var user string
+	h := a.Middleware(routes, ScopeAdmin, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = gwMiddleware.GetUserID(r.Context())
+	}))
+
+	tests := []struct {
+		name string
+		path string
+		key  string
+		want int
+		user string
+	}{
+		{"public", "/api/v1/health/live", "", http.StatusOK, ""},
+		{"chat", "/api/v1/chat/status", "web-key", http.StatusOK, "web"},
+		{"admin implies chat", "/api/v1/chat", "ops-key", http.StatusOK, "ops"},
+		{"admin", "/api/v1/sessions/page", "ops-key", http.StatusOK, "ops"},
+		{"insufficient scope", "/api/v1/sessions/page", "web-key", http.StatusForbidden, ""},
+		{"prefix boundary", "/api/v1/chatty", "web-key", http.StatusForbidden, ""},
+		{"unauthenticated", "/api/v1/chat/", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user = ""
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want || user != tt.user {
+				t.Errorf("code = %d, user = %q, want %d, %q", w.Code, user, tt.want, tt.user)
+			}
+		})
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"empty", Options{}, "至少需要"},
+		{"no name", Options{Keys: []APIKey{{Key: "k"}}}, "缺少 name"},
+		{"duplicate", Options{Keys: []APIKey{{Name: "a", Key: "k"}, {Name: "a", Key: "j"}}}, "重复"},
+		{"both", Options{Keys: []APIKey{{Name: "a", Key: "k", KeySHA256: HashKey("k")}}}, "只能配置一个"},
+		{"bad hash", Options{Keys: []APIKey{{Name: "a", KeySHA256: "abc"}}}, "64 位"},
+		{"bad scope", Options{Keys: []APIKey{{Name: "a", Key: "k", Scopes: []string{"root"}}}}, "权限范围"},
+		{"short secret", Options{Secrets: [][]byte{[]byte("short")}}, "至少需要 32 字节"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"icooclaw/pkg/gateway/models"
+	"icooclaw/pkg/storage"
+)
+
+// auditLimit 默认返回的审计记录条数
+const auditLimit = 100
+
+// AuditHandler 工具调用审计查询。
+type AuditHandler struct {
+	logger  *slog.Logger
+	storage *storage.Storage
+}
+
+func NewAuditHandler(logger *slog.Logger, storage *storage.Storage) *AuditHandler {
+	return &AuditHandler{logger: logger, storage: storage}
+}
+
+// AuditListResponse 审计记录查询结果
+type AuditListResponse struct {
+	Records []*storage.ToolAudit `json:"records"`
+	Total   int64                `json:"total"` // 符合条件的记录总数
+}
+
+// List 查询工具调用审计记录，按时间倒序。
+// 查询参数: tool、session_id、user_id、status 过滤；since、until 为 RFC 3339 时间；limit (默认 100)、offset 分页。
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := storage.AuditFilter{
+		Tool:      query.Get("tool"),
+		SessionID: query.Get("session_id"),
+		UserID:    query.Get("user_id"),
+		Status:    query.Get("status"),
+		Limit:     auditLimit,
+	}
+
+	for name, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, name+" 必须为非负整数", http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	for name, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, name+" 必须为 RFC 3339 时间，如 2026-10-01T00:00:00+08:00", http.StatusBadRequest)
+				return
+			}
+			*dst = t
+		}
+	}
+
+	records, total, err := h.storage.Audit().List(filter)
+	if err != nil {
+		h.logger.With("name", "【审计】").Error("获取审计记录失败", "error", err)
+		http.Error(w, "获取审计记录失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*AuditListResponse]{
+		Code:    http.StatusOK,
+		Message: "审计记录获取成功",
+		Data:    &AuditListResponse{Records: records, Total: total},
+	})
+}
+
+// Verify 校验审计记录的哈希链，报告第一条被修改或缺失的记录。
+func (h *AuditHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	result, err := h.storage.Audit().Verify()
+	if err != nil {
+		h.logger.With("name", "【审计】").Error("校验审计记录失败", "error", err)
+		http.Error(w, "校验审计记录失败", http.StatusInternalServerError)
+		return
+	}
+	if !result.Valid {
+		h.logger.With("name", "【审计】").Warn("审计记录校验失败", "seq", result.BrokenAt, "reason", result.Reason)
+	}
+
+	models.WriteData(w, models.BaseResponse[*storage.AuditVerification]{
+		Code:    http.StatusOK,
+		Message: "审计记录校验完成",
+		Data:    result,
+	})
+}
@@ -2,17 +2,24 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"icooclaw/pkg/agent"
 	"icooclaw/pkg/agent/react"
 	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
 	"icooclaw/pkg/channels/consts"
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/gateway/auth"
+	"icooclaw/pkg/gateway/middleware"
 	"icooclaw/pkg/gateway/models"
 	"icooclaw/pkg/gateway/websocket"
 	"icooclaw/pkg/storage"
+	"icooclaw/pkg/workspace"
 )
 
 // ChatHandler handles chat-related HTTP and WebSocket requests.
@@ -22,6 +29,8 @@ type ChatHandler struct {
 	wsManager    *websocket.Manager
 	bus          *bus.MessageBus
 	agentManager *agent.AgentManager
+	dedup        *channels.Deduper
+	workspaces   *workspace.Manager
 }
 
 // NewChatHandler creates a new ChatHandler.
@@ -30,7 +39,8 @@ func NewChatHandler(
 	storage *storage.Storage,
 ) *ChatHandler {
 	return &ChatHandler{
-		logger: logger,
+		logger:  logger,
+		storage: storage,
 	}
 }
 
@@ -59,6 +69,96 @@ func (h *ChatHandler) WithStorage(s *storage.Storage) *ChatHandler {
 	return h
 }
 
+// WithDeduper 设置去重器，启用聊天接口的 Idempotency-Key 支持。
+func (h *ChatHandler) WithDeduper(d *channels.Deduper) *ChatHandler {
+	h.dedup = d
+	return h
+}
+
+// WithWorkspaces 设置工作目录管理器，启用聊天请求的 workspace 字段。
+func (h *ChatHandler) WithWorkspaces(m *workspace.Manager) *ChatHandler {
+	h.workspaces = m
+	return h
+}
+
+// selectWorkspace 按请求的 workspace 字段为会话切换工作目录，选择会保留到后续请求。
+// 返回 false 表示已写入错误响应。
+func (h *ChatHandler) selectWorkspace(w http.ResponseWriter, req *ChatRequest) bool {
+	if req.Workspace == "" {
+		return true
+	}
+	if h.workspaces == nil {
+		http.Error(w, "【网关服务】未启用工作目录切换", http.StatusBadRequest)
+		return false
+	}
+	if _, ok := h.workspaces.Get(req.Workspace); !ok {
+		http.Error(w, "【网关服务】工作目录不存在: "+req.Workspace, http.StatusBadRequest)
+		return false
+	}
+	if _, err := h.workspaces.Select(consts.WEBSOCKET, req.SessionID, req.Workspace); err != nil {
+		h.logger.With("name", "【网关服务】").Error("切换工作目录失败", "error", err)
+		http.Error(w, "【网关服务】切换工作目录失败", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// authorizeSession 检查调用方能否使用会话。已认证的非管理员只能新建会话或继续自己的会话，
+// 否则凭 session_id 就能读取和续写他人的会话。返回 false 表示已写入错误响应。
+func (h *ChatHandler) authorizeSession(w http.ResponseWriter, r *http.Request, sessionID string) bool {
+	id := auth.FromContext(r.Context())
+	if id == nil || id.Allows(auth.ScopeAdmin) || h.storage == nil {
+		return true
+	}
+
+	sess, err := h.storage.Session().GetBySessionID(consts.WEBSOCKET, sessionID)
+	if errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+		return true
+	}
+	if err != nil {
+		h.logger.With("name", "【网关服务】").Error("查询会话失败", "error", err)
+		http.Error(w, "【网关服务】查询会话失败", http.StatusInternalServerError)
+		return false
+	}
+	if sess.UserID != "" && sess.UserID != id.Subject {
+		h.logger.With("name", "【网关服务】").Warn("拒绝访问他人的会话", "session_id", sessionID, "subject", id.Subject)
+		http.Error(w, "【网关服务】无权访问该会话", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// IdempotencyHeader 聊天请求的幂等键请求头，重试时携带相同的值不会重复运行智能体
+const IdempotencyHeader = "Idempotency-Key"
+
+// claimIdempotency 登记请求的幂等键。返回的 key 非空时，处理完成后需调用 Complete 或 Release；
+// replay 非空表示该请求已处理过，应直接返回其结果；ok 为 false 表示已写入错误响应。
+func (h *ChatHandler) claimIdempotency(w http.ResponseWriter, r *http.Request, sessionID string) (key string, replay *storage.Dedup, ok bool) {
+	header := r.Header.Get(IdempotencyHeader)
+	if header == "" || h.dedup == nil {
+		return "", nil, true
+	}
+
+	key = channels.IdempotencyKey(consts.WEBSOCKET, sessionID, header)
+	record, claimed, err := h.dedup.Claim(key, consts.WEBSOCKET)
+	if err != nil {
+		// 去重失败时照常处理，宁可重复也不拒绝请求
+		h.logger.With("name", "【网关服务】").Warn("登记幂等键失败", "error", err)
+		return "", nil, true
+	}
+	if claimed {
+		return key, nil, true
+	}
+	if !record.Done {
+		http.Error(w, "【网关服务】相同幂等键的请求正在处理", http.StatusConflict)
+		return "", nil, false
+	}
+
+	h.logger.With("name", "【网关服务】").Info("重放幂等请求的结果", "session_id", sessionID)
+	w.Header().Set("Idempotent-Replayed", "true")
+	return "", record, true
+}
+
 // HandleWebSocket handles WebSocket connection upgrade.
 func (h *ChatHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	if h.wsManager == nil {
@@ -90,17 +190,46 @@ func (h *ChatHandler) HandleWebSocketWithSessionID(w http.ResponseWriter, r *htt
 // ChatRequest represents a chat request.
 type ChatRequest struct {
 	SessionID string `json:"session_id"`
+	// ChatID session_id 的别名，两者都为空时拒绝请求
+	ChatID string `json:"chat_id,omitempty"`
+	// UserID 发送者 ID，用于权限、成本限额和会话归属；已认证的请求以认证身份为准
+	UserID    string `json:"user_id,omitempty"`
 	Content   string `json:"content"`
 	Stream    bool   `json:"stream,omitempty"`
 	AgentName string `json:"agent_name,omitempty"`
+	// Workspace 为会话切换到该命名工作目录，选择会保留到后续请求
+	Workspace string `json:"workspace,omitempty"`
+	// MaxChunkLength 客户端单条消息的最大字符数，设置后响应附带分段信息
+	MaxChunkLength int `json:"max_chunk_length,omitempty"`
 }
 
 // ChatResponse represents a chat response.
 type ChatResponse struct {
-	SessionID string `json:"session_id"`
-	Content   string `json:"content"`
-	AgentName string `json:"agent_name,omitempty"`
-	Timestamp int64  `json:"timestamp"`
+	SessionID string                  `json:"session_id"`
+	Content   string                  `json:"content"`
+	AgentName string                  `json:"agent_name,omitempty"`
+	Timestamp int64                   `json:"timestamp"`
+	Chunks    []channels.MessageChunk `json:"chunks,omitempty"`
+	// Stats 本轮的迭代次数、工具耗时、Token 用量和费用，命令等未调用模型的回复和重放的结果没有
+	Stats *react.TurnStats `json:"stats,omitempty"`
+}
+
+// inbound 将聊天请求转换为总线消息。与 WebSocket 共用渠道和会话 ID，
+// 同一会话在两种接口间共享历史。发送者优先取认证身份，其次是请求的 user_id。
+func (h *ChatHandler) inbound(r *http.Request, req *ChatRequest) bus.InboundMessage {
+	sender := bus.SenderInfo{ID: "http", Name: "HTTP Client"}
+	if userID := middleware.GetUserID(r.Context()); userID != "" {
+		sender = bus.SenderInfo{ID: userID, Name: userID}
+	} else if req.UserID != "" {
+		sender = bus.SenderInfo{ID: req.UserID, Name: req.UserID}
+	}
+	return bus.InboundMessage{
+		Channel:   consts.WEBSOCKET,
+		SessionID: req.SessionID,
+		Sender:    sender,
+		Text:      req.Content,
+		Timestamp: time.Now(),
+	}
 }
 
 // HandleChat handles HTTP chat requests.
@@ -118,28 +247,48 @@ func (h *ChatHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.SessionID == "" {
+		req.SessionID = req.ChatID
+	}
 	if req.SessionID == "" {
 		h.logger.With("name", "【网关服务】").Error("会话ID不能为空")
 		http.Error(w, "【网关服务】会话ID不能为空", http.StatusBadRequest)
 		return
 	}
 
+	if !h.authorizeSession(w, r, req.SessionID) || !h.selectWorkspace(w, req) {
+		return
+	}
+
 	// Process with agent loop
 	if h.agentManager != nil {
-		inbound := bus.InboundMessage{
-			Channel:   consts.WEBSOCKET,
-			SessionID: req.SessionID,
-			Sender:    bus.SenderInfo{ID: "http", Name: "HTTP Client"},
-			Text:      req.Content,
-			Timestamp: time.Now(),
+		// 重试的请求直接返回首次处理的结果，避免重复消耗 Token
+		key, replay, ok := h.claimIdempotency(w, r, req.SessionID)
+		if !ok {
+			return
 		}
 
-		finalResponse, err := h.agentManager.RunAgent(inbound)
-
-		if err != nil {
-			h.logger.With("name", "【网关服务】").Error("处理聊天失败", "error", err)
-			http.Error(w, "【网关服务】处理聊天失败", http.StatusInternalServerError)
-			return
+		var (
+			finalResponse string
+			stats         *react.TurnStats
+		)
+		if replay != nil {
+			finalResponse = replay.Response
+		} else {
+			inbound := h.inbound(r, req)
+
+			finalResponse, stats, err = h.agentManager.RunAgentStats(inbound)
+			if err != nil {
+				if key != "" {
+					h.dedup.Release(key)
+				}
+				h.logger.With("name", "【网关服务】").Error("处理聊天失败", "error", err)
+				http.Error(w, "【网关服务】处理聊天失败", http.StatusInternalServerError)
+				return
+			}
+			if key != "" {
+				h.dedup.Complete(key, finalResponse)
+			}
 		}
 
 		models.WriteData(w, models.BaseResponse[*ChatResponse]{
@@ -149,6 +298,8 @@ func (h *ChatHandler) HandleChat(w http.ResponseWriter, r *http.Request) {
 				SessionID: req.SessionID,
 				Content:   finalResponse,
 				Timestamp: time.Now().Unix(),
+				Chunks:    channels.ClientChunks(finalResponse, req.MaxChunkLength),
+				Stats:     stats,
 			},
 		})
 		return
@@ -173,12 +324,31 @@ func (h *ChatHandler) HandleChatStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.SessionID == "" {
+		req.SessionID = req.ChatID
+	}
 	if req.SessionID == "" {
 		h.logger.With("name", "【网关服务】").Error("会话ID不能为空")
 		http.Error(w, "【网关服务】会话ID不能为空", http.StatusBadRequest)
 		return
 	}
 
+	if !h.authorizeSession(w, r, req.SessionID) || !h.selectWorkspace(w, req) {
+		return
+	}
+
+	// 幂等键在写入 SSE 响应头之前登记，正在处理时返回 409
+	var (
+		key    string
+		replay *storage.Dedup
+	)
+	if h.agentManager != nil {
+		var ok bool
+		if key, replay, ok = h.claimIdempotency(w, r, req.SessionID); !ok {
+			return
+		}
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -198,31 +368,66 @@ func (h *ChatHandler) HandleChatStream(w http.ResponseWriter, r *http.Request) {
 	flusher.Flush()
 
 	// Process with agent loop
-	if h.agentManager != nil {
-		inbound := bus.InboundMessage{
-			Channel:   consts.WEBSOCKET,
-			SessionID: req.SessionID,
-			Sender:    bus.SenderInfo{ID: "http", Name: "HTTP Client"},
-			Text:      req.Content,
-			Timestamp: time.Now(),
-		}
+	if replay != nil {
+		// 已处理过的请求一次性返回完整结果
+		h.writeSSE(w, "content", map[string]string{
+			"session_id": req.SessionID,
+			"content":    replay.Response,
+		})
+		h.writeSSE(w, "content", map[string]string{
+			"session_id": req.SessionID,
+			"type":       "end",
+		})
+		flusher.Flush()
+	} else if h.agentManager != nil {
+		var content strings.Builder
+		inbound := h.inbound(r, req)
 
 		err := h.agentManager.RunAgentStream(inbound, func(chunk react.StreamChunk) error {
+			// SSE 接口不下发迭代事件
+			if chunk.Start {
+				return nil
+			}
+
+			// 发送工具实时输出事件
+			if chunk.ToolOutput != "" {
+				h.writeSSE(w, "tool_output", map[string]string{
+					"session_id": req.SessionID,
+					"tool":       chunk.ToolName,
+					"stream":     chunk.ToolStream,
+					"content":    chunk.ToolOutput,
+				})
+				flusher.Flush()
+				return nil
+			}
+
 			// 发送流式内容事件
+			content.WriteString(chunk.Content)
 			h.writeSSE(w, "content", map[string]string{
 				"session_id": req.SessionID,
 				"content":    chunk.Content,
 			})
 
+			// 完成时在内容之后下发本轮统计
+			if chunk.Done && chunk.Stats != nil {
+				h.writeSSE(w, "stats", chunk.Stats)
+			}
+
 			flusher.Flush()
 			return nil
 		})
 
 		if err != nil {
+			if key != "" {
+				h.dedup.Release(key)
+			}
 			h.writeSSE(w, "error", map[string]string{"error": "处理消息失败: " + err.Error()})
 			flusher.Flush()
 			return
 		}
+		if key != "" {
+			h.dedup.Complete(key, content.String())
+		}
 
 		h.writeSSE(w, "content", map[string]string{
 			"session_id": req.SessionID,
@@ -370,3 +575,12 @@ func (h *ChatHandler) GetConnectionStatus(w http.ResponseWriter, r *http.Request
 		Data:    status,
 	})
 }
+
+// GetProtocol 返回 WebSocket 协议版本、能力和事件 JSON Schema
+func (h *ChatHandler) GetProtocol(w http.ResponseWriter, r *http.Request) {
+	models.WriteData(w, models.BaseResponse[*websocket.ProtocolInfo]{
+		Code:    http.StatusOK,
+		Message: "【网关服务】协议说明获取成功",
+		Data:    websocket.GetProtocolInfo(),
+	})
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"icooclaw/pkg/agent"
+	"icooclaw/pkg/bus"
+	chconsts "icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/gateway/auth"
+	"icooclaw/pkg/gateway/middleware"
+	"icooclaw/pkg/gateway/models"
+	"icooclaw/pkg/memory"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/skill"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+// echoProvider 回复最后一条用户消息，并记录收到的请求。
+type echoProvider struct {
+	mu       sync.Mutex
+	requests []providers.ChatRequest
+}
+
+func (p *echoProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	p.mu.Unlock()
+	return &providers.ChatResponse{Content: "echo: " + req.Messages[len(req.Messages)-1].Content}, nil
+}
+
+func (p *echoProvider) ChatStream(ctx context.Context, req providers.ChatRequest, callback providers.StreamCallback) error {
+	resp, err := p.Chat(ctx, req)
+	if err != nil {
+		return err
+	}
+	return callback(resp.Content, "", nil, true)
+}
+
+func (p *echoProvider) GetName() string  { return "echo" }
+func (p *echoProvider) GetModel() string { return "m" }
+func (p *echoProvider) SetModel(string)  {}
+func (p *echoProvider) last() []providers.ChatMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests[len(p.requests)-1].Messages
+}
+
+func newTestChatHandler(t *testing.T) (*ChatHandler, *storage.Storage, *echoProvider) {
+	t.Helper()
+	workspace := t.TempDir()
+	for _, name := range []string{"agents/AGENTS.md", "SOUL.md", "USER.md"} {
+		path := filepath.Join(workspace, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("# "+name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := storage.New(workspace, "", filepath.Join(t.TempDir(), "chat.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Param().Set(consts.DEFAULT_MODEL_KEY, "echo/m", "", ""); err != nil {
+		t.Fatalf("Param().Set() error = %v", err)
+	}
+
+	provider := &echoProvider{}
+	factory := providers.NewFactory(store)
+	factory.Register("echo", provider)
+
+	logger := slog.Default()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	manager := agent.NewAgentManager(ctx, logger).
+		WithStorage(store).
+		WithBus(bus.NewMessageBus(bus.DefaultConfig())).
+		WithProviderFactory(factory).
+		WithMemory(memory.NewLoader(store, 50, logger)).
+		WithSkills(skill.NewLoader(workspace, store, logger)).
+		WithTools(tools.NewRegistry())
+
+	return NewChatHandler(logger, store).WithAgentManager(manager), store, provider
+}
+
+// postChat 以 identity 的身份发送聊天请求，identity 为 nil 表示未启用认证。
+func postChat(h *ChatHandler, identity *auth.Identity, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/chat", strings.NewReader(body))
+	if identity != nil {
+		ctx := auth.NewContext(r.Context(), identity)
+		r = r.WithContext(context.WithValue(ctx, middleware.UserIDKey, identity.Subject))
+	}
+	w := httptest.NewRecorder()
+	h.HandleChat(w, r)
+	return w
+}
+
+func TestChatHandler_Inbound(t *testing.T) {
+	h := NewChatHandler(slog.Default(), nil)
+	alice := &auth.Identity{Subject: "alice", Scopes: []string{auth.ScopeChat}}
+
+	tests := []struct {
+		name     string
+		identity *auth.Identity
+		userID   string
+		want     string
+	}{
+		{"anonymous", nil, "", "http"},
+		{"user_id", nil, "bob", "bob"},
+		{"identity wins", alice, "bob", "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/chat", nil)
+			if tt.identity != nil {
+				r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, tt.identity.Subject))
+			}
+			msg := h.inbound(r, &ChatRequest{SessionID: "s1", UserID: tt.userID, Content: "hi"})
+			if msg.Sender.ID != tt.want || msg.Channel != chconsts.WEBSOCKET || msg.SessionID != "s1" {
+				t.Errorf("inbound() = %+v, want sender %q", msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatHandler_HandleChat(t *testing.T) {
+	h, store, provider := newTestChatHandler(t)
+	alice := &auth.Identity{Subject: "alice", Scopes: []string{auth.ScopeChat}}
+
+	// chat_id 是 session_id 的别名，会话归属认证身份而不是请求的 user_id
+	w := postChat(h, alice, `{"chat_id":"s1","user_id":"mallory","content":"first"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleChat() status = %d, body = %s", w.Code, w.Body)
+	}
+	var resp models.BaseResponse[*ChatResponse]
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.SessionID != "s1" || resp.Data.Content != "echo: first" {
+		t.Errorf("response = %+v", resp.Data)
+	}
+	sess, err := store.Session().GetBySessionID(chconsts.WEBSOCKET, "s1")
+	if err != nil || sess.UserID != "alice" {
+		t.Fatalf("session = %+v, %v", sess, err)
+	}
+
+	// 后续请求带上之前的历史
+	if w := postChat(h, alice, `{"session_id":"s1","content":"second"}`); w.Code != http.StatusOK {
+		t.Fatalf("HandleChat() status = %d, body = %s", w.Code, w.Body)
+	}
+	var history []string
+	for _, m := range provider.last() {
+		history = append(history, m.Content)
+	}
+	if got := strings.Join(history[1:], "|"); got != "first|echo: first|second" {
+		t.Errorf("history = %q", got)
+	}
+	saved, err := store.Message().Get(consts.GetSessionKey(chconsts.WEBSOCKET, "s1"), 0)
+	if err != nil || len(saved) != 4 {
+		t.Errorf("saved messages = %d, %v", len(saved), err)
+	}
+
+	// 其他调用方不能读取或续写该会话，管理员可以
+	mallory := &auth.Identity{Subject: "mallory", Scopes: []string{auth.ScopeChat}}
+	for _, body := range []string{`{"session_id":"s1","content":"leak"}`, `{"chat_id":"s1","content":"leak"}`} {
+		if w := postChat(h, mallory, body); w.Code != http.StatusForbidden {
+			t.Errorf("HandleChat(%s) status = %d, want %d", body, w.Code, http.StatusForbidden)
+		}
+	}
+	ops := &auth.Identity{Subject: "ops", Scopes: []string{auth.ScopeAdmin}}
+	if w := postChat(h, ops, `{"session_id":"s1","content":"audit"}`); w.Code != http.StatusOK {
+		t.Errorf("admin status = %d, body = %s", w.Code, w.Body)
+	}
+
+	// 新会话归属首个调用方
+	if w := postChat(h, mallory, `{"session_id":"s2","content":"mine"}`); w.Code != http.StatusOK {
+		t.Errorf("new session status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := postChat(h, nil, `{"content":"no session"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("missing session status = %d", w.Code)
+	}
+}
@@ -1,27 +1,171 @@
 package handlers
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 
 	"icooclaw/pkg/gateway/models"
 )
 
+// readinessTimeout 单项就绪检查的超时
+const readinessTimeout = 3 * time.Second
+
+// 依赖检查状态
+const (
+	CheckOK       = "ok"
+	CheckFail     = "fail"
+	CheckDegraded = "degraded" // 非关键依赖异常，不影响就绪
+)
+
+// ReadinessCheck checks one dependency. It returns details shown in the
+// readiness response and an error when the dependency is unavailable.
+type ReadinessCheck func(ctx context.Context) (any, error)
+
+// readinessEntry 已注册的就绪检查
+type readinessEntry struct {
+	name     string
+	critical bool
+	check    ReadinessCheck
+}
+
+// DependencyStatus 单个依赖的检查结果
+type DependencyStatus struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	Detail    any    `json:"detail,omitempty"`
+}
+
+// ReadinessResult 就绪检查结果
+type ReadinessResult struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
 type CommonHandler struct {
-	logger *slog.Logger
+	logger  *slog.Logger
+	started time.Time
+	checks  []readinessEntry
+	mu      sync.RWMutex
 }
 
 func NewCommonHandler(logger *slog.Logger) *CommonHandler {
-	return &CommonHandler{logger: logger}
+	return &CommonHandler{logger: logger, started: time.Now()}
 }
 
-// HealthCheck 健康检查
+// WithCheck registers a readiness check. When a critical check fails the
+// readiness probe answers 503; a failing non-critical check is reported as
+// degraded only. Registering a name again replaces the previous check.
+func (h *CommonHandler) WithCheck(name string, critical bool, check ReadinessCheck) *CommonHandler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, c := range h.checks {
+		if c.name == name {
+			h.checks[i] = readinessEntry{name: name, critical: critical, check: check}
+			return h
+		}
+	}
+	h.checks = append(h.checks, readinessEntry{name: name, critical: critical, check: check})
+	return h
+}
+
+// HealthCheck 健康检查，与存活探针相同，保留以兼容旧的探测地址
 func (h *CommonHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	models.WriteData(w, models.BaseResponse[map[string]string]{
+	h.Liveness(w, r)
+}
+
+// Liveness 存活探针，只要进程能处理请求即返回 200，不检查外部依赖
+func (h *CommonHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	models.WriteData(w, models.BaseResponse[map[string]any]{
 		Code:    http.StatusOK,
 		Message: "OK",
-		Data: map[string]string{
-			"status": "healthy",
+		Data: map[string]any{
+			"status":         "healthy",
+			"uptime_seconds": int64(time.Since(h.started).Seconds()),
 		},
 	})
 }
+
+// Readiness 就绪探针，检查各依赖，关键依赖不可用时返回 503
+func (h *CommonHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	result := h.Ready(r.Context())
+
+	code, message := http.StatusOK, "OK"
+	if result.Status != "ready" {
+		code, message = http.StatusServiceUnavailable, "服务未就绪"
+	}
+	models.WriteData(w, models.BaseResponse[*ReadinessResult]{
+		Code:    code,
+		Message: message,
+		Data:    result,
+	})
+}
+
+// Ready runs all readiness checks concurrently, each bounded by its own
+// timeout, and reports "ready" unless a critical check failed.
+func (h *CommonHandler) Ready(ctx context.Context) *ReadinessResult {
+	h.mu.RLock()
+	checks := append([]readinessEntry(nil), h.checks...)
+	h.mu.RUnlock()
+
+	statuses := make([]DependencyStatus, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c readinessEntry) {
+			defer wg.Done()
+			statuses[i] = runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	result := &ReadinessResult{Status: "ready", Dependencies: make(map[string]DependencyStatus, len(checks))}
+	for i, c := range checks {
+		result.Dependencies[c.name] = statuses[i]
+		if statuses[i].Status == CheckFail {
+			result.Status = "not_ready"
+			h.logger.With("name", "【健康检查】").Warn("依赖不可用", "dependency", c.name, "error", statuses[i].Error)
+		}
+	}
+	return result
+}
+
+// runCheck 执行单项检查，超时视为失败
+func runCheck(ctx context.Context, c readinessEntry) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	type outcome struct {
+		detail any
+		err    error
+	}
+	start := time.Now()
+	done := make(chan outcome, 1)
+	go func() {
+		detail, err := c.check(ctx)
+		done <- outcome{detail, err}
+	}()
+
+	status := DependencyStatus{Status: CheckOK, Critical: c.critical}
+	select {
+	case o := <-done:
+		status.Detail = o.detail
+		if o.err != nil {
+			status.Error = o.err.Error()
+		}
+	case <-ctx.Done():
+		status.Error = "检查超时"
+	}
+	status.LatencyMs = time.Since(start).Milliseconds()
+	if status.Error != "" {
+		status.Status = CheckFail
+		if !c.critical {
+			status.Status = CheckDegraded
+		}
+	}
+	return status
+}
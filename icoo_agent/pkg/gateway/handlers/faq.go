@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/faq"
+	"icooclaw/pkg/gateway/models"
+	"icooclaw/pkg/storage"
+)
+
+type FAQHandler struct {
+	logger  *slog.Logger
+	storage *storage.Storage
+	matcher *faq.Matcher
+}
+
+func NewFAQHandler(logger *slog.Logger, storage *storage.Storage) *FAQHandler {
+	return &FAQHandler{logger: logger, storage: storage}
+}
+
+// WithMatcher 设置匹配器，用于测试问题命中的常见问题。
+func (h *FAQHandler) WithMatcher(m *faq.Matcher) *FAQHandler {
+	h.matcher = m
+	return h
+}
+
+// PromoteFAQRequest 将智能体回复提升为常见问题的请求
+type PromoteFAQRequest struct {
+	MessageID string `json:"message_id"`         // 智能体回复的消息ID
+	Question  string `json:"question,omitempty"` // 问题，为空时使用回复之前的用户消息
+}
+
+// MatchFAQRequest 测试常见问题匹配的请求
+type MatchFAQRequest struct {
+	Text    string `json:"text"`              // 用户消息
+	Channel string `json:"channel,omitempty"` // 渠道
+}
+
+func (h *FAQHandler) Page(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*storage.QueryFAQ](r)
+	if err != nil {
+		h.logger.Error("绑定分页请求失败", "error", err)
+		http.Error(w, "绑定分页请求失败", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.storage.FAQ().Page(req)
+	if err != nil {
+		h.logger.Error("获取常见问题列表失败", "error", err)
+		http.Error(w, "获取常见问题列表失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*storage.ResQueryFAQ]{
+		Code:    http.StatusOK,
+		Message: "常见问题列表获取成功",
+		Data:    res,
+	})
+}
+
+func (h *FAQHandler) Create(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*storage.FAQ](r)
+	if err != nil {
+		h.logger.Error("绑定创建常见问题请求失败", "error", err)
+		http.Error(w, "绑定创建常见问题请求失败", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Question) == "" || strings.TrimSpace(req.Answer) == "" {
+		http.Error(w, "问题和回复不能为空", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.FAQ().Create(req); err != nil {
+		h.logger.Error("创建常见问题失败", "error", err)
+		http.Error(w, "创建常见问题失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*storage.FAQ]{
+		Code:    http.StatusOK,
+		Message: "常见问题创建成功",
+		Data:    req,
+	})
+}
+
+func (h *FAQHandler) Update(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*storage.FAQ](r)
+	if err != nil {
+		h.logger.Error("绑定更新常见问题请求失败", "error", err)
+		http.Error(w, "绑定更新常见问题请求失败", http.StatusBadRequest)
+		return
+	}
+	if req.ID == "" {
+		http.Error(w, "常见问题ID不能为空", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.FAQ().Update(req); err != nil {
+		h.logger.Error("更新常见问题失败", "error", err)
+		http.Error(w, "更新常见问题失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*storage.FAQ]{
+		Code:    http.StatusOK,
+		Message: "常见问题更新成功",
+		Data:    req,
+	})
+}
+
+func (h *FAQHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := models.BindID(r)
+	if err != nil {
+		h.logger.Error("绑定删除常见问题请求失败", "error", err)
+		http.Error(w, "绑定删除常见问题请求失败", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.FAQ().Delete(id); err != nil {
+		h.logger.Error("删除常见问题失败", "error", err)
+		http.Error(w, "删除常见问题失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[any]{
+		Code:    http.StatusOK,
+		Message: "常见问题删除成功",
+	})
+}
+
+func (h *FAQHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := models.BindID(r)
+	if err != nil {
+		h.logger.Error("绑定获取常见问题请求失败", "error", err)
+		http.Error(w, "绑定获取常见问题请求失败", http.StatusBadRequest)
+		return
+	}
+
+	f, err := h.storage.FAQ().GetByID(id)
+	if errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+		http.Error(w, "常见问题不存在", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("获取常见问题失败", "error", err)
+		http.Error(w, "获取常见问题失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*storage.FAQ]{
+		Code:    http.StatusOK,
+		Message: "常见问题获取成功",
+		Data:    f,
+	})
+}
+
+// Promote 将智能体的回复提升为常见问题
+func (h *FAQHandler) Promote(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*PromoteFAQRequest](r)
+	if err != nil {
+		h.logger.Error("绑定提升常见问题请求失败", "error", err)
+		http.Error(w, "绑定提升常见问题请求失败", http.StatusBadRequest)
+		return
+	}
+	if req.MessageID == "" {
+		http.Error(w, "消息ID不能为空", http.StatusBadRequest)
+		return
+	}
+
+	f, err := faq.Promote(h.storage, req.MessageID, req.Question)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*storage.FAQ]{
+		Code:    http.StatusOK,
+		Message: "已添加常见问题",
+		Data:    f,
+	})
+}
+
+// Match 测试消息命中的常见问题，不计入命中次数
+func (h *FAQHandler) Match(w http.ResponseWriter, r *http.Request) {
+	if h.matcher == nil {
+		http.Error(w, "常见问题未启用", http.StatusServiceUnavailable)
+		return
+	}
+
+	req, err := models.Bind[*MatchFAQRequest](r)
+	if err != nil {
+		h.logger.Error("绑定匹配常见问题请求失败", "error", err)
+		http.Error(w, "绑定匹配常见问题请求失败", http.StatusBadRequest)
+		return
+	}
+
+	match, err := h.matcher.Match(req.Text, req.Channel)
+	if err != nil {
+		h.logger.Error("匹配常见问题失败", "error", err)
+		http.Error(w, "匹配常见问题失败", http.StatusInternalServerError)
+		return
+	}
+
+	message := "没有命中的常见问题"
+	if match != nil {
+		message = "命中常见问题"
+	}
+	models.WriteData(w, models.BaseResponse[*faq.Match]{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    match,
+	})
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/gateway/models"
+	"icooclaw/pkg/jobs"
+	"icooclaw/pkg/storage"
+)
+
+type JobHandler struct {
+	logger  *slog.Logger
+	storage *storage.Storage
+	runner  *jobs.Runner
+}
+
+func NewJobHandler(logger *slog.Logger, storage *storage.Storage) *JobHandler {
+	return &JobHandler{logger: logger, storage: storage}
+}
+
+// WithRunner 设置作业执行器，未设置时只能查询作业
+func (h *JobHandler) WithRunner(r *jobs.Runner) *JobHandler {
+	h.runner = r
+	return h
+}
+
+// CreateJobRequest 提交作业请求
+type CreateJobRequest struct {
+	Type   string          `json:"type"`             // 作业类型
+	Params json.RawMessage `json:"params,omitempty"` // 作业参数
+}
+
+// JobResponse 作业及完成百分比
+type JobResponse struct {
+	*storage.Job
+	Progress float64 `json:"progress"` // 完成百分比
+}
+
+// ResQueryJobResponse 作业分页结果
+type ResQueryJobResponse struct {
+	Page    storage.Page   `json:"page"`
+	Records []*JobResponse `json:"records"`
+}
+
+func newJobResponse(j *storage.Job) *JobResponse {
+	return &JobResponse{Job: j, Progress: j.Progress()}
+}
+
+// Page 分页查询作业
+func (h *JobHandler) Page(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*storage.QueryJob](r)
+	if err != nil {
+		h.logger.Error("绑定分页请求失败", "error", err)
+		http.Error(w, "绑定分页请求失败", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.storage.Job().Page(req)
+	if err != nil {
+		h.logger.With("name", "【作业】").Error("获取作业列表失败", "error", err)
+		http.Error(w, "获取作业列表失败", http.StatusInternalServerError)
+		return
+	}
+
+	records := make([]*JobResponse, 0, len(res.Records))
+	for _, j := range res.Records {
+		records = append(records, newJobResponse(j))
+	}
+	models.WriteData(w, models.BaseResponse[*ResQueryJobResponse]{
+		Code:    http.StatusOK,
+		Message: "作业列表获取成功",
+		Data:    &ResQueryJobResponse{Page: res.Page, Records: records},
+	})
+}
+
+// GetByID 获取作业状态和进度
+func (h *JobHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := models.BindID(r)
+	if err != nil {
+		h.logger.Error("绑定获取作业请求失败", "error", err)
+		http.Error(w, "绑定获取作业请求失败", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.storage.Job().Get(id)
+	if errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+		http.Error(w, "作业不存在", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.With("name", "【作业】").Error("获取作业失败", "error", err)
+		http.Error(w, "获取作业失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*JobResponse]{
+		Code:    http.StatusOK,
+		Message: "作业获取成功",
+		Data:    newJobResponse(job),
+	})
+}
+
+// Create 提交作业
+func (h *JobHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if h.runner == nil {
+		http.Error(w, "作业执行器未启用", http.StatusServiceUnavailable)
+		return
+	}
+
+	req, err := models.Bind[*CreateJobRequest](r)
+	if err != nil {
+		h.logger.Error("绑定提交作业请求失败", "error", err)
+		http.Error(w, "绑定提交作业请求失败", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.runner.Submit(req.Type, req.Params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*JobResponse]{
+		Code:    http.StatusOK,
+		Message: "作业已提交",
+		Data:    newJobResponse(job),
+	})
+}
+
+// Pause 暂停作业
+func (h *JobHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, "作业已暂停", func(id string) (*storage.Job, error) { return h.runner.Pause(id) })
+}
+
+// Resume 恢复暂停或失败的作业
+func (h *JobHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, "作业已恢复", func(id string) (*storage.Job, error) { return h.runner.Resume(id) })
+}
+
+// Cancel 取消作业
+func (h *JobHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, "作业已取消", func(id string) (*storage.Job, error) { return h.runner.Cancel(id) })
+}
+
+// transition 修改作业状态
+func (h *JobHandler) transition(w http.ResponseWriter, r *http.Request, message string, fn func(id string) (*storage.Job, error)) {
+	if h.runner == nil {
+		http.Error(w, "作业执行器未启用", http.StatusServiceUnavailable)
+		return
+	}
+
+	id, err := models.BindID(r)
+	if err != nil {
+		h.logger.Error("绑定作业请求失败", "error", err)
+		http.Error(w, "绑定作业请求失败", http.StatusBadRequest)
+		return
+	}
+
+	job, err := fn(id)
+	if errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+		http.Error(w, "作业不存在", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*JobResponse]{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    newJobResponse(job),
+	})
+}
+
+// Types 返回可提交的作业类型
+func (h *JobHandler) Types(w http.ResponseWriter, r *http.Request) {
+	types := []string{}
+	if h.runner != nil {
+		types = h.runner.Types()
+	}
+	models.WriteData(w, models.BaseResponse[[]string]{
+		Code:    http.StatusOK,
+		Message: "作业类型获取成功",
+		Data:    types,
+	})
+}
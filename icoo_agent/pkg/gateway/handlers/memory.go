@@ -5,16 +5,24 @@ import (
 	"net/http"
 
 	"icooclaw/pkg/gateway/models"
+	"icooclaw/pkg/memory"
 	"icooclaw/pkg/storage"
 )
 
 type MemoryHandler struct {
 	logger  *slog.Logger
 	storage *storage.Storage
+	score   memory.ScoreConfig
 }
 
 func NewMemoryHandler(logger *slog.Logger, storage *storage.Storage) *MemoryHandler {
-	return &MemoryHandler{logger: logger, storage: storage}
+	return &MemoryHandler{logger: logger, storage: storage, score: memory.DefaultScoreConfig()}
+}
+
+// WithScoreConfig 设置搜索排序使用的记忆评分参数。
+func (h *MemoryHandler) WithScoreConfig(cfg memory.ScoreConfig) *MemoryHandler {
+	h.score = cfg
+	return h
 }
 
 func (h *MemoryHandler) Page(w http.ResponseWriter, r *http.Request) {
@@ -128,7 +136,9 @@ func (h *MemoryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 func (h *MemoryHandler) Search(w http.ResponseWriter, r *http.Request) {
 	req, err := models.Bind[struct {
-		Query string `json:"query"`
+		SessionID string `json:"session_id"`
+		Query     string `json:"query"`
+		Limit     int    `json:"limit"`
 	}](r)
 	if err != nil {
 		h.logger.Error("绑定搜索记忆请求失败", "error", err)
@@ -136,13 +146,21 @@ func (h *MemoryHandler) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	memories, err := h.storage.Memory().Page(&storage.QueryMemory{Query: req.Query})
+	// 按 评分×相关度 排序，命中的记忆记录一次访问
+	ranked, err := h.score.Recall(h.storage, storage.MemoryFilter{SessionID: req.SessionID}, req.Query, 0, req.Limit)
 	if err != nil {
 		h.logger.Error("搜索记忆失败", "error", err)
 		http.Error(w, "搜索记忆失败", http.StatusInternalServerError)
 		return
 	}
 
+	memories := &storage.ResQueryMemory{Page: storage.Page{Total: int64(len(ranked))}}
+	for _, r := range ranked {
+		m := *r.Memory
+		m.Score = r.Score * r.Relevance
+		memories.Records = append(memories.Records, m)
+	}
+
 	models.WriteData(w, models.BaseResponse[*storage.ResQueryMemory]{
 		Code:    http.StatusOK,
 		Message: "记忆搜索成功",
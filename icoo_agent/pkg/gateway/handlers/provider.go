@@ -5,18 +5,26 @@ import (
 	"net/http"
 
 	"icooclaw/pkg/gateway/models"
+	"icooclaw/pkg/providers"
 	"icooclaw/pkg/storage"
 )
 
 type ProviderHandler struct {
 	logger  *slog.Logger
 	storage *storage.Storage
+	factory *providers.Factory
 }
 
 func NewProviderHandler(logger *slog.Logger, storage *storage.Storage) *ProviderHandler {
 	return &ProviderHandler{logger: logger, storage: storage}
 }
 
+// WithFactory 设置提供商工厂，用于查询健康状态。
+func (h *ProviderHandler) WithFactory(f *providers.Factory) *ProviderHandler {
+	h.factory = f
+	return h
+}
+
 func (h *ProviderHandler) Page(w http.ResponseWriter, r *http.Request) {
 	req, err := models.Bind[*storage.QueryProvider](r)
 	if err != nil {
@@ -177,3 +185,72 @@ func (h *ProviderHandler) GetEnabled(w http.ResponseWriter, r *http.Request) {
 		Data:    configs,
 	})
 }
+
+// Presets 返回内置提供商预设，前端创建提供商时据此填充默认地址和模型。
+func (h *ProviderHandler) Presets(w http.ResponseWriter, r *http.Request) {
+	models.WriteData(w, models.BaseResponse[[]providers.ProviderPreset]{
+		Code:    http.StatusOK,
+		Message: "供应商预设获取成功",
+		Data:    providers.Presets(),
+	})
+}
+
+// Health 返回已加载提供商的健康与熔断状态。
+func (h *ProviderHandler) Health(w http.ResponseWriter, r *http.Request) {
+	health := []providers.ProviderHealth{}
+	if h.factory != nil {
+		health = h.factory.Health()
+	}
+
+	models.WriteData(w, models.BaseResponse[[]providers.ProviderHealth]{
+		Code:    http.StatusOK,
+		Message: "供应商健康状态获取成功",
+		Data:    health,
+	})
+}
+
+// Keys 返回使用多个 API 密钥的提供商的各密钥状态。
+func (h *ProviderHandler) Keys(w http.ResponseWriter, r *http.Request) {
+	keys := []providers.ProviderKeys{}
+	if h.factory != nil {
+		keys = h.factory.Keys()
+	}
+
+	models.WriteData(w, models.BaseResponse[[]providers.ProviderKeys]{
+		Code:    http.StatusOK,
+		Message: "供应商密钥状态获取成功",
+		Data:    keys,
+	})
+}
+
+// EnableKey 恢复被自动停用的 API 密钥。
+func (h *ProviderHandler) EnableKey(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[struct {
+		Provider string `json:"provider"`
+		Key      string `json:"key"`
+	}](r)
+	if err != nil {
+		h.logger.Error("绑定恢复密钥请求失败", "error", err)
+		http.Error(w, "绑定恢复密钥请求失败", http.StatusBadRequest)
+		return
+	}
+
+	if h.factory == nil || !h.factory.EnableKey(req.Provider, req.Key) {
+		http.Error(w, "密钥不存在", http.StatusNotFound)
+		return
+	}
+	h.logger.Info("已恢复供应商密钥", "provider", req.Provider, "key", req.Key)
+
+	models.WriteData(w, models.BaseResponse[any]{
+		Code:    http.StatusOK,
+		Message: "供应商密钥已恢复",
+	})
+}
+
+// Metrics 以 Prometheus 文本格式输出提供商健康指标。
+func (h *ProviderHandler) Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if h.factory != nil {
+		h.factory.WriteMetrics(w)
+	}
+}
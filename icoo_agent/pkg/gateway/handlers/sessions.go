@@ -1,31 +1,55 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+
 	"icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/ephemeral"
+	icooclawErrors "icooclaw/pkg/errors"
 	"icooclaw/pkg/gateway/models"
 	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/vars"
 )
 
 type SessionHandler struct {
-	logger  *slog.Logger
-	storage *storage.Storage
+	logger    *slog.Logger
+	storage   *storage.Storage
+	registry  *tools.Registry
+	ephemeral *ephemeral.Registry
 }
 
 func NewSessionHandler(logger *slog.Logger, storage *storage.Storage) *SessionHandler {
 	return &SessionHandler{logger: logger, storage: storage}
 }
 
+// WithRegistry 设置工具注册表，用于计算会话实际可用的工具。
+func (h *SessionHandler) WithRegistry(r *tools.Registry) *SessionHandler {
+	h.registry = r
+	return h
+}
+
+// WithEphemeral 设置无痕会话注册表，创建会话时可通过 ephemeral 字段开启无痕模式。
+func (h *SessionHandler) WithEphemeral(r *ephemeral.Registry) *SessionHandler {
+	h.ephemeral = r
+	return h
+}
+
 // CreateSessionRequest 创建会话请求
 type CreateSessionRequest struct {
 	Channel   string            `json:"channel,omitempty"`    // 渠道 (默认为 "websocket")
 	UserID    string            `json:"user_id,omitempty"`    // 用户ID
 	SessionID string            `json:"session_id,omitempty"` // 会话ID (可选，不提供则自动生成)
 	Metadata  map[string]string `json:"metadata,omitempty"`   // 元数据 (JSON格式)
+	Ephemeral bool              `json:"ephemeral,omitempty"`  // 无痕会话，只保存在内存中，关闭后清除
 }
 
 // CreateSessionResponse 创建会话响应
@@ -33,6 +57,7 @@ type CreateSessionResponse struct {
 	SessionID string `json:"session_id"`
 	Channel   string `json:"channel"`
 	UserID    string `json:"user_id"`
+	Ephemeral bool   `json:"ephemeral,omitempty"`
 }
 
 // Create 创建新会话 (供前端调用)
@@ -56,6 +81,12 @@ func (h *SessionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		req.SessionID = fmt.Sprintf("session-%d", time.Now().UnixNano())
 	}
 
+	// 无痕会话只登记在内存中，不写入数据库
+	if req.Ephemeral {
+		h.createEphemeral(w, req)
+		return
+	}
+
 	// 创建会话
 	session := storage.Session{
 		Channel: req.Channel,
@@ -87,6 +118,64 @@ func (h *SessionHandler) Create(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// createEphemeral 开启无痕会话。
+func (h *SessionHandler) createEphemeral(w http.ResponseWriter, req *CreateSessionRequest) {
+	if h.ephemeral == nil {
+		http.Error(w, "无痕会话未启用", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.ephemeral.Open(req.Channel, req.SessionID); err != nil {
+		h.logger.With("name", "【会话】").Error("创建无痕会话失败", "error", err)
+		http.Error(w, "创建无痕会话失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*CreateSessionResponse]{
+		Code:    http.StatusOK,
+		Message: "无痕会话创建成功",
+		Data: &CreateSessionResponse{
+			SessionID: req.SessionID,
+			Channel:   req.Channel,
+			UserID:    req.UserID,
+			Ephemeral: true,
+		},
+	})
+}
+
+// CloseSessionRequest 关闭无痕会话请求
+type CloseSessionRequest struct {
+	Channel   string `json:"channel,omitempty"` // 渠道 (默认为 "websocket")
+	SessionID string `json:"session_id"`        // 会话ID
+}
+
+// Close 关闭无痕会话，清除内存中的消息和临时工作目录
+func (h *SessionHandler) Close(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*CloseSessionRequest](r)
+	if err != nil {
+		h.logger.Error("绑定关闭会话请求失败", "error", err)
+		http.Error(w, "绑定关闭会话请求失败", http.StatusBadRequest)
+		return
+	}
+
+	if req.SessionID == "" {
+		http.Error(w, "会话ID不能为空", http.StatusBadRequest)
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = consts.WEBSOCKET
+	}
+
+	if !h.ephemeral.Close(req.Channel, req.SessionID) {
+		http.Error(w, "无痕会话不存在", http.StatusNotFound)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[any]{
+		Code:    http.StatusOK,
+		Message: "无痕会话已关闭",
+	})
+}
+
 func (h *SessionHandler) Page(w http.ResponseWriter, r *http.Request) {
 	req, err := models.Bind[*storage.QuerySession](r)
 	if err != nil {
@@ -179,3 +268,314 @@ func (h *SessionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		Data:    session,
 	})
 }
+
+// ResetSessionRequest 重置会话请求
+type ResetSessionRequest struct {
+	Channel   string `json:"channel,omitempty"` // 渠道 (默认为 "websocket")
+	SessionID string `json:"session_id"`        // 会话ID
+	storage.ResetOptions
+}
+
+// Reset 归档当前会话并开始新的上下文
+func (h *SessionHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*ResetSessionRequest](r)
+	if err != nil {
+		h.logger.Error("绑定重置会话请求失败", "error", err)
+		http.Error(w, "绑定重置会话请求失败", http.StatusBadRequest)
+		return
+	}
+
+	if req.SessionID == "" {
+		http.Error(w, "会话ID不能为空", http.StatusBadRequest)
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = consts.WEBSOCKET
+	}
+
+	result, err := h.storage.ResetSession(req.Channel, req.SessionID, req.ResetOptions)
+	if err != nil {
+		h.logger.With("name", "【会话】").Error("重置会话失败", "error", err)
+		http.Error(w, "重置会话失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*storage.ResetResult]{
+		Code:    http.StatusOK,
+		Message: "会话重置成功",
+		Data:    result,
+	})
+}
+
+// Merge 合并同一逻辑对话的重复会话，dry_run 时只返回合并后的时间线预览
+func (h *SessionHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*storage.MergeOptions](r)
+	if err != nil {
+		h.logger.Error("绑定合并会话请求失败", "error", err)
+		http.Error(w, "绑定合并会话请求失败", http.StatusBadRequest)
+		return
+	}
+
+	if req.Target == "" || len(req.Sources) == 0 {
+		http.Error(w, "target 和 sources 不能为空", http.StatusBadRequest)
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = consts.WEBSOCKET
+	}
+
+	result, err := h.storage.MergeSessions(*req)
+	if err != nil {
+		h.logger.With("name", "【会话】").Error("合并会话失败", "error", err)
+		http.Error(w, fmt.Sprintf("合并会话失败: %s", err), http.StatusBadRequest)
+		return
+	}
+	message := "会话合并预览（未修改数据）"
+	if !req.DryRun {
+		message = "会话合并成功"
+		h.logger.With("name", "【会话】").Info("会话已合并", "channel", req.Channel, "target", req.Target,
+			"sources", result.Sources, "messages", result.Messages)
+	}
+
+	models.WriteData(w, models.BaseResponse[*storage.MergeResult]{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    result,
+	})
+}
+
+// SessionToolsRequest 会话工具策略请求
+type SessionToolsRequest struct {
+	Channel   string `json:"channel,omitempty"` // 渠道 (默认为 "websocket")
+	SessionID string `json:"session_id"`        // 会话ID
+	tools.Policy
+}
+
+// SessionToolsResponse 会话工具策略响应
+type SessionToolsResponse struct {
+	Policy    tools.Policy `json:"policy"`    // 会话工具策略
+	Available []string     `json:"available"` // 策略生效后可用的工具
+}
+
+// GetTools 获取会话工具策略及实际可用的工具
+func (h *SessionHandler) GetTools(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*SessionToolsRequest](r)
+	if err != nil {
+		h.logger.Error("绑定会话工具请求失败", "error", err)
+		http.Error(w, "绑定会话工具请求失败", http.StatusBadRequest)
+		return
+	}
+	if req.SessionID == "" {
+		http.Error(w, "会话ID不能为空", http.StatusBadRequest)
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = consts.WEBSOCKET
+	}
+
+	var policy tools.Policy
+	if _, err := h.storage.Session().GetMetadata(req.Channel, req.SessionID, tools.PolicyMetadataKey, &policy); err != nil {
+		h.logger.With("name", "【会话】").Error("获取会话工具策略失败", "error", err)
+		http.Error(w, "获取会话工具策略失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*SessionToolsResponse]{
+		Code:    http.StatusOK,
+		Message: "会话工具策略获取成功",
+		Data:    h.toolsResponse(policy),
+	})
+}
+
+// SetTools 设置会话工具策略，allow/deny/enable 均为空时清除策略
+func (h *SessionHandler) SetTools(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*SessionToolsRequest](r)
+	if err != nil {
+		h.logger.Error("绑定会话工具请求失败", "error", err)
+		http.Error(w, "绑定会话工具请求失败", http.StatusBadRequest)
+		return
+	}
+	if req.SessionID == "" {
+		http.Error(w, "会话ID不能为空", http.StatusBadRequest)
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = consts.WEBSOCKET
+	}
+
+	var value any = req.Policy
+	if req.Policy.IsZero() {
+		value = nil
+	}
+	if err := h.storage.Session().SetMetadata(req.Channel, req.SessionID, tools.PolicyMetadataKey, value); err != nil {
+		h.logger.With("name", "【会话】").Error("设置会话工具策略失败", "error", err)
+		http.Error(w, "设置会话工具策略失败", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.With("name", "【会话】").Info("会话工具策略已更新",
+		"session_id", req.SessionID,
+		"allow", req.Allow,
+		"deny", req.Deny,
+		"enable", req.Enable)
+
+	models.WriteData(w, models.BaseResponse[*SessionToolsResponse]{
+		Code:    http.StatusOK,
+		Message: "会话工具策略设置成功",
+		Data:    h.toolsResponse(req.Policy),
+	})
+}
+
+// toolsResponse 构建会话工具策略响应。
+func (h *SessionHandler) toolsResponse(policy tools.Policy) *SessionToolsResponse {
+	resp := &SessionToolsResponse{Policy: policy, Available: []string{}}
+	if h.registry != nil {
+		for _, t := range h.registry.ListFor(policy) {
+			resp.Available = append(resp.Available, t.Name())
+		}
+	}
+	return resp
+}
+
+// SessionVarsRequest 会话变量请求
+type SessionVarsRequest struct {
+	Channel   string            `json:"channel,omitempty"` // 渠道 (默认为 "websocket")
+	SessionID string            `json:"session_id"`        // 会话ID
+	Vars      map[string]string `json:"vars,omitempty"`    // 设置的变量，与已有变量合并
+	Unset     []string          `json:"unset,omitempty"`   // 删除的变量
+}
+
+// GetVars 获取会话变量
+func (h *SessionHandler) GetVars(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*SessionVarsRequest](r)
+	if err != nil {
+		h.logger.Error("绑定会话变量请求失败", "error", err)
+		http.Error(w, "绑定会话变量请求失败", http.StatusBadRequest)
+		return
+	}
+	if req.SessionID == "" {
+		http.Error(w, "会话ID不能为空", http.StatusBadRequest)
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = consts.WEBSOCKET
+	}
+
+	v, err := vars.NewStore(h.storage.Session()).Load(req.Channel, req.SessionID)
+	if err != nil {
+		h.logger.With("name", "【会话】").Error("获取会话变量失败", "error", err)
+		http.Error(w, "获取会话变量失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[tools.Vars]{
+		Code:    http.StatusOK,
+		Message: "会话变量获取成功",
+		Data:    v,
+	})
+}
+
+// SetVars 设置或删除会话变量，返回更新后的全部变量
+func (h *SessionHandler) SetVars(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*SessionVarsRequest](r)
+	if err != nil {
+		h.logger.Error("绑定会话变量请求失败", "error", err)
+		http.Error(w, "绑定会话变量请求失败", http.StatusBadRequest)
+		return
+	}
+	if req.SessionID == "" {
+		http.Error(w, "会话ID不能为空", http.StatusBadRequest)
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = consts.WEBSOCKET
+	}
+
+	// 先校验全部变量名，避免部分写入
+	for key := range req.Vars {
+		if err := tools.ValidVarName(key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	store := vars.NewStore(h.storage.Session())
+	for key, value := range req.Vars {
+		if err := store.Set(req.Channel, req.SessionID, key, value); err != nil {
+			http.Error(w, fmt.Sprintf("设置会话变量失败: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+	for _, key := range req.Unset {
+		if _, err := store.Delete(req.Channel, req.SessionID, key); err != nil {
+			h.logger.With("name", "【会话】").Error("删除会话变量失败", "error", err)
+			http.Error(w, "删除会话变量失败", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	v, err := store.Load(req.Channel, req.SessionID)
+	if err != nil {
+		h.logger.With("name", "【会话】").Error("获取会话变量失败", "error", err)
+		http.Error(w, "获取会话变量失败", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.With("name", "【会话】").Info("会话变量已更新",
+		"session_id", req.SessionID,
+		"set", len(req.Vars),
+		"unset", req.Unset)
+
+	models.WriteData(w, models.BaseResponse[tools.Vars]{
+		Code:    http.StatusOK,
+		Message: "会话变量设置成功",
+		Data:    v,
+	})
+}
+
+// Artifacts 列出会话的制品
+// 路径参数: id 会话ID；查询参数: channel 渠道，为空时不按渠道过滤
+func (h *SessionHandler) Artifacts(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	artifacts, err := h.storage.Artifact().ListBySession(r.URL.Query().Get("channel"), sessionID)
+	if err != nil {
+		h.logger.With("name", "【会话】").Error("获取会话制品失败", "error", err, "session_id", sessionID)
+		http.Error(w, "获取会话制品失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[[]*storage.Artifact]{
+		Code:    http.StatusOK,
+		Message: "会话制品获取成功",
+		Data:    artifacts,
+	})
+}
+
+// ArtifactFile 下载会话的制品文件
+func (h *SessionHandler) ArtifactFile(w http.ResponseWriter, r *http.Request) {
+	sessionID, artifactID := chi.URLParam(r, "id"), chi.URLParam(r, "artifact_id")
+	a, err := h.storage.Artifact().GetByID(artifactID)
+	if errors.Is(err, icooclawErrors.ErrRecordNotFound) || (err == nil && a.SessionID != sessionID) {
+		http.Error(w, "制品不存在", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.With("name", "【会话】").Error("获取制品失败", "error", err, "artifact_id", artifactID)
+		http.Error(w, "获取制品失败", http.StatusInternalServerError)
+		return
+	}
+
+	f, err := os.Open(a.Path)
+	if err != nil {
+		h.logger.With("name", "【会话】").Error("打开制品文件失败", "error", err, "artifact_id", artifactID)
+		http.Error(w, "制品文件不存在", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	if a.MIMEType != "" {
+		w.Header().Set("Content-Type", a.MIMEType)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	http.ServeContent(w, r, a.Name, a.UpdatedAt, f)
+}
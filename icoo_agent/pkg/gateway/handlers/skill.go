@@ -3,20 +3,42 @@ package handlers
 import (
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"icooclaw/pkg/gateway/models"
+	"icooclaw/pkg/skill/trigger"
 	"icooclaw/pkg/storage"
 )
 
+// firingsLimit 默认返回的触发记录条数
+const firingsLimit = 100
+
 type SkillHandler struct {
-	logger  *slog.Logger
-	storage *storage.Storage
+	logger   *slog.Logger
+	storage  *storage.Storage
+	triggers *trigger.Engine
 }
 
 func NewSkillHandler(logger *slog.Logger, storage *storage.Storage) *SkillHandler {
 	return &SkillHandler{logger: logger, storage: storage}
 }
 
+// WithTriggers 设置技能触发引擎，技能变更后重新加载触发规则。
+func (h *SkillHandler) WithTriggers(e *trigger.Engine) *SkillHandler {
+	h.triggers = e
+	return h
+}
+
+// reloadTriggers 技能变更后重新加载触发规则。
+func (h *SkillHandler) reloadTriggers() {
+	if h.triggers == nil {
+		return
+	}
+	if err := h.triggers.Reload(); err != nil {
+		h.logger.Warn("重新加载技能触发规则失败", "error", err)
+	}
+}
+
 func (h *SkillHandler) Page(w http.ResponseWriter, r *http.Request) {
 	req, err := models.Bind[*storage.QuerySkill](r)
 	if err != nil {
@@ -53,6 +75,7 @@ func (h *SkillHandler) Save(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "保存技能失败", http.StatusInternalServerError)
 		return
 	}
+	h.reloadTriggers()
 
 	models.WriteData(w, models.BaseResponse[*storage.Skill]{
 		Code:    http.StatusOK,
@@ -75,6 +98,7 @@ func (h *SkillHandler) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "创建技能失败", http.StatusInternalServerError)
 		return
 	}
+	h.reloadTriggers()
 
 	models.WriteData(w, models.BaseResponse[*storage.Skill]{
 		Code:    http.StatusOK,
@@ -97,6 +121,7 @@ func (h *SkillHandler) Update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "更新技能失败", http.StatusInternalServerError)
 		return
 	}
+	h.reloadTriggers()
 
 	models.WriteData(w, models.BaseResponse[*storage.Skill]{
 		Code:    http.StatusOK,
@@ -119,6 +144,7 @@ func (h *SkillHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "删除技能失败", http.StatusInternalServerError)
 		return
 	}
+	h.reloadTriggers()
 
 	models.WriteData(w, models.BaseResponse[any]{
 		Code:    http.StatusOK,
@@ -216,10 +242,38 @@ func (h *SkillHandler) Upsert(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "创建或更新技能失败", http.StatusInternalServerError)
 		return
 	}
+	h.reloadTriggers()
 
 	models.WriteData(w, models.BaseResponse[*storage.Skill]{
 		Code:    http.StatusOK,
 		Message: "技能创建或更新成功",
 		Data:    req,
 	})
-}
\ No newline at end of file
+}
+
+// Firings 列出技能触发记录，可按 skill 查询参数过滤，limit 指定条数。
+func (h *SkillHandler) Firings(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := firingsLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit 必须为正整数", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	firings, err := h.storage.SkillFiring().List(query.Get("skill"), limit)
+	if err != nil {
+		h.logger.Error("获取技能触发记录失败", "error", err)
+		http.Error(w, "获取技能触发记录失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[[]*storage.SkillFiring]{
+		Code:    http.StatusOK,
+		Message: "技能触发记录获取成功",
+		Data:    firings,
+	})
+}
@@ -4,19 +4,54 @@ import (
 	"log/slog"
 	"net/http"
 
+	"icooclaw/pkg/authz"
 	"icooclaw/pkg/gateway/models"
 	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/plugin"
 )
 
 type ToolHandler struct {
-	logger  *slog.Logger
-	storage *storage.Storage
+	logger      *slog.Logger
+	storage     *storage.Storage
+	registry    *tools.Registry
+	permissions *authz.Permissions
+	plugins     *plugin.Monitor
+}
+
+// RemovePermissionRequest 删除工具权限规则的请求
+type RemovePermissionRequest struct {
+	Name string `json:"name"` // 规则名称
+}
+
+// CheckPermissionRequest 测试工具调用是否被允许的请求
+type CheckPermissionRequest struct {
+	Tool string         `json:"tool"`           // 工具名称
+	Args map[string]any `json:"args,omitempty"` // 工具参数
 }
 
 func NewToolHandler(logger *slog.Logger, storage *storage.Storage) *ToolHandler {
 	return &ToolHandler{logger: logger, storage: storage}
 }
 
+// WithRegistry 设置工具注册表，用于查询工具执行统计。
+func (h *ToolHandler) WithRegistry(r *tools.Registry) *ToolHandler {
+	h.registry = r
+	return h
+}
+
+// WithPermissions 设置工具权限引擎，用于查询和修改工具权限规则。
+func (h *ToolHandler) WithPermissions(p *authz.Permissions) *ToolHandler {
+	h.permissions = p
+	return h
+}
+
+// WithPluginMonitor 设置插件健康检查，工具统计附带已停用的插件。
+func (h *ToolHandler) WithPluginMonitor(m *plugin.Monitor) *ToolHandler {
+	h.plugins = m
+	return h
+}
+
 func (h *ToolHandler) Page(w http.ResponseWriter, r *http.Request) {
 	req, err := models.Bind[*storage.QueryTool](r)
 	if err != nil {
@@ -154,4 +189,123 @@ func (h *ToolHandler) GetEnabled(w http.ResponseWriter, r *http.Request) {
 		Message: "启用工具列表获取成功",
 		Data:    tools,
 	})
-}
\ No newline at end of file
+}
+
+// Stats 返回工具执行统计和当前注入提示词的工具使用提示。
+func (h *ToolHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	if h.registry == nil {
+		http.Error(w, "未配置工具注册表", http.StatusServiceUnavailable)
+		return
+	}
+
+	stats := h.registry.Stats()
+	data := map[string]any{
+		"tools": stats.Snapshot(),
+		"notes": stats.Notes(),
+	}
+	if h.plugins != nil {
+		data["disabled"] = h.plugins.Disabled()
+	}
+	models.WriteData(w, models.BaseResponse[map[string]any]{
+		Code:    http.StatusOK,
+		Message: "工具统计获取成功",
+		Data:    data,
+	})
+}
+
+// Permissions 返回全部工具权限规则，配置中的规则在前。
+func (h *ToolHandler) Permissions(w http.ResponseWriter, r *http.Request) {
+	if h.permissions == nil {
+		http.Error(w, "工具权限未启用", http.StatusServiceUnavailable)
+		return
+	}
+
+	rules := h.permissions.Rules()
+	if rules == nil {
+		rules = []authz.PermissionRule{}
+	}
+	models.WriteData(w, models.BaseResponse[[]authz.PermissionRule]{
+		Code:    http.StatusOK,
+		Message: "工具权限规则获取成功",
+		Data:    rules,
+	})
+}
+
+// AddPermission 添加或替换运行时工具权限规则，立即生效并保存。
+func (h *ToolHandler) AddPermission(w http.ResponseWriter, r *http.Request) {
+	if h.permissions == nil {
+		http.Error(w, "工具权限未启用", http.StatusServiceUnavailable)
+		return
+	}
+
+	req, err := models.Bind[*authz.PermissionRule](r)
+	if err != nil {
+		h.logger.Error("绑定工具权限规则请求失败", "error", err)
+		http.Error(w, "绑定工具权限规则请求失败", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.permissions.Add(*req); err != nil {
+		h.logger.Error("添加工具权限规则失败", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*authz.PermissionRule]{
+		Code:    http.StatusOK,
+		Message: "工具权限规则已保存",
+		Data:    req,
+	})
+}
+
+// RemovePermission 删除运行时工具权限规则，配置中的规则不能删除。
+func (h *ToolHandler) RemovePermission(w http.ResponseWriter, r *http.Request) {
+	if h.permissions == nil {
+		http.Error(w, "工具权限未启用", http.StatusServiceUnavailable)
+		return
+	}
+
+	req, err := models.Bind[*RemovePermissionRequest](r)
+	if err != nil {
+		h.logger.Error("绑定删除工具权限规则请求失败", "error", err)
+		http.Error(w, "绑定删除工具权限规则请求失败", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.permissions.Remove(req.Name); err != nil {
+		h.logger.Error("删除工具权限规则失败", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[any]{
+		Code:    http.StatusOK,
+		Message: "工具权限规则已删除",
+	})
+}
+
+// CheckPermission 测试一次工具调用是否被工具权限规则允许，不执行工具。
+func (h *ToolHandler) CheckPermission(w http.ResponseWriter, r *http.Request) {
+	if h.permissions == nil {
+		http.Error(w, "工具权限未启用", http.StatusServiceUnavailable)
+		return
+	}
+
+	req, err := models.Bind[*CheckPermissionRequest](r)
+	if err != nil {
+		h.logger.Error("绑定检查工具权限请求失败", "error", err)
+		http.Error(w, "绑定检查工具权限请求失败", http.StatusBadRequest)
+		return
+	}
+
+	d := h.permissions.Check(r.Context(), req.Tool, req.Args)
+	message := "允许调用"
+	if !d.Allow {
+		message = "拒绝调用"
+	}
+	models.WriteData(w, models.BaseResponse[authz.Decision]{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    d,
+	})
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/gateway/models"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/trace"
+
+	"gorm.io/gorm"
+)
+
+type TraceHandler struct {
+	logger  *slog.Logger
+	storage *storage.Storage
+}
+
+func NewTraceHandler(logger *slog.Logger, storage *storage.Storage) *TraceHandler {
+	return &TraceHandler{logger: logger, storage: storage}
+}
+
+// ListTracesRequest 查询会话轨迹请求
+type ListTracesRequest struct {
+	Channel   string `json:"channel,omitempty"` // 渠道 (默认为 "websocket")
+	SessionID string `json:"session_id"`        // 会话ID
+	Limit     int    `json:"limit,omitempty"`   // 返回条数 (默认 20)
+}
+
+// List 列出会话最近的对话轨迹，不含详情
+func (h *TraceHandler) List(w http.ResponseWriter, r *http.Request) {
+	req, err := models.Bind[*ListTracesRequest](r)
+	if err != nil {
+		h.logger.Error("绑定轨迹查询请求失败", "error", err)
+		http.Error(w, "绑定轨迹查询请求失败", http.StatusBadRequest)
+		return
+	}
+
+	if req.SessionID == "" {
+		http.Error(w, "会话ID不能为空", http.StatusBadRequest)
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = consts.WEBSOCKET
+	}
+
+	traces, err := h.storage.Trace().ListBySession(req.Channel, req.SessionID, req.Limit)
+	if err != nil {
+		h.logger.With("name", "【轨迹】").Error("获取轨迹列表失败", "error", err)
+		http.Error(w, "获取轨迹列表失败", http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[[]*storage.Trace]{
+		Code:    http.StatusOK,
+		Message: "轨迹列表获取成功",
+		Data:    traces,
+	})
+}
+
+// Export 导出单轮对话轨迹报告。
+// 查询参数: id 轨迹ID，或 session_id (+ channel) 导出会话最近一轮；format 为 markdown (默认)、html，或 openai、anthropic、langchain、sharegpt 对话格式。
+func (h *TraceHandler) Export(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var (
+		record *storage.Trace
+		err    error
+	)
+	switch id, sessionID := query.Get("id"), query.Get("session_id"); {
+	case id != "":
+		record, err = h.storage.Trace().Get(id)
+	case sessionID != "":
+		channel := query.Get("channel")
+		if channel == "" {
+			channel = consts.WEBSOCKET
+		}
+		record, err = h.storage.Trace().Latest(channel, sessionID)
+	default:
+		http.Error(w, "需要提供 id 或 session_id", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "轨迹不存在", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.With("name", "【轨迹】").Error("获取轨迹失败", "error", err)
+		http.Error(w, "获取轨迹失败", http.StatusInternalServerError)
+		return
+	}
+
+	turn, err := trace.Decode(record.Data)
+	if err != nil {
+		h.logger.With("name", "【轨迹】").Error("解析轨迹失败", "id", record.ID, "error", err)
+		http.Error(w, "解析轨迹失败", http.StatusInternalServerError)
+		return
+	}
+	turn.ID = record.ID
+
+	content, contentType, ext, err := trace.Render(turn, query.Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="trace-%s%s"`, record.ID, ext))
+	w.Write(content)
+}
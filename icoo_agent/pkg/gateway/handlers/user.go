@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"icooclaw/pkg/gateway/models"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/userdata"
+	"icooclaw/pkg/workspace"
+)
+
+type UserHandler struct {
+	logger     *slog.Logger
+	storage    *storage.Storage
+	workspaces []workspace.Entry
+}
+
+func NewUserHandler(logger *slog.Logger, storage *storage.Storage) *UserHandler {
+	return &UserHandler{logger: logger, storage: storage}
+}
+
+// WithWorkspaces 设置需要检查用户文件的工作目录。
+func (h *UserHandler) WithWorkspaces(list []workspace.Entry) *UserHandler {
+	h.workspaces = list
+	return h
+}
+
+// PurgeUserResponse 删除用户数据响应
+type PurgeUserResponse struct {
+	Report *userdata.Report `json:"report"`           // 删除报告
+	Export *userdata.Export `json:"export,omitempty"` // 删除前导出的数据，dry_run 时为空
+}
+
+// ExportData 导出用户的全部数据
+func (h *UserHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	export, err := userdata.New(h.storage, h.workspaces, h.logger).Export(userID)
+	if err != nil {
+		h.logger.With("name", "【用户数据】").Error("导出用户数据失败", "error", err, "user_id", userID)
+		http.Error(w, "导出用户数据失败: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*userdata.Export]{
+		Code:    http.StatusOK,
+		Message: "用户数据导出成功",
+		Data:    export,
+	})
+}
+
+// PurgeData 导出并删除用户的全部数据，查询参数 dry_run=true 时只返回将被删除的内容
+func (h *UserHandler) PurgeData(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	svc := userdata.New(h.storage, h.workspaces, h.logger)
+
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		report, err := svc.Plan(userID)
+		if err != nil {
+			h.logger.With("name", "【用户数据】").Error("统计用户数据失败", "error", err, "user_id", userID)
+			http.Error(w, "统计用户数据失败: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		models.WriteData(w, models.BaseResponse[*PurgeUserResponse]{
+			Code:    http.StatusOK,
+			Message: "以下数据将被删除",
+			Data:    &PurgeUserResponse{Report: report},
+		})
+		return
+	}
+
+	export, report, err := svc.Purge(userID)
+	if err != nil {
+		h.logger.With("name", "【用户数据】").Error("删除用户数据失败", "error", err, "user_id", userID)
+		http.Error(w, "删除用户数据失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	models.WriteData(w, models.BaseResponse[*PurgeUserResponse]{
+		Code:    http.StatusOK,
+		Message: "用户数据已删除",
+		Data:    &PurgeUserResponse{Report: report, Export: export},
+	})
+}
@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"icooclaw/pkg/agent"
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/gateway/handlers"
+	"icooclaw/pkg/mcp"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+)
+
+// storageCheck 检查数据库能否在超时内响应查询
+func storageCheck(s *storage.Storage) handlers.ReadinessCheck {
+	return func(ctx context.Context) (any, error) {
+		return nil, s.Ping(ctx)
+	}
+}
+
+// busCheck 检查消息总线未关闭且队列未满
+func busCheck(b *bus.MessageBus) handlers.ReadinessCheck {
+	return func(ctx context.Context) (any, error) {
+		if b.IsClosed() {
+			return nil, errors.New("消息总线已关闭")
+		}
+		stats := b.Stats()
+		detail := map[string]any{"queue": stats, "dropped": b.DropCount()}
+		if stats.Full() {
+			return detail, errors.New("消息队列已满")
+		}
+		return detail, nil
+	}
+}
+
+// workerPoolCheck 报告工作池的并发和排队情况，未启用工作池时只标明未启用
+func workerPoolCheck(m *agent.AgentManager) handlers.ReadinessCheck {
+	return func(ctx context.Context) (any, error) {
+		stats, ok := m.WorkerPoolStats()
+		if !ok {
+			return map[string]any{"enabled": false}, nil
+		}
+		return stats, nil
+	}
+}
+
+// providerCheck 检查至少有一个提供商的熔断器未断开
+func providerCheck(f *providers.Factory) handlers.ReadinessCheck {
+	return func(ctx context.Context) (any, error) {
+		health := f.Health()
+		states := make(map[string]providers.CircuitState, len(health))
+		available := 0
+		for _, h := range health {
+			states[h.Name] = h.State
+			if h.State != providers.CircuitOpen {
+				available++
+			}
+		}
+		if len(health) == 0 {
+			return states, errors.New("未配置提供商")
+		}
+		if available == 0 {
+			return states, errors.New("所有提供商均已熔断")
+		}
+		return states, nil
+	}
+}
+
+// mcpCheck 检查 MCP 服务连接状态，未连接的服务会列在错误中
+func mcpCheck(m *mcp.Manager) handlers.ReadinessCheck {
+	return func(ctx context.Context) (any, error) {
+		status := m.GetConnectionStatus()
+		states := make(map[string]string, len(status))
+		var down []string
+		for name, state := range status {
+			states[name] = state.String()
+			if state != mcp.ConnectionStateConnected {
+				down = append(down, name)
+			}
+		}
+		if len(down) > 0 {
+			sort.Strings(down)
+			return states, fmt.Errorf("%d 个 MCP 服务未连接: %v", len(down), down)
+		}
+		return states, nil
+	}
+}
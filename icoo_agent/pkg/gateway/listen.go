@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// serve 按配置创建监听器并启动 HTTP/HTTPS 服务。
+func (s *Server) serve() error {
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(s.cfg.AutocertDomains) > 0:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(s.cfg.AutocertDomains...),
+			Cache:      autocert.DirCache(s.cfg.AutocertCacheDir),
+			Email:      s.cfg.AutocertEmail,
+		}
+		s.server.TLSConfig = m.TLSConfig()
+		return s.server.ServeTLS(ln, "", "")
+	case s.cfg.TLSCertFile != "":
+		return s.server.ServeTLS(ln, s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+	default:
+		return s.server.Serve(ln)
+	}
+}
+
+// listen 创建 TCP 或 unix socket 监听器。
+func (s *Server) listen() (net.Listener, error) {
+	if s.cfg.UnixSocket != "" {
+		return listenUnix(s.cfg.UnixSocket)
+	}
+
+	if err := s.checkBindAddr(); err != nil {
+		return nil, err
+	}
+	return net.Listen("tcp", s.server.Addr)
+}
+
+// checkBindAddr 校验监听地址，未启用认证却监听所有网卡时输出警告。
+func (s *Server) checkBindAddr() error {
+	host, _, err := net.SplitHostPort(s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("监听地址无效 %s: %w", s.server.Addr, err)
+	}
+
+	if isAllInterfaces(host) && !s.cfg.AuthEnabled {
+		s.logger.With("name", "【网关服务】").Warn("正在监听所有网卡且未启用认证，任何能访问该端口的人都可以调用智能体，"+
+			"建议设置 gateway.host = \"127.0.0.1\" 或启用认证", "addr", s.server.Addr)
+	}
+	return nil
+}
+
+// listenUnix 在 path 上监听 unix socket，并清理上次遗留的 socket 文件。
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&fs.ModeSocket == 0 {
+			return nil, fmt.Errorf("unix socket 路径已存在且不是 socket: %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("清理 unix socket 失败: %w", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 仅允许同组用户（如反向代理）访问
+	if err := os.Chmod(path, 0660); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("设置 unix socket 权限失败: %w", err)
+	}
+	return ln, nil
+}
+
+// isAllInterfaces 判断监听地址是否为所有网卡。
+func isAllInterfaces(host string) bool {
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// listenAddr 返回用于日志的监听地址。
+func (s *Server) listenAddr() string {
+	if s.cfg.UnixSocket != "" {
+		return "unix:" + s.cfg.UnixSocket
+	}
+	return s.server.Addr
+}
+
+// tlsEnabled 是否启用了 HTTPS。
+func (s *Server) tlsEnabled() bool {
+	return s.cfg.TLSCertFile != "" || len(s.cfg.AutocertDomains) > 0
+}
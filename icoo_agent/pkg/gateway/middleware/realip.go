@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseTrustedProxies parses a list of IPs or CIDRs into networks.
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy: %s", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %s", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// TrustedRealIP returns a middleware that sets RemoteAddr from X-Forwarded-For
+// or X-Real-IP, but only when the direct peer is a trusted proxy.
+// Unlike chi's RealIP, forwarded headers from untrusted peers are ignored,
+// so clients cannot spoof their address.
+func TrustedRealIP(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := realIP(r, trusted); ip != "" {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// realIP resolves the client IP, returning "" when RemoteAddr should be kept.
+func realIP(r *http.Request, trusted []*net.IPNet) string {
+	// unix socket 的对端总是本机代理，视为受信任
+	if !isUnixPeer(r.RemoteAddr) && (len(trusted) == 0 || !isTrusted(peerIP(r.RemoteAddr), trusted)) {
+		return ""
+	}
+
+	// 从右向左跳过受信任的代理，第一个不受信任的地址即客户端地址
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			if i == 0 || !isTrusted(ip, trusted) {
+				return ip.String()
+			}
+		}
+	}
+
+	if xrip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); xrip != nil {
+		return xrip.String()
+	}
+	return ""
+}
+
+// isUnixPeer reports whether the request came over a unix socket.
+func isUnixPeer(remoteAddr string) bool {
+	return remoteAddr == "" || remoteAddr == "@"
+}
+
+// peerIP extracts the IP from a RemoteAddr.
+func peerIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// isTrusted reports whether ip belongs to a trusted network.
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
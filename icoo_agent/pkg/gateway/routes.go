@@ -5,6 +5,7 @@ import (
 
 	"icooclaw/pkg/agent"
 	"icooclaw/pkg/bus"
+	"icooclaw/pkg/gateway/auth"
 	"icooclaw/pkg/gateway/handlers"
 	"icooclaw/pkg/gateway/websocket"
 	"icooclaw/pkg/scheduler"
@@ -29,6 +30,11 @@ type Handlers struct {
 	Tool     *handlers.ToolHandler
 	Binding  *handlers.BindingHandler
 	Chat     *handlers.ChatHandler
+	Trace    *handlers.TraceHandler
+	User     *handlers.UserHandler
+	Job      *handlers.JobHandler
+	FAQ      *handlers.FAQHandler
+	Audit    *handlers.AuditHandler
 }
 
 // NewHandlers 创建所有处理器
@@ -60,13 +66,31 @@ func NewHandlers(
 		Tool:     handlers.NewToolHandler(logger, storage),
 		Binding:  handlers.NewBindingHandler(logger, storage),
 		Chat:     chatHandler,
+		Trace:    handlers.NewTraceHandler(logger, storage),
+		User:     handlers.NewUserHandler(logger, storage),
+		Job:      handlers.NewJobHandler(logger, storage),
+		FAQ:      handlers.NewFAQHandler(logger, storage),
+		Audit:    handlers.NewAuditHandler(logger, storage),
 	}
 }
 
+// RouteScopes 启用认证时各路由所需的权限范围，按顺序匹配路径前缀，未列出的路由需要 admin 权限。
+// 渠道回调由各渠道自行校验签名，在 Setup 中追加为公开路由。
+var RouteScopes = []auth.Route{
+	{Prefix: "/api/v1/health", Scope: auth.ScopePublic},
+	{Prefix: "/api/v1/chat/queue/max", Scope: auth.ScopeAdmin},
+	{Prefix: "/api/v1/chat/agents/max", Scope: auth.ScopeAdmin},
+	{Prefix: "/api/v1/chat", Scope: auth.ScopeChat},
+	{Prefix: "/ws", Scope: auth.ScopeChat},
+	{Prefix: "/events", Scope: auth.ScopeChat},
+}
+
 // RegisterRoutes 注册所有 CRUD 路由
 func RegisterRoutes(r chi.Router, h *Handlers) {
 	// 健康检查
 	r.Get("/api/v1/health", h.Common.HealthCheck)
+	r.Get("/api/v1/health/live", h.Common.Liveness)   // 存活探针
+	r.Get("/api/v1/health/ready", h.Common.Readiness) // 就绪探针
 
 	// Chat 路由
 	r.Route("/api/v1/chat", func(r chi.Router) {
@@ -76,15 +100,27 @@ func RegisterRoutes(r chi.Router, h *Handlers) {
 		r.Get("/queue", h.Chat.GetQueueStatus)        // 队列状态
 		r.Post("/queue/max", h.Chat.SetMaxConcurrent) // 设置最大并发
 		r.Post("/agents/max", h.Chat.SetMaxAgents)    // 设置最大 Agent 数
+		r.Get("/protocol", h.Chat.GetProtocol)        // WebSocket 协议说明
 	})
 
 	// Session 路由
 	r.Route("/api/v1/sessions", func(r chi.Router) {
-		r.Post("/page", h.Session.Page)     // 分页查询
-		r.Post("/save", h.Session.Save)     // 保存
-		r.Post("/create", h.Session.Create) // 创建新会话
-		r.Post("/delete", h.Session.Delete) // 删除
-		r.Post("/get", h.Session.GetByID)   // 获取单个
+		r.Post("/page", h.Session.Page)          // 分页查询
+		r.Post("/save", h.Session.Save)          // 保存
+		r.Post("/create", h.Session.Create)      // 创建新会话
+		r.Post("/delete", h.Session.Delete)      // 删除
+		r.Post("/get", h.Session.GetByID)        // 获取单个
+		r.Post("/reset", h.Session.Reset)        // 归档并重置
+		r.Post("/merge", h.Session.Merge)        // 合并重复会话
+		r.Post("/close", h.Session.Close)        // 关闭并清除无痕会话
+		r.Post("/tools", h.Session.GetTools)     // 获取会话工具策略
+		r.Post("/tools/set", h.Session.SetTools) // 设置会话工具策略
+		r.Post("/vars", h.Session.GetVars)       // 获取会话变量
+		r.Post("/vars/set", h.Session.SetVars)   // 设置或删除会话变量
+
+		// 制品，查询参数 channel 可按渠道过滤
+		r.Get("/{id}/artifacts", h.Session.Artifacts)                  // 会话的制品
+		r.Get("/{id}/artifacts/{artifact_id}", h.Session.ArtifactFile) // 下载制品文件
 	})
 
 	// Message 路由
@@ -97,6 +133,29 @@ func RegisterRoutes(r chi.Router, h *Handlers) {
 		r.Post("/by-session", h.Message.GetBySessionID)
 	})
 
+	// Trace 路由
+	r.Route("/api/v1/traces", func(r chi.Router) {
+		r.Post("/list", h.Trace.List)    // 会话最近的对话轨迹
+		r.Get("/export", h.Trace.Export) // 导出 Markdown/HTML 报告或对话格式
+	})
+
+	// 用户数据路由
+	r.Route("/api/v1/users/{id}", func(r chi.Router) {
+		r.Get("/data", h.User.ExportData)   // 导出用户的全部数据
+		r.Delete("/data", h.User.PurgeData) // 导出并删除，?dry_run=true 只列出
+	})
+
+	// 后台作业路由
+	r.Route("/api/v1/jobs", func(r chi.Router) {
+		r.Post("/page", h.Job.Page)     // 分页查询
+		r.Post("/create", h.Job.Create) // 提交作业
+		r.Post("/get", h.Job.GetByID)   // 状态与进度
+		r.Post("/pause", h.Job.Pause)   // 暂停
+		r.Post("/resume", h.Job.Resume) // 恢复
+		r.Post("/cancel", h.Job.Cancel) // 取消
+		r.Get("/types", h.Job.Types)    // 可提交的作业类型
+	})
+
 	// MCP 路由
 	r.Route("/api/v1/mcp", func(r chi.Router) {
 		r.Post("/page", h.MCP.Page)
@@ -117,6 +176,17 @@ func RegisterRoutes(r chi.Router, h *Handlers) {
 		r.Post("/search", h.Memory.Search)
 	})
 
+	// 常见问题路由
+	r.Route("/api/v1/faqs", func(r chi.Router) {
+		r.Post("/page", h.FAQ.Page)
+		r.Post("/create", h.FAQ.Create)
+		r.Post("/update", h.FAQ.Update)
+		r.Post("/delete", h.FAQ.Delete)
+		r.Post("/get", h.FAQ.GetByID)
+		r.Post("/promote", h.FAQ.Promote) // 将智能体回复提升为常见问题
+		r.Post("/match", h.FAQ.Match)     // 测试消息命中的常见问题
+	})
+
 	// Task 路由
 	r.Route("/api/v1/tasks", func(r chi.Router) {
 		r.Post("/page", h.Task.Page)
@@ -138,6 +208,11 @@ func RegisterRoutes(r chi.Router, h *Handlers) {
 		r.Post("/get", h.Provider.GetByID)
 		r.Get("/all", h.Provider.GetAll)
 		r.Get("/enabled", h.Provider.GetEnabled)
+		r.Get("/presets", h.Provider.Presets) // 内置厂商预设
+		r.Get("/health", h.Provider.Health)   // 健康状态与熔断状态
+		r.Get("/metrics", h.Provider.Metrics) // Prometheus 指标
+		r.Get("/keys", h.Provider.Keys)       // 多密钥的使用与停用状态
+		r.Post("/keys/enable", h.Provider.EnableKey)
 	})
 
 	// Skill 路由
@@ -151,6 +226,7 @@ func RegisterRoutes(r chi.Router, h *Handlers) {
 		r.Post("/upsert", h.Skill.Upsert)
 		r.Get("/all", h.Skill.GetAll)
 		r.Get("/enabled", h.Skill.GetEnabled)
+		r.Get("/firings", h.Skill.Firings) // 触发记录
 	})
 
 	// Channel 路由
@@ -189,6 +265,19 @@ func RegisterRoutes(r chi.Router, h *Handlers) {
 		r.Post("/get", h.Tool.GetByID)
 		r.Get("/all", h.Tool.GetAll)
 		r.Get("/enabled", h.Tool.GetEnabled)
+		r.Get("/stats", h.Tool.Stats) // 执行统计
+
+		// 工具权限规则
+		r.Get("/permissions", h.Tool.Permissions)              // 全部规则
+		r.Post("/permissions/set", h.Tool.AddPermission)       // 添加或替换运行时规则
+		r.Post("/permissions/delete", h.Tool.RemovePermission) // 删除运行时规则
+		r.Post("/permissions/check", h.Tool.CheckPermission)   // 测试工具调用是否被允许
+	})
+
+	// 工具调用审计
+	r.Route("/api/v1/audit", func(r chi.Router) {
+		r.Get("/", h.Audit.List)         // 查询审计记录
+		r.Get("/verify", h.Audit.Verify) // 校验哈希链
 	})
 
 	// Binding 路由
@@ -3,15 +3,31 @@ package gateway
 import (
 	"context"
 	"log/slog"
+	"net"
 	"net/http"
+	"slices"
 	"time"
 
 	"icooclaw/pkg/agent"
+	"icooclaw/pkg/authz"
 	"icooclaw/pkg/bus"
+	"icooclaw/pkg/channels"
+	"icooclaw/pkg/ephemeral"
+	"icooclaw/pkg/faq"
+	"icooclaw/pkg/gateway/auth"
+	gwMiddleware "icooclaw/pkg/gateway/middleware"
 	"icooclaw/pkg/gateway/sse"
 	"icooclaw/pkg/gateway/websocket"
+	"icooclaw/pkg/jobs"
+	"icooclaw/pkg/mcp"
+	"icooclaw/pkg/memory"
+	"icooclaw/pkg/providers"
 	"icooclaw/pkg/scheduler"
+	"icooclaw/pkg/skill/trigger"
 	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/plugin"
+	"icooclaw/pkg/workspace"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -21,6 +37,8 @@ import (
 type Server struct {
 	router       chi.Router
 	server       *http.Server
+	cfg          *ServerConfig
+	trusted      []*net.IPNet
 	storage      *storage.Storage
 	logger       *slog.Logger
 	handlers     *Handlers
@@ -29,6 +47,8 @@ type Server struct {
 	sseBroker    *sse.Broker
 	bus          *bus.MessageBus
 	agentManager *agent.AgentManager
+	webhooks     map[string]http.Handler
+	auth         *auth.Authenticator
 }
 
 // ServerConfig holds the server configuration.
@@ -38,6 +58,22 @@ type ServerConfig struct {
 	WriteTimeout    time.Duration
 	IdleTimeout     time.Duration
 	MaxConcurrentWS int
+
+	// UnixSocket 监听的 unix socket 路径，设置后忽略 Addr
+	UnixSocket string
+	// TrustedProxies 受信任的反向代理 IP 或 CIDR
+	TrustedProxies []string
+	// TLSCertFile/TLSKeyFile HTTPS 证书和私钥
+	TLSCertFile string
+	TLSKeyFile  string
+	// AutocertDomains 自动签发证书的域名
+	AutocertDomains []string
+	// AutocertCacheDir 自动证书缓存目录
+	AutocertCacheDir string
+	// AutocertEmail ACME 账户邮箱
+	AutocertEmail string
+	// AuthEnabled 是否启用了认证，未启用时监听所有网卡会输出警告
+	AuthEnabled bool
 }
 
 // DefaultServerConfig returns the default server configuration.
@@ -61,13 +97,24 @@ func NewServer(
 	wsManager *websocket.Manager,
 	agentManager *agent.AgentManager,
 ) *Server {
+	if cfg == nil {
+		cfg = DefaultServerConfig()
+	}
 	if logger == nil {
 		logger = slog.Default()
 	}
 
+	trusted, err := gwMiddleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logger.With("name", "【网关服务】").Warn("受信任代理配置无效，将忽略转发头", "error", err)
+		trusted = nil
+	}
+
 	r := chi.NewRouter()
 	s := &Server{
 		router:       r,
+		cfg:          cfg,
+		trusted:      trusted,
 		storage:      store,
 		logger:       logger,
 		schedule:     schedule,
@@ -86,6 +133,17 @@ func NewServer(
 		s.wsManager,
 	)
 
+	// 注册就绪检查
+	if store != nil {
+		s.handlers.Common.WithCheck("storage", true, storageCheck(store))
+	}
+	if bus != nil {
+		s.handlers.Common.WithCheck("bus", true, busCheck(bus))
+	}
+	if agentManager != nil {
+		s.handlers.Common.WithCheck("workers", false, workerPoolCheck(agentManager))
+	}
+
 	// Setup middleware
 	s.setupMiddleware()
 
@@ -116,9 +174,112 @@ func (s *Server) WithSSE() *Server {
 	return s
 }
 
+// WithProviderFactory sets the provider factory used to report provider health.
+func (s *Server) WithProviderFactory(f *providers.Factory) *Server {
+	s.handlers.Provider.WithFactory(f)
+	if f != nil {
+		s.handlers.Common.WithCheck("providers", true, providerCheck(f))
+	}
+	return s
+}
+
+// WithToolRegistry sets the tool registry used to report tool statistics
+// and to resolve the tools available to a session.
+func (s *Server) WithToolRegistry(r *tools.Registry) *Server {
+	s.handlers.Tool.WithRegistry(r)
+	s.handlers.Session.WithRegistry(r)
+	return s
+}
+
+// WithEphemeral sets the registry used by the "ephemeral" field of session creation.
+func (s *Server) WithEphemeral(r *ephemeral.Registry) *Server {
+	s.handlers.Session.WithEphemeral(r)
+	return s
+}
+
+// WithMemoryScore sets the scoring model used to rank memory search results.
+func (s *Server) WithMemoryScore(cfg memory.ScoreConfig) *Server {
+	s.handlers.Memory.WithScoreConfig(cfg)
+	return s
+}
+
+// WithDeduper sets the deduper used for Idempotency-Key on the chat endpoints.
+func (s *Server) WithDeduper(d *channels.Deduper) *Server {
+	s.handlers.Chat.WithDeduper(d)
+	return s
+}
+
+// WithWorkspaces sets the workspace manager used by the "workspace" field of the chat endpoints
+// and by the user data endpoints to find the user's files.
+func (s *Server) WithWorkspaces(m *workspace.Manager) *Server {
+	s.handlers.Chat.WithWorkspaces(m)
+	if m != nil {
+		s.handlers.User.WithWorkspaces(m.List())
+	}
+	return s
+}
+
+// WithJobs sets the runner used by the jobs endpoints to submit and control background jobs.
+func (s *Server) WithJobs(r *jobs.Runner) *Server {
+	s.handlers.Job.WithRunner(r)
+	return s
+}
+
+// WithFAQ sets the matcher used by the FAQ endpoints to test which entry a message hits.
+func (s *Server) WithFAQ(m *faq.Matcher) *Server {
+	s.handlers.FAQ.WithMatcher(m)
+	return s
+}
+
+// WithSkillTriggers sets the trigger engine reloaded when skills are saved or deleted.
+func (s *Server) WithSkillTriggers(e *trigger.Engine) *Server {
+	s.handlers.Skill.WithTriggers(e)
+	return s
+}
+
+// WithPermissions sets the tool permission engine managed by the tool permission endpoints.
+func (s *Server) WithPermissions(p *authz.Permissions) *Server {
+	s.handlers.Tool.WithPermissions(p)
+	return s
+}
+
+// WithPluginMonitor sets the plugin health monitor whose disabled tools are listed in the tool stats.
+func (s *Server) WithPluginMonitor(m *plugin.Monitor) *Server {
+	s.handlers.Tool.WithPluginMonitor(m)
+	return s
+}
+
+// WithChannels mounts the webhook endpoints of channels that receive events over HTTP.
+func (s *Server) WithChannels(m *channels.Manager) *Server {
+	if m != nil {
+		s.webhooks = m.WebhookHandlers()
+	}
+	return s
+}
+
+// WithAuth requires API keys or JWT bearer tokens on every route except health
+// checks and channel webhooks, with the scopes listed in RouteScopes.
+func (s *Server) WithAuth(a *auth.Authenticator) *Server {
+	s.auth = a
+	return s
+}
+
+// WithMCP sets the MCP manager whose server connections are reported by the
+// readiness probe. A disconnected MCP server degrades tools but does not make
+// the gateway unready.
+func (s *Server) WithMCP(m *mcp.Manager) *Server {
+	if m != nil {
+		s.handlers.Common.WithCheck("mcp", false, mcpCheck(m))
+	}
+	return s
+}
+
 // WithBus sets the message bus.
 func (s *Server) WithBus(b *bus.MessageBus) *Server {
 	s.bus = b
+	if b != nil {
+		s.handlers.Common.WithCheck("bus", true, busCheck(b))
+	}
 	if s.wsManager != nil {
 		s.wsManager.WithBus(b)
 	}
@@ -127,6 +288,9 @@ func (s *Server) WithBus(b *bus.MessageBus) *Server {
 
 func (s *Server) WithAgentManager(m *agent.AgentManager) *Server {
 	s.agentManager = m
+	if m != nil {
+		s.handlers.Common.WithCheck("workers", false, workerPoolCheck(m))
+	}
 	if s.wsManager != nil {
 		s.wsManager.WithAgentManager(m)
 	}
@@ -158,6 +322,11 @@ func (s *Server) Setup() *Server {
 		s.router.Get("/events", s.sseBroker.Handler())
 	}
 
+	// Add channel webhook routes
+	for path, handler := range s.webhooks {
+		s.router.Handle(path, handler)
+	}
+
 	s.server.Handler = s.router
 
 	return s
@@ -168,8 +337,8 @@ func (s *Server) setupMiddleware() {
 	// Request ID
 	s.router.Use(middleware.RequestID)
 
-	// Real IP
-	s.router.Use(middleware.RealIP)
+	// Real IP，仅采信受信任代理的转发头
+	s.router.Use(gwMiddleware.TrustedRealIP(s.trusted))
 
 	// Logger
 	s.router.Use(middleware.Logger)
@@ -182,6 +351,15 @@ func (s *Server) setupMiddleware() {
 
 	// CORS
 	s.router.Use(corsMiddleware)
+
+	// 认证，放在 CORS 之后以便预检请求无需凭证
+	if s.auth != nil {
+		routes := slices.Clone(RouteScopes)
+		for path := range s.webhooks {
+			routes = append(routes, auth.Route{Prefix: path, Scope: auth.ScopePublic})
+		}
+		s.router.Use(s.auth.Middleware(routes, auth.ScopeAdmin, s.logger))
+	}
 }
 
 // corsMiddleware handles CORS headers.
@@ -202,7 +380,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 
 // Start starts the HTTP server.
 func (s *Server) Start() error {
-	s.logger.With("name", "【网关服务】").Info("已启动", "addr", s.server.Addr)
+	s.logger.With("name", "【网关服务】").Info("已启动", "addr", s.listenAddr(), "tls", s.tlsEnabled())
 
 	// Start WebSocket manager if configured
 	if s.wsManager != nil {
@@ -222,7 +400,7 @@ func (s *Server) Start() error {
 		}()
 	}
 
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := s.serve(); err != nil && err != http.ErrServerClosed {
 		s.logger.With("name", "【网关服务】").Error("【网关服务】已启动失败", "error", err)
 	}
 	return nil
@@ -9,6 +9,9 @@ import (
 	"sync/atomic"
 	"time"
 
+	"icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/command"
+
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
@@ -29,6 +32,10 @@ type Client struct {
 	lastPing   time.Time
 	lastPong   time.Time
 	messageSeq atomic.Uint64
+	eventSeq   atomic.Uint64
+
+	// 协商后的协议状态，为空表示旧版协议
+	proto atomic.Pointer[protocolState]
 
 	// Configuration
 	writeWait      time.Duration
@@ -240,10 +247,17 @@ func (c *Client) handleMessage(ctx context.Context, messageType int, message []b
 			go c.manager.ProcessMessage(ctx, c, &msg)
 		}
 
+	case EventHello:
+		c.handleHello(message)
+
 	case "create_session":
 		// Handle create_session message from frontend
 		c.handleCreateSession(ctx, message)
 
+	case "commands":
+		// 返回命令面板数据
+		c.handleCommands()
+
 	case "ping":
 		c.SendJSON(map[string]interface{}{
 			"type":      "pong",
@@ -296,6 +310,21 @@ func (c *Client) handleCreateSession(ctx context.Context, message []byte) {
 	c.logger.With("name", "【WebSocket】").Info("会话创建成功", "session_id", sessionID, "client_id", c.ID)
 }
 
+// handleCommands 发送 WebSocket 命令面板数据。
+func (c *Client) handleCommands() {
+	if c.manager == nil || c.manager.agentManager == nil {
+		c.SendError("manager not configured")
+		return
+	}
+
+	cmds := c.manager.agentManager.Commands().List(consts.WEBSOCKET)
+	c.SendJSON(map[string]interface{}{
+		"type":      "commands",
+		"data":      command.RenderPalette(cmds),
+		"timestamp": time.Now().Unix(),
+	})
+}
+
 // Send queues a message to be sent to the client.
 func (c *Client) Send(message []byte) bool {
 	if !c.connected.Load() {
@@ -323,6 +352,10 @@ func (c *Client) SendJSON(v interface{}) bool {
 
 // SendError sends an error message to the client.
 func (c *Client) SendError(message string) {
+	if c.Negotiated() {
+		c.Emit(EventError, c.sessionID, ErrorPayload{Message: message})
+		return
+	}
 	c.SendJSON(map[string]interface{}{
 		"type":      "error",
 		"message":   message,
@@ -0,0 +1,119 @@
+package websocket
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// protocolState 连接协商后的协议状态
+type protocolState struct {
+	version      int
+	capabilities []string
+}
+
+// Negotiated 是否已通过 hello 协商协议版本。
+func (c *Client) Negotiated() bool {
+	return c.proto.Load() != nil
+}
+
+// ProtocolVersion 返回协商后的协议版本，未协商时返回 0（旧版协议）。
+func (c *Client) ProtocolVersion() int {
+	if st := c.proto.Load(); st != nil {
+		return st.version
+	}
+	return 0
+}
+
+// HasCapability 判断连接是否协商了指定能力。
+func (c *Client) HasCapability(capability string) bool {
+	st := c.proto.Load()
+	return st != nil && slices.Contains(st.capabilities, capability)
+}
+
+// handleHello 处理客户端握手帧。
+func (c *Client) handleHello(message []byte) {
+	var req HelloRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		c.SendError("握手消息格式错误")
+		return
+	}
+
+	version, caps, ok := negotiate(req)
+	if !ok {
+		c.SendError("不支持的协议版本，服务端最低支持版本为 1")
+		return
+	}
+
+	c.proto.Store(&protocolState{version: version, capabilities: caps})
+	c.logger.With("name", "【WebSocket】").Info("协议协商成功",
+		"client_id", c.ID,
+		"client", req.Client,
+		"version", version,
+		"capabilities", caps)
+
+	c.Emit(EventHello, c.sessionID, HelloPayload{
+		Version:      version,
+		MinVersion:   MinProtocolVersion,
+		MaxVersion:   ProtocolVersion,
+		Capabilities: caps,
+		ClientID:     c.ID,
+		SessionID:    c.sessionID,
+	})
+}
+
+// Emit 向客户端发送事件。
+// 已协商协议的连接发送事件帧，并过滤未协商能力对应的事件；
+// 旧版连接将事件转换为 chunk/end/error 帧，无法表达的事件直接丢弃。
+func (c *Client) Emit(eventType, sessionID string, data any) bool {
+	st := c.proto.Load()
+	if st == nil {
+		return c.emitLegacy(data)
+	}
+
+	if capability, ok := eventCapability[eventType]; ok && !slices.Contains(st.capabilities, capability) {
+		return false
+	}
+	if delta, ok := data.(DeltaPayload); ok && delta.Reasoning != "" && !slices.Contains(st.capabilities, CapReasoning) {
+		if delta.Content == "" {
+			return false
+		}
+		delta.Reasoning = ""
+		data = delta
+	}
+
+	return c.SendJSON(Event{
+		Type:      eventType,
+		Version:   st.version,
+		Seq:       c.eventSeq.Add(1),
+		SessionID: sessionID,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// emitLegacy 以旧版帧格式发送事件。
+func (c *Client) emitLegacy(data any) bool {
+	now := time.Now().Unix()
+
+	switch p := data.(type) {
+	case DeltaPayload:
+		chunk := map[string]any{"content": p.Content}
+		if p.Reasoning != "" {
+			chunk["reasoning"] = p.Reasoning
+		}
+		return c.SendJSON(map[string]any{"type": "chunk", "data": chunk, "timestamp": now})
+
+	case MessagePayload:
+		if p.Content != "" {
+			c.SendJSON(map[string]any{"type": "chunk", "data": map[string]any{"content": p.Content}, "timestamp": now})
+		}
+		return c.SendJSON(map[string]any{"type": "end", "timestamp": now})
+
+	case ErrorPayload:
+		return c.SendJSON(map[string]any{"type": "error", "error": map[string]string{"message": p.Message}, "timestamp": now})
+
+	default:
+		return false
+	}
+}
@@ -12,7 +12,9 @@ import (
 	"icooclaw/pkg/agent"
 	"icooclaw/pkg/agent/react"
 	"icooclaw/pkg/bus"
-	"icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/channels"
+	channelConsts "icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/consts"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -261,51 +263,27 @@ func (m *Manager) ProcessMessage(ctx context.Context, client *Client, msg *ChatM
 		"session_id", msg.SessionID,
 		"content_length", len(msg.Content))
 
-	// 发送错误响应消息
-	sendErrorResponse := func(errMsg string) {
-		client.SendJSON(map[string]any{
-			"type":      "error",
-			"error":     map[string]string{"message": errMsg},
-			"timestamp": time.Now().Unix(),
-		})
+	// 没有智能体管理器，发送错误响应消息
+	if m.agentManager == nil {
+		client.Emit(EventError, msg.SessionID, ErrorPayload{Message: "服务未配置：缺少智能体或消息总线"})
+		return nil
 	}
 
-	// 如果有智能体管理器，直接处理消息
-	if m.agentManager != nil {
-		// 直接处理消息
-		inbound := bus.InboundMessage{
-			Channel:   consts.WEBSOCKET,
-			SessionID: msg.SessionID,
-			Sender:    bus.SenderInfo{ID: client.userID, Name: client.userID},
-			Text:      msg.Content,
-			Timestamp: time.Now(),
-		}
-		// 运行智能体
-		finallyContent, err := m.agentManager.RunAgent(inbound)
-		if err != nil {
-			return err
-		}
-
-		// 发送 chunk 消息
-		data := map[string]any{
-			"content": finallyContent,
-		}
-		client.SendJSON(map[string]any{
-			"type":      "chunk",
-			"data":      data,
-			"timestamp": time.Now().Unix(),
-		})
-
-		// 发送结束消息
-		client.SendJSON(map[string]any{
-			"type":      "end",
-			"timestamp": time.Now().Unix(),
-		})
-		return nil
+	// 运行智能体
+	finallyContent, stats, err := m.agentManager.RunAgentStats(m.inbound(client, msg))
+	if err != nil {
+		client.Emit(EventError, msg.SessionID, ErrorPayload{Message: "处理消息失败: " + err.Error()})
+		return err
 	}
 
-	// 没有智能体管理器，发送错误响应消息
-	sendErrorResponse("服务未配置：缺少智能体或消息总线")
+	client.Emit(EventMessage, msg.SessionID, MessagePayload{
+		Role:    consts.RoleAssistant.ToString(),
+		Content: finallyContent,
+		Chunks:  channels.ClientChunks(finallyContent, msg.MaxChunkLength),
+	})
+	if stats != nil {
+		client.Emit(EventStats, msg.SessionID, stats)
+	}
 	return nil
 }
 
@@ -315,52 +293,14 @@ func (m *Manager) ProcessStreamMessage(ctx context.Context, client *Client, msg
 		"client_id", client.ID,
 		"session_id", msg.SessionID)
 
-	// Helper function to send stream error event
-	sendStreamError := func(errMsg string) {
-		client.SendJSON(map[string]any{
-			"type":      "error",
-			"error":     map[string]string{"message": errMsg},
-			"timestamp": time.Now().Unix(),
-		})
-	}
-
 	if m.agentManager == nil {
-		sendStreamError("服务未配置：缺少智能体管理器")
+		client.Emit(EventError, msg.SessionID, ErrorPayload{Message: "服务未配置：缺少智能体管理器"})
 		return nil
 	}
 
-	inbound := bus.InboundMessage{
-		Channel:   consts.WEBSOCKET,
-		SessionID: msg.SessionID,
-		Sender:    bus.SenderInfo{ID: client.userID, Name: client.userID},
-		Text:      msg.Content,
-		Timestamp: time.Now(),
-	}
-
 	// 运行智能体流式处理
-	err := m.agentManager.RunAgentStream(inbound, func(chunk react.StreamChunk) error {
-		if chunk.Content != "" || chunk.Reasoning != "" {
-			data := map[string]interface{}{
-				"content": chunk.Content,
-			}
-			if chunk.Reasoning != "" {
-				data["reasoning"] = chunk.Reasoning
-			}
-			client.SendJSON(map[string]interface{}{
-				"type":      "chunk",
-				"data":      data,
-				"timestamp": time.Now().Unix(),
-			})
-		}
-
-		// Send end message when done
-		if chunk.Done {
-			client.SendJSON(map[string]interface{}{
-				"type":      "end",
-				"timestamp": time.Now().Unix(),
-			})
-		}
-
+	err := m.agentManager.RunAgentStream(m.inbound(client, msg), func(chunk react.StreamChunk) error {
+		m.emitChunk(client, msg, chunk)
 		return nil
 	})
 
@@ -369,13 +309,84 @@ func (m *Manager) ProcessStreamMessage(ctx context.Context, client *Client, msg
 			"error", err,
 			"client_id", client.ID,
 			"session_id", msg.SessionID)
-		sendStreamError("处理消息失败: " + err.Error())
+		client.Emit(EventError, msg.SessionID, ErrorPayload{Message: "处理消息失败: " + err.Error()})
 		return err
 	}
 
 	return nil
 }
 
+// inbound 将客户端消息转换为总线消息。
+func (m *Manager) inbound(client *Client, msg *ChatMessage) bus.InboundMessage {
+	return bus.InboundMessage{
+		Channel:   channelConsts.WEBSOCKET,
+		SessionID: msg.SessionID,
+		Sender:    bus.SenderInfo{ID: client.userID, Name: client.userID},
+		Text:      msg.Content,
+		Timestamp: time.Now(),
+	}
+}
+
+// emitChunk 将智能体流式数据块转换为事件发送给客户端。
+func (m *Manager) emitChunk(client *Client, msg *ChatMessage, chunk react.StreamChunk) {
+	sessionID := msg.SessionID
+	switch {
+	case chunk.Error != nil:
+		// 错误由调用方统一发送
+	case chunk.Done:
+		client.Emit(EventMessage, sessionID, MessagePayload{
+			Role:      consts.RoleAssistant.ToString(),
+			Content:   chunk.Content,
+			Iteration: chunk.Iteration,
+			Chunks:    channels.ClientChunks(chunk.Content, msg.MaxChunkLength),
+		})
+		usage := UsagePayload{Iterations: chunk.Iteration}
+		if st := chunk.Stats; st != nil {
+			usage.PromptTokens = st.PromptTokens
+			usage.CompletionTokens = st.CompletionTokens
+			usage.TotalTokens = st.TotalTokens
+			usage.Estimated = st.Estimated
+		}
+		client.Emit(EventUsage, sessionID, usage)
+		if chunk.Stats != nil {
+			client.Emit(EventStats, sessionID, chunk.Stats)
+		}
+	case chunk.Start:
+		client.Emit(EventIteration, sessionID, IterationPayload{
+			Iteration: chunk.Iteration,
+			WrapUp:    chunk.WrapUp,
+		})
+	case chunk.ToolOutput != "":
+		client.Emit(EventToolOutput, sessionID, ToolOutputPayload{
+			ID:        chunk.ToolCallID,
+			Name:      chunk.ToolName,
+			Stream:    chunk.ToolStream,
+			Content:   chunk.ToolOutput,
+			Iteration: chunk.Iteration,
+		})
+	case chunk.ToolResult != "":
+		client.Emit(EventToolResult, sessionID, ToolResultPayload{
+			ID:        chunk.ToolCallID,
+			Name:      chunk.ToolName,
+			Content:   chunk.ToolResult,
+			Iteration: chunk.Iteration,
+		})
+	case chunk.ToolName != "":
+		client.Emit(EventToolCall, sessionID, ToolCallPayload{
+			ID:        chunk.ToolCallID,
+			Name:      chunk.ToolName,
+			Arguments: chunk.ToolArgs,
+			Iteration: chunk.Iteration,
+		})
+	case chunk.Content != "" || chunk.Reasoning != "":
+		client.Emit(EventDelta, sessionID, DeltaPayload{
+			Content:   chunk.Content,
+			Reasoning: chunk.Reasoning,
+			Iteration: chunk.Iteration,
+		})
+	}
+}
+
 // QueueStatus represents the queue status.
 type QueueStatus struct {
 	Connections   int `json:"connections"`
@@ -389,6 +400,8 @@ type ChatMessage struct {
 	Content   string `json:"content"`
 	Stream    bool   `json:"stream,omitempty"`
 	Timestamp int64  `json:"timestamp,omitempty"`
+	// MaxChunkLength 客户端单条消息的最大字符数，设置后完整消息附带分段信息
+	MaxChunkLength int `json:"max_chunk_length,omitempty"`
 }
 
 // ChatResponse represents a chat response.
@@ -0,0 +1,196 @@
+package websocket
+
+import (
+	_ "embed"
+	"encoding/json"
+	"slices"
+
+	"icooclaw/pkg/channels"
+)
+
+// 协议版本。
+//
+// 客户端连接后发送 hello 帧协商版本与能力，协商成功后服务端只发送带类型的事件帧；
+// 未发送 hello 的客户端保持旧版 chunk/end 帧格式，保证已有客户端不受影响。
+const (
+	// ProtocolVersion 当前协议版本
+	ProtocolVersion = 1
+	// MinProtocolVersion 支持的最低协议版本
+	MinProtocolVersion = 1
+)
+
+// 事件类型
+const (
+	EventHello      = "hello"       // 握手
+	EventMessage    = "message"     // 完整消息
+	EventDelta      = "delta"       // 流式增量
+	EventIteration  = "iteration"   // 新一轮推理迭代开始
+	EventToolCall   = "tool_call"   // 工具调用
+	EventToolResult = "tool_result" // 工具结果
+	EventToolOutput = "tool_output" // 工具执行中的实时输出
+	EventError      = "error"       // 错误
+	EventUsage      = "usage"       // 用量统计
+	EventStats      = "stats"       // 本轮统计，完整消息之后下发
+	EventSystem     = "system"      // 系统通知
+)
+
+// 能力，客户端在 hello 中声明需要的能力，未声明的事件不会下发
+const (
+	CapReasoning = "reasoning" // 推理过程增量
+	CapTools     = "tools"     // 工具调用与结果事件
+	CapUsage     = "usage"     // 用量统计事件
+	CapSystem    = "system"    // 系统通知事件
+	CapStats     = "stats"     // 本轮统计事件
+)
+
+// serverCapabilities 服务端支持的能力
+var serverCapabilities = []string{CapReasoning, CapTools, CapUsage, CapSystem, CapStats}
+
+// eventCapability 事件所需的能力，不在表中的事件始终下发
+var eventCapability = map[string]string{
+	EventIteration:  CapTools,
+	EventToolCall:   CapTools,
+	EventToolResult: CapTools,
+	EventToolOutput: CapTools,
+	EventUsage:      CapUsage,
+	EventSystem:     CapSystem,
+	EventStats:      CapStats,
+}
+
+//go:embed protocol.schema.json
+var protocolSchema []byte
+
+// Event 事件帧，协议版本 >= 1 时智能体输出都使用该结构。
+// 客户端应忽略未知的事件类型和字段。
+type Event struct {
+	Type      string `json:"type"`                 // 事件类型
+	Version   int    `json:"version"`              // 协议版本
+	Seq       uint64 `json:"seq"`                  // 连接内递增序号
+	SessionID string `json:"session_id,omitempty"` // 会话ID
+	Data      any    `json:"data,omitempty"`       // 事件数据
+	Timestamp int64  `json:"timestamp"`            // 时间戳
+}
+
+// HelloRequest 客户端握手帧
+type HelloRequest struct {
+	Type         string   `json:"type"`
+	Version      int      `json:"version"`      // 客户端支持的最高协议版本
+	Capabilities []string `json:"capabilities"` // 需要的能力，为空表示全部
+	Client       string   `json:"client"`       // 客户端标识，仅用于日志
+}
+
+// HelloPayload 服务端握手响应
+type HelloPayload struct {
+	Version      int      `json:"version"`      // 协商后的协议版本
+	MinVersion   int      `json:"min_version"`  // 服务端支持的最低版本
+	MaxVersion   int      `json:"max_version"`  // 服务端支持的最高版本
+	Capabilities []string `json:"capabilities"` // 协商后的能力
+	ClientID     string   `json:"client_id"`    // 连接ID
+	SessionID    string   `json:"session_id"`   // 默认会话ID
+}
+
+// MessagePayload 完整消息，一轮对话结束时下发
+type MessagePayload struct {
+	Role      string                  `json:"role"`
+	Content   string                  `json:"content"`
+	Iteration int                     `json:"iteration,omitempty"`
+	Chunks    []channels.MessageChunk `json:"chunks,omitempty"` // 按 max_chunk_length 切分的分段，内容超长时才有
+}
+
+// DeltaPayload 流式增量
+type DeltaPayload struct {
+	Content   string `json:"content,omitempty"`
+	Reasoning string `json:"reasoning,omitempty"`
+	Iteration int    `json:"iteration,omitempty"`
+}
+
+// IterationPayload 推理迭代开始，之后的增量、工具调用和结果都属于该迭代
+type IterationPayload struct {
+	Iteration int  `json:"iteration"`
+	WrapUp    bool `json:"wrap_up,omitempty"` // 时间预算用尽，本次迭代不再调用工具
+}
+
+// ToolCallPayload 工具调用
+type ToolCallPayload struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+	Iteration int    `json:"iteration,omitempty"`
+}
+
+// ToolResultPayload 工具结果
+type ToolResultPayload struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Content   string `json:"content"`
+	Iteration int    `json:"iteration,omitempty"`
+}
+
+// ToolOutputPayload 工具实时输出片段，同一工具调用的片段按顺序拼接即为完整输出
+type ToolOutputPayload struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Stream    string `json:"stream,omitempty"` // stdout 或 stderr
+	Content   string `json:"content"`
+	Iteration int    `json:"iteration,omitempty"`
+}
+
+// ErrorPayload 错误
+type ErrorPayload struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// UsagePayload 用量统计
+type UsagePayload struct {
+	PromptTokens     int  `json:"prompt_tokens,omitempty"`
+	CompletionTokens int  `json:"completion_tokens,omitempty"`
+	TotalTokens      int  `json:"total_tokens,omitempty"`
+	Iterations       int  `json:"iterations"`
+	Estimated        bool `json:"estimated,omitempty"` // token 数按字数估算，提供商未返回实际用量
+}
+
+// SystemPayload 系统通知
+type SystemPayload struct {
+	Message string         `json:"message"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// ProtocolInfo 协议说明，供客户端发现版本和事件结构
+type ProtocolInfo struct {
+	Version      int             `json:"version"`
+	MinVersion   int             `json:"min_version"`
+	Capabilities []string        `json:"capabilities"`
+	Schema       json.RawMessage `json:"schema"`
+}
+
+// GetProtocolInfo 返回协议说明和 JSON Schema。
+func GetProtocolInfo() *ProtocolInfo {
+	return &ProtocolInfo{
+		Version:      ProtocolVersion,
+		MinVersion:   MinProtocolVersion,
+		Capabilities: slices.Clone(serverCapabilities),
+		Schema:       protocolSchema,
+	}
+}
+
+// negotiate 根据客户端 hello 协商协议版本与能力。
+// 客户端版本低于最低版本时返回 ok=false。
+func negotiate(req HelloRequest) (version int, caps []string, ok bool) {
+	version = min(req.Version, ProtocolVersion)
+	if version < MinProtocolVersion {
+		return 0, nil, false
+	}
+
+	if len(req.Capabilities) == 0 {
+		return version, slices.Clone(serverCapabilities), true
+	}
+
+	caps = make([]string, 0, len(req.Capabilities))
+	for _, c := range serverCapabilities {
+		if slices.Contains(req.Capabilities, c) {
+			caps = append(caps, c)
+		}
+	}
+	return version, caps, true
+}
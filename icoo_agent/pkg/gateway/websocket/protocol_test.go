@@ -0,0 +1,133 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"icooclaw/pkg/agent/react"
+)
+
+func newTestClient() *Client {
+	c := &Client{
+		ID:        "client-1",
+		send:      make(chan []byte, 16),
+		sessionID: "session-1",
+		logger:    slog.Default(),
+	}
+	c.connected.Store(true)
+	return c
+}
+
+func readFrame(t *testing.T, c *Client) map[string]any {
+	t.Helper()
+	select {
+	case data := <-c.send:
+		var frame map[string]any
+		if err := json.Unmarshal(data, &frame); err != nil {
+			t.Fatalf("invalid frame %s: %v", data, err)
+		}
+		return frame
+	default:
+		t.Fatal("no frame sent")
+		return nil
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      HelloRequest
+		wantVer  int
+		wantCaps []string
+		wantOK   bool
+	}{
+		{"all capabilities", HelloRequest{Version: 1}, 1, serverCapabilities, true},
+		{"newer client", HelloRequest{Version: 9, Capabilities: []string{CapTools}}, ProtocolVersion, []string{CapTools}, true},
+		{"unknown capability", HelloRequest{Version: 1, Capabilities: []string{"video", CapUsage}}, 1, []string{CapUsage}, true},
+		{"too old", HelloRequest{Version: 0}, 0, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ver, caps, ok := negotiate(tt.req)
+			if ok != tt.wantOK || ver != tt.wantVer || !slices.Equal(caps, tt.wantCaps) {
+				t.Errorf("negotiate() = %d, %v, %v; want %d, %v, %v", ver, caps, ok, tt.wantVer, tt.wantCaps, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestClient_EmitLegacy(t *testing.T) {
+	c := newTestClient()
+
+	c.Emit(EventDelta, "s", DeltaPayload{Content: "你"})
+	if frame := readFrame(t, c); frame["type"] != "chunk" {
+		t.Errorf("delta frame type = %v, want chunk", frame["type"])
+	}
+
+	c.Emit(EventMessage, "s", MessagePayload{Role: "assistant", Content: "你好"})
+	if frame := readFrame(t, c); frame["type"] != "chunk" {
+		t.Errorf("message frame type = %v, want chunk", frame["type"])
+	}
+	if frame := readFrame(t, c); frame["type"] != "end" {
+		t.Errorf("message frame type = %v, want end", frame["type"])
+	}
+
+	if c.Emit(EventToolCall, "s", ToolCallPayload{Name: "grep"}) {
+		t.Error("legacy client should not receive tool_call events")
+	}
+}
+
+func TestClient_EmitNegotiated(t *testing.T) {
+	c := newTestClient()
+	c.handleHello([]byte(`{"type":"hello","version":1,"capabilities":["tools"]}`))
+
+	hello := readFrame(t, c)
+	if hello["type"] != EventHello || hello["seq"] != float64(1) {
+		t.Fatalf("unexpected hello frame: %v", hello)
+	}
+
+	c.Emit(EventToolCall, "s", ToolCallPayload{Name: "grep"})
+	frame := readFrame(t, c)
+	if frame["type"] != EventToolCall || frame["version"] != float64(1) || frame["seq"] != float64(2) {
+		t.Errorf("unexpected tool_call frame: %v", frame)
+	}
+
+	if c.Emit(EventUsage, "s", UsagePayload{Iterations: 1}) {
+		t.Error("usage event should be filtered without usage capability")
+	}
+	if c.Emit(EventDelta, "s", DeltaPayload{Reasoning: "思考中"}) {
+		t.Error("reasoning-only delta should be filtered without reasoning capability")
+	}
+}
+
+func TestManager_EmitChunk(t *testing.T) {
+	m := &Manager{}
+	c := newTestClient()
+	c.handleHello([]byte(`{"type":"hello","version":1}`))
+	readFrame(t, c)
+
+	msg := &ChatMessage{SessionID: "s"}
+	m.emitChunk(c, msg, react.StreamChunk{Start: true, Iteration: 2, WrapUp: true})
+	frame := readFrame(t, c)
+	data, _ := frame["data"].(map[string]any)
+	if frame["type"] != EventIteration || data["iteration"] != float64(2) || data["wrap_up"] != true {
+		t.Errorf("unexpected iteration frame: %v", frame)
+	}
+
+	m.emitChunk(c, msg, react.StreamChunk{Content: "好", Done: true, Iteration: 2,
+		Stats: &react.TurnStats{Iterations: 2, PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12, Estimated: true}})
+	if frame := readFrame(t, c); frame["type"] != EventMessage {
+		t.Errorf("unexpected message frame: %v", frame)
+	}
+	frame = readFrame(t, c)
+	data, _ = frame["data"].(map[string]any)
+	if frame["type"] != EventUsage || data["total_tokens"] != float64(12) || data["estimated"] != true || data["iterations"] != float64(2) {
+		t.Errorf("unexpected usage frame: %v", frame)
+	}
+	if frame := readFrame(t, c); frame["type"] != EventStats {
+		t.Errorf("unexpected stats frame: %v", frame)
+	}
+}
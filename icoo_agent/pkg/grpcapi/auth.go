@@ -0,0 +1,98 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"icooclaw/pkg/gateway/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MethodScopes 启用认证时各服务所需的权限范围，与 HTTP 网关的 RouteScopes 一致：
+// 聊天需要 chat 权限，未列出的服务（工具、会话、记忆和反射）需要 admin 权限。
+var MethodScopes = []auth.Route{
+	{Prefix: "/icooclaw.v1.ChatService", Scope: auth.ScopeChat},
+}
+
+// WithAuth 要求调用携带 API 密钥或 JWT，凭证放在 authorization（Bearer）或 x-api-key 元数据中。
+func (s *Server) WithAuth(a *auth.Authenticator) *Server {
+	s.auth = a
+	return s
+}
+
+// methodScope 返回方法所需的权限范围。
+func methodScope(method string) string {
+	for _, route := range MethodScopes {
+		if strings.HasPrefix(method, route.Prefix+"/") {
+			return route.Scope
+		}
+	}
+	return auth.ScopeAdmin
+}
+
+// authenticate 校验元数据中的凭证和方法所需的权限，返回携带调用方的上下文。未启用认证时原样返回。
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if s.auth == nil {
+		return ctx, nil
+	}
+	logger := s.logger.With("name", "【gRPC服务】")
+
+	id, err := s.auth.AuthenticateCredential(credential(ctx))
+	if err != nil {
+		logger.Warn("认证失败", "method", method, "error", err)
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	scope := methodScope(method)
+	if !id.Allows(scope) {
+		logger.Warn("权限不足", "method", method, "subject", id.Subject, "scope", scope)
+		return nil, status.Errorf(codes.PermissionDenied, "需要 %s 权限", scope)
+	}
+	return auth.NewContext(ctx, id), nil
+}
+
+// credential 从 authorization 或 x-api-key 元数据读取凭证。
+func credential(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if scheme, token, ok := strings.Cut(v, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	if keys := md.Get("x-api-key"); len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// authStream 使用携带调用方的上下文的服务端流。
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context 返回携带调用方的上下文。
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+// unaryAuthInterceptor 在处理一元调用前校验凭证。
+func (s *Server) unaryAuthInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamAuthInterceptor 在处理流式调用前校验凭证。
+func (s *Server) streamAuthInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+}
@@ -0,0 +1,153 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/bus"
+	channelConsts "icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/gateway/auth"
+	"icooclaw/pkg/grpcapi/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chatService 对话服务，对接智能体管理器。
+type chatService struct {
+	pb.UnimplementedChatServiceServer
+	s *Server
+}
+
+// inbound 将请求转换为入站消息，渠道默认为 websocket，与 REST 接口共享会话。
+// 已认证的调用以认证身份作为发送者，忽略请求中的 user_id。
+func (c *chatService) inbound(ctx context.Context, req *pb.ChatRequest) (bus.InboundMessage, error) {
+	if req.GetContent() == "" {
+		return bus.InboundMessage{}, status.Error(codes.InvalidArgument, "内容不能为空")
+	}
+	if req.GetSessionId() == "" {
+		return bus.InboundMessage{}, status.Error(codes.InvalidArgument, "会话ID不能为空")
+	}
+	if c.s.agentManager == nil {
+		return bus.InboundMessage{}, status.Error(codes.Unavailable, "未配置智能体")
+	}
+
+	channel := req.GetChannel()
+	if channel == "" {
+		channel = channelConsts.WEBSOCKET
+	}
+	userID := req.GetUserId()
+	if id := auth.FromContext(ctx); id != nil {
+		userID = id.Subject
+	} else if userID == "" {
+		userID = "grpc"
+	}
+
+	return bus.InboundMessage{
+		Channel:   channel,
+		SessionID: req.GetSessionId(),
+		Sender:    bus.SenderInfo{ID: userID, Name: userID},
+		Text:      req.GetContent(),
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// Chat 发送消息并等待完整回复。
+func (c *chatService) Chat(ctx context.Context, req *pb.ChatRequest) (*pb.ChatResponse, error) {
+	msg, err := c.inbound(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.s.agentManager.RunAgent(msg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "处理聊天失败: %v", err)
+	}
+
+	return &pb.ChatResponse{
+		SessionId: req.GetSessionId(),
+		Content:   reply,
+		Timestamp: time.Now().Unix(),
+	}, nil
+}
+
+// ChatStream 发送消息并以事件流返回回复。
+// 客户端断开后回调返回错误，智能体随之停止输出。
+func (c *chatService) ChatStream(req *pb.ChatRequest, stream pb.ChatService_ChatStreamServer) error {
+	msg, err := c.inbound(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	var seq uint64
+	err = c.s.agentManager.RunAgentStream(msg, func(chunk react.StreamChunk) error {
+		if err := stream.Context().Err(); err != nil {
+			return err
+		}
+
+		for _, ev := range chunkEvents(chunk) {
+			seq++
+			ev.SessionId = req.GetSessionId()
+			ev.Seq = seq
+			ev.Timestamp = time.Now().Unix()
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if stream.Context().Err() != nil {
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+		return status.Errorf(codes.Internal, "处理消息失败: %v", err)
+	}
+	return nil
+}
+
+// chunkEvents 将智能体流式数据块转换为事件，映射规则与 WebSocket 协议一致。
+func chunkEvents(chunk react.StreamChunk) []*pb.ChatEvent {
+	iteration := int32(chunk.Iteration)
+
+	switch {
+	case chunk.Error != nil:
+		// 错误由 RunAgentStream 返回，作为 gRPC 状态下发
+		return nil
+	case chunk.Done:
+		return []*pb.ChatEvent{
+			{Event: &pb.ChatEvent_Message{Message: &pb.Reply{
+				Role:      consts.RoleAssistant.ToString(),
+				Content:   chunk.Content,
+				Iteration: iteration,
+			}}},
+			{Event: &pb.ChatEvent_Usage{Usage: &pb.Usage{Iterations: iteration}}},
+		}
+	case chunk.ToolOutput != "":
+		// gRPC 协议暂无实时输出事件，完整结果仍通过 tool_result 下发
+		return nil
+	case chunk.ToolResult != "":
+		return []*pb.ChatEvent{{Event: &pb.ChatEvent_ToolResult{ToolResult: &pb.ToolResult{
+			Id:        chunk.ToolCallID,
+			Name:      chunk.ToolName,
+			Content:   chunk.ToolResult,
+			Iteration: iteration,
+		}}}}
+	case chunk.ToolName != "":
+		return []*pb.ChatEvent{{Event: &pb.ChatEvent_ToolCall{ToolCall: &pb.ToolCall{
+			Id:        chunk.ToolCallID,
+			Name:      chunk.ToolName,
+			Arguments: chunk.ToolArgs,
+			Iteration: iteration,
+		}}}}
+	case chunk.Content != "" || chunk.Reasoning != "":
+		return []*pb.ChatEvent{{Event: &pb.ChatEvent_Delta{Delta: &pb.Delta{
+			Content:   chunk.Content,
+			Reasoning: chunk.Reasoning,
+			Iteration: iteration,
+		}}}}
+	default:
+		return nil
+	}
+}
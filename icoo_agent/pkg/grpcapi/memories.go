@@ -0,0 +1,97 @@
+package grpcapi
+
+import (
+	"context"
+
+	"icooclaw/pkg/grpcapi/pb"
+	"icooclaw/pkg/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// memoryService 记忆管理服务。
+type memoryService struct {
+	pb.UnimplementedMemoryServiceServer
+	s *Server
+}
+
+func toPBMemory(m *storage.Memory) *pb.Memory {
+	return &pb.Memory{
+		Id:        m.ID,
+		SessionId: m.SessionID,
+		Role:      m.Role,
+		Content:   m.Content,
+		Metadata:  m.Metadata,
+		Pinned:    m.Pinned,
+		CreatedAt: m.CreatedAt.Unix(),
+	}
+}
+
+// ListMemories 分页查询记忆，设置 query 时按内容搜索。
+func (ms *memoryService) ListMemories(ctx context.Context, req *pb.ListMemoriesRequest) (*pb.ListMemoriesResponse, error) {
+	query := &storage.QueryMemory{
+		Page:      storage.Page{Page: int(req.GetPage()), Size: int(req.GetSize())},
+		SessionID: req.GetSessionId(),
+		Role:      req.GetRole(),
+		Query:     req.GetQuery(),
+	}
+
+	var (
+		records []*storage.Memory
+		total   int64
+	)
+	if query.Query != "" {
+		memories, err := ms.s.storage.Memory().Search(query)
+		if err != nil {
+			return nil, toStatus(err, "搜索记忆失败")
+		}
+		records, total = memories, int64(len(memories))
+	} else {
+		res, err := ms.s.storage.Memory().Page(query)
+		if err != nil {
+			return nil, toStatus(err, "查询记忆失败")
+		}
+		for i := range res.Records {
+			records = append(records, &res.Records[i])
+		}
+		total = res.Page.Total
+	}
+
+	out := make([]*pb.Memory, 0, len(records))
+	for _, m := range records {
+		out = append(out, toPBMemory(m))
+	}
+	return &pb.ListMemoriesResponse{Memories: out, Total: total}, nil
+}
+
+// CreateMemory 创建记忆。
+func (ms *memoryService) CreateMemory(ctx context.Context, req *pb.CreateMemoryRequest) (*pb.Memory, error) {
+	if req.GetContent() == "" {
+		return nil, status.Error(codes.InvalidArgument, "记忆内容不能为空")
+	}
+
+	m := &storage.Memory{
+		SessionID: req.GetSessionId(),
+		Role:      req.GetRole(),
+		Content:   req.GetContent(),
+		Metadata:  req.GetMetadata(),
+		Pinned:    req.GetPinned(),
+	}
+	if err := ms.s.storage.Memory().Save(m); err != nil {
+		return nil, toStatus(err, "创建记忆失败")
+	}
+	return toPBMemory(m), nil
+}
+
+// DeleteMemory 删除单条记忆。
+func (ms *memoryService) DeleteMemory(ctx context.Context, req *pb.DeleteMemoryRequest) (*pb.DeleteMemoryResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "记忆ID不能为空")
+	}
+
+	if err := ms.s.storage.Memory().DeleteByID(req.GetId()); err != nil {
+		return nil, toStatus(err, "删除记忆失败")
+	}
+	return &pb.DeleteMemoryResponse{}, nil
+}
@@ -0,0 +1,4 @@
+// Package pb contains the generated gRPC and protobuf code for the icooclaw API.
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative icooclaw.proto
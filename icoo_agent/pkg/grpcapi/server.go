@@ -0,0 +1,161 @@
+// Package grpcapi serves the icooclaw API over gRPC.
+//
+// 服务与 HTTP 网关共用存储、工具注册表和智能体管理器，
+// 流式接口直接对接 AgentManager.RunAgentStream，事件类型与 WebSocket 协议保持一致。
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+
+	"icooclaw/pkg/agent"
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/gateway/auth"
+	"icooclaw/pkg/grpcapi/pb"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Config gRPC 服务配置。
+type Config struct {
+	// Addr 监听地址
+	Addr string
+	// TLSCertFile/TLSKeyFile 证书和私钥，为空时使用明文
+	TLSCertFile string
+	TLSKeyFile  string
+	// Reflection 是否注册服务反射
+	Reflection bool
+}
+
+// Server gRPC 服务。
+type Server struct {
+	cfg          *Config
+	logger       *slog.Logger
+	storage      *storage.Storage
+	agentManager *agent.AgentManager
+	tools        *tools.Registry
+	auth         *auth.Authenticator
+	server       *grpc.Server
+}
+
+// NewServer 创建 gRPC 服务。
+func NewServer(
+	cfg *Config,
+	logger *slog.Logger,
+	store *storage.Storage,
+	agentManager *agent.AgentManager,
+	registry *tools.Registry,
+) (*Server, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	s := &Server{
+		cfg:          cfg,
+		logger:       logger,
+		storage:      store,
+		agentManager: agentManager,
+		tools:        registry,
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryAuthInterceptor, s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamAuthInterceptor, s.streamInterceptor),
+	}
+	if cfg.TLSCertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	s.server = grpc.NewServer(opts...)
+	pb.RegisterChatServiceServer(s.server, &chatService{s: s})
+	pb.RegisterSessionServiceServer(s.server, &sessionService{s: s})
+	pb.RegisterToolServiceServer(s.server, &toolService{s: s})
+	pb.RegisterMemoryServiceServer(s.server, &memoryService{s: s})
+	if cfg.Reflection {
+		reflection.Register(s.server)
+	}
+
+	return s, nil
+}
+
+// Start 启动 gRPC 服务，阻塞直到服务停止。
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	s.logger.With("name", "【gRPC服务】").Info("已启动", "addr", s.cfg.Addr, "tls", s.cfg.TLSCertFile != "")
+	return s.server.Serve(ln)
+}
+
+// Shutdown 优雅关闭服务，ctx 超时后强制关闭。
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.logger.With("name", "【gRPC服务】").Info("正在关闭")
+
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// unaryInterceptor 记录失败的调用并捕获 panic。
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.With("name", "【gRPC服务】").Error("处理请求时发生 panic", "method", info.FullMethod, "panic", r)
+			err = status.Error(codes.Internal, "服务内部错误")
+		}
+	}()
+
+	resp, err = handler(ctx, req)
+	if err != nil {
+		s.logger.With("name", "【gRPC服务】").Error("请求失败", "method", info.FullMethod, "error", err)
+	}
+	return resp, err
+}
+
+// streamInterceptor 记录失败的流式调用并捕获 panic。
+func (s *Server) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.With("name", "【gRPC服务】").Error("处理流式请求时发生 panic", "method", info.FullMethod, "panic", r)
+			err = status.Error(codes.Internal, "服务内部错误")
+		}
+	}()
+
+	err = handler(srv, ss)
+	if err != nil {
+		s.logger.With("name", "【gRPC服务】").Error("流式请求失败", "method", info.FullMethod, "error", err)
+	}
+	return err
+}
+
+// toStatus 将存储层错误转换为 gRPC 状态。
+func toStatus(err error, msg string) error {
+	if errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+		return status.Error(codes.NotFound, "记录不存在")
+	}
+	return status.Errorf(codes.Internal, "%s: %v", msg, err)
+}
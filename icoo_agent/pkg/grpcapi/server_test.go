@@ -0,0 +1,217 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/gateway/auth"
+	"icooclaw/pkg/grpcapi/pb"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestConn(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	return newTestConnWithAuth(t, nil)
+}
+
+// newTestConnWithAuth 连接启用了认证的服务，a 为 nil 时不认证。
+func newTestConnWithAuth(t *testing.T, a *auth.Authenticator) *grpc.ClientConn {
+	t.Helper()
+
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "grpc.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	registry := tools.NewRegistry()
+	registry.Register(builtin.NewDateTimeTool())
+
+	srv, err := NewServer(&Config{}, nil, store, nil, registry)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	srv.WithAuth(a)
+
+	ln := bufconn.Listen(1 << 20)
+	go srv.server.Serve(ln)
+	t.Cleanup(srv.server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSessionService(t *testing.T) {
+	client := pb.NewSessionServiceClient(newTestConn(t))
+	ctx := context.Background()
+
+	created, err := client.CreateSession(ctx, &pb.CreateSessionRequest{SessionId: "s1", Title: "测试"})
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	if created.GetChannel() != "websocket" {
+		t.Errorf("CreateSession() channel = %q, want websocket", created.GetChannel())
+	}
+
+	list, err := client.ListSessions(ctx, &pb.ListSessionsRequest{})
+	if err != nil || list.GetTotal() != 1 || list.GetSessions()[0].GetTitle() != "测试" {
+		t.Fatalf("ListSessions() = %v, %v", list, err)
+	}
+
+	_, err = client.GetSession(ctx, &pb.GetSessionRequest{Id: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetSession(missing) code = %v, want NotFound", status.Code(err))
+	}
+}
+
+func TestToolService(t *testing.T) {
+	client := pb.NewToolServiceClient(newTestConn(t))
+	ctx := context.Background()
+
+	list, err := client.ListTools(ctx, &pb.ListToolsRequest{})
+	if err != nil || len(list.GetTools()) != 1 {
+		t.Fatalf("ListTools() = %v, %v", list, err)
+	}
+
+	_, err = client.CallTool(ctx, &pb.CallToolRequest{Name: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("CallTool(missing) code = %v, want NotFound", status.Code(err))
+	}
+
+	_, err = client.CallTool(ctx, &pb.CallToolRequest{Name: list.GetTools()[0].GetName(), ArgumentsJson: "{"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CallTool(bad args) code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestChatService_NoAgent(t *testing.T) {
+	client := pb.NewChatServiceClient(newTestConn(t))
+
+	_, err := client.Chat(context.Background(), &pb.ChatRequest{SessionId: "s1", Content: "你好"})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Chat() code = %v, want Unavailable", status.Code(err))
+	}
+
+	_, err = client.Chat(context.Background(), &pb.ChatRequest{SessionId: "s1"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Chat(empty) code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestAuth(t *testing.T) {
+	a, err := auth.New(auth.Options{Keys: []auth.APIKey{
+		{Name: "bot", Key: "chat-key"},
+		{Name: "ops", Key: "admin-key", Scopes: []string{auth.ScopeAdmin}},
+	}})
+	if err != nil {
+		t.Fatalf("auth.New() error = %v", err)
+	}
+	conn := newTestConnWithAuth(t, a)
+	tools, chat := pb.NewToolServiceClient(conn), pb.NewChatServiceClient(conn)
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+key)
+	}
+	call := &pb.CallToolRequest{Name: "missing"}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		call func(ctx context.Context) error
+		want codes.Code
+	}{
+		{"call tool without credential", context.Background(), func(ctx context.Context) error {
+			_, err := tools.CallTool(ctx, call)
+			return err
+		}, codes.Unauthenticated},
+		{"call tool with invalid key", withKey("wrong"), func(ctx context.Context) error {
+			_, err := tools.CallTool(ctx, call)
+			return err
+		}, codes.Unauthenticated},
+		{"call tool with chat scope", withKey("chat-key"), func(ctx context.Context) error {
+			_, err := tools.CallTool(ctx, call)
+			return err
+		}, codes.PermissionDenied},
+		{"call tool with admin scope", withKey("admin-key"), func(ctx context.Context) error {
+			_, err := tools.CallTool(ctx, call)
+			return err
+		}, codes.NotFound},
+		{"chat with chat scope", withKey("chat-key"), func(ctx context.Context) error {
+			_, err := chat.Chat(ctx, &pb.ChatRequest{SessionId: "s1", Content: "你好"})
+			return err
+		}, codes.Unavailable},
+		{"chat stream without credential", context.Background(), func(ctx context.Context) error {
+			stream, err := chat.ChatStream(ctx, &pb.ChatRequest{SessionId: "s1", Content: "你好"})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call(tt.ctx)); got != tt.want {
+				t.Errorf("code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkEvents(t *testing.T) {
+	tests := []struct {
+		name  string
+		chunk react.StreamChunk
+		want  []string
+	}{
+		{"delta", react.StreamChunk{Content: "你"}, []string{"delta"}},
+		{"tool call", react.StreamChunk{ToolName: "grep", ToolArgs: "{}"}, []string{"tool_call"}},
+		{"tool result", react.StreamChunk{ToolName: "grep", ToolResult: "ok"}, []string{"tool_result"}},
+		{"done", react.StreamChunk{Content: "你好", Done: true, Iteration: 2}, []string{"message", "usage"}},
+		{"empty", react.StreamChunk{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := chunkEvents(tt.chunk)
+			if len(events) != len(tt.want) {
+				t.Fatalf("chunkEvents() returned %d events, want %d", len(events), len(tt.want))
+			}
+			for i, ev := range events {
+				var got string
+				switch ev.GetEvent().(type) {
+				case *pb.ChatEvent_Delta:
+					got = "delta"
+				case *pb.ChatEvent_ToolCall:
+					got = "tool_call"
+				case *pb.ChatEvent_ToolResult:
+					got = "tool_result"
+				case *pb.ChatEvent_Message:
+					got = "message"
+				case *pb.ChatEvent_Usage:
+					got = "usage"
+				}
+				if got != tt.want[i] {
+					t.Errorf("event[%d] = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
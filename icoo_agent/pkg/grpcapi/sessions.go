@@ -0,0 +1,168 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	channelConsts "icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/grpcapi/pb"
+	"icooclaw/pkg/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sessionService 会话管理服务。
+type sessionService struct {
+	pb.UnimplementedSessionServiceServer
+	s *Server
+}
+
+func toPBSession(sess *storage.Session) *pb.Session {
+	return &pb.Session{
+		Id:         sess.ID,
+		Channel:    sess.Channel,
+		UserId:     sess.UserID,
+		Title:      sess.Title,
+		Summary:    sess.Summary,
+		Archived:   sess.Archived,
+		ParentId:   sess.ParentID,
+		LastActive: sess.LastActive.Unix(),
+		CreatedAt:  sess.CreatedAt.Unix(),
+	}
+}
+
+// ListSessions 分页查询会话。
+func (ss *sessionService) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	query := &storage.QuerySession{
+		Page:    storage.Page{Page: int(req.GetPage()), Size: int(req.GetSize())},
+		KeyWord: req.GetKeyword(),
+		Channel: req.GetChannel(),
+		UserID:  req.GetUserId(),
+	}
+	if query.Channel == "" {
+		query.Channel = channelConsts.WEBSOCKET
+	}
+	if req.Archived != nil {
+		archived := req.GetArchived()
+		query.Archived = &archived
+	}
+
+	res, err := ss.s.storage.Session().Page(query)
+	if err != nil {
+		return nil, toStatus(err, "查询会话失败")
+	}
+
+	sessions := make([]*pb.Session, 0, len(res.Records))
+	for _, sess := range res.Records {
+		sessions = append(sessions, toPBSession(sess))
+	}
+	return &pb.ListSessionsResponse{Sessions: sessions, Total: res.Page.Total}, nil
+}
+
+// GetSession 获取会话。
+func (ss *sessionService) GetSession(ctx context.Context, req *pb.GetSessionRequest) (*pb.Session, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "会话ID不能为空")
+	}
+
+	sess, err := ss.s.storage.Session().Get(req.GetId())
+	if err != nil {
+		return nil, toStatus(err, "获取会话失败")
+	}
+	return toPBSession(sess), nil
+}
+
+// CreateSession 创建会话。
+func (ss *sessionService) CreateSession(ctx context.Context, req *pb.CreateSessionRequest) (*pb.Session, error) {
+	sess := &storage.Session{
+		Channel: req.GetChannel(),
+		UserID:  req.GetUserId(),
+		Title:   req.GetTitle(),
+	}
+	if sess.Channel == "" {
+		sess.Channel = channelConsts.WEBSOCKET
+	}
+	sess.ID = req.GetSessionId()
+	if sess.ID == "" {
+		sess.ID = fmt.Sprintf("session-%d", time.Now().UnixNano())
+	}
+
+	if err := ss.s.storage.Session().Save(sess); err != nil {
+		return nil, toStatus(err, "创建会话失败")
+	}
+	return toPBSession(sess), nil
+}
+
+// DeleteSession 删除会话。
+func (ss *sessionService) DeleteSession(ctx context.Context, req *pb.DeleteSessionRequest) (*pb.DeleteSessionResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "会话ID不能为空")
+	}
+
+	if err := ss.s.storage.Session().Delete(req.GetId()); err != nil {
+		return nil, toStatus(err, "删除会话失败")
+	}
+	return &pb.DeleteSessionResponse{}, nil
+}
+
+// ResetSession 归档当前上下文并重新开始。
+func (ss *sessionService) ResetSession(ctx context.Context, req *pb.ResetSessionRequest) (*pb.ResetSessionResponse, error) {
+	if req.GetSessionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "会话ID不能为空")
+	}
+	channel := req.GetChannel()
+	if channel == "" {
+		channel = channelConsts.WEBSOCKET
+	}
+
+	res, err := ss.s.storage.ResetSession(channel, req.GetSessionId(), storage.ResetOptions{
+		KeepPinned:  req.GetKeepPinned(),
+		KeepSummary: req.GetKeepSummary(),
+	})
+	if err != nil {
+		return nil, toStatus(err, "重置会话失败")
+	}
+
+	return &pb.ResetSessionResponse{
+		SessionId:      res.SessionID,
+		ArchiveId:      res.ArchiveID,
+		Messages:       res.Messages,
+		Memories:       res.Memories,
+		KeptMemories:   res.KeptMemories,
+		SummaryCarried: res.SummaryCarried,
+	}, nil
+}
+
+// ListMessages 获取会话最近的历史消息。
+func (ss *sessionService) ListMessages(ctx context.Context, req *pb.ListMessagesRequest) (*pb.ListMessagesResponse, error) {
+	if req.GetSessionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "会话ID不能为空")
+	}
+	channel := req.GetChannel()
+	if channel == "" {
+		channel = channelConsts.WEBSOCKET
+	}
+
+	messages, err := ss.s.storage.Message().Get(consts.GetSessionKey(channel, req.GetSessionId()), int(req.GetLimit()))
+	if err != nil {
+		return nil, toStatus(err, "获取消息失败")
+	}
+
+	out := make([]*pb.Message, 0, len(messages))
+	for _, m := range messages {
+		out = append(out, &pb.Message{
+			Id:         m.ID,
+			SessionId:  m.SessionID,
+			Role:       m.Role.ToString(),
+			Content:    m.Content,
+			ToolName:   m.ToolName,
+			ToolArgs:   m.ToolArgs,
+			ToolResult: m.ToolResult,
+			CreatedAt:  m.CreatedAt.Unix(),
+		})
+	}
+	return &pb.ListMessagesResponse{Messages: out}, nil
+}
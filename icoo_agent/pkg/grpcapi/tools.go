@@ -0,0 +1,66 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+
+	"icooclaw/pkg/grpcapi/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toolService 工具查询与调用服务。
+type toolService struct {
+	pb.UnimplementedToolServiceServer
+	s *Server
+}
+
+// ListTools 列出已注册的工具。
+func (ts *toolService) ListTools(ctx context.Context, req *pb.ListToolsRequest) (*pb.ListToolsResponse, error) {
+	if ts.s.tools == nil {
+		return &pb.ListToolsResponse{}, nil
+	}
+
+	list := ts.s.tools.List()
+	out := make([]*pb.Tool, 0, len(list))
+	for _, tool := range list {
+		params, err := json.Marshal(tool.Parameters())
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "序列化工具参数失败: %v", err)
+		}
+		out = append(out, &pb.Tool{
+			Name:           tool.Name(),
+			Description:    tool.Description(),
+			ParametersJson: string(params),
+		})
+	}
+	return &pb.ListToolsResponse{Tools: out}, nil
+}
+
+// CallTool 直接调用工具，不经过智能体。
+func (ts *toolService) CallTool(ctx context.Context, req *pb.CallToolRequest) (*pb.CallToolResponse, error) {
+	if ts.s.tools == nil {
+		return nil, status.Error(codes.Unavailable, "未配置工具注册表")
+	}
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "工具名称不能为空")
+	}
+	if !ts.s.tools.HasTool(req.GetName()) {
+		return nil, status.Errorf(codes.NotFound, "工具不存在: %s", req.GetName())
+	}
+
+	args := map[string]any{}
+	if raw := req.GetArgumentsJson(); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &args); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "工具参数格式错误: %v", err)
+		}
+	}
+
+	result := ts.s.tools.ExecuteWithContext(ctx, req.GetName(), args, req.GetChannel(), req.GetSessionId(), nil)
+	resp := &pb.CallToolResponse{Success: result.Success, Content: result.Content}
+	if result.Error != nil {
+		resp.Error = result.Error.Error()
+	}
+	return resp, nil
+}
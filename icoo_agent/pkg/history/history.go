@@ -0,0 +1,132 @@
+// Package history 将部署 icooclaw 之前的聊天记录（Telegram 导出的 JSON、Slack 导出的 zip）
+// 转换为会话和消息，导入后记忆检索、记忆合并和会话摘要都能使用这些历史上下文。
+package history
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+)
+
+// 导入来源
+const (
+	SourceTelegram = "telegram"
+	SourceSlack    = "slack"
+)
+
+// ImportedTag 导入的记忆带有的标签，另有来源名称作为第二个标签
+const ImportedTag = "imported"
+
+// idNamespace 生成导入记录 ID 的命名空间，同一条历史消息每次导入得到相同的 ID
+var idNamespace = uuid.MustParse("5b0f6b1e-7c1d-4b53-9a55-3f2f3c1e9d42")
+
+// Conversation 导出文件中的一个会话。
+type Conversation struct {
+	ID       string    `json:"id"`     // 来源中的会话 ID（Telegram chat id、Slack 频道 ID）
+	Title    string    `json:"title"`  // 会话名称
+	Direct   bool      `json:"direct"` // 是否单聊
+	Messages []Message `json:"messages"`
+}
+
+// Message 导出文件中的一条消息。
+type Message struct {
+	ID         string    `json:"id"` // 来源中的消息 ID，会话内唯一
+	SenderID   string    `json:"sender_id"`
+	SenderName string    `json:"sender_name"`
+	Text       string    `json:"text"`
+	Time       time.Time `json:"time"`
+}
+
+// Options 转换选项。
+type Options struct {
+	Source     string   // 来源，见 Source* 常量
+	Channel    string   // 导入到的渠道，默认与来源同名
+	Assistants []string // 发送者 ID 或名称在其中的消息作为助手消息
+	UserID     string   // 会话所属用户，默认为第一个非助手发送者
+	Memory     bool     // 同时将消息写入记忆，供记忆检索和合并使用
+}
+
+// Records converts a conversation to the session, messages and (with
+// opts.Memory) memories to store. Record IDs are derived from the source, so
+// importing the same export again produces the same records.
+func Records(conv *Conversation, opts Options) (*storage.Session, []*storage.Message, []*storage.Memory) {
+	channel := opts.Channel
+	if channel == "" {
+		channel = opts.Source
+	}
+	sessionKey := consts.GetSessionKey(channel, conv.ID)
+
+	msgs := slices.Clone(conv.Messages)
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].Time.Before(msgs[j].Time) })
+
+	sess := &storage.Session{
+		Model:   storage.Model{ID: conv.ID},
+		Channel: channel,
+		UserID:  opts.UserID,
+		Title:   conv.Title,
+	}
+
+	var messages []*storage.Message
+	var memories []*storage.Memory
+	for _, m := range msgs {
+		if m.Text == "" {
+			continue
+		}
+
+		role := consts.RoleUser
+		if slices.Contains(opts.Assistants, m.SenderID) || slices.Contains(opts.Assistants, m.SenderName) {
+			role = consts.RoleAssistant
+		} else if sess.UserID == "" {
+			sess.UserID = m.SenderID
+		}
+
+		// 群聊中保留发送者名称，区分不同成员的发言
+		content := m.Text
+		if !conv.Direct && role == consts.RoleUser && m.SenderName != "" {
+			content = m.SenderName + ": " + content
+		}
+
+		key := opts.Source + "/" + conv.ID + "/" + m.ID
+		metadata := mustMarshal(map[string]string{
+			"imported":    opts.Source,
+			"original_id": m.ID,
+			"sender_id":   m.SenderID,
+			"sender_name": m.SenderName,
+		})
+
+		messages = append(messages, &storage.Message{
+			Model:     storage.Model{ID: uuid.NewSHA1(idNamespace, []byte(key)).String(), CreatedAt: m.Time, UpdatedAt: m.Time},
+			SessionID: sessionKey,
+			Role:      role,
+			Content:   content,
+			Metadata:  metadata,
+		})
+		if opts.Memory {
+			memories = append(memories, &storage.Memory{
+				Model:     storage.Model{ID: uuid.NewSHA1(idNamespace, []byte("memory/"+key)).String(), CreatedAt: m.Time, UpdatedAt: m.Time},
+				SessionID: sessionKey,
+				Role:      role.ToString(),
+				Content:   content,
+				Metadata:  metadata,
+				Tags:      storage.StringArray{ImportedTag, opts.Source},
+			})
+		}
+	}
+
+	if len(messages) > 0 {
+		sess.CreatedAt = messages[0].CreatedAt
+		sess.LastActive = messages[len(messages)-1].CreatedAt
+	}
+	return sess, messages, memories
+}
+
+func mustMarshal(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,190 @@
+package history
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+)
+
+const telegramExport = `{
+  "about": "full export",
+  "chats": {"list": [{
+    "name": "Project",
+    "type": "private_supergroup",
+    "id": 1234567890,
+    "messages": [
+      {"id": 1, "type": "service", "date": "2024-03-01T09:00:00", "date_unixtime": "1709283600", "actor": "Alice", "action": "create_group"},
+      {"id": 2, "type": "message", "date": "2024-03-01T09:01:00", "date_unixtime": "1709283660", "from": "Alice", "from_id": "user1", "text": "Deadline is Friday"},
+      {"id": 3, "type": "message", "date": "2024-03-01T09:02:00", "date_unixtime": "1709283720", "from": "Helper", "from_id": "user99",
+       "text": ["Noted, see ", {"type": "link", "text": "https://example.com"}]},
+      {"id": 4, "type": "message", "date": "2024-03-01T09:03:00", "date_unixtime": "1709283780", "from": "Bob", "from_id": "user2", "text": "", "photo": "photos/1.jpg"}
+    ]
+  }]}
+}`
+
+func TestParseTelegram(t *testing.T) {
+	convs, err := ParseTelegram(strings.NewReader(telegramExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(convs) != 1 {
+		t.Fatalf("conversations = %d, want 1", len(convs))
+	}
+	conv := convs[0]
+	if conv.ID != "1234567890" || conv.Title != "Project" || conv.Direct {
+		t.Errorf("conversation = %+v", conv)
+	}
+	if len(conv.Messages) != 2 {
+		t.Fatalf("messages = %+v", conv.Messages)
+	}
+	if m := conv.Messages[1]; m.Text != "Noted, see https://example.com" || m.SenderID != "user99" || m.Time.Unix() != 1709283720 {
+		t.Errorf("message = %+v", m)
+	}
+
+	// 单个会话的导出
+	single := `{"name": "Alice", "type": "personal_chat", "id": 42, "messages": [{"id": 7, "type": "message", "date_unixtime": "1709283600", "from": "Alice", "from_id": "user1", "text": "hi"}]}`
+	convs, err = ParseTelegram(strings.NewReader(single))
+	if err != nil || len(convs) != 1 || !convs[0].Direct || convs[0].Messages[0].ID != "7" {
+		t.Fatalf("single chat = %+v, %v", convs, err)
+	}
+}
+
+func TestParseSlack(t *testing.T) {
+	files := map[string]string{
+		"users.json":    `[{"id": "U1", "name": "alice", "profile": {"display_name": "Alice"}}, {"id": "U2", "name": "bob", "real_name": "Bob B"}]`,
+		"channels.json": `[{"id": "C1", "name": "general"}]`,
+		"dms.json":      `[{"id": "D1", "members": ["U1", "U2"]}]`,
+		"general/2024-03-02.json": `[
+			{"type": "message", "user": "U2", "text": "thanks &lt;3", "ts": "1709370000.000200"}
+		]`,
+		"general/2024-03-01.json": `[
+			{"type": "message", "subtype": "channel_join", "user": "U2", "text": "<@U2> has joined the channel", "ts": "1709283500.000100"},
+			{"type": "message", "user": "U1", "text": "<@U2> see <https://example.com|the doc> in <#C1|general>", "ts": "1709283600.000100"}
+		]`,
+		"D1/2024-03-01.json": `[{"type": "message", "user": "U1", "text": "hello", "ts": "1709283600.000300"}]`,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	convs, err := ParseSlack(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(convs) != 2 {
+		t.Fatalf("conversations = %d, want 2", len(convs))
+	}
+
+	general := convs[0]
+	if general.ID != "C1" || general.Title != "general" || len(general.Messages) != 2 {
+		t.Fatalf("general = %+v", general)
+	}
+	if m := general.Messages[0]; m.Text != "@Bob B see the doc in #general" || m.SenderName != "Alice" || m.ID != "1709283600.000100" {
+		t.Errorf("first message = %+v", m)
+	}
+	if m := general.Messages[1]; m.Text != "thanks <3" {
+		t.Errorf("second message = %+v", m)
+	}
+
+	dm := convs[1]
+	if dm.ID != "D1" || !dm.Direct || dm.Title != "Alice, Bob B" || len(dm.Messages) != 1 {
+		t.Errorf("dm = %+v", dm)
+	}
+}
+
+func TestParseTranscript(t *testing.T) {
+	const sharegpt = `{"conversations": [
+		{"from": "system", "value": "你是助手"},
+		{"from": "human", "value": "天气"},
+		{"from": "function_call", "value": "{\"name\": \"weather\", \"arguments\": {}}"},
+		{"from": "observation", "value": "晴"},
+		{"from": "gpt", "value": "今天晴"}
+	]}`
+	start := time.Unix(1709283600, 0)
+	convs, err := ParseTranscript("sharegpt", "demo", strings.NewReader(sharegpt), start)
+	if err != nil {
+		t.Fatalf("ParseTranscript() error = %v", err)
+	}
+	conv := convs[0]
+	if conv.ID != "demo" || !conv.Direct || len(conv.Messages) != 2 {
+		t.Fatalf("conv = %+v", conv)
+	}
+
+	_, messages, _ := Records(conv, Options{Source: "sharegpt", Assistants: []string{TranscriptAssistant}})
+	if messages[0].Role != consts.RoleUser || messages[0].Content != "天气" {
+		t.Errorf("messages[0] = %+v", messages[0])
+	}
+	if messages[1].Role != consts.RoleAssistant || messages[1].Content != "今天晴" || !messages[1].CreatedAt.After(messages[0].CreatedAt) {
+		t.Errorf("messages[1] = %+v", messages[1])
+	}
+}
+
+func TestImportRecords(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	start := time.Unix(1709283600, 0)
+	conv := &Conversation{
+		ID:    "C1",
+		Title: "general",
+		Messages: []Message{
+			{ID: "2", SenderID: "B1", SenderName: "Helper", Text: "Noted", Time: start.Add(time.Minute)},
+			{ID: "1", SenderID: "U1", SenderName: "Alice", Text: "Deadline is Friday", Time: start},
+		},
+	}
+	opts := Options{Source: SourceSlack, Assistants: []string{"Helper"}, Memory: true}
+
+	sess, messages, memories := Records(conv, opts)
+	if sess.UserID != "U1" || !sess.LastActive.Equal(start.Add(time.Minute)) {
+		t.Errorf("session = %+v", sess)
+	}
+	if messages[0].Content != "Alice: Deadline is Friday" || messages[1].Role != consts.RoleAssistant {
+		t.Errorf("messages = %+v, %+v", messages[0], messages[1])
+	}
+
+	res, err := store.ImportHistory(sess, messages, memories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.SessionCreated || res.Messages != 2 || res.Memories != 2 {
+		t.Errorf("first import = %+v", res)
+	}
+
+	// 重复导入不产生新记录
+	sess, messages, memories = Records(conv, opts)
+	res, err = store.ImportHistory(sess, messages, memories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SessionCreated || res.Messages != 0 || res.Memories != 0 {
+		t.Errorf("second import = %+v", res)
+	}
+
+	stored, err := store.Message().Get(consts.GetSessionKey(SourceSlack, "C1"), 0)
+	if err != nil || len(stored) != 2 || !stored[1].CreatedAt.Equal(start) {
+		t.Fatalf("stored messages = %+v, %v", stored, err)
+	}
+	got, err := store.Session().GetBySessionID(SourceSlack, "C1")
+	if err != nil || got.Title != "general" || !got.LastActive.Equal(start.Add(time.Minute)) {
+		t.Errorf("stored session = %+v, %v", got, err)
+	}
+}
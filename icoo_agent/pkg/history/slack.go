@@ -0,0 +1,238 @@
+package history
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type slackUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RealName string `json:"real_name"`
+	Profile  struct {
+		DisplayName string `json:"display_name"`
+		RealName    string `json:"real_name"`
+	} `json:"profile"`
+}
+
+// displayName 按显示名、真实姓名、用户名的顺序取名称。
+func (u slackUser) displayName() string {
+	for _, name := range []string{u.Profile.DisplayName, u.Profile.RealName, u.RealName, u.Name} {
+		if name != "" {
+			return name
+		}
+	}
+	return u.ID
+}
+
+type slackChannel struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+type slackMessage struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	User     string `json:"user"`
+	BotID    string `json:"bot_id"`
+	Username string `json:"username"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+}
+
+// slackSubtypes 作为对话内容导入的消息子类型，加入、离开频道等系统消息被忽略
+var slackSubtypes = map[string]bool{
+	"":                 true,
+	"bot_message":      true,
+	"me_message":       true,
+	"thread_broadcast": true,
+	"file_share":       true,
+}
+
+var (
+	slackMentionRegex = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|[^>]*)?>`)
+	slackLinkRegex    = regexp.MustCompile(`<([^@!#>|][^>|]*)(?:\|([^>]*))?>`)
+	slackChannelRegex = regexp.MustCompile(`<#[A-Z0-9]+\|([^>]*)>`)
+)
+
+// ParseSlack parses a Slack workspace export zip. Public channels, private
+// channels, direct messages and group direct messages are read when their
+// listing file (channels.json, groups.json, dms.json, mpims.json) is present.
+func ParseSlack(r io.ReaderAt, size int64) ([]*Conversation, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("打开 Slack 导出文件失败: %w", err)
+	}
+
+	// 按目录归类每日消息文件
+	files := make(map[string]*zip.File)
+	dirs := make(map[string][]*zip.File)
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		files[f.Name] = f
+		if dir := path.Dir(f.Name); dir != "." {
+			dirs[dir] = append(dirs[dir], f)
+		}
+	}
+
+	var users []slackUser
+	if err := readZipJSON(files["users.json"], &users); err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.displayName()
+	}
+
+	var conversations []*Conversation
+	for _, listing := range []struct {
+		file   string
+		direct bool
+	}{
+		{"channels.json", false},
+		{"groups.json", false},
+		{"dms.json", true},
+		{"mpims.json", false},
+	} {
+		var channels []slackChannel
+		if err := readZipJSON(files[listing.file], &channels); err != nil {
+			return nil, err
+		}
+		for _, ch := range channels {
+			// 单聊的目录以 ID 命名，其他会话以名称命名
+			dir := ch.Name
+			if dir == "" {
+				dir = ch.ID
+			}
+			conv, err := parseSlackConversation(dirs[dir], names)
+			if err != nil {
+				return nil, fmt.Errorf("会话 %s: %w", dir, err)
+			}
+			conv.ID = ch.ID
+			conv.Title = ch.Name
+			conv.Direct = listing.direct
+			if conv.Title == "" {
+				conv.Title = slackMembers(ch.Members, names)
+			}
+			conversations = append(conversations, conv)
+		}
+	}
+
+	if len(conversations) == 0 {
+		return nil, fmt.Errorf("Slack 导出文件中没有会话")
+	}
+	return conversations, nil
+}
+
+// parseSlackConversation 读取一个会话目录下按日期命名的消息文件。
+func parseSlackConversation(files []*zip.File, names map[string]string) (*Conversation, error) {
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	conv := &Conversation{}
+	for _, f := range files {
+		if path.Ext(f.Name) != ".json" {
+			continue
+		}
+		var messages []slackMessage
+		if err := readZipJSON(f, &messages); err != nil {
+			return nil, err
+		}
+		for _, m := range messages {
+			if m.Type != "message" || !slackSubtypes[m.Subtype] {
+				continue
+			}
+			text := slackText(m.Text, names)
+			if text == "" {
+				continue
+			}
+
+			senderID, senderName := m.User, names[m.User]
+			if senderID == "" {
+				senderID = m.BotID
+			}
+			if senderName == "" {
+				senderName = m.Username
+			}
+			conv.Messages = append(conv.Messages, Message{
+				ID:         m.TS,
+				SenderID:   senderID,
+				SenderName: senderName,
+				Text:       text,
+				Time:       slackTime(m.TS),
+			})
+		}
+	}
+	return conv, nil
+}
+
+// slackText 将 Slack 标记还原为纯文本：@ 用户替换为名称，链接保留显示文字。
+func slackText(text string, names map[string]string) string {
+	text = slackMentionRegex.ReplaceAllStringFunc(text, func(m string) string {
+		id := slackMentionRegex.FindStringSubmatch(m)[1]
+		if name, ok := names[id]; ok {
+			return "@" + name
+		}
+		return "@" + id
+	})
+	text = slackChannelRegex.ReplaceAllString(text, "#$1")
+	text = slackLinkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		sub := slackLinkRegex.FindStringSubmatch(m)
+		if sub[2] != "" {
+			return sub[2]
+		}
+		return sub[1]
+	})
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+// slackTime 消息 ts 为 "秒.微秒"。
+func slackTime(ts string) time.Time {
+	sec, frac, _ := strings.Cut(ts, ".")
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	us, _ := strconv.ParseInt(frac, 10, 64)
+	return time.Unix(s, us*int64(time.Microsecond))
+}
+
+// slackMembers 单聊没有名称，用成员名称作为标题。
+func slackMembers(members []string, names map[string]string) string {
+	parts := make([]string, 0, len(members))
+	for _, id := range members {
+		if name, ok := names[id]; ok {
+			parts = append(parts, name)
+		} else {
+			parts = append(parts, id)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// readZipJSON 解析 zip 中的 JSON 文件，文件不存在时保持 v 不变。
+func readZipJSON(f *zip.File, v any) error {
+	if f == nil {
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", f.Name, err)
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("解析 %s 失败: %w", f.Name, err)
+	}
+	return nil
+}
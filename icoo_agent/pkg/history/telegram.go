@@ -0,0 +1,130 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telegramChat Telegram Desktop 导出的单个会话（result.json）。
+type telegramChat struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	ID       json.Number       `json:"id"`
+	Messages []telegramMessage `json:"messages"`
+}
+
+type telegramMessage struct {
+	ID           json.Number     `json:"id"`
+	Type         string          `json:"type"` // message 或 service
+	Date         string          `json:"date"`
+	DateUnixtime string          `json:"date_unixtime"`
+	From         string          `json:"from"`
+	FromID       string          `json:"from_id"`
+	Text         json.RawMessage `json:"text"`
+}
+
+// telegramDirectTypes 单聊类型的会话
+var telegramDirectTypes = map[string]bool{
+	"personal_chat":  true,
+	"bot_chat":       true,
+	"saved_messages": true,
+}
+
+// ParseTelegram parses a Telegram Desktop JSON export (result.json), either of a
+// single chat or of all chats. Service messages and messages without text are
+// skipped.
+func ParseTelegram(r io.Reader) ([]*Conversation, error) {
+	var export struct {
+		telegramChat
+		Chats struct {
+			List []telegramChat `json:"list"`
+		} `json:"chats"`
+	}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&export); err != nil {
+		return nil, fmt.Errorf("解析 Telegram 导出文件失败: %w", err)
+	}
+
+	chats := export.Chats.List
+	if len(chats) == 0 && export.ID != "" {
+		chats = []telegramChat{export.telegramChat}
+	}
+	if len(chats) == 0 {
+		return nil, fmt.Errorf("Telegram 导出文件中没有会话")
+	}
+
+	conversations := make([]*Conversation, 0, len(chats))
+	for _, chat := range chats {
+		conv := &Conversation{
+			ID:     chat.ID.String(),
+			Title:  chat.Name,
+			Direct: telegramDirectTypes[chat.Type],
+		}
+		for _, m := range chat.Messages {
+			if m.Type != "message" {
+				continue
+			}
+			text, err := telegramText(m.Text)
+			if err != nil {
+				return nil, fmt.Errorf("会话 %s 消息 %s: %w", conv.ID, m.ID, err)
+			}
+			if text == "" {
+				continue
+			}
+			conv.Messages = append(conv.Messages, Message{
+				ID:         m.ID.String(),
+				SenderID:   m.FromID,
+				SenderName: m.From,
+				Text:       text,
+				Time:       telegramTime(m),
+			})
+		}
+		conversations = append(conversations, conv)
+	}
+	return conversations, nil
+}
+
+// telegramText 消息文本为字符串，或字符串与 {type, text} 实体混合的数组。
+func telegramText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s), nil
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", fmt.Errorf("无法解析消息文本: %w", err)
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(part, &s); err == nil {
+			b.WriteString(s)
+		} else if err := json.Unmarshal(part, &entity); err == nil {
+			b.WriteString(entity.Text)
+		}
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// telegramTime 优先使用 date_unixtime，旧版本导出只有本地时间的 date。
+func telegramTime(m telegramMessage) time.Time {
+	if sec, err := strconv.ParseInt(m.DateUnixtime, 10, 64); err == nil {
+		return time.Unix(sec, 0)
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", m.Date, time.Local); err == nil {
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,54 @@
+package history
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/transcript"
+)
+
+// TranscriptAssistant 对话格式中助手消息的发送者 ID，导入时需加入 Options.Assistants。
+const TranscriptAssistant = "assistant"
+
+// ParseTranscript parses a conversation in one of the transcript formats
+// (openai, anthropic, langchain, sharegpt) as a single direct conversation
+// with the given ID. Only user and assistant text is kept: system prompts, tool
+// calls and tool results have no place in imported history. The formats carry
+// no timestamps, so messages are spaced one second apart from start.
+func ParseTranscript(format, id string, r io.Reader, start time.Time) ([]*Conversation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	messages, err := transcript.Decode(format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	conv := &Conversation{ID: id, Title: id, Direct: true}
+	for i, m := range messages {
+		if m.Content == "" {
+			continue
+		}
+		var senderID string
+		switch consts.ToRole(m.Role) {
+		case consts.RoleUser:
+			senderID = m.Name
+		case consts.RoleAssistant:
+			senderID = TranscriptAssistant
+		default:
+			continue
+		}
+		conv.Messages = append(conv.Messages, Message{
+			ID:         strconv.Itoa(i + 1),
+			SenderID:   senderID,
+			SenderName: m.Name,
+			Text:       m.Content,
+			Time:       start.Add(time.Duration(i) * time.Second),
+		})
+	}
+	return []*Conversation{conv}, nil
+}
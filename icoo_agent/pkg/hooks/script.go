@@ -0,0 +1,437 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"icooclaw/pkg/agent/react"
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/script"
+	"icooclaw/pkg/tools"
+)
+
+// DefaultScriptDir 工作目录中存放钩子脚本的默认子目录
+const DefaultScriptDir = "hooks"
+
+// DefaultScriptTimeout 单个处理函数的默认最长执行时间
+const DefaultScriptTimeout = 2 * time.Second
+
+// 脚本可以注册的事件，对应全局对象 hooks 上的同名方法
+const (
+	EventToolCall    = "onToolCall"    // 工具执行前，可以改写参数或拒绝调用
+	EventToolResult  = "onToolResult"  // 工具执行后，可以改写结果
+	EventLLMResponse = "onLLMResponse" // 每次收到模型响应，可以改写内容或拒绝响应
+	EventComplete    = "onComplete"    // 本轮得到最终回复，可以改写回复
+)
+
+var scriptEvents = []string{EventToolCall, EventToolResult, EventLLMResponse, EventComplete}
+
+var _ react.ReactHooks = (*ScriptHooks)(nil)
+
+// ScriptHooks 从目录加载 *.js 钩子脚本，实现 react.ReactHooks。
+//
+// 脚本通过全局对象 hooks 注册处理函数，如 hooks.onToolCall(function (call) { ... })。
+// 每个脚本运行在独立的沙箱中，fs、http、shell 等内置对象受 script.Config 的权限限制。
+// 处理函数按文件名顺序调用，返回 undefined 表示不修改；抛出异常或超时时记录日志并忽略该处理函数。
+// 脚本文件有增删改时自动重新加载。
+type ScriptHooks struct {
+	react.BaseHooks
+
+	dir     string
+	cfg     *script.Config
+	timeout time.Duration
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	scripts []*hookScript
+	stamp   string // 已加载文件的名称和修改时间
+}
+
+// hookScript 一个钩子脚本，goja 运行时不能并发使用，调用时持有锁。
+type hookScript struct {
+	name     string
+	mu       sync.Mutex
+	engine   *script.Engine
+	handlers map[string][]goja.Callable
+}
+
+// NewScriptHooks 创建脚本钩子并加载 dir 中的脚本，目录不存在时没有任何处理函数。
+func NewScriptHooks(dir string, cfg *script.Config, timeout time.Duration, logger *slog.Logger) (*ScriptHooks, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if timeout <= 0 {
+		timeout = DefaultScriptTimeout
+	}
+	h := &ScriptHooks{dir: dir, cfg: cfg, timeout: timeout, logger: logger}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Reload 重新加载钩子脚本，任一脚本有误时保留原有脚本并返回错误。
+func (h *ScriptHooks) Reload() error {
+	files, stamp, err := h.scan()
+	if err != nil {
+		return err
+	}
+
+	scripts := make([]*hookScript, 0, len(files))
+	for _, file := range files {
+		s, err := h.load(file)
+		if err != nil {
+			return err
+		}
+		scripts = append(scripts, s)
+	}
+
+	h.mu.Lock()
+	h.scripts = scripts
+	h.stamp = stamp
+	h.mu.Unlock()
+
+	h.logger.With("name", "【钩子】").Info("已加载钩子脚本", "dir", h.dir, "scripts", len(scripts))
+	return nil
+}
+
+// Scripts 返回已加载的脚本及其注册的事件。
+func (h *ScriptHooks) Scripts() map[string][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := make(map[string][]string, len(h.scripts))
+	for _, s := range h.scripts {
+		events := []string{}
+		for _, event := range scriptEvents {
+			if len(s.handlers[event]) > 0 {
+				events = append(events, event)
+			}
+		}
+		result[s.name] = events
+	}
+	return result
+}
+
+// scan 列出脚本文件，返回按名称排序的路径和文件状态摘要。
+func (h *ScriptHooks) scan() ([]string, string, error) {
+	entries, err := os.ReadDir(h.dir)
+	if os.IsNotExist(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("读取钩子目录失败: %w", err)
+	}
+
+	var files []string
+	var stamp strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".js" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(h.dir, entry.Name()))
+		fmt.Fprintf(&stamp, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	sort.Strings(files)
+	return files, stamp.String(), nil
+}
+
+// load 在新的沙箱中执行脚本，收集其注册的处理函数。
+func (h *ScriptHooks) load(file string) (*hookScript, error) {
+	name := filepath.Base(file)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取钩子脚本失败: %w", err)
+	}
+
+	s := &hookScript{
+		name:     name,
+		engine:   script.NewEngine(h.cfg, h.logger.With("script", name)),
+		handlers: make(map[string][]goja.Callable),
+	}
+	vm := s.engine.VM()
+	registry := make(map[string]any, len(scriptEvents))
+	for _, event := range scriptEvents {
+		registry[event] = func(fn goja.Value) {
+			callable, ok := goja.AssertFunction(fn)
+			if !ok {
+				panic(vm.NewTypeError("hooks.%s 的参数必须是函数", event))
+			}
+			s.handlers[event] = append(s.handlers[event], callable)
+		}
+	}
+	if err := s.engine.SetGlobal("hooks", registry); err != nil {
+		return nil, err
+	}
+
+	timer := time.AfterFunc(h.timeout, func() { vm.Interrupt("执行超时") })
+	defer func() {
+		timer.Stop()
+		vm.ClearInterrupt()
+	}()
+	if _, err := vm.RunScript(name, string(data)); err != nil {
+		return nil, fmt.Errorf("钩子脚本 %s 执行失败: %w", name, err)
+	}
+	return s, nil
+}
+
+// reloadIfChanged 脚本文件有增删改时重新加载。
+func (h *ScriptHooks) reloadIfChanged() {
+	_, stamp, err := h.scan()
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	changed := stamp != h.stamp
+	h.mu.Unlock()
+	if !changed {
+		return
+	}
+	if err := h.Reload(); err != nil {
+		h.logger.With("name", "【钩子】").Error("重新加载钩子脚本失败，继续使用原有脚本", "error", err)
+		// 记录本次文件状态，避免每次事件都重复加载同一个有误的脚本
+		h.mu.Lock()
+		h.stamp = stamp
+		h.mu.Unlock()
+	}
+}
+
+// dispatch 按顺序调用各脚本中 event 的处理函数。build 每次调用前构造事件对象，
+// 以便后面的处理函数看到前面的改写；handle 处理非 undefined 的返回值，返回错误时停止。
+func (h *ScriptHooks) dispatch(ctx context.Context, event string, build func() map[string]any, handle func(script string, ret any) error) error {
+	h.reloadIfChanged()
+
+	h.mu.Lock()
+	scripts := h.scripts
+	h.mu.Unlock()
+
+	for _, s := range scripts {
+		for _, fn := range s.handlers[event] {
+			ret, err := s.call(ctx, h.timeout, fn, build())
+			if err != nil {
+				h.logger.With("name", "【钩子】").Warn("钩子处理函数执行失败，已忽略",
+					"script", s.name,
+					"event", event,
+					"error", err)
+				continue
+			}
+			if ret == nil {
+				continue
+			}
+			if err := handle(s.name, ret); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// has 是否有脚本注册了 event，没有时跳过事件对象的构造。
+func (h *ScriptHooks) has(event string) bool {
+	h.reloadIfChanged()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.scripts {
+		if len(s.handlers[event]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// call 在超时限制内调用处理函数，返回导出为 Go 值的返回值，undefined 和 null 返回 nil。
+func (s *hookScript) call(ctx context.Context, timeout time.Duration, fn goja.Callable, event map[string]any) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vm := s.engine.VM()
+	s.engine.SetContext(ctx)
+	timer := time.AfterFunc(timeout, func() { vm.Interrupt("执行超时") })
+	defer func() {
+		timer.Stop()
+		vm.ClearInterrupt()
+	}()
+
+	v, err := fn(goja.Undefined(), vm.ToValue(event))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, nil
+	}
+	return v.Export(), nil
+}
+
+// messageEnv 事件对象中的消息字段，与授权策略的条件变量一致。
+func messageEnv(msg bus.InboundMessage, env map[string]any) map[string]any {
+	env["user"] = map[string]any{"id": msg.Sender.ID, "name": msg.Sender.Name}
+	env["channel"] = msg.Channel
+	env["session"] = msg.SessionID
+	return env
+}
+
+// vetoReason 返回值为 false 或带 veto 字段的对象时表示拒绝，返回拒绝原因。
+func vetoReason(ret any) (string, bool) {
+	switch v := ret.(type) {
+	case bool:
+		if !v {
+			return "", true
+		}
+	case map[string]any:
+		switch veto := v["veto"].(type) {
+		case string:
+			return veto, veto != ""
+		case bool:
+			reason, _ := v["reason"].(string)
+			return reason, veto
+		}
+	}
+	return "", false
+}
+
+// vetoError 拒绝错误，原因会作为工具结果或错误返回给模型和用户。
+func vetoError(what, script, reason string) error {
+	if reason == "" {
+		reason = "未说明原因"
+	}
+	return fmt.Errorf("%s被钩子 %s 拒绝: %s", what, script, reason)
+}
+
+// rewrittenContent 返回值为字符串或带 content 字段的对象时表示改写内容。
+func rewrittenContent(ret any) (string, bool) {
+	switch v := ret.(type) {
+	case string:
+		return v, true
+	case map[string]any:
+		content, ok := v["content"].(string)
+		return content, ok
+	}
+	return "", false
+}
+
+// OnToolCallBefore 调用 onToolCall，事件对象为 {tool, args, user, channel, session}。
+// 处理函数返回 false 或 {veto: "原因"} 拒绝调用，返回 {args: {...}} 替换参数。
+func (h *ScriptHooks) OnToolCallBefore(ctx context.Context, toolName string, tc providers.ToolCall, msg bus.InboundMessage) (providers.ToolCall, error) {
+	if !h.has(EventToolCall) {
+		return tc, nil
+	}
+
+	args := map[string]any{}
+	if tc.Function.Arguments != "" {
+		json.Unmarshal([]byte(tc.Function.Arguments), &args)
+	}
+	changed := false
+	err := h.dispatch(ctx, EventToolCall, func() map[string]any {
+		return messageEnv(msg, map[string]any{"tool": toolName, "args": args})
+	}, func(script string, ret any) error {
+		if reason, ok := vetoReason(ret); ok {
+			return vetoError("工具调用", script, reason)
+		}
+		if m, ok := ret.(map[string]any); ok {
+			if replaced, ok := m["args"].(map[string]any); ok {
+				args, changed = replaced, true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return tc, err
+	}
+
+	if changed {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return tc, fmt.Errorf("钩子返回的参数无法序列化: %w", err)
+		}
+		tc.Function.Arguments = string(data)
+	}
+	return tc, nil
+}
+
+// OnToolCallAfter 调用 onToolResult，事件对象为 {tool, content, user, channel, session}。
+// 处理函数返回字符串或 {content: "..."} 替换工具结果。
+func (h *ScriptHooks) OnToolCallAfter(ctx context.Context, toolName string, msg bus.InboundMessage, result *tools.Result) error {
+	if !h.has(EventToolResult) {
+		return nil
+	}
+	return h.dispatch(ctx, EventToolResult, func() map[string]any {
+		return messageEnv(msg, map[string]any{"tool": toolName, "content": result.Content})
+	}, func(script string, ret any) error {
+		if content, ok := rewrittenContent(ret); ok {
+			result.Content = content
+		}
+		return nil
+	})
+}
+
+// OnLLMResponse 调用 onLLMResponse，事件对象为 {content, reasoning, tool_calls, user, channel, session}，
+// tool_calls 的每一项为 {id, name, arguments}。处理函数返回字符串或 {content: "..."} 替换内容，
+// 返回 false 或 {veto: "原因"} 拒绝响应并结束本轮。
+func (h *ScriptHooks) OnLLMResponse(ctx context.Context, msg bus.InboundMessage, resp *providers.ChatResponse) error {
+	if !h.has(EventLLMResponse) {
+		return nil
+	}
+	return h.dispatch(ctx, EventLLMResponse, func() map[string]any {
+		calls := make([]any, 0, len(resp.ToolCalls))
+		for _, tc := range resp.ToolCalls {
+			args := map[string]any{}
+			json.Unmarshal([]byte(tc.Function.Arguments), &args)
+			calls = append(calls, map[string]any{"id": tc.ID, "name": tc.Function.Name, "arguments": args})
+		}
+		return messageEnv(msg, map[string]any{
+			"content":    resp.Content,
+			"reasoning":  resp.Reasoning,
+			"tool_calls": calls,
+		})
+	}, func(script string, ret any) error {
+		if reason, ok := vetoReason(ret); ok {
+			return vetoError("模型响应", script, reason)
+		}
+		if content, ok := rewrittenContent(ret); ok {
+			resp.Content = content
+		}
+		return nil
+	})
+}
+
+// OnRunLLMAfter 调用 onComplete，事件对象为 {content, user, channel, session}。
+// 处理函数返回字符串或 {content: "..."} 替换最终回复。
+func (h *ScriptHooks) OnRunLLMAfter(ctx context.Context, msg bus.InboundMessage, history []providers.ChatMessage) ([]providers.ChatMessage, error) {
+	n := len(history)
+	if n == 0 || history[n-1].Role != consts.RoleAssistant.ToString() || !h.has(EventComplete) {
+		return history, nil
+	}
+
+	final := history[n-1]
+	err := h.dispatch(ctx, EventComplete, func() map[string]any {
+		return messageEnv(msg, map[string]any{"content": final.Content})
+	}, func(script string, ret any) error {
+		if content, ok := rewrittenContent(ret); ok {
+			final.Content = content
+		}
+		return nil
+	})
+	if err != nil {
+		return history, err
+	}
+
+	result := append(history[:n-1:n-1], final)
+	return result, nil
+}
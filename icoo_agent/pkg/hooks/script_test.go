@@ -0,0 +1,138 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/script"
+	"icooclaw/pkg/tools"
+)
+
+func writeScript(t *testing.T, dir, name, src string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScriptHooks(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "10-guard.js", `
+hooks.onToolCall(function (call) {
+  if (call.tool === "shell_command" && call.args.command.indexOf("rm ") === 0) {
+    return { veto: "不允许删除文件" };
+  }
+  if (call.tool === "write_file" && call.user.id === "guest") {
+    return false;
+  }
+  if (call.tool === "read_file") {
+    call.args.path = "safe/" + call.args.path;
+    return { args: call.args };
+  }
+});
+hooks.onToolResult(function (r) {
+  return r.content.replace(/secret-\w+/g, "[已隐藏]");
+});
+`)
+	writeScript(t, dir, "20-reply.js", `
+hooks.onLLMResponse(function (resp) {
+  if (resp.content.indexOf("FORBIDDEN") >= 0) return { veto: true, reason: "包含禁止内容" };
+  if (resp.tool_calls.length > 0 && resp.tool_calls[0].arguments.path === "x") return resp.content + "!";
+});
+hooks.onComplete(function (reply) {
+  return { content: reply.content + "\n-- " + reply.channel };
+});
+hooks.onComplete(function (reply) {
+  throw new Error("忽略出错的处理函数");
+});
+`)
+
+	h, err := NewScriptHooks(dir, script.DefaultConfig(), time.Second, nil)
+	if err != nil {
+		t.Fatalf("NewScriptHooks() error = %v", err)
+	}
+	if got := h.Scripts(); len(got["10-guard.js"]) != 2 || len(got["20-reply.js"]) != 2 {
+		t.Errorf("Scripts() = %v", got)
+	}
+
+	ctx := context.Background()
+	msg := bus.InboundMessage{Channel: "telegram", SessionID: "s1"}
+	toolCall := func(name, args string) providers.ToolCall {
+		tc := providers.ToolCall{ID: "call_1", Type: "function"}
+		tc.Function.Name = name
+		tc.Function.Arguments = args
+		return tc
+	}
+
+	if _, err := h.OnToolCallBefore(ctx, "shell_command", toolCall("shell_command", `{"command":"rm -rf x"}`), msg); err == nil || !strings.Contains(err.Error(), "不允许删除文件") {
+		t.Errorf("OnToolCallBefore() veto error = %v", err)
+	}
+	guest := msg
+	guest.Sender.ID = "guest"
+	if _, err := h.OnToolCallBefore(ctx, "write_file", toolCall("write_file", `{"path":"a"}`), guest); err == nil {
+		t.Error("OnToolCallBefore() should veto when the handler returns false")
+	}
+	tc, err := h.OnToolCallBefore(ctx, "read_file", toolCall("read_file", `{"path":"a.txt"}`), msg)
+	if err != nil || tc.Function.Arguments != `{"path":"safe/a.txt"}` {
+		t.Errorf("OnToolCallBefore() = %q, %v", tc.Function.Arguments, err)
+	}
+	tc, err = h.OnToolCallBefore(ctx, "list_dir", toolCall("list_dir", `{"path":"."}`), msg)
+	if err != nil || tc.Function.Arguments != `{"path":"."}` {
+		t.Errorf("OnToolCallBefore() unchanged = %q, %v", tc.Function.Arguments, err)
+	}
+
+	result := &tools.Result{Success: true, Content: "token: secret-abc"}
+	if err := h.OnToolCallAfter(ctx, "read_file", msg, result); err != nil || result.Content != "token: [已隐藏]" {
+		t.Errorf("OnToolCallAfter() = %q, %v", result.Content, err)
+	}
+
+	if err := h.OnLLMResponse(ctx, msg, &providers.ChatResponse{Content: "FORBIDDEN"}); err == nil || !strings.Contains(err.Error(), "包含禁止内容") {
+		t.Errorf("OnLLMResponse() veto error = %v", err)
+	}
+	resp := &providers.ChatResponse{Content: "好的", ToolCalls: []providers.ToolCall{toolCall("read_file", `{"path":"x"}`)}}
+	if err := h.OnLLMResponse(ctx, msg, resp); err != nil || resp.Content != "好的!" {
+		t.Errorf("OnLLMResponse() = %q, %v", resp.Content, err)
+	}
+
+	history := []providers.ChatMessage{{Role: "user", Content: "你好"}, {Role: "assistant", Content: "你好！"}}
+	got, err := h.OnRunLLMAfter(ctx, msg, history)
+	if err != nil || got[1].Content != "你好！\n-- telegram" || history[1].Content != "你好！" {
+		t.Errorf("OnRunLLMAfter() = %+v, %v", got, err)
+	}
+}
+
+func TestScriptHooks_TimeoutAndReload(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "loop.js", `hooks.onComplete(function () { for (;;) {} });`)
+
+	h, err := NewScriptHooks(dir, script.DefaultConfig(), 50*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("NewScriptHooks() error = %v", err)
+	}
+	history := []providers.ChatMessage{{Role: "assistant", Content: "ok"}}
+	got, err := h.OnRunLLMAfter(context.Background(), bus.InboundMessage{}, history)
+	if err != nil || got[0].Content != "ok" {
+		t.Errorf("OnRunLLMAfter() with a timed out handler = %+v, %v", got, err)
+	}
+
+	// 修改后的脚本在下一个事件时生效，有误的脚本不替换原有脚本
+	writeScript(t, dir, "loop.js", `hooks.onComplete(function () { return "new"; });`)
+	os.Chtimes(filepath.Join(dir, "loop.js"), time.Now(), time.Now().Add(time.Second))
+	if got, _ := h.OnRunLLMAfter(context.Background(), bus.InboundMessage{}, history); got[0].Content != "new" {
+		t.Errorf("OnRunLLMAfter() after reload = %+v", got)
+	}
+	writeScript(t, dir, "broken.js", `hooks.onComplete(`)
+	if got, _ := h.OnRunLLMAfter(context.Background(), bus.InboundMessage{}, history); got[0].Content != "new" {
+		t.Errorf("OnRunLLMAfter() after a broken reload = %+v", got)
+	}
+
+	if _, err := NewScriptHooks(dir, script.DefaultConfig(), time.Second, nil); err == nil {
+		t.Error("NewScriptHooks() should fail on a broken script")
+	}
+}
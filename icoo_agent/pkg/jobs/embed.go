@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/utils"
+)
+
+// TypeEmbedMemories 为记忆库生成向量嵌入的作业类型
+const TypeEmbedMemories = "embed_memories"
+
+// EmbedParams 嵌入作业的参数。
+type EmbedParams struct {
+	Model  string               `json:"model"`  // 嵌入模型，格式为 提供商/模型，为空时使用配置的默认模型
+	Filter storage.MemoryFilter `json:"filter"` // 只处理符合条件的记忆
+}
+
+// EmbedMemories 为尚未由指定模型生成向量的记忆生成向量嵌入，每批调用一次嵌入接口。
+// 更换嵌入模型后重新提交作业即可全部重新生成。
+type EmbedMemories struct {
+	storage      *storage.Storage
+	factory      *providers.Factory
+	defaultModel string
+}
+
+// NewEmbedMemories 创建嵌入作业，defaultModel 为参数未指定模型时使用的 提供商/模型。
+func NewEmbedMemories(s *storage.Storage, factory *providers.Factory, defaultModel string) *EmbedMemories {
+	return &EmbedMemories{storage: s, factory: factory, defaultModel: defaultModel}
+}
+
+// params 解析作业参数。
+func (e *EmbedMemories) params(raw string) (*EmbedParams, error) {
+	p := &EmbedParams{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), p); err != nil {
+			return nil, fmt.Errorf("解析作业参数失败: %w", err)
+		}
+	}
+	if p.Model == "" {
+		p.Model = e.defaultModel
+	}
+	if p.Model == "" {
+		return nil, fmt.Errorf("未指定嵌入模型，请在参数中设置 model 或配置 agent.jobs.embedding_model")
+	}
+	if len(utils.SplitProviderModel(p.Model)) != 2 {
+		return nil, fmt.Errorf("嵌入模型格式错误: %s，应为 提供商/模型", p.Model)
+	}
+	return p, nil
+}
+
+// Validate 校验作业参数。
+func (e *EmbedMemories) Validate(raw string) error {
+	_, err := e.params(raw)
+	return err
+}
+
+// Count 返回待生成向量的记忆数。
+func (e *EmbedMemories) Count(ctx context.Context, job *storage.Job) (int64, error) {
+	p, err := e.params(job.Params)
+	if err != nil {
+		return 0, err
+	}
+	return e.storage.Memory().CountForEmbedding(p.Filter, p.Model)
+}
+
+// Batch 为一批记忆生成向量。
+func (e *EmbedMemories) Batch(ctx context.Context, job *storage.Job, size int) (BatchResult, error) {
+	var res BatchResult
+	p, err := e.params(job.Params)
+	if err != nil {
+		return res, err
+	}
+
+	memories, err := e.storage.Memory().ListForEmbedding(p.Filter, p.Model, job.Cursor, size)
+	if err != nil {
+		return res, err
+	}
+	if len(memories) == 0 {
+		res.Done = true
+		return res, nil
+	}
+
+	parts := utils.SplitProviderModel(p.Model)
+	embedder, err := e.factory.Embedder(parts[0])
+	if err != nil {
+		return res, err
+	}
+	inputs := make([]string, len(memories))
+	for i, m := range memories {
+		inputs[i] = m.Content
+	}
+	embeddings, err := embedder.Embed(ctx, parts[1], inputs)
+	if err != nil {
+		return res, fmt.Errorf("生成向量失败: %w", err)
+	}
+
+	for i, m := range memories {
+		if err := e.storage.Memory().SetEmbedding(m.ID, embeddings[i], p.Model); err != nil {
+			res.Failed++
+			res.Error = fmt.Sprintf("%s: %v", m.ID, err)
+			continue
+		}
+		res.Processed++
+	}
+	res.Cursor = memories[len(memories)-1].ID
+	res.Done = len(memories) < size
+	return res, nil
+}
@@ -0,0 +1,309 @@
+// Package jobs 执行批量处理的后台作业，如为记忆库生成向量嵌入、修改摘要提示词后重新摘要全部会话。
+//
+// 作业按记录 ID 分批处理，批次之间按配置的间隔限速，每批完成后保存游标和进度；
+// 作业可以暂停、恢复和取消，进程重启或失败后从游标处继续。启用集群时只在主实例上执行。
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/storage"
+)
+
+// pollInterval 检查新作业的间隔，其他实例通过接口提交的作业依靠轮询发现
+const pollInterval = 5 * time.Second
+
+// Handler 一种作业的处理逻辑。
+type Handler interface {
+	// Count 返回待处理的记录数，作业首次执行时调用，用于计算进度
+	Count(ctx context.Context, job *storage.Job) (int64, error)
+	// Batch 处理 job.Cursor 之后的至多 size 条记录。返回错误时作业失败，恢复后从同一游标重试
+	Batch(ctx context.Context, job *storage.Job, size int) (BatchResult, error)
+}
+
+// Validator 提交作业时校验参数的处理逻辑，可选实现。
+type Validator interface {
+	Validate(params string) error
+}
+
+// BatchResult 一批的处理结果。
+type BatchResult struct {
+	Cursor    string // 本批最后一条记录的 ID，作为下一批的游标
+	Processed int    // 成功处理数
+	Failed    int    // 失败数，单条记录失败不影响作业继续
+	Error     string // 最近一条记录的错误
+	Done      bool   // 没有更多记录
+}
+
+// Config 作业执行配置。
+type Config struct {
+	BatchSize int           // 每批处理的记录数
+	Interval  time.Duration // 批次之间的间隔，用于限制调用模型的速率
+}
+
+// Runner 依次执行后台作业。
+type Runner struct {
+	store  *storage.JobStorage
+	cfg    Config
+	owner  string
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	leader   func() bool
+
+	wake chan struct{}
+}
+
+// NewRunner 创建作业执行器，owner 为本实例 ID。
+func NewRunner(store *storage.JobStorage, cfg Config, owner string, logger *slog.Logger) *Runner {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Runner{
+		store:    store,
+		cfg:      cfg,
+		owner:    owner,
+		logger:   logger,
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register 注册作业类型。
+func (r *Runner) Register(jobType string, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[jobType] = h
+}
+
+// SetLeader 设置主实例判断，返回 false 时不执行作业。
+func (r *Runner) SetLeader(fn func() bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leader = fn
+}
+
+// Types 返回已注册的作业类型。
+func (r *Runner) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.handlers))
+	for t := range r.handlers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// handler 返回作业类型的处理逻辑。
+func (r *Runner) handler(jobType string) Handler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.handlers[jobType]
+}
+
+// isLeader 判断本实例是否应执行作业。
+func (r *Runner) isLeader() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.leader == nil || r.leader()
+}
+
+// notify 唤醒执行循环。
+func (r *Runner) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Submit 提交作业，params 为 JSON 对象，可以为空。
+func (r *Runner) Submit(jobType string, params json.RawMessage) (*storage.Job, error) {
+	h := r.handler(jobType)
+	if h == nil {
+		return nil, fmt.Errorf("未知的作业类型 %q，可选: %v", jobType, r.Types())
+	}
+	if len(params) > 0 && !json.Valid(params) {
+		return nil, fmt.Errorf("作业参数不是有效的 JSON")
+	}
+	if v, ok := h.(Validator); ok {
+		if err := v.Validate(string(params)); err != nil {
+			return nil, err
+		}
+	}
+
+	job := &storage.Job{Type: jobType, Status: storage.JobPending, Params: string(params)}
+	if err := r.store.Create(job); err != nil {
+		return nil, err
+	}
+	r.logger.With("name", "【作业】").Info("作业已提交", "job_id", job.ID, "type", jobType)
+	r.notify()
+	return job, nil
+}
+
+// Pause 暂停作业，执行中的作业在当前批次完成后停止。
+func (r *Runner) Pause(id string) (*storage.Job, error) {
+	return r.transition(id, "暂停", storage.JobPaused, storage.JobPending, storage.JobRunning)
+}
+
+// Resume 恢复暂停或失败的作业，从游标处继续。
+func (r *Runner) Resume(id string) (*storage.Job, error) {
+	job, err := r.transition(id, "恢复", storage.JobPending, storage.JobPaused, storage.JobFailed)
+	if err == nil {
+		r.notify()
+	}
+	return job, err
+}
+
+// Cancel 取消未结束的作业，已处理的记录不会回滚。
+func (r *Runner) Cancel(id string) (*storage.Job, error) {
+	return r.transition(id, "取消", storage.JobCanceled,
+		storage.JobPending, storage.JobRunning, storage.JobPaused, storage.JobFailed)
+}
+
+// transition 修改作业状态，当前状态不在 from 中时返回错误。
+func (r *Runner) transition(id, action, status string, from ...string) (*storage.Job, error) {
+	ok, err := r.store.Transition(id, status, from...)
+	if err != nil {
+		return nil, err
+	}
+	job, err := r.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("作业当前状态为 %s，无法%s", job.Status, action)
+	}
+	r.logger.With("name", "【作业】").Info("作业状态已更新", "job_id", id, "status", status)
+	return job, nil
+}
+
+// Run 依次执行待处理的作业，直到 ctx 取消。执行中的作业在退出时回到等待状态，下次启动后继续。
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.runPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// runPending 执行所有待处理的作业。
+func (r *Runner) runPending(ctx context.Context) {
+	if !r.isLeader() {
+		return
+	}
+
+	// 其他实例或上次运行中断的作业重新排队
+	if n, err := r.store.Requeue(r.owner); err != nil {
+		r.logger.With("name", "【作业】").Warn("重新排队中断的作业失败", "error", err)
+	} else if n > 0 {
+		r.logger.With("name", "【作业】").Info("中断的作业已重新排队", "count", n)
+	}
+
+	for ctx.Err() == nil && r.isLeader() {
+		job, err := r.store.Claim(r.owner)
+		if err != nil {
+			r.logger.With("name", "【作业】").Warn("获取待处理作业失败", "error", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+		r.execute(ctx, job)
+	}
+}
+
+// execute 分批执行作业，直到完成、失败、被暂停或取消。
+func (r *Runner) execute(ctx context.Context, job *storage.Job) {
+	logger := r.logger.With("name", "【作业】", "job_id", job.ID, "type", job.Type)
+
+	h := r.handler(job.Type)
+	if h == nil {
+		r.fail(job, errors.New("未知的作业类型"))
+		return
+	}
+
+	if job.Total == 0 && job.Cursor == "" {
+		total, err := h.Count(ctx, job)
+		if err != nil {
+			r.fail(job, err)
+			return
+		}
+		job.Total = total
+	}
+	logger.Info("开始执行作业", "total", job.Total, "cursor", job.Cursor)
+
+	for {
+		// 退出或不再是主实例时回到等待状态，由之后的执行继续
+		if ctx.Err() != nil || !r.isLeader() {
+			r.store.Transition(job.ID, storage.JobPending, storage.JobRunning)
+			logger.Info("作业已中断，等待继续", "processed", job.Processed)
+			return
+		}
+
+		res, err := h.Batch(ctx, job, r.cfg.BatchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			r.fail(job, err)
+			return
+		}
+
+		if res.Cursor != "" {
+			job.Cursor = res.Cursor
+		}
+		job.Processed += int64(res.Processed)
+		job.Failed += int64(res.Failed)
+		if res.Error != "" {
+			job.Error = res.Error
+		}
+		ok, err := r.store.SaveProgress(job)
+		if err != nil {
+			logger.Warn("保存作业进度失败", "error", err)
+		} else if !ok {
+			logger.Info("作业已暂停或取消", "processed", job.Processed)
+			return
+		}
+
+		if res.Done {
+			r.store.Transition(job.ID, storage.JobCompleted, storage.JobRunning)
+			logger.Info("作业已完成", "processed", job.Processed, "failed", job.Failed)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(r.cfg.Interval):
+		}
+	}
+}
+
+// fail 记录错误并将作业标记为失败。
+func (r *Runner) fail(job *storage.Job, err error) {
+	job.Error = err.Error()
+	if _, saveErr := r.store.SaveProgress(job); saveErr != nil {
+		r.logger.With("name", "【作业】").Warn("保存作业进度失败", "error", saveErr, "job_id", job.ID)
+	}
+	r.store.Transition(job.ID, storage.JobFailed, storage.JobRunning)
+	r.logger.With("name", "【作业】").Warn("作业执行失败", "error", err, "job_id", job.ID, "type", job.Type)
+}
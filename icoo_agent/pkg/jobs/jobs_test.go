@@ -0,0 +1,243 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/storage"
+)
+
+// fakeHandler 处理 001..n 的记录，before 在每批开始前调用。
+type fakeHandler struct {
+	n       int
+	seen    []string
+	before  func(job *storage.Job) error
+	invalid bool
+}
+
+func (h *fakeHandler) Validate(params string) error {
+	if h.invalid {
+		return errors.New("参数无效")
+	}
+	return nil
+}
+
+func (h *fakeHandler) Count(ctx context.Context, job *storage.Job) (int64, error) {
+	return int64(h.n), nil
+}
+
+func (h *fakeHandler) Batch(ctx context.Context, job *storage.Job, size int) (BatchResult, error) {
+	var res BatchResult
+	if h.before != nil {
+		if err := h.before(job); err != nil {
+			return res, err
+		}
+	}
+	for i := 1; i <= h.n && res.Processed < size; i++ {
+		id := fmt.Sprintf("%03d", i)
+		if id <= job.Cursor {
+			continue
+		}
+		h.seen = append(h.seen, id)
+		res.Processed++
+		res.Cursor = id
+	}
+	res.Done = res.Cursor == "" || res.Cursor == fmt.Sprintf("%03d", h.n)
+	return res, nil
+}
+
+func newTestRunner(t *testing.T, h Handler) *Runner {
+	t.Helper()
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	r := NewRunner(store.Job(), Config{BatchSize: 3}, "node-a", nil)
+	r.Register("fake", h)
+	return r
+}
+
+func mustGet(t *testing.T, r *Runner, id string) *storage.Job {
+	t.Helper()
+	job, err := r.store.Get(id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	return job
+}
+
+func TestRunner_Complete(t *testing.T) {
+	h := &fakeHandler{n: 10}
+	r := newTestRunner(t, h)
+
+	job, err := r.Submit("fake", nil)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	r.runPending(context.Background())
+
+	got := mustGet(t, r, job.ID)
+	if got.Status != storage.JobCompleted {
+		t.Fatalf("status = %q, want %q", got.Status, storage.JobCompleted)
+	}
+	if got.Total != 10 || got.Processed != 10 || got.Cursor != "010" {
+		t.Fatalf("total/processed/cursor = %d/%d/%q, want 10/10/010", got.Total, got.Processed, got.Cursor)
+	}
+	if got.Progress() != 100 {
+		t.Errorf("Progress() = %v, want 100", got.Progress())
+	}
+	if got.StartedAt.IsZero() || got.FinishedAt.IsZero() {
+		t.Errorf("started_at/finished_at not set: %v/%v", got.StartedAt, got.FinishedAt)
+	}
+}
+
+func TestRunner_PauseResume(t *testing.T) {
+	h := &fakeHandler{n: 10}
+	r := newTestRunner(t, h)
+
+	job, err := r.Submit("fake", nil)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	// 第二批执行期间暂停，当前批次完成后停止
+	h.before = func(j *storage.Job) error {
+		if j.Cursor == "003" {
+			if _, err := r.Pause(j.ID); err != nil {
+				t.Fatalf("Pause() error = %v", err)
+			}
+		}
+		return nil
+	}
+	r.runPending(context.Background())
+
+	got := mustGet(t, r, job.ID)
+	if got.Status != storage.JobPaused {
+		t.Fatalf("status = %q, want %q", got.Status, storage.JobPaused)
+	}
+
+	h.before = nil
+	if _, err := r.Resume(job.ID); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	r.runPending(context.Background())
+
+	got = mustGet(t, r, job.ID)
+	if got.Status != storage.JobCompleted {
+		t.Fatalf("status = %q, want %q", got.Status, storage.JobCompleted)
+	}
+	// 暂停时的批次未保存进度，恢复后从上次保存的游标重新处理
+	if got.Processed != 10 {
+		t.Errorf("processed = %d, want 10", got.Processed)
+	}
+	if want := "001,002,003,004,005,006,004,005,006,007,008,009,010"; strings.Join(h.seen, ",") != want {
+		t.Errorf("seen = %v, want %s", h.seen, want)
+	}
+}
+
+func TestRunner_FailResume(t *testing.T) {
+	h := &fakeHandler{n: 5}
+	r := newTestRunner(t, h)
+
+	job, err := r.Submit("fake", nil)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	h.before = func(j *storage.Job) error {
+		if j.Cursor == "003" {
+			return errors.New("模型不可用")
+		}
+		return nil
+	}
+	r.runPending(context.Background())
+
+	got := mustGet(t, r, job.ID)
+	if got.Status != storage.JobFailed || got.Error != "模型不可用" || got.Cursor != "003" {
+		t.Fatalf("status/error/cursor = %q/%q/%q, want failed/模型不可用/003", got.Status, got.Error, got.Cursor)
+	}
+
+	h.before = nil
+	if _, err := r.Resume(job.ID); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	r.runPending(context.Background())
+
+	got = mustGet(t, r, job.ID)
+	if got.Status != storage.JobCompleted || got.Processed != 5 || got.Error != "" {
+		t.Fatalf("status/processed/error = %q/%d/%q, want completed/5/empty", got.Status, got.Processed, got.Error)
+	}
+}
+
+func TestRunner_Cancel(t *testing.T) {
+	h := &fakeHandler{n: 5}
+	r := newTestRunner(t, h)
+
+	job, err := r.Submit("fake", nil)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if _, err := r.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	r.runPending(context.Background())
+
+	if len(h.seen) != 0 {
+		t.Errorf("canceled job processed %v", h.seen)
+	}
+	if _, err := r.Resume(job.ID); err == nil {
+		t.Error("Resume() of canceled job should fail")
+	}
+	if _, err := r.Cancel(job.ID); err == nil {
+		t.Error("Cancel() of canceled job should fail")
+	}
+}
+
+func TestRunner_Submit(t *testing.T) {
+	h := &fakeHandler{n: 1}
+	r := newTestRunner(t, h)
+
+	if _, err := r.Submit("unknown", nil); err == nil {
+		t.Error("Submit() of unknown type should fail")
+	}
+	if _, err := r.Submit("fake", []byte("{")); err == nil {
+		t.Error("Submit() with invalid JSON should fail")
+	}
+	h.invalid = true
+	if _, err := r.Submit("fake", nil); err == nil {
+		t.Error("Submit() should fail when Validate fails")
+	}
+}
+
+func TestRunner_LeaderAndRequeue(t *testing.T) {
+	h := &fakeHandler{n: 4}
+	r := newTestRunner(t, h)
+
+	job, err := r.Submit("fake", nil)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// 非主实例不执行
+	r.SetLeader(func() bool { return false })
+	r.runPending(context.Background())
+	if got := mustGet(t, r, job.ID); got.Status != storage.JobPending {
+		t.Fatalf("status = %q, want %q", got.Status, storage.JobPending)
+	}
+
+	// 其他实例认领后中断的作业由新的主实例继续
+	if _, err := r.store.Claim("node-b"); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	r.SetLeader(func() bool { return true })
+	r.runPending(context.Background())
+
+	got := mustGet(t, r, job.ID)
+	if got.Status != storage.JobCompleted || got.Owner != "node-a" {
+		t.Fatalf("status/owner = %q/%q, want completed/node-a", got.Status, got.Owner)
+	}
+}
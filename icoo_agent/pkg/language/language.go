@@ -0,0 +1,171 @@
+// Package language 识别用户消息的语言并跟踪会话的回复语言。
+package language
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 常用语言代码
+const (
+	Chinese  = "zh"
+	English  = "en"
+	Japanese = "ja"
+	Korean   = "ko"
+	Russian  = "ru"
+	Arabic   = "ar"
+)
+
+// names 语言代码对应的名称，用于提示词和命令输出
+var names = map[string]string{
+	Chinese:  "中文",
+	English:  "English",
+	Japanese: "日本語",
+	Korean:   "한국어",
+	Russian:  "Русский",
+	Arabic:   "العربية",
+	"fr":     "Français",
+	"de":     "Deutsch",
+	"es":     "Español",
+	"pt":     "Português",
+	"it":     "Italiano",
+	"vi":     "Tiếng Việt",
+	"th":     "ไทย",
+}
+
+// aliases 命令中可以使用的语言别名
+var aliases = map[string]string{
+	"中文": Chinese, "汉语": Chinese, "chinese": Chinese, "zh-cn": Chinese, "zh-hans": Chinese,
+	"英文": English, "英语": English, "english": English,
+	"日文": Japanese, "日语": Japanese, "japanese": Japanese,
+	"韩文": Korean, "韩语": Korean, "korean": Korean,
+	"俄文": Russian, "俄语": Russian, "russian": Russian,
+	"法语": "fr", "french": "fr",
+	"德语": "de", "german": "de",
+	"西班牙语": "es", "spanish": "es",
+}
+
+// reCode BCP 47 形式的语言代码，例如 fr、pt-BR、zh-Hant
+var reCode = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// Name 返回语言的显示名称，未知代码原样返回。
+func Name(code string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+// Normalize 将命令参数转换为语言代码，支持代码、英文名称和中文名称。
+func Normalize(input string) (string, error) {
+	s := strings.TrimSpace(input)
+	if code, ok := aliases[strings.ToLower(s)]; ok {
+		return code, nil
+	}
+	for code, name := range names {
+		if strings.EqualFold(s, name) {
+			return code, nil
+		}
+	}
+	if !reCode.MatchString(s) {
+		return "", fmt.Errorf("无法识别的语言 %q，请使用语言代码（如 zh、en、ja）", input)
+	}
+	base, region, _ := strings.Cut(s, "-")
+	if region != "" {
+		return strings.ToLower(base) + "-" + region, nil
+	}
+	return strings.ToLower(base), nil
+}
+
+var (
+	// reNoise 识别语言时忽略的内容：代码块、行内代码、链接和 @提及
+	reNoise = regexp.MustCompile("(?s)```.*?```|`[^`]*`|https?://\\S+|@\\S+")
+	// reLatinWord 拉丁字母单词
+	reLatinWord = regexp.MustCompile(`\p{Latin}{2,}`)
+)
+
+// minLatinLetters 判定为拉丁字母语言至少需要的字母数，"ok"、"thx" 这类短回复不足以判断
+const minLatinLetters = 8
+
+// stopwords 拉丁字母语言的常用虚词，用于区分使用同一文字系统的语言
+var stopwords = map[string][]string{
+	English: {"the", "is", "are", "and", "you", "to", "of", "in", "it", "what", "how", "can", "please", "this", "with", "for", "my"},
+	"fr":    {"le", "la", "les", "est", "et", "vous", "je", "de", "des", "une", "pour", "avec", "pas", "que", "mon"},
+	"de":    {"der", "die", "das", "ist", "und", "ich", "sie", "nicht", "mit", "ein", "eine", "für", "wie", "bitte"},
+	"es":    {"el", "los", "las", "es", "y", "que", "por", "para", "con", "una", "cómo", "qué", "mi", "está"},
+	"pt":    {"o", "os", "as", "é", "e", "que", "não", "para", "com", "uma", "você", "meu", "está"},
+	"it":    {"il", "gli", "è", "e", "che", "non", "per", "con", "una", "sono", "come", "mio"},
+}
+
+// Detect 识别文本的主要语言，无法可靠判断时（太短、只有代码或符号）返回空串。
+//
+// 按文字系统判断：假名为日语，谚文为韩语，汉字为中文，西里尔字母为俄语，阿拉伯字母为阿拉伯语，
+// 拉丁字母按常用虚词区分英语、法语、德语等。中英混写时按汉字数与英文单词数比较，夹杂少量英文术语的中文仍判定为中文。
+func Detect(text string) string {
+	text = reNoise.ReplaceAllString(text, " ")
+
+	var han, kana, hangul, cyrillic, arabic, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	words := len(reLatinWord.FindAllString(text, -1))
+
+	switch {
+	case kana > 0 && kana+han >= 2:
+		return Japanese
+	case hangul >= 2:
+		return Korean
+	case han >= 2 && han >= words:
+		return Chinese
+	case cyrillic >= 4 && cyrillic >= latin:
+		return Russian
+	case arabic >= 4 && arabic >= latin:
+		return Arabic
+	case latin >= minLatinLetters && han < words:
+		return latinLanguage(text)
+	}
+	return ""
+}
+
+// latinLanguage 按常用虚词的命中数区分拉丁字母语言，没有明显胜出者时返回空串。
+func latinLanguage(text string) string {
+	hits := map[string]int{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		for code, list := range stopwords {
+			for _, sw := range list {
+				if w == sw {
+					hits[code]++
+				}
+			}
+		}
+	}
+
+	best, bestHits, tie := "", 0, false
+	for code, n := range hits {
+		switch {
+		case n > bestHits:
+			best, bestHits, tie = code, n, false
+		case n == bestHits:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return best
+}
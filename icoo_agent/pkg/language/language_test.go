@@ -0,0 +1,92 @@
+package language
+
+import (
+	"path/filepath"
+	"testing"
+
+	"icooclaw/pkg/storage"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"帮我看一下这个报错", Chinese},
+		{"帮我看下这个 error log", Chinese},
+		{"What does 你好 mean in Chinese?", English},
+		{"Can you check the deploy logs for me?", English},
+		{"この設定を確認してください", Japanese},
+		{"이 설정을 확인해 주세요", Korean},
+		{"Привет, как дела?", Russian},
+		{"Pouvez-vous vérifier les logs de la production?", "fr"},
+		{"Kannst du bitte die Logs prüfen, ich sehe nicht was los ist", "de"},
+		{"¿Puedes revisar los registros por favor? No funciona", "es"},
+		{"ok", ""},
+		{"👍", ""},
+		{"```go\nfunc main() { fmt.Println(\"hello world\") }\n```", ""},
+		{"https://example.com/some/long/path/to/a/page", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for input, want := range map[string]string{"中文": Chinese, "English": English, "JA": "ja", "pt-BR": "pt-BR", "Deutsch": "de"} {
+		if got, err := Normalize(input); err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := Normalize("klingon please"); err == nil {
+		t.Error("expected error for an unknown language")
+	}
+}
+
+func TestManager(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "language.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	m := NewManager("", store, nil)
+	if code, src := m.Current("feishu", "c1"); code != "" || src != SourceDefault {
+		t.Errorf("Current() = %q, %q, want no constraint", code, src)
+	}
+
+	// 识别出的语言随消息更新，无法识别的短消息不改变记录
+	m.Observe("feishu", "c1", "Could you summarize the meeting notes?")
+	m.Observe("feishu", "c1", "ok")
+	if code, src := m.Current("feishu", "c1"); code != English || src != SourceDetected {
+		t.Errorf("Current() = %q, %q, want detected en", code, src)
+	}
+	m.Observe("feishu", "c1", "还是用中文回复吧")
+	if code, _ := m.Current("feishu", "c1"); code != Chinese {
+		t.Errorf("Current() = %q, want zh after a Chinese message", code)
+	}
+
+	// 显式设置优先于识别结果，会话级优先于渠道级
+	if _, err := m.Select("feishu", "", "english"); err != nil {
+		t.Fatalf("Select(channel) error = %v", err)
+	}
+	if code, src := m.Current("feishu", "c1"); code != English || src != SourceChannel {
+		t.Errorf("Current() = %q, %q, want channel en", code, src)
+	}
+	if _, err := m.Select("feishu", "c1", "日语"); err != nil {
+		t.Fatalf("Select(session) error = %v", err)
+	}
+	if code, src := m.Current("feishu", "c1"); code != Japanese || src != SourceSession {
+		t.Errorf("Current() = %q, %q, want session ja", code, src)
+	}
+
+	// 清除后恢复为渠道设置
+	if _, err := m.Select("feishu", "c1", ""); err != nil {
+		t.Fatalf("Select(clear) error = %v", err)
+	}
+	if code, _ := m.Current("feishu", "c1"); code != English {
+		t.Errorf("Current() after clear = %q, want en", code)
+	}
+}
@@ -0,0 +1,109 @@
+package language
+
+import (
+	"errors"
+	"log/slog"
+
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/storage"
+)
+
+// MetadataKey 会话元数据中保存识别出的用户语言的键
+const MetadataKey = "language"
+
+// Source 回复语言的来源。
+type Source string
+
+const (
+	SourceSession  Source = "session"  // 会话设置
+	SourceChannel  Source = "channel"  // 渠道设置
+	SourceDetected Source = "detected" // 从用户消息识别
+	SourceDefault  Source = "default"  // 配置的默认语言
+)
+
+// Manager 跟踪会话的回复语言。
+//
+// 通过 /language 设置的语言保存在会话绑定中，会话级优先于渠道级；未设置时使用从用户消息中识别出的语言，
+// 保存在会话元数据中；仍未知时使用配置的默认语言，默认语言为空表示不约束回复语言。
+type Manager struct {
+	def     string
+	storage *storage.Storage
+	logger  *slog.Logger
+}
+
+// NewManager 创建回复语言管理器。
+func NewManager(def string, s *storage.Storage, logger *slog.Logger) *Manager {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Manager{def: def, storage: s, logger: logger}
+}
+
+// Observe 识别用户消息的语言并记录为会话的用户语言，无法可靠识别时保持原记录。
+func (m *Manager) Observe(channel, sessionID, text string) {
+	code := Detect(text)
+	if code == "" || m.storage == nil {
+		return
+	}
+	if code == m.detected(channel, sessionID) {
+		return
+	}
+	if err := m.storage.Session().SetMetadata(channel, sessionID, MetadataKey, code); err != nil {
+		m.logger.With("name", "【回复语言】").Warn("保存用户语言失败", "channel", channel, "session_id", sessionID, "error", err)
+	}
+}
+
+// Select 设置会话的回复语言，sessionID 为空时设置整个渠道的回复语言。
+// input 为空表示清除设置，恢复自动识别。
+func (m *Manager) Select(channel, sessionID, input string) (string, error) {
+	if m.storage == nil {
+		return "", errors.New("未配置存储")
+	}
+
+	var code string
+	if input != "" {
+		var err error
+		if code, err = Normalize(input); err != nil {
+			return "", err
+		}
+	}
+	if err := m.storage.Binding().SetLanguage(channel, sessionID, code); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Current 获取会话当前的回复语言及其来源，返回空串表示不约束回复语言。
+func (m *Manager) Current(channel, sessionID string) (string, Source) {
+	if m.storage != nil {
+		for _, sid := range []string{sessionID, ""} {
+			b, err := m.storage.Binding().GetBinding(channel, sid)
+			if err != nil {
+				if !errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+					m.logger.With("name", "【回复语言】").Warn("获取语言绑定失败", "channel", channel, "session_id", sid, "error", err)
+				}
+				continue
+			}
+			if b.Language == "" {
+				continue
+			}
+			if sid == "" {
+				return b.Language, SourceChannel
+			}
+			return b.Language, SourceSession
+		}
+		if code := m.detected(channel, sessionID); code != "" {
+			return code, SourceDetected
+		}
+	}
+	return m.def, SourceDefault
+}
+
+// detected 读取会话记录的用户语言。
+func (m *Manager) detected(channel, sessionID string) string {
+	var code string
+	if _, err := m.storage.Session().GetMetadata(channel, sessionID, MetadataKey, &code); err != nil {
+		m.logger.With("name", "【回复语言】").Warn("读取用户语言失败", "channel", channel, "session_id", sessionID, "error", err)
+	}
+	return code
+}
@@ -0,0 +1,234 @@
+// Package mail sends email through an SMTP server.
+//
+// 邮件以 multipart/alternative 格式发送纯文本和 HTML 两个版本，支持 STARTTLS、隐式 TLS（通常为 465 端口）
+// 和不加密三种连接方式，服务器支持时使用 PLAIN 认证。不加密的连接只允许向本机服务器认证。
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 连接加密方式
+const (
+	SecurityStartTLS = "starttls" // 明文连接后升级为 TLS，通常为 587 端口
+	SecurityTLS      = "tls"      // 隐式 TLS，通常为 465 端口
+	SecurityNone     = "none"     // 不加密，只用于本机或内网中继
+)
+
+// Config SMTP 配置。
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string        // 发件人，可以是 "名称 <地址>" 格式
+	Security string        // 连接加密方式，默认 starttls
+	Timeout  time.Duration // 连接和发送的超时时间，默认 30 秒
+}
+
+// Validate 检查配置是否完整。
+func (c Config) Validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("未配置 SMTP 服务器")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("SMTP 端口必须在 1 到 65535 之间")
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("发件人地址无效: %w", err)
+	}
+	switch c.Security {
+	case "", SecurityStartTLS, SecurityTLS, SecurityNone:
+	default:
+		return fmt.Errorf("未知的加密方式: %s，可用 starttls、tls 或 none", c.Security)
+	}
+	return nil
+}
+
+// Message 待发送的邮件。
+type Message struct {
+	To      []string
+	Subject string
+	Text    string // 纯文本正文
+	HTML    string // HTML 正文，为空时只发送纯文本
+}
+
+// Send 发送邮件。
+func Send(ctx context.Context, cfg Config, msg Message) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if len(msg.To) == 0 {
+		return fmt.Errorf("没有收件人")
+	}
+	from, _ := mail.ParseAddress(cfg.From)
+	to := make([]string, 0, len(msg.To))
+	for _, addr := range msg.To {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return fmt.Errorf("收件人地址无效 %q: %w", addr, err)
+		}
+		to = append(to, a.Address)
+	}
+	data, err := Compose(cfg.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := dial(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	defer c.Close()
+
+	if cfg.Security == "" || cfg.Security == SecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP 服务器不支持 STARTTLS")
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			return fmt.Errorf("STARTTLS 失败: %w", err)
+		}
+	}
+	if cfg.Username != "" {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+				return fmt.Errorf("SMTP 认证失败: %w", err)
+			}
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("设置发件人失败: %w", err)
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return fmt.Errorf("设置收件人 %s 失败: %w", addr, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return c.Quit()
+}
+
+// dial 按加密方式建立连接。
+func dial(ctx context.Context, cfg Config) (net.Conn, error) {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	if cfg.Security == SecurityTLS {
+		d := &tls.Dialer{Config: &tls.Config{ServerName: cfg.Host}}
+		return d.DialContext(ctx, "tcp", addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// Compose 生成邮件内容，包括邮件头和 quoted-printable 编码的正文。
+func Compose(from string, msg Message, now time.Time) ([]byte, error) {
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+
+	header("From", encodeAddress(from))
+	to := make([]string, 0, len(msg.To))
+	for _, addr := range msg.To {
+		to = append(to, encodeAddress(addr))
+	}
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header("Content-Type", "text/plain; charset=UTF-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		if err := writeQP(&b, msg.Text); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+	header("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", boundary))
+	b.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		header("Content-Type", part.contentType+"; charset=UTF-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		if err := writeQP(&b, part.body); err != nil {
+			return nil, err
+		}
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+// encodeAddress 编码地址中的非 ASCII 名称，无法解析时原样返回。
+func encodeAddress(addr string) string {
+	a, err := mail.ParseAddress(addr)
+	if err != nil {
+		return addr
+	}
+	return a.String()
+}
+
+// writeQP 以 quoted-printable 编码写入正文。
+func writeQP(b *bytes.Buffer, text string) error {
+	w := quotedprintable.NewWriter(b)
+	if _, err := w.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// randomBoundary 生成 multipart 分隔符。
+func randomBoundary() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "icooclaw-" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,86 @@
+package mail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Config{Host: "smtp.example.com", Port: 587, From: "icooclaw <bot@example.com>"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		edit func(*Config)
+		want string
+	}{
+		{"missing host", func(c *Config) { c.Host = "" }, "未配置 SMTP 服务器"},
+		{"bad port", func(c *Config) { c.Port = 0 }, "端口"},
+		{"bad from", func(c *Config) { c.From = "bot" }, "发件人地址无效"},
+		{"bad security", func(c *Config) { c.Security = "ssl" }, "未知的加密方式"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.edit(&c)
+			if err := c.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompose(t *testing.T) {
+	now := time.Date(2026, 3, 5, 8, 0, 0, 0, time.UTC)
+	data, err := Compose("机器人 <bot@example.com>", Message{
+		To:      []string{"admin@example.com"},
+		Subject: "活动摘要",
+		Text:    "第一行\n第二行",
+		HTML:    "<p>摘要</p>",
+	}, now)
+	if err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	var dec mime.WordDecoder
+	if subject, _ := dec.DecodeHeader(msg.Header.Get("Subject")); subject != "活动摘要" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if from, _ := msg.Header.AddressList("From"); len(from) != 1 || from[0].Name != "机器人" {
+		t.Errorf("From = %v", from)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := r.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextRawPart() error = %v", err)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		bodies = append(bodies, string(body))
+	}
+	if len(bodies) != 2 || bodies[0] != "第一行\r\n第二行" || bodies[1] != "<p>摘要</p>" {
+		t.Errorf("bodies = %q", bodies)
+	}
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+)
+
+// ConsolidateConfig 低分记忆合并参数。
+type ConsolidateConfig struct {
+	Score     ScoreConfig
+	Threshold float64       // 评分低于该值的记忆参与合并
+	MinAge    time.Duration // 创建时间不足该时长的记忆不参与合并
+	MinGroup  int           // 同一会话至少有多少条低分记忆才合并
+}
+
+// ConsolidateResult 合并结果。
+type ConsolidateResult struct {
+	Sessions  int `json:"sessions"`  // 生成摘要的会话数
+	Memories  int `json:"memories"`  // 被合并的记忆数
+	Summaries int `json:"summaries"` // 生成的摘要记忆数
+}
+
+// Consolidate 将评分衰减到阈值以下的记忆按会话合并为摘要记忆，代替直接删除。
+// 置顶记忆不参与合并；摘要记忆继承被合并记忆的标签和最高重要度。
+func Consolidate(ctx context.Context, s *storage.Storage, summarizer Summarizer, cfg ConsolidateConfig, now time.Time) (*ConsolidateResult, error) {
+	pinned := false
+	memories, err := s.Memory().List(storage.MemoryFilter{
+		Before: now.Add(-cfg.MinAge),
+		Pinned: &pinned,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	minGroup := max(cfg.MinGroup, 2)
+	groups := make(map[string][]*storage.Memory)
+	var order []string
+	for _, m := range memories {
+		if cfg.Score.Score(m, now) >= cfg.Threshold {
+			continue
+		}
+		if _, ok := groups[m.SessionID]; !ok {
+			order = append(order, m.SessionID)
+		}
+		groups[m.SessionID] = append(groups[m.SessionID], m)
+	}
+
+	res := &ConsolidateResult{}
+	for _, sessionID := range order {
+		group := groups[sessionID]
+		if len(group) < minGroup {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		summary, err := consolidateGroup(ctx, summarizer, sessionID, group)
+		if err != nil {
+			return res, err
+		}
+
+		ids := make([]string, 0, len(group))
+		for _, m := range group {
+			ids = append(ids, m.ID)
+		}
+		if err := s.Memory().Consolidate(summary, ids); err != nil {
+			return res, err
+		}
+
+		res.Sessions++
+		res.Summaries++
+		res.Memories += len(group)
+	}
+	return res, nil
+}
+
+// consolidateGroup 为一组记忆生成摘要记忆。
+func consolidateGroup(ctx context.Context, summarizer Summarizer, sessionID string, group []*storage.Memory) (*storage.Memory, error) {
+	messages := make([]providers.ChatMessage, 0, len(group))
+	var (
+		tags       storage.StringArray
+		importance float64
+	)
+	for _, m := range group {
+		messages = append(messages, providers.ChatMessage{Role: m.Role, Content: m.Content})
+		for _, t := range m.Tags {
+			if !slices.Contains(tags, t) {
+				tags = append(tags, t)
+			}
+		}
+		importance = max(importance, m.Importance)
+	}
+
+	content, err := summarizer.Summarize(ctx, messages)
+	if err != nil {
+		return nil, fmt.Errorf("生成记忆摘要失败: %w", err)
+	}
+
+	return &storage.Memory{
+		SessionID:  sessionID,
+		Role:       consts.RoleSystem.ToString(),
+		Content:    content,
+		Metadata:   mustMarshalJSON(map[string]any{"type": TypeSummary, "consolidated": len(group)}),
+		Type:       TypeSummary,
+		Tags:       tags,
+		Importance: importance,
+	}, nil
+}
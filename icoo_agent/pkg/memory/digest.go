@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/utils"
+)
+
+const (
+	// conflictMinSimilarity 判定为同一事物的最低相似度
+	conflictMinSimilarity = 0.5
+	// digestIDLen 摘要中显示的记忆 ID 前缀长度
+	digestIDLen = 8
+	// digestContentLen 摘要中记忆内容的最大字符数
+	digestContentLen = 80
+)
+
+// negations 否定词，相似的两条记忆一条含否定词、另一条不含时视为矛盾
+var negations = []string{"不", "没", "别", "无", "非", "not", "n't", "never", "no longer"}
+
+// numberPattern 匹配记忆中的数字
+var numberPattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// DigestConfig 记忆回顾摘要参数。
+type DigestConfig struct {
+	Score     ScoreConfig
+	Threshold float64       // 评分低于该值的记忆会被合并
+	Horizon   time.Duration // 预测在该时长内评分跌破阈值的记忆视为即将过期
+	MaxItems  int           // 每一类最多列出的条数
+}
+
+// Conflict 一对可能矛盾的记忆。
+type Conflict struct {
+	A *storage.Memory `json:"a"`
+	B *storage.Memory `json:"b"`
+}
+
+// Digest 记忆回顾摘要。
+type Digest struct {
+	Since     time.Time         `json:"since"`
+	Total     int               `json:"total"`     // 当前记忆总数
+	New       []*storage.Memory `json:"new"`       // 上次回顾以来新增的记忆
+	Expiring  []*storage.Memory `json:"expiring"`  // 即将因评分过低被合并的记忆
+	Conflicts []Conflict        `json:"conflicts"` // 可能矛盾的记忆
+}
+
+// IsEmpty 是否没有需要回顾的内容。
+func (d *Digest) IsEmpty() bool {
+	return len(d.New) == 0 && len(d.Expiring) == 0 && len(d.Conflicts) == 0
+}
+
+// BuildDigest 根据全部记忆生成 since 以来的回顾摘要。
+// 矛盾检测只比较同一会话内至少有一条是新增记忆的记忆对。
+func BuildDigest(memories []*storage.Memory, since, now time.Time, cfg DigestConfig) *Digest {
+	d := &Digest{Since: since, Total: len(memories)}
+
+	bySession := make(map[string][]*storage.Memory)
+	for _, m := range memories {
+		if m.Type == TypeSummary {
+			continue
+		}
+		bySession[m.SessionID] = append(bySession[m.SessionID], m)
+
+		if m.CreatedAt.After(since) {
+			d.New = append(d.New, m)
+			continue
+		}
+		if !m.Pinned && cfg.Score.Score(m, now) >= cfg.Threshold &&
+			cfg.Score.Score(m, now.Add(cfg.Horizon)) < cfg.Threshold {
+			d.Expiring = append(d.Expiring, m)
+		}
+	}
+
+	for _, m := range d.New {
+		for _, other := range bySession[m.SessionID] {
+			if other.ID == m.ID || (other.CreatedAt.After(since) && other.ID < m.ID) {
+				continue // 两条都是新增记忆时只比较一次
+			}
+			if Contradicts(m.Content, other.Content) {
+				d.Conflicts = append(d.Conflicts, Conflict{A: other, B: m})
+			}
+		}
+	}
+
+	if cfg.MaxItems > 0 {
+		d.New = d.New[:min(len(d.New), cfg.MaxItems)]
+		d.Expiring = d.Expiring[:min(len(d.Expiring), cfg.MaxItems)]
+		d.Conflicts = d.Conflicts[:min(len(d.Conflicts), cfg.MaxItems)]
+	}
+	return d
+}
+
+// Contradicts 判断两条记忆是否可能矛盾：内容相似但否定词或数字不同。
+func Contradicts(a, b string) bool {
+	if a == b {
+		return false
+	}
+	sim := utils.Jaccard(utils.Bigrams(a), utils.Bigrams(b))
+	if sim < conflictMinSimilarity || sim >= 1 {
+		return false
+	}
+	if negated(a) != negated(b) {
+		return true
+	}
+	na, nb := numberPattern.FindAllString(a, -1), numberPattern.FindAllString(b, -1)
+	return len(na) > 0 && len(nb) > 0 && !slices.Equal(na, nb)
+}
+
+// negated 文本是否含否定词。
+func negated(text string) bool {
+	lower := strings.ToLower(text)
+	for _, n := range negations {
+		if strings.Contains(lower, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// DigestAction 回顾摘要中的快捷操作，渠道可渲染为按钮，点击后以斜杠命令回传。
+type DigestAction struct {
+	Label   string `json:"label"`
+	Command string `json:"command"`
+}
+
+// Text 将回顾摘要格式化为消息文本，每条记忆附带可直接发送的操作命令。
+func (d *Digest) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📋 记忆回顾（%s 至今，共 %d 条记忆）\n", d.Since.Format("2006-01-02"), d.Total)
+
+	if len(d.New) > 0 {
+		fmt.Fprintf(&sb, "\n新增记忆 %d 条:\n", len(d.New))
+		for _, m := range d.New {
+			writeDigestItem(&sb, m)
+		}
+	}
+	if len(d.Expiring) > 0 {
+		fmt.Fprintf(&sb, "\n即将合并 %d 条（置顶可保留）:\n", len(d.Expiring))
+		for _, m := range d.Expiring {
+			writeDigestItem(&sb, m)
+		}
+	}
+	if len(d.Conflicts) > 0 {
+		fmt.Fprintf(&sb, "\n可能矛盾 %d 组:\n", len(d.Conflicts))
+		for _, c := range d.Conflicts {
+			writeDigestItem(&sb, c.A)
+			writeDigestItem(&sb, c.B)
+			sb.WriteString("\n")
+		}
+	}
+
+	sb.WriteString("\n操作: /memory pin <id> 置顶，/memory delete <id> 删除，/memory correct <id> <新内容> 修正")
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// Actions 返回回顾摘要中每条记忆的快捷操作。
+func (d *Digest) Actions() []DigestAction {
+	var actions []DigestAction
+	add := func(m *storage.Memory) {
+		id := ShortID(m.ID)
+		actions = append(actions,
+			DigestAction{Label: "置顶 " + id, Command: "/memory pin " + id},
+			DigestAction{Label: "删除 " + id, Command: "/memory delete " + id},
+		)
+	}
+	for _, m := range d.New {
+		add(m)
+	}
+	for _, m := range d.Expiring {
+		add(m)
+	}
+	for _, c := range d.Conflicts {
+		add(c.A)
+		add(c.B)
+	}
+	return actions
+}
+
+// ShortID 返回摘要和命令中使用的记忆 ID 前缀。
+func ShortID(id string) string {
+	if len(id) <= digestIDLen {
+		return id
+	}
+	return id[:digestIDLen]
+}
+
+// writeDigestItem 写入一条记忆。
+func writeDigestItem(sb *strings.Builder, m *storage.Memory) {
+	content := strings.Join(strings.Fields(m.Content), " ")
+	if utf8.RuneCountInString(content) > digestContentLen {
+		content = string([]rune(content)[:digestContentLen]) + "…"
+	}
+	fmt.Fprintf(sb, "- [%s] %s\n", ShortID(m.ID), content)
+}
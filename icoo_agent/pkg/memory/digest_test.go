@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"icooclaw/pkg/storage"
+)
+
+func TestContradicts(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"用户喜欢喝咖啡", "用户不喜欢喝咖啡", true},
+		{"The deploy window is 3pm", "The deploy window is 5pm", true},
+		{"用户喜欢喝咖啡", "用户喜欢喝咖啡", false},
+		{"用户喜欢喝咖啡", "项目截止日期是周五", false},
+		{"用户喜欢喝咖啡。", "用户喜欢喝咖啡", false},
+	}
+	for _, c := range cases {
+		if got := Contradicts(c.a, c.b); got != c.want {
+			t.Errorf("Contradicts(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	since := now.Add(-7 * 24 * time.Hour)
+	cfg := DigestConfig{
+		Score:     ScoreConfig{HalfLife: 30 * 24 * time.Hour},
+		Threshold: 0.2,
+		Horizon:   7 * 24 * time.Hour,
+	}
+
+	memory := func(id, content string, created time.Time) *storage.Memory {
+		return &storage.Memory{Model: storage.Model{ID: id, CreatedAt: created}, SessionID: "s1", Content: content}
+	}
+	memories := []*storage.Memory{
+		memory("old", "用户喜欢喝咖啡", now.Add(-30*24*time.Hour)),
+		// 评分约 0.21，一周后跌破阈值
+		memory("fading", "会议室在三楼", now.Add(-67*24*time.Hour)),
+		memory("gone", "早已过期的记忆", now.Add(-365*24*time.Hour)),
+		memory("new", "用户不喜欢喝咖啡", now.Add(-24*time.Hour)),
+	}
+
+	d := BuildDigest(memories, since, now, cfg)
+	if len(d.New) != 1 || d.New[0].ID != "new" {
+		t.Errorf("unexpected new memories: %v", d.New)
+	}
+	if len(d.Expiring) != 1 || d.Expiring[0].ID != "fading" {
+		t.Errorf("unexpected expiring memories: %v", d.Expiring)
+	}
+	if len(d.Conflicts) != 1 || d.Conflicts[0].A.ID != "old" || d.Conflicts[0].B.ID != "new" {
+		t.Errorf("unexpected conflicts: %v", d.Conflicts)
+	}
+	if len(d.Actions()) != 8 {
+		t.Errorf("expected 8 actions, got %d", len(d.Actions()))
+	}
+}
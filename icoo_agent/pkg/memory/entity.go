@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+)
+
+// entityPrompt 实体抽取提示词
+const entityPrompt = `You extract entities from a conversation turn for a long-term knowledge graph.
+Only extract concrete named entities worth remembering: people, projects, services, teams, products, repositories.
+Ignore the assistant itself, generic concepts and one-off values.
+Reply with JSON only, no explanation, in this shape:
+{"entities":[{"name":"Alice","type":"person","aliases":["ali"],"facts":["is the tech lead of the payments team"]}],
+ "relations":[{"subject":"Alice","predicate":"owns","object":"billing-service"}]}
+Facts must be short standalone statements about the entity, written in the conversation's language.
+Relations must reference entity names from the entities list. Reply {"entities":[],"relations":[]} if nothing is worth remembering.`
+
+// ExtractedEntity 抽取出的实体。
+type ExtractedEntity struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Aliases []string `json:"aliases"`
+	Facts   []string `json:"facts"`
+}
+
+// ExtractedRelation 抽取出的实体关系。
+type ExtractedRelation struct {
+	Subject   string `json:"subject"`
+	Predicate string `json:"predicate"`
+	Object    string `json:"object"`
+}
+
+// Extraction 一次实体抽取的结果。
+type Extraction struct {
+	Entities  []ExtractedEntity   `json:"entities"`
+	Relations []ExtractedRelation `json:"relations"`
+}
+
+// IsEmpty 是否未抽取到任何内容。
+func (e *Extraction) IsEmpty() bool {
+	return len(e.Entities) == 0 && len(e.Relations) == 0
+}
+
+// EntityExtractor 使用 LLM 从对话中抽取实体、事实和关系，并写入实体图。
+type EntityExtractor struct {
+	provider providers.Provider
+	model    string
+	storage  *storage.Storage
+	logger   *slog.Logger
+}
+
+// NewEntityExtractor 创建实体抽取器。
+func NewEntityExtractor(p providers.Provider, model string, s *storage.Storage, logger *slog.Logger) *EntityExtractor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &EntityExtractor{
+		provider: p,
+		model:    model,
+		storage:  s,
+		logger:   logger,
+	}
+}
+
+// Extract 从一轮对话中抽取实体。
+func (x *EntityExtractor) Extract(ctx context.Context, userText, reply string) (*Extraction, error) {
+	req := providers.ChatRequest{
+		Model: x.model,
+		Messages: []providers.ChatMessage{
+			{Role: consts.RoleSystem.ToString(), Content: entityPrompt},
+			{
+				Role:    consts.RoleUser.ToString(),
+				Content: "user: " + userText + "\nassistant: " + reply,
+			},
+		},
+	}
+
+	resp, err := x.provider.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return ParseExtraction(resp.Content)
+}
+
+// Process 抽取实体并保存，返回保存的实体数。
+func (x *EntityExtractor) Process(ctx context.Context, sessionID, userText, reply string) (int, error) {
+	ext, err := x.Extract(ctx, userText, reply)
+	if err != nil {
+		return 0, err
+	}
+	return SaveExtraction(x.storage.Entity(), ext, sessionID)
+}
+
+// ParseExtraction 解析模型返回的抽取结果，容忍代码块包裹和前后的说明文字。
+func ParseExtraction(content string) (*Extraction, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("实体抽取结果不是 JSON: %s", content)
+	}
+
+	var ext Extraction
+	if err := json.Unmarshal([]byte(content[start:end+1]), &ext); err != nil {
+		return nil, fmt.Errorf("解析实体抽取结果失败: %w", err)
+	}
+	return &ext, nil
+}
+
+// SaveExtraction 将抽取结果写入实体图，关系中未在实体列表出现的名称会自动创建实体。
+func SaveExtraction(s *storage.EntityStorage, ext *Extraction, sessionID string) (int, error) {
+	ids := make(map[string]string)
+	upsert := func(name, entityType string, aliases []string) (string, error) {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if id, ok := ids[key]; ok && entityType == "" && len(aliases) == 0 {
+			return id, nil
+		}
+		e, err := s.Upsert(name, entityType, "", aliases)
+		if err != nil {
+			return "", err
+		}
+		ids[key] = e.ID
+		return e.ID, nil
+	}
+
+	saved := 0
+	for _, ent := range ext.Entities {
+		if strings.TrimSpace(ent.Name) == "" {
+			continue
+		}
+		id, err := upsert(ent.Name, ent.Type, ent.Aliases)
+		if err != nil {
+			return saved, err
+		}
+		for _, fact := range ent.Facts {
+			if err := s.AddFact(id, fact, sessionID); err != nil {
+				return saved, err
+			}
+		}
+		saved++
+	}
+
+	for _, rel := range ext.Relations {
+		if strings.TrimSpace(rel.Subject) == "" || strings.TrimSpace(rel.Object) == "" {
+			continue
+		}
+		subject, err := upsert(rel.Subject, "", nil)
+		if err != nil {
+			return saved, err
+		}
+		object, err := upsert(rel.Object, "", nil)
+		if err != nil {
+			return saved, err
+		}
+		if err := s.AddRelation(subject, rel.Predicate, object, sessionID); err != nil {
+			return saved, err
+		}
+	}
+	return saved, nil
+}
@@ -0,0 +1,30 @@
+package memory
+
+import "testing"
+
+func TestParseExtraction(t *testing.T) {
+	content := "好的：\n```json\n" +
+		`{"entities":[{"name":"Alice","type":"person","facts":["负责支付团队"]},{"name":"billing-service","type":"service"}],` +
+		`"relations":[{"subject":"Alice","predicate":"owns","object":"billing-service"}]}` +
+		"\n```"
+
+	ext, err := ParseExtraction(content)
+	if err != nil {
+		t.Fatalf("ParseExtraction() error = %v", err)
+	}
+	if len(ext.Entities) != 2 || ext.Entities[0].Facts[0] != "负责支付团队" {
+		t.Errorf("unexpected entities: %+v", ext.Entities)
+	}
+	if len(ext.Relations) != 1 || ext.Relations[0].Predicate != "owns" {
+		t.Errorf("unexpected relations: %+v", ext.Relations)
+	}
+
+	empty, err := ParseExtraction(`{"entities":[],"relations":[]}`)
+	if err != nil || !empty.IsEmpty() {
+		t.Errorf("expected empty extraction, got %+v, %v", empty, err)
+	}
+
+	if _, err := ParseExtraction("没有实体"); err == nil {
+		t.Error("expected error for non-JSON content")
+	}
+}
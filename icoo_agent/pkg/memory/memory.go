@@ -71,7 +71,7 @@ func (l *DefaultLoader) Save(ctx context.Context, sessionKey, role, content stri
 
 // Clear clears memory for a session.
 func (l *DefaultLoader) Clear(ctx context.Context, sessionKey string) error {
-	return l.storage.Message().Delete(sessionKey)
+	return l.storage.Message().DeleteBySession(sessionKey)
 }
 
 // Summarizer generates summaries of conversations.
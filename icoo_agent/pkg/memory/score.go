@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/utils"
+)
+
+// TypeSummary 由低分记忆合并生成的摘要记忆类型
+const TypeSummary = "summary"
+
+// ScoreConfig 记忆重要度评分参数。
+//
+// 评分 = (1 + 显式重要度 + AccessWeight×ln(1+检索次数) + 置顶加分) × 0.5^(闲置时长/HalfLife)，
+// 闲置时长从最近一次被检索（或创建）开始计算，置顶记忆不衰减。
+type ScoreConfig struct {
+	HalfLife     time.Duration // 评分衰减半衰期
+	AccessWeight float64       // 检索次数权重
+	PinBonus     float64       // 置顶加分
+}
+
+// DefaultScoreConfig 返回默认评分参数。
+func DefaultScoreConfig() ScoreConfig {
+	return ScoreConfig{
+		HalfLife:     30 * 24 * time.Hour,
+		AccessWeight: 0.5,
+		PinBonus:     1,
+	}
+}
+
+// Score 计算记忆在 now 时刻的重要度评分。
+func (c ScoreConfig) Score(m *storage.Memory, now time.Time) float64 {
+	score := 1 + m.Importance + c.AccessWeight*math.Log1p(float64(m.AccessCount))
+	if m.Pinned {
+		return score + c.PinBonus
+	}
+	if c.HalfLife <= 0 {
+		return score
+	}
+
+	last := m.CreatedAt
+	if m.LastAccessedAt.After(last) {
+		last = m.LastAccessedAt
+	}
+	idle := now.Sub(last)
+	if idle <= 0 {
+		return score
+	}
+	return score * math.Pow(0.5, float64(idle)/float64(c.HalfLife))
+}
+
+// Ranked 带评分的检索结果。
+type Ranked struct {
+	*storage.Memory
+	Score     float64 `json:"score"`     // 重要度评分
+	Relevance float64 `json:"relevance"` // 与查询的相关度
+}
+
+// Rank 按 评分×相关度 对记忆排序，query 为空时只按评分排序，
+// 相关度低于 minRelevance 或为 0 的记忆被过滤。limit 小于等于 0 时返回全部结果。
+func (c ScoreConfig) Rank(memories []*storage.Memory, query string, minRelevance float64, now time.Time, limit int) []Ranked {
+	if query == "" {
+		return c.rank(memories, func(*storage.Memory) (float64, bool) { return 1, true }, now, limit)
+	}
+	return c.rank(memories, keywordRelevance(query, minRelevance), now, limit)
+}
+
+// keywordRelevance 返回按字符二元组包含度计算相关度的函数。
+func keywordRelevance(query string, minRelevance float64) func(*storage.Memory) (float64, bool) {
+	q := utils.Bigrams(query)
+	return func(m *storage.Memory) (float64, bool) {
+		r := utils.Containment(q, utils.Bigrams(m.Content))
+		return r, r > 0 && r >= minRelevance
+	}
+}
+
+// rank 按 评分×相关度 排序，relevance 返回 false 的记忆被过滤。
+func (c ScoreConfig) rank(memories []*storage.Memory, relevance func(*storage.Memory) (float64, bool), now time.Time, limit int) []Ranked {
+	ranked := make([]Ranked, 0, len(memories))
+	for _, m := range memories {
+		rel, ok := relevance(m)
+		if !ok {
+			continue
+		}
+		ranked = append(ranked, Ranked{Memory: m, Score: c.Score(m, now), Relevance: rel})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score*ranked[i].Relevance > ranked[j].Score*ranked[j].Relevance
+	})
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// Recall 检索记忆并按 评分×相关度 排序，返回的记忆会记录一次访问，提高其后续评分。
+func (c ScoreConfig) Recall(s *storage.Storage, filter storage.MemoryFilter, query string, minRelevance float64, limit int) ([]Ranked, error) {
+	memories, err := s.Memory().List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return touch(s, c.Rank(memories, query, minRelevance, time.Now(), limit))
+}
+
+// touch 记录检索结果的访问。
+func touch(s *storage.Storage, ranked []Ranked) ([]Ranked, error) {
+	ids := make([]string, 0, len(ranked))
+	for _, r := range ranked {
+		ids = append(ids, r.ID)
+	}
+	if err := s.Memory().Touch(ids); err != nil {
+		return nil, err
+	}
+	return ranked, nil
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/storage"
+)
+
+func TestScoreConfig_Score(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	cfg := ScoreConfig{HalfLife: 10 * 24 * time.Hour, AccessWeight: 1, PinBonus: 1}
+
+	fresh := &storage.Memory{Model: storage.Model{CreatedAt: now}}
+	if got := cfg.Score(fresh, now); got != 1 {
+		t.Errorf("fresh memory score = %v, want 1", got)
+	}
+
+	old := &storage.Memory{Model: storage.Model{CreatedAt: now.Add(-20 * 24 * time.Hour)}}
+	if got := cfg.Score(old, now); math.Abs(got-0.25) > 1e-9 {
+		t.Errorf("two half-lives should quarter the score, got %v", got)
+	}
+
+	// 检索会刷新衰减起点并提高评分
+	accessed := &storage.Memory{
+		Model:          storage.Model{CreatedAt: now.Add(-20 * 24 * time.Hour)},
+		AccessCount:    3,
+		LastAccessedAt: now,
+	}
+	if got, want := cfg.Score(accessed, now), 1+math.Log1p(3); math.Abs(got-want) > 1e-9 {
+		t.Errorf("accessed memory score = %v, want %v", got, want)
+	}
+
+	pinned := &storage.Memory{Model: storage.Model{CreatedAt: now.Add(-365 * 24 * time.Hour)}, Pinned: true}
+	if got := cfg.Score(pinned, now); got != 2 {
+		t.Errorf("pinned memory should not decay, got %v", got)
+	}
+}
+
+func TestScoreConfig_Rank(t *testing.T) {
+	now := time.Now()
+	cfg := DefaultScoreConfig()
+	memories := []*storage.Memory{
+		{Model: storage.Model{ID: "old", CreatedAt: now.Add(-90 * 24 * time.Hour)}, Content: "项目 X 的负责人是 Alice"},
+		{Model: storage.Model{ID: "new", CreatedAt: now}, Content: "项目 X 使用 Go 开发"},
+		{Model: storage.Model{ID: "other", CreatedAt: now}, Content: "用户喜欢喝绿茶"},
+	}
+
+	ranked := cfg.Rank(memories, "项目X", 0, now, 0)
+	if len(ranked) != 2 {
+		t.Fatalf("expected unrelated memory to be filtered, got %d results", len(ranked))
+	}
+	if ranked[0].ID != "new" || ranked[1].ID != "old" {
+		t.Errorf("expected fresher memory first, got %s, %s", ranked[0].ID, ranked[1].ID)
+	}
+
+	if got := cfg.Rank(memories, "", 0, now, 1); len(got) != 1 {
+		t.Errorf("limit should cap results, got %d", len(got))
+	}
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/utils"
+)
+
+// Semantic 语义检索参数。
+//
+// 用查询向量与记忆向量的余弦相似度作为相关度，向量由嵌入作业或检索时顺带生成，
+// 尚未生成向量（或由其他模型生成）的记忆仍按关键词计算相关度。
+type Semantic struct {
+	Embedder      providers.Embedder // 嵌入接口
+	Model         string             // 嵌入模型，格式为 提供商/模型，与记忆的 EmbeddingModel 对应
+	MinSimilarity float64            // 最低余弦相似度
+	EmbedMissing  int                // 每次检索顺带为缺少向量的记忆生成向量的最多条数，0 表示不生成
+}
+
+// Cosine 计算两个向量的余弦相似度，长度不同或存在零向量时返回 0。
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// RankSemantic 按 评分×相关度 对记忆排序，有 sem.Model 向量的记忆以与 queryVec 的余弦相似度为相关度，
+// 低于 sem.MinSimilarity 的被过滤；其余记忆按关键词计算相关度，低于 minRelevance 的被过滤。
+func (c ScoreConfig) RankSemantic(memories []*storage.Memory, query string, queryVec []float32, sem Semantic, minRelevance float64, now time.Time, limit int) []Ranked {
+	keyword := keywordRelevance(query, minRelevance)
+	return c.rank(memories, func(m *storage.Memory) (float64, bool) {
+		if !hasEmbedding(m, sem.Model, len(queryVec)) {
+			return keyword(m)
+		}
+		sim := Cosine(queryVec, m.Embedding)
+		return sim, sim > 0 && sim >= sem.MinSimilarity
+	}, now, limit)
+}
+
+// RecallSemantic 语义检索记忆并按 评分×相关度 排序，返回的记忆会记录一次访问。
+// 查询向量与最多 sem.EmbedMissing 条缺少向量的记忆在同一次嵌入请求中生成，新向量会保存下来。
+func (c ScoreConfig) RecallSemantic(ctx context.Context, s *storage.Storage, sem Semantic, filter storage.MemoryFilter, query string, minRelevance float64, limit int) ([]Ranked, error) {
+	parts := utils.SplitProviderModel(sem.Model)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("嵌入模型格式错误: %s，应为 提供商/模型", sem.Model)
+	}
+
+	memories, err := s.Memory().List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []*storage.Memory
+	for _, m := range memories {
+		if len(missing) >= sem.EmbedMissing {
+			break
+		}
+		if m.EmbeddingModel != sem.Model || len(m.Embedding) == 0 {
+			missing = append(missing, m)
+		}
+	}
+
+	inputs := make([]string, 0, len(missing)+1)
+	inputs = append(inputs, query)
+	for _, m := range missing {
+		inputs = append(inputs, m.Content)
+	}
+	embeddings, err := sem.Embedder.Embed(ctx, parts[1], inputs)
+	if err != nil {
+		return nil, fmt.Errorf("生成向量失败: %w", err)
+	}
+	for i, m := range missing {
+		m.Embedding, m.EmbeddingModel = embeddings[i+1], sem.Model
+		if err := s.Memory().SetEmbedding(m.ID, m.Embedding, sem.Model); err != nil {
+			return nil, err
+		}
+	}
+
+	return touch(s, c.RankSemantic(memories, query, embeddings[0], sem, minRelevance, time.Now(), limit))
+}
+
+// hasEmbedding 记忆是否有指定模型生成的、维度为 dim 的向量。
+func hasEmbedding(m *storage.Memory, model string, dim int) bool {
+	return m.EmbeddingModel == model && len(m.Embedding) == dim && dim > 0
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/storage"
+)
+
+// topicEmbedder 按文本涉及的话题生成向量，模拟语义相近而字面不同的文本。
+type topicEmbedder struct {
+	calls int
+}
+
+func (e *topicEmbedder) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	e.calls++
+	topics := [][]string{{"茶", "饮料", "喝"}, {"部署", "服务器", "上线"}}
+	out := make([][]float32, len(inputs))
+	for i, in := range inputs {
+		v := make([]float32, len(topics))
+		for j, words := range topics {
+			for _, w := range words {
+				if strings.Contains(in, w) {
+					v[j]++
+				}
+			}
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func TestCosine(t *testing.T) {
+	if got := Cosine([]float32{1, 0}, []float32{2, 0}); math.Abs(got-1) > 1e-9 {
+		t.Errorf("parallel vectors = %v", got)
+	}
+	if got := Cosine([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("orthogonal vectors = %v", got)
+	}
+	if got := Cosine([]float32{1}, []float32{1, 0}); got != 0 {
+		t.Errorf("different lengths = %v", got)
+	}
+}
+
+func TestScoreConfig_RecallSemantic(t *testing.T) {
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "semantic.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	for _, content := range []string{"用户偏好乌龙茶", "周五晚上服务器上线", "用户住在杭州"} {
+		s.Memory().Save(&storage.Memory{SessionID: "s1", Content: content})
+	}
+
+	e := &topicEmbedder{}
+	sem := Semantic{Embedder: e, Model: "test/embed", MinSimilarity: 0.5, EmbedMissing: 10}
+	ranked, err := DefaultScoreConfig().RecallSemantic(context.Background(), s, sem, storage.MemoryFilter{SessionID: "s1"}, "喝点什么饮料", 0.3, 5)
+	if err != nil {
+		t.Fatalf("RecallSemantic() error = %v", err)
+	}
+	if len(ranked) != 1 || ranked[0].Content != "用户偏好乌龙茶" {
+		t.Fatalf("RecallSemantic() = %+v", ranked)
+	}
+
+	// 缺少的向量已保存，再次检索只为查询生成向量
+	pending, _ := s.Memory().CountForEmbedding(storage.MemoryFilter{}, sem.Model)
+	if pending != 0 {
+		t.Errorf("memories without embedding = %d", pending)
+	}
+	sem.EmbedMissing = 0
+	if ranked, _ := DefaultScoreConfig().RecallSemantic(context.Background(), s, sem, storage.MemoryFilter{}, "什么时候部署", 0.3, 5); len(ranked) != 1 || ranked[0].Content != "周五晚上服务器上线" {
+		t.Errorf("RecallSemantic() = %+v", ranked)
+	}
+	if e.calls != 2 {
+		t.Errorf("Embed() calls = %d", e.calls)
+	}
+}
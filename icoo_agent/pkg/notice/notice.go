@@ -0,0 +1,219 @@
+// Package notice renders the system messages icooclaw sends without calling the model,
+// such as offline notices, queue positions, cost confirmations and errors.
+//
+// 每种提示都有内置的中文和英文文本，运维人员可以在配置中按语言和渠道覆盖措辞，无需修改代码：
+//
+//	[[notices.templates]]
+//	key = "offline"
+//	language = "en"
+//	channel = "feishu"
+//	text = "We're offline for a moment. Your message is queued and we'll reply as soon as we're back."
+//
+// 文本是 Go 模板，可以引用 {{.Channel}}、{{.UserID}}、{{.UserName}} 以及各提示自己的变量，
+// 如排队提示的 {{.Ahead}}。查找顺序：用户语言+渠道、用户语言、渠道、通用的配置模板，然后是用户语言的内置文本，
+// 再依次回退到默认语言和中文。语言先按完整代码（如 zh-Hant）查找，再按主语言（zh）查找。
+package notice
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/template"
+
+	lang "icooclaw/pkg/language"
+)
+
+// 提示类型
+const (
+	Offline     = "offline"      // 提供商不可用，消息已排队
+	Stopped     = "stopped"      // 用户停止了当前回复
+	QueueFull   = "queue_full"   // 排队的消息已达上限
+	Queued      = "queued"       // 并发已满，消息开始排队，变量 Ahead
+	QueueMoved  = "queue_moved"  // 排队位置变化，变量 Ahead
+	CostConfirm = "cost_confirm" // 本轮预计用量达到阈值，等待确认，变量 Estimate、Model、Tools
+	Denied      = "denied"       // 消息未通过授权，变量 Reason
+	Blocked     = "blocked"      // 回复被提供商的内容安全策略拦截
+	Error       = "error"        // 处理消息失败
+)
+
+// DefaultLanguage 未配置默认语言时使用的语言
+const DefaultLanguage = "zh"
+
+// builtin 内置文本，按提示类型和语言索引
+var builtin = map[string]map[string]string{
+	Offline: {
+		"zh": "智能体暂时离线，您的消息已排队，服务恢复后会自动处理并回复。",
+		"en": "The assistant is temporarily offline. Your message has been queued and will be answered once service is restored.",
+	},
+	Stopped: {
+		"zh": "⏹ 已停止当前回复",
+		"en": "⏹ Stopped the current reply",
+	},
+	QueueFull: {
+		"zh": "当前排队的消息过多，请稍后再试",
+		"en": "Too many messages are waiting right now, please try again later",
+	},
+	Queued: {
+		"zh": "当前处理的消息较多，已为你排队，前面还有 {{.Ahead}} 条消息",
+		"en": "We're handling a lot of messages right now. You're in the queue with {{.Ahead}} message(s) ahead of you",
+	},
+	QueueMoved: {
+		"zh": "排队中，前面还有 {{.Ahead}} 条消息",
+		"en": "Still queued, {{.Ahead}} message(s) ahead of you",
+	},
+	CostConfirm: {
+		"zh": "本轮预计使用{{.Estimate}}（模型 {{.Model}}{{if .Tools}}，可调用 {{.Tools}} 个工具{{end}}），是否继续？\n回复 /cost yes 继续，/cost no 取消。",
+		"en": "This turn is estimated to use {{.Estimate}} (model {{.Model}}{{if .Tools}}, {{.Tools}} tools available{{end}}). Continue?\nReply /cost yes to continue or /cost no to cancel.",
+	},
+	Denied: {
+		"zh": "该消息未通过授权{{if .Reason}}: {{.Reason}}{{end}}",
+		"en": "This message was not authorized{{if .Reason}}: {{.Reason}}{{end}}",
+	},
+	Blocked: {
+		"zh": "抱歉，这条回复被模型提供商的内容安全策略拦截，无法显示。可以换个说法再试。",
+		"en": "Sorry, this reply was blocked by the model provider's content policy. Try rephrasing your request.",
+	},
+	Error: {
+		"zh": "抱歉，处理您的消息时出错了，请稍后再试。",
+		"en": "Sorry, something went wrong while handling your message. Please try again later.",
+	},
+}
+
+// Keys 返回所有提示类型，按名称排序。
+func Keys() []string {
+	keys := make([]string, 0, len(builtin))
+	for key := range builtin {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Template 配置的提示模板。
+type Template struct {
+	Key      string `mapstructure:"key" json:"key"`           // 提示类型，如 offline、queued
+	Language string `mapstructure:"language" json:"language"` // 适用的语言代码，为空表示全部语言
+	Channel  string `mapstructure:"channel" json:"channel"`   // 适用的渠道，为空表示全部渠道
+	Text     string `mapstructure:"text" json:"text"`         // 模板文本
+}
+
+// Data 渲染提示时可引用的变量。
+type Data struct {
+	Channel  string
+	UserID   string
+	UserName string
+	Vars     map[string]any
+}
+
+// Catalog 提示模板目录。
+type Catalog struct {
+	language  string
+	templates map[string]*template.Template // 键为 key/language/channel
+	logger    *slog.Logger
+}
+
+// New 校验并编译配置的模板，language 为空时默认语言为中文。语言可以使用代码或名称，如 en、英语。
+func New(language string, templates []Template, logger *slog.Logger) (*Catalog, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if language == "" {
+		language = DefaultLanguage
+	}
+	language, err := lang.Normalize(language)
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{language: language, templates: make(map[string]*template.Template), logger: logger}
+
+	for key, texts := range builtin {
+		for code, text := range texts {
+			c.templates[index(key, "builtin:"+code, "")] = template.Must(template.New(key).Parse(text))
+		}
+	}
+	for i, t := range templates {
+		if _, ok := builtin[t.Key]; !ok {
+			return nil, fmt.Errorf("第 %d 个模板的提示类型 %q 无效，可选 %s", i+1, t.Key, strings.Join(Keys(), "、"))
+		}
+		if strings.TrimSpace(t.Text) == "" {
+			return nil, fmt.Errorf("第 %d 个模板 %s 缺少 text", i+1, t.Key)
+		}
+		if t.Language != "" {
+			if t.Language, err = lang.Normalize(t.Language); err != nil {
+				return nil, fmt.Errorf("第 %d 个模板 %s: %w", i+1, t.Key, err)
+			}
+		}
+		id := index(t.Key, t.Language, t.Channel)
+		if _, ok := c.templates[id]; ok {
+			return nil, fmt.Errorf("第 %d 个模板重复：%s 语言 %q 渠道 %q 已配置", i+1, t.Key, t.Language, t.Channel)
+		}
+		tmpl, err := template.New(t.Key).Option("missingkey=zero").Parse(t.Text)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个模板 %s 解析失败: %w", i+1, t.Key, err)
+		}
+		c.templates[id] = tmpl
+	}
+	return c, nil
+}
+
+// index 模板的索引键。
+func index(key, language, channel string) string {
+	return key + "/" + language + "/" + channel
+}
+
+// Render 按语言和渠道渲染提示，language 为空时使用默认语言。
+// 配置的模板渲染失败时记录日志并使用内置文本。
+func (c *Catalog) Render(key, language string, data Data) string {
+	values := map[string]any{
+		"Channel":  data.Channel,
+		"UserID":   data.UserID,
+		"UserName": data.UserName,
+	}
+	for k, v := range data.Vars {
+		values[k] = v
+	}
+
+	for _, tmpl := range c.lookup(key, language, data.Channel) {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, values); err != nil {
+			c.logger.With("name", "【系统提示】").Warn("提示模板渲染失败", "key", key, "language", language, "channel", data.Channel, "error", err)
+			continue
+		}
+		return buf.String()
+	}
+	return ""
+}
+
+// lookup 按优先级返回候选模板。
+func (c *Catalog) lookup(key, language, channel string) []*template.Template {
+	if language == "" {
+		language = c.language
+	}
+	user := expand(language)
+	var ids []string
+	for _, code := range user {
+		ids = append(ids, index(key, code, channel), index(key, code, ""))
+	}
+	ids = append(ids, index(key, "", channel), index(key, "", ""))
+	for _, code := range slices.Concat(user, expand(c.language), []string{DefaultLanguage}) {
+		ids = append(ids, index(key, code, channel), index(key, code, ""), index(key, "builtin:"+code, ""))
+	}
+
+	var candidates []*template.Template
+	for _, id := range ids {
+		if tmpl, ok := c.templates[id]; ok && !slices.Contains(candidates, tmpl) {
+			candidates = append(candidates, tmpl)
+		}
+	}
+	return candidates
+}
+
+// expand 返回语言代码及其主语言，如 zh-Hant 返回 zh-Hant 和 zh。
+func expand(language string) []string {
+	if base, _, ok := strings.Cut(language, "-"); ok {
+		return []string{language, base}
+	}
+	return []string{language}
+}
@@ -0,0 +1,83 @@
+package notice
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalog_Render(t *testing.T) {
+	c, err := New("", []Template{
+		{Key: Offline, Text: "服务维护中，{{.UserName}} 的消息已排队"},
+		{Key: Offline, Language: "en", Text: "We're offline, your message is queued"},
+		{Key: Offline, Language: "en", Channel: "feishu", Text: "Feishu: back soon"},
+		{Key: Queued, Channel: "telegram", Text: "⏳ {{.Ahead}} ahead"},
+		{Key: Stopped, Language: "ja", Text: "停止しました"},
+		{Key: Error, Language: "英语", Text: "Oops"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		language string
+		data     Data
+		want     string
+	}{
+		{"language and channel", Offline, "en", Data{Channel: "feishu"}, "Feishu: back soon"},
+		{"language", Offline, "en", Data{Channel: "telegram"}, "We're offline, your message is queued"},
+		{"any language", Offline, "ja", Data{UserName: "小王"}, "服务维护中，小王 的消息已排队"},
+		{"channel", Queued, "en", Data{Channel: "telegram", Vars: map[string]any{"Ahead": 3}}, "⏳ 3 ahead"},
+		{"builtin language", Queued, "en", Data{Channel: "feishu", Vars: map[string]any{"Ahead": 3}},
+			"We're handling a lot of messages right now. You're in the queue with 3 message(s) ahead of you"},
+		{"base language", Stopped, "ja-JP", Data{}, "停止しました"},
+		{"default language", Stopped, "", Data{}, "⏹ 已停止当前回复"},
+		{"unknown language", Stopped, "fr", Data{}, "⏹ 已停止当前回复"},
+		{"language name", Error, "en", Data{}, "Oops"},
+		{"conditional", Denied, "zh", Data{Vars: map[string]any{"Reason": "超出额度"}}, "该消息未通过授权: 超出额度"},
+		{"conditional empty", Denied, "en", Data{}, "This message was not authorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Render(tt.key, tt.language, tt.data); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalog_DefaultLanguage(t *testing.T) {
+	c, err := New("en", nil, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := c.Render(Stopped, "", Data{}); got != "⏹ Stopped the current reply" {
+		t.Errorf("Render() = %q", got)
+	}
+	// 用户语言有内置文本时优先于默认语言
+	if got := c.Render(Stopped, "zh", Data{}); got != "⏹ 已停止当前回复" {
+		t.Errorf("Render() = %q", got)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl []Template
+		want string
+	}{
+		{"unknown key", []Template{{Key: "greeting", Text: "hi"}}, "提示类型"},
+		{"empty text", []Template{{Key: Offline}}, "缺少 text"},
+		{"duplicate", []Template{{Key: Offline, Text: "a"}, {Key: Offline, Text: "b"}}, "重复"},
+		{"bad template", []Template{{Key: Offline, Text: "{{.Ahead"}}, "解析失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New("", tt.tmpl, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
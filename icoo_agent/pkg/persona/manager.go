@@ -0,0 +1,251 @@
+package persona
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/storage"
+)
+
+// fileStamp 文件快照，用于判断人设文件是否变更。
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Manager 管理人设的加载、热重载和会话选择。
+type Manager struct {
+	dir      string
+	storage  *storage.Storage
+	logger   *slog.Logger
+	personas map[string]*Persona
+	stamps   map[string]fileStamp
+	mu       sync.RWMutex
+}
+
+// NewManager 创建人设管理器，人设目录为 workspace/personas。
+func NewManager(workspace string, s *storage.Storage, logger *slog.Logger) *Manager {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Manager{
+		dir:      filepath.Join(workspace, PERSONA_DIR),
+		storage:  s,
+		logger:   logger,
+		personas: make(map[string]*Persona),
+		stamps:   make(map[string]fileStamp),
+	}
+}
+
+// Dir 返回人设目录。
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// Load 重新加载人设目录下的全部人设文件。
+func (m *Manager) Load() error {
+	stamps, err := m.scan()
+	if err != nil {
+		return err
+	}
+
+	personas := make(map[string]*Persona, len(stamps))
+	for path := range stamps {
+		p, err := ParseFile(path)
+		if err != nil {
+			m.logger.With("name", "【人设】").Warn("解析人设文件失败", "path", path, "error", err)
+			continue
+		}
+		if _, ok := personas[p.Name]; ok {
+			m.logger.With("name", "【人设】").Warn("人设名称重复，已忽略", "name", p.Name, "path", path)
+			continue
+		}
+		personas[p.Name] = p
+	}
+
+	m.mu.Lock()
+	m.personas = personas
+	m.stamps = stamps
+	m.mu.Unlock()
+
+	m.logger.With("name", "【人设】").Debug("人设加载完成", "count", len(personas))
+	return nil
+}
+
+// Watch 定期检查人设目录，文件变更时自动重载。
+func (m *Manager) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !m.changed() {
+				continue
+			}
+			if err := m.Load(); err != nil {
+				m.logger.With("name", "【人设】").Warn("重载人设失败", "error", err)
+				continue
+			}
+			m.logger.With("name", "【人设】").Info("人设文件已变更，已重新加载")
+		}
+	}
+}
+
+// Get 获取指定名称的人设。
+func (m *Manager) Get(name string) (*Persona, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.personas[name]
+	return p, ok
+}
+
+// List 列出所有人设，按名称排序。
+func (m *Manager) List() []*Persona {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]*Persona, 0, len(m.personas))
+	for _, p := range m.personas {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Select 为会话选择人设，sessionID 为空时设置整个渠道的默认人设。
+// name 为空表示清除选择。
+func (m *Manager) Select(channel, sessionID, name string) (*Persona, error) {
+	if m.storage == nil {
+		return nil, errors.New("未配置存储")
+	}
+
+	var p *Persona
+	if name != "" {
+		var ok bool
+		p, ok = m.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("人设不存在: %s", name)
+		}
+	}
+
+	if err := m.storage.Binding().SetPersona(channel, sessionID, name); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Current 获取会话当前生效的人设，优先会话级选择，其次渠道级选择。
+func (m *Manager) Current(channel, sessionID string) *Persona {
+	if m.storage == nil {
+		return nil
+	}
+
+	for _, sid := range []string{sessionID, ""} {
+		b, err := m.storage.Binding().GetBinding(channel, sid)
+		if err != nil {
+			if !errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+				m.logger.With("name", "【人设】").Warn("获取人设绑定失败", "channel", channel, "session_id", sid, "error", err)
+			}
+			continue
+		}
+		if b.Persona == "" {
+			continue
+		}
+		if p, ok := m.Get(b.Persona); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// Resolve 返回处理消息的人设：name 为已存在的人设时使用该人设（如路由规则指定的人设），
+// 否则为会话当前生效的人设。
+func (m *Manager) Resolve(channel, sessionID, name string) *Persona {
+	if name != "" {
+		if p, ok := m.Get(name); ok {
+			return p
+		}
+	}
+	return m.Current(channel, sessionID)
+}
+
+// MatchPrefix 返回以消息前缀选择的人设和去掉前缀后的消息，多个前缀匹配时取最长的，没有匹配时返回 nil。
+func (m *Manager) MatchPrefix(text string) (*Persona, string) {
+	text = strings.TrimLeftFunc(text, unicode.IsSpace)
+
+	var (
+		matched *Persona
+		rest    string
+		best    int
+	)
+	for _, p := range m.List() {
+		if n, remain := p.matchPrefix(text); n > best {
+			matched, rest, best = p, remain, n
+		}
+	}
+	return matched, rest
+}
+
+// changed 判断人设目录是否有变更。
+func (m *Manager) changed() bool {
+	stamps, err := m.scan()
+	if err != nil {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(stamps) != len(m.stamps) {
+		return true
+	}
+	for path, st := range stamps {
+		old, ok := m.stamps[path]
+		if !ok || !old.modTime.Equal(st.modTime) || old.size != st.size {
+			return true
+		}
+	}
+	return false
+}
+
+// scan 扫描人设目录下的 markdown 文件。
+func (m *Manager) scan() (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stamps, nil
+		}
+		return nil, fmt.Errorf("failed to read persona dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".md") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		stamps[filepath.Join(m.dir, entry.Name())] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
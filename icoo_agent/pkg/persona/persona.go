@@ -0,0 +1,273 @@
+// Package persona provides persona pack loading for icooclaw.
+//
+// 人设包存放在工作空间的 personas/ 目录下，每个 markdown 文件定义一个人设：
+//
+//	---
+//	name: teacher
+//	description: 耐心的编程老师
+//	voice: 温和、循序渐进，多用示例
+//	tools: [read_file, shell_command]
+//	greeting: 你好，今天想学点什么？
+//	model: deepseek/deepseek-chat
+//	allow_tools: [read_file, list_directory, shell_command]
+//	memory: isolated
+//	prefixes: ["@teacher", "老师："]
+//	---
+//	你是一位耐心的编程老师……
+//
+// 元数据之后的正文即为人设的系统提示词。tools 只是提示模型优先使用的工具；
+// model、allow_tools / deny_tools 和 memory 让人设成为独立的智能体配置：使用单独的模型、
+// 只能调用限定的工具、对话历史和记忆与其他人设分开保存。以 prefixes 中任一前缀开头的消息交给该人设处理。
+package persona
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PERSONA_DIR 人设目录名
+const PERSONA_DIR = "personas"
+
+// 记忆范围。
+const (
+	// MemoryShared 与会话中的其他人设共用对话历史和记忆（默认）
+	MemoryShared = "shared"
+	// MemoryIsolated 对话历史和记忆按人设单独保存
+	MemoryIsolated = "isolated"
+)
+
+var (
+	// namePattern 校验人设名称：字母数字与连字符/下划线
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9]+([-_][a-zA-Z0-9]+)*$`)
+	// reFrontmatter 匹配 --- 包裹的元数据
+	reFrontmatter = regexp.MustCompile(`(?s)^---(?:\r\n|\n|\r)(.*?)(?:\r\n|\n|\r)---(?:\r\n|\n|\r)*`)
+)
+
+// Persona 人设定义。
+type Persona struct {
+	Name           string   `json:"name"`                  // 人设名称
+	Description    string   `json:"description"`           // 人设描述
+	Voice          string   `json:"voice"`                 // 语气风格
+	SystemPrompt   string   `json:"system_prompt"`         // 系统提示词
+	PreferredTools []string `json:"preferred_tools"`       // 偏好工具
+	Greeting       string   `json:"greeting"`              // 问候语
+	Model          string   `json:"model,omitempty"`       // 使用的模型（提供商/模型），为空时使用默认模型
+	AllowTools     []string `json:"allow_tools,omitempty"` // 只能调用的工具，支持通配符，为空表示不限制
+	DenyTools      []string `json:"deny_tools,omitempty"`  // 禁止调用的工具
+	Memory         string   `json:"memory,omitempty"`      // 记忆范围：shared（默认）或 isolated
+	Prefixes       []string `json:"prefixes,omitempty"`    // 消息前缀，以任一前缀开头的消息交给该人设处理
+	Path           string   `json:"path,omitempty"`        // 文件路径
+}
+
+// frontmatter 人设文件的元数据。
+type frontmatter struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Voice       string   `json:"voice"`
+	Tools       []string `json:"tools"`
+	Greeting    string   `json:"greeting"`
+	Model       string   `json:"model"`
+	AllowTools  []string `json:"allow_tools"`
+	DenyTools   []string `json:"deny_tools"`
+	Memory      string   `json:"memory"`
+	Prefixes    []string `json:"prefixes"`
+}
+
+// ParseFile 解析给定路径的人设文件。
+func ParseFile(path string) (*Persona, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persona file: %w", err)
+	}
+
+	p, err := Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	p.Path = path
+	return p, nil
+}
+
+// Parse 解析人设内容，包含元数据和系统提示词。
+func Parse(content string) (*Persona, error) {
+	match := reFrontmatter.FindStringSubmatch(content)
+	if len(match) < 2 {
+		return nil, errors.New("missing frontmatter (expected --- delimited block)")
+	}
+
+	meta, err := parseFrontmatter(match[1])
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Persona{
+		Name:           meta.Name,
+		Description:    meta.Description,
+		Voice:          meta.Voice,
+		PreferredTools: meta.Tools,
+		Greeting:       meta.Greeting,
+		Model:          meta.Model,
+		AllowTools:     meta.AllowTools,
+		DenyTools:      meta.DenyTools,
+		Memory:         meta.Memory,
+		Prefixes:       meta.Prefixes,
+		SystemPrompt:   strings.TrimSpace(content[len(match[0]):]),
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate 验证人设是否有效。
+func (p *Persona) Validate() error {
+	var errs error
+	if p.Name == "" {
+		errs = errors.Join(errs, errors.New("name is required"))
+	} else if !namePattern.MatchString(p.Name) {
+		errs = errors.Join(errs, fmt.Errorf("invalid persona name: %s", p.Name))
+	}
+	if p.SystemPrompt == "" {
+		errs = errors.Join(errs, errors.New("system prompt is required"))
+	}
+	if p.Model != "" && !strings.Contains(p.Model, "/") {
+		errs = errors.Join(errs, fmt.Errorf("invalid model %q (expected provider/model)", p.Model))
+	}
+	if p.Memory != "" && p.Memory != MemoryShared && p.Memory != MemoryIsolated {
+		errs = errors.Join(errs, fmt.Errorf("invalid memory scope %q (expected %s or %s)", p.Memory, MemoryShared, MemoryIsolated))
+	}
+	for _, prefix := range p.Prefixes {
+		if strings.TrimSpace(prefix) == "" {
+			errs = errors.Join(errs, errors.New("prefix must not be empty"))
+		}
+	}
+	return errs
+}
+
+// MemoryKey 返回人设读写对话历史和记忆的键，独立记忆的人设在会话键后追加人设名称。
+func (p *Persona) MemoryKey(sessionKey string) string {
+	if p.Memory == MemoryIsolated {
+		return sessionKey + "#" + p.Name
+	}
+	return sessionKey
+}
+
+// matchPrefix 判断消息是否以人设的某个前缀开头（不区分大小写），返回匹配的前缀长度和去掉前缀后的消息。
+// 以字母或数字结尾的前缀后面必须是空白或标点，避免 @ops 匹配 @opsteam；前缀后的冒号、逗号一并去掉，只有前缀没有内容时不匹配。
+func (p *Persona) matchPrefix(text string) (int, string) {
+	best, rest := 0, ""
+	for _, prefix := range p.Prefixes {
+		if len(prefix) <= best || len(text) < len(prefix) || !strings.EqualFold(text[:len(prefix)], prefix) {
+			continue
+		}
+		remain := text[len(prefix):]
+		last, _ := utf8.DecodeLastRuneInString(prefix)
+		next, _ := utf8.DecodeRuneInString(remain)
+		if isWordRune(last) && isWordRune(next) {
+			continue
+		}
+		if remain = strings.TrimSpace(strings.TrimLeft(remain, ":：,，")); remain == "" {
+			continue
+		}
+		best, rest = len(prefix), remain
+	}
+	return best, rest
+}
+
+// isWordRune 是否为字母或数字。
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Prompt 生成注入系统提示词的人设片段。
+func (p *Persona) Prompt() string {
+	sb := strings.Builder{}
+	sb.WriteString("\n\n## 人设\n")
+	sb.WriteString(fmt.Sprintf("当前人设: %s\n", p.Name))
+	if p.Voice != "" {
+		sb.WriteString(fmt.Sprintf("语气风格: %s\n", p.Voice))
+	}
+	if len(p.PreferredTools) > 0 {
+		sb.WriteString(fmt.Sprintf("优先使用的工具: %s\n", strings.Join(p.PreferredTools, ", ")))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(p.SystemPrompt)
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// parseFrontmatter 解析元数据，支持 JSON 与简单 YAML。
+func parseFrontmatter(content string) (*frontmatter, error) {
+	content = strings.TrimSpace(content)
+
+	if strings.HasPrefix(content, "{") {
+		var meta frontmatter
+		if err := json.Unmarshal([]byte(content), &meta); err != nil {
+			return nil, fmt.Errorf("invalid JSON frontmatter: %w", err)
+		}
+		return &meta, nil
+	}
+
+	meta := &frontmatter{}
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	for _, line := range strings.Split(normalized, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.Trim(strings.TrimSpace(parts[1]), "\"'")
+
+		switch key {
+		case "name":
+			meta.Name = value
+		case "description":
+			meta.Description = value
+		case "voice":
+			meta.Voice = value
+		case "greeting":
+			meta.Greeting = value
+		case "tools", "preferred_tools":
+			meta.Tools = parseList(value)
+		case "model":
+			meta.Model = value
+		case "allow_tools":
+			meta.AllowTools = parseList(value)
+		case "deny_tools":
+			meta.DenyTools = parseList(value)
+		case "memory":
+			meta.Memory = value
+		case "prefixes", "prefix":
+			meta.Prefixes = parseList(value)
+		}
+	}
+
+	return meta, nil
+}
+
+// parseList 解析 [a, b] 或 a, b 形式的列表。
+func parseList(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.Trim(strings.TrimSpace(item), "\"'")
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
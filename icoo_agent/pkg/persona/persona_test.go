@@ -0,0 +1,217 @@
+package persona
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantErr    bool
+		wantName   string
+		wantTools  int
+		wantPrompt string
+	}{
+		{
+			name: "YAML frontmatter",
+			content: `---
+name: teacher
+description: 编程老师
+voice: 温和
+tools: [read_file, exec]
+greeting: 你好
+---
+
+你是一位耐心的编程老师。`,
+			wantName:   "teacher",
+			wantTools:  2,
+			wantPrompt: "你是一位耐心的编程老师。",
+		},
+		{
+			name: "JSON frontmatter",
+			content: `---
+{"name": "pirate", "tools": ["web_search"]}
+---
+Arr!`,
+			wantName:   "pirate",
+			wantTools:  1,
+			wantPrompt: "Arr!",
+		},
+		{
+			name:    "missing frontmatter",
+			content: "just text",
+			wantErr: true,
+		},
+		{
+			name: "missing prompt",
+			content: `---
+name: empty
+---
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid name",
+			content: `---
+name: bad name
+---
+prompt`,
+			wantErr: true,
+		},
+		{
+			name: "invalid memory scope",
+			content: `---
+name: ops
+memory: private
+---
+prompt`,
+			wantErr: true,
+		},
+		{
+			name: "invalid model",
+			content: `---
+name: ops
+model: gpt-4o
+---
+prompt`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if p.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", p.Name, tt.wantName)
+			}
+			if len(p.PreferredTools) != tt.wantTools {
+				t.Errorf("PreferredTools = %v, want %d items", p.PreferredTools, tt.wantTools)
+			}
+			if p.SystemPrompt != tt.wantPrompt {
+				t.Errorf("SystemPrompt = %q, want %q", p.SystemPrompt, tt.wantPrompt)
+			}
+		})
+	}
+}
+
+func TestParse_Profile(t *testing.T) {
+	p, err := Parse(`---
+name: ops
+model: deepseek/deepseek-chat
+allow_tools: [shell_command, "kv_*"]
+deny_tools: [write_file]
+memory: isolated
+prefix: "@ops"
+---
+你是运维助手。`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if p.Model != "deepseek/deepseek-chat" || len(p.AllowTools) != 2 || len(p.DenyTools) != 1 || len(p.Prefixes) != 1 {
+		t.Errorf("Parse() = %+v", p)
+	}
+	if got := p.MemoryKey("web:1"); got != "web:1#ops" {
+		t.Errorf("MemoryKey() = %q, want %q", got, "web:1#ops")
+	}
+	p.Memory = ""
+	if got := p.MemoryKey("web:1"); got != "web:1" {
+		t.Errorf("MemoryKey() = %q, want %q", got, "web:1")
+	}
+}
+
+func TestManager_MatchPrefix(t *testing.T) {
+	workspace := t.TempDir()
+	dir := filepath.Join(workspace, PERSONA_DIR)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"ops.md":     "---\nname: ops\nprefixes: [\"@ops\"]\n---\nops",
+		"opsteam.md": "---\nname: opsteam\nprefixes: [\"@ops team\"]\n---\nteam",
+		"teacher.md": "---\nname: teacher\nprefixes: [\"老师：\"]\n---\nteacher",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := NewManager(workspace, nil, nil)
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		text     string
+		want     string
+		wantRest string
+	}{
+		{"@ops 重启服务", "ops", "重启服务"},
+		{"@OPS, 看下日志", "ops", "看下日志"},
+		{"@ops team 排班", "opsteam", "排班"},
+		{"老师：什么是闭包", "teacher", "什么是闭包"},
+		{"@opsx 重启", "", ""},
+		{"@ops", "", ""},
+		{"请 @ops 处理", "", ""},
+	}
+	for _, tt := range tests {
+		p, rest := m.MatchPrefix(tt.text)
+		got := ""
+		if p != nil {
+			got = p.Name
+		}
+		if got != tt.want || rest != tt.wantRest {
+			t.Errorf("MatchPrefix(%q) = %q, %q, want %q, %q", tt.text, got, rest, tt.want, tt.wantRest)
+		}
+	}
+}
+
+func TestManager_Reload(t *testing.T) {
+	workspace := t.TempDir()
+	dir := filepath.Join(workspace, PERSONA_DIR)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "teacher.md")
+	write := func(prompt string) {
+		content := "---\nname: teacher\n---\n" + prompt
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("v1")
+	m := NewManager(workspace, nil, nil)
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if p, ok := m.Get("teacher"); !ok || p.SystemPrompt != "v1" {
+		t.Fatalf("Get() = %v, %v", p, ok)
+	}
+	if m.changed() {
+		t.Error("changed() = true right after Load")
+	}
+
+	write("version 2")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+	if !m.changed() {
+		t.Fatal("changed() = false after file update")
+	}
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if p, _ := m.Get("teacher"); p.SystemPrompt != "version 2" {
+		t.Errorf("SystemPrompt = %q, want %q", p.SystemPrompt, "version 2")
+	}
+}
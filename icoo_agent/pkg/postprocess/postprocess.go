@@ -0,0 +1,208 @@
+// Package postprocess provides an output post-processing pipeline for icooclaw.
+//
+// 智能体回复在投递前按顺序经过配置的规则处理，规则可以限定渠道或人设：
+//
+//	[[postprocess.rules]]
+//	name = "strip-preamble"
+//	type = "regex"
+//	pattern = '^(好的|当然)[^\n]*\n+'
+//	replace = ""
+//	channels = ["feishu"]
+//
+// 支持的规则类型：regex（正则替换）、prefix/suffix（模板前后缀）、
+// truncate（按字符数截断）、strip_signature（去除末尾签名）。
+package postprocess
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// 规则类型
+const (
+	TypeRegex          = "regex"           // 正则替换
+	TypePrefix         = "prefix"          // 添加前缀
+	TypeSuffix         = "suffix"          // 添加后缀
+	TypeTruncate       = "truncate"        // 截断到最大长度
+	TypeStripSignature = "strip_signature" // 去除末尾签名
+)
+
+// defaultSignature 默认签名格式：以 "-- " 分隔的签名块，或末行以破折号开头的落款。
+const defaultSignature = `(?s)\n+(?:-- ?\n.*|[ \t]*(?:——|—)[ \t]*[^\n—-]+)$`
+
+// defaultEllipsis 截断后追加的默认标记。
+const defaultEllipsis = "…"
+
+// Rule 后处理规则。
+type Rule struct {
+	Name      string   `mapstructure:"name" json:"name"`             // 规则名称，仅用于日志
+	Type      string   `mapstructure:"type" json:"type"`             // 规则类型
+	Pattern   string   `mapstructure:"pattern" json:"pattern"`       // 正则表达式，regex 必填，strip_signature 可选
+	Replace   string   `mapstructure:"replace" json:"replace"`       // 替换内容，支持 $1 引用分组
+	Template  string   `mapstructure:"template" json:"template"`     // prefix/suffix 模板；truncate 时为截断标记
+	MaxLength int      `mapstructure:"max_length" json:"max_length"` // truncate 最大字符数
+	Channels  []string `mapstructure:"channels" json:"channels"`     // 适用渠道，为空表示全部
+	Personas  []string `mapstructure:"personas" json:"personas"`     // 适用人设，为空表示全部
+}
+
+// Scope 回复的投递范围，用于匹配规则和渲染模板。
+type Scope struct {
+	Channel   string
+	SessionID string
+	Persona   string
+}
+
+// templateData 模板可用字段。
+type templateData struct {
+	Scope
+	Date string
+	Time string
+}
+
+// compiledRule 编译后的规则。
+type compiledRule struct {
+	Rule
+	re   *regexp.Regexp
+	tmpl *template.Template
+}
+
+// Pipeline 按顺序执行的后处理规则集合。
+type Pipeline struct {
+	rules []compiledRule
+	now   func() time.Time
+}
+
+// New 编译规则并创建处理管道，规则无效时返回错误。
+func New(rules []Rule) (*Pipeline, error) {
+	p := &Pipeline{now: time.Now}
+
+	for i, r := range rules {
+		cr := compiledRule{Rule: r}
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		switch r.Type {
+		case TypeRegex, TypeStripSignature:
+			pattern := r.Pattern
+			if pattern == "" {
+				if r.Type == TypeRegex {
+					return nil, fmt.Errorf("规则 %s 缺少 pattern", name)
+				}
+				pattern = defaultSignature
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("规则 %s 的正则表达式无效: %w", name, err)
+			}
+			cr.re = re
+		case TypePrefix, TypeSuffix:
+			tmpl, err := template.New(name).Parse(r.Template)
+			if err != nil {
+				return nil, fmt.Errorf("规则 %s 的模板无效: %w", name, err)
+			}
+			cr.tmpl = tmpl
+		case TypeTruncate:
+			if r.MaxLength <= 0 {
+				return nil, fmt.Errorf("规则 %s 的 max_length 必须大于 0", name)
+			}
+		default:
+			return nil, fmt.Errorf("规则 %s 的类型不支持: %s", name, r.Type)
+		}
+
+		p.rules = append(p.rules, cr)
+	}
+
+	return p, nil
+}
+
+// Len 返回规则数量。
+func (p *Pipeline) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.rules)
+}
+
+// Apply 对回复依次执行匹配范围的规则。
+func (p *Pipeline) Apply(text string, scope Scope) string {
+	if p.Len() == 0 || text == "" {
+		return text
+	}
+
+	for _, r := range p.rules {
+		if !r.matches(scope) {
+			continue
+		}
+		text = r.apply(text, p.data(scope))
+	}
+	return text
+}
+
+// data 构造模板数据。
+func (p *Pipeline) data(scope Scope) templateData {
+	now := p.now()
+	return templateData{
+		Scope: scope,
+		Date:  now.Format("2006-01-02"),
+		Time:  now.Format("15:04"),
+	}
+}
+
+// matches 判断规则是否适用于当前范围。
+func (r *compiledRule) matches(scope Scope) bool {
+	if len(r.Channels) > 0 && !slices.Contains(r.Channels, scope.Channel) {
+		return false
+	}
+	if len(r.Personas) > 0 && !slices.Contains(r.Personas, scope.Persona) {
+		return false
+	}
+	return true
+}
+
+// apply 执行单条规则。
+func (r *compiledRule) apply(text string, data templateData) string {
+	switch r.Type {
+	case TypeRegex:
+		return r.re.ReplaceAllString(text, r.Replace)
+	case TypeStripSignature:
+		return strings.TrimRight(r.re.ReplaceAllString(text, ""), " \t\r\n")
+	case TypePrefix:
+		return r.render(data) + text
+	case TypeSuffix:
+		return text + r.render(data)
+	case TypeTruncate:
+		return truncate(text, r.MaxLength, r.Template)
+	default:
+		return text
+	}
+}
+
+// render 渲染模板，出错时返回空字符串，不影响回复投递。
+func (r *compiledRule) render(data templateData) string {
+	var sb strings.Builder
+	if err := r.tmpl.Execute(&sb, data); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+// truncate 按字符数截断，截断后追加标记，结果总长度不超过 maxLen。
+func truncate(text string, maxLen int, ellipsis string) string {
+	if utf8.RuneCountInString(text) <= maxLen {
+		return text
+	}
+	if ellipsis == "" {
+		ellipsis = defaultEllipsis
+	}
+
+	keep := max(maxLen-utf8.RuneCountInString(ellipsis), 0)
+	runes := []rune(text)
+	return strings.TrimRight(string(runes[:keep]), " \t\r\n") + ellipsis
+}
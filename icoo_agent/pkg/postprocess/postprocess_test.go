@@ -0,0 +1,118 @@
+package postprocess
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPipeline_Apply(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+		scope Scope
+		in    string
+		want  string
+	}{
+		{
+			name:  "strip preamble",
+			rules: []Rule{{Type: TypeRegex, Pattern: `^(好的|当然|Sure)[^\n]*\n+`}},
+			in:    "好的，下面是答案：\n\n42",
+			want:  "42",
+		},
+		{
+			name:  "regex groups",
+			rules: []Rule{{Type: TypeRegex, Pattern: `(\d+)元`, Replace: "¥$1"}},
+			in:    "价格 100元",
+			want:  "价格 ¥100",
+		},
+		{
+			name: "prefix and suffix templates",
+			rules: []Rule{
+				{Type: TypePrefix, Template: "[{{.Channel}}] "},
+				{Type: TypeSuffix, Template: "\n— {{.Persona}} {{.Date}}"},
+			},
+			scope: Scope{Channel: "feishu", Persona: "teacher"},
+			in:    "你好",
+			want:  "[feishu] 你好\n— teacher 2026-01-02",
+		},
+		{
+			name:  "truncate",
+			rules: []Rule{{Type: TypeTruncate, MaxLength: 5}},
+			in:    "一二三四五六七",
+			want:  "一二三四…",
+		},
+		{
+			name:  "truncate custom marker",
+			rules: []Rule{{Type: TypeTruncate, MaxLength: 8, Template: "[...]"}},
+			in:    "abcdefghijk",
+			want:  "abc[...]",
+		},
+		{
+			name:  "strip dash signature",
+			rules: []Rule{{Type: TypeStripSignature}},
+			in:    "答案是 42。\n\n—— 你的 AI 助手",
+			want:  "答案是 42。",
+		},
+		{
+			name:  "strip email signature",
+			rules: []Rule{{Type: TypeStripSignature}},
+			in:    "Done.\n-- \nBot\nexample.com",
+			want:  "Done.",
+		},
+		{
+			name:  "keep markdown rule",
+			rules: []Rule{{Type: TypeStripSignature}},
+			in:    "上文\n\n---\n\n下文",
+			want:  "上文\n\n---\n\n下文",
+		},
+		{
+			name:  "channel filter",
+			rules: []Rule{{Type: TypePrefix, Template: "x", Channels: []string{"telegram"}}},
+			scope: Scope{Channel: "feishu"},
+			in:    "hi",
+			want:  "hi",
+		},
+		{
+			name:  "persona filter",
+			rules: []Rule{{Type: TypeSuffix, Template: "!", Personas: []string{"teacher"}}},
+			scope: Scope{Persona: "teacher"},
+			in:    "hi",
+			want:  "hi!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.rules)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			p.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC) }
+
+			if got := p.Apply(tt.in, tt.scope); got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"unknown type", Rule{Type: "shout"}},
+		{"missing pattern", Rule{Type: TypeRegex}},
+		{"bad pattern", Rule{Type: TypeRegex, Pattern: "("}},
+		{"bad template", Rule{Type: TypePrefix, Template: "{{.Channel"}},
+		{"missing max length", Rule{Type: TypeTruncate}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New([]Rule{tt.rule}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"strings"
 )
 
+// anthropicVersion Anthropic API 版本头
+const anthropicVersion = "2023-06-01"
+
 // AnthropicProvider implements Provider for Anthropic Claude.
 type AnthropicProvider struct {
 	*BaseProvider
@@ -34,56 +37,17 @@ func NewAnthropicProvider(cfg *storage.Provider) Provider {
 	}
 }
 
-// Chat sends a chat request to Anthropic.
-func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
-	// Convert messages to Anthropic format
-	messages := make([]map[string]any, 0, len(req.Messages))
-	var systemPrompt string
-
-	for _, msg := range req.Messages {
-		if msg.Role == "system" {
-			systemPrompt = msg.Content
-			continue
-		}
-		messages = append(messages, map[string]any{
-			"role":    msg.Role,
-			"content": msg.Content,
-		})
-	}
-
-	anthropicReq := map[string]any{
-		"model":      req.Model,
-		"max_tokens": 4096,
-		"messages":   messages,
-	}
-
-	if systemPrompt != "" {
-		anthropicReq["system"] = systemPrompt
-	}
-
-	if req.MaxTokens > 0 {
-		anthropicReq["max_tokens"] = req.MaxTokens
-	}
-
-	// Convert tools
-	if len(req.Tools) > 0 {
-		tools := make([]map[string]any, 0, len(req.Tools))
-		for _, t := range req.Tools {
-			tools = append(tools, map[string]any{
-				"name":         t.Function.Name,
-				"description":  t.Function.Description,
-				"input_schema": t.Function.Parameters,
-			})
-		}
-		anthropicReq["tools"] = tools
-	}
-
-	headers := map[string]string{
+// headers Anthropic 认证头
+func (p *AnthropicProvider) headers() map[string]string {
+	return map[string]string{
 		"x-api-key":         p.apiKey,
-		"anthropic-version": "2023-06-01",
+		"anthropic-version": anthropicVersion,
 	}
+}
 
-	resp, err := p.doRequestWithHeaders(ctx, "POST", "/messages", anthropicReq, headers)
+// Chat sends a chat request to Anthropic.
+func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	resp, err := p.doRequestWithHeaders(ctx, "POST", "/messages", anthropicRequest(req, false), p.headers())
 	if err != nil {
 		return nil, err
 	}
@@ -97,8 +61,11 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRes
 		ID      string `json:"id"`
 		Model   string `json:"model"`
 		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
 		} `json:"content"`
 		StopReason string `json:"stop_reason"`
 		Usage      struct {
@@ -112,16 +79,25 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRes
 	}
 
 	var content string
+	var toolCalls []ToolCall
 	for _, c := range result.Content {
-		if c.Type == "text" {
+		switch c.Type {
+		case "text":
 			content += c.Text
+		case "tool_use":
+			toolCalls = append(toolCalls, anthropicToolCall(c.ID, c.Name, string(c.Input)))
 		}
 	}
+	// 拒答时 content 中可能有部分回复，作为拒答说明返回
+	if result.StopReason == anthropicRefusal {
+		return nil, &ContentFilterError{Provider: p.name, Reason: result.StopReason, Refusal: content}
+	}
 
 	return &ChatResponse{
-		ID:      result.ID,
-		Model:   result.Model,
-		Content: content,
+		ID:        result.ID,
+		Model:     result.Model,
+		Content:   content,
+		ToolCalls: toolCalls,
 		Usage: Usage{
 			PromptTokens:     result.Usage.InputTokens,
 			CompletionTokens: result.Usage.OutputTokens,
@@ -130,92 +106,216 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRes
 	}, nil
 }
 
+// Probe 使用 Anthropic 认证头请求 /models 接口探测可用性。
+func (p *AnthropicProvider) Probe(ctx context.Context) error {
+	return p.probe(ctx, p.headers())
+}
+
 // ChatStream sends a streaming chat request to Anthropic.
 func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest, callback StreamCallback) error {
-	// Convert messages to Anthropic format
+	resp, err := p.doRequestWithHeaders(ctx, "POST", "/messages", anthropicRequest(req, true), p.headers())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return p.handleError(resp)
+	}
+
+	return streamAnthropic(p.name, resp, callback)
+}
+
+// anthropicRequest 将通用请求转换为 Messages API 请求：系统消息合并为 system，
+// 助手的工具调用转换为 tool_use 块，连续的工具结果合并为一条 user 消息中的 tool_result 块。
+func anthropicRequest(req ChatRequest, stream bool) map[string]any {
 	messages := make([]map[string]any, 0, len(req.Messages))
-	var systemPrompt string
+	var system []string
 
 	for _, msg := range req.Messages {
-		if msg.Role == "system" {
-			systemPrompt = msg.Content
-			continue
+		switch {
+		case msg.Role == "system":
+			system = append(system, msg.Content)
+		case msg.Role == "tool":
+			block := map[string]any{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     msg.Content,
+			}
+			if n := len(messages); n > 0 && isToolResults(messages[n-1]) {
+				messages[n-1]["content"] = append(messages[n-1]["content"].([]map[string]any), block)
+				continue
+			}
+			messages = append(messages, map[string]any{
+				"role":    "user",
+				"content": []map[string]any{block},
+			})
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			blocks := make([]map[string]any, 0, len(msg.ToolCalls)+1)
+			if msg.Content != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				input := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, map[string]any{
+					"type":  "tool_use",
+					"id":    tc.ID,
+					"name":  tc.Function.Name,
+					"input": input,
+				})
+			}
+			messages = append(messages, map[string]any{
+				"role":    "assistant",
+				"content": blocks,
+			})
+		default:
+			messages = append(messages, map[string]any{
+				"role":    msg.Role,
+				"content": msg.Content,
+			})
 		}
-		messages = append(messages, map[string]any{
-			"role":    msg.Role,
-			"content": msg.Content,
-		})
 	}
 
 	anthropicReq := map[string]any{
 		"model":      req.Model,
 		"max_tokens": 4096,
 		"messages":   messages,
-		"stream":     true,
+	}
+	if stream {
+		anthropicReq["stream"] = true
 	}
 
-	if systemPrompt != "" {
-		anthropicReq["system"] = systemPrompt
+	if len(system) > 0 {
+		anthropicReq["system"] = strings.Join(system, "\n\n")
 	}
 
 	if req.MaxTokens > 0 {
 		anthropicReq["max_tokens"] = req.MaxTokens
 	}
 
-	headers := map[string]string{
-		"x-api-key":         p.apiKey,
-		"anthropic-version": "2023-06-01",
+	// Convert tools
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			tools = append(tools, map[string]any{
+				"name":         t.Function.Name,
+				"description":  t.Function.Description,
+				"input_schema": t.Function.Parameters,
+			})
+		}
+		anthropicReq["tools"] = tools
 	}
 
-	resp, err := p.doRequestWithHeaders(ctx, "POST", "/messages", anthropicReq, headers)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return anthropicReq
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return p.handleError(resp)
+// isToolResults 消息是否为只包含 tool_result 块的 user 消息。
+func isToolResults(msg map[string]any) bool {
+	blocks, ok := msg["content"].([]map[string]any)
+	return ok && msg["role"] == "user" && len(blocks) > 0 && blocks[0]["type"] == "tool_result"
+}
+
+// anthropicToolCall 将 tool_use 块转换为工具调用，参数为空时使用 {}。
+func anthropicToolCall(id, name, input string) ToolCall {
+	if strings.TrimSpace(input) == "" {
+		input = "{}"
 	}
+	tc := ToolCall{ID: id, Type: "function"}
+	tc.Function.Name = name
+	tc.Function.Arguments = input
+	return tc
+}
+
+// anthropicRefusal 模型因安全策略拒答时的 stop_reason
+const anthropicRefusal = "refusal"
+
+// anthropicEvent Anthropic 流式事件。
+type anthropicEvent struct {
+	Type         string `json:"type"`
+	Index        int    `json:"index"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"` // message_delta
+	} `json:"delta"`
+	Error json.RawMessage `json:"error"`
+}
+
+// streamAnthropic 解析 Anthropic 流式响应。
+//
+// tool_use 块开始时以真实 id 发送工具调用名称，之后的 input_json_delta 以
+// stream_index:N 发送参数增量（N 为本次响应中工具调用的序号），与 OpenAI 兼容流的格式一致，
+// 由智能体合并为完整的工具调用。stop_reason 为 refusal 时以 ContentFilterError 结束。
+func streamAnthropic(provider string, resp *http.Response, callback StreamCallback) error {
+	calls := make(map[int]int) // 内容块序号 -> 工具调用序号
 
 	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
 			continue
 		}
 
-		data := strings.TrimPrefix(line, "data: ")
-
-		var event struct {
-			Type  string `json:"type"`
-			Index int    `json:"index"`
-			Delta struct {
-				Type string `json:"type"`
-				Text string `json:"text"`
-			} `json:"delta"`
-			Message struct {
-				StopReason string `json:"stop_reason"`
-			} `json:"message"`
-		}
-
-		if err := json.Unmarshal([]byte(data), &event); err != nil {
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
 			continue
 		}
 
 		switch event.Type {
+		case "content_block_start":
+			if event.ContentBlock.Type != "tool_use" {
+				continue
+			}
+			calls[event.Index] = len(calls)
+			tc := ToolCall{ID: event.ContentBlock.ID, Type: "function"}
+			tc.Function.Name = event.ContentBlock.Name
+			if err := callback("", "", []ToolCall{tc}, false); err != nil {
+				return err
+			}
 		case "content_block_delta":
-			if event.Delta.Type == "text_delta" {
-				if err := callback(event.Delta.Text, "", nil, false); err != nil {
-					return err
+			var err error
+			switch event.Delta.Type {
+			case "text_delta":
+				err = callback(event.Delta.Text, "", nil, false)
+			case "thinking_delta":
+				err = callback("", event.Delta.Thinking, nil, false)
+			case "input_json_delta":
+				index, ok := calls[event.Index]
+				if !ok || event.Delta.PartialJSON == "" {
+					continue
 				}
+				tc := ToolCall{ID: fmt.Sprintf("stream_index:%d", index), Type: "function"}
+				tc.Function.Arguments = event.Delta.PartialJSON
+				err = callback("", "", []ToolCall{tc}, false)
 			}
-		case "message_stop":
-			if err := callback("", "", nil, true); err != nil {
+			if err != nil {
 				return err
 			}
-			return nil
+		case "message_delta":
+			if event.Delta.StopReason == anthropicRefusal {
+				return &ContentFilterError{Provider: provider, Reason: event.Delta.StopReason}
+			}
+		case "error":
+			return &streamError{payload: event.Error}
+		case "message_stop":
+			return callback("", "", nil, true)
 		}
 	}
 
-	return scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	// 连接提前关闭时仍然结束本次回复
+	return callback("", "", nil, true)
 }
@@ -0,0 +1,88 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parseAnthropic 以测试提供商名称解析 Anthropic 流式响应。
+func parseAnthropic(resp *http.Response, callback StreamCallback) error {
+	return streamAnthropic("anthropic", resp, callback)
+}
+
+func TestStreamAnthropic(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "stream", "anthropic.sse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := collectCallbacks(t, string(body), parseAnthropic)
+	if err != nil {
+		t.Fatalf("streamAnthropic() error = %v", err)
+	}
+	if res.content != "Let me check." || res.reasoning != "Need weather and time." {
+		t.Errorf("content = %q, reasoning = %q", res.content, res.reasoning)
+	}
+	// 没有参数的调用由智能体补为 {}
+	want := []string{`get_weather({"city": "Paris"})`, `get_time()`}
+	if strings.Join(res.calls, ";") != strings.Join(want, ";") {
+		t.Errorf("tool calls = %v, want %v", res.calls, want)
+	}
+	if res.dones != 1 {
+		t.Errorf("done callbacks = %d, want 1", res.dones)
+	}
+
+	_, err = collectCallbacks(t, `data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`+"\n", parseAnthropic)
+	if err == nil || !strings.Contains(err.Error(), "Overloaded") {
+		t.Errorf("error event: err = %v", err)
+	}
+
+	refusal := `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"I"}}` + "\n" +
+		`data: {"type":"message_delta","delta":{"stop_reason":"refusal"}}` + "\n" +
+		`data: {"type":"message_stop"}` + "\n"
+	res, err = collectCallbacks(t, refusal, parseAnthropic)
+	if !IsContentFiltered(err) || res.dones != 0 {
+		t.Errorf("refusal: err = %v, dones = %d", err, res.dones)
+	}
+}
+
+func TestAnthropicRequest(t *testing.T) {
+	call := ToolCall{ID: "toolu_01", Type: "function"}
+	call.Function.Name = "get_weather"
+	call.Function.Arguments = `{"city":"Paris"}`
+	other := ToolCall{ID: "toolu_02", Type: "function"}
+	other.Function.Name = "get_time"
+
+	req := anthropicRequest(ChatRequest{
+		Model: "claude",
+		Messages: []ChatMessage{
+			{Role: "system", Content: "You are helpful."},
+			{Role: "system", Content: "Summary: none."},
+			{Role: "user", Content: "Weather and time in Paris?"},
+			{Role: "assistant", Content: "Let me check.", ToolCalls: []ToolCall{call, other}},
+			{Role: "tool", ToolCallID: "toolu_01", Content: "sunny"},
+			{Role: "tool", ToolCallID: "toolu_02", Content: "10:00"},
+		},
+		Tools: []Tool{{Type: "function", Function: Function{Name: "get_weather", Parameters: map[string]any{"type": "object"}}}},
+	}, true)
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`"system":"You are helpful.\n\nSummary: none."`,
+		`"stream":true`,
+		`{"content":[{"text":"Let me check.","type":"text"},{"id":"toolu_01","input":{"city":"Paris"},"name":"get_weather","type":"tool_use"},{"id":"toolu_02","input":{},"name":"get_time","type":"tool_use"}],"role":"assistant"}`,
+		`{"content":[{"content":"sunny","tool_use_id":"toolu_01","type":"tool_result"},{"content":"10:00","tool_use_id":"toolu_02","type":"tool_result"}],"role":"user"}`,
+		`"tools":[{"description":"","input_schema":{"type":"object"},"name":"get_weather"}]`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("request missing %s\n%s", want, got)
+		}
+	}
+}
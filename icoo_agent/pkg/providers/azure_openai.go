@@ -1,13 +1,11 @@
 package providers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"icooclaw/pkg/consts"
 	"icooclaw/pkg/storage"
-	"io"
 	"net/http"
 )
 
@@ -47,24 +45,9 @@ func (p *AzureOpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatR
 	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
 		p.apiBase, p.deployment, p.apiVersion)
 
-	var reqBody io.Reader
-	data, err := json.Marshal(req)
+	resp, err := p.sendRequest(ctx, "POST", url, req, map[string]string{"api-key": p.apiKey})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-	reqBody = bytes.NewReader(data)
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("api-key", p.apiKey)
-
-	resp, err := p.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -80,6 +63,7 @@ func (p *AzureOpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatR
 				Role      string     `json:"role"`
 				Content   string     `json:"content"`
 				ToolCalls []ToolCall `json:"tool_calls"`
+				Refusal   string     `json:"refusal"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -93,6 +77,9 @@ func (p *AzureOpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatR
 	if len(result.Choices) == 0 {
 		return nil, fmt.Errorf("no choices in response")
 	}
+	if err := contentFilter(p.name, result.Choices[0].FinishReason, result.Choices[0].Message.Refusal); err != nil {
+		return nil, err
+	}
 
 	return &ChatResponse{
 		ID:        result.ID,
@@ -110,22 +97,9 @@ func (p *AzureOpenAIProvider) ChatStream(ctx context.Context, req ChatRequest, c
 	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
 		p.apiBase, p.deployment, p.apiVersion)
 
-	data, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("api-key", p.apiKey)
-
-	resp, err := p.httpClient.Do(httpReq)
+	resp, err := p.sendRequest(ctx, "POST", url, req, map[string]string{"api-key": p.apiKey})
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -0,0 +1,103 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+	"net/http"
+)
+
+// BaichuanProvider implements Provider for Baichuan (百川智能).
+type BaichuanProvider struct {
+	*BaseProvider
+	opts presetOptions
+}
+
+// baichuanRequest 百川请求，在 OpenAI 参数之外附加搜索增强开关。
+type baichuanRequest struct {
+	ChatRequest
+	WithSearchEnhance bool `json:"with_search_enhance,omitempty"`
+}
+
+// NewBaichuanProvider creates a new Baichuan provider.
+func NewBaichuanProvider(cfg *storage.Provider) Provider {
+	providerName := consts.ProviderBaichuan
+	apiBase, defaultModel := presetDefaults(cfg, consts.ProviderBaichuan)
+
+	return &BaichuanProvider{
+		BaseProvider: NewBaseProvider(providerName.ToString(), cfg.APIKey, apiBase, defaultModel),
+		opts:         parsePresetOptions(cfg),
+	}
+}
+
+// buildRequest 映射请求参数，百川的 temperature 取值范围为 [0, 1]。
+func (p *BaichuanProvider) buildRequest(req ChatRequest) baichuanRequest {
+	req.Temperature = clampTemperature(req.Temperature, 1)
+	return baichuanRequest{ChatRequest: req, WithSearchEnhance: p.opts.WithSearchEnhance}
+}
+
+// Chat sends a chat request to Baichuan.
+func (p *BaichuanProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	req.Stream = false
+	resp, err := p.doRequest(ctx, "POST", "/chat/completions", p.buildRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.handleError(resp)
+	}
+
+	var result struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Role      string     `json:"role"`
+				Content   string     `json:"content"`
+				Reasoning string     `json:"reasoning_content"`
+				ToolCalls []ToolCall `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage Usage `json:"usage"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+	if err := contentFilter(p.name, result.Choices[0].FinishReason, ""); err != nil {
+		return nil, err
+	}
+
+	return &ChatResponse{
+		ID:        result.ID,
+		Model:     result.Model,
+		Content:   result.Choices[0].Message.Content,
+		Reasoning: result.Choices[0].Message.Reasoning,
+		ToolCalls: result.Choices[0].Message.ToolCalls,
+		Usage:     result.Usage,
+	}, nil
+}
+
+// ChatStream sends a streaming chat request to Baichuan.
+func (p *BaichuanProvider) ChatStream(ctx context.Context, req ChatRequest, callback StreamCallback) error {
+	req.Stream = true
+	resp, err := p.doRequest(ctx, "POST", "/chat/completions", p.buildRequest(req))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return p.handleError(resp)
+	}
+
+	return p.streamResponse(resp, callback)
+}
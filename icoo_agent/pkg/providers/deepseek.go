@@ -66,6 +66,9 @@ func (p *DeepSeekProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResp
 	if len(result.Choices) == 0 {
 		return nil, fmt.Errorf("no choices in response")
 	}
+	if err := contentFilter(p.name, result.Choices[0].FinishReason, ""); err != nil {
+		return nil, err
+	}
 
 	return &ChatResponse{
 		ID:        result.ID,
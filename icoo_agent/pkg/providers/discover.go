@@ -0,0 +1,291 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+)
+
+// discoverTimeout 单个提供商探测模型的超时时间
+const discoverTimeout = 30 * time.Second
+
+// ollamaAutoContext Modelfile 和配置都未指定上下文长度时使用的上限，
+// 避免按模型训练长度（常见 128K）请求时分配过大的 KV 缓存
+const ollamaAutoContext = 8192
+
+// ModelDiscoverer 能从服务端查询模型元数据的提供商。
+// 本地部署的模型上下文长度差异很大，不能按内置的模型表估计。
+// contextWindow 为配置的上下文长度，服务端未给出时使用。
+type ModelDiscoverer interface {
+	DiscoverModels(ctx context.Context, contextWindow int) ([]*ModelInfo, error)
+}
+
+// 运行时探测到的模型，优先于内置定义
+var (
+	discoveredMu sync.RWMutex
+	discovered   = map[string]*ModelInfo{}
+)
+
+// RegisterModel 注册运行时探测到的模型信息，按 ID 和别名覆盖内置定义。
+func RegisterModel(info *ModelInfo) {
+	discoveredMu.Lock()
+	defer discoveredMu.Unlock()
+	info.Discovered = true
+	discovered[info.ID] = info
+	for _, alias := range info.Aliases {
+		discovered[alias] = info
+	}
+}
+
+// discoveredModel 返回运行时探测到的模型信息。
+func discoveredModel(modelID string) *ModelInfo {
+	discoveredMu.RLock()
+	defer discoveredMu.RUnlock()
+	return discovered[modelID]
+}
+
+// discoverOptions 提供商 config 字段中的模型探测配置。
+type discoverOptions struct {
+	Discover      *bool `json:"discover_models"` // 是否探测，Ollama 默认探测
+	ContextWindow int   `json:"context_window"`  // 服务端未给出时使用的上下文长度
+}
+
+// parseDiscoverOptions 解析提供商的模型探测配置，返回是否探测和配置的上下文长度。
+func parseDiscoverOptions(cfg *storage.Provider) (bool, int) {
+	var opts discoverOptions
+	if cfg.Config != "" {
+		_ = json.Unmarshal([]byte(cfg.Config), &opts)
+	}
+	if opts.Discover != nil {
+		return *opts.Discover, opts.ContextWindow
+	}
+	return cfg.Type == consts.ProviderOllama, opts.ContextWindow
+}
+
+// DiscoverModels 查询启用了模型探测的提供商，将实际的上下文长度、工具支持和量化方式注册到模型表。
+// Ollama 默认探测；LocalAI、vLLM 等 OpenAI 兼容服务需要在 config 中设置 discover_models。
+func (f *Factory) DiscoverModels(ctx context.Context) {
+	logger := slog.Default().With("name", "【提供商】")
+
+	configs, err := f.storage.Provider().List()
+	if err != nil {
+		logger.Warn("获取提供商列表失败", "error", err)
+		return
+	}
+
+	for _, cfg := range configs {
+		enabled, contextWindow := parseDiscoverOptions(cfg)
+		if !cfg.Enabled || !enabled {
+			continue
+		}
+		p, err := f.createFromConfig(cfg)
+		if err != nil {
+			logger.Warn("加载提供商失败", "provider", cfg.Name, "error", err)
+			continue
+		}
+		d, ok := p.(ModelDiscoverer)
+		if !ok {
+			continue
+		}
+
+		dctx, cancel := context.WithTimeout(ctx, discoverTimeout)
+		models, err := d.DiscoverModels(dctx, contextWindow)
+		cancel()
+		if err != nil {
+			logger.Warn("探测模型失败", "provider", cfg.Name, "error", err)
+			continue
+		}
+		for _, m := range models {
+			RegisterModel(m)
+			logger.Info("已探测模型",
+				"provider", cfg.Name,
+				"model", m.ID,
+				"context_window", m.ContextWindow,
+				"tools", m.SupportsTools,
+				"quantization", m.Quantization)
+		}
+	}
+}
+
+// DiscoverModels 查询 Ollama 本地模型的上下文长度、能力和量化方式。
+//
+// Ollama 按请求中的 num_ctx 分配上下文，未指定时使用服务端默认值并静默截断超出的提示词，
+// 因此探测到的上下文长度同时作为请求的 num_ctx：优先取 Modelfile 中的 num_ctx，
+// 其次为配置的 context_window，都没有时取模型训练长度与 8192 中的较小值。
+func (p *OllamaProvider) DiscoverModels(ctx context.Context, contextWindow int) ([]*ModelInfo, error) {
+	resp, err := p.doRequest(ctx, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.handleError(resp)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]*ModelInfo, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		info, err := p.showModel(ctx, m.Name, contextWindow)
+		if err != nil {
+			return models, fmt.Errorf("查询模型 %s 失败: %w", m.Name, err)
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// ollamaShow /api/show 的响应。
+type ollamaShow struct {
+	Parameters string `json:"parameters"`
+	Details    struct {
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+	ModelInfo    map[string]any `json:"model_info"`
+	Capabilities []string       `json:"capabilities"`
+}
+
+// showModel 查询单个模型的元数据。
+func (p *OllamaProvider) showModel(ctx context.Context, name string, contextWindow int) (*ModelInfo, error) {
+	resp, err := p.doRequest(ctx, http.MethodPost, "/api/show", map[string]string{"model": name})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.handleError(resp)
+	}
+
+	var show ollamaShow
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	info := &ModelInfo{
+		ID:             name,
+		Name:           name,
+		Provider:       p.name,
+		ContextWindow:  show.contextWindow(contextWindow),
+		SupportsStream: true,
+		Quantization:   show.Details.QuantizationLevel,
+	}
+	// 旧版本 Ollama 不返回 capabilities，按支持工具处理，由服务端决定
+	info.SupportsTools = len(show.Capabilities) == 0
+	for _, c := range show.Capabilities {
+		switch c {
+		case "tools":
+			info.SupportsTools = true
+		case "vision":
+			info.SupportsVision = true
+		}
+	}
+	// 配置中常省略默认标签
+	if base, ok := strings.CutSuffix(name, ":latest"); ok {
+		info.Aliases = []string{base}
+	}
+	return info, nil
+}
+
+// contextWindow 计算实际使用的上下文长度。
+func (s *ollamaShow) contextWindow(configured int) int {
+	trained := 0
+	for key, v := range s.ModelInfo {
+		if strings.HasSuffix(key, ".context_length") {
+			if n, ok := v.(float64); ok {
+				trained = int(n)
+			}
+		}
+	}
+
+	window := 0
+	for _, line := range strings.Split(s.Parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			window, _ = strconv.Atoi(fields[1])
+		}
+	}
+	if window <= 0 {
+		window = configured
+	}
+	if window <= 0 {
+		window = ollamaAutoContext
+	}
+	if trained > 0 && window > trained {
+		window = trained
+	}
+	return window
+}
+
+// DiscoverModels 查询 OpenAI 兼容服务的 /models 接口。
+// 除 id 外，各服务以不同字段给出上下文长度：vLLM 为 max_model_len，LM Studio 为 max_context_length，
+// LocalAI 为 context_size，OpenRouter 等为 context_length；都没有时使用配置的 context_window，
+// 仍无法确定的模型不注册。
+func (p *BaseProvider) DiscoverModels(ctx context.Context, contextWindow int) ([]*ModelInfo, error) {
+	resp, err := p.doRequest(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.handleError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	var list struct {
+		Data []struct {
+			ID               string `json:"id"`
+			ContextLength    int    `json:"context_length"`
+			MaxModelLen      int    `json:"max_model_len"`
+			MaxContextLength int    `json:"max_context_length"`
+			ContextSize      int    `json:"context_size"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]*ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		window := contextWindow
+		for _, n := range []int{m.MaxModelLen, m.MaxContextLength, m.ContextSize, m.ContextLength} {
+			if n > 0 {
+				window = n
+				break
+			}
+		}
+		if m.ID == "" || window <= 0 {
+			continue
+		}
+		models = append(models, &ModelInfo{
+			ID:             m.ID,
+			Name:           m.ID,
+			Provider:       p.name,
+			ContextWindow:  window,
+			SupportsTools:  true,
+			SupportsStream: true,
+		})
+	}
+	return models, nil
+}
@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+)
+
+func TestOllamaDiscoverModels(t *testing.T) {
+	var chatOptions map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"disc-llama:latest"},{"name":"disc-qwen:7b"},{"name":"disc-phi:mini"}]}`))
+		case "/api/show":
+			var req struct{ Model string }
+			json.NewDecoder(r.Body).Decode(&req)
+			switch req.Model {
+			case "disc-llama:latest":
+				// 训练长度 128K，未设置 num_ctx
+				w.Write([]byte(`{"details":{"quantization_level":"Q4_K_M"},"model_info":{"llama.context_length":131072},"capabilities":["completion","tools"]}`))
+			case "disc-qwen:7b":
+				// Modelfile 设置了 num_ctx
+				w.Write([]byte(`{"parameters":"stop \"<|im_end|>\"\nnum_ctx                        4096","details":{"quantization_level":"Q8_0"},"model_info":{"qwen2.context_length":32768},"capabilities":["completion","tools","vision"]}`))
+			default:
+				// 训练长度小于默认上限，不支持工具
+				w.Write([]byte(`{"details":{"quantization_level":"F16"},"model_info":{"phi3.context_length":4096},"capabilities":["completion"]}`))
+			}
+		case "/api/chat":
+			var req struct {
+				Options map[string]any `json:"options"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			chatOptions = req.Options
+			w.Write([]byte(`{"message":{"role":"assistant","content":"ok"},"done":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewOllamaProvider(&storage.Provider{Type: consts.ProviderOllama, APIBase: srv.URL}).(*OllamaProvider)
+	models, err := p.DiscoverModels(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("DiscoverModels() error = %v", err)
+	}
+	if len(models) != 3 {
+		t.Fatalf("models = %d, want 3", len(models))
+	}
+	for _, m := range models {
+		RegisterModel(m)
+	}
+
+	tests := []struct {
+		model  string
+		window int
+		tools  bool
+		vision bool
+		quant  string
+	}{
+		{"disc-llama", ollamaAutoContext, true, false, "Q4_K_M"},
+		{"disc-qwen:7b", 4096, true, true, "Q8_0"},
+		{"disc-phi:mini", 4096, false, false, "F16"},
+	}
+	for _, tt := range tests {
+		info := GetModelInfo(tt.model)
+		if info == nil || !info.Discovered {
+			t.Errorf("GetModelInfo(%s) = %+v", tt.model, info)
+			continue
+		}
+		if info.ContextWindow != tt.window || info.SupportsTools != tt.tools || info.SupportsVision != tt.vision || info.Quantization != tt.quant {
+			t.Errorf("%s = %+v", tt.model, info)
+		}
+	}
+
+	// 配置的上下文长度不超过训练长度
+	models, _ = p.DiscoverModels(context.Background(), 65536)
+	if models[0].ContextWindow != 65536 || models[1].ContextWindow != 4096 || models[2].ContextWindow != 4096 {
+		t.Errorf("configured windows = %d, %d, %d", models[0].ContextWindow, models[1].ContextWindow, models[2].ContextWindow)
+	}
+
+	// 请求按探测到的长度设置 num_ctx
+	if _, err := p.Chat(context.Background(), ChatRequest{Model: "disc-qwen:7b"}); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if chatOptions["num_ctx"] != float64(4096) {
+		t.Errorf("options = %v", chatOptions)
+	}
+}
+
+func TestOpenAIDiscoverModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"object":"list","data":[
+			{"id":"disc-vllm","max_model_len":16384},
+			{"id":"disc-lmstudio","max_context_length":8192},
+			{"id":"disc-localai"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	p := NewBaseProvider("openai", "", srv.URL, "")
+	models, err := p.DiscoverModels(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("DiscoverModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0].ContextWindow != 16384 || models[1].ContextWindow != 8192 {
+		t.Errorf("models = %+v", models)
+	}
+
+	// 服务端未给出长度时使用配置值
+	models, _ = p.DiscoverModels(context.Background(), 4096)
+	if len(models) != 3 || models[2].ID != "disc-localai" || models[2].ContextWindow != 4096 {
+		t.Errorf("models = %+v", models)
+	}
+}
+
+func TestParseDiscoverOptions(t *testing.T) {
+	tests := []struct {
+		cfg     storage.Provider
+		enabled bool
+		window  int
+	}{
+		{storage.Provider{Type: consts.ProviderOllama}, true, 0},
+		{storage.Provider{Type: consts.ProviderOllama, Config: `{"discover_models":false}`}, false, 0},
+		{storage.Provider{Type: consts.ProviderOpenAI}, false, 0},
+		{storage.Provider{Type: consts.ProviderOpenAI, Config: `{"discover_models":true,"context_window":4096}`}, true, 4096},
+	}
+	for _, tt := range tests {
+		enabled, window := parseDiscoverOptions(&tt.cfg)
+		if enabled != tt.enabled || window != tt.window {
+			t.Errorf("parseDiscoverOptions(%s, %s) = %v, %d", tt.cfg.Type, tt.cfg.Config, enabled, window)
+		}
+	}
+}
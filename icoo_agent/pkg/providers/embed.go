@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Embedder 支持向量嵌入的提供商。
+type Embedder interface {
+	Embed(ctx context.Context, model string, inputs []string) ([][]float32, error)
+}
+
+// Embed 调用 OpenAI 兼容的 /embeddings 接口，返回与 inputs 一一对应的向量。
+func (p *BaseProvider) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	resp, err := p.doRequest(ctx, http.MethodPost, "/embeddings", map[string]any{
+		"model": model,
+		"input": inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.handleError(resp)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(result.Data))
+	}
+
+	sort.Slice(result.Data, func(i, j int) bool { return result.Data[i].Index < result.Data[j].Index })
+	embeddings := make([][]float32, len(result.Data))
+	for i, d := range result.Data {
+		embeddings[i] = d.Embedding
+	}
+	return embeddings, nil
+}
+
+// Embed 调用 Ollama 的 /api/embed 接口。
+func (p *OllamaProvider) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	resp, err := p.doRequest(ctx, http.MethodPost, "/api/embed", map[string]any{
+		"model": model,
+		"input": inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.handleError(resp)
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(result.Embeddings))
+	}
+	return result.Embeddings, nil
+}
+
+// Embedder 返回指定提供商的嵌入接口。
+func (f *Factory) Embedder(name string) (Embedder, error) {
+	cfg, err := f.storage.Provider().GetByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("供应商 %s 未找到: %w", name, err)
+	}
+	p, err := f.createFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := p.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("供应商 %s 不支持向量嵌入", name)
+	}
+	return e, nil
+}
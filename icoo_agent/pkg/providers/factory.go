@@ -14,6 +14,15 @@ type Factory struct {
 	storage   *storage.Storage
 	providers map[string]Provider
 	mu        sync.RWMutex
+
+	healthCfg *HealthConfig              // 熔断与健康检查配置，nil 表示未启用
+	health    map[string]*providerHealth // 提供商健康记录
+
+	promptLog *PromptLog // 提示词日志，nil 表示未启用
+
+	retry RetryConfig // 请求重试配置
+
+	keyed map[string]*keyedProvider // 使用多个密钥的提供商
 }
 
 // NewFactory creates a new Factory.
@@ -21,6 +30,8 @@ func NewFactory(s *storage.Storage) *Factory {
 	return &Factory{
 		storage:   s,
 		providers: make(map[string]Provider),
+		retry:     DefaultRetryConfig(),
+		keyed:     make(map[string]*keyedProvider),
 	}
 }
 
@@ -28,7 +39,7 @@ func NewFactory(s *storage.Storage) *Factory {
 func (f *Factory) Register(name string, p Provider) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	f.providers[name] = p
+	f.providers[name] = f.guard(name, f.logged(name, p))
 }
 
 // Get gets a provider by name.
@@ -38,6 +49,7 @@ func (f *Factory) Get(name string) (Provider, error) {
 		f.mu.RUnlock()
 		return p, nil
 	}
+	retry := f.retry
 	f.mu.RUnlock()
 
 	// Try to load from database
@@ -46,14 +58,21 @@ func (f *Factory) Get(name string) (Provider, error) {
 		return nil, fmt.Errorf("供应商 %s 未找到: %w", name, err)
 	}
 
-	p, err := f.createFromConfig(cfg)
+	p, err := f.build(cfg, retry)
 	if err != nil {
 		return nil, err
 	}
 
 	f.mu.Lock()
+	defer f.mu.Unlock()
+	if existing, ok := f.providers[name]; ok {
+		return existing, nil
+	}
+	if k, ok := p.(*keyedProvider); ok {
+		f.keyed[name] = k
+	}
+	p = f.guard(name, f.logged(name, p))
 	f.providers[name] = p
-	f.mu.Unlock()
 	return p, nil
 }
 
@@ -82,6 +101,14 @@ func (f *Factory) createFromConfig(cfg *storage.Provider) (Provider, error) {
 		return NewOpenRouterProvider(cfg), nil
 	case consts.ProviderQwen, consts.ProviderQwenCodingPlan:
 		return NewQwenProvider(cfg), nil
+	case consts.ProviderZhipu:
+		return NewZhipuProvider(cfg), nil
+	case consts.ProviderMoonshot:
+		return NewMoonshotProvider(cfg), nil
+	case consts.ProviderBaichuan:
+		return NewBaichuanProvider(cfg), nil
+	case consts.ProviderOllama:
+		return NewOllamaProvider(cfg), nil
 	default:
 		return nil, fmt.Errorf("未支持的供应商类型: %s", cfg.Type)
 	}
@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// filterReasons 表示回复被内容安全策略拦截的结束原因（小写），来自 OpenAI 兼容接口的 finish_reason、
+// Anthropic 的 stop_reason 和 Gemini 的 finishReason / blockReason
+var filterReasons = map[string]bool{
+	"content_filter":     true, // OpenAI、Azure OpenAI 及多数兼容接口
+	"refusal":            true, // Anthropic 拒答
+	"sensitive":          true, // 智谱
+	"safety":             true, // Gemini
+	"prohibited_content": true,
+	"blocklist":          true,
+	"spii":               true,
+	"image_safety":       true,
+}
+
+// ContentFilterError 提供商因内容安全策略拦截了本次回复，可能只返回了部分内容或拒答说明。
+// 智能体据此换用更谨慎的提示重试一次，仍被拦截时向用户显示明确的提示，而不是空消息。
+type ContentFilterError struct {
+	Provider string
+	Reason   string // 提供商给出的原因，如 content_filter、refusal、SAFETY
+	Refusal  string // 模型的拒答说明，可能为空
+}
+
+func (e *ContentFilterError) Error() string {
+	msg := fmt.Sprintf("回复被提供商 %s 的内容安全策略拦截 (%s)", e.Provider, e.Reason)
+	if e.Refusal != "" {
+		msg += ": " + e.Refusal
+	}
+	return msg
+}
+
+// IsContentFiltered 判断错误是否表示回复被内容安全策略拦截。
+func IsContentFiltered(err error) bool {
+	var filtered *ContentFilterError
+	return errors.As(err, &filtered)
+}
+
+// contentFilter 根据结束原因和拒答内容判断回复是否被拦截，未被拦截时返回 nil。
+// OpenAI 的拒答以 refusal 字段返回，结束原因仍为 stop。
+func contentFilter(provider, reason, refusal string) error {
+	switch {
+	case filterReasons[strings.ToLower(reason)]:
+		return &ContentFilterError{Provider: provider, Reason: reason, Refusal: refusal}
+	case refusal != "":
+		return &ContentFilterError{Provider: provider, Reason: "refusal", Refusal: refusal}
+	default:
+		return nil
+	}
+}
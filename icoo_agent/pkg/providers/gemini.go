@@ -3,7 +3,6 @@ package providers
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -39,6 +38,11 @@ func NewGeminiProvider(cfg *storage.Provider) Provider {
 	}
 }
 
+// geminiPromptFeedback 提示词被安全策略拦截时没有候选回复，原因在 blockReason 中。
+type geminiPromptFeedback struct {
+	BlockReason string `json:"blockReason"`
+}
+
 // Chat sends a chat request to Gemini.
 func (p *GeminiProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	// Convert messages to Gemini format
@@ -94,25 +98,9 @@ func (p *GeminiProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 	// Build URL with API key
 	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", p.apiBase, req.Model, p.apiKey)
 
-	var reqBody io.Reader
-	if geminiReq != nil {
-		data, err := json.Marshal(geminiReq)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request: %w", err)
-		}
-		reqBody = bytes.NewReader(data)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, reqBody)
+	resp, err := p.sendRequest(ctx, "POST", url, geminiReq, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := p.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -134,7 +122,8 @@ func (p *GeminiProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 			} `json:"content"`
 			FinishReason string `json:"finishReason"`
 		} `json:"candidates"`
-		UsageMetadata struct {
+		PromptFeedback geminiPromptFeedback `json:"promptFeedback"`
+		UsageMetadata  struct {
 			PromptTokenCount     int `json:"promptTokenCount"`
 			CandidatesTokenCount int `json:"candidatesTokenCount"`
 			TotalTokenCount      int `json:"totalTokenCount"`
@@ -146,8 +135,14 @@ func (p *GeminiProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 	}
 
 	if len(result.Candidates) == 0 {
+		if err := contentFilter(p.name, result.PromptFeedback.BlockReason, ""); err != nil {
+			return nil, err
+		}
 		return nil, fmt.Errorf("no candidates in response")
 	}
+	if err := contentFilter(p.name, result.Candidates[0].FinishReason, ""); err != nil {
+		return nil, err
+	}
 
 	var content string
 	var toolCalls []ToolCall
@@ -223,25 +218,9 @@ func (p *GeminiProvider) ChatStream(ctx context.Context, req ChatRequest, callba
 	// Build URL with API key
 	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?key=%s&alt=sse", p.apiBase, req.Model, p.apiKey)
 
-	var reqBody io.Reader
-	if geminiReq != nil {
-		data, err := json.Marshal(geminiReq)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request: %w", err)
-		}
-		reqBody = bytes.NewReader(data)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, reqBody)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := p.httpClient.Do(httpReq)
+	resp, err := p.sendRequest(ctx, "POST", url, geminiReq, nil)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -268,11 +247,15 @@ func (p *GeminiProvider) ChatStream(ctx context.Context, req ChatRequest, callba
 				} `json:"content"`
 				FinishReason string `json:"finishReason"`
 			} `json:"candidates"`
+			PromptFeedback geminiPromptFeedback `json:"promptFeedback"`
 		}
 
 		if err := json.Unmarshal([]byte(data), &result); err != nil {
 			continue
 		}
+		if err := contentFilter(p.name, result.PromptFeedback.BlockReason, ""); err != nil {
+			return err
+		}
 
 		if len(result.Candidates) > 0 {
 			done := result.Candidates[0].FinishReason != ""
@@ -280,9 +263,13 @@ func (p *GeminiProvider) ChatStream(ctx context.Context, req ChatRequest, callba
 			for _, part := range result.Candidates[0].Content.Parts {
 				content += part.Text
 			}
-			if err := callback(content, "", nil, done); err != nil {
+			filterErr := contentFilter(p.name, result.Candidates[0].FinishReason, "")
+			if err := callback(content, "", nil, done && filterErr == nil); err != nil {
 				return err
 			}
+			if filterErr != nil {
+				return filterErr
+			}
 		}
 	}
 
@@ -65,6 +65,9 @@ func (p *GroqProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 	if len(result.Choices) == 0 {
 		return nil, fmt.Errorf("no choices in response")
 	}
+	if err := contentFilter(p.name, result.Choices[0].FinishReason, ""); err != nil {
+		return nil, err
+	}
 
 	return &ChatResponse{
 		ID:        result.ID,
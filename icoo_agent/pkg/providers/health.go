@@ -0,0 +1,510 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	icooclawErrors "icooclaw/pkg/errors"
+)
+
+// CircuitState 熔断器状态。
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // 正常放行
+	CircuitOpen     CircuitState = "open"      // 熔断中，请求直接失败
+	CircuitHalfOpen CircuitState = "half_open" // 冷却结束，放行一个试探请求
+)
+
+// HealthConfig 健康检查与熔断配置。
+type HealthConfig struct {
+	Interval         time.Duration // 探测间隔，0 表示只熔断不主动探测
+	Timeout          time.Duration // 单次探测超时
+	FailureThreshold int           // 连续失败多少次后熔断
+	Cooldown         time.Duration // 熔断后多久放行试探请求
+	Fallbacks        []string      // 熔断时依次尝试的备用提供商
+}
+
+// CircuitBreaker 连续失败达到阈值后打开，冷却结束后进入半开状态放行一个试探请求，
+// 试探成功则关闭，失败则重新打开。
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	trial     bool
+	now       func() time.Time
+}
+
+// NewCircuitBreaker 创建熔断器。
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+		state:     CircuitClosed,
+		now:       time.Now,
+	}
+}
+
+// Allow 判断是否放行请求，半开状态下同一时间只放行一个试探请求。
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		b.trial = true
+		return true
+	case CircuitHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Success 记录一次成功，关闭熔断器。
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = CircuitClosed
+	b.failures = 0
+	b.trial = false
+}
+
+// Failure 记录一次失败，连续失败达到阈值或试探失败时打开熔断器。
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+	}
+}
+
+// release 释放半开状态下的试探名额，不改变状态。
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// State 返回当前状态。
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Failures 返回连续失败次数。
+func (b *CircuitBreaker) Failures() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures
+}
+
+// ProviderHealth 提供商健康状态。
+type ProviderHealth struct {
+	Name                string       `json:"name"`
+	Healthy             bool         `json:"healthy"`
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastError           string       `json:"last_error,omitempty"`
+	LastCheck           time.Time    `json:"last_check"`
+	LatencyMs           int64        `json:"latency_ms"`
+	Requests            uint64       `json:"requests"`
+	Failures            uint64       `json:"failures"`
+	ShortCircuits       uint64       `json:"short_circuits"`
+}
+
+// providerHealth 单个提供商的熔断器与统计。
+type providerHealth struct {
+	name    string
+	breaker *CircuitBreaker
+
+	mu            sync.Mutex
+	lastError     string
+	lastCheck     time.Time
+	latency       time.Duration
+	requests      uint64
+	failures      uint64
+	shortCircuits uint64
+}
+
+// record 记录一次请求结果。只有提供商不可达类错误才计入熔断，
+// 认证失败、限流等说明服务端有响应，视为可达；请求被取消时不改变状态。
+func (h *providerHealth) record(err error, latency time.Duration) {
+	h.mu.Lock()
+	h.requests++
+	h.latency = latency
+	if err != nil {
+		h.failures++
+		h.lastError = err.Error()
+	}
+	h.mu.Unlock()
+
+	switch {
+	case icooclawErrors.IsProviderUnavailable(err):
+		h.breaker.Failure()
+	case err != nil && errors.Is(err, context.Canceled):
+		h.breaker.release()
+	default:
+		h.breaker.Success()
+	}
+}
+
+// probed 记录一次健康探测结果。
+func (h *providerHealth) probed(err error, latency time.Duration) {
+	h.mu.Lock()
+	h.lastCheck = time.Now()
+	h.latency = latency
+	if err != nil {
+		h.lastError = err.Error()
+	} else {
+		h.lastError = ""
+	}
+	h.mu.Unlock()
+
+	if err != nil {
+		h.breaker.Failure()
+	} else {
+		h.breaker.Success()
+	}
+}
+
+// rejected 记录一次被熔断拒绝的请求。
+func (h *providerHealth) rejected() {
+	h.mu.Lock()
+	h.shortCircuits++
+	h.mu.Unlock()
+}
+
+// snapshot 返回当前健康状态。
+func (h *providerHealth) snapshot() ProviderHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state := h.breaker.State()
+	return ProviderHealth{
+		Name:                h.name,
+		Healthy:             state == CircuitClosed,
+		State:               state,
+		ConsecutiveFailures: h.breaker.Failures(),
+		LastError:           h.lastError,
+		LastCheck:           h.lastCheck,
+		LatencyMs:           h.latency.Milliseconds(),
+		Requests:            h.requests,
+		Failures:            h.failures,
+		ShortCircuits:       h.shortCircuits,
+	}
+}
+
+// Prober 支持轻量健康探测的提供商。
+type Prober interface {
+	Probe(ctx context.Context) error
+}
+
+// Probe 请求 /models 接口探测提供商是否可用，服务端有响应且认证通过即视为健康。
+func (p *BaseProvider) Probe(ctx context.Context) error {
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	return p.probe(ctx, headers)
+}
+
+// probe 使用指定请求头请求 /models 接口。
+func (p *BaseProvider) probe(ctx context.Context, headers map[string]string) error {
+	resp, err := p.doRequestWithHeaders(withoutRetry(ctx), http.MethodGet, "/models", nil, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return p.handleError(resp)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return icooclawErrors.NewFailoverError(icooclawErrors.FailoverTimeout, p.name, "", resp.StatusCode,
+			fmt.Errorf("server error: %d", resp.StatusCode))
+	default:
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+}
+
+// guardedProvider 在提供商外层接入熔断器，熔断时快速失败或转到备用提供商。
+type guardedProvider struct {
+	Provider
+	factory *Factory
+	health  *providerHealth
+}
+
+// Chat 发送聊天请求。
+func (g *guardedProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if !g.health.breaker.Allow() {
+		g.health.rejected()
+		if fb := g.factory.fallback(g.health.name); fb != nil {
+			req.Model = fb.GetModel()
+			return fb.Chat(ctx, req)
+		}
+		return nil, g.openError(req.Model)
+	}
+
+	start := time.Now()
+	resp, err := g.Provider.Chat(ctx, req)
+	g.health.record(err, time.Since(start))
+	return resp, err
+}
+
+// ChatStream 发送流式聊天请求。
+func (g *guardedProvider) ChatStream(ctx context.Context, req ChatRequest, callback StreamCallback) error {
+	if !g.health.breaker.Allow() {
+		g.health.rejected()
+		if fb := g.factory.fallback(g.health.name); fb != nil {
+			req.Model = fb.GetModel()
+			return fb.ChatStream(ctx, req, callback)
+		}
+		return g.openError(req.Model)
+	}
+
+	start := time.Now()
+	err := g.Provider.ChatStream(ctx, req, callback)
+	g.health.record(err, time.Since(start))
+	return err
+}
+
+// openError 熔断时返回的错误，归类为提供商不可用，可进入离线队列。
+func (g *guardedProvider) openError(model string) error {
+	return icooclawErrors.NewFailoverError(icooclawErrors.FailoverTimeout, g.health.name, model, 0,
+		fmt.Errorf("%w: 熔断器已打开", icooclawErrors.ErrProviderUnavailable))
+}
+
+// WithHealth 启用熔断与健康检查，之后通过 Get 获取的提供商都会接入熔断器。
+func (f *Factory) WithHealth(cfg HealthConfig) *Factory {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	f.healthCfg = &cfg
+	f.health = make(map[string]*providerHealth)
+	return f
+}
+
+// healthOf 返回提供商的健康记录，不存在时创建。调用方需持有写锁。
+func (f *Factory) healthOf(name string) *providerHealth {
+	h, ok := f.health[name]
+	if !ok {
+		h = &providerHealth{
+			name:    name,
+			breaker: NewCircuitBreaker(f.healthCfg.FailureThreshold, f.healthCfg.Cooldown),
+		}
+		f.health[name] = h
+	}
+	return h
+}
+
+// guard 为提供商接入熔断器，未启用健康检查时原样返回。调用方需持有写锁。
+func (f *Factory) guard(name string, p Provider) Provider {
+	if f.healthCfg == nil {
+		return p
+	}
+	return &guardedProvider{Provider: p, factory: f, health: f.healthOf(name)}
+}
+
+// fallback 返回第一个熔断器关闭的备用提供商。
+func (f *Factory) fallback(name string) Provider {
+	f.mu.RLock()
+	fallbacks := slices.Clone(f.healthCfg.Fallbacks)
+	f.mu.RUnlock()
+
+	for _, fb := range fallbacks {
+		if fb == name {
+			continue
+		}
+		p, err := f.Get(fb)
+		if err != nil {
+			continue
+		}
+		if g, ok := p.(*guardedProvider); ok && g.health.breaker.State() != CircuitClosed {
+			continue
+		}
+		slog.Default().With("name", "【提供商】").Warn("提供商已熔断，使用备用提供商",
+			"provider", name, "fallback", fb)
+		return p
+	}
+	return nil
+}
+
+// Health 返回所有已加载提供商的健康状态，按名称排序。
+func (f *Factory) Health() []ProviderHealth {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	result := make([]ProviderHealth, 0, len(f.health))
+	for _, h := range f.health {
+		result = append(result, h.snapshot())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// CheckHealth 对所有启用的提供商执行一次健康探测。
+// 实现了 Prober 的提供商请求模型列表接口，否则发送一个最小的补全请求。
+func (f *Factory) CheckHealth(ctx context.Context) {
+	if f.healthCfg == nil {
+		return
+	}
+	logger := slog.Default().With("name", "【提供商】")
+
+	configs, err := f.storage.Provider().List()
+	if err != nil {
+		logger.Warn("获取提供商列表失败", "error", err)
+		return
+	}
+
+	for _, cfg := range configs {
+		if !cfg.Enabled {
+			continue
+		}
+		p, err := f.Get(cfg.Name)
+		if err != nil {
+			logger.Warn("加载提供商失败", "provider", cfg.Name, "error", err)
+			continue
+		}
+		g, ok := p.(*guardedProvider)
+		if !ok {
+			continue
+		}
+
+		before := g.health.breaker.State()
+		start := time.Now()
+		err = probe(ctx, g.Provider, f.healthCfg.Timeout)
+		g.health.probed(err, time.Since(start))
+
+		switch after := g.health.breaker.State(); {
+		case after == CircuitOpen && before != CircuitOpen:
+			logger.Warn("提供商不可用，已熔断", "provider", cfg.Name, "error", err)
+		case after == CircuitClosed && before != CircuitClosed:
+			logger.Info("提供商已恢复", "provider", cfg.Name)
+		}
+	}
+}
+
+// RunHealthChecks 按配置间隔周期性探测提供商，直到 ctx 取消。
+func (f *Factory) RunHealthChecks(ctx context.Context) {
+	if f.healthCfg == nil || f.healthCfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(f.healthCfg.Interval)
+	defer ticker.Stop()
+
+	f.CheckHealth(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.CheckHealth(ctx)
+		}
+	}
+}
+
+// probe 探测单个提供商。
+func probe(ctx context.Context, p Provider, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return ping(ctx, p)
+}
+
+// ping 实现了 Prober 的提供商请求模型列表接口，否则发送一个最小的补全请求。
+func ping(ctx context.Context, p Provider) error {
+	if prober, ok := p.(Prober); ok {
+		return prober.Probe(ctx)
+	}
+	_, err := p.Chat(ctx, ChatRequest{
+		Model:     p.GetModel(),
+		Messages:  []ChatMessage{{Role: "user", Content: "ping"}},
+		MaxTokens: 1,
+	})
+	return err
+}
+
+// WriteMetrics 以 Prometheus 文本格式输出提供商健康指标。
+func (f *Factory) WriteMetrics(w io.Writer) {
+	health := f.Health()
+
+	fmt.Fprintln(w, "# HELP icooclaw_provider_up Whether the provider circuit is closed.")
+	fmt.Fprintln(w, "# TYPE icooclaw_provider_up gauge")
+	for _, h := range health {
+		up := 0
+		if h.Healthy {
+			up = 1
+		}
+		fmt.Fprintf(w, "icooclaw_provider_up{provider=%q} %d\n", h.Name, up)
+	}
+
+	fmt.Fprintln(w, "# HELP icooclaw_provider_circuit_open Whether the provider circuit is open.")
+	fmt.Fprintln(w, "# TYPE icooclaw_provider_circuit_open gauge")
+	for _, h := range health {
+		open := 0
+		if h.State == CircuitOpen {
+			open = 1
+		}
+		fmt.Fprintf(w, "icooclaw_provider_circuit_open{provider=%q} %d\n", h.Name, open)
+	}
+
+	fmt.Fprintln(w, "# HELP icooclaw_provider_consecutive_failures Consecutive failed requests or probes.")
+	fmt.Fprintln(w, "# TYPE icooclaw_provider_consecutive_failures gauge")
+	for _, h := range health {
+		fmt.Fprintf(w, "icooclaw_provider_consecutive_failures{provider=%q} %d\n", h.Name, h.ConsecutiveFailures)
+	}
+
+	fmt.Fprintln(w, "# HELP icooclaw_provider_latency_seconds Latency of the last request or probe.")
+	fmt.Fprintln(w, "# TYPE icooclaw_provider_latency_seconds gauge")
+	for _, h := range health {
+		fmt.Fprintf(w, "icooclaw_provider_latency_seconds{provider=%q} %g\n", h.Name, float64(h.LatencyMs)/1000)
+	}
+
+	fmt.Fprintln(w, "# HELP icooclaw_provider_requests_total Requests sent to the provider.")
+	fmt.Fprintln(w, "# TYPE icooclaw_provider_requests_total counter")
+	for _, h := range health {
+		fmt.Fprintf(w, "icooclaw_provider_requests_total{provider=%q} %d\n", h.Name, h.Requests)
+	}
+
+	fmt.Fprintln(w, "# HELP icooclaw_provider_failures_total Failed requests to the provider.")
+	fmt.Fprintln(w, "# TYPE icooclaw_provider_failures_total counter")
+	for _, h := range health {
+		fmt.Fprintf(w, "icooclaw_provider_failures_total{provider=%q} %d\n", h.Name, h.Failures)
+	}
+
+	fmt.Fprintln(w, "# HELP icooclaw_provider_short_circuits_total Requests rejected by the open circuit.")
+	fmt.Fprintln(w, "# TYPE icooclaw_provider_short_circuits_total counter")
+	for _, h := range health {
+		fmt.Fprintf(w, "icooclaw_provider_short_circuits_total{provider=%q} %d\n", h.Name, h.ShortCircuits)
+	}
+}
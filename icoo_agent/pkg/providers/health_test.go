@@ -0,0 +1,134 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	icooclawErrors "icooclaw/pkg/errors"
+)
+
+// stubProvider 按预设错误返回的测试提供商。
+type stubProvider struct {
+	*BaseProvider
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &ChatResponse{Model: req.Model, Content: p.name}, nil
+}
+
+func (p *stubProvider) ChatStream(ctx context.Context, req ChatRequest, callback StreamCallback) error {
+	_, err := p.Chat(ctx, req)
+	return err
+}
+
+func newStub(name string, err error) *stubProvider {
+	return &stubProvider{BaseProvider: NewBaseProvider(name, "", "", name+"-model"), err: err}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	if b.State() != CircuitClosed || !b.Allow() {
+		t.Fatal("breaker should stay closed below threshold")
+	}
+
+	b.Failure()
+	if b.State() != CircuitOpen || b.Allow() {
+		t.Fatal("breaker should open at threshold")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.Allow() || b.State() != CircuitHalfOpen {
+		t.Fatal("breaker should let one trial through after cooldown")
+	}
+	if b.Allow() {
+		t.Fatal("breaker should allow only one trial while half open")
+	}
+
+	b.Failure()
+	if b.State() != CircuitOpen {
+		t.Fatal("failed trial should reopen the breaker")
+	}
+
+	now = now.Add(time.Minute)
+	b.Allow()
+	b.Success()
+	if b.State() != CircuitClosed || b.Failures() != 0 {
+		t.Fatal("successful trial should close the breaker")
+	}
+}
+
+func TestFactory_CircuitOpen(t *testing.T) {
+	down := newStub("primary", icooclawErrors.NewFailoverError(icooclawErrors.FailoverTimeout, "primary", "", 503, fmt.Errorf("server error")))
+
+	f := NewFactory(nil).WithHealth(HealthConfig{FailureThreshold: 2, Cooldown: time.Hour})
+	f.Register("primary", down)
+	p, _ := f.Get("primary")
+
+	for range 2 {
+		p.Chat(context.Background(), ChatRequest{})
+	}
+
+	_, err := p.Chat(context.Background(), ChatRequest{})
+	if !icooclawErrors.IsProviderUnavailable(err) || !strings.Contains(err.Error(), "熔断") {
+		t.Fatalf("expected fast circuit-open failure, got %v", err)
+	}
+	if down.calls != 2 {
+		t.Errorf("open circuit should not reach the provider, calls = %d", down.calls)
+	}
+
+	health := f.Health()
+	if len(health) != 1 || health[0].Healthy || health[0].State != CircuitOpen || health[0].ShortCircuits != 1 {
+		t.Errorf("unexpected health: %+v", health)
+	}
+}
+
+func TestFactory_Fallback(t *testing.T) {
+	down := newStub("primary", icooclawErrors.ErrProviderUnavailable)
+	backup := newStub("backup", nil)
+
+	f := NewFactory(nil).WithHealth(HealthConfig{FailureThreshold: 1, Cooldown: time.Hour, Fallbacks: []string{"backup"}})
+	f.Register("primary", down)
+	f.Register("backup", backup)
+	p, _ := f.Get("primary")
+
+	p.Chat(context.Background(), ChatRequest{Model: "primary-model"})
+
+	resp, err := p.Chat(context.Background(), ChatRequest{Model: "primary-model"})
+	if err != nil {
+		t.Fatalf("fallback failed: %v", err)
+	}
+	if resp.Content != "backup" || resp.Model != "backup-model" {
+		t.Errorf("expected backup provider with its default model, got %+v", resp)
+	}
+}
+
+func TestFactory_WriteMetrics(t *testing.T) {
+	f := NewFactory(nil).WithHealth(HealthConfig{FailureThreshold: 1})
+	f.Register("primary", newStub("primary", nil))
+	p, _ := f.Get("primary")
+	p.Chat(context.Background(), ChatRequest{})
+
+	var sb strings.Builder
+	f.WriteMetrics(&sb)
+	for _, want := range []string{
+		`icooclaw_provider_up{provider="primary"} 1`,
+		`icooclaw_provider_requests_total{provider="primary"} 1`,
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, sb.String())
+		}
+	}
+}
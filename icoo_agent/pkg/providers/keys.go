@@ -0,0 +1,440 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/storage"
+)
+
+// Key selection strategies.
+const (
+	KeyRoundRobin = "round_robin" // 依次轮流使用
+	KeyLRU        = "lru"         // 使用最久未使用的密钥
+)
+
+const (
+	// defaultKeyFailures 连续认证失败或限流多少次后停用密钥
+	defaultKeyFailures = 3
+	// defaultKeyCooldown 因限流停用的密钥多久后恢复
+	defaultKeyCooldown = 10 * time.Minute
+)
+
+// APIKey 提供商 config 字段 api_keys 中的一个密钥。
+type APIKey struct {
+	Name              string `json:"name"`                // 密钥名称，用于日志和状态查询，默认为脱敏后的密钥
+	Key               string `json:"key"`                 // 密钥
+	RequestsPerMinute int    `json:"requests_per_minute"` // 该密钥每分钟最多请求数，0 表示使用提供商的限速
+	Burst             int    `json:"burst"`               // 突发请求数，默认 1
+}
+
+// keyOptions 提供商 config 字段中的多密钥配置。
+type keyOptions struct {
+	APIKeys      []APIKey `json:"api_keys"`      // 额外的密钥，与 api_key 一起分担请求
+	KeySelection string   `json:"key_selection"` // 密钥选择策略：round_robin（默认）或 lru
+	KeyFailures  int      `json:"key_failures"`  // 连续认证失败或限流多少次后停用密钥，默认 3
+	KeyCooldown  string   `json:"key_cooldown"`  // 因限流停用的密钥多久后恢复，默认 10m；认证失败的密钥需手动恢复
+}
+
+// parseKeyOptions 解析多密钥配置，api_key 非空时作为第一个密钥。
+func parseKeyOptions(cfg *storage.Provider) (keyOptions, error) {
+	var opts keyOptions
+	if cfg.Config != "" {
+		if err := json.Unmarshal([]byte(cfg.Config), &opts); err != nil {
+			return opts, fmt.Errorf("invalid provider config: %w", err)
+		}
+	}
+
+	var keys []APIKey
+	if cfg.APIKey != "" {
+		keys = append(keys, APIKey{Name: "default", Key: cfg.APIKey})
+	}
+	for _, k := range opts.APIKeys {
+		if k.Key == "" {
+			continue
+		}
+		if k.Name == "" {
+			k.Name = maskKey(k.Key)
+		}
+		keys = append(keys, k)
+	}
+	opts.APIKeys = keys
+
+	switch opts.KeySelection {
+	case "":
+		opts.KeySelection = KeyRoundRobin
+	case KeyRoundRobin, KeyLRU:
+	default:
+		return opts, fmt.Errorf("unknown key_selection %q", opts.KeySelection)
+	}
+	if opts.KeyFailures <= 0 {
+		opts.KeyFailures = defaultKeyFailures
+	}
+	if opts.KeyCooldown != "" {
+		if _, err := time.ParseDuration(opts.KeyCooldown); err != nil {
+			return opts, fmt.Errorf("invalid key_cooldown: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// cooldown 返回因限流停用的密钥的恢复时间。
+func (o keyOptions) cooldown() time.Duration {
+	if d, err := time.ParseDuration(o.KeyCooldown); err == nil && d > 0 {
+		return d
+	}
+	return defaultKeyCooldown
+}
+
+// maskKey 脱敏密钥，只保留末 4 位。
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
+
+// KeyStatus 密钥的使用状态。
+type KeyStatus struct {
+	Name          string     `json:"name"`
+	Requests      int64      `json:"requests"`                 // 请求次数
+	Failures      int        `json:"failures"`                 // 连续认证失败或限流次数
+	LastUsed      *time.Time `json:"last_used,omitempty"`      // 最近一次使用时间
+	Disabled      bool       `json:"disabled"`                 // 是否已停用
+	DisabledUntil *time.Time `json:"disabled_until,omitempty"` // 因限流停用时的恢复时间，认证失败停用时为空
+	LastError     string     `json:"last_error,omitempty"`     // 最近一次认证失败或限流的错误
+}
+
+// ProviderKeys 提供商的密钥状态。
+type ProviderKeys struct {
+	Provider  string      `json:"provider"`
+	Selection string      `json:"selection"`
+	Keys      []KeyStatus `json:"keys"`
+}
+
+// poolKey 密钥池中的一个密钥。
+type poolKey struct {
+	name     string
+	provider Provider
+
+	requests      int64
+	failures      int
+	lastUsed      time.Time
+	disabled      bool
+	disabledUntil time.Time // 零值表示需手动恢复
+	lastErr       string
+}
+
+// available 判断密钥当前是否可用，限流停用到期后自动恢复。
+func (k *poolKey) available(now time.Time) bool {
+	if !k.disabled {
+		return true
+	}
+	if !k.disabledUntil.IsZero() && !now.Before(k.disabledUntil) {
+		k.disabled, k.disabledUntil, k.failures = false, time.Time{}, 0
+		return true
+	}
+	return false
+}
+
+// keyedProvider 使用多个密钥分担请求的提供商。每个密钥对应一个独立的提供商实例，有各自的限流器；
+// 认证失败或限流时换下一个密钥重试，连续失败达到阈值后停用该密钥。
+type keyedProvider struct {
+	name      string
+	selection string
+	threshold int
+	cooldown  time.Duration
+
+	mu   sync.Mutex
+	keys []*poolKey
+	next int
+	now  func() time.Time
+}
+
+// newKeyedProvider 创建多密钥提供商，keys 与 members 一一对应。
+func newKeyedProvider(name string, opts keyOptions, members []Provider) *keyedProvider {
+	p := &keyedProvider{
+		name:      name,
+		selection: opts.KeySelection,
+		threshold: opts.KeyFailures,
+		cooldown:  opts.cooldown(),
+		now:       time.Now,
+	}
+	for i, m := range members {
+		p.keys = append(p.keys, &poolKey{name: opts.APIKeys[i].Name, provider: m})
+	}
+	return p
+}
+
+// acquire 选择一个可用且本次请求未尝试过的密钥，没有时返回 nil。
+func (p *keyedProvider) acquire(tried map[*poolKey]bool) *poolKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var picked *poolKey
+	switch p.selection {
+	case KeyLRU:
+		for _, k := range p.keys {
+			if tried[k] || !k.available(now) {
+				continue
+			}
+			if picked == nil || k.lastUsed.Before(picked.lastUsed) {
+				picked = k
+			}
+		}
+	default:
+		for range p.keys {
+			k := p.keys[p.next]
+			p.next = (p.next + 1) % len(p.keys)
+			if !tried[k] && k.available(now) {
+				picked = k
+				break
+			}
+		}
+	}
+	if picked != nil {
+		picked.requests++
+		picked.lastUsed = now
+	}
+	return picked
+}
+
+// keyFailure 判断错误是否是密钥本身的问题（认证失败或限流），返回失败原因。
+func keyFailure(err error) (icooclawErrors.FailoverReason, bool) {
+	var failover *icooclawErrors.FailoverError
+	if !errors.As(err, &failover) {
+		return "", false
+	}
+	switch failover.Reason {
+	case icooclawErrors.FailoverAuth, icooclawErrors.FailoverRateLimit:
+		return failover.Reason, true
+	}
+	return "", false
+}
+
+// record 记录一次请求的结果，返回是否应换下一个密钥重试。
+func (p *keyedProvider) record(k *poolKey, err error) bool {
+	reason, ok := keyFailure(err)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !ok {
+		if err == nil {
+			k.failures = 0
+		}
+		return false
+	}
+
+	k.failures++
+	k.lastErr = err.Error()
+	if k.failures >= p.threshold && !k.disabled {
+		k.disabled = true
+		if reason == icooclawErrors.FailoverRateLimit {
+			k.disabledUntil = p.now().Add(p.cooldown)
+		}
+		slog.Default().With("name", "【提供商】").Warn("API 密钥连续失败，已停用",
+			"provider", p.name,
+			"key", k.name,
+			"reason", reason,
+			"failures", k.failures,
+			"until", k.disabledUntil)
+	}
+	return true
+}
+
+// unavailable 所有密钥都不可用时返回的错误。只有限流停用的密钥会自动恢复，此时归类为提供商暂时不可用。
+func (p *keyedProvider) unavailable(model string, last error) error {
+	if last != nil {
+		return last
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if !k.disabledUntil.IsZero() {
+			return icooclawErrors.NewFailoverError(icooclawErrors.FailoverRateLimit, p.name, model, 0,
+				fmt.Errorf("%w: 所有 API 密钥均因限流暂停使用", icooclawErrors.ErrProviderUnavailable))
+		}
+	}
+	return icooclawErrors.NewFailoverError(icooclawErrors.FailoverAuth, p.name, model, 0,
+		errors.New("所有 API 密钥均已停用"))
+}
+
+// Chat 使用选中的密钥发送聊天请求，认证失败或限流时换下一个密钥。
+func (p *keyedProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	ctx = withKeyRotation(ctx)
+	tried := make(map[*poolKey]bool)
+	var last error
+	for {
+		k := p.acquire(tried)
+		if k == nil {
+			return nil, p.unavailable(req.Model, last)
+		}
+		tried[k] = true
+		resp, err := k.provider.Chat(ctx, req)
+		if !p.record(k, err) {
+			return resp, err
+		}
+		last = err
+	}
+}
+
+// ChatStream 使用选中的密钥发送流式聊天请求，只在尚未收到任何分片时换下一个密钥。
+func (p *keyedProvider) ChatStream(ctx context.Context, req ChatRequest, callback StreamCallback) error {
+	ctx = withKeyRotation(ctx)
+	tried := make(map[*poolKey]bool)
+	var last error
+	for {
+		k := p.acquire(tried)
+		if k == nil {
+			return p.unavailable(req.Model, last)
+		}
+		tried[k] = true
+		started := false
+		err := k.provider.ChatStream(ctx, req, func(chunk, reasoning string, calls []ToolCall, done bool) error {
+			started = true
+			return callback(chunk, reasoning, calls, done)
+		})
+		if !p.record(k, err) || started {
+			return err
+		}
+		last = err
+	}
+}
+
+// Probe 使用一个可用的密钥探测可用性。
+func (p *keyedProvider) Probe(ctx context.Context) error {
+	k := p.acquire(nil)
+	if k == nil {
+		return p.unavailable(p.GetModel(), nil)
+	}
+	err := ping(ctx, k.provider)
+	p.record(k, err)
+	return err
+}
+
+// GetName 获取提供者的名称。
+func (p *keyedProvider) GetName() string {
+	return p.keys[0].provider.GetName()
+}
+
+// GetModel 获取当前使用的模型。
+func (p *keyedProvider) GetModel() string {
+	return p.keys[0].provider.GetModel()
+}
+
+// SetModel 设置所有密钥使用的模型。
+func (p *keyedProvider) SetModel(model string) {
+	for _, k := range p.keys {
+		k.provider.SetModel(model)
+	}
+}
+
+// Status 返回各密钥的使用状态。
+func (p *keyedProvider) Status() ProviderKeys {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	status := ProviderKeys{Provider: p.name, Selection: p.selection, Keys: make([]KeyStatus, 0, len(p.keys))}
+	for _, k := range p.keys {
+		k.available(now)
+		s := KeyStatus{Name: k.name, Requests: k.requests, Failures: k.failures, Disabled: k.disabled, LastError: k.lastErr}
+		if !k.lastUsed.IsZero() {
+			lastUsed := k.lastUsed
+			s.LastUsed = &lastUsed
+		}
+		if k.disabled && !k.disabledUntil.IsZero() {
+			until := k.disabledUntil
+			s.DisabledUntil = &until
+		}
+		status.Keys = append(status.Keys, s)
+	}
+	return status
+}
+
+// Enable 恢复停用的密钥，返回是否找到该密钥。
+func (p *keyedProvider) Enable(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if k.name == name {
+			k.disabled, k.disabledUntil, k.failures, k.lastErr = false, time.Time{}, 0, ""
+			return true
+		}
+	}
+	return false
+}
+
+// keyRotationKey 上下文键，设置后限流响应不在同一个密钥上重试，由密钥池换下一个密钥
+type keyRotationKey struct{}
+
+// withKeyRotation 返回限流时换密钥而不是等待重试的上下文。
+func withKeyRotation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyRotationKey{}, true)
+}
+
+// keyRotation 上下文是否要求限流时换密钥。
+func keyRotation(ctx context.Context) bool {
+	v, _ := ctx.Value(keyRotationKey{}).(bool)
+	return v
+}
+
+// build 根据配置创建提供商。配置了多个密钥时每个密钥创建一个实例，组成密钥池。
+func (f *Factory) build(cfg *storage.Provider, retry RetryConfig) (Provider, error) {
+	opts, err := parseKeyOptions(cfg)
+	if err != nil || len(opts.APIKeys) <= 1 {
+		if err != nil {
+			slog.Default().With("name", "【提供商】").Warn("多密钥配置无效，只使用 api_key", "provider", cfg.Name, "error", err)
+		}
+		p, err := f.createFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return applyRetry(applyStreamQuirks(p, cfg), cfg, retry), nil
+	}
+
+	members := make([]Provider, 0, len(opts.APIKeys))
+	for _, key := range opts.APIKeys {
+		c := *cfg
+		c.APIKey = key.Key
+		p, err := f.createFromConfig(&c)
+		if err != nil {
+			return nil, err
+		}
+		p = applyRetry(applyStreamQuirks(p, &c), &c, retry)
+		if s, ok := p.(interface{ SetRateLimit(rpm, burst int) }); ok && key.RequestsPerMinute > 0 {
+			s.SetRateLimit(key.RequestsPerMinute, key.Burst)
+		}
+		members = append(members, p)
+	}
+	return newKeyedProvider(cfg.Name, opts, members), nil
+}
+
+// Keys 返回所有使用多个密钥的已加载提供商的密钥状态，按名称排序。
+func (f *Factory) Keys() []ProviderKeys {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	result := make([]ProviderKeys, 0, len(f.keyed))
+	for _, p := range f.keyed {
+		result = append(result, p.Status())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Provider < result[j].Provider })
+	return result
+}
+
+// EnableKey 恢复提供商停用的密钥，返回是否找到该密钥。
+func (f *Factory) EnableKey(provider, key string) bool {
+	f.mu.RLock()
+	p, ok := f.keyed[provider]
+	f.mu.RUnlock()
+	return ok && p.Enable(key)
+}
@@ -0,0 +1,154 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/consts"
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/storage"
+)
+
+func newTestKeyed(selection string, members ...Provider) *keyedProvider {
+	opts := keyOptions{KeySelection: selection, KeyFailures: 2, KeyCooldown: "1m"}
+	for _, m := range members {
+		opts.APIKeys = append(opts.APIKeys, APIKey{Name: m.GetName()})
+	}
+	return newKeyedProvider("pool", opts, members)
+}
+
+func TestKeyedProvider_Selection(t *testing.T) {
+	a, b, c := newStub("a", nil), newStub("b", nil), newStub("c", nil)
+	p := newTestKeyed(KeyRoundRobin, a, b, c)
+	for range 6 {
+		if _, err := p.Chat(context.Background(), ChatRequest{}); err != nil {
+			t.Fatalf("Chat() error = %v", err)
+		}
+	}
+	if a.calls != 2 || b.calls != 2 || c.calls != 2 {
+		t.Errorf("round robin calls = %d/%d/%d", a.calls, b.calls, c.calls)
+	}
+
+	// lru 总是选择最久未使用的密钥
+	now := time.Now()
+	a, b = newStub("a", nil), newStub("b", nil)
+	p = newTestKeyed(KeyLRU, a, b)
+	p.now = func() time.Time { return now }
+	p.keys[0].lastUsed = now.Add(-time.Second)
+	p.keys[1].lastUsed = now.Add(-time.Hour)
+	resp, _ := p.Chat(context.Background(), ChatRequest{})
+	if resp.Content != "b" {
+		t.Errorf("lru picked %q, want b", resp.Content)
+	}
+}
+
+func TestKeyedProvider_Failover(t *testing.T) {
+	now := time.Now()
+	auth := icooclawErrors.NewFailoverError(icooclawErrors.FailoverAuth, "a", "", 401, errors.New("bad key"))
+	limited := icooclawErrors.NewFailoverError(icooclawErrors.FailoverRateLimit, "b", "", 429, errors.New("slow down"))
+	a, b, c := newStub("a", auth), newStub("b", limited), newStub("c", nil)
+	p := newTestKeyed(KeyRoundRobin, a, b, c)
+	p.now = func() time.Time { return now }
+
+	// 失败的密钥被跳过，请求由下一个密钥完成
+	for range 2 {
+		resp, err := p.Chat(context.Background(), ChatRequest{})
+		if err != nil || resp.Content != "c" {
+			t.Fatalf("Chat() = %v, %v", resp, err)
+		}
+	}
+	status := p.Status()
+	if !status.Keys[0].Disabled || status.Keys[0].DisabledUntil != nil {
+		t.Errorf("auth failures should disable key until enabled: %+v", status.Keys[0])
+	}
+	if !status.Keys[1].Disabled || status.Keys[1].DisabledUntil == nil {
+		t.Errorf("rate limits should disable key for the cooldown: %+v", status.Keys[1])
+	}
+
+	// 停用的密钥不再使用
+	a.calls, b.calls = 0, 0
+	p.Chat(context.Background(), ChatRequest{})
+	if a.calls != 0 || b.calls != 0 {
+		t.Errorf("disabled keys called %d/%d times", a.calls, b.calls)
+	}
+
+	// 冷却结束后限流的密钥自动恢复，认证失败的密钥需手动恢复
+	now = now.Add(time.Minute)
+	status = p.Status()
+	if !status.Keys[0].Disabled || status.Keys[1].Disabled {
+		t.Errorf("after cooldown: %+v", status.Keys)
+	}
+	if !p.Enable("a") || p.Status().Keys[0].Disabled {
+		t.Error("Enable() should restore the key")
+	}
+}
+
+func TestKeyedProvider_AllUnavailable(t *testing.T) {
+	limited := icooclawErrors.NewFailoverError(icooclawErrors.FailoverRateLimit, "a", "", 429, errors.New("slow down"))
+	p := newTestKeyed(KeyRoundRobin, newStub("a", limited), newStub("b", limited))
+
+	_, err := p.Chat(context.Background(), ChatRequest{})
+	if !errors.Is(err, limited) {
+		t.Errorf("Chat() error = %v, want last key error", err)
+	}
+	p.Chat(context.Background(), ChatRequest{})
+	// 所有密钥都因限流停用，可排队等待恢复
+	_, err = p.Chat(context.Background(), ChatRequest{})
+	if !icooclawErrors.IsProviderUnavailable(err) {
+		t.Errorf("Chat() with all keys paused error = %v", err)
+	}
+}
+
+func TestFactory_MultipleKeys(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls = map[string]int{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Authorization")
+		mu.Lock()
+		calls[key]++
+		mu.Unlock()
+		if key == "Bearer sk-limited" {
+			// 限流的密钥不在同一个密钥上重试，直接换下一个
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","model":"m","choices":[{"message":{"content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	f := NewFactory(nil).WithRetry(fastRetry)
+	p, err := f.build(&storage.Provider{
+		Name:    "team",
+		Type:    consts.ProviderOpenAI,
+		APIKey:  "sk-limited",
+		APIBase: srv.URL,
+		Config:  `{"api_keys": [{"name": "bob", "key": "sk-bob-0001"}], "key_failures": 5}`,
+	}, fastRetry)
+	if err != nil {
+		t.Fatalf("build() error = %v", err)
+	}
+	for range 2 {
+		resp, err := p.Chat(context.Background(), ChatRequest{Model: "m"})
+		if err != nil || resp.Content != "ok" {
+			t.Fatalf("Chat() = %v, %v", resp, err)
+		}
+	}
+	if calls["Bearer sk-limited"] != 2 || calls["Bearer sk-bob-0001"] != 2 {
+		t.Errorf("calls = %v", calls)
+	}
+
+	status := p.(*keyedProvider).Status()
+	if status.Keys[0].Name != "default" || status.Keys[1].Name != "bob" || status.Keys[0].Failures != 2 {
+		t.Errorf("Status() = %+v", status)
+	}
+}
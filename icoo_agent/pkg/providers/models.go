@@ -17,6 +17,8 @@ type ModelInfo struct {
 	InputPrice      float64  `json:"input_price"`  // per 1M tokens
 	OutputPrice     float64  `json:"output_price"` // per 1M tokens
 	Aliases         []string `json:"aliases,omitempty"`
+	Quantization    string   `json:"quantization,omitempty"` // 量化方式，仅本地模型
+	Discovered      bool     `json:"discovered,omitempty"`   // 是否从服务端探测得到
 }
 
 // Built-in model definitions
@@ -285,8 +287,47 @@ var modelRegistry = map[string]*ModelInfo{
 		InputPrice:      0.05,
 		OutputPrice:     0.20,
 	},
+	"qwen-long": {
+		ID:              "qwen-long",
+		Name:            "Qwen Long",
+		Provider:        "qwen",
+		ContextWindow:   10000000,
+		MaxOutputTokens: 8192,
+		SupportsVision:  false,
+		SupportsTools:   true,
+		SupportsJSON:    true,
+		SupportsStream:  true,
+		InputPrice:      0.07,
+		OutputPrice:     0.28,
+	},
 
 	// Zhipu models
+	"glm-4-plus": {
+		ID:              "glm-4-plus",
+		Name:            "GLM-4 Plus",
+		Provider:        "zhipu",
+		ContextWindow:   128000,
+		MaxOutputTokens: 4096,
+		SupportsVision:  false,
+		SupportsTools:   true,
+		SupportsJSON:    true,
+		SupportsStream:  true,
+		InputPrice:      0.70,
+		OutputPrice:     0.70,
+	},
+	"glm-4-air": {
+		ID:              "glm-4-air",
+		Name:            "GLM-4 Air",
+		Provider:        "zhipu",
+		ContextWindow:   128000,
+		MaxOutputTokens: 4096,
+		SupportsVision:  false,
+		SupportsTools:   true,
+		SupportsJSON:    true,
+		SupportsStream:  true,
+		InputPrice:      0.07,
+		OutputPrice:     0.07,
+	},
 	"glm-4": {
 		ID:              "glm-4",
 		Name:            "GLM-4",
@@ -315,6 +356,32 @@ var modelRegistry = map[string]*ModelInfo{
 	},
 
 	// Moonshot models
+	"moonshot-v1-auto": {
+		ID:              "moonshot-v1-auto",
+		Name:            "Moonshot V1 Auto",
+		Provider:        "moonshot",
+		ContextWindow:   131072,
+		MaxOutputTokens: 4096,
+		SupportsVision:  false,
+		SupportsTools:   true,
+		SupportsJSON:    true,
+		SupportsStream:  true,
+		InputPrice:      2.00,
+		OutputPrice:     2.00,
+	},
+	"kimi-k2-0905-preview": {
+		ID:              "kimi-k2-0905-preview",
+		Name:            "Kimi K2",
+		Provider:        "moonshot",
+		ContextWindow:   262144,
+		MaxOutputTokens: 16384,
+		SupportsVision:  false,
+		SupportsTools:   true,
+		SupportsJSON:    true,
+		SupportsStream:  true,
+		InputPrice:      0.60,
+		OutputPrice:     2.50,
+	},
 	"moonshot-v1-8k": {
 		ID:              "moonshot-v1-8k",
 		Name:            "Moonshot V1 8K",
@@ -322,7 +389,7 @@ var modelRegistry = map[string]*ModelInfo{
 		ContextWindow:   8192,
 		MaxOutputTokens: 4096,
 		SupportsVision:  false,
-		SupportsTools:   false,
+		SupportsTools:   true,
 		SupportsJSON:    true,
 		SupportsStream:  true,
 		InputPrice:      0.50,
@@ -335,7 +402,7 @@ var modelRegistry = map[string]*ModelInfo{
 		ContextWindow:   32768,
 		MaxOutputTokens: 4096,
 		SupportsVision:  false,
-		SupportsTools:   false,
+		SupportsTools:   true,
 		SupportsJSON:    true,
 		SupportsStream:  true,
 		InputPrice:      1.00,
@@ -348,13 +415,54 @@ var modelRegistry = map[string]*ModelInfo{
 		ContextWindow:   131072,
 		MaxOutputTokens: 4096,
 		SupportsVision:  false,
-		SupportsTools:   false,
+		SupportsTools:   true,
 		SupportsJSON:    true,
 		SupportsStream:  true,
 		InputPrice:      2.00,
 		OutputPrice:     2.00,
 	},
 
+	// Baichuan models
+	"Baichuan4-Turbo": {
+		ID:              "Baichuan4-Turbo",
+		Name:            "Baichuan 4 Turbo",
+		Provider:        "baichuan",
+		ContextWindow:   32768,
+		MaxOutputTokens: 2048,
+		SupportsVision:  false,
+		SupportsTools:   true,
+		SupportsJSON:    true,
+		SupportsStream:  true,
+		InputPrice:      2.10,
+		OutputPrice:     2.10,
+	},
+	"Baichuan4-Air": {
+		ID:              "Baichuan4-Air",
+		Name:            "Baichuan 4 Air",
+		Provider:        "baichuan",
+		ContextWindow:   32768,
+		MaxOutputTokens: 2048,
+		SupportsVision:  false,
+		SupportsTools:   true,
+		SupportsJSON:    true,
+		SupportsStream:  true,
+		InputPrice:      0.14,
+		OutputPrice:     0.14,
+	},
+	"Baichuan4": {
+		ID:              "Baichuan4",
+		Name:            "Baichuan 4",
+		Provider:        "baichuan",
+		ContextWindow:   32768,
+		MaxOutputTokens: 2048,
+		SupportsVision:  false,
+		SupportsTools:   true,
+		SupportsJSON:    true,
+		SupportsStream:  true,
+		InputPrice:      14.00,
+		OutputPrice:     14.00,
+	},
+
 	// xAI Grok models
 	"grok-2-latest": {
 		ID:              "grok-2-latest",
@@ -401,6 +509,11 @@ var modelRegistry = map[string]*ModelInfo{
 
 // GetModelInfo returns model information by ID.
 func GetModelInfo(modelID string) *ModelInfo {
+	// Discovered models take precedence
+	if info := discoveredModel(modelID); info != nil {
+		return info
+	}
+
 	// Direct lookup
 	if info, ok := modelRegistry[modelID]; ok {
 		return info
@@ -421,8 +534,18 @@ func GetModelInfo(modelID string) *ModelInfo {
 // ListModels lists all available models.
 func ListModels() []*ModelInfo {
 	models := make([]*ModelInfo, 0, len(modelRegistry))
-	for _, info := range modelRegistry {
-		models = append(models, info)
+	for id, info := range modelRegistry {
+		if discoveredModel(id) == nil {
+			models = append(models, info)
+		}
+	}
+
+	discoveredMu.RLock()
+	defer discoveredMu.RUnlock()
+	for id, info := range discovered {
+		if id == info.ID {
+			models = append(models, info)
+		}
 	}
 	return models
 }
@@ -430,7 +553,7 @@ func ListModels() []*ModelInfo {
 // ListModelsByProvider lists models for a specific provider.
 func ListModelsByProvider(provider string) []*ModelInfo {
 	models := make([]*ModelInfo, 0)
-	for _, info := range modelRegistry {
+	for _, info := range ListModels() {
 		if info.Provider == provider {
 			models = append(models, info)
 		}
@@ -17,24 +17,23 @@ type MoonshotProvider struct {
 // NewMoonshotProvider creates a new Moonshot provider.
 func NewMoonshotProvider(cfg *storage.Provider) Provider {
 	providerName := consts.ProviderMoonshot
-	apiBase := cfg.APIBase
-	if apiBase == "" {
-		apiBase = "https://api.moonshot.cn/v1"
-	}
-	defaultModel := cfg.DefaultModel
-	if defaultModel == "" {
-		defaultModel = "moonshot-v1-8k"
-	}
+	apiBase, defaultModel := presetDefaults(cfg, consts.ProviderMoonshot)
 
 	return &MoonshotProvider{
 		BaseProvider: NewBaseProvider(providerName.ToString(), cfg.APIKey, apiBase, defaultModel),
 	}
 }
 
+// buildRequest 映射请求参数，Moonshot 的 temperature 取值范围为 [0, 1]。
+func (p *MoonshotProvider) buildRequest(req ChatRequest) ChatRequest {
+	req.Temperature = clampTemperature(req.Temperature, 1)
+	return req
+}
+
 // Chat sends a chat request to Moonshot.
 func (p *MoonshotProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	req.Stream = false
-	resp, err := p.doRequest(ctx, "POST", "/chat/completions", req)
+	resp, err := p.doRequest(ctx, "POST", "/chat/completions", p.buildRequest(req))
 	if err != nil {
 		return nil, err
 	}
@@ -49,8 +48,10 @@ func (p *MoonshotProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResp
 		Model   string `json:"model"`
 		Choices []struct {
 			Message struct {
-				Role    string `json:"role"`
-				Content string `json:"content"`
+				Role      string     `json:"role"`
+				Content   string     `json:"content"`
+				Reasoning string     `json:"reasoning_content"`
+				ToolCalls []ToolCall `json:"tool_calls"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -64,19 +65,24 @@ func (p *MoonshotProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResp
 	if len(result.Choices) == 0 {
 		return nil, fmt.Errorf("no choices in response")
 	}
+	if err := contentFilter(p.name, result.Choices[0].FinishReason, ""); err != nil {
+		return nil, err
+	}
 
 	return &ChatResponse{
-		ID:      result.ID,
-		Model:   result.Model,
-		Content: result.Choices[0].Message.Content,
-		Usage:   result.Usage,
+		ID:        result.ID,
+		Model:     result.Model,
+		Content:   result.Choices[0].Message.Content,
+		Reasoning: result.Choices[0].Message.Reasoning,
+		ToolCalls: result.Choices[0].Message.ToolCalls,
+		Usage:     result.Usage,
 	}, nil
 }
 
 // ChatStream sends a streaming chat request to Moonshot.
 func (p *MoonshotProvider) ChatStream(ctx context.Context, req ChatRequest, callback StreamCallback) error {
 	req.Stream = true
-	resp, err := p.doRequest(ctx, "POST", "/chat/completions", req)
+	resp, err := p.doRequest(ctx, "POST", "/chat/completions", p.buildRequest(req))
 	if err != nil {
 		return err
 	}
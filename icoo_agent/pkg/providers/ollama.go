@@ -3,7 +3,6 @@ package providers
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -36,6 +35,16 @@ func NewOllamaProvider(cfg *storage.Provider) Provider {
 	}
 }
 
+// ollamaOptions 返回请求参数，按探测到的上下文长度设置 num_ctx，
+// 使服务端分配的上下文与智能体裁剪历史时使用的一致。
+func ollamaOptions(model string) map[string]any {
+	info := GetModelInfo(model)
+	if info == nil || !info.Discovered || info.ContextWindow <= 0 {
+		return nil
+	}
+	return map[string]any{"num_ctx": info.ContextWindow}
+}
+
 // Chat sends a chat request to Ollama.
 func (p *OllamaProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	// Convert messages to Ollama format
@@ -52,6 +61,9 @@ func (p *OllamaProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 		"messages": messages,
 		"stream":   false,
 	}
+	if options := ollamaOptions(req.Model); options != nil {
+		ollamaReq["options"] = options
+	}
 
 	if len(req.Tools) > 0 {
 		tools := make([]map[string]any, 0, len(req.Tools))
@@ -68,24 +80,9 @@ func (p *OllamaProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 		ollamaReq["tools"] = tools
 	}
 
-	var reqBody io.Reader
-	data, err := json.Marshal(ollamaReq)
+	resp, err := p.doRequestWithHeaders(ctx, "POST", "/api/chat", ollamaReq, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-	reqBody = bytes.NewReader(data)
-
-	url := p.apiBase + "/api/chat"
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := p.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -153,23 +150,13 @@ func (p *OllamaProvider) ChatStream(ctx context.Context, req ChatRequest, callba
 		"messages": messages,
 		"stream":   true,
 	}
-
-	data, err := json.Marshal(ollamaReq)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	url := p.apiBase + "/api/chat"
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	if options := ollamaOptions(req.Model); options != nil {
+		ollamaReq["options"] = options
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := p.httpClient.Do(httpReq)
+	resp, err := p.doRequestWithHeaders(ctx, "POST", "/api/chat", ollamaReq, nil)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
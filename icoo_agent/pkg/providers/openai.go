@@ -52,6 +52,7 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 				Role      string     `json:"role"`
 				Content   string     `json:"content"`
 				ToolCalls []ToolCall `json:"tool_calls"`
+				Refusal   string     `json:"refusal"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -65,6 +66,9 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 	if len(result.Choices) == 0 {
 		return nil, fmt.Errorf("no choices in response")
 	}
+	if err := contentFilter(p.name, result.Choices[0].FinishReason, result.Choices[0].Message.Refusal); err != nil {
+		return nil, err
+	}
 
 	return &ChatResponse{
 		ID:        result.ID,
@@ -65,6 +65,7 @@ func (p *OpenRouterProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRe
 				Role      string     `json:"role"`
 				Content   string     `json:"content"`
 				ToolCalls []ToolCall `json:"tool_calls"`
+				Refusal   string     `json:"refusal"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -78,6 +79,9 @@ func (p *OpenRouterProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRe
 	if len(result.Choices) == 0 {
 		return nil, fmt.Errorf("no choices in response")
 	}
+	if err := contentFilter(p.name, result.Choices[0].FinishReason, result.Choices[0].Message.Refusal); err != nil {
+		return nil, err
+	}
 
 	return &ChatResponse{
 		ID:        result.ID,
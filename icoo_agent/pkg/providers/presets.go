@@ -0,0 +1,148 @@
+package providers
+
+import (
+	"encoding/json"
+	"slices"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+)
+
+// ProviderPreset 提供商预设，包含接入所需的默认地址、模型和认证方式，
+// 创建提供商时只需填写 API Key 即可使用。
+type ProviderPreset struct {
+	Type         consts.ProviderType `json:"type"`
+	Name         string              `json:"name"`
+	APIBase      string              `json:"api_base"`
+	Regions      map[string]string   `json:"regions,omitempty"` // 区域 -> API 地址，通过 config 中的 region 选择
+	DefaultModel string              `json:"default_model"`
+	Models       []string            `json:"models"`
+	Auth         string              `json:"auth"`              // 认证方式说明
+	Options      []string            `json:"options,omitempty"` // config 中支持的扩展参数
+	Docs         string              `json:"docs"`
+}
+
+// 国内主流厂商预设
+var presets = []ProviderPreset{
+	{
+		Type:    consts.ProviderQwen,
+		Name:    "通义千问 (DashScope)",
+		APIBase: "https://dashscope.aliyuncs.com/compatible-mode/v1",
+		Regions: map[string]string{
+			"cn":   "https://dashscope.aliyuncs.com/compatible-mode/v1",
+			"intl": "https://dashscope-intl.aliyuncs.com/compatible-mode/v1",
+		},
+		DefaultModel: "qwen-plus",
+		Models:       []string{"qwen-max", "qwen-plus", "qwen-turbo", "qwen-long", "qwen3-coder-plus", "qwq-plus"},
+		Auth:         "Authorization: Bearer <DashScope API Key>",
+		Options:      []string{"region", "enable_search", "enable_thinking"},
+		Docs:         "https://help.aliyun.com/zh/model-studio/compatibility-of-openai-with-dashscope",
+	},
+	{
+		Type:         consts.ProviderQwenCodingPlan,
+		Name:         "通义千问编码计划",
+		APIBase:      "https://coding.dashscope.aliyuncs.com/v1",
+		DefaultModel: "qwen3-coder-plus",
+		Models:       []string{"qwen3-coder-plus"},
+		Auth:         "Authorization: Bearer <Coding Plan API Key>",
+		Docs:         "https://help.aliyun.com/zh/model-studio/coding-plan",
+	},
+	{
+		Type:    consts.ProviderZhipu,
+		Name:    "智谱 GLM",
+		APIBase: "https://open.bigmodel.cn/api/paas/v4",
+		Regions: map[string]string{
+			"cn":   "https://open.bigmodel.cn/api/paas/v4",
+			"intl": "https://api.z.ai/api/paas/v4",
+		},
+		DefaultModel: "glm-4-plus",
+		Models:       []string{"glm-4-plus", "glm-4-air", "glm-4-flash", "glm-4-long", "glm-4v-plus", "glm-4.5"},
+		Auth:         "Authorization: Bearer <JWT>，API Key 为 id.secret 格式时自动签发 JWT",
+		Options:      []string{"region"},
+		Docs:         "https://open.bigmodel.cn/dev/api",
+	},
+	{
+		Type:    consts.ProviderMoonshot,
+		Name:    "Moonshot (Kimi)",
+		APIBase: "https://api.moonshot.cn/v1",
+		Regions: map[string]string{
+			"cn":   "https://api.moonshot.cn/v1",
+			"intl": "https://api.moonshot.ai/v1",
+		},
+		DefaultModel: "moonshot-v1-auto",
+		Models:       []string{"moonshot-v1-auto", "moonshot-v1-8k", "moonshot-v1-32k", "moonshot-v1-128k", "kimi-k2-0905-preview", "kimi-latest"},
+		Auth:         "Authorization: Bearer <API Key>",
+		Options:      []string{"region"},
+		Docs:         "https://platform.moonshot.cn/docs",
+	},
+	{
+		Type:         consts.ProviderBaichuan,
+		Name:         "百川智能",
+		APIBase:      "https://api.baichuan-ai.com/v1",
+		DefaultModel: "Baichuan4-Turbo",
+		Models:       []string{"Baichuan4-Turbo", "Baichuan4-Air", "Baichuan4", "Baichuan3-Turbo", "Baichuan3-Turbo-128k"},
+		Auth:         "Authorization: Bearer <API Key>",
+		Options:      []string{"with_search_enhance"},
+		Docs:         "https://platform.baichuan-ai.com/docs/api",
+	},
+}
+
+// Presets 返回所有内置提供商预设。
+func Presets() []ProviderPreset {
+	return slices.Clone(presets)
+}
+
+// GetPreset 按提供商类型获取预设，不存在时返回 nil。
+func GetPreset(providerType consts.ProviderType) *ProviderPreset {
+	for i := range presets {
+		if presets[i].Type == providerType {
+			return &presets[i]
+		}
+	}
+	return nil
+}
+
+// presetOptions 提供商 config 字段中的扩展参数。
+type presetOptions struct {
+	Region            string `json:"region"`              // 区域，如 cn、intl
+	EnableSearch      bool   `json:"enable_search"`       // 通义千问联网搜索
+	EnableThinking    *bool  `json:"enable_thinking"`     // 通义千问流式请求是否开启思考
+	WithSearchEnhance bool   `json:"with_search_enhance"` // 百川搜索增强
+}
+
+// parsePresetOptions 解析提供商 config 字段，格式错误时忽略。
+func parsePresetOptions(cfg *storage.Provider) presetOptions {
+	var opts presetOptions
+	if cfg.Config != "" {
+		_ = json.Unmarshal([]byte(cfg.Config), &opts)
+	}
+	return opts
+}
+
+// presetDefaults 根据预设返回 API 地址与默认模型，用户配置优先。
+func presetDefaults(cfg *storage.Provider, providerType consts.ProviderType) (apiBase, defaultModel string) {
+	apiBase, defaultModel = cfg.APIBase, cfg.DefaultModel
+
+	preset := GetPreset(providerType)
+	if preset == nil {
+		return apiBase, defaultModel
+	}
+	if apiBase == "" {
+		apiBase = preset.APIBase
+		if base, ok := preset.Regions[parsePresetOptions(cfg).Region]; ok {
+			apiBase = base
+		}
+	}
+	if defaultModel == "" {
+		defaultModel = preset.DefaultModel
+	}
+	return apiBase, defaultModel
+}
+
+// clampTemperature 将温度限制在厂商支持的范围内，0 表示使用厂商默认值。
+func clampTemperature(t, upper float64) float64 {
+	if t <= 0 {
+		return 0
+	}
+	return min(t, upper)
+}
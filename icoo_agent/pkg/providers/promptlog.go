@@ -0,0 +1,226 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/utils"
+)
+
+// PromptLogMode 提示词日志模式。
+type PromptLogMode string
+
+const (
+	// PromptLogOff 不记录
+	PromptLogOff PromptLogMode = "off"
+	// PromptLogMetadata 只记录消息数量、字符数、哈希和用量，不含内容
+	PromptLogMetadata PromptLogMode = "metadata"
+	// PromptLogFull 记录脱敏后的完整提示词和回复
+	PromptLogFull PromptLogMode = "full"
+)
+
+// ParsePromptLogMode 解析提示词日志模式，空字符串视为 off。
+func ParsePromptLogMode(mode string) (PromptLogMode, error) {
+	switch m := PromptLogMode(strings.ToLower(mode)); m {
+	case "", PromptLogOff:
+		return PromptLogOff, nil
+	case PromptLogMetadata, PromptLogFull:
+		return m, nil
+	default:
+		return "", fmt.Errorf("未知的提示词日志模式: %s", mode)
+	}
+}
+
+// PromptRecord 一次模型调用的提示词日志记录。
+type PromptRecord struct {
+	Time          time.Time      `json:"time"`
+	Provider      string         `json:"provider"`
+	Model         string         `json:"model"`
+	Stream        bool           `json:"stream"`
+	DurationMs    int64          `json:"duration_ms"`
+	Error         string         `json:"error,omitempty"`
+	Messages      int            `json:"messages"`       // 消息条数
+	Roles         map[string]int `json:"roles"`          // 各角色消息条数
+	PromptChars   int            `json:"prompt_chars"`   // 提示词总字符数
+	PromptHash    string         `json:"prompt_hash"`    // 提示词哈希，用于比对两次调用是否相同
+	Tools         int            `json:"tools"`          // 提供给模型的工具数
+	ResponseChars int            `json:"response_chars"` // 回复字符数
+	ResponseHash  string         `json:"response_hash,omitempty"`
+	ToolCalls     []string       `json:"tool_calls,omitempty"` // 模型调用的工具名称
+	Usage         *Usage         `json:"usage,omitempty"`
+	Prompt        []ChatMessage  `json:"prompt,omitempty"`   // 仅 full 模式，已脱敏
+	Response      string         `json:"response,omitempty"` // 仅 full 模式，已脱敏
+}
+
+// PromptLog 将模型调用以 JSON Lines 写入专用日志，按模式决定是否包含内容。
+type PromptLog struct {
+	mode     PromptLogMode
+	w        io.Writer
+	redactor *utils.Redactor
+	mu       sync.Mutex
+}
+
+// NewPromptLog 创建提示词日志，redact 为额外的脱敏正则。
+func NewPromptLog(mode PromptLogMode, w io.Writer, redact []string) (*PromptLog, error) {
+	redactor, err := utils.NewRedactor(redact)
+	if err != nil {
+		return nil, err
+	}
+	return &PromptLog{mode: mode, w: w, redactor: redactor}, nil
+}
+
+// Enabled 是否需要记录。
+func (l *PromptLog) Enabled() bool {
+	return l != nil && l.mode != PromptLogOff && l.w != nil
+}
+
+// newRecord 根据请求生成日志记录的提示词部分。
+func (l *PromptLog) newRecord(provider string, req ChatRequest, stream bool) *PromptRecord {
+	rec := &PromptRecord{
+		Time:     time.Now(),
+		Provider: provider,
+		Model:    req.Model,
+		Stream:   stream,
+		Messages: len(req.Messages),
+		Roles:    make(map[string]int),
+		Tools:    len(req.Tools),
+	}
+
+	h := sha256.New()
+	for _, m := range req.Messages {
+		rec.Roles[m.Role]++
+		rec.PromptChars += len([]rune(m.Content))
+		h.Write([]byte(m.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(m.Content))
+		h.Write([]byte{0})
+	}
+	rec.PromptHash = hex.EncodeToString(h.Sum(nil))[:16]
+
+	if l.mode == PromptLogFull {
+		rec.Prompt = make([]ChatMessage, 0, len(req.Messages))
+		for _, m := range req.Messages {
+			m.Content = l.redactor.Redact(m.Content)
+			if len(m.ToolCalls) > 0 {
+				calls := make([]ToolCall, len(m.ToolCalls))
+				copy(calls, m.ToolCalls)
+				for i := range calls {
+					calls[i].Function.Arguments = l.redactor.Redact(calls[i].Function.Arguments)
+				}
+				m.ToolCalls = calls
+			}
+			rec.Prompt = append(rec.Prompt, m)
+		}
+	}
+	return rec
+}
+
+// finish 补充回复部分并写入日志，写入失败只忽略，不影响模型调用。
+func (l *PromptLog) finish(rec *PromptRecord, content string, toolCalls []ToolCall, usage *Usage, err error) {
+	rec.DurationMs = time.Since(rec.Time).Milliseconds()
+	if err != nil {
+		rec.Error = l.redactor.Redact(err.Error())
+	}
+	rec.ResponseChars = len([]rune(content))
+	if content != "" {
+		sum := sha256.Sum256([]byte(content))
+		rec.ResponseHash = hex.EncodeToString(sum[:])[:16]
+	}
+	for _, tc := range toolCalls {
+		if tc.Function.Name != "" {
+			rec.ToolCalls = append(rec.ToolCalls, tc.Function.Name)
+		}
+	}
+	rec.Usage = usage
+	if l.mode == PromptLogFull {
+		rec.Response = l.redactor.Redact(content)
+	}
+
+	data, jerr := json.Marshal(rec)
+	if jerr != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(data, '\n'))
+}
+
+// skipPromptLogKey 跳过提示词日志的上下文键
+type skipPromptLogKey struct{}
+
+// WithoutPromptLog 标记本次调用不写入提示词日志，用于无痕会话。
+func WithoutPromptLog(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipPromptLogKey{}, true)
+}
+
+// promptLogSkipped 判断上下文是否要求跳过提示词日志。
+func promptLogSkipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipPromptLogKey{}).(bool)
+	return skip
+}
+
+// loggedProvider 在提供商外层记录提示词日志。
+type loggedProvider struct {
+	Provider
+	name string
+	log  *PromptLog
+}
+
+// Chat 发送聊天请求。
+func (p *loggedProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if promptLogSkipped(ctx) {
+		return p.Provider.Chat(ctx, req)
+	}
+	rec := p.log.newRecord(p.name, req, false)
+	resp, err := p.Provider.Chat(ctx, req)
+	if resp == nil {
+		p.log.finish(rec, "", nil, nil, err)
+		return resp, err
+	}
+	usage := resp.Usage
+	p.log.finish(rec, resp.Content, resp.ToolCalls, &usage, err)
+	return resp, err
+}
+
+// ChatStream 发送流式聊天请求，累积各分片后记录完整回复。
+func (p *loggedProvider) ChatStream(ctx context.Context, req ChatRequest, callback StreamCallback) error {
+	if promptLogSkipped(ctx) {
+		return p.Provider.ChatStream(ctx, req, callback)
+	}
+	rec := p.log.newRecord(p.name, req, true)
+
+	var (
+		content   strings.Builder
+		toolCalls []ToolCall
+	)
+	err := p.Provider.ChatStream(ctx, req, func(chunk, reasoning string, calls []ToolCall, done bool) error {
+		content.WriteString(chunk)
+		toolCalls = append(toolCalls, calls...)
+		return callback(chunk, reasoning, calls, done)
+	})
+	p.log.finish(rec, content.String(), toolCalls, nil, err)
+	return err
+}
+
+// WithPromptLog 启用提示词日志，之后通过 Get 获取的提供商都会记录调用。
+func (f *Factory) WithPromptLog(log *PromptLog) *Factory {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.promptLog = log
+	return f
+}
+
+// logged 为提供商接入提示词日志，未启用时原样返回。调用方需持有写锁。
+func (f *Factory) logged(name string, p Provider) Provider {
+	if !f.promptLog.Enabled() {
+		return p
+	}
+	return &loggedProvider{Provider: p, name: name, log: f.promptLog}
+}
@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPromptLogModes(t *testing.T) {
+	req := ChatRequest{
+		Model: "m",
+		Messages: []ChatMessage{
+			{Role: "system", Content: "you are helpful"},
+			{Role: "user", Content: "my key is sk-abcdefghijklmnopqrstuvwxyz, password: hunter2"},
+		},
+	}
+
+	for _, mode := range []PromptLogMode{PromptLogMetadata, PromptLogFull} {
+		var buf bytes.Buffer
+		log, err := NewPromptLog(mode, &buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := &loggedProvider{Provider: newStub("stub", nil), name: "stub", log: log}
+		if _, err := p.Chat(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+
+		var rec PromptRecord
+		if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+			t.Fatalf("%s: invalid record %q: %v", mode, buf.String(), err)
+		}
+		if rec.Messages != 2 || rec.Roles["user"] != 1 || rec.PromptHash == "" || rec.ResponseChars != 4 {
+			t.Errorf("%s: unexpected metadata: %+v", mode, rec)
+		}
+		if strings.Contains(buf.String(), "sk-abcdefghijklmnopqrstuvwxyz") || strings.Contains(buf.String(), "hunter2") {
+			t.Errorf("%s: secret leaked into prompt log: %s", mode, buf.String())
+		}
+
+		switch mode {
+		case PromptLogMetadata:
+			if rec.Prompt != nil || rec.Response != "" {
+				t.Errorf("metadata mode should not log content: %s", buf.String())
+			}
+		case PromptLogFull:
+			if len(rec.Prompt) != 2 || rec.Response != "stub" ||
+				rec.Prompt[1].Content != "my key is [REDACTED], password: [REDACTED]" {
+				t.Errorf("full mode should log redacted content: %s", buf.String())
+			}
+		}
+	}
+}
+
+func TestParsePromptLogMode(t *testing.T) {
+	if m, err := ParsePromptLogMode(""); err != nil || m != PromptLogOff {
+		t.Errorf("empty mode should be off, got %q, %v", m, err)
+	}
+	if m, err := ParsePromptLogMode("FULL"); err != nil || m != PromptLogFull {
+		t.Errorf("mode should be case-insensitive, got %q, %v", m, err)
+	}
+	if _, err := ParsePromptLogMode("verbose"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
@@ -2,14 +2,13 @@
 package providers
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"strings"
 	"time"
 
 	"icooclaw/pkg/errors"
@@ -22,6 +21,9 @@ type BaseProvider struct {
 	apiBase    string
 	model      string
 	httpClient *http.Client
+	quirks     StreamQuirks // 流式响应兼容处理
+	retry      RetryConfig  // 请求重试配置
+	limiter    *rateLimiter // 请求限流
 }
 
 // NewBaseProvider creates a new BaseProvider.
@@ -34,6 +36,8 @@ func NewBaseProvider(name, apiKey, apiBase, model string) *BaseProvider {
 		httpClient: &http.Client{
 			Timeout: 300 * time.Second, // 5 minutes for long LLM responses
 		},
+		retry:   DefaultRetryConfig(),
+		limiter: newRateLimiter(0, 0),
 	}
 }
 
@@ -52,64 +56,111 @@ func (p *BaseProvider) SetModel(model string) {
 	p.model = model
 }
 
-// doRequest performs an HTTP request.
+// doRequest performs an HTTP request with bearer authentication.
 func (p *BaseProvider) doRequest(ctx context.Context, method, path string, body any) (*http.Response, error) {
-	var reqBody io.Reader
-	if body != nil {
-		data, err := json.Marshal(body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request: %w", err)
-		}
-		reqBody = bytes.NewReader(data)
-	}
-
-	url := p.apiBase + path
-	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
+	var headers map[string]string
 	if p.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+		headers = map[string]string{"Authorization": "Bearer " + p.apiKey}
 	}
-
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-
-	return resp, nil
+	return p.doRequestWithHeaders(ctx, method, path, body, headers)
 }
 
 // doRequestWithHeaders performs an HTTP request with custom headers.
+//
+// 请求经过提供商的限流器，遇到 429、5xx 或网络错误时按指数退避重试，优先使用 Retry-After 指定的等待时间，
+// 重试用尽后返回最后一次响应，由调用方按状态码处理。流式响应只在开始前重试。
 func (p *BaseProvider) doRequestWithHeaders(ctx context.Context, method, path string, body any, headers map[string]string) (*http.Response, error) {
-	var reqBody io.Reader
+	return p.sendRequest(ctx, method, p.apiBase+path, body, headers)
+}
+
+// sendRequest 向完整地址发送请求，重试和限流同 doRequestWithHeaders。
+func (p *BaseProvider) sendRequest(ctx context.Context, method, url string, body any, headers map[string]string) (*http.Response, error) {
+	var data []byte
 	if body != nil {
-		data, err := json.Marshal(body)
+		var err error
+		data, err = json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request: %w", err)
 		}
-		reqBody = bytes.NewReader(data)
 	}
 
-	url := p.apiBase + path
-	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	retryable := !retryDisabled(ctx)
+	for attempt := 0; ; attempt++ {
+		if retryable {
+			if err := p.limiter.wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		var reqBody io.Reader
+		if data != nil {
+			reqBody = bytes.NewReader(data)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+
+		resp, err := p.httpClient.Do(req)
+		delay, retry := p.retryDelay(ctx, attempt, resp, err)
+		if !retry || !retryable {
+			if err != nil {
+				return nil, fmt.Errorf("request failed: %w", err)
+			}
+			return resp, nil
+		}
+
+		reason := "network error"
+		if resp != nil {
+			reason = resp.Status
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		slog.Default().With("name", "【提供商】").Warn("请求失败，稍后重试",
+			"provider", p.name,
+			"reason", reason,
+			"error", err,
+			"attempt", attempt+1,
+			"delay", delay)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
 	}
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	for key, value := range headers {
-		req.Header.Set(key, value)
+// retryDelay 判断第 attempt 次请求的结果是否需要重试，返回重试前的等待时间。
+// 收到 Retry-After 时暂停该提供商的所有请求。
+func (p *BaseProvider) retryDelay(ctx context.Context, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if ctx.Err() != nil || attempt >= p.retry.MaxRetries {
+		return 0, false
+	}
+	if err == nil && !retryableStatus(resp.StatusCode) {
+		return 0, false
 	}
 
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	// 使用密钥池时限流响应不在同一个密钥上重试，暂停该密钥后由密钥池换下一个密钥
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests && keyRotation(ctx) {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			p.limiter.pause(after)
+		}
+		return 0, false
 	}
 
-	return resp, nil
+	delay := p.retry.backoff(attempt)
+	if resp != nil {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if p.retry.MaxRetryAfter > 0 && after > p.retry.MaxRetryAfter {
+				return 0, false
+			}
+			p.limiter.pause(after)
+			delay = after
+		}
+	}
+	return delay, true
 }
 
 // handleError handles HTTP error responses.
@@ -121,102 +172,9 @@ func (p *BaseProvider) handleError(resp *http.Response) error {
 		return errors.NewFailoverError(errors.FailoverAuth, p.name, "", resp.StatusCode, fmt.Errorf("auth failed: %s", string(body)))
 	case 429:
 		return errors.NewFailoverError(errors.FailoverRateLimit, p.name, "", resp.StatusCode, fmt.Errorf("rate limited: %s", string(body)))
-	case 500, 502, 503, 504:
+	case 500, 502, 503, 504, 529:
 		return errors.NewFailoverError(errors.FailoverTimeout, p.name, "", resp.StatusCode, fmt.Errorf("server error: %s", string(body)))
 	default:
 		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 }
-
-// parseStreamChunk parses a streaming chunk.
-func parseStreamChunk(data string) (content string, reasoning string, toolCalls []ToolCall, done bool, err error) {
-	if data == "[DONE]" {
-		return "", "", nil, true, nil
-	}
-
-	var chunk struct {
-		Choices []struct {
-			Delta struct {
-				Content   string `json:"content"`
-				Reasoning string `json:"reasoning_content"`
-				// ToolCalls in streaming format uses index instead of id
-				ToolCalls []struct {
-					Index    int    `json:"index"`
-					ID       string `json:"id"`
-					Type     string `json:"type"`
-					Function struct {
-						Name      string `json:"name"`
-						Arguments string `json:"arguments"`
-					} `json:"function"`
-				} `json:"tool_calls"`
-			} `json:"delta"`
-			FinishReason string `json:"finish_reason"`
-		} `json:"choices"`
-	}
-
-	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-		return "", "", nil, false, err
-	}
-
-	if len(chunk.Choices) > 0 {
-		done = chunk.Choices[0].FinishReason != ""
-
-		// Convert streaming tool calls to ToolCall format
-		// We use ID field to store index temporarily for merging
-		var calls []ToolCall
-		for _, tc := range chunk.Choices[0].Delta.ToolCalls {
-			// Create a unique key for merging: use index as part of ID
-			// Format: "stream_index:N" where N is the index
-			streamID := fmt.Sprintf("stream_index:%d", tc.Index)
-			if tc.ID != "" {
-				// If we have a real ID, use it but remember the index
-				streamID = tc.ID
-			}
-
-			calls = append(calls, ToolCall{
-				ID:   streamID,
-				Type: tc.Type,
-				Function: struct {
-					Name      string `json:"name"`
-					Arguments string `json:"arguments"`
-				}{
-					Name:      tc.Function.Name,
-					Arguments: tc.Function.Arguments,
-				},
-			})
-		}
-
-		return chunk.Choices[0].Delta.Content, chunk.Choices[0].Delta.Reasoning, calls, done, nil
-	}
-
-	return "", "", nil, false, nil
-}
-
-// streamResponse handles streaming response parsing.
-func (p *BaseProvider) streamResponse(resp *http.Response, callback StreamCallback) error {
-	defer resp.Body.Close()
-
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
-			continue
-		}
-
-		data := strings.TrimPrefix(line, "data: ")
-		content, reasoning, toolCalls, done, err := parseStreamChunk(data)
-		if err != nil {
-			continue
-		}
-
-		if err := callback(content, reasoning, toolCalls, done); err != nil {
-			return err
-		}
-
-		if done {
-			return nil
-		}
-	}
-
-	return scanner.Err()
-}
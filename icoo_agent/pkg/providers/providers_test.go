@@ -2,7 +2,13 @@
 package providers
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"icooclaw/pkg/consts"
 	"icooclaw/pkg/storage"
@@ -261,3 +267,99 @@ func TestModelInfo_ContextWindow(t *testing.T) {
 		})
 	}
 }
+
+func TestPresetDefaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       *storage.Provider
+		ptype     consts.ProviderType
+		wantBase  string
+		wantModel string
+	}{
+		{"qwen default", &storage.Provider{}, consts.ProviderQwen, "https://dashscope.aliyuncs.com/compatible-mode/v1", "qwen-plus"},
+		{"qwen intl", &storage.Provider{Config: `{"region":"intl"}`}, consts.ProviderQwen, "https://dashscope-intl.aliyuncs.com/compatible-mode/v1", "qwen-plus"},
+		{"moonshot override", &storage.Provider{APIBase: "http://proxy/v1", DefaultModel: "kimi-latest"}, consts.ProviderMoonshot, "http://proxy/v1", "kimi-latest"},
+		{"baichuan", &storage.Provider{}, consts.ProviderBaichuan, "https://api.baichuan-ai.com/v1", "Baichuan4-Turbo"},
+		{"unknown", &storage.Provider{}, consts.ProviderOpenAI, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, model := presetDefaults(tt.cfg, tt.ptype)
+			if base != tt.wantBase || model != tt.wantModel {
+				t.Errorf("presetDefaults() = %s, %s; want %s, %s", base, model, tt.wantBase, tt.wantModel)
+			}
+		})
+	}
+}
+
+func TestFactory_ChinesePresets(t *testing.T) {
+	f := NewFactory(nil)
+	for _, ptype := range []consts.ProviderType{consts.ProviderQwen, consts.ProviderZhipu, consts.ProviderMoonshot, consts.ProviderBaichuan} {
+		p, err := f.createFromConfig(&storage.Provider{Type: ptype, APIKey: "key"})
+		if err != nil {
+			t.Fatalf("createFromConfig(%s) failed: %v", ptype, err)
+		}
+		if p.GetModel() != GetPreset(ptype).DefaultModel {
+			t.Errorf("%s default model = %s, want %s", ptype, p.GetModel(), GetPreset(ptype).DefaultModel)
+		}
+	}
+}
+
+func TestZhipuProvider_AuthHeaders(t *testing.T) {
+	p := NewZhipuProvider(&storage.Provider{APIKey: "myid.mysecret"}).(*ZhipuProvider)
+	now := time.UnixMilli(1700000000000)
+	p.now = func() time.Time { return now }
+
+	headers, err := p.authHeaders()
+	if err != nil {
+		t.Fatalf("authHeaders() failed: %v", err)
+	}
+	token := strings.TrimPrefix(headers["Authorization"], "Bearer ")
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected JWT, got %s", token)
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]any
+	json.Unmarshal(payload, &claims)
+	if claims["api_key"] != "myid" || claims["timestamp"] != float64(now.UnixMilli()) {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+
+	mac := hmac.New(sha256.New, []byte("mysecret"))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if parts[2] != base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) {
+		t.Error("invalid JWT signature")
+	}
+
+	again, _ := p.authHeaders()
+	if again["Authorization"] != headers["Authorization"] {
+		t.Error("token should be cached until it nears expiry")
+	}
+
+	plain := NewZhipuProvider(&storage.Provider{APIKey: "plainkey"}).(*ZhipuProvider)
+	if h, _ := plain.authHeaders(); h["Authorization"] != "Bearer plainkey" {
+		t.Errorf("plain key should be sent as-is, got %s", h["Authorization"])
+	}
+}
+
+func TestPresetRequestMapping(t *testing.T) {
+	qwen := NewQwenProvider(&storage.Provider{Config: `{"enable_search":true}`}).(*QwenProvider)
+	data, _ := json.Marshal(qwen.buildRequest(ChatRequest{Model: "qwen3-32b"}))
+	if !strings.Contains(string(data), `"enable_thinking":false`) || !strings.Contains(string(data), `"enable_search":true`) {
+		t.Errorf("qwen non-stream request should disable thinking and keep search: %s", data)
+	}
+
+	zhipu := NewZhipuProvider(&storage.Provider{}).(*ZhipuProvider)
+	if got := zhipu.buildRequest(ChatRequest{Temperature: 1.5}).Temperature; got != 0.99 {
+		t.Errorf("zhipu temperature = %v, want 0.99", got)
+	}
+
+	baichuan := NewBaichuanProvider(&storage.Provider{Config: `{"with_search_enhance":true}`}).(*BaichuanProvider)
+	req := baichuan.buildRequest(ChatRequest{Temperature: 1.2})
+	if req.Temperature != 1 || !req.WithSearchEnhance {
+		t.Errorf("unexpected baichuan request: %+v", req)
+	}
+}
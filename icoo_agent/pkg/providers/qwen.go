@@ -12,37 +12,50 @@ import (
 // QwenProvider implements Provider for Alibaba Qwen (通义千问).
 type QwenProvider struct {
 	*BaseProvider
+	opts presetOptions
+}
+
+// qwenRequest DashScope 兼容模式请求，在 OpenAI 参数之外附加厂商扩展参数。
+type qwenRequest struct {
+	ChatRequest
+	EnableSearch   bool  `json:"enable_search,omitempty"`   // 联网搜索
+	EnableThinking *bool `json:"enable_thinking,omitempty"` // 思考模式，非流式请求必须关闭
 }
 
 // NewQwenProvider creates a new Qwen provider.
 func NewQwenProvider(cfg *storage.Provider) Provider {
 	providerName := consts.ProviderQwen
-	apiBase := cfg.APIBase
-	// 处理默认值
-	if apiBase == "" {
-		apiBase = "https://dashscope.aliyuncs.com/compatible-mode/v1"
-	}
+	apiBase, defaultModel := presetDefaults(cfg, consts.ProviderQwen)
 
 	// 处理编码计划
 	if cfg.Type == consts.ProviderQwenCodingPlan {
-		apiBase = "https://coding.dashscope.aliyuncs.com/v1"
+		apiBase = GetPreset(consts.ProviderQwenCodingPlan).APIBase
 		providerName = consts.ProviderQwenCodingPlan
 	}
 
-	defaultModel := cfg.DefaultModel
-	if defaultModel == "" {
-		defaultModel = "qwen-plus"
-	}
-
 	return &QwenProvider{
 		BaseProvider: NewBaseProvider(providerName.ToString(), cfg.APIKey, apiBase, defaultModel),
+		opts:         parsePresetOptions(cfg),
 	}
 }
 
+// buildRequest 附加 DashScope 扩展参数。
+// Qwen3 等混合思考模型在非流式调用时必须显式关闭 enable_thinking，否则接口报错。
+func (p *QwenProvider) buildRequest(req ChatRequest) qwenRequest {
+	r := qwenRequest{ChatRequest: req, EnableSearch: p.opts.EnableSearch}
+	if req.Stream {
+		r.EnableThinking = p.opts.EnableThinking
+	} else {
+		disabled := false
+		r.EnableThinking = &disabled
+	}
+	return r
+}
+
 // Chat sends a chat request to Qwen.
 func (p *QwenProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	req.Stream = false
-	resp, err := p.doRequest(ctx, "POST", "/chat/completions", req)
+	resp, err := p.doRequest(ctx, "POST", "/chat/completions", p.buildRequest(req))
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +87,9 @@ func (p *QwenProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 	if len(result.Choices) == 0 {
 		return nil, fmt.Errorf("no choices in response")
 	}
+	if err := contentFilter(p.name, result.Choices[0].FinishReason, ""); err != nil {
+		return nil, err
+	}
 
 	return &ChatResponse{
 		ID:        result.ID,
@@ -88,7 +104,7 @@ func (p *QwenProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse
 // ChatStream sends a streaming chat request to Qwen.
 func (p *QwenProvider) ChatStream(ctx context.Context, req ChatRequest, callback StreamCallback) error {
 	req.Stream = true
-	resp, err := p.doRequest(ctx, "POST", "/chat/completions", req)
+	resp, err := p.doRequest(ctx, "POST", "/chat/completions", p.buildRequest(req))
 	if err != nil {
 		return err
 	}
@@ -52,6 +52,8 @@ func (r *Registry) RegisterBuiltins() {
 	r.RegisterFactory(consts.ProviderMoonshot, NewMoonshotProvider)
 	r.RegisterFactory(consts.ProviderZhipu, NewZhipuProvider)
 	r.RegisterFactory(consts.ProviderQwen, NewQwenProvider)
+	r.RegisterFactory(consts.ProviderQwenCodingPlan, NewQwenProvider)
+	r.RegisterFactory(consts.ProviderBaichuan, NewBaichuanProvider)
 	r.RegisterFactory(consts.ProviderSiliconFlow, NewSiliconFlowProvider)
 	r.RegisterFactory(consts.ProviderGrok, NewGrokProvider)
 }
@@ -75,7 +77,7 @@ func (r *Registry) CreateProvider(cfg *storage.Provider) (Provider, error) {
 		return nil, fmt.Errorf("unknown provider type: %s", cfg.Type)
 	}
 
-	return factory(cfg), nil
+	return applyStreamQuirks(factory(cfg), cfg), nil
 }
 
 // Register registers a provider instance.
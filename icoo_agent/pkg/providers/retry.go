@@ -0,0 +1,215 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/storage"
+)
+
+// RetryConfig 请求遇到限流、服务端错误或网络错误时的重试配置。
+type RetryConfig struct {
+	// MaxRetries 最多重试次数，0 表示不重试
+	MaxRetries int
+	// InitialBackoff 第一次重试前的等待时间，之后每次翻倍
+	InitialBackoff time.Duration
+	// MaxBackoff 指数退避的最长等待时间
+	MaxBackoff time.Duration
+	// MaxRetryAfter Retry-After 要求的等待超过该值时不再重试，直接返回错误以便切换提供商
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryConfig 返回默认的重试配置。
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:     3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		MaxRetryAfter:  time.Minute,
+	}
+}
+
+// backoff 第 attempt 次（从 0 开始）重试前的指数退避时间，带 ±20% 抖动。
+func (c RetryConfig) backoff(attempt int) time.Duration {
+	d := c.InitialBackoff
+	for range attempt {
+		d *= 2
+		if d >= c.MaxBackoff {
+			d = c.MaxBackoff
+			break
+		}
+	}
+	if d <= 0 {
+		return 0
+	}
+	jitter := time.Duration(rand.Int64N(int64(d)/5*2+1)) - d/5
+	return d + jitter
+}
+
+// retryableStatus 可以重试的状态码，529 为 Anthropic 的过载响应。
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, 529:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter 解析 Retry-After 响应头，支持秒数和 HTTP 日期。
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// rateLimiter 提供商级别的令牌桶限流。收到 Retry-After 后暂停该提供商的所有请求，
+// 避免并发会话在限流期间继续请求。
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // 生成一个令牌的间隔，0 表示不限速
+	burst    float64
+	tokens   float64
+	last     time.Time
+	until    time.Time // 暂停到该时间
+}
+
+// newRateLimiter 创建每分钟最多 rpm 个请求的限流器，rpm 为 0 时只在限流响应后暂停。
+func newRateLimiter(rpm, burst int) *rateLimiter {
+	l := &rateLimiter{}
+	if rpm > 0 {
+		l.interval = time.Minute / time.Duration(rpm)
+		l.burst = float64(max(burst, 1))
+		l.tokens = l.burst
+		l.last = time.Now()
+	}
+	return l
+}
+
+// wait 等待可以发送请求，上下文取消时返回错误。
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	delay := max(l.until.Sub(now), 0)
+	if l.interval > 0 {
+		l.tokens = min(l.burst, l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+		l.last = now
+		// 令牌不足时预留下一个令牌，tokens 可以为负，按顺序排队
+		l.tokens--
+		if l.tokens < 0 {
+			delay = max(delay, time.Duration(-l.tokens*float64(l.interval)))
+		}
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		if l.interval > 0 {
+			l.mu.Lock()
+			l.tokens++
+			l.mu.Unlock()
+		}
+		return err
+	}
+	return nil
+}
+
+// pause 暂停到 d 之后。
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.until) {
+		l.until = until
+	}
+}
+
+// sleep 等待 d，上下文取消时提前返回。
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// noRetryKey 上下文键，设置后请求不重试也不经过限流器
+type noRetryKey struct{}
+
+// withoutRetry 返回不重试的上下文，用于健康探测等需要快速得到结果的请求。
+func withoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// retryDisabled 上下文是否要求不重试。
+func retryDisabled(ctx context.Context) bool {
+	v, _ := ctx.Value(noRetryKey{}).(bool)
+	return v
+}
+
+// SetRetry 设置请求重试配置。
+func (p *BaseProvider) SetRetry(c RetryConfig) {
+	p.retry = c
+}
+
+// SetRateLimit 设置每分钟最多请求数和突发请求数，rpm 为 0 表示不限速。
+func (p *BaseProvider) SetRateLimit(rpm, burst int) {
+	p.limiter = newRateLimiter(rpm, burst)
+}
+
+// retryOptions 提供商 config 字段中的重试和限流配置。
+type retryOptions struct {
+	MaxRetries        *int `json:"max_retries"`         // 覆盖全局重试次数
+	RequestsPerMinute int  `json:"requests_per_minute"` // 每分钟最多请求数
+	Burst             int  `json:"burst"`               // 突发请求数，默认 1
+}
+
+// applyRetry 应用全局重试配置和提供商 config 字段中的重试、限流配置，配置有误时忽略。
+func applyRetry(p Provider, cfg *storage.Provider, retry RetryConfig) Provider {
+	s, ok := p.(interface {
+		SetRetry(RetryConfig)
+		SetRateLimit(rpm, burst int)
+	})
+	if !ok {
+		return p
+	}
+	var opts retryOptions
+	if cfg.Config != "" {
+		_ = json.Unmarshal([]byte(cfg.Config), &opts)
+	}
+	if opts.MaxRetries != nil && *opts.MaxRetries >= 0 {
+		retry.MaxRetries = *opts.MaxRetries
+	}
+	s.SetRetry(retry)
+	if opts.RequestsPerMinute > 0 {
+		s.SetRateLimit(opts.RequestsPerMinute, opts.Burst)
+	}
+	return p
+}
+
+// WithRetry 设置之后通过 Get 创建的提供商的重试配置。
+func (f *Factory) WithRetry(c RetryConfig) *Factory {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.retry = c
+	return f
+}
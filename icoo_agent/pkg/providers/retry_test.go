@@ -0,0 +1,152 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/storage"
+)
+
+// fastRetry 测试用的短退避重试配置。
+var fastRetry = RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, MaxRetryAfter: time.Second}
+
+func TestSendRequest_Retry(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		wantStatus int
+		wantCalls  int32
+	}{
+		{"success", []int{200}, "", 200, 1},
+		{"rate limited then ok", []int{429, 200}, "0", 200, 2},
+		{"server errors then ok", []int{502, 503, 200}, "", 200, 3},
+		{"overloaded", []int{529, 200}, "", 200, 2},
+		{"retries exhausted", []int{500, 500, 500, 200}, "", 500, 3},
+		{"client error", []int{400, 200}, "", 400, 1},
+		{"retry after too long", []int{429, 200}, "120", 429, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				if got := r.Header.Get("Authorization"); got != "Bearer key" {
+					t.Errorf("attempt %d Authorization = %q", n, got)
+				}
+				// 每次重试都要重新发送完整的请求体
+				if body, _ := io.ReadAll(r.Body); !strings.Contains(string(body), `"model":"m"`) {
+					t.Errorf("attempt %d body = %q", n, body)
+				}
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			p := NewBaseProvider("test", "key", srv.URL, "m")
+			p.SetRetry(fastRetry)
+			resp, err := p.doRequest(context.Background(), http.MethodPost, "/chat", map[string]any{"model": "m"})
+			if err != nil {
+				t.Fatalf("doRequest() error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || calls.Load() != tt.wantCalls {
+				t.Errorf("status = %d after %d calls, want %d after %d", resp.StatusCode, calls.Load(), tt.wantStatus, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSendRequest_NoRetryForProbe(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	p := NewBaseProvider("test", "", srv.URL, "m")
+	p.SetRetry(fastRetry)
+	if err := p.probe(context.Background(), nil); err == nil {
+		t.Error("probe() should fail on 503")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("probe() made %d requests, want 1", calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRetryConfig_Backoff(t *testing.T) {
+	c := RetryConfig{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		got := c.backoff(attempt)
+		if got < want*4/5 || got > want*6/5 {
+			t.Errorf("backoff(%d) = %v, want %v ±20%%", attempt, got, want)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	// 每分钟 600 次即每 100ms 一个令牌，突发 2 次
+	l := newRateLimiter(600, 2)
+	ctx := context.Background()
+	start := time.Now()
+	for range 4 {
+		if err := l.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond || elapsed > time.Second {
+		t.Errorf("4 requests with burst 2 took %v, want about 200ms", elapsed)
+	}
+
+	// 暂停期间请求等待，上下文取消时返回错误
+	l.pause(time.Hour)
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(cancelled); err == nil {
+		t.Error("wait() should return the context error while paused")
+	}
+}
+
+func TestApplyRetry(t *testing.T) {
+	cfg := &storage.Provider{Config: `{"max_retries": 0, "requests_per_minute": 30, "burst": 3}`}
+	p := applyRetry(NewOpenAIProvider(cfg), cfg, DefaultRetryConfig())
+
+	base := p.(*OpenAIProvider).BaseProvider
+	if base.retry.MaxRetries != 0 || base.retry.MaxBackoff != DefaultRetryConfig().MaxBackoff {
+		t.Errorf("retry = %+v", base.retry)
+	}
+	if base.limiter.interval != 2*time.Second || base.limiter.burst != 3 {
+		t.Errorf("limiter interval = %v, burst = %v", base.limiter.interval, base.limiter.burst)
+	}
+}
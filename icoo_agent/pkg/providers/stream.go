@@ -0,0 +1,318 @@
+package providers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"icooclaw/pkg/storage"
+)
+
+// maxStreamLineSize SSE 单行的最大长度，部分后端会在一个事件中发送完整的工具调用参数
+const maxStreamLineSize = 4 * 1024 * 1024
+
+// StreamQuirks OpenAI 兼容后端在流式响应上的差异。
+//
+// 以下差异总是按宽松方式处理，无需配置：data: 后可以没有空格；没有 [DONE] 时以连接关闭作为结束；
+// 推理内容可以位于 reasoning_content 或 reasoning；只带用量的空 choices 块被忽略；错误事件作为错误返回。
+type StreamQuirks struct {
+	// WaitForDone 收到 finish_reason 后继续读取，直到 [DONE] 或连接关闭，
+	// 用于在 finish_reason 之后仍发送内容或工具调用参数的后端
+	WaitForDone bool `json:"wait_for_done"`
+	// ToolCallsWithoutIndex 工具调用增量不带 index（或总为 0），按 id 区分调用，
+	// 不带 id 的增量归属最近出现的调用
+	ToolCallsWithoutIndex bool `json:"tool_calls_without_index"`
+	// CumulativeArguments 工具调用参数每次发送到目前为止的完整内容而不是增量
+	CumulativeArguments bool `json:"cumulative_arguments"`
+}
+
+// 内置的流式兼容档案，名称对应常见的 OpenAI 兼容后端
+var streamProfiles = map[string]StreamQuirks{
+	"openai":   {},
+	"vllm":     {WaitForDone: true},
+	"llamacpp": {WaitForDone: true, CumulativeArguments: true},
+	"lmstudio": {ToolCallsWithoutIndex: true},
+	"tgi":      {ToolCallsWithoutIndex: true},
+}
+
+// StreamProfiles 返回内置流式兼容档案的名称。
+func StreamProfiles() []string {
+	names := make([]string, 0, len(streamProfiles))
+	for name := range streamProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// streamOptions 提供商 config 字段中的流式兼容配置。
+type streamOptions struct {
+	Profile string          `json:"stream_profile"` // 内置档案名称
+	Quirks  json.RawMessage `json:"stream_quirks"`  // 在档案之上覆盖的单项配置
+}
+
+// ParseStreamQuirks 解析提供商 config 字段中的 stream_profile 和 stream_quirks，
+// 先取档案，再用 stream_quirks 中出现的字段覆盖。
+func ParseStreamQuirks(cfg *storage.Provider) (StreamQuirks, error) {
+	var quirks StreamQuirks
+	if cfg.Config == "" {
+		return quirks, nil
+	}
+
+	var opts streamOptions
+	if err := json.Unmarshal([]byte(cfg.Config), &opts); err != nil {
+		return quirks, fmt.Errorf("解析提供商配置失败: %w", err)
+	}
+	if opts.Profile != "" {
+		profile, ok := streamProfiles[strings.ToLower(opts.Profile)]
+		if !ok {
+			return quirks, fmt.Errorf("未知的流式兼容档案 %q，可选: %s", opts.Profile, strings.Join(StreamProfiles(), ", "))
+		}
+		quirks = profile
+	}
+	if len(opts.Quirks) > 0 {
+		if err := json.Unmarshal(opts.Quirks, &quirks); err != nil {
+			return quirks, fmt.Errorf("解析 stream_quirks 失败: %w", err)
+		}
+	}
+	return quirks, nil
+}
+
+// SetStreamQuirks 设置流式响应的兼容处理。
+func (p *BaseProvider) SetStreamQuirks(q StreamQuirks) {
+	p.quirks = q
+}
+
+// applyStreamQuirks 为使用 OpenAI 兼容流式解析的提供商应用配置的兼容档案，配置有误时忽略。
+func applyStreamQuirks(p Provider, cfg *storage.Provider) Provider {
+	s, ok := p.(interface{ SetStreamQuirks(StreamQuirks) })
+	if !ok {
+		return p
+	}
+	if quirks, err := ParseStreamQuirks(cfg); err == nil {
+		s.SetStreamQuirks(quirks)
+	}
+	return p
+}
+
+// streamParser 解析 OpenAI 兼容的流式响应，跨块保存工具调用的归属和参数。
+type streamParser struct {
+	quirks StreamQuirks
+
+	ids  map[string]int // 工具调用 id -> 序号，仅 ToolCallsWithoutIndex
+	last int            // 最近出现的工具调用序号，仅 ToolCallsWithoutIndex
+	args map[int]string // 工具调用序号 -> 已收到的参数，仅 CumulativeArguments
+
+	finishReason string          // 最近收到的 finish_reason
+	refusal      strings.Builder // 拒答内容增量
+}
+
+func newStreamParser(q StreamQuirks) *streamParser {
+	return &streamParser{quirks: q, ids: make(map[string]int), last: -1, args: make(map[int]string)}
+}
+
+// streamChunk OpenAI 兼容的流式响应块。
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			Reasoning        string `json:"reasoning"`
+			Refusal          string `json:"refusal"`
+			// ToolCalls in streaming format uses index instead of id
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Type     string `json:"type"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Error json.RawMessage `json:"error"`
+}
+
+// parse 解析一个 data 负载，finished 表示模型已给出 finish_reason。
+func (s *streamParser) parse(data string) (content, reasoning string, toolCalls []ToolCall, finished bool, err error) {
+	var chunk streamChunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return "", "", nil, false, err
+	}
+	if len(chunk.Error) > 0 && string(chunk.Error) != "null" {
+		return "", "", nil, false, &streamError{payload: chunk.Error}
+	}
+	if len(chunk.Choices) == 0 {
+		// 只带用量等信息的块
+		return "", "", nil, false, nil
+	}
+
+	choice := chunk.Choices[0]
+	s.refusal.WriteString(choice.Delta.Refusal)
+	if choice.FinishReason != "" {
+		s.finishReason = choice.FinishReason
+	}
+	reasoning = choice.Delta.ReasoningContent
+	if reasoning == "" {
+		reasoning = choice.Delta.Reasoning
+	}
+
+	// Convert streaming tool calls to ToolCall format
+	// We use ID field to store index temporarily for merging
+	for _, tc := range choice.Delta.ToolCalls {
+		index := s.index(tc.ID, tc.Index)
+
+		// Create a unique key for merging: use index as part of ID
+		// Format: "stream_index:N" where N is the index
+		streamID := fmt.Sprintf("stream_index:%d", index)
+		if tc.ID != "" {
+			// If we have a real ID, use it but remember the index
+			streamID = tc.ID
+		}
+
+		arguments := tc.Function.Arguments
+		if s.quirks.CumulativeArguments {
+			arguments = s.delta(index, arguments)
+		}
+
+		toolCalls = append(toolCalls, ToolCall{
+			ID:   streamID,
+			Type: tc.Type,
+			Function: struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			}{
+				Name:      tc.Function.Name,
+				Arguments: arguments,
+			},
+		})
+	}
+
+	return choice.Delta.Content, reasoning, toolCalls, choice.FinishReason != "", nil
+}
+
+// index 返回工具调用增量所属调用的序号。
+func (s *streamParser) index(id string, index int) int {
+	if !s.quirks.ToolCallsWithoutIndex {
+		return index
+	}
+	if id == "" {
+		return max(s.last, 0)
+	}
+	if i, ok := s.ids[id]; ok {
+		s.last = i
+		return i
+	}
+	s.last = len(s.ids)
+	s.ids[id] = s.last
+	return s.last
+}
+
+// delta 将完整的参数内容转换为相对上一次的增量。
+func (s *streamParser) delta(index int, arguments string) string {
+	prev := s.args[index]
+	if arguments == "" {
+		return ""
+	}
+	s.args[index] = arguments
+	if strings.HasPrefix(arguments, prev) {
+		return arguments[len(prev):]
+	}
+	// 不是前一次内容的延续，说明该块本身就是增量
+	s.args[index] = prev + arguments
+	return arguments
+}
+
+// filtered 返回回复被内容安全策略拦截（content_filter 或拒答）时的错误，未被拦截时返回 nil。
+func (s *streamParser) filtered(provider string) error {
+	return contentFilter(provider, s.finishReason, s.refusal.String())
+}
+
+// streamError 流中返回的错误事件。
+type streamError struct {
+	payload json.RawMessage
+}
+
+func (e *streamError) Error() string {
+	var detail struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(e.payload, &detail) == nil && detail.Message != "" {
+		return "stream error: " + detail.Message
+	}
+	var text string
+	if json.Unmarshal(e.payload, &text) == nil && text != "" {
+		return "stream error: " + text
+	}
+	return "stream error: " + string(e.payload)
+}
+
+// streamResponse handles streaming response parsing.
+func (p *BaseProvider) streamResponse(resp *http.Response, callback StreamCallback) error {
+	defer resp.Body.Close()
+
+	parser := newStreamParser(p.quirks)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			// 注释、event:、id: 等行
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			if err := parser.filtered(p.name); err != nil {
+				return err
+			}
+			return callback("", "", nil, true)
+		}
+
+		content, reasoning, toolCalls, done, err := parser.parse(data)
+		if err != nil {
+			var streamErr *streamError
+			if errors.As(err, &streamErr) {
+				return err
+			}
+			continue
+		}
+
+		// 被拦截时先传递本块内容，再以错误结束，不发送完成信号
+		if done {
+			if filterErr := parser.filtered(p.name); filterErr != nil {
+				if err := callback(content, reasoning, toolCalls, false); err != nil {
+					return err
+				}
+				return filterErr
+			}
+		}
+
+		// 等待 [DONE] 时，finish_reason 只作为普通块传递
+		last := done && !p.quirks.WaitForDone
+		if err := callback(content, reasoning, toolCalls, last); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := parser.filtered(p.name); err != nil {
+		return err
+	}
+	// 没有 [DONE] 的后端以连接关闭作为结束
+	return callback("", "", nil, true)
+}
@@ -0,0 +1,207 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/storage"
+)
+
+// streamResult 流式回调收集到的结果，工具调用按智能体的合并方式合并。
+type streamResult struct {
+	content   string
+	reasoning string
+	calls     []string // name(arguments)
+	dones     int
+}
+
+// collectStream 用给定的兼容处理解析 SSE 内容。
+func collectStream(t *testing.T, body string, quirks StreamQuirks) (*streamResult, error) {
+	t.Helper()
+	p := NewBaseProvider("test", "", "", "")
+	p.SetStreamQuirks(quirks)
+	return collectCallbacks(t, body, p.streamResponse)
+}
+
+// collectCallbacks 用 parse 解析 SSE 内容并收集回调结果。
+func collectCallbacks(t *testing.T, body string, parse func(*http.Response, StreamCallback) error) (*streamResult, error) {
+	t.Helper()
+	res := &streamResult{}
+	var order []string
+	byKey := map[string]*ToolCall{}
+	ids := map[string]string{} // 真实 id -> 合并键
+	err := parse(&http.Response{Body: io.NopCloser(strings.NewReader(body))},
+		func(chunk, reasoning string, toolCalls []ToolCall, done bool) error {
+			if res.dones > 0 {
+				t.Fatal("callback after done")
+			}
+			res.content += chunk
+			res.reasoning += reasoning
+			for _, tc := range toolCalls {
+				key := tc.ID
+				if !strings.HasPrefix(key, "stream_index:") {
+					if k, ok := ids[tc.ID]; ok {
+						key = k
+					} else {
+						key = fmt.Sprintf("stream_index:%d", len(ids))
+						ids[tc.ID] = key
+					}
+				}
+				existing, ok := byKey[key]
+				if !ok {
+					copy := tc
+					byKey[key] = &copy
+					order = append(order, key)
+					continue
+				}
+				if tc.Function.Name != "" {
+					existing.Function.Name = tc.Function.Name
+				}
+				existing.Function.Arguments += tc.Function.Arguments
+			}
+			if done {
+				res.dones++
+			}
+			return nil
+		})
+	for _, key := range order {
+		tc := byKey[key]
+		res.calls = append(res.calls, tc.Function.Name+"("+tc.Function.Arguments+")")
+	}
+	return res, err
+}
+
+func TestStreamProfiles(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		content   string
+		reasoning string
+		calls     []string
+	}{
+		{"openai", "Let me check.", "", []string{`get_weather({"city":"Paris"})`, `get_time({})`}},
+		// finish_reason 之后仍有工具调用参数
+		{"vllm", "", "The user wants the weather.", []string{`get_weather({"city": "Paris"})`}},
+		// 参数每次发送完整内容，结束时没有 [DONE]
+		{"llamacpp", "", "Need the weather.", []string{`get_weather({"city":"Paris"})`}},
+		// 并行调用的 index 总为 0
+		{"lmstudio", "Checking both.", "", []string{`get_weather({"city":"Paris"})`, `get_time({"zone":"UTC"})`}},
+		// data: 后没有空格，工具调用不带 index
+		{"tgi", "Sure.", "", []string{`get_weather({"city":"Paris"})`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", "stream", tt.fixture+".sse"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := collectStream(t, string(body), streamProfiles[tt.fixture])
+			if err != nil {
+				t.Fatalf("streamResponse() error = %v", err)
+			}
+			if res.content != tt.content || res.reasoning != tt.reasoning {
+				t.Errorf("content = %q, reasoning = %q", res.content, res.reasoning)
+			}
+			if strings.Join(res.calls, ";") != strings.Join(tt.calls, ";") {
+				t.Errorf("tool calls = %v, want %v", res.calls, tt.calls)
+			}
+			if res.dones != 1 {
+				t.Errorf("done callbacks = %d, want 1", res.dones)
+			}
+		})
+	}
+}
+
+func TestStreamTolerance(t *testing.T) {
+	// 没有 [DONE] 也没有 finish_reason 时以连接关闭结束
+	res, err := collectStream(t, "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n: keep-alive\n\ndata: not json\n", StreamQuirks{})
+	if err != nil || res.content != "hi" || res.dones != 1 {
+		t.Errorf("truncated stream = %+v, %v", res, err)
+	}
+
+	// 超过默认 64KB 的单行
+	long := strings.Repeat("x", 100*1024)
+	res, err = collectStream(t, `data: {"choices":[{"delta":{"content":"`+long+`"},"finish_reason":"stop"}]}`+"\n", StreamQuirks{})
+	if err != nil || len(res.content) != len(long) {
+		t.Errorf("long line: len = %d, err = %v", len(res.content), err)
+	}
+
+	// 错误事件
+	_, err = collectStream(t, "data:{\"error\":\"Input validation error: max_tokens too large\",\"error_type\":\"validation\"}\n", StreamQuirks{})
+	if err == nil || !strings.Contains(err.Error(), "max_tokens too large") {
+		t.Errorf("error event: err = %v", err)
+	}
+	_, err = collectStream(t, `data: {"error":{"message":"model overloaded","code":503}}`+"\n", StreamQuirks{})
+	if err == nil || !strings.Contains(err.Error(), "model overloaded") {
+		t.Errorf("error object: err = %v", err)
+	}
+}
+
+func TestStreamContentFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		quirks  StreamQuirks
+		content string
+		refusal string
+	}{
+		{
+			name:    "content_filter",
+			body:    "data: {\"choices\":[{\"delta\":{\"content\":\"部分\"}}]}\n\ndata: {\"choices\":[{\"delta\":{},\"finish_reason\":\"content_filter\"}]}\n\ndata: [DONE]\n",
+			content: "部分",
+		},
+		{
+			name:    "refusal",
+			body:    "data: {\"choices\":[{\"delta\":{\"refusal\":\"I can't \"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"refusal\":\"help.\"},\"finish_reason\":\"stop\"}]}\n",
+			refusal: "I can't help.",
+		},
+		{
+			name:   "wait for done",
+			body:   "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"content_filter\"}]}\n\ndata: [DONE]\n",
+			quirks: StreamQuirks{WaitForDone: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := collectStream(t, tt.body, tt.quirks)
+			var filtered *ContentFilterError
+			if !errors.As(err, &filtered) {
+				t.Fatalf("err = %v, want ContentFilterError", err)
+			}
+			if filtered.Refusal != tt.refusal || res.content != tt.content || res.dones != 0 {
+				t.Errorf("filtered = %+v, result = %+v", filtered, res)
+			}
+		})
+	}
+}
+
+func TestParseStreamQuirks(t *testing.T) {
+	tests := []struct {
+		config  string
+		want    StreamQuirks
+		wantErr bool
+	}{
+		{"", StreamQuirks{}, false},
+		{`{"region":"cn"}`, StreamQuirks{}, false},
+		{`{"stream_profile":"vLLM"}`, StreamQuirks{WaitForDone: true}, false},
+		{`{"stream_profile":"llamacpp","stream_quirks":{"wait_for_done":false}}`, StreamQuirks{CumulativeArguments: true}, false},
+		{`{"stream_quirks":{"tool_calls_without_index":true}}`, StreamQuirks{ToolCallsWithoutIndex: true}, false},
+		{`{"stream_profile":"unknown"}`, StreamQuirks{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStreamQuirks(&storage.Provider{Config: tt.config})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStreamQuirks(%s) error = %v", tt.config, err)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseStreamQuirks(%s) = %+v, want %+v", tt.config, got, tt.want)
+		}
+	}
+}
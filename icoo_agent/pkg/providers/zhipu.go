@@ -2,39 +2,111 @@ package providers
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"icooclaw/pkg/consts"
 	"icooclaw/pkg/storage"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
+// zhipuTokenTTL 智谱 JWT 有效期，提前一分钟刷新。
+const zhipuTokenTTL = 30 * time.Minute
+
 // ZhipuProvider implements Provider for Zhipu AI (智谱).
 type ZhipuProvider struct {
 	*BaseProvider
+
+	mu       sync.Mutex
+	token    string
+	tokenExp time.Time
+	now      func() time.Time
 }
 
 // NewZhipuProvider creates a new Zhipu provider.
 func NewZhipuProvider(cfg *storage.Provider) Provider {
 	providerName := consts.ProviderZhipu
-	apiBase := cfg.APIBase
-	if apiBase == "" {
-		apiBase = "https://open.bigmodel.cn/api/paas/v4"
-	}
-	defaultModel := cfg.DefaultModel
-	if defaultModel == "" {
-		defaultModel = "glm-4"
-	}
+	apiBase, defaultModel := presetDefaults(cfg, consts.ProviderZhipu)
 
 	return &ZhipuProvider{
 		BaseProvider: NewBaseProvider(providerName.ToString(), cfg.APIKey, apiBase, defaultModel),
+		now:          time.Now,
+	}
+}
+
+// authHeaders 返回认证头。API Key 为 id.secret 格式时按智谱规范签发 HS256 JWT，
+// 否则直接作为 Bearer Token 使用。
+func (p *ZhipuProvider) authHeaders() (map[string]string, error) {
+	id, secret, ok := strings.Cut(p.apiKey, ".")
+	if !ok {
+		return map[string]string{"Authorization": "Bearer " + p.apiKey}, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if p.token == "" || now.Add(time.Minute).After(p.tokenExp) {
+		exp := now.Add(zhipuTokenTTL)
+		token, err := signZhipuToken(id, secret, now, exp)
+		if err != nil {
+			return nil, err
+		}
+		p.token, p.tokenExp = token, exp
+	}
+	return map[string]string{"Authorization": "Bearer " + p.token}, nil
+}
+
+// signZhipuToken 签发智谱 JWT，时间字段使用毫秒。
+func signZhipuToken(id, secret string, now, exp time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "sign_type": "SIGN"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(map[string]any{
+		"api_key":   id,
+		"exp":       exp.UnixMilli(),
+		"timestamp": now.UnixMilli(),
+	})
+	if err != nil {
+		return "", err
 	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+// buildRequest 映射请求参数，智谱的 temperature 取值范围为开区间 (0, 1)。
+func (p *ZhipuProvider) buildRequest(req ChatRequest) ChatRequest {
+	req.Temperature = clampTemperature(req.Temperature, 0.99)
+	return req
+}
+
+// Probe 使用智谱认证头探测可用性。
+func (p *ZhipuProvider) Probe(ctx context.Context) error {
+	headers, err := p.authHeaders()
+	if err != nil {
+		return err
+	}
+	return p.probe(ctx, headers)
 }
 
 // Chat sends a chat request to Zhipu.
 func (p *ZhipuProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	req.Stream = false
-	resp, err := p.doRequest(ctx, "POST", "/chat/completions", req)
+	headers, err := p.authHeaders()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.doRequestWithHeaders(ctx, "POST", "/chat/completions", p.buildRequest(req), headers)
 	if err != nil {
 		return nil, err
 	}
@@ -51,6 +123,7 @@ func (p *ZhipuProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespons
 			Message struct {
 				Role      string     `json:"role"`
 				Content   string     `json:"content"`
+				Reasoning string     `json:"reasoning_content"`
 				ToolCalls []ToolCall `json:"tool_calls"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
@@ -65,11 +138,15 @@ func (p *ZhipuProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespons
 	if len(result.Choices) == 0 {
 		return nil, fmt.Errorf("no choices in response")
 	}
+	if err := contentFilter(p.name, result.Choices[0].FinishReason, ""); err != nil {
+		return nil, err
+	}
 
 	return &ChatResponse{
 		ID:        result.ID,
 		Model:     result.Model,
 		Content:   result.Choices[0].Message.Content,
+		Reasoning: result.Choices[0].Message.Reasoning,
 		ToolCalls: result.Choices[0].Message.ToolCalls,
 		Usage:     result.Usage,
 	}, nil
@@ -78,7 +155,11 @@ func (p *ZhipuProvider) Chat(ctx context.Context, req ChatRequest) (*ChatRespons
 // ChatStream sends a streaming chat request to Zhipu.
 func (p *ZhipuProvider) ChatStream(ctx context.Context, req ChatRequest, callback StreamCallback) error {
 	req.Stream = true
-	resp, err := p.doRequest(ctx, "POST", "/chat/completions", req)
+	headers, err := p.authHeaders()
+	if err != nil {
+		return err
+	}
+	resp, err := p.doRequestWithHeaders(ctx, "POST", "/chat/completions", p.buildRequest(req), headers)
 	if err != nil {
 		return err
 	}
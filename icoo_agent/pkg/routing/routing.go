@@ -0,0 +1,245 @@
+// Package routing provides declarative inbound message routing for icooclaw.
+//
+// 入站消息在到达默认智能体之前按配置顺序匹配规则，第一条匹配的规则生效，没有匹配时交给默认智能体：
+//
+//	[[routing.rules]]
+//	name = "after-hours"
+//	channels = ["feishu"]
+//	hours = "18:00-09:00"
+//	reply = "{{.UserName}} 您好，现在是非工作时间，我们会在 9 点后回复。"
+//
+// 条件之间为"且"关系，未设置的条件不限制：channels（渠道）、users（发送者 ID）、
+// keywords（包含任一关键词，不区分大小写）、pattern（正则）、hours（时段）、weekdays（星期）。
+// 动作三选一：persona（交给指定人设处理本条消息）、command（执行斜杠命令，如 /reset）、
+// reply（按模板自动回复，不调用模型）。
+package routing
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"icooclaw/pkg/clock"
+)
+
+// 路由动作
+const (
+	ActionPersona = "persona" // 交给指定人设处理
+	ActionCommand = "command" // 执行斜杠命令
+	ActionReply   = "reply"   // 自动回复
+)
+
+// Rule 路由规则。
+type Rule struct {
+	Name     string   `mapstructure:"name" json:"name"`         // 规则名称，用于日志和测试输出
+	Channels []string `mapstructure:"channels" json:"channels"` // 适用渠道，为空表示全部
+	Users    []string `mapstructure:"users" json:"users"`       // 发送者 ID，为空表示全部
+	Keywords []string `mapstructure:"keywords" json:"keywords"` // 消息包含任一关键词，不区分大小写
+	Pattern  string   `mapstructure:"pattern" json:"pattern"`   // 消息匹配的正则表达式
+	Hours    string   `mapstructure:"hours" json:"hours"`       // 时段 HH:MM-HH:MM，结束早于开始表示跨午夜
+	Weekdays []int    `mapstructure:"weekdays" json:"weekdays"` // 星期，0 为周日
+	Persona  string   `mapstructure:"persona" json:"persona"`   // 处理消息的人设
+	Command  string   `mapstructure:"command" json:"command"`   // 执行的斜杠命令模板
+	Reply    string   `mapstructure:"reply" json:"reply"`       // 自动回复模板
+}
+
+// Message 待路由的入站消息。
+type Message struct {
+	Channel   string
+	SessionID string
+	UserID    string
+	UserName  string
+	Text      string
+	Time      time.Time // 按用户时区的当前时间，用于匹配时段和星期
+}
+
+// Decision 匹配规则后的路由结果。
+type Decision struct {
+	Rule    string `json:"rule"`              // 规则名称
+	Action  string `json:"action"`            // 路由动作
+	Persona string `json:"persona,omitempty"` // persona 动作的人设
+	Text    string `json:"text,omitempty"`    // command 或 reply 渲染后的文本
+}
+
+// Step 单条规则的评估结果，用于测试路由规则。
+type Step struct {
+	Rule    string // 规则名称
+	Matched bool   // 是否匹配
+	Reason  string // 不匹配的原因
+}
+
+// templateData 模板可用字段，Time 覆盖 Message.Time 为 HH:MM 格式。
+type templateData struct {
+	Message
+	Date string
+	Time string
+}
+
+// compiledRule 编译后的规则。
+type compiledRule struct {
+	Rule
+	action   string
+	keywords []string
+	re       *regexp.Regexp
+	tmpl     *template.Template
+	hours    *clock.Hours // 未设置时段时为 nil
+}
+
+// Router 按顺序匹配的路由规则集合。
+type Router struct {
+	rules []compiledRule
+}
+
+// New 编译规则并创建路由器，规则无效时返回错误。
+func New(rules []Rule) (*Router, error) {
+	r := &Router{}
+
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("#%d", i+1)
+		}
+		cr := compiledRule{Rule: rule}
+
+		var actions []string
+		if rule.Persona != "" {
+			actions = append(actions, ActionPersona)
+		}
+		if rule.Command != "" {
+			actions = append(actions, ActionCommand)
+			if !strings.HasPrefix(strings.TrimSpace(rule.Command), "/") {
+				return nil, fmt.Errorf("规则 %s 的 command 必须以 / 开头", rule.Name)
+			}
+		}
+		if rule.Reply != "" {
+			actions = append(actions, ActionReply)
+		}
+		if len(actions) != 1 {
+			return nil, fmt.Errorf("规则 %s 必须且只能设置 persona、command、reply 之一", rule.Name)
+		}
+		cr.action = actions[0]
+
+		if cr.action != ActionPersona {
+			tmpl, err := template.New(rule.Name).Parse(rule.Command + rule.Reply)
+			if err != nil {
+				return nil, fmt.Errorf("规则 %s 的模板无效: %w", rule.Name, err)
+			}
+			cr.tmpl = tmpl
+		}
+
+		for _, k := range rule.Keywords {
+			if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+				cr.keywords = append(cr.keywords, k)
+			}
+		}
+
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("规则 %s 的正则表达式无效: %w", rule.Name, err)
+			}
+			cr.re = re
+		}
+
+		if rule.Hours != "" {
+			hours, err := clock.ParseHours(rule.Hours)
+			if err != nil {
+				return nil, fmt.Errorf("规则 %s 的 hours 无效: %w", rule.Name, err)
+			}
+			cr.hours = &hours
+		}
+
+		for _, d := range rule.Weekdays {
+			if d < 0 || d > 6 {
+				return nil, fmt.Errorf("规则 %s 的 weekdays 取值为 0-6，0 为周日", rule.Name)
+			}
+		}
+
+		r.rules = append(r.rules, cr)
+	}
+
+	return r, nil
+}
+
+// Len 返回规则数量。
+func (r *Router) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.rules)
+}
+
+// Route 返回第一条匹配规则的路由结果，没有匹配时返回 nil。
+func (r *Router) Route(msg Message) (*Decision, error) {
+	_, d, err := r.Explain(msg)
+	return d, err
+}
+
+// Explain 按顺序评估规则直到第一条匹配，返回每条规则的评估结果和路由结果，用于测试规则。
+func (r *Router) Explain(msg Message) ([]Step, *Decision, error) {
+	if r.Len() == 0 {
+		return nil, nil, nil
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+
+	var steps []Step
+	for _, cr := range r.rules {
+		if reason := cr.mismatch(msg); reason != "" {
+			steps = append(steps, Step{Rule: cr.Name, Reason: reason})
+			continue
+		}
+		steps = append(steps, Step{Rule: cr.Name, Matched: true})
+
+		d := &Decision{Rule: cr.Name, Action: cr.action, Persona: cr.Persona}
+		if cr.tmpl != nil {
+			text, err := cr.render(msg)
+			if err != nil {
+				return steps, nil, fmt.Errorf("规则 %s 渲染模板失败: %w", cr.Name, err)
+			}
+			d.Text = text
+		}
+		return steps, d, nil
+	}
+	return steps, nil, nil
+}
+
+// mismatch 返回规则不匹配消息的原因，匹配时返回空字符串。
+func (r *compiledRule) mismatch(msg Message) string {
+	if len(r.Channels) > 0 && !slices.Contains(r.Channels, msg.Channel) {
+		return "渠道不匹配"
+	}
+	if len(r.Users) > 0 && !slices.Contains(r.Users, msg.UserID) {
+		return "用户不匹配"
+	}
+	if len(r.keywords) > 0 {
+		text := strings.ToLower(msg.Text)
+		if !slices.ContainsFunc(r.keywords, func(k string) bool { return strings.Contains(text, k) }) {
+			return "不包含关键词"
+		}
+	}
+	if r.re != nil && !r.re.MatchString(msg.Text) {
+		return "正则不匹配"
+	}
+	if len(r.Weekdays) > 0 && !slices.Contains(r.Weekdays, int(msg.Time.Weekday())) {
+		return "星期不匹配"
+	}
+	if r.hours != nil && !r.hours.Contains(msg.Time) {
+		return "不在时段内"
+	}
+	return ""
+}
+
+// render 渲染 command 或 reply 模板。
+func (r *compiledRule) render(msg Message) (string, error) {
+	var sb strings.Builder
+	err := r.tmpl.Execute(&sb, templateData{
+		Message: msg,
+		Date:    msg.Time.Format("2006-01-02"),
+		Time:    msg.Time.Format("15:04"),
+	})
+	return strings.TrimSpace(sb.String()), err
+}
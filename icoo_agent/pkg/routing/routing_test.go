@@ -0,0 +1,127 @@
+package routing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRouter_Route(t *testing.T) {
+	// 2026-10-17 是周六
+	saturdayNight := time.Date(2026, 10, 17, 22, 30, 0, 0, time.UTC)
+	mondayMorning := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	rules := []Rule{
+		{Name: "vip", Users: []string{"u-vip"}, Persona: "vip"},
+		{Name: "after-hours", Channels: []string{"feishu"}, Hours: "18:00-09:00", Reply: "{{.UserName}} 您好，现在是 {{.Time}}"},
+		{Name: "weekend", Weekdays: []int{0, 6}, Reply: "周末休息"},
+		{Name: "refund", Keywords: []string{"退款", "Refund"}, Persona: "support"},
+		{Name: "order", Pattern: `订单\s*\d{6}`, Command: "/lookup {{.Text}}"},
+	}
+	router, err := New(rules)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		msg  Message
+		want *Decision
+	}{
+		{
+			name: "user rule first",
+			msg:  Message{Channel: "feishu", UserID: "u-vip", Text: "退款", Time: saturdayNight},
+			want: &Decision{Rule: "vip", Action: ActionPersona, Persona: "vip"},
+		},
+		{
+			name: "time of day across midnight",
+			msg:  Message{Channel: "feishu", UserName: "张三", Text: "在吗", Time: saturdayNight},
+			want: &Decision{Rule: "after-hours", Action: ActionReply, Text: "张三 您好，现在是 22:30"},
+		},
+		{
+			name: "weekday",
+			msg:  Message{Channel: "websocket", Text: "在吗", Time: saturdayNight},
+			want: &Decision{Rule: "weekend", Action: ActionReply, Text: "周末休息"},
+		},
+		{
+			name: "keyword case insensitive",
+			msg:  Message{Channel: "feishu", Text: "I want a REFUND", Time: mondayMorning},
+			want: &Decision{Rule: "refund", Action: ActionPersona, Persona: "support"},
+		},
+		{
+			name: "pattern renders command",
+			msg:  Message{Channel: "feishu", Text: "订单 123456 到哪了", Time: mondayMorning},
+			want: &Decision{Rule: "order", Action: ActionCommand, Text: "/lookup 订单 123456 到哪了"},
+		},
+		{
+			name: "no match",
+			msg:  Message{Channel: "feishu", Text: "你好", Time: mondayMorning},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := router.Route(tt.msg)
+			if err != nil {
+				t.Fatalf("Route() error = %v", err)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("Route() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouter_Explain(t *testing.T) {
+	router, err := New([]Rule{
+		{Name: "feishu-only", Channels: []string{"feishu"}, Reply: "a"},
+		{Keywords: []string{"help"}, Reply: "b"},
+		{Name: "never-reached", Reply: "c"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	steps, d, err := router.Explain(Message{Channel: "websocket", Text: "help me"})
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	want := []Step{
+		{Rule: "feishu-only", Reason: "渠道不匹配"},
+		{Rule: "#2", Matched: true},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("steps = %+v, want %+v", steps, want)
+	}
+	for i := range want {
+		if steps[i] != want[i] {
+			t.Errorf("steps[%d] = %+v, want %+v", i, steps[i], want[i])
+		}
+	}
+	if d == nil || d.Rule != "#2" || d.Text != "b" {
+		t.Errorf("decision = %+v, want rule #2 with text b", d)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"no action", Rule{Name: "x"}},
+		{"two actions", Rule{Persona: "a", Reply: "b"}},
+		{"command without slash", Rule{Command: "reset"}},
+		{"bad template", Rule{Reply: "{{.Text"}},
+		{"bad pattern", Rule{Pattern: "(", Reply: "x"}},
+		{"bad hours", Rule{Hours: "9-18", Reply: "x"}},
+		{"same hours", Rule{Hours: "09:00-09:00", Reply: "x"}},
+		{"bad weekday", Rule{Weekdays: []int{7}, Reply: "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New([]Rule{tt.rule}); err == nil {
+				t.Error("New() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/clock"
+	"icooclaw/pkg/consts"
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/storage"
+
+	"github.com/robfig/cron/v3"
+)
+
+// defaultHeartbeatTimeout 未设置超时时，心跳发出后多久未收到完成事件即视为结束
+const defaultHeartbeatTimeout = 10 * time.Minute
+
+// Heartbeat 心跳：定期让智能体按清单检查是否有需要主动告知用户的事项。
+type Heartbeat struct {
+	Persona      string        // 处理心跳的人设（子智能体），为空时使用默认智能体
+	Interval     time.Duration // 心跳间隔
+	Cron         string        // cron 表达式（分 时 日 月 周，可加 CRON_TZ= 前缀），设置后代替 Interval
+	Channel      string        // 心跳发送的渠道
+	SessionID    string        // 心跳发送的会话ID
+	Prompt       string        // 心跳内容，为空时每次从 File 读取
+	File         string        // 心跳清单文件，不存在或为空时跳过本次心跳
+	QuietHours   *clock.Hours  // 免打扰时段，按用户时区判断，为 nil 时不限制
+	Window       *clock.Hours  // 允许运行的时段，如工作时间，按用户时区判断，为 nil 时不限制
+	ActiveWindow time.Duration // 用户在此时间内活跃过则跳过本次心跳，为 0 时不限制
+	Jitter       time.Duration // 每次触发前随机延迟 [0, Jitter)，错开同时触发的心跳
+}
+
+// name 返回心跳的名称，用于日志。
+func (hb *Heartbeat) name() string {
+	if hb.Persona != "" {
+		return hb.Persona
+	}
+	return consts.DEFAULT_AGENT_NAME
+}
+
+// id 返回心跳的标识，同一人设在不同会话的心跳各自计数。
+func (hb *Heartbeat) id() string {
+	return hb.name() + "@" + hb.Channel + "/" + hb.SessionID
+}
+
+// schedule 返回心跳的触发计划。
+func (hb *Heartbeat) schedule() (cron.Schedule, error) {
+	if hb.Cron != "" {
+		schedule, err := cron.ParseStandard(hb.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("无效的心跳 cron 表达式: %w", err)
+		}
+		return schedule, nil
+	}
+	if hb.Interval < time.Minute {
+		return nil, fmt.Errorf("心跳间隔不能小于 1 分钟")
+	}
+	return cron.Every(hb.Interval), nil
+}
+
+// heartbeatRuns 已发出但尚未处理完成的心跳，限制同时运行的心跳数，并避免同一心跳重叠运行。
+type heartbeatRuns struct {
+	mu      sync.Mutex
+	running map[string]time.Time // 心跳标识 -> 发出时间
+	limit   int                  // 同时运行的上限，0 表示不限制
+	timeout time.Duration        // 超过该时长未完成视为已结束，避免完成事件丢失后永久占用名额
+}
+
+// acquire 占用一个运行名额，不能运行时返回原因。
+func (r *heartbeatRuns) acquire(id string, now time.Time) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	timeout := r.timeout
+	if timeout <= 0 {
+		timeout = defaultHeartbeatTimeout
+	}
+	for key, started := range r.running {
+		if now.Sub(started) >= timeout {
+			delete(r.running, key)
+		}
+	}
+	if _, ok := r.running[id]; ok {
+		return "上一次心跳尚未完成"
+	}
+	if r.limit > 0 && len(r.running) >= r.limit {
+		return "同时运行的心跳已达上限"
+	}
+	if r.running == nil {
+		r.running = make(map[string]time.Time)
+	}
+	r.running[id] = now
+	return ""
+}
+
+// release 释放心跳的运行名额。
+func (r *heartbeatRuns) release(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, id)
+}
+
+// SetHeartbeatLimit 设置同时运行的心跳上限（0 表示不限制），以及心跳发出后最长占用名额的时间。
+// 同一心跳上一次尚未处理完成时总是跳过本次触发。
+func (s *Scheduler) SetHeartbeatLimit(limit int, timeout time.Duration) {
+	s.runs.mu.Lock()
+	defer s.runs.mu.Unlock()
+	s.runs.limit = limit
+	s.runs.timeout = timeout
+}
+
+// WatchHeartbeats 订阅心跳完成事件并释放运行名额，直到 ctx 取消。
+func (s *Scheduler) WatchHeartbeats(ctx context.Context) {
+	const subscriber = "scheduler.heartbeat"
+	events := s.bus.SubscribeEvents(subscriber, 0)
+	defer s.bus.UnsubscribeEvents(subscriber)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if ev.Type != bus.EventHeartbeatDone {
+				continue
+			}
+			if id, _ := ev.Data["id"].(string); id != "" {
+				s.runs.release(id)
+			}
+		}
+	}
+}
+
+// SetSessions 设置会话存储，心跳的 ActiveWindow 依赖会话的最后活跃时间。
+func (s *Scheduler) SetSessions(sessions *storage.SessionStorage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = sessions
+}
+
+// SetLocation 设置用户时区查询函数，心跳的免打扰时段按会话用户的时区判断，未设置时使用 UTC。
+func (s *Scheduler) SetLocation(fn func(channel, sessionID string) *time.Location) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.location = fn
+}
+
+// AddHeartbeat 添加心跳。
+func (s *Scheduler) AddHeartbeat(hb *Heartbeat) error {
+	schedule, err := hb.schedule()
+	if err != nil {
+		return err
+	}
+	if hb.Channel == "" || hb.SessionID == "" {
+		return fmt.Errorf("心跳需要配置渠道和会话ID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cron.Schedule(schedule, cron.FuncJob(func() {
+		if !s.isLeader() {
+			s.logger.Debug("非主实例，跳过心跳", "name", hb.name())
+			return
+		}
+		if hb.Jitter > 0 {
+			time.Sleep(rand.N(hb.Jitter))
+		}
+		if reason := s.beat(hb, time.Now()); reason != "" {
+			s.logger.Debug("跳过心跳", "name", hb.name(), "reason", reason)
+		}
+	}))
+
+	s.logger.Info("心跳已添加", "name", hb.name(), "interval", hb.Interval, "cron", hb.Cron,
+		"channel", hb.Channel, "session_id", hb.SessionID)
+	return nil
+}
+
+// beat 执行一次心跳，跳过时返回原因。
+func (s *Scheduler) beat(hb *Heartbeat, now time.Time) string {
+	local := now.In(s.locationOf(hb.Channel, hb.SessionID))
+	if hb.Window != nil && !hb.Window.Contains(local) {
+		return "不在运行时段"
+	}
+	if hb.QuietHours != nil && hb.QuietHours.Contains(local) {
+		return "免打扰时段"
+	}
+	if hb.ActiveWindow > 0 && s.sessions != nil {
+		sess, err := s.sessions.GetBySessionID(hb.Channel, hb.SessionID)
+		if err != nil && !errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+			s.logger.Warn("读取会话失败", "error", err, "session_id", hb.SessionID)
+		}
+		if err == nil && now.Sub(sess.LastActive) < hb.ActiveWindow {
+			return "用户近期活跃"
+		}
+	}
+
+	content, err := hb.content()
+	if err != nil {
+		s.logger.Warn("读取心跳清单失败", "error", err, "file", hb.File)
+		return "读取心跳清单失败"
+	}
+	if content == "" {
+		return "心跳清单为空"
+	}
+
+	id := hb.id()
+	if reason := s.runs.acquire(id, now); reason != "" {
+		return reason
+	}
+	metadata := map[string]any{consts.META_HEARTBEAT: true, consts.META_HEARTBEAT_ID: id}
+	if hb.Persona != "" {
+		metadata[consts.META_PERSONA] = hb.Persona
+	}
+	err = s.bus.PublishInbound(context.Background(), bus.InboundMessage{
+		Channel:   hb.Channel,
+		SessionID: hb.SessionID,
+		Text:      heartbeatText(content),
+		Timestamp: now,
+		Metadata:  metadata,
+		Priority:  bus.PriorityLow,
+	})
+	if err != nil {
+		s.runs.release(id)
+		s.logger.Warn("发送心跳失败", "error", err, "name", hb.name())
+		return "发送心跳失败"
+	}
+	s.logger.Info("已发送心跳", "name", hb.name(), "channel", hb.Channel, "session_id", hb.SessionID)
+	return ""
+}
+
+// locationOf 返回会话用户的时区。
+func (s *Scheduler) locationOf(channel, sessionID string) *time.Location {
+	s.mu.RLock()
+	fn := s.location
+	s.mu.RUnlock()
+	if fn != nil {
+		if loc := fn(channel, sessionID); loc != nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// content 返回心跳内容，清单文件不存在时返回空字符串。
+func (hb *Heartbeat) content() (string, error) {
+	if hb.Prompt != "" {
+		return strings.TrimSpace(hb.Prompt), nil
+	}
+	if hb.File == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(hb.File)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// heartbeatText 生成发给智能体的心跳消息。
+func heartbeatText(content string) string {
+	return "【心跳】这是定期心跳检查，不是用户发来的消息。请按以下清单检查是否有需要主动告知用户的事项，" +
+		"有则直接写给用户；没有时只回复 " + consts.HEARTBEAT_OK + "，不要附加其他内容。\n\n" + content
+}
@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/clock"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+)
+
+func TestScheduler_Beat(t *testing.T) {
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "heartbeat.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	mb := bus.NewMessageBus(bus.DefaultConfig())
+	t.Cleanup(mb.Close)
+	sched := NewScheduler(s.Task(), mb, nil)
+	sched.SetSessions(s.Session())
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	sched.SetLocation(func(channel, sessionID string) *time.Location { return shanghai })
+
+	checklist := filepath.Join(t.TempDir(), "HEARTBEAT.md")
+	quiet, _ := clock.ParseHours("22:00-08:00")
+	hb := &Heartbeat{
+		Persona:      "ops",
+		Interval:     30 * time.Minute,
+		Channel:      "feishu",
+		SessionID:    "oc_1",
+		File:         checklist,
+		QuietHours:   &quiet,
+		ActiveWindow: 15 * time.Minute,
+	}
+
+	// 10:00 UTC+8
+	day := time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)
+	if got := sched.beat(hb, day); got != "心跳清单为空" {
+		t.Errorf("missing checklist: beat() = %q", got)
+	}
+
+	os.WriteFile(checklist, []byte("- 检查 CI 是否失败\n"), 0644)
+	// 03:00 UTC+8
+	if got := sched.beat(hb, time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC)); got != "免打扰时段" {
+		t.Errorf("quiet hours: beat() = %q", got)
+	}
+
+	s.Session().Touch("feishu", "oc_1", "u1")
+	if got := sched.beat(hb, time.Now()); got != "用户近期活跃" {
+		t.Errorf("recent activity: beat() = %q", got)
+	}
+
+	if got := sched.beat(hb, time.Now().Add(time.Hour)); got != "" {
+		t.Fatalf("beat() = %q, want published", got)
+	}
+	msg, _ := mb.ConsumeInbound(context.Background())
+	if msg.Priority != bus.PriorityLow || msg.Channel != "feishu" || msg.SessionID != "oc_1" || !strings.Contains(msg.Text, "检查 CI 是否失败") ||
+		msg.Metadata[consts.META_HEARTBEAT] != true || msg.Metadata[consts.META_PERSONA] != "ops" {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestScheduler_BeatLimits(t *testing.T) {
+	mb := bus.NewMessageBus(bus.DefaultConfig())
+	t.Cleanup(mb.Close)
+	sched := NewScheduler(nil, mb, nil)
+	sched.SetHeartbeatLimit(1, time.Hour)
+
+	business, _ := clock.ParseHours("09:00-18:00")
+	ops := &Heartbeat{Persona: "ops", Cron: "*/15 * * * *", Channel: "feishu", SessionID: "oc_1", Prompt: "检查告警", Window: &business}
+	sales := &Heartbeat{Persona: "sales", Interval: time.Hour, Channel: "feishu", SessionID: "oc_2", Prompt: "检查商机"}
+	for _, hb := range []*Heartbeat{ops, sales} {
+		if err := sched.AddHeartbeat(hb); err != nil {
+			t.Fatalf("AddHeartbeat(%s) error = %v", hb.Persona, err)
+		}
+	}
+	if err := sched.AddHeartbeat(&Heartbeat{Cron: "every minute", Channel: "feishu", SessionID: "oc_1"}); err == nil {
+		t.Error("无效的 cron 表达式应返回错误")
+	}
+
+	if got := sched.beat(ops, time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC)); got != "不在运行时段" {
+		t.Errorf("outside window: beat() = %q", got)
+	}
+
+	noon := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	if got := sched.beat(ops, noon); got != "" {
+		t.Fatalf("beat() = %q, want published", got)
+	}
+	msg, _ := mb.ConsumeInbound(context.Background())
+	id, _ := msg.Metadata[consts.META_HEARTBEAT_ID].(string)
+	if id != "ops@feishu/oc_1" {
+		t.Fatalf("heartbeat id = %q", id)
+	}
+	if got := sched.beat(ops, noon.Add(time.Minute)); got != "上一次心跳尚未完成" {
+		t.Errorf("overlap: beat() = %q", got)
+	}
+	if got := sched.beat(sales, noon.Add(time.Minute)); got != "同时运行的心跳已达上限" {
+		t.Errorf("limit: beat() = %q", got)
+	}
+
+	// 完成事件释放名额
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sched.WatchHeartbeats(ctx)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mb.PublishEvent(bus.Event{Type: bus.EventHeartbeatDone, Data: map[string]any{"id": id}})
+		if got := sched.beat(sales, noon.Add(2*time.Minute)); got == "" {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("after done: beat() = %q", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 超时未完成的心跳不再占用名额
+	if got := sched.beat(ops, noon.Add(2*time.Hour)); got != "" {
+		t.Errorf("after timeout: beat() = %q", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"icooclaw/pkg/channels/consts"
 	"icooclaw/pkg/storage"
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
@@ -46,6 +47,11 @@ type Scheduler struct {
 	storage *storage.TaskStorage
 	bus     *bus.MessageBus
 	running bool
+	leader  func() bool // 多实例部署时判断本实例是否为主实例，为 nil 时总是执行
+
+	sessions *storage.SessionStorage                        // 会话存储，心跳据此判断用户是否近期活跃
+	location func(channel, sessionID string) *time.Location // 用户时区，心跳据此判断免打扰时段
+	runs     heartbeatRuns                                  // 尚未处理完成的心跳
 }
 
 // NewScheduler 创建定时任务调度器.
@@ -64,6 +70,22 @@ func NewScheduler(storage *storage.TaskStorage, bus *bus.MessageBus, logger *slo
 	}
 }
 
+// SetLeader 设置主实例判断函数。多个实例共享数据库时，定时任务只在主实例上执行，
+// 避免同一任务在每个实例上各执行一次。
+func (s *Scheduler) SetLeader(fn func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leader = fn
+}
+
+// isLeader 判断本实例是否负责执行定时任务，手动执行的任务不受限制。
+func (s *Scheduler) isLeader() bool {
+	s.mu.RLock()
+	leader := s.leader
+	s.mu.RUnlock()
+	return leader == nil || leader()
+}
+
 // AddTask 添加定时任务.
 func (s *Scheduler) AddTask(task *Task) error {
 	s.mu.Lock()
@@ -81,6 +103,10 @@ func (s *Scheduler) AddTask(task *Task) error {
 
 	// Create cron job
 	entryID := s.cron.Schedule(schedule, cron.FuncJob(func() {
+		if !s.isLeader() {
+			s.logger.Debug("非主实例，跳过任务", "id", task.ID, "name", task.Name)
+			return
+		}
 		s.executeTask(task)
 	}))
 
@@ -266,6 +292,7 @@ func (s *Scheduler) executeTask(task *Task) {
 		SessionID: "",
 		Text:      task.Description + " " + task.Params,
 		Timestamp: time.Now(),
+		Priority:  bus.PriorityLow,
 		Metadata: map[string]any{
 			"task_id":   task.ID,
 			"task_name": task.Name,
@@ -312,6 +339,27 @@ const (
 	EveryMonth     = "0 0 1 * *"    // 每月1号执行一次
 )
 
+// LocalizeSchedule 为 5 段 Cron 表达式加上 CRON_TZ 前缀，使其按用户时区而不是调度器的 UTC 执行。
+// 已指定时区的表达式、@every 等描述符以及 UTC 保持不变。
+func LocalizeSchedule(expr string, loc *time.Location) (string, error) {
+	expr = strings.TrimSpace(expr)
+	if loc == nil || loc == time.UTC || strings.HasPrefix(expr, "@") ||
+		strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") || len(strings.Fields(expr)) != 5 {
+		return expr, nil
+	}
+
+	name := loc.String()
+	if _, err := time.LoadLocation(name); err != nil {
+		// 固定偏移的时区没有 IANA 名称，整点偏移换算为 Etc/GMT 时区（符号相反）
+		_, offset := time.Now().In(loc).Zone()
+		if offset%3600 != 0 {
+			return "", fmt.Errorf("时区 %s 不能用于定时任务，请使用 IANA 名称（如 Asia/Kolkata）", name)
+		}
+		name = fmt.Sprintf("Etc/GMT%+d", -offset/3600)
+	}
+	return "CRON_TZ=" + name + " " + expr, nil
+}
+
 // ParseDuration 解析持续时间字符串并返回定时任务调度表达式.
 func ParseDuration(d string) (string, error) {
 	duration, err := time.ParseDuration(d)
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"icooclaw/pkg/bus"
+	"icooclaw/pkg/clock"
 	"icooclaw/pkg/scheduler"
 	"icooclaw/pkg/storage"
 	"icooclaw/pkg/tools"
@@ -76,7 +77,7 @@ func (t *Tool) Parameters() map[string]any {
 		},
 		"cron_expr": map[string]any{
 			"type":        "string",
-			"description": "Cron 表达式 (例如: '0 * * * *' 每小时执行, '*/5 * * * *' 每5分钟执行)",
+			"description": "Cron 表达式 (例如: '0 * * * *' 每小时执行, '*/5 * * * *' 每5分钟执行)，按用户时区执行，可用 'CRON_TZ=Asia/Tokyo 0 9 * * *' 指定其他时区",
 		},
 		"handler": map[string]any{
 			"type":        "string",
@@ -116,11 +117,11 @@ func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
 	case "list":
 		return t.listTasks(args)
 	case "get":
-		return t.getTask(args)
+		return t.getTask(ctx, args)
 	case "create":
-		return t.createTask(args)
+		return t.createTask(ctx, args)
 	case "update":
-		return t.updateTask(args)
+		return t.updateTask(ctx, args)
 	case "delete":
 		return t.deleteTask(args)
 	case "run":
@@ -190,7 +191,7 @@ func (t *Tool) listTasks(args map[string]any) *tools.Result {
 }
 
 // getTask 获取单个定时任务详情.
-func (t *Tool) getTask(args map[string]any) *tools.Result {
+func (t *Tool) getTask(ctx context.Context, args map[string]any) *tools.Result {
 	taskID, _ := args["task_id"].(string)
 	if taskID == "" {
 		return tools.ErrorResult("需要提供 task_id 参数")
@@ -221,14 +222,15 @@ func (t *Tool) getTask(args map[string]any) *tools.Result {
 	if task.NextRunAt != "" {
 		output += fmt.Sprintf("- 下次运行: %s\n", task.NextRunAt)
 	}
-	output += fmt.Sprintf("- 创建时间: %s\n", task.CreatedAt.Format(time.RFC3339))
-	output += fmt.Sprintf("- 更新时间: %s\n", task.UpdatedAt.Format(time.RFC3339))
+	loc := tools.GetLocation(ctx)
+	output += fmt.Sprintf("- 创建时间: %s\n", clock.Format(task.CreatedAt, loc))
+	output += fmt.Sprintf("- 更新时间: %s\n", clock.Format(task.UpdatedAt, loc))
 
 	return tools.SuccessResult(output)
 }
 
 // createTask 创建新定时任务.
-func (t *Tool) createTask(args map[string]any) *tools.Result {
+func (t *Tool) createTask(ctx context.Context, args map[string]any) *tools.Result {
 	name, _ := args["name"].(string)
 	if name == "" {
 		return tools.ErrorResult("需要提供 name 参数")
@@ -243,6 +245,10 @@ func (t *Tool) createTask(args map[string]any) *tools.Result {
 	if err := t.validateCronExpr(cronExpr); err != nil {
 		return tools.ErrorResult(fmt.Sprintf("无效的 Cron 表达式: %v", err))
 	}
+	cronExpr, err := scheduler.LocalizeSchedule(cronExpr, tools.GetLocation(ctx))
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
 
 	description, _ := args["description"].(string)
 	if description == "" {
@@ -281,6 +287,7 @@ func (t *Tool) createTask(args map[string]any) *tools.Result {
 	}
 
 	// Add to scheduler if enabled and scheduler is available
+	var nextRun time.Time
 	if t.scheduler != nil && enabled {
 		schedTask := &scheduler.Task{
 			ID:          task.ID,
@@ -293,16 +300,18 @@ func (t *Tool) createTask(args map[string]any) *tools.Result {
 		if err := t.scheduler.AddTask(schedTask); err != nil {
 			t.logger.Warn("添加任务到调度器失败", "task_id", task.ID, "error", err)
 		}
+		nextRun = schedTask.NextRun
 	}
 
 	output := fmt.Sprintf("✅ 任务创建成功\n\n**%s** (`%s`)\n- 调度: `%s`\n- 通道名称: %s\n- 状态: %s",
 		task.Name, task.ID, task.CronExpr, task.Channel, map[bool]string{true: "已启用", false: "已禁用"}[task.Enabled])
+	output += nextRunLine(ctx, nextRun)
 
 	return tools.SuccessResult(output)
 }
 
 // updateTask 更新定时任务.
-func (t *Tool) updateTask(args map[string]any) *tools.Result {
+func (t *Tool) updateTask(ctx context.Context, args map[string]any) *tools.Result {
 	taskID, _ := args["task_id"].(string)
 	if taskID == "" {
 		return tools.ErrorResult("需要提供 task_id 参数")
@@ -325,6 +334,9 @@ func (t *Tool) updateTask(args map[string]any) *tools.Result {
 		if err := t.validateCronExpr(cronExpr); err != nil {
 			return tools.ErrorResult(fmt.Sprintf("无效的 Cron 表达式: %v", err))
 		}
+		if cronExpr, err = scheduler.LocalizeSchedule(cronExpr, tools.GetLocation(ctx)); err != nil {
+			return tools.ErrorResult(err.Error())
+		}
 		task.CronExpr = cronExpr
 	}
 	if params, ok := args["params"].(string); ok {
@@ -347,6 +359,7 @@ func (t *Tool) updateTask(args map[string]any) *tools.Result {
 	}
 
 	// Update scheduler if available
+	var nextRun time.Time
 	if t.scheduler != nil {
 		// Remove old task and add updated one
 		t.scheduler.RemoveTask(task.ID)
@@ -361,6 +374,7 @@ func (t *Tool) updateTask(args map[string]any) *tools.Result {
 			if err := t.scheduler.AddTask(schedTask); err != nil {
 				t.logger.Warn("更新调度器任务失败", "task_id", task.ID, "error", err)
 			}
+			nextRun = schedTask.NextRun
 		}
 	}
 
@@ -371,6 +385,7 @@ func (t *Tool) updateTask(args map[string]any) *tools.Result {
 
 	output := fmt.Sprintf("✅ 任务更新成功\n\n**%s** (`%s`)\n- 调度: `%s`\n- 通道名称: %s\n- 状态: %s",
 		task.Name, task.ID, task.CronExpr, task.Channel, status)
+	output += nextRunLine(ctx, nextRun)
 
 	return tools.SuccessResult(output)
 }
@@ -482,6 +497,14 @@ func (t *Tool) disableTask(args map[string]any) *tools.Result {
 	return tools.SuccessResult(fmt.Sprintf("✅ 任务 %s 已禁用", taskID))
 }
 
+// nextRunLine 按用户时区展示下次运行时间，调度器未启动时不展示。
+func nextRunLine(ctx context.Context, next time.Time) string {
+	if next.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("\n- 下次运行: %s", clock.Format(next, tools.GetLocation(ctx)))
+}
+
 // validateCronExpr 验证 Cron 表达式是否有效.
 func (t *Tool) validateCronExpr(expr string) error {
 	// Common cron expressions
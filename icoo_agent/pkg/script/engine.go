@@ -17,6 +17,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/dop251/goja"
 )
@@ -43,6 +44,28 @@ type Config struct {
 	MaxMemory int64
 	// AllowedDomains is the whitelist for network requests.
 	AllowedDomains []string
+	// KV is the backing store for the kv builtin; nil disables it.
+	KV KVStore
+}
+
+// mutates reports whether scripts may change files or run commands.
+func (c *Config) mutates() bool {
+	return c.AllowFileWrite || c.AllowFileDelete || c.AllowExec
+}
+
+// mutations lists the permissions that let scripts change the workspace.
+func (c *Config) mutations() string {
+	var perms []string
+	if c.AllowFileWrite {
+		perms = append(perms, "file write")
+	}
+	if c.AllowFileDelete {
+		perms = append(perms, "file delete")
+	}
+	if c.AllowExec {
+		perms = append(perms, "exec")
+	}
+	return strings.Join(perms, ", ")
 }
 
 // DefaultConfig returns the default configuration.
@@ -173,9 +196,12 @@ func (e *Engine) setupBuiltins() {
 	// Register default builtins
 	e.RegisterBuiltin(NewConsole(e.logger))
 	e.RegisterBuiltin(NewFileSystem(e.cfg, e.logger))
-	e.RegisterBuiltin(NewHTTPClient(e.cfg, e.logger))
+	e.RegisterBuiltin(NewHTTPClientWithContext(e.cfg, e.logger, func() context.Context { return e.ctx }))
 	e.RegisterBuiltin(NewShellExec(e.ctx, e.cfg, e.logger))
 	e.RegisterBuiltin(NewUtils())
+	if e.cfg.KV != nil {
+		e.RegisterBuiltin(NewKV(e.cfg.KV, func() context.Context { return e.ctx }))
+	}
 
 	// Standard library extensions
 	e.setupStdLib()
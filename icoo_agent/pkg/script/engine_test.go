@@ -3,7 +3,14 @@ package script
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"icooclaw/pkg/tools"
 )
 
 func TestEngine_Run(t *testing.T) {
@@ -179,6 +186,32 @@ func TestHTTPClient_Disabled(t *testing.T) {
 	}
 }
 
+func TestHTTPClient_Download(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	cfg := &Config{Workspace: t.TempDir(), AllowNetwork: true}
+	httpClient := NewHTTPClient(cfg, nil)
+	if _, err := httpClient.Download(srv.URL, "a.txt", nil); err == nil {
+		t.Error("Expected error when file writing is disabled")
+	}
+
+	cfg.AllowFileWrite = true
+	sum := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	res, err := httpClient.Download(srv.URL, "a.txt", map[string]any{"sha256": sum})
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if res["size"] != int64(5) || res["sha256"] != sum {
+		t.Errorf("Download() = %v", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(cfg.Workspace, "a.txt")); string(data) != "hello" {
+		t.Errorf("downloaded file = %q", data)
+	}
+}
+
 func TestFileSystem_Disabled(t *testing.T) {
 	cfg := &Config{AllowFileRead: false, AllowFileWrite: false}
 	fs := NewFileSystem(cfg, nil)
@@ -206,4 +239,48 @@ func TestScriptTool(t *testing.T) {
 	if !result.Success {
 		t.Errorf("Expected success, got error: %v", result.Error)
 	}
-}
\ No newline at end of file
+}
+func TestScriptTools_ReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	ctx := tools.WithReadOnly(context.Background())
+
+	tests := []struct {
+		name string
+		cfg  func(*Config)
+		code string
+	}{
+		{"file write", func(c *Config) { c.AllowFileWrite = true }, `fs.writeFile("out.txt", "x")`},
+		{"exec", func(c *Config) { c.AllowExec = true }, `shell.exec("echo x > out.txt")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Workspace = dir
+			tt.cfg(cfg)
+			registry := tools.NewRegistry()
+			RegisterScriptTools(registry, cfg, nil)
+
+			res := registry.Execute(ctx, "script", map[string]any{"code": tt.code})
+			if !res.Success || !strings.Contains(res.Content, "只读") {
+				t.Errorf("script = %+v, want skipped", res)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "out.txt")); err == nil {
+				t.Fatal("script wrote a file in a read-only workspace")
+			}
+			res = registry.Execute(ctx, "script_file", map[string]any{"path": "run.js"})
+			if !res.Success || !strings.Contains(res.Content, "只读") {
+				t.Errorf("script_file = %+v, want skipped", res)
+			}
+		})
+	}
+
+	// 没有写入和执行权限的脚本照常执行
+	cfg := DefaultConfig()
+	cfg.Workspace = dir
+	registry := tools.NewRegistry()
+	RegisterScriptTools(registry, cfg, nil)
+	res := registry.Execute(ctx, "script", map[string]any{"code": "1 + 1"})
+	if !res.Success || res.Content != "2" {
+		t.Errorf("script(read only permissions) = %+v, want 2", res)
+	}
+}
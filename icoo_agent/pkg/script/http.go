@@ -3,6 +3,7 @@ package script
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,17 +12,31 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"icooclaw/pkg/download"
+	"icooclaw/pkg/tools"
 )
 
 // HTTPClient provides HTTP client operations.
 type HTTPClient struct {
-	cfg    *Config
-	logger *slog.Logger
-	client *http.Client
+	cfg     *Config
+	logger  *slog.Logger
+	client  *http.Client
+	timeout time.Duration
+	ctx     func() context.Context // current execution context; requests never outlive its deadline
 }
 
 // NewHTTPClient creates a new HTTPClient builtin.
 func NewHTTPClient(cfg *Config, logger *slog.Logger) *HTTPClient {
+	return NewHTTPClientWithContext(cfg, logger, nil)
+}
+
+// NewHTTPClientWithContext creates a new HTTPClient builtin bound to the
+// engine's current context, so requests never outlive the tool call.
+func NewHTTPClientWithContext(cfg *Config, logger *slog.Logger, ctx func() context.Context) *HTTPClient {
+	if ctx == nil {
+		ctx = context.Background
+	}
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -32,11 +47,11 @@ func NewHTTPClient(cfg *Config, logger *slog.Logger) *HTTPClient {
 	}
 
 	return &HTTPClient{
-		cfg:    cfg,
-		logger: logger,
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		cfg:     cfg,
+		logger:  logger,
+		client:  &http.Client{},
+		timeout: timeout,
+		ctx:     ctx,
 	}
 }
 
@@ -48,11 +63,12 @@ func (h *HTTPClient) Name() string {
 // Object returns the http object.
 func (h *HTTPClient) Object() map[string]any {
 	return map[string]any{
-		"get":     h.Get,
-		"post":    h.Post,
-		"put":     h.Put,
-		"delete":  h.Delete,
-		"request": h.Request,
+		"get":      h.Get,
+		"post":     h.Post,
+		"put":      h.Put,
+		"delete":   h.Delete,
+		"request":  h.Request,
+		"download": h.Download,
 	}
 }
 
@@ -82,22 +98,8 @@ func (h *HTTPClient) Request(method, reqURL string, body any, headers map[string
 		return nil, fmt.Errorf("network access is not allowed")
 	}
 
-	// Check domain whitelist
-	if len(h.cfg.AllowedDomains) > 0 {
-		parsedURL, err := url.Parse(reqURL)
-		if err != nil {
-			return nil, fmt.Errorf("invalid URL: %w", err)
-		}
-		allowed := false
-		for _, domain := range h.cfg.AllowedDomains {
-			if strings.HasSuffix(parsedURL.Host, domain) {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			return nil, fmt.Errorf("domain not allowed: %s", parsedURL.Host)
-		}
+	if err := h.checkDomain(reqURL); err != nil {
+		return nil, err
 	}
 
 	// Prepare body
@@ -115,8 +117,11 @@ func (h *HTTPClient) Request(method, reqURL string, body any, headers map[string
 		}
 	}
 
+	ctx, cancel := tools.WithTimeout(h.ctx(), h.timeout)
+	defer cancel()
+
 	// Create request
-	req, err := http.NewRequest(method, reqURL, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -137,14 +142,14 @@ func (h *HTTPClient) Request(method, reqURL string, body any, headers map[string
 	startTime := time.Now()
 	resp, err := h.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", tools.DeadlineError(ctx, h.timeout, err))
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", tools.DeadlineError(ctx, h.timeout, err))
 	}
 
 	// Build result
@@ -169,6 +174,97 @@ func (h *HTTPClient) Request(method, reqURL string, body any, headers map[string
 	return result, nil
 }
 
+// Download downloads a file into the workspace with resume, SHA256 verification and size limits.
+// options: sha256, maxBytes, rateLimit (bytes/s), headers, timeout (seconds, default 30 minutes).
+// Progress lines are streamed as tool output when the script runs inside a tool call.
+func (h *HTTPClient) Download(reqURL, path string, options map[string]any) (map[string]any, error) {
+	if !h.cfg.AllowNetwork {
+		return nil, fmt.Errorf("network access is not allowed")
+	}
+	if !h.cfg.AllowFileWrite {
+		return nil, fmt.Errorf("file writing is not allowed")
+	}
+	if err := h.checkDomain(reqURL); err != nil {
+		return nil, err
+	}
+	dest := (&FileSystem{cfg: h.cfg}).resolvePath(path)
+	if dest == "" {
+		return nil, fmt.Errorf("invalid path: %s", path)
+	}
+
+	opts := download.Options{Client: h.client}
+	timeout := 30 * time.Minute
+	if v, ok := options["sha256"].(string); ok {
+		opts.SHA256 = v
+	}
+	if v, ok := toInt64(options["maxBytes"]); ok {
+		opts.MaxBytes = v
+	}
+	if v, ok := toInt64(options["rateLimit"]); ok {
+		opts.RateLimit = v
+	}
+	if v, ok := toInt64(options["timeout"]); ok && v > 0 {
+		timeout = time.Duration(v) * time.Second
+	}
+	if headers, ok := options["headers"].(map[string]any); ok {
+		opts.Headers = make(map[string]string, len(headers))
+		for k, v := range headers {
+			opts.Headers[k] = fmt.Sprint(v)
+		}
+	}
+	if output := tools.GetOutput(h.ctx()); output != nil {
+		opts.OnProgress = func(p download.Progress) {
+			output(tools.StreamStdout, p.String()+"\n")
+		}
+	}
+
+	ctx, cancel := tools.WithTimeout(h.ctx(), timeout)
+	defer cancel()
+
+	res, err := download.Download(ctx, reqURL, dest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", tools.DeadlineError(ctx, timeout, err))
+	}
+	return map[string]any{
+		"path":     res.Path,
+		"size":     res.Size,
+		"sha256":   res.SHA256,
+		"resumed":  res.Resumed,
+		"attempts": res.Attempts,
+	}, nil
+}
+
+// checkDomain checks the URL against the domain whitelist.
+func (h *HTTPClient) checkDomain(reqURL string) error {
+	if len(h.cfg.AllowedDomains) == 0 {
+		return nil
+	}
+	parsedURL, err := url.Parse(reqURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	for _, domain := range h.cfg.AllowedDomains {
+		if strings.HasSuffix(parsedURL.Host, domain) {
+			return nil
+		}
+	}
+	return fmt.Errorf("domain not allowed: %s", parsedURL.Host)
+}
+
+// toInt64 converts a JavaScript number to int64.
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
+
 func flattenHeaders(headers http.Header) map[string]string {
 	result := make(map[string]string)
 	for key, values := range headers {
@@ -0,0 +1,80 @@
+// Package script provides JavaScript scripting engine for icooclaw.
+package script
+
+import (
+	"context"
+	"fmt"
+)
+
+// KVStore is the backing store for the kv builtin.
+// Scope is "user" (default) or "global"; the store resolves it from the context.
+type KVStore interface {
+	Get(ctx context.Context, scope, key string) (any, bool, error)
+	Set(ctx context.Context, scope, key string, value any) error
+	List(ctx context.Context, scope, prefix string) (map[string]any, error)
+	Delete(ctx context.Context, scope, key string) error
+}
+
+// KV provides persistent key-value storage for scripts.
+type KV struct {
+	store KVStore
+	ctx   func() context.Context
+}
+
+// NewKV creates a new KV builtin.
+func NewKV(store KVStore, ctx func() context.Context) *KV {
+	return &KV{store: store, ctx: ctx}
+}
+
+// Name returns the builtin name.
+func (k *KV) Name() string {
+	return "kv"
+}
+
+// Object returns the kv object.
+func (k *KV) Object() map[string]any {
+	return map[string]any{
+		"get":    k.Get,
+		"set":    k.Set,
+		"list":   k.List,
+		"delete": k.Delete,
+	}
+}
+
+// Get returns the value of key, or null if it does not exist.
+func (k *KV) Get(key string, scope ...string) (any, error) {
+	value, ok, err := k.store.Get(k.ctx(), scopeArg(scope), key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// Set stores value under key.
+func (k *KV) Set(key string, value any, scope ...string) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	return k.store.Set(k.ctx(), scopeArg(scope), key, value)
+}
+
+// List returns all values whose key starts with prefix.
+func (k *KV) List(prefix string, scope ...string) (map[string]any, error) {
+	return k.store.List(k.ctx(), scopeArg(scope), prefix)
+}
+
+// Delete removes key.
+func (k *KV) Delete(key string, scope ...string) error {
+	return k.store.Delete(k.ctx(), scopeArg(scope), key)
+}
+
+// scopeArg returns the optional scope argument.
+func scopeArg(scope []string) string {
+	if len(scope) > 0 && scope[0] != "" {
+		return scope[0]
+	}
+	return "user"
+}
@@ -84,6 +84,16 @@ func (t *ScriptTool) Execute(ctx context.Context, args map[string]any) *tools.Re
 	}
 }
 
+// DescribeChange implements tools.Mutator: scripts that may write files or
+// run commands are not executed in a read-only workspace.
+func (t *ScriptTool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	if !t.engine.cfg.mutates() {
+		return "", false
+	}
+	code, _ := args["code"].(string)
+	return fmt.Sprintf("run a script with %s permission: %s", t.engine.cfg.mutations(), code), true
+}
+
 // ScriptFileTool executes script files.
 type ScriptFileTool struct {
 	engine *Engine
@@ -163,6 +173,16 @@ func (t *ScriptFileTool) Execute(ctx context.Context, args map[string]any) *tool
 	}
 }
 
+// DescribeChange implements tools.Mutator: scripts that may write files or
+// run commands are not executed in a read-only workspace.
+func (t *ScriptFileTool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	if !t.engine.cfg.mutates() {
+		return "", false
+	}
+	path, _ := args["path"].(string)
+	return fmt.Sprintf("run script file %s with %s permission", path, t.engine.cfg.mutations()), true
+}
+
 // RegisterScriptTools registers script tools to the registry.
 func RegisterScriptTools(registry *tools.Registry, cfg *Config, logger *slog.Logger) {
 	registry.Register(NewScriptTool(cfg, logger))
@@ -107,15 +107,34 @@ func (l *DefaultLoader) LoadMetadata(ctx context.Context, name string) (*Metadat
 	return skill, nil
 }
 
-// LoadInfo 加载技能详细信息
+// LoadInfo 加载技能详细信息，优先读取技能记录的路径下的 SKILL.md
 func (l *DefaultLoader) LoadInfo(ctx context.Context, name string) (*Info, error) {
-	// 加载技能详细信息
-	info, err := l.ReadSkill(ctx, name, "")
+	sk, err := l.storage.Skill().GetSkill(name)
+	if err != nil || sk.Path == "" {
+		// 加载技能详细信息
+		info, err := l.ReadSkill(ctx, name, "")
+		if err != nil {
+			return nil, fmt.Errorf("read skill %s info failed: %w", name, err)
+		}
+		return info, nil
+	}
+
+	path := filepath.Join(sk.Path, SkillFile)
+	parseInfo, err := NewParser().ParseFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("read skill %s info failed: %w", name, err)
+		return nil, fmt.Errorf("parse skill file %s failed: %w", path, err)
 	}
 
-	return info, nil
+	return &Info{
+		Metadata: Metadata{
+			Name:        sk.Name,
+			Description: sk.Description,
+			Version:     sk.Version,
+		},
+		Path:    sk.Path,
+		Content: parseInfo.Content,
+		Source:  l.workspace,
+	}, nil
 }
 
 // List 列出所有技能
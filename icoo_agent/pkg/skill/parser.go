@@ -27,6 +27,8 @@ const (
 	MaxDescriptionLength = 1024
 	MaxVersionLength     = 32
 	MaxContentLength     = 100 * 1024 // 100KB
+	// SkillFile 技能目录下的技能文件名
+	SkillFile = "SKILL.md"
 )
 
 // ParsedSkill 解析后的技能，包含元数据和内容。
@@ -259,7 +261,7 @@ func (p *SkillParser) CreateSkillFile(dir string, skill *ParsedSkill) error {
 	sb.WriteString(skill.Content)
 
 	// Write file
-	skillPath := filepath.Join(skillDir, "SKILL.md")
+	skillPath := filepath.Join(skillDir, SkillFile)
 	if err := os.WriteFile(skillPath, []byte(sb.String()), 0o600); err != nil {
 		return fmt.Errorf("failed to write skill file: %w", err)
 	}
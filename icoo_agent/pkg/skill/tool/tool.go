@@ -14,6 +14,9 @@ import (
 	"time"
 )
 
+// installTimeout 下载技能文件的超时
+const installTimeout = 15 * time.Second
+
 type InstallTool struct {
 	workspace string
 	store     *storage.SkillStorage
@@ -78,7 +81,7 @@ func (t *InstallTool) Execute(ctx context.Context, args map[string]any) *tools.R
 		Path:        filepath.Join(t.workspace, consts.SKILL_DIR, name),
 	}
 	if err := t.store.SaveSkill(saveData); err != nil {
-		return tools.ErrorResult(fmt.Sprintf("保存技能 %s 失败: %w", name, err.Error()))
+		return tools.ErrorResult(fmt.Sprintf("保存技能 %s 失败: %s", name, err.Error()))
 	}
 
 	return tools.SuccessResult("安装成功")
@@ -94,7 +97,11 @@ func (t *InstallTool) InstallFromGitHub(ctx context.Context, repo string) error
 
 	url := fmt.Sprintf("https://raw.githubusercontent.com/%s/main/SKILL.md", repo)
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	// 超时取 15 秒和本轮剩余预算中的较小者
+	ctx, cancel := tools.WithTimeout(ctx, installTimeout)
+	defer cancel()
+
+	client := &http.Client{}
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %s", err.Error())
@@ -103,7 +110,7 @@ func (t *InstallTool) InstallFromGitHub(ctx context.Context, repo string) error
 	// 请求技能文件
 	resp, err := utils.DoRequestWithRetry(client, req)
 	if err != nil {
-		return fmt.Errorf("failed to fetch skill: %s", err.Error())
+		return fmt.Errorf("failed to fetch skill: %w", tools.DeadlineError(ctx, installTimeout, err))
 	}
 	defer resp.Body.Close()
 
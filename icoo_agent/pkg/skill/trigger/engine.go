@@ -0,0 +1,375 @@
+// Package trigger activates skills automatically from the triggers configured
+// on them: regex triggers match inbound messages, schedule triggers fire on a
+// cron expression and event triggers fire on bus events.
+//
+// 同一条消息或同一个事件命中多个技能时按优先级、匹配长度、技能名称的顺序排序，
+// 前 MaxActive 个技能生效，其余技能记录为被覆盖。每次触发都写入触发记录，便于排查技能为什么（没有）生效。
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"icooclaw/pkg/bus"
+	chanConsts "icooclaw/pkg/channels/consts"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+)
+
+const (
+	// subscriberName 事件订阅者名称
+	subscriberName = "skill-trigger"
+	// eventBuffer 事件订阅缓冲大小
+	eventBuffer = 64
+	// pruneInterval 清理过期触发记录的间隔
+	pruneInterval = time.Hour
+	// defaultPrompt 定时和事件触发未设置消息时交给智能体的消息
+	defaultPrompt = "请按技能 %s 的说明处理：%s"
+)
+
+// Config 触发引擎配置。
+type Config struct {
+	MaxActive int           // 同一条消息或事件最多激活的技能数，小于 1 时按 1 处理
+	AuditKeep time.Duration // 触发记录保留时长，0 表示永久保留
+}
+
+// rule 编译后的触发规则。
+type rule struct {
+	skill   string
+	trigger storage.SkillTrigger
+	re      *regexp.Regexp
+}
+
+// candidate 命中的触发规则。
+type candidate struct {
+	rule   *rule
+	length int    // 匹配的文本长度，优先级相同时匹配越长越具体
+	detail string // 命中的文本或事件类型
+}
+
+// Engine 技能触发引擎。
+type Engine struct {
+	store  *storage.Storage
+	bus    *bus.MessageBus
+	cfg    Config
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	regex    []*rule
+	events   []*rule
+	schedule []*rule
+	cron     *cron.Cron
+	leader   func() bool
+}
+
+// New 创建触发引擎，调用 Reload 加载规则。
+func New(store *storage.Storage, b *bus.MessageBus, cfg Config, logger *slog.Logger) *Engine {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.MaxActive < 1 {
+		cfg.MaxActive = 1
+	}
+	return &Engine{store: store, bus: b, cfg: cfg, logger: logger}
+}
+
+// SetLeader 设置主实例判断，多个实例共享数据库时定时触发只在主实例上执行。
+func (e *Engine) SetLeader(fn func() bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leader = fn
+}
+
+// isLeader 判断本实例是否负责定时触发。
+func (e *Engine) isLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader == nil || e.leader()
+}
+
+// Reload 重新加载已启用技能的触发规则，无效的规则记录日志后跳过。
+func (e *Engine) Reload() error {
+	skills, err := e.store.Skill().ListEnabledSkills()
+	if err != nil {
+		return err
+	}
+
+	var regex, events, schedule []*rule
+	for _, sk := range skills {
+		for _, t := range sk.Triggers {
+			r, err := compile(sk.Name, t)
+			if err != nil {
+				e.logger.With("name", "【技能触发】").Warn("触发规则无效，已跳过", "skill", sk.Name, "trigger", t.Describe(), "error", err)
+				continue
+			}
+			switch t.Type {
+			case storage.SkillTriggerRegex:
+				regex = append(regex, r)
+			case storage.SkillTriggerEvent:
+				events = append(events, r)
+			case storage.SkillTriggerSchedule:
+				schedule = append(schedule, r)
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.regex, e.events, e.schedule = regex, events, schedule
+	running := e.cron != nil
+	e.mu.Unlock()
+	if running {
+		e.startCron()
+	}
+
+	e.logger.With("name", "【技能触发】").Info("触发规则已加载",
+		"regex", len(regex), "schedule", len(schedule), "event", len(events))
+	return nil
+}
+
+// compile 校验并编译触发规则。
+func compile(skill string, t storage.SkillTrigger) (*rule, error) {
+	r := &rule{skill: skill, trigger: t}
+	switch t.Type {
+	case storage.SkillTriggerRegex:
+		if t.Pattern == "" {
+			return nil, fmt.Errorf("pattern is required")
+		}
+		re, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		r.re = re
+	case storage.SkillTriggerSchedule:
+		if _, err := cron.ParseStandard(t.Schedule); err != nil {
+			return nil, fmt.Errorf("invalid schedule: %w", err)
+		}
+	case storage.SkillTriggerEvent:
+		if t.Event == "" {
+			return nil, fmt.Errorf("event is required")
+		}
+	default:
+		return nil, fmt.Errorf("unknown trigger type %q", t.Type)
+	}
+	return r, nil
+}
+
+// Match 返回入站消息激活的技能名称，并记录命中的所有技能。
+func (e *Engine) Match(msg bus.InboundMessage) []string {
+	if msg.Text == "" {
+		return nil
+	}
+	e.mu.RLock()
+	rules := e.regex
+	e.mu.RUnlock()
+
+	var matched []candidate
+	for _, r := range rules {
+		if len(r.trigger.Channels) > 0 && !slices.Contains(r.trigger.Channels, msg.Channel) {
+			continue
+		}
+		loc := r.re.FindStringIndex(msg.Text)
+		if loc == nil {
+			continue
+		}
+		matched = append(matched, candidate{rule: r, length: loc[1] - loc[0], detail: msg.Text[loc[0]:loc[1]]})
+	}
+	return e.resolve(matched, msg.Channel, msg.SessionID)
+}
+
+// resolve 按优先级、匹配长度、技能名称排序，同一技能只保留最优的规则，
+// 返回前 MaxActive 个技能，并记录全部命中。
+func (e *Engine) resolve(matched []candidate, channel, sessionID string) []string {
+	if len(matched) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(matched, func(a, b candidate) int {
+		if a.rule.trigger.Priority != b.rule.trigger.Priority {
+			return b.rule.trigger.Priority - a.rule.trigger.Priority
+		}
+		if a.length != b.length {
+			return b.length - a.length
+		}
+		return strings.Compare(a.rule.skill, b.rule.skill)
+	})
+
+	var (
+		active  []string
+		firings []*storage.SkillFiring
+	)
+	for _, c := range matched {
+		f := &storage.SkillFiring{
+			Skill:     c.rule.skill,
+			Trigger:   c.rule.trigger.Describe(),
+			Channel:   channel,
+			SessionID: sessionID,
+			Detail:    c.detail,
+		}
+		switch {
+		case slices.Contains(active, c.rule.skill):
+			f.Reason = "同一技能的其他规则已命中"
+		case len(active) >= e.cfg.MaxActive:
+			f.Reason = "被 " + strings.Join(active, "、") + " 覆盖"
+		default:
+			f.Activated = true
+			active = append(active, c.rule.skill)
+		}
+		firings = append(firings, f)
+	}
+	e.audit(firings...)
+
+	if len(matched) > 1 {
+		e.logger.With("name", "【技能触发】").Info("多个技能同时命中，按优先级激活",
+			"channel", channel, "session_id", sessionID, "matched", len(matched), "active", active)
+	}
+	return active
+}
+
+// audit 写入触发记录。
+func (e *Engine) audit(firings ...*storage.SkillFiring) {
+	if err := e.store.SkillFiring().Save(firings...); err != nil {
+		e.logger.With("name", "【技能触发】").Warn("保存触发记录失败", "error", err)
+	}
+}
+
+// Run 启动定时触发并订阅事件，直到 ctx 取消。
+func (e *Engine) Run(ctx context.Context) {
+	events := e.bus.SubscribeEvents(subscriberName, eventBuffer)
+	defer e.bus.UnsubscribeEvents(subscriberName)
+
+	e.startCron()
+	defer e.stopCron()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			e.fireEvent(ctx, ev)
+		case <-ticker.C:
+			e.prune()
+		}
+	}
+}
+
+// startCron 按当前的定时规则重建定时任务。
+func (e *Engine) startCron() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cron != nil {
+		e.cron.Stop()
+	}
+	e.cron = cron.New()
+	for _, r := range e.schedule {
+		// 规则已在加载时校验，这里不会出错
+		schedule, _ := cron.ParseStandard(r.trigger.Schedule)
+		e.cron.Schedule(schedule, cron.FuncJob(func() {
+			if !e.isLeader() {
+				return
+			}
+			e.audit(&storage.SkillFiring{
+				Skill:     r.skill,
+				Trigger:   r.trigger.Describe(),
+				Channel:   r.trigger.Channel,
+				SessionID: r.trigger.SessionID,
+				Detail:    time.Now().Format(time.DateTime),
+				Activated: true,
+			})
+			e.publish(context.Background(), r.trigger.Channel, r.trigger.SessionID, []string{r.skill}, r.trigger)
+		}))
+	}
+	e.cron.Start()
+}
+
+// stopCron 停止定时任务。
+func (e *Engine) stopCron() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cron != nil {
+		e.cron.Stop()
+		e.cron = nil
+	}
+}
+
+// matchEvent 判断事件类型是否匹配规则，* 结尾表示前缀匹配。
+func matchEvent(pattern, typ string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(typ, prefix)
+	}
+	return pattern == typ
+}
+
+// fireEvent 激活订阅了事件的技能。命中多个技能时只发送一条消息，由优先级最高的规则决定发送目标和内容。
+func (e *Engine) fireEvent(ctx context.Context, ev bus.Event) {
+	e.mu.RLock()
+	rules := e.events
+	e.mu.RUnlock()
+
+	var matched []candidate
+	for _, r := range rules {
+		if matchEvent(r.trigger.Event, ev.Type) {
+			matched = append(matched, candidate{rule: r, length: len(r.trigger.Event), detail: ev.Type})
+		}
+	}
+	active := e.resolve(matched, ev.Channel, ev.SessionID)
+	if len(active) == 0 {
+		return
+	}
+
+	t := matched[0].rule.trigger
+	channel, sessionID := t.Channel, t.SessionID
+	if channel == "" {
+		channel, sessionID = ev.Channel, ev.SessionID
+	}
+	e.publish(ctx, channel, sessionID, active, t)
+}
+
+// publish 将触发的技能作为低优先级消息交给智能体。
+func (e *Engine) publish(ctx context.Context, channel, sessionID string, skills []string, t storage.SkillTrigger) {
+	if channel == "" {
+		channel = chanConsts.WEBSOCKET
+	}
+	text := t.Prompt
+	if text == "" {
+		text = fmt.Sprintf(defaultPrompt, strings.Join(skills, "、"), t.Describe())
+	}
+	msg := bus.InboundMessage{
+		Channel:   channel,
+		SessionID: sessionID,
+		Text:      text,
+		Timestamp: time.Now(),
+		Priority:  bus.PriorityLow,
+		Metadata:  map[string]any{consts.META_SKILL: skills},
+	}
+	if err := e.bus.PublishInbound(ctx, msg); err != nil {
+		e.logger.With("name", "【技能触发】").Warn("发送技能触发消息失败", "error", err, "skills", skills)
+	}
+}
+
+// prune 清理过期的触发记录。
+func (e *Engine) prune() {
+	if e.cfg.AuditKeep <= 0 {
+		return
+	}
+	n, err := e.store.SkillFiring().Prune(time.Now().Add(-e.cfg.AuditKeep))
+	if err != nil {
+		e.logger.With("name", "【技能触发】").Warn("清理触发记录失败", "error", err)
+		return
+	}
+	if n > 0 {
+		e.logger.With("name", "【技能触发】").Debug("已清理过期触发记录", "count", n)
+	}
+}
@@ -0,0 +1,144 @@
+package trigger
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/bus"
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+)
+
+func newTestEngine(t *testing.T, maxActive int, skills ...*storage.Skill) (*Engine, *storage.Storage, *bus.MessageBus) {
+	t.Helper()
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "trigger.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	for _, sk := range skills {
+		if err := store.Skill().SaveSkill(sk); err != nil {
+			t.Fatalf("SaveSkill() error = %v", err)
+		}
+	}
+
+	b := bus.NewMessageBus(bus.DefaultConfig())
+	t.Cleanup(b.Close)
+	e := New(store, b, Config{MaxActive: maxActive}, nil)
+	if err := e.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	return e, store, b
+}
+
+func TestEngine_Match(t *testing.T) {
+	e, store, _ := newTestEngine(t, 1,
+		&storage.Skill{Name: "deploy", Enabled: true, Triggers: storage.SkillTriggers{
+			{Type: storage.SkillTriggerRegex, Pattern: `部署`},
+		}},
+		&storage.Skill{Name: "deploy-prod", Enabled: true, Triggers: storage.SkillTriggers{
+			{Type: storage.SkillTriggerRegex, Pattern: `部署到生产`},
+		}},
+		&storage.Skill{Name: "incident", Enabled: true, Triggers: storage.SkillTriggers{
+			{Type: storage.SkillTriggerRegex, Pattern: `故障|告警`, Priority: 10},
+			{Type: storage.SkillTriggerRegex, Pattern: `[`}, // 无效规则被跳过
+		}},
+		&storage.Skill{Name: "feishu-only", Enabled: true, Triggers: storage.SkillTriggers{
+			{Type: storage.SkillTriggerRegex, Pattern: `部署`, Channels: storage.StringArray{"feishu"}},
+		}},
+		&storage.Skill{Name: "disabled", Enabled: false, Triggers: storage.SkillTriggers{
+			{Type: storage.SkillTriggerRegex, Pattern: `.*`},
+		}},
+	)
+
+	tests := []struct {
+		name    string
+		channel string
+		text    string
+		want    []string
+	}{
+		{"single match", "websocket", "帮我部署一下", []string{"deploy"}},
+		{"longer match wins", "websocket", "部署到生产环境", []string{"deploy-prod"}},
+		{"priority wins", "websocket", "部署到生产时出现故障", []string{"incident"}},
+		{"channel restricted", "feishu", "部署", []string{"deploy"}},
+		{"no match", "websocket", "你好", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := e.Match(bus.InboundMessage{Channel: tt.channel, SessionID: "s1", Text: tt.text})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 被覆盖的技能也有触发记录
+	firings, err := store.SkillFiring().List("deploy", 0)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var suppressed int
+	for _, f := range firings {
+		if !f.Activated {
+			suppressed++
+			if f.Reason == "" {
+				t.Errorf("suppressed firing without reason: %+v", f)
+			}
+		}
+	}
+	if len(firings) != 4 || suppressed != 2 {
+		t.Errorf("deploy firings = %d, suppressed = %d", len(firings), suppressed)
+	}
+}
+
+func TestEngine_MaxActive(t *testing.T) {
+	e, _, _ := newTestEngine(t, 2,
+		&storage.Skill{Name: "a", Enabled: true, Triggers: storage.SkillTriggers{{Type: storage.SkillTriggerRegex, Pattern: `x`}}},
+		&storage.Skill{Name: "b", Enabled: true, Triggers: storage.SkillTriggers{{Type: storage.SkillTriggerRegex, Pattern: `x`, Priority: 1}}},
+		&storage.Skill{Name: "c", Enabled: true, Triggers: storage.SkillTriggers{{Type: storage.SkillTriggerRegex, Pattern: `x`}}},
+	)
+	got := e.Match(bus.InboundMessage{Channel: "websocket", Text: "x"})
+	if want := []string{"b", "a"}; !slices.Equal(got, want) {
+		t.Errorf("Match() = %v, want %v", got, want)
+	}
+}
+
+func TestEngine_FireEvent(t *testing.T) {
+	e, _, b := newTestEngine(t, 1,
+		&storage.Skill{Name: "recap", Enabled: true, Triggers: storage.SkillTriggers{
+			{Type: storage.SkillTriggerEvent, Event: bus.EventSessionClosed, Prompt: "总结刚结束的会话"},
+		}},
+		&storage.Skill{Name: "ops", Enabled: true, Triggers: storage.SkillTriggers{
+			{Type: storage.SkillTriggerEvent, Event: "provider.*", Channel: "feishu", SessionID: "ops"},
+		}},
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	e.fireEvent(ctx, bus.Event{Type: bus.EventSessionClosed, Channel: "telegram", SessionID: "u1"})
+	msg, ok := b.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no message published")
+	}
+	if msg.Channel != "telegram" || msg.SessionID != "u1" || msg.Text != "总结刚结束的会话" || msg.Priority != bus.PriorityLow {
+		t.Errorf("message = %+v", msg)
+	}
+	if skills, _ := msg.Metadata[consts.META_SKILL].([]string); !slices.Equal(skills, []string{"recap"}) {
+		t.Errorf("skills = %v", skills)
+	}
+
+	// 前缀匹配，发送到规则指定的会话
+	e.fireEvent(ctx, bus.Event{Type: bus.EventProviderOffline})
+	msg, ok = b.ConsumeInbound(ctx)
+	if !ok || msg.Channel != "feishu" || msg.SessionID != "ops" {
+		t.Errorf("message = %+v", msg)
+	}
+
+	e.fireEvent(ctx, bus.Event{Type: bus.EventFormSubmitted})
+	if s := b.Stats(); s.Low != 0 {
+		t.Errorf("unmatched event published a message: %+v", s)
+	}
+}
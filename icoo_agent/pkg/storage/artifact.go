@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	icooclawErrors "icooclaw/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// Artifact 智能体生成的制品（报告、脚本、图表等），文件保存在工作目录中，回复里按 ID 引用。
+type Artifact struct {
+	Model
+	Channel     string `gorm:"column:channel;type:varchar(50);not null;index:idx_artifact_session;comment:渠道" json:"channel"`          // 渠道
+	SessionID   string `gorm:"column:session_id;type:varchar(100);not null;index:idx_artifact_session;comment:会话ID" json:"session_id"` // 会话ID
+	UserID      string `gorm:"column:user_id;type:varchar(100);index;comment:用户ID" json:"user_id"`                                     // 生成制品时的用户
+	Name        string `gorm:"column:name;type:varchar(200);not null;comment:文件名" json:"name"`                                         // 文件名
+	Kind        string `gorm:"column:kind;type:varchar(50);comment:类型(report/script/diagram等)" json:"kind"`                            // 类型
+	MIMEType    string `gorm:"column:mime_type;type:varchar(100);comment:MIME类型" json:"mime_type"`                                     // MIME 类型
+	Description string `gorm:"column:description;type:text;comment:说明" json:"description,omitempty"`                                   // 说明
+	Path        string `gorm:"column:path;type:text;not null;comment:文件路径" json:"-"`                                                   // 文件的绝对路径，不对外暴露
+	Size        int64  `gorm:"column:size;default:0;comment:字节数" json:"size"`                                                          // 字节数
+	SHA256      string `gorm:"column:sha256;type:char(64);comment:SHA256摘要" json:"sha256"`                                             // SHA256 摘要
+}
+
+// TableName returns the table name for Artifact.
+func (Artifact) TableName() string {
+	return tableNamePrefix + "artifacts"
+}
+
+type ArtifactStorage struct {
+	db *gorm.DB
+}
+
+func NewArtifactStorage(db *gorm.DB) *ArtifactStorage {
+	return &ArtifactStorage{db: db}
+}
+
+// Create creates an artifact record.
+func (s *ArtifactStorage) Create(a *Artifact) error {
+	if result := s.db.Create(a); result.Error != nil {
+		return fmt.Errorf("failed to create artifact: %w", result.Error)
+	}
+	return nil
+}
+
+// GetByID gets an artifact by ID.
+func (s *ArtifactStorage) GetByID(id string) (*Artifact, error) {
+	var a Artifact
+	result := s.db.Where("id = ?", id).First(&a)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, icooclawErrors.ErrRecordNotFound
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get artifact: %w", result.Error)
+	}
+	return &a, nil
+}
+
+// ListBySession lists the artifacts of a session, oldest first. An empty
+// channel matches the session ID on every channel.
+func (s *ArtifactStorage) ListBySession(channel, sessionID string) ([]*Artifact, error) {
+	qry := s.db.Where("session_id = ?", sessionID)
+	if channel != "" {
+		qry = qry.Where("channel = ?", channel)
+	}
+
+	var artifacts []*Artifact
+	if result := qry.Order("created_at").Find(&artifacts); result.Error != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", result.Error)
+	}
+	return artifacts, nil
+}
+
+// Delete deletes an artifact record. The file is left to the caller.
+func (s *ArtifactStorage) Delete(id string) error {
+	if result := s.db.Where("id = ?", id).Delete(&Artifact{}); result.Error != nil {
+		return fmt.Errorf("failed to delete artifact: %w", result.Error)
+	}
+	return nil
+}
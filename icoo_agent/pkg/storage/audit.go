@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ToolAudit 工具调用审计记录。记录按 Seq 组成哈希链：Hash 覆盖记录内容和上一条记录的 Hash，
+// 修改或删除中间的记录都会使校验失败；按保留期限清理只删除最早的记录，不破坏剩余的链。
+type ToolAudit struct {
+	Model
+	Seq        int64  `gorm:"column:seq;type:bigint;uniqueIndex;comment:序号" json:"seq"`                   // 序号，从 1 递增
+	Tool       string `gorm:"column:tool;type:varchar(100);index;comment:工具名称" json:"tool"`               // 工具名称
+	Params     string `gorm:"column:params;type:text;comment:调用参数(JSON格式，已脱敏)" json:"params"`             // 调用参数
+	Status     string `gorm:"column:status;type:varchar(20);comment:结果状态" json:"status"`                  // 结果状态，见 AuditStatus*
+	ResultHash string `gorm:"column:result_hash;type:char(64);comment:结果哈希" json:"result_hash"`           // 结果内容的 SHA-256
+	Error      string `gorm:"column:error;type:text;comment:错误信息" json:"error,omitempty"`                 // 错误信息，已脱敏
+	DurationMs int64  `gorm:"column:duration_ms;type:bigint;default:0;comment:耗时(毫秒)" json:"duration_ms"` // 耗时
+	Channel    string `gorm:"column:channel;type:varchar(50);comment:渠道" json:"channel"`                  // 渠道
+	SessionID  string `gorm:"column:session_id;type:varchar(100);index;comment:会话ID" json:"session_id"`   // 会话ID
+	UserID     string `gorm:"column:user_id;type:varchar(100);index;comment:用户ID" json:"user_id"`         // 触发调用的用户
+	PrevHash   string `gorm:"column:prev_hash;type:char(64);comment:上一条记录的哈希" json:"prev_hash"`           // 上一条记录的哈希，第一条为空
+	Hash       string `gorm:"column:hash;type:char(64);comment:记录哈希" json:"hash"`                         // 本条记录的哈希
+}
+
+// Audit statuses.
+const (
+	AuditStatusSuccess = "success" // 执行成功
+	AuditStatusError   = "error"   // 执行失败或超时
+	AuditStatusDenied  = "denied"  // 被会话策略或授权钩子拒绝，未执行
+	AuditStatusSkipped = "skipped" // 只读工作目录中跳过执行
+)
+
+// TableName returns the table name for ToolAudit.
+func (ToolAudit) TableName() string {
+	return tableNamePrefix + "tool_audits"
+}
+
+// Digest computes the chained hash of the record from its content and PrevHash.
+func (a *ToolAudit) Digest() string {
+	data, _ := json.Marshal([]any{
+		a.Seq, a.CreatedAt.UnixMicro(), a.Tool, a.Params, a.Status, a.ResultHash, a.Error,
+		a.DurationMs, a.Channel, a.SessionID, a.UserID, a.PrevHash,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditFilter filters audit records. Zero fields do not filter.
+type AuditFilter struct {
+	Tool      string
+	SessionID string
+	UserID    string
+	Status    string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+	Offset    int
+}
+
+// AuditVerification is the result of verifying the audit hash chain.
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Records  int64  `json:"records"`             // 校验的记录数
+	FirstSeq int64  `json:"first_seq,omitempty"` // 最早的记录序号，之前的记录已按保留期限清理
+	LastSeq  int64  `json:"last_seq,omitempty"`
+	BrokenAt int64  `json:"broken_at,omitempty"` // 第一条校验失败的记录序号
+	Reason   string `json:"reason,omitempty"`
+}
+
+// AuditStorage stores the tool invocation audit trail.
+type AuditStorage struct {
+	db *gorm.DB
+	mu sync.Mutex // 串行追加，保证哈希链连续
+}
+
+// NewAuditStorage creates an audit storage.
+func NewAuditStorage(db *gorm.DB) *AuditStorage {
+	return &AuditStorage{db: db}
+}
+
+// Append assigns the next sequence number, chains the record to the last one
+// and saves it.
+func (s *AuditStorage) Append(a *ToolAudit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var last ToolAudit
+		err := tx.Order("seq DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return fmt.Errorf("failed to get last audit record: %w", err)
+		}
+
+		a.Seq = last.Seq + 1
+		a.PrevHash = last.Hash
+		if a.CreatedAt.IsZero() {
+			a.CreatedAt = time.Now()
+		}
+		a.CreatedAt = a.CreatedAt.Truncate(time.Microsecond)
+		a.UpdatedAt = a.CreatedAt
+		a.Hash = a.Digest()
+		if err := tx.Create(a).Error; err != nil {
+			return fmt.Errorf("failed to save audit record: %w", err)
+		}
+		return nil
+	})
+}
+
+// List lists audit records, newest first, and the total number of matching records.
+func (s *AuditStorage) List(f AuditFilter) ([]*ToolAudit, int64, error) {
+	qry := s.db.Model(&ToolAudit{})
+	if f.Tool != "" {
+		qry = qry.Where("tool = ?", f.Tool)
+	}
+	if f.SessionID != "" {
+		qry = qry.Where("session_id = ?", f.SessionID)
+	}
+	if f.UserID != "" {
+		qry = qry.Where("user_id = ?", f.UserID)
+	}
+	if f.Status != "" {
+		qry = qry.Where("status = ?", f.Status)
+	}
+	if !f.Since.IsZero() {
+		qry = qry.Where("created_at >= ?", f.Since)
+	}
+	if !f.Until.IsZero() {
+		qry = qry.Where("created_at < ?", f.Until)
+	}
+
+	var total int64
+	if err := qry.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit records: %w", err)
+	}
+
+	qry = qry.Order("seq DESC")
+	if f.Limit > 0 {
+		qry = qry.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		qry = qry.Offset(f.Offset)
+	}
+	var records []*ToolAudit
+	if err := qry.Find(&records).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit records: %w", err)
+	}
+	return records, total, nil
+}
+
+// Prune deletes records created before the given time. The newest record is
+// always kept so that new records continue the chain.
+func (s *AuditStorage) Prune(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	newest := s.db.Model(&ToolAudit{}).Select("MAX(seq)")
+	result := s.db.Where("created_at < ? AND seq < (?)", before, newest).Delete(&ToolAudit{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune audit records: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// auditVerifyBatch 校验时每批读取的记录数
+const auditVerifyBatch = 500
+
+// Verify walks the hash chain from the oldest remaining record and reports
+// the first record that was modified, or the first gap left by a deleted record.
+func (s *AuditStorage) Verify() (*AuditVerification, error) {
+	v := &AuditVerification{Valid: true}
+	var prev *ToolAudit
+	for {
+		var batch []*ToolAudit
+		qry := s.db.Order("seq ASC").Limit(auditVerifyBatch)
+		if prev != nil {
+			qry = qry.Where("seq > ?", prev.Seq)
+		}
+		if err := qry.Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to read audit records: %w", err)
+		}
+
+		for _, a := range batch {
+			if err := verifyLink(prev, a); err != nil {
+				v.Valid, v.BrokenAt, v.Reason = false, a.Seq, err.Error()
+				return v, nil
+			}
+			if prev == nil {
+				v.FirstSeq = a.Seq
+			}
+			v.Records++
+			v.LastSeq = a.Seq
+			prev = a
+		}
+		if len(batch) < auditVerifyBatch {
+			return v, nil
+		}
+	}
+}
+
+// verifyLink 校验记录自身的哈希及与上一条记录的链接。
+func verifyLink(prev, a *ToolAudit) error {
+	if a.Digest() != a.Hash {
+		return errors.New("record content does not match its hash")
+	}
+	if prev == nil {
+		return nil
+	}
+	if a.Seq != prev.Seq+1 {
+		return fmt.Errorf("records %d to %d are missing", prev.Seq+1, a.Seq-1)
+	}
+	if a.PrevHash != prev.Hash {
+		return errors.New("previous hash does not match the previous record")
+	}
+	return nil
+}
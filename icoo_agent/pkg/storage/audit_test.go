@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditStorage_Chain(t *testing.T) {
+	s, err := New(t.TempDir(), "", filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	base := time.Now().Add(-48 * time.Hour)
+	for i, tool := range []string{"read_file", "shell_command", "write_file", "shell_command"} {
+		a := &ToolAudit{Tool: tool, Params: `{"i":1}`, Status: AuditStatusSuccess, SessionID: "s1"}
+		a.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if err := s.Audit().Append(a); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		if a.Seq != int64(i+1) {
+			t.Fatalf("Seq = %d, want %d", a.Seq, i+1)
+		}
+	}
+
+	records, total, err := s.Audit().List(AuditFilter{Tool: "shell_command", Limit: 1})
+	if err != nil || total != 2 || len(records) != 1 || records[0].Seq != 4 {
+		t.Fatalf("List() = %+v, %d, %v", records, total, err)
+	}
+	if v, err := s.Audit().Verify(); err != nil || !v.Valid || v.Records != 4 {
+		t.Fatalf("Verify() = %+v, %v", v, err)
+	}
+
+	// 清理最早的记录后剩余的链仍然有效，新记录接在最后
+	if n, err := s.Audit().Prune(base.Add(90 * time.Minute)); err != nil || n != 2 {
+		t.Fatalf("Prune() = %d, %v", n, err)
+	}
+	if err := s.Audit().Append(&ToolAudit{Tool: "read_file", Status: AuditStatusError}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if v, _ := s.Audit().Verify(); !v.Valid || v.FirstSeq != 3 || v.LastSeq != 5 {
+		t.Errorf("Verify() after prune = %+v", v)
+	}
+
+	// 清理不删除最新的记录
+	if n, _ := s.Audit().Prune(time.Now().Add(time.Hour)); n != 2 {
+		t.Errorf("Prune(all) = %d, want 2", n)
+	}
+
+	// 修改内容或删除中间的记录后校验失败
+	for i := 0; i < 2; i++ {
+		if err := s.Audit().Append(&ToolAudit{Tool: "kv_get", Status: AuditStatusSuccess}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.db.Model(&ToolAudit{}).Where("seq = ?", 6).Update("params", `{"tampered":true}`)
+	if v, _ := s.Audit().Verify(); v.Valid || v.BrokenAt != 6 {
+		t.Errorf("Verify() after update = %+v", v)
+	}
+	s.db.Where("seq = ?", 6).Delete(&ToolAudit{})
+	if v, _ := s.Audit().Verify(); v.Valid || v.BrokenAt != 7 {
+		t.Errorf("Verify() after delete = %+v", v)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
+	"icooclaw/pkg/consts"
 	icooclawErrors "icooclaw/pkg/errors"
 )
 
@@ -15,6 +16,10 @@ type Binding struct {
 	Channel    string `gorm:"column:channel;type:varchar(50);not null;uniqueIndex:idx_binding;comment:渠道" json:"channel"`
 	SessionID  string `gorm:"column:session_id;type:varchar(100);not null;uniqueIndex:idx_binding;comment:会话ID" json:"session_id"`
 	AgentName  string `gorm:"column:agent_name;type:varchar(100);not null;comment:代理名称" json:"agent_name"`
+	Persona    string `gorm:"column:persona;type:varchar(100);comment:人设名称" json:"persona"`
+	Workspace  string `gorm:"column:workspace;type:varchar(100);comment:工作目录名称" json:"workspace"`
+	Timezone   string `gorm:"column:timezone;type:varchar(64);comment:时区" json:"timezone"`
+	Language   string `gorm:"column:language;type:varchar(20);comment:回复语言" json:"language"`
 	Enabled    bool   `gorm:"column:enabled;type:tinyint(1);default:true;comment:是否启用" json:"enabled"`
 }
 
@@ -35,11 +40,91 @@ func NewBindingStorage(db *gorm.DB) *BindingStorage {
 func (s *BindingStorage) SaveBinding(b *Binding) error {
 	result := s.db.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "channel"}, {Name: "session_id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"agent_name", "enabled"}),
+		DoUpdates: clause.AssignmentColumns([]string{"agent_name", "persona", "workspace", "timezone", "language", "enabled"}),
 	}).Create(b)
 	return result.Error
 }
 
+// SetPersona sets the persona of a binding, creating the binding if needed.
+// An empty sessionID sets the channel-level default persona.
+func (s *BindingStorage) SetPersona(channel, sessionID, persona string) error {
+	b := &Binding{
+		Channel:   channel,
+		SessionID: sessionID,
+		AgentName: consts.DEFAULT_AGENT_NAME,
+		Persona:   persona,
+		Enabled:   true,
+	}
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel"}, {Name: "session_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"persona"}),
+	}).Create(b)
+	if result.Error != nil {
+		return fmt.Errorf("failed to set persona: %w", result.Error)
+	}
+	return nil
+}
+
+// SetWorkspace sets the named workspace of a binding, creating the binding if needed.
+// An empty sessionID sets the channel-level default workspace.
+func (s *BindingStorage) SetWorkspace(channel, sessionID, workspace string) error {
+	b := &Binding{
+		Channel:   channel,
+		SessionID: sessionID,
+		AgentName: consts.DEFAULT_AGENT_NAME,
+		Workspace: workspace,
+		Enabled:   true,
+	}
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel"}, {Name: "session_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"workspace"}),
+	}).Create(b)
+	if result.Error != nil {
+		return fmt.Errorf("failed to set workspace: %w", result.Error)
+	}
+	return nil
+}
+
+// SetTimezone sets the timezone of a binding, creating the binding if needed.
+// An empty sessionID sets the channel-level default timezone.
+func (s *BindingStorage) SetTimezone(channel, sessionID, timezone string) error {
+	b := &Binding{
+		Channel:   channel,
+		SessionID: sessionID,
+		AgentName: consts.DEFAULT_AGENT_NAME,
+		Timezone:  timezone,
+		Enabled:   true,
+	}
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel"}, {Name: "session_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"timezone"}),
+	}).Create(b)
+	if result.Error != nil {
+		return fmt.Errorf("failed to set timezone: %w", result.Error)
+	}
+	return nil
+}
+
+// SetLanguage sets the reply language of a binding, creating the binding if needed.
+// An empty sessionID sets the channel-level default reply language.
+func (s *BindingStorage) SetLanguage(channel, sessionID, language string) error {
+	b := &Binding{
+		Channel:   channel,
+		SessionID: sessionID,
+		AgentName: consts.DEFAULT_AGENT_NAME,
+		Language:  language,
+		Enabled:   true,
+	}
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel"}, {Name: "session_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"language"}),
+	}).Create(b)
+	if result.Error != nil {
+		return fmt.Errorf("failed to set language: %w", result.Error)
+	}
+	return nil
+}
+
 // GetBinding gets a binding by channel and session ID.
 func (s *BindingStorage) GetBinding(channel, sessionID string) (*Binding, error) {
 	var b Binding
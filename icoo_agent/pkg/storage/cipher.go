@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// encryptedPrefix marks an encrypted column value: prefix + key ID + ":" + base64(nonce || ciphertext).
+// Values without the prefix are plaintext rows written before encryption was enabled.
+const encryptedPrefix = "icooclaw-enc:v1:"
+
+// KeySize is the required key length, AES-256.
+const KeySize = 32
+
+// Cipher encrypts column values with AES-GCM. New values are encrypted with
+// the current key; previous keys are kept to read rows written before a key
+// rotation until they are re-encrypted.
+type Cipher struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewCipher creates a cipher from the current key and any previous keys.
+func NewCipher(current []byte, previous ...[]byte) (*Cipher, error) {
+	c := &Cipher{aeads: make(map[string]cipher.AEAD)}
+	for i, key := range append([][]byte{current}, previous...) {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+		id := KeyID(key)
+		if i == 0 {
+			c.current = id
+		}
+		c.aeads[id] = aead
+	}
+	return c, nil
+}
+
+// KeyID returns the short identifier stored with values encrypted by key.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// CurrentKeyID returns the ID of the key used for new values.
+func (c *Cipher) CurrentKeyID() string {
+	return c.current
+}
+
+// Encrypt encrypts a value with the current key. Empty values are kept empty.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	aead := c.aeads[c.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(c.current))
+	return encryptedPrefix + c.current + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a stored value. Plaintext values are returned unchanged.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	id, data, ok := parseEncrypted(value)
+	if !ok {
+		return value, nil
+	}
+	aead, found := c.aeads[id]
+	if !found {
+		return "", fmt.Errorf("value is encrypted with unknown key %s", id)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value with key %s: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+// IsEncrypted reports whether a stored value is encrypted, and with which key.
+func IsEncrypted(value string) (keyID string, ok bool) {
+	id, _, ok := parseEncrypted(value)
+	return id, ok
+}
+
+func parseEncrypted(value string) (id, data string, ok bool) {
+	rest, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
+
+type cipherKey struct{}
+
+// withCipher attaches the cipher to the context of every statement run on db.
+func withCipher(db *gorm.DB, c *Cipher) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, cipherKey{}, c))
+}
+
+// cipherFrom returns the cipher of a statement context, nil when encryption is disabled.
+func cipherFrom(ctx context.Context) *Cipher {
+	if ctx == nil {
+		return nil
+	}
+	c, _ := ctx.Value(cipherKey{}).(*Cipher)
+	return c
+}
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// EncryptedSerializer is the GORM serializer for string columns tagged
+// serializer:encrypted. Values are encrypted on write when the storage has a
+// cipher and decrypted on read; plaintext rows are read as they are.
+type EncryptedSerializer struct{}
+
+// Scan implements schema.SerializerInterface.
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported data type for encrypted column: %T", dbValue)
+	}
+
+	if _, encrypted := IsEncrypted(value); encrypted {
+		c := cipherFrom(ctx)
+		if c == nil {
+			return fmt.Errorf("column %s is encrypted but no encryption key is configured", field.DBName)
+		}
+		plain, err := c.Decrypt(value)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", field.DBName, err)
+		}
+		value = plain
+	}
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+// Value implements schema.SerializerValuerInterface.
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	value, _ := fieldValue.(string)
+	c := cipherFrom(ctx)
+	if c == nil {
+		return value, nil
+	}
+	return c.Encrypt(value)
+}
+
+// encryptValue encrypts a value for updates that bypass the serializer, such
+// as Update with a column name.
+func encryptValue(db *gorm.DB, value string) (string, error) {
+	if c := cipherFrom(db.Statement.Context); c != nil {
+		return c.Encrypt(value)
+	}
+	return value, nil
+}
+
+// whereContentContains filters model rows whose content contains keyword.
+// Encrypted content cannot be matched in SQL, so the candidate rows are
+// decrypted and matched here, case-insensitively like SQLite LIKE.
+func whereContentContains(qry *gorm.DB, model any, keyword string) *gorm.DB {
+	c := cipherFrom(qry.Statement.Context)
+	if c == nil {
+		return qry.Where("content LIKE ?", "%"+keyword+"%")
+	}
+
+	var rows []struct {
+		ID      string
+		Content string
+	}
+	if err := qry.Session(&gorm.Session{}).Model(model).Select("id", "content").Scan(&rows).Error; err != nil {
+		qry.AddError(fmt.Errorf("failed to search encrypted content: %w", err))
+		return qry
+	}
+	keyword = strings.ToLower(keyword)
+	ids := make([]string, 0)
+	for _, row := range rows {
+		plain, err := c.Decrypt(row.Content)
+		if err != nil {
+			qry.AddError(err)
+			return qry
+		}
+		if strings.Contains(strings.ToLower(plain), keyword) {
+			ids = append(ids, row.ID)
+		}
+	}
+	return qry.Where("id IN ?", ids)
+}
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/consts"
+)
+
+func testKey(b byte) []byte { return bytes.Repeat([]byte{b}, KeySize) }
+
+// rawColumn reads a column without the serializer.
+func rawColumn(t *testing.T, s *Storage, table, column, id string) string {
+	t.Helper()
+	var row map[string]any
+	if err := s.history.Table(table).Select(column).Where("id = ?", id).Take(&row).Error; err != nil {
+		t.Fatalf("read %s.%s: %v", table, column, err)
+	}
+	return columnString(row[column])
+}
+
+func TestCipher(t *testing.T) {
+	if _, err := NewCipher([]byte("short")); err == nil {
+		t.Error("NewCipher() should reject short keys")
+	}
+	old, _ := NewCipher(testKey(1))
+	c, _ := NewCipher(testKey(2), testKey(1))
+
+	enc, err := old.Encrypt("你好")
+	if err != nil || !strings.HasPrefix(enc, encryptedPrefix+KeyID(testKey(1))+":") {
+		t.Fatalf("Encrypt() = %q, %v", enc, err)
+	}
+	if plain, err := c.Decrypt(enc); err != nil || plain != "你好" {
+		t.Errorf("Decrypt() with previous key = %q, %v", plain, err)
+	}
+	if plain, err := c.Decrypt("plain text"); err != nil || plain != "plain text" {
+		t.Errorf("Decrypt(plaintext) = %q, %v", plain, err)
+	}
+	if _, err := old.Decrypt(mustEncrypt(t, c, "x")); err == nil {
+		t.Error("Decrypt() with an unknown key should fail")
+	}
+	if enc, _ := c.Encrypt(""); enc != "" {
+		t.Errorf("Encrypt(\"\") = %q", enc)
+	}
+}
+
+func mustEncrypt(t *testing.T, c *Cipher, s string) string {
+	t.Helper()
+	enc, err := c.Encrypt(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
+
+func TestStorage_Encryption(t *testing.T) {
+	dir, path := t.TempDir(), filepath.Join(t.TempDir(), "enc.db")
+	session := consts.GetSessionKey("websocket", "s1")
+
+	// 启用加密前写入的明文记录
+	plain, err := New(dir, "", path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	oldMsg := &Message{SessionID: session, Role: consts.RoleUser, Content: "旧的明文消息"}
+	plain.Message().Save(oldMsg)
+	oldTrace := &Trace{Channel: "websocket", SessionID: "s1", Input: "旧的问题", Data: `{"prompt":"旧的轨迹"}`}
+	plain.Trace().Save(oldTrace)
+	plain.Close()
+
+	c1, _ := NewCipher(testKey(1))
+	s, err := New(dir, "", path, WithCipher(c1))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	msg := &Message{SessionID: session, Role: consts.RoleAssistant, Content: "机密回复", ToolArgs: `{"a":1}`}
+	if err := s.Message().Save(msg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	mem := &Memory{SessionID: session, Role: "user", Content: "用户的密码提示是 Blue"}
+	if err := s.Memory().Save(mem); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if raw := rawColumn(t, s, Message{}.TableName(), "content", msg.ID); !strings.HasPrefix(raw, encryptedPrefix) || strings.Contains(raw, "机密") {
+		t.Errorf("stored content = %q, want ciphertext", raw)
+	}
+
+	// 轨迹、会话摘要同样加密
+	tr := &Trace{Channel: "websocket", SessionID: "s1", Input: "机密问题", Data: `{"prompt":"机密轨迹"}`}
+	if err := s.Trace().Save(tr); err != nil {
+		t.Fatalf("Trace().Save() error = %v", err)
+	}
+	for _, col := range []string{"input", "data"} {
+		if raw := rawColumn(t, s, Trace{}.TableName(), col, tr.ID); !strings.HasPrefix(raw, encryptedPrefix) || strings.Contains(raw, "机密") {
+			t.Errorf("stored trace %s = %q, want ciphertext", col, raw)
+		}
+	}
+	if got, err := s.Trace().Get(tr.ID); err != nil || got.Data != tr.Data || got.Input != "机密问题" {
+		t.Errorf("Trace().Get() = %+v, %v", got, err)
+	}
+	sess := &Session{Model: Model{ID: "s1"}, Channel: "websocket", UserID: "u1"}
+	if err := s.Session().Save(sess); err != nil {
+		t.Fatalf("Session().Save() error = %v", err)
+	}
+	if err := s.Session().SetSummary("websocket", "s1", "机密摘要"); err != nil {
+		t.Fatalf("SetSummary() error = %v", err)
+	}
+	if raw := rawColumn(t, s, Session{}.TableName(), "summary", "s1"); !strings.HasPrefix(raw, encryptedPrefix) {
+		t.Errorf("stored summary = %q, want ciphertext", raw)
+	}
+	if got, err := s.Session().Get("s1"); err != nil || got.Summary != "机密摘要" {
+		t.Errorf("Session().Get() = %+v, %v", got, err)
+	}
+	// 实体事实和键值同样加密，事实在解密后的内容上去重
+	ent, err := s.Entity().Upsert("Alice", "person", "", nil)
+	if err != nil {
+		t.Fatalf("Entity().Upsert() error = %v", err)
+	}
+	for range 2 {
+		if err := s.Entity().AddFact(ent.ID, "机密事实", "s1"); err != nil {
+			t.Fatalf("AddFact() error = %v", err)
+		}
+	}
+	if g, err := s.Entity().Graph(ent, 0); err != nil || len(g.Facts) != 1 || g.Facts[0].Content != "机密事实" {
+		t.Fatalf("Entity().Graph() = %+v, %v", g, err)
+	} else if raw := rawColumn(t, s, EntityFact{}.TableName(), "content", g.Facts[0].ID); !strings.HasPrefix(raw, encryptedPrefix) {
+		t.Errorf("stored fact = %q, want ciphertext", raw)
+	}
+	if err := s.KV().Set("global", "token", `"机密值"`); err != nil {
+		t.Fatalf("KV().Set() error = %v", err)
+	}
+	if err := s.KV().Set("global", "token", `"新的机密值"`); err != nil {
+		t.Fatalf("KV().Set() overwrite error = %v", err)
+	}
+	if kv, err := s.KV().Get("global", "token"); err != nil || kv.Value != `"新的机密值"` {
+		t.Errorf("KV().Get() = %+v, %v", kv, err)
+	} else if raw := rawColumn(t, s, KV{}.TableName(), "value", kv.ID); !strings.HasPrefix(raw, encryptedPrefix) {
+		t.Errorf("stored kv value = %q, want ciphertext", raw)
+	}
+	// 会话元数据中保存的挂起回合同样加密
+	if err := s.Session().SetMetadata("websocket", "s1", "ask_user", map[string]string{"question": "机密问题"}); err != nil {
+		t.Fatalf("SetMetadata() error = %v", err)
+	}
+	if raw := rawColumn(t, s, Session{}.TableName(), "metadata", "s1"); !strings.HasPrefix(raw, encryptedPrefix) || strings.Contains(raw, "机密") {
+		t.Errorf("stored metadata = %q, want ciphertext", raw)
+	}
+	var pending map[string]string
+	if ok, err := s.Session().GetMetadata("websocket", "s1", "ask_user", &pending); !ok || err != nil || pending["question"] != "机密问题" {
+		t.Errorf("GetMetadata() = %v, %v, %v", pending, ok, err)
+	}
+	if raw := rawColumn(t, s, Message{}.TableName(), "tool_result", msg.ID); raw != "" {
+		t.Errorf("empty tool_result stored as %q", raw)
+	}
+	msgs, err := s.Message().Get(session, 10)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("Get() = %v, %v", msgs, err)
+	}
+	for _, m := range msgs {
+		if m.Content != "机密回复" && m.Content != "旧的明文消息" {
+			t.Errorf("Get() content = %q", m.Content)
+		}
+	}
+
+	// 加密后按关键词检索在解密后的内容上进行
+	res, err := s.Message().Page(&QueryMessage{SessionID: session, KeyWord: "机密"})
+	if err != nil || res.Page.Total != 1 || res.Records[0].ID != msg.ID {
+		t.Errorf("Page(keyword) = %+v, %v", res, err)
+	}
+	found, err := s.Memory().List(MemoryFilter{Query: "blue"})
+	if err != nil || len(found) != 1 {
+		t.Errorf("List(query) = %v, %v", found, err)
+	}
+	if err := s.Memory().UpdateContent(mem.ID, "已更新"); err != nil {
+		t.Fatalf("UpdateContent() error = %v", err)
+	}
+	if raw := rawColumn(t, s, Memory{}.TableName(), "content", mem.ID); !strings.HasPrefix(raw, encryptedPrefix) {
+		t.Errorf("UpdateContent() stored %q", raw)
+	}
+
+	// 迁移明文记录
+	results, err := s.Reencrypt(context.Background(), false, 1, nil)
+	if err != nil || results[0].Rows != 2 || results[0].Rewritten != 1 {
+		t.Fatalf("Reencrypt() = %+v, %v", results, err)
+	}
+	if raw := rawColumn(t, s, Message{}.TableName(), "content", oldMsg.ID); !strings.HasPrefix(raw, encryptedPrefix) {
+		t.Errorf("plaintext row after Reencrypt() = %q", raw)
+	}
+	if raw := rawColumn(t, s, Trace{}.TableName(), "data", oldTrace.ID); !strings.HasPrefix(raw, encryptedPrefix) {
+		t.Errorf("plaintext trace after Reencrypt() = %q", raw)
+	}
+	s.Close()
+
+	// 没有密钥时拒绝读取加密数据
+	noKey, _ := New(dir, "", path)
+	if _, err := noKey.Message().Get(session, 10); err == nil {
+		t.Error("reading encrypted rows without a key should fail")
+	}
+	noKey.Close()
+
+	// 轮换密钥：新密钥加密，旧密钥用于读取，重新加密后不再需要旧密钥
+	c2, _ := NewCipher(testKey(2), testKey(1))
+	s, _ = New(dir, "", path, WithCipher(c2))
+	if results, err := s.Reencrypt(context.Background(), false, 0, nil); err != nil || results[0].Rewritten != 2 || results[1].Rewritten != 1 {
+		t.Fatalf("Reencrypt() after rotation = %+v, %v", results, err)
+	}
+	s.Close()
+	c3, _ := NewCipher(testKey(2))
+	s, _ = New(dir, "", path, WithCipher(c3))
+	if m, err := s.Memory().FindByPrefix(mem.ID[:8]); err != nil || m.Content != "已更新" {
+		t.Errorf("read with the new key = %+v, %v", m, err)
+	}
+
+	// 解密后可以关闭加密
+	if _, err := s.Reencrypt(context.Background(), true, 0, nil); err != nil {
+		t.Fatalf("Reencrypt(decrypt) error = %v", err)
+	}
+	if raw := rawColumn(t, s, Message{}.TableName(), "content", msg.ID); raw != "机密回复" {
+		t.Errorf("content after decrypt = %q", raw)
+	}
+	s.Close()
+}
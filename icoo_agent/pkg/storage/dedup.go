@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	icooclawErrors "icooclaw/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Dedup 已接收的入站消息 ID 或请求幂等键，用于丢弃重复投递和重放重试请求的结果。
+type Dedup struct {
+	Key       string    `gorm:"column:dedup_key;type:varchar(255);primaryKey;comment:渠道与消息ID或幂等键" json:"key"` // 渠道与消息ID或幂等键
+	Channel   string    `gorm:"column:channel;type:varchar(50);not null;comment:渠道" json:"channel"`           // 渠道
+	Done      bool      `gorm:"column:done;type:tinyint(1);default:false;comment:是否已处理完成" json:"done"`        // 是否已处理完成
+	Response  string    `gorm:"column:response;type:text;comment:处理结果" json:"response"`                       // 处理结果，用于重放幂等请求
+	CreatedAt time.Time `gorm:"column:created_at;type:datetime;comment:创建时间" json:"created_at"`               // 创建时间
+	ExpiresAt time.Time `gorm:"column:expires_at;type:datetime;index;comment:过期时间" json:"expires_at"`         // 过期时间
+}
+
+// TableName returns the table name for Dedup.
+func (Dedup) TableName() string {
+	return tableNamePrefix + "dedup"
+}
+
+type DedupStorage struct {
+	db *gorm.DB
+}
+
+func NewDedupStorage(db *gorm.DB) *DedupStorage {
+	return &DedupStorage{db: db}
+}
+
+// Claim records a key for ttl. It reports true when the key was not seen
+// before (or had expired); otherwise it returns the existing record.
+func (s *DedupStorage) Claim(key, channel string, ttl time.Duration) (*Dedup, bool, error) {
+	now := time.Now()
+	if result := s.db.Where("dedup_key = ? AND expires_at < ?", key, now).Delete(&Dedup{}); result.Error != nil {
+		return nil, false, fmt.Errorf("failed to expire dedup key: %w", result.Error)
+	}
+
+	d := &Dedup{Key: key, Channel: channel, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(d)
+	if result.Error != nil {
+		return nil, false, fmt.Errorf("failed to claim dedup key: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return d, true, nil
+	}
+
+	existing, err := s.Get(key)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// Get gets a record by key.
+func (s *DedupStorage) Get(key string) (*Dedup, error) {
+	var d Dedup
+	result := s.db.Where("dedup_key = ?", key).First(&d)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, icooclawErrors.ErrRecordNotFound
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get dedup key: %w", result.Error)
+	}
+	return &d, nil
+}
+
+// Complete marks a key as processed and stores its response.
+func (s *DedupStorage) Complete(key, response string) error {
+	result := s.db.Model(&Dedup{}).Where("dedup_key = ?", key).
+		Updates(map[string]any{"done": true, "response": response})
+	if result.Error != nil {
+		return fmt.Errorf("failed to complete dedup key: %w", result.Error)
+	}
+	return nil
+}
+
+// Release deletes a key so that a retry is processed again.
+func (s *DedupStorage) Release(key string) error {
+	if result := s.db.Where("dedup_key = ?", key).Delete(&Dedup{}); result.Error != nil {
+		return fmt.Errorf("failed to release dedup key: %w", result.Error)
+	}
+	return nil
+}
+
+// Prune deletes expired keys.
+func (s *DedupStorage) Prune(now time.Time) (int64, error) {
+	result := s.db.Where("expires_at < ?", now).Delete(&Dedup{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune dedup keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,358 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	icooclawErrors "icooclaw/pkg/errors"
+)
+
+// Entity 实体模型，对话中提到的人、项目、服务等。
+type Entity struct {
+	Model
+	Name        string      `gorm:"column:name;type:varchar(200);not null;comment:名称" json:"name"`
+	Key         string      `gorm:"column:key;type:varchar(200);not null;uniqueIndex;comment:名称小写形式" json:"-"` // 用于大小写不敏感的去重
+	Type        string      `gorm:"column:type;type:varchar(50);index;comment:实体类型(person/project/service等)" json:"type"`
+	Aliases     StringArray `gorm:"column:aliases;type:text;comment:别名(逗号分隔)" json:"aliases,omitempty"`
+	Description string      `gorm:"column:description;type:text;comment:描述" json:"description,omitempty"`
+}
+
+// TableName returns the table name for Entity.
+func (Entity) TableName() string {
+	return tableNamePrefix + "entity"
+}
+
+// EntityRelation 实体关系模型，如 "Alice — owns — billing-service"。
+type EntityRelation struct {
+	Model
+	SubjectID string `gorm:"column:subject_id;type:char(36);not null;uniqueIndex:idx_entity_relation;comment:主体实体ID" json:"subject_id"`
+	Predicate string `gorm:"column:predicate;type:varchar(100);not null;uniqueIndex:idx_entity_relation;comment:关系" json:"predicate"`
+	ObjectID  string `gorm:"column:object_id;type:char(36);not null;uniqueIndex:idx_entity_relation;index;comment:客体实体ID" json:"object_id"`
+	SessionID string `gorm:"column:session_id;type:varchar(100);comment:来源会话ID" json:"session_id,omitempty"`
+}
+
+// TableName returns the table name for EntityRelation.
+func (EntityRelation) TableName() string {
+	return tableNamePrefix + "entity_relation"
+}
+
+// EntityFact 实体事实模型，关于某个实体的一条陈述。
+type EntityFact struct {
+	Model
+	EntityID  string `gorm:"column:entity_id;type:char(36);not null;index;comment:实体ID" json:"entity_id"`
+	Content   string `gorm:"column:content;type:text;not null;serializer:encrypted;comment:事实内容" json:"content"`
+	SessionID string `gorm:"column:session_id;type:varchar(100);comment:来源会话ID" json:"session_id,omitempty"`
+}
+
+// TableName returns the table name for EntityFact.
+func (EntityFact) TableName() string {
+	return tableNamePrefix + "entity_fact"
+}
+
+// RelationView 以实体名称表示的关系。
+type RelationView struct {
+	Subject   string `json:"subject"`
+	Predicate string `json:"predicate"`
+	Object    string `json:"object"`
+}
+
+// String 返回 "主体 — 关系 — 客体" 形式的关系描述。
+func (r RelationView) String() string {
+	return r.Subject + " — " + r.Predicate + " — " + r.Object
+}
+
+// EntityGraph 实体及其事实和关系。
+type EntityGraph struct {
+	Entity    *Entity        `json:"entity"`
+	Facts     []*EntityFact  `json:"facts"`
+	Relations []RelationView `json:"relations"`
+}
+
+// String 将实体图格式化为适合注入提示词的文本。
+func (g *EntityGraph) String() string {
+	var sb strings.Builder
+	sb.WriteString(g.Entity.Name)
+	if g.Entity.Type != "" {
+		fmt.Fprintf(&sb, " (%s)", g.Entity.Type)
+	}
+	if len(g.Entity.Aliases) > 0 {
+		fmt.Fprintf(&sb, "，别名：%s", strings.Join(g.Entity.Aliases, "、"))
+	}
+	sb.WriteString("\n")
+	if g.Entity.Description != "" {
+		fmt.Fprintf(&sb, "  %s\n", g.Entity.Description)
+	}
+	for _, f := range g.Facts {
+		fmt.Fprintf(&sb, "  - %s\n", f.Content)
+	}
+	for _, r := range g.Relations {
+		fmt.Fprintf(&sb, "  - %s\n", r)
+	}
+	return sb.String()
+}
+
+// entityMinMention 参与提及匹配的名称最少字符数，避免过短的名称误匹配
+const entityMinMention = 2
+
+type EntityStorage struct {
+	db *gorm.DB
+}
+
+func NewEntityStorage(db *gorm.DB) *EntityStorage {
+	return &EntityStorage{db: db}
+}
+
+// entityKey 返回实体名称的去重键。
+func entityKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Upsert creates an entity or merges type, aliases and description into the
+// existing one. Entities are matched case-insensitively by name or alias.
+func (s *EntityStorage) Upsert(name, entityType, description string, aliases []string) (*Entity, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("entity name is required")
+	}
+
+	e, err := s.Find(name)
+	if err != nil && err != icooclawErrors.ErrRecordNotFound {
+		return nil, err
+	}
+	if e == nil {
+		e = &Entity{Name: name, Key: entityKey(name), Type: entityType, Description: description}
+		e.Aliases = mergeAliases(e, aliases)
+		if err := s.db.Create(e).Error; err != nil {
+			return nil, fmt.Errorf("failed to create entity: %w", err)
+		}
+		return e, nil
+	}
+
+	updates := map[string]any{}
+	if e.Type == "" && entityType != "" {
+		e.Type = entityType
+		updates["type"] = entityType
+	}
+	if e.Description == "" && description != "" {
+		e.Description = description
+		updates["description"] = description
+	}
+	if merged := mergeAliases(e, append(slices.Clone(aliases), name)); !slices.Equal(merged, e.Aliases) {
+		e.Aliases = merged
+		updates["aliases"] = merged
+	}
+	if len(updates) > 0 {
+		if err := s.db.Model(&Entity{}).Where("id = ?", e.ID).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update entity: %w", err)
+		}
+	}
+	return e, nil
+}
+
+// mergeAliases 合并别名，去掉与实体名称相同或重复的别名。
+func mergeAliases(e *Entity, aliases []string) StringArray {
+	result := slices.Clone(e.Aliases)
+	for _, a := range aliases {
+		a = strings.TrimSpace(strings.ReplaceAll(a, ",", " "))
+		if a == "" || entityKey(a) == e.Key {
+			continue
+		}
+		if slices.ContainsFunc(result, func(x string) bool { return entityKey(x) == entityKey(a) }) {
+			continue
+		}
+		result = append(result, a)
+	}
+	return result
+}
+
+// Find finds an entity by name or alias, case-insensitively.
+func (s *EntityStorage) Find(name string) (*Entity, error) {
+	key := entityKey(name)
+	var e Entity
+	result := s.db.Where("key = ?", key).First(&e)
+	if result.Error == nil {
+		return &e, nil
+	}
+	if result.Error != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to find entity: %w", result.Error)
+	}
+
+	// 按别名查找
+	var candidates []*Entity
+	if err := s.db.Where("LOWER(',' || aliases || ',') LIKE ?", "%,"+key+",%").Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to find entity: %w", err)
+	}
+	if len(candidates) == 0 {
+		return nil, icooclawErrors.ErrRecordNotFound
+	}
+	return candidates[0], nil
+}
+
+// List lists all entities, optionally filtered by type.
+func (s *EntityStorage) List(entityType string) ([]*Entity, error) {
+	var entities []*Entity
+	qry := s.db.Model(&Entity{})
+	if entityType != "" {
+		qry = qry.Where("type = ?", entityType)
+	}
+	if err := qry.Order("name").Find(&entities).Error; err != nil {
+		return nil, fmt.Errorf("failed to list entities: %w", err)
+	}
+	return entities, nil
+}
+
+// AddFact adds a fact to an entity, ignoring exact duplicates.
+func (s *EntityStorage) AddFact(entityID, content, sessionID string) error {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil
+	}
+
+	// 内容可能加密存储，在解密后的内容上去重
+	var facts []*EntityFact
+	if err := s.db.Select("content").Where("entity_id = ?", entityID).Find(&facts).Error; err != nil {
+		return fmt.Errorf("failed to check entity fact: %w", err)
+	}
+	if slices.ContainsFunc(facts, func(f *EntityFact) bool { return f.Content == content }) {
+		return nil
+	}
+	if err := s.db.Create(&EntityFact{EntityID: entityID, Content: content, SessionID: sessionID}).Error; err != nil {
+		return fmt.Errorf("failed to create entity fact: %w", err)
+	}
+	return nil
+}
+
+// AddRelation links two entities, ignoring duplicates.
+func (s *EntityStorage) AddRelation(subjectID, predicate, objectID, sessionID string) error {
+	predicate = strings.TrimSpace(predicate)
+	if predicate == "" || subjectID == objectID {
+		return nil
+	}
+
+	var count int64
+	if err := s.db.Model(&EntityRelation{}).
+		Where("subject_id = ? AND predicate = ? AND object_id = ?", subjectID, predicate, objectID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check entity relation: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	rel := &EntityRelation{SubjectID: subjectID, Predicate: predicate, ObjectID: objectID, SessionID: sessionID}
+	if err := s.db.Create(rel).Error; err != nil {
+		return fmt.Errorf("failed to create entity relation: %w", err)
+	}
+	return nil
+}
+
+// Graph returns an entity with its latest facts and all relations in both
+// directions. factLimit <= 0 returns all facts.
+func (s *EntityStorage) Graph(e *Entity, factLimit int) (*EntityGraph, error) {
+	g := &EntityGraph{Entity: e}
+
+	qry := s.db.Where("entity_id = ?", e.ID).Order("created_at DESC")
+	if factLimit > 0 {
+		qry = qry.Limit(factLimit)
+	}
+	if err := qry.Find(&g.Facts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list entity facts: %w", err)
+	}
+
+	var relations []*EntityRelation
+	if err := s.db.Where("subject_id = ? OR object_id = ?", e.ID, e.ID).Order("created_at").Find(&relations).Error; err != nil {
+		return nil, fmt.Errorf("failed to list entity relations: %w", err)
+	}
+	if len(relations) == 0 {
+		return g, nil
+	}
+
+	ids := make([]string, 0, len(relations)*2)
+	for _, r := range relations {
+		ids = append(ids, r.SubjectID, r.ObjectID)
+	}
+	var linked []*Entity
+	if err := s.db.Where("id IN ?", ids).Find(&linked).Error; err != nil {
+		return nil, fmt.Errorf("failed to list related entities: %w", err)
+	}
+	names := make(map[string]string, len(linked))
+	for _, l := range linked {
+		names[l.ID] = l.Name
+	}
+	for _, r := range relations {
+		subject, object := names[r.SubjectID], names[r.ObjectID]
+		if subject == "" || object == "" {
+			continue
+		}
+		g.Relations = append(g.Relations, RelationView{Subject: subject, Predicate: r.Predicate, Object: object})
+	}
+	return g, nil
+}
+
+// Mentioned returns the entities whose name or alias appears in text,
+// at most limit entities (limit <= 0 means no limit).
+func (s *EntityStorage) Mentioned(text string, limit int) ([]*Entity, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	entities, err := s.List("")
+	if err != nil {
+		return nil, err
+	}
+	return matchMentions(entities, text, limit), nil
+}
+
+// matchMentions 返回名称或别名出现在文本中的实体，名称越长越优先。
+func matchMentions(entities []*Entity, text string, limit int) []*Entity {
+	lower := strings.ToLower(text)
+
+	type match struct {
+		entity *Entity
+		length int
+	}
+	var matches []match
+	for _, e := range entities {
+		best := 0
+		for _, name := range append([]string{e.Name}, e.Aliases...) {
+			if utf8.RuneCountInString(name) < entityMinMention {
+				continue
+			}
+			if strings.Contains(lower, entityKey(name)) {
+				best = max(best, len(name))
+			}
+		}
+		if best > 0 {
+			matches = append(matches, match{entity: e, length: best})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b match) int { return b.length - a.length })
+	result := make([]*Entity, 0, len(matches))
+	for _, m := range matches {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		result = append(result, m.entity)
+	}
+	return result
+}
+
+// Delete deletes an entity with its facts and relations.
+func (s *EntityStorage) Delete(id string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("entity_id = ?", id).Delete(&EntityFact{}).Error; err != nil {
+			return fmt.Errorf("failed to delete entity facts: %w", err)
+		}
+		if err := tx.Where("subject_id = ? OR object_id = ?", id, id).Delete(&EntityRelation{}).Error; err != nil {
+			return fmt.Errorf("failed to delete entity relations: %w", err)
+		}
+		if err := tx.Where("id = ?", id).Delete(&Entity{}).Error; err != nil {
+			return fmt.Errorf("failed to delete entity: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchMentions(t *testing.T) {
+	entities := []*Entity{
+		{Model: Model{ID: "1"}, Name: "Alice", Key: "alice"},
+		{Model: Model{ID: "2"}, Name: "billing-service", Key: "billing-service", Aliases: StringArray{"计费服务"}},
+		{Model: Model{ID: "3"}, Name: "billing", Key: "billing"},
+		{Model: Model{ID: "4"}, Name: "X", Key: "x"},
+	}
+
+	got := matchMentions(entities, "alice 负责的计费服务 和 billing 有什么关系？x", 0)
+	ids := make([]string, 0, len(got))
+	for _, e := range got {
+		ids = append(ids, e.ID)
+	}
+	if want := []string{"2", "3", "1"}; !slices.Equal(ids, want) {
+		t.Errorf("matchMentions() = %v, want %v", ids, want)
+	}
+
+	if got := matchMentions(entities, "alice and billing-service", 1); len(got) != 1 || got[0].ID != "2" {
+		t.Errorf("matchMentions() with limit should keep the longest match")
+	}
+}
+
+func TestMergeAliases(t *testing.T) {
+	e := &Entity{Name: "Alice", Key: "alice", Aliases: StringArray{"Ali"}}
+	got := mergeAliases(e, []string{"ALI", "alice", " Lis ", "", "a,b"})
+	if want := (StringArray{"Ali", "Lis", "a b"}); !slices.Equal(got, want) {
+		t.Errorf("mergeAliases() = %v, want %v", got, want)
+	}
+}
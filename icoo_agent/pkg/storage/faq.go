@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	icooclawErrors "icooclaw/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// FAQ 常见问题，命中时直接回复 Answer，不调用模型。
+type FAQ struct {
+	Model
+	Question string      `gorm:"column:question;type:text;not null;comment:问题" json:"question"`                  // 问题
+	Aliases  StringArray `gorm:"column:aliases;type:text;serializer:json;comment:其他问法(JSON数组)" json:"aliases"`   // 同一问题的其他问法
+	Answer   string      `gorm:"column:answer;type:text;not null;comment:回复内容" json:"answer"`                    // 回复内容，{{key}} 引用变量
+	Channels StringArray `gorm:"column:channels;type:text;serializer:json;comment:适用渠道(JSON数组)" json:"channels"` // 适用渠道，为空表示全部
+	Enabled  bool        `gorm:"column:enabled;type:tinyint(1);default:true;comment:是否启用" json:"enabled"`        // 是否启用
+	Hits     int64       `gorm:"column:hits;default:0;comment:命中次数" json:"hits"`                                 // 命中次数
+	Source   string      `gorm:"column:source;type:varchar(36);comment:来源消息ID" json:"source,omitempty"`          // 从智能体回复提升时的来源消息ID
+}
+
+// TableName returns the table name for FAQ.
+func (FAQ) TableName() string {
+	return tableNamePrefix + "faqs"
+}
+
+type QueryFAQ struct {
+	Page    Page   `json:"page"`
+	KeyWord string `json:"key_word"`
+	Enabled *bool  `json:"enabled"`
+}
+
+type ResQueryFAQ struct {
+	Page    Page   `json:"page"`
+	Records []*FAQ `json:"records"`
+}
+
+type FAQStorage struct {
+	db *gorm.DB
+}
+
+func NewFAQStorage(db *gorm.DB) *FAQStorage {
+	return &FAQStorage{db: db}
+}
+
+// Create creates a FAQ entry.
+func (s *FAQStorage) Create(f *FAQ) error {
+	if result := s.db.Create(f); result.Error != nil {
+		return fmt.Errorf("failed to create faq: %w", result.Error)
+	}
+	return nil
+}
+
+// Update updates a FAQ entry.
+func (s *FAQStorage) Update(f *FAQ) error {
+	if result := s.db.Save(f); result.Error != nil {
+		return fmt.Errorf("failed to update faq: %w", result.Error)
+	}
+	return nil
+}
+
+// Delete deletes a FAQ entry by ID.
+func (s *FAQStorage) Delete(id string) error {
+	if result := s.db.Where("id = ?", id).Delete(&FAQ{}); result.Error != nil {
+		return fmt.Errorf("failed to delete faq: %w", result.Error)
+	}
+	return nil
+}
+
+// GetByID gets a FAQ entry by ID.
+func (s *FAQStorage) GetByID(id string) (*FAQ, error) {
+	var f FAQ
+	result := s.db.Where("id = ?", id).First(&f)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, icooclawErrors.ErrRecordNotFound
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get faq: %w", result.Error)
+	}
+	return &f, nil
+}
+
+// ListEnabled lists all enabled FAQ entries, oldest first.
+func (s *FAQStorage) ListEnabled() ([]*FAQ, error) {
+	var list []*FAQ
+	if result := s.db.Where("enabled = ?", true).Order("created_at").Find(&list); result.Error != nil {
+		return nil, fmt.Errorf("failed to list faqs: %w", result.Error)
+	}
+	return list, nil
+}
+
+// Hit increments the hit counter of a FAQ entry.
+func (s *FAQStorage) Hit(id string) error {
+	result := s.db.Model(&FAQ{}).Where("id = ?", id).UpdateColumn("hits", gorm.Expr("hits + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("failed to update faq hits: %w", result.Error)
+	}
+	return nil
+}
+
+// Page pages FAQ entries, most hit first.
+func (s *FAQStorage) Page(query *QueryFAQ) (*ResQueryFAQ, error) {
+	var res ResQueryFAQ
+
+	qry := s.db.Model(&FAQ{})
+	if query.KeyWord != "" {
+		like := "%" + query.KeyWord + "%"
+		qry = qry.Where("question LIKE ? OR aliases LIKE ? OR answer LIKE ?", like, like, like)
+	}
+	if query.Enabled != nil {
+		qry = qry.Where("enabled = ?", *query.Enabled)
+	}
+	qry = qry.Order("hits DESC, created_at DESC")
+
+	if result := qry.Count(&res.Page.Total); result.Error != nil {
+		return nil, fmt.Errorf("failed to count faqs: %w", result.Error)
+	}
+
+	var result *gorm.DB
+	if query.Page.Page == 0 || query.Page.Size == 0 {
+		result = qry.Find(&res.Records)
+	} else {
+		result = qry.Limit(query.Page.Size).
+			Offset((query.Page.Page - 1) * query.Page.Size).
+			Find(&res.Records)
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get faqs: %w", result.Error)
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// HistoryImportResult 历史导入结果。
+type HistoryImportResult struct {
+	SessionCreated bool  `json:"session_created"` // 是否新建了会话
+	Messages       int64 `json:"messages"`        // 新导入的消息数
+	Memories       int64 `json:"memories"`        // 新导入的记忆数
+}
+
+// ImportHistory imports a conversation from before icooclaw was deployed.
+// The session is created if missing and its last active time moved forward to
+// sess.LastActive when later. Messages and memories whose IDs already exist are
+// skipped, so importing the same export twice is a no-op.
+func (s *Storage) ImportHistory(sess *Session, messages []*Message, memories []*Memory) (*HistoryImportResult, error) {
+	if sess.ID == "" || sess.Channel == "" {
+		return nil, fmt.Errorf("channel and session id are required")
+	}
+
+	res := &HistoryImportResult{}
+	skip := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoNothing: true}
+
+	err := s.transaction(func(tx, htx *gorm.DB) error {
+		// 直接写入而不经过 SessionStorage.Save，保留历史的最后活跃时间
+		result := tx.Clauses(skip).Create(sess)
+		if result.Error != nil {
+			return fmt.Errorf("failed to create session: %w", result.Error)
+		}
+		res.SessionCreated = result.RowsAffected > 0
+		if !res.SessionCreated {
+			err := tx.Model(&Session{}).
+				Where("channel = ? AND id = ? AND last_active < ?", sess.Channel, sess.ID, sess.LastActive).
+				Update("last_active", sess.LastActive).Error
+			if err != nil {
+				return fmt.Errorf("failed to update session: %w", err)
+			}
+		}
+
+		if len(messages) > 0 {
+			result := htx.Clauses(skip).CreateInBatches(messages, 100)
+			if result.Error != nil {
+				return fmt.Errorf("failed to import messages: %w", result.Error)
+			}
+			res.Messages = result.RowsAffected
+		}
+
+		if len(memories) > 0 {
+			result := htx.Clauses(skip).CreateInBatches(memories, 100)
+			if result.Error != nil {
+				return fmt.Errorf("failed to import memories: %w", result.Error)
+			}
+			res.Memories = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// HistoryMoveResult counts the rows of one table moved by MoveHistory.
+type HistoryMoveResult struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+// MoveHistory moves the messages and memories left in the operational
+// database into the history database in batches, after history has been
+// configured to be stored separately. Rows are copied as stored, so
+// encrypted content stays encrypted. Each batch is deleted from the
+// operational database only after it was written to the history database,
+// and rows already there are skipped, so it can be re-run after an interruption.
+func (s *Storage) MoveHistory(ctx context.Context, batchSize int, progress func(HistoryMoveResult)) ([]HistoryMoveResult, error) {
+	if !s.SplitHistory() {
+		return nil, fmt.Errorf("history database is not configured")
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	skip := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoNothing: true}
+	results := make([]HistoryMoveResult, 0, len(historyModels))
+	for _, model := range []schema.Tabler{Message{}, Memory{}} {
+		table := model.TableName()
+		res := HistoryMoveResult{Table: table}
+		// 旧数据库中可能没有该表，例如拆分后才创建的数据库
+		if !s.db.Migrator().HasTable(table) {
+			results = append(results, res)
+			continue
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			var rows []map[string]any
+			if err := s.db.Table(table).Order("id").Limit(batchSize).Find(&rows).Error; err != nil {
+				return results, fmt.Errorf("failed to read %s: %w", table, err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+
+			if err := s.history.Table(table).Clauses(skip).Create(rows).Error; err != nil {
+				return results, fmt.Errorf("failed to copy %s: %w", table, err)
+			}
+			ids := make([]string, 0, len(rows))
+			for _, row := range rows {
+				ids = append(ids, columnString(row["id"]))
+			}
+			if err := s.db.Table(table).Where("id IN ?", ids).Delete(nil).Error; err != nil {
+				return results, fmt.Errorf("failed to delete %s: %w", table, err)
+			}
+
+			res.Rows += int64(len(rows))
+			if progress != nil {
+				progress(res)
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
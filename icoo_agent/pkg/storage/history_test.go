@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"icooclaw/pkg/consts"
+)
+
+func TestStorage_SplitHistory(t *testing.T) {
+	dir := t.TempDir()
+	mainPath, historyPath := filepath.Join(dir, "main.db"), filepath.Join(dir, "history.db")
+	session := consts.GetSessionKey("telegram", "a")
+
+	// 拆分前写入同一个数据库的记录
+	single, err := New(dir, "", mainPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if single.SplitHistory() {
+		t.Error("SplitHistory() without a history path = true")
+	}
+	single.db.Create(&Session{Model: Model{ID: "a"}, Channel: "telegram", UserID: "u1"})
+	single.Message().Save(&Message{SessionID: session, Role: consts.RoleUser, Content: "旧消息"})
+	single.Memory().Save(&Memory{SessionID: session, Content: "旧记忆"})
+	single.Close()
+
+	s, err := New(dir, "", mainPath, WithHistoryPath(historyPath))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if !s.SplitHistory() {
+		t.Fatal("SplitHistory() = false")
+	}
+	if !s.db.Migrator().HasTable(&Session{}) || s.history.Migrator().HasTable(&Session{}) {
+		t.Error("sessions should only be in the main database")
+	}
+
+	results, err := s.MoveHistory(context.Background(), 1, nil)
+	if err != nil || len(results) != 2 || results[0].Rows != 1 || results[1].Rows != 1 {
+		t.Fatalf("MoveHistory() = %+v, %v", results, err)
+	}
+	var left int64
+	s.db.Table(Message{}.TableName()).Count(&left)
+	if left != 0 {
+		t.Errorf("messages left in the main database = %d", left)
+	}
+	if results, err := s.MoveHistory(context.Background(), 0, nil); err != nil || results[0].Rows != 0 {
+		t.Errorf("MoveHistory() again = %+v, %v", results, err)
+	}
+
+	s.Message().Save(&Message{SessionID: session, Role: consts.RoleAssistant, Content: "新回复"})
+	if msgs, err := s.Message().Get(session, 10); err != nil || len(msgs) != 2 {
+		t.Fatalf("Get() = %v, %v", msgs, err)
+	}
+
+	// 跨库的会话重置和用户数据删除
+	res, err := s.ResetSession("telegram", "a", ResetOptions{})
+	if err != nil || res.Messages != 2 || res.Memories != 1 {
+		t.Fatalf("ResetSession() = %+v, %v", res, err)
+	}
+	data, err := s.CollectUserData("u1")
+	if err != nil || len(data.Sessions) != 2 || len(data.Messages) != 2 || len(data.Memories) != 1 {
+		t.Fatalf("CollectUserData() = %+v, %v", data, err)
+	}
+	if err := s.DeleteUserData(data); err != nil {
+		t.Fatalf("DeleteUserData() error = %v", err)
+	}
+	var count int64
+	s.history.Model(&Message{}).Count(&count)
+	if count != 0 {
+		t.Errorf("messages after DeleteUserData() = %d", count)
+	}
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	icooclawErrors "icooclaw/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// 后台作业状态
+const (
+	JobPending   = "pending"   // 等待执行
+	JobRunning   = "running"   // 执行中
+	JobPaused    = "paused"    // 已暂停，恢复后从游标处继续
+	JobCompleted = "completed" // 已完成
+	JobFailed    = "failed"    // 失败，恢复后从游标处重试
+	JobCanceled  = "canceled"  // 已取消
+)
+
+// Job 批量处理的后台作业，按记录 ID 升序分批处理，每批完成后保存游标和进度，
+// 进程重启或暂停后从游标处继续。
+type Job struct {
+	Model
+	Type       string    `gorm:"column:type;type:varchar(50);not null;index;comment:作业类型" json:"type"`     // 作业类型
+	Status     string    `gorm:"column:status;type:varchar(20);not null;index;comment:作业状态" json:"status"` // 作业状态
+	Params     string    `gorm:"column:params;type:text;comment:作业参数(JSON格式)" json:"params,omitempty"`     // 作业参数
+	Cursor     string    `gorm:"column:cursor;type:varchar(100);comment:最后处理的记录ID" json:"cursor"`          // 最后处理的记录ID
+	Total      int64     `gorm:"column:total;default:0;comment:待处理总数" json:"total"`                        // 待处理总数，开始执行时统计
+	Processed  int64     `gorm:"column:processed;default:0;comment:已处理数" json:"processed"`                 // 已处理数
+	Failed     int64     `gorm:"column:failed;default:0;comment:失败数" json:"failed"`                        // 失败数
+	Error      string    `gorm:"column:error;type:text;comment:最近的错误" json:"error,omitempty"`              // 最近的错误
+	Owner      string    `gorm:"column:owner;type:varchar(128);comment:执行实例ID" json:"owner,omitempty"`     // 执行实例ID
+	StartedAt  time.Time `gorm:"column:started_at;type:datetime;comment:开始时间" json:"started_at"`           // 开始时间
+	FinishedAt time.Time `gorm:"column:finished_at;type:datetime;comment:结束时间" json:"finished_at"`         // 结束时间
+}
+
+// TableName returns the table name for Job.
+func (Job) TableName() string {
+	return tableNamePrefix + "jobs"
+}
+
+// Progress 返回完成百分比，总数未知时为 0。
+func (j *Job) Progress() float64 {
+	if j.Total <= 0 {
+		return 0
+	}
+	return float64(j.Processed+j.Failed) * 100 / float64(j.Total)
+}
+
+type QueryJob struct {
+	Page   Page   `json:"page"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+type ResQueryJob struct {
+	Page    Page   `json:"page"`
+	Records []*Job `json:"records"`
+}
+
+type JobStorage struct {
+	db *gorm.DB
+}
+
+func NewJobStorage(db *gorm.DB) *JobStorage {
+	return &JobStorage{db: db}
+}
+
+// Create creates a job.
+func (s *JobStorage) Create(j *Job) error {
+	if result := s.db.Create(j); result.Error != nil {
+		return fmt.Errorf("failed to create job: %w", result.Error)
+	}
+	return nil
+}
+
+// Get gets a job by ID.
+func (s *JobStorage) Get(id string) (*Job, error) {
+	var j Job
+	result := s.db.Where("id = ?", id).First(&j)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, icooclawErrors.ErrRecordNotFound
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get job: %w", result.Error)
+	}
+	return &j, nil
+}
+
+// Save saves a job.
+func (s *JobStorage) Save(j *Job) error {
+	if result := s.db.Save(j); result.Error != nil {
+		return fmt.Errorf("failed to save job: %w", result.Error)
+	}
+	return nil
+}
+
+// SaveProgress saves the cursor and counters of a job. It reports false
+// when the job is no longer running, e.g. it was paused or canceled meanwhile.
+func (s *JobStorage) SaveProgress(j *Job) (bool, error) {
+	result := s.db.Model(&Job{}).
+		Where("id = ? AND status = ?", j.ID, JobRunning).
+		Updates(map[string]any{
+			"cursor":     j.Cursor,
+			"total":      j.Total,
+			"processed":  j.Processed,
+			"failed":     j.Failed,
+			"error":      j.Error,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to save job progress: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Transition changes the status of a job from one of the given states. It
+// reports false when the job is not in any of them.
+func (s *JobStorage) Transition(id, status string, from ...string) (bool, error) {
+	updates := map[string]any{"status": status, "updated_at": time.Now()}
+	switch status {
+	case JobCompleted, JobFailed, JobCanceled:
+		updates["finished_at"] = time.Now()
+	case JobPending:
+		updates["error"] = ""
+	}
+	result := s.db.Model(&Job{}).Where("id = ? AND status IN ?", id, from).Updates(updates)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update job status: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Claim marks the oldest pending job as running by owner and returns it, or
+// nil when there is none.
+func (s *JobStorage) Claim(owner string) (*Job, error) {
+	for {
+		var j Job
+		result := s.db.Where("status = ?", JobPending).Order("created_at").First(&j)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to claim job: %w", result.Error)
+		}
+
+		now := time.Now()
+		updates := map[string]any{"status": JobRunning, "owner": owner, "updated_at": now}
+		if j.StartedAt.IsZero() {
+			updates["started_at"] = now
+		}
+		// 条件更新保证多个实例只有一个认领成功
+		result = s.db.Model(&Job{}).Where("id = ? AND status = ?", j.ID, JobPending).Updates(updates)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to claim job: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return s.Get(j.ID)
+		}
+	}
+}
+
+// Requeue returns running jobs not owned by owner to pending, used when a
+// runner starts to resume jobs interrupted by a restart or a failed instance.
+func (s *JobStorage) Requeue(owner string) (int64, error) {
+	result := s.db.Model(&Job{}).
+		Where("status = ? AND owner <> ?", JobRunning, owner).
+		Update("status", JobPending)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to requeue jobs: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// Page pages jobs, newest first.
+func (s *JobStorage) Page(query *QueryJob) (*ResQueryJob, error) {
+	var res ResQueryJob
+
+	qry := s.db.Model(&Job{})
+	if query.Type != "" {
+		qry = qry.Where("type = ?", query.Type)
+	}
+	if query.Status != "" {
+		qry = qry.Where("status = ?", query.Status)
+	}
+	qry = qry.Order("created_at DESC")
+
+	if result := qry.Count(&res.Page.Total); result.Error != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", result.Error)
+	}
+
+	var result *gorm.DB
+	if query.Page.Page == 0 || query.Page.Size == 0 {
+		result = qry.Find(&res.Records)
+	} else {
+		result = qry.Limit(query.Page.Size).
+			Offset((query.Page.Page - 1) * query.Page.Size).
+			Find(&res.Records)
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", result.Error)
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	icooclawErrors "icooclaw/pkg/errors"
+)
+
+// KV 键值存储模型，用于工具和智能体跨会话持久化少量结构化状态。
+type KV struct {
+	Model
+	Scope string `gorm:"column:scope;type:varchar(200);not null;uniqueIndex:idx_kv;comment:作用域" json:"scope"` // 作用域，如 global、user:<channel>:<user_id>
+	Key   string `gorm:"column:key;type:varchar(200);not null;uniqueIndex:idx_kv;comment:键" json:"key"`       // 键
+	Value string `gorm:"column:value;type:text;serializer:encrypted;comment:值(JSON格式)" json:"value"`                               // 值（JSON 格式）
+}
+
+// TableName returns the table name for KV.
+func (KV) TableName() string {
+	return tableNamePrefix + "kv"
+}
+
+type KVStorage struct {
+	db *gorm.DB
+}
+
+func NewKVStorage(db *gorm.DB) *KVStorage {
+	return &KVStorage{db: db}
+}
+
+// Set creates or updates a value by scope and key.
+func (s *KVStorage) Set(scope, key, value string) error {
+	kv := &KV{Scope: scope, Key: key, Value: value}
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(kv)
+	if result.Error != nil {
+		return fmt.Errorf("failed to set kv: %w", result.Error)
+	}
+	return nil
+}
+
+// Get gets a value by scope and key.
+func (s *KVStorage) Get(scope, key string) (*KV, error) {
+	var kv KV
+	result := s.db.Where("scope = ? AND key = ?", scope, key).First(&kv)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, icooclawErrors.ErrRecordNotFound
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get kv: %w", result.Error)
+	}
+	return &kv, nil
+}
+
+// List lists values in a scope, optionally filtered by key prefix.
+func (s *KVStorage) List(scope, prefix string) ([]*KV, error) {
+	var items []*KV
+	qry := s.db.Where("scope = ?", scope)
+	if prefix != "" {
+		qry = qry.Where("key LIKE ?", prefix+"%")
+	}
+	result := qry.Order("key").Find(&items)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list kv: %w", result.Error)
+	}
+	return items, nil
+}
+
+// Delete deletes a value by scope and key.
+func (s *KVStorage) Delete(scope, key string) error {
+	result := s.db.Where("scope = ? AND key = ?", scope, key).Delete(&KV{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete kv: %w", result.Error)
+	}
+	return nil
+}
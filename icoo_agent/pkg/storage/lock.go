@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	icooclawErrors "icooclaw/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Lock 带租约的分布式锁，多个实例共享数据库时保证同名任务同一时刻只由一个实例执行。
+// 持有者需要在租约到期前续约，实例宕机后租约过期，其他实例即可接管。
+type Lock struct {
+	Name       string    `gorm:"column:name;type:varchar(128);primaryKey;comment:锁名称" json:"name"`       // 锁名称
+	Owner      string    `gorm:"column:owner;type:varchar(128);not null;comment:持有者实例ID" json:"owner"`   // 持有者实例ID
+	AcquiredAt time.Time `gorm:"column:acquired_at;type:datetime;comment:获得时间" json:"acquired_at"`       // 获得时间
+	ExpiresAt  time.Time `gorm:"column:expires_at;type:datetime;index;comment:租约到期时间" json:"expires_at"` // 租约到期时间
+}
+
+// TableName returns the table name for Lock.
+func (Lock) TableName() string {
+	return tableNamePrefix + "locks"
+}
+
+type LockStorage struct {
+	db *gorm.DB
+}
+
+func NewLockStorage(db *gorm.DB) *LockStorage {
+	return &LockStorage{db: db}
+}
+
+// Acquire acquires or renews the lock for owner until now+ttl. It reports
+// true when owner holds the lock afterwards, i.e. the lock was free, had
+// expired, or was already held by owner.
+func (s *LockStorage) Acquire(name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	l := &Lock{Name: name, Owner: owner, AcquiredAt: now, ExpiresAt: now.Add(ttl)}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(l)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	// 续约自己持有的锁，或接管已过期的锁；条件更新保证只有一个实例成功
+	result = s.db.Model(&Lock{}).
+		Where("name = ? AND (owner = ? OR expires_at < ?)", name, owner, now).
+		Updates(map[string]any{
+			"owner":       owner,
+			"expires_at":  now.Add(ttl),
+			"acquired_at": gorm.Expr("CASE WHEN owner = ? THEN acquired_at ELSE ? END", owner, now),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Release releases the lock if it is held by owner.
+func (s *LockStorage) Release(name, owner string) error {
+	if result := s.db.Where("name = ? AND owner = ?", name, owner).Delete(&Lock{}); result.Error != nil {
+		return fmt.Errorf("failed to release lock: %w", result.Error)
+	}
+	return nil
+}
+
+// Get gets a lock by name.
+func (s *LockStorage) Get(name string) (*Lock, error) {
+	var l Lock
+	result := s.db.Where("name = ?", name).First(&l)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, icooclawErrors.ErrRecordNotFound
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get lock: %w", result.Error)
+	}
+	return &l, nil
+}
+
+// List lists all locks.
+func (s *LockStorage) List() ([]*Lock, error) {
+	var locks []*Lock
+	if result := s.db.Order("name").Find(&locks); result.Error != nil {
+		return nil, fmt.Errorf("failed to list locks: %w", result.Error)
+	}
+	return locks, nil
+}
@@ -2,17 +2,31 @@ package storage
 
 import (
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+
+	icooclawErrors "icooclaw/pkg/errors"
 )
 
 // Memory represents a memory entry.
 type Memory struct {
 	Model
-	SessionID string `gorm:"column:session_id;type:char(36);not null;index;comment:会话ID" json:"session_id"`
-	Role      string `gorm:"column:role;type:varchar(50);not null;comment:角色(user/assistant/system)" json:"role"`
-	Content   string `gorm:"column:content;type:text;not null;comment:消息内容" json:"content"`
-	Metadata  string `gorm:"column:metadata;type:text;comment:元数据(JSON格式)" json:"metadata"` // JSON object
+	SessionID string      `gorm:"column:session_id;type:char(36);not null;index;comment:会话ID" json:"session_id"`
+	Role      string      `gorm:"column:role;type:varchar(50);not null;comment:角色(user/assistant/system)" json:"role"`
+	Content   string      `gorm:"column:content;type:text;not null;serializer:encrypted;comment:消息内容" json:"content"`
+	Metadata  string      `gorm:"column:metadata;type:text;comment:元数据(JSON格式)" json:"metadata"`          // JSON object
+	Pinned    bool        `gorm:"column:pinned;type:tinyint(1);default:false;comment:是否置顶" json:"pinned"` // 置顶记忆在会话重置时可保留
+	Type      string      `gorm:"column:type;type:varchar(50);index;comment:记忆类型(fact/preference/note等)" json:"type,omitempty"`
+	Tags      StringArray `gorm:"column:tags;type:text;comment:标签(逗号分隔)" json:"tags,omitempty"`
+	// 重要度评分相关字段，检索时按 评分×相关度 排序，低分记忆会被合并为摘要
+	Importance     float64   `gorm:"column:importance;default:0;comment:显式重要度" json:"importance"`
+	AccessCount    int       `gorm:"column:access_count;default:0;comment:被检索次数" json:"access_count"`
+	LastAccessedAt time.Time `gorm:"column:last_accessed_at;type:datetime;comment:最近被检索时间" json:"last_accessed_at"`
+	Score          float64   `gorm:"-" json:"score,omitempty"` // 检索时计算的 评分×相关度，不持久化
+	// 向量嵌入，由批量嵌入作业生成
+	Embedding      []float32 `gorm:"column:embedding;type:text;serializer:json;comment:向量嵌入" json:"-"`
+	EmbeddingModel string    `gorm:"column:embedding_model;type:varchar(150);comment:嵌入模型" json:"embedding_model,omitempty"`
 }
 
 // TableName returns the table name for Memory.
@@ -61,6 +75,89 @@ func (s *MemoryStorage) Get(sessionID string, limit int) ([]*Memory, error) {
 	return memories, nil
 }
 
+// ListPinned lists pinned memory entries of a session.
+func (s *MemoryStorage) ListPinned(sessionID string) ([]*Memory, error) {
+	var memories []*Memory
+	result := s.db.Where("session_id = ? AND pinned = ?", sessionID, true).
+		Order("created_at").
+		Find(&memories)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list pinned memory: %w", result.Error)
+	}
+	return memories, nil
+}
+
+// SetPinned pins or unpins a memory entry.
+func (s *MemoryStorage) SetPinned(id string, pinned bool) error {
+	result := s.db.Model(&Memory{}).Where("id = ?", id).Update("pinned", pinned)
+	if result.Error != nil {
+		return fmt.Errorf("failed to pin memory: %w", result.Error)
+	}
+	return nil
+}
+
+// FindByPrefix finds a memory by ID or unique ID prefix, as shown in review digests.
+func (s *MemoryStorage) FindByPrefix(prefix string) (*Memory, error) {
+	if len(prefix) < 4 {
+		return nil, fmt.Errorf("memory id prefix too short: %s", prefix)
+	}
+
+	var memories []*Memory
+	result := s.db.Where("id LIKE ?", prefix+"%").Limit(2).Find(&memories)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to find memory: %w", result.Error)
+	}
+	switch len(memories) {
+	case 0:
+		return nil, icooclawErrors.ErrRecordNotFound
+	case 1:
+		return memories[0], nil
+	default:
+		return nil, fmt.Errorf("memory id prefix is ambiguous: %s", prefix)
+	}
+}
+
+// UpdateContent replaces the content of a memory entry.
+func (s *MemoryStorage) UpdateContent(id, content string) error {
+	content, err := encryptValue(s.db, content)
+	if err != nil {
+		return fmt.Errorf("failed to update memory: %w", err)
+	}
+	result := s.db.Model(&Memory{}).Where("id = ?", id).Update("content", content)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update memory: %w", result.Error)
+	}
+	return nil
+}
+
+// Touch records that memories were retrieved, increasing their access count.
+func (s *MemoryStorage) Touch(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	result := s.db.Model(&Memory{}).Where("id IN ?", ids).Updates(map[string]any{
+		"access_count":     gorm.Expr("access_count + 1"),
+		"last_accessed_at": time.Now(),
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to touch memory: %w", result.Error)
+	}
+	return nil
+}
+
+// Consolidate replaces memories with a summary memory in one transaction.
+func (s *MemoryStorage) Consolidate(summary *Memory, ids []string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(summary).Error; err != nil {
+			return fmt.Errorf("failed to save summary memory: %w", err)
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&Memory{}).Error; err != nil {
+			return fmt.Errorf("failed to delete consolidated memories: %w", err)
+		}
+		return nil
+	})
+}
+
 // Delete deletes memory entries for a session.
 func (s *MemoryStorage) Delete(sessionID string) error {
 	result := s.db.Where("session_id = ?", sessionID).Delete(&Memory{})
@@ -70,6 +167,15 @@ func (s *MemoryStorage) Delete(sessionID string) error {
 	return nil
 }
 
+// DeleteByID deletes a single memory entry.
+func (s *MemoryStorage) DeleteByID(id string) error {
+	result := s.db.Where("id = ?", id).Delete(&Memory{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete memory: %w", result.Error)
+	}
+	return nil
+}
+
 // Page gets memories with pagination.
 func (s *MemoryStorage) Page(query *QueryMemory) (*ResQueryMemory, error) {
 	var res ResQueryMemory
@@ -121,7 +227,7 @@ func (s *MemoryStorage) Search(query *QueryMemory) ([]*Memory, error) {
 	}
 
 	if query.Query != "" {
-		qry = qry.Where("content LIKE ?", "%"+query.Query+"%")
+		qry = whereContentContains(qry, &Memory{}, query.Query)
 	}
 
 	var result *gorm.DB
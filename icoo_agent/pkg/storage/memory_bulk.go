@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"icooclaw/pkg/utils"
+)
+
+// MemoryFilter 批量操作的记忆筛选条件，零值字段不参与筛选。
+type MemoryFilter struct {
+	SessionID string    `json:"session_id"`
+	Role      string    `json:"role"`
+	Type      string    `json:"type"`
+	Tag       string    `json:"tag"`
+	Query     string    `json:"query"`  // 内容包含
+	Before    time.Time `json:"before"` // 创建时间早于
+	After     time.Time `json:"after"`  // 创建时间晚于
+	Pinned    *bool     `json:"pinned"`
+}
+
+// IsEmpty 是否未设置任何筛选条件。
+func (f MemoryFilter) IsEmpty() bool {
+	return f == MemoryFilter{}
+}
+
+// apply 将筛选条件应用到查询。
+func (f MemoryFilter) apply(qry *gorm.DB) *gorm.DB {
+	if f.SessionID != "" {
+		qry = qry.Where("session_id = ?", f.SessionID)
+	}
+	if f.Role != "" {
+		qry = qry.Where("role = ?", f.Role)
+	}
+	if f.Type != "" {
+		qry = qry.Where("type = ?", f.Type)
+	}
+	if f.Tag != "" {
+		// 标签以逗号分隔存储，首尾补逗号后精确匹配
+		qry = qry.Where("(',' || tags || ',') LIKE ?", "%,"+f.Tag+",%")
+	}
+	if f.Query != "" {
+		qry = whereContentContains(qry, &Memory{}, f.Query)
+	}
+	if !f.Before.IsZero() {
+		qry = qry.Where("created_at < ?", f.Before)
+	}
+	if !f.After.IsZero() {
+		qry = qry.Where("created_at > ?", f.After)
+	}
+	if f.Pinned != nil {
+		qry = qry.Where("pinned = ?", *f.Pinned)
+	}
+	return qry
+}
+
+// List lists all memories matching the filter, oldest first.
+func (s *MemoryStorage) List(filter MemoryFilter) ([]*Memory, error) {
+	var memories []*Memory
+	result := filter.apply(s.db.Model(&Memory{})).Order("created_at").Find(&memories)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", result.Error)
+	}
+	return memories, nil
+}
+
+// embeddingQuery returns the memories matching the filter that have no
+// embedding from model.
+func (s *MemoryStorage) embeddingQuery(filter MemoryFilter, model string) *gorm.DB {
+	return filter.apply(s.db.Model(&Memory{})).
+		Where("embedding_model IS NULL OR embedding_model <> ?", model)
+}
+
+// CountForEmbedding counts memories matching the filter that have no
+// embedding from model.
+func (s *MemoryStorage) CountForEmbedding(filter MemoryFilter, model string) (int64, error) {
+	var count int64
+	if result := s.embeddingQuery(filter, model).Count(&count); result.Error != nil {
+		return 0, fmt.Errorf("failed to count memories: %w", result.Error)
+	}
+	return count, nil
+}
+
+// ListForEmbedding lists up to limit memories matching the filter that have
+// no embedding from model, in ID order after cursor.
+func (s *MemoryStorage) ListForEmbedding(filter MemoryFilter, model, cursor string, limit int) ([]*Memory, error) {
+	var memories []*Memory
+	result := s.embeddingQuery(filter, model).
+		Where("id > ?", cursor).
+		Order("id").
+		Limit(limit).
+		Find(&memories)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", result.Error)
+	}
+	return memories, nil
+}
+
+// SetEmbedding saves the embedding of a memory without touching updated_at.
+func (s *MemoryStorage) SetEmbedding(id string, embedding []float32, model string) error {
+	data, err := json.Marshal(embedding)
+	if err != nil {
+		return fmt.Errorf("failed to marshal embedding: %w", err)
+	}
+	result := s.db.Model(&Memory{}).Where("id = ?", id).UpdateColumns(map[string]any{
+		"embedding":       string(data),
+		"embedding_model": model,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to save embedding: %w", result.Error)
+	}
+	return nil
+}
+
+// Import saves memories in one transaction, keeping their IDs and timestamps.
+// Existing records with the same ID are overwritten unless skipExisting is set.
+func (s *MemoryStorage) Import(memories []*Memory, skipExisting bool) (int64, error) {
+	if len(memories) == 0 {
+		return 0, nil
+	}
+
+	conflict := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}
+	if skipExisting {
+		conflict = clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoNothing: true}
+	}
+
+	var imported int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(conflict).CreateInBatches(memories, 100)
+		if result.Error != nil {
+			return fmt.Errorf("failed to import memories: %w", result.Error)
+		}
+		imported = result.RowsAffected
+		return nil
+	})
+	return imported, err
+}
+
+// Retag adds and removes tags on all memories matching the filter.
+func (s *MemoryStorage) Retag(filter MemoryFilter, add, remove []string) (int64, error) {
+	memories, err := s.List(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var changed int64
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range memories {
+			tags := retag(m.Tags, add, remove)
+			if slices.Equal(tags, m.Tags) {
+				continue
+			}
+			if err := tx.Model(&Memory{}).Where("id = ?", m.ID).Update("tags", tags).Error; err != nil {
+				return fmt.Errorf("failed to retag memory: %w", err)
+			}
+			changed++
+		}
+		return nil
+	})
+	return changed, err
+}
+
+// SetType changes the type of all memories matching the filter.
+func (s *MemoryStorage) SetType(filter MemoryFilter, memoryType string) (int64, error) {
+	result := filter.apply(s.db.Model(&Memory{})).Update("type", memoryType)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to set memory type: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// DeleteByFilter deletes all memories matching the filter.
+// An empty filter is rejected to avoid wiping every memory by accident.
+func (s *MemoryStorage) DeleteByFilter(filter MemoryFilter) (int64, error) {
+	if filter.IsEmpty() {
+		return 0, fmt.Errorf("delete by filter requires at least one condition")
+	}
+
+	result := filter.apply(s.db).Delete(&Memory{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete memories: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// DuplicateGroup 一组相似的记忆，Keep 为合并后保留的记录。
+type DuplicateGroup struct {
+	Keep       *Memory   `json:"keep"`
+	Duplicates []*Memory `json:"duplicates"`
+}
+
+// FindDuplicates groups memories of the same session whose content similarity
+// is at least threshold (0-1). The earliest record of each group is kept.
+func (s *MemoryStorage) FindDuplicates(filter MemoryFilter, threshold float64) ([]DuplicateGroup, error) {
+	memories, err := s.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	return groupDuplicates(memories, threshold), nil
+}
+
+// MergeDuplicates merges every group into its kept record: tags are united,
+// pinned is kept if any duplicate was pinned, and the duplicates are deleted.
+func (s *MemoryStorage) MergeDuplicates(groups []DuplicateGroup) (int64, error) {
+	var removed int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, g := range groups {
+			tags := g.Keep.Tags
+			pinned := g.Keep.Pinned
+			ids := make([]string, 0, len(g.Duplicates))
+			for _, d := range g.Duplicates {
+				tags = retag(tags, d.Tags, nil)
+				pinned = pinned || d.Pinned
+				ids = append(ids, d.ID)
+			}
+
+			if err := tx.Model(&Memory{}).Where("id = ?", g.Keep.ID).
+				Updates(map[string]any{"tags": tags, "pinned": pinned}).Error; err != nil {
+				return fmt.Errorf("failed to merge memory: %w", err)
+			}
+			result := tx.Where("id IN ?", ids).Delete(&Memory{})
+			if result.Error != nil {
+				return fmt.Errorf("failed to delete duplicate memories: %w", result.Error)
+			}
+			removed += result.RowsAffected
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// groupDuplicates 按会话对记忆做相似度分组，每条记忆只归入第一个匹配的组。
+func groupDuplicates(memories []*Memory, threshold float64) []DuplicateGroup {
+	type candidate struct {
+		group    *DuplicateGroup
+		shingles map[string]struct{}
+	}
+
+	var groups []*DuplicateGroup
+	bySession := make(map[string][]candidate)
+	for _, m := range memories {
+		sh := utils.Bigrams(m.Content)
+
+		matched := false
+		for _, c := range bySession[m.SessionID] {
+			if utils.Jaccard(sh, c.shingles) >= threshold {
+				c.group.Duplicates = append(c.group.Duplicates, m)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		g := &DuplicateGroup{Keep: m}
+		groups = append(groups, g)
+		bySession[m.SessionID] = append(bySession[m.SessionID], candidate{group: g, shingles: sh})
+	}
+
+	result := make([]DuplicateGroup, 0)
+	for _, g := range groups {
+		if len(g.Duplicates) > 0 {
+			result = append(result, *g)
+		}
+	}
+	return result
+}
+
+// retag 在标签列表上添加和移除标签，保持原有顺序并去重。
+func retag(tags, add, remove []string) StringArray {
+	result := make(StringArray, 0, len(tags)+len(add))
+	for _, t := range append(slices.Clone(tags), add...) {
+		t = strings.TrimSpace(t)
+		if t == "" || slices.Contains(remove, t) || slices.Contains(result, t) {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
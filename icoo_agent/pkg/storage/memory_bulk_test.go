@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGroupDuplicates(t *testing.T) {
+	memories := []*Memory{
+		{Model: Model{ID: "1"}, SessionID: "s1", Content: "用户喜欢喝绿茶，不加糖"},
+		{Model: Model{ID: "2"}, SessionID: "s1", Content: "The deadline is Friday"},
+		{Model: Model{ID: "3"}, SessionID: "s1", Content: "用户喜欢喝绿茶,不加糖。"},
+		{Model: Model{ID: "4"}, SessionID: "s2", Content: "用户喜欢喝绿茶，不加糖"},
+		{Model: Model{ID: "5"}, SessionID: "s1", Content: "the DEADLINE is friday!"},
+	}
+
+	groups := groupDuplicates(memories, 0.85)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].Keep.ID != "1" || len(groups[0].Duplicates) != 1 || groups[0].Duplicates[0].ID != "3" {
+		t.Errorf("unexpected first group: keep %s, duplicates %d", groups[0].Keep.ID, len(groups[0].Duplicates))
+	}
+	if groups[1].Keep.ID != "2" || groups[1].Duplicates[0].ID != "5" {
+		t.Errorf("unexpected second group: keep %s", groups[1].Keep.ID)
+	}
+}
+
+func TestRetag(t *testing.T) {
+	got := retag(StringArray{"a", "b"}, []string{"c", "a", " "}, []string{"b"})
+	if want := (StringArray{"a", "c"}); !slices.Equal(got, want) {
+		t.Errorf("retag() = %v, want %v", got, want)
+	}
+}
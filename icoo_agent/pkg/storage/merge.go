@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"icooclaw/pkg/consts"
+)
+
+// maxRedirects 解析合并重定向时最多跟随的次数，防止异常数据形成环
+const maxRedirects = 10
+
+// MergeOptions 会话合并选项。
+type MergeOptions struct {
+	Channel string   `json:"channel"` // 渠道，只能合并同一渠道的会话
+	Target  string   `json:"target"`  // 保留的会话ID
+	Sources []string `json:"sources"` // 合并到 Target 的会话ID
+	DryRun  bool     `json:"dry_run"` // 只预览合并后的时间线，不修改数据
+}
+
+// MergeEntry 合并后时间线中的一条消息。
+type MergeEntry struct {
+	SessionID string    `json:"session_id"` // 消息原来所属的会话ID
+	Role      string    `json:"role"`       // 角色
+	Content   string    `json:"content"`    // 消息内容，预览时截断
+	CreatedAt time.Time `json:"created_at"` // 消息时间
+}
+
+// MergeResult 会话合并结果。
+type MergeResult struct {
+	Target    string       `json:"target"`             // 保留的会话ID
+	Sources   []string     `json:"sources"`            // 已合并的会话ID，保留为指向 Target 的重定向记录
+	Messages  int64        `json:"messages"`           // 移动的消息数
+	Memories  int64        `json:"memories"`           // 移动的记忆数
+	Artifacts int64        `json:"artifacts"`          // 移动的制品数
+	Traces    int64        `json:"traces"`             // 移动的对话轨迹数
+	DryRun    bool         `json:"dry_run"`            // 是否只是预览
+	Timeline  []MergeEntry `json:"timeline,omitempty"` // 合并后的时间线，仅预览时返回
+}
+
+// previewContentRunes 预览时间线中每条消息保留的字符数
+const previewContentRunes = 80
+
+// MergeSessions combines duplicate sessions of one channel into opts.Target.
+// Messages keep their timestamps, so the merged history reads chronologically;
+// memories, artifacts and traces are reattributed to the target. The source
+// sessions are archived and keep a merged_into redirect, so messages that
+// still arrive for them continue in the target session. With opts.DryRun the
+// merged timeline is returned and nothing is changed.
+func (s *Storage) MergeSessions(opts MergeOptions) (*MergeResult, error) {
+	if opts.Channel == "" || opts.Target == "" || len(opts.Sources) == 0 {
+		return nil, fmt.Errorf("channel, target and sources are required")
+	}
+	sources := slices.Compact(slices.Sorted(slices.Values(opts.Sources)))
+	if slices.Contains(sources, opts.Target) {
+		return nil, fmt.Errorf("target %s cannot also be a source", opts.Target)
+	}
+
+	targetKey := consts.GetSessionKey(opts.Channel, opts.Target)
+	sourceKeys := make([]string, len(sources))
+	for i, id := range sources {
+		sourceKeys[i] = consts.GetSessionKey(opts.Channel, id)
+	}
+	res := &MergeResult{Target: opts.Target, Sources: sources, DryRun: opts.DryRun}
+
+	err := s.transaction(func(tx, htx *gorm.DB) error {
+		// 1. 校验会话：都属于该渠道，且都没有被合并过
+		var sessions []*Session
+		ids := append([]string{opts.Target}, sources...)
+		if err := tx.Where("channel = ? AND id IN ?", opts.Channel, ids).Find(&sessions).Error; err != nil {
+			return fmt.Errorf("failed to get sessions: %w", err)
+		}
+		var target *Session
+		for _, id := range ids {
+			i := slices.IndexFunc(sessions, func(sess *Session) bool { return sess.ID == id })
+			if i < 0 {
+				return fmt.Errorf("session %s not found in channel %s", id, opts.Channel)
+			}
+			if sessions[i].MergedInto != "" {
+				return fmt.Errorf("session %s has already been merged into %s", id, sessions[i].MergedInto)
+			}
+			if id == opts.Target {
+				target = sessions[i]
+			}
+		}
+
+		// 2. 移动消息、记忆、制品和对话轨迹，预览时只统计数量
+		moves := []struct {
+			qry   *gorm.DB
+			value string
+			n     *int64
+		}{
+			{htx.Model(&Message{}).Where("session_id IN ?", sourceKeys), targetKey, &res.Messages},
+			{htx.Model(&Memory{}).Where("session_id IN ?", sourceKeys), targetKey, &res.Memories},
+			{tx.Model(&Artifact{}).Where("channel = ? AND session_id IN ?", opts.Channel, sources), opts.Target, &res.Artifacts},
+			{tx.Model(&Trace{}).Where("channel = ? AND session_id IN ?", opts.Channel, sources), opts.Target, &res.Traces},
+		}
+		for _, m := range moves {
+			if opts.DryRun {
+				if err := m.qry.Count(m.n).Error; err != nil {
+					return fmt.Errorf("failed to count records: %w", err)
+				}
+				continue
+			}
+			result := m.qry.Update("session_id", m.value)
+			if result.Error != nil {
+				return fmt.Errorf("failed to move records: %w", result.Error)
+			}
+			*m.n = result.RowsAffected
+		}
+
+		if opts.DryRun {
+			var messages []*Message
+			err := htx.Where("session_id IN ?", append([]string{targetKey}, sourceKeys...)).
+				Order("created_at").
+				Find(&messages).Error
+			if err != nil {
+				return fmt.Errorf("failed to get messages: %w", err)
+			}
+			res.Timeline = mergeTimeline(messages, opts.Channel)
+			return nil
+		}
+
+		// 3. 更新目标会话：沿用最近活跃的时间，补全缺失的用户、标题和摘要
+		slices.SortFunc(sessions, func(a, b *Session) int { return b.LastActive.Compare(a.LastActive) })
+		for _, sess := range sessions {
+			if sess.LastActive.After(target.LastActive) {
+				target.LastActive = sess.LastActive
+			}
+			if target.UserID == "" {
+				target.UserID = sess.UserID
+			}
+			if target.Title == "" {
+				target.Title = sess.Title
+			}
+			if target.Summary == "" {
+				target.Summary = sess.Summary
+			}
+		}
+		if err := tx.Save(target).Error; err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+
+		// 4. 源会话归档并保留重定向，之前合并到源会话的重定向改为指向目标会话
+		now := time.Now()
+		err := tx.Model(&Session{}).Where("channel = ? AND id IN ?", opts.Channel, sources).
+			Updates(map[string]any{"archived": true, "archived_at": now, "merged_into": opts.Target}).Error
+		if err != nil {
+			return fmt.Errorf("failed to archive sessions: %w", err)
+		}
+		err = tx.Model(&Session{}).Where("channel = ? AND merged_into IN ?", opts.Channel, sources).
+			Update("merged_into", opts.Target).Error
+		if err != nil {
+			return fmt.Errorf("failed to update redirects: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// mergeTimeline 生成合并后时间线的预览。
+func mergeTimeline(messages []*Message, channel string) []MergeEntry {
+	prefix := channel + ":"
+	timeline := make([]MergeEntry, 0, len(messages))
+	for _, m := range messages {
+		content := []rune(m.Content)
+		if len(content) > previewContentRunes {
+			content = append(content[:previewContentRunes], '…')
+		}
+		timeline = append(timeline, MergeEntry{
+			SessionID: m.SessionID[len(prefix):],
+			Role:      m.Role.ToString(),
+			Content:   string(content),
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return timeline
+}
+
+// ResolveMerged follows merged_into redirects and returns the session that
+// now holds the conversation, or sessionID itself when it was not merged.
+func (s *SessionStorage) ResolveMerged(channel, sessionID string) (string, error) {
+	for range maxRedirects {
+		var sess Session
+		err := s.db.Select("merged_into").Where("channel = ? AND id = ?", channel, sessionID).First(&sess).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && sess.MergedInto == "") {
+			return sessionID, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to get session: %w", err)
+		}
+		sessionID = sess.MergedInto
+	}
+	return sessionID, nil
+}
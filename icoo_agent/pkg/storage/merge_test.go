@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/consts"
+)
+
+func TestMergeSessions(t *testing.T) {
+	s, err := New(t.TempDir(), "", filepath.Join(t.TempDir(), "merge.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	base := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	for i, id := range []string{"a", "b", "c"} {
+		s.db.Create(&Session{Model: Model{ID: id}, Channel: "telegram", UserID: "u1", LastActive: base.Add(time.Duration(i) * time.Hour)})
+	}
+	s.db.Create(&Session{Model: Model{ID: "old"}, Channel: "telegram", Archived: true, MergedInto: "b"})
+	for i, m := range []struct{ session, content string }{
+		{"a", "第一条"}, {"b", "第二条"}, {"a", "第三条"}, {"c", "第四条"},
+	} {
+		msg := &Message{SessionID: consts.GetSessionKey("telegram", m.session), Role: consts.RoleUser, Content: m.content}
+		msg.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		s.db.Create(msg)
+	}
+	s.db.Create(&Memory{SessionID: consts.GetSessionKey("telegram", "b"), Content: "用户喜欢绿茶"})
+	s.db.Create(&Artifact{Channel: "telegram", SessionID: "c", Name: "report.md"})
+
+	opts := MergeOptions{Channel: "telegram", Target: "a", Sources: []string{"b", "c"}, DryRun: true}
+	preview, err := s.MergeSessions(opts)
+	if err != nil {
+		t.Fatalf("MergeSessions(dry run) error = %v", err)
+	}
+	if len(preview.Timeline) != 4 || preview.Timeline[1].SessionID != "b" || preview.Timeline[3].Content != "第四条" {
+		t.Errorf("timeline = %+v", preview.Timeline)
+	}
+	if preview.Messages != 2 || preview.Memories != 1 || preview.Artifacts != 1 {
+		t.Errorf("preview = %+v", preview)
+	}
+	if msgs, _ := s.Message().Get(consts.GetSessionKey("telegram", "a"), 10); len(msgs) != 2 {
+		t.Fatalf("dry run moved messages: %d", len(msgs))
+	}
+
+	opts.DryRun = false
+	res, err := s.MergeSessions(opts)
+	if err != nil {
+		t.Fatalf("MergeSessions() error = %v", err)
+	}
+	if res.Messages != 2 || res.Memories != 1 || res.Artifacts != 1 {
+		t.Errorf("result = %+v", res)
+	}
+	if msgs, _ := s.Message().Get(consts.GetSessionKey("telegram", "a"), 10); len(msgs) != 4 || msgs[0].Content != "第四条" {
+		t.Errorf("merged messages = %d", len(msgs))
+	}
+	if target, _ := s.Session().GetBySessionID("telegram", "a"); !target.LastActive.Equal(base.Add(2 * time.Hour)) {
+		t.Errorf("target last active = %v", target.LastActive)
+	}
+	for _, id := range []string{"b", "c", "old"} {
+		if got, _ := s.Session().ResolveMerged("telegram", id); got != "a" {
+			t.Errorf("ResolveMerged(%s) = %s, want a", id, got)
+		}
+	}
+
+	if _, err := s.MergeSessions(MergeOptions{Channel: "telegram", Target: "b", Sources: []string{"a"}}); err == nil {
+		t.Error("merging into a merged session should fail")
+	}
+	if _, err := s.MergeSessions(MergeOptions{Channel: "telegram", Target: "a", Sources: []string{"missing"}}); err == nil {
+		t.Error("merging a missing session should fail")
+	}
+}
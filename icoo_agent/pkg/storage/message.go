@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"icooclaw/pkg/consts"
+	icooclawErrors "icooclaw/pkg/errors"
 
 	"gorm.io/gorm"
 )
@@ -12,10 +14,10 @@ type Message struct {
 	Model
 	SessionID  string          `gorm:"column:session_id;type:char(36);not null;index;comment:会话ID" json:"session_id"`
 	Role       consts.RoleType `gorm:"column:role;type:varchar(50);not null;comment:角色(user/assistant/system)" json:"role"`
-	Content    string          `gorm:"column:content;type:text;not null;comment:消息内容" json:"content"`
+	Content    string          `gorm:"column:content;type:text;not null;serializer:encrypted;comment:消息内容" json:"content"`
 	ToolName   string          `gorm:"column:tool_name;type:varchar(50);comment:工具名称" json:"tool_name"`
-	ToolArgs   string          `gorm:"column:tool_args;type:text;comment:工具参数(JSON格式)" json:"tool_args"`
-	ToolResult string          `gorm:"column:tool_result;type:text;comment:工具执行结果(JSON格式)" json:"tool_result"`
+	ToolArgs   string          `gorm:"column:tool_args;type:text;serializer:encrypted;comment:工具参数(JSON格式)" json:"tool_args"`
+	ToolResult string          `gorm:"column:tool_result;type:text;serializer:encrypted;comment:工具执行结果(JSON格式)" json:"tool_result"`
 	Metadata   string          `gorm:"column:metadata;type:text;comment:元数据(JSON格式)" json:"metadata"`
 }
 
@@ -65,6 +67,67 @@ func (s *MessageStorage) Get(sessionID string, limit int) ([]*Message, error) {
 	return messages, nil
 }
 
+// Recent gets the latest limit messages across the given sessions, newest
+// first.
+func (s *MessageStorage) Recent(sessionIDs []string, limit int) ([]*Message, error) {
+	var messages []*Message
+	result := s.db.Where("session_id IN ?", sessionIDs).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&messages)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", result.Error)
+	}
+	return messages, nil
+}
+
+// Previous gets the latest message with the given role sent before m in the
+// same session.
+func (s *MessageStorage) Previous(m *Message, role consts.RoleType) (*Message, error) {
+	var prev Message
+	result := s.db.Where("session_id = ? AND role = ? AND created_at < ?", m.SessionID, role, m.CreatedAt).
+		Order("created_at DESC").
+		First(&prev)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, icooclawErrors.ErrRecordNotFound
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get previous message: %w", result.Error)
+	}
+	return &prev, nil
+}
+
+// DeleteBySession deletes all messages of a session.
+func (s *MessageStorage) DeleteBySession(sessionID string) error {
+	result := s.db.Where("session_id = ?", sessionID).Delete(&Message{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete session messages: %w", result.Error)
+	}
+	return nil
+}
+
+// CountByRole counts messages of a session grouped by role.
+func (s *MessageStorage) CountByRole(sessionID string) (map[consts.RoleType]int64, error) {
+	var rows []struct {
+		Role  consts.RoleType
+		Count int64
+	}
+	result := s.db.Model(&Message{}).
+		Select("role, COUNT(*) AS count").
+		Where("session_id = ?", sessionID).
+		Group("role").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", result.Error)
+	}
+
+	counts := make(map[consts.RoleType]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Role] = row.Count
+	}
+	return counts, nil
+}
+
 // GetByID gets a message by ID.
 func (s *MessageStorage) GetByID(id string) (*Message, error) {
 	var m Message
@@ -102,7 +165,7 @@ func (s *MessageStorage) Page(query *QueryMessage) (*ResQueryMessage, error) {
 	}
 
 	if query.KeyWord != "" {
-		qry = qry.Where("content LIKE ?", "%"+query.KeyWord+"%")
+		qry = whereContentContains(qry, &Message{}, query.KeyWord)
 	}
 
 	qry = qry.Order("created_at")
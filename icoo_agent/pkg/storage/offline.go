@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 离线消息状态
+const (
+	OfflineStatusPending = "pending" // 等待处理
+	OfflineStatusDone    = "done"    // 已处理
+	OfflineStatusFailed  = "failed"  // 处理失败
+)
+
+// OfflineMessage 提供商不可用期间排队的入站消息。
+type OfflineMessage struct {
+	Model
+	Channel     string    `gorm:"column:channel;type:varchar(50);not null;index;comment:渠道" json:"channel"`               // 渠道
+	SessionID   string    `gorm:"column:session_id;type:varchar(100);not null;comment:会话ID" json:"session_id"`            // 会话ID
+	SenderID    string    `gorm:"column:sender_id;type:varchar(100);comment:发送者ID" json:"sender_id"`                      // 发送者ID
+	SenderName  string    `gorm:"column:sender_name;type:varchar(100);comment:发送者名称" json:"sender_name"`                  // 发送者名称
+	Text        string    `gorm:"column:text;type:text;not null;serializer:encrypted;comment:消息内容" json:"text"`           // 消息内容
+	Metadata    string    `gorm:"column:metadata;type:text;comment:元数据(JSON格式)" json:"metadata"`                          // 元数据
+	Saved       bool      `gorm:"column:saved;type:tinyint(1);default:false;comment:用户消息是否已写入历史" json:"saved"`            // 用户消息是否已写入历史
+	Status      string    `gorm:"column:status;type:varchar(20);not null;index;default:pending;comment:状态" json:"status"` // 状态
+	Attempts    int       `gorm:"column:attempts;type:int;default:0;comment:重试次数" json:"attempts"`                        // 重试次数
+	LastError   string    `gorm:"column:last_error;type:text;comment:最后一次错误" json:"last_error"`                           // 最后一次错误
+	ReceivedAt  time.Time `gorm:"column:received_at;type:datetime;comment:接收时间" json:"received_at"`                       // 接收时间
+	ProcessedAt time.Time `gorm:"column:processed_at;type:datetime;comment:处理时间" json:"processed_at"`                     // 处理时间
+}
+
+// TableName returns the table name for OfflineMessage.
+func (OfflineMessage) TableName() string {
+	return tableNamePrefix + "offline_messages"
+}
+
+type OfflineStorage struct {
+	db *gorm.DB
+}
+
+func NewOfflineStorage(db *gorm.DB) *OfflineStorage {
+	return &OfflineStorage{db: db}
+}
+
+// Enqueue saves a pending offline message.
+func (s *OfflineStorage) Enqueue(m *OfflineMessage) error {
+	m.Status = OfflineStatusPending
+	if result := s.db.Create(m); result.Error != nil {
+		return fmt.Errorf("failed to enqueue offline message: %w", result.Error)
+	}
+	return nil
+}
+
+// ListPending lists pending messages, oldest first.
+func (s *OfflineStorage) ListPending(limit int) ([]*OfflineMessage, error) {
+	var items []*OfflineMessage
+	qry := s.db.Where("status = ?", OfflineStatusPending).Order("received_at ASC")
+	if limit > 0 {
+		qry = qry.Limit(limit)
+	}
+	if result := qry.Find(&items); result.Error != nil {
+		return nil, fmt.Errorf("failed to list offline messages: %w", result.Error)
+	}
+	return items, nil
+}
+
+// CountPending counts pending messages.
+func (s *OfflineStorage) CountPending() (int64, error) {
+	var count int64
+	result := s.db.Model(&OfflineMessage{}).Where("status = ?", OfflineStatusPending).Count(&count)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to count offline messages: %w", result.Error)
+	}
+	return count, nil
+}
+
+// MarkDone marks a message as processed.
+func (s *OfflineStorage) MarkDone(id string) error {
+	result := s.db.Model(&OfflineMessage{}).Where("id = ?", id).Updates(map[string]any{
+		"status":       OfflineStatusDone,
+		"processed_at": time.Now(),
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update offline message: %w", result.Error)
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt, giving up once maxAttempts is reached.
+func (s *OfflineStorage) MarkFailed(id string, cause error, maxAttempts int) error {
+	var m OfflineMessage
+	if result := s.db.Where("id = ?", id).First(&m); result.Error != nil {
+		return fmt.Errorf("failed to get offline message: %w", result.Error)
+	}
+
+	updates := map[string]any{
+		"attempts":   m.Attempts + 1,
+		"last_error": cause.Error(),
+	}
+	if m.Attempts+1 >= maxAttempts {
+		updates["status"] = OfflineStatusFailed
+		updates["processed_at"] = time.Now()
+	}
+
+	if result := s.db.Model(&m).Updates(updates); result.Error != nil {
+		return fmt.Errorf("failed to update offline message: %w", result.Error)
+	}
+	return nil
+}
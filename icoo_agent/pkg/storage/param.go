@@ -34,6 +34,29 @@ func (s *ParamStorage) Save(p *ParamConfig) error {
 	return s.db.Create(p).Error
 }
 
+// Set creates or updates a param by key.
+func (s *ParamStorage) Set(key, value, description, group string) error {
+	p, err := s.Get(key)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return s.Save(&ParamConfig{
+			Key:         key,
+			Value:       value,
+			Description: description,
+			Group:       group,
+			Enabled:     true,
+		})
+	}
+
+	p.Value = value
+	if result := s.db.Save(p); result.Error != nil {
+		return fmt.Errorf("failed to set param: %w", result.Error)
+	}
+	return nil
+}
+
 // Get gets a param by key.
 func (s *ParamStorage) Get(key string) (*ParamConfig, error) {
 	var p ParamConfig
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// encryptedColumns lists the columns tagged serializer:encrypted, by table.
+// history marks tables stored in the history database.
+var encryptedColumns = []struct {
+	table   string
+	columns []string
+	history bool
+}{
+	{Message{}.TableName(), []string{"content", "tool_args", "tool_result"}, true},
+	{Memory{}.TableName(), []string{"content"}, true},
+	{Session{}.TableName(), []string{"summary", "metadata"}, false},
+	{Trace{}.TableName(), []string{"input", "data"}, false},
+	{OfflineMessage{}.TableName(), []string{"text"}, false},
+	{EntityFact{}.TableName(), []string{"content"}, false},
+	{KV{}.TableName(), []string{"value"}, false},
+}
+
+// ReencryptResult counts the rows of one table checked and rewritten by Reencrypt.
+type ReencryptResult struct {
+	Table     string `json:"table"`
+	Rows      int64  `json:"rows"`
+	Rewritten int64  `json:"rewritten"`
+}
+
+// Encrypted reports whether the storage encrypts content at rest.
+func (s *Storage) Encrypted() bool {
+	return s.cipher != nil
+}
+
+// Reencrypt rewrites the encrypted columns of existing rows in batches.
+// With encryption enabled, plaintext rows and rows encrypted with a previous
+// key are encrypted with the current key, which both migrates an existing
+// database and completes a key rotation. With decrypt set, every row is
+// written back as plaintext so that encryption can be turned off.
+// Rows already in the wanted form are left untouched, so it can be re-run
+// after an interruption.
+func (s *Storage) Reencrypt(ctx context.Context, decrypt bool, batchSize int, progress func(ReencryptResult)) ([]ReencryptResult, error) {
+	if s.cipher == nil {
+		return nil, fmt.Errorf("encryption key is not configured")
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	results := make([]ReencryptResult, 0, len(encryptedColumns))
+	for _, t := range encryptedColumns {
+		res := ReencryptResult{Table: t.table}
+		db := s.db
+		if t.history {
+			db = s.history
+		}
+		cursor := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			var rows []map[string]any
+			err := db.Table(t.table).
+				Select(append([]string{"id"}, t.columns...)).
+				Where("id > ?", cursor).
+				Order("id").
+				Limit(batchSize).
+				Find(&rows).Error
+			if err != nil {
+				return results, fmt.Errorf("failed to read %s: %w", t.table, err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+
+			err = db.Transaction(func(tx *gorm.DB) error {
+				for _, row := range rows {
+					id := columnString(row["id"])
+					updates := make(map[string]any)
+					for _, col := range t.columns {
+						value := columnString(row[col])
+						rewritten, err := s.rewriteValue(value, decrypt)
+						if err != nil {
+							return fmt.Errorf("%s %s.%s: %w", t.table, id, col, err)
+						}
+						if rewritten != value {
+							updates[col] = rewritten
+						}
+					}
+					if len(updates) == 0 {
+						continue
+					}
+					if err := tx.Table(t.table).Where("id = ?", id).UpdateColumns(updates).Error; err != nil {
+						return fmt.Errorf("failed to update %s: %w", t.table, err)
+					}
+					res.Rewritten++
+				}
+				return nil
+			})
+			if err != nil {
+				return results, err
+			}
+
+			res.Rows += int64(len(rows))
+			cursor = columnString(rows[len(rows)-1]["id"])
+			if progress != nil {
+				progress(res)
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// rewriteValue returns the stored form of value: plaintext when decrypting,
+// otherwise encrypted with the current key.
+func (s *Storage) rewriteValue(value string, decrypt bool) (string, error) {
+	keyID, encrypted := IsEncrypted(value)
+	if decrypt {
+		if !encrypted {
+			return value, nil
+		}
+		return s.cipher.Decrypt(value)
+	}
+	if value == "" || (encrypted && keyID == s.cipher.CurrentKeyID()) {
+		return value, nil
+	}
+	plain, err := s.cipher.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return s.cipher.Encrypt(plain)
+}
+
+// columnString converts a raw column value to a string.
+func columnString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"icooclaw/pkg/consts"
+)
+
+// ResetOptions 会话重置选项。
+type ResetOptions struct {
+	KeepPinned  bool `json:"keep_pinned"`  // 保留置顶记忆
+	KeepSummary bool `json:"keep_summary"` // 保留会话摘要
+}
+
+// ResetResult 会话重置结果。
+type ResetResult struct {
+	SessionID      string `json:"session_id"`      // 当前会话ID
+	ArchiveID      string `json:"archive_id"`      // 归档会话ID
+	Messages       int64  `json:"messages"`        // 归档的消息数
+	Memories       int64  `json:"memories"`        // 归档的记忆数
+	KeptMemories   int64  `json:"kept_memories"`   // 保留的记忆数
+	SummaryCarried bool   `json:"summary_carried"` // 是否保留了摘要
+}
+
+// ResetSession archives the current conversation of a session and starts a fresh one.
+// Messages (and memories not kept by opts) are moved to a new archived session,
+// so the session ID stays the same for the channel while the history is preserved.
+func (s *Storage) ResetSession(channel, sessionID string, opts ResetOptions) (*ResetResult, error) {
+	if channel == "" || sessionID == "" {
+		return nil, fmt.Errorf("channel and session id are required")
+	}
+
+	sessionKey := consts.GetSessionKey(channel, sessionID)
+	archiveID := uuid.New().String()
+	archiveKey := consts.GetSessionKey(channel, archiveID)
+	res := &ResetResult{SessionID: sessionID, ArchiveID: archiveID}
+
+	err := s.transaction(func(tx, htx *gorm.DB) error {
+		// 1. 读取当前会话（可能不存在）
+		var current Session
+		found := true
+		if err := tx.Where("channel = ? AND id = ?", channel, sessionID).First(&current).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("failed to get session: %w", err)
+			}
+			found = false
+		}
+
+		// 2. 创建归档会话
+		now := time.Now()
+		archive := &Session{
+			Model:      Model{ID: archiveID},
+			Channel:    channel,
+			UserID:     current.UserID,
+			Summary:    current.Summary,
+			Title:      current.Title,
+			LastActive: current.LastActive,
+			Archived:   true,
+			ArchivedAt: now,
+			ParentID:   sessionID,
+		}
+		if archive.Title == "" {
+			archive.Title = "归档会话 " + now.Format("2006-01-02 15:04")
+		}
+		if err := tx.Create(archive).Error; err != nil {
+			return fmt.Errorf("failed to create archive session: %w", err)
+		}
+
+		// 3. 将消息移动到归档会话
+		result := htx.Model(&Message{}).Where("session_id = ?", sessionKey).Update("session_id", archiveKey)
+		if result.Error != nil {
+			return fmt.Errorf("failed to archive messages: %w", result.Error)
+		}
+		res.Messages = result.RowsAffected
+
+		// 4. 移动记忆，按需保留置顶记忆
+		qry := htx.Model(&Memory{}).Where("session_id = ?", sessionKey)
+		if opts.KeepPinned {
+			qry = qry.Where("pinned = ?", false)
+		}
+		result = qry.Update("session_id", archiveKey)
+		if result.Error != nil {
+			return fmt.Errorf("failed to archive memory: %w", result.Error)
+		}
+		res.Memories = result.RowsAffected
+
+		if err := htx.Model(&Memory{}).Where("session_id = ?", sessionKey).Count(&res.KeptMemories).Error; err != nil {
+			return fmt.Errorf("failed to count memory: %w", err)
+		}
+
+		// 5. 更新当前会话
+		if !found {
+			return nil
+		}
+		if !opts.KeepSummary {
+			current.Summary = ""
+		}
+		res.SummaryCarried = current.Summary != ""
+		current.LastActive = now
+		if err := tx.Save(&current).Error; err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
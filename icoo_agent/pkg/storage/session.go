@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,11 +14,16 @@ import (
 // Session represents a chat session.
 type Session struct {
 	Model
-	Channel    string    `gorm:"column:channel;type:varchar(50);not null;comment:渠道" json:"channel"`    // 渠道
-	UserID     string    `gorm:"column:user_id;type:varchar(100);not null;comment:用户ID" json:"user_id"` // 用户ID
-	Summary    string    `gorm:"column:summary;type:text;comment:会话摘要" json:"summary"`                  // 会话摘要
-	Title      string    `gorm:"column:title;type:varchar(100);comment:会话标题" json:"title"`              // 会话标题
-	LastActive time.Time `gorm:"column:last_active;type:datetime;comment:最后活跃时间" json:"last_active"`    // 最后活跃时间
+	Channel    string    `gorm:"column:channel;type:varchar(50);not null;comment:渠道" json:"channel"`                           // 渠道
+	UserID     string    `gorm:"column:user_id;type:varchar(100);not null;comment:用户ID" json:"user_id"`                        // 用户ID
+	Summary    string    `gorm:"column:summary;type:text;serializer:encrypted;comment:会话摘要" json:"summary"`                    // 会话摘要
+	Title      string    `gorm:"column:title;type:varchar(100);comment:会话标题" json:"title"`                                     // 会话标题
+	LastActive time.Time `gorm:"column:last_active;type:datetime;comment:最后活跃时间" json:"last_active"`                           // 最后活跃时间
+	Archived   bool      `gorm:"column:archived;type:tinyint(1);default:false;comment:是否归档" json:"archived"`                   // 是否归档
+	ArchivedAt time.Time `gorm:"column:archived_at;type:datetime;comment:归档时间" json:"archived_at"`                             // 归档时间
+	ParentID   string    `gorm:"column:parent_id;type:varchar(100);comment:归档来源会话ID" json:"parent_id"`                         // 归档来源会话ID
+	Metadata   string    `gorm:"column:metadata;type:text;serializer:encrypted;comment:元数据(JSON格式)" json:"metadata,omitempty"` // 元数据，如会话级工具策略
+	MergedInto string    `gorm:"column:merged_into;type:varchar(100);comment:合并到的会话ID" json:"merged_into,omitempty"`           // 合并到的会话ID，发往本会话的消息转到该会话
 }
 
 // TableName returns the table name for Session.
@@ -29,6 +36,8 @@ type QuerySession struct {
 	KeyWord string `json:"key_word"`
 	Channel string `json:"channel"`
 	UserID  string `json:"user_id"`
+	// Archived 为空时不过滤归档状态
+	Archived *bool `json:"archived"`
 }
 
 type ResQuerySession struct {
@@ -77,6 +86,157 @@ func (s *SessionStorage) GetBySessionID(channel, sessionID string) (*Session, er
 	return &sess, nil
 }
 
+// Touch updates the last active time of a session, creating it if missing.
+func (s *SessionStorage) Touch(channel, sessionID, userID string) error {
+	sess, err := s.GetBySessionID(channel, sessionID)
+	if err != nil {
+		if !errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+			return err
+		}
+		sess = &Session{
+			Model:   Model{ID: sessionID},
+			Channel: channel,
+			UserID:  userID,
+		}
+	}
+	if sess.UserID == "" {
+		sess.UserID = userID
+	}
+	return s.Save(sess)
+}
+
+// GetMetadata decodes a session metadata entry into v.
+// It reports false when the session or the key does not exist.
+func (s *SessionStorage) GetMetadata(channel, sessionID, key string, v any) (bool, error) {
+	sess, err := s.GetBySessionID(channel, sessionID)
+	if errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	meta, err := sess.metadata()
+	if err != nil {
+		return false, err
+	}
+	raw, ok := meta[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("failed to decode session metadata %q: %w", key, err)
+	}
+	return true, nil
+}
+
+// SetMetadata sets a session metadata entry, creating the session if missing.
+// A nil value removes the entry.
+func (s *SessionStorage) SetMetadata(channel, sessionID, key string, value any) error {
+	sess, err := s.GetBySessionID(channel, sessionID)
+	if err != nil {
+		if !errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+			return err
+		}
+		sess = &Session{Model: Model{ID: sessionID}, Channel: channel}
+	}
+
+	meta, err := sess.metadata()
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		delete(meta, key)
+	} else {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode session metadata %q: %w", key, err)
+		}
+		meta[key] = raw
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode session metadata: %w", err)
+	}
+	sess.Metadata = string(data)
+	return s.Save(sess)
+}
+
+// metadata 解析会话元数据。
+func (sess *Session) metadata() (map[string]json.RawMessage, error) {
+	meta := make(map[string]json.RawMessage)
+	if sess.Metadata == "" {
+		return meta, nil
+	}
+	if err := json.Unmarshal([]byte(sess.Metadata), &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode session metadata: %w", err)
+	}
+	return meta, nil
+}
+
+// ListIdle lists active sessions whose last activity is before the given time.
+func (s *SessionStorage) ListIdle(before time.Time) ([]*Session, error) {
+	var sessions []*Session
+	result := s.db.Where("archived = ? AND last_active < ?", false, before).
+		Order("last_active").
+		Find(&sessions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list idle sessions: %w", result.Error)
+	}
+	return sessions, nil
+}
+
+// CountActive counts sessions that are not archived.
+func (s *SessionStorage) CountActive() (int64, error) {
+	var count int64
+	if result := s.db.Model(&Session{}).Where("archived = ?", false).Count(&count); result.Error != nil {
+		return 0, fmt.Errorf("failed to count sessions: %w", result.Error)
+	}
+	return count, nil
+}
+
+// ListActiveAfter lists up to limit sessions that are not archived, in ID
+// order after cursor.
+func (s *SessionStorage) ListActiveAfter(cursor string, limit int) ([]*Session, error) {
+	var sessions []*Session
+	result := s.db.Where("archived = ? AND id > ?", false, cursor).
+		Order("id").
+		Limit(limit).
+		Find(&sessions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", result.Error)
+	}
+	return sessions, nil
+}
+
+// ListArchives lists the archives of a session, oldest first.
+func (s *SessionStorage) ListArchives(channel, id string) ([]*Session, error) {
+	var sessions []*Session
+	result := s.db.Where("channel = ? AND parent_id = ? AND archived = ?", channel, id, true).
+		Order("archived_at").
+		Find(&sessions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list archives: %w", result.Error)
+	}
+	return sessions, nil
+}
+
+// SetSummary updates the summary of a session without touching its last
+// activity.
+func (s *SessionStorage) SetSummary(channel, id, summary string) error {
+	summary, err := encryptValue(s.db, summary)
+	if err != nil {
+		return fmt.Errorf("failed to update summary: %w", err)
+	}
+	result := s.db.Model(&Session{}).Where("channel = ? AND id = ?", channel, id).
+		UpdateColumn("summary", summary)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update summary: %w", result.Error)
+	}
+	return nil
+}
+
 // Delete deletes a session.
 func (s *SessionStorage) Delete(id string) error {
 	result := s.db.Where("id = ?", id).Delete(&Session{})
@@ -95,6 +255,10 @@ func (s *SessionStorage) Page(query *QuerySession) (*ResQuerySession, error) {
 			query.Channel, "%"+query.KeyWord+"%").
 		Order("last_active DESC")
 
+	if query.Archived != nil {
+		qry = qry.Where("archived = ?", *query.Archived)
+	}
+
 	var count int64
 	result := qry.Count(&count)
 	if result.Error != nil {
@@ -12,11 +12,12 @@ import (
 // Skill represents a skill configuration.
 type Skill struct {
 	Model
-	Name        string `gorm:"column:name;type:varchar(100);uniqueIndex;not null;comment:技能名称" json:"name"` // 技能名称
-	Description string `gorm:"column:description;type:text;comment:技能描述" json:"description"`                // 技能描述
-	Enabled     bool   `gorm:"column:enabled;type:tinyint(1);default:true;comment:是否启用" json:"enabled"`     // 是否启用
-	Version     string `gorm:"column:version;type:varchar(10);default:1.0.0;comment:版本号" json:"version"`    // 版本号
-	Path        string `gorm:"column:path;type:text;comment:技能路径" json:"path"`                              // 技能路径 默认 workspace/.skills/<name>-<version>/
+	Name        string        `gorm:"column:name;type:varchar(100);uniqueIndex;not null;comment:技能名称" json:"name"` // 技能名称
+	Description string        `gorm:"column:description;type:text;comment:技能描述" json:"description"`                // 技能描述
+	Enabled     bool          `gorm:"column:enabled;type:tinyint(1);default:true;comment:是否启用" json:"enabled"`     // 是否启用
+	Version     string        `gorm:"column:version;type:varchar(10);default:1.0.0;comment:版本号" json:"version"`    // 版本号
+	Path        string        `gorm:"column:path;type:text;comment:技能路径" json:"path"`                              // 技能路径 默认 workspace/.skills/<name>-<version>/
+	Triggers    SkillTriggers `gorm:"column:triggers;type:text;comment:触发规则(JSON数组)" json:"triggers"`              // 自动激活技能的触发规则
 }
 
 // TableName returns the table name for Skill.
@@ -36,13 +37,14 @@ func NewSkillStorage(db *gorm.DB) *SkillStorage {
 func (s *SkillStorage) SaveSkill(sk *Skill) error {
 	return s.db.Table(sk.TableName()).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "name"}},
-		DoUpdates: clause.AssignmentColumns([]string{"description", "enabled", "version", "path", "updated_at"}),
+		DoUpdates: clause.AssignmentColumns([]string{"description", "enabled", "version", "path", "triggers", "updated_at"}),
 	}).Create(map[string]interface{}{
 		"name":        sk.Name,
 		"description": sk.Description,
 		"enabled":     sk.Enabled,
 		"version":     sk.Version,
 		"path":        sk.Path,
+		"triggers":    sk.Triggers,
 	}).Error
 }
 
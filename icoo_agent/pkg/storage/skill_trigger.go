@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Skill trigger types.
+const (
+	SkillTriggerRegex    = "regex"    // 入站消息匹配正则表达式
+	SkillTriggerSchedule = "schedule" // 按 cron 表达式定时触发
+	SkillTriggerEvent    = "event"    // 消息总线上发布指定类型的事件
+)
+
+// SkillTrigger 自动激活技能的触发规则。
+type SkillTrigger struct {
+	Type      string      `json:"type"`                 // 触发类型：regex、schedule、event
+	Pattern   string      `json:"pattern,omitempty"`    // regex 类型的正则表达式
+	Schedule  string      `json:"schedule,omitempty"`   // schedule 类型的 cron 表达式（5 段）
+	Event     string      `json:"event,omitempty"`      // event 类型的事件类型，* 结尾表示前缀匹配
+	Channels  StringArray `json:"channels,omitempty"`   // regex 类型适用的渠道，为空表示全部
+	Priority  int         `json:"priority,omitempty"`   // 多个技能同时命中时优先级高的生效
+	Channel   string      `json:"channel,omitempty"`    // schedule 和 event 类型发送到的渠道，event 默认为事件所在渠道
+	SessionID string      `json:"session_id,omitempty"` // schedule 和 event 类型发送到的会话，event 默认为事件所在会话
+	Prompt    string      `json:"prompt,omitempty"`     // schedule 和 event 类型触发时交给智能体的消息
+}
+
+// Describe returns the condition of the trigger for logs and audits.
+func (t SkillTrigger) Describe() string {
+	switch t.Type {
+	case SkillTriggerRegex:
+		return t.Type + ":" + t.Pattern
+	case SkillTriggerSchedule:
+		return t.Type + ":" + t.Schedule
+	case SkillTriggerEvent:
+		return t.Type + ":" + t.Event
+	}
+	return t.Type
+}
+
+// SkillTriggers is a list of skill triggers stored as JSON.
+type SkillTriggers []SkillTrigger
+
+// Scan implements sql.Scanner.
+func (t *SkillTriggers) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported skill triggers type %T", value)
+	}
+	if len(data) == 0 {
+		*t = nil
+		return nil
+	}
+	if err := json.Unmarshal(data, t); err != nil {
+		return fmt.Errorf("failed to decode skill triggers: %w", err)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (t SkillTriggers) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode skill triggers: %w", err)
+	}
+	return string(data), nil
+}
+
+// SkillFiring 技能触发记录，记录每次触发是否激活了技能。
+type SkillFiring struct {
+	Model
+	Skill     string `gorm:"column:skill;type:varchar(100);index;comment:技能名称" json:"skill"`               // 技能名称
+	Trigger   string `gorm:"column:rule;type:text;comment:触发条件" json:"trigger"`                            // 触发条件，如 regex:^部署
+	Channel   string `gorm:"column:channel;type:varchar(50);comment:渠道" json:"channel"`                    // 渠道
+	SessionID string `gorm:"column:session_id;type:varchar(100);comment:会话ID" json:"session_id"`           // 会话ID
+	Detail    string `gorm:"column:detail;type:text;comment:触发内容" json:"detail"`                           // 命中的文本或事件类型
+	Activated bool   `gorm:"column:activated;type:tinyint(1);default:false;comment:是否激活" json:"activated"` // 是否激活了技能
+	Reason    string `gorm:"column:reason;type:text;comment:未激活原因" json:"reason,omitempty"`                // 未激活的原因，如被优先级更高的技能覆盖
+}
+
+// TableName returns the table name for SkillFiring.
+func (SkillFiring) TableName() string {
+	return tableNamePrefix + "skill_firings"
+}
+
+// SkillFiringStorage stores the audit of skill trigger firings.
+type SkillFiringStorage struct {
+	db *gorm.DB
+}
+
+// NewSkillFiringStorage creates a skill firing storage.
+func NewSkillFiringStorage(db *gorm.DB) *SkillFiringStorage {
+	return &SkillFiringStorage{db: db}
+}
+
+// Save records trigger firings.
+func (s *SkillFiringStorage) Save(firings ...*SkillFiring) error {
+	if len(firings) == 0 {
+		return nil
+	}
+	if err := s.db.Create(firings).Error; err != nil {
+		return fmt.Errorf("failed to save skill firings: %w", err)
+	}
+	return nil
+}
+
+// List lists the newest firings, optionally of one skill.
+func (s *SkillFiringStorage) List(skill string, limit int) ([]*SkillFiring, error) {
+	qry := s.db.Order("created_at DESC")
+	if skill != "" {
+		qry = qry.Where("skill = ?", skill)
+	}
+	if limit > 0 {
+		qry = qry.Limit(limit)
+	}
+	var firings []*SkillFiring
+	if err := qry.Find(&firings).Error; err != nil {
+		return nil, fmt.Errorf("failed to list skill firings: %w", err)
+	}
+	return firings, nil
+}
+
+// Prune deletes firings recorded before the given time.
+func (s *SkillFiringStorage) Prune(before time.Time) (int64, error) {
+	result := s.db.Where("created_at < ?", before).Delete(&SkillFiring{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune skill firings: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
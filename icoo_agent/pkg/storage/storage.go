@@ -2,6 +2,8 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"gorm.io/driver/sqlite"
@@ -11,6 +13,7 @@ import (
 // Storage provides SQLite-based storage using GORM.
 type Storage struct {
 	db        *gorm.DB
+	history   *gorm.DB // 消息和记忆所在的数据库，未单独配置时与 db 相同
 	path      string
 	skill     *SkillStorage
 	binding   *BindingStorage
@@ -24,6 +27,34 @@ type Storage struct {
 	param     *ParamStorage
 	task      *TaskStorage
 	workspace *WorkspaceStorage
+	kv        *KVStorage
+	offline   *OfflineStorage
+	entity    *EntityStorage
+	trace     *TraceStorage
+	dedup     *DedupStorage
+	lock      *LockStorage
+	job       *JobStorage
+	faq       *FAQStorage
+	firing    *SkillFiringStorage
+	artifact  *ArtifactStorage
+	audit     *AuditStorage
+	cipher    *Cipher
+}
+
+// Option configures a Storage.
+type Option func(*options)
+
+type options struct {
+	cipher      *Cipher
+	historyPath string
+}
+
+// WithCipher encrypts message and memory content at rest with c. A nil
+// cipher leaves encryption disabled.
+func WithCipher(c *Cipher) Option {
+	return func(o *options) {
+		o.cipher = c
+	}
 }
 
 func (s *Storage) Skill() *SkillStorage {
@@ -74,34 +105,91 @@ func (s *Storage) Workspace() *WorkspaceStorage {
 	return s.workspace
 }
 
-// New creates a new Storage instance.
-func New(workspace string, mode string, path string) (*Storage, error) {
-	db, err := gorm.Open(sqlite.Open(path+"?_journal_mode=WAL&_busy_timeout=5000"), &gorm.Config{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+func (s *Storage) KV() *KVStorage {
+	return s.kv
+}
+
+func (s *Storage) Offline() *OfflineStorage {
+	return s.offline
+}
+
+func (s *Storage) Entity() *EntityStorage {
+	return s.entity
+}
+
+func (s *Storage) Trace() *TraceStorage {
+	return s.trace
+}
+
+func (s *Storage) Dedup() *DedupStorage {
+	return s.dedup
+}
+
+func (s *Storage) Lock() *LockStorage {
+	return s.lock
+}
+
+func (s *Storage) Job() *JobStorage {
+	return s.job
+}
+
+func (s *Storage) FAQ() *FAQStorage {
+	return s.faq
+}
+
+// SkillFiring returns the skill trigger firing audit storage.
+func (s *Storage) SkillFiring() *SkillFiringStorage {
+	return s.firing
+}
+
+func (s *Storage) Artifact() *ArtifactStorage {
+	return s.artifact
+}
+
+// Audit returns the tool invocation audit storage.
+func (s *Storage) Audit() *AuditStorage {
+	return s.audit
+}
+
+// WithHistoryPath stores conversation history (messages and memories) in a
+// separate SQLite database at path, for example on an encrypted volume, while
+// operational data stays in the main database. An empty path or the path of
+// the main database keeps everything in one database.
+func WithHistoryPath(path string) Option {
+	return func(o *options) {
+		o.historyPath = path
 	}
+}
 
-	// 开启调试模式
-	if mode == "debug" {
-		db = db.Debug()
+// New creates a new Storage instance.
+func New(workspace string, mode string, path string, opts ...Option) (*Storage, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	// Get underlying sql.DB 获取数据库连接池设置
-	sqlDB, err := db.DB()
+	db, err := open(path, mode, o.cipher)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get underlying db: %w", err)
+		return nil, err
+	}
+	history := db
+	if o.historyPath != "" && o.historyPath != path {
+		if history, err = open(o.historyPath, mode, o.cipher); err != nil {
+			closeDB(db)
+			return nil, fmt.Errorf("history database: %w", err)
+		}
 	}
-	sqlDB.SetMaxOpenConns(1) // SQLite recommends single connection
-	sqlDB.SetMaxIdleConns(1)
 
 	s := &Storage{
 		db:        db,
+		history:   history,
 		path:      path,
+		cipher:    o.cipher,
 		skill:     NewSkillStorage(db),
 		binding:   NewBindingStorage(db),
 		session:   NewSessionStorage(db),
-		message:   NewMessageStorage(db),
-		memory:    NewMemoryStorage(db),
+		message:   NewMessageStorage(history),
+		memory:    NewMemoryStorage(history),
 		tool:      NewToolStorage(db),
 		provider:  NewProviderStorage(db),
 		mcp:       NewMCPStorage(db),
@@ -109,42 +197,146 @@ func New(workspace string, mode string, path string) (*Storage, error) {
 		param:     NewParamStorage(db),
 		task:      NewTaskStorage(db),
 		workspace: NewWorkspaceStorage(workspace),
+		kv:        NewKVStorage(db),
+		offline:   NewOfflineStorage(db),
+		entity:    NewEntityStorage(db),
+		trace:     NewTraceStorage(db),
+		dedup:     NewDedupStorage(db),
+		lock:      NewLockStorage(db),
+		job:       NewJobStorage(db),
+		faq:       NewFAQStorage(db),
+		firing:    NewSkillFiringStorage(db),
+		artifact:  NewArtifactStorage(db),
+		audit:     NewAuditStorage(db),
 	}
 
 	if err := s.autoMigrate(); err != nil {
+		s.Close()
 		return nil, fmt.Errorf("failed to migrate: %w", err)
 	}
 
 	return s, nil
 }
 
+// open opens a SQLite database.
+func open(path, mode string, cipher *Cipher) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path+"?_journal_mode=WAL&_busy_timeout=5000"), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// 开启调试模式
+	if mode == "debug" {
+		db = db.Debug()
+	}
+
+	// Get underlying sql.DB 获取数据库连接池设置
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying db: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1) // SQLite recommends single connection
+	sqlDB.SetMaxIdleConns(1)
+
+	// 加密列通过语句上下文中的密钥加解密
+	if cipher != nil {
+		db = withCipher(db, cipher)
+	}
+	return db, nil
+}
+
+// closeDB closes the connection pool of db.
+func closeDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// historyModels are the models stored in the history database.
+var historyModels = []any{&Memory{}, &Message{}}
+
 // autoMigrate runs auto migration for all models.
 func (s *Storage) autoMigrate() error {
+	if err := s.history.AutoMigrate(historyModels...); err != nil {
+		return err
+	}
 	return s.db.AutoMigrate(
 		&Provider{},
 		&Channel{},
 		&Session{},
 		&Binding{},
-		&Memory{},
-		&Message{},
 		&Tool{},
 		&Skill{},
 		&MCPConfig{},
 		&ParamConfig{},
 		&Task{},
+		&KV{},
+		&OfflineMessage{},
+		&Entity{},
+		&EntityRelation{},
+		&EntityFact{},
+		&Trace{},
+		&Dedup{},
+		&Lock{},
+		&Job{},
+		&FAQ{},
+		&Artifact{},
+		&SkillFiring{},
+		&ToolAudit{},
 	)
 }
 
-// Close closes the database connection.
+// Close closes the database connections.
 func (s *Storage) Close() error {
-	sqlDB, err := s.db.DB()
-	if err != nil {
+	err := closeDB(s.db)
+	if s.SplitHistory() {
+		err = errors.Join(err, closeDB(s.history))
+	}
+	return err
+}
+
+// Ping checks that the databases answer a query within ctx.
+func (s *Storage) Ping(ctx context.Context) error {
+	if err := s.db.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
 		return err
 	}
-	return sqlDB.Close()
+	if s.SplitHistory() {
+		if err := s.history.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
+			return fmt.Errorf("history database: %w", err)
+		}
+	}
+	return nil
 }
 
-// DB returns the underlying GORM database.
+// DB returns the underlying GORM database of operational data.
 func (s *Storage) DB() *gorm.DB {
 	return s.db
 }
+
+// HistoryDB returns the GORM database holding messages and memories, which is
+// the same as DB unless history is stored separately.
+func (s *Storage) HistoryDB() *gorm.DB {
+	return s.history
+}
+
+// SplitHistory reports whether messages and memories are stored in a separate database.
+func (s *Storage) SplitHistory() bool {
+	return s.history != s.db
+}
+
+// transaction runs fn with a transaction on the operational database and one
+// on the history database. They are the same transaction unless history is
+// stored separately; then the history transaction commits first, and a
+// failure to commit the operational one afterwards cannot roll it back.
+func (s *Storage) transaction(fn func(tx, htx *gorm.DB) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if !s.SplitHistory() {
+			return fn(tx, tx)
+		}
+		return s.history.Transaction(func(htx *gorm.DB) error {
+			return fn(tx, htx)
+		})
+	})
+}
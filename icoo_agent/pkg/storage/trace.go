@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Trace 单轮对话的完整轨迹，Data 为 JSON 格式的轨迹详情，其余字段用于列表展示。
+type Trace struct {
+	Model
+	Channel     string `gorm:"column:channel;type:varchar(50);not null;index:idx_trace_session;comment:渠道" json:"channel"`          // 渠道
+	SessionID   string `gorm:"column:session_id;type:varchar(100);not null;index:idx_trace_session;comment:会话ID" json:"session_id"` // 会话ID
+	ModelName   string `gorm:"column:model_name;type:varchar(100);comment:模型名称" json:"model"`                                       // 模型名称
+	Input       string `gorm:"column:input;type:text;serializer:encrypted;comment:用户消息摘要" json:"input"`                             // 用户消息摘要
+	Iterations  int    `gorm:"column:iterations;type:int;default:0;comment:迭代次数" json:"iterations"`                                 // 迭代次数
+	ToolCalls   int    `gorm:"column:tool_calls;type:int;default:0;comment:工具调用次数" json:"tool_calls"`                               // 工具调用次数
+	TotalTokens int    `gorm:"column:total_tokens;type:int;default:0;comment:Token 总用量" json:"total_tokens"`                        // Token 总用量
+	DurationMs  int64  `gorm:"column:duration_ms;type:bigint;default:0;comment:耗时(毫秒)" json:"duration_ms"`                          // 耗时
+	Error       string `gorm:"column:error;type:text;comment:错误信息" json:"error,omitempty"`                                          // 错误信息
+	Data        string `gorm:"column:data;type:text;serializer:encrypted;comment:轨迹详情(JSON格式)" json:"-"`                            // 轨迹详情
+}
+
+// TableName returns the table name for Trace.
+func (Trace) TableName() string {
+	return tableNamePrefix + "traces"
+}
+
+type TraceStorage struct {
+	db *gorm.DB
+}
+
+func NewTraceStorage(db *gorm.DB) *TraceStorage {
+	return &TraceStorage{db: db}
+}
+
+// Save saves a trace.
+func (s *TraceStorage) Save(t *Trace) error {
+	if result := s.db.Create(t); result.Error != nil {
+		return fmt.Errorf("failed to save trace: %w", result.Error)
+	}
+	return nil
+}
+
+// Get gets a trace by ID, including its data.
+func (s *TraceStorage) Get(id string) (*Trace, error) {
+	var t Trace
+	if result := s.db.Where("id = ?", id).First(&t); result.Error != nil {
+		return nil, fmt.Errorf("failed to get trace: %w", result.Error)
+	}
+	return &t, nil
+}
+
+// Latest gets the most recent trace of a session, including its data.
+func (s *TraceStorage) Latest(channel, sessionID string) (*Trace, error) {
+	var t Trace
+	result := s.db.Where("channel = ? AND session_id = ?", channel, sessionID).
+		Order("created_at DESC").
+		First(&t)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get trace: %w", result.Error)
+	}
+	return &t, nil
+}
+
+// ListBySession lists traces of a session without their data, newest first.
+func (s *TraceStorage) ListBySession(channel, sessionID string, limit int) ([]*Trace, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	var traces []*Trace
+	result := s.db.Omit("data").
+		Where("channel = ? AND session_id = ?", channel, sessionID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&traces)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list traces: %w", result.Error)
+	}
+	return traces, nil
+}
+
+// Prune keeps only the newest keep traces of a session.
+func (s *TraceStorage) Prune(channel, sessionID string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	newest := s.db.Model(&Trace{}).Select("id").
+		Where("channel = ? AND session_id = ?", channel, sessionID).
+		Order("created_at DESC").
+		Limit(keep)
+	result := s.db.Where("channel = ? AND session_id = ?", channel, sessionID).
+		Where("id NOT IN (?)", newest).
+		Delete(&Trace{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to prune traces: %w", result.Error)
+	}
+	return nil
+}
+
+// ListBetween lists traces created in [from, to), including their data, oldest first.
+func (s *TraceStorage) ListBetween(from, to time.Time) ([]*Trace, error) {
+	var traces []*Trace
+	result := s.db.Where("created_at >= ? AND created_at < ?", from, to).
+		Order("created_at ASC").
+		Find(&traces)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list traces: %w", result.Error)
+	}
+	return traces, nil
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"icooclaw/pkg/consts"
+)
+
+// UserData 某个用户在存储中的全部数据，包括归档会话。
+type UserData struct {
+	UserID          string            `json:"user_id"`
+	Sessions        []*Session        `json:"sessions"`
+	Messages        []*Message        `json:"messages"`
+	Memories        []*Memory         `json:"memories"`
+	Traces          []*Trace          `json:"traces"`
+	OfflineMessages []*OfflineMessage `json:"offline_messages"`
+	Bindings        []*Binding        `json:"bindings"`
+	KV              []*KV             `json:"kv"`
+	EntityFacts     []*EntityFact     `json:"entity_facts"`
+	EntityRelations []*EntityRelation `json:"entity_relations"`
+}
+
+// UserDataCounts 用户数据各部分的条数。
+type UserDataCounts struct {
+	Sessions        int `json:"sessions"`
+	Messages        int `json:"messages"`
+	Memories        int `json:"memories"`
+	Traces          int `json:"traces"`
+	OfflineMessages int `json:"offline_messages"`
+	Bindings        int `json:"bindings"`
+	KV              int `json:"kv"`
+	EntityFacts     int `json:"entity_facts"`
+	EntityRelations int `json:"entity_relations"`
+}
+
+// Counts 统计各部分的条数。
+func (d *UserData) Counts() UserDataCounts {
+	return UserDataCounts{
+		Sessions:        len(d.Sessions),
+		Messages:        len(d.Messages),
+		Memories:        len(d.Memories),
+		Traces:          len(d.Traces),
+		OfflineMessages: len(d.OfflineMessages),
+		Bindings:        len(d.Bindings),
+		KV:              len(d.KV),
+		EntityFacts:     len(d.EntityFacts),
+		EntityRelations: len(d.EntityRelations),
+	}
+}
+
+// CollectUserData collects everything stored for a user: their sessions (archived ones included)
+// and the messages, memories, traces, offline messages, bindings, key-value entries and
+// entity facts/relations that belong to those sessions.
+func (s *Storage) CollectUserData(userID string) (*UserData, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user id is required")
+	}
+
+	data := &UserData{UserID: userID}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&data.Sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	var sessionKeys, sessionIDs []string
+	channels := make(map[string]bool)
+	for _, sess := range data.Sessions {
+		sessionKeys = append(sessionKeys, consts.GetSessionKey(sess.Channel, sess.ID))
+		sessionIDs = append(sessionIDs, sess.ID)
+		channels[sess.Channel] = true
+	}
+
+	// 发送者为该用户的离线消息，即使会话记录不存在也属于该用户
+	offline := s.db.Where("sender_id = ?", userID)
+	if len(sessionIDs) > 0 {
+		offline = offline.Or("session_id IN ?", sessionIDs)
+	}
+	if err := offline.Order("created_at").Find(&data.OfflineMessages).Error; err != nil {
+		return nil, fmt.Errorf("failed to list offline messages: %w", err)
+	}
+
+	// user 作用域的键值按渠道和用户隔离，无法确定用户时按会话隔离
+	var scopes []string
+	for channel := range channels {
+		scopes = append(scopes, fmt.Sprintf("user:%s:%s", channel, userID))
+	}
+	for _, sess := range data.Sessions {
+		scopes = append(scopes, fmt.Sprintf("session:%s:%s", sess.Channel, sess.ID))
+	}
+	if len(scopes) > 0 {
+		if err := s.db.Where("scope IN ?", scopes).Order("created_at").Find(&data.KV).Error; err != nil {
+			return nil, fmt.Errorf("failed to list kv entries: %w", err)
+		}
+	}
+
+	if len(data.Sessions) == 0 {
+		return data, nil
+	}
+
+	if err := s.history.Where("session_id IN ?", sessionKeys).Order("created_at").Find(&data.Messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	if err := s.history.Where("session_id IN ?", sessionKeys).Order("created_at").Find(&data.Memories).Error; err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+	if err := s.db.Where("session_id IN ?", sessionIDs).Order("created_at").Find(&data.EntityFacts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list entity facts: %w", err)
+	}
+	if err := s.db.Where("session_id IN ?", sessionIDs).Order("created_at").Find(&data.EntityRelations).Error; err != nil {
+		return nil, fmt.Errorf("failed to list entity relations: %w", err)
+	}
+
+	// 轨迹和绑定按 渠道+会话 区分，会话 ID 可能在不同渠道重复
+	for _, sess := range data.Sessions {
+		var traces []*Trace
+		if err := s.db.Where("channel = ? AND session_id = ?", sess.Channel, sess.ID).Order("created_at").Find(&traces).Error; err != nil {
+			return nil, fmt.Errorf("failed to list traces: %w", err)
+		}
+		data.Traces = append(data.Traces, traces...)
+
+		var bindings []*Binding
+		if err := s.db.Where("channel = ? AND session_id = ?", sess.Channel, sess.ID).Find(&bindings).Error; err != nil {
+			return nil, fmt.Errorf("failed to list bindings: %w", err)
+		}
+		data.Bindings = append(data.Bindings, bindings...)
+	}
+
+	// 离线消息按会话 ID 匹配时可能命中其他渠道的同名会话
+	owned := make(map[string]bool, len(data.Sessions))
+	for _, sess := range data.Sessions {
+		owned[consts.GetSessionKey(sess.Channel, sess.ID)] = true
+	}
+	filtered := data.OfflineMessages[:0]
+	for _, m := range data.OfflineMessages {
+		if m.SenderID == userID || owned[consts.GetSessionKey(m.Channel, m.SessionID)] {
+			filtered = append(filtered, m)
+		}
+	}
+	data.OfflineMessages = filtered
+
+	return data, nil
+}
+
+// DeleteUserData deletes the records collected by CollectUserData in one transaction,
+// one per database when history is stored separately.
+// Entities themselves are shared between users and are kept; only the facts and
+// relations learned from the user's sessions are removed.
+func (s *Storage) DeleteUserData(data *UserData) error {
+	return s.transaction(func(tx, htx *gorm.DB) error {
+		deletes := []struct {
+			db    *gorm.DB
+			model any
+			ids   []string
+		}{
+			{htx, &Message{}, idsOf(data.Messages)},
+			{htx, &Memory{}, idsOf(data.Memories)},
+			{tx, &Trace{}, idsOf(data.Traces)},
+			{tx, &OfflineMessage{}, idsOf(data.OfflineMessages)},
+			{tx, &Binding{}, idsOf(data.Bindings)},
+			{tx, &KV{}, idsOf(data.KV)},
+			{tx, &EntityFact{}, idsOf(data.EntityFacts)},
+			{tx, &EntityRelation{}, idsOf(data.EntityRelations)},
+			{tx, &Session{}, idsOf(data.Sessions)},
+		}
+		for _, d := range deletes {
+			// 分批删除，避免超出 SQLite 的参数个数限制
+			for start := 0; start < len(d.ids); start += deleteBatchSize {
+				end := min(start+deleteBatchSize, len(d.ids))
+				if err := d.db.Where("id IN ?", d.ids[start:end]).Delete(d.model).Error; err != nil {
+					return fmt.Errorf("failed to delete user data: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// deleteBatchSize 每批删除的记录数
+const deleteBatchSize = 500
+
+// identified 带主键的模型。
+type identified interface {
+	primaryKey() string
+}
+
+// primaryKey 返回记录主键。
+func (m *Model) primaryKey() string {
+	return m.ID
+}
+
+// idsOf 提取记录主键。
+func idsOf[T identified](records []T) []string {
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.primaryKey())
+	}
+	return ids
+}
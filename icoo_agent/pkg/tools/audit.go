@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"time"
+)
+
+// Invocation 一次工具调用，包括被拒绝或跳过的调用，交给审计钩子记录。
+type Invocation struct {
+	Name      string
+	Args      map[string]any
+	Result    *Result
+	Duration  time.Duration
+	Channel   string
+	SessionID string
+	UserID    string
+	Denied    bool // 被会话策略或授权钩子拒绝，未执行
+	Skipped   bool // 只读工作目录中跳过执行
+}
+
+// Auditor 工具调用的审计钩子，每次调用结束后同步调用，实现应尽快返回。
+type Auditor interface {
+	AuditTool(ctx context.Context, inv Invocation)
+}
+
+// SetAuditor 设置工具调用的审计钩子，nil 表示不审计。
+func (r *Registry) SetAuditor(a Auditor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditor = a
+}
+
+// audit 将调用交给审计钩子。
+func (r *Registry) audit(ctx context.Context, inv Invocation) {
+	r.mu.RLock()
+	a := r.auditor
+	r.mu.RUnlock()
+	if a == nil {
+		return
+	}
+	inv.UserID = GetSender(ctx).ID
+	a.AuditTool(ctx, inv)
+}
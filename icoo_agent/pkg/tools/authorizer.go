@@ -0,0 +1,22 @@
+package tools
+
+import "context"
+
+// Authorizer 工具执行前的授权钩子，返回非 nil 错误时拒绝本次调用，错误信息会返回给模型。
+type Authorizer interface {
+	AuthorizeTool(ctx context.Context, name string, args map[string]any) error
+}
+
+// SetAuthorizer 设置工具执行前的授权钩子，nil 表示不做额外授权。
+func (r *Registry) SetAuthorizer(a Authorizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.authorizer = a
+}
+
+// getAuthorizer 返回当前的授权钩子。
+func (r *Registry) getAuthorizer() Authorizer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.authorizer
+}
@@ -0,0 +1,37 @@
+package artifact
+
+import (
+	"regexp"
+
+	"icooclaw/pkg/storage"
+)
+
+// referenceRegex 回复中对制品的引用
+var referenceRegex = regexp.MustCompile(`\[artifact:([0-9a-fA-F-]{36})\]`)
+
+// Reference 返回在回复中引用制品的写法。
+func Reference(id string) string {
+	return "[artifact:" + id + "]"
+}
+
+// Resolve replaces the artifact references in text with the file name and
+// returns the referenced artifacts of the given session, each once, in the order
+// they are referenced. Unknown IDs and artifacts of other sessions are left as is.
+func Resolve(text, channel, sessionID string, store *storage.ArtifactStorage) (string, []*storage.Artifact) {
+	var artifacts []*storage.Artifact
+	seen := make(map[string]bool)
+
+	text = referenceRegex.ReplaceAllStringFunc(text, func(ref string) string {
+		id := referenceRegex.FindStringSubmatch(ref)[1]
+		a, err := store.GetByID(id)
+		if err != nil || a.Channel != channel || a.SessionID != sessionID {
+			return ref
+		}
+		if !seen[id] {
+			seen[id] = true
+			artifacts = append(artifacts, a)
+		}
+		return "📎 " + a.Name + "（制品 " + a.ID + "）"
+	})
+	return text, artifacts
+}
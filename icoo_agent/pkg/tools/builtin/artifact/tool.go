@@ -0,0 +1,196 @@
+// Package artifact 提供制品工具：智能体生成的较大产物（报告、脚本、图表等）保存到会话的工作目录，
+// 回复中以 [artifact:<id>] 引用，支持文件的渠道作为附件发送，而不是在回复里贴出整段代码块。
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+// Dir 工作目录中存放制品的子目录，每个制品一个 artifacts/<id> 目录
+const Dir = "artifacts"
+
+// MaxSize 单个制品的大小上限
+const MaxSize = 10 << 20
+
+// 制品类型
+var kinds = []string{"report", "script", "diagram", "data", "other"}
+
+// Tool 保存制品。
+type Tool struct {
+	store   *storage.ArtifactStorage
+	workDir string
+}
+
+// NewTool 创建 save_artifact 工具，workDir 为会话未选择工作目录时使用的默认目录。
+func NewTool(store *storage.ArtifactStorage, workDir string) *Tool {
+	if workDir == "" {
+		workDir = "./workspace"
+	}
+	return &Tool{store: store, workDir: workDir}
+}
+
+// Name 工具名称.
+func (t *Tool) Name() string {
+	return "save_artifact"
+}
+
+// Description 工具描述.
+func (t *Tool) Description() string {
+	return "将较长的生成内容（报告、脚本、图表源码、数据文件等）保存为制品。保存后在回复中写 " +
+		"[artifact:<id>] 引用它，支持文件的渠道会作为附件发送；不要再在回复中贴出全文，只需简要说明内容。"
+}
+
+// Parameters 工具参数.
+func (t *Tool) Parameters() map[string]any {
+	return map[string]any{
+		"name": map[string]any{
+			"type":        "string",
+			"description": "文件名，包含扩展名，例如 report.md、deploy.sh、flow.mmd",
+			"required":    true,
+		},
+		"content": map[string]any{
+			"type":        "string",
+			"description": "制品的完整内容",
+			"required":    true,
+		},
+		"kind": map[string]any{
+			"type":        "string",
+			"description": "制品类型，默认 other",
+			"enum":        kinds,
+		},
+		"description": map[string]any{
+			"type":        "string",
+			"description": "一句话说明制品的内容",
+		},
+	}
+}
+
+// DescribeChange 实现 tools.Mutator，保存制品会写入工作目录。
+func (t *Tool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	name, _ := args["name"].(string)
+	return "保存制品 " + name, true
+}
+
+// Execute 执行 save_artifact.
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	channel := tools.GetChannel(ctx)
+	sessionID := tools.GetSessionID(ctx)
+	if channel == "" || sessionID == "" {
+		return tools.ErrorResult("缺少会话上下文，无法保存制品")
+	}
+	if tools.IsEphemeral(ctx) {
+		return tools.ErrorResult("无痕会话不保存制品，请直接在回复中给出内容")
+	}
+
+	name := sanitizeName(argString(args, "name"))
+	if name == "" {
+		return tools.ErrorResult("需要提供 name 参数")
+	}
+	content, ok := args["content"].(string)
+	if !ok || content == "" {
+		return tools.ErrorResult("需要提供 content 参数")
+	}
+	kind := argString(args, "kind")
+	if kind == "" {
+		kind = "other"
+	}
+
+	a, err := Save(ctx, t.store, t.workDir, name, kind, argString(args, "description"), []byte(content))
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+	return tools.SuccessResult(fmt.Sprintf("已保存制品 %s（%d 字节）。在回复中写 %s 引用它，不要再贴出全文。",
+		name, a.Size, Reference(a.ID)))
+}
+
+// Save writes content to a new artifact directory in the session workspace
+// (workDir when the session has not selected one) and records it for the
+// session in ctx. Other tools that produce files use it to return artifacts.
+func Save(ctx context.Context, store *storage.ArtifactStorage, workDir, name, kind, description string, content []byte) (*storage.Artifact, error) {
+	if len(content) > MaxSize {
+		return nil, fmt.Errorf("制品超过大小上限 %d MB", MaxSize>>20)
+	}
+
+	id := uuid.New().String()
+	dir := filepath.Join(tools.GetWorkspace(ctx, workDir), Dir, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建制品目录失败: %w", err)
+	}
+	path, err := filepath.Abs(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("解析制品路径失败: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return nil, fmt.Errorf("写入制品失败: %w", err)
+	}
+
+	sum := sha256.Sum256(content)
+	a := &storage.Artifact{
+		Model:       storage.Model{ID: id},
+		Channel:     tools.GetChannel(ctx),
+		SessionID:   tools.GetSessionID(ctx),
+		UserID:      tools.GetSender(ctx).ID,
+		Name:        name,
+		Kind:        kind,
+		MIMEType:    MIMEType(name),
+		Description: description,
+		Path:        path,
+		Size:        int64(len(content)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	if err := store.Create(a); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("保存制品失败: %w", err)
+	}
+	return a, nil
+}
+
+// officeTypes 系统 MIME 表中可能缺少的 Office 文档类型
+var officeTypes = map[string]string{
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// MIMEType 按扩展名推断 MIME 类型，未知扩展名按纯文本处理。
+func MIMEType(name string) string {
+	if t, ok := officeTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// sanitizeName 只保留文件名部分，去掉路径和控制字符。
+func sanitizeName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	return name
+}
+
+func argString(args map[string]any, key string) string {
+	s, _ := args[key].(string)
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,94 @@
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+func newTestTool(t *testing.T) (*Tool, *storage.ArtifactStorage) {
+	t.Helper()
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "artifact.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return NewTool(s.Artifact(), t.TempDir()), s.Artifact()
+}
+
+func TestTool_SaveAndResolve(t *testing.T) {
+	tool, store := newTestTool(t)
+	workspace := t.TempDir()
+	ctx := tools.WithWorkspace(tools.WithToolContext(context.Background(), "websocket", "s1"), workspace)
+
+	result := tool.Execute(ctx, map[string]any{
+		"name":    "../../deploy.sh",
+		"content": "#!/bin/sh\necho ok\n",
+		"kind":    "script",
+	})
+	if result.Error != nil {
+		t.Fatalf("Execute() error = %v", result.Error)
+	}
+
+	m := referenceRegex.FindStringSubmatch(result.Content)
+	if m == nil {
+		t.Fatalf("result has no reference: %s", result.Content)
+	}
+	a, err := store.GetByID(m[1])
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if a.Name != "deploy.sh" || a.Kind != "script" || a.Size != 18 {
+		t.Errorf("artifact = %+v", a)
+	}
+	if !strings.HasPrefix(a.Path, filepath.Join(workspace, Dir)) {
+		t.Errorf("path = %s, want under %s", a.Path, workspace)
+	}
+	if data, err := os.ReadFile(a.Path); err != nil || string(data) != "#!/bin/sh\necho ok\n" {
+		t.Errorf("file = %q, %v", data, err)
+	}
+
+	text := "见 " + Reference(a.ID) + "，再次引用 " + Reference(a.ID)
+	got, artifacts := Resolve(text, "websocket", "s1", store)
+	if len(artifacts) != 1 || artifacts[0].ID != a.ID {
+		t.Errorf("artifacts = %+v", artifacts)
+	}
+	if strings.Contains(got, "[artifact:") || !strings.Contains(got, "📎 deploy.sh") {
+		t.Errorf("Resolve() = %q", got)
+	}
+
+	// 其他会话不能引用
+	got, artifacts = Resolve(text, "websocket", "s2", store)
+	if len(artifacts) != 0 || got != text {
+		t.Errorf("foreign session: %q, %+v", got, artifacts)
+	}
+}
+
+func TestTool_Rejects(t *testing.T) {
+	tool, _ := newTestTool(t)
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+	args := map[string]any{"name": "a.md", "content": "x"}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		args map[string]any
+	}{
+		{"no context", context.Background(), args},
+		{"ephemeral", tools.WithEphemeral(ctx), args},
+		{"no name", ctx, map[string]any{"name": "..", "content": "x"}},
+		{"no content", ctx, map[string]any{"name": "a.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tool.Execute(tt.ctx, tt.args); result.Error == nil {
+				t.Errorf("Execute() = %+v, want error", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package ask
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/storage"
+)
+
+// MetadataKey 等待回答的问题在会话元数据中的键
+const MetadataKey = "ask_user"
+
+// Pending 等待用户回答的问题及暂停时的对话进度。
+type Pending struct {
+	Question   string                  `json:"question"`          // 问题
+	Options    []string                `json:"options,omitempty"` // 候选答案
+	ToolCallID string                  `json:"tool_call_id"`      // ask_user 调用的 ID，回答作为它的结果
+	Text       string                  `json:"text"`              // 本轮的用户消息
+	Persona    string                  `json:"persona,omitempty"` // 路由规则指定的人设
+	Messages   []providers.ChatMessage `json:"messages"`          // 用户消息之后本轮已产生的消息
+	AskedAt    time.Time               `json:"asked_at"`          // 提问时间
+	ExpiresAt  time.Time               `json:"expires_at"`        // 超过该时间未回答时放弃本轮
+}
+
+// Expired 问题是否已过期。
+func (p *Pending) Expired(now time.Time) bool {
+	return !p.ExpiresAt.IsZero() && now.After(p.ExpiresAt)
+}
+
+// Prompt 返回发送给用户的问题，附带编号的候选答案。
+func (p *Pending) Prompt() string {
+	if len(p.Options) == 0 {
+		return p.Question
+	}
+	sb := strings.Builder{}
+	sb.WriteString(p.Question)
+	for i, o := range p.Options {
+		fmt.Fprintf(&sb, "\n%d. %s", i+1, o)
+	}
+	return sb.String()
+}
+
+// Resolve 返回用户的回答，回复候选答案的序号时换成对应的答案。
+func (p *Pending) Resolve(reply string) string {
+	reply = strings.TrimSpace(reply)
+	if n, err := strconv.Atoi(reply); err == nil && n >= 1 && n <= len(p.Options) {
+		return p.Options[n-1]
+	}
+	return reply
+}
+
+// Answer 用户对问题的回答，随恢复的消息交给智能体。
+type Answer struct {
+	Pending *Pending
+	Text    string
+}
+
+// Store 等待回答的问题仓库，保存在会话元数据中，随会话持久化。
+type Store struct {
+	sessions *storage.SessionStorage
+}
+
+// NewStore 创建问题仓库。
+func NewStore(sessions *storage.SessionStorage) *Store {
+	return &Store{sessions: sessions}
+}
+
+// Load 读取会话等待回答的问题，没有时返回 nil。
+func (s *Store) Load(channel, sessionID string) (*Pending, error) {
+	var p Pending
+	ok, err := s.sessions.GetMetadata(channel, sessionID, MetadataKey, &p)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Save 保存会话等待回答的问题，已有的问题被替换。
+func (s *Store) Save(channel, sessionID string, p *Pending) error {
+	return s.sessions.SetMetadata(channel, sessionID, MetadataKey, p)
+}
+
+// Clear 清除会话等待回答的问题。
+func (s *Store) Clear(channel, sessionID string) error {
+	return s.sessions.SetMetadata(channel, sessionID, MetadataKey, nil)
+}
@@ -0,0 +1,86 @@
+// Package ask provides a tool that lets the agent ask the user a clarifying
+// question in the middle of a turn.
+//
+// 调用 ask_user 后本轮对话暂停，问题发送给用户，进度保存在会话元数据中；
+// 用户回复后从暂停处继续，回答作为 ask_user 的工具结果交给模型。超过有效期未回答时放弃本轮。
+package ask
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/tools"
+)
+
+// ToolName 向用户提问的工具名称，调用由智能体循环处理而不是执行。
+const ToolName = "ask_user"
+
+// maxOptions 最多提供的候选答案个数
+const maxOptions = 10
+
+// Tool 向用户提出澄清问题。
+type Tool struct{}
+
+// NewTool 创建 ask_user 工具。
+func NewTool() *Tool {
+	return &Tool{}
+}
+
+// Name 工具名称.
+func (t *Tool) Name() string {
+	return ToolName
+}
+
+// Description 工具描述.
+func (t *Tool) Description() string {
+	return "缺少完成任务所必需、又无法从对话和工具中得知的信息时，向用户提出一个澄清问题。" +
+		"调用后本轮暂停，用户回复后继续，回答作为本工具的结果返回。" +
+		"只在确实需要时使用，每次只问一个问题；可以提供几个候选答案供用户选择。"
+}
+
+// Parameters 工具参数.
+func (t *Tool) Parameters() map[string]any {
+	return map[string]any{
+		"question": map[string]any{
+			"type":        "string",
+			"description": "向用户提出的问题",
+			"required":    true,
+		},
+		"options": map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "string"},
+			"description": fmt.Sprintf("候选答案，最多 %d 个，用户也可以回答其他内容", maxOptions),
+		},
+	}
+}
+
+// Execute ask_user 由智能体循环拦截处理，直接执行时返回错误。
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	return tools.ErrorResult("ask_user 只能在对话中使用")
+}
+
+// ParseArgs 解析 ask_user 的参数，返回问题和去重后的候选答案。
+func ParseArgs(args map[string]any) (question string, options []string, err error) {
+	question, _ = args["question"].(string)
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return "", nil, fmt.Errorf("需要提供 question 参数")
+	}
+
+	raw, _ := args["options"].([]any)
+	seen := make(map[string]bool, len(raw))
+	for _, v := range raw {
+		s, _ := v.(string)
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		options = append(options, s)
+		if len(options) == maxOptions {
+			break
+		}
+	}
+	return question, options, nil
+}
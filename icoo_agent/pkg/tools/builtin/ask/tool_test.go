@@ -0,0 +1,69 @@
+package ask
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/storage"
+)
+
+func TestParseArgs(t *testing.T) {
+	if _, _, err := ParseArgs(map[string]any{"question": "  "}); err == nil {
+		t.Error("empty question should fail")
+	}
+	q, options, err := ParseArgs(map[string]any{
+		"question": " 去哪个城市？ ",
+		"options":  []any{"北京", "", "上海", "北京", 3},
+	})
+	if err != nil || q != "去哪个城市？" || len(options) != 2 || options[1] != "上海" {
+		t.Errorf("ParseArgs() = %q, %v, %v", q, options, err)
+	}
+}
+
+func TestPending(t *testing.T) {
+	p := &Pending{Question: "去哪个城市？", Options: []string{"北京", "上海"}}
+	if got := p.Prompt(); got != "去哪个城市？\n1. 北京\n2. 上海" {
+		t.Errorf("Prompt() = %q", got)
+	}
+	for reply, want := range map[string]string{"2": "上海", " 北京 ": "北京", "3": "3", "广州": "广州"} {
+		if got := p.Resolve(reply); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", reply, got, want)
+		}
+	}
+
+	now := time.Now()
+	if p.Expired(now) {
+		t.Error("pending without expiry should not expire")
+	}
+	p.ExpiresAt = now.Add(-time.Second)
+	if !p.Expired(now) {
+		t.Error("Expired() = false")
+	}
+}
+
+func TestStore(t *testing.T) {
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "ask.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	store := NewStore(s.Session())
+	if p, err := store.Load("websocket", "s1"); err != nil || p != nil {
+		t.Fatalf("Load() = %+v, %v", p, err)
+	}
+	if err := store.Save("websocket", "s1", &Pending{Question: "几点？", ToolCallID: "c1", Text: "订会议室"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	p, err := store.Load("websocket", "s1")
+	if err != nil || p == nil || p.ToolCallID != "c1" || p.Text != "订会议室" {
+		t.Fatalf("Load() = %+v, %v", p, err)
+	}
+	if err := store.Clear("websocket", "s1"); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if p, _ := store.Load("websocket", "s1"); p != nil {
+		t.Errorf("Load() after Clear() = %+v", p)
+	}
+}
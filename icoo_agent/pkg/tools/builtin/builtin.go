@@ -8,10 +8,12 @@ import (
 	"icooclaw/pkg/tools/builtin/file"
 	"icooclaw/pkg/tools/builtin/shell"
 	"icooclaw/pkg/tools/builtin/web"
+	"icooclaw/pkg/vfs"
 )
 
 // RegisterBuiltinTools registers all built-in tools.
-func RegisterBuiltinTools(registry *tools.Registry) {
+// mounts 为文件工具挂载的远程存储，可以为 nil；ignore 为递归列出目录和搜索文件时的忽略规则，nil 表示不忽略；shellOpts 追加到 shell 命令工具的默认选项之后。
+func RegisterBuiltinTools(registry *tools.Registry, mounts *vfs.Mounts, ignore *file.IgnoreOptions, shellOpts ...shell.ShellCommandOption) {
 	registry.Register(web.NewHTTPTool())
 	registry.Register(web.NewWebSearchTool())
 	registry.Register(NewDateTimeTool())
@@ -24,17 +26,47 @@ func RegisterBuiltinTools(registry *tools.Registry) {
 	}
 
 	// 注册综合文件系统工具
-	registry.Register(file.NewFilesystemTool(workDir))
+	fsTool := file.NewFilesystemTool(workDir)
+	fsTool.Mounts = mounts
+	fsTool.Ignore = ignore
+	registry.Register(fsTool)
 
 	// 注册独立的文件操作工具
-	registry.Register(file.NewReadFileTool(workDir))
-	registry.Register(file.NewWriteFileTool(workDir))
-	registry.Register(file.NewListDirTool(workDir))
-	registry.Register(file.NewCopyFileTool(workDir))
+	readTool := file.NewReadFileTool(workDir)
+	readTool.Mounts = mounts
+	registry.Register(readTool)
+	writeTool := file.NewWriteFileTool(workDir)
+	writeTool.Mounts = mounts
+	registry.Register(writeTool)
+	listTool := file.NewListDirTool(workDir)
+	listTool.Mounts = mounts
+	registry.Register(listTool)
+	grepTool := file.NewGrepTool(workDir)
+	grepTool.Mounts = mounts
+	grepTool.Ignore = ignore
+	registry.Register(grepTool)
+	outlineTool := file.NewCodeOutlineTool(workDir)
+	outlineTool.Mounts = mounts
+	registry.Register(outlineTool)
+	symbolTool := file.NewSymbolSearchTool(workDir)
+	symbolTool.Mounts = mounts
+	symbolTool.Ignore = ignore
+	registry.Register(symbolTool)
+	copyTool := file.NewCopyFileTool(workDir)
+	copyTool.Mounts = mounts
+	registry.Register(copyTool)
+	patchTool := file.NewApplyPatchTool(workDir)
+	patchTool.Mounts = mounts
+	registry.Register(patchTool)
+
+	// 注册下载工具，文件保存到会话的工作目录
+	downloadTool := web.NewDownloadTool(workDir)
+	downloadTool.Mounts = mounts
+	registry.Register(downloadTool)
 
 	// 注册 shell 命令工具
-	registry.Register(shell.NewShellCommandTool(
+	registry.Register(shell.NewShellCommandTool(append([]shell.ShellCommandOption{
 		shell.WithWorkDir(workDir),
 		shell.WithTimeout(60),
-	))
+	}, shellOpts...)...))
 }
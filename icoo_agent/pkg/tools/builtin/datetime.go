@@ -4,16 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"icooclaw/pkg/clock"
 	"icooclaw/pkg/tools"
 	"time"
 )
 
 // DateTimeTool provides date/time functionality.
-type DateTimeTool struct{}
+type DateTimeTool struct {
+	now func() time.Time
+}
 
 // NewDateTimeTool creates a new datetime tool.
 func NewDateTimeTool() *DateTimeTool {
-	return &DateTimeTool{}
+	return &DateTimeTool{now: time.Now}
 }
 
 // Name returns the tool name.
@@ -23,57 +26,91 @@ func (t *DateTimeTool) Name() string {
 
 // Description returns the tool description.
 func (t *DateTimeTool) Description() string {
-	return "获取当前日期和时间信息。"
+	return "获取当前时间、在时区之间换算时间，或将\"next Tuesday 3pm\"、\"明天下午3点\"这类自然语言解析为具体时间。" +
+		"未指定时区时使用用户所在时区，安排定时任务前应先用 parse 确认具体时间。"
 }
 
 // Parameters returns the tool parameters.
 func (t *DateTimeTool) Parameters() map[string]any {
 	return map[string]any{
+		"operation": map[string]any{
+			"type":        "string",
+			"description": "操作: now(当前时间)、convert(时区换算)、parse(解析自然语言时间)，默认 now",
+			"enum":        []string{"now", "convert", "parse"},
+		},
+		"time": map[string]any{
+			"type":        "string",
+			"description": "convert 和 parse 的时间文本，例如 '2026-03-10 15:00'、'next Tuesday 3pm'、'明天下午3点'、'in 2 hours'",
+		},
 		"timezone": map[string]any{
 			"type":        "string",
-			"description": "时区 (例如: 'UTC', 'Asia/Shanghai')",
+			"description": "结果所用的时区 (例如: 'UTC', 'Asia/Shanghai', 'UTC+8')，默认用户时区",
+		},
+		"from_timezone": map[string]any{
+			"type":        "string",
+			"description": "convert 和 parse 时理解 time 所用的时区，默认用户时区",
 		},
 		"format": map[string]any{
 			"type":        "string",
-			"description": "时间格式 (例如: '2006-01-02 15:04:05')",
+			"description": "额外输出的时间格式 (例如: '2006-01-02 15:04:05')",
 		},
 	}
 }
 
 // Execute executes the datetime tool.
 func (t *DateTimeTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
-	now := time.Now()
+	userLoc := tools.GetLocation(ctx)
+	loc, err := argLocation(args, "timezone", userLoc)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
 
-	// Handle timezone
-	if tz, ok := args["timezone"].(string); ok {
-		loc, err := time.LoadLocation(tz)
+	now := t.now()
+	operation, _ := args["operation"].(string)
+	switch operation {
+	case "", "now":
+	case "convert", "parse":
+		text, _ := args["time"].(string)
+		if text == "" {
+			return &tools.Result{Success: false, Error: fmt.Errorf("%s 需要提供 time 参数", operation)}
+		}
+		from, err := argLocation(args, "from_timezone", userLoc)
 		if err != nil {
-			return &tools.Result{Success: false, Error: fmt.Errorf("无效的时区: %v", err)}
+			return &tools.Result{Success: false, Error: err}
 		}
-		now = now.In(loc)
-	}
-
-	// Handle format
-	format := time.RFC3339
-	if f, ok := args["format"].(string); ok {
-		format = f
+		if now, err = clock.Parse(text, now.In(from)); err != nil {
+			return &tools.Result{Success: false, Error: err}
+		}
+	default:
+		return &tools.Result{Success: false, Error: fmt.Errorf("未知操作: %s", operation)}
 	}
+	now = now.In(loc)
 
 	result := map[string]any{
-		"formatted": now.Format(format),
+		"display":   clock.Format(now, nil),
+		"rfc3339":   now.Format(time.RFC3339),
+		"timezone":  clock.Zone(now),
 		"timestamp": now.Unix(),
 		"date":      now.Format("2006-01-02"),
 		"time":      now.Format("15:04:05"),
 		"weekday":   now.Weekday().String(),
-		"unix_nano": now.UnixNano(),
-		"year":      now.Year(),
-		"month":     int(now.Month()),
-		"day":       now.Day(),
-		"hour":      now.Hour(),
-		"minute":    now.Minute(),
-		"second":    now.Second(),
+	}
+	if loc.String() != userLoc.String() {
+		result["user_time"] = clock.Format(now, userLoc)
+	}
+	if f, ok := args["format"].(string); ok && f != "" {
+		result["formatted"] = now.Format(f)
 	}
 
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 	return &tools.Result{Success: true, Content: string(resultJSON)}
 }
+
+// argLocation 读取时区参数，未提供时返回 fallback。
+func argLocation(args map[string]any, key string, fallback *time.Location) (*time.Location, error) {
+	name, _ := args[key].(string)
+	if name == "" {
+		return fallback, nil
+	}
+	return clock.LoadLocation(name)
+}
@@ -0,0 +1,179 @@
+// Package diagram 提供图表渲染工具：将 Mermaid 或 PlantUML 源码通过 Kroki 服务渲染为 SVG/PNG，
+// 结果保存为制品，支持文件的渠道会以图片或附件形式展示。
+package diagram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/artifact"
+)
+
+// DefaultEndpoint 公共 Kroki 服务，图表源码会发送到该服务，敏感内容应自建 Kroki
+const DefaultEndpoint = "https://kroki.io"
+
+// 支持的图表语言和输出格式
+var (
+	languages = []string{"mermaid", "plantuml"}
+	formats   = []string{"svg", "png"}
+)
+
+// Tool 渲染图表。
+type Tool struct {
+	store    *storage.ArtifactStorage
+	workDir  string
+	endpoint string
+	client   *http.Client
+	timeout  time.Duration // 单次请求超时，与本轮剩余预算取较小者
+}
+
+// NewTool 创建 render_diagram 工具，endpoint 为 Kroki 服务地址，为空时使用公共服务。
+func NewTool(store *storage.ArtifactStorage, workDir, endpoint string, timeout time.Duration) *Tool {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Tool{
+		store:    store,
+		workDir:  workDir,
+		endpoint: strings.TrimRight(endpoint, "/"),
+		client:   &http.Client{},
+		timeout:  timeout,
+	}
+}
+
+// Name 工具名称.
+func (t *Tool) Name() string {
+	return "render_diagram"
+}
+
+// Description 工具描述.
+func (t *Tool) Description() string {
+	return "将 Mermaid 或 PlantUML 源码渲染为 SVG/PNG 图片（架构图、流程图、时序图等）并保存为制品。" +
+		"渲染成功后在回复中写返回的 [artifact:<id>] 引用图片；语法错误时根据错误信息修改源码后重试。"
+}
+
+// Parameters 工具参数.
+func (t *Tool) Parameters() map[string]any {
+	return map[string]any{
+		"source": map[string]any{
+			"type":        "string",
+			"description": "图表源码，PlantUML 需包含 @startuml/@enduml",
+			"required":    true,
+		},
+		"language": map[string]any{
+			"type":        "string",
+			"description": "图表语言，默认 mermaid",
+			"enum":        languages,
+		},
+		"format": map[string]any{
+			"type":        "string",
+			"description": "输出格式，默认 png；需要无损缩放时用 svg",
+			"enum":        formats,
+		},
+		"name": map[string]any{
+			"type":        "string",
+			"description": "文件名（不含扩展名），默认 diagram",
+		},
+		"description": map[string]any{
+			"type":        "string",
+			"description": "一句话说明图表的内容",
+		},
+	}
+}
+
+// DescribeChange 实现 tools.Mutator，渲染结果会写入工作目录。
+func (t *Tool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	return "渲染图表并保存为制品", true
+}
+
+// Execute 执行 render_diagram.
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	if tools.GetChannel(ctx) == "" || tools.GetSessionID(ctx) == "" {
+		return tools.ErrorResult("缺少会话上下文，无法保存图表")
+	}
+	if tools.IsEphemeral(ctx) {
+		return tools.ErrorResult("无痕会话不保存图表，请直接在回复中给出图表源码")
+	}
+
+	source, _ := args["source"].(string)
+	if strings.TrimSpace(source) == "" {
+		return tools.ErrorResult("需要提供 source 参数")
+	}
+	language := strings.ToLower(argOr(args, "language", "mermaid"))
+	if !slices.Contains(languages, language) {
+		return tools.ErrorResult(fmt.Sprintf("不支持的图表语言: %s（可选 %s）", language, strings.Join(languages, "、")))
+	}
+	format := strings.ToLower(argOr(args, "format", "png"))
+	if !slices.Contains(formats, format) {
+		return tools.ErrorResult(fmt.Sprintf("不支持的输出格式: %s（可选 %s）", format, strings.Join(formats, "、")))
+	}
+
+	image, err := t.render(ctx, language, format, source)
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+
+	name := fileName(argOr(args, "name", "diagram")) + "." + format
+	a, err := artifact.Save(ctx, t.store, t.workDir, name, "diagram", argOr(args, "description", ""), image)
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+	return tools.SuccessResult(fmt.Sprintf("已渲染图表 %s（%d 字节）。在回复中写 %s 引用它。",
+		name, a.Size, artifact.Reference(a.ID)))
+}
+
+// render 调用 Kroki 的 POST /{language}/{format} 接口，请求体为图表源码。
+func (t *Tool) render(ctx context.Context, language, format, source string) ([]byte, error) {
+	ctx, cancel := tools.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+"/"+language+"/"+format, strings.NewReader(source))
+	if err != nil {
+		return nil, fmt.Errorf("创建渲染请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("渲染服务请求失败: %w", tools.DeadlineError(ctx, t.timeout, err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, artifact.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取渲染结果失败: %w", tools.DeadlineError(ctx, t.timeout, err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Kroki 以纯文本返回语法错误，原样交给模型修正
+		return nil, fmt.Errorf("渲染失败 (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// fileName 只保留文件名部分并去掉扩展名。
+func fileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "diagram"
+	}
+	return name
+}
+
+func argOr(args map[string]any, key, fallback string) string {
+	if s, _ := args[key].(string); strings.TrimSpace(s) != "" {
+		return strings.TrimSpace(s)
+	}
+	return fallback
+}
@@ -0,0 +1,81 @@
+package diagram
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+func TestTool_Render(t *testing.T) {
+	var gotPath, gotSource string
+	kroki := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotSource = r.URL.Path, string(body)
+		if strings.Contains(gotSource, "oops") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Error 400: Parse error on line 1\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write([]byte("<svg/>"))
+	}))
+	defer kroki.Close()
+
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "diagram.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	tool := NewTool(s.Artifact(), t.TempDir(), kroki.URL+"/", 0)
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+
+	result := tool.Execute(ctx, map[string]any{
+		"source":   "graph TD; A-->B",
+		"language": "Mermaid",
+		"format":   "svg",
+		"name":     "Flow.mmd",
+	})
+	if result.Error != nil {
+		t.Fatalf("Execute() error = %v", result.Error)
+	}
+	if gotPath != "/mermaid/svg" || gotSource != "graph TD; A-->B" {
+		t.Errorf("request = %s %q", gotPath, gotSource)
+	}
+
+	artifacts, err := s.Artifact().ListBySession("websocket", "s1")
+	if err != nil || len(artifacts) != 1 {
+		t.Fatalf("artifacts = %+v, %v", artifacts, err)
+	}
+	a := artifacts[0]
+	if a.Name != "Flow.svg" || a.Kind != "diagram" || a.MIMEType != "image/svg+xml" {
+		t.Errorf("artifact = %+v", a)
+	}
+	if !strings.Contains(result.Content, "[artifact:"+a.ID+"]") {
+		t.Errorf("result = %s", result.Content)
+	}
+	if data, err := os.ReadFile(a.Path); err != nil || string(data) != "<svg/>" {
+		t.Errorf("file = %q, %v", data, err)
+	}
+
+	// 语法错误原样返回给模型
+	result = tool.Execute(ctx, map[string]any{"source": "oops"})
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "Parse error") {
+		t.Errorf("syntax error result = %+v", result)
+	}
+	if gotPath != "/mermaid/png" {
+		t.Errorf("default path = %s", gotPath)
+	}
+
+	if result := tool.Execute(ctx, map[string]any{"source": "A", "language": "graphviz"}); result.Error == nil {
+		t.Error("unsupported language should fail")
+	}
+}
@@ -0,0 +1,93 @@
+// Package entity provides tools for querying the entity memory graph.
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+// RecallTool 查询实体图中某个实体的事实和关系。
+type RecallTool struct {
+	entities *storage.EntityStorage
+}
+
+// NewRecallTool 创建 recall_entity 工具。
+func NewRecallTool(entities *storage.EntityStorage) *RecallTool {
+	return &RecallTool{entities: entities}
+}
+
+// Name 工具名称.
+func (t *RecallTool) Name() string {
+	return "recall_entity"
+}
+
+// Description 工具描述.
+func (t *RecallTool) Description() string {
+	return "查询对话中提到过的人、项目、服务等实体，返回已知的事实和关系（例如 \"Alice — owns — billing-service\"）。" +
+		"回答\"关于 X 我们知道什么\"之类的问题时使用；不提供 name 时列出已知实体。"
+}
+
+// Parameters 工具参数.
+func (t *RecallTool) Parameters() map[string]any {
+	return map[string]any{
+		"name": map[string]any{
+			"type":        "string",
+			"description": "实体名称或别名，不区分大小写",
+		},
+		"type": map[string]any{
+			"type":        "string",
+			"description": "列出实体时按类型筛选，如 person、project、service",
+		},
+	}
+}
+
+// Execute 执行 recall_entity.
+func (t *RecallTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	name, _ := args["name"].(string)
+	if strings.TrimSpace(name) == "" {
+		entityType, _ := args["type"].(string)
+		return t.list(entityType)
+	}
+
+	e, err := t.entities.Find(name)
+	if errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+		return tools.SuccessResult(fmt.Sprintf("没有关于 %s 的记录", name))
+	}
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("查询实体失败: %s", err))
+	}
+
+	g, err := t.entities.Graph(e, 0)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("查询实体关系失败: %s", err))
+	}
+	return tools.SuccessResult(g.String())
+}
+
+// list 列出已知实体。
+func (t *RecallTool) list(entityType string) *tools.Result {
+	entities, err := t.entities.List(entityType)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("列出实体失败: %s", err))
+	}
+	if len(entities) == 0 {
+		return tools.SuccessResult("暂无已知实体")
+	}
+
+	var sb strings.Builder
+	for _, e := range entities {
+		sb.WriteString("- ")
+		sb.WriteString(e.Name)
+		if e.Type != "" {
+			fmt.Fprintf(&sb, " (%s)", e.Type)
+		}
+		sb.WriteString("\n")
+	}
+	return tools.SuccessResult(sb.String())
+}
@@ -4,14 +4,15 @@ import (
 	"context"
 	"fmt"
 	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
 // ListDirTool 提供目录列表功能。
 type ListDirTool struct {
 	WorkDir string
+	Mounts  *vfs.Mounts
 }
 
 // NewListDirTool 创建一个新的目录列表工具。
@@ -30,7 +31,7 @@ func (t *ListDirTool) Name() string {
 
 // Description 返回工具描述。
 func (t *ListDirTool) Description() string {
-	return "列出指定目录下的文件和子目录。"
+	return "列出指定目录下的文件和子目录。" + mountNote(t.Mounts)
 }
 
 // Parameters 返回工具参数。
@@ -49,14 +50,12 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]any) *tools.R
 	path, _ := args["path"].(string)
 
 	// 安全检查
-	fullPath := filepath.Join(t.WorkDir, filepath.Clean(path))
-	absWorkDir, _ := filepath.Abs(t.WorkDir)
-	absFullPath, _ := filepath.Abs(fullPath)
-	if !strings.HasPrefix(absFullPath, absWorkDir) {
-		return &tools.Result{Success: false, Error: fmt.Errorf("路径超出工作目录范围")}
+	fsys, name, err := resolve(ctx, t.WorkDir, t.Mounts, path)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
 	}
 
-	entries, err := os.ReadDir(absFullPath)
+	entries, err := fsys.ReadDir(ctx, name)
 	if err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("读取目录失败: %w", err)}
 	}
@@ -68,8 +67,7 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]any) *tools.R
 		if entry.IsDir() {
 			result.WriteString(fmt.Sprintf("📁 %s/\n", entry.Name()))
 		} else {
-			info, _ := entry.Info()
-			result.WriteString(fmt.Sprintf("📄 %s (%d 字节)\n", entry.Name(), info.Size()))
+			result.WriteString(fmt.Sprintf("📄 %s (%d 字节)\n", entry.Name(), entry.Size()))
 		}
 	}
 
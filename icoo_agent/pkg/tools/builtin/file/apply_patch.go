@@ -0,0 +1,331 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
+)
+
+// ApplyPatchTool 应用跨多个文件的统一差异补丁。所有文件先校验，全部通过后才写入；
+// 写入中途失败时已写入的文件恢复原状，保证要么全部生效，要么都不生效。
+type ApplyPatchTool struct {
+	WorkDir string
+	Mounts  *vfs.Mounts
+}
+
+// NewApplyPatchTool 创建一个新的补丁工具。
+func NewApplyPatchTool(workDir string) *ApplyPatchTool {
+	if workDir == "" {
+		workDir = "./workspace"
+	}
+	os.MkdirAll(workDir, 0755)
+	return &ApplyPatchTool{WorkDir: workDir}
+}
+
+// Name 返回工具名称。
+func (t *ApplyPatchTool) Name() string {
+	return "apply_patch"
+}
+
+// Description 返回工具描述。
+func (t *ApplyPatchTool) Description() string {
+	return "应用统一差异格式（diff -u / git diff）的补丁，一次可修改、新建（--- /dev/null）或删除（+++ /dev/null）多个文件。" +
+		"所有修改块先与文件当前内容校验，任一文件校验失败则不修改任何文件，并报告每个文件的状态。" +
+		"修改已有文件时优先使用本工具，只需给出修改处前后几行上下文，不必重写整个文件。" + mountNote(t.Mounts)
+}
+
+// Parameters 返回工具参数。
+func (t *ApplyPatchTool) Parameters() map[string]any {
+	return map[string]any{
+		"patch": map[string]any{
+			"type":        "string",
+			"description": "统一差异格式的补丁，每个文件以 \"--- a/路径\" 和 \"+++ b/路径\" 开头，后跟 \"@@ -起始行,行数 +起始行,行数 @@\" 修改块",
+			"required":    true,
+		},
+		"dry_run": map[string]any{
+			"type":        "boolean",
+			"description": "只校验补丁能否应用，不修改文件，默认 false",
+		},
+	}
+}
+
+// patchFile 补丁中单个文件的处理状态。
+type patchFile struct {
+	patch   *FilePatch
+	fsys    vfs.FS
+	name    string // 目标文件在 fsys 中的名称
+	oldFS   vfs.FS
+	oldName string // 重命名或删除时原文件在 oldFS 中的名称
+
+	before  []byte
+	existed bool
+	after   []byte
+
+	status string
+	err    error
+}
+
+// 文件状态
+const (
+	patchCreated    = "新建"
+	patchModified   = "修改"
+	patchDeleted    = "删除"
+	patchRenamed    = "重命名"
+	patchFailed     = "失败"
+	patchSkipped    = "未应用"
+	patchRolledBack = "已回滚"
+)
+
+// Execute 校验并应用补丁。
+func (t *ApplyPatchTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	text, ok := args["patch"].(string)
+	if !ok || strings.TrimSpace(text) == "" {
+		return &tools.Result{Success: false, Error: fmt.Errorf("需要提供 patch 参数")}
+	}
+	dryRun, _ := args["dry_run"].(bool)
+
+	patches, err := ParsePatch(text)
+	if err != nil {
+		return &tools.Result{Success: false, Error: fmt.Errorf("解析补丁失败: %w", err)}
+	}
+
+	files := make([]*patchFile, len(patches))
+	failed := 0
+	seen := make(map[string]bool)
+	for i, p := range patches {
+		files[i] = t.prepare(ctx, p)
+		// 每个文件段都基于文件的原内容计算，同一文件以不同写法（./a.txt、绝对路径）出现多次时，
+		// 后写入的会覆盖前面的修改，ParsePatch 只能发现写法相同的重复
+		if err := t.claim(ctx, seen, p); err != nil && files[i].err == nil {
+			files[i].status, files[i].err = patchFailed, err
+		}
+		if files[i].err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		for _, f := range files {
+			if f.err == nil {
+				f.status = patchSkipped
+			}
+		}
+		report := patchReport(files)
+		return &tools.Result{
+			Success: false,
+			Content: report,
+			Error:   fmt.Errorf("%d 个文件校验失败，补丁未应用，所有文件保持不变:\n%s", failed, report),
+		}
+	}
+	if dryRun {
+		return tools.SuccessResult("补丁校验通过，可以应用:\n" + patchReport(files))
+	}
+	if err := ctx.Err(); err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+
+	if err := t.commit(ctx, files); err != nil {
+		report := patchReport(files)
+		return &tools.Result{
+			Success: false,
+			Content: report,
+			Error:   fmt.Errorf("写入失败，已恢复所有文件: %w\n%s", err, report),
+		}
+	}
+	for _, f := range files {
+		if f.patch.NewPath == "" {
+			recordDelete(ctx, f.oldName, f.before, false)
+			continue
+		}
+		if f.oldName != "" {
+			recordDelete(ctx, f.oldName, f.before, false)
+			recordWrite(ctx, f.name, nil, false, f.after)
+			continue
+		}
+		recordWrite(ctx, f.name, f.before, f.existed, f.after)
+	}
+	return tools.SuccessResult(fmt.Sprintf("补丁已应用到 %d 个文件:\n%s", len(files), patchReport(files)))
+}
+
+// prepare 读取文件当前内容并计算应用补丁后的内容，不修改文件。
+func (t *ApplyPatchTool) prepare(ctx context.Context, p *FilePatch) *patchFile {
+	f := &patchFile{patch: p}
+	fail := func(err error) *patchFile {
+		f.status, f.err = patchFailed, err
+		return f
+	}
+
+	if p.OldPath != "" {
+		fsys, name, err := resolve(ctx, t.WorkDir, t.Mounts, p.OldPath)
+		if err != nil {
+			return fail(err)
+		}
+		if err := Writable(name); err != nil {
+			return fail(err)
+		}
+		if info, err := fsys.Stat(ctx, name); err != nil {
+			return fail(fmt.Errorf("文件不存在"))
+		} else if info.IsDir() {
+			return fail(fmt.Errorf("是目录"))
+		}
+		if f.before, err = vfs.ReadFile(ctx, fsys, name); err != nil {
+			return fail(fmt.Errorf("读取文件失败: %w", err))
+		}
+		f.fsys, f.name, f.existed = fsys, name, true
+		f.oldFS = fsys
+	}
+	if p.NewPath != "" && p.NewPath != p.OldPath {
+		fsys, name, err := resolve(ctx, t.WorkDir, t.Mounts, p.NewPath)
+		if err != nil {
+			return fail(err)
+		}
+		if err := Writable(name); err != nil {
+			return fail(err)
+		}
+		if _, err := fsys.Stat(ctx, name); err == nil {
+			return fail(fmt.Errorf("目标文件已存在"))
+		}
+		if f.existed {
+			f.oldName = f.name
+		}
+		f.fsys, f.name = fsys, name
+	}
+	if p.NewPath == "" {
+		f.oldName = f.name
+	}
+
+	after, err := applyHunks(string(f.before), p.Hunks)
+	if err != nil {
+		return fail(err)
+	}
+	if p.NewPath == "" && after != "" {
+		return fail(fmt.Errorf("删除文件的补丁需要删除全部内容"))
+	}
+	f.after = []byte(after)
+
+	switch {
+	case p.OldPath == "":
+		f.status = patchCreated
+	case p.NewPath == "":
+		f.status = patchDeleted
+	case f.oldName != "":
+		f.status = patchRenamed
+	default:
+		f.status = patchModified
+	}
+	return f
+}
+
+// claim 记录文件段涉及的路径，路径已被之前的文件段使用时返回错误。
+func (t *ApplyPatchTool) claim(ctx context.Context, seen map[string]bool, p *FilePatch) error {
+	paths := []string{p.OldPath}
+	if p.NewPath != p.OldPath {
+		paths = append(paths, p.NewPath)
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		_, name, err := resolve(ctx, t.WorkDir, t.Mounts, path)
+		if err != nil {
+			return err
+		}
+		if seen[name] {
+			return fmt.Errorf("%s 在补丁中出现了多次，请把同一文件的修改合并到一个文件段", path)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// commit 依次写入所有文件，任一文件失败时按相反顺序恢复已写入的文件。
+func (t *ApplyPatchTool) commit(ctx context.Context, files []*patchFile) error {
+	var undo []func() error
+	rollback := func(i int, cause error) error {
+		var errs []error
+		for j := len(undo) - 1; j >= 0; j-- {
+			if err := undo[j](); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		for j, f := range files {
+			switch {
+			case j < i:
+				f.status = patchRolledBack
+			case j == i:
+				f.status, f.err = patchFailed, cause
+			default:
+				f.status = patchSkipped
+			}
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%w（恢复时出错: %w）", cause, errors.Join(errs...))
+		}
+		return cause
+	}
+
+	for i, f := range files {
+		if f.patch.NewPath != "" {
+			if err := f.fsys.WriteFile(ctx, f.name, f.after); err != nil {
+				return rollback(i, fmt.Errorf("写入 %s 失败: %w", f.patch.NewPath, err))
+			}
+			undo = append(undo, restore(ctx, f.fsys, f.name, f.before, f.existed && f.oldName == ""))
+		}
+		if f.oldName != "" {
+			if err := f.oldFS.Remove(ctx, f.oldName, false); err != nil {
+				return rollback(i, fmt.Errorf("删除 %s 失败: %w", f.patch.OldPath, err))
+			}
+			undo = append(undo, restore(ctx, f.oldFS, f.oldName, f.before, true))
+		}
+	}
+	return nil
+}
+
+// restore 返回将文件恢复到补丁前状态的函数：原本存在的文件写回原内容，新建的文件删除。
+// 恢复不受调用方取消影响，避免文件停留在中间状态。
+func restore(ctx context.Context, fsys vfs.FS, name string, before []byte, existed bool) func() error {
+	ctx = context.WithoutCancel(ctx)
+	return func() error {
+		if existed {
+			return fsys.WriteFile(ctx, name, before)
+		}
+		return fsys.Remove(ctx, name, false)
+	}
+}
+
+// patchReport 逐个文件列出处理状态。
+func patchReport(files []*patchFile) string {
+	var sb strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&sb, "- %s: %s", f.patch.Path(), f.status)
+		if f.patch.OldPath != "" && f.patch.NewPath != "" && f.patch.OldPath != f.patch.NewPath {
+			fmt.Fprintf(&sb, "（原 %s）", f.patch.OldPath)
+		}
+		if f.err != nil {
+			fmt.Fprintf(&sb, "，%v", f.err)
+		} else if added, removed := patchStat(f.patch); added+removed > 0 {
+			fmt.Fprintf(&sb, "，%d 个块，+%d -%d", len(f.patch.Hunks), added, removed)
+		}
+		sb.WriteByte('\n')
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// patchStat 统计补丁新增和删除的行数。
+func patchStat(p *FilePatch) (added, removed int) {
+	for _, h := range p.Hunks {
+		for _, l := range h.Lines {
+			switch l[0] {
+			case '+':
+				added++
+			case '-':
+				removed++
+			}
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,201 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyHunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "modify",
+			content: "a\nb\nc\nd\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -2,2 +2,2 @@\n b\n-c\n+C\n",
+			want:    "a\nb\nC\nd\n",
+		},
+		{
+			name:    "wrong line numbers",
+			content: "x\ny\na\nb\nc\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,2 +1,3 @@\n a\n+a2\n b\n",
+			want:    "x\ny\na\na2\nb\nc\n",
+		},
+		{
+			name:    "insert without context",
+			content: "a\nb\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,0 +2 @@\n+x\n",
+			want:    "a\nx\nb\n",
+		},
+		{
+			name:    "crlf and trailing whitespace",
+			content: "a  \r\nb\r\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n+B\n",
+			want:    "a  \r\nB\r\n",
+		},
+		{
+			name:    "no newline at end",
+			content: "a\nb\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -2 +2 @@\n-b\n+c\n\\ No newline at end of file\n",
+			want:    "a\nc",
+		},
+		{
+			name:    "context mismatch",
+			content: "a\nb\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-z\n+Z\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patches, err := ParsePatch(tt.patch)
+			if err != nil {
+				t.Fatalf("ParsePatch() error = %v", err)
+			}
+			got, err := applyHunks(tt.content, patches[0].Hunks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyHunks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("applyHunks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchTool(t *testing.T) {
+	workDir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(workDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(workDir, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+	write("main.go", "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+	write("old.txt", "bye\n")
+	write("util.go", "package main\n")
+
+	tool := NewApplyPatchTool(workDir)
+	ctx := context.Background()
+
+	// 任一文件不匹配时所有文件保持不变
+	bad := "--- a/main.go\n+++ b/main.go\n@@ -4 +4 @@\n-\tprintln(\"hi\")\n+\tprintln(\"hello\")\n" +
+		"--- a/util.go\n+++ b/util.go\n@@ -1 +1 @@\n-package util\n+package lib\n"
+	res := tool.Execute(ctx, map[string]any{"patch": bad})
+	if res.Success || !strings.Contains(res.Content, "main.go: 未应用") || !strings.Contains(res.Content, "util.go: 失败") {
+		t.Fatalf("Execute(bad) = %+v", res)
+	}
+	if got := read("main.go"); strings.Contains(got, "hello") {
+		t.Errorf("main.go was modified: %q", got)
+	}
+
+	patch := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -4 +4 @@\n-\tprintln(\"hi\")\n+\tprintln(\"hello\")\n" +
+		"--- /dev/null\n+++ b/pkg/new.go\n@@ -0,0 +1,2 @@\n+package pkg\n+\n" +
+		"--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n"
+
+	// dry_run 只校验
+	res = tool.Execute(ctx, map[string]any{"patch": patch, "dry_run": true})
+	if !res.Success || read("pkg/new.go") != "<missing>" {
+		t.Fatalf("Execute(dry_run) = %+v", res)
+	}
+
+	res = tool.Execute(ctx, map[string]any{"patch": patch})
+	if !res.Success {
+		t.Fatalf("Execute() error = %v", res.Error)
+	}
+	for _, want := range []string{"main.go: 修改，1 个块，+1 -1", "pkg/new.go: 新建", "old.txt: 删除"} {
+		if !strings.Contains(res.Content, want) {
+			t.Errorf("Execute() content = %q, want %q", res.Content, want)
+		}
+	}
+	if got := read("main.go"); !strings.Contains(got, "hello") {
+		t.Errorf("main.go = %q", got)
+	}
+	if got := read("pkg/new.go"); got != "package pkg\n\n" {
+		t.Errorf("pkg/new.go = %q", got)
+	}
+	if got := read("old.txt"); got != "<missing>" {
+		t.Errorf("old.txt = %q, want deleted", got)
+	}
+
+	// 新建已存在的文件失败
+	res = tool.Execute(ctx, map[string]any{"patch": "--- /dev/null\n+++ b/util.go\n@@ -0,0 +1 @@\n+x\n"})
+	if res.Success || !strings.Contains(res.Content, "目标文件已存在") {
+		t.Errorf("Execute(create existing) = %+v", res)
+	}
+}
+
+func TestApplyPatchTool_DuplicatePath(t *testing.T) {
+	workDir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(workDir, name), []byte("a\nb\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{"same file twice", "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n" +
+			"--- a/./a.txt\n+++ b/./a.txt\n@@ -2 +2 @@\n-b\n+B\n"},
+		{"absolute path", "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n" +
+			"--- " + filepath.ToSlash(filepath.Join(workDir, "a.txt")) + "\n+++ " + filepath.ToSlash(filepath.Join(workDir, "a.txt")) + "\n@@ -2 +2 @@\n-b\n+B\n"},
+		{"delete modified file", "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n" +
+			"--- a/sub/../a.txt\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-a\n-b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := NewApplyPatchTool(workDir).Execute(context.Background(), map[string]any{"patch": tt.patch})
+			if res.Success || !strings.Contains(res.Content, "出现了多次") {
+				t.Errorf("Execute() = %+v, want duplicate path error", res)
+			}
+			if data, _ := os.ReadFile(filepath.Join(workDir, "a.txt")); string(data) != "a\nb\n" {
+				t.Errorf("a.txt = %q, want unchanged", data)
+			}
+		})
+	}
+}
+
+func TestApplyPatchTool_Rollback(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// b 是普通文件，新建 b/c.txt 校验通过但在写入时失败
+	if err := os.WriteFile(filepath.Join(workDir, "b"), []byte("file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n" +
+		"--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+n\n" +
+		"--- /dev/null\n+++ b/b/c.txt\n@@ -0,0 +1 @@\n+c\n"
+	res := NewApplyPatchTool(workDir).Execute(context.Background(), map[string]any{"patch": patch})
+	if res.Success {
+		t.Fatal("Execute() succeeded, want write failure")
+	}
+	for _, want := range []string{"a.txt: 已回滚", "new.txt: 已回滚", "b/c.txt: 失败"} {
+		if !strings.Contains(res.Content, want) {
+			t.Errorf("Execute() content = %q, want %q", res.Content, want)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(workDir, "a.txt")); string(data) != "a\n" {
+		t.Errorf("a.txt = %q, want restored", data)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("new.txt exists after rollback: %v", err)
+	}
+}
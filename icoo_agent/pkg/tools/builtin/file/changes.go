@@ -0,0 +1,48 @@
+package file
+
+import (
+	"context"
+
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
+)
+
+// snapshot 读取文件修改前的状态，供本轮修改汇总使用。本轮不记录修改时不读取。
+// 文件过大时 data 为 nil，只记录被修改，不统计行数。
+func snapshot(ctx context.Context, fsys vfs.FS, name string) (data []byte, existed, dir bool) {
+	if tools.GetChanges(ctx) == nil {
+		return nil, false, false
+	}
+	info, err := fsys.Stat(ctx, name)
+	if err != nil {
+		return nil, false, false
+	}
+	if info.IsDir() {
+		return nil, true, true
+	}
+	if info.Size() > tools.MaxDiffBytes {
+		return nil, true, false
+	}
+	data, err = vfs.ReadFile(ctx, fsys, name)
+	if err != nil {
+		return nil, true, false
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data, true, false
+}
+
+// recordWrite 记录写入文件。
+func recordWrite(ctx context.Context, name string, before []byte, existed bool, after []byte) {
+	if c := tools.GetChanges(ctx); c != nil {
+		c.RecordWrite(displayPath(name), before, existed, after)
+	}
+}
+
+// recordDelete 记录删除文件或目录。
+func recordDelete(ctx context.Context, name string, before []byte, dir bool) {
+	if c := tools.GetChanges(ctx); c != nil {
+		c.RecordDelete(displayPath(name), before, dir)
+	}
+}
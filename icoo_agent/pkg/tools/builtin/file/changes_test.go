@@ -0,0 +1,61 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"icooclaw/pkg/tools"
+)
+
+func TestRecordChanges(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "app.toml"), []byte("host = \"a\"\nport = 80\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "old.txt"), []byte("x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := tools.NewRegistry()
+	r.Register(NewFilesystemTool(workDir))
+	r.Register(NewWriteFileTool(workDir))
+	r.Register(NewCopyFileTool(workDir))
+	changes := tools.NewChanges()
+	ctx := tools.WithChanges(context.Background(), changes)
+
+	for _, call := range []struct {
+		tool string
+		args map[string]any
+	}{
+		{"write_file", map[string]any{"path": "app.toml", "content": "host = \"a\"\nport = 8080\n"}},
+		{"filesystem", map[string]any{"operation": "write", "path": "docs/readme.md", "content": "# app\n\nusage\n"}},
+		{"copy_file", map[string]any{"source": "app.toml", "destination": "app.bak"}},
+		{"filesystem", map[string]any{"operation": "delete", "path": "old.txt"}},
+		{"filesystem", map[string]any{"operation": "read", "path": "app.toml"}},
+	} {
+		if res := r.Execute(ctx, call.tool, call.args); !res.Success {
+			t.Fatalf("%s error = %v", call.tool, res.Error)
+		}
+	}
+
+	s := changes.Summary()
+	if s == nil {
+		t.Fatal("Summary() = nil")
+	}
+	want := []tools.FileChange{
+		{Path: "app.toml", Added: 1, Removed: 1},
+		{Path: "docs/readme.md", Added: 3, Created: true},
+		{Path: "app.bak", Added: 2, Created: true},
+		{Path: "old.txt", Removed: 1, Deleted: true},
+	}
+	if len(s.Files) != len(want) {
+		t.Fatalf("Files = %+v", s.Files)
+	}
+	for i, f := range s.Files {
+		if f != want[i] {
+			t.Errorf("Files[%d] = %+v, want %+v", i, f, want[i])
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package file
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
+)
+
+const (
+	// symbolDefaultResults symbol_search 默认返回的最大符号数
+	symbolDefaultResults = 50
+	// symbolMaxResults symbol_search max_results 参数的上限
+	symbolMaxResults = 500
+)
+
+// CodeOutlineTool 列出源文件中的函数、类型、类和方法及其行范围，模型可以据此只读取需要的部分。
+type CodeOutlineTool struct {
+	WorkDir string
+	Mounts  *vfs.Mounts
+}
+
+// NewCodeOutlineTool 创建一个新的代码大纲工具。
+func NewCodeOutlineTool(workDir string) *CodeOutlineTool {
+	if workDir == "" {
+		workDir = "./workspace"
+	}
+	os.MkdirAll(workDir, 0755)
+	return &CodeOutlineTool{WorkDir: workDir}
+}
+
+// Name 返回工具名称。
+func (t *CodeOutlineTool) Name() string {
+	return "code_outline"
+}
+
+// Description 返回工具描述。
+func (t *CodeOutlineTool) Description() string {
+	return "列出源文件中的函数、类型、类和方法及其起止行号和签名，不返回函数体。" +
+		"阅读大文件前先用它了解结构，再读取需要的行。支持 Go、Python、JavaScript 和 TypeScript。"
+}
+
+// Parameters 返回工具参数。
+func (t *CodeOutlineTool) Parameters() map[string]any {
+	return map[string]any{
+		"path": map[string]any{
+			"type":        "string",
+			"description": "源文件路径",
+			"required":    true,
+		},
+	}
+}
+
+// Execute 解析文件并列出符号。
+func (t *CodeOutlineTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	p, _ := args["path"].(string)
+	if p == "" {
+		return &tools.Result{Success: false, Error: fmt.Errorf("需要提供 path 参数")}
+	}
+	fsys, name, err := resolve(ctx, t.WorkDir, t.Mounts, p)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	src, err := vfs.ReadFile(ctx, fsys, name)
+	if err != nil {
+		return &tools.Result{Success: false, Error: fmt.Errorf("读取文件失败: %w", err)}
+	}
+
+	symbols, err := Outline(name, src)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	lines := bytes.Count(src, []byte("\n"))
+	if len(src) > 0 && !bytes.HasSuffix(src, []byte("\n")) {
+		lines++
+	}
+	if len(symbols) == 0 {
+		return tools.SuccessResult(fmt.Sprintf("%s（%d 行）中没有找到函数或类型声明", p, lines))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s（%d 行，%d 个符号）:\n", p, lines, len(symbols))
+	for _, s := range symbols {
+		indent := ""
+		if s.Parent != "" {
+			indent = "  "
+		}
+		fmt.Fprintf(&sb, "%s%d-%d %s %s: %s\n", indent, s.StartLine, s.EndLine, s.Kind, s.QualifiedName(), s.Signature)
+	}
+	return tools.SuccessResult(strings.TrimSuffix(sb.String(), "\n"))
+}
+
+// SymbolSearchTool 在工作目录的源文件中按名称查找函数、类型、类和方法的定义。
+type SymbolSearchTool struct {
+	WorkDir string
+	Mounts  *vfs.Mounts
+	// Ignore 跳过的文件，为 nil 时搜索全部文件
+	Ignore *IgnoreOptions
+}
+
+// NewSymbolSearchTool 创建一个新的符号搜索工具。
+func NewSymbolSearchTool(workDir string) *SymbolSearchTool {
+	if workDir == "" {
+		workDir = "./workspace"
+	}
+	os.MkdirAll(workDir, 0755)
+	return &SymbolSearchTool{WorkDir: workDir}
+}
+
+// Name 返回工具名称。
+func (t *SymbolSearchTool) Name() string {
+	return "symbol_search"
+}
+
+// Description 返回工具描述。
+func (t *SymbolSearchTool) Description() string {
+	return "按名称查找函数、类型、类和方法的定义位置（文件和起止行号），比 grep 更准确，不会匹配调用和注释。" +
+		"支持 Go、Python、JavaScript 和 TypeScript，跳过忽略的文件。"
+}
+
+// Parameters 返回工具参数。
+func (t *SymbolSearchTool) Parameters() map[string]any {
+	return map[string]any{
+		"query": map[string]any{
+			"type":        "string",
+			"description": "符号名称，不区分大小写的子串匹配；可以写成 类型.方法 限定所属类型，如 Server.Start",
+			"required":    true,
+		},
+		"path": map[string]any{
+			"type":        "string",
+			"description": "搜索的目录（默认为工作目录）",
+		},
+		"kind": map[string]any{
+			"type":        "string",
+			"description": "只返回该类型的符号",
+			"enum":        []string{SymbolFunc, SymbolMethod, SymbolStruct, SymbolInterface, SymbolType, SymbolClass, SymbolEnum, SymbolConst, SymbolVar},
+		},
+		"max_results": map[string]any{
+			"type":        "integer",
+			"description": fmt.Sprintf("最多返回的符号数，默认 %d，上限 %d", symbolDefaultResults, symbolMaxResults),
+		},
+	}
+}
+
+// symbolMatch 一个匹配的符号。
+type symbolMatch struct {
+	file  string
+	sym   Symbol
+	exact bool // 名称完全相同（不区分大小写）
+}
+
+// Execute 查找符号。
+func (t *SymbolSearchTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	query, _ := args["query"].(string)
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return &tools.Result{Success: false, Error: fmt.Errorf("需要提供 query 参数")}
+	}
+	kind, _ := args["kind"].(string)
+	limit := symbolDefaultResults
+	if n, ok := args["max_results"].(float64); ok && n > 0 {
+		limit = min(int(n), symbolMaxResults)
+	}
+	p, _ := args["path"].(string)
+
+	// 类型.方法 形式时分别匹配所属类型和名称
+	owner, name := "", strings.ToLower(query)
+	if i := strings.LastIndex(name, "."); i > 0 && i < len(name)-1 {
+		owner, name = name[:i], name[i+1:]
+	}
+
+	fsys, root, err := resolve(ctx, t.WorkDir, t.Mounts, p)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	var ig *ignorer
+	if t.Ignore != nil {
+		ig = newIgnorer(ctx, fsys, t.Ignore, root)
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	files := make(chan string)
+	walkErr := make(chan error, 1)
+	go func() {
+		walkErr <- walkFiles(ctx, fsys, root, "", ig, files)
+		close(files)
+	}()
+
+	var matches []symbolMatch
+	lowered := []byte(name)
+	for file := range files {
+		if !CanOutline(file) || len(matches) >= symbolMaxResults {
+			continue
+		}
+		src, err := vfs.ReadFile(ctx, fsys, file)
+		// 文件中不含名称时不解析
+		if err != nil || !bytes.Contains(bytes.ToLower(src), lowered) {
+			continue
+		}
+		symbols, _ := Outline(file, src)
+		for _, s := range symbols {
+			n := strings.ToLower(s.Name)
+			if !strings.Contains(n, name) || (kind != "" && s.Kind != kind) ||
+				(owner != "" && !strings.Contains(strings.ToLower(s.Parent), owner)) {
+				continue
+			}
+			matches = append(matches, symbolMatch{file: displayPath(file), sym: s, exact: n == name})
+		}
+		if len(matches) >= symbolMaxResults {
+			// 已足够排序出前 limit 个，停止遍历
+			cancel()
+		}
+	}
+	if err := <-walkErr; err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	if err := parent.Err(); err != nil {
+		return &tools.Result{Success: false, Error: fmt.Errorf("搜索被中止: %w", err)}
+	}
+
+	if len(matches) == 0 {
+		return tools.SuccessResult(fmt.Sprintf("没有找到名称包含 %q 的符号", query))
+	}
+	// 名称完全相同的排在前面
+	slices.SortFunc(matches, func(a, b symbolMatch) int {
+		if a.exact != b.exact {
+			if a.exact {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(strings.Compare(a.file, b.file), a.sym.StartLine-b.sym.StartLine)
+	})
+
+	var sb strings.Builder
+	if len(matches) > limit {
+		fmt.Fprintf(&sb, "超过 %d 个符号，只列出前 %d 个，可以用 kind、path 或更完整的名称缩小范围:\n", limit, limit)
+		matches = matches[:limit]
+	} else {
+		fmt.Fprintf(&sb, "找到 %d 个符号:\n", len(matches))
+	}
+	for _, m := range matches {
+		fmt.Fprintf(&sb, "%s:%d-%d %s %s: %s\n", m.file, m.sym.StartLine, m.sym.EndLine, m.sym.Kind, m.sym.QualifiedName(), m.sym.Signature)
+	}
+	return tools.SuccessResult(strings.TrimSuffix(sb.String(), "\n"))
+}
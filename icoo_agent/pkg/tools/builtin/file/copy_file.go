@@ -4,15 +4,14 @@ import (
 	"context"
 	"fmt"
 	"icooclaw/pkg/tools"
-	"io"
+	"icooclaw/pkg/vfs"
 	"os"
-	"path/filepath"
-	"strings"
 )
 
 // CopyFileTool 提供文件复制功能。
 type CopyFileTool struct {
 	WorkDir string
+	Mounts  *vfs.Mounts
 }
 
 // NewCopyFileTool 创建一个新的文件复制工具。
@@ -63,45 +62,34 @@ func (t *CopyFileTool) Execute(ctx context.Context, args map[string]any) *tools.
 	}
 
 	// 安全检查
-	absWorkDir, _ := filepath.Abs(t.WorkDir)
-
-	srcPath := filepath.Join(t.WorkDir, filepath.Clean(source))
-	absSrcPath, _ := filepath.Abs(srcPath)
-	if !strings.HasPrefix(absSrcPath, absWorkDir) {
-		return &tools.Result{Success: false, Error: fmt.Errorf("源路径超出工作目录范围")}
+	fsys, srcName, err := resolve(ctx, t.WorkDir, t.Mounts, source)
+	if err != nil {
+		return &tools.Result{Success: false, Error: fmt.Errorf("源%w", err)}
 	}
 
-	dstPath := filepath.Join(t.WorkDir, filepath.Clean(destination))
-	absDstPath, _ := filepath.Abs(dstPath)
-	if !strings.HasPrefix(absDstPath, absWorkDir) {
-		return &tools.Result{Success: false, Error: fmt.Errorf("目标路径超出工作目录范围")}
+	_, dstName, err := resolve(ctx, t.WorkDir, t.Mounts, destination)
+	if err != nil {
+		return &tools.Result{Success: false, Error: fmt.Errorf("目标%w", err)}
+	}
+	if err := Writable(dstName); err != nil {
+		return &tools.Result{Success: false, Error: err}
 	}
 
-	// 打开源文件
-	srcFile, err := os.Open(absSrcPath)
+	// 读取源文件，源和目标可以分别位于本地和远程挂载中
+	data, err := vfs.ReadFile(ctx, fsys, srcName)
 	if err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("打开源文件失败: %w", err)}
 	}
-	defer srcFile.Close()
-
-	// 确保目标目录存在
-	os.MkdirAll(filepath.Dir(absDstPath), 0755)
 
-	// 创建目标文件
-	dstFile, err := os.Create(absDstPath)
-	if err != nil {
+	// 写入目标文件，上级目录由文件系统自动创建
+	before, existed, _ := snapshot(ctx, fsys, dstName)
+	if err := fsys.WriteFile(ctx, dstName, data); err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("创建目标文件失败: %w", err)}
 	}
-	defer dstFile.Close()
-
-	// 复制内容
-	written, err := io.Copy(dstFile, srcFile)
-	if err != nil {
-		return &tools.Result{Success: false, Error: fmt.Errorf("复制文件失败: %w", err)}
-	}
+	recordWrite(ctx, dstName, before, existed, data)
 
 	return &tools.Result{
 		Success: true,
-		Content: fmt.Sprintf("文件复制成功: %s -> %s (%d 字节)", source, destination, written),
+		Content: fmt.Sprintf("文件复制成功: %s -> %s (%d 字节)", source, destination, len(data)),
 	}
 }
@@ -3,18 +3,26 @@ package file
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
+	"io"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
 	"time"
 )
 
 // FilesystemTool 提供文件系统操作功能。
 type FilesystemTool struct {
-	// WorkDir 工作目录，所有文件操作都限制在此目录内
+	// WorkDir 默认工作目录，所有文件操作都限制在会话的工作目录内，会话未选择时为此目录
 	WorkDir string
+	// Mounts 挂载到工作目录子路径的远程存储，为 nil 时只访问本地文件
+	Mounts *vfs.Mounts
+	// Ignore 递归列出目录时跳过的文件，为 nil 时列出全部内容
+	Ignore *IgnoreOptions
 }
 
 // NewFilesystemTool 创建一个新的文件系统工具。
@@ -35,7 +43,7 @@ func (t *FilesystemTool) Name() string {
 
 // Description 返回工具描述。
 func (t *FilesystemTool) Description() string {
-	return "文件系统操作工具，支持读取、写入、列出目录、创建目录、删除文件等操作。"
+	return "文件系统操作工具，支持读取、写入、列出目录、创建目录、删除文件等操作。" + mountNote(t.Mounts)
 }
 
 // Parameters 返回工具参数定义。
@@ -56,7 +64,11 @@ func (t *FilesystemTool) Parameters() map[string]any {
 		},
 		"recursive": map[string]any{
 			"type":        "boolean",
-			"description": "是否递归操作（用于 list 和 delete）",
+			"description": "是否递归操作（用于 list 和 delete）。递归列出时跳过 .gitignore、.icooclawignore 和默认忽略列表（node_modules、构建产物等）中的文件，被忽略的目录标记为 ignored",
+		},
+		"all": map[string]any{
+			"type":        "boolean",
+			"description": "递归列出时包含被忽略的文件（仅用于 list），默认 false",
 		},
 	}
 }
@@ -68,72 +80,46 @@ func (t *FilesystemTool) Execute(ctx context.Context, args map[string]any) *tool
 		return &tools.Result{Success: false, Error: fmt.Errorf("需要提供 operation 参数")}
 	}
 
-	path, _ := args["path"].(string)
-	if path == "" && operation != "list" {
+	p, _ := args["path"].(string)
+	if p == "" && operation != "list" {
 		return &tools.Result{Success: false, Error: fmt.Errorf("需要提供 path 参数")}
 	}
 
-	// 安全检查：确保路径在工作目录内
-	fullPath, err := t.safePath(path)
+	// 安全检查：确保路径在会话的工作目录内，挂载点下的路径转发到远程存储
+	fsys, name, err := resolve(ctx, t.WorkDir, t.Mounts, p)
 	if err != nil {
 		return &tools.Result{Success: false, Error: err}
 	}
+	if operation == "write" || operation == "mkdir" || operation == "delete" {
+		if err := Writable(name); err != nil {
+			return &tools.Result{Success: false, Error: err}
+		}
+	}
 
 	switch operation {
 	case "read":
-		return t.readFile(fullPath)
+		return t.readFile(ctx, fsys, name)
 	case "write":
 		content, _ := args["content"].(string)
-		return t.writeFile(fullPath, content)
+		return t.writeFile(ctx, fsys, name, content)
 	case "list":
-		return t.listDir(fullPath, args)
+		return t.listDir(ctx, fsys, name, args)
 	case "mkdir":
-		return t.mkdir(fullPath)
+		return t.mkdir(ctx, fsys, name)
 	case "delete":
-		return t.delete(fullPath, args)
+		return t.delete(ctx, fsys, name, args)
 	case "exists":
-		return t.exists(fullPath)
+		return t.exists(ctx, fsys, name)
 	case "info":
-		return t.info(fullPath)
+		return t.info(ctx, fsys, name)
 	default:
 		return &tools.Result{Success: false, Error: fmt.Errorf("不支持的操作类型: %s", operation)}
 	}
 }
 
-// safePath 确保路径在工作目录内，防止路径遍历攻击。
-func (t *FilesystemTool) safePath(path string) (string, error) {
-	if path == "" {
-		return t.WorkDir, nil
-	}
-
-	// 清理路径
-	cleanPath := filepath.Clean(path)
-
-	// 构建完整路径
-	fullPath := filepath.Join(t.WorkDir, cleanPath)
-
-	// 获取绝对路径进行比较
-	absWorkDir, err := filepath.Abs(t.WorkDir)
-	if err != nil {
-		return "", fmt.Errorf("获取工作目录绝对路径失败: %w", err)
-	}
-
-	absFullPath, err := filepath.Abs(fullPath)
-	if err != nil {
-		return "", fmt.Errorf("获取文件绝对路径失败: %w", err)
-	}
-
-	// 检查路径是否在工作目录内
-	if !strings.HasPrefix(absFullPath, absWorkDir) {
-		return "", fmt.Errorf("路径超出工作目录范围: %s", path)
-	}
-
-	return absFullPath, nil
-}
-
 // readFile 读取文件内容。
-func (t *FilesystemTool) readFile(path string) *tools.Result {
-	content, err := os.ReadFile(path)
+func (t *FilesystemTool) readFile(ctx context.Context, fsys vfs.FS, name string) *tools.Result {
+	content, err := vfs.ReadFile(ctx, fsys, name)
 	if err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("读取文件失败: %w", err)}
 	}
@@ -144,133 +130,146 @@ func (t *FilesystemTool) readFile(path string) *tools.Result {
 	}
 }
 
-// writeFile 写入文件内容。
-func (t *FilesystemTool) writeFile(path, content string) *tools.Result {
-	// 确保目录存在
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return &tools.Result{Success: false, Error: fmt.Errorf("创建目录失败: %w", err)}
-	}
-
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+// writeFile 写入文件内容，自动创建上级目录。
+func (t *FilesystemTool) writeFile(ctx context.Context, fsys vfs.FS, name, content string) *tools.Result {
+	before, existed, _ := snapshot(ctx, fsys, name)
+	if err := fsys.WriteFile(ctx, name, []byte(content)); err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("写入文件失败: %w", err)}
 	}
+	recordWrite(ctx, name, before, existed, []byte(content))
 
 	return &tools.Result{
 		Success: true,
-		Content: fmt.Sprintf("文件写入成功: %s (%d 字节)", filepath.Base(path), len(content)),
+		Content: fmt.Sprintf("文件写入成功: %s (%d 字节)", path.Base(name), len(content)),
 	}
 }
 
-// listDir 列出目录内容。
-func (t *FilesystemTool) listDir(path string, args map[string]any) *tools.Result {
-	recursive := false
-	if r, ok := args["recursive"].(bool); ok {
-		recursive = r
-	}
+// listEntry 目录列表中的一项。
+type listEntry struct {
+	Name    string `json:"name"`
+	IsDir   bool   `json:"is_dir"`
+	Size    int64  `json:"size,omitempty"`
+	ModTime string `json:"mod_time,omitempty"`
+	Ignored bool   `json:"ignored,omitempty"` // 被忽略规则跳过的目录，未列出其中的内容
+}
+
+// listDir 列出目录内容。递归列出时跳过忽略规则匹配的文件，被忽略的目录只列出自身。
+func (t *FilesystemTool) listDir(ctx context.Context, fsys vfs.FS, name string, args map[string]any) *tools.Result {
+	recursive, _ := args["recursive"].(bool)
+	all, _ := args["all"].(bool)
 
-	entries, err := os.ReadDir(path)
+	var ig *ignorer
+	if recursive && !all && t.Ignore != nil {
+		ig = newIgnorer(ctx, fsys, t.Ignore, name)
+	}
+	files, err := t.walk(ctx, fsys, name, "", recursive, ig)
 	if err != nil {
-		return &tools.Result{Success: false, Error: fmt.Errorf("读取目录失败: %w", err)}
+		return &tools.Result{Success: false, Error: err}
 	}
 
-	type FileInfo struct {
-		Name    string `json:"name"`
-		IsDir   bool   `json:"is_dir"`
-		Size    int64  `json:"size,omitempty"`
-		ModTime string `json:"mod_time,omitempty"`
-	}
+	resultJSON, _ := json.MarshalIndent(files, "", "  ")
+	return &tools.Result{Success: true, Content: string(resultJSON)}
+}
 
-	var files []FileInfo
+// walk 列出 name 下的内容，名称加上 prefix 前缀。
+func (t *FilesystemTool) walk(ctx context.Context, fsys vfs.FS, name, prefix string, recursive bool, ig *ignorer) ([]listEntry, error) {
+	entries, err := fsys.ReadDir(ctx, name)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("列出目录被中止: %w", ctx.Err())
+		}
+		if prefix != "" {
+			// 子目录读取失败时跳过，不影响其他内容
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取目录失败: %w", err)
+	}
 
+	var files []listEntry
 	for _, entry := range entries {
-		info := FileInfo{
-			Name:  entry.Name(),
+		child := path.Join(name, entry.Name())
+		info := listEntry{
+			Name:  prefix + entry.Name(),
 			IsDir: entry.IsDir(),
 		}
+		if ig != nil && ig.ignored(child, entry.IsDir()) {
+			if !entry.IsDir() {
+				continue
+			}
+			info.Ignored = true
+		}
 
 		if !entry.IsDir() {
-			if fi, err := entry.Info(); err == nil {
-				info.Size = fi.Size()
-				info.ModTime = fi.ModTime().Format(time.RFC3339)
+			info.Size = entry.Size()
+			if !entry.ModTime().IsZero() {
+				info.ModTime = entry.ModTime().Format(time.RFC3339)
 			}
 		}
 
 		files = append(files, info)
 
-		// 递归列出子目录
-		if recursive && entry.IsDir() {
-			subPath := filepath.Join(path, entry.Name())
-			subResult := t.listDir(subPath, args)
-			if subResult.Success {
-				// 解析子目录结果并添加前缀
-				var subFiles []FileInfo
-				if err := json.Unmarshal([]byte(subResult.Content), &subFiles); err == nil {
-					for _, sf := range subFiles {
-						sf.Name = entry.Name() + "/" + sf.Name
-						files = append(files, sf)
-					}
-				}
+		// 递归列出子目录，超时或取消后中止，不返回不完整的列表
+		if recursive && entry.IsDir() && !info.Ignored {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("列出目录被中止: %w", err)
 			}
+			sub := ig
+			if ig != nil {
+				sub = ig.enter(ctx, fsys, child)
+			}
+			subFiles, err := t.walk(ctx, fsys, child, info.Name+"/", recursive, sub)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, subFiles...)
 		}
 	}
-
-	resultJSON, _ := json.MarshalIndent(files, "", "  ")
-	return &tools.Result{Success: true, Content: string(resultJSON)}
+	return files, nil
 }
 
 // mkdir 创建目录。
-func (t *FilesystemTool) mkdir(path string) *tools.Result {
-	if err := os.MkdirAll(path, 0755); err != nil {
+func (t *FilesystemTool) mkdir(ctx context.Context, fsys vfs.FS, name string) *tools.Result {
+	if err := fsys.MkdirAll(ctx, name); err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("创建目录失败: %w", err)}
 	}
 
 	return &tools.Result{
 		Success: true,
-		Content: fmt.Sprintf("目录创建成功: %s", filepath.Base(path)),
+		Content: fmt.Sprintf("目录创建成功: %s", path.Base(name)),
 	}
 }
 
 // delete 删除文件或目录。
-func (t *FilesystemTool) delete(path string, args map[string]any) *tools.Result {
+func (t *FilesystemTool) delete(ctx context.Context, fsys vfs.FS, name string, args map[string]any) *tools.Result {
 	recursive := false
 	if r, ok := args["recursive"].(bool); ok {
 		recursive = r
 	}
 
-	info, err := os.Stat(path)
-	if err != nil {
+	if _, err := fsys.Stat(ctx, name); err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("文件或目录不存在: %w", err)}
 	}
 
-	if info.IsDir() {
-		if recursive {
-			err = os.RemoveAll(path)
-		} else {
-			err = os.Remove(path)
-		}
-	} else {
-		err = os.Remove(path)
-	}
-
-	if err != nil {
+	before, _, dir := snapshot(ctx, fsys, name)
+	if err := fsys.Remove(ctx, name, recursive); err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("删除失败: %w", err)}
 	}
+	recordDelete(ctx, name, before, dir)
 
 	return &tools.Result{
 		Success: true,
-		Content: fmt.Sprintf("删除成功: %s", filepath.Base(path)),
+		Content: fmt.Sprintf("删除成功: %s", path.Base(name)),
 	}
 }
 
 // exists 检查文件或目录是否存在。
-func (t *FilesystemTool) exists(path string) *tools.Result {
-	info, err := os.Stat(path)
+func (t *FilesystemTool) exists(ctx context.Context, fsys vfs.FS, name string) *tools.Result {
+	info, err := fsys.Stat(ctx, name)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			result := map[string]any{
 				"exists": false,
-				"path":   path,
+				"path":   displayPath(name),
 			}
 			resultJSON, _ := json.MarshalIndent(result, "", "  ")
 			return &tools.Result{Success: true, Content: string(resultJSON)}
@@ -280,7 +279,7 @@ func (t *FilesystemTool) exists(path string) *tools.Result {
 
 	result := map[string]any{
 		"exists": true,
-		"path":   path,
+		"path":   displayPath(name),
 		"is_dir": info.IsDir(),
 		"size":   info.Size(),
 	}
@@ -289,15 +288,15 @@ func (t *FilesystemTool) exists(path string) *tools.Result {
 }
 
 // info 获取文件或目录详细信息。
-func (t *FilesystemTool) info(path string) *tools.Result {
-	info, err := os.Stat(path)
+func (t *FilesystemTool) info(ctx context.Context, fsys vfs.FS, name string) *tools.Result {
+	info, err := fsys.Stat(ctx, name)
 	if err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("获取文件信息失败: %w", err)}
 	}
 
 	result := map[string]any{
 		"name":     info.Name(),
-		"path":     path,
+		"path":     displayPath(name),
 		"is_dir":   info.IsDir(),
 		"size":     info.Size(),
 		"mode":     info.Mode().String(),
@@ -306,11 +305,11 @@ func (t *FilesystemTool) info(path string) *tools.Result {
 
 	// 如果是文件，尝试检测内容类型
 	if !info.IsDir() {
-		file, err := os.Open(path)
+		file, err := fsys.Open(ctx, name)
 		if err == nil {
 			defer file.Close()
 			buf := make([]byte, 512)
-			n, _ := file.Read(buf)
+			n, _ := io.ReadFull(file, buf)
 			if n > 0 {
 				// 简单的内容类型检测
 				content := string(buf[:n])
@@ -328,3 +327,11 @@ func (t *FilesystemTool) info(path string) *tools.Result {
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 	return &tools.Result{Success: true, Content: string(resultJSON)}
 }
+
+// displayPath 返回工具输出中的相对路径，工作目录本身为 .。
+func displayPath(name string) string {
+	if name == "" {
+		return "."
+	}
+	return name
+}
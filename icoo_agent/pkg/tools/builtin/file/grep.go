@@ -0,0 +1,369 @@
+package file
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"regexp/syntax"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
+)
+
+const (
+	// grepDefaultResults 默认返回的最大匹配数
+	grepDefaultResults = 100
+	// grepMaxResults max_results 参数的上限
+	grepMaxResults = 1000
+	// grepMaxFileSize 超过该大小的文件不搜索
+	grepMaxFileSize = 4 << 20
+	// grepBinaryProbe 判断二进制文件时检查的前缀长度
+	grepBinaryProbe = 8000
+	// grepMaxLineLen 结果中每行保留的最大字符数
+	grepMaxLineLen = 200
+)
+
+// GrepTool 在工作目录的文件中搜索文本。多个文件并行搜索，不含正则元字符的模式按字面量查找，
+// 跳过二进制文件、过大的文件和忽略规则匹配的文件，匹配数达到上限后立即停止。
+type GrepTool struct {
+	WorkDir string
+	Mounts  *vfs.Mounts
+	// Ignore 跳过的文件，为 nil 时搜索全部文件
+	Ignore *IgnoreOptions
+	// Workers 并行搜索的文件数，0 表示按 CPU 数量
+	Workers int
+}
+
+// NewGrepTool 创建一个新的文本搜索工具。
+func NewGrepTool(workDir string) *GrepTool {
+	if workDir == "" {
+		workDir = "./workspace"
+	}
+	os.MkdirAll(workDir, 0755)
+	return &GrepTool{WorkDir: workDir}
+}
+
+// Name 返回工具名称。
+func (t *GrepTool) Name() string {
+	return "grep"
+}
+
+// Description 返回工具描述。
+func (t *GrepTool) Description() string {
+	return "在工作目录的文件中搜索文本或正则表达式，返回 路径:行号: 内容。" +
+		"跳过二进制文件、超过 4 MiB 的文件，以及 .gitignore、.icooclawignore 和默认忽略列表中的文件。" + mountNote(t.Mounts)
+}
+
+// Parameters 返回工具参数。
+func (t *GrepTool) Parameters() map[string]any {
+	return map[string]any{
+		"pattern": map[string]any{
+			"type":        "string",
+			"description": "要搜索的文本或 Go 正则表达式（RE2 语法），不含正则元字符时按字面量查找",
+			"required":    true,
+		},
+		"path": map[string]any{
+			"type":        "string",
+			"description": "搜索的目录或文件（默认为工作目录）",
+		},
+		"include": map[string]any{
+			"type":        "string",
+			"description": "只搜索文件名匹配该通配符的文件，如 *.go",
+		},
+		"ignore_case": map[string]any{
+			"type":        "boolean",
+			"description": "忽略大小写，默认 false",
+		},
+		"max_results": map[string]any{
+			"type":        "integer",
+			"description": fmt.Sprintf("最多返回的匹配数，默认 %d，上限 %d", grepDefaultResults, grepMaxResults),
+		},
+		"all": map[string]any{
+			"type":        "boolean",
+			"description": "同时搜索被忽略的文件，默认 false",
+		},
+	}
+}
+
+// grepMatch 一处匹配。
+type grepMatch struct {
+	name string
+	line int
+	text string
+}
+
+// matcher 判断文件内容和单行是否匹配。
+type matcher struct {
+	literal []byte // 字面量模式，nil 表示使用正则
+	re      *regexp.Regexp
+	file    *regexp.Regexp // 多行模式的 re，^ 和 $ 匹配每行的首尾，用于整个文件的预筛选，nil 表示不预筛选
+}
+
+// newMatcher 编译模式，不含正则元字符且区分大小写时使用 bytes.Contains 查找。
+func newMatcher(pattern string, ignoreCase bool) (*matcher, error) {
+	if !ignoreCase && regexp.QuoteMeta(pattern) == pattern {
+		return &matcher{literal: []byte(pattern)}, nil
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的正则表达式: %w", err)
+	}
+	m := &matcher{re: re}
+	// \A、\z 逐行匹配时表示行首行尾，在整个文件上表示文件首尾，这类模式不预筛选
+	if parsed, err := syntax.Parse("(?m)"+pattern, syntax.Perl); err == nil && !hasTextAnchor(parsed) {
+		m.file = regexp.MustCompile("(?m)" + pattern)
+	}
+	return m, nil
+}
+
+// hasTextAnchor 判断正则是否含有匹配文本首尾的 \A 或 \z。
+func hasTextAnchor(re *syntax.Regexp) bool {
+	if re.Op == syntax.OpBeginText || re.Op == syntax.OpEndText {
+		return true
+	}
+	return slices.ContainsFunc(re.Sub, hasTextAnchor)
+}
+
+// matchLine 判断单行是否匹配。
+func (m *matcher) matchLine(line []byte) bool {
+	if m.literal != nil {
+		return bytes.Contains(line, m.literal)
+	}
+	return m.re.Match(line)
+}
+
+// matchFile 判断文件中是否可能有匹配的行。
+func (m *matcher) matchFile(data []byte) bool {
+	if m.literal != nil {
+		return bytes.Contains(data, m.literal)
+	}
+	if m.file == nil {
+		return true
+	}
+	// 逐行匹配时去掉了行尾的 \r，预筛选同样去掉，否则 foo$ 在 CRLF 文件中不匹配
+	if bytes.Contains(data, []byte("\r\n")) {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	}
+	return m.file.Match(data)
+}
+
+// Execute 执行搜索。
+func (t *GrepTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		return &tools.Result{Success: false, Error: fmt.Errorf("需要提供 pattern 参数")}
+	}
+	p, _ := args["path"].(string)
+	include, _ := args["include"].(string)
+	if include != "" {
+		if _, err := path.Match(include, ""); err != nil {
+			return &tools.Result{Success: false, Error: fmt.Errorf("无效的 include 通配符: %w", err)}
+		}
+	}
+	ignoreCase, _ := args["ignore_case"].(bool)
+	all, _ := args["all"].(bool)
+	limit := grepDefaultResults
+	if n, ok := args["max_results"].(float64); ok && n > 0 {
+		limit = min(int(n), grepMaxResults)
+	}
+
+	m, err := newMatcher(pattern, ignoreCase)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	fsys, name, err := resolve(ctx, t.WorkDir, t.Mounts, p)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	info, err := fsys.Stat(ctx, name)
+	if err != nil {
+		return &tools.Result{Success: false, Error: fmt.Errorf("路径不存在: %s", p)}
+	}
+
+	var ig *ignorer
+	if !all && t.Ignore != nil && info.IsDir() {
+		ig = newIgnorer(ctx, fsys, t.Ignore, name)
+	}
+	matches, truncated, err := t.search(ctx, fsys, name, info.IsDir(), include, m, ig, limit)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	return &tools.Result{Success: true, Content: grepReport(matches, truncated, limit)}
+}
+
+// search 并行搜索文件，匹配数超过 limit 后停止遍历和搜索，truncated 表示还有更多匹配。
+func (t *GrepTool) search(ctx context.Context, fsys vfs.FS, root string, dir bool, include string, m *matcher, ig *ignorer, limit int) (matches []grepMatch, truncated bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	files := make(chan string)
+	var (
+		mu    sync.Mutex
+		count atomic.Int64
+		wg    sync.WaitGroup
+	)
+	workers := t.Workers
+	if workers <= 0 {
+		workers = min(runtime.NumCPU(), 8)
+	}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range files {
+				found := grepFile(ctx, fsys, name, m)
+				if len(found) == 0 {
+					continue
+				}
+				mu.Lock()
+				matches = append(matches, found...)
+				mu.Unlock()
+				if count.Add(int64(len(found))) > int64(limit) {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	var walkErr error
+	if dir {
+		walkErr = walkFiles(ctx, fsys, root, include, ig, files)
+	} else {
+		select {
+		case files <- root:
+		case <-ctx.Done():
+		}
+	}
+	close(files)
+	wg.Wait()
+
+	// 超过上限后取消的遍历不算错误
+	switch truncated = count.Load() > int64(limit); {
+	case truncated:
+	case walkErr != nil:
+		return nil, false, walkErr
+	case ctx.Err() != nil:
+		return nil, false, fmt.Errorf("搜索被中止: %w", ctx.Err())
+	}
+
+	slices.SortFunc(matches, func(a, b grepMatch) int {
+		return cmp.Or(strings.Compare(a.name, b.name), a.line-b.line)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, truncated, nil
+}
+
+// walkFiles 递归遍历目录，将需要搜索的文件发送到 files，ctx 取消后停止。
+func walkFiles(ctx context.Context, fsys vfs.FS, dir, include string, ig *ignorer, files chan<- string) error {
+	entries, err := fsys.ReadDir(ctx, dir)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if dir != "" {
+			// 子目录读取失败时跳过
+			return nil
+		}
+		return fmt.Errorf("读取目录失败: %w", err)
+	}
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		if ig != nil && ig.ignored(name, entry.IsDir()) {
+			continue
+		}
+		if entry.IsDir() {
+			sub := ig
+			if ig != nil {
+				sub = ig.enter(ctx, fsys, name)
+			}
+			if err := walkFiles(ctx, fsys, name, include, sub, files); err != nil {
+				return err
+			}
+			continue
+		}
+		if entry.Size() > grepMaxFileSize {
+			continue
+		}
+		if include != "" {
+			if ok, _ := path.Match(include, entry.Name()); !ok {
+				continue
+			}
+		}
+		select {
+		case files <- name:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// grepFile 搜索单个文件，跳过二进制文件。整个文件不匹配时不逐行检查。
+func grepFile(ctx context.Context, fsys vfs.FS, name string, m *matcher) []grepMatch {
+	if ctx.Err() != nil {
+		return nil
+	}
+	data, err := vfs.ReadFile(ctx, fsys, name)
+	if err != nil || len(data) > grepMaxFileSize {
+		return nil
+	}
+	if bytes.IndexByte(data[:min(len(data), grepBinaryProbe)], 0) >= 0 || !m.matchFile(data) {
+		return nil
+	}
+
+	var found []grepMatch
+	for i, line := 1, data; len(line) > 0; i++ {
+		end := bytes.IndexByte(line, '\n')
+		cur := line
+		if end >= 0 {
+			cur, line = line[:end], line[end+1:]
+		} else {
+			line = nil
+		}
+		cur = bytes.TrimSuffix(cur, []byte("\r"))
+		if m.matchLine(cur) {
+			found = append(found, grepMatch{name: displayPath(name), line: i, text: truncateLine(string(cur))})
+		}
+	}
+	return found
+}
+
+// truncateLine 截断过长的行。
+func truncateLine(s string) string {
+	s = strings.TrimSpace(s)
+	if r := []rune(s); len(r) > grepMaxLineLen {
+		return string(r[:grepMaxLineLen]) + "…"
+	}
+	return s
+}
+
+// grepReport 格式化搜索结果。
+func grepReport(matches []grepMatch, truncated bool, limit int) string {
+	if len(matches) == 0 {
+		return "没有找到匹配的内容"
+	}
+	var sb strings.Builder
+	if truncated {
+		fmt.Fprintf(&sb, "找到至少 %d 处匹配，已达到 max_results 上限，结果不完整，可以缩小搜索范围:\n", limit)
+	} else {
+		fmt.Fprintf(&sb, "找到 %d 处匹配:\n", len(matches))
+	}
+	for _, m := range matches {
+		fmt.Fprintf(&sb, "%s:%d: %s\n", m.name, m.line, m.text)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,85 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGrepTool(t *testing.T) {
+	workDir := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":          "*.log\n",
+		"main.go":             "package main\n\nfunc main() {\n\tprintln(\"TODO: greet\")\n}\n",
+		"util/util.go":        "package util\n// todo later\nfunc Helper() {}\n",
+		"util/notes.md":       "TODO: docs\r\n",
+		"debug.log":           "TODO: ignored\n",
+		"node_modules/x.js":   "// TODO: dependency\n",
+		"image.bin":           "TODO\x00\x01\x02",
+		"util/deep/config.go": "package deep\nvar x = 1 // TODO(a.b)\n",
+	} {
+		p := filepath.Join(workDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewGrepTool(workDir)
+	tool.Ignore = &IgnoreOptions{Patterns: DefaultIgnorePatterns, GitIgnore: true}
+	ctx := context.Background()
+	grep := func(args map[string]any) string {
+		t.Helper()
+		res := tool.Execute(ctx, args)
+		if !res.Success {
+			t.Fatalf("Execute(%v) error = %v", args, res.Error)
+		}
+		return res.Content
+	}
+
+	// 字面量查找，跳过忽略的文件和二进制文件，结果按路径和行号排序
+	got := grep(map[string]any{"pattern": "TODO"})
+	want := "找到 3 处匹配:\nmain.go:4: println(\"TODO: greet\")\nutil/deep/config.go:2: var x = 1 // TODO(a.b)\nutil/notes.md:1: TODO: docs"
+	if got != want {
+		t.Errorf("grep TODO = %q, want %q", got, want)
+	}
+
+	// 含元字符的模式按正则匹配
+	if got := grep(map[string]any{"pattern": `TODO\(a\.b\)`}); !strings.Contains(got, "找到 1 处匹配") {
+		t.Errorf("grep escaped = %q", got)
+	}
+
+	// 正则、忽略大小写、include 和行首锚点
+	got = grep(map[string]any{"pattern": `^//\s*todo`, "ignore_case": true, "include": "*.go", "path": "util"})
+	if got != "找到 1 处匹配:\nutil/util.go:2: // todo later" {
+		t.Errorf("grep regexp = %q", got)
+	}
+
+	// all 搜索被忽略的文件
+	if got := grep(map[string]any{"pattern": "TODO", "all": true}); !strings.Contains(got, "debug.log:1") || !strings.Contains(got, "node_modules/x.js:1") {
+		t.Errorf("grep all = %q", got)
+	}
+
+	// 超过上限时截断，恰好等于上限时不算截断
+	if got := grep(map[string]any{"pattern": "TODO", "max_results": float64(2)}); !strings.Contains(got, "结果不完整") || strings.Count(got, "\n") != 2 {
+		t.Errorf("grep max_results = %q", got)
+	}
+	if got := grep(map[string]any{"pattern": "TODO", "max_results": float64(3)}); strings.Contains(got, "结果不完整") || strings.Count(got, "\n") != 3 {
+		t.Errorf("grep max_results = matches = %q", got)
+	}
+
+	// 行尾锚点在 CRLF 文件中匹配，\A 和 \z 表示行首行尾
+	for _, pattern := range []string{`docs$`, `\ATODO: docs\z`, `docs\s*$`} {
+		if got := grep(map[string]any{"pattern": pattern, "path": "util"}); got != "找到 1 处匹配:\nutil/notes.md:1: TODO: docs" {
+			t.Errorf("grep %s = %q", pattern, got)
+		}
+	}
+
+	if res := tool.Execute(ctx, map[string]any{"pattern": "a("}); res.Success {
+		t.Error("invalid regexp should fail")
+	}
+}
@@ -0,0 +1,170 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"icooclaw/pkg/vfs"
+)
+
+// IgnoreFileName 工作目录中专供 icooclaw 使用的忽略文件，格式与 .gitignore 相同，总是读取。
+const IgnoreFileName = ".icooclawignore"
+
+// DefaultIgnorePatterns 默认忽略的版本库、依赖、缓存和构建产物。
+var DefaultIgnorePatterns = []string{
+	".git/", ".hg/", ".svn/",
+	"node_modules/", "bower_components/", ".venv/", "venv/", "__pycache__/",
+	".pytest_cache/", ".mypy_cache/", ".tox/", ".gradle/", ".next/", ".nuxt/", ".cache/",
+	"dist/", "build/", "target/", "coverage/",
+	"*.pyc", "*.class", "*.o", ".DS_Store",
+}
+
+// IgnoreOptions 递归遍历目录和搜索时跳过的文件。
+type IgnoreOptions struct {
+	// Patterns gitignore 格式的全局忽略模式
+	Patterns []string
+	// GitIgnore 读取各级目录中的 .gitignore
+	GitIgnore bool
+}
+
+// ValidateIgnorePatterns 校验 gitignore 格式的模式。工作目录中忽略文件的无效模式直接跳过，不报错。
+func ValidateIgnorePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if rules := parseIgnore("", []string{p}); len(rules) == 0 && strings.TrimSpace(p) != "" && !strings.HasPrefix(p, "#") {
+			return fmt.Errorf("无效的忽略模式 %q", p)
+		}
+	}
+	return nil
+}
+
+// ignoreRule 一条 gitignore 格式的规则。
+type ignoreRule struct {
+	base     string // 规则所在目录，全局规则为空
+	pattern  string
+	negate   bool // 以 ! 开头，重新包含之前被忽略的文件
+	dirOnly  bool // 以 / 结尾，只匹配目录
+	anchored bool // 包含 /，相对规则所在目录匹配；否则匹配任意层级的文件名
+}
+
+// parseIgnore 解析 gitignore 格式的内容，base 为规则所在目录。
+func parseIgnore(base string, lines []string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			r.negate, line = true, line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			r.anchored, line = true, strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			continue
+		}
+		r.pattern = line
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// match 判断 name（文件系统中的完整路径）是否匹配规则。
+func (r ignoreRule) match(name string, dir bool) bool {
+	if r.dirOnly && !dir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(name, r.base+"/") {
+			return false
+		}
+		name = name[len(r.base)+1:]
+	}
+	if !r.anchored {
+		ok, _ := path.Match(r.pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(r.pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments 按 / 分段匹配路径，** 匹配任意层级（包括零层）。
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ignorer 遍历到某个目录时生效的忽略规则，后出现的规则优先。
+type ignorer struct {
+	opts  *IgnoreOptions
+	rules []ignoreRule
+}
+
+// newIgnorer 创建从 root 开始遍历的忽略规则，包括全局规则和 root 及其上级目录中的忽略文件。
+func newIgnorer(ctx context.Context, fsys vfs.FS, opts *IgnoreOptions, root string) *ignorer {
+	ig := &ignorer{opts: opts, rules: parseIgnore("", opts.Patterns)}
+	dir := ""
+	ig = ig.enter(ctx, fsys, dir)
+	if root != "" {
+		for _, seg := range strings.Split(root, "/") {
+			dir = path.Join(dir, seg)
+			ig = ig.enter(ctx, fsys, dir)
+		}
+	}
+	return ig
+}
+
+// enter 读取目录中的 .gitignore 和 .icooclawignore，返回进入该目录后生效的规则。
+func (ig *ignorer) enter(ctx context.Context, fsys vfs.FS, dir string) *ignorer {
+	names := []string{IgnoreFileName}
+	if ig.opts.GitIgnore {
+		names = []string{".gitignore", IgnoreFileName}
+	}
+	var added []ignoreRule
+	for _, n := range names {
+		data, err := vfs.ReadFile(ctx, fsys, path.Join(dir, n))
+		if err != nil {
+			continue
+		}
+		added = append(added, parseIgnore(dir, strings.Split(string(data), "\n"))...)
+	}
+	if len(added) == 0 {
+		return ig
+	}
+	return &ignorer{opts: ig.opts, rules: append(append([]ignoreRule(nil), ig.rules...), added...)}
+}
+
+// ignored 判断文件或目录是否被忽略。
+func (ig *ignorer) ignored(name string, dir bool) bool {
+	for i := len(ig.rules) - 1; i >= 0; i-- {
+		if ig.rules[i].match(name, dir) {
+			return !ig.rules[i].negate
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListIgnore(t *testing.T) {
+	workDir := t.TempDir()
+	for name, content := range map[string]string{
+		"proj/.gitignore":              "*.log\n/out/\n!keep.log\n",
+		"proj/.icooclawignore":         "fixtures/\n",
+		"proj/main.go":                 "package main",
+		"proj/debug.log":               "x",
+		"proj/keep.log":                "x",
+		"proj/out/bin":                 "x",
+		"proj/src/out/gen.go":          "x",
+		"proj/src/fixtures/big.json":   "x",
+		"proj/node_modules/a/index.js": "x",
+	} {
+		p := filepath.Join(workDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewFilesystemTool(workDir)
+	tool.Ignore = &IgnoreOptions{Patterns: DefaultIgnorePatterns, GitIgnore: true}
+	list := func(args map[string]any) map[string]bool {
+		t.Helper()
+		res := tool.Execute(context.Background(), args)
+		if !res.Success {
+			t.Fatalf("Execute(%v) error = %v", args, res.Error)
+		}
+		var entries []listEntry
+		if err := json.Unmarshal([]byte(res.Content), &entries); err != nil {
+			t.Fatal(err)
+		}
+		names := make(map[string]bool)
+		for _, e := range entries {
+			names[e.Name] = e.Ignored
+		}
+		return names
+	}
+
+	names := list(map[string]any{"operation": "list", "path": "proj", "recursive": true})
+	for _, want := range []string{"main.go", "keep.log", "src/out/gen.go", ".gitignore"} {
+		if ignored, ok := names[want]; !ok || ignored {
+			t.Errorf("%s missing from listing %v", want, names)
+		}
+	}
+	for _, dir := range []string{"node_modules", "out", "src/fixtures"} {
+		if !names[dir] {
+			t.Errorf("%s not marked ignored in %v", dir, names)
+		}
+	}
+	for _, skipped := range []string{"debug.log", "node_modules/a", "out/bin", "src/fixtures/big.json"} {
+		if _, ok := names[skipped]; ok {
+			t.Errorf("%s listed, want ignored", skipped)
+		}
+	}
+
+	// 从子目录开始列出时仍使用上级目录的规则
+	names = list(map[string]any{"operation": "list", "path": "proj/src", "recursive": true})
+	if _, ok := names["fixtures/big.json"]; ok || !names["fixtures"] {
+		t.Errorf("listing proj/src = %v, want fixtures ignored", names)
+	}
+
+	// all 列出全部内容
+	names = list(map[string]any{"operation": "list", "path": "proj", "recursive": true, "all": true})
+	for _, want := range []string{"node_modules/a/index.js", "debug.log"} {
+		if _, ok := names[want]; !ok {
+			t.Errorf("%s missing from listing with all", want)
+		}
+	}
+}
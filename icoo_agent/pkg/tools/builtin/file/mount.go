@@ -0,0 +1,45 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
+)
+
+// resolve 将工具参数中的路径解析为会话工作目录叠加远程挂载后的文件系统，以及路径在其中的名称。
+// 路径越界检查与 ResolvePath 相同，挂载点下的路径转发到对应的远程存储。
+// 无痕会话不使用远程挂载，避免文件留存在临时工作目录之外。
+func resolve(ctx context.Context, workDir string, mounts *vfs.Mounts, path string) (vfs.FS, string, error) {
+	workDir = tools.GetWorkspace(ctx, workDir)
+	if tools.IsEphemeral(ctx) {
+		mounts = nil
+	}
+	target, err := ResolvePath(workDir, path)
+	if err != nil {
+		return nil, "", err
+	}
+	return mounts.FS(workDir), vfs.Clean(RelPath(workDir, target)), nil
+}
+
+// Writable 拒绝修改 .git 中的文件：改写版本库配置可以让 git 工具执行任意命令。
+// name 为相对工作目录、以 / 分隔的路径，比较不区分大小写，兼容不区分大小写的文件系统。
+func Writable(name string) error {
+	for _, part := range strings.Split(name, "/") {
+		if strings.EqualFold(part, ".git") {
+			return fmt.Errorf("不允许修改 .git 中的文件: %s", name)
+		}
+	}
+	return nil
+}
+
+// mountNote 在工具描述中列出远程挂载路径，没有挂载时为空。
+func mountNote(mounts *vfs.Mounts) string {
+	paths := mounts.Paths()
+	if len(paths) == 0 {
+		return ""
+	}
+	return "工作目录中的 " + strings.Join(paths, "、") + " 为远程存储挂载，可以像本地文件一样读写，但有文件大小限制。"
+}
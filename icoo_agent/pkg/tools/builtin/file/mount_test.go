@@ -0,0 +1,74 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
+)
+
+func TestMountedStorage(t *testing.T) {
+	workDir, remote := t.TempDir(), t.TempDir()
+	mounts := vfs.NewMounts()
+	mounts.Add("drive", vfs.NewCached(vfs.Dir(remote), vfs.CacheOptions{MaxFileSize: 16}))
+
+	fsTool := NewFilesystemTool(workDir)
+	fsTool.Mounts = mounts
+	copyTool := NewCopyFileTool(workDir)
+	copyTool.Mounts = mounts
+	r := tools.NewRegistry()
+	r.Register(fsTool)
+	r.Register(copyTool)
+	ctx := context.Background()
+
+	res := r.Execute(ctx, "filesystem", map[string]any{"operation": "write", "path": "drive/notes/a.md", "content": "remote"})
+	if !res.Success {
+		t.Fatalf("write error = %v", res.Error)
+	}
+	if data, _ := os.ReadFile(filepath.Join(remote, "notes", "a.md")); string(data) != "remote" {
+		t.Errorf("mounted file = %q, want remote", data)
+	}
+
+	// 挂载点出现在工作目录的列表中
+	res = r.Execute(ctx, "filesystem", map[string]any{"operation": "list", "path": ""})
+	if !res.Success || !strings.Contains(res.Content, `"name": "drive"`) {
+		t.Errorf("list result = %+v", res)
+	}
+
+	// 在挂载和本地之间复制
+	res = r.Execute(ctx, "copy_file", map[string]any{"source": "drive/notes/a.md", "destination": "local.md"})
+	if !res.Success {
+		t.Fatalf("copy error = %v", res.Error)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workDir, "local.md")); string(data) != "remote" {
+		t.Errorf("copied file = %q, want remote", data)
+	}
+
+	// 超过挂载的大小限制
+	res = r.Execute(ctx, "filesystem", map[string]any{"operation": "write", "path": "drive/big.txt", "content": strings.Repeat("x", 17)})
+	if res.Success || !strings.Contains(res.Error.Error(), "大小限制") {
+		t.Errorf("oversized write result = %+v", res)
+	}
+
+	res = r.Execute(ctx, "filesystem", map[string]any{"operation": "delete", "path": "drive", "recursive": true})
+	if res.Success {
+		t.Error("expected error deleting the mount point")
+	}
+	// 无痕会话不使用远程挂载，写入留在临时工作目录中
+	tmp := t.TempDir()
+	ephemeralCtx := tools.WithEphemeral(tools.WithWorkspace(ctx, tmp))
+	res = r.Execute(ephemeralCtx, "filesystem", map[string]any{"operation": "write", "path": "drive/secret.md", "content": "local"})
+	if !res.Success {
+		t.Fatalf("ephemeral write error = %v", res.Error)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "secret.md")); !os.IsNotExist(err) {
+		t.Errorf("ephemeral write reached the mount, err = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmp, "drive", "secret.md")); string(data) != "local" {
+		t.Errorf("ephemeral file = %q, want local", data)
+	}
+}
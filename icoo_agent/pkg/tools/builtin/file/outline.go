@@ -0,0 +1,315 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Symbol kinds.
+const (
+	SymbolFunc      = "func"
+	SymbolMethod    = "method"
+	SymbolStruct    = "struct"
+	SymbolInterface = "interface"
+	SymbolType      = "type"
+	SymbolClass     = "class"
+	SymbolEnum      = "enum"
+	SymbolConst     = "const"
+	SymbolVar       = "var"
+)
+
+// Symbol 源文件中的一个顶层声明或方法。
+type Symbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Parent    string `json:"parent,omitempty"` // 方法的接收者类型或所在的类
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Signature string `json:"signature"` // 声明的首行，去掉函数体
+}
+
+// QualifiedName 返回带所属类型的名称，如 Server.Start。
+func (s Symbol) QualifiedName() string {
+	if s.Parent != "" {
+		return s.Parent + "." + s.Name
+	}
+	return s.Name
+}
+
+// outliners 按扩展名选择的解析器。Go 使用 go/ast 精确解析，其他语言按行匹配声明。
+var outliners = map[string]func(src []byte) []Symbol{
+	".go":  outlineGo,
+	".py":  outlinePython,
+	".js":  outlineJS,
+	".jsx": outlineJS,
+	".mjs": outlineJS,
+	".cjs": outlineJS,
+	".ts":  outlineJS,
+	".tsx": outlineJS,
+}
+
+// CanOutline 判断是否支持解析该文件的符号。
+func CanOutline(name string) bool {
+	_, ok := outliners[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+// Outline 解析源文件中的函数、类型等声明及其行范围。
+func Outline(name string, src []byte) ([]Symbol, error) {
+	outline, ok := outliners[strings.ToLower(path.Ext(name))]
+	if !ok {
+		return nil, fmt.Errorf("不支持的文件类型 %q，支持 Go、Python、JavaScript 和 TypeScript", path.Ext(name))
+	}
+	return outline(src), nil
+}
+
+// outlineGo 用 go/ast 解析 Go 源文件，语法错误时返回能解析出的部分。
+func outlineGo(src []byte) []Symbol {
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+	line := func(p token.Pos) int { return fset.Position(p).Line }
+	text := func(from, to token.Pos) string {
+		start, end := fset.Position(from).Offset, fset.Position(to).Offset
+		if start < 0 || end > len(src) || start >= end {
+			return ""
+		}
+		return strings.Join(strings.Fields(string(src[start:end])), " ")
+	}
+
+	var symbols []Symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			s := Symbol{Name: d.Name.Name, Kind: SymbolFunc, StartLine: line(d.Pos()), EndLine: line(d.End())}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				s.Kind, s.Parent = SymbolMethod, receiverType(d.Recv.List[0].Type)
+			}
+			end := d.End()
+			if d.Body != nil {
+				end = d.Body.Lbrace
+			}
+			s.Signature = text(d.Pos(), end)
+			symbols = append(symbols, s)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					s := Symbol{Name: sp.Name.Name, Kind: SymbolType, StartLine: line(sp.Pos()), EndLine: line(sp.End())}
+					switch sp.Type.(type) {
+					case *ast.StructType:
+						s.Kind = SymbolStruct
+					case *ast.InterfaceType:
+						s.Kind = SymbolInterface
+					}
+					s.Signature = "type " + firstLine(text(sp.Pos(), sp.End()))
+					symbols = append(symbols, s)
+				case *ast.ValueSpec:
+					kind := SymbolVar
+					if d.Tok == token.CONST {
+						kind = SymbolConst
+					}
+					for _, n := range sp.Names {
+						if n.Name == "_" {
+							continue
+						}
+						symbols = append(symbols, Symbol{
+							Name: n.Name, Kind: kind, StartLine: line(sp.Pos()), EndLine: line(sp.End()),
+							Signature: kind + " " + truncateLine(text(sp.Pos(), sp.End())),
+						})
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// receiverType 返回方法接收者的类型名，去掉指针和类型参数。
+func receiverType(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// firstLine 截取类型声明到结构体或接口的左花括号为止。
+func firstLine(s string) string {
+	if i := strings.Index(s, "{"); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return truncateLine(s)
+}
+
+var (
+	pyDecl = regexp.MustCompile(`^(\s*)(?:async\s+)?(def|class)\s+([A-Za-z_]\w*)`)
+
+	jsFunc      = regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)`)
+	jsClass     = regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`)
+	jsTypeDecl  = regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(interface|type|enum)\s+([A-Za-z_$][\w$]*)`)
+	jsArrow     = regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|(?:\([^)]*\)|[A-Za-z_$][\w$]*)\s*(?::[^=]+)?=>)`)
+	jsMethod    = regexp.MustCompile(`^\s+(?:(?:public|private|protected|static|async|readonly|override|get|set)\s+)*\*?\s*([A-Za-z_$#][\w$]*)\s*(?:<[^>]*>)?\((?:[^)]*\)\s*(?::[^{]+)?\{|[^)]*)\s*$`)
+	jsNotMethod = map[string]bool{"if": true, "for": true, "while": true, "switch": true, "catch": true, "function": true, "return": true, "with": true}
+)
+
+// outlinePython 按缩进确定函数和类的行范围，类中的函数为方法，函数中嵌套的函数和类不列出。
+func outlinePython(src []byte) []Symbol {
+	lines := splitLines(src)
+	type open struct {
+		index  int // symbols 中的下标，-1 表示不列出的嵌套声明
+		indent int
+	}
+	var (
+		symbols []Symbol
+		stack   []open
+	)
+	// 缩进不大于声明的非空行结束该声明，end 为之前最后一个非空行
+	closeTo := func(indent, end int) {
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			if idx := stack[len(stack)-1].index; idx >= 0 {
+				symbols[idx].EndLine = end
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	last := 0
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(l) - len(strings.TrimLeft(l, " \t"))
+		closeTo(indent, last)
+		last = i + 1
+
+		m := pyDecl.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		s := Symbol{Name: m[3], Kind: SymbolFunc, StartLine: i + 1, EndLine: i + 1, Signature: strings.TrimSuffix(trimmed, ":")}
+		if m[2] == "class" {
+			s.Kind = SymbolClass
+		}
+		if len(stack) > 0 {
+			top := stack[len(stack)-1].index
+			if top < 0 || symbols[top].Kind != SymbolClass {
+				stack = append(stack, open{index: -1, indent: indent})
+				continue
+			}
+			if s.Kind == SymbolFunc {
+				s.Kind, s.Parent = SymbolMethod, symbols[top].Name
+			}
+		}
+		symbols = append(symbols, s)
+		stack = append(stack, open{index: len(symbols) - 1, indent: indent})
+	}
+	closeTo(0, last)
+	return symbols
+}
+
+// outlineJS 按行匹配 JavaScript、TypeScript 的函数、类和类方法，用花括号配对确定行范围。
+// 函数和方法体内的行不再匹配，嵌套的函数不列出。
+func outlineJS(src []byte) []Symbol {
+	lines := splitLines(src)
+	var symbols []Symbol
+	class, classEnd := "", 0
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		if class != "" && i+1 > classEnd {
+			class = ""
+		}
+		s := Symbol{StartLine: i + 1, Signature: truncateLine(strings.TrimSuffix(strings.TrimSpace(l), "{"))}
+		switch {
+		case jsClass.MatchString(l):
+			s.Name, s.Kind = jsClass.FindStringSubmatch(l)[1], SymbolClass
+		case jsFunc.MatchString(l):
+			s.Name, s.Kind = jsFunc.FindStringSubmatch(l)[1], SymbolFunc
+		case jsTypeDecl.MatchString(l):
+			m := jsTypeDecl.FindStringSubmatch(l)
+			s.Name, s.Kind = m[2], map[string]string{"interface": SymbolInterface, "type": SymbolType, "enum": SymbolEnum}[m[1]]
+		case jsArrow.MatchString(l):
+			s.Name, s.Kind = jsArrow.FindStringSubmatch(l)[1], SymbolFunc
+		case class != "" && jsMethod.MatchString(l) && !jsNotMethod[jsMethod.FindStringSubmatch(l)[1]]:
+			s.Name, s.Kind, s.Parent = jsMethod.FindStringSubmatch(l)[1], SymbolMethod, class
+		default:
+			continue
+		}
+		if class != "" && s.Kind != SymbolMethod {
+			// 类中的属性等不是顶层声明
+			continue
+		}
+		s.EndLine = braceEnd(lines, i)
+		symbols = append(symbols, s)
+		if s.Kind == SymbolClass {
+			// 继续匹配类体中的方法
+			class, classEnd = s.Name, s.EndLine
+			continue
+		}
+		i = s.EndLine - 1
+	}
+	return symbols
+}
+
+// braceEnd 从 start 行开始配对花括号，返回声明结束的行号（从 1 开始）。
+// 跳过字符串和行注释中的花括号；类型别名等没有花括号的声明在遇到分号或空行时结束。
+func braceEnd(lines []string, start int) int {
+	depth, opened := 0, false
+	for i := start; i < len(lines); i++ {
+		var quote byte
+		l := lines[i]
+		for j := 0; j < len(l); j++ {
+			c := l[j]
+			switch {
+			case quote != 0:
+				if c == '\\' {
+					j++
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'' || c == '`':
+				quote = c
+			case c == '/' && j+1 < len(l) && l[j+1] == '/':
+				j = len(l)
+			case c == '{':
+				depth++
+				opened = true
+			case c == '}':
+				depth--
+				if opened && depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		if !opened && (strings.HasSuffix(strings.TrimSpace(l), ";") || (i > start && strings.TrimSpace(l) == "")) {
+			return i + 1
+		}
+	}
+	if !opened {
+		return start + 1
+	}
+	return len(lines)
+}
+
+// splitLines 按行拆分源文件，兼容 CRLF。
+func splitLines(src []byte) []string {
+	src = bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n"))
+	return strings.Split(strings.TrimSuffix(string(src), "\n"), "\n")
+}
@@ -0,0 +1,165 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// outlineString 将符号格式化为 "起止行 类型 名称"，便于比较。
+func outlineString(symbols []Symbol) string {
+	var lines []string
+	for _, s := range symbols {
+		lines = append(lines, fmt.Sprintf("%d-%d %s %s", s.StartLine, s.EndLine, s.Kind, s.QualifiedName()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestOutline(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "main.go",
+			src: `package main
+
+const Version = "1.0"
+
+// Server 服务。
+type Server struct {
+	addr string
+}
+
+type Handler interface {
+	Serve() error
+}
+
+func (s *Server) Start(port int) error {
+	return nil
+}
+
+func main() {
+	println("hi")
+}
+`,
+			want: "3-3 const Version\n6-8 struct Server\n10-12 interface Handler\n14-16 method Server.Start\n18-20 func main",
+		},
+		{
+			name: "app.py",
+			src: `import os
+
+class Store:
+    """存储。"""
+
+    def get(self, key):
+        def helper():
+            pass
+        return helper()
+
+    async def put(self, key, value):
+        pass
+
+
+def main():
+    Store().get("a")
+`,
+			want: "3-12 class Store\n6-9 method Store.get\n11-12 method Store.put\n15-16 func main",
+		},
+		{
+			name: "app.ts",
+			src: `import { x } from "./x";
+
+export interface Options {
+  name: string;
+}
+
+export class Client {
+  private url: string;
+
+  constructor(url: string) {
+    if (url) {
+      this.url = url;
+    }
+  }
+
+  async fetch(path: string): Promise<string> {
+    return "}";
+  }
+}
+
+export const handler = async (req) => {
+  return req;
+};
+
+function helper() {
+  function inner() {}
+}
+`,
+			want: "3-5 interface Options\n7-19 class Client\n10-14 method Client.constructor\n16-18 method Client.fetch\n21-23 func handler\n25-27 func helper",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbols, err := Outline(tt.name, []byte(tt.src))
+			if err != nil {
+				t.Fatalf("Outline() error = %v", err)
+			}
+			if got := outlineString(symbols); got != tt.want {
+				t.Errorf("Outline() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Outline("a.rb", nil); err == nil {
+		t.Error("Outline(a.rb) should fail")
+	}
+}
+
+func TestSymbolSearchTool(t *testing.T) {
+	workDir := t.TempDir()
+	for name, content := range map[string]string{
+		"server.go":           "package main\n\ntype Server struct{}\n\nfunc (s *Server) Start() {}\n\nfunc StartAll() {}\n",
+		"web/client.js":       "class Client {\n  start() {\n  }\n}\n",
+		"node_modules/x/a.js": "function start() {}\n",
+		"docs/start.md":       "start here\n",
+	} {
+		p := filepath.Join(workDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewSymbolSearchTool(workDir)
+	tool.Ignore = &IgnoreOptions{Patterns: DefaultIgnorePatterns}
+	ctx := context.Background()
+
+	// 名称完全相同的排在前面，跳过忽略的目录
+	res := tool.Execute(ctx, map[string]any{"query": "start"})
+	want := "找到 3 个符号:\nserver.go:5-5 method Server.Start: func (s *Server) Start()\nweb/client.js:2-3 method Client.start: start()\nserver.go:7-7 func StartAll: func StartAll()"
+	if !res.Success || res.Content != want {
+		t.Errorf("symbol_search start = %q, want %q", res.Content, want)
+	}
+
+	// 类型.方法 和 kind 过滤
+	res = tool.Execute(ctx, map[string]any{"query": "server.start"})
+	if !strings.HasPrefix(res.Content, "找到 1 个符号:\nserver.go:5-5") {
+		t.Errorf("symbol_search server.start = %q", res.Content)
+	}
+	res = tool.Execute(ctx, map[string]any{"query": "start", "kind": "func"})
+	if !strings.HasPrefix(res.Content, "找到 1 个符号:\nserver.go:7-7") {
+		t.Errorf("symbol_search kind = %q", res.Content)
+	}
+
+	outline := NewCodeOutlineTool(workDir).Execute(ctx, map[string]any{"path": "server.go"})
+	if !outline.Success || !strings.Contains(outline.Content, "  5-5 method Server.Start: func (s *Server) Start()") {
+		t.Errorf("code_outline = %q", outline.Content)
+	}
+}
@@ -0,0 +1,305 @@
+package file
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader 匹配统一差异格式的块头，如 "@@ -12,3 +12,4 @@"。
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// FilePatch 统一差异补丁中对单个文件的修改。
+type FilePatch struct {
+	OldPath string // 修改前的路径，为空表示新建文件
+	NewPath string // 修改后的路径，为空表示删除文件
+	Hunks   []*Hunk
+}
+
+// Path 返回补丁作用的文件路径，删除时为原路径。
+func (p *FilePatch) Path() string {
+	if p.NewPath != "" {
+		return p.NewPath
+	}
+	return p.OldPath
+}
+
+// Hunk 补丁中的一个修改块。
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []string // 带前缀的行：' ' 上下文、'-' 删除、'+' 新增
+
+	NoNewlineOld bool // 修改前最后一行没有换行符
+	NoNewlineNew bool // 修改后最后一行没有换行符
+}
+
+// before 返回块期望在文件中找到的行（上下文和删除的行）。
+func (h *Hunk) before() []string {
+	var lines []string
+	for _, l := range h.Lines {
+		if l[0] != '+' {
+			lines = append(lines, l[1:])
+		}
+	}
+	return lines
+}
+
+// replace 返回块替换 matched 后的行。上下文行保留文件中的原文，忽略行尾空白匹配时不改动这些行。
+func (h *Hunk) replace(matched []string) []string {
+	var lines []string
+	j := 0
+	for _, l := range h.Lines {
+		switch l[0] {
+		case ' ':
+			lines = append(lines, matched[j])
+			j++
+		case '-':
+			j++
+		default:
+			lines = append(lines, l[1:])
+		}
+	}
+	return lines
+}
+
+// ParsePatch 解析统一差异格式的补丁，支持多个文件。"diff --git"、"index" 等扩展头和补丁前的说明文字会被忽略，
+// 路径的 a/、b/ 前缀会被去掉，/dev/null 表示新建或删除。
+func ParsePatch(text string) ([]*FilePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var patches []*FilePatch
+	seen := make(map[string]bool)
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "--- ") || i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "+++ ") {
+			continue
+		}
+		p := &FilePatch{
+			OldPath: headerPath(lines[i][4:], "a/"),
+			NewPath: headerPath(lines[i+1][4:], "b/"),
+		}
+		if p.OldPath == "" && p.NewPath == "" {
+			return nil, fmt.Errorf("第 %d 行: 文件头的新旧路径不能都是 /dev/null", i+1)
+		}
+		for _, path := range []string{p.OldPath, p.NewPath} {
+			if path != "" && seen[path] {
+				return nil, fmt.Errorf("第 %d 行: 文件 %s 在补丁中出现了多次", i+1, path)
+			}
+		}
+		seen[p.OldPath], seen[p.NewPath] = true, true
+		i += 2
+
+		for i < len(lines) && strings.HasPrefix(lines[i], "@@") {
+			h, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p.Path(), err)
+			}
+			p.Hunks = append(p.Hunks, h)
+			i = next
+		}
+		if len(p.Hunks) == 0 && p.OldPath != "" && p.NewPath != "" && p.OldPath == p.NewPath {
+			return nil, fmt.Errorf("%s: 没有修改块", p.Path())
+		}
+		patches = append(patches, p)
+		i--
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("没有找到文件头，补丁需要为统一差异格式（--- a/文件 与 +++ b/文件）")
+	}
+	return patches, nil
+}
+
+// parseHunk 解析从 lines[i] 开始的修改块，返回块和块之后的行号。
+// 块的行数以块头为准，行尾空白被编辑器去掉的空上下文行视为空行。
+func parseHunk(lines []string, i int) (*Hunk, int, error) {
+	m := hunkHeader.FindStringSubmatch(lines[i])
+	if m == nil {
+		return nil, i, fmt.Errorf("第 %d 行: 无效的块头 %q", i+1, lines[i])
+	}
+	h := &Hunk{
+		OldStart: atoi(m[1], 0),
+		OldLines: atoi(m[2], 1),
+		NewStart: atoi(m[3], 0),
+		NewLines: atoi(m[4], 1),
+	}
+	start := i + 1
+	oldLeft, newLeft := h.OldLines, h.NewLines
+	for i = start; i < len(lines) && (oldLeft > 0 || newLeft > 0); i++ {
+		line := lines[i]
+		if line == "" {
+			line = " "
+		}
+		switch line[0] {
+		case ' ':
+			oldLeft--
+			newLeft--
+		case '-':
+			oldLeft--
+		case '+':
+			newLeft--
+		case '\\':
+			h.markNoNewline()
+			continue
+		default:
+			return nil, i, fmt.Errorf("第 %d 行: 块 %s 的行数与块头不符", start, strings.TrimSpace(lines[start-1]))
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return nil, i, fmt.Errorf("第 %d 行: 块 %s 的行数与块头不符", start, strings.TrimSpace(lines[start-1]))
+		}
+		h.Lines = append(h.Lines, line)
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, i, fmt.Errorf("第 %d 行: 块 %s 不完整", start, strings.TrimSpace(lines[start-1]))
+	}
+	if i < len(lines) && strings.HasPrefix(lines[i], `\`) {
+		h.markNoNewline()
+		i++
+	}
+	return h, i, nil
+}
+
+// markNoNewline 处理 "\ No newline at end of file"，作用于上一行。
+func (h *Hunk) markNoNewline() {
+	if len(h.Lines) == 0 {
+		return
+	}
+	switch h.Lines[len(h.Lines)-1][0] {
+	case '-':
+		h.NoNewlineOld = true
+	case '+':
+		h.NoNewlineNew = true
+	default:
+		h.NoNewlineOld, h.NoNewlineNew = true, true
+	}
+}
+
+// headerPath 从文件头中取出路径，去掉时间戳和 a/、b/ 前缀，/dev/null 返回空。
+func headerPath(s, prefix string) string {
+	if idx := strings.IndexByte(s, '\t'); idx >= 0 {
+		s = s[:idx]
+	}
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(s, prefix)
+}
+
+func atoi(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// PatchPaths 返回补丁涉及的全部文件路径，补丁无效时返回 nil。供权限检查提取 apply_patch 的路径参数。
+func PatchPaths(text string) []string {
+	patches, err := ParsePatch(text)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, p := range patches {
+		if p.OldPath != "" {
+			paths = append(paths, p.OldPath)
+		}
+		if p.NewPath != "" && p.NewPath != p.OldPath {
+			paths = append(paths, p.NewPath)
+		}
+	}
+	return paths
+}
+
+// applyHunks 将修改块依次应用到文件内容上。块先在块头给出的行号处匹配，
+// 不匹配时在附近查找（模型生成的行号常有偏差），再不匹配时忽略行尾空白重试。
+// 文件使用 CRLF 换行时保持 CRLF。
+func applyHunks(content string, hunks []*Hunk) (string, error) {
+	eol := "\n"
+	if strings.Contains(content, "\r\n") {
+		eol = "\r\n"
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+	trailing := content == "" || strings.HasSuffix(content, "\n")
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	// pos 为上一个块结束的位置，之后的块只在其后查找；offset 为之前的块造成的行号偏移
+	pos, offset := 0, 0
+	for i, h := range hunks {
+		old := h.before()
+		at := locate(lines, old, pos, h.OldStart-1+offset, len(old) == 0)
+		if at < 0 {
+			return "", fmt.Errorf("第 %d 个块（@@ -%d,%d）与文件当前内容不匹配", i+1, h.OldStart, h.OldLines)
+		}
+		end := at + len(old)
+		repl := h.replace(lines[at:end])
+		if end == len(lines) {
+			if h.NoNewlineNew {
+				trailing = false
+			} else if len(repl) > 0 {
+				trailing = true
+			}
+		}
+		lines = append(lines[:at], append(repl, lines[end:]...)...)
+		pos = at + len(repl)
+		offset += len(repl) - len(old)
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+	out := strings.Join(lines, eol)
+	if trailing {
+		out += eol
+	}
+	return out, nil
+}
+
+// locate 返回 old 在 lines 中从 from 开始、离 want 最近的位置，找不到时返回 -1。
+// insert 为 true 表示块没有上下文（纯新增），直接使用块头给出的位置。
+func locate(lines, old []string, from, want int, insert bool) int {
+	if insert {
+		// 纯新增块的起始行是插入位置之前的行
+		want++
+		if want < from {
+			want = from
+		}
+		return min(want, len(lines))
+	}
+	for _, eq := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		func(a, b string) bool { return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t") },
+	} {
+		best := -1
+		for at := from; at+len(old) <= len(lines); at++ {
+			if matches(lines[at:at+len(old)], old, eq) && (best < 0 || abs(at-want) < abs(best-want)) {
+				best = at
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	return -1
+}
+
+func matches(lines, old []string, eq func(a, b string) bool) bool {
+	for i := range old {
+		if !eq(lines[i], old[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
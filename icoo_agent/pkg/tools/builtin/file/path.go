@@ -0,0 +1,80 @@
+package file
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ResolvePath 将工具参数中的路径解析为工作目录内的绝对路径。
+//
+// 参数可以使用 / 或系统分隔符；相对路径基于工作目录，绝对路径（含 Windows 盘符和
+// UNC 路径 \\server\share）必须位于工作目录内。两侧都经过 filepath 规范化后逐段比较，
+// 只有盘符和 UNC 主机/共享名不区分大小写，其余部分保持原样，兼容区分大小写的网络共享。
+func ResolvePath(workDir, path string) (string, error) {
+	root, err := filepath.Abs(workDir)
+	if err != nil {
+		return "", fmt.Errorf("获取工作目录绝对路径失败: %w", err)
+	}
+
+	path = filepath.FromSlash(path)
+	var target string
+	switch {
+	case path == "":
+		target = root
+	case filepath.IsAbs(path) || filepath.VolumeName(path) != "":
+		target, err = filepath.Abs(path)
+		if err != nil {
+			return "", fmt.Errorf("获取文件绝对路径失败: %w", err)
+		}
+	default:
+		target = filepath.Join(root, path)
+	}
+
+	if !Within(root, target) {
+		return "", fmt.Errorf("路径超出工作目录范围: %s", filepath.ToSlash(path))
+	}
+	return target, nil
+}
+
+// Within 判断 target 是否为 root 本身或位于 root 之下，两者都应为绝对路径。
+// 与前缀比较不同，/work 不会被视为包含 /workspace。
+func Within(root, target string) bool {
+	root, target = filepath.Clean(root), filepath.Clean(target)
+	rootVol, targetVol := filepath.VolumeName(root), filepath.VolumeName(target)
+	if !strings.EqualFold(rootVol, targetVol) {
+		return false
+	}
+
+	rootParts := splitPath(root[len(rootVol):])
+	targetParts := splitPath(target[len(targetVol):])
+	if len(targetParts) < len(rootParts) {
+		return false
+	}
+	for i, part := range rootParts {
+		if targetParts[i] != part {
+			return false
+		}
+	}
+	return true
+}
+
+// RelPath 返回 target 相对工作目录的路径，统一使用 / 分隔，用于工具输出。
+func RelPath(workDir, target string) string {
+	root, err := filepath.Abs(workDir)
+	if err != nil {
+		return filepath.ToSlash(target)
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return filepath.ToSlash(target)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// splitPath 按系统分隔符拆分已清理路径，忽略空段。
+func splitPath(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool {
+		return r == filepath.Separator
+	})
+}
@@ -0,0 +1,112 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"icooclaw/pkg/tools"
+)
+
+func TestResolvePath(t *testing.T) {
+	workDir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "空路径为工作目录", path: "", want: workDir},
+		{name: "相对路径", path: "a/b.txt", want: filepath.Join(workDir, "a", "b.txt")},
+		{name: "清理后仍在目录内", path: "a/../b.txt", want: filepath.Join(workDir, "b.txt")},
+		{name: "工作目录内的绝对路径", path: filepath.Join(workDir, "c.txt"), want: filepath.Join(workDir, "c.txt")},
+		{name: "向上越界", path: "../outside.txt", wantErr: true},
+		{name: "同名前缀的兄弟目录", path: workDir + "2/x.txt", wantErr: true},
+		{name: "工作目录外的绝对路径", path: filepath.Dir(workDir), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolvePath(workDir, tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ResolvePath(%q) = %q, want error", tt.path, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolvePath(%q) error: %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolvePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithinIsCaseSensitive(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "srv", "Workspace")
+	if !Within(root, filepath.Join(root, "Docs")) {
+		t.Error("子目录应在工作目录内")
+	}
+	if Within(root, filepath.Join(string(filepath.Separator), "srv", "workspace", "Docs")) {
+		t.Error("大小写不同的目录不应视为工作目录")
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	workDir := t.TempDir()
+	if got := RelPath(workDir, filepath.Join(workDir, "a", "b.txt")); got != "a/b.txt" {
+		t.Errorf("RelPath = %q, want a/b.txt", got)
+	}
+}
+
+func TestGitDirNotWritable(t *testing.T) {
+	workDir := t.TempDir()
+	config := filepath.Join(workDir, "repo", ".git", "config")
+	if err := os.MkdirAll(filepath.Dir(config), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config, []byte("[core]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "src.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := tools.NewRegistry()
+	r.Register(NewWriteFileTool(workDir))
+	r.Register(NewFilesystemTool(workDir))
+	r.Register(NewCopyFileTool(workDir))
+	r.Register(NewApplyPatchTool(workDir))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		tool string
+		args map[string]any
+	}{
+		{"write_file", "write_file", map[string]any{"path": "repo/.git/config", "content": "x"}},
+		{"case insensitive", "write_file", map[string]any{"path": "repo/.GIT/config", "content": "x"}},
+		{"filesystem write", "filesystem", map[string]any{"operation": "write", "path": "repo/.git/hooks/pre-commit", "content": "x"}},
+		{"filesystem delete", "filesystem", map[string]any{"operation": "delete", "path": "repo/.git"}},
+		{"copy_file", "copy_file", map[string]any{"source": "src.txt", "destination": "repo/.git/config"}},
+		{"apply_patch", "apply_patch", map[string]any{"patch": "--- a/repo/.git/config\n+++ b/repo/.git/config\n@@ -1 +1,2 @@\n [core]\n+\tfsmonitor = evil\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if res := r.Execute(ctx, tt.tool, tt.args); res.Success {
+				t.Errorf("%s(%v) succeeded", tt.tool, tt.args)
+			}
+		})
+	}
+	if data, _ := os.ReadFile(config); string(data) != "[core]\n" {
+		t.Errorf(".git/config = %q", data)
+	}
+
+	// 读取照常执行
+	if res := r.Execute(ctx, "filesystem", map[string]any{"operation": "read", "path": "repo/.git/config"}); !res.Success {
+		t.Errorf("read .git/config = %+v", res)
+	}
+}
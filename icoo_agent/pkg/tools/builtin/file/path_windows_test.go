@@ -0,0 +1,47 @@
+package file
+
+import "testing"
+
+func TestWithinWindows(t *testing.T) {
+	tests := []struct {
+		root   string
+		target string
+		want   bool
+	}{
+		{`C:\work`, `C:\work\a.txt`, true},
+		{`C:\work`, `c:\work\a.txt`, true},        // 盘符不区分大小写
+		{`C:\work`, `C:/work/sub/../a.txt`, true}, // 混合分隔符
+		{`C:\work`, `C:\work2\a.txt`, false},      // 同名前缀
+		{`C:\work`, `D:\work\a.txt`, false},       // 不同盘符
+		{`C:\Work`, `C:\work\a.txt`, false},       // 目录名保持大小写
+		{`\\server\share\ws`, `\\server\share\ws\a.txt`, true},
+		{`\\server\share\ws`, `\\SERVER\Share\ws\a.txt`, true}, // UNC 主机和共享名不区分大小写
+		{`\\server\share\ws`, `\\server\share\WS\a.txt`, false},
+		{`\\server\share\ws`, `\\server\other\ws\a.txt`, false},
+	}
+
+	for _, tt := range tests {
+		if got := Within(tt.root, tt.target); got != tt.want {
+			t.Errorf("Within(%q, %q) = %v, want %v", tt.root, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePathWindows(t *testing.T) {
+	root := `\\server\share\ws`
+
+	got, err := ResolvePath(root, "docs/readme.md")
+	if err != nil {
+		t.Fatalf("ResolvePath error: %v", err)
+	}
+	if want := `\\server\share\ws\docs\readme.md`; got != want {
+		t.Errorf("ResolvePath = %q, want %q", got, want)
+	}
+
+	if _, err := ResolvePath(root, `\\server\share\other\a.txt`); err == nil {
+		t.Error("工作目录外的 UNC 路径应被拒绝")
+	}
+	if _, err := ResolvePath(root, `C:\Windows\win.ini`); err == nil {
+		t.Error("其他盘符的路径应被拒绝")
+	}
+}
@@ -4,14 +4,14 @@ import (
 	"context"
 	"fmt"
 	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
 	"os"
-	"path/filepath"
-	"strings"
 )
 
 // ReadFileTool 提供简单的文件读取功能。
 type ReadFileTool struct {
 	WorkDir string
+	Mounts  *vfs.Mounts
 }
 
 // NewReadFileTool 创建一个新的文件读取工具。
@@ -52,14 +52,12 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]any) *tools.
 	}
 
 	// 安全检查
-	fullPath := filepath.Join(t.WorkDir, filepath.Clean(path))
-	absWorkDir, _ := filepath.Abs(t.WorkDir)
-	absFullPath, _ := filepath.Abs(fullPath)
-	if !strings.HasPrefix(absFullPath, absWorkDir) {
-		return &tools.Result{Success: false, Error: fmt.Errorf("路径超出工作目录范围")}
+	fsys, name, err := resolve(ctx, t.WorkDir, t.Mounts, path)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
 	}
 
-	content, err := os.ReadFile(absFullPath)
+	content, err := vfs.ReadFile(ctx, fsys, name)
 	if err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("读取文件失败: %w", err)}
 	}
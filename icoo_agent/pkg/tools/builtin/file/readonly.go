@@ -0,0 +1,100 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
+)
+
+var (
+	_ tools.Mutator = (*FilesystemTool)(nil)
+	_ tools.Mutator = (*WriteFileTool)(nil)
+	_ tools.Mutator = (*CopyFileTool)(nil)
+	_ tools.Mutator = (*ApplyPatchTool)(nil)
+)
+
+// DescribeChange 实现 tools.Mutator，read、list、exists、info 操作不修改工作目录。
+func (t *FilesystemTool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	operation, _ := args["operation"].(string)
+	path, _ := args["path"].(string)
+	switch operation {
+	case "write":
+		content, _ := args["content"].(string)
+		return describeWrite(ctx, t.WorkDir, t.Mounts, path, len(content)), true
+	case "mkdir":
+		return fmt.Sprintf("创建目录 %s", path), true
+	case "delete":
+		if recursive, _ := args["recursive"].(bool); recursive {
+			return fmt.Sprintf("递归删除 %s 及其中的全部内容", path), true
+		}
+		return fmt.Sprintf("删除 %s", path), true
+	default:
+		return "", false
+	}
+}
+
+// DescribeChange 实现 tools.Mutator。
+func (t *WriteFileTool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	path, _ := args["path"].(string)
+	content, _ := args["content"].(string)
+	return describeWrite(ctx, t.WorkDir, t.Mounts, path, len(content)), true
+}
+
+// DescribeChange 实现 tools.Mutator。
+func (t *CopyFileTool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	source, _ := args["source"].(string)
+	destination, _ := args["destination"].(string)
+	action := "新建"
+	if exists(ctx, t.WorkDir, t.Mounts, destination) {
+		action = "覆盖"
+	}
+	return fmt.Sprintf("将 %s 复制到 %s（%s目标文件）", source, destination, action), true
+}
+
+// DescribeChange 实现 tools.Mutator，dry_run 只校验不修改。补丁无效时仍视为修改，执行时报告解析错误。
+func (t *ApplyPatchTool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	if dryRun, _ := args["dry_run"].(bool); dryRun {
+		return "", false
+	}
+	text, _ := args["patch"].(string)
+	patches, err := ParsePatch(text)
+	if err != nil {
+		return "应用补丁（补丁无法解析）", true
+	}
+	changes := make([]string, 0, len(patches))
+	for _, p := range patches {
+		switch {
+		case p.OldPath == "":
+			changes = append(changes, "新建 "+p.NewPath)
+		case p.NewPath == "":
+			changes = append(changes, "删除 "+p.OldPath)
+		case p.OldPath != p.NewPath:
+			changes = append(changes, fmt.Sprintf("将 %s 重命名为 %s", p.OldPath, p.NewPath))
+		default:
+			added, removed := patchStat(p)
+			changes = append(changes, fmt.Sprintf("修改 %s（+%d -%d）", p.NewPath, added, removed))
+		}
+	}
+	return "应用补丁: " + strings.Join(changes, "；"), true
+}
+
+// describeWrite 说明写入文件将做出的修改。
+func describeWrite(ctx context.Context, workDir string, mounts *vfs.Mounts, path string, size int) string {
+	if exists(ctx, workDir, mounts, path) {
+		return fmt.Sprintf("用 %d 字节的新内容覆盖文件 %s", size, path)
+	}
+	return fmt.Sprintf("新建文件 %s（%d 字节）", path, size)
+}
+
+// exists 判断会话工作目录中的路径是否存在，路径越界时视为不存在。
+func exists(ctx context.Context, workDir string, mounts *vfs.Mounts, path string) bool {
+	fsys, name, err := resolve(ctx, workDir, mounts, path)
+	if err != nil {
+		return false
+	}
+	_, err = fsys.Stat(ctx, name)
+	return err == nil
+}
@@ -0,0 +1,55 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/tools"
+)
+
+func TestReadOnlyWorkspace(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "app.toml"), []byte("port = 80"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := tools.NewRegistry()
+	r.Register(NewFilesystemTool(t.TempDir()))
+	r.Register(NewWriteFileTool(t.TempDir()))
+	ctx := tools.WithReadOnly(tools.WithWorkspace(context.Background(), workDir))
+
+	// 写入只返回将做出的修改，文件保持不变
+	res := r.Execute(ctx, "write_file", map[string]any{"path": "app.toml", "content": "port = 8080"})
+	if !res.Success || !strings.Contains(res.Content, "覆盖文件 app.toml") {
+		t.Errorf("write_file result = %+v", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workDir, "app.toml")); string(data) != "port = 80" {
+		t.Errorf("file was modified: %q", data)
+	}
+
+	res = r.Execute(ctx, "filesystem", map[string]any{"operation": "delete", "path": "app.toml", "recursive": true})
+	if !res.Success || !strings.Contains(res.Content, "递归删除 app.toml") {
+		t.Errorf("delete result = %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "app.toml")); err != nil {
+		t.Errorf("file was deleted: %v", err)
+	}
+
+	// 读取照常执行
+	res = r.Execute(ctx, "filesystem", map[string]any{"operation": "read", "path": "app.toml"})
+	if !res.Success || res.Content != "port = 80" {
+		t.Errorf("read result = %+v", res)
+	}
+
+	// 可写的工作目录照常写入
+	res = r.Execute(tools.WithWorkspace(context.Background(), workDir), "write_file", map[string]any{"path": "new.txt", "content": "x"})
+	if !res.Success {
+		t.Fatalf("write_file error = %v", res.Error)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "new.txt")); err != nil {
+		t.Errorf("file was not written in a writable workspace: %v", err)
+	}
+}
@@ -4,14 +4,14 @@ import (
 	"context"
 	"fmt"
 	"icooclaw/pkg/tools"
+	"icooclaw/pkg/vfs"
 	"os"
-	"path/filepath"
-	"strings"
 )
 
 // WriteFileTool 提供简单的文件写入功能。
 type WriteFileTool struct {
 	WorkDir string
+	Mounts  *vfs.Mounts
 }
 
 // NewWriteFileTool 创建一个新的文件写入工具。
@@ -62,19 +62,20 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]any) *tools
 	}
 
 	// 安全检查
-	fullPath := filepath.Join(t.WorkDir, filepath.Clean(path))
-	absWorkDir, _ := filepath.Abs(t.WorkDir)
-	absFullPath, _ := filepath.Abs(fullPath)
-	if !strings.HasPrefix(absFullPath, absWorkDir) {
-		return &tools.Result{Success: false, Error: fmt.Errorf("路径超出工作目录范围")}
+	fsys, name, err := resolve(ctx, t.WorkDir, t.Mounts, path)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	if err := Writable(name); err != nil {
+		return &tools.Result{Success: false, Error: err}
 	}
 
-	// 确保目录存在
-	os.MkdirAll(filepath.Dir(absFullPath), 0755)
-
-	if err := os.WriteFile(absFullPath, []byte(content), 0644); err != nil {
+	// 上级目录由文件系统自动创建
+	before, existed, _ := snapshot(ctx, fsys, name)
+	if err := fsys.WriteFile(ctx, name, []byte(content)); err != nil {
 		return &tools.Result{Success: false, Error: fmt.Errorf("写入文件失败: %w", err)}
 	}
+	recordWrite(ctx, name, before, existed, []byte(content))
 
 	return &tools.Result{
 		Success: true,
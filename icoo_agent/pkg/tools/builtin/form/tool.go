@@ -0,0 +1,100 @@
+// Package form provides a tool that starts a configured slot filling form.
+package form
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/form"
+	"icooclaw/pkg/tools"
+)
+
+// Tool 让智能体为当前会话开始填写表单，之后用户的回复由表单逐项收集。
+type Tool struct {
+	forms *form.Registry
+	store *form.Store
+}
+
+// NewTool 创建 start_form 工具。
+func NewTool(forms *form.Registry, store *form.Store) *Tool {
+	return &Tool{forms: forms, store: store}
+}
+
+// Name 工具名称.
+func (t *Tool) Name() string {
+	return "start_form"
+}
+
+// Description 工具描述，列出可用的表单。
+func (t *Tool) Description() string {
+	sb := strings.Builder{}
+	sb.WriteString("需要向用户收集一组结构化信息时开始填写表单。开始后用户的回复由表单逐项提问和校验，" +
+		"填写完成后表单内容会作为一条新消息交给你处理。已从对话中得知的字段可通过 values 预先填写。可用表单:")
+	for _, f := range t.forms.List() {
+		fields := make([]string, 0, len(f.Fields))
+		for _, field := range f.Fields {
+			fields = append(fields, field.Name)
+		}
+		sb.WriteString(fmt.Sprintf("\n- %s（%s）: %s 字段: %s", f.Name, f.Title, f.Description, strings.Join(fields, ", ")))
+	}
+	return sb.String()
+}
+
+// Parameters 工具参数.
+func (t *Tool) Parameters() map[string]any {
+	names := make([]string, 0, t.forms.Len())
+	for _, f := range t.forms.List() {
+		names = append(names, f.Name)
+	}
+	return map[string]any{
+		"form": map[string]any{
+			"type":        "string",
+			"description": "表单名称",
+			"enum":        names,
+		},
+		"values": map[string]any{
+			"type":        "object",
+			"description": "已知的字段值，键为字段名",
+		},
+	}
+}
+
+// Execute 执行 start_form.
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	channel := tools.GetChannel(ctx)
+	sessionID := tools.GetSessionID(ctx)
+	if channel == "" || sessionID == "" {
+		return tools.ErrorResult("缺少会话上下文，无法开始填写表单")
+	}
+
+	name, _ := args["form"].(string)
+	if name == "" {
+		return tools.ErrorResult("需要提供 form 参数")
+	}
+	prefill := make(map[string]string)
+	if values, ok := args["values"].(map[string]any); ok {
+		for k, v := range values {
+			if v != nil {
+				prefill[k] = fmt.Sprint(v)
+			}
+		}
+	}
+
+	st, prompt, ignored, err := t.forms.Start(name, prefill, time.Now())
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+	if err := t.store.Save(channel, sessionID, st); err != nil {
+		return tools.ErrorResult(fmt.Sprintf("保存表单进度失败: %s", err))
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("表单已开始，之后用户的回复将由表单收集。请在回复中原样转达以下内容，不要自行追问其他字段:\n")
+	sb.WriteString(prompt)
+	for field, reason := range ignored {
+		sb.WriteString(fmt.Sprintf("\n（预填的 %s 无效，已忽略: %s）", field, reason))
+	}
+	return tools.SuccessResult(sb.String())
+}
@@ -0,0 +1,49 @@
+package form
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/form"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+func TestTool_Start(t *testing.T) {
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "form.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	forms, err := form.New([]form.Form{{
+		Name:        "ticket",
+		Title:       "提交工单",
+		Description: "报告故障",
+		Fields:      []form.Field{{Name: "title", Label: "标题"}, {Name: "count", Label: "人数", Type: form.TypeInteger}},
+	}}, form.Options{})
+	if err != nil {
+		t.Fatalf("form.New() error = %v", err)
+	}
+	store := form.NewStore(s.Session())
+	tool := NewTool(forms, store)
+	if !strings.Contains(tool.Description(), "- ticket（提交工单）: 报告故障 字段: title, count") {
+		t.Errorf("Description() = %q", tool.Description())
+	}
+
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+	if r := tool.Execute(ctx, map[string]any{"form": "missing"}); r.Success {
+		t.Error("unknown form should fail")
+	}
+	r := tool.Execute(ctx, map[string]any{"form": "ticket", "values": map[string]any{"title": "打印机坏了", "count": 3.5}})
+	if !r.Success || !strings.Contains(r.Content, "请输入人数") || !strings.Contains(r.Content, "预填的 count 无效") {
+		t.Fatalf("Execute() = %+v", r)
+	}
+
+	st, err := store.Load("websocket", "s1")
+	if err != nil || st == nil || st.Values["title"] != "打印机坏了" || st.Field != 1 {
+		t.Errorf("saved state = %+v, %v", st, err)
+	}
+}
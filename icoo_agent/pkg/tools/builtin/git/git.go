@@ -0,0 +1,218 @@
+// Package git 提供 git_status、git_diff、git_log、git_commit 和 git_branch 工具，
+// 智能体不开放命令执行也能查看和提交工作目录中的修改。
+//
+// 工具直接调用 git 程序（不经过 shell），版本库只在会话工作目录内查找，
+// 并禁用钩子、外部 diff 等会执行其他程序的配置。
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/file"
+)
+
+// defaultMaxOutput 默认返回给模型的最大输出字节数
+const defaultMaxOutput = 64 << 10
+
+// Options git 工具的配置。
+type Options struct {
+	// AuthorName 版本库未配置 user.name 时提交使用的作者名
+	AuthorName string
+	// AuthorEmail 版本库未配置 user.email 时提交使用的邮箱
+	AuthorEmail string
+	// MaxOutput 返回的最大输出字节数，0 表示默认 64 KiB
+	MaxOutput int
+}
+
+// safeConfig 每次调用都附加的配置，禁止版本库配置执行钩子、fsmonitor、分页器和签名程序。
+var safeConfig = []string{
+	"-c", "core.hooksPath=" + os.DevNull,
+	"-c", "core.fsmonitor=false",
+	"-c", "core.pager=cat",
+	"-c", "color.ui=never",
+	"-c", "commit.gpgSign=false",
+}
+
+// inheritedEnv 不从进程继承的环境变量，它们会让 git 操作工作目录以外的版本库。
+var inheritedEnv = []string{
+	"GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE", "GIT_OBJECT_DIRECTORY",
+	"GIT_ALTERNATE_OBJECT_DIRECTORIES", "GIT_COMMON_DIR", "GIT_NAMESPACE",
+	"GIT_CONFIG", "GIT_CONFIG_PARAMETERS", "GIT_CONFIG_COUNT", "GIT_EXTERNAL_DIFF",
+	"GIT_PAGER", "GIT_EDITOR", "GIT_SSH", "GIT_SSH_COMMAND", "GIT_ASKPASS",
+}
+
+// driverPattern 会执行外部命令的过滤器、diff 和合并驱动配置。.gitattributes 只能引用驱动，
+// 命令本身在配置中定义，所以只检查配置。
+const driverPattern = `^(filter\..*\.(clean|smudge|process)|diff\..*\.(textconv|command)|merge\..*\.driver)$`
+
+// refPattern 允许的分支名、提交和范围，如 main、HEAD~2、v1.0..HEAD、origin/dev。
+var refPattern = regexp.MustCompile(`^[\w./@{}~^:+-]+$`)
+
+// validRef 校验引用参数，拒绝以 - 开头（会被当作选项）和含空白、控制字符的值。
+func validRef(ref string) error {
+	if strings.HasPrefix(ref, "-") || !refPattern.MatchString(ref) {
+		return fmt.Errorf("无效的引用: %q", ref)
+	}
+	return nil
+}
+
+// runner 在会话工作目录内执行 git 命令，由各工具共享。
+type runner struct {
+	workDir string
+	opts    Options
+}
+
+// repoParam 版本库目录参数定义
+var repoParam = map[string]any{
+	"type":        "string",
+	"description": "版本库所在目录，相对工作目录（默认为工作目录）",
+}
+
+// dir 返回执行 git 的目录，repo 参数必须位于会话工作目录内。
+func (r *runner) dir(ctx context.Context, args map[string]any) (string, error) {
+	repo, _ := args["repo"].(string)
+	dir, err := file.ResolvePath(tools.GetWorkspace(ctx, r.workDir), repo)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("目录不存在: %s", repo)
+	}
+	return dir, nil
+}
+
+// run 执行 git 命令，返回标准输出。命令失败时错误中包含 git 的错误输出。
+func (r *runner) run(ctx context.Context, dir string, args ...string) (string, error) {
+	out, _, err := r.exec(ctx, dir, "", args...)
+	return out, err
+}
+
+// runInput 执行 git 命令，input 写入标准输入。
+func (r *runner) runInput(ctx context.Context, dir, input string, args ...string) (string, error) {
+	out, _, err := r.exec(ctx, dir, input, args...)
+	return out, err
+}
+
+// exec 执行 git 命令，code 为退出码。版本库向上查找不超过会话工作目录的上级目录，
+// 工作目录本身不是版本库时不会使用其上级的版本库。
+// 版本库配置（含 include 的文件）定义了过滤器等驱动时拒绝执行：能写入文件的智能体可以修改
+// .git/config，git add、status、switch 等命令会运行这些驱动，相当于任意命令执行。
+func (r *runner) exec(ctx context.Context, dir, input string, args ...string) (out string, code int, err error) {
+	root, err := filepath.Abs(tools.GetWorkspace(ctx, r.workDir))
+	if err != nil {
+		return "", -1, fmt.Errorf("获取工作目录绝对路径失败: %w", err)
+	}
+
+	// 退出码 1 表示没有匹配的配置
+	drivers, code, err := r.command(ctx, root, dir, "", "config", "--show-scope", "--includes", "--get-regexp", driverPattern)
+	if err != nil && code != 1 {
+		return "", code, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(drivers), "\n") {
+		scope, entry, _ := strings.Cut(line, "\t")
+		// 用户和系统配置（如 git-lfs）不在工作目录中，智能体无法修改
+		if scope == "" || scope == "global" || scope == "system" {
+			continue
+		}
+		name, _, _ := strings.Cut(entry, " ")
+		return "", -1, fmt.Errorf("版本库配置了会执行外部命令的 %s，git 工具不会在此版本库中执行", name)
+	}
+	return r.command(ctx, root, dir, input, args...)
+}
+
+// command 在 dir 中执行 git 命令，root 为会话工作目录的绝对路径。
+func (r *runner) command(ctx context.Context, root, dir, input string, args ...string) (out string, code int, err error) {
+	cmd := exec.CommandContext(ctx, "git", append(append([]string{"--no-pager"}, safeConfig...), args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(environ(),
+		"GIT_CEILING_DIRECTORIES="+filepath.Dir(root),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_OPTIONAL_LOCKS=0",
+		"LC_ALL=C",
+	)
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", -1, fmt.Errorf("执行 git 失败: %w", err)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		return stdout.String(), exitErr.ExitCode(), fmt.Errorf("git %s 失败: %s", subcommand(args), msg)
+	}
+	return stdout.String(), 0, nil
+}
+
+// subcommand 返回跳过 -c 配置后的子命令名，用于错误信息。
+func subcommand(args []string) string {
+	for len(args) > 2 && args[0] == "-c" {
+		args = args[2:]
+	}
+	return args[0]
+}
+
+// environ 返回去掉 inheritedEnv 的进程环境变量。
+func environ() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		skip := false
+		for _, n := range inheritedEnv {
+			if strings.EqualFold(name, n) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// limit 截断过长的输出。
+func (r *runner) limit(out string) string {
+	size := r.opts.MaxOutput
+	if size <= 0 {
+		size = defaultMaxOutput
+	}
+	out = strings.TrimRight(out, "\n")
+	if len(out) <= size {
+		return out
+	}
+	cut := strings.LastIndexByte(out[:size], '\n')
+	if cut <= 0 {
+		cut = size
+	}
+	return out[:cut] + fmt.Sprintf("\n…（输出超过 %d 字节已截断，可以用 path 参数缩小范围）", size)
+}
+
+// Available 判断 PATH 中是否有 git 程序。
+func Available() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// RegisterTools 注册全部 git 工具。
+func RegisterTools(registry *tools.Registry, workDir string, opts Options) {
+	r := &runner{workDir: workDir, opts: opts}
+	registry.Register(&StatusTool{r})
+	registry.Register(&DiffTool{r})
+	registry.Register(&LogTool{r})
+	registry.Register(&CommitTool{r})
+	registry.Register(&BranchTool{r})
+}
@@ -0,0 +1,194 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/tools"
+)
+
+// newTools 在 workDir 中初始化版本库并返回各工具。
+func newTools(t *testing.T, workDir string) map[string]tools.Tool {
+	t.Helper()
+	if !Available() {
+		t.Skip("git not installed")
+	}
+	registry := tools.NewRegistry()
+	RegisterTools(registry, workDir, Options{AuthorName: "icooclaw", AuthorEmail: "agent@example.com"})
+	got := map[string]tools.Tool{}
+	for _, name := range []string{"git_status", "git_diff", "git_log", "git_commit", "git_branch"} {
+		tool, err := registry.Get(name)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", name, err)
+		}
+		got[name] = tool
+	}
+	return got
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitTools(t *testing.T) {
+	workDir := t.TempDir()
+	g := newTools(t, workDir)
+	ctx := context.Background()
+	r := &runner{workDir: workDir}
+	if _, err := r.run(ctx, workDir, "init", "--quiet", "--initial-branch=main"); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+
+	writeFile(t, filepath.Join(workDir, "a.txt"), "one\n")
+	res := g["git_status"].Execute(ctx, nil)
+	if !res.Success || !strings.Contains(res.Content, "?? a.txt") {
+		t.Fatalf("git_status = %+v", res)
+	}
+
+	// 没有暂存的修改时不提交
+	res = g["git_commit"].Execute(ctx, map[string]any{"message": "empty"})
+	if res.Success {
+		t.Fatalf("git_commit(nothing staged) = %+v", res)
+	}
+	res = g["git_commit"].Execute(ctx, map[string]any{"message": "add a", "paths": []any{"a.txt"}})
+	if !res.Success || !strings.Contains(res.Content, "已提交") || !strings.Contains(res.Content, "a.txt") {
+		t.Fatalf("git_commit = %+v", res)
+	}
+
+	writeFile(t, filepath.Join(workDir, "a.txt"), "one\ntwo\n")
+	res = g["git_diff"].Execute(ctx, map[string]any{"path": "a.txt"})
+	if !res.Success || !strings.Contains(res.Content, "+two") {
+		t.Fatalf("git_diff = %+v", res)
+	}
+	res = g["git_diff"].Execute(ctx, map[string]any{"staged": true})
+	if !res.Success || res.Content != "没有修改" {
+		t.Fatalf("git_diff(staged) = %+v", res)
+	}
+	res = g["git_commit"].Execute(ctx, map[string]any{"message": "add two\n\ndetails", "all": true})
+	if !res.Success {
+		t.Fatalf("git_commit(all) = %+v", res)
+	}
+
+	res = g["git_log"].Execute(ctx, map[string]any{"max_count": float64(1)})
+	if !res.Success || !strings.Contains(res.Content, "icooclaw add two") || strings.Contains(res.Content, "add a") {
+		t.Fatalf("git_log = %+v", res)
+	}
+
+	res = g["git_branch"].Execute(ctx, map[string]any{"action": "create", "name": "feature", "start_point": "HEAD~1"})
+	if !res.Success {
+		t.Fatalf("git_branch(create) = %+v", res)
+	}
+	res = g["git_branch"].Execute(ctx, map[string]any{"action": "switch", "name": "feature"})
+	if !res.Success {
+		t.Fatalf("git_branch(switch) = %+v", res)
+	}
+	res = g["git_branch"].Execute(ctx, map[string]any{})
+	if !res.Success || !strings.Contains(res.Content, "* feature") || !strings.Contains(res.Content, "main") {
+		t.Fatalf("git_branch(list) = %+v", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workDir, "a.txt")); string(data) != "one\n" {
+		t.Errorf("a.txt after switch = %q", data)
+	}
+}
+
+func TestGitTools_Validation(t *testing.T) {
+	workDir := t.TempDir()
+	g := newTools(t, workDir)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		tool string
+		args map[string]any
+	}{
+		{"option as ref", "git_diff", map[string]any{"ref": "--output=/tmp/x"}},
+		{"option as branch", "git_branch", map[string]any{"action": "create", "name": "-D"}},
+		{"path outside workspace", "git_log", map[string]any{"path": "../other"}},
+		{"repo outside workspace", "git_status", map[string]any{"repo": ".."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if res := g[tt.tool].Execute(ctx, tt.args); res.Success {
+				t.Errorf("%s(%v) succeeded", tt.tool, tt.args)
+			}
+		})
+	}
+}
+
+func TestGitTools_CeilingAtWorkspace(t *testing.T) {
+	outer := t.TempDir()
+	workDir := filepath.Join(outer, "workspace")
+	if err := os.Mkdir(workDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	g := newTools(t, workDir)
+	ctx := context.Background()
+	// 上级目录的版本库不能通过工作目录访问
+	r := &runner{workDir: outer}
+	if _, err := r.run(ctx, outer, "init", "--quiet"); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+
+	res := g["git_status"].Execute(ctx, nil)
+	if res.Success || !strings.Contains(res.Error.Error(), "not a git repository") {
+		t.Errorf("git_status = %+v, want not a git repository", res)
+	}
+}
+
+func TestGitTools_RefusesDrivers(t *testing.T) {
+	workDir := t.TempDir()
+	g := newTools(t, workDir)
+	ctx := context.Background()
+	r := &runner{workDir: workDir}
+	if _, err := r.run(ctx, workDir, "init", "--quiet"); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	marker := filepath.Join(t.TempDir(), "pwned")
+	writeFile(t, filepath.Join(workDir, ".gitattributes"), "*.txt filter=evil\n")
+	writeFile(t, filepath.Join(workDir, "a.txt"), "one\n")
+
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"filter", "[filter \"evil\"]\n\tclean = touch " + marker + "\n"},
+		{"included", "[include]\n\tpath = extra.conf\n"},
+		{"textconv", "[diff \"evil\"]\n\ttextconv = touch " + marker + "\n"},
+	}
+	writeFile(t, filepath.Join(workDir, ".git", "extra.conf"), "[filter \"evil\"]\n\tprocess = touch "+marker+"\n")
+	config := filepath.Join(workDir, ".git", "config")
+	base, err := os.ReadFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeFile(t, config, string(base)+tt.config)
+
+			for _, call := range []struct {
+				tool string
+				args map[string]any
+			}{
+				{"git_status", nil},
+				{"git_commit", map[string]any{"message": "add", "all": true}},
+			} {
+				res := g[call.tool].Execute(ctx, call.args)
+				if res.Success || !strings.Contains(res.Error.Error(), "外部命令") {
+					t.Errorf("%s = %+v, want refused", call.tool, res)
+				}
+			}
+			if _, err := os.Stat(marker); err == nil {
+				t.Fatal("driver command was executed")
+			}
+		})
+	}
+}
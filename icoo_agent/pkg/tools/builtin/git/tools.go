@@ -0,0 +1,435 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/file"
+)
+
+const (
+	// logDefaultCount git_log 默认返回的提交数
+	logDefaultCount = 20
+	// logMaxCount git_log max_count 参数的上限
+	logMaxCount = 200
+)
+
+// paths 将工具参数中的路径解析为工作目录内的绝对路径，放在 -- 之后传给 git。
+func (r *runner) paths(ctx context.Context, raw any) ([]string, error) {
+	var list []string
+	switch v := raw.(type) {
+	case nil:
+	case string:
+		if v != "" {
+			list = []string{v}
+		}
+	case []any:
+		for _, p := range v {
+			s, ok := p.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("paths 必须是非空字符串数组")
+			}
+			list = append(list, s)
+		}
+	default:
+		return nil, fmt.Errorf("paths 必须是字符串数组")
+	}
+	workDir := tools.GetWorkspace(ctx, r.workDir)
+	resolved := make([]string, 0, len(list))
+	for _, p := range list {
+		abs, err := file.ResolvePath(workDir, p)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, abs)
+	}
+	return resolved, nil
+}
+
+// StatusTool 查看工作区状态。
+type StatusTool struct {
+	*runner
+}
+
+// Name 返回工具名称。
+func (t *StatusTool) Name() string {
+	return "git_status"
+}
+
+// Description 返回工具描述。
+func (t *StatusTool) Description() string {
+	return "查看 git 版本库的当前分支、与上游的差异，以及已暂存、未暂存和未跟踪的文件（git status --short --branch）。"
+}
+
+// Parameters 返回工具参数。
+func (t *StatusTool) Parameters() map[string]any {
+	return map[string]any{
+		"repo": repoParam,
+	}
+}
+
+// Execute 执行 git status。
+func (t *StatusTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	dir, err := t.dir(ctx, args)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	out, err := t.run(ctx, dir, "status", "--short", "--branch", "--untracked-files=normal")
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	if strings.Count(strings.TrimRight(out, "\n"), "\n") == 0 {
+		out = strings.TrimRight(out, "\n") + "\n工作区干净，没有需要提交的修改"
+	}
+	return tools.SuccessResult(t.limit(out))
+}
+
+// DiffTool 查看修改内容。
+type DiffTool struct {
+	*runner
+}
+
+// Name 返回工具名称。
+func (t *DiffTool) Name() string {
+	return "git_diff"
+}
+
+// Description 返回工具描述。
+func (t *DiffTool) Description() string {
+	return "查看 git 修改内容（统一 diff 格式）。默认显示未暂存的修改；staged 显示已暂存待提交的修改；" +
+		"ref 与指定提交比较或查看提交范围（如 HEAD~1、main..dev）。输出过长时先用 stat 查看改动的文件，再按 path 查看。"
+}
+
+// Parameters 返回工具参数。
+func (t *DiffTool) Parameters() map[string]any {
+	return map[string]any{
+		"repo": repoParam,
+		"staged": map[string]any{
+			"type":        "boolean",
+			"description": "显示已暂存的修改，默认 false",
+		},
+		"ref": map[string]any{
+			"type":        "string",
+			"description": "比较的提交或范围，如 HEAD、HEAD~3、main..dev",
+		},
+		"path": map[string]any{
+			"type":        "string",
+			"description": "只显示该文件或目录的修改",
+		},
+		"stat": map[string]any{
+			"type":        "boolean",
+			"description": "只列出改动的文件和行数，默认 false",
+		},
+	}
+}
+
+// Execute 执行 git diff。
+func (t *DiffTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	dir, err := t.dir(ctx, args)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	cmd := []string{"diff", "--no-ext-diff", "--no-textconv", "--no-color"}
+	if staged, _ := args["staged"].(bool); staged {
+		cmd = append(cmd, "--cached")
+	}
+	if stat, _ := args["stat"].(bool); stat {
+		cmd = append(cmd, "--stat")
+	}
+	if ref, _ := args["ref"].(string); ref != "" {
+		if err := validRef(ref); err != nil {
+			return &tools.Result{Success: false, Error: err}
+		}
+		cmd = append(cmd, ref)
+	}
+	paths, err := t.paths(ctx, args["path"])
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	out, err := t.run(ctx, dir, append(append(cmd, "--"), paths...)...)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	if strings.TrimSpace(out) == "" {
+		return tools.SuccessResult("没有修改")
+	}
+	return tools.SuccessResult(t.limit(out))
+}
+
+// LogTool 查看提交历史。
+type LogTool struct {
+	*runner
+}
+
+// Name 返回工具名称。
+func (t *LogTool) Name() string {
+	return "git_log"
+}
+
+// Description 返回工具描述。
+func (t *LogTool) Description() string {
+	return "查看 git 提交历史，每行一个提交：短哈希 日期 作者 标题。可以按分支、范围或文件筛选。"
+}
+
+// Parameters 返回工具参数。
+func (t *LogTool) Parameters() map[string]any {
+	return map[string]any{
+		"repo": repoParam,
+		"max_count": map[string]any{
+			"type":        "integer",
+			"description": fmt.Sprintf("最多返回的提交数，默认 %d，上限 %d", logDefaultCount, logMaxCount),
+		},
+		"ref": map[string]any{
+			"type":        "string",
+			"description": "分支、提交或范围，默认为当前分支",
+		},
+		"path": map[string]any{
+			"type":        "string",
+			"description": "只显示修改了该文件或目录的提交",
+		},
+	}
+}
+
+// Execute 执行 git log。
+func (t *LogTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	dir, err := t.dir(ctx, args)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	count := logDefaultCount
+	if n, ok := args["max_count"].(float64); ok && n > 0 {
+		count = min(int(n), logMaxCount)
+	}
+	cmd := []string{"log", "--no-color", "--date=short", "--pretty=format:%h %ad %an %s", fmt.Sprintf("--max-count=%d", count)}
+	if ref, _ := args["ref"].(string); ref != "" {
+		if err := validRef(ref); err != nil {
+			return &tools.Result{Success: false, Error: err}
+		}
+		cmd = append(cmd, ref)
+	}
+	paths, err := t.paths(ctx, args["path"])
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	out, err := t.run(ctx, dir, append(append(cmd, "--"), paths...)...)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	if strings.TrimSpace(out) == "" {
+		return tools.SuccessResult("没有提交记录")
+	}
+	return tools.SuccessResult(t.limit(out))
+}
+
+// CommitTool 暂存并提交修改。
+type CommitTool struct {
+	*runner
+}
+
+// Name 返回工具名称。
+func (t *CommitTool) Name() string {
+	return "git_commit"
+}
+
+// Description 返回工具描述。
+func (t *CommitTool) Description() string {
+	return "提交 git 修改。paths 先暂存指定的文件，all 暂存全部修改（包括新文件和删除），" +
+		"都不提供时只提交已暂存的修改。提交前先用 git_status 和 git_diff 确认要提交的内容。不会执行钩子和推送。"
+}
+
+// Parameters 返回工具参数。
+func (t *CommitTool) Parameters() map[string]any {
+	return map[string]any{
+		"repo": repoParam,
+		"message": map[string]any{
+			"type":        "string",
+			"description": "提交说明，首行为简短标题",
+			"required":    true,
+		},
+		"paths": map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "string"},
+			"description": "提交前暂存的文件或目录",
+		},
+		"all": map[string]any{
+			"type":        "boolean",
+			"description": "暂存全部修改后提交，默认 false",
+		},
+	}
+}
+
+// DescribeChange 实现 tools.Mutator。
+func (t *CommitTool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	message, _ := args["message"].(string)
+	title, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	if all, _ := args["all"].(bool); all {
+		return "暂存全部修改并提交: " + title, true
+	}
+	if paths, _ := args["paths"].([]any); len(paths) > 0 {
+		return fmt.Sprintf("暂存 %d 个路径并提交: %s", len(paths), title), true
+	}
+	return "提交已暂存的修改: " + title, true
+}
+
+// Execute 暂存并提交。
+func (t *CommitTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	message, _ := args["message"].(string)
+	if strings.TrimSpace(message) == "" {
+		return &tools.Result{Success: false, Error: fmt.Errorf("需要提供 message 参数")}
+	}
+	dir, err := t.dir(ctx, args)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	paths, err := t.paths(ctx, args["paths"])
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+
+	if len(paths) > 0 {
+		if _, err := t.run(ctx, dir, append([]string{"add", "--"}, paths...)...); err != nil {
+			return &tools.Result{Success: false, Error: err}
+		}
+	}
+	if all, _ := args["all"].(bool); all {
+		if _, err := t.run(ctx, dir, "add", "--all"); err != nil {
+			return &tools.Result{Success: false, Error: err}
+		}
+	}
+	// 退出码 1 表示有已暂存的修改
+	switch _, code, err := t.exec(ctx, dir, "", "diff", "--cached", "--quiet", "--no-ext-diff"); code {
+	case 0:
+		return &tools.Result{Success: false, Error: fmt.Errorf("没有已暂存的修改，可以用 paths 或 all 参数暂存")}
+	case 1:
+	default:
+		return &tools.Result{Success: false, Error: err}
+	}
+
+	cmd := append(t.identity(ctx, dir), "commit", "--no-verify", "--cleanup=strip", "--file=-")
+	if _, err := t.runInput(ctx, dir, message, cmd...); err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	out, err := t.run(ctx, dir, "log", "-1", "--no-color", "--stat", "--pretty=format:已提交 %h %s")
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	return tools.SuccessResult(t.limit(out))
+}
+
+// identity 版本库未配置作者时使用配置中的作者，已配置时不覆盖。
+func (t *CommitTool) identity(ctx context.Context, dir string) []string {
+	var args []string
+	for key, value := range map[string]string{"user.name": t.opts.AuthorName, "user.email": t.opts.AuthorEmail} {
+		if value == "" {
+			continue
+		}
+		if out, err := t.run(ctx, dir, "config", "--get", key); err == nil && strings.TrimSpace(out) != "" {
+			continue
+		}
+		args = append(args, "-c", key+"="+value)
+	}
+	return args
+}
+
+// BranchTool 列出、创建、切换和删除分支。
+type BranchTool struct {
+	*runner
+}
+
+// Name 返回工具名称。
+func (t *BranchTool) Name() string {
+	return "git_branch"
+}
+
+// Description 返回工具描述。
+func (t *BranchTool) Description() string {
+	return "管理 git 分支：list 列出本地分支及其最新提交，create 创建分支（不切换），switch 切换分支，" +
+		"delete 删除已合并的分支。未提交的修改与目标分支冲突时 switch 会失败，不会丢失修改。"
+}
+
+// Parameters 返回工具参数。
+func (t *BranchTool) Parameters() map[string]any {
+	return map[string]any{
+		"repo": repoParam,
+		"action": map[string]any{
+			"type":        "string",
+			"description": "操作，默认 list",
+			"enum":        []string{"list", "create", "switch", "delete"},
+		},
+		"name": map[string]any{
+			"type":        "string",
+			"description": "分支名，create、switch、delete 时必填",
+		},
+		"start_point": map[string]any{
+			"type":        "string",
+			"description": "create 时新分支的起点，默认为当前提交",
+		},
+	}
+}
+
+// DescribeChange 实现 tools.Mutator，list 不修改版本库。
+func (t *BranchTool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	action, _ := args["action"].(string)
+	name, _ := args["name"].(string)
+	switch action {
+	case "create":
+		return "创建分支 " + name, true
+	case "switch":
+		return "切换到分支 " + name, true
+	case "delete":
+		return "删除分支 " + name, true
+	default:
+		return "", false
+	}
+}
+
+// Execute 执行分支操作。
+func (t *BranchTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	dir, err := t.dir(ctx, args)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	action, _ := args["action"].(string)
+	if action == "" || action == "list" {
+		out, err := t.run(ctx, dir, "branch", "--list", "--no-color", "-v")
+		if err != nil {
+			return &tools.Result{Success: false, Error: err}
+		}
+		if strings.TrimSpace(out) == "" {
+			return tools.SuccessResult("还没有分支，首次提交后创建")
+		}
+		return tools.SuccessResult(t.limit(out))
+	}
+
+	name, _ := args["name"].(string)
+	if name == "" {
+		return &tools.Result{Success: false, Error: fmt.Errorf("%s 需要提供 name 参数", action)}
+	}
+	if err := validRef(name); err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	var cmd []string
+	var done string
+	switch action {
+	case "create":
+		cmd, done = []string{"branch", name}, "已创建分支 "+name
+		if start, _ := args["start_point"].(string); start != "" {
+			if err := validRef(start); err != nil {
+				return &tools.Result{Success: false, Error: err}
+			}
+			cmd, done = append(cmd, start), fmt.Sprintf("已从 %s 创建分支 %s", start, name)
+		}
+	case "switch":
+		cmd, done = []string{"switch", name}, "已切换到分支 "+name
+	case "delete":
+		cmd, done = []string{"branch", "-d", name}, "已删除分支 "+name
+	default:
+		return &tools.Result{Success: false, Error: fmt.Errorf("未知操作: %s", action)}
+	}
+	if _, err := t.run(ctx, dir, cmd...); err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	return tools.SuccessResult(done)
+}
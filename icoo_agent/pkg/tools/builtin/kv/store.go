@@ -0,0 +1,134 @@
+// Package kv provides key-value tools for persisting small structured state.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+const (
+	// ScopeUser 按用户隔离的作用域
+	ScopeUser = "user"
+	// ScopeGlobal 全局作用域
+	ScopeGlobal = "global"
+
+	// MaxValueSize 单个值的最大字节数
+	MaxValueSize = 64 * 1024
+)
+
+// Store 基于存储的键值仓库，按调用上下文解析作用域。
+type Store struct {
+	kv       *storage.KVStorage
+	sessions *storage.SessionStorage
+}
+
+// NewStore 创建键值仓库。
+func NewStore(kv *storage.KVStorage, sessions *storage.SessionStorage) *Store {
+	return &Store{kv: kv, sessions: sessions}
+}
+
+// ResolveScope 将 user/global 解析为实际存储的作用域。
+// user 作用域优先按会话所属用户隔离，无法确定用户时退化为按会话隔离。
+func (s *Store) ResolveScope(ctx context.Context, scope string) (string, error) {
+	switch scope {
+	case "", ScopeUser:
+	case ScopeGlobal:
+		return ScopeGlobal, nil
+	default:
+		return "", fmt.Errorf("无效的作用域: %s，可选 user 或 global", scope)
+	}
+
+	channel := tools.GetChannel(ctx)
+	sessionID := tools.GetSessionID(ctx)
+	if channel == "" || sessionID == "" {
+		return "", fmt.Errorf("缺少会话上下文，无法使用 user 作用域")
+	}
+
+	if s.sessions != nil {
+		if sess, err := s.sessions.GetBySessionID(channel, sessionID); err == nil && sess.UserID != "" {
+			return fmt.Sprintf("user:%s:%s", channel, sess.UserID), nil
+		}
+	}
+	return fmt.Sprintf("session:%s:%s", channel, sessionID), nil
+}
+
+// Get 获取值，不存在时返回 false。
+func (s *Store) Get(ctx context.Context, scope, key string) (any, bool, error) {
+	resolved, err := s.ResolveScope(ctx, scope)
+	if err != nil {
+		return nil, false, err
+	}
+
+	item, err := s.kv.Get(resolved, key)
+	if err != nil {
+		if errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(item.Value), &value); err != nil {
+		return nil, false, fmt.Errorf("解析值失败: %w", err)
+	}
+	return value, true, nil
+}
+
+// Set 保存值，值以 JSON 格式存储。
+func (s *Store) Set(ctx context.Context, scope, key string, value any) error {
+	if key == "" {
+		return fmt.Errorf("key 不能为空")
+	}
+
+	resolved, err := s.ResolveScope(ctx, scope)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("序列化值失败: %w", err)
+	}
+	if len(data) > MaxValueSize {
+		return fmt.Errorf("值过大: %d 字节，最大 %d 字节", len(data), MaxValueSize)
+	}
+	return s.kv.Set(resolved, key, string(data))
+}
+
+// List 列出作用域内以 prefix 开头的所有键值。
+func (s *Store) List(ctx context.Context, scope, prefix string) (map[string]any, error) {
+	resolved, err := s.ResolveScope(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.kv.List(resolved, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]any, len(items))
+	for _, item := range items {
+		var value any
+		if err := json.Unmarshal([]byte(item.Value), &value); err != nil {
+			value = item.Value
+		}
+		result[item.Key] = value
+	}
+	return result, nil
+}
+
+// Delete 删除键。
+func (s *Store) Delete(ctx context.Context, scope, key string) error {
+	resolved, err := s.ResolveScope(ctx, scope)
+	if err != nil {
+		return err
+	}
+	return s.kv.Delete(resolved, key)
+}
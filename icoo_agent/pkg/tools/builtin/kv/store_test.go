@@ -0,0 +1,125 @@
+package kv
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"icooclaw/pkg/script"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	if err := s.Session().Touch("websocket", "s1", "u1"); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	return NewStore(s.KV(), s.Session())
+}
+
+func TestStore_ResolveScope(t *testing.T) {
+	store := newTestStore(t)
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		scope     string
+		want      string
+		wantError bool
+	}{
+		{"global", context.Background(), "global", "global", false},
+		{"user", tools.WithToolContext(context.Background(), "websocket", "s1"), "user", "user:websocket:u1", false},
+		{"default user", tools.WithToolContext(context.Background(), "websocket", "s1"), "", "user:websocket:u1", false},
+		{"unknown user", tools.WithToolContext(context.Background(), "websocket", "s2"), "user", "session:websocket:s2", false},
+		{"no context", context.Background(), "user", "", true},
+		{"invalid", context.Background(), "team", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.ResolveScope(tt.ctx, tt.scope)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ResolveScope() error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveScope() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_SetGetList(t *testing.T) {
+	store := newTestStore(t)
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+
+	if err := store.Set(ctx, "", "counter", 1); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set(ctx, "", "counter", 2); err != nil {
+		t.Fatalf("Set() overwrite error = %v", err)
+	}
+	if err := store.Set(ctx, "", "watch.items", []string{"a", "b"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	value, ok, err := store.Get(ctx, "", "counter")
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v, %v", value, ok, err)
+	}
+	if value != float64(2) {
+		t.Errorf("Get() = %v, want 2", value)
+	}
+
+	if _, ok, _ := store.Get(ctx, "global", "counter"); ok {
+		t.Error("global scope should not see user values")
+	}
+
+	items, err := store.List(ctx, "", "watch.")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(items) != 1 {
+		t.Errorf("List() = %v, want 1 item", items)
+	}
+
+	if err := store.Delete(ctx, "", "counter"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, "", "counter"); ok {
+		t.Error("Get() after Delete should not find the key")
+	}
+}
+
+func TestStore_Script(t *testing.T) {
+	store := newTestStore(t)
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+
+	cfg := script.DefaultConfig()
+	cfg.KV = store
+	registry := tools.NewRegistry()
+	script.RegisterScriptTools(registry, cfg, nil)
+	tool, err := registry.Get("script")
+	if err != nil {
+		t.Fatalf("Get(script) error = %v", err)
+	}
+
+	res := tool.Execute(ctx, map[string]any{"code": `kv.set("counter", 3); kv.set("cursor", {page: 2}, "global"); kv.get("counter") + 1`})
+	if !res.Success || res.Content != "4" {
+		t.Fatalf("script(set) = %+v, want 4", res)
+	}
+	// 值在下一次执行中仍然可见，Go 侧也能读到
+	res = tool.Execute(ctx, map[string]any{"code": `kv.get("cursor", "global").page + ":" + kv.get("missing")`})
+	if !res.Success || res.Content != "2:null" {
+		t.Fatalf("script(get) = %+v, want 2:null", res)
+	}
+	if value, ok, _ := store.Get(ctx, "", "counter"); !ok || value != float64(3) {
+		t.Errorf("Get(counter) = %v, %v, want 3", value, ok)
+	}
+}
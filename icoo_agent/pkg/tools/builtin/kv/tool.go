@@ -0,0 +1,243 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icooclaw/pkg/tools"
+)
+
+// scopeParam 作用域参数定义
+var scopeParam = map[string]any{
+	"type":        "string",
+	"description": "作用域: user (当前用户，默认) 或 global (所有会话共享)",
+	"enum":        []string{ScopeUser, ScopeGlobal},
+}
+
+// SetTool 保存键值。
+type SetTool struct {
+	store *Store
+}
+
+// NewSetTool 创建 kv_set 工具。
+func NewSetTool(store *Store) *SetTool {
+	return &SetTool{store: store}
+}
+
+// Name 工具名称.
+func (t *SetTool) Name() string {
+	return "kv_set"
+}
+
+// Description 工具描述.
+func (t *SetTool) Description() string {
+	return "保存一个键值，用于跨会话持久化少量结构化状态（计数器、关注列表、API 游标等）。value 为 JSON 格式。"
+}
+
+// Parameters 工具参数.
+func (t *SetTool) Parameters() map[string]any {
+	return map[string]any{
+		"key": map[string]any{
+			"type":        "string",
+			"description": "键名，建议使用 a.b.c 形式的命名空间",
+			"required":    true,
+		},
+		"value": map[string]any{
+			"type":        "string",
+			"description": "值 (JSON 格式，例如: '1', '\"text\"', '[\"a\",\"b\"]', '{\"cursor\":\"x\"}')",
+			"required":    true,
+		},
+		"scope": scopeParam,
+	}
+}
+
+// Execute 执行 kv_set.
+func (t *SetTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	key, _ := args["key"].(string)
+	if key == "" {
+		return tools.ErrorResult("需要提供 key 参数")
+	}
+
+	value, err := parseValue(args["value"])
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+
+	scope, _ := args["scope"].(string)
+	if err := t.store.Set(ctx, scope, key, value); err != nil {
+		return tools.ErrorResult(fmt.Sprintf("保存失败: %s", err))
+	}
+	return tools.SuccessResult(fmt.Sprintf("已保存: %s", key))
+}
+
+// GetTool 读取键值。
+type GetTool struct {
+	store *Store
+}
+
+// NewGetTool 创建 kv_get 工具。
+func NewGetTool(store *Store) *GetTool {
+	return &GetTool{store: store}
+}
+
+// Name 工具名称.
+func (t *GetTool) Name() string {
+	return "kv_get"
+}
+
+// Description 工具描述.
+func (t *GetTool) Description() string {
+	return "读取通过 kv_set 保存的键值，返回 JSON 格式的值。"
+}
+
+// Parameters 工具参数.
+func (t *GetTool) Parameters() map[string]any {
+	return map[string]any{
+		"key": map[string]any{
+			"type":        "string",
+			"description": "键名",
+			"required":    true,
+		},
+		"scope": scopeParam,
+	}
+}
+
+// Execute 执行 kv_get.
+func (t *GetTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	key, _ := args["key"].(string)
+	if key == "" {
+		return tools.ErrorResult("需要提供 key 参数")
+	}
+
+	scope, _ := args["scope"].(string)
+	value, ok, err := t.store.Get(ctx, scope, key)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("读取失败: %s", err))
+	}
+	if !ok {
+		return tools.SuccessResult(fmt.Sprintf("键不存在: %s", key))
+	}
+
+	data, _ := json.Marshal(value)
+	return tools.SuccessResult(string(data))
+}
+
+// ListTool 列出键值。
+type ListTool struct {
+	store *Store
+}
+
+// NewListTool 创建 kv_list 工具。
+func NewListTool(store *Store) *ListTool {
+	return &ListTool{store: store}
+}
+
+// Name 工具名称.
+func (t *ListTool) Name() string {
+	return "kv_list"
+}
+
+// Description 工具描述.
+func (t *ListTool) Description() string {
+	return "列出作用域内的键值，可按键名前缀过滤。"
+}
+
+// Parameters 工具参数.
+func (t *ListTool) Parameters() map[string]any {
+	return map[string]any{
+		"prefix": map[string]any{
+			"type":        "string",
+			"description": "键名前缀，为空时列出全部",
+		},
+		"scope": scopeParam,
+	}
+}
+
+// Execute 执行 kv_list.
+func (t *ListTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	prefix, _ := args["prefix"].(string)
+	scope, _ := args["scope"].(string)
+
+	items, err := t.store.List(ctx, scope, prefix)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("列出失败: %s", err))
+	}
+	if len(items) == 0 {
+		return tools.SuccessResult("暂无数据")
+	}
+
+	data, _ := json.MarshalIndent(items, "", "  ")
+	return tools.SuccessResult(string(data))
+}
+
+// DeleteTool 删除键值。
+type DeleteTool struct {
+	store *Store
+}
+
+// NewDeleteTool 创建 kv_delete 工具。
+func NewDeleteTool(store *Store) *DeleteTool {
+	return &DeleteTool{store: store}
+}
+
+// Name 工具名称.
+func (t *DeleteTool) Name() string {
+	return "kv_delete"
+}
+
+// Description 工具描述.
+func (t *DeleteTool) Description() string {
+	return "删除通过 kv_set 保存的键值。"
+}
+
+// Parameters 工具参数.
+func (t *DeleteTool) Parameters() map[string]any {
+	return map[string]any{
+		"key": map[string]any{
+			"type":        "string",
+			"description": "键名",
+			"required":    true,
+		},
+		"scope": scopeParam,
+	}
+}
+
+// Execute 执行 kv_delete.
+func (t *DeleteTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	key, _ := args["key"].(string)
+	if key == "" {
+		return tools.ErrorResult("需要提供 key 参数")
+	}
+
+	scope, _ := args["scope"].(string)
+	if err := t.store.Delete(ctx, scope, key); err != nil {
+		return tools.ErrorResult(fmt.Sprintf("删除失败: %s", err))
+	}
+	return tools.SuccessResult(fmt.Sprintf("已删除: %s", key))
+}
+
+// RegisterTools 注册全部键值工具。
+func RegisterTools(registry *tools.Registry, store *Store) {
+	registry.Register(NewSetTool(store))
+	registry.Register(NewGetTool(store))
+	registry.Register(NewListTool(store))
+	registry.Register(NewDeleteTool(store))
+}
+
+// parseValue 解析 JSON 格式的值，非法 JSON 按普通字符串处理。
+func parseValue(raw any) (any, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, fmt.Errorf("需要提供 value 参数")
+	case string:
+		var value any
+		if err := json.Unmarshal([]byte(v), &value); err != nil {
+			return v, nil
+		}
+		return value, nil
+	default:
+		// 模型可能直接传入结构化的值
+		return v, nil
+	}
+}
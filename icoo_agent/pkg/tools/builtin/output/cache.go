@@ -0,0 +1,98 @@
+// Package output caches the full output of tool calls whose results were condensed
+// before entering the model context, and provides the tool_output tool to read them back.
+package output
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Entry 缓存的一次完整工具输出。
+type Entry struct {
+	ID        string
+	Channel   string
+	SessionID string
+	Tool      string
+	Content   string
+	CreatedAt time.Time
+}
+
+// Cache 进程内的完整工具输出缓存，按总字节数和有效期淘汰最早的输出。
+type Cache struct {
+	mu       sync.Mutex
+	maxBytes int
+	ttl      time.Duration
+	entries  map[string]*Entry
+	order    []string // 按写入顺序，用于淘汰
+	size     int
+	now      func() time.Time
+}
+
+// NewCache 创建输出缓存，maxBytes 为缓存总大小上限，ttl 为每条输出的有效期，0 表示不过期。
+func NewCache(maxBytes int, ttl time.Duration) *Cache {
+	return &Cache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		entries:  make(map[string]*Entry),
+		now:      time.Now,
+	}
+}
+
+// Put 缓存一次完整输出并返回其 ID。最新写入的输出总是保留，即使单条超过大小上限。
+func (c *Cache) Put(channel, sessionID, tool, content string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := newID()
+	c.entries[id] = &Entry{
+		ID:        id,
+		Channel:   channel,
+		SessionID: sessionID,
+		Tool:      tool,
+		Content:   content,
+		CreatedAt: c.now(),
+	}
+	c.order = append(c.order, id)
+	c.size += len(content)
+	c.evict()
+	return id
+}
+
+// Get 读取会话的缓存输出，不存在、已过期或属于其他会话时返回 false。
+func (c *Cache) Get(channel, sessionID, id string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evict()
+	e, ok := c.entries[id]
+	if !ok || e.Channel != channel || e.SessionID != sessionID {
+		return nil, false
+	}
+	return e, true
+}
+
+// evict 淘汰过期输出，并从最早的开始淘汰直到不超过大小上限。
+func (c *Cache) evict() {
+	now := c.now()
+	drop := 0
+	for drop < len(c.order)-1 {
+		e := c.entries[c.order[drop]]
+		expired := c.ttl > 0 && now.Sub(e.CreatedAt) > c.ttl
+		if !expired && (c.maxBytes <= 0 || c.size <= c.maxBytes) {
+			break
+		}
+		c.size -= len(e.Content)
+		delete(c.entries, e.ID)
+		drop++
+	}
+	c.order = c.order[drop:]
+}
+
+// newID 生成输出 ID。
+func newID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "out-" + hex.EncodeToString(b)
+}
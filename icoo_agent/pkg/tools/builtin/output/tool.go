@@ -0,0 +1,139 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/tools"
+)
+
+// ToolName 读取缓存输出的工具名称，它的结果不会再被压缩。
+const ToolName = "tool_output"
+
+const (
+	// defaultLimit 每次默认读取的字符数
+	defaultLimit = 4000
+	// maxLimit 每次最多读取的字符数
+	maxLimit = 20000
+	// maxMatches 搜索时最多返回的匹配行数
+	maxMatches = 50
+)
+
+// Tool 分段读取或搜索被压缩的工具结果的完整输出。
+type Tool struct {
+	cache *Cache
+}
+
+// NewTool 创建 tool_output 工具。
+func NewTool(cache *Cache) *Tool {
+	return &Tool{cache: cache}
+}
+
+// Name 工具名称.
+func (t *Tool) Name() string {
+	return ToolName
+}
+
+// Description 工具描述.
+func (t *Tool) Description() string {
+	return "读取过长而被压缩的工具结果的完整输出。压缩后的结果会注明输出 ID（out-开头），" +
+		"可按字符位置分段读取，或按关键词搜索匹配的行。"
+}
+
+// Parameters 工具参数.
+func (t *Tool) Parameters() map[string]any {
+	return map[string]any{
+		"id": map[string]any{
+			"type":        "string",
+			"description": "输出 ID，如 out-1a2b3c4d5e6f",
+			"required":    true,
+		},
+		"offset": map[string]any{
+			"type":        "integer",
+			"description": "起始字符位置，默认 0",
+		},
+		"limit": map[string]any{
+			"type":        "integer",
+			"description": fmt.Sprintf("读取的字符数，默认 %d，最多 %d", defaultLimit, maxLimit),
+		},
+		"query": map[string]any{
+			"type":        "string",
+			"description": "搜索关键词（不区分大小写），设置后返回包含关键词的行及行号，忽略 offset 和 limit",
+		},
+	}
+}
+
+// Execute 执行 tool_output.
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	id, _ := args["id"].(string)
+	if id == "" {
+		return tools.ErrorResult("需要提供 id 参数")
+	}
+	e, ok := t.cache.Get(tools.GetChannel(ctx), tools.GetSessionID(ctx), strings.TrimSpace(id))
+	if !ok {
+		return tools.ErrorResult(fmt.Sprintf("输出 %s 不存在或已过期", id))
+	}
+
+	if query, _ := args["query"].(string); query != "" {
+		return tools.SuccessResult(search(e, query))
+	}
+
+	offset, limit := 0, defaultLimit
+	if v, ok := args["offset"].(float64); ok && v > 0 {
+		offset = int(v)
+	}
+	if v, ok := args["limit"].(float64); ok && v > 0 {
+		limit = min(int(v), maxLimit)
+	}
+	return tools.SuccessResult(page(e, offset, limit))
+}
+
+// page 按字符位置读取一段输出。
+func page(e *Entry, offset, limit int) string {
+	runes := []rune(e.Content)
+	if offset >= len(runes) {
+		return fmt.Sprintf("%s 的输出共 %d 字符，offset 超出范围", e.Tool, len(runes))
+	}
+	end := min(offset+limit, len(runes))
+	header := fmt.Sprintf("[%s 的输出第 %d-%d 字符，共 %d 字符", e.Tool, offset, end, len(runes))
+	if end < len(runes) {
+		header += fmt.Sprintf("，继续读取请使用 offset=%d", end)
+	}
+	return header + "]\n" + string(runes[offset:end])
+}
+
+// search 返回包含关键词的行及行号。
+func search(e *Entry, query string) string {
+	q := strings.ToLower(query)
+	sb := strings.Builder{}
+	matches := 0
+	for i, line := range strings.Split(e.Content, "\n") {
+		if !strings.Contains(strings.ToLower(line), q) {
+			continue
+		}
+		matches++
+		if matches > maxMatches {
+			continue
+		}
+		fmt.Fprintf(&sb, "%d: %s\n", i+1, truncateLine(line))
+	}
+	if matches == 0 {
+		return fmt.Sprintf("%s 的输出中没有包含 %q 的行", e.Tool, query)
+	}
+	header := fmt.Sprintf("[%s 的输出中有 %d 行包含 %q", e.Tool, matches, query)
+	if matches > maxMatches {
+		header += fmt.Sprintf("，只显示前 %d 行", maxMatches)
+	}
+	return header + "]\n" + strings.TrimRight(sb.String(), "\n")
+}
+
+// truncateLine 截断过长的单行，如压缩后的 HTML。
+func truncateLine(line string) string {
+	const maxLine = 500
+	runes := []rune(line)
+	if len(runes) <= maxLine {
+		return line
+	}
+	return string(runes[:maxLine]) + "…"
+}
@@ -0,0 +1,62 @@
+package output
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/tools"
+)
+
+func TestCache_Evict(t *testing.T) {
+	now := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	c := NewCache(10, time.Hour)
+	c.now = func() time.Time { return now }
+
+	first := c.Put("telegram", "s1", "http_request", "123456")
+	second := c.Put("telegram", "s1", "http_request", "abcdef")
+	if _, ok := c.Get("telegram", "s1", first); ok {
+		t.Error("oldest output should be evicted over the size limit")
+	}
+	if _, ok := c.Get("telegram", "s2", second); ok {
+		t.Error("output should not be visible to other sessions")
+	}
+	if e, ok := c.Get("telegram", "s1", second); !ok || e.Content != "abcdef" {
+		t.Errorf("Get() = %+v, %v", e, ok)
+	}
+
+	// 最新的输出即使超过上限也保留，过期后淘汰
+	big := c.Put("telegram", "s1", "shell_command", strings.Repeat("x", 20))
+	if _, ok := c.Get("telegram", "s1", big); !ok {
+		t.Error("latest output should be kept")
+	}
+	now = now.Add(2 * time.Hour)
+	third := c.Put("telegram", "s1", "shell_command", "new")
+	if _, ok := c.Get("telegram", "s1", big); ok {
+		t.Error("expired output should be evicted")
+	}
+	if _, ok := c.Get("telegram", "s1", third); !ok {
+		t.Error("fresh output should be kept")
+	}
+}
+
+func TestTool_Execute(t *testing.T) {
+	c := NewCache(1<<20, 0)
+	id := c.Put("websocket", "s1", "http_request", "第一行\nerror: 超时\n第三行\nERROR: 拒绝连接")
+	tool := NewTool(c)
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+
+	r := tool.Execute(ctx, map[string]any{"id": id, "offset": float64(4), "limit": float64(9)})
+	if !r.Success || !strings.HasSuffix(r.Content, "error: 超时") || !strings.Contains(r.Content, "offset=13") {
+		t.Errorf("page = %q", r.Content)
+	}
+	r = tool.Execute(ctx, map[string]any{"id": id, "query": "error"})
+	if !strings.Contains(r.Content, "2: error: 超时") || !strings.Contains(r.Content, "4: ERROR: 拒绝连接") {
+		t.Errorf("search = %q", r.Content)
+	}
+	other := tools.WithToolContext(context.Background(), "websocket", "s2")
+	if r := tool.Execute(other, map[string]any{"id": id}); r.Success {
+		t.Error("other sessions should not read the output")
+	}
+}
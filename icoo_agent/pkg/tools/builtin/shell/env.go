@@ -0,0 +1,97 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// EnvConfig 命令执行环境配置，决定守护进程的哪些环境变量对命令可见。
+//
+// 变量名匹配使用 path.Match 通配符且不区分大小写（Windows 环境变量名本身不区分大小写）。
+type EnvConfig struct {
+	// Allow 继承的环境变量白名单，为空表示继承全部
+	Allow []string
+	// Deny 不继承的环境变量，优先于 Allow
+	Deny []string
+	// Extra 额外注入的环境变量，覆盖继承的同名变量
+	Extra map[string]string
+	// Path 非空时用这些目录替换 PATH，命令只能找到其中的可执行文件
+	Path []string
+}
+
+// Validate 检查通配符是否合法。
+func (c EnvConfig) Validate() error {
+	for _, pattern := range slices.Concat(c.Allow, c.Deny) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("环境变量模式 %q 无效: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Build 基于 base（通常为 os.Environ()）生成命令的环境变量，overrides 为单次调用传入的 KEY=value。
+// 设置了 PATH 沙箱时，overrides 中的 PATH 会被忽略。
+func (c EnvConfig) Build(base, overrides []string) []string {
+	env := make(map[string]string) // 规范化变量名 -> KEY=value
+	set := func(key, value string) {
+		env[envKey(key)] = key + "=" + value
+	}
+
+	for _, kv := range base {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" || !c.inherits(key) {
+			continue
+		}
+		set(key, value)
+	}
+	for key, value := range c.Extra {
+		set(key, value)
+	}
+	if len(c.Path) > 0 {
+		set("PATH", strings.Join(c.Path, string(os.PathListSeparator)))
+	}
+	for _, kv := range overrides {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" || (len(c.Path) > 0 && envKey(key) == envKey("PATH")) {
+			continue
+		}
+		set(key, value)
+	}
+
+	result := make([]string, 0, len(env))
+	for _, kv := range env {
+		result = append(result, kv)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// inherits 变量是否从守护进程环境继承。
+func (c EnvConfig) inherits(key string) bool {
+	if matchEnv(c.Deny, key) {
+		return false
+	}
+	return len(c.Allow) == 0 || matchEnv(c.Allow, key)
+}
+
+// envKey 规范化变量名，Windows 下变量名不区分大小写。
+func envKey(key string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(key)
+	}
+	return key
+}
+
+// matchEnv 变量名是否匹配任一模式。
+func matchEnv(patterns []string, key string) bool {
+	key = strings.ToUpper(key)
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, err := path.Match(strings.ToUpper(pattern), key)
+		return ok && err == nil
+	})
+}
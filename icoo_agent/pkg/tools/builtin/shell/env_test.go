@@ -0,0 +1,81 @@
+package shell
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEnvConfig_Build(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 下 PATH 分隔符与变量名大小写规则不同")
+	}
+
+	base := []string{"HOME=/home/a", "LANG=C", "OPENAI_API_KEY=sk-x", "ICOOCLAW_MODE=debug", "PATH=/usr/bin"}
+
+	tests := []struct {
+		name      string
+		cfg       EnvConfig
+		overrides []string
+		want      []string
+	}{
+		{
+			name: "零值继承全部",
+			want: base,
+		},
+		{
+			name: "黑名单不区分大小写",
+			cfg:  EnvConfig{Deny: []string{"icooclaw_*", "*_API_KEY"}},
+			want: []string{"HOME=/home/a", "LANG=C", "PATH=/usr/bin"},
+		},
+		{
+			name: "白名单与额外变量",
+			cfg:  EnvConfig{Allow: []string{"HOME", "PATH"}, Extra: map[string]string{"GOFLAGS": "-mod=mod"}},
+			want: []string{"GOFLAGS=-mod=mod", "HOME=/home/a", "PATH=/usr/bin"},
+		},
+		{
+			name:      "PATH 沙箱不可被单次调用覆盖",
+			cfg:       EnvConfig{Allow: []string{"LANG"}, Path: []string{"/opt/bin", "/bin"}},
+			overrides: []string{"PATH=/tmp", "LANG=zh_CN.UTF-8"},
+			want:      []string{"LANG=zh_CN.UTF-8", "PATH=/opt/bin:/bin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.Build(base, tt.overrides)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("Build() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestEnvConfig_Validate(t *testing.T) {
+	if err := (EnvConfig{Deny: []string{"[bad"}}).Validate(); err == nil {
+		t.Error("非法通配符应返回错误")
+	}
+}
+
+func TestShellCommandTool_EnvIsolation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 sh 验证")
+	}
+	t.Setenv("ICOOCLAW_TEST_SECRET", "hidden")
+
+	tool := NewShellCommandTool(WithEnv(EnvConfig{Deny: []string{"*_SECRET"}}))
+	result := tool.Execute(context.Background(), map[string]any{
+		"command": "echo \"[$ICOOCLAW_TEST_SECRET][$EXTRA]\"",
+		"env":     []any{"EXTRA=1"},
+	})
+	if !result.Success {
+		t.Fatalf("执行失败: %v", result.Error)
+	}
+	if !strings.Contains(result.Content, "[][1]") {
+		t.Errorf("输出应不含被拒绝的变量: %s", result.Content)
+	}
+}
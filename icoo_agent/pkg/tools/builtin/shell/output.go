@@ -0,0 +1,106 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"unicode/utf8"
+
+	"icooclaw/pkg/tools"
+)
+
+// defaultTailSize 工具结果中保留的输出尾部字节数
+const defaultTailSize = 10 * 1024
+
+// outputCollector 收集命令输出：只保留最后 limit 字节作为工具结果，同时把增量转发给实时输出回调。
+// stdout 和 stderr 共用一个尾部缓冲，按读取到的先后顺序写入。
+type outputCollector struct {
+	mu      sync.Mutex
+	fn      tools.OutputFunc
+	limit   int
+	tail    []byte
+	dropped int64
+}
+
+// newOutputCollector 创建输出收集器，fn 可以为 nil。
+func newOutputCollector(limit int, fn tools.OutputFunc) *outputCollector {
+	if limit <= 0 {
+		limit = defaultTailSize
+	}
+	return &outputCollector{fn: fn, limit: limit}
+}
+
+// writer 返回指定输出流的写入器。
+func (c *outputCollector) writer(stream string) *streamWriter {
+	return &streamWriter{c: c, stream: stream}
+}
+
+// append 写入尾部缓冲并转发完整的 UTF-8 片段。
+func (c *outputCollector) append(stream string, p []byte, forward []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tail = append(c.tail, p...)
+	if over := len(c.tail) - c.limit; over > 0 {
+		c.dropped += int64(over)
+		c.tail = append(c.tail[:0], c.tail[over:]...)
+	}
+	if c.fn != nil && len(forward) > 0 {
+		c.fn(stream, string(forward))
+	}
+}
+
+// output 返回保留的输出尾部及是否发生截断。
+func (c *outputCollector) output() (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dropped == 0 {
+		return string(c.tail), false
+	}
+	// 尾部缓冲可能从一个多字节字符中间开始
+	tail := c.tail
+	for len(tail) > 0 && !utf8.RuneStart(tail[0]) {
+		tail = tail[1:]
+	}
+	return fmt.Sprintf("... (前 %d 字节输出已省略)\n%s", c.dropped+int64(len(c.tail)-len(tail)), tail), true
+}
+
+// streamWriter 单个输出流的写入器，转发时不拆分多字节字符。
+type streamWriter struct {
+	c       *outputCollector
+	stream  string
+	pending []byte
+}
+
+var _ io.Writer = (*streamWriter)(nil)
+
+// Write 实现 io.Writer。
+func (w *streamWriter) Write(p []byte) (int, error) {
+	buf := append(w.pending, p...)
+	n := completeUTF8(buf)
+	w.pending = append([]byte(nil), buf[n:]...)
+	w.c.append(w.stream, p, buf[:n])
+	return len(p), nil
+}
+
+// flush 转发剩余的不完整字节。
+func (w *streamWriter) flush() {
+	if len(w.pending) > 0 {
+		w.c.append(w.stream, nil, w.pending)
+		w.pending = nil
+	}
+}
+
+// completeUTF8 返回 b 中以完整 UTF-8 字符结尾的前缀长度。
+func completeUTF8(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:]) {
+				return len(b)
+			}
+			return i
+		}
+	}
+	return len(b)
+}
@@ -0,0 +1,88 @@
+package shell
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"icooclaw/pkg/tools"
+)
+
+func TestOutputCollector_Tail(t *testing.T) {
+	c := newOutputCollector(8, nil)
+	w := c.writer(tools.StreamStdout)
+	w.Write([]byte("0123456789"))
+	w.Write([]byte("ab"))
+
+	out, truncated := c.output()
+	if !truncated {
+		t.Fatal("超出尾部大小应标记截断")
+	}
+	if !strings.HasSuffix(out, "456789ab") || !strings.Contains(out, "前 4 字节") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestOutputCollector_SplitRune(t *testing.T) {
+	var chunks []string
+	c := newOutputCollector(0, func(stream, chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	w := c.writer(tools.StreamStdout)
+
+	data := []byte("构建")
+	w.Write(data[:4]) // “构”加上“建”的第一个字节
+	w.Write(data[4:])
+	w.flush()
+
+	if len(chunks) != 2 || chunks[0] != "构" || chunks[1] != "建" {
+		t.Errorf("chunks = %q, 多字节字符不应被拆分", chunks)
+	}
+	if out, _ := c.output(); out != "构建" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestShellCommandTool_StreamOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 sh 验证")
+	}
+
+	var (
+		mu      sync.Mutex
+		streams = map[string]string{}
+	)
+	ctx := tools.WithOutput(context.Background(), func(stream, chunk string) {
+		mu.Lock()
+		defer mu.Unlock()
+		streams[stream] += chunk
+	})
+
+	// stdout 和 stderr 由不同的管道读取，等待片刻再写 stderr，保证它落在尾部缓冲最后
+	tool := NewShellCommandTool(WithTailSize(16))
+	result := tool.Execute(ctx, map[string]any{
+		"command": "for i in 1 2 3 4 5 6 7 8 9; do echo line-$i; done; sleep 0.2; echo oops >&2",
+	})
+	if !result.Success {
+		t.Fatalf("执行失败: %v", result.Error)
+	}
+
+	if !strings.Contains(streams[tools.StreamStdout], "line-1\n") || !strings.Contains(streams[tools.StreamStdout], "line-9\n") {
+		t.Errorf("stdout 应完整实时下发: %q", streams[tools.StreamStdout])
+	}
+	if streams[tools.StreamStderr] != "oops\n" {
+		t.Errorf("stderr = %q", streams[tools.StreamStderr])
+	}
+
+	var res map[string]any
+	if err := json.Unmarshal([]byte(result.Content), &res); err != nil {
+		t.Fatalf("结果不是 JSON: %v", err)
+	}
+	output, _ := res["output"].(string)
+	if strings.Contains(output, "line-1\n") || !strings.HasSuffix(output, "oops\n") || res["truncated"] != true {
+		t.Errorf("结果应只保留输出尾部: %q", output)
+	}
+}
@@ -0,0 +1,283 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// 违规类别。
+const (
+	// ViolationDenied 命中禁止规则
+	ViolationDenied = "denied"
+	// ViolationNotAllowed 设置了允许列表但命令不在其中
+	ViolationNotAllowed = "not_allowed"
+	// ViolationPackageManager 未启用包管理器时调用了安装/卸载类命令
+	ViolationPackageManager = "package_manager"
+)
+
+// regexPrefix 规则模式以此开头时按正则匹配，否则按命令前缀匹配。
+const regexPrefix = "re:"
+
+// Rule 命令匹配规则。
+type Rule struct {
+	Name    string // 规则名称，出现在违规信息中
+	Pattern string // 前缀或 re: 开头的正则
+	Reason  string // 拒绝原因，供智能体向用户解释
+	re      *regexp.Regexp
+}
+
+// newRule 编译规则。
+func newRule(name, pattern, reason string) (Rule, error) {
+	r := Rule{Name: name, Pattern: pattern, Reason: reason}
+	if expr, ok := strings.CutPrefix(pattern, regexPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return Rule{}, fmt.Errorf("命令规则 %q 无效: %w", pattern, err)
+		}
+		r.re = re
+	}
+	return r, nil
+}
+
+// mustRule 编译内置规则。
+func mustRule(name, pattern, reason string) Rule {
+	r, err := newRule(name, pattern, reason)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Match 判断命令是否匹配规则：正则匹配整条命令，前缀匹配以 ; && || | 分隔的任一段。
+func (r Rule) Match(command string) bool {
+	if r.re != nil {
+		return r.re.MatchString(command)
+	}
+	for _, segment := range commandSegments(command) {
+		if strings.HasPrefix(segment, r.Pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// redirection 2>&1、&> 等重定向，拆分命令前去掉，避免被当作后台运算符
+	redirection = regexp.MustCompile(`[0-9]*[<>]&[0-9-]*|&>>?`)
+	// segmentSeparator 分隔多条命令的运算符
+	segmentSeparator = regexp.MustCompile(`&&|\|\||[;|&\n]`)
+	// fdDuplication 2>&1、>&2 等文件描述符复制，不写入文件
+	fdDuplication = regexp.MustCompile(`[0-9]*[<>]&[0-9-]+`)
+)
+
+// unsafeForAllowList 返回命令中允许列表无法约束的结构：命令替换、进程替换和输出重定向。
+// 它们在允许的命令内执行其他命令或写入任意文件，前缀匹配看不到。
+func unsafeForAllowList(command string) string {
+	for _, s := range []string{"`", "$(", "<(", ">("} {
+		if strings.Contains(command, s) {
+			return s
+		}
+	}
+	if strings.Contains(fdDuplication.ReplaceAllString(command, ""), ">") {
+		return ">"
+	}
+	return ""
+}
+
+// hasCommandPrefix 判断命令段是否以 prefix 开头，且 prefix 在单词边界结束：
+// ls 匹配 ls 和 ls -la，不匹配 lsof；以空格或 / 等非单词字符结尾的前缀照常匹配。
+func hasCommandPrefix(segment, prefix string) bool {
+	rest, ok := strings.CutPrefix(segment, prefix)
+	if !ok || rest == "" || prefix == "" {
+		return ok
+	}
+	last := prefix[len(prefix)-1]
+	if !isWordByte(last) {
+		return true
+	}
+	return !isWordByte(rest[0]) && rest[0] != '.'
+}
+
+// isWordByte 判断字节是否可以出现在程序名中。
+func isWordByte(b byte) bool {
+	return b == '_' || b == '-' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
+// commandSegments 拆分复合命令。
+func commandSegments(command string) []string {
+	var segments []string
+	for _, s := range segmentSeparator.Split(redirection.ReplaceAllString(command, " "), -1) {
+		if s = strings.TrimSpace(s); s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// Programs returns the program run by each part of a compound command, with
+// leading VAR=value assignments and sudo/env/command prefixes skipped and the
+// directory stripped, e.g. "sudo /bin/rm -rf x | FOO=1 dd" gives rm and dd.
+func Programs(command string) []string {
+	var programs []string
+	for _, segment := range commandSegments(command) {
+		fields := strings.Fields(segment)
+		for len(fields) > 0 && (strings.Contains(fields[0], "=") || slices.Contains([]string{"sudo", "env", "command", "exec", "nohup"}, fields[0])) {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		program := strings.Trim(fields[0], `"'(`)
+		if i := strings.LastIndexAny(program, `/\`); i >= 0 {
+			program = program[i+1:]
+		}
+		if program != "" {
+			programs = append(programs, program)
+		}
+	}
+	return programs
+}
+
+// builtinDenyRules 始终生效的禁止规则
+var builtinDenyRules = []Rule{
+	mustRule("rm_root", `re:\brm\s+(?:-{1,2}[\w-]+\s+)*(?:/|/\*|~|~/|\$HOME/?)(?:\s|$|;|&|\|)`, "递归删除根目录或主目录会造成不可恢复的数据丢失"),
+	mustRule("pipe_to_shell", `re:\b(?:curl|wget|iwr|irm|Invoke-WebRequest|Invoke-RestMethod)\b[^|]*\|\s*(?:sudo\s+)?(?:(?:ba|z|da|k)?sh|iex|Invoke-Expression|python3?|perl|ruby)\b`, "将网络下载的内容直接交给解释器执行，无法审查脚本内容"),
+	mustRule("mkfs", `re:\bmkfs(?:\.\w+)?\b`, "格式化文件系统会清除磁盘数据"),
+	mustRule("format_drive", `re:(?i)\bformat(?:\.com)?\s+[a-z]:`, "格式化磁盘会清除磁盘数据"),
+	mustRule("dd_device", `re:\bdd\b.*\bof=/dev/`, "直接写入块设备会破坏磁盘数据"),
+	mustRule("fork_bomb", `re::\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`, "fork 炸弹会耗尽系统资源"),
+	mustRule("power", `re:(?:^|[;&|]\s*|\bsudo\s+)(?:shutdown|reboot|halt|poweroff)\b`, "关机或重启会中断守护进程"),
+}
+
+// packageManagerRules 安装、卸载或升级软件包的命令
+var packageManagerRules = []Rule{
+	mustRule("system_package", `re:(?:^|[;&|]\s*|\bsudo\s+)(?:apt(?:-get)?|yum|dnf|apk|pacman|zypper|brew|port|choco|winget|scoop)\s+(?:install|remove|uninstall|purge|upgrade|update|add|del|-S\w*|-R\w*)\b`, "安装或卸载系统软件包会修改宿主机环境"),
+	mustRule("language_package", `re:(?:^|[;&|]\s*|\bsudo\s+)(?:npm|pnpm|yarn|bun)\s+(?:install|i|add|remove|rm|uninstall|update|upgrade)\b|\bnpx\s|\b(?:pip3?|pipx|uv\s+pip)\s+(?:install|uninstall)\b|\b(?:go|cargo|gem)\s+install\b|\bcomposer\s+(?:require|install|update)\b`, "安装或卸载依赖包会从网络下载并执行代码"),
+}
+
+// Policy 命令执行策略：内置禁止规则、自定义禁止/允许规则和包管理器开关。
+//
+// 检查顺序为 内置禁止规则 -> Deny -> 包管理器 -> Allow，Allow 为空表示不限制。
+type Policy struct {
+	Allow                []Rule
+	Deny                 []Rule
+	AllowPackageManagers bool
+}
+
+// NewPolicy 根据前缀或 re: 正则模式创建策略。
+func NewPolicy(allow, deny []string, allowPackageManagers bool) (*Policy, error) {
+	p := &Policy{AllowPackageManagers: allowPackageManagers}
+	for _, pattern := range allow {
+		r, err := newRule("allow", pattern, "")
+		if err != nil {
+			return nil, err
+		}
+		p.Allow = append(p.Allow, r)
+	}
+	for _, pattern := range deny {
+		r, err := newRule("deny", pattern, "命令匹配了配置的禁止规则 "+pattern)
+		if err != nil {
+			return nil, err
+		}
+		p.Deny = append(p.Deny, r)
+	}
+	return p, nil
+}
+
+// DefaultPolicy 默认策略：只启用内置禁止规则，不允许包管理器。
+func DefaultPolicy() *Policy {
+	return &Policy{}
+}
+
+// Check 检查命令，允许时返回 nil。
+func (p *Policy) Check(command string) *Violation {
+	for _, rules := range [][]Rule{builtinDenyRules, p.Deny} {
+		for _, r := range rules {
+			if r.Match(command) {
+				return &Violation{Command: command, Category: ViolationDenied, Rule: r.Name, Reason: r.Reason}
+			}
+		}
+	}
+	if !p.AllowPackageManagers {
+		for _, r := range packageManagerRules {
+			if r.Match(command) {
+				return &Violation{
+					Command:  command,
+					Category: ViolationPackageManager,
+					Rule:     r.Name,
+					Reason:   r.Reason,
+					Hint:     "包管理器未启用，请让用户手动安装，或由管理员开启 agent.exec.allow_package_managers",
+				}
+			}
+		}
+	}
+	if len(p.Allow) > 0 {
+		if s := unsafeForAllowList(command); s != "" {
+			return &Violation{
+				Command:  command,
+				Category: ViolationNotAllowed,
+				Rule:     "allow",
+				Reason:   fmt.Sprintf("设置了允许列表时不能使用命令替换、进程替换或输出重定向（%s）", s),
+				Hint:     "去掉 `...`、$(...)、<(...) 和 > 重定向后重试，只能执行允许列表中的命令: " + p.allowList(),
+			}
+		}
+	}
+	if len(p.Allow) > 0 && !p.allowed(command) {
+		return &Violation{
+			Command:  command,
+			Category: ViolationNotAllowed,
+			Rule:     "allow",
+			Reason:   "命令不在允许列表中",
+			Hint:     "只能执行允许列表中的命令: " + p.allowList(),
+		}
+	}
+	return nil
+}
+
+// allowed 命令是否在允许列表中：整条命令匹配任一正则规则，或每一段都在单词边界上匹配某条前缀规则。
+func (p *Policy) allowed(command string) bool {
+	for _, r := range p.Allow {
+		if r.re != nil && r.re.MatchString(command) {
+			return true
+		}
+	}
+	segments := commandSegments(command)
+	for _, segment := range segments {
+		if !slices.ContainsFunc(p.Allow, func(r Rule) bool {
+			return r.re == nil && hasCommandPrefix(segment, r.Pattern)
+		}) {
+			return false
+		}
+	}
+	return len(segments) > 0
+}
+
+// allowList 允许规则的模式列表。
+func (p *Policy) allowList() string {
+	patterns := make([]string, 0, len(p.Allow))
+	for _, r := range p.Allow {
+		patterns = append(patterns, r.Pattern)
+	}
+	return strings.Join(patterns, ", ")
+}
+
+// Violation 命令违反执行策略，错误信息为结构化 JSON，便于智能体向用户说明无法执行的原因。
+type Violation struct {
+	Command  string `json:"command"`
+	Category string `json:"category"` // denied、not_allowed 或 package_manager
+	Rule     string `json:"rule"`
+	Reason   string `json:"reason"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+// Error 实现 error 接口。
+func (v *Violation) Error() string {
+	data, _ := json.Marshal(struct {
+		Error string `json:"error"`
+		*Violation
+	}{Error: "command_denied", Violation: v})
+	return "命令被执行策略拒绝: " + string(data)
+}
@@ -5,15 +5,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"icooclaw/pkg/tools"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
 )
 
+// outputWaitDelay 命令结束后等待输出管道关闭的最长时间
+const outputWaitDelay = 2 * time.Second
+
+// 支持的 shell。
+const (
+	// ShellSh POSIX sh，类 Unix 系统默认
+	ShellSh = "sh"
+	// ShellBash bash，Windows 下为 Git Bash 等提供的 bash.exe
+	ShellBash = "bash"
+	// ShellZsh zsh，仅类 Unix 系统
+	ShellZsh = "zsh"
+	// ShellCmd Windows cmd.exe，Windows 默认
+	ShellCmd = "cmd"
+	// ShellPowerShell Windows PowerShell 5.x
+	ShellPowerShell = "powershell"
+	// ShellPwsh PowerShell Core（跨平台）
+	ShellPwsh = "pwsh"
+)
+
 // ShellCommandTool 提供 shell 命令执行功能。
 type ShellCommandTool struct {
-	// WorkDir 工作目录，命令执行的基础目录
+	// WorkDir 默认工作目录，会话选择了工作目录时以会话的为准
 	WorkDir string
 	// Timeout 默认超时时间（秒）
 	Timeout int
@@ -21,6 +42,14 @@ type ShellCommandTool struct {
 	AllowedCommands []string
 	// BlockedCommands 禁止执行的命令列表
 	BlockedCommands []string
+	// Shell 执行命令使用的 shell，为空时按操作系统选择默认值
+	Shell string
+	// Env 命令执行环境，零值表示继承守护进程的全部环境变量
+	Env EnvConfig
+	// Policy 命令执行策略，在 AllowedCommands/BlockedCommands 之后检查
+	Policy *Policy
+	// TailSize 工具结果中保留的输出尾部字节数，完整输出通过实时输出回调下发
+	TailSize int
 }
 
 // ShellCommandOption 配置选项。
@@ -54,6 +83,34 @@ func WithBlockedCommands(commands []string) ShellCommandOption {
 	}
 }
 
+// WithShell 设置执行命令使用的 shell（sh、bash、zsh、cmd、powershell、pwsh）。
+func WithShell(shell string) ShellCommandOption {
+	return func(t *ShellCommandTool) {
+		t.Shell = shell
+	}
+}
+
+// WithEnv 设置命令执行环境。
+func WithEnv(env EnvConfig) ShellCommandOption {
+	return func(t *ShellCommandTool) {
+		t.Env = env
+	}
+}
+
+// WithPolicy 设置命令执行策略。
+func WithPolicy(policy *Policy) ShellCommandOption {
+	return func(t *ShellCommandTool) {
+		t.Policy = policy
+	}
+}
+
+// WithTailSize 设置工具结果中保留的输出尾部字节数。
+func WithTailSize(size int) ShellCommandOption {
+	return func(t *ShellCommandTool) {
+		t.TailSize = size
+	}
+}
+
 // NewShellCommandTool 创建一个新的 shell 命令工具。
 func NewShellCommandTool(opts ...ShellCommandOption) *ShellCommandTool {
 	t := &ShellCommandTool{
@@ -65,6 +122,8 @@ func NewShellCommandTool(opts ...ShellCommandOption) *ShellCommandTool {
 			"dd if=/dev/zero",
 			":(){ :|:& };:", // Fork bomb
 		},
+		Policy:   DefaultPolicy(),
+		TailSize: defaultTailSize,
 	}
 
 	for _, opt := range opts {
@@ -81,7 +140,7 @@ func (t *ShellCommandTool) Name() string {
 
 // Description 返回工具描述。
 func (t *ShellCommandTool) Description() string {
-	return "执行 shell 命令并返回输出结果。支持设置超时时间和工作目录。"
+	return "执行 shell 命令并返回输出结果。支持设置超时时间和工作目录，输出过长时只返回末尾部分。"
 }
 
 // Parameters 返回工具参数定义。
@@ -130,10 +189,10 @@ func (t *ShellCommandTool) Execute(ctx context.Context, args map[string]any) *to
 		timeout = 60
 	}
 
-	// 获取工作目录
-	workDir := t.WorkDir
+	// 获取工作目录，默认为会话的工作目录
+	workDir := tools.GetWorkspace(ctx, t.WorkDir)
 	if wd, ok := args["work_dir"].(string); ok && wd != "" {
-		workDir = wd
+		workDir = resolveWorkDir(workDir, wd)
 	}
 
 	// 获取环境变量
@@ -155,12 +214,55 @@ func (t *ShellCommandTool) Execute(ctx context.Context, args map[string]any) *to
 	return result
 }
 
-// checkCommand 检查命令是否被允许执行。
+// DescribeChange 实现 tools.Mutator：命令可能修改任意文件，只读工作目录中一律不执行。
+func (t *ShellCommandTool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	command, _ := args["command"].(string)
+	workDir := tools.GetWorkspace(ctx, t.WorkDir)
+	if wd, ok := args["work_dir"].(string); ok && wd != "" {
+		workDir = resolveWorkDir(workDir, wd)
+	}
+	return fmt.Sprintf("在 %s 中执行命令: %s", workDir, command), true
+}
+
+// resolveWorkDir 规范化工作目录：统一分隔符，相对路径基于 base。
+func resolveWorkDir(base, dir string) string {
+	dir = filepath.FromSlash(dir)
+	if filepath.IsAbs(dir) || filepath.VolumeName(dir) != "" || base == "" {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(base, dir)
+}
+
+// shell 返回实际使用的 shell。
+func (t *ShellCommandTool) shell() string {
+	if t.Shell == "" {
+		return defaultShell
+	}
+	return t.Shell
+}
+
+// ValidateShell 检查当前系统是否支持该 shell，空字符串表示默认 shell。
+func ValidateShell(shell string) error {
+	_, err := newShellCmd(context.Background(), shell, "")
+	return err
+}
+
+// powerShellArgs PowerShell 执行单条命令的参数，不加载用户配置文件，避免交互提示。
+func powerShellArgs(command string) []string {
+	return []string{"-NoProfile", "-NonInteractive", "-Command", command}
+}
+
+// checkCommand 检查命令是否被允许执行，拒绝时返回 *Violation。
 func (t *ShellCommandTool) checkCommand(command string) error {
 	// 检查禁止的命令
 	for _, blocked := range t.BlockedCommands {
 		if strings.Contains(command, blocked) {
-			return fmt.Errorf("命令被禁止执行: 包含危险操作 '%s'", blocked)
+			return &Violation{
+				Command:  command,
+				Category: ViolationDenied,
+				Rule:     "blocked_commands",
+				Reason:   fmt.Sprintf("包含危险操作 '%s'", blocked),
+			}
 		}
 	}
 
@@ -174,49 +276,65 @@ func (t *ShellCommandTool) checkCommand(command string) error {
 			}
 		}
 		if !allowed {
-			return fmt.Errorf("命令不在允许列表中")
+			return &Violation{
+				Command:  command,
+				Category: ViolationNotAllowed,
+				Rule:     "allowed_commands",
+				Reason:   "命令不在允许列表中",
+				Hint:     "只能执行以下列命令开头的命令: " + strings.Join(t.AllowedCommands, ", "),
+			}
 		}
 	}
 
+	if t.Policy != nil {
+		if v := t.Policy.Check(command); v != nil {
+			return v
+		}
+	}
 	return nil
 }
 
 // runCommand 执行命令并返回结果。
 func (t *ShellCommandTool) runCommand(ctx context.Context, command, workDir string, env []string) *tools.Result {
-	var cmd *exec.Cmd
-
-	// 根据操作系统选择 shell
-	if runtime.GOOS == "windows" {
-		cmd = exec.CommandContext(ctx, "cmd.exe", "/c", command)
-	} else {
-		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd, err := newShellCmd(ctx, t.shell(), command)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
 	}
 
 	// 设置工作目录
 	if workDir != "" {
-		cmd.Dir = workDir
+		cmd.Dir = filepath.Clean(workDir)
 	}
 
-	// 设置环境变量
-	if len(env) > 0 {
-		cmd.Env = append(cmd.Env, env...)
-	}
+	// 设置环境变量：按配置过滤守护进程环境后追加会话变量和本次调用的变量，
+	// 不能只传入追加的变量，Windows 下缺少 SystemRoot 等变量会导致命令无法运行
+	cmd.Env = t.Env.Build(os.Environ(), append(tools.GetVars(ctx).Env(), env...))
+
+	// 执行命令，输出实时转发给上下文中的回调，结果只保留尾部
+	collector := newOutputCollector(t.TailSize, tools.GetOutput(ctx))
+	stdout, stderr := collector.writer(tools.StreamStdout), collector.writer(tools.StreamStderr)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// 超时杀死 shell 后，后台子进程可能仍持有输出管道，最多再等待该时长
+	cmd.WaitDelay = outputWaitDelay
 
-	// 执行命令并获取输出
 	startTime := time.Now()
-	output, err := cmd.CombinedOutput()
+	err = cmd.Run()
 	duration := time.Since(startTime)
+	stdout.flush()
+	stderr.flush()
+	output, truncated := collector.output()
 
 	// 构建结果
 	result := map[string]any{
 		"command":      command,
 		"duration_ms":  duration.Milliseconds(),
-		"output":       string(output),
+		"output":       output,
 		"success":      err == nil,
 		"exit_code":    0,
 		"timed_out":    false,
 		"work_dir":     workDir,
 		"platform":     runtime.GOOS,
+		"shell":        t.shell(),
 	}
 
 	// 处理错误
@@ -234,10 +352,7 @@ func (t *ShellCommandTool) runCommand(ctx context.Context, command, workDir stri
 		}
 	}
 
-	// 截断过长的输出
-	outputStr := string(output)
-	if len(outputStr) > 10000 {
-		result["output"] = outputStr[:10000] + "\n... (输出已截断)"
+	if truncated {
 		result["truncated"] = true
 	}
 
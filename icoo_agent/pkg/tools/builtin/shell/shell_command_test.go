@@ -2,9 +2,15 @@ package shell
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
+
+	"icooclaw/pkg/tools"
 )
 
 func TestShellCommandTool_Name(t *testing.T) {
@@ -189,4 +195,51 @@ func TestShellCommandTool_ExecuteWithEnv(t *testing.T) {
 	if !result.Success {
 		t.Errorf("Command should succeed, error: %v", result.Error)
 	}
-}
\ No newline at end of file
+}
+func TestShellCommandTool_RelativeWorkDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 下由 shell_windows_test.go 覆盖")
+	}
+
+	workDir := t.TempDir()
+	tool := NewShellCommandTool(WithWorkDir(workDir))
+	if got, want := resolveWorkDir(tool.WorkDir, "sub/dir"), filepath.Join(workDir, "sub", "dir"); got != want {
+		t.Errorf("resolveWorkDir = %q, want %q", got, want)
+	}
+	if got := resolveWorkDir(tool.WorkDir, "/var/tmp/"); got != "/var/tmp" {
+		t.Errorf("resolveWorkDir = %q, want /var/tmp", got)
+	}
+}
+
+func TestShellCommandTool_SessionWorkspace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 sh 验证")
+	}
+
+	sessionDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(sessionDir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewShellCommandTool(WithWorkDir(t.TempDir()))
+	ctx := tools.WithWorkspace(context.Background(), sessionDir)
+
+	// 会话的工作目录优先于工具配置的工作目录，相对 work_dir 也基于它
+	for _, tc := range []struct{ workDir, want string }{
+		{"", sessionDir},
+		{"sub", filepath.Join(sessionDir, "sub")},
+	} {
+		result := tool.Execute(ctx, map[string]any{"command": "pwd", "work_dir": tc.workDir})
+		if !result.Success {
+			t.Fatalf("执行失败: %v", result.Error)
+		}
+		var res map[string]any
+		if err := json.Unmarshal([]byte(result.Content), &res); err != nil {
+			t.Fatalf("结果不是 JSON: %v", err)
+		}
+		got, _ := res["output"].(string)
+		want, _ := filepath.EvalSymlinks(tc.want)
+		if got = strings.TrimSpace(got); got != tc.want && got != want {
+			t.Errorf("work_dir %q: pwd = %q, want %q", tc.workDir, got, tc.want)
+		}
+	}
+}
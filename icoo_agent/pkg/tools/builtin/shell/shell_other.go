@@ -0,0 +1,27 @@
+//go:build !windows
+
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// defaultShell 类 Unix 系统下默认使用 /bin/sh。
+const defaultShell = ShellSh
+
+// newShellCmd 创建在指定 shell 中执行命令的进程。
+func newShellCmd(ctx context.Context, shell, command string) (*exec.Cmd, error) {
+	switch shell {
+	case "", ShellSh:
+		return exec.CommandContext(ctx, "/bin/sh", "-c", command), nil
+	case ShellBash, ShellZsh:
+		return exec.CommandContext(ctx, shell, "-c", command), nil
+	case ShellPowerShell, ShellPwsh:
+		// 非 Windows 系统只有 PowerShell Core
+		return exec.CommandContext(ctx, "pwsh", powerShellArgs(command)...), nil
+	default:
+		return nil, fmt.Errorf("当前系统不支持的 shell: %s", shell)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build windows
+
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// defaultShell Windows 下默认使用 cmd。
+const defaultShell = ShellCmd
+
+// newShellCmd 创建在指定 shell 中执行命令的进程。
+//
+// cmd.exe 不使用 MSVC 的参数转义规则，直接交给 exec 会把命令中的引号转义成 \"，
+// 因此手动拼接命令行：/s /c "<command>" 会原样去掉首尾引号后执行。
+func newShellCmd(ctx context.Context, shell, command string) (*exec.Cmd, error) {
+	switch shell {
+	case "", ShellCmd:
+		cmd := exec.CommandContext(ctx, "cmd.exe")
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			CmdLine: cmdLine(command),
+		}
+		return cmd, nil
+	case ShellPowerShell:
+		return exec.CommandContext(ctx, "powershell.exe", powerShellArgs(command)...), nil
+	case ShellPwsh:
+		return exec.CommandContext(ctx, "pwsh.exe", powerShellArgs(command)...), nil
+	case ShellSh, ShellBash:
+		return exec.CommandContext(ctx, shell, "-c", command), nil
+	default:
+		return nil, fmt.Errorf("不支持的 shell: %s", shell)
+	}
+}
+
+// cmdLine 生成 cmd.exe 的完整命令行，/d 跳过 AutoRun 注册表项。
+func cmdLine(command string) string {
+	return `cmd.exe /d /s /c "` + command + `"`
+}
@@ -0,0 +1,42 @@
+package shell
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestNewShellCmdWindows(t *testing.T) {
+	ctx := context.Background()
+
+	cmd, err := newShellCmd(ctx, ShellCmd, `echo "a b"`)
+	if err != nil {
+		t.Fatalf("newShellCmd error: %v", err)
+	}
+	if want := `cmd.exe /d /s /c "echo "a b""`; cmd.SysProcAttr == nil || cmd.SysProcAttr.CmdLine != want {
+		t.Errorf("CmdLine = %+v, want %q", cmd.SysProcAttr, want)
+	}
+
+	cmd, err = newShellCmd(ctx, ShellPowerShell, "Get-Location")
+	if err != nil {
+		t.Fatalf("newShellCmd error: %v", err)
+	}
+	if want := []string{"-NoProfile", "-NonInteractive", "-Command", "Get-Location"}; !slices.Equal(cmd.Args[1:], want) {
+		t.Errorf("Args = %v, want %v", cmd.Args[1:], want)
+	}
+
+	if _, err := newShellCmd(ctx, "zsh", "echo"); err == nil {
+		t.Error("不支持的 shell 应返回错误")
+	}
+}
+
+func TestResolveWorkDirWindows(t *testing.T) {
+	tool := NewShellCommandTool(WithWorkDir(`\\server\share\ws`))
+
+	if got, want := resolveWorkDir(tool.WorkDir, "sub/dir"), `\\server\share\ws\sub\dir`; got != want {
+		t.Errorf("resolveWorkDir = %q, want %q", got, want)
+	}
+	if got, want := resolveWorkDir(tool.WorkDir, `D:/build`), `D:\build`; got != want {
+		t.Errorf("resolveWorkDir = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,293 @@
+// Package spreadsheet 提供表格工具：将结构化行数据或工作目录中的 CSV 文件生成带格式的 .xlsx 文件，
+// 结果保存为制品，支持文件的渠道作为附件发送。
+package spreadsheet
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/artifact"
+	"icooclaw/pkg/tools/builtin/file"
+)
+
+// 工作簿的大小限制
+const (
+	maxSheets = 20
+	maxRows   = 100000
+)
+
+// Tool 生成 Excel 表格。
+type Tool struct {
+	store   *storage.ArtifactStorage
+	workDir string
+}
+
+// NewTool 创建 write_spreadsheet 工具，workDir 为会话未选择工作目录时使用的默认目录。
+func NewTool(store *storage.ArtifactStorage, workDir string) *Tool {
+	if workDir == "" {
+		workDir = "./workspace"
+	}
+	return &Tool{store: store, workDir: workDir}
+}
+
+// Name 工具名称.
+func (t *Tool) Name() string {
+	return "write_spreadsheet"
+}
+
+// Description 工具描述.
+func (t *Tool) Description() string {
+	return "生成 Excel 表格（.xlsx）并保存为制品，支持多个工作表、列宽和数字格式。用户要求表格或 Excel 文件时使用，" +
+		"不要输出 CSV 文本。数据来自 sheets 参数，或工作目录中的 CSV 文件（csv_path）。生成后在回复中写 [artifact:<id>] 引用。"
+}
+
+// Parameters 工具参数.
+func (t *Tool) Parameters() map[string]any {
+	column := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"header": map[string]any{"type": "string", "description": "表头"},
+			"width":  map[string]any{"type": "number", "description": "列宽（字符数），默认按内容估算"},
+			"format": map[string]any{
+				"type": "string",
+				"description": "数字格式：integer、decimal、percent（0.15 显示为 15.00%）、currency、date、datetime、text，" +
+					"或 Excel 格式代码如 0.0；日期列可使用 2024-01-31 形式的文本",
+			},
+		},
+	}
+	return map[string]any{
+		"name": map[string]any{
+			"type":        "string",
+			"description": "文件名，默认 sheet.xlsx",
+		},
+		"sheets": map[string]any{
+			"type":        "array",
+			"description": "工作表列表",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":    map[string]any{"type": "string", "description": "工作表名称，最多 31 个字符"},
+					"columns": map[string]any{"type": "array", "description": "列定义，按顺序对应每行的值", "items": column},
+					"rows": map[string]any{
+						"type":        "array",
+						"description": "数据行，每行是单元格值的数组（数字、文本、布尔值或 null）",
+						"items":       map[string]any{"type": "array"},
+					},
+				},
+			},
+		},
+		"csv_path": map[string]any{
+			"type":        "string",
+			"description": "工作目录中的 CSV 文件路径，第一行为表头，与 sheets 二选一",
+		},
+		"columns": map[string]any{
+			"type":        "array",
+			"description": "使用 csv_path 时按位置指定列宽和格式，表头取自 CSV",
+			"items":       column,
+		},
+		"description": map[string]any{
+			"type":        "string",
+			"description": "一句话说明表格的内容",
+		},
+	}
+}
+
+// DescribeChange 实现 tools.Mutator，生成的表格会写入工作目录。
+func (t *Tool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	return "生成表格 " + fileName(args), true
+}
+
+// sheetArg 模型传入的工作表
+type sheetArg struct {
+	Name    string      `json:"name"`
+	Columns []columnArg `json:"columns"`
+	Rows    [][]any     `json:"rows"`
+}
+
+type columnArg struct {
+	Header string  `json:"header"`
+	Width  float64 `json:"width"`
+	Format string  `json:"format"`
+}
+
+// Execute 执行 write_spreadsheet.
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	if tools.GetChannel(ctx) == "" || tools.GetSessionID(ctx) == "" {
+		return tools.ErrorResult("缺少会话上下文，无法保存表格")
+	}
+	if tools.IsEphemeral(ctx) {
+		return tools.ErrorResult("无痕会话不保存表格")
+	}
+
+	var in struct {
+		Sheets  []sheetArg  `json:"sheets"`
+		CSVPath string      `json:"csv_path"`
+		Columns []columnArg `json:"columns"`
+	}
+	raw, _ := json.Marshal(args)
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&in); err != nil {
+		return tools.ErrorResult(fmt.Sprintf("参数格式错误: %s", err))
+	}
+
+	var sheets []Sheet
+	switch {
+	case in.CSVPath != "":
+		sheet, err := t.readCSV(ctx, in.CSVPath, in.Columns)
+		if err != nil {
+			return tools.ErrorResult(err.Error())
+		}
+		sheets = []Sheet{sheet}
+	case len(in.Sheets) > 0:
+		for _, s := range in.Sheets {
+			sheets = append(sheets, Sheet{Name: s.Name, Columns: columns(s.Columns, nil), Rows: s.Rows})
+		}
+	default:
+		return tools.ErrorResult("需要提供 sheets 或 csv_path 参数")
+	}
+	if len(sheets) > maxSheets {
+		return tools.ErrorResult(fmt.Sprintf("工作表不能超过 %d 个", maxSheets))
+	}
+	rows := 0
+	for _, s := range sheets {
+		rows += len(s.Rows)
+	}
+	if rows > maxRows {
+		return tools.ErrorResult(fmt.Sprintf("数据行不能超过 %d 行", maxRows))
+	}
+	sheetNames(sheets)
+
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, sheets); err != nil {
+		return tools.ErrorResult(fmt.Sprintf("生成表格失败: %s", err))
+	}
+
+	name := fileName(args)
+	a, err := artifact.Save(ctx, t.store, t.workDir, name, "data", argString(args, "description"), buf.Bytes())
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+	return tools.SuccessResult(fmt.Sprintf("已生成表格 %s（%d 个工作表，%d 行数据）。在回复中写 %s 引用它。",
+		name, len(sheets), rows, artifact.Reference(a.ID)))
+}
+
+// readCSV 读取工作目录中的 CSV 文件，第一行为表头，可解析的数字转换为数值。
+func (t *Tool) readCSV(ctx context.Context, path string, cols []columnArg) (Sheet, error) {
+	resolved, err := file.ResolvePath(tools.GetWorkspace(ctx, t.workDir), path)
+	if err != nil {
+		return Sheet{}, err
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return Sheet{}, fmt.Errorf("读取 CSV 文件失败: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Excel 导出的 UTF-8 BOM
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return Sheet{}, fmt.Errorf("解析 CSV 文件失败: %w", err)
+	}
+	if len(records) == 0 {
+		return Sheet{}, fmt.Errorf("CSV 文件为空")
+	}
+
+	sheet := Sheet{
+		Name:    strings.TrimSuffix(filepath.Base(resolved), filepath.Ext(resolved)),
+		Columns: columns(cols, records[0]),
+	}
+	for _, record := range records[1:] {
+		row := make([]any, len(record))
+		for i, v := range record {
+			row[i] = csvValue(v)
+		}
+		sheet.Rows = append(sheet.Rows, row)
+	}
+	return sheet, nil
+}
+
+// columns 合并列定义和 CSV 表头，headers 非空时表头取自 headers。
+func columns(cols []columnArg, headers []string) []Column {
+	n := max(len(cols), len(headers))
+	result := make([]Column, n)
+	for i := range n {
+		if i < len(cols) {
+			result[i] = Column{Header: cols[i].Header, Width: cols[i].Width, Format: cols[i].Format}
+		}
+		if i < len(headers) {
+			result[i].Header = headers[i]
+		}
+	}
+	return result
+}
+
+// csvValue CSV 中的数字转换为数值，带前导零的编号（如 007）保持文本。
+func csvValue(v string) any {
+	s := strings.TrimSpace(v)
+	if s == "" || (len(s) > 1 && s[0] == '0' && s[1] != '.') || !strings.ContainsAny(s[:1], "0123456789+-.") {
+		return v
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return v
+}
+
+// sheetNames 规范化工作表名称：去掉 Excel 不允许的字符，截断为 31 个字符，重名时追加序号。
+func sheetNames(sheets []Sheet) {
+	seen := make(map[string]bool)
+	for i := range sheets {
+		name := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`[]:*?/\`, r) {
+				return -1
+			}
+			return r
+		}, strings.TrimSpace(sheets[i].Name))
+		name = strings.Trim(name, "'")
+		if name == "" {
+			name = fmt.Sprintf("Sheet%d", i+1)
+		}
+		name = truncate(name, 31)
+		base := name
+		for n := 2; seen[strings.ToLower(name)]; n++ {
+			suffix := fmt.Sprintf(" (%d)", n)
+			name = truncate(base, 31-len(suffix)) + suffix
+		}
+		seen[strings.ToLower(name)] = true
+		sheets[i].Name = name
+	}
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// fileName 返回 .xlsx 文件名，只保留文件名部分。
+func fileName(args map[string]any) string {
+	name := filepath.Base(strings.ReplaceAll(argString(args, "name"), "\\", "/"))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		name = "sheet"
+	}
+	return name + ".xlsx"
+}
+
+func argString(args map[string]any, key string) string {
+	s, _ := args[key].(string)
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,162 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+// readXLSX 解压工作簿并校验每个 XML 部件格式正确。
+func readXLSX(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		dec := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: invalid xml: %v", f.Name, err)
+			}
+		}
+		parts[f.Name] = string(content)
+	}
+	return parts
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteXLSX(&buf, []Sheet{
+		{
+			Name: "Q1 <sales>",
+			Columns: []Column{
+				{Header: "区域"},
+				{Header: "Revenue", Format: "decimal"},
+				{Header: "Growth", Format: "percent"},
+				{Header: "Date", Format: "date"},
+			},
+			Rows: [][]any{
+				{"East & West", 1234.5, "12.5%", "2024-01-31"},
+				{"North", 99.0, 0.2, nil},
+			},
+		},
+		{Name: "Notes", Rows: [][]any{{"raw", true}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := readXLSX(t, buf.Bytes())
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Q1 &lt;sales&gt;"`) {
+		t.Errorf("workbook = %s", parts["xl/workbook.xml"])
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">区域</t></is></c>`,
+		`<t xml:space="preserve">East &amp; West</t>`,
+		`<c r="B2" s="2"><v>1234.5</v></c>`,
+		`<c r="C2" s="3"><v>0.125</v></c>`,
+		`<c r="D2" s="4"><v>45322</v></c>`, // 2024-01-31 的日期序列号
+		`state="frozen"`,
+		`<autoFilter ref="A1:D3"/>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet1 missing %s", want)
+		}
+	}
+	if strings.Contains(sheet, `r="D3"`) {
+		t.Error("nil cell should be omitted")
+	}
+	if styles := parts["xl/styles.xml"]; !strings.Contains(styles, `formatCode="yyyy-mm-dd"`) || !strings.Contains(styles, `numFmtId="10"`) {
+		t.Errorf("styles = %s", styles)
+	}
+	if sheet2 := parts["xl/worksheets/sheet2.xml"]; !strings.Contains(sheet2, `<c r="B1" s="0" t="b"><v>1</v></c>`) || strings.Contains(sheet2, "frozen") {
+		t.Errorf("sheet2 = %s", sheet2)
+	}
+}
+
+func TestTool_CSV(t *testing.T) {
+	s, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "sheet.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	workspace := t.TempDir()
+	csvData := "\xef\xbb\xbfid,name,amount\n007,Alice,12.50\n8,Bob,3\n"
+	if err := os.WriteFile(filepath.Join(workspace, "data.csv"), []byte(csvData), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewTool(s.Artifact(), workspace)
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+	result := tool.Execute(ctx, map[string]any{
+		"name":     "report",
+		"csv_path": "data.csv",
+		"columns":  []any{map[string]any{}, map[string]any{"width": 20}, map[string]any{"format": "currency"}},
+	})
+	if result.Error != nil {
+		t.Fatalf("Execute() error = %v", result.Error)
+	}
+
+	artifacts, err := s.Artifact().ListBySession("websocket", "s1")
+	if err != nil || len(artifacts) != 1 {
+		t.Fatalf("artifacts = %+v, %v", artifacts, err)
+	}
+	a := artifacts[0]
+	if a.Name != "report.xlsx" || !strings.HasSuffix(a.MIMEType, "spreadsheetml.sheet") {
+		t.Errorf("artifact = %+v", a)
+	}
+	data, err := os.ReadFile(a.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := readXLSX(t, data)
+	if !strings.Contains(parts["xl/workbook.xml"], `name="data"`) {
+		t.Errorf("workbook = %s", parts["xl/workbook.xml"])
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<t xml:space="preserve">id</t>`,
+		`<t xml:space="preserve">007</t>`,
+		`<c r="A3" s="0"><v>8</v></c>`,
+		`<c r="C2" s="2"><v>12.5</v></c>`,
+		`<col min="2" max="2" width="20.0" customWidth="1"/>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet missing %s\n%s", want, sheet)
+		}
+	}
+
+	if result := tool.Execute(ctx, map[string]any{"csv_path": "../outside.csv"}); result.Error == nil {
+		t.Error("path outside the workspace should fail")
+	}
+	if result := tool.Execute(ctx, map[string]any{}); result.Error == nil {
+		t.Error("missing data should fail")
+	}
+}
@@ -0,0 +1,359 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Sheet 一个工作表，有列定义时第一行为表头。
+type Sheet struct {
+	Name    string
+	Columns []Column
+	Rows    [][]any
+}
+
+// Column 列的表头、宽度和数字格式。
+type Column struct {
+	Header string
+	Width  float64 // 字符宽度，0 表示按内容估算
+	Format string  // 格式别名或 Excel 格式代码，为空为常规格式
+}
+
+// formatAliases 常用格式别名对应的 Excel 格式代码
+var formatAliases = map[string]string{
+	"integer":  "#,##0",
+	"decimal":  "#,##0.00",
+	"percent":  "0.00%",
+	"currency": "¥#,##0.00",
+	"date":     "yyyy-mm-dd",
+	"datetime": "yyyy-mm-dd hh:mm:ss",
+	"text":     "@",
+}
+
+// builtinFormats Excel 内置格式代码的 ID，其余格式（包括日期，内置日期格式随区域变化）从 164 开始自定义
+var builtinFormats = map[string]int{
+	"0":        1,
+	"0.00":     2,
+	"#,##0":    3,
+	"#,##0.00": 4,
+	"0%":       9,
+	"0.00%":    10,
+	"@":        49,
+}
+
+// dateLayouts 日期列中可转换为 Excel 日期的文本格式
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "2006/01/02"}
+
+// excelEpoch Excel 日期序列号的起点（兼容 1900 年闰年错误）
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// formatCode 返回列格式对应的 Excel 格式代码。
+func formatCode(format string) string {
+	if code, ok := formatAliases[strings.ToLower(format)]; ok {
+		return code
+	}
+	return format
+}
+
+// isDateFormat 判断格式代码是否为日期时间格式。
+func isDateFormat(code string) bool {
+	code = strings.ToLower(code)
+	return strings.Contains(code, "yy") || strings.Contains(code, "dd") || strings.Contains(code, "hh")
+}
+
+// styles 收集工作簿用到的数字格式，每种格式一个单元格样式。
+type styles struct {
+	numFmts []string       // 自定义格式代码，ID 依次为 164、165...
+	xfs     []int          // 单元格样式对应的格式 ID，样式 0 为默认、1 为表头
+	index   map[string]int // 格式代码 -> 样式索引
+}
+
+func newStyles() *styles {
+	return &styles{xfs: []int{0, 0}, index: map[string]int{}}
+}
+
+// style 返回格式代码的样式索引，空代码为默认样式。
+func (s *styles) style(code string) int {
+	if code == "" {
+		return 0
+	}
+	if i, ok := s.index[code]; ok {
+		return i
+	}
+	id, ok := builtinFormats[code]
+	if !ok {
+		s.numFmts = append(s.numFmts, code)
+		id = 163 + len(s.numFmts)
+	}
+	s.xfs = append(s.xfs, id)
+	s.index[code] = len(s.xfs) - 1
+	return s.index[code]
+}
+
+// WriteXLSX writes the sheets as an Office Open XML workbook. The header row is
+// bold, frozen and filterable; column widths default to the content width.
+func WriteXLSX(w io.Writer, sheets []Sheet) error {
+	zw := zip.NewWriter(w)
+	st := newStyles()
+
+	sheetXML := make([]string, len(sheets))
+	for i, sh := range sheets {
+		sheetXML[i] = worksheet(sh, st)
+	}
+
+	var workbook, rels, types strings.Builder
+	workbook.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	types.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i, sh := range sheets {
+		n := i + 1
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(sh.Name), n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+	}
+	workbook.WriteString(`</sheets></workbook>`)
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`, len(sheets)+1)
+	types.WriteString(`</Types>`)
+
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", types.String()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", rels.String()},
+		{"xl/styles.xml", st.xml()},
+	}
+	for i, content := range sheetXML {
+		files = append(files, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), content})
+	}
+
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// worksheet 生成工作表 XML。
+func worksheet(sh Sheet, st *styles) string {
+	ncols := len(sh.Columns)
+	for _, row := range sh.Rows {
+		ncols = max(ncols, len(row))
+	}
+
+	// 列格式和宽度
+	codes := make([]string, ncols)
+	widths := make([]float64, ncols)
+	for i := range ncols {
+		if i < len(sh.Columns) {
+			codes[i] = formatCode(sh.Columns[i].Format)
+			widths[i] = sh.Columns[i].Width
+		}
+	}
+
+	var rows strings.Builder
+	estimate := make([]int, ncols)
+	if len(sh.Columns) > 0 {
+		rows.WriteString(`<row r="1">`)
+		for i, col := range sh.Columns {
+			estimate[i] = textWidth(col.Header)
+			fmt.Fprintf(&rows, `<c r="%s1" s="1" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, columnName(i), escape(col.Header))
+		}
+		rows.WriteString(`</row>`)
+	}
+	first := 1
+	if len(sh.Columns) > 0 {
+		first = 2
+	}
+	for r, row := range sh.Rows {
+		n := first + r
+		fmt.Fprintf(&rows, `<row r="%d">`, n)
+		for i, v := range row {
+			ref := columnName(i) + strconv.Itoa(n)
+			s := st.style(codes[i])
+			text, ok := cellXML(ref, s, v, codes[i])
+			if !ok {
+				continue
+			}
+			estimate[i] = max(estimate[i], textWidth(fmt.Sprint(v)))
+			rows.WriteString(text)
+		}
+		rows.WriteString(`</row>`)
+	}
+
+	var b strings.Builder
+	b.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(sh.Columns) > 0 {
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	if ncols > 0 {
+		b.WriteString(`<cols>`)
+		for i := range ncols {
+			w := widths[i]
+			if w <= 0 {
+				w = math.Min(float64(estimate[i])+2, 60)
+				if isDateFormat(codes[i]) {
+					w = math.Max(w, float64(len(codes[i]))+2)
+				}
+			}
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%.1f" customWidth="1"/>`, i+1, i+1, w)
+		}
+		b.WriteString(`</cols>`)
+	}
+	b.WriteString(`<sheetData>`)
+	b.WriteString(rows.String())
+	b.WriteString(`</sheetData>`)
+	if len(sh.Columns) > 0 && len(sh.Rows) > 0 {
+		fmt.Fprintf(&b, `<autoFilter ref="A1:%s%d"/>`, columnName(len(sh.Columns)-1), len(sh.Rows)+1)
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+// cellXML 生成单元格 XML，空值不输出单元格。
+// 数字和布尔值按原类型写入；文本在数字或日期格式的列中能解析时转换为数字或日期。
+func cellXML(ref string, style int, v any, code string) (string, bool) {
+	if n, ok := cellNumber(v, code); ok {
+		return fmt.Sprintf(`<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(n, 'f', -1, 64)), true
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		return fmt.Sprintf(`<c r="%s" s="%d" t="b"><v>%d</v></c>`, ref, style, b), true
+	case string:
+		if v == "" {
+			return "", false
+		}
+		return fmt.Sprintf(`<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(v)), true
+	default:
+		return cellXML(ref, style, fmt.Sprint(v), "@")
+	}
+}
+
+// cellNumber 返回单元格的数值，日期转换为 Excel 日期序列号。
+func cellNumber(v any, code string) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case time.Time:
+		return excelDate(v), true
+	case string:
+		if code == "" || code == "@" {
+			return 0, false
+		}
+		s := strings.TrimSpace(v)
+		if isDateFormat(code) {
+			for _, layout := range dateLayouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					return excelDate(t), true
+				}
+			}
+			return 0, false
+		}
+		percent := strings.HasSuffix(s, "%")
+		s = strings.ReplaceAll(strings.TrimSuffix(s, "%"), ",", "")
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, false
+		}
+		if percent {
+			f /= 100
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+// excelDate 将时间转换为 Excel 日期序列号，时间按其自身时区的钟面值记录。
+func excelDate(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(excelEpoch).Hours() / 24
+}
+
+// columnName 返回第 i 列（从 0 开始）的列名：A、B ... Z、AA ...
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// textWidth 估算文本宽度，全角字符按两个字符计算。
+func textWidth(s string) int {
+	w := 0
+	for _, line := range strings.Split(s, "\n") {
+		lw := 0
+		for _, r := range line {
+			if utf8.RuneLen(r) > 1 {
+				lw += 2
+			} else {
+				lw++
+			}
+		}
+		w = max(w, lw)
+	}
+	return w
+}
+
+// xml 生成 styles.xml：字体 1 和填充 2 用于表头。
+func (s *styles) xml() string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(s.numFmts) > 0 {
+		fmt.Fprintf(&b, `<numFmts count="%d">`, len(s.numFmts))
+		for i, code := range s.numFmts {
+			fmt.Fprintf(&b, `<numFmt numFmtId="%d" formatCode="%s"/>`, 164+i, escape(code))
+		}
+		b.WriteString(`</numFmts>`)
+	}
+	b.WriteString(`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+		`<fill><patternFill patternType="solid"><fgColor rgb="FFD9E1F2"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`)
+	fmt.Fprintf(&b, `<cellXfs count="%d">`, len(s.xfs))
+	b.WriteString(`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>`)
+	b.WriteString(`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>`)
+	for _, id := range s.xfs[2:] {
+		fmt.Fprintf(&b, `<xf numFmtId="%d" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`, id)
+	}
+	b.WriteString(`</cellXfs><cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles></styleSheet>`)
+	return b.String()
+}
+
+// escape 转义 XML 文本和属性值。
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,75 @@
+// Package timezone provides a tool for reading and recording the user's time zone.
+package timezone
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"icooclaw/pkg/clock"
+	"icooclaw/pkg/tools"
+)
+
+// Tool 查看或记录当前会话的用户时区。
+type Tool struct {
+	manager *clock.Manager
+}
+
+// NewTool 创建 user_timezone 工具。
+func NewTool(manager *clock.Manager) *Tool {
+	return &Tool{manager: manager}
+}
+
+// Name 工具名称.
+func (t *Tool) Name() string {
+	return "user_timezone"
+}
+
+// Description 工具描述.
+func (t *Tool) Description() string {
+	return "查看或记录用户所在的时区。用户提到自己所在的城市或时区（例如\"我在东京\"）时用 set 记录，" +
+		"之后的当前时间、时间解析和定时任务都按该时区处理。"
+}
+
+// Parameters 工具参数.
+func (t *Tool) Parameters() map[string]any {
+	return map[string]any{
+		"action": map[string]any{
+			"type":        "string",
+			"description": "操作: get (默认) 或 set",
+			"enum":        []string{"get", "set"},
+		},
+		"timezone": map[string]any{
+			"type":        "string",
+			"description": "set 时的 IANA 时区名称，例如 Asia/Tokyo、America/New_York，也可以是 UTC+8 这样的偏移",
+		},
+	}
+}
+
+// Execute 执行 user_timezone.
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	channel := tools.GetChannel(ctx)
+	sessionID := tools.GetSessionID(ctx)
+	if channel == "" || sessionID == "" {
+		return tools.ErrorResult("缺少会话上下文，无法读取用户时区")
+	}
+
+	action, _ := args["action"].(string)
+	switch action {
+	case "", "get":
+		loc := t.manager.Current(channel, sessionID)
+		return tools.SuccessResult(fmt.Sprintf("用户时区: %s\n当前时间: %s", loc, clock.Format(time.Now(), loc)))
+	case "set":
+		name, _ := args["timezone"].(string)
+		if name == "" {
+			return tools.ErrorResult("需要提供 timezone 参数")
+		}
+		loc, err := t.manager.Select(channel, sessionID, name)
+		if err != nil {
+			return tools.ErrorResult(fmt.Sprintf("设置时区失败: %s", err))
+		}
+		return tools.SuccessResult(fmt.Sprintf("已记录用户时区 %s，当前时间 %s", loc, clock.Format(time.Now(), loc)))
+	default:
+		return tools.ErrorResult(fmt.Sprintf("未知操作: %s", action))
+	}
+}
@@ -0,0 +1,44 @@
+package timezone
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/clock"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+func TestTool_SetGet(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "timezone.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	manager := clock.NewManager(time.UTC, nil, store, nil)
+	tool := NewTool(manager)
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+
+	if r := tool.Execute(ctx, map[string]any{"action": "set", "timezone": "Nowhere/City"}); r.Success {
+		t.Error("unknown timezone should be rejected")
+	}
+	if r := tool.Execute(ctx, map[string]any{"action": "set", "timezone": "UTC+9"}); !r.Success {
+		t.Fatalf("set failed: %v", r.Error)
+	}
+	if got := manager.Current("websocket", "s1").String(); got != "UTC+09:00" {
+		t.Errorf("Current() = %s, want the recorded timezone", got)
+	}
+	if r := tool.Execute(ctx, map[string]any{}); !strings.Contains(r.Content, "UTC+09:00") {
+		t.Errorf("get = %q", r.Content)
+	}
+
+	// 其他会话不受影响
+	other := tools.WithToolContext(context.Background(), "websocket", "s2")
+	if r := tool.Execute(other, map[string]any{}); !strings.Contains(r.Content, "用户时区: UTC\n") {
+		t.Errorf("other session get = %q", r.Content)
+	}
+}
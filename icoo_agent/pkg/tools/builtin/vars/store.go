@@ -0,0 +1,73 @@
+// Package vars provides session-scoped variables, persisted in session metadata.
+package vars
+
+import (
+	"fmt"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+const (
+	// MaxVars 单个会话的最大变量数量
+	MaxVars = 50
+	// MaxValueSize 单个变量值的最大字节数
+	MaxValueSize = 4 * 1024
+)
+
+// Store 会话变量仓库，变量保存在会话元数据中，随会话持久化。
+type Store struct {
+	sessions *storage.SessionStorage
+}
+
+// NewStore 创建会话变量仓库。
+func NewStore(sessions *storage.SessionStorage) *Store {
+	return &Store{sessions: sessions}
+}
+
+// Load 读取会话的全部变量，未设置时返回空集合。
+func (s *Store) Load(channel, sessionID string) (tools.Vars, error) {
+	v := tools.Vars{}
+	if _, err := s.sessions.GetMetadata(channel, sessionID, tools.VarsMetadataKey, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Set 设置变量，会话不存在时自动创建。
+func (s *Store) Set(channel, sessionID, key, value string) error {
+	if err := tools.ValidVarName(key); err != nil {
+		return err
+	}
+	if len(value) > MaxValueSize {
+		return fmt.Errorf("变量值过大: %d 字节，最大 %d 字节", len(value), MaxValueSize)
+	}
+
+	v, err := s.Load(channel, sessionID)
+	if err != nil {
+		return err
+	}
+	if _, exists := v[key]; !exists && len(v) >= MaxVars {
+		return fmt.Errorf("会话变量已达上限 %d 个", MaxVars)
+	}
+	v[key] = value
+	return s.sessions.SetMetadata(channel, sessionID, tools.VarsMetadataKey, v)
+}
+
+// Delete 删除变量，返回变量是否存在。
+func (s *Store) Delete(channel, sessionID, key string) (bool, error) {
+	v, err := s.Load(channel, sessionID)
+	if err != nil {
+		return false, err
+	}
+	if _, exists := v[key]; !exists {
+		return false, nil
+	}
+	delete(v, key)
+
+	var value any = v
+	if len(v) == 0 {
+		value = nil
+	}
+	return true, s.sessions.SetMetadata(channel, sessionID, tools.VarsMetadataKey, value)
+}
@@ -0,0 +1,112 @@
+package vars
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/tools"
+)
+
+// Tool 读取和设置当前会话的变量。
+type Tool struct {
+	store *Store
+}
+
+// NewTool 创建 session_vars 工具。
+func NewTool(store *Store) *Tool {
+	return &Tool{store: store}
+}
+
+// Name 工具名称.
+func (t *Tool) Name() string {
+	return "session_vars"
+}
+
+// Description 工具描述.
+func (t *Tool) Description() string {
+	return "读取或设置当前会话的变量（如默认项目路径、工单号）。变量随会话保存，" +
+		"可在提示词中以 {{key}} 引用，执行命令时以 ICOOCLAW_VAR_<KEY> 环境变量提供。"
+}
+
+// Parameters 工具参数.
+func (t *Tool) Parameters() map[string]any {
+	return map[string]any{
+		"action": map[string]any{
+			"type":        "string",
+			"description": "操作: list (默认)、get、set 或 delete",
+			"enum":        []string{"list", "get", "set", "delete"},
+		},
+		"key": map[string]any{
+			"type":        "string",
+			"description": "变量名，只能包含字母、数字和 _",
+		},
+		"value": map[string]any{
+			"type":        "string",
+			"description": "set 时的变量值",
+		},
+	}
+}
+
+// Execute 执行 session_vars.
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	channel := tools.GetChannel(ctx)
+	sessionID := tools.GetSessionID(ctx)
+	if channel == "" || sessionID == "" {
+		return tools.ErrorResult("缺少会话上下文，无法读取会话变量")
+	}
+
+	action, _ := args["action"].(string)
+	key, _ := args["key"].(string)
+	if action != "" && action != "list" && key == "" {
+		return tools.ErrorResult("需要提供 key 参数")
+	}
+
+	switch action {
+	case "", "list":
+		v, err := t.store.Load(channel, sessionID)
+		if err != nil {
+			return tools.ErrorResult(fmt.Sprintf("读取会话变量失败: %s", err))
+		}
+		return tools.SuccessResult(Render(v))
+	case "get":
+		v, err := t.store.Load(channel, sessionID)
+		if err != nil {
+			return tools.ErrorResult(fmt.Sprintf("读取会话变量失败: %s", err))
+		}
+		value, ok := v[key]
+		if !ok {
+			return tools.SuccessResult(fmt.Sprintf("变量 %s 未设置", key))
+		}
+		return tools.SuccessResult(value)
+	case "set":
+		value, _ := args["value"].(string)
+		if err := t.store.Set(channel, sessionID, key, value); err != nil {
+			return tools.ErrorResult(fmt.Sprintf("设置会话变量失败: %s", err))
+		}
+		return tools.SuccessResult(fmt.Sprintf("已设置: %s", key))
+	case "delete":
+		deleted, err := t.store.Delete(channel, sessionID, key)
+		if err != nil {
+			return tools.ErrorResult(fmt.Sprintf("删除会话变量失败: %s", err))
+		}
+		if !deleted {
+			return tools.SuccessResult(fmt.Sprintf("变量 %s 未设置", key))
+		}
+		return tools.SuccessResult(fmt.Sprintf("已删除: %s", key))
+	default:
+		return tools.ErrorResult(fmt.Sprintf("未知操作: %s", action))
+	}
+}
+
+// Render 以 key = value 列表形式渲染变量。
+func Render(v tools.Vars) string {
+	if len(v) == 0 {
+		return "暂无会话变量"
+	}
+	sb := strings.Builder{}
+	for _, key := range v.Keys() {
+		sb.WriteString(fmt.Sprintf("- %s = %s\n", key, v[key]))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,52 @@
+package vars
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/tools"
+)
+
+func TestTool_SetListDelete(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "vars.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	tool := NewTool(NewStore(store.Session()))
+	ctx := tools.WithToolContext(context.Background(), "websocket", "s1")
+
+	if r := tool.Execute(ctx, map[string]any{"action": "set", "key": "ticket", "value": "ABC-123"}); !r.Success {
+		t.Fatalf("set failed: %v", r.Error)
+	}
+	if r := tool.Execute(ctx, map[string]any{"action": "set", "key": "bad-name", "value": "x"}); r.Success {
+		t.Error("invalid name should be rejected")
+	}
+
+	// 变量随会话持久化，其他会话不可见
+	v, err := NewStore(store.Session()).Load("websocket", "s1")
+	if err != nil || v["ticket"] != "ABC-123" {
+		t.Fatalf("Load() = %v, %v", v, err)
+	}
+	other := tools.WithToolContext(context.Background(), "websocket", "s2")
+	if r := tool.Execute(other, map[string]any{}); !strings.Contains(r.Content, "暂无会话变量") {
+		t.Errorf("other session should have no vars, got %q", r.Content)
+	}
+
+	if r := tool.Execute(ctx, map[string]any{"action": "list"}); !strings.Contains(r.Content, "ticket = ABC-123") {
+		t.Errorf("list = %q", r.Content)
+	}
+	if r := tool.Execute(ctx, map[string]any{"action": "get", "key": "ticket"}); r.Content != "ABC-123" {
+		t.Errorf("get = %q", r.Content)
+	}
+	if r := tool.Execute(ctx, map[string]any{"action": "delete", "key": "ticket"}); !r.Success {
+		t.Fatalf("delete failed: %v", r.Error)
+	}
+	if v, _ := NewStore(store.Session()).Load("websocket", "s1"); len(v) != 0 {
+		t.Errorf("vars after delete = %v", v)
+	}
+}
@@ -0,0 +1,170 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"icooclaw/pkg/download"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/file"
+	"icooclaw/pkg/vfs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// DownloadTool 将文件下载到工作目录，支持断点续传、限速和 SHA256 校验。
+type DownloadTool struct {
+	// WorkDir 默认工作目录，会话选择了其他工作目录时下载到该目录
+	WorkDir string
+	// Mounts 远程存储挂载，挂载路径下不能直接下载
+	Mounts *vfs.Mounts
+	// MaxBytes 未指定 max_mb 时的文件大小上限
+	MaxBytes int64
+	client   *http.Client
+	timeout  time.Duration // 单次下载的总时长上限，与本轮剩余预算取较小者
+}
+
+// NewDownloadTool creates a new download tool.
+func NewDownloadTool(workDir string) *DownloadTool {
+	if workDir == "" {
+		workDir = "./workspace"
+	}
+	return &DownloadTool{
+		WorkDir:  workDir,
+		MaxBytes: 2 << 30,
+		client:   &http.Client{},
+		timeout:  30 * time.Minute,
+	}
+}
+
+// Name returns the tool name.
+func (t *DownloadTool) Name() string {
+	return "download_file"
+}
+
+// Description returns the tool description.
+func (t *DownloadTool) Description() string {
+	return "将文件下载到工作目录。中断后再次下载同一地址会从断点续传，可指定 SHA256 校验，超过大小上限或校验失败的文件会被移入 .quarantine 目录。"
+}
+
+// Parameters returns the tool parameters.
+func (t *DownloadTool) Parameters() map[string]any {
+	return map[string]any{
+		"url": map[string]any{
+			"type":        "string",
+			"description": "下载地址",
+			"required":    true,
+		},
+		"path": map[string]any{
+			"type":        "string",
+			"description": "保存路径（相对于工作目录），为空时使用地址中的文件名",
+		},
+		"sha256": map[string]any{
+			"type":        "string",
+			"description": "期望的 SHA256 十六进制摘要，下载完成后校验",
+		},
+		"max_mb": map[string]any{
+			"type":        "number",
+			"description": "文件大小上限（MB），超出时中止下载",
+		},
+		"rate_limit_kb": map[string]any{
+			"type":        "number",
+			"description": "限速（KB/s），0 表示不限速",
+		},
+		"headers": map[string]any{
+			"type":        "object",
+			"description": "HTTP 请求头，键值对形式",
+		},
+	}
+}
+
+// Execute downloads the file.
+func (t *DownloadTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	reqURL, _ := args["url"].(string)
+	if reqURL == "" {
+		return &tools.Result{Success: false, Error: fmt.Errorf("需要提供 url 参数")}
+	}
+
+	name := t.targetName(args)
+	if name == "" {
+		return &tools.Result{Success: false, Error: fmt.Errorf("无法从地址推断文件名，需要提供 path 参数")}
+	}
+	workDir := tools.GetWorkspace(ctx, t.WorkDir)
+	dest, err := file.ResolvePath(workDir, name)
+	if err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	rel := file.RelPath(workDir, dest)
+	if err := file.Writable(rel); err != nil {
+		return &tools.Result{Success: false, Error: err}
+	}
+	for _, p := range t.Mounts.Paths() {
+		if rel == p || strings.HasPrefix(rel, p+"/") {
+			return &tools.Result{Success: false, Error: fmt.Errorf("不能直接下载到远程挂载 %s，请先下载到本地再用 copy_file 复制", p)}
+		}
+	}
+
+	opts := download.Options{
+		Client:   t.client,
+		MaxBytes: t.MaxBytes,
+	}
+	opts.SHA256, _ = args["sha256"].(string)
+	if mb, ok := args["max_mb"].(float64); ok && mb > 0 {
+		opts.MaxBytes = int64(mb * (1 << 20))
+	}
+	if kb, ok := args["rate_limit_kb"].(float64); ok && kb > 0 {
+		opts.RateLimit = int64(kb * 1024)
+	}
+	if headers, ok := args["headers"].(map[string]any); ok {
+		opts.Headers = make(map[string]string, len(headers))
+		for k, v := range headers {
+			opts.Headers[k] = fmt.Sprint(v)
+		}
+	}
+	if output := tools.GetOutput(ctx); output != nil {
+		opts.OnProgress = func(p download.Progress) {
+			output(tools.StreamStdout, p.String()+"\n")
+		}
+	}
+
+	ctx, cancel := tools.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	res, err := download.Download(ctx, reqURL, dest, opts)
+	if err != nil {
+		return &tools.Result{Success: false, Error: tools.DeadlineError(ctx, t.timeout, err)}
+	}
+
+	result := map[string]any{
+		"path":     rel,
+		"size":     res.Size,
+		"sha256":   res.SHA256,
+		"resumed":  res.Resumed,
+		"attempts": res.Attempts,
+	}
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+	return &tools.Result{Success: true, Content: string(resultJSON)}
+}
+
+// DescribeChange 实现 tools.Mutator。
+func (t *DownloadTool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	reqURL, _ := args["url"].(string)
+	return fmt.Sprintf("下载 %s 并保存为 %s", reqURL, t.targetName(args)), true
+}
+
+// targetName 返回保存路径，未指定时取地址中的文件名。
+func (t *DownloadTool) targetName(args map[string]any) string {
+	if p, _ := args["path"].(string); p != "" {
+		return p
+	}
+	reqURL, _ := args["url"].(string)
+	reqURL, _, _ = strings.Cut(reqURL, "?")
+	reqURL, _, _ = strings.Cut(reqURL, "#")
+	base := path.Base(reqURL)
+	if base == "." || base == "/" || strings.Contains(base, ":") {
+		return ""
+	}
+	return base
+}
@@ -13,15 +13,15 @@ import (
 
 // HTTPTool provides HTTP request functionality.
 type HTTPTool struct {
-	client *http.Client
+	client  *http.Client
+	timeout time.Duration // 单次请求超时，与本轮剩余预算取较小者
 }
 
 // NewHTTPTool creates a new HTTP tool.
 func NewHTTPTool() *HTTPTool {
 	return &HTTPTool{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:  &http.Client{},
+		timeout: 30 * time.Second,
 	}
 }
 
@@ -71,6 +71,9 @@ func (t *HTTPTool) Execute(ctx context.Context, args map[string]any) *tools.Resu
 		method = strings.ToUpper(m)
 	}
 
+	ctx, cancel := tools.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
 	// Create request
 	var req *http.Request
 	var err error
@@ -95,14 +98,14 @@ func (t *HTTPTool) Execute(ctx context.Context, args map[string]any) *tools.Resu
 	// Execute
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return &tools.Result{Success: false, Error: err}
+		return &tools.Result{Success: false, Error: tools.DeadlineError(ctx, t.timeout, err)}
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return &tools.Result{Success: false, Error: err}
+		return &tools.Result{Success: false, Error: tools.DeadlineError(ctx, t.timeout, err)}
 	}
 
 	result := map[string]any{
@@ -14,15 +14,15 @@ import (
 
 // WebSearchTool provides web search functionality.
 type WebSearchTool struct {
-	client *http.Client
+	client  *http.Client
+	timeout time.Duration // 单次请求超时，与本轮剩余预算取较小者
 }
 
 // NewWebSearchTool creates a new web search tool.
 func NewWebSearchTool() *WebSearchTool {
 	return &WebSearchTool{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:  &http.Client{},
+		timeout: 30 * time.Second,
 	}
 }
 
@@ -66,6 +66,9 @@ func (t *WebSearchTool) Execute(ctx context.Context, args map[string]any) *tools
 	// Use DuckDuckGo Instant Answer API
 	searchURL := fmt.Sprintf("https://api.duckduckgo.com/?q=%s&format=json&no_html=1", url.QueryEscape(query))
 
+	ctx, cancel := tools.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
 	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
 		return &tools.Result{Success: false, Error: err}
@@ -73,13 +76,13 @@ func (t *WebSearchTool) Execute(ctx context.Context, args map[string]any) *tools
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return &tools.Result{Success: false, Error: err}
+		return &tools.Result{Success: false, Error: tools.DeadlineError(ctx, t.timeout, err)}
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return &tools.Result{Success: false, Error: err}
+		return &tools.Result{Success: false, Error: tools.DeadlineError(ctx, t.timeout, err)}
 	}
 
 	var result struct {
@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// MaxDiffBytes 统计行数的文件大小上限，更大的文件只记录被修改，不统计行数
+const MaxDiffBytes = 1 << 20
+
+// maxDiffCells 逐行比较的规模上限（新旧行数之积），超过后按整段替换统计
+const maxDiffCells = 4_000_000
+
+// FileChange 一轮对话中对单个文件的修改。
+type FileChange struct {
+	Path    string `json:"path"`
+	Added   int    `json:"added"`             // 新增行数
+	Removed int    `json:"removed"`           // 删除行数
+	Created bool   `json:"created,omitempty"` // 本轮新建的文件
+	Deleted bool   `json:"deleted,omitempty"` // 本轮删除的文件或目录
+	Dir     bool   `json:"dir,omitempty"`     // 删除的是目录，不统计行数
+	Binary  bool   `json:"binary,omitempty"`  // 二进制或过大的文件，不统计行数
+}
+
+// ChangeSummary 一轮对话对工作目录的修改汇总，类似 git diff --stat。
+type ChangeSummary struct {
+	Files   []FileChange `json:"files"`
+	Added   int          `json:"added"`
+	Removed int          `json:"removed"`
+	Created int          `json:"created"`
+	Deleted int          `json:"deleted"`
+}
+
+// Text 返回 diffstat 风格的文本，每个文件一行，最后一行为合计。
+func (s *ChangeSummary) Text() string {
+	var sb strings.Builder
+	width := 0
+	for _, f := range s.Files {
+		width = max(width, len(f.Path))
+	}
+	for _, f := range s.Files {
+		fmt.Fprintf(&sb, "%-*s | ", width, f.Path)
+		switch {
+		case f.Dir:
+			sb.WriteString("目录")
+		case f.Binary:
+			sb.WriteString("二进制")
+		default:
+			fmt.Fprintf(&sb, "+%d -%d", f.Added, f.Removed)
+		}
+		switch {
+		case f.Created:
+			sb.WriteString("（新建）")
+		case f.Deleted:
+			sb.WriteString("（删除）")
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "%d 个文件，新建 %d 个，删除 %d 个，+%d -%d", len(s.Files), s.Created, s.Deleted, s.Added, s.Removed)
+	return sb.String()
+}
+
+// fileState 文件在本轮中的首次和最新状态。
+type fileState struct {
+	existed bool   // 本轮修改前是否存在
+	before  []byte // 本轮修改前的内容
+	exists  bool   // 最新是否存在
+	after   []byte // 最新内容
+	dir     bool   // 删除的是目录
+	large   bool   // 超过统计行数的大小上限
+}
+
+// Changes 记录一轮对话中工具对工作目录的修改，同一文件多次修改时按首次和最新的内容统计。
+// 可以在多个工具调用中并发使用。
+type Changes struct {
+	mu    sync.Mutex
+	files map[string]*fileState
+	order []string
+}
+
+// NewChanges 创建修改记录。
+func NewChanges() *Changes {
+	return &Changes{files: make(map[string]*fileState)}
+}
+
+// state 返回文件的状态，首次修改时以 before 作为本轮修改前的内容。
+func (c *Changes) state(path string, before []byte, existed bool) *fileState {
+	s, ok := c.files[path]
+	if !ok {
+		s = &fileState{existed: existed, before: before, large: len(before) > MaxDiffBytes || (existed && before == nil)}
+		c.files[path] = s
+		c.order = append(c.order, path)
+	}
+	return s
+}
+
+// RecordWrite 记录写入文件，before 为写入前的内容，existed 为写入前文件是否存在。
+// existed 为 true 而 before 为 nil 表示文件过大未读取，不统计行数。
+func (c *Changes) RecordWrite(path string, before []byte, existed bool, after []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.state(path, before, existed)
+	s.exists, s.after, s.dir = true, after, false
+	s.large = s.large || len(after) > MaxDiffBytes
+}
+
+// RecordDelete 记录删除文件或目录，before 为删除前的文件内容，目录为 nil。
+func (c *Changes) RecordDelete(path string, before []byte, dir bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.state(path, before, true)
+	s.exists, s.after, s.dir = false, nil, dir
+}
+
+// Summary 汇总本轮的修改，没有修改时返回 nil。先建后删和内容未变的文件不计入。
+func (c *Changes) Summary() *ChangeSummary {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var sum ChangeSummary
+	for _, path := range c.order {
+		s := c.files[path]
+		if !s.existed && !s.exists {
+			continue
+		}
+		if s.existed && s.exists && bytes.Equal(s.before, s.after) {
+			continue
+		}
+		f := FileChange{Path: path, Created: !s.existed, Deleted: !s.exists, Dir: s.dir && !s.exists}
+		switch {
+		case f.Dir:
+		case s.large || !isText(s.before) || !isText(s.after):
+			f.Binary = true
+		default:
+			f.Added, f.Removed = DiffLines(s.before, s.after)
+		}
+		sum.Files = append(sum.Files, f)
+		sum.Added += f.Added
+		sum.Removed += f.Removed
+		if f.Created {
+			sum.Created++
+		}
+		if f.Deleted {
+			sum.Deleted++
+		}
+	}
+	if len(sum.Files) == 0 {
+		return nil
+	}
+	return &sum
+}
+
+// isText 判断内容是否为文本。
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// DiffLines 逐行比较新旧内容，返回新增和删除的行数。
+func DiffLines(before, after []byte) (added, removed int) {
+	a, b := splitLines(before), splitLines(after)
+
+	// 去掉相同的开头和结尾，通常只剩下很小的修改区域
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	if len(a) == 0 || len(b) == 0 || len(a)*len(b) > maxDiffCells {
+		return len(b), len(a)
+	}
+
+	// 最长公共子序列之外的行即为新增和删除的行
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	common := prev[len(b)]
+	return len(b) - common, len(a) - common
+}
+
+// splitLines 按行拆分内容，末尾的换行不产生空行。
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// changesKey 修改记录的上下文键
+type changesKey struct{}
+
+// WithChanges 将本轮的修改记录注入上下文，修改文件的工具据此上报修改。
+func WithChanges(ctx context.Context, c *Changes) context.Context {
+	return context.WithValue(ctx, changesKey{}, c)
+}
+
+// GetChanges 从上下文提取修改记录，未设置时返回 nil，工具可据此跳过读取修改前的内容。
+func GetChanges(ctx context.Context) *Changes {
+	c, _ := ctx.Value(changesKey{}).(*Changes)
+	return c
+}
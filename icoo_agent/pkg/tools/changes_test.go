@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		added         int
+		removed       int
+	}{
+		{"new file", "", "a\nb\n", 2, 0},
+		{"emptied", "a\nb\n", "", 0, 2},
+		{"unchanged", "a\nb\n", "a\nb\n", 0, 0},
+		{"modify middle", "a\nb\nc\n", "a\nB\nc\n", 1, 1},
+		{"insert", "a\nc\n", "a\nb\nc\n", 1, 0},
+		{"moved line", "a\nb\nc\nd\n", "b\nc\na\nd\n", 1, 1},
+		{"missing final newline", "a\nb", "a\nb\n", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := DiffLines([]byte(tt.before), []byte(tt.after))
+			if added != tt.added || removed != tt.removed {
+				t.Errorf("DiffLines() = +%d -%d, want +%d -%d", added, removed, tt.added, tt.removed)
+			}
+		})
+	}
+}
+
+func TestChanges_Summary(t *testing.T) {
+	c := NewChanges()
+	if c.Summary() != nil {
+		t.Fatal("empty changes should have no summary")
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// 多次写入同一文件，按首次和最新的内容统计
+		c.RecordWrite("main.go", []byte("a\nb\n"), true, []byte("a\nB\n"))
+		c.RecordWrite("main.go", []byte("a\nB\n"), true, []byte("a\nB\nc\n"))
+	}()
+	go func() {
+		defer wg.Done()
+		c.RecordWrite("new.md", nil, false, []byte("# 标题\n"))
+	}()
+	wg.Wait()
+	c.RecordDelete("old.txt", []byte("x\ny\n"), false)
+	c.RecordDelete("build", nil, true)
+	c.RecordWrite("logo.png", []byte{}, false, []byte{0x89, 'P', 0})
+	// 先建后删、内容未变的文件不计入
+	c.RecordWrite("tmp.txt", nil, false, []byte("t"))
+	c.RecordDelete("tmp.txt", []byte("t"), false)
+	c.RecordWrite("same.txt", []byte("s"), true, []byte("s"))
+
+	s := c.Summary()
+	if s == nil || len(s.Files) != 5 {
+		t.Fatalf("Summary() = %+v", s)
+	}
+	want := []FileChange{
+		{Path: "main.go", Added: 2, Removed: 1},
+		{Path: "new.md", Added: 1, Created: true},
+		{Path: "old.txt", Removed: 2, Deleted: true},
+		{Path: "build", Deleted: true, Dir: true},
+		{Path: "logo.png", Created: true, Binary: true},
+	}
+	for _, w := range want {
+		var found bool
+		for _, f := range s.Files {
+			if f.Path == w.Path {
+				found = true
+				if f != w {
+					t.Errorf("change = %+v, want %+v", f, w)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("missing change for %s", w.Path)
+		}
+	}
+	if s.Added != 3 || s.Removed != 3 || s.Created != 2 || s.Deleted != 2 {
+		t.Errorf("totals = %+v", s)
+	}
+	if text := s.Text(); !strings.Contains(text, "main.go  | +2 -1") || !strings.HasSuffix(text, "5 个文件，新建 2 个，删除 2 个，+3 -3") {
+		t.Errorf("Text() = %q", text)
+	}
+}
+
+func TestGetChanges(t *testing.T) {
+	if GetChanges(context.Background()) != nil {
+		t.Error("GetChanges() without recorder should be nil")
+	}
+	c := NewChanges()
+	if GetChanges(WithChanges(context.Background(), c)) != c {
+		t.Error("GetChanges() should return the recorder")
+	}
+}
@@ -50,4 +50,24 @@ func GetSessionID(ctx context.Context) string {
 // Deprecated: Use GetSessionID instead.
 func GetChatID(ctx context.Context) string {
 	return GetSessionID(ctx)
-}
\ No newline at end of file
+}
+
+// senderKey is the context key for the message sender.
+type senderKey struct{}
+
+// Sender identifies the user whose message triggered the tool call.
+type Sender struct {
+	ID   string
+	Name string
+}
+
+// WithSender injects the message sender into a context.
+func WithSender(ctx context.Context, id, name string) context.Context {
+	return context.WithValue(ctx, senderKey{}, Sender{ID: id, Name: name})
+}
+
+// GetSender extracts the message sender from a context.
+func GetSender(ctx context.Context) Sender {
+	sender, _ := ctx.Value(senderKey{}).(Sender)
+	return sender
+}
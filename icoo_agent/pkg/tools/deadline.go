@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBudgetExceeded 本轮时间预算已用尽，区别于请求自身的超时。
+var ErrBudgetExceeded = errors.New("deadline exceeded by budget")
+
+// budgetKey 本轮时间预算截止时间的上下文键
+type budgetKey struct{}
+
+// WithBudgetDeadline 设置本轮时间预算的截止时间，返回在截止时取消的上下文。
+// 工具可以据此区分"请求自身超时"和"预算用尽"。
+func WithBudgetDeadline(ctx context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	ctx = context.WithValue(ctx, budgetKey{}, deadline)
+	return context.WithDeadline(ctx, deadline)
+}
+
+// BudgetDeadline 返回本轮时间预算的截止时间，未设置时返回 false。
+func BudgetDeadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(budgetKey{}).(time.Time)
+	return deadline, ok
+}
+
+// WithTimeout 返回取配置超时和上下文剩余时间较小者的上下文，timeout <= 0 表示只受上下文限制。
+// 工具的 HTTP 请求应使用该上下文，而不是 http.Client.Timeout，避免拖过本轮预算。
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// DeadlineError 将超时导致的请求错误转换为可区分的错误：
+// 预算截止时间已过时包装 ErrBudgetExceeded，否则报告请求超时；其他错误原样返回。
+func DeadlineError(ctx context.Context, timeout time.Duration, err error) error {
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	if deadline, ok := BudgetDeadline(ctx); ok && !time.Now().Before(deadline) {
+		return fmt.Errorf("%w: 本轮时间预算已用尽，请求被中止", ErrBudgetExceeded)
+	}
+	if timeout > 0 {
+		return fmt.Errorf("请求超时 (%s): %w", timeout, context.DeadlineExceeded)
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func slowServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	t.Cleanup(func() {
+		close(done)
+		srv.Close()
+	})
+	return srv
+}
+
+func get(ctx context.Context, url string, timeout time.Duration) error {
+	ctx, cancel := WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return DeadlineError(ctx, timeout, err)
+}
+
+func TestDeadlineError_Budget(t *testing.T) {
+	srv := slowServer(t)
+
+	ctx, cancel := WithBudgetDeadline(context.Background(), time.Now().Add(50*time.Millisecond))
+	defer cancel()
+
+	start := time.Now()
+	err := get(ctx, srv.URL, 10*time.Second)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request should stop at the budget deadline, took %s", elapsed)
+	}
+}
+
+func TestDeadlineError_Timeout(t *testing.T) {
+	srv := slowServer(t)
+
+	// 配置超时先于预算到期，报告为请求超时
+	ctx, cancel := WithBudgetDeadline(context.Background(), time.Now().Add(time.Minute))
+	defer cancel()
+
+	err := get(ctx, srv.URL, 50*time.Millisecond)
+	if err == nil || errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected plain timeout, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "请求超时") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDeadlineError_Passthrough(t *testing.T) {
+	other := errors.New("connection refused")
+	if err := DeadlineError(context.Background(), time.Second, other); err != other {
+		t.Errorf("unrelated errors should pass through, got %v", err)
+	}
+	if _, ok := BudgetDeadline(context.Background()); ok {
+		t.Error("budget deadline should be unset")
+	}
+}
@@ -0,0 +1,22 @@
+package tools
+
+import (
+	"context"
+	"time"
+)
+
+// locationKey 用户时区的上下文键
+type locationKey struct{}
+
+// WithLocation 将会话的用户时区注入上下文，时间相关的工具据此解析和展示时间。
+func WithLocation(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, locationKey{}, loc)
+}
+
+// GetLocation 从上下文提取用户时区，未设置时返回服务器本地时区。
+func GetLocation(ctx context.Context) *time.Location {
+	if loc, _ := ctx.Value(locationKey{}).(*time.Location); loc != nil {
+		return loc
+	}
+	return time.Local
+}
@@ -0,0 +1,27 @@
+package tools
+
+import "context"
+
+// 实时输出流名称
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// OutputFunc 工具执行期间的实时输出回调，stream 为 stdout 或 stderr。
+// 回调可能在工具内部的协程中调用，但同一次工具执行内不会并发调用。
+type OutputFunc func(stream, chunk string)
+
+// outputKey 实时输出回调的上下文键
+type outputKey struct{}
+
+// WithOutput 将实时输出回调注入上下文，支持增量输出的工具（如 shell_command）据此上报进度。
+func WithOutput(ctx context.Context, fn OutputFunc) context.Context {
+	return context.WithValue(ctx, outputKey{}, fn)
+}
+
+// GetOutput 从上下文提取实时输出回调，未设置时返回 nil。
+func GetOutput(ctx context.Context) OutputFunc {
+	fn, _ := ctx.Value(outputKey{}).(OutputFunc)
+	return fn
+}
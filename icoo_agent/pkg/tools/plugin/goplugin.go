@@ -0,0 +1,31 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package plugin
+
+import (
+	"fmt"
+	goplugin "plugin"
+
+	"icooclaw/pkg/tools"
+)
+
+// openGoPlugin 加载 Go 插件，导出符号可以是 func() tools.Tool 或 tools.Tool 类型的变量。
+func openGoPlugin(m *Manifest) (tools.Tool, error) {
+	p, err := goplugin.Open(m.resolve(m.Path))
+	if err != nil {
+		return nil, fmt.Errorf("加载 Go 插件失败（插件需用相同版本的 Go 和本模块构建）: %w", err)
+	}
+	sym, err := p.Lookup(m.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("Go 插件缺少导出符号 %s: %w", m.Symbol, err)
+	}
+
+	switch v := sym.(type) {
+	case func() tools.Tool:
+		return v(), nil
+	case *tools.Tool:
+		return *v, nil
+	default:
+		return nil, fmt.Errorf("Go 插件符号 %s 的类型 %T 无效，应为 func() tools.Tool", m.Symbol, sym)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package plugin
+
+import (
+	"fmt"
+	"runtime"
+
+	"icooclaw/pkg/tools"
+)
+
+// openGoPlugin 当前平台不支持 Go 插件。
+func openGoPlugin(m *Manifest) (tools.Tool, error) {
+	return nil, fmt.Errorf("%s 平台不支持 Go 插件，请改用 process 类型", runtime.GOOS)
+}
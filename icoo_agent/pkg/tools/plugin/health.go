@@ -0,0 +1,208 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"icooclaw/pkg/tools"
+)
+
+// HealthConfig 插件健康检查配置。
+type HealthConfig struct {
+	// Interval 检查运行失败的间隔
+	Interval time.Duration
+	// SelfTestInterval 执行自检的间隔，0 表示只在启动时自检
+	SelfTestInterval time.Duration
+	// FailureThreshold 对话中连续失败多少次后停用，0 表示不按运行失败停用
+	FailureThreshold int
+}
+
+// Disabled 被停用的插件工具。
+type Disabled struct {
+	Name       string    `json:"name"`
+	Reason     string    `json:"reason"`
+	SelfTest   bool      `json:"self_test"` // 因自检失败停用，自检恢复后自动启用
+	DisabledAt time.Time `json:"disabled_at"`
+}
+
+// Monitor 检查插件工具是否仍然可用：定期执行清单声明的自检，并根据工具统计发现对话中反复失败的插件。
+// 不可用的插件从注册表移除并通知，避免模型在每轮对话中继续调用；自检恢复后重新注册。
+type Monitor struct {
+	registry *tools.Registry
+	cfg      HealthConfig
+	logger   *slog.Logger
+	notify   func(Disabled)
+
+	mu       sync.Mutex
+	tools    map[string]*Tool    // 受监控的插件工具
+	disabled map[string]Disabled // 已停用的插件
+	tested   time.Time           // 上次自检时间
+}
+
+// NewMonitor 监控已注册到 registry 的插件工具，names 为 Register 返回的工具名称。
+func NewMonitor(registry *tools.Registry, names []string, cfg HealthConfig, logger *slog.Logger) *Monitor {
+	m := &Monitor{
+		registry: registry,
+		cfg:      cfg,
+		logger:   logger.With("name", "【插件】"),
+		tools:    make(map[string]*Tool),
+		disabled: make(map[string]Disabled),
+	}
+	for _, name := range names {
+		if t, ok := registry.GetOK(name); ok {
+			if tool, ok := t.(*Tool); ok {
+				m.tools[name] = tool
+			}
+		}
+	}
+	return m
+}
+
+// OnDisable 设置插件被停用时的通知。
+func (m *Monitor) OnDisable(fn func(Disabled)) *Monitor {
+	m.notify = fn
+	return m
+}
+
+// Disabled 返回已停用的插件，按名称排序。
+func (m *Monitor) Disabled() []Disabled {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]Disabled, 0, len(m.disabled))
+	for _, d := range m.disabled {
+		result = append(result, d)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Run 启动时自检一次，之后定期检查，直到 ctx 取消。
+func (m *Monitor) Run(ctx context.Context) {
+	m.Check(ctx)
+	if m.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Check 检查运行失败，到达自检间隔时执行自检。
+func (m *Monitor) Check(ctx context.Context) {
+	m.checkFailures()
+
+	m.mu.Lock()
+	due := m.tested.IsZero() || (m.cfg.SelfTestInterval > 0 && time.Since(m.tested) >= m.cfg.SelfTestInterval)
+	if due {
+		m.tested = time.Now()
+	}
+	m.mu.Unlock()
+	if due {
+		m.selfTest(ctx)
+	}
+}
+
+// checkFailures 停用对话中连续失败达到阈值的插件。
+func (m *Monitor) checkFailures() {
+	if m.cfg.FailureThreshold <= 0 {
+		return
+	}
+	for _, st := range m.registry.Stats().Snapshot() {
+		if _, ok := m.tools[st.Name]; !ok || st.ConsecutiveErr < m.cfg.FailureThreshold {
+			continue
+		}
+		m.disable(st.Name, fmt.Sprintf("连续 %d 次调用失败，最近错误: %s", st.ConsecutiveErr, st.LastError), false)
+	}
+}
+
+// selfTest 执行所有插件的自检，停用失败的插件，恢复因自检失败停用但已通过自检的插件。
+func (m *Monitor) selfTest(ctx context.Context) {
+	names := make([]string, 0, len(m.tools))
+	for name, tool := range m.tools {
+		if tool.manifest.SelfTest != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return
+		}
+		err := runSelfTest(ctx, m.tools[name])
+		if err != nil {
+			m.disable(name, "自检失败: "+err.Error(), true)
+			continue
+		}
+		m.enable(name)
+	}
+}
+
+// runSelfTest 执行一次自检调用，不经过注册表，不计入工具统计。
+func runSelfTest(ctx context.Context, tool *Tool) error {
+	test := tool.manifest.SelfTest
+	args := test.Args
+	if args == nil {
+		args = map[string]any{}
+	}
+	result := tool.Execute(ctx, args)
+	switch {
+	case result.Error != nil:
+		return result.Error
+	case !result.Success:
+		return fmt.Errorf("调用未成功")
+	case test.Expect != "" && !strings.Contains(result.Content, test.Expect):
+		return fmt.Errorf("结果不包含 %q", test.Expect)
+	default:
+		return nil
+	}
+}
+
+// disable 从注册表移除插件并通知，已停用的插件不重复处理。
+func (m *Monitor) disable(name, reason string, selfTest bool) {
+	m.mu.Lock()
+	if _, ok := m.disabled[name]; ok {
+		m.mu.Unlock()
+		return
+	}
+	d := Disabled{Name: name, Reason: reason, SelfTest: selfTest, DisabledAt: time.Now()}
+	m.disabled[name] = d
+	m.mu.Unlock()
+
+	m.registry.Unregister(name)
+	m.logger.Error("插件工具不可用，已停用", "tool", name, "reason", reason)
+	if m.notify != nil {
+		m.notify(d)
+	}
+}
+
+// enable 重新注册因自检失败停用的插件，并清除其统计。
+func (m *Monitor) enable(name string) {
+	m.mu.Lock()
+	d, ok := m.disabled[name]
+	if !ok || !d.SelfTest {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.disabled, name)
+	m.mu.Unlock()
+
+	tool := m.tools[name]
+	m.registry.Stats().Reset(name)
+	m.registry.Register(tool)
+	m.logger.Info("插件工具自检通过，已重新启用", "tool", name)
+}
@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"icooclaw/pkg/tools"
+)
+
+func TestMonitor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("测试脚本依赖 /bin/sh")
+	}
+
+	dir := t.TempDir()
+	flag := filepath.Join(t.TempDir(), "broken")
+	if err := os.WriteFile(flag, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 标记文件存在时返回旧接口的错误，删除后恢复
+	writePlugin(t, dir, "api", `{
+		"name": "api",
+		"description": "api",
+		"type": "process",
+		"command": ["./run.sh"],
+		"self_test": {"args": {"q": "ping"}, "expect": "pong"}
+	}`, `if [ -e '`+flag+`' ]; then echo '{"error": "unknown field"}'; else echo pong; fi`)
+	writePlugin(t, dir, "flaky", `{"name": "flaky", "description": "flaky", "type": "process", "command": ["./run.sh"]}`,
+		`echo '{"error": "410 gone"}'`)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry := tools.NewRegistry()
+	names := Register(registry, dir, t.TempDir(), logger)
+
+	var notified []Disabled
+	monitor := NewMonitor(registry, names, HealthConfig{FailureThreshold: 2}, logger).
+		OnDisable(func(d Disabled) { notified = append(notified, d) })
+
+	// 启动时自检失败的插件被停用
+	ctx := context.Background()
+	monitor.Check(ctx)
+	if _, ok := registry.GetOK("api"); ok {
+		t.Fatal("自检失败的插件应从注册表移除")
+	}
+	if len(notified) != 1 || notified[0].Name != "api" || !notified[0].SelfTest {
+		t.Fatalf("notified = %+v", notified)
+	}
+
+	// 对话中连续失败达到阈值的插件被停用，自检不会恢复
+	registry.Execute(ctx, "flaky", nil)
+	registry.Execute(ctx, "flaky", nil)
+	monitor.Check(ctx)
+	if _, ok := registry.GetOK("flaky"); ok {
+		t.Fatal("反复失败的插件应从注册表移除")
+	}
+	if got := monitor.Disabled(); len(got) != 2 || got[0].Name != "api" || got[1].Name != "flaky" || got[1].SelfTest {
+		t.Fatalf("Disabled() = %+v", got)
+	}
+
+	// 自检恢复后重新启用
+	if err := os.Remove(flag); err != nil {
+		t.Fatal(err)
+	}
+	monitor.selfTest(ctx)
+	if _, ok := registry.GetOK("api"); !ok {
+		t.Fatal("自检通过的插件应重新注册")
+	}
+	if got := monitor.Disabled(); len(got) != 1 || got[0].Name != "flaky" {
+		t.Errorf("Disabled() = %+v", got)
+	}
+	if len(notified) != 2 {
+		t.Errorf("notified = %+v", notified)
+	}
+}
@@ -0,0 +1,163 @@
+// Package plugin 从清单加载编译型的扩展工具，团队无需修改本仓库或用 JS 重写即可发布私有工具。
+//
+// 每个工具位于插件目录下的独立子目录，由 manifest.json 描述名称、参数模式和权限，支持两种运行方式：
+//   - process: 每次调用启动一个辅助进程，通过标准输入输出交换 JSON，跨平台、与宿主版本无关；
+//   - go_plugin: 加载 Go 插件 .so，在进程内执行，只支持 Linux/macOS/FreeBSD，且必须用相同版本的 Go 和本模块构建。
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// ManifestFile 插件子目录中的清单文件名
+const ManifestFile = "manifest.json"
+
+// 插件类型。
+const (
+	// TypeProcess 辅助进程
+	TypeProcess = "process"
+	// TypeGoPlugin Go 插件 .so
+	TypeGoPlugin = "go_plugin"
+)
+
+// 工作目录访问权限。
+const (
+	WorkspaceNone  = "none"
+	WorkspaceRead  = "read"
+	WorkspaceWrite = "write"
+)
+
+// defaultTimeout 清单未指定超时时的单次调用超时
+const defaultTimeout = 30 * time.Second
+
+// defaultSymbol Go 插件导出的构造函数名
+const defaultSymbol = "NewTool"
+
+// toolName 工具名称格式，与提供商的函数名要求一致
+var toolName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Manifest 插件工具清单。
+type Manifest struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`         // 参数属性，格式同 Tool.Parameters
+	Type        string         `json:"type"`               // process 或 go_plugin
+	Command     []string       `json:"command,omitempty"`  // process: 可执行文件及参数，相对路径基于清单目录
+	Path        string         `json:"path,omitempty"`     // go_plugin: .so 文件路径，相对路径基于清单目录
+	Symbol      string         `json:"symbol,omitempty"`   // go_plugin: 导出的 func() tools.Tool，默认 NewTool
+	Timeout     string         `json:"timeout,omitempty"`  // 单次调用超时，如 "30s"
+	Optional    bool           `json:"optional,omitempty"` // 注册为可选工具，只有会话工具策略启用时才可用
+	Permissions Permissions    `json:"permissions"`
+	SelfTest    *SelfTest      `json:"self_test,omitempty"` // 自检调用，启用插件健康检查时定期执行
+
+	dir     string        // 清单所在目录
+	timeout time.Duration // 解析后的超时
+}
+
+// Permissions 插件声明需要的权限。
+//
+// process 插件由宿主强制执行：只继承 Env 中列出的环境变量，工作目录权限决定进程的当前目录；
+// go_plugin 在进程内运行无法隔离，权限仅作声明，加载前需确认插件可信。
+type Permissions struct {
+	// Env 需要继承的环境变量，支持通配符，如 JIRA_*
+	Env []string `json:"env,omitempty"`
+	// Workspace 工作目录访问权限：none（默认）、read 或 write
+	Workspace string `json:"workspace,omitempty"`
+	// Network 是否需要访问网络，仅作声明，便于审查
+	Network bool `json:"network,omitempty"`
+}
+
+// SelfTest 插件的自检调用。依赖外部接口的插件可以声明一次无副作用的调用，
+// 接口变更或凭证失效时自检失败，插件被停用，而不是在对话中反复失败。
+type SelfTest struct {
+	// Args 调用参数
+	Args map[string]any `json:"args,omitempty"`
+	// Expect 结果应包含的文本，为空时只要求调用成功
+	Expect string `json:"expect,omitempty"`
+}
+
+// Dir 清单所在目录。
+func (m *Manifest) Dir() string {
+	return m.dir
+}
+
+// Validate 检查清单并补全默认值。
+func (m *Manifest) Validate() error {
+	if !toolName.MatchString(m.Name) {
+		return fmt.Errorf("插件工具名称 %q 无效，只能包含字母、数字、_ 和 -", m.Name)
+	}
+	if m.Description == "" {
+		return fmt.Errorf("插件工具 %s 缺少 description", m.Name)
+	}
+	if m.Parameters == nil {
+		m.Parameters = map[string]any{}
+	}
+
+	switch m.Type {
+	case TypeProcess:
+		if len(m.Command) == 0 || m.Command[0] == "" {
+			return fmt.Errorf("插件工具 %s 缺少 command", m.Name)
+		}
+	case TypeGoPlugin:
+		if m.Path == "" {
+			return fmt.Errorf("插件工具 %s 缺少 path", m.Name)
+		}
+		if m.Symbol == "" {
+			m.Symbol = defaultSymbol
+		}
+	default:
+		return fmt.Errorf("插件工具 %s 的类型 %q 无效，应为 process 或 go_plugin", m.Name, m.Type)
+	}
+
+	m.timeout = defaultTimeout
+	if m.Timeout != "" {
+		d, err := time.ParseDuration(m.Timeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("插件工具 %s 的 timeout %q 无效", m.Name, m.Timeout)
+		}
+		m.timeout = d
+	}
+
+	switch m.Permissions.Workspace {
+	case "":
+		m.Permissions.Workspace = WorkspaceNone
+	case WorkspaceNone, WorkspaceRead, WorkspaceWrite:
+	default:
+		return fmt.Errorf("插件工具 %s 的 permissions.workspace %q 无效，应为 none、read 或 write", m.Name, m.Permissions.Workspace)
+	}
+	return nil
+}
+
+// resolve 将清单中的相对路径解析为基于清单目录的路径。
+func (m *Manifest) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(m.dir, filepath.FromSlash(path))
+}
+
+// ReadManifest 读取并校验清单文件。
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取插件清单失败: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("解析插件清单 %s 失败: %w", path, err)
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("获取插件目录失败: %w", err)
+	}
+	m.dir = dir
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"icooclaw/pkg/tools"
+)
+
+// Tool 由清单描述的插件工具，名称、描述和参数以清单为准。
+type Tool struct {
+	manifest *Manifest
+	run      func(ctx context.Context, args map[string]any) *tools.Result
+}
+
+// New 根据清单创建插件工具，workspace 为宿主工作目录。
+func New(m *Manifest, workspace string) (*Tool, error) {
+	switch m.Type {
+	case TypeProcess:
+		runner, err := newProcessRunner(m, workspace)
+		if err != nil {
+			return nil, err
+		}
+		return &Tool{manifest: m, run: runner.run}, nil
+	case TypeGoPlugin:
+		impl, err := openGoPlugin(m)
+		if err != nil {
+			return nil, err
+		}
+		return &Tool{manifest: m, run: impl.Execute}, nil
+	default:
+		return nil, fmt.Errorf("插件工具 %s 的类型 %q 无效", m.Name, m.Type)
+	}
+}
+
+// Manifest 返回工具清单。
+func (t *Tool) Manifest() *Manifest {
+	return t.manifest
+}
+
+// Name returns the tool name.
+func (t *Tool) Name() string {
+	return t.manifest.Name
+}
+
+// Description returns the tool description.
+func (t *Tool) Description() string {
+	return t.manifest.Description
+}
+
+// Parameters returns the tool parameters.
+func (t *Tool) Parameters() map[string]any {
+	return t.manifest.Parameters
+}
+
+// Execute 在清单超时内执行工具。
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	ctx, cancel := context.WithTimeout(ctx, t.manifest.timeout)
+	defer cancel()
+	return t.run(ctx, args)
+}
+
+// DescribeChange 实现 tools.Mutator：拥有工作目录写权限的插件在只读工作目录中不执行。
+func (t *Tool) DescribeChange(ctx context.Context, args map[string]any) (string, bool) {
+	if t.manifest.Permissions.Workspace != WorkspaceWrite {
+		return "", false
+	}
+	params, _ := json.Marshal(args)
+	return fmt.Sprintf("以工作目录写权限运行插件工具 %s，参数: %s", t.manifest.Name, params), true
+}
+
+// Discover 读取插件目录下所有子目录中的清单，按目录名排序。目录不存在时返回空列表。
+// 单个清单无效不影响其他插件，错误一并返回。
+func Discover(dir string) ([]*Manifest, []error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{fmt.Errorf("读取插件目录失败: %w", err)}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var (
+		manifests []*Manifest
+		errs      []error
+	)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name(), ManifestFile)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		m, err := ReadManifest(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, errs
+}
+
+// Register 加载插件目录中的工具并注册，与已注册工具同名的插件会被跳过。
+// 返回成功注册的工具名称，失败的插件只记录日志。
+func Register(registry *tools.Registry, dir, workspace string, logger *slog.Logger) []string {
+	log := logger.With("name", "【插件】")
+
+	manifests, errs := Discover(dir)
+	for _, err := range errs {
+		log.Error("加载插件清单失败", "error", err)
+	}
+
+	var names []string
+	for _, m := range manifests {
+		if _, exists := registry.GetOK(m.Name); exists {
+			log.Error("插件工具与已有工具同名，已跳过", "tool", m.Name, "dir", m.Dir())
+			continue
+		}
+		tool, err := New(m, workspace)
+		if err != nil {
+			log.Error("加载插件工具失败", "tool", m.Name, "error", err)
+			continue
+		}
+		registry.Register(tool)
+		if m.Optional {
+			registry.SetOptional(m.Name)
+		}
+		log.Info("插件工具已注册",
+			"tool", m.Name,
+			"type", m.Type,
+			"workspace", m.Permissions.Workspace,
+			"env", m.Permissions.Env,
+			"network", m.Permissions.Network)
+		names = append(names, m.Name)
+	}
+	return names
+}
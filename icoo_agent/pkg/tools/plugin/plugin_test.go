@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/tools"
+)
+
+// stubTool 与插件同名的已注册工具。
+type stubTool struct{}
+
+func (stubTool) Name() string               { return "builtin" }
+func (stubTool) Description() string        { return "builtin" }
+func (stubTool) Parameters() map[string]any { return map[string]any{} }
+func (stubTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	return tools.SuccessResult("builtin")
+}
+
+// writePlugin 在插件目录下创建一个插件子目录。
+func writePlugin(t *testing.T, dir, name, manifest, script string) {
+	t.Helper()
+	pluginDir := filepath.Join(dir, name)
+	if err := os.MkdirAll(pluginDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, ManifestFile), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if script != "" {
+		if err := os.WriteFile(filepath.Join(pluginDir, "run.sh"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestManifestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest Manifest
+		wantErr  bool
+	}{
+		{"process", Manifest{Name: "jira_search", Description: "d", Type: TypeProcess, Command: []string{"./run"}}, false},
+		{"go_plugin", Manifest{Name: "jira", Description: "d", Type: TypeGoPlugin, Path: "jira.so"}, false},
+		{"bad name", Manifest{Name: "jira search", Description: "d", Type: TypeProcess, Command: []string{"./run"}}, true},
+		{"no command", Manifest{Name: "jira", Description: "d", Type: TypeProcess}, true},
+		{"bad type", Manifest{Name: "jira", Description: "d", Type: "wasm"}, true},
+		{"bad timeout", Manifest{Name: "jira", Description: "d", Type: TypeProcess, Command: []string{"x"}, Timeout: "soon"}, true},
+		{"bad workspace", Manifest{Name: "jira", Description: "d", Type: TypeProcess, Command: []string{"x"},
+			Permissions: Permissions{Workspace: "admin"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.manifest
+			err := m.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (m.timeout != defaultTimeout || m.Permissions.Workspace != WorkspaceNone) {
+				t.Errorf("默认值未补全: %+v", m)
+			}
+		})
+	}
+}
+
+func TestRegister_Process(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("测试脚本依赖 /bin/sh")
+	}
+	t.Setenv("PLUGIN_VISIBLE", "yes")
+	t.Setenv("PLUGIN_HIDDEN", "secret")
+
+	dir := t.TempDir()
+	workspace := t.TempDir()
+	writePlugin(t, dir, "echo", `{
+		"name": "echo_request",
+		"description": "echo",
+		"type": "process",
+		"command": ["./run.sh"],
+		"permissions": {"env": ["PLUGIN_VIS*"], "workspace": "read"}
+	}`, `cat; printf '\n%s|%s|%s|%s' "$ICOOCLAW_TOOL" "$PLUGIN_VISIBLE" "$PLUGIN_HIDDEN" "$(pwd)"`)
+	writePlugin(t, dir, "fail", `{"name": "fail", "description": "fail", "type": "process", "command": ["./run.sh"], "optional": true}`,
+		`echo '{"error": "quota exceeded"}'`)
+	writePlugin(t, dir, "slow", `{"name": "slow", "description": "slow", "type": "process", "command": ["./run.sh"], "timeout": "100ms"}`,
+		`exec sleep 5`)
+	writePlugin(t, dir, "clash", `{"name": "builtin", "description": "d", "type": "process", "command": ["./run.sh"]}`, `echo hi`)
+	writePlugin(t, dir, "broken", `{"name": "broken"}`, "")
+
+	registry := tools.NewRegistry()
+	registry.Register(&stubTool{})
+	names := Register(registry, dir, workspace, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if got := strings.Join(names, ","); got != "echo_request,fail,slow" {
+		t.Fatalf("Register() = %s", got)
+	}
+	if !registry.IsOptional("fail") {
+		t.Error("optional 插件应注册为可选工具")
+	}
+
+	ctx := tools.WithPolicy(context.Background(), tools.Policy{Enable: []string{"fail"}})
+	result := registry.ExecuteWithContext(ctx, "echo_request", map[string]any{"q": "go"}, "websocket", "s1", nil)
+	if !result.Success {
+		t.Fatalf("echo_request error = %v", result.Error)
+	}
+	for _, want := range []string{`"arguments":{"q":"go"}`, `"session_id":"s1"`, `"workspace":"` + workspace + `"`,
+		"echo_request|yes||" + workspace} {
+		if !strings.Contains(result.Content, want) {
+			t.Errorf("输出缺少 %q:\n%s", want, result.Content)
+		}
+	}
+
+	if result := registry.Execute(ctx, "fail", nil); result.Success || result.Error.Error() != "quota exceeded" {
+		t.Errorf("fail = %+v", result)
+	}
+	if result := registry.Execute(ctx, "slow", nil); result.Success || !strings.Contains(result.Error.Error(), "超时") {
+		t.Errorf("slow = %+v", result)
+	}
+}
+
+func TestDiscover_MissingDir(t *testing.T) {
+	manifests, errs := Discover(filepath.Join(t.TempDir(), "none"))
+	if len(manifests) != 0 || len(errs) != 0 {
+		t.Errorf("插件目录不存在时应返回空列表, got %v %v", manifests, errs)
+	}
+}
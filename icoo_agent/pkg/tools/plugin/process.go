@@ -0,0 +1,175 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/tools/builtin/shell"
+)
+
+// processWaitDelay 超时后等待进程输出关闭的时间
+const processWaitDelay = 2 * time.Second
+
+// stderrTail 失败时附带的标准错误输出长度
+const stderrTail = 2048
+
+// baseEnv 辅助进程始终继承的系统环境变量，保证解释器和运行时能正常启动
+var baseEnv = []string{
+	"PATH", "HOME", "USER", "LANG", "LC_*", "TZ", "TMPDIR", "TEMP", "TMP",
+	"SystemRoot", "SystemDrive", "ComSpec", "PATHEXT", "USERPROFILE", "APPDATA", "LOCALAPPDATA",
+}
+
+// Request 宿主写入辅助进程标准输入的调用请求。
+type Request struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+	Channel   string         `json:"channel,omitempty"`
+	SessionID string         `json:"session_id,omitempty"`
+	Workspace string         `json:"workspace,omitempty"` // 未授予工作目录权限时为空
+	Vars      tools.Vars     `json:"vars,omitempty"`      // 会话变量
+}
+
+// Response 辅助进程写入标准输出的结果。标准输出不是 JSON 对象时整体作为 content。
+type Response struct {
+	Content string `json:"content"`
+	Error   string `json:"error,omitempty"`
+}
+
+// processRunner 通过辅助进程执行一次工具调用。
+type processRunner struct {
+	manifest  *Manifest
+	workspace string // 默认工作目录的绝对路径，未授予工作目录权限时为空
+}
+
+// newProcessRunner 创建辅助进程执行器。
+func newProcessRunner(m *Manifest, workspace string) (*processRunner, error) {
+	if m.Permissions.Workspace != WorkspaceNone {
+		abs, err := filepath.Abs(workspace)
+		if err != nil {
+			return nil, fmt.Errorf("获取工作目录绝对路径失败: %w", err)
+		}
+		workspace = abs
+	} else {
+		workspace = ""
+	}
+	return &processRunner{manifest: m, workspace: workspace}, nil
+}
+
+// command 解析可执行文件：包含路径分隔符的按清单目录解析，否则在 PATH 中查找。
+func (r *processRunner) command() string {
+	name := r.manifest.Command[0]
+	if strings.ContainsAny(name, `/\`) {
+		return r.manifest.resolve(name)
+	}
+	return name
+}
+
+// workspaceFor 返回本次调用可访问的工作目录：未授予权限时为空，否则为会话选择的工作目录。
+func (r *processRunner) workspaceFor(ctx context.Context) string {
+	if r.workspace == "" {
+		return ""
+	}
+	dir := tools.GetWorkspace(ctx, r.workspace)
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return dir
+}
+
+// env 辅助进程的环境变量：系统变量、清单声明的变量和宿主注入的调用信息。
+func (r *processRunner) env(workspace string) []string {
+	extra := map[string]string{
+		"ICOOCLAW_TOOL":             r.manifest.Name,
+		"ICOOCLAW_PLUGIN_DIR":       r.manifest.dir,
+		"ICOOCLAW_WORKSPACE_ACCESS": r.manifest.Permissions.Workspace,
+	}
+	if workspace != "" {
+		extra["ICOOCLAW_WORKSPACE"] = workspace
+	}
+	cfg := shell.EnvConfig{
+		Allow: append(append([]string(nil), baseEnv...), r.manifest.Permissions.Env...),
+		Extra: extra,
+	}
+	return cfg.Build(os.Environ(), nil)
+}
+
+// run 执行一次调用。
+func (r *processRunner) run(ctx context.Context, args map[string]any) *tools.Result {
+	workspace := r.workspaceFor(ctx)
+	input, err := json.Marshal(Request{
+		Tool:      r.manifest.Name,
+		Arguments: args,
+		Channel:   tools.GetChannel(ctx),
+		SessionID: tools.GetSessionID(ctx),
+		Workspace: workspace,
+		Vars:      tools.GetVars(ctx),
+	})
+	if err != nil {
+		return &tools.Result{Success: false, Error: fmt.Errorf("序列化插件请求失败: %w", err)}
+	}
+
+	cmd := exec.CommandContext(ctx, r.command(), r.manifest.Command[1:]...)
+	cmd.Dir = r.manifest.dir
+	if workspace != "" {
+		cmd.Dir = workspace
+	}
+	cmd.Env = r.env(workspace)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.WaitDelay = processWaitDelay
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return &tools.Result{Success: false, Error: fmt.Errorf("插件工具 %s 执行超时 (%s)", r.manifest.Name, r.manifest.timeout)}
+		}
+		return &tools.Result{Success: false, Error: fmt.Errorf("插件工具 %s 执行失败: %w%s", r.manifest.Name, err, tail(stderr.String()))}
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	resp, ok := parseResponse(out)
+	if !ok {
+		return tools.SuccessResult(string(out))
+	}
+	if resp.Error != "" {
+		return &tools.Result{Success: false, Content: resp.Content, Error: errors.New(resp.Error)}
+	}
+	return tools.SuccessResult(resp.Content)
+}
+
+// parseResponse 解析包含 content 或 error 字段的 JSON 对象，其他输出按纯文本处理。
+func parseResponse(out []byte) (Response, bool) {
+	var fields map[string]json.RawMessage
+	if len(out) == 0 || out[0] != '{' || json.Unmarshal(out, &fields) != nil {
+		return Response{}, false
+	}
+	_, hasContent := fields["content"]
+	_, hasError := fields["error"]
+	var resp Response
+	if !(hasContent || hasError) || json.Unmarshal(out, &resp) != nil {
+		return Response{}, false
+	}
+	return resp, true
+}
+
+// tail 返回标准错误输出的末尾部分，用于错误信息。
+func tail(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	if len(s) > stderrTail {
+		s = "..." + strings.ToValidUTF8(s[len(s)-stderrTail:], "")
+	}
+	return "\n" + s
+}
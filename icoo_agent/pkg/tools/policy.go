@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"context"
+	"path"
+	"slices"
+)
+
+// PolicyMetadataKey 会话元数据中保存工具策略的键
+const PolicyMetadataKey = "tools"
+
+// Policy 会话级工具策略，名称支持 path.Match 通配符（如 kv_*）。
+//
+// Deny 优先于 Allow；Allow 非空时只允许匹配的工具；
+// Enable 用于启用注册为可选（默认不提供）的工具。
+// Scope 为处理消息的人设限定的工具集，不随会话保存，Enable 也不能越过该范围。
+type Policy struct {
+	Allow  []string `json:"allow,omitempty"`  // 白名单，为空表示不限制
+	Deny   []string `json:"deny,omitempty"`   // 黑名单
+	Enable []string `json:"enable,omitempty"` // 额外启用的可选工具
+	Scope  []string `json:"-"`                // 人设的工具集，为空表示不限制
+}
+
+// IsZero 是否未设置任何规则。
+func (p Policy) IsZero() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0 && len(p.Enable) == 0 && len(p.Scope) == 0
+}
+
+// Permits 判断工具是否可用，optional 表示该工具是否为可选工具。
+func (p Policy) Permits(name string, optional bool) bool {
+	if matchAny(p.Deny, name) {
+		return false
+	}
+	if len(p.Scope) > 0 && !matchAny(p.Scope, name) {
+		return false
+	}
+	if optional && !matchAny(p.Enable, name) {
+		return false
+	}
+	if len(p.Allow) > 0 && !matchAny(p.Allow, name) && !(optional && matchAny(p.Enable, name)) {
+		return false
+	}
+	return true
+}
+
+// matchAny 名称是否匹配任一模式。
+func matchAny(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, err := path.Match(pattern, name)
+		return ok && err == nil
+	})
+}
+
+// policyKey 工具策略的上下文键
+type policyKey struct{}
+
+// WithPolicy 将会话工具策略注入上下文，Registry 执行工具时据此校验。
+func WithPolicy(ctx context.Context, p Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, p)
+}
+
+// GetPolicy 从上下文提取会话工具策略，未设置时返回零值策略。
+func GetPolicy(ctx context.Context) Policy {
+	p, _ := ctx.Value(policyKey{}).(Policy)
+	return p
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"testing"
+)
+
+type namedTool struct{ name string }
+
+func (t namedTool) Name() string               { return t.name }
+func (t namedTool) Description() string        { return t.name }
+func (t namedTool) Parameters() map[string]any { return map[string]any{} }
+func (t namedTool) Execute(ctx context.Context, args map[string]any) *Result {
+	return SuccessResult("ok")
+}
+
+func TestPolicyPermits(t *testing.T) {
+	cases := []struct {
+		policy   Policy
+		name     string
+		optional bool
+		want     bool
+	}{
+		{Policy{}, "read_file", false, true},
+		{Policy{}, "shell_command", true, false},
+		{Policy{Deny: []string{"shell_*"}}, "shell_command", false, false},
+		{Policy{Allow: []string{"read_file", "kv_*"}}, "kv_get", false, true},
+		{Policy{Allow: []string{"read_file"}}, "write_file", false, false},
+		{Policy{Enable: []string{"shell_command"}}, "shell_command", true, true},
+		{Policy{Allow: []string{"read_file"}, Enable: []string{"shell_command"}}, "shell_command", true, true},
+		{Policy{Deny: []string{"shell_command"}, Enable: []string{"shell_command"}}, "shell_command", true, false},
+		{Policy{Scope: []string{"kv_*"}}, "kv_get", false, true},
+		{Policy{Scope: []string{"kv_*"}, Allow: []string{"read_file"}}, "read_file", false, false},
+		{Policy{Scope: []string{"kv_*"}, Enable: []string{"shell_command"}}, "shell_command", true, false},
+	}
+	for _, c := range cases {
+		if got := c.policy.Permits(c.name, c.optional); got != c.want {
+			t.Errorf("%+v.Permits(%q, %v) = %v, want %v", c.policy, c.name, c.optional, got, c.want)
+		}
+	}
+}
+
+func TestRegistryPolicy(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{"read_file", "write_file", "shell_command"} {
+		r.Register(namedTool{name: name})
+	}
+	r.SetOptional("shell_command")
+
+	if defs := r.ToProviderDefs(); len(defs) != 2 {
+		t.Errorf("expected optional tool to be hidden, got %d definitions", len(defs))
+	}
+
+	policy := Policy{Deny: []string{"write_file"}, Enable: []string{"shell_command"}}
+	defs := r.ToProviderDefsFor(policy)
+	if len(defs) != 2 || defs[0].Function.Name != "read_file" || defs[1].Function.Name != "shell_command" {
+		t.Errorf("unexpected definitions: %+v", defs)
+	}
+
+	ctx := WithPolicy(context.Background(), policy)
+	if res := r.Execute(ctx, "write_file", nil); res.Error == nil {
+		t.Error("expected denied tool to be rejected")
+	}
+	if res := r.Execute(ctx, "shell_command", nil); res.Error != nil {
+		t.Errorf("expected enabled optional tool to run, got %v", res.Error)
+	}
+	if res := r.Execute(context.Background(), "shell_command", nil); res.Error == nil {
+		t.Error("expected optional tool to be rejected without policy")
+	}
+}
@@ -67,16 +67,26 @@ type AsyncCallback func(result *Result)
 
 // Registry manages tool registration and execution.
 type Registry struct {
-	tools  map[string]Tool
-	mu     sync.RWMutex
-	logger *slog.Logger
+	tools    map[string]Tool
+	optional map[string]bool // 可选工具，只提供给通过策略启用它们的会话
+	mu       sync.RWMutex
+	logger   *slog.Logger
+	stats    *Stats
+
+	authorizer Authorizer // 工具执行前的授权钩子
+	auditor    Auditor    // 工具调用的审计钩子
+
+	timeout  time.Duration            // 工具执行的默认超时，0 表示不限制
+	timeouts map[string]time.Duration // 按工具设置的超时，覆盖默认超时
 }
 
 // NewRegistry creates a new tool registry.
 func NewRegistry() *Registry {
 	return &Registry{
-		tools:  make(map[string]Tool),
-		logger: slog.Default(),
+		tools:    make(map[string]Tool),
+		optional: make(map[string]bool),
+		logger:   slog.Default(),
+		stats:    NewStats(),
 	}
 }
 
@@ -86,8 +96,10 @@ func NewRegistryWithLogger(logger *slog.Logger) *Registry {
 		logger = slog.Default()
 	}
 	return &Registry{
-		tools:  make(map[string]Tool),
-		logger: logger,
+		tools:    make(map[string]Tool),
+		optional: make(map[string]bool),
+		logger:   logger,
+		stats:    NewStats(),
 	}
 }
 
@@ -115,6 +127,44 @@ func (r *Registry) Unregister(name string) {
 	}
 }
 
+// SetOptional marks tools as optional: they stay registered but are only
+// offered to sessions whose tool policy enables them.
+func (r *Registry) SetOptional(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		r.optional[name] = true
+	}
+}
+
+// IsOptional reports whether a tool is optional.
+func (r *Registry) IsOptional(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.optional[name]
+}
+
+// Permitted reports whether a tool is available under the session policy.
+func (r *Registry) Permitted(name string, p Policy) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return p.Permits(name, r.optional[name])
+}
+
+// ListFor returns the tools available under the session policy.
+func (r *Registry) ListFor(p Policy) []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := r.permittedToolNames(p)
+	tools := make([]Tool, 0, len(names))
+	for _, name := range names {
+		tools = append(tools, r.tools[name])
+	}
+	return tools
+}
+
 // Get gets a tool by name.
 func (r *Registry) Get(name string) (Tool, error) {
 	r.mu.RLock()
@@ -170,6 +220,17 @@ func (r *Registry) sortedToolNames() []string {
 	return names
 }
 
+// permittedToolNames returns sorted names of tools available under the policy.
+func (r *Registry) permittedToolNames(p Policy) []string {
+	names := make([]string, 0, len(r.tools))
+	for _, name := range r.sortedToolNames() {
+		if p.Permits(name, r.optional[name]) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // Execute executes a tool by name.
 func (r *Registry) Execute(ctx context.Context, name string, args map[string]any) *Result {
 	return r.ExecuteWithContext(ctx, name, args, "", "", nil)
@@ -200,9 +261,48 @@ func (r *Registry) ExecuteWithContext(
 		}
 	}
 
+	// 校验会话工具策略，模型可能调用未提供给它的工具
+	if !r.Permitted(name, GetPolicy(ctx)) {
+		r.logger.With("name", "【智能体】").Warn("工具在当前会话中不可用",
+			"tool", name,
+			"session_id", sessionID)
+		result := &Result{
+			Success: false,
+			Error:   fmt.Errorf("tool %q is not available in this session", name),
+		}
+		r.audit(ctx, Invocation{Name: name, Args: args, Result: result, Channel: channel, SessionID: sessionID, Denied: true})
+		return result
+	}
+
 	// Inject context
 	ctx = WithToolContext(ctx, channel, sessionID)
 
+	// 授权钩子，按组织策略拒绝调用
+	if a := r.getAuthorizer(); a != nil {
+		if err := a.AuthorizeTool(ctx, name, args); err != nil {
+			r.logger.With("name", "【智能体】").Warn("工具调用未通过授权",
+				"tool", name,
+				"session_id", sessionID,
+				"reason", err)
+			result := &Result{Success: false, Error: err}
+			r.audit(ctx, Invocation{Name: name, Args: args, Result: result, Channel: channel, SessionID: sessionID, Denied: true})
+			return result
+		}
+	}
+
+	// 只读工作目录中，会修改文件或执行命令的调用只返回将要做出的修改
+	if m, ok := tool.(Mutator); ok && IsReadOnly(ctx) {
+		if description, mutates := m.DescribeChange(ctx, args); mutates {
+			r.logger.With("name", "【智能体】").Info("只读工作目录，跳过工具执行",
+				"tool", name,
+				"session_id", sessionID,
+				"change", description)
+			result := readOnlyResult(description)
+			r.audit(ctx, Invocation{Name: name, Args: args, Result: result, Channel: channel, SessionID: sessionID, Skipped: true})
+			return result
+		}
+	}
+
 	// Execute with timing
 	start := time.Now()
 	var result *Result
@@ -213,9 +313,11 @@ func (r *Registry) ExecuteWithContext(
 			"tool", name)
 		result = asyncExec.ExecuteAsync(ctx, args, asyncCallback)
 	} else {
-		result = tool.Execute(ctx, args)
+		result = executeWithTimeout(ctx, tool, args, r.toolTimeout(name))
 	}
 	duration := time.Since(start)
+	r.stats.Record(name, result.Error, duration)
+	r.audit(ctx, Invocation{Name: name, Args: args, Result: result, Duration: duration, Channel: channel, SessionID: sessionID})
 
 	// Log based on result type
 	if result.Error != nil {
@@ -262,12 +364,18 @@ func (r *Registry) GetToolDefinitions() []map[string]any {
 }
 
 // ToProviderDefs converts tool definitions to provider-compatible format.
-// This is the format expected by LLM provider APIs.
+// This is the format expected by LLM provider APIs. Optional tools are left out.
 func (r *Registry) ToProviderDefs() []ToolDefinition {
+	return r.ToProviderDefsFor(Policy{})
+}
+
+// ToProviderDefsFor converts the definitions of tools available under the
+// session policy to provider-compatible format.
+func (r *Registry) ToProviderDefsFor(p Policy) []ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	names := r.sortedToolNames()
+	names := r.permittedToolNames(p)
 	definitions := make([]ToolDefinition, 0, len(names))
 
 	for _, name := range names {
@@ -303,6 +411,11 @@ func (r *Registry) GetSummaries() []string {
 	return summaries
 }
 
+// Stats returns the tool execution statistics.
+func (r *Registry) Stats() *Stats {
+	return r.stats
+}
+
 // HasTool checks if a tool exists.
 func (r *Registry) HasTool(name string) bool {
 	r.mu.RLock()
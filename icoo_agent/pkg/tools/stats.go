@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// statsWindow 计算近期成功率时保留的最近调用次数
+	statsWindow = 20
+	// notesMinFailures 生成工具提示所需的最少近期失败次数
+	notesMinFailures = 2
+	// notesMaxAge 超过该时长没有失败的工具不再生成提示
+	notesMaxAge = 30 * time.Minute
+	// notesErrorLen 提示中错误信息的最大字符数
+	notesErrorLen = 120
+)
+
+// ToolStats 单个工具的调用统计。
+type ToolStats struct {
+	Name           string        `json:"name"`
+	Calls          int64         `json:"calls"`           // 累计调用次数
+	Failures       int64         `json:"failures"`        // 累计失败次数
+	RecentCalls    int           `json:"recent_calls"`    // 最近窗口内调用次数
+	RecentFailures int           `json:"recent_failures"` // 最近窗口内失败次数
+	ConsecutiveErr int           `json:"consecutive_failures"`
+	LastError      string        `json:"last_error,omitempty"`
+	LastFailure    time.Time     `json:"last_failure,omitempty"`
+	LastCall       time.Time     `json:"last_call"`
+	AvgDuration    time.Duration `json:"avg_duration"`
+}
+
+// SuccessRate 返回最近窗口内的成功率。
+func (s ToolStats) SuccessRate() float64 {
+	if s.RecentCalls == 0 {
+		return 1
+	}
+	return float64(s.RecentCalls-s.RecentFailures) / float64(s.RecentCalls)
+}
+
+// toolRecord 工具统计的内部状态。
+type toolRecord struct {
+	stats    ToolStats
+	recent   []bool // 最近调用结果，true 表示失败
+	duration time.Duration
+}
+
+// Stats 记录工具执行结果，用于生成提示词中的工具使用提示。
+type Stats struct {
+	mu      sync.Mutex
+	records map[string]*toolRecord
+	now     func() time.Time
+}
+
+// NewStats 创建工具统计。
+func NewStats() *Stats {
+	return &Stats{
+		records: make(map[string]*toolRecord),
+		now:     time.Now,
+	}
+}
+
+// Record 记录一次工具执行结果。
+func (s *Stats) Record(name string, err error, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[name]
+	if !ok {
+		r = &toolRecord{stats: ToolStats{Name: name}}
+		s.records[name] = r
+	}
+
+	now := s.now()
+	r.stats.Calls++
+	r.stats.LastCall = now
+	r.duration += duration
+
+	failed := err != nil
+	if failed {
+		r.stats.Failures++
+		r.stats.ConsecutiveErr++
+		r.stats.LastError = err.Error()
+		r.stats.LastFailure = now
+	} else {
+		r.stats.ConsecutiveErr = 0
+	}
+
+	r.recent = append(r.recent, failed)
+	if len(r.recent) > statsWindow {
+		r.recent = r.recent[len(r.recent)-statsWindow:]
+	}
+}
+
+// Reset 清除工具的统计，工具恢复后重新计数。
+func (s *Stats) Reset(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, name)
+}
+
+// Snapshot 返回所有工具的统计，按名称排序。
+func (s *Stats) Snapshot() []ToolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]ToolStats, 0, len(s.records))
+	for _, r := range s.records {
+		st := r.stats
+		st.RecentCalls = len(r.recent)
+		for _, failed := range r.recent {
+			if failed {
+				st.RecentFailures++
+			}
+		}
+		st.AvgDuration = r.duration / time.Duration(st.Calls)
+		result = append(result, st)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Notes 根据近期失败情况生成工具使用提示，每个有问题的工具一行。
+// 只有近期失败次数达到阈值、且成功率低于一半或连续失败的工具才会出现，
+// 工具恢复正常或长时间未再失败后提示自动消失。
+func (s *Stats) Notes() []string {
+	now := s.now()
+
+	var notes []string
+	for _, st := range s.Snapshot() {
+		if st.RecentFailures < notesMinFailures || now.Sub(st.LastFailure) > notesMaxAge {
+			continue
+		}
+		if st.ConsecutiveErr < notesMinFailures && st.SuccessRate() >= 0.5 {
+			continue
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "- %s: 最近 %d 次调用失败 %d 次", st.Name, st.RecentCalls, st.RecentFailures)
+		if st.ConsecutiveErr >= notesMinFailures {
+			fmt.Fprintf(&sb, "（连续失败 %d 次）", st.ConsecutiveErr)
+		}
+		fmt.Fprintf(&sb, "，最近错误：%s", truncateError(st.LastError))
+		if st.ConsecutiveErr >= notesMinFailures {
+			sb.WriteString("。当前可能不可用，除非参数明显不同，请优先使用其他工具")
+		}
+		notes = append(notes, sb.String())
+	}
+	return notes
+}
+
+// truncateError 截断错误信息并压缩为单行。
+func truncateError(msg string) string {
+	msg = strings.Join(strings.Fields(msg), " ")
+	if utf8.RuneCountInString(msg) <= notesErrorLen {
+		return msg
+	}
+	return string([]rune(msg)[:notesErrorLen]) + "…"
+}
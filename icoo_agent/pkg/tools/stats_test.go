@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats_Notes(t *testing.T) {
+	now := time.Now()
+	s := NewStats()
+	s.now = func() time.Time { return now }
+
+	s.Record("datetime", nil, time.Millisecond)
+	s.Record("web_search", nil, time.Second)
+	s.Record("web_search", errors.New("brave engine: 503\nService Unavailable"), time.Second)
+	if notes := s.Notes(); len(notes) != 0 {
+		t.Fatalf("single failure should not produce notes, got %v", notes)
+	}
+
+	s.Record("web_search", errors.New("brave engine: 503"), time.Second)
+	notes := s.Notes()
+	if len(notes) != 1 {
+		t.Fatalf("expected one note, got %v", notes)
+	}
+	for _, want := range []string{"web_search", "最近 3 次调用失败 2 次", "连续失败 2 次", "brave engine: 503"} {
+		if !strings.Contains(notes[0], want) {
+			t.Errorf("note %q missing %q", notes[0], want)
+		}
+	}
+
+	// 工具恢复后提示消失
+	s.Record("web_search", nil, time.Second)
+	s.Record("web_search", nil, time.Second)
+	if notes := s.Notes(); len(notes) != 0 {
+		t.Errorf("recovered tool should not produce notes, got %v", notes)
+	}
+
+	// 长时间未失败的工具不再提示
+	s.Record("shell", errors.New("exit 1"), 0)
+	s.Record("shell", errors.New("exit 1"), 0)
+	now = now.Add(time.Hour)
+	if notes := s.Notes(); len(notes) != 0 {
+		t.Errorf("stale failures should not produce notes, got %v", notes)
+	}
+}
+
+func TestStats_Snapshot(t *testing.T) {
+	s := NewStats()
+	for i := range statsWindow + 5 {
+		var err error
+		if i%2 == 0 {
+			err = errors.New("boom")
+		}
+		s.Record("grep", err, 2*time.Millisecond)
+	}
+
+	snap := s.Snapshot()
+	if len(snap) != 1 {
+		t.Fatalf("expected one tool, got %d", len(snap))
+	}
+	st := snap[0]
+	if st.Calls != statsWindow+5 || st.RecentCalls != statsWindow || st.AvgDuration != 2*time.Millisecond {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+	if rate := st.SuccessRate(); rate != 0.5 {
+		t.Errorf("SuccessRate() = %v, want 0.5", rate)
+	}
+}
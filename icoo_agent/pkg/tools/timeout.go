@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutError 工具执行超过超时时间被中止，错误信息会返回给模型，提示其缩小范围或换用其他方法。
+type TimeoutError struct {
+	Tool    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("工具 %s 执行超时 (%s)，已中止。请缩小范围（如更具体的路径、更少的数据）后重试，或改用其他方法", e.Tool, e.Timeout)
+}
+
+// Unwrap 使 errors.Is(err, context.DeadlineExceeded) 成立。
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// IsTimeout 判断错误是否表示工具执行超时。
+func IsTimeout(err error) bool {
+	var timeout *TimeoutError
+	return errors.As(err, &timeout)
+}
+
+// TimeoutResult 返回工具执行超时的结果。
+func TimeoutResult(name string, timeout time.Duration) *Result {
+	return &Result{Success: false, Error: &TimeoutError{Tool: name, Timeout: timeout}}
+}
+
+// SetTimeout 设置工具执行的默认超时，0 表示不限制。
+func (r *Registry) SetTimeout(timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeout = timeout
+}
+
+// SetToolTimeout 为单个工具设置超时，覆盖默认超时，0 表示该工具不限制。
+func (r *Registry) SetToolTimeout(name string, timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.timeouts == nil {
+		r.timeouts = make(map[string]time.Duration)
+	}
+	r.timeouts[name] = timeout
+}
+
+// toolTimeout 返回工具的执行超时。
+func (r *Registry) toolTimeout(name string) time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if timeout, ok := r.timeouts[name]; ok {
+		return timeout
+	}
+	return r.timeout
+}
+
+// executeWithTimeout 在超时内执行工具。超时或上下文取消时很快返回，不等待忽略上下文的工具结束，
+// 工具在后台运行完后丢弃其结果；工具检查上下文后提前返回失败时，同样转换为超时结果并保留已有输出。
+func executeWithTimeout(ctx context.Context, tool Tool, args map[string]any, timeout time.Duration) *Result {
+	runCtx, cancel := WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan *Result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- &Result{Success: false, Error: fmt.Errorf("工具 %s 执行时发生 panic: %v", tool.Name(), r)}
+			}
+		}()
+		done <- tool.Execute(runCtx, args)
+	}()
+
+	select {
+	case result := <-done:
+		return stopped(ctx, runCtx, tool.Name(), timeout, result)
+	case <-runCtx.Done():
+	}
+
+	// 给检查上下文的工具留一点时间返回部分输出
+	select {
+	case result := <-done:
+		return stopped(ctx, runCtx, tool.Name(), timeout, result)
+	case <-time.After(stopGrace):
+		return interrupted(ctx, tool.Name(), timeout)
+	}
+}
+
+// stopGrace 上下文结束后等待工具返回的时间
+const stopGrace = 100 * time.Millisecond
+
+// stopped 上下文结束后失败的结果转换为中止结果，保留工具已产生的部分输出。
+func stopped(ctx, runCtx context.Context, name string, timeout time.Duration, result *Result) *Result {
+	if runCtx.Err() == nil || result == nil || result.Success {
+		return result
+	}
+	out := interrupted(ctx, name, timeout)
+	out.Content = result.Content
+	return out
+}
+
+// interrupted 返回工具被中止的结果：调用方上下文结束时报告预算用尽或取消，否则为工具自身超时。
+func interrupted(ctx context.Context, name string, timeout time.Duration) *Result {
+	switch err := ctx.Err(); {
+	case err == nil:
+		return TimeoutResult(name, timeout)
+	case errors.Is(err, context.DeadlineExceeded):
+		if deadline, ok := BudgetDeadline(ctx); ok && !time.Now().Before(deadline) {
+			return &Result{Success: false, Error: fmt.Errorf("%w: 本轮时间预算已用尽，工具 %s 被中止", ErrBudgetExceeded, name)}
+		}
+		return &Result{Success: false, Error: fmt.Errorf("工具 %s 被中止: %w", name, err)}
+	default:
+		return &Result{Success: false, Error: fmt.Errorf("工具 %s 已取消: %w", name, err)}
+	}
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// sleepTool 忽略上下文，固定睡眠后返回；cooperative 为 true 时上下文结束即返回部分输出。
+type sleepTool struct {
+	name        string
+	sleep       time.Duration
+	cooperative bool
+}
+
+func (t sleepTool) Name() string               { return t.name }
+func (t sleepTool) Description() string        { return t.name }
+func (t sleepTool) Parameters() map[string]any { return map[string]any{} }
+func (t sleepTool) Execute(ctx context.Context, args map[string]any) *Result {
+	if t.cooperative {
+		select {
+		case <-ctx.Done():
+			return &Result{Success: false, Content: "partial", Error: ctx.Err()}
+		case <-time.After(t.sleep):
+		}
+	} else {
+		time.Sleep(t.sleep)
+	}
+	return SuccessResult("done")
+}
+
+func TestRegistry_ExecuteTimeout(t *testing.T) {
+	r := NewRegistry()
+	r.Register(sleepTool{name: "stubborn", sleep: time.Second})
+	r.Register(sleepTool{name: "polite", sleep: time.Second, cooperative: true})
+	r.Register(sleepTool{name: "slow", sleep: 100 * time.Millisecond})
+	r.SetTimeout(20 * time.Millisecond)
+	r.SetToolTimeout("slow", 0)
+
+	// 忽略上下文的工具超时后立即返回
+	start := time.Now()
+	result := r.Execute(context.Background(), "stubborn", nil)
+	if !IsTimeout(result.Error) || !errors.Is(result.Error, context.DeadlineExceeded) {
+		t.Fatalf("Execute(stubborn) error = %v, want timeout", result.Error)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Execute(stubborn) took %s, want to return at the timeout", elapsed)
+	}
+
+	// 检查上下文的工具转换为超时结果并保留部分输出
+	result = r.Execute(context.Background(), "polite", nil)
+	if !IsTimeout(result.Error) || result.Content != "partial" {
+		t.Errorf("Execute(polite) = %q, %v", result.Content, result.Error)
+	}
+
+	// 单独设置为 0 的工具不受默认超时限制
+	if result = r.Execute(context.Background(), "slow", nil); !result.Success {
+		t.Errorf("Execute(slow) error = %v", result.Error)
+	}
+
+	// 调用方取消时报告取消而不是超时
+	r.SetTimeout(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	result = r.Execute(ctx, "stubborn", nil)
+	if IsTimeout(result.Error) || !errors.Is(result.Error, context.Canceled) {
+		t.Errorf("Execute(cancelled) error = %v, want context.Canceled", result.Error)
+	}
+
+	// 本轮预算用尽时报告预算用尽
+	ctx, cancel = WithBudgetDeadline(context.Background(), time.Now().Add(20*time.Millisecond))
+	defer cancel()
+	result = r.Execute(ctx, "stubborn", nil)
+	if !errors.Is(result.Error, ErrBudgetExceeded) {
+		t.Errorf("Execute(budget) error = %v, want ErrBudgetExceeded", result.Error)
+	}
+}
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// VarsMetadataKey 会话元数据中保存会话变量的键
+const VarsMetadataKey = "vars"
+
+// VarEnvPrefix 会话变量注入命令和插件环境时的变量名前缀，如 project_path -> ICOOCLAW_VAR_PROJECT_PATH
+const VarEnvPrefix = "ICOOCLAW_VAR_"
+
+// varName 变量名格式，同时保证可以作为环境变量名
+var varName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// varRef 提示词中的变量引用，如 {{project_path}}
+var varRef = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Vars 会话变量，如默认项目路径、工单号，随会话持久化。
+type Vars map[string]string
+
+// ValidVarName 检查变量名是否合法。
+func ValidVarName(name string) error {
+	if !varName.MatchString(name) {
+		return fmt.Errorf("变量名 %q 无效，只能包含字母、数字和 _，且不能以数字开头", name)
+	}
+	return nil
+}
+
+// ParseAssignment 解析 key=value 形式的赋值，value 可以为空。
+func ParseAssignment(s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("格式错误，应为 key=value: %s", s)
+	}
+	key = strings.TrimSpace(key)
+	if err := ValidVarName(key); err != nil {
+		return "", "", err
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+// Keys 返回排序后的变量名。
+func (v Vars) Keys() []string {
+	return slices.Sorted(maps.Keys(v))
+}
+
+// Interpolate 将文本中的 {{key}} 替换为变量值，未定义的变量保持原样。
+func (v Vars) Interpolate(text string) string {
+	if len(v) == 0 || !strings.Contains(text, "{{") {
+		return text
+	}
+	return varRef.ReplaceAllStringFunc(text, func(ref string) string {
+		key := varRef.FindStringSubmatch(ref)[1]
+		if value, ok := v[key]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// Env 以 ICOOCLAW_VAR_<KEY>=value 形式返回环境变量，按变量名排序。
+func (v Vars) Env() []string {
+	env := make([]string, 0, len(v))
+	for _, key := range v.Keys() {
+		env = append(env, VarEnvPrefix+strings.ToUpper(key)+"="+v[key])
+	}
+	return env
+}
+
+// varsKey 会话变量的上下文键
+type varsKey struct{}
+
+// WithVars 将会话变量注入上下文，工具执行时可读取。
+func WithVars(ctx context.Context, v Vars) context.Context {
+	return context.WithValue(ctx, varsKey{}, v)
+}
+
+// GetVars 从上下文提取会话变量，未设置时返回 nil。
+func GetVars(ctx context.Context) Vars {
+	v, _ := ctx.Value(varsKey{}).(Vars)
+	return v
+}
+
+// GetVar 从上下文读取单个会话变量。
+func GetVar(ctx context.Context, key string) (string, bool) {
+	value, ok := GetVars(ctx)[key]
+	return value, ok
+}
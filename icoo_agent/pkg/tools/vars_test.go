@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestVars_Interpolate(t *testing.T) {
+	v := Vars{"project_path": "/srv/app", "ticket": "ABC-123"}
+
+	got := v.Interpolate("项目位于 {{project_path}}，当前工单 {{ ticket }}，{{unknown}} 保持不变")
+	want := "项目位于 /srv/app，当前工单 ABC-123，{{unknown}} 保持不变"
+	if got != want {
+		t.Errorf("Interpolate() = %q, want %q", got, want)
+	}
+
+	if got := Vars(nil).Interpolate("{{ticket}}"); got != "{{ticket}}" {
+		t.Errorf("nil vars should leave text unchanged, got %q", got)
+	}
+}
+
+func TestParseAssignment(t *testing.T) {
+	tests := []struct {
+		in         string
+		key, value string
+		wantErr    bool
+	}{
+		{in: "ticket=ABC-123", key: "ticket", value: "ABC-123"},
+		{in: "path = /srv/my app ", key: "path", value: "/srv/my app"},
+		{in: "empty=", key: "empty", value: ""},
+		{in: "url=https://x.test/?a=b", key: "url", value: "https://x.test/?a=b"},
+		{in: "novalue", wantErr: true},
+		{in: "1bad=x", wantErr: true},
+		{in: "bad-name=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		key, value, err := ParseAssignment(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAssignment(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if key != tt.key || value != tt.value {
+			t.Errorf("ParseAssignment(%q) = %q, %q, want %q, %q", tt.in, key, value, tt.key, tt.value)
+		}
+	}
+}
+
+func TestVars_EnvAndContext(t *testing.T) {
+	v := Vars{"ticket": "ABC-123", "project_path": "/srv/app"}
+	want := []string{"ICOOCLAW_VAR_PROJECT_PATH=/srv/app", "ICOOCLAW_VAR_TICKET=ABC-123"}
+	if got := v.Env(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Env() = %v, want %v", got, want)
+	}
+
+	ctx := WithVars(context.Background(), v)
+	if value, ok := GetVar(ctx, "ticket"); !ok || value != "ABC-123" {
+		t.Errorf("GetVar() = %q, %v", value, ok)
+	}
+	if _, ok := GetVar(context.Background(), "ticket"); ok {
+		t.Error("GetVar() without vars should report false")
+	}
+}
@@ -0,0 +1,63 @@
+package tools
+
+import "context"
+
+// workspaceKey 会话工作目录的上下文键
+type workspaceKey struct{}
+
+// WithWorkspace 将会话选择的工作目录注入上下文，文件、命令和插件工具据此解析路径。
+func WithWorkspace(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, dir)
+}
+
+// GetWorkspace 从上下文提取会话的工作目录，未设置时返回工具自身配置的 fallback。
+func GetWorkspace(ctx context.Context, fallback string) string {
+	if dir, _ := ctx.Value(workspaceKey{}).(string); dir != "" {
+		return dir
+	}
+	return fallback
+}
+
+// readOnlyKey 工作目录只读标记的上下文键
+type readOnlyKey struct{}
+
+// WithReadOnly 标记会话的工作目录为只读，注册表不再执行会修改文件或执行命令的工具。
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// IsReadOnly 判断会话的工作目录是否只读。
+func IsReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
+
+// Mutator 会修改工作目录或执行命令的工具实现该接口。
+// 工作目录只读时注册表不执行这类调用，而是向模型返回其说明。
+type Mutator interface {
+	// DescribeChange 说明本次调用将做出的修改，mutates 为 false 表示本次调用只读，可以照常执行。
+	DescribeChange(ctx context.Context, args map[string]any) (description string, mutates bool)
+}
+
+// readOnlyResult 工作目录只读时代替实际执行的结果。
+func readOnlyResult(description string) *Result {
+	return &Result{
+		Success: true,
+		Content: "当前工作目录为只读，本次调用未执行，没有做任何修改。该调用原本会: " + description +
+			"\n如确需修改，请告知用户切换到可写的工作目录。",
+	}
+}
+
+// ephemeralKey 无痕会话标记的上下文键
+type ephemeralKey struct{}
+
+// WithEphemeral 标记当前会话为无痕会话，工具不应在临时工作目录之外留下任何文件或记录。
+func WithEphemeral(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ephemeralKey{}, true)
+}
+
+// IsEphemeral 判断当前会话是否为无痕会话。
+func IsEphemeral(ctx context.Context) bool {
+	ephemeral, _ := ctx.Value(ephemeralKey{}).(bool)
+	return ephemeral
+}
@@ -0,0 +1,238 @@
+// Package toolserver serves icooclaw's tool registry on its own, without the agent loop or providers,
+// so other agent frameworks can use the sandboxed tool implementations as a backend.
+//
+// 工具通过 MCP（stdio 或 streamable HTTP，路径 /mcp）和 REST 接口对外提供：
+//
+//	GET  /api/v1/tools         列出工具及参数
+//	POST /api/v1/tools/{name}  以 JSON 对象为参数调用工具
+//
+// 调用与智能体中的工具执行走同一个注册表，工作目录、只读模式和工具授权同样生效。
+package toolserver
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"icooclaw/pkg/gateway/auth"
+	"icooclaw/pkg/gateway/models"
+	"icooclaw/pkg/tools"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Channel 工具调用的渠道标识，写入工具上下文和日志
+const Channel = "toolserver"
+
+// Config 工具服务配置。
+type Config struct {
+	// Addr HTTP 监听地址
+	Addr string
+	// Workspace 工具的工作目录
+	Workspace string
+	// Tools 对外提供的工具，为空时提供全部已注册工具
+	Tools []string
+	// ReadOnly 只读模式，修改文件或执行命令的调用只返回将要做出的修改
+	ReadOnly bool
+	// Version 服务版本，在 MCP 初始化时返回给客户端
+	Version string
+}
+
+// Server 独立的工具服务。
+type Server struct {
+	cfg      *Config
+	registry *tools.Registry
+	auth     *auth.Authenticator
+	logger   *slog.Logger
+	mcp      *server.MCPServer
+	http     *http.Server
+}
+
+// New 创建工具服务，Tools 中的工具必须已注册。
+func New(cfg *Config, registry *tools.Registry, logger *slog.Logger) (*Server, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	for _, name := range cfg.Tools {
+		if !registry.HasTool(name) {
+			return nil, fmt.Errorf("工具不存在: %s", name)
+		}
+	}
+
+	s := &Server{
+		cfg:      cfg,
+		registry: registry,
+		logger:   logger.With("name", "【工具服务】"),
+		http:     &http.Server{ReadHeaderTimeout: 10 * time.Second},
+	}
+	s.mcp = server.NewMCPServer("icooclaw", cmp.Or(cfg.Version, "dev"), server.WithToolCapabilities(false))
+	for _, def := range s.definitions() {
+		schema, err := json.Marshal(def.Function.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("序列化工具 %s 的参数失败: %w", def.Function.Name, err)
+		}
+		name := def.Function.Name
+		s.mcp.AddTool(mcp.NewToolWithRawSchema(name, def.Function.Description, schema), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result := s.call(ctx, name, req.GetArguments())
+			if !result.Success || result.Error != nil {
+				return mcp.NewToolResultError(errorText(result)), nil
+			}
+			return mcp.NewToolResultText(result.Content), nil
+		})
+	}
+	return s, nil
+}
+
+// WithAuth 要求 HTTP 请求携带 admin 权限的 API 密钥或 JWT，健康检查除外；stdio 不受影响。
+func (s *Server) WithAuth(a *auth.Authenticator) *Server {
+	s.auth = a
+	return s
+}
+
+// definitions 返回对外提供的工具定义，按名称排序。
+func (s *Server) definitions() []tools.ToolDefinition {
+	var defs []tools.ToolDefinition
+	for _, def := range s.registry.ToProviderDefs() {
+		if s.exposed(def.Function.Name) {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// exposed 判断工具是否对外提供。
+func (s *Server) exposed(name string) bool {
+	return len(s.cfg.Tools) == 0 || slices.Contains(s.cfg.Tools, name)
+}
+
+// call 在配置的工作目录中执行工具。
+func (s *Server) call(ctx context.Context, name string, args map[string]any) *tools.Result {
+	if !s.exposed(name) {
+		return &tools.Result{Success: false, Error: fmt.Errorf("tool %q not found", name)}
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	ctx = tools.WithWorkspace(ctx, s.cfg.Workspace)
+	if s.cfg.ReadOnly {
+		ctx = tools.WithReadOnly(ctx)
+	}
+	return s.registry.ExecuteWithContext(ctx, name, args, Channel, "", nil)
+}
+
+// errorText 返回失败结果的说明。
+func errorText(result *tools.Result) string {
+	if result.Error != nil {
+		return result.Error.Error()
+	}
+	if result.Content != "" {
+		return result.Content
+	}
+	return "工具执行失败"
+}
+
+// Handler 返回 REST 和 MCP 接口的 HTTP 处理器。
+func (s *Server) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Recoverer)
+	if s.auth != nil {
+		r.Use(s.auth.Middleware([]auth.Route{{Prefix: "/health", Scope: auth.ScopePublic}}, auth.ScopeAdmin, s.logger))
+	}
+
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		models.WriteData(w, models.BaseResponse[map[string]int]{
+			Code:    http.StatusOK,
+			Message: "ok",
+			Data:    map[string]int{"tools": len(s.definitions())},
+		})
+	})
+	r.Get("/api/v1/tools", s.handleList)
+	r.Post("/api/v1/tools/{name}", s.handleCall)
+	r.Handle("/mcp", server.NewStreamableHTTPServer(s.mcp))
+	return r
+}
+
+// handleList 列出对外提供的工具。
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	models.WriteData(w, models.BaseResponse[[]tools.ToolDefinition]{
+		Code:    http.StatusOK,
+		Message: "工具列表获取成功",
+		Data:    s.definitions(),
+	})
+}
+
+// handleCall 调用工具，请求体为参数对象，可以为空。
+func (s *Server) handleCall(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if !s.exposed(name) || !s.registry.HasTool(name) {
+		models.WriteData(w, models.BaseResponse[any]{Code: http.StatusNotFound, Message: "工具不存在: " + name})
+		return
+	}
+
+	args := map[string]any{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil && !errors.Is(err, io.EOF) {
+		models.WriteData(w, models.BaseResponse[any]{Code: http.StatusBadRequest, Message: "工具参数格式错误: " + err.Error()})
+		return
+	}
+
+	result := s.call(r.Context(), name, args)
+	data := map[string]any{"success": result.Success, "content": result.Content}
+	if result.Error != nil {
+		data["error"] = result.Error.Error()
+	}
+	models.WriteData(w, models.BaseResponse[map[string]any]{
+		Code:    http.StatusOK,
+		Message: "工具调用完成",
+		Data:    data,
+	})
+}
+
+// ServeStdio 通过标准输入输出提供 MCP 服务，阻塞直到 ctx 取消或输入结束。
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.logger.Info("MCP stdio 服务已启动", "tools", len(s.definitions()))
+	return server.NewStdioServer(s.mcp).Listen(ctx, in, out)
+}
+
+// Start 启动 HTTP 服务，阻塞直到服务停止。
+func (s *Server) Start() error {
+	if s.auth == nil {
+		if host, _, _ := net.SplitHostPort(s.cfg.Addr); !isLoopback(host) {
+			return fmt.Errorf("监听非本机地址 %s 时必须启用 gateway.auth", s.cfg.Addr)
+		}
+	}
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	s.http.Handler = s.Handler()
+	s.logger.Info("HTTP 服务已启动", "addr", ln.Addr().String(), "tools", len(s.definitions()), "read_only", s.cfg.ReadOnly)
+	return s.http.Serve(ln)
+}
+
+// Shutdown 优雅关闭 HTTP 服务，在 Start 之前调用时 Start 直接返回 http.ErrServerClosed。
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.logger.Info("正在关闭")
+	return s.http.Shutdown(ctx)
+}
+
+// isLoopback 判断监听地址是否只对本机开放。
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,141 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"icooclaw/pkg/gateway/auth"
+	"icooclaw/pkg/tools"
+)
+
+// echoTool 返回参数和工作目录的测试工具。
+type echoTool struct{ name string }
+
+func (t echoTool) Name() string        { return t.name }
+func (t echoTool) Description() string { return "echo " + t.name }
+func (t echoTool) Parameters() map[string]any {
+	return map[string]any{"text": map[string]any{"type": "string"}}
+}
+func (t echoTool) Execute(ctx context.Context, args map[string]any) *tools.Result {
+	text, _ := args["text"].(string)
+	return &tools.Result{Success: true, Content: tools.GetWorkspace(ctx, "") + ":" + text}
+}
+
+func newTestServer(t *testing.T, exposed ...string) *Server {
+	t.Helper()
+	registry := tools.NewRegistry()
+	registry.Register(echoTool{name: "echo"})
+	registry.Register(echoTool{name: "hidden"})
+	s, err := New(&Config{Workspace: "/ws", Tools: exposed}, registry, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return s
+}
+
+func TestHandler_REST(t *testing.T) {
+	h := newTestServer(t, "echo").Handler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tools", nil))
+	var list struct {
+		Data []tools.ToolDefinition `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].Function.Name != "echo" {
+		t.Errorf("tools = %+v, want only echo", list.Data)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/tools/echo", strings.NewReader(`{"text":"hi"}`)))
+	var call struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &call); err != nil {
+		t.Fatalf("decode call: %v", err)
+	}
+	if call.Data["content"] != "/ws:hi" || call.Data["success"] != true {
+		t.Errorf("call = %v", call.Data)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/tools/hidden", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("hidden tool code = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandler_Auth(t *testing.T) {
+	a, err := auth.New(auth.Options{Keys: []auth.APIKey{
+		{Name: "chat", Key: "chat-key"},
+		{Name: "ops", Key: "ops-key", Scopes: []string{auth.ScopeAdmin}},
+	}})
+	if err != nil {
+		t.Fatalf("auth.New() error = %v", err)
+	}
+	h := newTestServer(t).WithAuth(a).Handler()
+
+	tests := []struct {
+		path string
+		key  string
+		want int
+	}{
+		{"/health", "", http.StatusOK},
+		{"/api/v1/tools", "", http.StatusUnauthorized},
+		{"/api/v1/tools", "chat-key", http.StatusForbidden},
+		{"/api/v1/tools", "ops-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.key != "" {
+			r.Header.Set("X-API-Key", tt.key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s with %q: code = %d, want %d", tt.path, tt.key, w.Code, tt.want)
+		}
+	}
+}
+
+func TestMCP_CallTool(t *testing.T) {
+	s := newTestServer(t)
+	resp := s.mcp.HandleMessage(context.Background(), json.RawMessage(
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"text":"mcp"}}}`))
+	out, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(out), `/ws:mcp`) {
+		t.Errorf("response = %s", out)
+	}
+
+	resp = s.mcp.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`))
+	out, _ = json.Marshal(resp)
+	if !strings.Contains(string(out), `"echo"`) || !strings.Contains(string(out), `"hidden"`) {
+		t.Errorf("tools/list = %s", out)
+	}
+}
+
+func TestNew_UnknownTool(t *testing.T) {
+	if _, err := New(&Config{Tools: []string{"missing"}}, tools.NewRegistry(), nil); err == nil {
+		t.Error("New() error = nil, want unknown tool error")
+	}
+}
+
+func TestStart_RequiresAuthOffLoopback(t *testing.T) {
+	registry := tools.NewRegistry()
+	s, err := New(&Config{Addr: "0.0.0.0:0", Workspace: "/ws"}, registry, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := s.Start(); err == nil || !strings.Contains(err.Error(), "gateway.auth") {
+		t.Errorf("Start() error = %v, want gateway.auth required", err)
+	}
+}
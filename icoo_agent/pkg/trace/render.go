@@ -0,0 +1,265 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/transcript"
+)
+
+// 导出格式。
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	// FormatTurn 完整轨迹的 JSON，可保存为用例并用 trace replay 重放
+	FormatTurn = "turn"
+)
+
+// ParseFormat 解析导出格式，空字符串视为 markdown。
+// 除报告格式外也接受 transcript 包的对话格式（openai、anthropic、langchain、sharegpt）。
+func ParseFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", "md", FormatMarkdown:
+		return FormatMarkdown, nil
+	case "htm", FormatHTML:
+		return FormatHTML, nil
+	case FormatTurn:
+		return FormatTurn, nil
+	}
+	if f, err := transcript.ParseFormat(format); err == nil {
+		return f, nil
+	}
+	return "", fmt.Errorf("未知的导出格式: %s（可选 %s）", format, strings.Join(Formats(), "、"))
+}
+
+// Formats 返回支持的导出格式。
+func Formats() []string {
+	return slices.Concat([]string{FormatMarkdown, FormatHTML, FormatTurn}, transcript.Formats())
+}
+
+// Render 按格式渲染报告，返回内容、Content-Type 和文件扩展名。
+// 对话格式导出 Messages 的 JSON，可作为评测用例或回放输入；turn 导出完整轨迹，可用于重放。
+func Render(t *Turn, format string) (content []byte, contentType, ext string, err error) {
+	format, err = ParseFormat(format)
+	if err != nil {
+		return nil, "", "", err
+	}
+	switch format {
+	case FormatMarkdown:
+		return []byte(Markdown(t)), "text/markdown; charset=utf-8", ".md", nil
+	case FormatHTML:
+		content, err = HTML(t)
+		return content, "text/html; charset=utf-8", ".html", err
+	case FormatTurn:
+		content, err = json.MarshalIndent(t, "", "  ")
+		return content, "application/json; charset=utf-8", ".json", err
+	default:
+		content, err = transcript.Encode(format, t.Messages())
+		return content, "application/json; charset=utf-8", ".json", err
+	}
+}
+
+// Markdown 将轨迹渲染为 Markdown 报告。
+func Markdown(t *Turn) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# 对话轨迹 %s\n\n", t.ID)
+	b.WriteString("| 项目 | 值 |\n| --- | --- |\n")
+	for _, row := range summary(t) {
+		fmt.Fprintf(&b, "| %s | %s |\n", row[0], strings.ReplaceAll(row[1], "|", `\|`))
+	}
+
+	b.WriteString("\n## 用户消息\n\n")
+	b.WriteString(fence(t.Input, "text"))
+
+	fmt.Fprintf(&b, "\n## 提示词（%d 条消息）\n\n", len(t.Prompt))
+	for i, m := range t.Prompt {
+		fmt.Fprintf(&b, "### %d. %s\n\n", i+1, messageTitle(m.Role, m.ToolCallID))
+		if m.Content != "" {
+			b.WriteString(fence(m.Content, "text"))
+		}
+		for _, tc := range m.ToolCalls {
+			fmt.Fprintf(&b, "\n调用 `%s`:\n\n", tc.Function.Name)
+			b.WriteString(fence(prettyJSON(tc.Function.Arguments), "json"))
+		}
+		b.WriteString("\n")
+	}
+
+	for _, it := range t.Iterations {
+		fmt.Fprintf(&b, "## 迭代 %d\n\n", it.Index)
+		fmt.Fprintf(&b, "- 模型耗时: %s\n", formatMs(it.DurationMs))
+		if it.Usage != nil {
+			fmt.Fprintf(&b, "- Token: %s\n", formatUsage(it.Usage.PromptTokens, it.Usage.CompletionTokens, it.Usage.TotalTokens))
+		}
+		if it.WrapUp {
+			b.WriteString("- 时间预算已用尽，本次调用不提供工具\n")
+		}
+		if it.Reasoning != "" {
+			b.WriteString("\n### 推理过程\n\n")
+			b.WriteString(fence(it.Reasoning, "text"))
+		}
+		if it.Content != "" {
+			b.WriteString("\n### 模型回复\n\n")
+			b.WriteString(fence(it.Content, "text"))
+		}
+		for i, tc := range it.ToolCalls {
+			fmt.Fprintf(&b, "\n### 工具调用 %d: %s（%s）\n\n", i+1, tc.Name, formatMs(tc.DurationMs))
+			b.WriteString("参数:\n\n")
+			b.WriteString(fence(prettyJSON(tc.Arguments), "json"))
+			if tc.Truncated {
+				fmt.Fprintf(&b, "\n结果（已截断为前 %d 个字符）:\n\n", t.ResultLimit)
+			} else {
+				b.WriteString("\n结果:\n\n")
+			}
+			b.WriteString(fence(tc.Result, "text"))
+		}
+		b.WriteString("\n")
+	}
+
+	if t.Changes != nil {
+		b.WriteString("## 工作目录修改\n\n")
+		b.WriteString(fence(t.Changes.Text(), "text"))
+		b.WriteString("\n")
+	}
+	if t.Error != "" {
+		b.WriteString("## 错误\n\n")
+		b.WriteString(fence(t.Error, "text"))
+		b.WriteString("\n")
+	}
+	if t.Content != "" {
+		b.WriteString("## 最终回复\n\n")
+		b.WriteString(t.Content)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// HTML 将轨迹渲染为不依赖外部资源的 HTML 报告。
+func HTML(t *Turn) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, t); err != nil {
+		return nil, fmt.Errorf("渲染轨迹报告失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// summary 报告头部的概要信息。
+func summary(t *Turn) [][2]string {
+	return [][2]string{
+		{"会话", t.Channel + ":" + t.SessionID},
+		{"模型", t.Model},
+		{"开始时间", t.StartedAt.Format(time.DateTime)},
+		{"总耗时", formatMs(t.DurationMs)},
+		{"迭代次数", fmt.Sprint(len(t.Iterations))},
+		{"工具调用", fmt.Sprint(t.ToolCallCount())},
+		{"Token", formatUsage(t.Usage.PromptTokens, t.Usage.CompletionTokens, t.Usage.TotalTokens)},
+	}
+}
+
+// messageTitle 提示词消息的标题。
+func messageTitle(role, toolCallID string) string {
+	if toolCallID != "" {
+		return role + " (" + toolCallID + ")"
+	}
+	return role
+}
+
+// fence 用代码块包裹文本，围栏长度超过文本中最长的连续反引号。
+func fence(text, lang string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	marker := strings.Repeat("`", max(3, longest+1))
+	return marker + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + marker + "\n"
+}
+
+// prettyJSON 格式化 JSON 参数，无法解析时原样返回。
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+// formatMs 格式化毫秒耗时。
+func formatMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// formatUsage 格式化 Token 用量，未知时显示 -。
+func formatUsage(prompt, completion, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("输入 %d / 输出 %d / 合计 %d", prompt, completion, total)
+}
+
+var htmlTemplate = template.Must(template.New("trace").Funcs(template.FuncMap{
+	"summary":      summary,
+	"messageTitle": messageTitle,
+	"prettyJSON":   prettyJSON,
+	"formatMs":     formatMs,
+	"formatUsage":  formatUsage,
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>对话轨迹 {{.ID}}</title>
+<style>
+body{font-family:-apple-system,"Segoe UI","PingFang SC","Microsoft YaHei",sans-serif;max-width:960px;margin:2em auto;padding:0 1em;color:#222;line-height:1.5}
+h1{font-size:1.4em}h2{font-size:1.2em;border-bottom:1px solid #ddd;padding-bottom:.2em;margin-top:1.6em}
+table{border-collapse:collapse}td{border:1px solid #ddd;padding:.3em .8em}td:first-child{background:#f6f8fa;white-space:nowrap}
+pre{background:#f6f8fa;padding:.8em;overflow-x:auto;white-space:pre-wrap;word-break:break-word;font-size:.9em}
+details{margin:.5em 0}summary{cursor:pointer;font-weight:600}
+.tool{border-left:3px solid #0969da;padding-left:.8em;margin:1em 0}.meta{color:#666;font-size:.9em}
+.wrapup{color:#9a6700}.error{color:#cf222e}
+</style>
+</head>
+<body>
+<h1>对话轨迹 {{.ID}}</h1>
+<table>{{range summary .}}<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>{{end}}</table>
+
+<h2>用户消息</h2>
+<pre>{{.Input}}</pre>
+
+<h2>提示词（{{len .Prompt}} 条消息）</h2>
+{{range $i, $m := .Prompt}}<details><summary>{{messageTitle $m.Role $m.ToolCallID}}</summary>
+{{if $m.Content}}<pre>{{$m.Content}}</pre>{{end}}
+{{range $m.ToolCalls}}<p class="meta">调用 {{.Function.Name}}</p><pre>{{prettyJSON .Function.Arguments}}</pre>{{end}}
+</details>
+{{end}}
+{{range .Iterations}}
+<h2>迭代 {{.Index}}</h2>
+<p class="meta">模型耗时 {{formatMs .DurationMs}}{{with .Usage}} · Token {{formatUsage .PromptTokens .CompletionTokens .TotalTokens}}{{end}}</p>
+{{if .WrapUp}}<p class="wrapup">时间预算已用尽，本次调用不提供工具</p>{{end}}
+{{if .Reasoning}}<details open><summary>推理过程</summary><pre>{{.Reasoning}}</pre></details>{{end}}
+{{if .Content}}<details open><summary>模型回复</summary><pre>{{.Content}}</pre></details>{{end}}
+{{range $i, $tc := .ToolCalls}}<div class="tool">
+<p><strong>工具调用: {{$tc.Name}}</strong> <span class="meta">{{formatMs $tc.DurationMs}} · {{$tc.ID}}</span></p>
+<details open><summary>参数</summary><pre>{{prettyJSON $tc.Arguments}}</pre></details>
+<details><summary>结果{{if $tc.Truncated}}（已截断）{{end}}</summary><pre>{{$tc.Result}}</pre></details>
+</div>
+{{end}}
+{{end}}
+{{with .Changes}}<h2>工作目录修改</h2>
+<pre>{{.Text}}</pre>{{end}}
+{{if .Error}}<h2 class="error">错误</h2>
+<pre>{{.Error}}</pre>{{end}}
+{{if .Content}}<h2>最终回复</h2>
+<pre>{{.Content}}</pre>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,145 @@
+package trace
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+	"icooclaw/pkg/transcript"
+)
+
+func testTurn() *Turn {
+	return &Turn{
+		ID:          "t1",
+		Channel:     "websocket",
+		SessionID:   "s1",
+		Model:       "gpt-4",
+		Input:       "列出文件",
+		StartedAt:   time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		DurationMs:  1500,
+		Prompt:      []providers.ChatMessage{{Role: "system", Content: "你是助手"}, {Role: "user", Content: "列出文件"}},
+		ResultLimit: 10,
+		Iterations: []Iteration{
+			{
+				Index:      1,
+				DurationMs: 800,
+				Reasoning:  "先看看目录",
+				Usage:      &providers.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+				ToolCalls: []ToolCall{{
+					ID: "c1", Name: "list_dir", Arguments: `{"path":"."}`,
+					Result: "a.go\n```\n<script>alert(1)</script>", Truncated: true, DurationMs: 30,
+				}},
+			},
+			{Index: 2, DurationMs: 600, Content: "有 a.go"},
+		},
+		Content: "有 a.go",
+		Usage:   providers.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+		Changes: &tools.ChangeSummary{
+			Files: []tools.FileChange{{Path: "a.go", Added: 2, Removed: 1}},
+			Added: 2, Removed: 1,
+		},
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	md := Markdown(testTurn())
+	for _, want := range []string{
+		"# 对话轨迹 t1",
+		"| 工具调用 | 1 |",
+		"### 工具调用 1: list_dir（30ms）",
+		"\"path\": \".\"",
+		"结果（已截断为前 10 个字符）",
+		"## 工作目录修改",
+		"a.go | +2 -1",
+		"## 最终回复",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown 缺少 %q", want)
+		}
+	}
+	// 结果中包含 ``` 时围栏需要加长
+	if !strings.Contains(md, "````text\na.go\n```\n") {
+		t.Error("包含反引号的内容应使用更长的围栏")
+	}
+}
+
+func TestHTML(t *testing.T) {
+	html, err := HTML(testTurn())
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	s := string(html)
+	if strings.Contains(s, "<script>alert") {
+		t.Error("工具结果应被转义")
+	}
+	for _, want := range []string{"<title>对话轨迹 t1</title>", "list_dir", "先看看目录", "输入 100 / 输出 20 / 合计 120", "工作目录修改"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("HTML 缺少 %q", want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, _ := ParseFormat(""); f != FormatMarkdown {
+		t.Errorf("默认格式应为 markdown, got %q", f)
+	}
+	if f, _ := ParseFormat("HTML"); f != FormatHTML {
+		t.Errorf("ParseFormat(HTML) = %q", f)
+	}
+	if f, _ := ParseFormat("langsmith"); f != transcript.FormatLangChain {
+		t.Errorf("ParseFormat(langsmith) = %q", f)
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("未知格式应返回错误")
+	}
+}
+
+func TestMessages(t *testing.T) {
+	got := testTurn().Messages()
+	want := []providers.ChatMessage{
+		{Role: "system", Content: "你是助手"},
+		{Role: "user", Content: "列出文件"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1", Type: "function"}}},
+		{Role: "tool", Content: "a.go\n```\n<script>alert(1)</script>", ToolCallID: "c1"},
+		{Role: "assistant", Content: "有 a.go"},
+	}
+	want[2].ToolCalls[0].Function.Name = "list_dir"
+	want[2].ToolCalls[0].Function.Arguments = `{"path":"."}`
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Messages() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRender_Transcript(t *testing.T) {
+	content, contentType, ext, err := Render(testTurn(), transcript.FormatOpenAI)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if ext != ".json" || !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("contentType = %q, ext = %q", contentType, ext)
+	}
+	messages, err := transcript.Decode(transcript.FormatOpenAI, content)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(messages, testTurn().Messages()) {
+		t.Errorf("decoded = %+v", messages)
+	}
+}
+
+func TestRender_Turn(t *testing.T) {
+	content, _, ext, err := Render(testTurn(), FormatTurn)
+	if err != nil || ext != ".json" {
+		t.Fatalf("Render() = %q, %v", ext, err)
+	}
+	turn, err := Decode(string(content))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(turn.Messages(), testTurn().Messages()) {
+		t.Errorf("decoded = %+v", turn)
+	}
+}
@@ -0,0 +1,118 @@
+// Package trace 描述单轮对话的完整轨迹（提示词、推理过程、工具调用、耗时和 Token 用量），
+// 并将其导出为可独立查看的 Markdown 或 HTML 报告，便于分享“智能体为什么这样做”的分析。
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+	"icooclaw/pkg/tools"
+)
+
+// Turn 一轮对话的轨迹。
+type Turn struct {
+	ID          string                  `json:"id,omitempty"`
+	Channel     string                  `json:"channel"`
+	SessionID   string                  `json:"session_id"`
+	Model       string                  `json:"model"`
+	Input       string                  `json:"input"`             // 用户消息
+	StartedAt   time.Time               `json:"started_at"`        // 开始时间
+	DurationMs  int64                   `json:"duration_ms"`       // 总耗时
+	Prompt      []providers.ChatMessage `json:"prompt"`            // 首次请求模型时的完整提示词
+	Tools       []providers.Tool        `json:"tools,omitempty"`   // 首次请求提供给模型的工具定义
+	Iterations  []Iteration             `json:"iterations"`        // 每次模型调用及其工具调用
+	Content     string                  `json:"content"`           // 最终回复
+	Error       string                  `json:"error,omitempty"`   // 失败原因
+	Usage       providers.Usage         `json:"usage"`             // 各次模型调用的累计用量
+	Changes     *tools.ChangeSummary    `json:"changes,omitempty"` // 工具对工作目录的修改
+	ResultLimit int                     `json:"result_limit"`      // 工具结果截断长度（字符），0 表示不截断
+}
+
+// Iteration 一次模型调用。
+type Iteration struct {
+	Index      int              `json:"index"`
+	DurationMs int64            `json:"duration_ms"`         // 模型调用耗时
+	WrapUp     bool             `json:"wrap_up,omitempty"`   // 时间预算用尽后的收尾调用
+	Reasoning  string           `json:"reasoning,omitempty"` // 推理过程
+	Content    string           `json:"content,omitempty"`   // 模型回复
+	Usage      *providers.Usage `json:"usage,omitempty"`     // 本次调用用量，流式调用通常没有
+	ToolCalls  []ToolCall       `json:"tool_calls,omitempty"`
+}
+
+// ToolCall 一次工具调用。
+type ToolCall struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments"`
+	Result     string `json:"result"`
+	Truncated  bool   `json:"truncated,omitempty"` // 结果是否被截断
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// ToolCallCount 本轮工具调用总数。
+func (t *Turn) ToolCallCount() int {
+	n := 0
+	for _, it := range t.Iterations {
+		n += len(it.ToolCalls)
+	}
+	return n
+}
+
+// Messages 将轨迹还原为消息序列：提示词，之后每次模型调用的回复和工具调用，以及各工具结果。
+// 工具结果是记录时截断后的内容；推理过程不在消息中。
+func (t *Turn) Messages() []providers.ChatMessage {
+	messages := slices.Clone(t.Prompt)
+	replied := false
+	for _, it := range t.Iterations {
+		if it.Content == "" && len(it.ToolCalls) == 0 {
+			continue
+		}
+		replied = replied || it.Content != ""
+
+		m := providers.ChatMessage{Role: consts.RoleAssistant.ToString(), Content: it.Content}
+		for _, tc := range it.ToolCalls {
+			call := providers.ToolCall{ID: tc.ID, Type: "function"}
+			call.Function.Name = tc.Name
+			call.Function.Arguments = tc.Arguments
+			m.ToolCalls = append(m.ToolCalls, call)
+		}
+		messages = append(messages, m)
+		for _, tc := range it.ToolCalls {
+			messages = append(messages, providers.ChatMessage{
+				Role:       consts.RoleTool.ToString(),
+				Content:    tc.Result,
+				ToolCallID: tc.ID,
+			})
+		}
+	}
+	// 没有逐次记录回复时（如出错后的兜底回复）以最终回复结尾
+	if !replied && t.Content != "" {
+		messages = append(messages, providers.ChatMessage{Role: consts.RoleAssistant.ToString(), Content: t.Content})
+	}
+	return messages
+}
+
+// Decode 解析存储中的轨迹详情。
+func Decode(data string) (*Turn, error) {
+	var t Turn
+	if err := json.Unmarshal([]byte(data), &t); err != nil {
+		return nil, fmt.Errorf("解析轨迹失败: %w", err)
+	}
+	return &t, nil
+}
+
+// Truncate 按字符截断文本，返回截断后的文本及是否发生截断，limit 不大于 0 时不截断。
+func Truncate(text string, limit int) (string, bool) {
+	if limit <= 0 {
+		return text, false
+	}
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text, false
+	}
+	return string(runes[:limit]), true
+}
@@ -0,0 +1,190 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/providers"
+)
+
+// anthropicConversation Anthropic Messages API 的对话，system 与 messages 分开。
+type anthropicConversation struct {
+	System   []anthropicBlock   `json:"system,omitempty"`
+	Messages []anthropicMessage `json:"messages"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock 内容块，按 Type 使用不同字段。
+type anthropicBlock struct {
+	Type string `json:"type"`
+	// text
+	Text *string `json:"text,omitempty"`
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// tool_result
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+}
+
+func textBlock(text string) anthropicBlock {
+	return anthropicBlock{Type: "text", Text: &text}
+}
+
+// encodeAnthropic 编码为 {"system": [...], "messages": [...]}。
+// 系统消息移到顶层 system；工具结果作为 user 消息中的 tool_result 块，与相邻的用户消息合并。
+// Anthropic 格式没有消息名称，Name 不会导出；对话中间的系统消息也会移到开头。
+func encodeAnthropic(messages []providers.ChatMessage) *anthropicConversation {
+	conv := &anthropicConversation{Messages: []anthropicMessage{}}
+	for _, m := range messages {
+		switch m.Role {
+		case roleSystem:
+			conv.System = append(conv.System, textBlock(m.Content))
+		case roleAssistant:
+			am := anthropicMessage{Role: roleAssistant}
+			if m.Content != "" || len(m.ToolCalls) == 0 {
+				am.Content = append(am.Content, textBlock(m.Content))
+			}
+			for _, tc := range m.ToolCalls {
+				am.Content = append(am.Content, anthropicBlock{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: rawArguments(tc.Function.Arguments),
+				})
+			}
+			conv.Messages = append(conv.Messages, am)
+		default:
+			block := textBlock(m.Content)
+			if m.Role == roleTool {
+				result, _ := json.Marshal(m.Content)
+				block = anthropicBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: result}
+			}
+			if n := len(conv.Messages); n > 0 && conv.Messages[n-1].Role == roleUser {
+				conv.Messages[n-1].Content = append(conv.Messages[n-1].Content, block)
+				continue
+			}
+			conv.Messages = append(conv.Messages, anthropicMessage{Role: roleUser, Content: []anthropicBlock{block}})
+		}
+	}
+	return conv
+}
+
+// decodeAnthropic 解析 Anthropic 请求体或 messages 数组。
+// 用户消息中的每个文本块和 tool_result 块各还原为一条消息，助手消息的文本块拼接为一条回复；
+// 图片、思考过程等其他内容块被忽略。
+func decodeAnthropic(data []byte) ([]providers.ChatMessage, error) {
+	list, obj, err := unwrapList(data, "messages")
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []providers.ChatMessage
+	if obj != nil {
+		var top struct {
+			System json.RawMessage `json:"system"`
+		}
+		if err := json.Unmarshal(obj, &top); err != nil {
+			return nil, err
+		}
+		system, err := systemTexts(top.System)
+		if err != nil {
+			return nil, fmt.Errorf("system 字段: %w", err)
+		}
+		for _, text := range system {
+			messages = append(messages, providers.ChatMessage{Role: roleSystem, Content: text})
+		}
+	}
+
+	for i, raw := range list {
+		var am struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		}
+		if err := json.Unmarshal(raw, &am); err != nil {
+			return nil, fmt.Errorf("第 %d 条消息格式错误: %w", i+1, err)
+		}
+		blocks, err := anthropicBlocks(am.Content)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条消息: %w", i+1, err)
+		}
+
+		if am.Role == roleAssistant {
+			m := providers.ChatMessage{Role: roleAssistant}
+			var texts []string
+			for _, b := range blocks {
+				switch b.Type {
+				case "text":
+					texts = append(texts, deref(b.Text))
+				case "tool_use":
+					m.ToolCalls = append(m.ToolCalls, newToolCall(b.ID, b.Name, argumentsText(b.Input)))
+				}
+			}
+			m.Content = strings.Join(texts, "\n")
+			messages = append(messages, m)
+			continue
+		}
+
+		for _, b := range blocks {
+			switch b.Type {
+			case "text":
+				messages = append(messages, providers.ChatMessage{Role: am.Role, Content: deref(b.Text)})
+			case "tool_result":
+				content, err := contentText(b.Content)
+				if err != nil {
+					return nil, fmt.Errorf("第 %d 条消息的工具结果: %w", i+1, err)
+				}
+				messages = append(messages, providers.ChatMessage{
+					Role:       roleTool,
+					Content:    content,
+					ToolCallID: b.ToolUseID,
+				})
+			}
+		}
+	}
+	return messages, nil
+}
+
+// anthropicBlocks 解析消息内容，字符串视为单个文本块。
+func anthropicBlocks(raw json.RawMessage) ([]anthropicBlock, error) {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return []anthropicBlock{textBlock(text)}, nil
+	}
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, fmt.Errorf("消息内容格式错误: %w", err)
+	}
+	return blocks, nil
+}
+
+// systemTexts 解析 system 字段，字符串为一条系统消息，文本块数组每块一条。
+func systemTexts(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	blocks, err := anthropicBlocks(raw)
+	if err != nil {
+		return nil, err
+	}
+	var texts []string
+	for _, b := range blocks {
+		if b.Type == "text" {
+			texts = append(texts, deref(b.Text))
+		}
+	}
+	return texts, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,268 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"icooclaw/pkg/providers"
+)
+
+// LangChain 消息类型与内部角色的对应关系
+var (
+	langChainTypes = map[string]string{
+		roleSystem:    "system",
+		roleUser:      "human",
+		roleAssistant: "ai",
+		roleTool:      "tool",
+	}
+	langChainRoles = map[string]string{
+		"system":          roleSystem,
+		"human":           roleUser,
+		"ai":              roleAssistant,
+		"tool":            roleTool,
+		"function":        roleTool,
+		"SystemMessage":   roleSystem,
+		"HumanMessage":    roleUser,
+		"AIMessage":       roleAssistant,
+		"AIMessageChunk":  roleAssistant,
+		"ToolMessage":     roleTool,
+		"FunctionMessage": roleTool,
+	}
+)
+
+// langChainMessage messages_to_dict 输出的一条消息。
+type langChainMessage struct {
+	Type string        `json:"type"`
+	Data langChainData `json:"data"`
+}
+
+type langChainData struct {
+	Type       string              `json:"type"`
+	Content    json.RawMessage     `json:"content"`
+	Role       string              `json:"role,omitempty"` // 仅 chat 类型
+	Name       string              `json:"name,omitempty"`
+	ToolCalls  []langChainToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string              `json:"tool_call_id,omitempty"`
+}
+
+type langChainToolCall struct {
+	ID   string          `json:"id"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args"`
+	Type string          `json:"type,omitempty"`
+}
+
+// encodeLangChain 编码为 messages_to_dict 格式，可用 langchain_core.messages.messages_from_dict 加载。
+// 工具调用参数为 JSON 对象，不是合法 JSON 的参数以字符串保存；其他角色使用 chat 类型并保留 role。
+func encodeLangChain(messages []providers.ChatMessage) []langChainMessage {
+	out := make([]langChainMessage, 0, len(messages))
+	for _, m := range messages {
+		typ, ok := langChainTypes[m.Role]
+		content, _ := json.Marshal(m.Content)
+		data := langChainData{Type: typ, Content: content, Name: m.Name, ToolCallID: m.ToolCallID}
+		if !ok {
+			typ = "chat"
+			data.Type, data.Role = typ, m.Role
+		}
+		for _, tc := range m.ToolCalls {
+			data.ToolCalls = append(data.ToolCalls, langChainToolCall{
+				ID:   tc.ID,
+				Name: tc.Function.Name,
+				Args: rawArguments(tc.Function.Arguments),
+				Type: "tool_call",
+			})
+		}
+		out = append(out, langChainMessage{Type: typ, Data: data})
+	}
+	return out
+}
+
+// decodeLangChain 解析 LangChain 消息或 LangSmith 运行记录。支持：
+//
+//   - messages_to_dict 输出：[{"type": "human", "data": {...}}]
+//   - lc 序列化对象：{"lc": 1, "type": "constructor", "id": [..., "HumanMessage"], "kwargs": {...}}
+//   - 扁平的消息字典：{"type": "human", "content": ...} 或 {"role": "user", "content": ...}
+//   - LangSmith 运行记录：inputs.messages（可嵌套一层）之后接 outputs.generations 或 outputs.messages
+func decodeLangChain(data []byte) ([]providers.ChatMessage, error) {
+	data = bytes.TrimSpace(data)
+	var list []json.RawMessage
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if list, err = langSmithMessages(data); err != nil {
+			return nil, err
+		}
+	}
+
+	messages := make([]providers.ChatMessage, 0, len(list))
+	for i, raw := range list {
+		m, err := langChainDecodeMessage(raw)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条消息: %w", i+1, err)
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// langSmithMessages 从 LangSmith 运行记录中取出输入消息和输出消息。
+func langSmithMessages(data []byte) ([]json.RawMessage, error) {
+	var run struct {
+		Inputs struct {
+			Messages json.RawMessage `json:"messages"`
+		} `json:"inputs"`
+		Outputs struct {
+			Messages    json.RawMessage `json:"messages"`
+			Generations json.RawMessage `json:"generations"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	if len(run.Inputs.Messages) == 0 {
+		return nil, fmt.Errorf("缺少 inputs.messages 字段")
+	}
+
+	list, err := flattenMessages(run.Inputs.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("inputs.messages 字段: %w", err)
+	}
+	if len(run.Outputs.Messages) > 0 {
+		outputs, err := flattenMessages(run.Outputs.Messages)
+		if err != nil {
+			return nil, fmt.Errorf("outputs.messages 字段: %w", err)
+		}
+		// LangGraph 的输出通常包含完整的消息列表，跳过与输入重复的前缀
+		if len(outputs) >= len(list) {
+			outputs = outputs[len(list):]
+		}
+		list = append(list, outputs...)
+	}
+	if len(run.Outputs.Generations) > 0 {
+		generations, err := flattenMessages(run.Outputs.Generations)
+		if err != nil {
+			return nil, fmt.Errorf("outputs.generations 字段: %w", err)
+		}
+		for _, raw := range generations {
+			var g struct {
+				Message json.RawMessage `json:"message"`
+			}
+			if json.Unmarshal(raw, &g) == nil && len(g.Message) > 0 {
+				list = append(list, g.Message)
+			}
+		}
+	}
+	return list, nil
+}
+
+// flattenMessages 解析消息数组，批量调用时的嵌套数组展开为一层。
+func flattenMessages(raw json.RawMessage) ([]json.RawMessage, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, err
+	}
+	var out []json.RawMessage
+	for _, item := range list {
+		if item = bytes.TrimSpace(item); len(item) > 0 && item[0] == '[' {
+			nested, err := flattenMessages(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, nested...)
+			continue
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+// langChainDecodeMessage 解析一条消息的任一种表示。
+func langChainDecodeMessage(raw json.RawMessage) (providers.ChatMessage, error) {
+	var envelope struct {
+		Type   string          `json:"type"`
+		Role   string          `json:"role"`
+		ID     []string        `json:"id"`
+		LC     int             `json:"lc"`
+		Data   json.RawMessage `json:"data"`
+		Kwargs json.RawMessage `json:"kwargs"`
+	}
+	// lc 序列化对象的 id 是类路径，其他表示中的 id 是字符串，解析失败时忽略
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		envelope.ID = nil
+		var plain struct {
+			Type string          `json:"type"`
+			Role string          `json:"role"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &plain); err != nil {
+			return providers.ChatMessage{}, fmt.Errorf("格式错误: %w", err)
+		}
+		envelope.Type, envelope.Role, envelope.Data = plain.Type, plain.Role, plain.Data
+	}
+
+	typ, body := envelope.Type, raw
+	switch {
+	case envelope.LC > 0 && len(envelope.ID) > 0:
+		typ, body = envelope.ID[len(envelope.ID)-1], envelope.Kwargs
+	case len(envelope.Data) > 0:
+		body = envelope.Data
+	case typ == "" && envelope.Role != "":
+		typ = envelope.Role
+	}
+
+	var data langChainData
+	if err := json.Unmarshal(body, &data); err != nil {
+		return providers.ChatMessage{}, fmt.Errorf("格式错误: %w", err)
+	}
+	content, err := contentText(data.Content)
+	if err != nil {
+		return providers.ChatMessage{}, err
+	}
+
+	role, ok := langChainRoles[typ]
+	switch {
+	case ok:
+	case typ == "chat" || typ == "ChatMessage":
+		role = data.Role
+	case typ == roleUser || typ == roleAssistant:
+		role = typ
+	default:
+		return providers.ChatMessage{}, fmt.Errorf("未知的消息类型: %s", typ)
+	}
+
+	m := providers.ChatMessage{Role: role, Content: content, Name: data.Name, ToolCallID: data.ToolCallID}
+	for _, tc := range data.ToolCalls {
+		if tc.Name != "" {
+			m.ToolCalls = append(m.ToolCalls, newToolCall(tc.ID, tc.Name, argumentsText(tc.Args)))
+		}
+	}
+	if len(m.ToolCalls) == 0 && role == roleAssistant {
+		m.ToolCalls = openAIToolCalls(body)
+	}
+	return m, nil
+}
+
+// openAIToolCalls 解析 OpenAI 格式的工具调用，来自 OpenAI 风格的消息字典，
+// 或旧版 LangChain 放在 additional_kwargs 中的工具调用。
+func openAIToolCalls(body json.RawMessage) []providers.ToolCall {
+	var data struct {
+		ToolCalls        []openAIToolCall `json:"tool_calls"`
+		AdditionalKwargs struct {
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"additional_kwargs"`
+	}
+	if json.Unmarshal(body, &data) != nil {
+		return nil
+	}
+	var calls []providers.ToolCall
+	for _, otc := range append(data.ToolCalls, data.AdditionalKwargs.ToolCalls...) {
+		if otc.Function.Name != "" {
+			calls = append(calls, newToolCall(otc.ID, otc.Function.Name, otc.Function.Arguments))
+		}
+	}
+	return calls
+}
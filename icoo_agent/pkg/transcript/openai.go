@@ -0,0 +1,87 @@
+package transcript
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+
+	"icooclaw/pkg/providers"
+)
+
+// openAIConversation OpenAI 对话文件。
+type openAIConversation struct {
+	Messages []openAIMessage `json:"messages"`
+}
+
+// openAIMessage OpenAI Chat Completions 消息。
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	Name       string           `json:"name,omitempty"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// encodeOpenAI 编码为 {"messages": [...]}。只有工具调用的助手消息 content 为 null，与 API 一致。
+func encodeOpenAI(messages []providers.ChatMessage) *openAIConversation {
+	conv := &openAIConversation{Messages: make([]openAIMessage, 0, len(messages))}
+	for _, m := range messages {
+		om := openAIMessage{Role: m.Role, Name: m.Name, ToolCallID: m.ToolCallID}
+		if m.Content != "" || len(m.ToolCalls) == 0 {
+			om.Content = &m.Content
+		}
+		for _, tc := range m.ToolCalls {
+			otc := openAIToolCall{ID: tc.ID, Type: cmp.Or(tc.Type, toolCallType)}
+			otc.Function.Name = tc.Function.Name
+			otc.Function.Arguments = tc.Function.Arguments
+			om.ToolCalls = append(om.ToolCalls, otc)
+		}
+		conv.Messages = append(conv.Messages, om)
+	}
+	return conv
+}
+
+// decodeOpenAI 解析 messages 数组或包含 messages 字段的对象（如请求体、微调数据的一行）。
+// content 可以是字符串、null 或内容块数组，内容块只保留文本。
+func decodeOpenAI(data []byte) ([]providers.ChatMessage, error) {
+	list, _, err := unwrapList(data, "messages")
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]providers.ChatMessage, 0, len(list))
+	for i, raw := range list {
+		var om struct {
+			openAIMessage
+			Content json.RawMessage `json:"content"`
+		}
+		if err := json.Unmarshal(raw, &om); err != nil {
+			return nil, fmt.Errorf("第 %d 条消息格式错误: %w", i+1, err)
+		}
+		if om.Role == "" {
+			return nil, fmt.Errorf("第 %d 条消息缺少 role", i+1)
+		}
+		content, err := contentText(om.Content)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条消息: %w", i+1, err)
+		}
+
+		m := providers.ChatMessage{Role: om.Role, Content: content, Name: om.Name, ToolCallID: om.ToolCallID}
+		for _, otc := range om.ToolCalls {
+			tc := newToolCall(otc.ID, otc.Function.Name, otc.Function.Arguments)
+			tc.Type = cmp.Or(otc.Type, toolCallType)
+			m.ToolCalls = append(m.ToolCalls, tc)
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
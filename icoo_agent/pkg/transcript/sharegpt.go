@@ -0,0 +1,166 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/providers"
+)
+
+// ShareGPT 轮次类型
+const (
+	shareGPTSystem       = "system"
+	shareGPTHuman        = "human"
+	shareGPTGPT          = "gpt"
+	shareGPTFunctionCall = "function_call"
+	shareGPTObservation  = "observation"
+)
+
+// shareGPTConversation ShareGPT 数据集中的一条对话。
+type shareGPTConversation struct {
+	Conversations []shareGPTTurn `json:"conversations"`
+}
+
+type shareGPTTurn struct {
+	From  string `json:"from"`
+	Value string `json:"value"`
+}
+
+// shareGPTCall function_call 轮次中的一次调用。
+type shareGPTCall struct {
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// encodeShareGPT 编码为 {"conversations": [...]}，采用 LLaMA-Factory 的工具调用约定：
+// 助手的工具调用是 function_call 轮次，值为调用数组的 JSON（额外保存调用 ID），工具结果是 observation 轮次。
+// 有回复又有工具调用的助手消息拆为 gpt 和 function_call 两个轮次，解析时再合并；
+// ShareGPT 没有消息名称，Name 不会导出。
+func encodeShareGPT(messages []providers.ChatMessage) *shareGPTConversation {
+	conv := &shareGPTConversation{Conversations: make([]shareGPTTurn, 0, len(messages))}
+	for _, m := range messages {
+		switch m.Role {
+		case roleSystem:
+			conv.Conversations = append(conv.Conversations, shareGPTTurn{From: shareGPTSystem, Value: m.Content})
+		case roleAssistant:
+			if m.Content != "" || len(m.ToolCalls) == 0 {
+				conv.Conversations = append(conv.Conversations, shareGPTTurn{From: shareGPTGPT, Value: m.Content})
+			}
+			if len(m.ToolCalls) == 0 {
+				continue
+			}
+			calls := make([]shareGPTCall, 0, len(m.ToolCalls))
+			for _, tc := range m.ToolCalls {
+				calls = append(calls, shareGPTCall{ID: tc.ID, Name: tc.Function.Name, Arguments: rawArguments(tc.Function.Arguments)})
+			}
+			value, _ := json.Marshal(calls)
+			conv.Conversations = append(conv.Conversations, shareGPTTurn{From: shareGPTFunctionCall, Value: string(value)})
+		case roleTool:
+			conv.Conversations = append(conv.Conversations, shareGPTTurn{From: shareGPTObservation, Value: m.Content})
+		default:
+			conv.Conversations = append(conv.Conversations, shareGPTTurn{From: shareGPTHuman, Value: m.Content})
+		}
+	}
+	return conv
+}
+
+// decodeShareGPT 解析包含 conversations 字段的对象或轮次数组，顶层 system 字段作为第一条系统消息。
+// 轮次的 from 也接受 user、assistant、tool 等别名；function_call 的值可以是单个调用或调用数组，
+// 没有 ID 时按位置生成。observation 按顺序对应尚未返回结果的调用。
+func decodeShareGPT(data []byte) ([]providers.ChatMessage, error) {
+	list, obj, err := unwrapList(data, "conversations")
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []providers.ChatMessage
+	if obj != nil {
+		var top struct {
+			System string `json:"system"`
+		}
+		if err := json.Unmarshal(obj, &top); err != nil {
+			return nil, err
+		}
+		if top.System != "" {
+			messages = append(messages, providers.ChatMessage{Role: roleSystem, Content: top.System})
+		}
+	}
+
+	var pending []providers.ToolCall
+	callIndex := 0
+	for i, raw := range list {
+		var turn struct {
+			From    string `json:"from"`
+			Role    string `json:"role"`
+			Value   string `json:"value"`
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal(raw, &turn); err != nil {
+			return nil, fmt.Errorf("第 %d 个轮次格式错误: %w", i+1, err)
+		}
+		from, value := turn.From, turn.Value
+		if from == "" {
+			from, value = turn.Role, turn.Content
+		}
+
+		switch from {
+		case shareGPTSystem:
+			messages = append(messages, providers.ChatMessage{Role: roleSystem, Content: value})
+		case shareGPTHuman, roleUser:
+			messages = append(messages, providers.ChatMessage{Role: roleUser, Content: value})
+		case shareGPTGPT, "chatgpt", "bing", "bard", roleAssistant:
+			messages = append(messages, providers.ChatMessage{Role: roleAssistant, Content: value})
+		case shareGPTFunctionCall:
+			calls, err := shareGPTCalls(value)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 个轮次: %w", i+1, err)
+			}
+			var toolCalls []providers.ToolCall
+			for _, c := range calls {
+				callIndex++
+				id := c.ID
+				if id == "" {
+					id = fmt.Sprintf("call_%d", callIndex)
+				}
+				toolCalls = append(toolCalls, newToolCall(id, c.Name, argumentsText(c.Arguments)))
+			}
+			pending = append(pending, toolCalls...)
+
+			// 紧跟在回复之后的调用属于同一条助手消息
+			if n := len(messages); n > 0 && messages[n-1].Role == roleAssistant && len(messages[n-1].ToolCalls) == 0 {
+				messages[n-1].ToolCalls = toolCalls
+				continue
+			}
+			messages = append(messages, providers.ChatMessage{Role: roleAssistant, ToolCalls: toolCalls})
+		case shareGPTObservation, roleTool, "function":
+			m := providers.ChatMessage{Role: roleTool, Content: value}
+			if len(pending) > 0 {
+				m.ToolCallID = pending[0].ID
+				pending = pending[1:]
+			}
+			messages = append(messages, m)
+		default:
+			return nil, fmt.Errorf("第 %d 个轮次的 from 未知: %s", i+1, from)
+		}
+	}
+	return messages, nil
+}
+
+// shareGPTCalls 解析 function_call 轮次的值。
+func shareGPTCalls(value string) ([]shareGPTCall, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") {
+		var calls []shareGPTCall
+		if err := json.Unmarshal([]byte(value), &calls); err != nil {
+			return nil, fmt.Errorf("function_call 格式错误: %w", err)
+		}
+		return calls, nil
+	}
+	var call shareGPTCall
+	if err := json.Unmarshal([]byte(value), &call); err != nil {
+		return nil, fmt.Errorf("function_call 格式错误: %w", err)
+	}
+	return []shareGPTCall{call}, nil
+}
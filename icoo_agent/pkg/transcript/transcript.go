@@ -0,0 +1,204 @@
+// Package transcript 在内部消息格式（providers.ChatMessage）与常见的外部对话格式之间双向转换，
+// 供对话导入导出、评测用例和轨迹回放使用。
+//
+// 支持的格式：
+//
+//	openai     OpenAI Chat Completions 的 messages 数组
+//	anthropic  Anthropic Messages API 的 system 和 messages
+//	langchain  LangChain messages_to_dict 输出，解析时也接受 LangSmith 运行记录和 lc 序列化对象
+//	sharegpt   ShareGPT 的 conversations（含 function_call / observation 工具轮次）
+//
+// 角色、内容、工具调用（ID、名称、参数）和工具结果在各格式间往返不丢失。
+// 各格式无法表示的内容见对应的 Encode 函数说明。
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/providers"
+)
+
+// 对话格式。
+const (
+	FormatOpenAI    = "openai"
+	FormatAnthropic = "anthropic"
+	FormatLangChain = "langchain"
+	FormatShareGPT  = "sharegpt"
+)
+
+// 内部消息角色
+var (
+	roleSystem    = consts.RoleSystem.ToString()
+	roleUser      = consts.RoleUser.ToString()
+	roleAssistant = consts.RoleAssistant.ToString()
+	roleTool      = consts.RoleTool.ToString()
+)
+
+// toolCallType 工具调用类型，解析时缺省为 function
+const toolCallType = "function"
+
+// Formats 返回支持的格式名称。
+func Formats() []string {
+	return []string{FormatOpenAI, FormatAnthropic, FormatLangChain, FormatShareGPT}
+}
+
+// ParseFormat 解析格式名称，langsmith 视为 langchain，claude 视为 anthropic。
+func ParseFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case FormatOpenAI:
+		return FormatOpenAI, nil
+	case FormatAnthropic, "claude":
+		return FormatAnthropic, nil
+	case FormatLangChain, "langsmith":
+		return FormatLangChain, nil
+	case FormatShareGPT:
+		return FormatShareGPT, nil
+	default:
+		return "", fmt.Errorf("未知的对话格式: %s（可选 %s）", format, strings.Join(Formats(), "、"))
+	}
+}
+
+// Encode 将消息编码为指定格式的 JSON。
+func Encode(format string, messages []providers.ChatMessage) ([]byte, error) {
+	format, err := ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	switch format {
+	case FormatOpenAI:
+		v = encodeOpenAI(messages)
+	case FormatAnthropic:
+		v = encodeAnthropic(messages)
+	case FormatLangChain:
+		v = encodeLangChain(messages)
+	case FormatShareGPT:
+		v = encodeShareGPT(messages)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("编码 %s 对话失败: %w", format, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode 解析指定格式的 JSON 对话。
+func Decode(format string, data []byte) ([]providers.ChatMessage, error) {
+	format, err := ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []providers.ChatMessage
+	switch format {
+	case FormatOpenAI:
+		messages, err = decodeOpenAI(data)
+	case FormatAnthropic:
+		messages, err = decodeAnthropic(data)
+	case FormatLangChain:
+		messages, err = decodeLangChain(data)
+	case FormatShareGPT:
+		messages, err = decodeShareGPT(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 对话失败: %w", format, err)
+	}
+	return messages, nil
+}
+
+// newToolCall 创建工具调用。
+func newToolCall(id, name, arguments string) providers.ToolCall {
+	tc := providers.ToolCall{ID: id, Type: toolCallType}
+	tc.Function.Name = name
+	tc.Function.Arguments = arguments
+	return tc
+}
+
+// rawArguments 将工具调用参数转为 JSON 值：空参数为 {}，不是合法 JSON 的参数编码为字符串。
+func rawArguments(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(arguments)) {
+		return json.RawMessage(arguments)
+	}
+	data, _ := json.Marshal(arguments)
+	return data
+}
+
+// argumentsText 是 rawArguments 的逆过程，JSON 字符串还原为原文。
+func argumentsText(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return ""
+	}
+	var s string
+	if raw[0] == '"' && json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, raw) != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// contentText 解析字符串或内容块数组形式的消息内容，拼接其中的文本块。
+func contentText(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "", nil
+	}
+	if raw[0] == '"' {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", fmt.Errorf("消息内容格式错误: %w", err)
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" || p.Type == "input_text" || p.Type == "output_text" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// unwrapList 解析顶层数组，或对象中 key 字段的数组。
+func unwrapList(data []byte, key string) ([]json.RawMessage, json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	var list []json.RawMessage
+	if len(data) > 0 && data[0] == '[' {
+		err := json.Unmarshal(data, &list)
+		return list, nil, err
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, nil, err
+	}
+	raw, ok := obj[key]
+	if !ok {
+		return nil, nil, fmt.Errorf("缺少 %s 字段", key)
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, nil, fmt.Errorf("%s 字段格式错误: %w", key, err)
+	}
+	return list, data, nil
+}
@@ -0,0 +1,214 @@
+package transcript
+
+import (
+	"reflect"
+	"testing"
+
+	"icooclaw/pkg/providers"
+)
+
+// testConversation 覆盖系统提示、多轮对话、并行工具调用和带回复的工具调用。
+func testConversation() []providers.ChatMessage {
+	return []providers.ChatMessage{
+		{Role: "system", Content: "你是助手"},
+		{Role: "user", Content: "列出文件并读取 a.go"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{
+			newToolCall("c1", "list_directory", `{"path":"."}`),
+			newToolCall("c2", "read_file", `{"path":"a.go","limit":10}`),
+		}},
+		{Role: "tool", Content: "a.go\nb.go", ToolCallID: "c1"},
+		{Role: "tool", Content: "package main", ToolCallID: "c2"},
+		{Role: "assistant", Content: "再看看时间", ToolCalls: []providers.ToolCall{
+			newToolCall("c3", "datetime", `{}`),
+		}},
+		{Role: "tool", Content: "2024-05-01 10:00:00", ToolCallID: "c3"},
+		{Role: "assistant", Content: "目录中有 a.go 和 b.go，<a.go> 是 main 包。"},
+		{Role: "user", Content: "谢谢"},
+		{Role: "assistant", Content: ""},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := testConversation()
+	for _, format := range Formats() {
+		t.Run(format, func(t *testing.T) {
+			data, err := Encode(format, want)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			got, err := Decode(format, data)
+			if err != nil {
+				t.Fatalf("Decode() error = %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip mismatch\n got: %+v\nwant: %+v\n%s", got, want, data)
+			}
+
+			// 再编码一次应得到相同的内容
+			again, err := Encode(format, got)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if string(again) != string(data) {
+				t.Errorf("re-encode mismatch\n got: %s\nwant: %s", again, data)
+			}
+		})
+	}
+}
+
+func TestRoundTrip_Names(t *testing.T) {
+	want := []providers.ChatMessage{
+		{Role: "user", Content: "你好", Name: "alice"},
+		{Role: "assistant", Content: "你好", Name: "bot"},
+		{Role: "tool", Content: "ok", Name: "datetime", ToolCallID: "c1"},
+		{Role: "agent", Content: "其他角色"},
+	}
+	for _, format := range []string{FormatOpenAI, FormatLangChain} {
+		data, err := Encode(format, want)
+		if err != nil {
+			t.Fatalf("%s: Encode() error = %v", format, err)
+		}
+		got, err := Decode(format, data)
+		if err != nil {
+			t.Fatalf("%s: Decode() error = %v", format, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", format, got, want)
+		}
+	}
+}
+
+func TestRoundTrip_InvalidArguments(t *testing.T) {
+	want := []providers.ChatMessage{
+		{Role: "assistant", ToolCalls: []providers.ToolCall{newToolCall("c1", "shell_command", `ls -la`)}},
+		{Role: "tool", Content: "total 0", ToolCallID: "c1"},
+	}
+	for _, format := range Formats() {
+		data, err := Encode(format, want)
+		if err != nil {
+			t.Fatalf("%s: Encode() error = %v", format, err)
+		}
+		got, err := Decode(format, data)
+		if err != nil {
+			t.Fatalf("%s: Decode() error = %v", format, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", format, got, want)
+		}
+	}
+}
+
+func TestDecode_OpenAI(t *testing.T) {
+	got, err := Decode(FormatOpenAI, []byte(`[
+		{"role": "user", "content": [{"type": "text", "text": "看图"}, {"type": "image_url", "image_url": {"url": "x"}}]},
+		{"role": "assistant", "content": null, "tool_calls": [{"id": "c1", "function": {"name": "f", "arguments": "{}"}}]}
+	]`))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []providers.ChatMessage{
+		{Role: "user", Content: "看图"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{newToolCall("c1", "f", "{}")}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := Decode(FormatOpenAI, []byte(`{"messages": [{"content": "x"}]}`)); err == nil {
+		t.Error("Decode() error = nil, want missing role error")
+	}
+}
+
+func TestDecode_Anthropic(t *testing.T) {
+	got, err := Decode(FormatAnthropic, []byte(`{
+		"system": "你是助手",
+		"messages": [
+			{"role": "user", "content": "天气"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "..."},
+				{"type": "text", "text": "查一下"},
+				{"type": "tool_use", "id": "t1", "name": "weather", "input": {"city": "北京"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "t1", "content": [{"type": "text", "text": "晴"}]},
+				{"type": "text", "text": "明天呢"}
+			]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []providers.ChatMessage{
+		{Role: "system", Content: "你是助手"},
+		{Role: "user", Content: "天气"},
+		{Role: "assistant", Content: "查一下", ToolCalls: []providers.ToolCall{newToolCall("t1", "weather", `{"city":"北京"}`)}},
+		{Role: "tool", Content: "晴", ToolCallID: "t1"},
+		{Role: "user", Content: "明天呢"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecode_LangSmith(t *testing.T) {
+	got, err := Decode("langsmith", []byte(`{
+		"inputs": {"messages": [[
+			{"lc": 1, "type": "constructor", "id": ["langchain", "schema", "messages", "SystemMessage"], "kwargs": {"content": "你是助手"}},
+			{"lc": 1, "type": "constructor", "id": ["langchain", "schema", "messages", "HumanMessage"], "kwargs": {"content": "1+1"}},
+			{"type": "ai", "content": "", "tool_calls": [{"name": "calc", "args": {"expr": "1+1"}, "id": "x1"}], "id": "run-1"},
+			{"role": "tool", "content": "2", "tool_call_id": "x1"}
+		]]},
+		"outputs": {"generations": [[{"text": "等于 2", "message": {"lc": 1, "type": "constructor", "id": ["langchain", "schema", "messages", "AIMessage"], "kwargs": {"content": "等于 2"}}}]]}
+	}`))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []providers.ChatMessage{
+		{Role: "system", Content: "你是助手"},
+		{Role: "user", Content: "1+1"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{newToolCall("x1", "calc", `{"expr":"1+1"}`)}},
+		{Role: "tool", Content: "2", ToolCallID: "x1"},
+		{Role: "assistant", Content: "等于 2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecode_ShareGPT(t *testing.T) {
+	got, err := Decode(FormatShareGPT, []byte(`{
+		"system": "你是助手",
+		"conversations": [
+			{"from": "human", "value": "北京和上海天气"},
+			{"from": "function_call", "value": "{\"name\": \"weather\", \"arguments\": {\"city\": \"北京\"}}"},
+			{"from": "observation", "value": "晴"},
+			{"from": "gpt", "value": "北京晴"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []providers.ChatMessage{
+		{Role: "system", Content: "你是助手"},
+		{Role: "user", Content: "北京和上海天气"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{newToolCall("call_1", "weather", `{"city":"北京"}`)}},
+		{Role: "tool", Content: "晴", ToolCallID: "call_1"},
+		{Role: "assistant", Content: "北京晴"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := Decode(FormatShareGPT, []byte(`[{"from": "narrator", "value": "x"}]`)); err == nil {
+		t.Error("Decode() error = nil, want unknown from error")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml) error = nil")
+	}
+	if f, _ := ParseFormat("Claude"); f != FormatAnthropic {
+		t.Errorf("ParseFormat(Claude) = %q", f)
+	}
+}
@@ -0,0 +1,372 @@
+// Package update 检查发布渠道中的新版本，校验校验和与签名后原子替换当前可执行文件。
+//
+// 发布端点为每个渠道提供一个清单 <endpoint>/<channel>.json，列出版本和各平台的文件。
+// 发布者用 ed25519 私钥对 SignedMessage 签名，客户端用配置的公钥校验，
+// 签名内容包含渠道、版本和平台，旧版本或其他渠道的合法签名不能用来降级、
+// 把测试版推给稳定版用户，或替换为其他平台的文件。
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 发布渠道
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+// maxManifestSize 清单的最大长度
+const maxManifestSize = 1 << 20
+
+// ErrNoAsset 清单中没有当前平台的文件
+var ErrNoAsset = errors.New("发布清单中没有当前平台的文件")
+
+// Manifest 发布渠道的版本清单。
+type Manifest struct {
+	Version     string           `json:"version"`
+	PublishedAt time.Time        `json:"published_at"`
+	Notes       string           `json:"notes,omitempty"`
+	Assets      map[string]Asset `json:"assets"` // 平台（GOOS-GOARCH）-> 文件
+}
+
+// Asset 一个平台的可执行文件。
+type Asset struct {
+	URL       string `json:"url"`       // 下载地址，可以是相对清单的路径
+	SHA256    string `json:"sha256"`    // 十六进制校验和
+	Signature string `json:"signature"` // base64 编码的 ed25519 签名，签名内容见 SignedMessage
+}
+
+// Release 渠道中的最新版本。
+type Release struct {
+	Version     string
+	Channel     string
+	Platform    string
+	PublishedAt time.Time
+	Notes       string
+	Asset       Asset
+}
+
+// SignedMessage returns the bytes signed for a release file. It binds the
+// channel, version and platform to the checksum so that a signature cannot be
+// reused for another channel, version or platform.
+func SignedMessage(channel, version, platform, sum string) []byte {
+	return []byte(fmt.Sprintf("icooclaw %s %s %s %s", channel, version, platform, strings.ToLower(sum)))
+}
+
+// Platform 返回当前平台，如 linux-amd64。
+func Platform() string {
+	return runtime.GOOS + "-" + runtime.GOARCH
+}
+
+// ValidChannel 判断渠道名称是否有效。
+func ValidChannel(channel string) bool {
+	return channel == ChannelStable || channel == ChannelBeta
+}
+
+// Updater 从发布端点检查和下载新版本。
+type Updater struct {
+	endpoint  string
+	channel   string
+	publicKey ed25519.PublicKey
+	client    *http.Client
+}
+
+// New creates an updater for the release channel. publicKey is the base64
+// encoded ed25519 key that release files are signed with.
+func New(endpoint, channel, publicKey string) (*Updater, error) {
+	if endpoint == "" {
+		return nil, errors.New("未配置发布端点 update.endpoint")
+	}
+	if !ValidChannel(channel) {
+		return nil, fmt.Errorf("发布渠道只能是 %s 或 %s: %q", ChannelStable, ChannelBeta, channel)
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("update.public_key 必须是 base64 编码的 ed25519 公钥")
+	}
+	return &Updater{
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		channel:   channel,
+		publicKey: key,
+		client:    &http.Client{Timeout: 10 * time.Minute},
+	}, nil
+}
+
+// Check fetches the channel manifest and returns its release for the current
+// platform, and whether it is newer than current.
+func (u *Updater) Check(ctx context.Context, current string) (*Release, bool, error) {
+	manifestURL := u.endpoint + "/" + u.channel + ".json"
+	resp, err := u.get(ctx, manifestURL)
+	if err != nil {
+		return nil, false, fmt.Errorf("获取发布清单失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var m Manifest
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&m); err != nil {
+		return nil, false, fmt.Errorf("解析发布清单失败: %w", err)
+	}
+	if m.Version == "" {
+		return nil, false, errors.New("发布清单缺少 version")
+	}
+
+	platform := Platform()
+	asset, ok := m.Assets[platform]
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %s", ErrNoAsset, platform)
+	}
+	if asset.URL == "" || asset.SHA256 == "" || asset.Signature == "" {
+		return nil, false, fmt.Errorf("发布清单中 %s 的文件缺少 url、sha256 或 signature", platform)
+	}
+	// 相对地址按清单地址解析
+	base, _ := url.Parse(manifestURL)
+	ref, err := url.Parse(asset.URL)
+	if err != nil {
+		return nil, false, fmt.Errorf("文件地址无效: %w", err)
+	}
+	asset.URL = base.ResolveReference(ref).String()
+
+	r := &Release{
+		Version:     m.Version,
+		Channel:     u.channel,
+		Platform:    platform,
+		PublishedAt: m.PublishedAt,
+		Notes:       m.Notes,
+		Asset:       asset,
+	}
+	return r, Compare(m.Version, current) > 0, nil
+}
+
+// Download downloads the release file into dir, next to the executable it
+// will replace so that the final rename stays on one filesystem, and verifies
+// its checksum and signature. The file is removed when verification fails.
+func (u *Updater) Download(ctx context.Context, r *Release, dir string) (string, error) {
+	want, err := hex.DecodeString(r.Asset.SHA256)
+	if err != nil || len(want) != sha256.Size {
+		return "", errors.New("发布清单中的 sha256 无效")
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Asset.Signature)
+	if err != nil {
+		return "", errors.New("发布清单中的签名无效")
+	}
+	if !ed25519.Verify(u.publicKey, SignedMessage(r.Channel, r.Version, r.Platform, r.Asset.SHA256), sig) {
+		return "", errors.New("签名校验失败，发布清单可能被篡改")
+	}
+
+	resp, err := u.get(ctx, r.Asset.URL)
+	if err != nil {
+		return "", fmt.Errorf("下载新版本失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	f, err := os.CreateTemp(dir, ".icooclaw-update-*")
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	path := f.Name()
+	ok := false
+	defer func() {
+		if !ok {
+			f.Close()
+			os.Remove(path)
+		}
+	}()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		return "", fmt.Errorf("下载新版本失败: %w", err)
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		return "", fmt.Errorf("校验和不匹配: 期望 %s，实际 %x", r.Asset.SHA256, got)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := os.Chmod(path, 0o755); err != nil {
+		return "", fmt.Errorf("设置可执行权限失败: %w", err)
+	}
+	ok = true
+	return path, nil
+}
+
+func (u *Updater) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s 返回 %s", rawURL, resp.Status)
+	}
+	return resp, nil
+}
+
+// BackupPath 返回替换前保存旧版本的路径。
+func BackupPath(exe string) string {
+	return exe + ".old"
+}
+
+// Install replaces exe with the verified file at path and keeps the previous
+// version at BackupPath(exe). Where hard links are supported the old file is
+// linked to the backup first and the new file is renamed over exe in one step,
+// so exe is never missing; otherwise exe is renamed away first and restored
+// if the second rename fails.
+func Install(path, exe string) error {
+	backup := BackupPath(exe)
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除旧的备份失败: %w", err)
+	}
+
+	if err := os.Link(exe, backup); err == nil {
+		if err := os.Rename(path, exe); err != nil {
+			os.Remove(backup)
+			return fmt.Errorf("替换可执行文件失败: %w", err)
+		}
+		return nil
+	}
+
+	// 不支持硬链接（如 Windows 上运行中的文件）时先移走旧文件
+	if err := os.Rename(exe, backup); err != nil {
+		return fmt.Errorf("备份当前版本失败: %w", err)
+	}
+	if err := os.Rename(path, exe); err != nil {
+		if restoreErr := os.Rename(backup, exe); restoreErr != nil {
+			return fmt.Errorf("替换可执行文件失败且无法恢复，旧版本位于 %s: %w", backup, err)
+		}
+		return fmt.Errorf("替换可执行文件失败: %w", err)
+	}
+	return nil
+}
+
+// Rollback 用 Install 保留的备份恢复上一个版本。
+func Rollback(exe string) error {
+	backup := BackupPath(exe)
+	if _, err := os.Stat(backup); err != nil {
+		return fmt.Errorf("没有可恢复的旧版本: %w", err)
+	}
+	if err := os.Rename(backup, exe); err != nil {
+		if runtime.GOOS != "windows" {
+			return fmt.Errorf("恢复旧版本失败: %w", err)
+		}
+		// Windows 上不能覆盖运行中的文件，先移走当前版本
+		current := exe + ".rollback"
+		os.Remove(current)
+		if err := os.Rename(exe, current); err != nil {
+			return fmt.Errorf("恢复旧版本失败: %w", err)
+		}
+		if err := os.Rename(backup, exe); err != nil {
+			os.Rename(current, exe)
+			return fmt.Errorf("恢复旧版本失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Compare compares two versions such as 1.4.0, v1.4.0 or 1.5.0-beta.2 and
+// returns -1, 0 or 1. A release is newer than its pre-releases, and a version
+// that cannot be parsed (such as "dev") is older than any release.
+func Compare(a, b string) int {
+	av, aok := parseVersion(a)
+	bv, bok := parseVersion(b)
+	switch {
+	case !aok && !bok:
+		return strings.Compare(a, b)
+	case !aok:
+		return -1
+	case !bok:
+		return 1
+	}
+
+	for i := range max(len(av.nums), len(bv.nums)) {
+		var x, y int
+		if i < len(av.nums) {
+			x = av.nums[i]
+		}
+		if i < len(bv.nums) {
+			y = bv.nums[i]
+		}
+		if x != y {
+			return cmp(x, y)
+		}
+	}
+	switch {
+	case av.pre == bv.pre:
+		return 0
+	case av.pre == "":
+		return 1
+	case bv.pre == "":
+		return -1
+	}
+	return comparePre(av.pre, bv.pre)
+}
+
+// version 解析后的版本号。
+type version struct {
+	nums []int
+	pre  string
+}
+
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+") // 构建元数据不参与比较
+	core, pre, _ := strings.Cut(s, "-")
+	var v version
+	for _, part := range strings.Split(core, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.nums = append(v.nums, n)
+	}
+	v.pre = pre
+	return v, true
+}
+
+// comparePre 按 . 分段比较预发布标识，数字段按数值比较。
+func comparePre(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(as), len(bs)) {
+		x, xerr := strconv.Atoi(as[i])
+		y, yerr := strconv.Atoi(bs[i])
+		switch {
+		case xerr == nil && yerr == nil:
+			if x != y {
+				return cmp(x, y)
+			}
+		case as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return cmp(len(as), len(bs))
+}
+
+func cmp(x, y int) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,179 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.4.0", "1.4.0", 0},
+		{"v1.4.0", "1.4", 0},
+		{"1.10.0", "1.9.3", 1},
+		{"1.4.0", "1.4.0-beta.1", 1},
+		{"1.4.0-beta.2", "1.4.0-beta.10", -1},
+		{"1.4.0-beta.1", "1.4.0-alpha.3", 1},
+		{"1.4.0+build.7", "1.4.0", 0},
+		{"0.0.1", "dev", 1},
+		{"dev", "0.0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// releaseServer 提供签名的发布清单和文件
+func releaseServer(t *testing.T, priv ed25519.PrivateKey, version string, binary []byte, tamper func(*Asset)) *httptest.Server {
+	t.Helper()
+	sum := sha256.Sum256(binary)
+	asset := Asset{
+		URL:    "files/icooclaw",
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+	asset.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, SignedMessage(ChannelBeta, version, Platform(), asset.SHA256)))
+	if tamper != nil {
+		tamper(&asset)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/releases/beta.json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Manifest{
+			Version: version,
+			Notes:   "修复若干问题",
+			Assets:  map[string]Asset{Platform(): asset},
+		})
+	})
+	mux.HandleFunc("/releases/files/icooclaw", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUpdater(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(pub)
+	binary := []byte("new binary")
+	ctx := context.Background()
+
+	srv := releaseServer(t, priv, "1.5.0-beta.1", binary, nil)
+	u, err := New(srv.URL+"/releases/", ChannelBeta, key)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	r, newer, err := u.Check(ctx, "1.4.2")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !newer || r.Version != "1.5.0-beta.1" || r.Asset.URL != srv.URL+"/releases/files/icooclaw" {
+		t.Fatalf("Check() = %+v, newer=%v", r, newer)
+	}
+	if _, newer, _ := u.Check(ctx, "1.5.0"); newer {
+		t.Error("Check() reported a pre-release as newer than the release")
+	}
+
+	dir := t.TempDir()
+	path, err := u.Download(ctx, r, dir)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(binary) {
+		t.Errorf("downloaded %q", data)
+	}
+
+	// 安装后旧版本保留为 .old，可以回滚
+	exe := filepath.Join(dir, "icooclaw")
+	os.WriteFile(exe, []byte("old binary"), 0o755)
+	if err := Install(path, exe); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != string(binary) {
+		t.Errorf("installed %q", data)
+	}
+	if data, _ := os.ReadFile(BackupPath(exe)); string(data) != "old binary" {
+		t.Errorf("backup %q", data)
+	}
+	if err := Rollback(exe); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+		t.Errorf("rolled back %q", data)
+	}
+	if err := Rollback(exe); err == nil {
+		t.Error("Rollback() without a backup should fail")
+	}
+}
+
+func TestUpdater_Rejects(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	key := base64.StdEncoding.EncodeToString(pub)
+	binary := []byte("new binary")
+
+	tests := []struct {
+		name   string
+		tamper func(*Asset)
+		want   string
+	}{
+		{"bad signature", func(a *Asset) {
+			// 旧版本的合法签名不能用于新版本
+			a.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, SignedMessage(ChannelBeta, "1.0.0", Platform(), a.SHA256)))
+		}, "签名校验失败"},
+		{"bad checksum", func(a *Asset) {
+			sum := sha256.Sum256([]byte("other binary"))
+			a.SHA256 = hex.EncodeToString(sum[:])
+			a.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, SignedMessage(ChannelBeta, "2.0.0", Platform(), a.SHA256)))
+		}, "校验和不匹配"},
+		{"other channel", func(a *Asset) {
+			// 稳定版的签名不能用于测试版清单，反之亦然
+			a.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, SignedMessage(ChannelStable, "2.0.0", Platform(), a.SHA256)))
+		}, "签名校验失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := releaseServer(t, priv, "2.0.0", binary, tt.tamper)
+			u, err := New(srv.URL+"/releases", ChannelBeta, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, _, err := u.Check(context.Background(), "1.0.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := t.TempDir()
+			if _, err := u.Download(context.Background(), r, dir); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Download() error = %v, want %q", err, tt.want)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("temporary file left behind: %v", entries)
+			}
+		})
+	}
+
+	if _, err := New("https://example.com", "nightly", key); err == nil {
+		t.Error("New() should reject an unknown channel")
+	}
+	if _, err := New("https://example.com", ChannelStable, "bad"); err == nil {
+		t.Error("New() should reject an invalid public key")
+	}
+}
@@ -0,0 +1,199 @@
+// Package userdata 导出和删除某个用户的全部数据：存储中的会话、消息、记忆、轨迹等记录，
+// 以及各工作目录中 users/<user_id> 下的文件。
+package userdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/workspace"
+)
+
+// UsersDir 工作目录中存放用户专属文件的子目录，每个用户一个 users/<user_id> 目录
+const UsersDir = "users"
+
+// File 用户专属文件。
+type File struct {
+	Workspace string `json:"workspace"`         // 所在工作目录名称
+	Path      string `json:"path"`              // 相对工作目录的路径
+	Size      int64  `json:"size"`              // 字节数
+	Content   []byte `json:"content,omitempty"` // 文件内容，导出时填充
+}
+
+// Trace 导出的对话轨迹，包含存储中不随 JSON 输出的轨迹详情。
+type Trace struct {
+	*storage.Trace
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Export 用户数据导出。
+type Export struct {
+	ExportedAt time.Time `json:"exported_at"`
+	*storage.UserData
+	Traces []Trace `json:"traces"`
+	Files  []File  `json:"files"`
+}
+
+// Report 删除报告，DryRun 为 true 时只列出将被删除的内容。
+type Report struct {
+	UserID string `json:"user_id"`
+	DryRun bool   `json:"dry_run"`
+	storage.UserDataCounts
+	Files      []string `json:"files"`      // 工作目录名称:相对路径
+	FileBytes  int64    `json:"file_bytes"` // 文件总字节数
+	FileErrors []string `json:"file_errors,omitempty"`
+}
+
+// Service 导出和删除用户数据。
+type Service struct {
+	storage    *storage.Storage
+	workspaces []workspace.Entry
+	logger     *slog.Logger
+}
+
+// New 创建用户数据服务，workspaces 为需要检查用户文件的工作目录。
+func New(s *storage.Storage, workspaces []workspace.Entry, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{storage: s, workspaces: workspaces, logger: logger}
+}
+
+// Export 导出用户的全部数据，包含文件内容。
+func (s *Service) Export(userID string) (*Export, error) {
+	if err := validateUserID(userID); err != nil {
+		return nil, err
+	}
+	data, err := s.storage.CollectUserData(userID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.files(userID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &Export{ExportedAt: time.Now(), UserData: data, Files: files}
+	export.Traces = make([]Trace, 0, len(data.Traces))
+	for _, t := range data.Traces {
+		item := Trace{Trace: t}
+		if json.Valid([]byte(t.Data)) {
+			item.Data = json.RawMessage(t.Data)
+		}
+		export.Traces = append(export.Traces, item)
+	}
+	return export, nil
+}
+
+// Plan 列出删除用户时将移除的内容，不做任何修改。
+func (s *Service) Plan(userID string) (*Report, error) {
+	if err := validateUserID(userID); err != nil {
+		return nil, err
+	}
+	data, err := s.storage.CollectUserData(userID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.files(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	return newReport(data, files, true), nil
+}
+
+// Purge 先导出再删除用户的全部数据，返回导出内容和删除报告。
+// 存储中的记录在一个事务中删除；文件删除失败记录在报告中，不回滚已删除的记录。
+func (s *Service) Purge(userID string) (*Export, *Report, error) {
+	export, err := s.Export(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.storage.DeleteUserData(export.UserData); err != nil {
+		return nil, nil, err
+	}
+
+	report := newReport(export.UserData, export.Files, false)
+	for _, ws := range s.workspaces {
+		dir := userDir(ws.Dir, userID)
+		if err := os.RemoveAll(dir); err != nil {
+			report.FileErrors = append(report.FileErrors, fmt.Sprintf("%s: %v", ws.Name, err))
+		}
+	}
+
+	s.logger.With("name", "【用户数据】").Info("已删除用户数据",
+		"user_id", userID,
+		"sessions", report.Sessions,
+		"messages", report.Messages,
+		"memories", report.Memories,
+		"files", len(report.Files))
+	return export, report, nil
+}
+
+// files 列出各工作目录中用户的文件，withContent 为 true 时读取内容。
+func (s *Service) files(userID string, withContent bool) ([]File, error) {
+	var files []File
+	for _, ws := range s.workspaces {
+		root := userDir(ws.Dir, userID)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(ws.Dir, path)
+			f := File{Workspace: ws.Name, Path: filepath.ToSlash(rel), Size: info.Size()}
+			if withContent && info.Mode().IsRegular() {
+				if f.Content, err = os.ReadFile(path); err != nil {
+					return err
+				}
+			}
+			files = append(files, f)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("读取工作目录 %s 中的用户文件失败: %w", ws.Name, err)
+		}
+	}
+	return files, nil
+}
+
+// newReport 根据收集到的数据生成报告。
+func newReport(data *storage.UserData, files []File, dryRun bool) *Report {
+	report := &Report{UserID: data.UserID, DryRun: dryRun, UserDataCounts: data.Counts(), Files: []string{}}
+	for _, f := range files {
+		report.Files = append(report.Files, f.Workspace+":"+f.Path)
+		report.FileBytes += f.Size
+	}
+	return report
+}
+
+// userDir 用户在工作目录中的专属目录。
+func userDir(root, userID string) string {
+	return filepath.Join(root, UsersDir, userID)
+}
+
+// validateUserID 校验用户 ID，避免拼接出工作目录之外的路径。
+func validateUserID(userID string) error {
+	if strings.TrimSpace(userID) == "" {
+		return fmt.Errorf("用户ID不能为空")
+	}
+	if userID == "." || userID == ".." || strings.ContainsAny(userID, `/\`) {
+		return fmt.Errorf("无效的用户ID: %s", userID)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package userdata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"icooclaw/pkg/consts"
+	"icooclaw/pkg/storage"
+	"icooclaw/pkg/workspace"
+)
+
+func TestPurge(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "userdata.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	// alice 有一个会话和一个归档会话，bob 的数据不受影响
+	for _, sess := range []*storage.Session{
+		{Model: storage.Model{ID: "a1"}, Channel: "feishu", UserID: "alice"},
+		{Model: storage.Model{ID: "a0"}, Channel: "feishu", UserID: "alice", Archived: true, ParentID: "a1"},
+		{Model: storage.Model{ID: "b1"}, Channel: "feishu", UserID: "bob"},
+	} {
+		if err := store.Session().Save(sess); err != nil {
+			t.Fatal(err)
+		}
+		key := consts.GetSessionKey(sess.Channel, sess.ID)
+		if err := store.Message().Save(&storage.Message{SessionID: key, Role: consts.RoleUser, Content: "hi from " + sess.UserID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Memory().Save(&storage.Memory{SessionID: key, Role: "user", Content: sess.UserID + " likes tea"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Binding().SetLanguage(sess.Channel, sess.ID, "en"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Trace().Save(&storage.Trace{Channel: "feishu", SessionID: "a1", Input: "hi", Data: `{"input":"hi"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.KV().Set("user:feishu:alice", "city", `"Paris"`); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.KV().Set("global", "city", `"Berlin"`); err != nil {
+		t.Fatal(err)
+	}
+	entity, err := store.Entity().Upsert("Alice", "person", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Entity().AddFact(entity.ID, "works on billing", "a1"); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(ws, UsersDir, "alice"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(ws, UsersDir, "alice", "cv.md"), []byte("# Alice"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	svc := New(store, []workspace.Entry{{Name: workspace.DefaultName, Dir: ws}}, nil)
+
+	// dry-run 只统计，不删除
+	plan, err := svc.Plan("alice")
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	want := storage.UserDataCounts{Sessions: 2, Messages: 2, Memories: 2, Traces: 1, Bindings: 2, KV: 1, EntityFacts: 1}
+	if plan.UserDataCounts != want {
+		t.Errorf("Plan() counts = %+v, want %+v", plan.UserDataCounts, want)
+	}
+	if len(plan.Files) != 1 || plan.Files[0] != "default:users/alice/cv.md" || plan.FileBytes != 7 {
+		t.Errorf("Plan() files = %v (%d bytes)", plan.Files, plan.FileBytes)
+	}
+	if again, _ := svc.Plan("alice"); again.Sessions != 2 {
+		t.Error("Plan() should not delete anything")
+	}
+
+	export, report, err := svc.Purge("alice")
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if report.UserDataCounts != want || report.DryRun {
+		t.Errorf("Purge() report = %+v", report)
+	}
+	if len(export.Files) != 1 || string(export.Files[0].Content) != "# Alice" {
+		t.Errorf("exported files = %+v", export.Files)
+	}
+	if len(export.Traces) != 1 || string(export.Traces[0].Data) != `{"input":"hi"}` {
+		t.Errorf("exported traces = %+v", export.Traces)
+	}
+
+	after, err := svc.Plan("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.UserDataCounts != (storage.UserDataCounts{}) || len(after.Files) != 0 {
+		t.Errorf("data left after Purge() = %+v, files %v", after.UserDataCounts, after.Files)
+	}
+	if bob, _ := svc.Plan("bob"); bob.Sessions != 1 || bob.Messages != 1 || bob.Memories != 1 {
+		t.Errorf("bob's data = %+v, want untouched", bob.UserDataCounts)
+	}
+	if _, err := store.KV().Get("global", "city"); err != nil {
+		t.Errorf("global kv entry removed: %v", err)
+	}
+	if _, err := store.Entity().Find("Alice"); err != nil {
+		t.Errorf("shared entity removed: %v", err)
+	}
+
+	if _, err := svc.Plan("../etc"); err == nil {
+		t.Error("expected error for a user id with a path separator")
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Redacted 替换敏感信息的占位符
+const Redacted = "[REDACTED]"
+
+// redactRule 脱敏规则，repl 中可以引用分组保留键名。
+type redactRule struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// defaultRedactRules 默认识别的密钥格式
+var defaultRedactRules = []redactRule{
+	{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), Redacted},
+	{regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{16,}`), Redacted},
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), Redacted},
+	{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{30,}`), Redacted},
+	{regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9\-]{10,}`), Redacted},
+	{regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/\-]{16,}=*`), "${1}" + Redacted},
+	{regexp.MustCompile(`(?i)\b((?:password|passwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s,;]+)`), "${1}" + Redacted},
+}
+
+// Redactor 替换文本中的密钥、令牌和密码。
+type Redactor struct {
+	rules []redactRule
+}
+
+// NewRedactor 创建脱敏器，extra 为额外的正则表达式，匹配内容整体替换。
+func NewRedactor(extra []string) (*Redactor, error) {
+	rules := append([]redactRule(nil), defaultRedactRules...)
+	for _, pattern := range extra {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("脱敏规则 %q 无效: %w", pattern, err)
+		}
+		rules = append(rules, redactRule{re: re, repl: Redacted})
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// Redact 返回脱敏后的文本。
+func (r *Redactor) Redact(text string) string {
+	for _, rule := range r.rules {
+		text = rule.re.ReplaceAllString(text, rule.repl)
+	}
+	return text
+}
@@ -1,5 +1,10 @@
 package utils
 
+import (
+	"strings"
+	"unicode"
+)
+
 // SplitProviderModel 分割模型字符串，格式为 "provider/model"。
 func SplitProviderModel(modelStr string) []string {
 	idx := -1
@@ -14,3 +19,50 @@ func SplitProviderModel(modelStr string) []string {
 	}
 	return []string{modelStr[:idx], modelStr[idx+1:]}
 }
+
+// Bigrams 将文本规范化后切分为字符二元组，忽略大小写、空白和标点，对中文同样有效。
+func Bigrams(text string) map[string]struct{} {
+	runes := make([]rune, 0, len(text))
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			runes = append(runes, r)
+		}
+	}
+
+	set := make(map[string]struct{}, len(runes))
+	if len(runes) == 1 {
+		set[string(runes)] = struct{}{}
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		set[string(runes[i:i+2])] = struct{}{}
+	}
+	return set
+}
+
+// Jaccard 计算两个集合的 Jaccard 系数。
+func Jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	inter := intersect(a, b)
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// Containment 计算 a 中有多少比例的元素出现在 b 中，用于短查询匹配长文本。
+func Containment(a, b map[string]struct{}) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	return float64(intersect(a, b)) / float64(len(a))
+}
+
+// intersect 返回两个集合的交集大小。
+func intersect(a, b map[string]struct{}) int {
+	n := 0
+	for k := range a {
+		if _, ok := b[k]; ok {
+			n++
+		}
+	}
+	return n
+}
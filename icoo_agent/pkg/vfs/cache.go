@@ -0,0 +1,207 @@
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// ErrTooLarge 文件超过挂载配置的大小限制。
+var ErrTooLarge = errors.New("文件超过大小限制")
+
+// CacheOptions 远程存储的缓存与大小限制。
+type CacheOptions struct {
+	// TTL 文件内容、目录列表和文件信息的缓存有效期，0 表示不缓存
+	TTL time.Duration
+	// MaxSize 缓存的文件内容总大小上限（字节），超出后淘汰最早过期的条目
+	MaxSize int64
+	// MaxFileSize 单个文件读写的大小上限（字节），0 表示不限制
+	MaxFileSize int64
+}
+
+// cacheEntry 缓存条目，按操作只填写其中一项。
+type cacheEntry struct {
+	data    []byte
+	infos   []fs.FileInfo
+	info    fs.FileInfo
+	expires time.Time
+}
+
+// Cached 为远程存储增加读取缓存和文件大小限制。
+//
+// 缓存只在本进程内有效；任何写入、创建目录或删除都会清空该挂载的全部缓存，
+// 避免目录列表与文件内容不一致。其他客户端的修改最迟在 TTL 后可见。
+type Cached struct {
+	fs   FS
+	opts CacheOptions
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	size    int64
+	now     func() time.Time
+}
+
+// NewCached 创建带缓存和大小限制的文件系统。
+func NewCached(fsys FS, opts CacheOptions) *Cached {
+	return &Cached{
+		fs:      fsys,
+		opts:    opts,
+		entries: make(map[string]*cacheEntry),
+		now:     time.Now,
+	}
+}
+
+// get 返回未过期的缓存条目。
+func (c *Cached) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(e.expires) {
+		c.size -= int64(len(e.data))
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e, true
+}
+
+// put 写入缓存条目，文件内容超出总大小上限时先淘汰最早过期的条目。
+func (c *Cached) put(key string, e *cacheEntry) {
+	if c.opts.TTL <= 0 {
+		return
+	}
+	size := int64(len(e.data))
+	if c.opts.MaxSize > 0 && size > c.opts.MaxSize {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[key]; ok {
+		c.size -= int64(len(old.data))
+		delete(c.entries, key)
+	}
+	for c.opts.MaxSize > 0 && c.size+size > c.opts.MaxSize {
+		var oldest string
+		for k, v := range c.entries {
+			if len(v.data) > 0 && (oldest == "" || v.expires.Before(c.entries[oldest].expires)) {
+				oldest = k
+			}
+		}
+		if oldest == "" {
+			break
+		}
+		c.size -= int64(len(c.entries[oldest].data))
+		delete(c.entries, oldest)
+	}
+	e.expires = c.now().Add(c.opts.TTL)
+	c.entries[key] = e
+	c.size += size
+}
+
+// purge 清空缓存。
+func (c *Cached) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.size = 0
+}
+
+// tooLarge 返回超出大小限制的错误。
+func (c *Cached) tooLarge(name string, size int64) error {
+	return fmt.Errorf("%w: %s 大小 %d 字节，上限 %d 字节", ErrTooLarge, name, size, c.opts.MaxFileSize)
+}
+
+// ReadFile 实现 ReadFileFS，超过大小限制的文件在下载过程中即中止。
+func (c *Cached) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	name = Clean(name)
+	if e, ok := c.get("file:" + name); ok {
+		return e.data, nil
+	}
+
+	r, err := c.fs.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var src io.Reader = r
+	if c.opts.MaxFileSize > 0 {
+		src = io.LimitReader(r, c.opts.MaxFileSize+1)
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.MaxFileSize > 0 && int64(len(data)) > c.opts.MaxFileSize {
+		return nil, fmt.Errorf("%w: %s 超过上限 %d 字节", ErrTooLarge, name, c.opts.MaxFileSize)
+	}
+
+	c.put("file:"+name, &cacheEntry{data: data})
+	return data, nil
+}
+
+// Open 实现 FS，内容经过缓存和大小限制。
+func (c *Cached) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	data, err := c.ReadFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// WriteFile 实现 FS。
+func (c *Cached) WriteFile(ctx context.Context, name string, data []byte) error {
+	if c.opts.MaxFileSize > 0 && int64(len(data)) > c.opts.MaxFileSize {
+		return c.tooLarge(name, int64(len(data)))
+	}
+	defer c.purge()
+	return c.fs.WriteFile(ctx, name, data)
+}
+
+// ReadDir 实现 FS。
+func (c *Cached) ReadDir(ctx context.Context, name string) ([]fs.FileInfo, error) {
+	name = Clean(name)
+	if e, ok := c.get("dir:" + name); ok {
+		return e.infos, nil
+	}
+	infos, err := c.fs.ReadDir(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	c.put("dir:"+name, &cacheEntry{infos: infos})
+	return infos, nil
+}
+
+// Stat 实现 FS。
+func (c *Cached) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	name = Clean(name)
+	if e, ok := c.get("stat:" + name); ok {
+		return e.info, nil
+	}
+	info, err := c.fs.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	c.put("stat:"+name, &cacheEntry{info: info})
+	return info, nil
+}
+
+// MkdirAll 实现 FS。
+func (c *Cached) MkdirAll(ctx context.Context, name string) error {
+	defer c.purge()
+	return c.fs.MkdirAll(ctx, name)
+}
+
+// Remove 实现 FS。
+func (c *Cached) Remove(ctx context.Context, name string, recursive bool) error {
+	defer c.purge()
+	return c.fs.Remove(ctx, name, recursive)
+}
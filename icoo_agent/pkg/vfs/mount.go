@@ -0,0 +1,163 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// mount 挂载到工作目录子路径的文件系统。
+type mount struct {
+	path string
+	fs   FS
+}
+
+// Mounts 远程存储挂载表，所有工作目录共享同一组挂载点。
+type Mounts struct {
+	mounts []mount
+}
+
+// NewMounts 创建空的挂载表。
+func NewMounts() *Mounts {
+	return &Mounts{}
+}
+
+// Add 将文件系统挂载到工作目录中的相对路径 p，同一路径重复挂载时替换。
+func (m *Mounts) Add(p string, fsys FS) {
+	p = Clean(p)
+	m.mounts = slices.DeleteFunc(m.mounts, func(mt mount) bool { return mt.path == p })
+	m.mounts = append(m.mounts, mount{path: p, fs: fsys})
+	// 最长的挂载路径优先匹配，允许嵌套挂载
+	sort.SliceStable(m.mounts, func(i, j int) bool {
+		return len(m.mounts[i].path) > len(m.mounts[j].path)
+	})
+}
+
+// Paths 返回全部挂载路径，按名称排序。
+func (m *Mounts) Paths() []string {
+	if m == nil {
+		return nil
+	}
+	paths := make([]string, 0, len(m.mounts))
+	for _, mt := range m.mounts {
+		paths = append(paths, mt.path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// FS 返回以本地目录 root 为根、叠加挂载点的文件系统。m 为 nil 时即为本地目录。
+func (m *Mounts) FS(root string) FS {
+	if m == nil || len(m.mounts) == 0 {
+		return Dir(root)
+	}
+	return &mountFS{local: Dir(root), mounts: m.mounts}
+}
+
+// mountFS 按挂载路径将操作转发给远程存储，其余路径使用本地目录。
+type mountFS struct {
+	local  Dir
+	mounts []mount
+}
+
+// route 返回名称所在的文件系统和在其中的名称。
+func (m *mountFS) route(name string) (FS, string) {
+	name = Clean(name)
+	for _, mt := range m.mounts {
+		if name == mt.path {
+			return mt.fs, ""
+		}
+		if strings.HasPrefix(name, mt.path+"/") {
+			return mt.fs, name[len(mt.path)+1:]
+		}
+	}
+	return m.local, name
+}
+
+// children 返回位于本地目录 name 之下、挂载路径的下一级名称。
+func (m *mountFS) children(name string) []string {
+	name = Clean(name)
+	var names []string
+	for _, mt := range m.mounts {
+		rest := mt.path
+		if name != "" {
+			if !strings.HasPrefix(mt.path, name+"/") {
+				continue
+			}
+			rest = mt.path[len(name)+1:]
+		}
+		child, _, _ := strings.Cut(rest, "/")
+		if !slices.Contains(names, child) {
+			names = append(names, child)
+		}
+	}
+	return names
+}
+
+// Open 实现 FS。
+func (m *mountFS) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	fsys, sub := m.route(name)
+	return fsys.Open(ctx, sub)
+}
+
+// ReadFile 实现 ReadFileFS。
+func (m *mountFS) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	fsys, sub := m.route(name)
+	return ReadFile(ctx, fsys, sub)
+}
+
+// WriteFile 实现 FS。
+func (m *mountFS) WriteFile(ctx context.Context, name string, data []byte) error {
+	fsys, sub := m.route(name)
+	return fsys.WriteFile(ctx, sub, data)
+}
+
+// ReadDir 实现 FS，本地目录的列表中包含其下的挂载点。
+func (m *mountFS) ReadDir(ctx context.Context, name string) ([]fs.FileInfo, error) {
+	fsys, sub := m.route(name)
+	infos, err := fsys.ReadDir(ctx, sub)
+	if fsys != m.local {
+		return infos, err
+	}
+
+	children := m.children(sub)
+	if err != nil && !(errors.Is(err, fs.ErrNotExist) && len(children) > 0) {
+		return nil, err
+	}
+	for _, child := range children {
+		infos = slices.DeleteFunc(infos, func(fi fs.FileInfo) bool { return fi.Name() == child })
+		infos = append(infos, dirInfo(child))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+// Stat 实现 FS，挂载点的上级目录即使本地不存在也视为目录。
+func (m *mountFS) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	fsys, sub := m.route(name)
+	info, err := fsys.Stat(ctx, sub)
+	if fsys == m.local && errors.Is(err, fs.ErrNotExist) && len(m.children(sub)) > 0 {
+		return dirInfo(sub), nil
+	}
+	return info, err
+}
+
+// MkdirAll 实现 FS。
+func (m *mountFS) MkdirAll(ctx context.Context, name string) error {
+	fsys, sub := m.route(name)
+	return fsys.MkdirAll(ctx, sub)
+}
+
+// Remove 实现 FS，不允许删除挂载点及其上级目录。
+func (m *mountFS) Remove(ctx context.Context, name string, recursive bool) error {
+	fsys, sub := m.route(name)
+	if sub == "" || (fsys == m.local && len(m.children(sub)) > 0) {
+		return fmt.Errorf("不能删除挂载点或包含挂载点的目录: %s", Clean(name))
+	}
+	return fsys.Remove(ctx, sub, recursive)
+}
@@ -0,0 +1,428 @@
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Options S3 兼容对象存储的挂载参数。
+type S3Options struct {
+	// Endpoint 服务地址，例如 https://s3.us-east-1.amazonaws.com 或 MinIO 地址
+	Endpoint string
+	// Region 签名使用的区域，默认 us-east-1
+	Region string
+	// Bucket 存储桶名称
+	Bucket string
+	// Prefix 挂载的对象前缀，为空时挂载整个存储桶
+	Prefix string
+	// AccessKey、SecretKey 访问凭证
+	AccessKey string
+	SecretKey string
+	// PathStyle 使用 endpoint/bucket/key 形式的地址，MinIO 等自建服务通常需要开启
+	PathStyle bool
+	// Client 自定义 HTTP 客户端，默认为 30 秒超时的客户端
+	Client *http.Client
+}
+
+// S3 将 S3 存储桶前缀作为文件系统。
+//
+// 对象存储没有真正的目录：以 / 结尾的空对象视为目录标记，
+// 只要存在以 目录名/ 为前缀的对象，该目录就存在。
+type S3 struct {
+	opts     S3Options
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3 创建 S3 文件系统。
+func NewS3(opts S3Options) (*S3, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("S3 挂载需要 bucket")
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = "https://s3.amazonaws.com"
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint 无效: %s", opts.Endpoint)
+	}
+	opts.Prefix = Clean(opts.Prefix)
+
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &S3{opts: opts, endpoint: u, client: client, now: time.Now}, nil
+}
+
+// key 返回名称对应的对象键。
+func (s *S3) key(name string) string {
+	return Clean(s.opts.Prefix + "/" + Clean(name))
+}
+
+// dirKey 返回目录对应的对象键前缀，根目录为挂载前缀本身。
+func (s *S3) dirKey(name string) string {
+	if k := s.key(name); k != "" {
+		return k + "/"
+	}
+	return ""
+}
+
+// Open 实现 FS。
+func (s *S3) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	if Clean(name) == "" {
+		return nil, fmt.Errorf("不能读取目录: /")
+	}
+	resp, err := s.do(ctx, http.MethodGet, s.key(name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, notExist("open", name)
+	}
+	if err := checkStatus(resp, "读取对象"); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// WriteFile 实现 FS。
+func (s *S3) WriteFile(ctx context.Context, name string, data []byte) error {
+	if Clean(name) == "" {
+		return fmt.Errorf("不能写入目录: /")
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.key(name), nil, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "写入对象")
+}
+
+// ReadDir 实现 FS。
+func (s *S3) ReadDir(ctx context.Context, name string) ([]fs.FileInfo, error) {
+	prefix := s.dirKey(name)
+	var infos []fs.FileInfo
+	found := false
+	err := s.list(ctx, prefix, "/", 0, func(r *listResult) bool {
+		for _, p := range r.CommonPrefixes {
+			found = true
+			infos = append(infos, &fileInfo{name: path.Base(strings.TrimSuffix(p.Prefix, "/")), dir: true})
+		}
+		for _, o := range r.Contents {
+			found = true
+			if o.Key == prefix {
+				continue // 目录标记
+			}
+			infos = append(infos, o.info())
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found && prefix != "" {
+		return nil, notExist("readdir", name)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+// Stat 实现 FS。
+func (s *S3) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	if Clean(name) == "" {
+		return dirInfo(name), nil
+	}
+	resp, err := s.do(ctx, http.MethodHead, s.key(name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		if err := checkStatus(resp, "获取对象信息"); err != nil {
+			return nil, err
+		}
+		modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+		return &fileInfo{name: path.Base(Clean(name)), size: resp.ContentLength, modTime: modTime}, nil
+	}
+
+	isDir, err := s.hasPrefix(ctx, s.dirKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if !isDir {
+		return nil, notExist("stat", name)
+	}
+	return dirInfo(name), nil
+}
+
+// MkdirAll 实现 FS，写入目录标记对象。
+func (s *S3) MkdirAll(ctx context.Context, name string) error {
+	if Clean(name) == "" {
+		return nil
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.dirKey(name), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "创建目录")
+}
+
+// Remove 实现 FS。
+func (s *S3) Remove(ctx context.Context, name string, recursive bool) error {
+	info, err := s.Stat(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return s.deleteKey(ctx, s.key(name))
+	}
+
+	prefix := s.dirKey(name)
+	var keys []string
+	err = s.list(ctx, prefix, "", 0, func(r *listResult) bool {
+		for _, o := range r.Contents {
+			keys = append(keys, o.Key)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if !recursive && (len(keys) > 1 || (len(keys) == 1 && keys[0] != prefix)) {
+		return fmt.Errorf("目录不为空: %s", Clean(name))
+	}
+	for _, k := range keys {
+		if err := s.deleteKey(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteKey 删除单个对象。
+func (s *S3) deleteKey(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "删除对象")
+}
+
+// hasPrefix 判断是否存在以 prefix 开头的对象。
+func (s *S3) hasPrefix(ctx context.Context, prefix string) (bool, error) {
+	found := false
+	err := s.list(ctx, prefix, "", 1, func(r *listResult) bool {
+		found = len(r.Contents) > 0 || len(r.CommonPrefixes) > 0
+		return false
+	})
+	return found, err
+}
+
+// s3Object 对象列表中的对象。
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// info 返回对象的文件信息。
+func (o s3Object) info() fs.FileInfo {
+	return &fileInfo{name: path.Base(o.Key), size: o.Size, modTime: o.LastModified}
+}
+
+// listResult ListObjectsV2 的响应。
+type listResult struct {
+	Contents       []s3Object `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// list 分页列出对象，fn 返回 false 时停止。maxKeys 为 0 时使用服务端默认值。
+func (s *S3) list(ctx context.Context, prefix, delimiter string, maxKeys int, fn func(*listResult) bool) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if maxKeys > 0 {
+			query.Set("max-keys", strconv.Itoa(maxKeys))
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return err
+		}
+		if err := checkStatus(resp, "列出对象"); err != nil {
+			return err
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("解析对象列表失败: %w", err)
+		}
+
+		if !fn(&result) || !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// do 发送签名后的请求，key 为空时请求存储桶本身。
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	escapedKey := escapePath(key)
+	if s.opts.PathStyle {
+		base, escapedBase := strings.TrimSuffix(u.Path, "/"), strings.TrimSuffix(u.EscapedPath(), "/")
+		u.Path = base + "/" + s.opts.Bucket + "/" + key
+		u.RawPath = escapedBase + "/" + escapePath(s.opts.Bucket) + "/" + escapedKey
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+		u.Path = "/" + key
+		u.RawPath = "/" + escapedKey
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	s.sign(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 S3 失败: %w", err)
+	}
+	return resp, nil
+}
+
+// sign 使用 AWS Signature Version 4 签名请求。
+func (s *S3) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.opts.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), date)
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, scope, signedHeaders, signature))
+}
+
+// escapePath 按 SigV4 规则编码对象键，保留 /。
+func escapePath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = uriEncode(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// canonicalQuery 按 SigV4 规则编码并排序查询参数。
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode 编码除 RFC 3986 非保留字符外的全部字节。
+func uriEncode(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// checkStatus 将非 2xx 响应转换为错误并关闭响应体。
+func checkStatus(resp *http.Response, action string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s失败: %s %s", action, resp.Status, strings.TrimSpace(string(msg)))
+}
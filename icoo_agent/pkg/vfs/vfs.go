@@ -0,0 +1,167 @@
+// Package vfs 为文件工具提供虚拟文件系统，将 S3 存储桶前缀或 WebDAV 共享挂载为工作目录的子路径。
+//
+// 所有名称都是相对文件系统根的 / 分隔路径，空字符串表示根目录。
+// 文件不存在时返回的错误满足 errors.Is(err, fs.ErrNotExist)。
+package vfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FS 文件工具使用的文件系统。
+type FS interface {
+	// Open 打开文件读取内容。
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// WriteFile 写入文件，自动创建上级目录。
+	WriteFile(ctx context.Context, name string, data []byte) error
+	// ReadDir 列出目录内容，按名称排序。
+	ReadDir(ctx context.Context, name string) ([]fs.FileInfo, error)
+	// Stat 获取文件或目录信息。
+	Stat(ctx context.Context, name string) (fs.FileInfo, error)
+	// MkdirAll 创建目录及其上级目录，目录已存在时不报错。
+	MkdirAll(ctx context.Context, name string) error
+	// Remove 删除文件或目录，recursive 为 false 时不删除非空目录。
+	Remove(ctx context.Context, name string, recursive bool) error
+}
+
+// ReadFileFS 可以直接读取整个文件的文件系统，例如带缓存的远程存储。
+type ReadFileFS interface {
+	FS
+	ReadFile(ctx context.Context, name string) ([]byte, error)
+}
+
+// ReadFile 读取整个文件。
+func ReadFile(ctx context.Context, fsys FS, name string) ([]byte, error) {
+	if rf, ok := fsys.(ReadFileFS); ok {
+		return rf.ReadFile(ctx, name)
+	}
+	r, err := fsys.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Clean 规范化名称，去掉首尾的 /，根目录为空字符串。
+func Clean(name string) string {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	return strings.TrimPrefix(name, "/")
+}
+
+// notExist 返回满足 fs.ErrNotExist 的错误。
+func notExist(op, name string) error {
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// fileInfo 远程存储的文件信息。
+type fileInfo struct {
+	name    string
+	size    int64
+	dir     bool
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() any           { return nil }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+// dirInfo 返回目录的文件信息，用于根目录和挂载点。
+func dirInfo(name string) fs.FileInfo {
+	if name = path.Base(Clean(name)); name == "/" || name == "." {
+		name = "/"
+	}
+	return &fileInfo{name: name, dir: true}
+}
+
+// Dir 以本地目录为根的文件系统。
+type Dir string
+
+// local 返回名称对应的本地路径。
+func (d Dir) local(name string) string {
+	return filepath.Join(string(d), filepath.FromSlash(Clean(name)))
+}
+
+// Open 实现 FS。
+func (d Dir) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(d.local(name))
+}
+
+// ReadFile 实现 ReadFileFS。
+func (d Dir) ReadFile(_ context.Context, name string) ([]byte, error) {
+	return os.ReadFile(d.local(name))
+}
+
+// WriteFile 实现 FS。
+func (d Dir) WriteFile(_ context.Context, name string, data []byte) error {
+	p := d.local(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	return os.WriteFile(p, data, 0o644)
+}
+
+// ReadDir 实现 FS。
+func (d Dir) ReadDir(ctx context.Context, name string) ([]fs.FileInfo, error) {
+	// 递归列目录时逐层检查，超时或取消后不再继续
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(d.local(name))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			// 列出后被删除的文件直接跳过
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Stat 实现 FS。
+func (d Dir) Stat(_ context.Context, name string) (fs.FileInfo, error) {
+	return os.Stat(d.local(name))
+}
+
+// MkdirAll 实现 FS。
+func (d Dir) MkdirAll(_ context.Context, name string) error {
+	return os.MkdirAll(d.local(name), 0o755)
+}
+
+// Remove 实现 FS。
+func (d Dir) Remove(_ context.Context, name string, recursive bool) error {
+	p := d.local(name)
+	if _, err := os.Lstat(p); err != nil {
+		return err
+	}
+	if recursive {
+		return os.RemoveAll(p)
+	}
+	return os.Remove(p)
+}
@@ -0,0 +1,114 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingFS 统计读取次数的文件系统。
+type countingFS struct {
+	FS
+	opens int
+}
+
+func (c *countingFS) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	c.opens++
+	return c.FS.Open(ctx, name)
+}
+
+func TestMounts(t *testing.T) {
+	ctx := context.Background()
+	root, remote := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "local.txt"), []byte("local"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mounts := NewMounts()
+	mounts.Add("/drive/docs/", Dir(remote))
+	fsys := mounts.FS(root)
+
+	if err := fsys.WriteFile(ctx, "drive/docs/a.md", []byte("remote")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "a.md")); err != nil {
+		t.Errorf("file was not written to the mount: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "drive")); !os.IsNotExist(err) {
+		t.Errorf("mount point was created locally: %v", err)
+	}
+
+	// 挂载点的上级目录在本地不存在时也能列出和查看
+	infos, err := fsys.ReadDir(ctx, "")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if got := names(infos); len(got) != 2 || got[0] != "drive" || got[1] != "local.txt" {
+		t.Errorf("ReadDir(root) = %v", got)
+	}
+	if info, err := fsys.Stat(ctx, "drive"); err != nil || !info.IsDir() {
+		t.Errorf("Stat(drive) = %v, %v", info, err)
+	}
+
+	if err := fsys.Remove(ctx, "drive", true); err == nil {
+		t.Error("expected error removing a directory containing a mount point")
+	}
+	if err := fsys.Remove(ctx, "drive/docs/a.md", false); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+}
+
+func TestCached(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "big.bin"), make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &countingFS{FS: Dir(dir)}
+	c := NewCached(backend, CacheOptions{TTL: time.Minute, MaxSize: 1024, MaxFileSize: 10})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for range 2 {
+		if data, err := ReadFile(ctx, c, "a.txt"); err != nil || string(data) != "hello" {
+			t.Fatalf("ReadFile() = %q, %v", data, err)
+		}
+	}
+	if backend.opens != 1 {
+		t.Errorf("opens = %d, want 1 (second read from cache)", backend.opens)
+	}
+
+	// 过期后重新读取
+	now = now.Add(2 * time.Minute)
+	ReadFile(ctx, c, "a.txt")
+	if backend.opens != 2 {
+		t.Errorf("opens = %d, want 2 after expiry", backend.opens)
+	}
+
+	// 写入后缓存失效
+	if err := c.WriteFile(ctx, "a.txt", []byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ReadFile(ctx, c, "a.txt"); string(data) != "world" {
+		t.Errorf("ReadFile() after write = %q, want world", data)
+	}
+
+	if _, err := ReadFile(ctx, c, "big.bin"); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("ReadFile(big) error = %v, want ErrTooLarge", err)
+	}
+	if err := c.WriteFile(ctx, "big2.bin", make([]byte, 11)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("WriteFile(big) error = %v, want ErrTooLarge", err)
+	}
+	if _, err := c.Stat(ctx, "big2.bin"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("oversized file was written: %v", err)
+	}
+}
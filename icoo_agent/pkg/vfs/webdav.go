@@ -0,0 +1,270 @@
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebDAVOptions WebDAV 共享的挂载参数。
+type WebDAVOptions struct {
+	// URL 共享的根地址，例如 https://dav.example.com/remote.php/dav/files/alice/docs
+	URL string
+	// Username、Password 基本认证凭证，为空时不认证
+	Username string
+	Password string
+	// Client 自定义 HTTP 客户端，默认为 30 秒超时的客户端
+	Client *http.Client
+}
+
+// WebDAV 将 WebDAV 共享作为文件系统。
+type WebDAV struct {
+	opts   WebDAVOptions
+	base   *url.URL
+	client *http.Client
+}
+
+// NewWebDAV 创建 WebDAV 文件系统。
+func NewWebDAV(opts WebDAVOptions) (*WebDAV, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("WebDAV url 无效: %s", opts.URL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &WebDAV{opts: opts, base: u, client: client}, nil
+}
+
+// url 返回名称对应的地址，目录以 / 结尾。
+func (w *WebDAV) url(name string, dir bool) string {
+	u := *w.base
+	u.Path += "/" + Clean(name)
+	if dir && !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u.String()
+}
+
+// do 发送请求。
+func (w *WebDAV) do(ctx context.Context, method, target string, header http.Header, body []byte) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, r)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if w.opts.Username != "" || w.opts.Password != "" {
+		req.SetBasicAuth(w.opts.Username, w.opts.Password)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 WebDAV 失败: %w", err)
+	}
+	return resp, nil
+}
+
+// Open 实现 FS。
+func (w *WebDAV) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := w.do(ctx, http.MethodGet, w.url(name, false), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, notExist("open", name)
+	}
+	if err := checkStatus(resp, "读取文件"); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// WriteFile 实现 FS。
+func (w *WebDAV) WriteFile(ctx context.Context, name string, data []byte) error {
+	if dir := path.Dir(Clean(name)); dir != "." {
+		if err := w.MkdirAll(ctx, dir); err != nil {
+			return err
+		}
+	}
+	resp, err := w.do(ctx, http.MethodPut, w.url(name, false), nil, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "写入文件")
+}
+
+// ReadDir 实现 FS。
+func (w *WebDAV) ReadDir(ctx context.Context, name string) ([]fs.FileInfo, error) {
+	infos, err := w.propfind(ctx, name, "1")
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 || !infos[0].IsDir() {
+		return nil, fmt.Errorf("不是目录: %s", Clean(name))
+	}
+	children := infos[1:]
+	sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+	return children, nil
+}
+
+// Stat 实现 FS。
+func (w *WebDAV) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	infos, err := w.propfind(ctx, name, "0")
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, notExist("stat", name)
+	}
+	return infos[0], nil
+}
+
+// MkdirAll 实现 FS，逐级创建集合，已存在的集合跳过。
+func (w *WebDAV) MkdirAll(ctx context.Context, name string) error {
+	name = Clean(name)
+	if name == "" {
+		return nil
+	}
+	dir := ""
+	for _, part := range strings.Split(name, "/") {
+		dir = path.Join(dir, part)
+		resp, err := w.do(ctx, "MKCOL", w.url(dir, true), nil, nil)
+		if err != nil {
+			return err
+		}
+		// 405 表示资源已存在
+		if resp.StatusCode == http.StatusMethodNotAllowed {
+			resp.Body.Close()
+			continue
+		}
+		if err := checkStatus(resp, "创建目录"); err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// Remove 实现 FS。WebDAV 删除集合总是递归的，因此非递归删除先检查目录是否为空。
+func (w *WebDAV) Remove(ctx context.Context, name string, recursive bool) error {
+	info, err := w.Stat(ctx, name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && !recursive {
+		children, err := w.ReadDir(ctx, name)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return fmt.Errorf("目录不为空: %s", Clean(name))
+		}
+	}
+	resp, err := w.do(ctx, http.MethodDelete, w.url(name, info.IsDir()), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, "删除")
+}
+
+// propfindBody 只请求文件工具需要的属性。
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/><D:getcontentlength/><D:getlastmodified/></D:prop></D:propfind>`
+
+// multistatus PROPFIND 的响应。
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength string `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// propfind 查询资源属性，返回的第一项为资源本身，Depth 为 1 时其后为直接子项。
+func (w *WebDAV) propfind(ctx context.Context, name, depth string) ([]fs.FileInfo, error) {
+	header := http.Header{
+		"Depth":        {depth},
+		"Content-Type": {"application/xml; charset=utf-8"},
+	}
+	resp, err := w.do(ctx, "PROPFIND", w.url(name, false), header, []byte(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, notExist("stat", name)
+	}
+	if err := checkStatus(resp, "查询文件信息"); err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("解析 WebDAV 响应失败: %w", err)
+	}
+
+	self := strings.TrimSuffix(w.base.Path+"/"+Clean(name), "/")
+	var own fs.FileInfo
+	var children []fs.FileInfo
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		hrefPath := strings.TrimSuffix(href.Path, "/")
+
+		fi := &fileInfo{name: path.Base(hrefPath)}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			fi.dir = ps.Prop.ResourceType.Collection != nil
+			fi.size, _ = strconv.ParseInt(ps.Prop.ContentLength, 10, 64)
+			fi.modTime, _ = http.ParseTime(ps.Prop.LastModified)
+		}
+
+		if hrefPath == self {
+			if Clean(name) == "" {
+				fi.name = "/"
+			}
+			own = fi
+		} else {
+			children = append(children, fi)
+		}
+	}
+	if own == nil {
+		return nil, notExist("stat", name)
+	}
+	return append([]fs.FileInfo{own}, children...), nil
+}
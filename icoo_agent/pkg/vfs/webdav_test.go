@@ -0,0 +1,91 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+func TestWebDAV(t *testing.T) {
+	handler := &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	dav, err := NewWebDAV(WebDAVOptions{URL: srv.URL + "/dav/", Username: "alice", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testFS(t, dav)
+}
+
+// testFS 对远程文件系统执行相同的读写流程。
+func testFS(t *testing.T, fsys FS) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := fsys.WriteFile(ctx, "reports/2026/q1.md", []byte("# Q1")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	data, err := ReadFile(ctx, fsys, "reports/2026/q1.md")
+	if err != nil || string(data) != "# Q1" {
+		t.Fatalf("ReadFile() = %q, %v", data, err)
+	}
+
+	info, err := fsys.Stat(ctx, "reports/2026/q1.md")
+	if err != nil || info.IsDir() || info.Size() != 4 || info.Name() != "q1.md" {
+		t.Errorf("Stat(file) = %+v, %v", info, err)
+	}
+	if info, err := fsys.Stat(ctx, "reports"); err != nil || !info.IsDir() {
+		t.Errorf("Stat(dir) = %+v, %v", info, err)
+	}
+	if _, err := fsys.Stat(ctx, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(missing) error = %v, want fs.ErrNotExist", err)
+	}
+	if _, err := fsys.Open(ctx, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open(missing) error = %v, want fs.ErrNotExist", err)
+	}
+
+	if err := fsys.MkdirAll(ctx, "empty/sub"); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	infos, err := fsys.ReadDir(ctx, "")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(infos) != 2 || infos[0].Name() != "empty" || infos[1].Name() != "reports" || !infos[1].IsDir() {
+		t.Errorf("ReadDir(root) = %v", names(infos))
+	}
+
+	if err := fsys.Remove(ctx, "reports", false); err == nil {
+		t.Error("expected error removing a non-empty directory without recursive")
+	}
+	if err := fsys.Remove(ctx, "reports", true); err != nil {
+		t.Fatalf("Remove(recursive) error = %v", err)
+	}
+	if _, err := fsys.Stat(ctx, "reports/2026/q1.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() after remove error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func names(infos []fs.FileInfo) []string {
+	var list []string
+	for _, fi := range infos {
+		list = append(list, fi.Name())
+	}
+	return list
+}
@@ -0,0 +1,109 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	icooclawErrors "icooclaw/pkg/errors"
+	"icooclaw/pkg/storage"
+)
+
+// DefaultName 默认工作目录的名称，对应 agent.workspace
+const DefaultName = "default"
+
+// Entry 命名工作目录。
+type Entry struct {
+	Name     string `json:"name"`
+	Dir      string `json:"dir"`
+	ReadOnly bool   `json:"read_only,omitempty"` // 只读挂载，修改文件和执行命令的工具不执行
+}
+
+// Manager 管理命名工作目录和会话的工作目录选择。
+//
+// 选择保存在会话绑定中：会话级选择优先，其次渠道级默认，都未选择时使用 default。
+type Manager struct {
+	dirs    map[string]Entry
+	storage *storage.Storage
+	logger  *slog.Logger
+}
+
+// NewManager 创建工作目录管理器，workspaces 中应包含名为 default 的工作目录。
+func NewManager(workspaces []Entry, s *storage.Storage, logger *slog.Logger) *Manager {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	dirs := make(map[string]Entry, len(workspaces))
+	for _, e := range workspaces {
+		dirs[e.Name] = e
+	}
+	return &Manager{dirs: dirs, storage: s, logger: logger}
+}
+
+// Get 返回命名工作目录，空名称表示 default。
+func (m *Manager) Get(name string) (Entry, bool) {
+	if name == "" {
+		name = DefaultName
+	}
+	e, ok := m.dirs[name]
+	return e, ok
+}
+
+// List 返回全部工作目录，default 在前，其余按名称排序。
+func (m *Manager) List() []Entry {
+	list := []Entry{m.dirs[DefaultName]}
+	for _, name := range slices.Sorted(maps.Keys(m.dirs)) {
+		if name != DefaultName {
+			list = append(list, m.dirs[name])
+		}
+	}
+	return list
+}
+
+// Select 为会话选择工作目录，sessionID 为空时设置整个渠道的默认工作目录。
+// name 为空表示清除选择。
+func (m *Manager) Select(channel, sessionID, name string) (*Entry, error) {
+	if m.storage == nil {
+		return nil, errors.New("未配置存储")
+	}
+
+	var entry *Entry
+	if name != "" {
+		e, ok := m.dirs[name]
+		if !ok {
+			return nil, fmt.Errorf("工作目录不存在: %s", name)
+		}
+		entry = &e
+	}
+
+	if err := m.storage.Binding().SetWorkspace(channel, sessionID, name); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Current 获取会话当前生效的工作目录，优先会话级选择，其次渠道级选择，最后为 default。
+// 绑定的工作目录已从配置中移除时忽略该选择。
+func (m *Manager) Current(channel, sessionID string) Entry {
+	if m.storage != nil {
+		for _, sid := range []string{sessionID, ""} {
+			b, err := m.storage.Binding().GetBinding(channel, sid)
+			if err != nil {
+				if !errors.Is(err, icooclawErrors.ErrRecordNotFound) {
+					m.logger.With("name", "【工作目录】").Warn("获取工作目录绑定失败", "channel", channel, "session_id", sid, "error", err)
+				}
+				continue
+			}
+			if b.Workspace == "" {
+				continue
+			}
+			if e, ok := m.dirs[b.Workspace]; ok {
+				return e
+			}
+			m.logger.With("name", "【工作目录】").Warn("绑定的工作目录不存在，已忽略", "workspace", b.Workspace, "session_id", sid)
+		}
+	}
+	return m.dirs[DefaultName]
+}
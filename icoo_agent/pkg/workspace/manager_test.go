@@ -0,0 +1,60 @@
+package workspace
+
+import (
+	"path/filepath"
+	"testing"
+
+	"icooclaw/pkg/storage"
+)
+
+func TestManager_Select(t *testing.T) {
+	store, err := storage.New(t.TempDir(), "", filepath.Join(t.TempDir(), "workspace.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	m := NewManager([]Entry{
+		{Name: DefaultName, Dir: "/ws/default"},
+		{Name: "notes", Dir: "/ws/notes"},
+		{Name: "code", Dir: "/ws/code", ReadOnly: true},
+	}, store, nil)
+
+	if got := m.List(); len(got) != 3 || got[0].Name != DefaultName || got[1].Name != "code" {
+		t.Errorf("List() = %v, want default first then sorted names", got)
+	}
+	if got := m.Current("feishu", "c1"); got.Name != DefaultName || got.Dir != "/ws/default" {
+		t.Errorf("Current() = %v, want default", got)
+	}
+
+	// 渠道级默认对该渠道的所有会话生效
+	if _, err := m.Select("feishu", "", "notes"); err != nil {
+		t.Fatalf("Select(channel) error = %v", err)
+	}
+	if got := m.Current("feishu", "c1"); got.Name != "notes" {
+		t.Errorf("Current() = %v, want channel default notes", got)
+	}
+
+	// 会话级选择优先
+	if _, err := m.Select("feishu", "c1", "code"); err != nil {
+		t.Fatalf("Select(session) error = %v", err)
+	}
+	if got := m.Current("feishu", "c1"); got.Name != "code" || got.Dir != "/ws/code" || !got.ReadOnly {
+		t.Errorf("Current() = %v, want session selection code", got)
+	}
+	if got := m.Current("feishu", "c2"); got.Name != "notes" {
+		t.Errorf("other session Current() = %v, want notes", got)
+	}
+
+	// 清除会话选择后回到渠道默认
+	if entry, err := m.Select("feishu", "c1", ""); err != nil || entry != nil {
+		t.Fatalf("Select(clear) = %v, %v", entry, err)
+	}
+	if got := m.Current("feishu", "c1"); got.Name != "notes" {
+		t.Errorf("Current() after clear = %v, want notes", got)
+	}
+
+	if _, err := m.Select("feishu", "c1", "missing"); err == nil {
+		t.Error("expected error for an unknown workspace")
+	}
+}
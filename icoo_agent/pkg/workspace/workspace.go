@@ -0,0 +1,145 @@
+// Package workspace 根据模板初始化工作目录。
+//
+// 每个档案（profile）有自己的模板套装，位于模板根目录下的同名子目录，
+// 以 .tmpl 结尾的文件按 Go 模板渲染（去掉 .tmpl 后缀），其他文件原样复制。
+// 未提供 default 套装时使用内置模板。
+package workspace
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultProfile 默认档案名称
+const DefaultProfile = "default"
+
+// templateExt 需要渲染的模板文件后缀
+const templateExt = ".tmpl"
+
+//go:embed templates
+var builtinTemplates embed.FS
+
+// Data 模板变量。
+type Data struct {
+	AgentName string            // 智能体名称
+	UserName  string            // 用户名称
+	Date      string            // 初始化日期，如 2024-05-01
+	Profile   string            // 档案名称
+	Vars      map[string]string // 档案声明的其他变量，模板中以 {{.Vars.key}} 引用
+}
+
+// Options 初始化选项。
+type Options struct {
+	// Profile 档案名称，为空时使用 default
+	Profile string
+	// TemplateDir 模板套装目录，为空或不存在且档案为 default 时使用内置模板
+	TemplateDir string
+	// Data 模板变量，Date 和 Profile 为空时自动填充
+	Data Data
+	// Force 覆盖已存在的文件
+	Force bool
+}
+
+// Result 初始化结果，路径相对于工作目录。
+type Result struct {
+	Source  string   // 模板来源
+	Created []string // 新建或覆盖的文件
+	Skipped []string // 已存在而跳过的文件
+}
+
+// Init 用档案的模板套装初始化工作目录，默认不覆盖已存在的文件。
+func Init(dir string, opts Options) (*Result, error) {
+	if opts.Profile == "" {
+		opts.Profile = DefaultProfile
+	}
+	if opts.Data.Profile == "" {
+		opts.Data.Profile = opts.Profile
+	}
+	if opts.Data.Date == "" {
+		opts.Data.Date = time.Now().Format(time.DateOnly)
+	}
+
+	source, fsys, err := resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Source: source}
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		target := strings.TrimSuffix(name, templateExt)
+		dst := filepath.Join(dir, filepath.FromSlash(target))
+		if _, err := os.Stat(dst); err == nil && !opts.Force {
+			result.Skipped = append(result.Skipped, target)
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("读取模板 %s 失败: %w", name, err)
+		}
+		if strings.HasSuffix(name, templateExt) {
+			if content, err = render(name, content, opts.Data); err != nil {
+				return err
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+		if err := os.WriteFile(dst, content, 0o644); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", target, err)
+		}
+		result.Created = append(result.Created, target)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolve 确定模板来源：档案目录存在时使用该目录，否则 default 档案使用内置模板。
+func resolve(opts Options) (string, fs.FS, error) {
+	if opts.TemplateDir != "" {
+		info, err := os.Stat(opts.TemplateDir)
+		if err == nil && info.IsDir() {
+			return opts.TemplateDir, os.DirFS(opts.TemplateDir), nil
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("读取模板目录失败: %w", err)
+		}
+	}
+	if opts.Profile != DefaultProfile {
+		return "", nil, fmt.Errorf("档案 %s 的模板目录 %q 不存在", opts.Profile, opts.TemplateDir)
+	}
+	sub, err := fs.Sub(builtinTemplates, path.Join("templates", DefaultProfile))
+	if err != nil {
+		return "", nil, err
+	}
+	return "内置模板", sub, nil
+}
+
+// render 渲染模板，引用未声明的变量时报错，避免生成半成品提示词。
+func render(name string, content []byte, data Data) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("解析模板 %s 失败: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("渲染模板 %s 失败: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,101 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestInit_ProfileTemplates(t *testing.T) {
+	tmplDir := t.TempDir()
+	writeFile(t, filepath.Join(tmplDir, "SOUL.md.tmpl"), "I am {{.AgentName}} for {{.UserName}}, {{.Vars.product}} ({{.Profile}} {{.Date}})")
+	writeFile(t, filepath.Join(tmplDir, "agents", "AGENTS.md"), "{{.AgentName}} verbatim")
+
+	dir := t.TempDir()
+	result, err := Init(dir, Options{
+		Profile:     "support",
+		TemplateDir: tmplDir,
+		Data: Data{
+			AgentName: "小助",
+			UserName:  "客服团队",
+			Date:      "2024-05-01",
+			Vars:      map[string]string{"product": "icooclaw"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if result.Source != tmplDir || len(result.Created) != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	want := "I am 小助 for 客服团队, icooclaw (support 2024-05-01)"
+	if got := readFile(t, filepath.Join(dir, "SOUL.md")); got != want {
+		t.Errorf("SOUL.md = %q, want %q", got, want)
+	}
+	// 非 .tmpl 文件原样复制
+	if got := readFile(t, filepath.Join(dir, "agents", "AGENTS.md")); got != "{{.AgentName}} verbatim" {
+		t.Errorf("AGENTS.md = %q", got)
+	}
+}
+
+func TestInit_SkipExisting(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "SOUL.md"), "custom")
+
+	result, err := Init(dir, Options{Data: Data{AgentName: "icooclaw"}})
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if result.Source != "内置模板" {
+		t.Errorf("Source = %q, want built-in templates", result.Source)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "SOUL.md" {
+		t.Errorf("Skipped = %v, want [SOUL.md]", result.Skipped)
+	}
+	if got := readFile(t, filepath.Join(dir, "SOUL.md")); got != "custom" {
+		t.Errorf("existing file was overwritten: %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "agents", "AGENTS.md")); !strings.Contains(got, "You are icooclaw") {
+		t.Errorf("AGENTS.md = %q", got)
+	}
+
+	if _, err := Init(dir, Options{Force: true, Data: Data{AgentName: "icooclaw"}}); err != nil {
+		t.Fatalf("Init(force) error = %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "SOUL.md")); got == "custom" {
+		t.Error("force should overwrite existing files")
+	}
+}
+
+func TestInit_Errors(t *testing.T) {
+	if _, err := Init(t.TempDir(), Options{Profile: "missing", TemplateDir: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("expected error for a profile without templates")
+	}
+
+	tmplDir := t.TempDir()
+	writeFile(t, filepath.Join(tmplDir, "USER.md.tmpl"), "{{.Vars.unknown}}")
+	if _, err := Init(t.TempDir(), Options{Profile: "p", TemplateDir: tmplDir}); err == nil {
+		t.Error("expected error for an undefined variable")
+	}
+}